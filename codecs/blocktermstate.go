@@ -0,0 +1,63 @@
+package codecs
+
+import (
+	"fmt"
+
+	"github.com/geange/lucene-go/core/interface/index"
+)
+
+// BlockTermState
+// Holds all state required for PostingsReaderBase to produce a PostingsEnum without re-seeking
+// the terms dict.
+type BlockTermState interface {
+	index.TermState
+
+	// GetBlockTermState Returns the state shared by all postings formats.
+	GetBlockTermState() *BaseBlockTermState
+
+	// Clone Returns a deep copy of this state.
+	Clone() BlockTermState
+}
+
+var _ BlockTermState = &BaseBlockTermState{}
+
+type BaseBlockTermState struct {
+	// Ord term ord
+	Ord int64
+
+	// DocFreq how many docs have this term
+	DocFreq int
+
+	// TotalTermFreq total number of occurrences of this term
+	TotalTermFreq int64
+
+	// TermBlockOrd the term's ord in the current block
+	TermBlockOrd int
+
+	// BlockFilePointer fp into the terms dict primary file (_X.tim) that holds this term
+	BlockFilePointer int64
+}
+
+func NewBaseBlockTermState() *BaseBlockTermState {
+	return &BaseBlockTermState{}
+}
+
+func (b *BaseBlockTermState) GetBlockTermState() *BaseBlockTermState {
+	return b
+}
+
+func (b *BaseBlockTermState) Clone() BlockTermState {
+	other := *b
+	return &other
+}
+
+func (b *BaseBlockTermState) CopyFrom(other index.TermState) {
+	if v, ok := other.(BlockTermState); ok {
+		*b = *v.GetBlockTermState()
+	}
+}
+
+func (b *BaseBlockTermState) String() string {
+	return fmt.Sprintf("docFreq=%d totalTermFreq=%d termBlockOrd=%d blockFP=%d",
+		b.DocFreq, b.TotalTermFreq, b.TermBlockOrd, b.BlockFilePointer)
+}
@@ -0,0 +1,124 @@
+package blocktree
+
+import (
+	"context"
+	"errors"
+
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/automaton"
+	"github.com/geange/lucene-go/core/util/fst"
+)
+
+var _ index.Terms = &FieldReader{}
+
+// FieldReader BlockTree's implementation of Terms.
+// lucene.internal
+type FieldReader struct {
+	parent    *TermsReader
+	fieldInfo *document.FieldInfo
+
+	numTerms         int64
+	sumTotalTermFreq int64
+	sumDocFreq       int64
+	docCount         int
+
+	rootBlockFP int64
+	rootCode    []byte
+	minTerm     []byte
+	maxTerm     []byte
+
+	index *fst.FST
+}
+
+func newFieldReader(ctx context.Context, parent *TermsReader, fieldInfo *document.FieldInfo, numTerms int64,
+	rootCode []byte, sumTotalTermFreq, sumDocFreq int64, docCount int, indexStartFP int64,
+	metaIn, indexIn store.IndexInput, minTerm, maxTerm []byte) (*FieldReader, error) {
+
+	reader := &FieldReader{
+		parent:           parent,
+		fieldInfo:        fieldInfo,
+		numTerms:         numTerms,
+		sumTotalTermFreq: sumTotalTermFreq,
+		sumDocFreq:       sumDocFreq,
+		docCount:         docCount,
+		rootCode:         rootCode,
+		minTerm:          minTerm,
+		maxTerm:          maxTerm,
+	}
+
+	code, err := store.NewBytesInput(rootCode).ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reader.rootBlockFP = int64(code >> OUTPUT_FLAGS_NUM_BITS)
+
+	// The FST metadata lives in the meta file, its bytes in the index file
+	clone, ok := indexIn.Clone().(store.IndexInput)
+	if !ok {
+		return nil, errors.New("clone of terms index is not an IndexInput")
+	}
+	if _, err := clone.Seek(indexStartFP, 0); err != nil {
+		return nil, err
+	}
+	reader.index, err = fst.NewFstV1(ctx, fstOutputs, metaIn, clone)
+	if err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+func (f *FieldReader) Iterator() (index.TermsEnum, error) {
+	return newSegmentTermsEnum(f)
+}
+
+func (f *FieldReader) Intersect(compiled *automaton.CompiledAutomaton, startTerm []byte) (index.TermsEnum, error) {
+	// TODO: we could push "it's a range" or "it's a prefix" down into IntersectTermsEnum?
+	// can we optimize knowing that...?
+	if compiled.Type() != automaton.AUTOMATON_TYPE_NORMAL {
+		return nil, errors.New("please use CompiledAutomaton.getTermsEnum instead")
+	}
+	return newIntersectTermsEnum(f, compiled.Automaton(), compiled.RunAutomaton(),
+		compiled.CommonSuffixRef(), startTerm)
+}
+
+func (f *FieldReader) Size() (int, error) {
+	return int(f.numTerms), nil
+}
+
+func (f *FieldReader) GetSumTotalTermFreq() (int64, error) {
+	return f.sumTotalTermFreq, nil
+}
+
+func (f *FieldReader) GetSumDocFreq() (int64, error) {
+	return f.sumDocFreq, nil
+}
+
+func (f *FieldReader) GetDocCount() (int, error) {
+	return f.docCount, nil
+}
+
+func (f *FieldReader) HasFreqs() bool {
+	return f.fieldInfo.GetIndexOptions() >= document.INDEX_OPTIONS_DOCS_AND_FREQS
+}
+
+func (f *FieldReader) HasOffsets() bool {
+	return f.fieldInfo.GetIndexOptions() >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS
+}
+
+func (f *FieldReader) HasPositions() bool {
+	return f.fieldInfo.GetIndexOptions() >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS
+}
+
+func (f *FieldReader) HasPayloads() bool {
+	return f.fieldInfo.HasPayloads()
+}
+
+func (f *FieldReader) GetMin() ([]byte, error) {
+	return f.minTerm, nil
+}
+
+func (f *FieldReader) GetMax() ([]byte, error) {
+	return f.maxTerm, nil
+}
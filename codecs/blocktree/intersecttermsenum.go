@@ -0,0 +1,542 @@
+package blocktree
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/automaton"
+	"github.com/geange/lucene-go/core/util/fst"
+)
+
+var _ index.TermsEnum = &intersectTermsEnum{}
+
+// intersectTermsEnum This is used to implement efficient Terms.intersect for block-tree.
+// Note that it cannot seek, except for the initial term on init. It just "nexts" through the
+// intersection of the automaton and the terms. It does not use the terms index at all: on init,
+// it loads the root block, and scans its way to the initial term. Likewise, in next it scans
+// until it finds a term that matches the current automaton transition.
+type intersectTermsEnum struct {
+	*coreIndex.BaseTermsEnum
+
+	in store.IndexInput
+
+	stack []*intersectTermsEnumFrame
+
+	arcs []*fst.Arc
+
+	runAutomaton *automaton.ByteRunAutomaton
+	automaton    *automaton.Automaton
+	commonSuffix []byte
+
+	currentFrame      *intersectTermsEnumFrame
+	currentTransition *automaton.Transition
+
+	term []byte
+
+	fstReader fst.BytesReader
+
+	fr *FieldReader
+
+	// Set once there are no more terms
+	ended bool
+}
+
+// newIntersectTermsEnum
+// TODO: in some cases we can filter by length?  eg
+// regexp foo*bar must be at least length 6 bytes
+func newIntersectTermsEnum(fr *FieldReader, a *automaton.Automaton, runAutomaton *automaton.ByteRunAutomaton,
+	commonSuffix []byte, startTerm []byte) (*intersectTermsEnum, error) {
+
+	if a == nil || runAutomaton == nil {
+		return nil, errors.New("automaton and runAutomaton must be set")
+	}
+
+	in, ok := fr.parent.termsIn.Clone().(store.IndexInput)
+	if !ok {
+		return nil, errors.New("clone of terms dictionary is not an IndexInput")
+	}
+
+	enum := &intersectTermsEnum{
+		in:           in,
+		stack:        make([]*intersectTermsEnumFrame, 0, 5),
+		arcs:         make([]*fst.Arc, 0, 5),
+		runAutomaton: runAutomaton,
+		automaton:    a,
+		commonSuffix: commonSuffix,
+		term:         make([]byte, 0, 16),
+		fr:           fr,
+	}
+	enum.BaseTermsEnum = coreIndex.NewBaseTermsEnum(&coreIndex.BaseTermsEnumConfig{SeekCeil: enum.SeekCeil})
+
+	fstReader, err := fr.index.GetBytesReader()
+	if err != nil {
+		return nil, err
+	}
+	enum.fstReader = fstReader
+
+	// TODO: if the automaton is "smallish" we really
+	// should use the terms index to seek at least to
+	// the initial term and likely to subsequent terms
+	// (or, maybe just fallback to ATE for such cases).
+	// Else the seek cost of loading the frames will be
+	// too costly.
+
+	arc, err := fr.index.GetFirstArc(enum.getArc(0))
+	if err != nil {
+		return nil, err
+	}
+
+	// Special pushFrame since it's the first one:
+	f := enum.getFrame(0)
+	f.fp = fr.rootBlockFP
+	f.fpOrig = fr.rootBlockFP
+	f.prefix = 0
+	f.setState(0)
+	f.arc = arc
+	f.outputPrefix = arc.Output()
+	if err := f.load(nil, fr.rootCode); err != nil {
+		return nil, err
+	}
+
+	enum.currentFrame = f
+	if startTerm != nil {
+		if err := enum.seekToStartTerm(nil, startTerm); err != nil {
+			return nil, err
+		}
+	}
+	enum.currentTransition = enum.currentFrame.transition
+	return enum, nil
+}
+
+func (e *intersectTermsEnum) TermState() (index.TermState, error) {
+	if err := e.currentFrame.decodeMetaData(nil); err != nil {
+		return nil, err
+	}
+	return e.currentFrame.termState.Clone(), nil
+}
+
+func (e *intersectTermsEnum) getFrame(ord int) *intersectTermsEnumFrame {
+	for len(e.stack) <= ord {
+		e.stack = append(e.stack, newIntersectTermsEnumFrame(e, len(e.stack)))
+	}
+	return e.stack[ord]
+}
+
+func (e *intersectTermsEnum) getArc(ord int) *fst.Arc {
+	for len(e.arcs) <= ord {
+		e.arcs = append(e.arcs, &fst.Arc{})
+	}
+	return e.arcs[ord]
+}
+
+func (e *intersectTermsEnum) pushFrame(ctx context.Context, state int) (*intersectTermsEnumFrame, error) {
+	f := e.getFrame(1 + e.currentFrame.ord)
+
+	f.fp = e.currentFrame.lastSubFP
+	f.fpOrig = e.currentFrame.lastSubFP
+	f.prefix = e.currentFrame.prefix + e.currentFrame.suffix
+	f.setState(state)
+
+	// Walk the arc through the index -- we only
+	// "bother" with this so we can get the floor data
+	// from the index and skip floor blocks when
+	// possible:
+	arc := e.currentFrame.arc
+	idx := e.currentFrame.prefix
+	output := e.currentFrame.outputPrefix
+	for idx < f.prefix {
+		target := int(e.term[idx])
+		// TODO: we could be more efficient for the next()
+		// case by using current arc as starting point,
+		// passed to findTargetArc
+		nextArc, found, err := e.fr.index.FindTargetArc(ctx, target, e.fstReader, arc, e.getArc(1+idx))
+		if err != nil {
+			return nil, err
+		}
+		if !found || nextArc == nil {
+			return nil, errors.New("sub-block prefix is missing from the terms index")
+		}
+		arc = nextArc
+		if output, err = addOutput(output, arc.Output()); err != nil {
+			return nil, err
+		}
+		idx++
+	}
+
+	f.arc = arc
+	f.outputPrefix = output
+	frameData, err := addOutput(output, arc.NextFinalOutput())
+	if err != nil {
+		return nil, err
+	}
+	if err := f.load(ctx, outputBytes(frameData)); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (e *intersectTermsEnum) Term() ([]byte, error) {
+	return e.term, nil
+}
+
+func (e *intersectTermsEnum) DocFreq() (int, error) {
+	if err := e.currentFrame.decodeMetaData(nil); err != nil {
+		return 0, err
+	}
+	return e.currentFrame.termState.GetBlockTermState().DocFreq, nil
+}
+
+func (e *intersectTermsEnum) TotalTermFreq() (int64, error) {
+	if err := e.currentFrame.decodeMetaData(nil); err != nil {
+		return 0, err
+	}
+	return e.currentFrame.termState.GetBlockTermState().TotalTermFreq, nil
+}
+
+func (e *intersectTermsEnum) Postings(reuse index.PostingsEnum, flags int) (index.PostingsEnum, error) {
+	if err := e.currentFrame.decodeMetaData(nil); err != nil {
+		return nil, err
+	}
+	return e.fr.parent.postingsReader.Postings(e.fr.fieldInfo, e.currentFrame.termState, reuse, flags)
+}
+
+func (e *intersectTermsEnum) Impacts(flags int) (index.ImpactsEnum, error) {
+	if err := e.currentFrame.decodeMetaData(nil); err != nil {
+		return nil, err
+	}
+	return e.fr.parent.postingsReader.Impacts(e.fr.fieldInfo, e.currentFrame.termState, flags)
+}
+
+func (e *intersectTermsEnum) getState() int {
+	state := e.currentFrame.state
+	for idx := 0; idx < e.currentFrame.suffix; idx++ {
+		state = e.runAutomaton.Step(state, int(e.currentFrame.suffixBytes[e.currentFrame.startBytePos+idx]))
+	}
+	return state
+}
+
+// seekToStartTerm NOTE: specialized to only doing the first-time seek, but we could generalize
+// it to allow arbitrary seekExact/Ceil.  Note that this is a seekFloor!
+func (e *intersectTermsEnum) seekToStartTerm(ctx context.Context, target []byte) error {
+	for idx := 0; idx <= len(target); idx++ {
+		for {
+			frame := e.currentFrame
+			savNextEnt := frame.nextEnt
+			savePos := frame.suffixesReader.GetFilePointer()
+			saveLengthPos := frame.suffixLengthsReader.GetFilePointer()
+			saveStartBytePos := frame.startBytePos
+			saveSuffix := frame.suffix
+			saveLastSubFP := frame.lastSubFP
+			saveTermBlockOrd := frame.termState.GetBlockTermState().TermBlockOrd
+
+			isSubBlock, err := frame.next(ctx)
+			if err != nil {
+				return err
+			}
+
+			e.copyTerm()
+
+			if isSubBlock && bytes.HasPrefix(target, e.term) {
+				// Recurse
+				if e.currentFrame, err = e.pushFrame(ctx, e.getState()); err != nil {
+					return err
+				}
+				break
+			}
+
+			cmp := bytes.Compare(e.term, target)
+			if cmp < 0 {
+				if frame.nextEnt == frame.entCount {
+					if !frame.isLastInFloor {
+						// Advance to next floor block
+						if err := frame.loadNextFloorBlock(ctx); err != nil {
+							return err
+						}
+						continue
+					}
+					return nil
+				}
+				continue
+			}
+			if cmp == 0 {
+				return nil
+			}
+
+			// Fallback to prior entry: the semantics of
+			// this method is that the first call to
+			// next() will return the term after the
+			// requested term
+			frame.nextEnt = savNextEnt
+			frame.lastSubFP = saveLastSubFP
+			frame.startBytePos = saveStartBytePos
+			frame.suffix = saveSuffix
+			if _, err := frame.suffixesReader.Seek(savePos, 0); err != nil {
+				return err
+			}
+			if _, err := frame.suffixLengthsReader.Seek(saveLengthPos, 0); err != nil {
+				return err
+			}
+			frame.termState.GetBlockTermState().TermBlockOrd = saveTermBlockOrd
+			e.copyTerm()
+			// If the last entry was a block we don't
+			// need to bother recursing and pushing to
+			// the last term under it because the first
+			// next() will simply skip the frame anyway
+			return nil
+		}
+	}
+	return errors.New("start term is not reachable")
+}
+
+// popPushNext Pops finished frames and decodes the next entry, done is true if there are no more
+// entries at all.
+func (e *intersectTermsEnum) popPushNext(ctx context.Context) (isSubBlock, done bool, err error) {
+	// Pop finished frames
+	for e.currentFrame.nextEnt == e.currentFrame.entCount {
+		if !e.currentFrame.isLastInFloor {
+			// Advance to next floor block
+			if err := e.currentFrame.loadNextFloorBlock(ctx); err != nil {
+				return false, false, err
+			}
+			break
+		}
+
+		if e.currentFrame.ord == 0 {
+			return false, true, nil
+		}
+		e.currentFrame = e.stack[e.currentFrame.ord-1]
+		e.currentTransition = e.currentFrame.transition
+	}
+
+	isSubBlock, err = e.currentFrame.next(ctx)
+	return isSubBlock, false, err
+}
+
+// skipToTransition Scans the terms of the current frame until one starts with a label of at
+// least the current transition's minimum, popping the frame if none does.
+func (e *intersectTermsEnum) skipToTransition(ctx context.Context) (isSubBlock, done bool, err error) {
+	minTrans := e.currentTransition.Min
+	for e.currentFrame.nextEnt < e.currentFrame.entCount {
+		isSubBlock, err = e.currentFrame.next(ctx)
+		if err != nil {
+			return false, false, err
+		}
+		if int(e.currentFrame.suffixBytes[e.currentFrame.startBytePos]) >= minTrans {
+			return isSubBlock, false, nil
+		}
+	}
+	// End of frame:
+	return e.popPushNext(ctx)
+}
+
+func (e *intersectTermsEnum) Next(ctx context.Context) ([]byte, error) {
+	if e.ended {
+		return nil, nil
+	}
+	term, done, err := e.next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if done {
+		e.ended = true
+		return nil, nil
+	}
+	return term, nil
+}
+
+func (e *intersectTermsEnum) next(ctx context.Context) ([]byte, bool, error) {
+	isSubBlock, done, err := e.popPushNext(ctx)
+	if err != nil || done {
+		return nil, done, err
+	}
+
+nextTerm:
+	for {
+		var state, lastState int
+
+		// NOTE: suffix == 0 can only happen on the first term in a block, when
+		// there is a term exactly matching a prefix in the index.  If we
+		// could somehow re-org the code so we only checked this case immediately
+		// after pushing a frame...
+		if e.currentFrame.suffix != 0 {
+			suffixBytes := e.currentFrame.suffixBytes
+
+			// This is the first byte of the suffix of the term we are now on:
+			label := int(suffixBytes[e.currentFrame.startBytePos])
+
+			if label < e.currentTransition.Min {
+				// Common case: we are scanning terms in this block to "catch up" to
+				// current transition in the automaton:
+				isSubBlock, done, err = e.skipToTransition(ctx)
+				if err != nil || done {
+					return nil, done, err
+				}
+				continue nextTerm
+			}
+
+			// Advance where we are in the automaton to match this label:
+			for label > e.currentTransition.Max {
+				if e.currentFrame.transitionIndex >= e.currentFrame.transitionCount-1 {
+					// Pop this frame: no further matches are possible because
+					// we've moved beyond what the max transition will allow
+					// sneaky!  forces a pop above
+					e.currentFrame.isLastInFloor = true
+					e.currentFrame.nextEnt = e.currentFrame.entCount
+					isSubBlock, done, err = e.popPushNext(ctx)
+					if err != nil || done {
+						return nil, done, err
+					}
+					continue nextTerm
+				}
+				e.currentFrame.transitionIndex++
+				e.automaton.GetNextTransition(e.currentTransition)
+
+				if label < e.currentTransition.Min {
+					isSubBlock, done, err = e.skipToTransition(ctx)
+					if err != nil || done {
+						return nil, done, err
+					}
+					continue nextTerm
+				}
+			}
+
+			if e.commonSuffix != nil && !isSubBlock {
+				if !e.matchesCommonSuffix() {
+					isSubBlock, done, err = e.popPushNext(ctx)
+					if err != nil || done {
+						return nil, done, err
+					}
+					continue nextTerm
+				}
+			}
+
+			// TODO: maybe we should do the same linear test
+			// that AutomatonTermsEnum does, so that if we
+			// reach a part of the automaton where .* is
+			// "temporarily" accepted, we just blindly .next()
+			// until the limit
+
+			// See if the term suffix matches the automaton:
+
+			// We know from above that the first byte in our suffix (label) matches
+			// the current transition, so we step from the 2nd byte
+			// in the suffix:
+			lastState = e.currentFrame.state
+			state = e.currentTransition.Dest
+
+			end := e.currentFrame.startBytePos + e.currentFrame.suffix
+			for idx := e.currentFrame.startBytePos + 1; idx < end; idx++ {
+				lastState = state
+				state = e.runAutomaton.Step(state, int(suffixBytes[idx]))
+				if state == -1 {
+					// No match
+					isSubBlock, done, err = e.popPushNext(ctx)
+					if err != nil || done {
+						return nil, done, err
+					}
+					continue nextTerm
+				}
+			}
+		} else {
+			state = e.currentFrame.state
+			lastState = e.currentFrame.lastState
+		}
+
+		if isSubBlock {
+			// Match!  Recurse:
+			e.copyTerm()
+			if e.currentFrame, err = e.pushFrame(ctx, state); err != nil {
+				return nil, false, err
+			}
+			e.currentTransition = e.currentFrame.transition
+			e.currentFrame.lastState = lastState
+		} else if e.runAutomaton.IsAccept(state) {
+			e.copyTerm()
+			return e.term, false, nil
+		}
+
+		// Since this is a term we don't yet want, skip it:
+		isSubBlock, done, err = e.popPushNext(ctx)
+		if err != nil || done {
+			return nil, done, err
+		}
+	}
+}
+
+// matchesCommonSuffix Returns true if the term the current frame is positioned on ends with the
+// common suffix of the automaton.
+func (e *intersectTermsEnum) matchesCommonSuffix() bool {
+	frame := e.currentFrame
+	termLen := frame.prefix + frame.suffix
+	if termLen < len(e.commonSuffix) {
+		// No match
+		return false
+	}
+
+	commonSuffixBytesPos := 0
+	suffixBytesPos := 0
+	lenInPrefix := len(e.commonSuffix) - frame.suffix
+	if lenInPrefix > 0 {
+		// A prefix of the common suffix overlaps with
+		// the suffix of the block prefix so we first
+		// test whether the prefix part matches:
+		termBytesPos := frame.prefix - lenInPrefix
+		termBytesPosEnd := frame.prefix
+		for termBytesPos < termBytesPosEnd {
+			if e.term[termBytesPos] != e.commonSuffix[commonSuffixBytesPos] {
+				return false
+			}
+			termBytesPos++
+			commonSuffixBytesPos++
+		}
+		suffixBytesPos = frame.startBytePos
+	} else {
+		suffixBytesPos = frame.startBytePos + frame.suffix - len(e.commonSuffix)
+	}
+
+	// Test overlapping suffix part:
+	for commonSuffixBytesPos < len(e.commonSuffix) {
+		if frame.suffixBytes[suffixBytesPos] != e.commonSuffix[commonSuffixBytesPos] {
+			return false
+		}
+		suffixBytesPos++
+		commonSuffixBytesPos++
+	}
+	return true
+}
+
+func (e *intersectTermsEnum) copyTerm() {
+	frame := e.currentFrame
+	length := frame.prefix + frame.suffix
+	if cap(e.term) < length {
+		term := make([]byte, length, 2*length)
+		copy(term, e.term)
+		e.term = term
+	}
+	e.term = e.term[:length]
+	copy(e.term[frame.prefix:], frame.suffixBytes[frame.startBytePos:frame.startBytePos+frame.suffix])
+}
+
+func (e *intersectTermsEnum) SeekExact(ctx context.Context, text []byte) (bool, error) {
+	return false, errors.New("unsupported operation")
+}
+
+func (e *intersectTermsEnum) SeekExactByOrd(ctx context.Context, ord int64) error {
+	return errors.New("unsupported operation")
+}
+
+func (e *intersectTermsEnum) SeekExactExpert(ctx context.Context, term []byte, state index.TermState) error {
+	return errors.New("unsupported operation")
+}
+
+func (e *intersectTermsEnum) Ord() (int64, error) {
+	return 0, errors.New("unsupported operation")
+}
+
+func (e *intersectTermsEnum) SeekCeil(ctx context.Context, text []byte) (index.SeekStatus, error) {
+	return 0, errors.New("unsupported operation")
+}
@@ -0,0 +1,284 @@
+package blocktree
+
+import (
+	"context"
+	"io"
+
+	"github.com/geange/lucene-go/codecs"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/automaton"
+	"github.com/geange/lucene-go/core/util/fst"
+)
+
+type intersectTermsEnumFrame struct {
+	ord int
+
+	fp        int64
+	fpOrig    int64
+	fpEnd     int64
+	lastSubFP int64
+
+	// State in automaton
+	state int
+
+	// State just before the last label
+	lastState int
+
+	metaDataUpto int
+
+	suffixBytes         []byte
+	suffixesReader      *store.BytesInput
+	suffixLengthsReader *store.BytesInput
+
+	statsReader             *store.BytesInput
+	statsSingletonRunLength int
+
+	floorDataReader *store.BytesInput
+
+	// Length of prefix shared by all terms in this block
+	prefix int
+
+	// Number of entries (term or sub-block) in this block
+	entCount int
+
+	// Which term we will next read
+	nextEnt int
+
+	// True if this block is either not a floor block,
+	// or, it's the last sub-block of a floor block
+	isLastInFloor bool
+
+	// True if all entries are terms
+	isLeafBlock bool
+
+	numFollowFloorBlocks int
+	nextFloorLabel       int
+
+	transition      *automaton.Transition
+	transitionIndex int
+	transitionCount int
+
+	arc *fst.Arc
+
+	termState codecs.BlockTermState
+
+	// metadata buffer
+	bytesReader *store.BytesInput
+
+	// Cumulative output so far
+	outputPrefix fst.Output
+
+	startBytePos int
+	suffix       int
+
+	ite *intersectTermsEnum
+}
+
+func newIntersectTermsEnumFrame(ite *intersectTermsEnum, ord int) *intersectTermsEnumFrame {
+	termState := ite.fr.parent.postingsReader.NewTermState()
+	termState.GetBlockTermState().TotalTermFreq = -1
+	return &intersectTermsEnumFrame{
+		ord:        ord,
+		transition: automaton.NewTransition(),
+		termState:  termState,
+		ite:        ite,
+	}
+}
+
+func (i *intersectTermsEnumFrame) loadNextFloorBlock(ctx context.Context) error {
+	for {
+		code, err := i.floorDataReader.ReadUvarint(ctx)
+		if err != nil {
+			return err
+		}
+		i.fp = i.fpOrig + int64(code>>1)
+		i.numFollowFloorBlocks--
+		if i.numFollowFloorBlocks != 0 {
+			nextFloorLabel, err := i.floorDataReader.ReadByte()
+			if err != nil {
+				return err
+			}
+			i.nextFloorLabel = int(nextFloorLabel)
+		} else {
+			i.nextFloorLabel = 256
+		}
+
+		if i.numFollowFloorBlocks == 0 || i.nextFloorLabel > i.transition.Min {
+			break
+		}
+	}
+	return i.load(ctx, nil)
+}
+
+func (i *intersectTermsEnumFrame) setState(state int) {
+	i.state = state
+	i.transitionIndex = 0
+	i.transitionCount = i.ite.automaton.GetNumTransitionsWithState(state)
+	if i.transitionCount != 0 {
+		i.ite.automaton.InitTransition(state, i.transition)
+		i.ite.automaton.GetNextTransition(i.transition)
+	} else {
+		// Must set min to -1 so the "label < min" check never falsely triggers:
+		i.transition.Min = -1
+
+		// Must set max to -1 so we immediately realize we need to step to the next transition and
+		// then pop this frame:
+		i.transition.Max = -1
+	}
+}
+
+func (i *intersectTermsEnumFrame) load(ctx context.Context, frameIndexData []byte) error {
+	if frameIndexData != nil {
+		i.floorDataReader = store.NewBytesInput(frameIndexData)
+		// Skip first long -- has redundant fp, hasTerms
+		// flag, isFloor flag
+		code, err := i.floorDataReader.ReadUvarint(ctx)
+		if err != nil {
+			return err
+		}
+		if code&OUTPUT_FLAG_IS_FLOOR != 0 {
+			// Floor frame
+			numFollowFloorBlocks, err := i.floorDataReader.ReadUvarint(ctx)
+			if err != nil {
+				return err
+			}
+			i.numFollowFloorBlocks = int(numFollowFloorBlocks)
+			nextFloorLabel, err := i.floorDataReader.ReadByte()
+			if err != nil {
+				return err
+			}
+			i.nextFloorLabel = int(nextFloorLabel)
+
+			// If current state is not accept, and has transitions, we must process
+			// first block in case it has empty suffix:
+			if !i.ite.runAutomaton.IsAccept(i.state) && i.transitionCount != 0 {
+				// Maybe skip floor blocks:
+				for i.numFollowFloorBlocks != 0 && i.nextFloorLabel <= i.transition.Min {
+					code, err := i.floorDataReader.ReadUvarint(ctx)
+					if err != nil {
+						return err
+					}
+					i.fp = i.fpOrig + int64(code>>1)
+					i.numFollowFloorBlocks--
+					if i.numFollowFloorBlocks != 0 {
+						nextFloorLabel, err := i.floorDataReader.ReadByte()
+						if err != nil {
+							return err
+						}
+						i.nextFloorLabel = int(nextFloorLabel)
+					} else {
+						i.nextFloorLabel = 256
+					}
+				}
+			}
+		}
+	}
+
+	in := i.ite.in
+	if _, err := in.Seek(i.fp, io.SeekStart); err != nil {
+		return err
+	}
+	block, err := readBlock(ctx, in)
+	if err != nil {
+		return err
+	}
+	i.entCount = block.entCount
+	i.isLastInFloor = block.isLastInFloor
+	i.isLeafBlock = block.isLeafBlock
+	i.suffixBytes = block.suffixBytes
+	i.suffixesReader = store.NewBytesInput(block.suffixBytes)
+	i.suffixLengthsReader = store.NewBytesInput(block.suffixLengthBytes)
+	i.statsReader = store.NewBytesInput(block.statBytes)
+	i.bytesReader = store.NewBytesInput(block.metaBytes)
+
+	i.statsSingletonRunLength = 0
+	i.metaDataUpto = 0
+	i.termState.GetBlockTermState().TermBlockOrd = 0
+	i.nextEnt = 0
+
+	if !i.isLastInFloor {
+		// Sub-blocks of a single floor block are always
+		// written one after another -- tail recurse:
+		i.fpEnd = in.GetFilePointer()
+	}
+	return nil
+}
+
+// next Decodes next entry; returns true if it's a sub-block
+// TODO: maybe add scanToLabel; should give perf boost
+func (i *intersectTermsEnumFrame) next(ctx context.Context) (bool, error) {
+	if i.isLeafBlock {
+		return false, i.nextLeaf(ctx)
+	}
+	return i.nextNonLeaf(ctx)
+}
+
+func (i *intersectTermsEnumFrame) nextLeaf(ctx context.Context) error {
+	i.nextEnt++
+	suffix, err := i.suffixLengthsReader.ReadUvarint(ctx)
+	if err != nil {
+		return err
+	}
+	i.suffix = int(suffix)
+	i.startBytePos = int(i.suffixesReader.GetFilePointer())
+	return i.suffixesReader.SkipBytes(ctx, i.suffix)
+}
+
+func (i *intersectTermsEnumFrame) nextNonLeaf(ctx context.Context) (bool, error) {
+	i.nextEnt++
+	code, err := i.suffixLengthsReader.ReadUvarint(ctx)
+	if err != nil {
+		return false, err
+	}
+	i.suffix = int(code >> 1)
+	i.startBytePos = int(i.suffixesReader.GetFilePointer())
+	if err := i.suffixesReader.SkipBytes(ctx, i.suffix); err != nil {
+		return false, err
+	}
+	if code&1 == 0 {
+		// A normal term
+		i.termState.GetBlockTermState().TermBlockOrd++
+		return false, nil
+	}
+
+	// A sub-block; make sub-FP absolute:
+	subCode, err := i.suffixLengthsReader.ReadUvarint(ctx)
+	if err != nil {
+		return false, err
+	}
+	i.lastSubFP = i.fp - int64(subCode)
+	return true, nil
+}
+
+func (i *intersectTermsEnumFrame) getTermBlockOrd() int {
+	if i.isLeafBlock {
+		return i.nextEnt
+	}
+	return i.termState.GetBlockTermState().TermBlockOrd
+}
+
+func (i *intersectTermsEnumFrame) decodeMetaData(ctx context.Context) error {
+	// lazily catch up on metadata decode:
+	limit := i.getTermBlockOrd()
+	absolute := i.metaDataUpto == 0
+
+	state := i.termState.GetBlockTermState()
+	fieldInfo := i.ite.fr.fieldInfo
+
+	// TODO: better API would be "jump straight to term=N"???
+	for i.metaDataUpto < limit {
+		if err := decodeStats(ctx, i.statsReader, &i.statsSingletonRunLength, fieldInfo, state); err != nil {
+			return err
+		}
+
+		// metadata
+		if err := i.ite.fr.parent.postingsReader.DecodeTerm(i.bytesReader, fieldInfo, i.termState, absolute); err != nil {
+			return err
+		}
+
+		i.metaDataUpto++
+		absolute = false
+	}
+	state.TermBlockOrd = i.metaDataUpto
+	return nil
+}
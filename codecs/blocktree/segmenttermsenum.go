@@ -0,0 +1,599 @@
+package blocktree
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/fst"
+)
+
+var _ index.TermsEnum = &segmentTermsEnum{}
+
+// segmentTermsEnum Iterates through terms in this field.
+type segmentTermsEnum struct {
+	*coreIndex.BaseTermsEnum
+
+	// Lazy init:
+	in store.IndexInput
+
+	stack        []*segmentTermsEnumFrame
+	staticFrame  *segmentTermsEnumFrame
+	currentFrame *segmentTermsEnumFrame
+	termExists   bool
+	fr           *FieldReader
+
+	targetBeforeCurrentLength int
+
+	// What prefix of the current term was present in the index; when we only next() through the
+	// index, this stays at 0.  It's only set when we seekCeil/Exact:
+	validIndexPrefix int
+
+	term []byte
+
+	fstReader fst.BytesReader
+
+	arcs []*fst.Arc
+}
+
+func newSegmentTermsEnum(fr *FieldReader) (*segmentTermsEnum, error) {
+	enum := &segmentTermsEnum{
+		fr:    fr,
+		stack: make([]*segmentTermsEnumFrame, 0),
+		term:  make([]byte, 0, 16),
+		arcs:  []*fst.Arc{{}},
+	}
+	enum.BaseTermsEnum = coreIndex.NewBaseTermsEnum(&coreIndex.BaseTermsEnumConfig{SeekCeil: enum.SeekCeil})
+
+	// Used to hold seek by TermState, or cached seek
+	enum.staticFrame = newSegmentTermsEnumFrame(enum, -1)
+
+	fstReader, err := fr.index.GetBytesReader()
+	if err != nil {
+		return nil, err
+	}
+	enum.fstReader = fstReader
+
+	// Init w/ root block; don't use index since it may
+	// not (and need not) have been loaded
+	enum.currentFrame = enum.staticFrame
+	enum.validIndexPrefix = 0
+	return enum, nil
+}
+
+func (e *segmentTermsEnum) initIndexInput() error {
+	if e.in == nil {
+		in, ok := e.fr.parent.termsIn.Clone().(store.IndexInput)
+		if !ok {
+			return errors.New("clone of terms dictionary is not an IndexInput")
+		}
+		e.in = in
+	}
+	return nil
+}
+
+func (e *segmentTermsEnum) getFrame(ord int) *segmentTermsEnumFrame {
+	for len(e.stack) <= ord {
+		e.stack = append(e.stack, newSegmentTermsEnumFrame(e, len(e.stack)))
+	}
+	return e.stack[ord]
+}
+
+func (e *segmentTermsEnum) getArc(ord int) *fst.Arc {
+	for len(e.arcs) <= ord {
+		e.arcs = append(e.arcs, &fst.Arc{})
+	}
+	return e.arcs[ord]
+}
+
+// setTermLength Resizes the current term, keeping the bytes beyond the previous length.
+func (e *segmentTermsEnum) setTermLength(length int) {
+	e.growTerm(length)
+	e.term = e.term[:length]
+}
+
+func (e *segmentTermsEnum) growTerm(capacity int) {
+	if cap(e.term) < capacity {
+		term := make([]byte, len(e.term), max(capacity, 2*cap(e.term)))
+		copy(term[:cap(e.term)], e.term[:cap(e.term)])
+		e.term = term
+	}
+}
+
+func (e *segmentTermsEnum) setTermByteAt(idx int, b byte) {
+	e.term[:cap(e.term)][idx] = b
+}
+
+// pushFrameByData Pushes a frame we seek'd to
+func (e *segmentTermsEnum) pushFrameByData(arc *fst.Arc, frameData []byte, length int) (*segmentTermsEnumFrame, error) {
+	scratchReader := store.NewBytesInput(frameData)
+	code, err := scratchReader.ReadUvarint(nil)
+	if err != nil {
+		return nil, err
+	}
+	fpSeek := int64(code >> OUTPUT_FLAGS_NUM_BITS)
+	f := e.getFrame(1 + e.currentFrame.ord)
+	f.hasTerms = code&OUTPUT_FLAG_HAS_TERMS != 0
+	f.hasTermsOrig = f.hasTerms
+	f.isFloor = code&OUTPUT_FLAG_IS_FLOOR != 0
+	if f.isFloor {
+		if err := f.setFloorData(frameData[scratchReader.GetFilePointer():]); err != nil {
+			return nil, err
+		}
+	}
+	return e.pushFrame(arc, fpSeek, length)
+}
+
+// pushFrame Pushes next'd frame or seek'd frame; we later lazy-load the frame only when needed
+func (e *segmentTermsEnum) pushFrame(arc *fst.Arc, fp int64, length int) (*segmentTermsEnumFrame, error) {
+	f := e.getFrame(1 + e.currentFrame.ord)
+	f.arc = arc
+	if f.fpOrig == fp && f.nextEnt != -1 {
+		if f.ord > e.targetBeforeCurrentLength {
+			if err := f.rewind(); err != nil {
+				return nil, err
+			}
+		}
+		if length != f.prefix {
+			return nil, fmt.Errorf("reused frame has prefix %d, expected %d", f.prefix, length)
+		}
+	} else {
+		f.nextEnt = -1
+		f.prefix = length
+		f.state.GetBlockTermState().TermBlockOrd = 0
+		f.fpOrig = fp
+		f.fp = fp
+		f.lastSubFP = -1
+	}
+	return f, nil
+}
+
+func addOutput(prefix, output fst.Output) (fst.Output, error) {
+	if output == nil || output.IsNoOutput() {
+		return prefix, nil
+	}
+	return prefix.Add(output)
+}
+
+func outputBytes(output fst.Output) []byte {
+	return output.(*fst.BytesOutput).Bytes()
+}
+
+// seekPrefix Re-uses the seek state shared by the current term and the target and walks the
+// terms index for the rest of the target, positioning currentFrame on the block that may
+// contain the target. It returns done=true if the target is the current term.
+func (e *segmentTermsEnum) seekPrefix(ctx context.Context, target []byte, rewindLength int) (*fst.Arc, int, fst.Output, bool, error) {
+	e.growTerm(1 + len(target))
+
+	var arc *fst.Arc
+	var targetUpto int
+	var output fst.Output
+
+	e.targetBeforeCurrentLength = e.currentFrame.ord
+
+	if e.currentFrame != e.staticFrame {
+		// We are already seek'd; find the common
+		// prefix of new seek term vs current term and
+		// re-use the corresponding seek state.  For
+		// example, if app first seeks to foobar, then
+		// seeks to foobaz, we can re-use the seek state
+		// for the first 5 bytes.
+		arc = e.arcs[0]
+		output = arc.Output()
+		targetUpto = 0
+
+		lastFrame := e.stack[0]
+		targetLimit := min(len(target), e.validIndexPrefix)
+
+		cmp := 0
+
+		// First compare up to valid seek frames:
+		for targetUpto < targetLimit {
+			cmp = int(e.term[targetUpto]) - int(target[targetUpto])
+			if cmp != 0 {
+				break
+			}
+			arc = e.arcs[1+targetUpto]
+			var err error
+			if output, err = addOutput(output, arc.Output()); err != nil {
+				return nil, 0, nil, false, err
+			}
+			if arc.IsFinal() {
+				lastFrame = e.stack[1+lastFrame.ord]
+			}
+			targetUpto++
+		}
+
+		if cmp == 0 {
+			// Second compare the rest of the term, but
+			// don't save arc/output/frame; we only do this
+			// to find out if the target term is before,
+			// equal or after the current term
+			cmp = bytes.Compare(e.term[targetUpto:], target[targetUpto:])
+		}
+
+		if cmp < 0 {
+			// Common case: target term is after current
+			// term, ie, app is seeking multiple terms
+			// in sorted order
+			e.currentFrame = lastFrame
+		} else if cmp > 0 {
+			// Uncommon case: target term
+			// is before current term; this means we can
+			// keep the currentFrame but we must rewind it
+			// (so we scan from the start)
+			if rewindLength < 0 {
+				e.targetBeforeCurrentLength = lastFrame.ord
+			} else {
+				e.targetBeforeCurrentLength = rewindLength
+			}
+			e.currentFrame = lastFrame
+			if err := e.currentFrame.rewind(); err != nil {
+				return nil, 0, nil, false, err
+			}
+		} else {
+			// Target is exactly the same as current term
+			if e.termExists {
+				return arc, targetUpto, output, true, nil
+			}
+		}
+	} else {
+		e.targetBeforeCurrentLength = -1
+		var err error
+		arc, err = e.fr.index.GetFirstArc(e.arcs[0])
+		if err != nil {
+			return nil, 0, nil, false, err
+		}
+
+		// Empty string prefix must have an output (block) in the index!
+		output = arc.Output()
+		e.currentFrame = e.staticFrame
+
+		targetUpto = 0
+		frameData, err := addOutput(output, arc.NextFinalOutput())
+		if err != nil {
+			return nil, 0, nil, false, err
+		}
+		if e.currentFrame, err = e.pushFrameByData(arc, outputBytes(frameData), 0); err != nil {
+			return nil, 0, nil, false, err
+		}
+	}
+	return arc, targetUpto, output, false, nil
+}
+
+func (e *segmentTermsEnum) SeekExact(ctx context.Context, target []byte) (bool, error) {
+	if e.fr.numTerms > 0 && (bytes.Compare(target, e.fr.minTerm) < 0 || bytes.Compare(target, e.fr.maxTerm) > 0) {
+		return false, nil
+	}
+
+	arc, targetUpto, output, done, err := e.seekPrefix(ctx, target, -1)
+	if err != nil {
+		return false, err
+	}
+	if done {
+		return true, nil
+	}
+
+	// We are done sharing the common prefix with the incoming target and where we are currently
+	// seek'd; now continue walking the index:
+	for targetUpto < len(target) {
+		targetLabel := int(target[targetUpto])
+		nextArc, found, err := e.fr.index.FindTargetArc(ctx, targetLabel, e.fstReader, arc, e.getArc(1+targetUpto))
+		if err != nil {
+			return false, err
+		}
+
+		if !found || nextArc == nil {
+			// Index is exhausted
+			e.validIndexPrefix = e.currentFrame.prefix
+
+			if err := e.currentFrame.scanToFloorFrame(target); err != nil {
+				return false, err
+			}
+
+			if !e.currentFrame.hasTerms {
+				e.termExists = false
+				e.setTermByteAt(targetUpto, byte(targetLabel))
+				e.setTermLength(1 + targetUpto)
+				return false, nil
+			}
+
+			if err := e.currentFrame.loadBlock(ctx); err != nil {
+				return false, err
+			}
+
+			result, err := e.currentFrame.scanToTerm(ctx, target, true)
+			if err != nil {
+				return false, err
+			}
+			return result == index.SEEK_STATUS_FOUND, nil
+		}
+
+		// Follow this arc
+		arc = nextArc
+		e.setTermByteAt(targetUpto, byte(targetLabel))
+		// Aggregate output as we go:
+		if output, err = addOutput(output, arc.Output()); err != nil {
+			return false, err
+		}
+
+		targetUpto++
+
+		if arc.IsFinal() {
+			frameData, err := addOutput(output, arc.NextFinalOutput())
+			if err != nil {
+				return false, err
+			}
+			if e.currentFrame, err = e.pushFrameByData(arc, outputBytes(frameData), targetUpto); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	e.validIndexPrefix = e.currentFrame.prefix
+
+	if err := e.currentFrame.scanToFloorFrame(target); err != nil {
+		return false, err
+	}
+
+	// Target term is entirely contained in the index:
+	if !e.currentFrame.hasTerms {
+		e.termExists = false
+		e.setTermLength(targetUpto)
+		return false, nil
+	}
+
+	if err := e.currentFrame.loadBlock(ctx); err != nil {
+		return false, err
+	}
+
+	result, err := e.currentFrame.scanToTerm(ctx, target, true)
+	if err != nil {
+		return false, err
+	}
+	return result == index.SEEK_STATUS_FOUND, nil
+}
+
+func (e *segmentTermsEnum) SeekCeil(ctx context.Context, target []byte) (index.SeekStatus, error) {
+	arc, targetUpto, output, done, err := e.seekPrefix(ctx, target, 0)
+	if err != nil {
+		return 0, err
+	}
+	if done {
+		return index.SEEK_STATUS_FOUND, nil
+	}
+
+	// We are done sharing the common prefix with the incoming target and where we are currently
+	// seek'd; now continue walking the index:
+	for targetUpto < len(target) {
+		targetLabel := int(target[targetUpto])
+		nextArc, found, err := e.fr.index.FindTargetArc(ctx, targetLabel, e.fstReader, arc, e.getArc(1+targetUpto))
+		if err != nil {
+			return 0, err
+		}
+
+		if !found || nextArc == nil {
+			// Index is exhausted
+			e.validIndexPrefix = e.currentFrame.prefix
+			return e.scanToCeil(ctx, target)
+		}
+
+		// Follow this arc
+		e.setTermByteAt(targetUpto, byte(targetLabel))
+		arc = nextArc
+		// Aggregate output as we go:
+		if output, err = addOutput(output, arc.Output()); err != nil {
+			return 0, err
+		}
+
+		targetUpto++
+
+		if arc.IsFinal() {
+			frameData, err := addOutput(output, arc.NextFinalOutput())
+			if err != nil {
+				return 0, err
+			}
+			if e.currentFrame, err = e.pushFrameByData(arc, outputBytes(frameData), targetUpto); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	e.validIndexPrefix = e.currentFrame.prefix
+	return e.scanToCeil(ctx, target)
+}
+
+// scanToCeil Scans the current frame for the target, moving on to the next term if the target is
+// after all terms of the frame.
+func (e *segmentTermsEnum) scanToCeil(ctx context.Context, target []byte) (index.SeekStatus, error) {
+	if err := e.currentFrame.scanToFloorFrame(target); err != nil {
+		return 0, err
+	}
+	if err := e.currentFrame.loadBlock(ctx); err != nil {
+		return 0, err
+	}
+
+	result, err := e.currentFrame.scanToTerm(ctx, target, false)
+	if err != nil {
+		return 0, err
+	}
+	if result != index.SEEK_STATUS_END {
+		return result, nil
+	}
+
+	e.setTermLength(len(target))
+	copy(e.term, target)
+	e.termExists = false
+
+	term, err := e.Next(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if term != nil {
+		return index.SEEK_STATUS_NOT_FOUND, nil
+	}
+	return index.SEEK_STATUS_END, nil
+}
+
+// Next Decodes only the term bytes of the next term.  If caller then asks for metadata, ie
+// docFreq, totalTermFreq or pulls a D/&PEnum, we then (lazily) decode all metadata up to the
+// current term.
+func (e *segmentTermsEnum) Next(ctx context.Context) ([]byte, error) {
+	if e.in == nil {
+		// Fresh TermsEnum; seek to first term:
+		arc, err := e.fr.index.GetFirstArc(e.arcs[0])
+		if err != nil {
+			return nil, err
+		}
+		if e.currentFrame, err = e.pushFrameByData(arc, e.fr.rootCode, 0); err != nil {
+			return nil, err
+		}
+		if err := e.currentFrame.loadBlock(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	e.targetBeforeCurrentLength = e.currentFrame.ord
+
+	if e.currentFrame == e.staticFrame {
+		// If seek was previously called and the term was
+		// cached, or seek(TermState) was called, usually
+		// caller is just going to pull a D/&PEnum or get
+		// docFreq, etc.  But, if they then call next(),
+		// this method catches up all internal state so next()
+		// works properly:
+		found, err := e.SeekExact(ctx, bytes.Clone(e.term))
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, errors.New("seek to the current term failed")
+		}
+	}
+
+	// Pop finished blocks
+	for e.currentFrame.nextEnt == e.currentFrame.entCount {
+		if !e.currentFrame.isLastInFloor {
+			// Advance to next floor block
+			if err := e.currentFrame.loadNextFloorBlock(ctx); err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		if e.currentFrame.ord == 0 {
+			e.setTermLength(0)
+			e.validIndexPrefix = 0
+			if err := e.currentFrame.rewind(); err != nil {
+				return nil, err
+			}
+			e.termExists = false
+			return nil, nil
+		}
+
+		lastFP := e.currentFrame.fpOrig
+		e.currentFrame = e.stack[e.currentFrame.ord-1]
+
+		if e.currentFrame.nextEnt == -1 || e.currentFrame.lastSubFP != lastFP {
+			// We popped into a frame that's not loaded
+			// yet or not scan'd to the right entry
+			if err := e.currentFrame.scanToFloorFrame(e.term); err != nil {
+				return nil, err
+			}
+			if err := e.currentFrame.loadBlock(ctx); err != nil {
+				return nil, err
+			}
+			if err := e.currentFrame.scanToSubBlock(ctx, lastFP); err != nil {
+				return nil, err
+			}
+		}
+
+		// Note that the seek state (last seek) has been
+		// invalidated beyond this depth
+		e.validIndexPrefix = min(e.validIndexPrefix, e.currentFrame.prefix)
+	}
+
+	for {
+		isSubBlock, err := e.currentFrame.next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !isSubBlock {
+			return e.term, nil
+		}
+
+		// Push to new block:
+		if e.currentFrame, err = e.pushFrame(nil, e.currentFrame.lastSubFP, len(e.term)); err != nil {
+			return nil, err
+		}
+		// This is a "next" frame -- even if it's
+		// floor'd we must pretend it isn't so we don't
+		// try to scan to the right floor frame:
+		if err := e.currentFrame.loadBlock(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (e *segmentTermsEnum) SeekExactByOrd(ctx context.Context, ord int64) error {
+	return errors.New("unsupported operation")
+}
+
+func (e *segmentTermsEnum) SeekExactExpert(ctx context.Context, target []byte, otherState index.TermState) error {
+	if !bytes.Equal(target, e.term) || !e.termExists {
+		e.currentFrame = e.staticFrame
+		e.currentFrame.state.CopyFrom(otherState)
+		e.setTermLength(len(target))
+		copy(e.term, target)
+		e.currentFrame.metaDataUpto = e.currentFrame.getTermBlockOrd()
+		e.validIndexPrefix = 0
+	}
+	return nil
+}
+
+func (e *segmentTermsEnum) Term() ([]byte, error) {
+	return e.term, nil
+}
+
+func (e *segmentTermsEnum) Ord() (int64, error) {
+	return 0, errors.New("unsupported operation")
+}
+
+func (e *segmentTermsEnum) DocFreq() (int, error) {
+	if err := e.currentFrame.decodeMetaData(nil); err != nil {
+		return 0, err
+	}
+	return e.currentFrame.state.GetBlockTermState().DocFreq, nil
+}
+
+func (e *segmentTermsEnum) TotalTermFreq() (int64, error) {
+	if err := e.currentFrame.decodeMetaData(nil); err != nil {
+		return 0, err
+	}
+	return e.currentFrame.state.GetBlockTermState().TotalTermFreq, nil
+}
+
+func (e *segmentTermsEnum) Postings(reuse index.PostingsEnum, flags int) (index.PostingsEnum, error) {
+	if err := e.currentFrame.decodeMetaData(nil); err != nil {
+		return nil, err
+	}
+	return e.fr.parent.postingsReader.Postings(e.fr.fieldInfo, e.currentFrame.state, reuse, flags)
+}
+
+func (e *segmentTermsEnum) Impacts(flags int) (index.ImpactsEnum, error) {
+	if err := e.currentFrame.decodeMetaData(nil); err != nil {
+		return nil, err
+	}
+	return e.fr.parent.postingsReader.Impacts(e.fr.fieldInfo, e.currentFrame.state, flags)
+}
+
+func (e *segmentTermsEnum) TermState() (index.TermState, error) {
+	if err := e.currentFrame.decodeMetaData(nil); err != nil {
+		return nil, err
+	}
+	return e.currentFrame.state.Clone(), nil
+}
@@ -0,0 +1,671 @@
+package blocktree
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/geange/lucene-go/codecs"
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/compress/lowercaseascii"
+	"github.com/geange/lucene-go/core/util/compress/lz4"
+	"github.com/geange/lucene-go/core/util/fst"
+)
+
+type segmentTermsEnumFrame struct {
+	// Our index in stack[]:
+	ord int
+
+	hasTerms     bool
+	hasTermsOrig bool
+	isFloor      bool
+
+	arc *fst.Arc
+
+	// File pointer where this block was loaded from
+	fp     int64
+	fpOrig int64
+	fpEnd  int64
+
+	suffixBytes         []byte
+	suffixesReader      *store.BytesInput
+	suffixLengthsReader *store.BytesInput
+
+	statsReader             *store.BytesInput
+	statsSingletonRunLength int
+
+	floorDataReader *store.BytesInput
+
+	// Length of prefix shared by all terms in this block
+	prefix int
+
+	// Number of entries (term or sub-block) in this block
+	entCount int
+
+	// Which term we will next read, or -1 if the block isn't loaded yet
+	nextEnt int
+
+	// True if this block is either not a floor block,
+	// or, it's the last sub-block of a floor block
+	isLastInFloor bool
+
+	// True if all entries are terms
+	isLeafBlock bool
+
+	lastSubFP int64
+
+	nextFloorLabel       int
+	numFollowFloorBlocks int
+
+	// Next term to decode metaData; we decode metaData
+	// lazily so that scanning to find the matching term is
+	// fast and only if you find a match and app wants the
+	// stats or docs/positions enums, will we decode the
+	// metaData
+	metaDataUpto int
+
+	state codecs.BlockTermState
+
+	// metadata buffer
+	bytesReader *store.BytesInput
+
+	startBytePos int
+	suffix       int
+	subCode      int64
+
+	ste *segmentTermsEnum
+}
+
+func newSegmentTermsEnumFrame(ste *segmentTermsEnum, ord int) *segmentTermsEnumFrame {
+	state := ste.fr.parent.postingsReader.NewTermState()
+	state.GetBlockTermState().TotalTermFreq = -1
+	return &segmentTermsEnumFrame{
+		ord:     ord,
+		state:   state,
+		nextEnt: -1,
+		fpOrig:  -1,
+		ste:     ste,
+	}
+}
+
+func (s *segmentTermsEnumFrame) setFloorData(floorData []byte) error {
+	s.floorDataReader = store.NewBytesInput(bytes.Clone(floorData))
+	numFollowFloorBlocks, err := s.floorDataReader.ReadUvarint(nil)
+	if err != nil {
+		return err
+	}
+	s.numFollowFloorBlocks = int(numFollowFloorBlocks)
+	nextFloorLabel, err := s.floorDataReader.ReadByte()
+	if err != nil {
+		return err
+	}
+	s.nextFloorLabel = int(nextFloorLabel)
+	return nil
+}
+
+func (s *segmentTermsEnumFrame) getTermBlockOrd() int {
+	if s.isLeafBlock {
+		return s.nextEnt
+	}
+	return s.state.GetBlockTermState().TermBlockOrd
+}
+
+func (s *segmentTermsEnumFrame) loadNextFloorBlock(ctx context.Context) error {
+	s.fp = s.fpEnd
+	s.nextEnt = -1
+	return s.loadBlock(ctx)
+}
+
+// loadBlock Does initial decode of next block of terms; this doesn't actually decode the docFreq,
+// totalTermFreq, postings details (frq/prx offset, etc.) metadata; it just loads them as byte[]
+// blobs which are then decoded on-demand if the metadata is ever requested for any term in this
+// block. This enables terms-only intensive consumes (eg certain MTQs, respelling) to not pay the
+// price of decoding metadata they won't use.
+func (s *segmentTermsEnumFrame) loadBlock(ctx context.Context) error {
+	// Clone the IndexInput lazily, so that consumers
+	// that just pull a TermsEnum to
+	// seekExact(TermState) don't pay this cost:
+	if err := s.ste.initIndexInput(); err != nil {
+		return err
+	}
+
+	if s.nextEnt != -1 {
+		// Already loaded
+		return nil
+	}
+
+	in := s.ste.in
+	if _, err := in.Seek(s.fp, io.SeekStart); err != nil {
+		return err
+	}
+	block, err := readBlock(ctx, in)
+	if err != nil {
+		return err
+	}
+	s.entCount = block.entCount
+	s.isLastInFloor = block.isLastInFloor
+	s.isLeafBlock = block.isLeafBlock
+	s.suffixBytes = block.suffixBytes
+	s.suffixesReader = store.NewBytesInput(block.suffixBytes)
+	s.suffixLengthsReader = store.NewBytesInput(block.suffixLengthBytes)
+	s.statsReader = store.NewBytesInput(block.statBytes)
+	s.bytesReader = store.NewBytesInput(block.metaBytes)
+
+	s.statsSingletonRunLength = 0
+	s.metaDataUpto = 0
+	s.state.GetBlockTermState().TermBlockOrd = 0
+	s.nextEnt = 0
+	s.lastSubFP = -1
+
+	// Sub-blocks of a single floor block are always
+	// written one after another -- tail recurse:
+	s.fpEnd = in.GetFilePointer()
+	return nil
+}
+
+func (s *segmentTermsEnumFrame) rewind() error {
+	// Force reload:
+	s.fp = s.fpOrig
+	s.nextEnt = -1
+	s.hasTerms = s.hasTermsOrig
+	if s.isFloor {
+		if _, err := s.floorDataReader.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		numFollowFloorBlocks, err := s.floorDataReader.ReadUvarint(nil)
+		if err != nil {
+			return err
+		}
+		s.numFollowFloorBlocks = int(numFollowFloorBlocks)
+		nextFloorLabel, err := s.floorDataReader.ReadByte()
+		if err != nil {
+			return err
+		}
+		s.nextFloorLabel = int(nextFloorLabel)
+	}
+	return nil
+}
+
+// next Decodes next entry; returns true if it's a sub-block
+func (s *segmentTermsEnumFrame) next(ctx context.Context) (bool, error) {
+	if s.isLeafBlock {
+		return false, s.nextLeaf(ctx)
+	}
+	return s.nextNonLeaf(ctx)
+}
+
+func (s *segmentTermsEnumFrame) nextLeaf(ctx context.Context) error {
+	if s.nextEnt == -1 || s.nextEnt >= s.entCount {
+		return fmt.Errorf("nextEnt=%d entCount=%d fp=%d", s.nextEnt, s.entCount, s.fp)
+	}
+	s.nextEnt++
+	suffix, err := s.suffixLengthsReader.ReadUvarint(ctx)
+	if err != nil {
+		return err
+	}
+	s.suffix = int(suffix)
+	s.startBytePos = int(s.suffixesReader.GetFilePointer())
+	if err := s.suffixesReader.SkipBytes(ctx, s.suffix); err != nil {
+		return err
+	}
+	s.fillTerm()
+	s.ste.termExists = true
+	return nil
+}
+
+func (s *segmentTermsEnumFrame) nextNonLeaf(ctx context.Context) (bool, error) {
+	for {
+		if s.nextEnt == s.entCount {
+			if err := s.loadNextFloorBlock(ctx); err != nil {
+				return false, err
+			}
+			if s.isLeafBlock {
+				return false, s.nextLeaf(ctx)
+			}
+			continue
+		}
+
+		s.nextEnt++
+		code, err := s.suffixLengthsReader.ReadUvarint(ctx)
+		if err != nil {
+			return false, err
+		}
+		s.suffix = int(code >> 1)
+		s.startBytePos = int(s.suffixesReader.GetFilePointer())
+		if err := s.suffixesReader.SkipBytes(ctx, s.suffix); err != nil {
+			return false, err
+		}
+		s.fillTerm()
+		if code&1 == 0 {
+			// A normal term
+			s.ste.termExists = true
+			s.subCode = 0
+			s.state.GetBlockTermState().TermBlockOrd++
+			return false, nil
+		}
+
+		// A sub-block; make sub-FP absolute:
+		s.ste.termExists = false
+		subCode, err := s.suffixLengthsReader.ReadUvarint(ctx)
+		if err != nil {
+			return false, err
+		}
+		s.subCode = int64(subCode)
+		s.lastSubFP = s.fp - s.subCode
+		return true, nil
+	}
+}
+
+// scanToFloorFrame
+// TODO: make this array'd so we can do bin search?
+// likely not worth it?  need to measure how many
+// floor blocks we "typically" get
+func (s *segmentTermsEnumFrame) scanToFloorFrame(target []byte) error {
+	if !s.isFloor || len(target) <= s.prefix {
+		return nil
+	}
+
+	targetLabel := int(target[s.prefix])
+	if targetLabel < s.nextFloorLabel {
+		return nil
+	}
+
+	newFP := s.fpOrig
+	for {
+		code, err := s.floorDataReader.ReadUvarint(nil)
+		if err != nil {
+			return err
+		}
+		newFP = s.fpOrig + int64(code>>1)
+		s.hasTerms = code&1 != 0
+		s.isLastInFloor = s.numFollowFloorBlocks == 1
+		s.numFollowFloorBlocks--
+
+		if s.isLastInFloor {
+			s.nextFloorLabel = 256
+			break
+		}
+
+		nextFloorLabel, err := s.floorDataReader.ReadByte()
+		if err != nil {
+			return err
+		}
+		s.nextFloorLabel = int(nextFloorLabel)
+		if targetLabel < s.nextFloorLabel {
+			break
+		}
+	}
+
+	if newFP != s.fp {
+		// Force re-load of the block:
+		s.nextEnt = -1
+		s.fp = newFP
+	}
+	return nil
+}
+
+func (s *segmentTermsEnumFrame) decodeMetaData(ctx context.Context) error {
+	// lazily catch up on metadata decode:
+	limit := s.getTermBlockOrd()
+	absolute := s.metaDataUpto == 0
+
+	state := s.state.GetBlockTermState()
+	fieldInfo := s.ste.fr.fieldInfo
+
+	// TODO: better API would be "jump straight to term=N"???
+	for s.metaDataUpto < limit {
+		if err := decodeStats(ctx, s.statsReader, &s.statsSingletonRunLength, fieldInfo, state); err != nil {
+			return err
+		}
+
+		// metadata
+		if err := s.ste.fr.parent.postingsReader.DecodeTerm(s.bytesReader, fieldInfo, s.state, absolute); err != nil {
+			return err
+		}
+
+		s.metaDataUpto++
+		absolute = false
+	}
+	state.TermBlockOrd = s.metaDataUpto
+	return nil
+}
+
+// scanToSubBlock Scans to sub-block that has this target fp; only called by next(); NOTE: does not
+// set startBytePos/suffix as a side effect
+func (s *segmentTermsEnumFrame) scanToSubBlock(ctx context.Context, subFP int64) error {
+	if s.lastSubFP == subFP {
+		return nil
+	}
+
+	targetSubCode := s.fp - subFP
+	for {
+		if s.nextEnt >= s.entCount {
+			return fmt.Errorf("sub-block fp=%d not found in block fp=%d", subFP, s.fp)
+		}
+		s.nextEnt++
+		code, err := s.suffixLengthsReader.ReadUvarint(ctx)
+		if err != nil {
+			return err
+		}
+		if err := s.suffixesReader.SkipBytes(ctx, int(code>>1)); err != nil {
+			return err
+		}
+		if code&1 != 0 {
+			subCode, err := s.suffixLengthsReader.ReadUvarint(ctx)
+			if err != nil {
+				return err
+			}
+			if targetSubCode == int64(subCode) {
+				s.lastSubFP = subFP
+				return nil
+			}
+		} else {
+			s.state.GetBlockTermState().TermBlockOrd++
+		}
+	}
+}
+
+// scanToTerm NOTE: sets startBytePos/suffix as a side effect
+func (s *segmentTermsEnumFrame) scanToTerm(ctx context.Context, target []byte, exactOnly bool) (index.SeekStatus, error) {
+	if s.isLeafBlock {
+		return s.scanToTermLeaf(ctx, target, exactOnly)
+	}
+	return s.scanToTermNonLeaf(ctx, target, exactOnly)
+}
+
+// scanToTermLeaf Target's prefix matches this block's prefix; we scan the entries check if the
+// suffix matches.
+func (s *segmentTermsEnumFrame) scanToTermLeaf(ctx context.Context, target []byte, exactOnly bool) (index.SeekStatus, error) {
+	s.ste.termExists = true
+	s.subCode = 0
+
+	if s.nextEnt == s.entCount {
+		if exactOnly {
+			s.fillTerm()
+		}
+		return index.SEEK_STATUS_END, nil
+	}
+
+	// Loop over each entry (term or sub-block) in this block:
+	for s.nextEnt < s.entCount {
+		s.nextEnt++
+
+		suffix, err := s.suffixLengthsReader.ReadUvarint(ctx)
+		if err != nil {
+			return 0, err
+		}
+		s.suffix = int(suffix)
+		s.startBytePos = int(s.suffixesReader.GetFilePointer())
+		if err := s.suffixesReader.SkipBytes(ctx, s.suffix); err != nil {
+			return 0, err
+		}
+
+		cmp := bytes.Compare(s.suffixBytes[s.startBytePos:s.startBytePos+s.suffix], target[s.prefix:])
+		if cmp < 0 {
+			// Current entry is still before the target;
+			// keep scanning
+			continue
+		}
+
+		s.fillTerm()
+		if cmp > 0 {
+			// Done!  Current entry is after target --
+			// return NOT_FOUND:
+			return index.SEEK_STATUS_NOT_FOUND, nil
+		}
+		// Exact match!
+		return index.SEEK_STATUS_FOUND, nil
+	}
+
+	// It is possible (and OK) that terms index pointed us
+	// at this block, but, we scanned the entire block and
+	// did not find the term to position to.  This happens
+	// when the target is after the last term in the block
+	// (but, before the next term in the index).  EG
+	// target could be foozzz, and terms index pointed us
+	// to the foo* block, but the last term in this block
+	// was fooz (and, eg, first term in the next block will
+	// bee fop).
+	if exactOnly {
+		s.fillTerm()
+	}
+
+	// TODO: not consistent that in the
+	// not-exact case we don't next() into the next
+	// frame here
+	return index.SEEK_STATUS_END, nil
+}
+
+// scanToTermNonLeaf Target's prefix matches this block's prefix; we scan the entries check if the
+// suffix matches.
+func (s *segmentTermsEnumFrame) scanToTermNonLeaf(ctx context.Context, target []byte, exactOnly bool) (index.SeekStatus, error) {
+	if s.nextEnt == s.entCount {
+		if exactOnly {
+			s.fillTerm()
+			s.ste.termExists = s.subCode == 0
+		}
+		return index.SEEK_STATUS_END, nil
+	}
+
+	// Loop over each entry (term or sub-block) in this block:
+	for s.nextEnt < s.entCount {
+		s.nextEnt++
+
+		code, err := s.suffixLengthsReader.ReadUvarint(ctx)
+		if err != nil {
+			return 0, err
+		}
+		s.suffix = int(code >> 1)
+
+		termLen := s.prefix + s.suffix
+		s.startBytePos = int(s.suffixesReader.GetFilePointer())
+		if err := s.suffixesReader.SkipBytes(ctx, s.suffix); err != nil {
+			return 0, err
+		}
+		s.ste.termExists = code&1 == 0
+		if s.ste.termExists {
+			s.state.GetBlockTermState().TermBlockOrd++
+			s.subCode = 0
+		} else {
+			subCode, err := s.suffixLengthsReader.ReadUvarint(ctx)
+			if err != nil {
+				return 0, err
+			}
+			s.subCode = int64(subCode)
+			s.lastSubFP = s.fp - s.subCode
+		}
+
+		cmp := bytes.Compare(s.suffixBytes[s.startBytePos:s.startBytePos+s.suffix], target[s.prefix:])
+		if cmp < 0 {
+			// Current entry is still before the target;
+			// keep scanning
+			continue
+		}
+
+		s.fillTerm()
+		if cmp > 0 {
+			// Done!  Current entry is after target --
+			// return NOT_FOUND:
+			if !exactOnly && !s.ste.termExists {
+				// We are on a sub-block, and caller wants
+				// us to position to the next term after
+				// the target, so we must recurse into the
+				// sub-frame(s):
+				ste := s.ste
+				frame, err := ste.pushFrame(nil, ste.currentFrame.lastSubFP, termLen)
+				if err != nil {
+					return 0, err
+				}
+				ste.currentFrame = frame
+				if err := ste.currentFrame.loadBlock(ctx); err != nil {
+					return 0, err
+				}
+				for {
+					isSubBlock, err := ste.currentFrame.next(ctx)
+					if err != nil {
+						return 0, err
+					}
+					if !isSubBlock {
+						break
+					}
+					frame, err := ste.pushFrame(nil, ste.currentFrame.lastSubFP, len(ste.term))
+					if err != nil {
+						return 0, err
+					}
+					ste.currentFrame = frame
+					if err := ste.currentFrame.loadBlock(ctx); err != nil {
+						return 0, err
+					}
+				}
+			}
+			return index.SEEK_STATUS_NOT_FOUND, nil
+		}
+
+		// Exact match!
+		// This cannot be a sub-block because we
+		// would have followed the index to this
+		// sub-block from the start:
+		return index.SEEK_STATUS_FOUND, nil
+	}
+
+	// It is possible (and OK) that terms index pointed us
+	// at this block, but, we scanned the entire block and
+	// did not find the term to position to.  This happens
+	// when the target is after the last term in the block
+	// (but, before the next term in the index).  EG
+	// target could be foozzz, and terms index pointed us
+	// to the foo* block, but the last term in this block
+	// was fooz (and, eg, first term in the next block will
+	// bee fop).
+	if exactOnly {
+		s.fillTerm()
+	}
+
+	// TODO: not consistent that in the
+	// not-exact case we don't next() into the next
+	// frame here
+	return index.SEEK_STATUS_END, nil
+}
+
+func (s *segmentTermsEnumFrame) fillTerm() {
+	termLength := s.prefix + s.suffix
+	s.ste.setTermLength(termLength)
+	copy(s.ste.term[s.prefix:termLength], s.suffixBytes[s.startBytePos:s.startBytePos+s.suffix])
+}
+
+// blockData The raw content of a block of the terms dictionary.
+type blockData struct {
+	entCount          int
+	isLastInFloor     bool
+	isLeafBlock       bool
+	suffixBytes       []byte
+	suffixLengthBytes []byte
+	statBytes         []byte
+	metaBytes         []byte
+}
+
+// readBlock Reads the block the input is positioned at, as written by fieldWriter.writeBlock.
+func readBlock(ctx context.Context, in store.IndexInput) (*blockData, error) {
+	code, err := in.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	block := &blockData{
+		entCount:      int(code >> 1),
+		isLastInFloor: code&1 != 0,
+	}
+	if block.entCount <= 0 {
+		return nil, fmt.Errorf("invalid block entry count: %d", block.entCount)
+	}
+
+	// term suffixes:
+	token, err := in.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	block.isLeafBlock = token&0x04 != 0
+	block.suffixBytes = make([]byte, token>>3)
+	switch compression := token & 0x03; compression {
+	case COMPRESSION_NONE:
+		if _, err := io.ReadFull(in, block.suffixBytes); err != nil {
+			return nil, err
+		}
+	case COMPRESSION_LOWERCASE_ASCII:
+		if err := lowercaseascii.Decompress(ctx, in, block.suffixBytes, len(block.suffixBytes)); err != nil {
+			return nil, err
+		}
+	case COMPRESSION_LZ4:
+		if _, err := lz4.Decompress(in, len(block.suffixBytes), block.suffixBytes); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("illegal code for a compression algorithm: %d", compression)
+	}
+
+	numSuffixLengthBytes, err := in.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	allEqual := numSuffixLengthBytes&0x01 != 0
+	block.suffixLengthBytes = make([]byte, numSuffixLengthBytes>>1)
+	if allEqual {
+		b, err := in.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		for i := range block.suffixLengthBytes {
+			block.suffixLengthBytes[i] = b
+		}
+	} else if _, err := io.ReadFull(in, block.suffixLengthBytes); err != nil {
+		return nil, err
+	}
+
+	// stats
+	if block.statBytes, err = readBytesRef(ctx, in); err != nil {
+		return nil, err
+	}
+
+	// metadata
+	if block.metaBytes, err = readBytesRef(ctx, in); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// decodeStats Decodes the docFreq/totalTermFreq of the next term of a block, as written by statsWriter.
+func decodeStats(ctx context.Context, statsReader store.DataInput, singletonRunLength *int,
+	fieldInfo *document.FieldInfo, state *codecs.BaseBlockTermState) error {
+
+	if *singletonRunLength > 0 {
+		state.DocFreq = 1
+		state.TotalTermFreq = 1
+		*singletonRunLength--
+		return nil
+	}
+
+	token, err := statsReader.ReadUvarint(ctx)
+	if err != nil {
+		return err
+	}
+	if token&1 == 1 {
+		state.DocFreq = 1
+		state.TotalTermFreq = 1
+		*singletonRunLength = int(token >> 1)
+		return nil
+	}
+
+	state.DocFreq = int(token >> 1)
+	if fieldInfo.GetIndexOptions() == document.INDEX_OPTIONS_DOCS {
+		state.TotalTermFreq = int64(state.DocFreq)
+		return nil
+	}
+	delta, err := statsReader.ReadUvarint(ctx)
+	if err != nil {
+		return err
+	}
+	state.TotalTermFreq = int64(state.DocFreq) + int64(delta)
+	return nil
+}
@@ -0,0 +1,354 @@
+package blocktree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/geange/lucene-go/codecs"
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/fst"
+)
+
+const (
+	// TERMS_EXTENSION Extension of terms file
+	TERMS_EXTENSION  = "tim"
+	TERMS_CODEC_NAME = "BlockTreeTermsDict"
+
+	// TERMS_INDEX_EXTENSION Extension of terms index file
+	TERMS_INDEX_EXTENSION  = "tip"
+	TERMS_INDEX_CODEC_NAME = "BlockTreeTermsIndex"
+
+	// TERMS_META_EXTENSION Extension of terms meta file
+	TERMS_META_EXTENSION  = "tmd"
+	TERMS_META_CODEC_NAME = "BlockTreeTermsMeta"
+
+	// VERSION_START Initial terms format.
+	VERSION_START = 3
+
+	// VERSION_META_LONGS_REMOVED Version that drops the number of metadata longs of each field.
+	VERSION_META_LONGS_REMOVED = 4
+
+	// VERSION_COMPRESSED_SUFFIXES Version that compresses suffixes.
+	VERSION_COMPRESSED_SUFFIXES = 5
+
+	// VERSION_META_FILE Version that moves the metadata of the terms dictionary to a separate file.
+	VERSION_META_FILE = 6
+
+	// VERSION_CURRENT Current terms format.
+	VERSION_CURRENT = VERSION_META_FILE
+
+	OUTPUT_FLAGS_NUM_BITS = 2
+	OUTPUT_FLAGS_MASK     = 0x3
+	OUTPUT_FLAG_IS_FLOOR  = 0x1
+	OUTPUT_FLAG_HAS_TERMS = 0x2
+
+	// COMPRESSION_NONE Code of the suffix compression algorithm that leaves suffixes as-is,
+	// the only one this implementation writes.
+	COMPRESSION_NONE = 0
+
+	// COMPRESSION_LOWERCASE_ASCII Code of the suffix compression algorithm that packs lowercase
+	// ASCII characters on 6 bits, written by Lucene for blocks of mostly lowercase suffixes.
+	COMPRESSION_LOWERCASE_ASCII = 1
+
+	// COMPRESSION_LZ4 Code of the suffix compression algorithm that compresses suffixes with LZ4,
+	// written by Lucene for blocks whose suffixes are long and repetitive.
+	COMPRESSION_LZ4 = 2
+)
+
+// fstOutputs Outputs of the terms index FST, each output is the encoded code of a block.
+var fstOutputs = fst.NewBytesOutputManager()
+
+var _ index.FieldsProducer = &TermsReader{}
+
+// TermsReader A block-based terms index and dictionary that assigns terms to variable length blocks
+// according to how they share prefixes. The terms index is a prefix trie whose leaves are term
+// blocks. The advantage of this approach is that seekExact is often able to determine a term cannot
+// exist without doing any IO, and intersection with Automata is very fast. Note that this terms
+// dictionary has its own fixed terms index (ie, it does not support a pluggable terms index
+// implementation).
+//
+// NOTE: this terms dictionary supports min/maxItemsPerBlock during indexing to control how much
+// memory the terms index uses.
+//
+// The data structure used by this implementation is very similar to a burst trie, but with
+// added logic to break up too-large blocks of all terms sharing a given prefix into smaller ones.
+//
+// See Also: TermsWriter
+// lucene.experimental
+type TermsReader struct {
+	// Open input to the main terms dict file (_X.tim)
+	termsIn store.IndexInput
+
+	// Open input to the terms index file (_X.tip)
+	indexIn store.IndexInput
+
+	// Reads the terms dict entries, to gather state to produce DocsEnum on demand
+	postingsReader codecs.PostingsReaderBase
+
+	fields     map[string]*FieldReader
+	fieldNames []string
+
+	segment string
+	version int
+}
+
+// NewTermsReader Sole constructor.
+func NewTermsReader(ctx context.Context, postingsReader codecs.PostingsReaderBase,
+	state *index.SegmentReadState) (*TermsReader, error) {
+
+	reader := &TermsReader{
+		postingsReader: postingsReader,
+		fields:         make(map[string]*FieldReader),
+		fieldNames:     make([]string, 0),
+		segment:        state.SegmentInfo.Name(),
+	}
+
+	closeOnError := func(err error) (*TermsReader, error) {
+		_ = reader.Close()
+		return nil, err
+	}
+
+	segmentID := state.SegmentInfo.GetID()
+
+	var err error
+	termsName := store.SegmentFileName(reader.segment, state.SegmentSuffix, TERMS_EXTENSION)
+	if reader.termsIn, err = store.OpenInputWithContext(ctx, state.Directory, termsName, state.Context); err != nil {
+		return closeOnError(err)
+	}
+	// only the formats with a separate meta file are supported
+	reader.version, err = utils.CheckIndexHeader(ctx, reader.termsIn, TERMS_CODEC_NAME,
+		VERSION_META_FILE, VERSION_CURRENT, segmentID, state.SegmentSuffix)
+	if err != nil {
+		return closeOnError(err)
+	}
+
+	indexName := store.SegmentFileName(reader.segment, state.SegmentSuffix, TERMS_INDEX_EXTENSION)
+	if reader.indexIn, err = store.OpenInputWithContext(ctx, state.Directory, indexName, state.Context); err != nil {
+		return closeOnError(err)
+	}
+	_, err = utils.CheckIndexHeader(ctx, reader.indexIn, TERMS_INDEX_CODEC_NAME,
+		reader.version, reader.version, segmentID, state.SegmentSuffix)
+	if err != nil {
+		return closeOnError(err)
+	}
+
+	metaName := store.SegmentFileName(reader.segment, state.SegmentSuffix, TERMS_META_EXTENSION)
+	metaIn, err := store.OpenChecksumInput(state.Directory, metaName)
+	if err != nil {
+		return closeOnError(err)
+	}
+	defer metaIn.Close()
+
+	indexLength, termsLength, err := reader.readMeta(ctx, metaIn, state)
+	if err != nil {
+		return closeOnError(err)
+	}
+
+	// At this point the checksum of the meta file has been verified so the lengths are likely correct
+	if reader.indexIn.Length() != indexLength {
+		return closeOnError(fmt.Errorf("invalid terms index file length: expected=%d actual=%d",
+			indexLength, reader.indexIn.Length()))
+	}
+	if _, err := utils.RetrieveChecksum(reader.indexIn); err != nil {
+		return closeOnError(err)
+	}
+	if reader.termsIn.Length() != termsLength {
+		return closeOnError(fmt.Errorf("invalid terms file length: expected=%d actual=%d",
+			termsLength, reader.termsIn.Length()))
+	}
+	if _, err := utils.RetrieveChecksum(reader.termsIn); err != nil {
+		return closeOnError(err)
+	}
+
+	sort.Strings(reader.fieldNames)
+	return reader, nil
+}
+
+func (t *TermsReader) readMeta(ctx context.Context, metaIn store.ChecksumIndexInput,
+	state *index.SegmentReadState) (int64, int64, error) {
+
+	_, err := utils.CheckIndexHeader(ctx, metaIn, TERMS_META_CODEC_NAME, t.version, t.version,
+		state.SegmentInfo.GetID(), state.SegmentSuffix)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := t.postingsReader.Init(metaIn, state); err != nil {
+		return 0, 0, err
+	}
+
+	maxDoc, err := state.SegmentInfo.MaxDoc()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	numFields, err := metaIn.ReadUvarint(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	for i := 0; i < int(numFields); i++ {
+		fieldReader, err := t.readField(ctx, metaIn, state, maxDoc)
+		if err != nil {
+			return 0, 0, err
+		}
+		name := fieldReader.fieldInfo.Name()
+		if _, ok := t.fields[name]; ok {
+			return 0, 0, fmt.Errorf("duplicate field: %s", name)
+		}
+		t.fields[name] = fieldReader
+		t.fieldNames = append(t.fieldNames, name)
+	}
+
+	indexLength, err := metaIn.ReadUint64(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	termsLength, err := metaIn.ReadUint64(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := utils.CheckCodecFooter(metaIn); err != nil {
+		return 0, 0, err
+	}
+	return int64(indexLength), int64(termsLength), nil
+}
+
+func (t *TermsReader) readField(ctx context.Context, metaIn store.IndexInput,
+	state *index.SegmentReadState, maxDoc int) (*FieldReader, error) {
+
+	field, err := metaIn.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	numTerms, err := metaIn.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if numTerms <= 0 {
+		return nil, fmt.Errorf("illegal numTerms for field number: %d", field)
+	}
+	rootCode, err := readBytesRef(ctx, metaIn)
+	if err != nil {
+		return nil, err
+	}
+	fieldInfo := state.FieldInfos.FieldInfoByNumber(int(field))
+	if fieldInfo == nil {
+		return nil, fmt.Errorf("invalid field number: %d", field)
+	}
+
+	sumTotalTermFreq := uint64(0)
+	if fieldInfo.GetIndexOptions() != document.INDEX_OPTIONS_DOCS {
+		if sumTotalTermFreq, err = metaIn.ReadUvarint(ctx); err != nil {
+			return nil, err
+		}
+	}
+	sumDocFreq, err := metaIn.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if fieldInfo.GetIndexOptions() == document.INDEX_OPTIONS_DOCS {
+		// when frequencies are omitted, sumDocFreq=sumTotalTermFreq and only one value is written.
+		sumTotalTermFreq = sumDocFreq
+	}
+	docCount, err := metaIn.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	minTerm, err := readBytesRef(ctx, metaIn)
+	if err != nil {
+		return nil, err
+	}
+	maxTerm, err := readBytesRef(ctx, metaIn)
+	if err != nil {
+		return nil, err
+	}
+
+	if int(docCount) > maxDoc {
+		// #docs with field must be <= #docs
+		return nil, fmt.Errorf("invalid docCount: %d maxDoc: %d", docCount, maxDoc)
+	}
+	if sumDocFreq < docCount {
+		// #postings must be >= #docs with field
+		return nil, fmt.Errorf("invalid sumDocFreq: %d docCount: %d", sumDocFreq, docCount)
+	}
+	if sumTotalTermFreq < sumDocFreq {
+		// #positions must be >= #postings
+		return nil, fmt.Errorf("invalid sumTotalTermFreq: %d sumDocFreq: %d", sumTotalTermFreq, sumDocFreq)
+	}
+
+	indexStartFP, err := metaIn.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newFieldReader(ctx, t, fieldInfo, int64(numTerms), rootCode, int64(sumTotalTermFreq),
+		int64(sumDocFreq), int(docCount), int64(indexStartFP), metaIn, t.indexIn, minTerm, maxTerm)
+}
+
+func readBytesRef(ctx context.Context, in store.DataInput) ([]byte, error) {
+	size, err := in.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bs := make([]byte, size)
+	if _, err := io.ReadFull(in, bs); err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
+
+func (t *TermsReader) Names() []string {
+	return t.fieldNames
+}
+
+func (t *TermsReader) Terms(field string) (index.Terms, error) {
+	fieldReader, ok := t.fields[field]
+	if !ok {
+		return nil, nil
+	}
+	return fieldReader, nil
+}
+
+func (t *TermsReader) Size() int {
+	return len(t.fields)
+}
+
+func (t *TermsReader) Close() error {
+	var firstErr error
+	for _, closer := range []io.Closer{t.indexIn, t.termsIn, t.postingsReader} {
+		if closer == nil {
+			continue
+		}
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	t.indexIn, t.termsIn, t.postingsReader = nil, nil, nil
+	// Clear so refs to terms index is GCable even if app hangs onto us:
+	clear(t.fields)
+	return firstErr
+}
+
+func (t *TermsReader) CheckIntegrity() error {
+	if t.termsIn == nil {
+		return errors.New("terms reader is closed")
+	}
+	// terms index
+	if _, err := utils.ChecksumEntireFile(t.indexIn); err != nil {
+		return err
+	}
+	// term dictionary
+	if _, err := utils.ChecksumEntireFile(t.termsIn); err != nil {
+		return err
+	}
+	// postings
+	return t.postingsReader.CheckIntegrity()
+}
+
+func (t *TermsReader) GetMergeInstance() index.FieldsProducer {
+	return t
+}
@@ -0,0 +1,849 @@
+package blocktree
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bits-and-blooms/bitset"
+	"github.com/geange/lucene-go/codecs"
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/fst"
+)
+
+const (
+	// DEFAULT_MIN_BLOCK_SIZE Suggested default value for the minItemsInBlock parameter to NewTermsWriter.
+	DEFAULT_MIN_BLOCK_SIZE = 25
+
+	// DEFAULT_MAX_BLOCK_SIZE Suggested default value for the maxItemsInBlock parameter to NewTermsWriter.
+	DEFAULT_MAX_BLOCK_SIZE = 48
+)
+
+var _ index.FieldsConsumer = &TermsWriter{}
+
+// TermsWriter Block-based terms index and dictionary writer.
+// Writes terms dict and index, block-encoding (column stride) each term's metadata for each set of
+// terms between two index terms.
+//
+// Files:
+//   - .tim: Term Dictionary
+//   - .tip: Term Index
+//   - .tmd: Term Metadata
+//
+// The .tim file contains the list of terms in each field along with per-term statistics (such as
+// docfreq) and per-term metadata (typically pointers to the postings list for that term in the
+// inverted index).
+//
+// The .tim is arranged in blocks: with blocks containing a variable number of entries (by default
+// 25-48), where each entry is either a term or a reference to a sub-block.
+//
+// The .tip file contains an index into the term dictionary, so that it can be accessed randomly.
+// The index is also used to determine when a given term cannot exist on disk (in the .tim file),
+// saving a disk seek. The index is an FST mapping each term prefix that starts a block to the
+// file pointer of that block in the .tim file.
+//
+// See Also: TermsReader
+// lucene.experimental
+type TermsWriter struct {
+	metaOut  store.IndexOutput
+	termsOut store.IndexOutput
+	indexOut store.IndexOutput
+
+	maxDoc          int
+	minItemsInBlock int
+	maxItemsInBlock int
+
+	postingsWriter codecs.PostingsWriterBase
+	fieldInfos     index.FieldInfos
+
+	// per-field metadata, written to metaOut on close
+	fields []*store.BufferOutput
+
+	// Reused in writeBlock:
+	suffixWriter        *store.BufferOutput
+	suffixLengthsWriter *store.BufferOutput
+	statsWriter         *store.BufferOutput
+	metaWriter          *store.BufferOutput
+	scratchBytes        *store.BufferOutput
+
+	closed bool
+}
+
+// NewTermsWriter Create a new writer. The number of items (terms or sub-blocks) per block will
+// aim to be between minItemsPerBlock and maxItemsPerBlock, though in some cases the blocks may
+// be smaller than the min.
+func NewTermsWriter(state *index.SegmentWriteState, postingsWriter codecs.PostingsWriterBase,
+	minItemsInBlock, maxItemsInBlock int) (*TermsWriter, error) {
+
+	if err := ValidateSettings(minItemsInBlock, maxItemsInBlock); err != nil {
+		return nil, err
+	}
+
+	maxDoc, err := state.SegmentInfo.MaxDoc()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &TermsWriter{
+		maxDoc:              maxDoc,
+		minItemsInBlock:     minItemsInBlock,
+		maxItemsInBlock:     maxItemsInBlock,
+		postingsWriter:      postingsWriter,
+		fieldInfos:          state.FieldInfos,
+		fields:              make([]*store.BufferOutput, 0),
+		suffixWriter:        store.NewBufferDataOutput(),
+		suffixLengthsWriter: store.NewBufferDataOutput(),
+		statsWriter:         store.NewBufferDataOutput(),
+		metaWriter:          store.NewBufferDataOutput(),
+		scratchBytes:        store.NewBufferDataOutput(),
+	}
+
+	closeOnError := func(err error) (*TermsWriter, error) {
+		_ = w.closeOutputs()
+		return nil, err
+	}
+
+	segmentName := state.SegmentInfo.Name()
+	segmentID := state.SegmentInfo.GetID()
+
+	termsName := store.SegmentFileName(segmentName, state.SegmentSuffix, TERMS_EXTENSION)
+	if w.termsOut, err = store.CreateOutputWithContext(nil, state.Directory, termsName, state.Context); err != nil {
+		return closeOnError(err)
+	}
+	if err := utils.WriteIndexHeader(nil, w.termsOut, TERMS_CODEC_NAME, VERSION_CURRENT,
+		segmentID, state.SegmentSuffix); err != nil {
+		return closeOnError(err)
+	}
+
+	indexName := store.SegmentFileName(segmentName, state.SegmentSuffix, TERMS_INDEX_EXTENSION)
+	if w.indexOut, err = store.CreateOutputWithContext(nil, state.Directory, indexName, state.Context); err != nil {
+		return closeOnError(err)
+	}
+	if err := utils.WriteIndexHeader(nil, w.indexOut, TERMS_INDEX_CODEC_NAME, VERSION_CURRENT,
+		segmentID, state.SegmentSuffix); err != nil {
+		return closeOnError(err)
+	}
+
+	metaName := store.SegmentFileName(segmentName, state.SegmentSuffix, TERMS_META_EXTENSION)
+	if w.metaOut, err = store.CreateOutputWithContext(nil, state.Directory, metaName, state.Context); err != nil {
+		return closeOnError(err)
+	}
+	if err := utils.WriteIndexHeader(nil, w.metaOut, TERMS_META_CODEC_NAME, VERSION_CURRENT,
+		segmentID, state.SegmentSuffix); err != nil {
+		return closeOnError(err)
+	}
+
+	// have consumer write its format/header
+	if err := postingsWriter.Init(w.metaOut, state); err != nil {
+		return closeOnError(err)
+	}
+	return w, nil
+}
+
+// ValidateSettings Throws error if these settings are invalid.
+func ValidateSettings(minItemsInBlock, maxItemsInBlock int) error {
+	if minItemsInBlock <= 1 {
+		return fmt.Errorf("minItemsInBlock must be >= 2; got %d", minItemsInBlock)
+	}
+	if minItemsInBlock > maxItemsInBlock {
+		return fmt.Errorf("maxItemsInBlock must be >= minItemsInBlock; got maxItemsInBlock=%d minItemsInBlock=%d",
+			maxItemsInBlock, minItemsInBlock)
+	}
+	if 2*(minItemsInBlock-1) > maxItemsInBlock {
+		return fmt.Errorf("maxItemsInBlock must be at least 2*(minItemsInBlock-1); got maxItemsInBlock=%d minItemsInBlock=%d",
+			maxItemsInBlock, minItemsInBlock)
+	}
+	return nil
+}
+
+func (w *TermsWriter) Write(ctx context.Context, fields index.Fields, norms index.NormsProducer) error {
+	for _, field := range fields.Names() {
+		terms, err := fields.Terms(field)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				continue
+			}
+			return err
+		}
+		if terms == nil {
+			continue
+		}
+
+		termsEnum, err := terms.Iterator()
+		if err != nil {
+			return err
+		}
+
+		fieldInfo := w.fieldInfos.FieldInfo(field)
+		if fieldInfo == nil {
+			return fmt.Errorf("field %s not found in field infos", field)
+		}
+		fw := w.newFieldWriter(fieldInfo)
+		for {
+			term, err := termsEnum.Next(ctx)
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return err
+			}
+			if term == nil {
+				break
+			}
+			if err := fw.write(ctx, term, termsEnum, norms); err != nil {
+				return err
+			}
+		}
+		if err := fw.finish(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *TermsWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if err := w.writeTrailer(); err != nil {
+		_ = w.closeOutputs()
+		return err
+	}
+	return w.closeOutputs()
+}
+
+func (w *TermsWriter) writeTrailer() error {
+	if err := w.metaOut.WriteUvarint(nil, uint64(len(w.fields))); err != nil {
+		return err
+	}
+	for _, fieldMeta := range w.fields {
+		if err := fieldMeta.CopyTo(w.metaOut); err != nil {
+			return err
+		}
+	}
+	if err := utils.WriteFooter(w.indexOut); err != nil {
+		return err
+	}
+	if err := w.metaOut.WriteUint64(nil, uint64(w.indexOut.GetFilePointer())); err != nil {
+		return err
+	}
+	if err := utils.WriteFooter(w.termsOut); err != nil {
+		return err
+	}
+	if err := w.metaOut.WriteUint64(nil, uint64(w.termsOut.GetFilePointer())); err != nil {
+		return err
+	}
+	return utils.WriteFooter(w.metaOut)
+}
+
+func (w *TermsWriter) closeOutputs() error {
+	var firstErr error
+	for _, closer := range []io.Closer{w.metaOut, w.termsOut, w.indexOut} {
+		if closer == nil {
+			continue
+		}
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if w.postingsWriter != nil {
+		if err := w.postingsWriter.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	w.metaOut, w.termsOut, w.indexOut = nil, nil, nil
+	return firstErr
+}
+
+func encodeOutput(fp int64, hasTerms, isFloor bool) int64 {
+	output := fp << OUTPUT_FLAGS_NUM_BITS
+	if hasTerms {
+		output |= OUTPUT_FLAG_HAS_TERMS
+	}
+	if isFloor {
+		output |= OUTPUT_FLAG_IS_FLOOR
+	}
+	return output
+}
+
+type pendingEntry interface {
+	isTerm() bool
+}
+
+type pendingTerm struct {
+	termBytes []byte
+	// stats + metadata
+	state codecs.BlockTermState
+}
+
+func (p *pendingTerm) isTerm() bool {
+	return true
+}
+
+type pendingBlock struct {
+	prefix        []byte
+	fp            int64
+	index         *fst.FST
+	subIndices    []*fst.FST
+	hasTerms      bool
+	isFloor       bool
+	floorLeadByte int
+}
+
+func (p *pendingBlock) isTerm() bool {
+	return false
+}
+
+func (p *pendingBlock) compileIndex(ctx context.Context, blocks []*pendingBlock, scratchBytes *store.BufferOutput) error {
+	if err := scratchBytes.WriteUvarint(ctx, uint64(encodeOutput(p.fp, p.hasTerms, p.isFloor))); err != nil {
+		return err
+	}
+	if p.isFloor {
+		if err := scratchBytes.WriteUvarint(ctx, uint64(len(blocks)-1)); err != nil {
+			return err
+		}
+		for _, sub := range blocks[1:] {
+			if err := scratchBytes.WriteByte(byte(sub.floorLeadByte)); err != nil {
+				return err
+			}
+			code := (sub.fp - p.fp) << 1
+			if sub.hasTerms {
+				code |= 1
+			}
+			if err := scratchBytes.WriteUvarint(ctx, uint64(code)); err != nil {
+				return err
+			}
+		}
+	}
+
+	builder, err := fst.NewBuilder(fst.BYTE1, fstOutputs, fst.WithDoShareNonSingletonNodes(false))
+	if err != nil {
+		return err
+	}
+	output := bytes.Clone(scratchBytes.Bytes())
+	scratchBytes.Reset()
+	if err := builder.AddInts(ctx, toInts(p.prefix), fst.NewBytesOutput(output)); err != nil {
+		return err
+	}
+
+	// Copy over index for all sub-blocks
+	for _, block := range blocks {
+		for _, subIndex := range block.subIndices {
+			if err := appendIndex(ctx, builder, subIndex); err != nil {
+				return err
+			}
+		}
+		block.subIndices = nil
+	}
+
+	p.index, err = builder.Finish(ctx)
+	return err
+}
+
+// appendIndex Adds all (prefix, output) pairs of the sub-block index to the builder.
+func appendIndex(ctx context.Context, builder *fst.Builder, subIndex *fst.FST) error {
+	subIndexEnum, err := fst.NewEnum[byte](subIndex)
+	if err != nil {
+		return err
+	}
+	for {
+		kv, err := subIndexEnum.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if kv == nil {
+			return nil
+		}
+		if err := builder.AddInts(ctx, toInts(kv.GetInput()), kv.GetOutput()); err != nil {
+			return err
+		}
+	}
+}
+
+func toInts(bs []byte) []int {
+	ints := make([]int, len(bs))
+	for i, b := range bs {
+		ints[i] = int(b)
+	}
+	return ints
+}
+
+// statsWriter Writes the docFreq/totalTermFreq of the terms of a block, run-length encoding
+// consecutive terms that only occur once.
+type statsWriter struct {
+	out            store.DataOutput
+	hasFreqs       bool
+	singletonCount int
+}
+
+func newStatsWriter(out store.DataOutput, hasFreqs bool) *statsWriter {
+	return &statsWriter{out: out, hasFreqs: hasFreqs}
+}
+
+func (s *statsWriter) add(ctx context.Context, df int, ttf int64) error {
+	// Singletons (DF==1, TTF==1) are run-length encoded
+	if df == 1 && (!s.hasFreqs || ttf == 1) {
+		s.singletonCount++
+		return nil
+	}
+	if err := s.finish(ctx); err != nil {
+		return err
+	}
+	if err := s.out.WriteUvarint(ctx, uint64(df)<<1); err != nil {
+		return err
+	}
+	if s.hasFreqs {
+		return s.out.WriteUvarint(ctx, uint64(ttf-int64(df)))
+	}
+	return nil
+}
+
+func (s *statsWriter) finish(ctx context.Context) error {
+	if s.singletonCount > 0 {
+		if err := s.out.WriteUvarint(ctx, uint64((s.singletonCount-1)<<1|1)); err != nil {
+			return err
+		}
+		s.singletonCount = 0
+	}
+	return nil
+}
+
+// fieldWriter Writes the terms of a single field.
+type fieldWriter struct {
+	parent    *TermsWriter
+	fieldInfo *document.FieldInfo
+
+	numTerms         int64
+	docsSeen         *bitset.BitSet
+	sumTotalTermFreq int64
+	sumDocFreq       int64
+
+	// Records index into pending where the current prefix at that
+	// length "started"; for example, if current term starts with 't',
+	// prefixStarts[0] is the index into pending for the first
+	// term/sub-block starting with 't'. We use this to figure out when
+	// to write a new block:
+	lastTerm     []byte
+	prefixStarts []int
+
+	// Pending stack of terms and blocks. As terms arrive (in sorted order)
+	// we append to this stack, and once the top of the stack has enough
+	// terms starting with a common prefix, we write a new block with
+	// those terms and replace those terms in the stack with a new block:
+	pending []pendingEntry
+
+	// Reused in writeBlocks:
+	newBlocks []*pendingBlock
+
+	firstPendingTerm *pendingTerm
+	lastPendingTerm  *pendingTerm
+}
+
+func (w *TermsWriter) newFieldWriter(fieldInfo *document.FieldInfo) *fieldWriter {
+	w.postingsWriter.SetField(fieldInfo)
+	return &fieldWriter{
+		parent:       w,
+		fieldInfo:    fieldInfo,
+		docsSeen:     bitset.New(uint(w.maxDoc)),
+		lastTerm:     make([]byte, 0),
+		prefixStarts: make([]int, 8),
+		pending:      make([]pendingEntry, 0),
+		newBlocks:    make([]*pendingBlock, 0),
+	}
+}
+
+// write Writes one term's worth of postings.
+func (f *fieldWriter) write(ctx context.Context, text []byte, termsEnum index.TermsEnum, norms index.NormsProducer) error {
+	state, err := f.parent.postingsWriter.WriteTerm(text, termsEnum, f.docsSeen, norms)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		// the term has no documents
+		return nil
+	}
+
+	if err := f.pushTerm(ctx, text); err != nil {
+		return err
+	}
+
+	term := &pendingTerm{termBytes: bytes.Clone(text), state: state}
+	f.pending = append(f.pending, term)
+
+	blockTermState := state.GetBlockTermState()
+	f.sumDocFreq += int64(blockTermState.DocFreq)
+	f.sumTotalTermFreq += blockTermState.TotalTermFreq
+	f.numTerms++
+	if f.firstPendingTerm == nil {
+		f.firstPendingTerm = term
+	}
+	f.lastPendingTerm = term
+	return nil
+}
+
+// pushTerm Pushes the new term to the top of the stack, and writes new blocks.
+func (f *fieldWriter) pushTerm(ctx context.Context, text []byte) error {
+	// Find common prefix between last term and current term:
+	prefixLength := commonPrefixLength(f.lastTerm, text)
+
+	// Close the "abandoned" suffix now:
+	for i := len(f.lastTerm) - 1; i >= prefixLength; i-- {
+		// How many items on top of the stack share the current suffix
+		// we are closing:
+		prefixTopSize := len(f.pending) - f.prefixStarts[i]
+		if prefixTopSize >= f.parent.minItemsInBlock {
+			if err := f.writeBlocks(ctx, i+1, prefixTopSize); err != nil {
+				return err
+			}
+			f.prefixStarts[i] -= prefixTopSize - 1
+		}
+	}
+
+	if len(f.prefixStarts) < len(text) {
+		f.prefixStarts = append(f.prefixStarts, make([]int, len(text)-len(f.prefixStarts))...)
+	}
+
+	// Init new tail:
+	for i := prefixLength; i < len(text); i++ {
+		f.prefixStarts[i] = len(f.pending)
+	}
+
+	f.lastTerm = append(f.lastTerm[:0], text...)
+	return nil
+}
+
+func commonPrefixLength(a, b []byte) int {
+	size := min(len(a), len(b))
+	for i := 0; i < size; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return size
+}
+
+// writeBlocks Writes the top count entries in pending, using prevTerm to compute the prefix.
+func (f *fieldWriter) writeBlocks(ctx context.Context, prefixLength, count int) error {
+	lastSuffixLeadLabel := -1
+
+	// True if we saw at least one term in this block (we record if a block
+	// only points to sub-blocks in the terms index so we can avoid seeking
+	// to it when we are looking for a term):
+	hasTerms := false
+	hasSubBlocks := false
+
+	start := len(f.pending) - count
+	end := len(f.pending)
+	nextBlockStart := start
+	nextFloorLeadLabel := -1
+
+	for i := start; i < end; i++ {
+		ent := f.pending[i]
+
+		suffixLeadLabel := -1
+		switch entry := ent.(type) {
+		case *pendingTerm:
+			if len(entry.termBytes) > prefixLength {
+				suffixLeadLabel = int(entry.termBytes[prefixLength])
+			}
+			// else suffix is 0, i.e. prefix 'foo' and term is 'foo' so the term
+			// has empty string suffix in this block
+		case *pendingBlock:
+			suffixLeadLabel = int(entry.prefix[prefixLength])
+		}
+
+		if suffixLeadLabel != lastSuffixLeadLabel {
+			itemsInBlock := i - nextBlockStart
+			if itemsInBlock >= f.parent.minItemsInBlock && end-nextBlockStart > f.parent.maxItemsInBlock {
+				// The count is too large for one block, so we must break it into "floor" blocks, where we record
+				// the leading label of the suffix of the first term in each floor block, so at search time we can
+				// jump to the right floor block.  We just use a naive greedy segmenter here: make a new floor
+				// block as soon as we have at least minItemsInBlock.  This is not always best: it often produces
+				// a too-small block as the final block:
+				isFloor := itemsInBlock < count
+				block, err := f.writeBlock(ctx, prefixLength, isFloor, nextFloorLeadLabel, nextBlockStart, i, hasTerms, hasSubBlocks)
+				if err != nil {
+					return err
+				}
+				f.newBlocks = append(f.newBlocks, block)
+
+				hasTerms = false
+				hasSubBlocks = false
+				nextFloorLeadLabel = suffixLeadLabel
+				nextBlockStart = i
+			}
+			lastSuffixLeadLabel = suffixLeadLabel
+		}
+
+		if ent.isTerm() {
+			hasTerms = true
+		} else {
+			hasSubBlocks = true
+		}
+	}
+
+	// Write last block, if any:
+	if nextBlockStart < end {
+		itemsInBlock := end - nextBlockStart
+		isFloor := itemsInBlock < count
+		block, err := f.writeBlock(ctx, prefixLength, isFloor, nextFloorLeadLabel, nextBlockStart, end, hasTerms, hasSubBlocks)
+		if err != nil {
+			return err
+		}
+		f.newBlocks = append(f.newBlocks, block)
+	}
+
+	firstBlock := f.newBlocks[0]
+	if err := firstBlock.compileIndex(ctx, f.newBlocks, f.parent.scratchBytes); err != nil {
+		return err
+	}
+
+	// Remove slice from the top of the pending stack, that we just wrote:
+	clear(f.pending[start:])
+	f.pending = f.pending[:start]
+
+	// Append new block
+	f.pending = append(f.pending, firstBlock)
+	clear(f.newBlocks)
+	f.newBlocks = f.newBlocks[:0]
+	return nil
+}
+
+// writeBlock Writes the specified slice (start is inclusive, end is exclusive) from pending stack
+// as a new block. If isFloor is true, there were too many (more than maxItemsInBlock) entries
+// sharing the same prefix, and so we broke it into multiple floor blocks where we record the
+// starting label of the suffix of each floor block.
+func (f *fieldWriter) writeBlock(ctx context.Context, prefixLength int, isFloor bool, floorLeadLabel, start, end int,
+	hasTerms, hasSubBlocks bool) (*pendingBlock, error) {
+
+	w := f.parent
+	termsOut := w.termsOut
+	startFP := termsOut.GetFilePointer()
+
+	hasFloorLeadLabel := isFloor && floorLeadLabel != -1
+
+	prefix := make([]byte, prefixLength, prefixLength+1)
+	copy(prefix, f.lastTerm[:prefixLength])
+
+	// Write block header:
+	numEntries := end - start
+	code := numEntries << 1
+	if end == len(f.pending) {
+		// Last block:
+		code |= 1
+	}
+	if err := termsOut.WriteUvarint(ctx, uint64(code)); err != nil {
+		return nil, err
+	}
+
+	// 1st pass: pack term suffix bytes into byte[] blob
+	// We optimize the leaf block case (block has only terms), writing a more
+	// compact format in this case:
+	isLeafBlock := !hasSubBlocks
+
+	var subIndices []*fst.FST
+	absolute := true
+	hasFreqs := f.fieldInfo.GetIndexOptions() != document.INDEX_OPTIONS_DOCS
+	stats := newStatsWriter(w.statsWriter, hasFreqs)
+
+	for i := start; i < end; i++ {
+		switch entry := f.pending[i].(type) {
+		case *pendingTerm:
+			suffix := len(entry.termBytes) - prefixLength
+			// Write term suffix lengths. For non-leaf block we borrow 1 bit to record
+			// if entry is term or sub-block
+			suffixCode := uint64(suffix)
+			if !isLeafBlock {
+				suffixCode <<= 1
+			}
+			if err := w.suffixLengthsWriter.WriteUvarint(ctx, suffixCode); err != nil {
+				return nil, err
+			}
+			if _, err := w.suffixWriter.Write(entry.termBytes[prefixLength:]); err != nil {
+				return nil, err
+			}
+
+			// Write term stats, to separate byte[] blob:
+			blockTermState := entry.state.GetBlockTermState()
+			if err := stats.add(ctx, blockTermState.DocFreq, blockTermState.TotalTermFreq); err != nil {
+				return nil, err
+			}
+
+			// Write term meta data
+			if err := w.postingsWriter.EncodeTerm(w.metaWriter, f.fieldInfo, entry.state, absolute); err != nil {
+				return nil, err
+			}
+			absolute = false
+		case *pendingBlock:
+			suffix := len(entry.prefix) - prefixLength
+			// For non-leaf block we borrow 1 bit to record
+			// if entry is term or sub-block
+			if err := w.suffixLengthsWriter.WriteUvarint(ctx, uint64(suffix<<1|1)); err != nil {
+				return nil, err
+			}
+			if _, err := w.suffixWriter.Write(entry.prefix[prefixLength:]); err != nil {
+				return nil, err
+			}
+			if err := w.suffixLengthsWriter.WriteUvarint(ctx, uint64(startFP-entry.fp)); err != nil {
+				return nil, err
+			}
+			subIndices = append(subIndices, entry.index)
+		}
+	}
+	if err := stats.finish(ctx); err != nil {
+		return nil, err
+	}
+
+	// Write suffixes byte[] blob to terms dict output
+	token := uint64(w.suffixWriter.GetFilePointer()) << 3
+	if isLeafBlock {
+		token |= 0x04
+	}
+	token |= COMPRESSION_NONE
+	if err := termsOut.WriteUvarint(ctx, token); err != nil {
+		return nil, err
+	}
+	if err := w.suffixWriter.CopyTo(termsOut); err != nil {
+		return nil, err
+	}
+	w.suffixWriter.Reset()
+
+	// Write suffix lengths
+	suffixLengths := w.suffixLengthsWriter.Bytes()
+	numSuffixBytes := len(suffixLengths)
+	if allEqual(suffixLengths) {
+		// Structured fields like IDs often have most values of the same length
+		if err := termsOut.WriteUvarint(ctx, uint64(numSuffixBytes<<1|1)); err != nil {
+			return nil, err
+		}
+		if err := termsOut.WriteByte(suffixLengths[0]); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := termsOut.WriteUvarint(ctx, uint64(numSuffixBytes<<1)); err != nil {
+			return nil, err
+		}
+		if _, err := termsOut.Write(suffixLengths); err != nil {
+			return nil, err
+		}
+	}
+	w.suffixLengthsWriter.Reset()
+
+	// Stats
+	if err := termsOut.WriteUvarint(ctx, uint64(w.statsWriter.GetFilePointer())); err != nil {
+		return nil, err
+	}
+	if err := w.statsWriter.CopyTo(termsOut); err != nil {
+		return nil, err
+	}
+	w.statsWriter.Reset()
+
+	// Write term meta data byte[] blob
+	if err := termsOut.WriteUvarint(ctx, uint64(w.metaWriter.GetFilePointer())); err != nil {
+		return nil, err
+	}
+	if err := w.metaWriter.CopyTo(termsOut); err != nil {
+		return nil, err
+	}
+	w.metaWriter.Reset()
+
+	if hasFloorLeadLabel {
+		// We already allocated to length+1 above:
+		prefix = append(prefix, byte(floorLeadLabel))
+	}
+
+	return &pendingBlock{
+		prefix:        prefix,
+		fp:            startFP,
+		subIndices:    subIndices,
+		hasTerms:      hasTerms,
+		isFloor:       isFloor,
+		floorLeadByte: floorLeadLabel,
+	}, nil
+}
+
+func allEqual(bs []byte) bool {
+	for _, b := range bs[1:] {
+		if b != bs[0] {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *fieldWriter) finish(ctx context.Context) error {
+	if f.numTerms == 0 {
+		return nil
+	}
+
+	w := f.parent
+
+	// Add empty term to force closing of all final blocks:
+	if err := f.pushTerm(ctx, []byte{}); err != nil {
+		return err
+	}
+
+	// TODO: if pending.size() is already 1 with a non-zero prefix length
+	// we can save writing a "degenerate" root block, but we have to
+	// fix all the places that assume the root block's prefix is the empty string:
+	if err := f.writeBlocks(ctx, 0, len(f.pending)); err != nil {
+		return err
+	}
+
+	// We better have one final "root" block:
+	root, ok := f.pending[0].(*pendingBlock)
+	if !ok || len(f.pending) != 1 {
+		return errors.New("pending stack must contain a single root block")
+	}
+	rootCode := root.index.GetEmptyOutput().(*fst.BytesOutput).Bytes()
+
+	metaOut := store.NewBufferDataOutput()
+	w.fields = append(w.fields, metaOut)
+
+	if err := metaOut.WriteUvarint(ctx, uint64(f.fieldInfo.Number())); err != nil {
+		return err
+	}
+	if err := metaOut.WriteUvarint(ctx, uint64(f.numTerms)); err != nil {
+		return err
+	}
+	if err := writeBytesRef(ctx, metaOut, rootCode); err != nil {
+		return err
+	}
+	if f.fieldInfo.GetIndexOptions() != document.INDEX_OPTIONS_DOCS {
+		if err := metaOut.WriteUvarint(ctx, uint64(f.sumTotalTermFreq)); err != nil {
+			return err
+		}
+	}
+	if err := metaOut.WriteUvarint(ctx, uint64(f.sumDocFreq)); err != nil {
+		return err
+	}
+	if err := metaOut.WriteUvarint(ctx, uint64(f.docsSeen.Count())); err != nil {
+		return err
+	}
+	if err := writeBytesRef(ctx, metaOut, f.firstPendingTerm.termBytes); err != nil {
+		return err
+	}
+	if err := writeBytesRef(ctx, metaOut, f.lastPendingTerm.termBytes); err != nil {
+		return err
+	}
+	if err := metaOut.WriteUvarint(ctx, uint64(w.indexOut.GetFilePointer())); err != nil {
+		return err
+	}
+	// Write FST to index
+	return root.index.Save(ctx, metaOut, w.indexOut)
+}
+
+func writeBytesRef(ctx context.Context, out store.DataOutput, bs []byte) error {
+	if err := out.WriteUvarint(ctx, uint64(len(bs))); err != nil {
+		return err
+	}
+	_, err := out.Write(bs)
+	return err
+}
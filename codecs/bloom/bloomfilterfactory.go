@@ -0,0 +1,58 @@
+package bloom
+
+import (
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+)
+
+// BloomFilterFactory Class used to create index-time FuzzySet appropriately configured for each
+// field. Also called to right-size bitsets for serialization.
+//
+// lucene.experimental
+type BloomFilterFactory interface {
+	// GetSetForField Returns a FuzzySet to record the terms of the field, or nil if the field should
+	// not be bloom filtered.
+	GetSetForField(state *index.SegmentWriteState, info *document.FieldInfo) *FuzzySet
+
+	// Downsize Called when downsizing bitsets for serialization. Returns the set to be saved, or nil
+	// to save the initial set unchanged.
+	Downsize(info *document.FieldInfo, initialSet *FuzzySet) *FuzzySet
+
+	// IsSaturated Used to determine if the given filter has reached saturation and should be
+	// retired i.e. not saved any more.
+	IsSaturated(bloomFilter *FuzzySet, info *document.FieldInfo) bool
+}
+
+var _ BloomFilterFactory = &DefaultBloomFilterFactory{}
+
+// DefaultBloomFilterFactory Default policy is to allocate a bitset with 10% saturation given a
+// unique term per document. Bits are set via murmur3 hashing function.
+//
+// lucene.experimental
+type DefaultBloomFilterFactory struct {
+}
+
+func NewDefaultBloomFilterFactory() *DefaultBloomFilterFactory {
+	return &DefaultBloomFilterFactory{}
+}
+
+func (d *DefaultBloomFilterFactory) GetSetForField(state *index.SegmentWriteState, info *document.FieldInfo) *FuzzySet {
+	maxDoc, err := state.SegmentInfo.MaxDoc()
+	if err != nil {
+		return nil
+	}
+	// Assume all of the docs have a unique term (e.g. a primary key) and we hope to maintain a set
+	// with 10% of bits set
+	return NewFuzzySet(maxDoc, 0.10)
+}
+
+func (d *DefaultBloomFilterFactory) Downsize(info *document.FieldInfo, initialSet *FuzzySet) *FuzzySet {
+	// Aim for a bitset size that would have 10% of bits set (so 90% of searches would fail-fast)
+	return initialSet.Downsize(0.1)
+}
+
+func (d *DefaultBloomFilterFactory) IsSaturated(bloomFilter *FuzzySet, info *document.FieldInfo) bool {
+	// Don't bother saving bitsets if >90% of bits are set - we don't want to throw any more memory
+	// at this problem.
+	return bloomFilter.GetSaturation() > 0.9
+}
@@ -0,0 +1,219 @@
+package bloom
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/bits-and-blooms/bitset"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util"
+)
+
+const (
+	// FUZZY_SET_VERSION_MURMUR3 bloom filters hashed with the 32 bit murmur3 function
+	FUZZY_SET_VERSION_MURMUR3 = 3
+
+	FUZZY_SET_VERSION_CURRENT = FUZZY_SET_VERSION_MURMUR3
+)
+
+// ContainsResult Result from FuzzySet.Contains: can never return definitively YES (always MAYBE),
+// but can sometimes definitely return NO.
+type ContainsResult int
+
+const (
+	CONTAINS_RESULT_MAYBE = ContainsResult(iota)
+	CONTAINS_RESULT_NO
+)
+
+// usableBitSetSizes The sizes of bitsets a FuzzySet can use: every size is a power of two minus
+// one, so that it can be used as a mask on the hash of a value.
+var usableBitSetSizes []int
+
+func init() {
+	usableBitSetSizes = make([]int, 0, 30)
+	for size := 1 << 6; size > 0 && size <= 1<<30; size <<= 1 {
+		usableBitSetSizes = append(usableBitSetSizes, size-1)
+	}
+}
+
+// FuzzySet A class used to represent a set of many, potentially large, values (e.g. many long
+// strings such as URLs), using a significantly smaller amount of memory.
+//
+// The set is "lossy" in that it cannot definitively state that is does contain a value but it can
+// definitively say if a value is not in the set. It can therefore be used as a Bloom Filter.
+//
+// Another application of the set is that it can be used to perform fuzzy counting because it can
+// estimate reasonably accurately how many unique values are contained in the set.
+//
+// This class is NOT threadsafe.
+//
+// Internally a Bitset is used to record values and once a client has finished recording a stream
+// of values the Downsize method can be used to create a suitably smaller set that is sized
+// appropriately for the number of values recorded and desired saturation levels.
+//
+// lucene.experimental
+type FuzzySet struct {
+	filter    *bitset.BitSet
+	bloomSize int
+}
+
+// NewFuzzySet Creates a set able to hold about maxNumUniqueValues values while keeping at most
+// desiredMaxSaturation of its bits set.
+func NewFuzzySet(maxNumUniqueValues int, desiredMaxSaturation float64) *FuzzySet {
+	setSize := getNearestSetSize(2*maxNumUniqueValues, desiredMaxSaturation)
+	return newFuzzySet(bitset.New(uint(setSize+1)), setSize)
+}
+
+func newFuzzySet(filter *bitset.BitSet, bloomSize int) *FuzzySet {
+	return &FuzzySet{
+		filter:    filter,
+		bloomSize: bloomSize,
+	}
+}
+
+// getNearestSetSize Use this method to choose a set size where accuracy (low content saturation)
+// is more important than deciding how much memory to throw at the problem.
+//
+// desiredSaturation A number between 0 and 1 expressing the % of bits set once all values have
+// been recorded. Returns the size of the set needed to accommodate the required number of values
+// at the desired saturation, or the largest usable size.
+func getNearestSetSize(maxNumberOfValuesExpected int, desiredSaturation float64) int {
+	// Iterate around the various scales of bitset from smallest to largest looking for the first
+	// that satisfies value volumes at the chosen saturation level
+	for _, size := range usableBitSetSizes {
+		numSetBitsAtDesiredSaturation := int(float64(size) * desiredSaturation)
+		estimatedNumUniqueValues := getEstimatedNumberUniqueValuesAllowingForCollisions(size, numSetBitsAtDesiredSaturation)
+		if estimatedNumUniqueValues > maxNumberOfValuesExpected {
+			return size
+		}
+	}
+	return usableBitSetSizes[len(usableBitSetSizes)-1]
+}
+
+func getEstimatedNumberUniqueValuesAllowingForCollisions(setSize, numRecordedBits int) int {
+	saturation := float64(numRecordedBits) / float64(setSize)
+	logInverseSaturation := math.Log(1-saturation) * -1
+	return int(float64(setSize) * logInverseSaturation)
+}
+
+func hash(value []byte) int {
+	return int(util.Murmurhash3_x86_32(value, 0))
+}
+
+// Contains The main method required for a Bloom filter which, given a value determines set
+// membership. Unlike a conventional set, the fuzzy set returns NO or MAYBE rather than true or
+// false.
+func (f *FuzzySet) Contains(value []byte) ContainsResult {
+	if !f.filter.Test(uint(hash(value) & f.bloomSize)) {
+		return CONTAINS_RESULT_NO
+	}
+	return CONTAINS_RESULT_MAYBE
+}
+
+// AddValue Records a value in the set. The referenced bytes are hashed and then modulo n'd where n
+// is the chosen size of the internal bitset.
+func (f *FuzzySet) AddValue(value []byte) {
+	f.filter.Set(uint(hash(value) & f.bloomSize))
+}
+
+// Downsize Returns a smaller FuzzySet with the same content, projected onto the smallest bitset
+// that stays below targetMaxSaturation, or nil if this set can't be made smaller.
+//
+// targetMaxSaturation A number between 0 and 1 describing the % of bits that would ideally be set
+// in the result. Lower values have better accuracy but require more space.
+func (f *FuzzySet) Downsize(targetMaxSaturation float64) *FuzzySet {
+	numBitsSet := int(f.filter.Count())
+	rightSizedBitSetSize := f.bloomSize
+
+	// Hopefully find a smaller size bitset into which we can project accumulated values while
+	// maintaining desired saturation level
+	for _, candidateBitsetSize := range usableBitSetSizes {
+		candidateSaturation := float64(numBitsSet) / float64(candidateBitsetSize)
+		if candidateSaturation <= targetMaxSaturation {
+			rightSizedBitSetSize = candidateBitsetSize
+			break
+		}
+	}
+	if rightSizedBitSetSize >= f.bloomSize {
+		return nil
+	}
+
+	// Re-project the numbers to a smaller space if necessary
+	rightSizedBitSet := bitset.New(uint(rightSizedBitSetSize + 1))
+	for i, ok := f.filter.NextSet(0); ok; i, ok = f.filter.NextSet(i + 1) {
+		rightSizedBitSet.Set(i & uint(rightSizedBitSetSize))
+	}
+	return newFuzzySet(rightSizedBitSet, rightSizedBitSetSize)
+}
+
+// GetEstimatedUniqueValues Returns an estimate of the number of unique values recorded.
+func (f *FuzzySet) GetEstimatedUniqueValues() int {
+	return getEstimatedNumberUniqueValuesAllowingForCollisions(f.bloomSize, int(f.filter.Count()))
+}
+
+// GetSaturation Returns the proportion of the bits of the set that are set.
+func (f *FuzzySet) GetSaturation() float64 {
+	return float64(f.filter.Count()) / float64(f.bloomSize)
+}
+
+// Serialize Serializes the data set to file using the following format:
+//
+//   - FuzzySet --> FuzzySetVersion,NumBitSetBits,NumLongs,BitSet
+//   - FuzzySetVersion --> Uint32. The version number of the FuzzySet class, which also encodes the
+//     hash function used
+//   - NumBitSetBits --> Uint32. The number of the bits of the set, minus one
+//   - NumLongs --> Uint32. The number of longs which make up the bitset
+//   - BitSet --> Uint64^NumLongs. The bits of the set
+func (f *FuzzySet) Serialize(ctx context.Context, out store.DataOutput) error {
+	if err := out.WriteUint32(ctx, FUZZY_SET_VERSION_CURRENT); err != nil {
+		return err
+	}
+	if err := out.WriteUint32(ctx, uint32(f.bloomSize)); err != nil {
+		return err
+	}
+	words := f.filter.Bytes()
+	numLongs := (f.bloomSize + 1 + 63) / 64
+	if err := out.WriteUint32(ctx, uint32(numLongs)); err != nil {
+		return err
+	}
+	for i := 0; i < numLongs; i++ {
+		word := uint64(0)
+		if i < len(words) {
+			word = words[i]
+		}
+		if err := out.WriteUint64(ctx, word); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeserializeFuzzySet Reads a FuzzySet written by Serialize.
+func DeserializeFuzzySet(ctx context.Context, in store.DataInput) (*FuzzySet, error) {
+	version, err := in.ReadUint32(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version != FUZZY_SET_VERSION_CURRENT {
+		return nil, fmt.Errorf("unknown fuzzy set version: %d", version)
+	}
+	bloomSize, err := in.ReadUint32(ctx)
+	if err != nil {
+		return nil, err
+	}
+	numLongs, err := in.ReadUint32(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if int(numLongs) != (int(bloomSize)+1+63)/64 {
+		return nil, fmt.Errorf("invalid fuzzy set: bloomSize=%d numLongs=%d", bloomSize, numLongs)
+	}
+	words := make([]uint64, numLongs)
+	for i := range words {
+		if words[i], err = in.ReadUint64(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return newFuzzySet(bitset.FromWithLength(uint(bloomSize)+1, words), int(bloomSize)), nil
+}
@@ -0,0 +1,99 @@
+package bloom
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/geange/lucene-go/codecs/lucene84"
+	"github.com/geange/lucene-go/codecs/lucene87"
+	"github.com/geange/lucene-go/codecs/perfield"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/search"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// bloomIDCodec writes the postings of the "id" field with a bloom filter in front of Lucene84
+type bloomIDCodec struct {
+	*lucene87.Codec
+
+	postingsFormat *perfield.PostingsFormat
+}
+
+func newBloomIDCodec() *bloomIDCodec {
+	codec := &bloomIDCodec{Codec: lucene87.NewCodec(lucene87.BEST_SPEED)}
+	postingsFormat := NewPostingsFormat(lucene84.NewPostingsFormat(), NewDefaultBloomFilterFactory())
+	codec.postingsFormat = perfield.NewPostingsFormat(func(field string) index.PostingsFormat {
+		if field == "id" {
+			return postingsFormat
+		}
+		return codec.GetPostingsFormatForField(field)
+	})
+	return codec
+}
+
+func (c *bloomIDCodec) PostingsFormat() index.PostingsFormat {
+	return c.postingsFormat
+}
+
+func TestPostingsFormat_IndexWriter(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	similarity, err := search.NewBM25Similarity()
+	assert.Nil(t, err)
+	writer, err := coreIndex.NewIndexWriter(ctx, dir, coreIndex.NewIndexWriterConfig(newBloomIDCodec(), similarity))
+	assert.Nil(t, err)
+
+	const numDocs = 500
+	for i := 0; i < numDocs; i++ {
+		doc := document.NewDocument()
+		doc.Add(document.NewStringField("id", fmt.Sprintf("id%05d", i), false))
+		_, err := writer.AddDocument(ctx, doc)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, writer.Commit(ctx))
+	assert.Nil(t, writer.Close())
+
+	// the reader finds the delegate of the bloom filter from the name recorded in the .blm file
+	reader, err := coreIndex.OpenDirectoryReader(ctx, dir, nil, nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer reader.DecRef()
+
+	leaves, err := reader.Leaves()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(leaves))
+	terms, err := leaves[0].LeafReader().Terms("id")
+	assert.Nil(t, err)
+	if !assert.IsType(t, &bloomFilteredTerms{}, terms) {
+		return
+	}
+
+	for i := 0; i < numDocs; i++ {
+		termsEnum, err := terms.Iterator()
+		assert.Nil(t, err)
+		found, err := termsEnum.SeekExact(ctx, []byte(fmt.Sprintf("id%05d", i)))
+		assert.Nil(t, err)
+		assert.True(t, found, i)
+	}
+
+	// a term absent from the filter is rejected without opening the terms dictionary of the delegate
+	skipped := 0
+	for i := numDocs; i < 2*numDocs; i++ {
+		termsEnum, err := terms.Iterator()
+		assert.Nil(t, err)
+		found, err := termsEnum.SeekExact(ctx, []byte(fmt.Sprintf("id%05d", i)))
+		assert.Nil(t, err)
+		assert.False(t, found, i)
+		if termsEnum.(*bloomFilteredTermsEnum).delegateTermsEnum == nil {
+			skipped++
+		}
+	}
+	assert.Greater(t, skipped, numDocs*3/4)
+}
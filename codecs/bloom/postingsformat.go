@@ -0,0 +1,344 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+)
+
+const (
+	BLOOM_CODEC_NAME = "BloomFilter"
+
+	BLOOM_VERSION_START   = 3
+	BLOOM_VERSION_CURRENT = BLOOM_VERSION_START
+
+	// BLOOM_EXTENSION Extension of Bloom Filters file
+	BLOOM_EXTENSION = "blm"
+)
+
+func init() {
+	coreIndex.RegisterPostingsFormat(NewPostingsFormat(nil, nil))
+}
+
+var _ index.PostingsFormat = &PostingsFormat{}
+
+// PostingsFormat A PostingsFormat useful for low doc-frequency fields such as primary keys. Bloom
+// filters are maintained in a ".blm" file which offers "fast-fail" for reads in segments known to
+// have no record of the key. A choice of delegate PostingsFormat is used to record all other
+// Postings data.
+//
+// A choice of BloomFilterFactory can be passed to tailor Bloom Filter settings on a per-field
+// basis. The default configuration is DefaultBloomFilterFactory which sizes the bitset for a unique
+// term per document and hashes values using murmur3. This should be suitable for most purposes.
+//
+// The format of the blm file is as follows:
+//   - BloomFilter (.blm) --> Header, DelegatePostingsFormatName, NumFilteredFields,
+//     Filter^NumFilteredFields, Footer
+//   - Filter --> FieldNumber, FuzzySet
+//   - FuzzySet --> See FuzzySet.Serialize
+//   - Header --> IndexHeader
+//   - DelegatePostingsFormatName --> String The name of a ServiceProvider registered PostingsFormat
+//   - NumFilteredFields --> Uint32
+//   - FieldNumber --> Uint32 The number of the field in this segment
+//   - Footer --> CodecFooter
+//
+// lucene.experimental
+type PostingsFormat struct {
+	name                   string
+	delegatePostingsFormat index.PostingsFormat
+	bloomFilterFactory     BloomFilterFactory
+}
+
+// NewPostingsFormat Creates Bloom filters for a selection of fields created in the index. This is
+// recorded as a set of Bitsets held as a segment summary in an additional "blm" file. This
+// PostingsFormat delegates to a choice of delegate PostingsFormat for encoding all other
+// postings data.
+//
+// delegatePostingsFormat is the PostingsFormat that records all the non-bloom filter data i.e.
+// postings info. bloomFilterFactory is the BloomFilterFactory responsible for sizing BloomFilters
+// appropriately, it defaults to DefaultBloomFilterFactory when nil.
+//
+// A format created without a delegate can only read segments: the name of the delegate is read
+// from the index.
+func NewPostingsFormat(delegatePostingsFormat index.PostingsFormat, bloomFilterFactory BloomFilterFactory) *PostingsFormat {
+	if bloomFilterFactory == nil {
+		bloomFilterFactory = NewDefaultBloomFilterFactory()
+	}
+	return &PostingsFormat{
+		name:                   BLOOM_CODEC_NAME,
+		delegatePostingsFormat: delegatePostingsFormat,
+		bloomFilterFactory:     bloomFilterFactory,
+	}
+}
+
+func (p *PostingsFormat) GetName() string {
+	return p.name
+}
+
+func (p *PostingsFormat) FieldsConsumer(ctx context.Context, state *index.SegmentWriteState) (index.FieldsConsumer, error) {
+	if p.delegatePostingsFormat == nil {
+		return nil, errors.New("error - constructed without a choice of PostingsFormat")
+	}
+	delegate, err := p.delegatePostingsFormat.FieldsConsumer(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	return &fieldsConsumer{
+		format:       p,
+		delegate:     delegate,
+		state:        state,
+		bloomFilters: make(map[*document.FieldInfo]*FuzzySet),
+	}, nil
+}
+
+func (p *PostingsFormat) FieldsProducer(ctx context.Context, state *index.SegmentReadState) (index.FieldsProducer, error) {
+	return newFieldsProducer(ctx, state)
+}
+
+var _ index.FieldsConsumer = &fieldsConsumer{}
+
+type fieldsConsumer struct {
+	format       *PostingsFormat
+	delegate     index.FieldsConsumer
+	state        *index.SegmentWriteState
+	bloomFilters map[*document.FieldInfo]*FuzzySet
+	fields       []*document.FieldInfo
+	closed       bool
+}
+
+func (c *fieldsConsumer) Write(ctx context.Context, fields index.Fields, norms index.NormsProducer) error {
+	// Delegate must write first: it may have opened files on creating the class, and write() will
+	// close them
+	if err := c.delegate.Write(ctx, fields, norms); err != nil {
+		return err
+	}
+
+	for _, field := range fields.Names() {
+		terms, err := fields.Terms(field)
+		if err != nil {
+			return err
+		}
+		if terms == nil {
+			continue
+		}
+		fieldInfo := c.state.FieldInfos.FieldInfo(field)
+		if fieldInfo == nil {
+			return fmt.Errorf("field %s not found in field infos", field)
+		}
+		if err := c.addTerms(ctx, fieldInfo, terms); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *fieldsConsumer) addTerms(ctx context.Context, fieldInfo *document.FieldInfo, terms index.Terms) error {
+	termsEnum, err := terms.Iterator()
+	if err != nil {
+		return err
+	}
+
+	var bloomFilter *FuzzySet
+	var postingsEnum index.PostingsEnum
+	for {
+		term, err := termsEnum.Next(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if term == nil {
+			return nil
+		}
+
+		if bloomFilter == nil {
+			bloomFilter = c.format.bloomFilterFactory.GetSetForField(c.state, fieldInfo)
+			if bloomFilter == nil {
+				// Field not bloom'd
+				return nil
+			}
+			c.bloomFilters[fieldInfo] = bloomFilter
+			c.fields = append(c.fields, fieldInfo)
+		}
+
+		// Make sure there's at least one doc for this term
+		postingsEnum, err = termsEnum.Postings(postingsEnum, 0)
+		if err != nil {
+			return err
+		}
+		doc, err := postingsEnum.NextDoc()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if err == nil && doc != types.NO_MORE_DOCS {
+			bloomFilter.AddValue(term)
+		}
+	}
+}
+
+func (c *fieldsConsumer) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	if err := c.delegate.Close(); err != nil {
+		return err
+	}
+	return c.writeBloomFilters(context.Background())
+}
+
+func (c *fieldsConsumer) writeBloomFilters(ctx context.Context) error {
+	// Now we are done accumulating values for these fields
+	nonSaturatedBlooms := make([]*document.FieldInfo, 0, len(c.fields))
+	for _, fieldInfo := range c.fields {
+		if !c.format.bloomFilterFactory.IsSaturated(c.bloomFilters[fieldInfo], fieldInfo) {
+			nonSaturatedBlooms = append(nonSaturatedBlooms, fieldInfo)
+		}
+	}
+
+	bloomFileName := store.SegmentFileName(c.state.SegmentInfo.Name(), c.state.SegmentSuffix, BLOOM_EXTENSION)
+	out, err := store.CreateOutputWithContext(ctx, c.state.Directory, bloomFileName, c.state.Context)
+	if err != nil {
+		return err
+	}
+	if err := c.writeBloomFile(ctx, out, nonSaturatedBlooms); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (c *fieldsConsumer) writeBloomFile(ctx context.Context, out store.IndexOutput, fieldInfos []*document.FieldInfo) error {
+	if err := utils.WriteIndexHeader(ctx, out, BLOOM_CODEC_NAME, BLOOM_VERSION_CURRENT,
+		c.state.SegmentInfo.GetID(), c.state.SegmentSuffix); err != nil {
+		return err
+	}
+	// remember the name of the postings format we will delegate to
+	if err := out.WriteString(ctx, c.format.delegatePostingsFormat.GetName()); err != nil {
+		return err
+	}
+
+	// First field in the output file is the number of fields+blooms saved
+	if err := out.WriteUint32(ctx, uint32(len(fieldInfos))); err != nil {
+		return err
+	}
+	for _, fieldInfo := range fieldInfos {
+		if err := out.WriteUint32(ctx, uint32(fieldInfo.Number())); err != nil {
+			return err
+		}
+		bloomFilter := c.bloomFilters[fieldInfo]
+		if rightSizedSet := c.format.bloomFilterFactory.Downsize(fieldInfo, bloomFilter); rightSizedSet != nil {
+			bloomFilter = rightSizedSet
+		}
+		if err := bloomFilter.Serialize(ctx, out); err != nil {
+			return err
+		}
+	}
+	return utils.WriteFooter(out)
+}
+
+var _ index.FieldsProducer = &fieldsProducer{}
+
+type fieldsProducer struct {
+	delegate          index.FieldsProducer
+	bloomsByFieldName map[string]*FuzzySet
+}
+
+func newFieldsProducer(ctx context.Context, state *index.SegmentReadState) (*fieldsProducer, error) {
+	bloomFileName := store.SegmentFileName(state.SegmentInfo.Name(), state.SegmentSuffix, BLOOM_EXTENSION)
+	in, err := store.OpenChecksumInput(state.Directory, bloomFileName)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	if _, err := utils.CheckIndexHeader(ctx, in, BLOOM_CODEC_NAME, BLOOM_VERSION_START,
+		BLOOM_VERSION_CURRENT, state.SegmentInfo.GetID(), state.SegmentSuffix); err != nil {
+		return nil, err
+	}
+
+	// Load the delegate postings format
+	delegateName, err := in.ReadString(ctx)
+	if err != nil {
+		return nil, err
+	}
+	delegateFormat, ok := coreIndex.GetPostingsFormatByName(delegateName)
+	if !ok {
+		return nil, fmt.Errorf("unknown postings format: %s (resource=%s)", delegateName, bloomFileName)
+	}
+
+	bloomsByFieldName := make(map[string]*FuzzySet)
+	numBlooms, err := in.ReadUint32(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < int(numBlooms); i++ {
+		fieldNum, err := in.ReadUint32(ctx)
+		if err != nil {
+			return nil, err
+		}
+		bloom, err := DeserializeFuzzySet(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		fieldInfo := state.FieldInfos.FieldInfoByNumber(int(fieldNum))
+		if fieldInfo == nil {
+			return nil, fmt.Errorf("invalid field number: %d (resource=%s)", fieldNum, bloomFileName)
+		}
+		bloomsByFieldName[fieldInfo.Name()] = bloom
+	}
+	if _, err := utils.CheckCodecFooter(in); err != nil {
+		return nil, err
+	}
+
+	delegate, err := delegateFormat.FieldsProducer(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	return &fieldsProducer{
+		delegate:          delegate,
+		bloomsByFieldName: bloomsByFieldName,
+	}, nil
+}
+
+func (p *fieldsProducer) Names() []string {
+	return p.delegate.Names()
+}
+
+func (p *fieldsProducer) Terms(field string) (index.Terms, error) {
+	terms, err := p.delegate.Terms(field)
+	if err != nil || terms == nil {
+		return terms, err
+	}
+	filter, ok := p.bloomsByFieldName[field]
+	if !ok {
+		return terms, nil
+	}
+	return &bloomFilteredTerms{Terms: terms, filter: filter}, nil
+}
+
+func (p *fieldsProducer) Size() int {
+	return p.delegate.Size()
+}
+
+func (p *fieldsProducer) Close() error {
+	return p.delegate.Close()
+}
+
+func (p *fieldsProducer) CheckIntegrity() error {
+	return p.delegate.CheckIntegrity()
+}
+
+func (p *fieldsProducer) GetMergeInstance() index.FieldsProducer {
+	return p
+}
@@ -0,0 +1,164 @@
+package bloom
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/geange/lucene-go/codecs/codectest"
+	"github.com/geange/lucene-go/codecs/lucene84"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+// testBloomFilterFactory does not filter the fields in skip
+type testBloomFilterFactory struct {
+	*DefaultBloomFilterFactory
+
+	skip string
+}
+
+func (f *testBloomFilterFactory) GetSetForField(state *index.SegmentWriteState, info *document.FieldInfo) *FuzzySet {
+	if info.Name() == f.skip {
+		return nil
+	}
+	return f.DefaultBloomFilterFactory.GetSetForField(state, info)
+}
+
+func TestPostingsFormat(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	const numDocs = 1000
+	ids := make([]string, 0, numDocs)
+	idTerms := make([]codectest.Term, 0, numDocs)
+	for i := 0; i < numDocs; i++ {
+		ids = append(ids, fmt.Sprintf("id%05d", i))
+		idTerms = append(idTerms, codectest.NewTerm(ids[i], i))
+	}
+	fields := codectest.NewFields(document.INDEX_OPTIONS_DOCS, map[string][]codectest.Term{
+		"body": idTerms, "id": idTerms,
+	})
+
+	newInfo := func(name string, number int) *document.FieldInfo {
+		return document.NewFieldInfo(name, number, false, true, false, document.INDEX_OPTIONS_DOCS,
+			document.DOC_VALUES_TYPE_NONE, -1, map[string]string{}, 0, 0, 0, false)
+	}
+	fieldInfos := coreIndex.NewFieldInfos([]*document.FieldInfo{newInfo("body", 0), newInfo("id", 1)})
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", numDocs, false, nil,
+		map[string]string{}, []byte("0123456789abcdef"), map[string]string{}, nil)
+
+	format := NewPostingsFormat(lucene84.NewPostingsFormat(),
+		&testBloomFilterFactory{DefaultBloomFilterFactory: NewDefaultBloomFilterFactory(), skip: "body"})
+	assert.Equal(t, BLOOM_CODEC_NAME, format.GetName())
+
+	consumer, err := format.FieldsConsumer(ctx, index.NewSegmentWriteState(dir, si, fieldInfos, nil, nil))
+	assert.Nil(t, err)
+	assert.Nil(t, consumer.Write(ctx, fields, nil))
+	assert.Nil(t, consumer.Close())
+
+	files, err := dir.ListAll(ctx)
+	assert.Nil(t, err)
+	assert.Contains(t, files, "_0.blm")
+
+	// the reader resolves the delegate from the name recorded in the .blm file
+	readFormat, ok := coreIndex.GetPostingsFormatByName(BLOOM_CODEC_NAME)
+	assert.True(t, ok)
+	producer, err := readFormat.FieldsProducer(ctx, index.NewSegmentReadState(dir, si, fieldInfos, nil, ""))
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer producer.Close()
+	assert.Nil(t, producer.CheckIntegrity())
+	assert.Equal(t, []string{"body", "id"}, producer.Names())
+
+	body, err := producer.Terms("body")
+	assert.Nil(t, err)
+	_, filtered := body.(*bloomFilteredTerms)
+	assert.False(t, filtered)
+
+	terms, err := producer.Terms("id")
+	assert.Nil(t, err)
+	if !assert.IsType(t, &bloomFilteredTerms{}, terms) {
+		return
+	}
+
+	for _, id := range ids {
+		termsEnum, err := terms.Iterator()
+		assert.Nil(t, err)
+		found, err := termsEnum.SeekExact(ctx, []byte(id))
+		assert.Nil(t, err)
+		assert.True(t, found, id)
+
+		postings, err := termsEnum.Postings(nil, 0)
+		assert.Nil(t, err)
+		doc, err := postings.NextDoc()
+		assert.Nil(t, err)
+		assert.Equal(t, id, ids[doc])
+	}
+
+	untouched := 0
+	for i := numDocs; i < 2*numDocs; i++ {
+		termsEnum, err := terms.Iterator()
+		assert.Nil(t, err)
+		found, err := termsEnum.SeekExact(ctx, []byte(fmt.Sprintf("id%05d", i)))
+		assert.Nil(t, err)
+		assert.False(t, found)
+		if termsEnum.(*bloomFilteredTermsEnum).delegateTermsEnum == nil {
+			untouched++
+		}
+	}
+	// the filter is sized for 10% saturation, so about 90% of the misses never reach the terms dictionary
+	assert.Greater(t, untouched, numDocs*3/4)
+
+	// iteration goes through the delegate
+	termsEnum, err := terms.Iterator()
+	assert.Nil(t, err)
+	for _, id := range ids {
+		term, err := termsEnum.Next(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, id, string(term))
+	}
+}
+
+func TestFuzzySet(t *testing.T) {
+	ctx := context.Background()
+
+	set := NewFuzzySet(1000, 0.1)
+	for i := 0; i < 100; i++ {
+		set.AddValue([]byte(fmt.Sprintf("value%d", i)))
+	}
+	assert.Less(t, set.GetSaturation(), 0.1)
+
+	// downsizing keeps every value
+	small := set.Downsize(0.1)
+	if !assert.NotNil(t, small) {
+		return
+	}
+	assert.Less(t, small.bloomSize, set.bloomSize)
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, CONTAINS_RESULT_MAYBE, small.Contains([]byte(fmt.Sprintf("value%d", i))))
+	}
+	assert.Nil(t, small.Downsize(0.1))
+
+	out := store.NewBufferDataOutput()
+	assert.Nil(t, small.Serialize(ctx, out))
+	read, err := DeserializeFuzzySet(ctx, store.NewBytesInput(out.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, small.bloomSize, read.bloomSize)
+	assert.True(t, small.filter.Equal(read.filter))
+
+	misses := 0
+	for i := 100; i < 1100; i++ {
+		if read.Contains([]byte(fmt.Sprintf("value%d", i))) == CONTAINS_RESULT_NO {
+			misses++
+		}
+	}
+	assert.Greater(t, misses, 800)
+}
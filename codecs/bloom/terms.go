@@ -0,0 +1,153 @@
+package bloom
+
+import (
+	"context"
+
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/util/attribute"
+)
+
+var _ index.Terms = &bloomFilteredTerms{}
+
+// bloomFilteredTerms the terms of a field that has a bloom filter
+type bloomFilteredTerms struct {
+	index.Terms
+
+	filter *FuzzySet
+}
+
+func (t *bloomFilteredTerms) Iterator() (index.TermsEnum, error) {
+	return &bloomFilteredTermsEnum{delegateTerms: t.Terms, filter: t.filter}, nil
+}
+
+var _ index.TermsEnum = &bloomFilteredTermsEnum{}
+
+// bloomFilteredTermsEnum only creates the TermsEnum of the delegate when it is needed, so that
+// SeekExact on a term that is not in the bloom filter never touches the terms dictionary.
+type bloomFilteredTermsEnum struct {
+	delegateTerms     index.Terms
+	delegateTermsEnum index.TermsEnum
+	filter            *FuzzySet
+}
+
+func (e *bloomFilteredTermsEnum) delegate() (index.TermsEnum, error) {
+	if e.delegateTermsEnum == nil {
+		// pull the iterator only if we really need it
+		termsEnum, err := e.delegateTerms.Iterator()
+		if err != nil {
+			return nil, err
+		}
+		e.delegateTermsEnum = termsEnum
+	}
+	return e.delegateTermsEnum, nil
+}
+
+func (e *bloomFilteredTermsEnum) Next(ctx context.Context) ([]byte, error) {
+	delegate, err := e.delegate()
+	if err != nil {
+		return nil, err
+	}
+	return delegate.Next(ctx)
+}
+
+func (e *bloomFilteredTermsEnum) Attributes() *attribute.Source {
+	delegate, err := e.delegate()
+	if err != nil {
+		return nil
+	}
+	return delegate.Attributes()
+}
+
+func (e *bloomFilteredTermsEnum) SeekExact(ctx context.Context, text []byte) (bool, error) {
+	// The magical fail-fast speed up that is the entire point of all of this code - save a disk
+	// seek if there is a match on an in-memory structure that may occasionally give a false
+	// positive but guaranteed no false negatives
+	if e.filter.Contains(text) == CONTAINS_RESULT_NO {
+		return false, nil
+	}
+	delegate, err := e.delegate()
+	if err != nil {
+		return false, err
+	}
+	return delegate.SeekExact(ctx, text)
+}
+
+func (e *bloomFilteredTermsEnum) SeekCeil(ctx context.Context, text []byte) (index.SeekStatus, error) {
+	delegate, err := e.delegate()
+	if err != nil {
+		return 0, err
+	}
+	return delegate.SeekCeil(ctx, text)
+}
+
+func (e *bloomFilteredTermsEnum) SeekExactByOrd(ctx context.Context, ord int64) error {
+	delegate, err := e.delegate()
+	if err != nil {
+		return err
+	}
+	return delegate.SeekExactByOrd(ctx, ord)
+}
+
+func (e *bloomFilteredTermsEnum) SeekExactExpert(ctx context.Context, term []byte, state index.TermState) error {
+	delegate, err := e.delegate()
+	if err != nil {
+		return err
+	}
+	return delegate.SeekExactExpert(ctx, term, state)
+}
+
+func (e *bloomFilteredTermsEnum) Term() ([]byte, error) {
+	delegate, err := e.delegate()
+	if err != nil {
+		return nil, err
+	}
+	return delegate.Term()
+}
+
+func (e *bloomFilteredTermsEnum) Ord() (int64, error) {
+	delegate, err := e.delegate()
+	if err != nil {
+		return 0, err
+	}
+	return delegate.Ord()
+}
+
+func (e *bloomFilteredTermsEnum) DocFreq() (int, error) {
+	delegate, err := e.delegate()
+	if err != nil {
+		return 0, err
+	}
+	return delegate.DocFreq()
+}
+
+func (e *bloomFilteredTermsEnum) TotalTermFreq() (int64, error) {
+	delegate, err := e.delegate()
+	if err != nil {
+		return 0, err
+	}
+	return delegate.TotalTermFreq()
+}
+
+func (e *bloomFilteredTermsEnum) Postings(reuse index.PostingsEnum, flags int) (index.PostingsEnum, error) {
+	delegate, err := e.delegate()
+	if err != nil {
+		return nil, err
+	}
+	return delegate.Postings(reuse, flags)
+}
+
+func (e *bloomFilteredTermsEnum) Impacts(flags int) (index.ImpactsEnum, error) {
+	delegate, err := e.delegate()
+	if err != nil {
+		return nil, err
+	}
+	return delegate.Impacts(flags)
+}
+
+func (e *bloomFilteredTermsEnum) TermState() (index.TermState, error) {
+	delegate, err := e.delegate()
+	if err != nil {
+		return nil, err
+	}
+	return delegate.TermState()
+}
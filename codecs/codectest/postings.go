@@ -0,0 +1,284 @@
+package codectest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"slices"
+
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/types"
+)
+
+// Position an occurrence of a term in a document
+type Position struct {
+	Pos         int
+	StartOffset int
+	EndOffset   int
+	Payload     []byte
+}
+
+// Doc the occurrences of a term in a document. A document without positions has a freq of 1.
+type Doc struct {
+	ID        int
+	Positions []Position
+}
+
+// Freq Returns the number of occurrences of the term in the document
+func (d *Doc) Freq() int {
+	return max(1, len(d.Positions))
+}
+
+// Term a term and the documents it is in, sorted by ID
+type Term struct {
+	Text []byte
+	Docs []Doc
+}
+
+// NewTerm Returns a term which is once in each of the docs, without positions
+func NewTerm(text string, docs ...int) Term {
+	term := Term{Text: []byte(text), Docs: make([]Doc, 0, len(docs))}
+	for _, doc := range docs {
+		term.Docs = append(term.Docs, Doc{ID: doc})
+	}
+	return term
+}
+
+var _ index.Fields = &Fields{}
+
+// Fields in-memory postings to give to a FieldsConsumer: the fields are sorted by name, their terms by
+// term bytes. It only supports the methods used by the postings writers.
+type Fields struct {
+	names        []string
+	terms        map[string][]Term
+	indexOptions document.IndexOptions
+}
+
+// NewFields Returns the postings of the fields, which are all indexed with indexOptions
+func NewFields(indexOptions document.IndexOptions, fields map[string][]Term) *Fields {
+	names := make([]string, 0, len(fields))
+	terms := make(map[string][]Term, len(fields))
+	for name, fieldTerms := range fields {
+		names = append(names, name)
+		sorted := slices.Clone(fieldTerms)
+		slices.SortFunc(sorted, func(a, b Term) int {
+			return bytes.Compare(a.Text, b.Text)
+		})
+		terms[name] = sorted
+	}
+	slices.Sort(names)
+	return &Fields{names: names, terms: terms, indexOptions: indexOptions}
+}
+
+func (f *Fields) Names() []string {
+	return f.names
+}
+
+func (f *Fields) Terms(field string) (index.Terms, error) {
+	terms, ok := f.terms[field]
+	if !ok {
+		return nil, nil
+	}
+	return &Terms{terms: terms, indexOptions: f.indexOptions}, nil
+}
+
+func (f *Fields) Size() int {
+	return len(f.names)
+}
+
+// FieldTerms Returns the sorted terms of the field
+func (f *Fields) FieldTerms(field string) []Term {
+	return f.terms[field]
+}
+
+var _ index.Terms = &Terms{}
+
+// Terms the terms of a field of Fields
+type Terms struct {
+	index.Terms
+
+	terms        []Term
+	indexOptions document.IndexOptions
+}
+
+func (t *Terms) Iterator() (index.TermsEnum, error) {
+	return NewTermsEnum(t.terms), nil
+}
+
+func (t *Terms) Size() (int, error) {
+	return len(t.terms), nil
+}
+
+func (t *Terms) GetMin() ([]byte, error) {
+	if len(t.terms) == 0 {
+		return nil, nil
+	}
+	return t.terms[0].Text, nil
+}
+
+func (t *Terms) GetMax() ([]byte, error) {
+	if len(t.terms) == 0 {
+		return nil, nil
+	}
+	return t.terms[len(t.terms)-1].Text, nil
+}
+
+func (t *Terms) HasFreqs() bool {
+	return t.indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS
+}
+
+func (t *Terms) HasPositions() bool {
+	return t.indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS
+}
+
+func (t *Terms) HasOffsets() bool {
+	return t.indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS
+}
+
+func (t *Terms) HasPayloads() bool {
+	if !t.HasPositions() {
+		return false
+	}
+	for _, term := range t.terms {
+		for _, doc := range term.Docs {
+			for _, position := range doc.Positions {
+				if len(position.Payload) > 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+var _ index.TermsEnum = &TermsEnum{}
+
+// TermsEnum iterates over sorted terms. Next returns io.EOF after the last term.
+type TermsEnum struct {
+	index.TermsEnum
+
+	terms []Term
+	upto  int
+}
+
+// NewTermsEnum Returns an enum over the sorted terms, positioned before the first term
+func NewTermsEnum(terms []Term) *TermsEnum {
+	return &TermsEnum{terms: terms, upto: -1}
+}
+
+func (e *TermsEnum) Next(context.Context) ([]byte, error) {
+	if e.upto < len(e.terms) {
+		e.upto++
+	}
+	if e.upto == len(e.terms) {
+		return nil, io.EOF
+	}
+	return e.terms[e.upto].Text, nil
+}
+
+func (e *TermsEnum) Term() ([]byte, error) {
+	return e.terms[e.upto].Text, nil
+}
+
+func (e *TermsEnum) DocFreq() (int, error) {
+	return len(e.terms[e.upto].Docs), nil
+}
+
+func (e *TermsEnum) TotalTermFreq() (int64, error) {
+	totalTermFreq := int64(0)
+	for i := range e.terms[e.upto].Docs {
+		totalTermFreq += int64(e.terms[e.upto].Docs[i].Freq())
+	}
+	return totalTermFreq, nil
+}
+
+func (e *TermsEnum) Postings(reuse index.PostingsEnum, flags int) (index.PostingsEnum, error) {
+	return NewPostingsEnum(e.terms[e.upto].Docs), nil
+}
+
+var _ index.PostingsEnum = &PostingsEnum{}
+
+// PostingsEnum iterates over the docs of a term, and over the positions of the current doc
+type PostingsEnum struct {
+	docs    []Doc
+	upto    int
+	posUpto int
+}
+
+// NewPostingsEnum Returns an enum over the docs, positioned before the first doc
+func NewPostingsEnum(docs []Doc) *PostingsEnum {
+	return &PostingsEnum{docs: docs, upto: -1, posUpto: -1}
+}
+
+func (p *PostingsEnum) DocID() int {
+	if p.upto < 0 {
+		return -1
+	}
+	if p.upto >= len(p.docs) {
+		return types.NO_MORE_DOCS
+	}
+	return p.docs[p.upto].ID
+}
+
+func (p *PostingsEnum) NextDoc() (int, error) {
+	if p.upto < len(p.docs) {
+		p.upto++
+	}
+	p.posUpto = -1
+	return p.DocID(), nil
+}
+
+func (p *PostingsEnum) Advance(target int) (int, error) {
+	return types.SlowAdvance(p, target)
+}
+
+func (p *PostingsEnum) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(p, target)
+}
+
+func (p *PostingsEnum) Cost() int64 {
+	return int64(len(p.docs))
+}
+
+func (p *PostingsEnum) Freq() (int, error) {
+	return p.docs[p.upto].Freq(), nil
+}
+
+// NextPosition Returns -1 when the doc has no positions
+func (p *PostingsEnum) NextPosition() (int, error) {
+	p.posUpto++
+	if position := p.position(); position != nil {
+		return position.Pos, nil
+	}
+	return -1, nil
+}
+
+func (p *PostingsEnum) StartOffset() (int, error) {
+	if position := p.position(); position != nil {
+		return position.StartOffset, nil
+	}
+	return -1, nil
+}
+
+func (p *PostingsEnum) EndOffset() (int, error) {
+	if position := p.position(); position != nil {
+		return position.EndOffset, nil
+	}
+	return -1, nil
+}
+
+func (p *PostingsEnum) GetPayload() ([]byte, error) {
+	if position := p.position(); position != nil {
+		return position.Payload, nil
+	}
+	return nil, nil
+}
+
+func (p *PostingsEnum) position() *Position {
+	positions := p.docs[p.upto].Positions
+	if p.posUpto < 0 || p.posUpto >= len(positions) {
+		return nil
+	}
+	return &positions[p.posUpto]
+}
@@ -0,0 +1,472 @@
+package compressing
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/compress/lz4"
+)
+
+// CompressionMode A compression mode. Tells how much effort should be spent on compression
+// and decompression of stored fields.
+// lucene.experimental
+type CompressionMode interface {
+	// NewCompressor Create a new Compressor instance.
+	NewCompressor() Compressor
+
+	// NewDecompressor Create a new Decompressor instance.
+	NewDecompressor() Decompressor
+
+	String() string
+}
+
+// Compressor A data compressor.
+type Compressor interface {
+	// Compress bytes into out. It is the responsibility of the compressor to add all necessary
+	// information so that a Decompressor will know when to stop decompressing bytes from the stream.
+	Compress(ctx context.Context, data []byte, out store.DataOutput) error
+}
+
+// Decompressor A decompressor.
+type Decompressor interface {
+	// Decompress bytes that were stored between offsets offset and offset+length in the original
+	// stream from the compressed stream in. This method is only allowed to read from in what has
+	// been written by Compressor.Compress. originalLength is the length of the decompressed stream.
+	// The returned slice holds exactly length bytes and may be overwritten by the next call.
+	Decompress(ctx context.Context, in store.DataInput, originalLength, offset, length int) ([]byte, error)
+
+	Clone() Decompressor
+}
+
+var (
+	// FAST A compression mode that trades compression ratio for speed. Although the compression
+	// ratio might remain high, compression and decompression are very fast. Use this mode with
+	// indices that have a high update rate but should be able to load documents from disk quickly.
+	FAST CompressionMode = &fastMode{}
+
+	// HIGH_COMPRESSION A compression mode that trades speed for compression ratio. Although
+	// compression and decompression might be slow, this compression mode should provide a good
+	// compression ratio. This mode might be interesting if/when your index size is much bigger
+	// than your OS cache. Data is compressed with DEFLATE in independent sub blocks that share a
+	// preset dictionary, so that loading one document only needs to inflate the dictionary and the
+	// sub blocks the document spans.
+	HIGH_COMPRESSION CompressionMode = &deflateWithPresetDictMode{}
+
+	// LZ4_WITH_PRESET_DICT A compression mode that compresses with LZ4 in independent sub blocks
+	// that share a preset dictionary, like HIGH_COMPRESSION does with DEFLATE. It is faster than
+	// HIGH_COMPRESSION and compresses better than FAST since every sub block can refer to the
+	// dictionary.
+	LZ4_WITH_PRESET_DICT CompressionMode = &lz4WithPresetDictMode{}
+)
+
+type fastMode struct{}
+
+func (f *fastMode) NewCompressor() Compressor {
+	return &lz4FastCompressor{ht: lz4.NewFastCompressionHashTable()}
+}
+
+func (f *fastMode) NewDecompressor() Decompressor {
+	return &lz4Decompressor{}
+}
+
+func (f *fastMode) String() string {
+	return "FAST"
+}
+
+type lz4FastCompressor struct {
+	ht *lz4.FastCompressionHashTable
+}
+
+func (l *lz4FastCompressor) Compress(ctx context.Context, data []byte, out store.DataOutput) error {
+	return lz4.Compress(data, out, l.ht)
+}
+
+type lz4Decompressor struct {
+	buffer []byte
+}
+
+func (l *lz4Decompressor) Decompress(ctx context.Context, in store.DataInput, originalLength, offset, length int) ([]byte, error) {
+	if offset+length > originalLength {
+		return nil, fmt.Errorf("offset+length(%d) is larger than originalLength(%d)", offset+length, originalLength)
+	}
+	if length == 0 {
+		return nil, nil
+	}
+
+	// add 7 padding bytes, this is not necessary but can help decompression run faster
+	if cap(l.buffer) < originalLength+7 {
+		l.buffer = make([]byte, originalLength+7)
+	}
+	l.buffer = l.buffer[:originalLength+7]
+
+	// the stream is decompressed sequentially, so we can stop as soon as the requested bytes
+	// are available
+	decompressedLength, err := lz4.Decompress(in, offset+length, l.buffer)
+	if err != nil {
+		return nil, err
+	}
+	if decompressedLength > originalLength {
+		return nil, fmt.Errorf("corrupted: lengths mismatch: %d > %d", decompressedLength, originalLength)
+	}
+	return l.buffer[offset : offset+length], nil
+}
+
+func (l *lz4Decompressor) Clone() Decompressor {
+	return &lz4Decompressor{}
+}
+
+const (
+	// NUM_SUB_BLOCKS Number of sub blocks a chunk is split into, each of them is compressed
+	// independently with the dictionary.
+	NUM_SUB_BLOCKS = 10
+
+	// DICT_SIZE_FACTOR Divisor of the chunk length that gives the dictionary length:
+	// dictLength = chunkLength / (NUM_SUB_BLOCKS * DICT_SIZE_FACTOR)
+	DICT_SIZE_FACTOR = 6
+
+	// LZ4_DICT_SIZE_FACTOR Divisor of the chunk length that gives the LZ4 dictionary length:
+	// dictLength = chunkLength / (NUM_SUB_BLOCKS * LZ4_DICT_SIZE_FACTOR)
+	LZ4_DICT_SIZE_FACTOR = 16
+
+	// deflateLevel the level Lucene uses for its BEST_COMPRESSION mode
+	deflateLevel = 6
+)
+
+type lz4WithPresetDictMode struct{}
+
+func (l *lz4WithPresetDictMode) NewCompressor() Compressor {
+	return &lz4WithPresetDictCompressor{
+		ht:         lz4.NewFastCompressionHashTable(),
+		compressed: new(bytes.Buffer),
+	}
+}
+
+func (l *lz4WithPresetDictMode) NewDecompressor() Decompressor {
+	return &lz4WithPresetDictDecompressor{}
+}
+
+func (l *lz4WithPresetDictMode) String() string {
+	return "LZ4_WITH_PRESET_DICT"
+}
+
+// lz4WithPresetDictCompressor writes the lengths of the dictionary and of the sub blocks, then
+// the compressed lengths of the dictionary and of every sub block, and then the compressed bytes.
+type lz4WithPresetDictCompressor struct {
+	ht         *lz4.FastCompressionHashTable
+	buffer     []byte // the dictionary followed by the current sub block
+	compressed *bytes.Buffer
+}
+
+func (l *lz4WithPresetDictCompressor) Compress(ctx context.Context, data []byte, out store.DataOutput) error {
+	dictLength := len(data) / (NUM_SUB_BLOCKS * LZ4_DICT_SIZE_FACTOR)
+	blockLength := (len(data) - dictLength + NUM_SUB_BLOCKS - 1) / NUM_SUB_BLOCKS
+	if err := out.WriteUvarint(ctx, uint64(dictLength)); err != nil {
+		return err
+	}
+	if err := out.WriteUvarint(ctx, uint64(blockLength)); err != nil {
+		return err
+	}
+
+	l.compressed.Reset()
+	// Compress the dictionary first
+	l.buffer = append(l.buffer[:0], data[:dictLength]...)
+	if err := l.doCompress(ctx, 0, out); err != nil {
+		return err
+	}
+
+	// And then sub blocks
+	for start := dictLength; start < len(data); start += blockLength {
+		end := min(start+blockLength, len(data))
+		l.buffer = append(l.buffer[:dictLength], data[start:end]...)
+		if err := l.doCompress(ctx, dictLength, out); err != nil {
+			return err
+		}
+	}
+
+	// We only wrote lengths so far, now write compressed data
+	_, err := out.Write(l.compressed.Bytes())
+	return err
+}
+
+// doCompress compresses the buffer after dictLength bytes and writes the compressed length.
+func (l *lz4WithPresetDictCompressor) doCompress(ctx context.Context, dictLength int, out store.DataOutput) error {
+	before := l.compressed.Len()
+	if err := lz4.CompressWithDictionary(l.buffer, dictLength, l.compressed, l.ht); err != nil {
+		return err
+	}
+	return out.WriteUvarint(ctx, uint64(l.compressed.Len()-before))
+}
+
+type lz4WithPresetDictDecompressor struct {
+	compressedLengths []int
+	buffer            []byte
+	result            []byte
+}
+
+func (l *lz4WithPresetDictDecompressor) Decompress(ctx context.Context, in store.DataInput, originalLength, offset, length int) ([]byte, error) {
+	if offset+length > originalLength {
+		return nil, fmt.Errorf("offset+length(%d) is larger than originalLength(%d)", offset+length, originalLength)
+	}
+	if length == 0 {
+		return nil, nil
+	}
+
+	n, err := in.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dictLength := int(n)
+	if n, err = in.ReadUvarint(ctx); err != nil {
+		return nil, err
+	}
+	blockLength := int(n)
+
+	if err := l.readCompressedLengths(ctx, in, originalLength, dictLength, blockLength); err != nil {
+		return nil, err
+	}
+
+	// add 7 padding bytes, this is not necessary but can help decompression run faster
+	if cap(l.buffer) < dictLength+blockLength+7 {
+		l.buffer = make([]byte, dictLength+blockLength+7)
+	}
+	l.buffer = l.buffer[:dictLength+blockLength+7]
+
+	// Read the dictionary
+	decompressed, err := lz4.Decompress(in, dictLength, l.buffer)
+	if err != nil {
+		return nil, err
+	}
+	if decompressed != dictLength {
+		return nil, fmt.Errorf("corrupted: illegal dict length %d, expected %d", decompressed, dictLength)
+	}
+
+	offsetInBlock := dictLength
+	offsetInBytes := offset
+	result := l.result[:0]
+	if offset >= dictLength {
+		offsetInBytes -= dictLength
+
+		// Skip unneeded blocks
+		numBytesToSkip := 0
+		for i := 0; i < len(l.compressedLengths) && offsetInBlock+blockLength < offset; i++ {
+			numBytesToSkip += l.compressedLengths[i]
+			offsetInBlock += blockLength
+			offsetInBytes -= blockLength
+		}
+		if err := in.SkipBytes(ctx, numBytesToSkip); err != nil {
+			return nil, err
+		}
+	} else {
+		// The dictionary contains some bytes we need
+		result = append(result, l.buffer[:dictLength]...)
+	}
+
+	// Read blocks that intersect with the interval we need
+	for offsetInBlock < offset+length {
+		bytesToDecompress := min(blockLength, offset+length-offsetInBlock)
+		if _, err := lz4.DecompressWithDictionary(in, bytesToDecompress, l.buffer, dictLength); err != nil {
+			return nil, err
+		}
+		result = append(result, l.buffer[dictLength:dictLength+bytesToDecompress]...)
+		offsetInBlock += blockLength
+	}
+
+	l.result = result
+	if offsetInBytes+length > len(result) {
+		return nil, fmt.Errorf("corrupted: lengths mismatch: %d > %d", offsetInBytes+length, len(result))
+	}
+	return result[offsetInBytes : offsetInBytes+length], nil
+}
+
+// readCompressedLengths reads the compressed lengths of the sub blocks
+func (l *lz4WithPresetDictDecompressor) readCompressedLengths(ctx context.Context, in store.DataInput,
+	originalLength, dictLength, blockLength int) error {
+
+	if _, err := in.ReadUvarint(ctx); err != nil { // compressed length of the dictionary, unused
+		return err
+	}
+	if blockLength == 0 && originalLength > dictLength {
+		return fmt.Errorf("corrupted: empty sub blocks for %d bytes", originalLength)
+	}
+	l.compressedLengths = l.compressedLengths[:0]
+	for totalLength := dictLength; totalLength < originalLength; totalLength += blockLength {
+		compressedLength, err := in.ReadUvarint(ctx)
+		if err != nil {
+			return err
+		}
+		l.compressedLengths = append(l.compressedLengths, int(compressedLength))
+	}
+	return nil
+}
+
+func (l *lz4WithPresetDictDecompressor) Clone() Decompressor {
+	return &lz4WithPresetDictDecompressor{}
+}
+
+type deflateWithPresetDictMode struct{}
+
+func (d *deflateWithPresetDictMode) NewCompressor() Compressor {
+	return &deflateWithPresetDictCompressor{buffer: new(bytes.Buffer)}
+}
+
+func (d *deflateWithPresetDictMode) NewDecompressor() Decompressor {
+	return &deflateWithPresetDictDecompressor{}
+}
+
+func (d *deflateWithPresetDictMode) String() string {
+	return "HIGH_COMPRESSION"
+}
+
+type deflateWithPresetDictCompressor struct {
+	buffer *bytes.Buffer
+}
+
+func (d *deflateWithPresetDictCompressor) Compress(ctx context.Context, data []byte, out store.DataOutput) error {
+	dictLength := len(data) / (NUM_SUB_BLOCKS * DICT_SIZE_FACTOR)
+	blockLength := (len(data) - dictLength + NUM_SUB_BLOCKS - 1) / NUM_SUB_BLOCKS
+	if err := out.WriteUvarint(ctx, uint64(dictLength)); err != nil {
+		return err
+	}
+	if err := out.WriteUvarint(ctx, uint64(blockLength)); err != nil {
+		return err
+	}
+
+	// Compress the dictionary first
+	dict := data[:dictLength]
+	if err := d.doCompress(ctx, dict, nil, out); err != nil {
+		return err
+	}
+
+	// And then sub blocks
+	for start := dictLength; start < len(data); start += blockLength {
+		end := min(start+blockLength, len(data))
+		if err := d.doCompress(ctx, data[start:end], dict, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *deflateWithPresetDictCompressor) doCompress(ctx context.Context, data, dict []byte, out store.DataOutput) error {
+	if len(data) == 0 {
+		return out.WriteUvarint(ctx, 0)
+	}
+
+	d.buffer.Reset()
+	writer, err := flate.NewWriterDict(d.buffer, deflateLevel, dict)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	if err := out.WriteUvarint(ctx, uint64(d.buffer.Len())); err != nil {
+		return err
+	}
+	_, err = out.Write(d.buffer.Bytes())
+	return err
+}
+
+type deflateWithPresetDictDecompressor struct {
+	compressed []byte
+	buffer     []byte
+}
+
+func (d *deflateWithPresetDictDecompressor) Decompress(ctx context.Context, in store.DataInput, originalLength, offset, length int) ([]byte, error) {
+	if offset+length > originalLength {
+		return nil, fmt.Errorf("offset+length(%d) is larger than originalLength(%d)", offset+length, originalLength)
+	}
+	if length == 0 {
+		return nil, nil
+	}
+
+	dictLength, err := in.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	blockLength, err := in.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the dictionary
+	d.buffer = d.buffer[:0]
+	if d.buffer, err = d.doDecompress(ctx, in, nil, d.buffer); err != nil {
+		return nil, err
+	}
+	if len(d.buffer) != int(dictLength) {
+		return nil, fmt.Errorf("corrupted: expected dictionary of %d bytes, got %d", dictLength, len(d.buffer))
+	}
+	dict := d.buffer[:dictLength:dictLength]
+
+	// Skip unneeded blocks
+	offsetInBlock := int(dictLength)
+	offsetInBytes := offset
+	for offsetInBlock+int(blockLength) < offset {
+		compressedLength, err := in.ReadUvarint(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := in.SkipBytes(ctx, int(compressedLength)); err != nil {
+			return nil, err
+		}
+		offsetInBlock += int(blockLength)
+		offsetInBytes -= int(blockLength)
+	}
+
+	// Read blocks that intersect with the interval we need
+	blocks := make([]byte, 0, offsetInBytes+length)
+	blocks = append(blocks, dict...)
+	for offsetInBlock < offset+length {
+		if blocks, err = d.doDecompress(ctx, in, dict, blocks); err != nil {
+			return nil, err
+		}
+		offsetInBlock += int(blockLength)
+	}
+
+	if offsetInBytes+length > len(blocks) {
+		return nil, fmt.Errorf("corrupted: lengths mismatch: %d > %d", offsetInBytes+length, len(blocks))
+	}
+	d.buffer = blocks
+	return blocks[offsetInBytes : offsetInBytes+length], nil
+}
+
+// doDecompress inflates the next compressed block of in and appends it to dest.
+func (d *deflateWithPresetDictDecompressor) doDecompress(ctx context.Context, in store.DataInput, dict, dest []byte) ([]byte, error) {
+	compressedLength, err := in.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if compressedLength == 0 {
+		return dest, nil
+	}
+
+	if cap(d.compressed) < int(compressedLength) {
+		d.compressed = make([]byte, compressedLength)
+	}
+	d.compressed = d.compressed[:compressedLength]
+	if _, err := io.ReadFull(in, d.compressed); err != nil {
+		return nil, err
+	}
+
+	reader := flate.NewReaderDict(bytes.NewReader(d.compressed), dict)
+	defer reader.Close()
+
+	buf := bytes.NewBuffer(dest)
+	if _, err := buf.ReadFrom(reader); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (d *deflateWithPresetDictDecompressor) Clone() Decompressor {
+	return &deflateWithPresetDictDecompressor{}
+}
@@ -0,0 +1,55 @@
+package compressing
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/geange/lucene-go/core/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressionModes(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+
+	data := make([]byte, 0, 60*1024)
+	for i := 0; len(data) < 60*1024; i++ {
+		line := fmt.Sprintf("ts=%d level=%s msg=\"request served\" status=%d\n",
+			1700000000+i, []string{"INFO", "WARN", "DEBUG"}[r.Intn(3)], 200+r.Intn(5))
+		data = append(data, line...)
+	}
+
+	for _, mode := range []CompressionMode{FAST, HIGH_COMPRESSION, LZ4_WITH_PRESET_DICT} {
+		t.Run(mode.String(), func(t *testing.T) {
+			out := store.NewBufferDataOutput()
+			assert.Nil(t, mode.NewCompressor().Compress(context.Background(), data, out))
+			assert.Less(t, len(out.Bytes()), len(data)/3)
+
+			decompressor := mode.NewDecompressor()
+			check := func(offset, length int) {
+				in := store.NewBytesInput(out.Bytes())
+				actual, err := decompressor.Decompress(context.Background(), in, len(data), offset, length)
+				assert.Nil(t, err)
+				if length == 0 {
+					assert.Empty(t, actual)
+					return
+				}
+				assert.Equal(t, data[offset:offset+length], actual, "offset=%d length=%d", offset, length)
+			}
+
+			check(0, len(data))
+			check(0, 1)
+			check(len(data)-1, 1)
+			check(len(data)/2, 0)
+			for i := 0; i < 100; i++ {
+				offset := r.Intn(len(data))
+				check(offset, r.Intn(len(data)-offset))
+			}
+
+			_, err := decompressor.Decompress(context.Background(), store.NewBytesInput(out.Bytes()),
+				len(data), len(data), 1)
+			assert.NotNil(t, err)
+		})
+	}
+}
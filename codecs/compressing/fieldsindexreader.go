@@ -0,0 +1,174 @@
+package compressing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/packed"
+)
+
+// FieldsIndexReader Reads the index written by FieldsIndexWriter.
+// lucene.internal
+type FieldsIndexReader struct {
+	maxDoc                    int
+	blockShift                int
+	numChunks                 int
+	docsMeta                  *packed.DirectMonotonicMeta
+	startPointersMeta         *packed.DirectMonotonicMeta
+	docsStartPointer          int64
+	docsEndPointer            int64
+	startPointersStartPointer int64
+	startPointersEndPointer   int64
+	maxPointer                int64
+
+	indexInput    store.IndexInput
+	docs          *packed.DirectMonotonicReader
+	startPointers *packed.DirectMonotonicReader
+}
+
+func NewFieldsIndexReader(ctx context.Context, dir store.Directory, name, suffix, extension, codecName string,
+	id []byte, metaIn store.DataInput) (*FieldsIndexReader, error) {
+
+	f := &FieldsIndexReader{}
+
+	maxDoc, err := metaIn.ReadUint32(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f.maxDoc = int(maxDoc)
+	blockShift, err := metaIn.ReadUint32(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f.blockShift = int(blockShift)
+	numChunks, err := metaIn.ReadUint32(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f.numChunks = int(numChunks)
+	docsStartPointer, err := metaIn.ReadUint64(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f.docsStartPointer = int64(docsStartPointer)
+	if f.docsMeta, err = packed.LoadDirectMonotonicMeta(ctx, metaIn, int64(f.numChunks), f.blockShift); err != nil {
+		return nil, err
+	}
+	docsEndPointer, err := metaIn.ReadUint64(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f.docsEndPointer = int64(docsEndPointer)
+	f.startPointersStartPointer = f.docsEndPointer
+	if f.startPointersMeta, err = packed.LoadDirectMonotonicMeta(ctx, metaIn, int64(f.numChunks), f.blockShift); err != nil {
+		return nil, err
+	}
+	startPointersEndPointer, err := metaIn.ReadUint64(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f.startPointersEndPointer = int64(startPointersEndPointer)
+	maxPointer, err := metaIn.ReadUint64(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f.maxPointer = int64(maxPointer)
+
+	if f.indexInput, err = store.OpenInputWithContext(ctx, dir, store.SegmentFileName(name, suffix, extension), store.READ); err != nil {
+		return nil, err
+	}
+	if err := f.init(ctx, codecName, id, suffix); err != nil {
+		_ = f.indexInput.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FieldsIndexReader) init(ctx context.Context, codecName string, id []byte, suffix string) error {
+	if _, err := utils.CheckIndexHeader(ctx, f.indexInput, codecName+"Idx",
+		FIELDS_INDEX_VERSION_START, FIELDS_INDEX_VERSION_CURRENT, id, suffix); err != nil {
+		return err
+	}
+	if _, err := utils.RetrieveChecksum(f.indexInput); err != nil {
+		return err
+	}
+	return f.initReaders()
+}
+
+func (f *FieldsIndexReader) initReaders() error {
+	docsSlice, err := f.indexInput.RandomAccessSlice(f.docsStartPointer, f.docsEndPointer-f.docsStartPointer)
+	if err != nil {
+		return err
+	}
+	startPointersSlice, err := f.indexInput.RandomAccessSlice(f.startPointersStartPointer,
+		f.startPointersEndPointer-f.startPointersStartPointer)
+	if err != nil {
+		return err
+	}
+	if f.docs, err = packed.NewDirectMonotonicReader(f.docsMeta, docsSlice); err != nil {
+		return err
+	}
+	f.startPointers, err = packed.NewDirectMonotonicReader(f.startPointersMeta, startPointersSlice)
+	return err
+}
+
+// GetStartPointer Returns the start pointer of the block that contains docID.
+func (f *FieldsIndexReader) GetStartPointer(docID int) (int64, error) {
+	if docID < 0 || docID >= f.maxDoc {
+		return 0, fmt.Errorf("docID out of range [0-%d): %d", f.maxDoc, docID)
+	}
+
+	if f.docs == nil {
+		if err := f.initReaders(); err != nil {
+			return 0, err
+		}
+	}
+
+	// the last block whose first doc ID is <= docID
+	var err error
+	blockIndex := sort.Search(f.numChunks, func(i int) bool {
+		if err != nil {
+			return true
+		}
+		doc, getErr := f.docs.Get(int64(i))
+		if getErr != nil {
+			err = getErr
+			return true
+		}
+		return int(doc) > docID
+	}) - 1
+	if err != nil {
+		return 0, err
+	}
+
+	startPointer, err := f.startPointers.Get(int64(blockIndex))
+	if err != nil {
+		return 0, err
+	}
+	return int64(startPointer), nil
+}
+
+// GetMaxPointer Returns the end of the data file.
+func (f *FieldsIndexReader) GetMaxPointer() int64 {
+	return f.maxPointer
+}
+
+// Clone Returns a reader on a clone of the index file, its readers are created on first use.
+func (f *FieldsIndexReader) Clone() *FieldsIndexReader {
+	clone := *f
+	clone.indexInput = f.indexInput.Clone().(store.IndexInput)
+	clone.docs, clone.startPointers = nil, nil
+	return &clone
+}
+
+func (f *FieldsIndexReader) CheckIntegrity() error {
+	_, err := utils.ChecksumEntireFile(f.indexInput)
+	return err
+}
+
+func (f *FieldsIndexReader) Close() error {
+	return f.indexInput.Close()
+}
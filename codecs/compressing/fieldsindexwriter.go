@@ -0,0 +1,156 @@
+package compressing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/packed"
+)
+
+const (
+	FIELDS_INDEX_VERSION_START   = 0
+	FIELDS_INDEX_VERSION_CURRENT = FIELDS_INDEX_VERSION_START
+)
+
+// FieldsIndexWriter Efficient index format for block-based Codecs.
+// For each block of compressed stored fields, this stores the first document of the block and the
+// start pointer of the block in a DirectMonotonicWriter. At read time, the docID is binary-searched
+// in the DirectMonotonicReader that records doc IDs, and the returned index is used to look up the
+// start pointer in the DirectMonotonicReader that records start pointers.
+//
+// The number of documents and the start pointer of every block are buffered until Finish, since
+// the number of blocks must be known to write the monotonic values.
+// lucene.internal
+type FieldsIndexWriter struct {
+	dir        store.Directory
+	name       string
+	suffix     string
+	extension  string
+	codecName  string
+	id         []byte
+	blockShift int
+	ioContext  *store.IOContext
+
+	chunkDocs     []int
+	startPointers []int64
+	totalDocs     int
+	previousFP    int64
+}
+
+func NewFieldsIndexWriter(dir store.Directory, name, suffix, extension, codecName string, id []byte,
+	blockShift int, ioContext *store.IOContext) *FieldsIndexWriter {
+
+	return &FieldsIndexWriter{
+		dir:        dir,
+		name:       name,
+		suffix:     suffix,
+		extension:  extension,
+		codecName:  codecName,
+		id:         id,
+		blockShift: blockShift,
+		ioContext:  ioContext,
+	}
+}
+
+// WriteIndex Records a block of numDocs documents that starts at startPointer.
+func (f *FieldsIndexWriter) WriteIndex(numDocs int, startPointer int64) error {
+	if startPointer < f.previousFP {
+		return fmt.Errorf("start pointers must be increasing: %d < %d", startPointer, f.previousFP)
+	}
+	f.chunkDocs = append(f.chunkDocs, numDocs)
+	f.startPointers = append(f.startPointers, startPointer)
+	f.previousFP = startPointer
+	f.totalDocs += numDocs
+	return nil
+}
+
+// Finish Writes the index file and its metadata to metaOut. maxPointer is the end of the data
+// file, it is recorded as the last start pointer.
+func (f *FieldsIndexWriter) Finish(ctx context.Context, numDocs int, maxPointer int64, metaOut store.IndexOutput) error {
+	if numDocs != f.totalDocs {
+		return fmt.Errorf("expected %d docs, but got %d", numDocs, f.totalDocs)
+	}
+	if maxPointer < f.previousFP {
+		return fmt.Errorf("maxPointer(%d) is less than the last start pointer(%d)", maxPointer, f.previousFP)
+	}
+
+	dataOut, err := store.CreateOutputWithContext(ctx, f.dir, store.SegmentFileName(f.name, f.suffix, f.extension), f.ioContext)
+	if err != nil {
+		return err
+	}
+	if err := f.write(ctx, numDocs, maxPointer, metaOut, dataOut); err != nil {
+		_ = dataOut.Close()
+		return err
+	}
+	return dataOut.Close()
+}
+
+func (f *FieldsIndexWriter) write(ctx context.Context, numDocs int, maxPointer int64, metaOut, dataOut store.IndexOutput) error {
+	totalChunks := len(f.chunkDocs)
+	if err := utils.WriteIndexHeader(ctx, dataOut, f.codecName+"Idx", FIELDS_INDEX_VERSION_CURRENT, f.id, f.suffix); err != nil {
+		return err
+	}
+
+	if err := metaOut.WriteUint32(ctx, uint32(numDocs)); err != nil {
+		return err
+	}
+	if err := metaOut.WriteUint32(ctx, uint32(f.blockShift)); err != nil {
+		return err
+	}
+	if err := metaOut.WriteUint32(ctx, uint32(totalChunks+1)); err != nil {
+		return err
+	}
+	if err := metaOut.WriteUint64(ctx, uint64(dataOut.GetFilePointer())); err != nil {
+		return err
+	}
+
+	// the first doc ID of every block, followed by the number of documents
+	docs, err := packed.NewDirectMonotonicWriter(metaOut, dataOut, int64(totalChunks+1), f.blockShift)
+	if err != nil {
+		return err
+	}
+	doc := int64(0)
+	if err := docs.Add(ctx, doc); err != nil {
+		return err
+	}
+	for _, chunkDocs := range f.chunkDocs {
+		doc += int64(chunkDocs)
+		if err := docs.Add(ctx, doc); err != nil {
+			return err
+		}
+	}
+	if err := docs.Finish(ctx); err != nil {
+		return err
+	}
+
+	if err := metaOut.WriteUint64(ctx, uint64(dataOut.GetFilePointer())); err != nil {
+		return err
+	}
+
+	// the start pointer of every block, followed by maxPointer
+	filePointers, err := packed.NewDirectMonotonicWriter(metaOut, dataOut, int64(totalChunks+1), f.blockShift)
+	if err != nil {
+		return err
+	}
+	for _, fp := range f.startPointers {
+		if err := filePointers.Add(ctx, fp); err != nil {
+			return err
+		}
+	}
+	if err := filePointers.Add(ctx, maxPointer); err != nil {
+		return err
+	}
+	if err := filePointers.Finish(ctx); err != nil {
+		return err
+	}
+
+	if err := metaOut.WriteUint64(ctx, uint64(dataOut.GetFilePointer())); err != nil {
+		return err
+	}
+	if err := metaOut.WriteUint64(ctx, uint64(maxPointer)); err != nil {
+		return err
+	}
+	return utils.WriteFooter(dataOut)
+}
@@ -0,0 +1,80 @@
+package compressing
+
+import (
+	"context"
+	"io"
+
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/packed"
+)
+
+// writePackedInts Writes values with bitsPerValue bits each in the PACKED format, without header.
+// Exactly FormatPacked.ByteCount bytes are written.
+func writePackedInts(out store.DataOutput, values []int, bitsPerValue int) error {
+	encoder, err := packed.GetEncoder(packed.FormatPacked, packed.VERSION_CURRENT, bitsPerValue)
+	if err != nil {
+		return err
+	}
+	iterations := (len(values) + encoder.ByteValueCount() - 1) / encoder.ByteValueCount()
+	buffer := make([]uint64, iterations*encoder.ByteValueCount())
+	for i, v := range values {
+		buffer[i] = uint64(v)
+	}
+	blocks := make([]byte, iterations*encoder.ByteBlockCount())
+	encoder.EncodeBytes(buffer, blocks, iterations)
+	_, err = out.Write(blocks[:packed.FormatPacked.ByteCount(packed.VERSION_CURRENT, len(values), bitsPerValue)])
+	return err
+}
+
+// readPackedInts Reads count values written by writePackedInts.
+func readPackedInts(in store.DataInput, packedIntsVersion, count, bitsPerValue int) ([]int, error) {
+	values := make([]int, count)
+	if count == 0 {
+		return values, nil
+	}
+	decoder, err := packed.GetDecoder(packed.FormatPacked, packedIntsVersion, bitsPerValue)
+	if err != nil {
+		return nil, err
+	}
+	iterations := (count + decoder.ByteValueCount() - 1) / decoder.ByteValueCount()
+	blocks := make([]byte, iterations*decoder.ByteBlockCount())
+	byteCount := packed.FormatPacked.ByteCount(packedIntsVersion, count, bitsPerValue)
+	if _, err := io.ReadFull(in, blocks[:byteCount]); err != nil {
+		return nil, err
+	}
+	decoded := make([]uint64, iterations*decoder.ByteValueCount())
+	decoder.DecodeBytes(blocks, decoded, iterations)
+	for i := range values {
+		values[i] = int(decoded[i])
+	}
+	return values, nil
+}
+
+// writeBlockPacked Writes values with a BlockPackedWriter. The number of values is not written, the
+// reader knows it from the values it decoded before.
+func writeBlockPacked(ctx context.Context, out store.DataOutput, values []int) error {
+	writer := packed.NewBlockPackedWriter(out, PACKED_BLOCK_SIZE)
+	for _, v := range values {
+		if err := writer.Add(ctx, uint64(v)); err != nil {
+			return err
+		}
+	}
+	return writer.Finish(ctx)
+}
+
+// readBlockPacked Reads count values written by writeBlockPacked.
+func readBlockPacked(ctx context.Context, in store.DataInput, packedIntsVersion, count int) ([]int, error) {
+	values := make([]int, count)
+	if count == 0 {
+		return values, nil
+	}
+	it := packed.NewBlockPackedReaderIterator(in, packedIntsVersion, PACKED_BLOCK_SIZE, count)
+	for i := range values {
+		v, err := it.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = int(int64(v))
+	}
+	return values, nil
+}
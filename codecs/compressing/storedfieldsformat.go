@@ -0,0 +1,93 @@
+package compressing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/packed"
+)
+
+var _ index.StoredFieldsFormat = &StoredFieldsFormat{}
+
+// StoredFieldsFormat A StoredFieldsFormat that compresses documents in chunks in order to
+// improve the compression ratio.
+// For a chunk size of chunkSize bytes, this StoredFieldsFormat does not support documents larger
+// than (2^31 - chunkSize) bytes.
+// For optimal performance, you should use a MergePolicy that returns segments that have the
+// biggest byte size first.
+// lucene.experimental
+type StoredFieldsFormat struct {
+	formatName      string
+	segmentSuffix   string
+	compressionMode CompressionMode
+	chunkSize       int
+	maxDocsPerChunk int
+	blockShift      int
+}
+
+// NewStoredFieldsFormat Create a new StoredFieldsFormat.
+//
+// formatName is the name of the format. This name will be used in the file formats to perform
+// codec header checks.
+//
+// The compressionMode parameter allows you to choose between compression algorithms that have
+// various compression and decompression speeds so that you can pick the one that best fits your
+// indexing and searching throughput. You should never instantiate two StoredFieldsFormats that
+// have the same name but different CompressionModes.
+//
+// chunkSize is the minimum byte size of a chunk of documents. A value of 1 can make sense if
+// there is redundancy across fields. maxDocsPerChunk is an upperbound on how many docs may be
+// stored in a single chunk. This is to bound the cpu costs for highly compressible data.
+//
+// blockShift is the log in base 2 of number of chunks to store in an index block, it must be
+// between DIRECT_MONOTONIC_MIN_BLOCK_SHIFT and DIRECT_MONOTONIC_MAX_BLOCK_SHIFT.
+//
+// Higher values of chunkSize should improve the compression ratio but will require more memory
+// at indexing time and might make document loading a little slower (depending on the size of
+// your OS cache compared to the size of your index).
+func NewStoredFieldsFormat(formatName, segmentSuffix string, compressionMode CompressionMode,
+	chunkSize, maxDocsPerChunk, blockShift int) (*StoredFieldsFormat, error) {
+
+	if compressionMode == nil {
+		return nil, errors.New("compressionMode must not be nil")
+	}
+	if chunkSize < 1 {
+		return nil, errors.New("chunkSize must be >= 1")
+	}
+	if maxDocsPerChunk < 1 {
+		return nil, errors.New("maxDocsPerChunk must be >= 1")
+	}
+	if blockShift < packed.DIRECT_MONOTONIC_MIN_BLOCK_SHIFT || blockShift > packed.DIRECT_MONOTONIC_MAX_BLOCK_SHIFT {
+		return nil, fmt.Errorf("blockSize must be in %d-%d, got %d",
+			packed.DIRECT_MONOTONIC_MIN_BLOCK_SHIFT, packed.DIRECT_MONOTONIC_MAX_BLOCK_SHIFT, blockShift)
+	}
+	return &StoredFieldsFormat{
+		formatName:      formatName,
+		segmentSuffix:   segmentSuffix,
+		compressionMode: compressionMode,
+		chunkSize:       chunkSize,
+		maxDocsPerChunk: maxDocsPerChunk,
+		blockShift:      blockShift,
+	}, nil
+}
+
+func (s *StoredFieldsFormat) FieldsReader(ctx context.Context, directory store.Directory,
+	si index.SegmentInfo, fn index.FieldInfos, ioContext *store.IOContext) (index.StoredFieldsReader, error) {
+
+	return NewStoredFieldsReader(ctx, directory, si, s.segmentSuffix, fn, ioContext, s.formatName, s.compressionMode)
+}
+
+func (s *StoredFieldsFormat) FieldsWriter(ctx context.Context, directory store.Directory,
+	si index.SegmentInfo, ioContext *store.IOContext) (index.StoredFieldsWriter, error) {
+
+	return NewStoredFieldsWriter(ctx, directory, si, s.segmentSuffix, ioContext, s.formatName, s.compressionMode,
+		s.chunkSize, s.maxDocsPerChunk, s.blockShift)
+}
+
+func (s *StoredFieldsFormat) String() string {
+	return fmt.Sprintf("StoredFieldsFormat(compressionMode=%s, chunkSize=%d, maxDocsPerChunk=%d, blockShift=%d)",
+		s.compressionMode, s.chunkSize, s.maxDocsPerChunk, s.blockShift)
+}
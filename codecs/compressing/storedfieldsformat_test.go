@@ -0,0 +1,179 @@
+package compressing
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZFloat(t *testing.T) {
+	ctx := context.Background()
+	check := func(f float32, size int) {
+		out := store.NewBufferDataOutput()
+		assert.Nil(t, writeZFloat(ctx, out, f))
+		assert.Len(t, out.Bytes(), size, "%v", f)
+
+		actual, err := readZFloat(ctx, store.NewBytesInput(out.Bytes()))
+		assert.Nil(t, err)
+		assert.Equal(t, math.Float32bits(f), math.Float32bits(actual), "%v", f)
+	}
+
+	// small integers take a single byte
+	for i := -1; i <= 125; i++ {
+		check(float32(i), 1)
+	}
+	check(126, 4)
+	check(-2, 5)
+	check(float32(math.Copysign(0, -1)), 5)
+	check(0.5, 4)
+	check(-0.5, 5)
+	check(math.MaxFloat32, 4)
+	check(-math.MaxFloat32, 5)
+	check(math.SmallestNonzeroFloat32, 4)
+	check(float32(math.Inf(1)), 4)
+	check(float32(math.Inf(-1)), 5)
+}
+
+func TestZDouble(t *testing.T) {
+	ctx := context.Background()
+	check := func(d float64, size int) {
+		out := store.NewBufferDataOutput()
+		assert.Nil(t, writeZDouble(ctx, out, d))
+		assert.Len(t, out.Bytes(), size, "%v", d)
+
+		actual, err := readZDouble(ctx, store.NewBytesInput(out.Bytes()))
+		assert.Nil(t, err)
+		assert.Equal(t, math.Float64bits(d), math.Float64bits(actual), "%v", d)
+	}
+
+	// small integers take a single byte
+	for i := -1; i <= 124; i++ {
+		check(float64(i), 1)
+	}
+	// values that are exact floats take five bytes
+	check(125, 5)
+	check(-2, 5)
+	check(0.5, 5)
+	check(math.Copysign(0, -1), 5)
+	check(math.Inf(1), 5)
+	check(0.1, 8)
+	check(-0.1, 9)
+	check(math.MaxFloat64, 8)
+	check(-math.MaxFloat64, 9)
+	check(math.SmallestNonzeroFloat64, 8)
+}
+
+func TestTLong(t *testing.T) {
+	ctx := context.Background()
+	check := func(l int64, size int) {
+		out := store.NewBufferDataOutput()
+		assert.Nil(t, writeTLong(ctx, out, l))
+		assert.Len(t, out.Bytes(), size, "%d", l)
+
+		actual, err := readTLong(ctx, store.NewBytesInput(out.Bytes()))
+		assert.Nil(t, err)
+		assert.Equal(t, l, actual)
+	}
+
+	// the zigzag encoded value is stored in the header when it fits in 5 bits
+	for i := int64(-16); i < 16; i++ {
+		check(i, 1)
+		check(i*SECOND, 1)
+		check(i*HOUR, 1)
+		check(i*DAY, 1)
+	}
+	check(16, 2)
+	check(-17, 2)
+	check(SECOND+1, 2)
+	check(1700000000000, 5) // a timestamp with second precision
+	check(1700000000001, 7)
+	check(math.MaxInt64, 10)
+	check(math.MinInt64, 10)
+}
+
+// testIDVisitor collects the values of the "id" string field
+type testIDVisitor struct {
+	ids []string
+}
+
+func (v *testIDVisitor) BinaryField(fieldInfo *document.FieldInfo, value []byte) error {
+	return nil
+}
+
+func (v *testIDVisitor) Int32Field(fieldInfo *document.FieldInfo, value int32) error {
+	return nil
+}
+
+func (v *testIDVisitor) Int64Field(fieldInfo *document.FieldInfo, value int64) error {
+	return nil
+}
+
+func (v *testIDVisitor) Float32Field(fieldInfo *document.FieldInfo, value float32) error {
+	return nil
+}
+
+func (v *testIDVisitor) Float64Field(fieldInfo *document.FieldInfo, value float64) error {
+	return nil
+}
+
+func (v *testIDVisitor) StringField(fieldInfo *document.FieldInfo, value []byte) error {
+	v.ids = append(v.ids, string(value))
+	return nil
+}
+
+func (v *testIDVisitor) NeedsField(fieldInfo *document.FieldInfo) (document.STORED_FIELD_VISITOR_STATUS, error) {
+	return document.STORED_FIELD_VISITOR_YES, nil
+}
+
+func TestStoredFieldsFormat_DirtyChunks(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	info := document.NewFieldInfo("id", 0, false, true, false,
+		document.INDEX_OPTIONS_NONE, document.DOC_VALUES_TYPE_NONE, -1,
+		map[string]string{}, 0, 0, 0, false)
+	fieldInfos := coreIndex.NewFieldInfos([]*document.FieldInfo{info})
+
+	// 6 full chunks of 16 docs and a last chunk of 4 docs flushed by Finish
+	numDocs := 100
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", numDocs, false, nil,
+		map[string]string{}, []byte("0123456789abcdef"), map[string]string{}, nil)
+	format, err := NewStoredFieldsFormat("TestStoredFields", "", FAST, 1<<20, 16, 10)
+	assert.Nil(t, err)
+
+	writer, err := format.FieldsWriter(ctx, dir, si, store.DEFAULT)
+	assert.Nil(t, err)
+	for i := 0; i < numDocs; i++ {
+		assert.Nil(t, writer.StartDocument(ctx))
+		assert.Nil(t, writer.WriteField(ctx, info, document.NewStoredField("id", strconv.Itoa(i))))
+		assert.Nil(t, writer.FinishDocument(ctx))
+	}
+	assert.Nil(t, writer.Finish(ctx, fieldInfos, numDocs))
+	assert.Nil(t, writer.Close())
+
+	reader, err := format.FieldsReader(ctx, dir, si, fieldInfos, store.DEFAULT)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer reader.Close()
+
+	fieldsReader := reader.(*StoredFieldsReader)
+	assert.Equal(t, VERSION_CURRENT, fieldsReader.version)
+	assert.Equal(t, int64(7), fieldsReader.numChunks)
+	assert.Equal(t, int64(1), fieldsReader.numDirtyChunks)
+	assert.Equal(t, int64(4), fieldsReader.numDirtyDocs)
+
+	for _, doc := range []int{0, 15, 16, 95, 96, 99} {
+		visitor := &testIDVisitor{}
+		assert.Nil(t, reader.VisitDocument(ctx, doc, visitor))
+		assert.Equal(t, []string{strconv.Itoa(doc)}, visitor.ids)
+	}
+}
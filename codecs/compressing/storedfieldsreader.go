@@ -0,0 +1,530 @@
+package compressing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+)
+
+var _ index.StoredFieldsReader = &StoredFieldsReader{}
+
+// StoredFieldsReader StoredFieldsReader impl for CompressingStoredFieldsFormat.
+// lucene.experimental
+type StoredFieldsReader struct {
+	version           int
+	fieldInfos        index.FieldInfos
+	indexReader       *FieldsIndexReader
+	maxPointer        int64
+	fieldsStream      store.IndexInput
+	chunkSize         int
+	packedIntsVersion int
+	numDocs           int
+
+	compressionMode CompressionMode
+	decompressor    Decompressor
+
+	numChunks      int64 // number of written blocks
+	numDirtyChunks int64 // number of incomplete compressed blocks written
+	numDirtyDocs   int64 // cumulative number of missing docs in incomplete chunks
+
+	closed bool
+}
+
+// NewStoredFieldsReader Sole constructor.
+func NewStoredFieldsReader(ctx context.Context, directory store.Directory, si index.SegmentInfo, segmentSuffix string,
+	fn index.FieldInfos, ioContext *store.IOContext, formatName string, compressionMode CompressionMode) (*StoredFieldsReader, error) {
+
+	reader := &StoredFieldsReader{
+		fieldInfos:      fn,
+		compressionMode: compressionMode,
+		decompressor:    compressionMode.NewDecompressor(),
+	}
+
+	closeOnError := func(err error) (*StoredFieldsReader, error) {
+		_ = reader.Close()
+		return nil, err
+	}
+
+	segment := si.Name()
+	segmentID := si.GetID()
+	maxDoc, err := si.MaxDoc()
+	if err != nil {
+		return nil, err
+	}
+	reader.numDocs = maxDoc
+
+	fieldsStreamFN := store.SegmentFileName(segment, segmentSuffix, FIELDS_EXTENSION)
+	if reader.fieldsStream, err = store.OpenInputWithContext(ctx, directory, fieldsStreamFN, ioContext); err != nil {
+		return nil, err
+	}
+	if reader.version, err = utils.CheckIndexHeader(ctx, reader.fieldsStream, formatName,
+		VERSION_START, VERSION_CURRENT, segmentID, segmentSuffix); err != nil {
+		return closeOnError(err)
+	}
+	if reader.version < VERSION_META {
+		return closeOnError(fmt.Errorf("IndexFormatTooOld: codec=%s version=%d (needs to be between %d and %d)",
+			formatName, reader.version, VERSION_META, VERSION_CURRENT))
+	}
+
+	metaStreamFN := store.SegmentFileName(segment, segmentSuffix, META_EXTENSION)
+	metaIn, err := store.OpenChecksumInput(directory, metaStreamFN)
+	if err != nil {
+		return closeOnError(err)
+	}
+	defer metaIn.Close()
+
+	if _, err := utils.CheckIndexHeader(ctx, metaIn, INDEX_CODEC_NAME+"Meta",
+		META_VERSION_START, reader.version, segmentID, segmentSuffix); err != nil {
+		return closeOnError(err)
+	}
+
+	chunkSize, err := metaIn.ReadUvarint(ctx)
+	if err != nil {
+		return closeOnError(err)
+	}
+	reader.chunkSize = int(chunkSize)
+	packedIntsVersion, err := metaIn.ReadUvarint(ctx)
+	if err != nil {
+		return closeOnError(err)
+	}
+	reader.packedIntsVersion = int(packedIntsVersion)
+
+	// NOTE: data file is too costly to verify checksum against all the bytes on open,
+	// but for now we at least verify proper structure of the checksum footer: which looks
+	// for FOOTER_MAGIC + algorithmID. This is cheap and can detect some forms of corruption
+	// such as file truncation.
+	if _, err := utils.RetrieveChecksum(reader.fieldsStream); err != nil {
+		return closeOnError(err)
+	}
+
+	if reader.indexReader, err = NewFieldsIndexReader(ctx, directory, segment, segmentSuffix, INDEX_EXTENSION,
+		INDEX_CODEC_NAME, segmentID, metaIn); err != nil {
+		return closeOnError(err)
+	}
+	if reader.indexReader.maxDoc != maxDoc {
+		return closeOnError(fmt.Errorf("doc counts differ for segment %s: fieldsReader shows %d but segmentInfo shows %d",
+			segment, reader.indexReader.maxDoc, maxDoc))
+	}
+	reader.maxPointer = reader.indexReader.GetMaxPointer()
+
+	if reader.version >= VERSION_NUM_CHUNKS {
+		numChunks, err := metaIn.ReadUvarint(ctx)
+		if err != nil {
+			return closeOnError(err)
+		}
+		reader.numChunks = int64(numChunks)
+	}
+	numDirtyChunks, err := metaIn.ReadUvarint(ctx)
+	if err != nil {
+		return closeOnError(err)
+	}
+	reader.numDirtyChunks = int64(numDirtyChunks)
+	numDirtyDocs, err := metaIn.ReadUvarint(ctx)
+	if err != nil {
+		return closeOnError(err)
+	}
+	reader.numDirtyDocs = int64(numDirtyDocs)
+
+	if _, err := utils.CheckCodecFooter(metaIn); err != nil {
+		return closeOnError(err)
+	}
+
+	if reader.maxPointer+int64(utils.FooterLength()) != reader.fieldsStream.Length() {
+		return closeOnError(fmt.Errorf("invalid fieldsStream maxPointer (file truncated?): maxPointer=%d, length=%d",
+			reader.maxPointer, reader.fieldsStream.Length()))
+	}
+	return reader, nil
+}
+
+func (s *StoredFieldsReader) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	var errs []error
+	if s.indexReader != nil {
+		errs = append(errs, s.indexReader.Close())
+	}
+	if s.fieldsStream != nil {
+		errs = append(errs, s.fieldsStream.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (s *StoredFieldsReader) VisitDocument(ctx context.Context, docID int, visitor document.StoredFieldVisitor) error {
+	if docID < 0 || docID >= s.numDocs {
+		return fmt.Errorf("docID must be >= 0 and < maxDoc=%d (got docID=%d)", s.numDocs, docID)
+	}
+
+	startPointer, err := s.indexReader.GetStartPointer(docID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.fieldsStream.Seek(startPointer, io.SeekStart); err != nil {
+		return err
+	}
+
+	n, err := s.fieldsStream.ReadUvarint(ctx)
+	if err != nil {
+		return err
+	}
+	docBase := int(n)
+	token, err := s.fieldsStream.ReadUvarint(ctx)
+	if err != nil {
+		return err
+	}
+	chunkDocs := int(token >> 1)
+	if s.version >= VERSION_NUM_CHUNKS {
+		chunkDocs = int(token >> 2)
+	}
+	sliced := token&1 != 0
+	if docID < docBase || docID >= docBase+chunkDocs || docBase+chunkDocs > s.numDocs {
+		return fmt.Errorf("corrupted: docBase=%d, chunkDocs=%d, numDocs=%d", docBase, chunkDocs, s.numDocs)
+	}
+
+	// number of stored fields per document
+	numStoredFields, err := s.readInts(ctx, chunkDocs)
+	if err != nil {
+		return err
+	}
+	// the length of each document
+	lengths, err := s.readInts(ctx, chunkDocs)
+	if err != nil {
+		return err
+	}
+
+	docIndex := docID - docBase
+	offset, totalLength := 0, 0
+	for i, length := range lengths {
+		if i < docIndex {
+			offset += length
+		}
+		totalLength += length
+	}
+	length := lengths[docIndex]
+
+	if length == 0 {
+		if numStoredFields[docIndex] != 0 {
+			return errors.New("corrupted: stored fields without bytes")
+		}
+		return nil
+	}
+
+	var data []byte
+	if sliced {
+		data, err = s.readSliced(ctx, offset, length)
+	} else {
+		data, err = s.decompressor.Decompress(ctx, s.fieldsStream, totalLength, offset, length)
+	}
+	if err != nil {
+		return err
+	}
+
+	in := store.NewBytesInput(data)
+	for fieldIDX := 0; fieldIDX < numStoredFields[docIndex]; fieldIDX++ {
+		infoAndBits, err := in.ReadUvarint(ctx)
+		if err != nil {
+			return err
+		}
+		fieldNumber := int(infoAndBits >> TYPE_BITS)
+		fieldInfo := s.fieldInfos.FieldInfoByNumber(fieldNumber)
+		if fieldInfo == nil {
+			return fmt.Errorf("corrupted: unknown field number %d", fieldNumber)
+		}
+		bits := int(infoAndBits & TYPE_MASK)
+
+		status, err := visitor.NeedsField(fieldInfo)
+		if err != nil {
+			return err
+		}
+		switch status {
+		case document.STORED_FIELD_VISITOR_YES:
+			if err := readField(ctx, in, visitor, fieldInfo, bits); err != nil {
+				return err
+			}
+		case document.STORED_FIELD_VISITOR_NO:
+			if fieldIDX == numStoredFields[docIndex]-1 {
+				// don't skip the last field
+				return nil
+			}
+			if err := skipField(ctx, in, bits); err != nil {
+				return err
+			}
+		case document.STORED_FIELD_VISITOR_STOP:
+			return nil
+		}
+	}
+	return nil
+}
+
+// readSliced Reads the bytes of a document from a sliced chunk, whose slices of chunkSize bytes
+// were compressed independently. Such chunks end with the large document that made them big, so
+// documents always start in the first slice.
+func (s *StoredFieldsReader) readSliced(ctx context.Context, offset, length int) ([]byte, error) {
+	if offset >= s.chunkSize {
+		return nil, fmt.Errorf("corrupted: document at offset %d of a sliced chunk of %d bytes slices", offset, s.chunkSize)
+	}
+
+	data, err := s.decompressor.Decompress(ctx, s.fieldsStream, s.chunkSize, offset, min(length, s.chunkSize-offset))
+	if err != nil {
+		return nil, err
+	}
+	doc := append(make([]byte, 0, length), data...)
+	for len(doc) < length {
+		toDecompress := min(length-len(doc), s.chunkSize)
+		data, err := s.decompressor.Decompress(ctx, s.fieldsStream, toDecompress, 0, toDecompress)
+		if err != nil {
+			return nil, err
+		}
+		doc = append(doc, data...)
+	}
+	return doc, nil
+}
+
+// readInts reads count values written by saveInts.
+func (s *StoredFieldsReader) readInts(ctx context.Context, count int) ([]int, error) {
+	values := make([]int, count)
+	if count == 1 {
+		value, err := s.fieldsStream.ReadUvarint(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values[0] = int(value)
+		return values, nil
+	}
+
+	bitsPerValue, err := s.fieldsStream.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if bitsPerValue == 0 {
+		value, err := s.fieldsStream.ReadUvarint(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i := range values {
+			values[i] = int(value)
+		}
+		return values, nil
+	}
+	if bitsPerValue > 31 {
+		return nil, fmt.Errorf("corrupted: bitsPerValue=%d", bitsPerValue)
+	}
+
+	return readPackedInts(s.fieldsStream, s.packedIntsVersion, count, int(bitsPerValue))
+}
+
+func readField(ctx context.Context, in *store.BytesInput, visitor document.StoredFieldVisitor,
+	info *document.FieldInfo, bits int) error {
+
+	switch bits & TYPE_MASK {
+	case BYTE_ARR:
+		length, err := in.ReadUvarint(ctx)
+		if err != nil {
+			return err
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(in, data); err != nil {
+			return err
+		}
+		return visitor.BinaryField(info, data)
+	case STRING:
+		value, err := in.ReadString(ctx)
+		if err != nil {
+			return err
+		}
+		return visitor.StringField(info, []byte(value))
+	case NUMERIC_INT:
+		value, err := in.ReadZInt32(ctx)
+		if err != nil {
+			return err
+		}
+		return visitor.Int32Field(info, int32(value))
+	case NUMERIC_FLOAT:
+		value, err := readZFloat(ctx, in)
+		if err != nil {
+			return err
+		}
+		return visitor.Float32Field(info, value)
+	case NUMERIC_LONG:
+		value, err := readTLong(ctx, in)
+		if err != nil {
+			return err
+		}
+		return visitor.Int64Field(info, value)
+	case NUMERIC_DOUBLE:
+		value, err := readZDouble(ctx, in)
+		if err != nil {
+			return err
+		}
+		return visitor.Float64Field(info, value)
+	default:
+		return fmt.Errorf("unknown type flag: %x", bits)
+	}
+}
+
+func skipField(ctx context.Context, in *store.BytesInput, bits int) error {
+	switch bits & TYPE_MASK {
+	case BYTE_ARR, STRING:
+		length, err := in.ReadUvarint(ctx)
+		if err != nil {
+			return err
+		}
+		return in.SkipBytes(ctx, int(length))
+	case NUMERIC_INT:
+		_, err := in.ReadZInt32(ctx)
+		return err
+	case NUMERIC_FLOAT:
+		_, err := readZFloat(ctx, in)
+		return err
+	case NUMERIC_LONG:
+		_, err := readTLong(ctx, in)
+		return err
+	case NUMERIC_DOUBLE:
+		_, err := readZDouble(ctx, in)
+		return err
+	default:
+		return fmt.Errorf("unknown type flag: %x", bits)
+	}
+}
+
+// readZFloat Reads a float in a variable-length format. Reads between one and five bytes. Small
+// integral values typically take fewer bytes.
+func readZFloat(ctx context.Context, in store.DataInput) (float32, error) {
+	b, err := in.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b == 0xFF {
+		// negative value
+		bits, err := in.ReadUint32(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return math.Float32frombits(bits), nil
+	}
+	if b&0x80 != 0 {
+		// small integer [-1..125]
+		return float32(int(b&0x7f) - 1), nil
+	}
+	// positive float
+	high, err := in.ReadUint16(ctx)
+	if err != nil {
+		return 0, err
+	}
+	low, err := in.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	return math.Float32frombits(uint32(b)<<24 | uint32(high)<<8 | uint32(low)), nil
+}
+
+// readZDouble Reads a double in a variable-length format. Reads between one and nine bytes.
+// Small integral values typically take fewer bytes.
+func readZDouble(ctx context.Context, in store.DataInput) (float64, error) {
+	b, err := in.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case b == 0xFF:
+		// negative value
+		bits, err := in.ReadUint64(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return math.Float64frombits(bits), nil
+	case b == 0xFE:
+		// float
+		bits, err := in.ReadUint32(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return float64(math.Float32frombits(bits)), nil
+	case b&0x80 != 0:
+		// small integer [-1..124]
+		return float64(int(b&0x7f) - 1), nil
+	}
+	// positive double
+	high, err := in.ReadUint32(ctx)
+	if err != nil {
+		return 0, err
+	}
+	middle, err := in.ReadUint16(ctx)
+	if err != nil {
+		return 0, err
+	}
+	low, err := in.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(uint64(b)<<56 | uint64(high)<<24 | uint64(middle)<<8 | uint64(low)), nil
+}
+
+// readTLong Reads a long in a variable-length format. Reads between one and ten bytes. Small
+// values or values representing timestamps with day, hour or second precision typically require
+// fewer bytes.
+func readTLong(ctx context.Context, in store.DataInput) (int64, error) {
+	header, err := in.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	bits := uint64(header & 0x1F)
+	if header&0x20 != 0 {
+		// continuation bit
+		upperBits, err := in.ReadUvarint(ctx)
+		if err != nil {
+			return 0, err
+		}
+		bits |= upperBits << 5
+	}
+
+	l := int64(bits>>1) ^ -int64(bits&1)
+	switch header & DAY_ENCODING {
+	case SECOND_ENCODING:
+		l *= SECOND
+	case HOUR_ENCODING:
+		l *= HOUR
+	case DAY_ENCODING:
+		l *= DAY
+	}
+	return l, nil
+}
+
+func (s *StoredFieldsReader) Clone(ctx context.Context) index.StoredFieldsReader {
+	return &StoredFieldsReader{
+		version:           s.version,
+		fieldInfos:        s.fieldInfos,
+		indexReader:       s.indexReader.Clone(),
+		maxPointer:        s.maxPointer,
+		fieldsStream:      s.fieldsStream.Clone().(store.IndexInput),
+		chunkSize:         s.chunkSize,
+		packedIntsVersion: s.packedIntsVersion,
+		numDocs:           s.numDocs,
+		compressionMode:   s.compressionMode,
+		decompressor:      s.decompressor.Clone(),
+		numChunks:         s.numChunks,
+		numDirtyChunks:    s.numDirtyChunks,
+		numDirtyDocs:      s.numDirtyDocs,
+	}
+}
+
+func (s *StoredFieldsReader) CheckIntegrity() error {
+	if err := s.indexReader.CheckIntegrity(); err != nil {
+		return err
+	}
+	_, err := utils.ChecksumEntireFile(s.fieldsStream)
+	return err
+}
+
+func (s *StoredFieldsReader) GetMergeInstance() index.StoredFieldsReader {
+	return s
+}
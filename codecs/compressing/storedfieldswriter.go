@@ -0,0 +1,504 @@
+package compressing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/packed"
+)
+
+const (
+	// FIELDS_EXTENSION Extension of stored fields file
+	FIELDS_EXTENSION = "fdt"
+
+	// INDEX_EXTENSION Extension of stored fields index
+	INDEX_EXTENSION = "fdx"
+
+	// META_EXTENSION Extension of stored fields meta
+	META_EXTENSION = "fdm"
+
+	// INDEX_CODEC_NAME Codec name for the index.
+	INDEX_CODEC_NAME = "Lucene85FieldsIndex"
+
+	CODEC_SFX_IDX  = "Index"
+	CODEC_SFX_DAT  = "Data"
+	CODEC_SFX_META = "Meta"
+
+	VERSION_START         = 1
+	VERSION_OFFHEAP_INDEX = 2
+	// VERSION_META Version where all metadata were moved to the meta file.
+	VERSION_META = 3
+	// VERSION_NUM_CHUNKS Version where numChunks is explicitly recorded in meta file and a dirty
+	// chunk bit is recorded in each chunk
+	VERSION_NUM_CHUNKS = 4
+	VERSION_CURRENT    = VERSION_NUM_CHUNKS
+
+	META_VERSION_START = 0
+
+	STRING         = 0x00
+	BYTE_ARR       = 0x01
+	NUMERIC_INT    = 0x02
+	NUMERIC_FLOAT  = 0x03
+	NUMERIC_LONG   = 0x04
+	NUMERIC_DOUBLE = 0x05
+
+	TYPE_BITS = 3 // unsigned bits needed to store NUMERIC_DOUBLE
+	TYPE_MASK = 1<<TYPE_BITS - 1
+
+	// -0 isn't compressed.
+	NEGATIVE_ZERO_FLOAT  = 0x80000000
+	NEGATIVE_ZERO_DOUBLE = 0x8000000000000000
+
+	// for compression of timestamps
+	SECOND          = 1000
+	HOUR            = 60 * 60 * SECOND
+	DAY             = 24 * HOUR
+	SECOND_ENCODING = 0x40
+	HOUR_ENCODING   = 0x80
+	DAY_ENCODING    = 0xC0
+)
+
+var _ index.StoredFieldsWriter = &StoredFieldsWriter{}
+
+// StoredFieldsWriter StoredFieldsWriter impl for CompressingStoredFieldsFormat.
+// Documents are buffered until chunkSize bytes or maxDocsPerChunk documents are buffered, the
+// buffered documents are then compressed together as one chunk. Chunks of at least twice
+// chunkSize bytes, which only happen with large documents, are compressed in slices of chunkSize
+// bytes so that readers never need to decompress more than chunkSize bytes at once.
+// FieldsIndexWriter records the first document and the start pointer of every chunk.
+// lucene.experimental
+type StoredFieldsWriter struct {
+	segment         string
+	indexWriter     *FieldsIndexWriter
+	fieldsStream    store.IndexOutput
+	metaStream      store.IndexOutput
+	compressor      Compressor
+	chunkSize       int
+	maxDocsPerChunk int
+
+	bufferedDocs    *store.BufferOutput
+	numStoredFields []int // number of stored fields
+	endOffsets      []int // end offsets in bufferedDocs
+	docBase         int   // doc ID at the beginning of the chunk
+	numBufferedDocs int   // docBase + numBufferedDocs == current doc ID
+
+	numStoredFieldsInDoc int
+	numChunks            int64 // number of written chunks
+	numDirtyChunks       int64 // number of incomplete compressed blocks written
+	numDirtyDocs         int64 // cumulative number of missing docs in incomplete chunks
+
+	closed bool
+}
+
+// NewStoredFieldsWriter Sole constructor.
+func NewStoredFieldsWriter(ctx context.Context, directory store.Directory, si index.SegmentInfo, segmentSuffix string,
+	ioContext *store.IOContext, formatName string, compressionMode CompressionMode,
+	chunkSize, maxDocsPerChunk, blockShift int) (*StoredFieldsWriter, error) {
+
+	w := &StoredFieldsWriter{
+		segment:         si.Name(),
+		compressor:      compressionMode.NewCompressor(),
+		chunkSize:       chunkSize,
+		maxDocsPerChunk: maxDocsPerChunk,
+		bufferedDocs:    store.NewBufferDataOutput(),
+		numStoredFields: make([]int, 0, 16),
+		endOffsets:      make([]int, 0, 16),
+	}
+
+	success := false
+	defer func() {
+		if !success {
+			_ = w.closeOutputs()
+		}
+	}()
+
+	var err error
+	w.metaStream, err = store.CreateOutputWithContext(ctx, directory, store.SegmentFileName(w.segment, segmentSuffix, META_EXTENSION), ioContext)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.WriteIndexHeader(ctx, w.metaStream, INDEX_CODEC_NAME+"Meta", VERSION_CURRENT, si.GetID(), segmentSuffix); err != nil {
+		return nil, err
+	}
+
+	w.fieldsStream, err = store.CreateOutputWithContext(ctx, directory, store.SegmentFileName(w.segment, segmentSuffix, FIELDS_EXTENSION), ioContext)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.WriteIndexHeader(ctx, w.fieldsStream, formatName, VERSION_CURRENT, si.GetID(), segmentSuffix); err != nil {
+		return nil, err
+	}
+
+	w.indexWriter = NewFieldsIndexWriter(directory, w.segment, segmentSuffix, INDEX_EXTENSION, INDEX_CODEC_NAME,
+		si.GetID(), blockShift, ioContext)
+
+	if err := w.metaStream.WriteUvarint(ctx, uint64(chunkSize)); err != nil {
+		return nil, err
+	}
+	if err := w.metaStream.WriteUvarint(ctx, packed.VERSION_CURRENT); err != nil {
+		return nil, err
+	}
+
+	success = true
+	return w, nil
+}
+
+func (s *StoredFieldsWriter) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.closeOutputs()
+}
+
+func (s *StoredFieldsWriter) closeOutputs() error {
+	var errs []error
+	for _, closer := range []store.IndexOutput{s.metaStream, s.fieldsStream} {
+		if closer == nil {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.metaStream, s.fieldsStream = nil, nil
+	return errors.Join(errs...)
+}
+
+func (s *StoredFieldsWriter) StartDocument(ctx context.Context) error {
+	return nil
+}
+
+func (s *StoredFieldsWriter) FinishDocument(ctx context.Context) error {
+	s.numStoredFields = append(s.numStoredFields, s.numStoredFieldsInDoc)
+	s.numStoredFieldsInDoc = 0
+	s.endOffsets = append(s.endOffsets, int(s.bufferedDocs.GetFilePointer()))
+	s.numBufferedDocs++
+	if s.triggerFlush() {
+		return s.flush(ctx, false)
+	}
+	return nil
+}
+
+func (s *StoredFieldsWriter) WriteField(ctx context.Context, info *document.FieldInfo, field document.IndexableField) error {
+	s.numStoredFieldsInDoc++
+
+	var bits int
+	switch field.Get().(type) {
+	case int32:
+		bits = NUMERIC_INT
+	case int64:
+		bits = NUMERIC_LONG
+	case float32:
+		bits = NUMERIC_FLOAT
+	case float64:
+		bits = NUMERIC_DOUBLE
+	case string:
+		bits = STRING
+	case []byte:
+		bits = BYTE_ARR
+	default:
+		return errors.New("cannot store numeric type")
+	}
+
+	infoAndBits := uint64(info.Number())<<TYPE_BITS | uint64(bits)
+	if err := s.bufferedDocs.WriteUvarint(ctx, infoAndBits); err != nil {
+		return err
+	}
+
+	switch v := field.Get().(type) {
+	case int32:
+		return s.bufferedDocs.WriteZInt32(ctx, v)
+	case int64:
+		return writeTLong(ctx, s.bufferedDocs, v)
+	case float32:
+		return writeZFloat(ctx, s.bufferedDocs, v)
+	case float64:
+		return writeZDouble(ctx, s.bufferedDocs, v)
+	case string:
+		return s.bufferedDocs.WriteString(ctx, v)
+	case []byte:
+		if err := s.bufferedDocs.WriteUvarint(ctx, uint64(len(v))); err != nil {
+			return err
+		}
+		_, err := s.bufferedDocs.Write(v)
+		return err
+	}
+	return nil
+}
+
+// writeZFloat Writes a float in a variable-length format. Writes between one and five bytes.
+// Small integral values typically take fewer bytes.
+//
+// ZFloat --> Header, Bytes*?
+//
+//   - Header --> Uint8. When it is equal to 0xFF then the value is negative and stored in the
+//     next 4 bytes. Otherwise if the first bit is set then the other bits in the header encode
+//     the value plus one and no other bytes are read. Otherwise, the value is a positive float
+//     value whose first byte is the header, and 3 bytes need to be read to complete it.
+//   - Bytes --> Potential additional bytes to read depending on the header.
+func writeZFloat(ctx context.Context, out store.DataOutput, f float32) error {
+	floatBits := float32ToIntBits(f)
+	if f == float32(math.Trunc(float64(f))) && f >= -1 && f <= 0x7D && floatBits != NEGATIVE_ZERO_FLOAT {
+		// small integer value [-1..125]: single byte
+		return out.WriteByte(byte(0x80 | (1 + int(f))))
+	}
+	if floatBits>>31 == 0 {
+		// other positive floats: 4 bytes
+		return out.WriteUint32(ctx, floatBits)
+	}
+	// other negative float: 5 bytes
+	if err := out.WriteByte(0xFF); err != nil {
+		return err
+	}
+	return out.WriteUint32(ctx, floatBits)
+}
+
+// writeZDouble Writes a double in a variable-length format. Writes between one and nine bytes.
+// Small integral values typically take fewer bytes.
+//
+// ZDouble --> Header, Bytes*?
+//
+//   - Header --> Uint8. When it is equal to 0xFF then the value is negative and stored in the
+//     next 8 bytes. When it is equal to 0xFE then the value is stored as a float in the next 4
+//     bytes. Otherwise if the first bit is set then the other bits in the header encode the value
+//     plus one and no other bytes are read. Otherwise, the value is a positive double value whose
+//     first byte is the header, and 7 bytes need to be read to complete it.
+//   - Bytes --> Potential additional bytes to read depending on the header.
+func writeZDouble(ctx context.Context, out store.DataOutput, d float64) error {
+	doubleBits := float64ToLongBits(d)
+	if d == math.Trunc(d) && d >= -1 && d <= 0x7C && doubleBits != NEGATIVE_ZERO_DOUBLE {
+		// small integer value [-1..124]: single byte
+		return out.WriteByte(byte(0x80 | (int(d) + 1)))
+	}
+	if d == float64(float32(d)) {
+		// d has an accurate float representation: 5 bytes
+		if err := out.WriteByte(0xFE); err != nil {
+			return err
+		}
+		return out.WriteUint32(ctx, float32ToIntBits(float32(d)))
+	}
+	if doubleBits>>63 == 0 {
+		// other positive doubles: 8 bytes
+		return out.WriteUint64(ctx, doubleBits)
+	}
+	// other negative doubles: 9 bytes
+	if err := out.WriteByte(0xFF); err != nil {
+		return err
+	}
+	return out.WriteUint64(ctx, doubleBits)
+}
+
+// writeTLong Writes a long in a variable-length format. Writes between one and ten bytes. Small
+// values or values representing timestamps with day, hour or second precision typically require
+// fewer bytes.
+//
+// ZLong --> Header, Bytes*?
+//
+//   - Header --> The first two bits indicate the compression scheme:
+//     00 - uncompressed, 01 - multiple of 1000 (second), 10 - multiple of 3600000 (hour),
+//     11 - multiple of 86400000 (day). Then the next bit is a continuation bit, indicating
+//     whether more bytes need to be read, and the last 5 bits are the lower bits of the encoded
+//     value. In order to reconstruct the value, you need to combine the 5 lower bits of the
+//     header with a vLong in the next bytes (if the continuation bit is set to 1). Then
+//     zigzag-decode it and finally multiply by the multiple corresponding to the compression
+//     scheme.
+//   - Bytes --> Potential additional bytes to read depending on the header.
+func writeTLong(ctx context.Context, out store.DataOutput, l int64) error {
+	var header byte
+	switch {
+	case l%SECOND != 0:
+		header = 0
+	case l%DAY == 0:
+		// timestamp with day precision
+		header = DAY_ENCODING
+		l /= DAY
+	case l%HOUR == 0:
+		// timestamp with hour precision, or day precision with a timezone
+		header = HOUR_ENCODING
+		l /= HOUR
+	default:
+		// timestamp with second precision
+		header = SECOND_ENCODING
+		l /= SECOND
+	}
+
+	zigZagL := uint64(l<<1) ^ uint64(l>>63)
+	header |= byte(zigZagL & 0x1F) // last 5 bits
+	upperBits := zigZagL >> 5
+	if upperBits != 0 {
+		header |= 0x20
+	}
+	if err := out.WriteByte(header); err != nil {
+		return err
+	}
+	if upperBits != 0 {
+		return out.WriteUvarint(ctx, upperBits)
+	}
+	return nil
+}
+
+// float32ToIntBits Returns the bits of f like Java's Float.floatToIntBits, which collapses all
+// NaN values to a single canonical NaN.
+func float32ToIntBits(f float32) uint32 {
+	if f != f {
+		return 0x7fc00000
+	}
+	return math.Float32bits(f)
+}
+
+// float64ToLongBits Returns the bits of d like Java's Double.doubleToLongBits, which collapses
+// all NaN values to a single canonical NaN.
+func float64ToLongBits(d float64) uint64 {
+	if d != d {
+		return 0x7ff8000000000000
+	}
+	return math.Float64bits(d)
+}
+
+func (s *StoredFieldsWriter) triggerFlush() bool {
+	return int(s.bufferedDocs.GetFilePointer()) >= s.chunkSize || // chunks of at least chunkSize bytes
+		s.numBufferedDocs >= s.maxDocsPerChunk
+}
+
+func (s *StoredFieldsWriter) flush(ctx context.Context, force bool) error {
+	s.numChunks++
+	if force {
+		s.numDirtyChunks++ // incomplete: we had to force this flush
+		s.numDirtyDocs += int64(s.numBufferedDocs)
+	}
+	if err := s.indexWriter.WriteIndex(s.numBufferedDocs, s.fieldsStream.GetFilePointer()); err != nil {
+		return err
+	}
+
+	// transform end offsets into lengths
+	lengths := s.endOffsets
+	for i := len(lengths) - 1; i > 0; i-- {
+		lengths[i] = lengths[i] - lengths[i-1]
+	}
+
+	content := s.bufferedDocs.Bytes()
+	sliced := len(content) >= 2*s.chunkSize
+	if err := s.writeHeader(ctx, s.docBase, s.numBufferedDocs, s.numStoredFields, lengths, sliced, force); err != nil {
+		return err
+	}
+
+	// compress stored fields to fieldsStream
+	if sliced {
+		// big chunk, slice it
+		for compressed := 0; compressed < len(content); compressed += s.chunkSize {
+			end := min(compressed+s.chunkSize, len(content))
+			if err := s.compressor.Compress(ctx, content[compressed:end], s.fieldsStream); err != nil {
+				return err
+			}
+		}
+	} else {
+		if err := s.compressor.Compress(ctx, content, s.fieldsStream); err != nil {
+			return err
+		}
+	}
+
+	// reset
+	s.docBase += s.numBufferedDocs
+	s.numBufferedDocs = 0
+	s.bufferedDocs.Reset()
+	s.numStoredFields = s.numStoredFields[:0]
+	s.endOffsets = s.endOffsets[:0]
+	return nil
+}
+
+func (s *StoredFieldsWriter) writeHeader(ctx context.Context, docBase, numBufferedDocs int,
+	numStoredFields, lengths []int, sliced, dirtyChunk bool) error {
+
+	slicedBit := 0
+	if sliced {
+		slicedBit = 1
+	}
+	dirtyBit := 0
+	if dirtyChunk {
+		dirtyBit = 2
+	}
+
+	// save docBase and numBufferedDocs
+	if err := s.fieldsStream.WriteUvarint(ctx, uint64(docBase)); err != nil {
+		return err
+	}
+	if err := s.fieldsStream.WriteUvarint(ctx, uint64(numBufferedDocs<<2|dirtyBit|slicedBit)); err != nil {
+		return err
+	}
+
+	// save numStoredFields
+	if err := saveInts(ctx, numStoredFields, s.fieldsStream); err != nil {
+		return err
+	}
+
+	// save lengths
+	return saveInts(ctx, lengths, s.fieldsStream)
+}
+
+// saveInts writes a single value as is, or a zero followed by the common value when all values
+// are equal, or else the number of bits required by the values followed by the packed values.
+func saveInts(ctx context.Context, values []int, out store.DataOutput) error {
+	if len(values) == 1 {
+		return out.WriteUvarint(ctx, uint64(values[0]))
+	}
+
+	allEqual := true
+	for _, v := range values[1:] {
+		if v != values[0] {
+			allEqual = false
+			break
+		}
+	}
+	if allEqual {
+		if err := out.WriteUvarint(ctx, 0); err != nil {
+			return err
+		}
+		return out.WriteUvarint(ctx, uint64(values[0]))
+	}
+
+	maxValue := uint64(0)
+	for _, v := range values {
+		maxValue |= uint64(v)
+	}
+	bitsRequired := packed.UnsignedBitsRequired(maxValue)
+	if err := out.WriteUvarint(ctx, uint64(bitsRequired)); err != nil {
+		return err
+	}
+
+	return writePackedInts(out, values, bitsRequired)
+}
+
+func (s *StoredFieldsWriter) Finish(ctx context.Context, fieldInfos index.FieldInfos, numDocs int) error {
+	if s.numBufferedDocs > 0 {
+		if err := s.flush(ctx, true); err != nil {
+			return err
+		}
+	}
+	if s.docBase != numDocs {
+		return fmt.Errorf("wrote %d docs, finish called with numDocs=%d", s.docBase, numDocs)
+	}
+
+	if err := s.indexWriter.Finish(ctx, numDocs, s.fieldsStream.GetFilePointer(), s.metaStream); err != nil {
+		return err
+	}
+	if err := s.metaStream.WriteUvarint(ctx, uint64(s.numChunks)); err != nil {
+		return err
+	}
+	if err := s.metaStream.WriteUvarint(ctx, uint64(s.numDirtyChunks)); err != nil {
+		return err
+	}
+	if err := s.metaStream.WriteUvarint(ctx, uint64(s.numDirtyDocs)); err != nil {
+		return err
+	}
+	if err := utils.WriteFooter(s.metaStream); err != nil {
+		return err
+	}
+	return utils.WriteFooter(s.fieldsStream)
+}
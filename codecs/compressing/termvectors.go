@@ -0,0 +1,297 @@
+package compressing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/types"
+)
+
+var _ index.Fields = &tvFields{}
+
+// tvFields The term vectors of a single document, in the order the fields were written.
+type tvFields struct {
+	names []string
+	terms map[string]*tvTerms
+}
+
+func newTVFields() *tvFields {
+	return &tvFields{terms: make(map[string]*tvTerms)}
+}
+
+func (f *tvFields) add(name string, terms *tvTerms) {
+	f.names = append(f.names, name)
+	f.terms[name] = terms
+}
+
+func (f *tvFields) Names() []string {
+	return f.names
+}
+
+func (f *tvFields) Terms(field string) (index.Terms, error) {
+	terms, ok := f.terms[field]
+	if !ok || len(terms.terms) == 0 {
+		// no term
+		return nil, nil
+	}
+	return terms, nil
+}
+
+func (f *tvFields) Size() int {
+	return len(f.names)
+}
+
+var _ index.Terms = &tvTerms{}
+
+type tvTerms struct {
+	*coreIndex.BaseTerms
+
+	terms        []*tvTerm
+	hasPositions bool
+	hasOffsets   bool
+	hasPayloads  bool
+}
+
+type tvTerm struct {
+	term         []byte
+	freq         int
+	positions    []int
+	startOffsets []int
+	endOffsets   []int
+	payloads     [][]byte
+}
+
+func newTVTerms(hasPositions, hasOffsets, hasPayloads bool, numTerms int) *tvTerms {
+	terms := &tvTerms{
+		terms:        make([]*tvTerm, 0, numTerms),
+		hasPositions: hasPositions,
+		hasOffsets:   hasOffsets,
+		hasPayloads:  hasPayloads,
+	}
+	terms.BaseTerms = coreIndex.NewTerms(terms)
+	return terms
+}
+
+func (t *tvTerms) Iterator() (index.TermsEnum, error) {
+	return newTVTermsEnum(t.terms), nil
+}
+
+func (t *tvTerms) Size() (int, error) {
+	return len(t.terms), nil
+}
+
+func (t *tvTerms) GetSumTotalTermFreq() (int64, error) {
+	ttf := int64(0)
+	for _, term := range t.terms {
+		ttf += int64(term.freq)
+	}
+	return ttf, nil
+}
+
+func (t *tvTerms) GetSumDocFreq() (int64, error) {
+	return int64(len(t.terms)), nil
+}
+
+func (t *tvTerms) GetDocCount() (int, error) {
+	return 1, nil
+}
+
+func (t *tvTerms) HasFreqs() bool {
+	return true
+}
+
+func (t *tvTerms) HasOffsets() bool {
+	return t.hasOffsets
+}
+
+func (t *tvTerms) HasPositions() bool {
+	return t.hasPositions
+}
+
+func (t *tvTerms) HasPayloads() bool {
+	return t.hasPayloads
+}
+
+var _ index.TermsEnum = &tvTermsEnum{}
+
+type tvTermsEnum struct {
+	*coreIndex.BaseTermsEnum
+
+	terms []*tvTerm
+	ord   int
+}
+
+func newTVTermsEnum(terms []*tvTerm) *tvTermsEnum {
+	enum := &tvTermsEnum{
+		terms: terms,
+		ord:   -1,
+	}
+	enum.BaseTermsEnum = coreIndex.NewBaseTermsEnum(&coreIndex.BaseTermsEnumConfig{
+		SeekCeil: enum.SeekCeil,
+	})
+	return enum
+}
+
+func (e *tvTermsEnum) Next(context.Context) ([]byte, error) {
+	if e.ord+1 >= len(e.terms) {
+		e.ord = len(e.terms)
+		return nil, io.EOF
+	}
+	e.ord++
+	return e.terms[e.ord].term, nil
+}
+
+func (e *tvTermsEnum) SeekCeil(ctx context.Context, text []byte) (index.SeekStatus, error) {
+	e.ord = sort.Search(len(e.terms), func(i int) bool {
+		return bytes.Compare(e.terms[i].term, text) >= 0
+	})
+	if e.ord == len(e.terms) {
+		return index.SEEK_STATUS_END, nil
+	}
+	if bytes.Equal(e.terms[e.ord].term, text) {
+		return index.SEEK_STATUS_FOUND, nil
+	}
+	return index.SEEK_STATUS_NOT_FOUND, nil
+}
+
+func (e *tvTermsEnum) SeekExactByOrd(ctx context.Context, ord int64) error {
+	if ord < 0 || ord >= int64(len(e.terms)) {
+		return errors.New("ord out of range")
+	}
+	e.ord = int(ord)
+	return nil
+}
+
+func (e *tvTermsEnum) current() (*tvTerm, error) {
+	if e.ord < 0 || e.ord >= len(e.terms) {
+		return nil, errors.New("enum is not positioned")
+	}
+	return e.terms[e.ord], nil
+}
+
+func (e *tvTermsEnum) Term() ([]byte, error) {
+	term, err := e.current()
+	if err != nil {
+		return nil, err
+	}
+	return term.term, nil
+}
+
+func (e *tvTermsEnum) Ord() (int64, error) {
+	return int64(e.ord), nil
+}
+
+func (e *tvTermsEnum) DocFreq() (int, error) {
+	return 1, nil
+}
+
+func (e *tvTermsEnum) TotalTermFreq() (int64, error) {
+	term, err := e.current()
+	if err != nil {
+		return 0, err
+	}
+	return int64(term.freq), nil
+}
+
+func (e *tvTermsEnum) Postings(reuse index.PostingsEnum, flags int) (index.PostingsEnum, error) {
+	term, err := e.current()
+	if err != nil {
+		return nil, err
+	}
+	enum, ok := reuse.(*tvPostingsEnum)
+	if !ok {
+		enum = &tvPostingsEnum{}
+	}
+	enum.reset(term)
+	return enum, nil
+}
+
+func (e *tvTermsEnum) Impacts(flags int) (index.ImpactsEnum, error) {
+	enum, err := e.Postings(nil, flags)
+	if err != nil {
+		return nil, err
+	}
+	return coreIndex.NewSlowImpactsEnum(enum), nil
+}
+
+var _ index.PostingsEnum = &tvPostingsEnum{}
+
+// tvPostingsEnum Postings of a term vector: a single document (0) with the positions, offsets and
+// payloads of the term.
+type tvPostingsEnum struct {
+	term *tvTerm
+	doc  int
+	i    int
+}
+
+func (p *tvPostingsEnum) reset(term *tvTerm) {
+	p.term = term
+	p.doc = -1
+	p.i = -1
+}
+
+func (p *tvPostingsEnum) DocID() int {
+	return p.doc
+}
+
+func (p *tvPostingsEnum) NextDoc() (int, error) {
+	if p.doc == -1 {
+		p.doc = 0
+	} else {
+		p.doc = types.NO_MORE_DOCS
+	}
+	return p.doc, nil
+}
+
+func (p *tvPostingsEnum) Advance(target int) (int, error) {
+	return p.SlowAdvance(target)
+}
+
+func (p *tvPostingsEnum) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(p, target)
+}
+
+func (p *tvPostingsEnum) Cost() int64 {
+	return 1
+}
+
+func (p *tvPostingsEnum) Freq() (int, error) {
+	return p.term.freq, nil
+}
+
+func (p *tvPostingsEnum) NextPosition() (int, error) {
+	if p.i+1 >= p.term.freq {
+		return 0, errors.New("read past last position")
+	}
+	p.i++
+	if p.term.positions == nil {
+		return -1, nil
+	}
+	return p.term.positions[p.i], nil
+}
+
+func (p *tvPostingsEnum) StartOffset() (int, error) {
+	if p.term.startOffsets == nil || p.i < 0 {
+		return -1, nil
+	}
+	return p.term.startOffsets[p.i], nil
+}
+
+func (p *tvPostingsEnum) EndOffset() (int, error) {
+	if p.term.endOffsets == nil || p.i < 0 {
+		return -1, nil
+	}
+	return p.term.endOffsets[p.i], nil
+}
+
+func (p *tvPostingsEnum) GetPayload() ([]byte, error) {
+	if p.term.payloads == nil || p.i < 0 {
+		return nil, nil
+	}
+	return p.term.payloads[p.i], nil
+}
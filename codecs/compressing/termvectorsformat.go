@@ -0,0 +1,88 @@
+package compressing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/packed"
+)
+
+var _ index.TermVectorsFormat = &TermVectorsFormat{}
+
+// TermVectorsFormat A TermVectorsFormat that compresses chunks of documents together in
+// order to improve the compression ratio.
+//
+// Documents are buffered until chunkSize bytes of term suffixes and payloads or maxDocsPerChunk
+// documents are buffered. The per-field and per-term metadata (field numbers, flags, term counts,
+// prefix and suffix lengths, frequencies, positions and offsets) of a chunk is written with
+// packed ints, and the term suffixes and payloads are compressed together with the configured
+// CompressionMode. The start pointer of every chunk is recorded in a FieldsIndexWriter.
+// lucene.experimental
+type TermVectorsFormat struct {
+	formatName      string
+	segmentSuffix   string
+	compressionMode CompressionMode
+	chunkSize       int
+	maxDocsPerChunk int
+	blockShift      int
+}
+
+// NewTermVectorsFormat Create a new TermVectorsFormat.
+//
+// formatName is the name of the format. This name will be used in the file formats to perform
+// codec header checks.
+//
+// The compressionMode parameter allows you to choose between compression algorithms that have
+// various compression and decompression speeds so that you can pick the one that best fits your
+// indexing and searching throughput. You should never instantiate two TermVectorsFormats that
+// have the same name but different CompressionModes.
+//
+// chunkSize is the minimum byte size of a chunk of documents. maxDocsPerChunk is an upperbound
+// on how many docs may be stored in a single chunk. blockShift is the log in base 2 of number of
+// chunks to store in an index block.
+func NewTermVectorsFormat(formatName, segmentSuffix string, compressionMode CompressionMode,
+	chunkSize, maxDocsPerChunk, blockShift int) (*TermVectorsFormat, error) {
+
+	if compressionMode == nil {
+		return nil, errors.New("compressionMode must not be nil")
+	}
+	if chunkSize < 1 {
+		return nil, errors.New("chunkSize must be >= 1")
+	}
+	if maxDocsPerChunk < 1 {
+		return nil, errors.New("maxDocsPerChunk must be >= 1")
+	}
+	if blockShift < packed.DIRECT_MONOTONIC_MIN_BLOCK_SHIFT || blockShift > packed.DIRECT_MONOTONIC_MAX_BLOCK_SHIFT {
+		return nil, fmt.Errorf("blockSize must be in %d-%d, got %d",
+			packed.DIRECT_MONOTONIC_MIN_BLOCK_SHIFT, packed.DIRECT_MONOTONIC_MAX_BLOCK_SHIFT, blockShift)
+	}
+	return &TermVectorsFormat{
+		formatName:      formatName,
+		segmentSuffix:   segmentSuffix,
+		compressionMode: compressionMode,
+		chunkSize:       chunkSize,
+		maxDocsPerChunk: maxDocsPerChunk,
+		blockShift:      blockShift,
+	}, nil
+}
+
+func (t *TermVectorsFormat) VectorsReader(ctx context.Context, directory store.Directory,
+	segmentInfo index.SegmentInfo, fieldInfos index.FieldInfos, ioContext *store.IOContext) (index.TermVectorsReader, error) {
+
+	return NewTermVectorsReader(ctx, directory, segmentInfo, t.segmentSuffix, fieldInfos, ioContext, t.formatName, t.compressionMode)
+}
+
+func (t *TermVectorsFormat) VectorsWriter(ctx context.Context, directory store.Directory,
+	segmentInfo index.SegmentInfo, ioContext *store.IOContext) (index.TermVectorsWriter, error) {
+
+	return NewTermVectorsWriter(ctx, directory, segmentInfo, t.segmentSuffix, ioContext, t.formatName, t.compressionMode,
+		t.chunkSize, t.maxDocsPerChunk, t.blockShift)
+}
+
+func (t *TermVectorsFormat) String() string {
+	return fmt.Sprintf("TermVectorsFormat(compressionMode=%s, chunkSize=%d, maxDocsPerChunk=%d, blockShift=%d)",
+		t.compressionMode, t.chunkSize, t.maxDocsPerChunk, t.blockShift)
+}
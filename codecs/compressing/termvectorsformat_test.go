@@ -0,0 +1,293 @@
+package compressing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingMode records where and how much every decompressor call reads.
+type countingMode struct {
+	CompressionMode
+	calls []decompressCall
+}
+
+type decompressCall struct {
+	fp     int64
+	length int
+}
+
+func (m *countingMode) NewDecompressor() Decompressor {
+	return &countingDecompressor{Decompressor: m.CompressionMode.NewDecompressor(), mode: m}
+}
+
+type countingDecompressor struct {
+	Decompressor
+	mode *countingMode
+}
+
+func (d *countingDecompressor) Decompress(ctx context.Context, in store.DataInput, originalLength, offset, length int) ([]byte, error) {
+	d.mode.calls = append(d.mode.calls, decompressCall{fp: in.(store.IndexInput).GetFilePointer(), length: length})
+	return d.Decompressor.Decompress(ctx, in, originalLength, offset, length)
+}
+
+type testVectorTerm struct {
+	term         string
+	positions    []int
+	startOffsets []int
+	endOffsets   []int
+	payloads     [][]byte
+}
+
+type testVectorField struct {
+	info                         *document.FieldInfo
+	positions, offsets, payloads bool
+	terms                        []*testVectorTerm
+}
+
+func newTestVectorField(r *rand.Rand, info *document.FieldInfo, positions, offsets, payloads bool) *testVectorField {
+	field := &testVectorField{info: info, positions: positions, offsets: offsets, payloads: payloads}
+
+	seen := make(map[string]bool)
+	numTerms := 1 + r.Intn(20)
+	for len(seen) < numTerms {
+		seen[fmt.Sprintf("%s%d", []string{"lucene", "luc", "search", "sea", "index"}[r.Intn(5)], r.Intn(50))] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		term := &testVectorTerm{term: name}
+		freq := 1 + r.Intn(4)
+		position, offset := r.Intn(5), r.Intn(10)
+		for i := 0; i < freq; i++ {
+			position += r.Intn(10)
+			offset += r.Intn(20)
+			term.positions = append(term.positions, position)
+			term.startOffsets = append(term.startOffsets, offset)
+			term.endOffsets = append(term.endOffsets, offset+len(name))
+			var payload []byte
+			if n := r.Intn(4); n > 0 {
+				payload = make([]byte, n)
+				r.Read(payload)
+			}
+			term.payloads = append(term.payloads, payload)
+		}
+		field.terms = append(field.terms, term)
+	}
+	return field
+}
+
+func TestTermVectorsFormat(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(7))
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	newInfo := func(name string, number int, payloads bool) *document.FieldInfo {
+		return document.NewFieldInfo(name, number, true, false, payloads,
+			document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS, document.DOC_VALUES_TYPE_NONE,
+			-1, map[string]string{}, 0, 0, 0, false)
+	}
+	infos := []*document.FieldInfo{
+		newInfo("body", 0, true),
+		newInfo("title", 1, false),
+		newInfo("tags", 2, false),
+		newInfo("offsets", 3, false),
+	}
+	fieldInfos := coreIndex.NewFieldInfos(infos)
+
+	const numDocs = 500
+	docs := make([][]*testVectorField, numDocs)
+	for i := range docs {
+		if r.Intn(10) == 0 {
+			continue // no vectors
+		}
+		if r.Intn(2) == 0 {
+			docs[i] = append(docs[i], newTestVectorField(r, infos[0], true, true, true))
+		}
+		if r.Intn(2) == 0 {
+			docs[i] = append(docs[i], newTestVectorField(r, infos[1], true, false, false))
+		}
+		if r.Intn(2) == 0 {
+			docs[i] = append(docs[i], newTestVectorField(r, infos[2], false, false, false))
+		}
+		if r.Intn(2) == 0 {
+			docs[i] = append(docs[i], newTestVectorField(r, infos[3], false, true, false))
+		}
+	}
+
+	mode := &countingMode{CompressionMode: FAST}
+	format, err := NewTermVectorsFormat("TestTermVectors", "", mode, 512, 16, 10)
+	assert.Nil(t, err)
+
+	id := []byte("0123456789abcdef")
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", numDocs, false, nil,
+		map[string]string{}, id, map[string]string{}, nil)
+
+	writer, err := format.VectorsWriter(ctx, dir, si, store.DEFAULT)
+	assert.Nil(t, err)
+	for _, fields := range docs {
+		assert.Nil(t, writer.StartDocument(ctx, len(fields)))
+		for _, field := range fields {
+			assert.Nil(t, writer.StartField(ctx, field.info, len(field.terms), field.positions, field.offsets, field.payloads))
+			for _, term := range field.terms {
+				assert.Nil(t, writer.StartTerm(ctx, []byte(term.term), len(term.positions)))
+				if field.positions || field.offsets {
+					for i := range term.positions {
+						assert.Nil(t, writer.AddPosition(ctx, term.positions[i], term.startOffsets[i],
+							term.endOffsets[i], term.payloads[i]))
+					}
+				}
+				assert.Nil(t, writer.FinishTerm(ctx))
+			}
+			assert.Nil(t, writer.FinishField(ctx))
+		}
+		assert.Nil(t, writer.FinishDocument(ctx))
+	}
+	assert.Nil(t, writer.Finish(ctx, fieldInfos, numDocs))
+	assert.Nil(t, writer.Close())
+
+	reader, err := format.VectorsReader(ctx, dir, si, fieldInfos, store.DEFAULT)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer reader.Close()
+	assert.Nil(t, reader.CheckIntegrity())
+
+	tvReader := reader.(*TermVectorsReader)
+	assert.Greater(t, tvReader.numChunks, int64(1))
+	assert.Equal(t, int64(1), tvReader.numDirtyChunks)
+
+	for _, doc := range r.Perm(numDocs) {
+		mode.calls = mode.calls[:0]
+		fields, err := reader.Get(ctx, doc)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		if len(docs[doc]) == 0 {
+			assert.Nil(t, fields)
+			assert.Empty(t, mode.calls)
+			continue
+		}
+
+		// a single decompression, from within the chunk that holds the document
+		if assert.Len(t, mode.calls, 1) {
+			startPointer, err := tvReader.indexReader.GetStartPointer(doc)
+			assert.Nil(t, err)
+			assert.Greater(t, mode.calls[0].fp, startPointer)
+			assert.Less(t, mode.calls[0].fp, tvReader.maxPointer)
+		}
+
+		assertVectors(t, docs[doc], fields)
+	}
+
+	_, err = reader.Get(ctx, numDocs)
+	assert.NotNil(t, err)
+
+	clone := reader.Clone(ctx)
+	fields, err := clone.Get(ctx, 3)
+	assert.Nil(t, err)
+	if len(docs[3]) > 0 {
+		assertVectors(t, docs[3], fields)
+	}
+}
+
+func assertVectors(t *testing.T, expected []*testVectorField, fields index.Fields) {
+	ctx := context.Background()
+
+	if !assert.NotNil(t, fields) {
+		return
+	}
+	assert.Equal(t, len(expected), fields.Size())
+
+	for _, field := range expected {
+		terms, err := fields.Terms(field.info.Name())
+		assert.Nil(t, err)
+		if !assert.NotNil(t, terms) {
+			continue
+		}
+		assert.Equal(t, field.positions, terms.HasPositions())
+		assert.Equal(t, field.offsets, terms.HasOffsets())
+		assert.Equal(t, field.payloads, terms.HasPayloads())
+		size, err := terms.Size()
+		assert.Nil(t, err)
+		assert.Equal(t, len(field.terms), size)
+
+		termsEnum, err := terms.Iterator()
+		assert.Nil(t, err)
+		for _, term := range field.terms {
+			text, err := termsEnum.Next(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, term.term, string(text))
+
+			freq, err := termsEnum.TotalTermFreq()
+			assert.Nil(t, err)
+			assert.Equal(t, int64(len(term.positions)), freq)
+
+			postings, err := termsEnum.Postings(nil, coreIndex.POSTINGS_ENUM_ALL)
+			assert.Nil(t, err)
+			docID, err := postings.NextDoc()
+			assert.Nil(t, err)
+			assert.Equal(t, 0, docID)
+
+			for i := range term.positions {
+				position, err := postings.NextPosition()
+				assert.Nil(t, err)
+				if field.positions {
+					assert.Equal(t, term.positions[i], position)
+				} else {
+					assert.Equal(t, -1, position)
+				}
+
+				startOffset, _ := postings.StartOffset()
+				endOffset, _ := postings.EndOffset()
+				if field.offsets {
+					assert.Equal(t, term.startOffsets[i], startOffset)
+					assert.Equal(t, term.endOffsets[i], endOffset)
+				} else {
+					assert.Equal(t, -1, startOffset)
+					assert.Equal(t, -1, endOffset)
+				}
+
+				payload, _ := postings.GetPayload()
+				if field.payloads {
+					assert.Equal(t, term.payloads[i], payload)
+				} else {
+					assert.Nil(t, payload)
+				}
+			}
+
+			docID, err = postings.NextDoc()
+			assert.Nil(t, err)
+			assert.Equal(t, types.NO_MORE_DOCS, docID)
+		}
+		_, err = termsEnum.Next(ctx)
+		assert.ErrorIs(t, err, io.EOF)
+
+		// seek back to a term in the middle
+		middle := field.terms[len(field.terms)/2]
+		found, err := termsEnum.SeekExact(ctx, []byte(middle.term))
+		assert.Nil(t, err)
+		assert.True(t, found)
+		freq, err := termsEnum.TotalTermFreq()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(len(middle.positions)), freq)
+	}
+}
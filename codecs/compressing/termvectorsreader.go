@@ -0,0 +1,551 @@
+package compressing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/packed"
+)
+
+var _ index.TermVectorsReader = &TermVectorsReader{}
+
+// TermVectorsReader TermVectorsReader for CompressingTermVectorsFormat.
+// Loading the vectors of a document only reads the chunk that contains it, and only decompresses
+// the term suffixes and payloads up to the end of the document within that chunk.
+// lucene.experimental
+type TermVectorsReader struct {
+	version           int
+	fieldInfos        index.FieldInfos
+	indexReader       *FieldsIndexReader
+	vectorsStream     store.IndexInput
+	packedIntsVersion int
+	chunkSize         int
+	numDocs           int
+	maxPointer        int64
+
+	compressionMode CompressionMode
+	decompressor    Decompressor
+
+	numChunks      int64 // number of written blocks
+	numDirtyChunks int64 // number of incomplete compressed blocks written
+	numDirtyDocs   int64 // cumulative number of missing docs in incomplete chunks
+
+	closed bool
+}
+
+// NewTermVectorsReader Sole constructor.
+func NewTermVectorsReader(ctx context.Context, directory store.Directory, si index.SegmentInfo, segmentSuffix string,
+	fn index.FieldInfos, ioContext *store.IOContext, formatName string, compressionMode CompressionMode) (*TermVectorsReader, error) {
+
+	reader := &TermVectorsReader{
+		fieldInfos:      fn,
+		compressionMode: compressionMode,
+		decompressor:    compressionMode.NewDecompressor(),
+	}
+
+	closeOnError := func(err error) (*TermVectorsReader, error) {
+		_ = reader.Close()
+		return nil, err
+	}
+
+	segment := si.Name()
+	segmentID := si.GetID()
+	maxDoc, err := si.MaxDoc()
+	if err != nil {
+		return nil, err
+	}
+	reader.numDocs = maxDoc
+
+	// Load the index into memory
+	vectorsStreamFN := store.SegmentFileName(segment, segmentSuffix, VECTORS_EXTENSION)
+	if reader.vectorsStream, err = store.OpenInputWithContext(ctx, directory, vectorsStreamFN, ioContext); err != nil {
+		return nil, err
+	}
+	if reader.version, err = utils.CheckIndexHeader(ctx, reader.vectorsStream, formatName,
+		VERSION_META, VERSION_CURRENT, segmentID, segmentSuffix); err != nil {
+		return closeOnError(err)
+	}
+
+	metaStreamFN := store.SegmentFileName(segment, segmentSuffix, VECTORS_META_EXTENSION)
+	metaIn, err := store.OpenChecksumInput(directory, metaStreamFN)
+	if err != nil {
+		return closeOnError(err)
+	}
+	defer metaIn.Close()
+
+	if _, err := utils.CheckIndexHeader(ctx, metaIn, VECTORS_INDEX_CODEC_NAME+"Meta",
+		META_VERSION_START, reader.version, segmentID, segmentSuffix); err != nil {
+		return closeOnError(err)
+	}
+
+	packedIntsVersion, err := metaIn.ReadUvarint(ctx)
+	if err != nil {
+		return closeOnError(err)
+	}
+	reader.packedIntsVersion = int(packedIntsVersion)
+	chunkSize, err := metaIn.ReadUvarint(ctx)
+	if err != nil {
+		return closeOnError(err)
+	}
+	reader.chunkSize = int(chunkSize)
+
+	// NOTE: data file is too costly to verify checksum against all the bytes on open,
+	// but for now we at least verify proper structure of the checksum footer: which looks
+	// for FOOTER_MAGIC + algorithmID. This is cheap and can detect some forms of corruption
+	// such as file truncation.
+	if _, err := utils.RetrieveChecksum(reader.vectorsStream); err != nil {
+		return closeOnError(err)
+	}
+
+	if reader.indexReader, err = NewFieldsIndexReader(ctx, directory, segment, segmentSuffix, VECTORS_INDEX_EXTENSION,
+		VECTORS_INDEX_CODEC_NAME, segmentID, metaIn); err != nil {
+		return closeOnError(err)
+	}
+	if reader.indexReader.maxDoc != maxDoc {
+		return closeOnError(fmt.Errorf("doc counts differ for segment %s: vectorsReader shows %d but segmentInfo shows %d",
+			segment, reader.indexReader.maxDoc, maxDoc))
+	}
+	reader.maxPointer = reader.indexReader.GetMaxPointer()
+
+	if reader.version >= VERSION_NUM_CHUNKS {
+		numChunks, err := metaIn.ReadUvarint(ctx)
+		if err != nil {
+			return closeOnError(err)
+		}
+		reader.numChunks = int64(numChunks)
+	}
+	numDirtyChunks, err := metaIn.ReadUvarint(ctx)
+	if err != nil {
+		return closeOnError(err)
+	}
+	reader.numDirtyChunks = int64(numDirtyChunks)
+	numDirtyDocs, err := metaIn.ReadUvarint(ctx)
+	if err != nil {
+		return closeOnError(err)
+	}
+	reader.numDirtyDocs = int64(numDirtyDocs)
+
+	if _, err := utils.CheckCodecFooter(metaIn); err != nil {
+		return closeOnError(err)
+	}
+
+	if reader.maxPointer+int64(utils.FooterLength()) != reader.vectorsStream.Length() {
+		return closeOnError(fmt.Errorf("invalid vectorsStream maxPointer (file truncated?): maxPointer=%d, length=%d",
+			reader.maxPointer, reader.vectorsStream.Length()))
+	}
+	return reader, nil
+}
+
+func (t *TermVectorsReader) Close() error {
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	var errs []error
+	if t.indexReader != nil {
+		errs = append(errs, t.indexReader.Close())
+	}
+	if t.vectorsStream != nil {
+		errs = append(errs, t.vectorsStream.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (t *TermVectorsReader) Get(ctx context.Context, doc int) (index.Fields, error) {
+	if doc < 0 || doc >= t.numDocs {
+		return nil, fmt.Errorf("docID must be >= 0 and < maxDoc=%d (got docID=%d)", t.numDocs, doc)
+	}
+
+	// seek to the right place
+	startPointer, err := t.indexReader.GetStartPointer(doc)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := t.vectorsStream.Seek(startPointer, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	chunk, err := t.readChunk(ctx, doc)
+	if err != nil || chunk == nil {
+		return nil, err
+	}
+	return chunk.document(t.fieldInfos)
+}
+
+// tvChunk The decoded metadata of the fields of a chunk, along with the decompressed term suffixes
+// and payloads of one of its documents.
+type tvChunk struct {
+	skip      int // number of fields before the document
+	numFields int // number of fields of the document
+
+	fieldNums     []int
+	fieldNumOffs  []int
+	flags         []int
+	numTerms      []int
+	prefixLengths []int
+	suffixLengths []int
+	termFreqs     []int
+
+	positions      []int
+	charsPerTerm   []float32
+	startOffsets   []int
+	lengths        []int
+	payloadLengths []int
+
+	suffixBytes  []byte // term suffixes of the document
+	payloadBytes []byte // payloads of the document
+}
+
+func (t *TermVectorsReader) readChunk(ctx context.Context, doc int) (*tvChunk, error) {
+	in := t.vectorsStream
+
+	// decode
+	// - docBase: first doc ID of the chunk
+	// - chunkDocs: number of docs of the chunk
+	docBase, err := in.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chunkDocs, err := in.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if doc < int(docBase) || doc >= int(docBase+chunkDocs) || int(docBase+chunkDocs) > t.numDocs {
+		return nil, fmt.Errorf("corrupted: docBase=%d, chunkDocs=%d, doc=%d", docBase, chunkDocs, doc)
+	}
+
+	chunk := &tvChunk{}
+	totalFields := 0 // total number of fields of the chunk (sum for all docs)
+	if chunkDocs == 1 {
+		numFields, err := in.ReadUvarint(ctx)
+		if err != nil {
+			return nil, err
+		}
+		chunk.numFields = int(numFields)
+		totalFields = chunk.numFields
+	} else {
+		numFields, err := readBlockPacked(ctx, in, t.packedIntsVersion, int(chunkDocs))
+		if err != nil {
+			return nil, err
+		}
+		for i, n := range numFields {
+			if i < doc-int(docBase) {
+				chunk.skip += n
+			}
+			totalFields += n
+		}
+		chunk.numFields = numFields[doc-int(docBase)]
+	}
+
+	if chunk.numFields == 0 {
+		// no vectors
+		return nil, nil
+	}
+
+	// read field numbers that have term vectors
+	token, err := in.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	bitsPerFieldNum := int(token & 0x1F)
+	totalDistinctFields := int(token >> 5)
+	if totalDistinctFields == 0x07 {
+		n, err := in.ReadUvarint(ctx)
+		if err != nil {
+			return nil, err
+		}
+		totalDistinctFields += int(n)
+	}
+	totalDistinctFields++
+	if chunk.fieldNums, err = readPackedInts(in, t.packedIntsVersion, totalDistinctFields, bitsPerFieldNum); err != nil {
+		return nil, err
+	}
+
+	// read field numbers and flags
+	bitsPerOff := packed.UnsignedBitsRequired(uint64(totalDistinctFields - 1))
+	if chunk.fieldNumOffs, err = readPackedInts(in, t.packedIntsVersion, totalFields, bitsPerOff); err != nil {
+		return nil, err
+	}
+	flagsMode, err := in.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch flagsMode {
+	case 0:
+		fieldFlags, err := readPackedInts(in, t.packedIntsVersion, totalDistinctFields, FLAGS_BITS)
+		if err != nil {
+			return nil, err
+		}
+		chunk.flags = make([]int, totalFields)
+		for i, fieldNumOff := range chunk.fieldNumOffs {
+			if fieldNumOff >= totalDistinctFields {
+				return nil, fmt.Errorf("corrupted: field number offset %d >= %d", fieldNumOff, totalDistinctFields)
+			}
+			chunk.flags[i] = fieldFlags[fieldNumOff]
+		}
+	case 1:
+		if chunk.flags, err = readPackedInts(in, t.packedIntsVersion, totalFields, FLAGS_BITS); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("corrupted: unknown flags mode %d", flagsMode)
+	}
+
+	// number of terms per field for all fields
+	bitsRequired, err := in.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if chunk.numTerms, err = readPackedInts(in, t.packedIntsVersion, totalFields, int(bitsRequired)); err != nil {
+		return nil, err
+	}
+	totalTerms := sumInts(chunk.numTerms)
+
+	// term lengths and freqs
+	if chunk.prefixLengths, err = readBlockPacked(ctx, in, t.packedIntsVersion, totalTerms); err != nil {
+		return nil, err
+	}
+	if chunk.suffixLengths, err = readBlockPacked(ctx, in, t.packedIntsVersion, totalTerms); err != nil {
+		return nil, err
+	}
+	if chunk.termFreqs, err = readBlockPacked(ctx, in, t.packedIntsVersion, totalTerms); err != nil {
+		return nil, err
+	}
+	for i := range chunk.termFreqs {
+		chunk.termFreqs[i]++
+	}
+
+	// total number of positions, offsets and payloads
+	totalPositions, totalOffsets, totalPayloads := 0, 0, 0
+	termIndex := 0
+	for i, f := range chunk.flags {
+		for j := 0; j < chunk.numTerms[i]; j++ {
+			freq := chunk.termFreqs[termIndex]
+			termIndex++
+			if f&POSITIONS != 0 {
+				totalPositions += freq
+			}
+			if f&OFFSETS != 0 {
+				totalOffsets += freq
+			}
+			if f&PAYLOADS != 0 {
+				totalPayloads += freq
+			}
+		}
+	}
+
+	if chunk.positions, err = readBlockPacked(ctx, in, t.packedIntsVersion, totalPositions); err != nil {
+		return nil, err
+	}
+	if totalOffsets > 0 {
+		// average number of chars per term
+		chunk.charsPerTerm = make([]float32, totalDistinctFields)
+		for i := range chunk.charsPerTerm {
+			bits, err := in.ReadUint32(ctx)
+			if err != nil {
+				return nil, err
+			}
+			chunk.charsPerTerm[i] = math.Float32frombits(bits)
+		}
+		if chunk.startOffsets, err = readBlockPacked(ctx, in, t.packedIntsVersion, totalOffsets); err != nil {
+			return nil, err
+		}
+		if chunk.lengths, err = readBlockPacked(ctx, in, t.packedIntsVersion, totalOffsets); err != nil {
+			return nil, err
+		}
+	}
+	if chunk.payloadLengths, err = readBlockPacked(ctx, in, t.packedIntsVersion, totalPayloads); err != nil {
+		return nil, err
+	}
+
+	// the suffixes and payloads of every document are stored after each other, locate the ones of
+	// the document
+	docOff, docLen, totalLen := 0, 0, 0
+	payloadOff, payloadLen, totalPayloadLength := 0, 0, 0
+	termIndex, payloadIndex := 0, 0
+	for i, f := range chunk.flags {
+		suffixLength, payloadLength := 0, 0
+		for j := 0; j < chunk.numTerms[i]; j++ {
+			suffixLength += chunk.suffixLengths[termIndex]
+			if f&PAYLOADS != 0 {
+				for k := 0; k < chunk.termFreqs[termIndex]; k++ {
+					payloadLength += chunk.payloadLengths[payloadIndex]
+					payloadIndex++
+				}
+			}
+			termIndex++
+		}
+		switch {
+		case i < chunk.skip:
+			docOff += suffixLength
+			payloadOff += payloadLength
+		case i < chunk.skip+chunk.numFields:
+			docLen += suffixLength
+			payloadLen += payloadLength
+		}
+		totalLen += suffixLength
+		totalPayloadLength += payloadLength
+	}
+
+	// decompress data
+	data, err := t.decompressor.Decompress(ctx, in, totalLen+totalPayloadLength, docOff+payloadOff, docLen+payloadLen)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != docLen+payloadLen {
+		return nil, fmt.Errorf("corrupted: expected %d bytes, got %d", docLen+payloadLen, len(data))
+	}
+	// data may be overwritten by the next call to the decompressor
+	data = bytes.Clone(data)
+	chunk.suffixBytes = data[:docLen]
+	chunk.payloadBytes = data[docLen:]
+	return chunk, nil
+}
+
+func sumInts(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// document Builds the term vectors of the fields [skip, skip+numFields) of the chunk.
+func (c *tvChunk) document(fieldInfos index.FieldInfos) (index.Fields, error) {
+	fields := newTVFields()
+
+	// cursors in the streams of the chunk, which hold the values of all fields of the chunk
+	termUpto, posUpto, offUpto, payUpto := 0, 0, 0, 0
+	suffixUpto, payloadUpto := 0, 0
+	for i := 0; i < c.skip+c.numFields; i++ {
+		f := c.flags[i]
+		numTerms := c.numTerms[i]
+		if i < c.skip {
+			for j := 0; j < numTerms; j++ {
+				freq := c.termFreqs[termUpto+j]
+				if f&POSITIONS != 0 {
+					posUpto += freq
+				}
+				if f&OFFSETS != 0 {
+					offUpto += freq
+				}
+				if f&PAYLOADS != 0 {
+					payUpto += freq
+				}
+			}
+			termUpto += numTerms
+			continue
+		}
+
+		fieldNumOff := c.fieldNumOffs[i]
+		info := fieldInfos.FieldInfoByNumber(c.fieldNums[fieldNumOff])
+		if info == nil {
+			return nil, fmt.Errorf("corrupted: unknown field number %d", c.fieldNums[fieldNumOff])
+		}
+		terms := newTVTerms(f&POSITIONS != 0, f&OFFSETS != 0, f&PAYLOADS != 0, numTerms)
+
+		var lastTerm []byte
+		for j := 0; j < numTerms; j++ {
+			prefixLength := c.prefixLengths[termUpto]
+			suffixLength := c.suffixLengths[termUpto]
+			freq := c.termFreqs[termUpto]
+			termUpto++
+			if prefixLength > len(lastTerm) || suffixUpto+suffixLength > len(c.suffixBytes) {
+				return nil, fmt.Errorf("corrupted: invalid term in field %s", info.Name())
+			}
+
+			term := make([]byte, prefixLength+suffixLength)
+			copy(term, lastTerm[:prefixLength])
+			copy(term[prefixLength:], c.suffixBytes[suffixUpto:suffixUpto+suffixLength])
+			suffixUpto += suffixLength
+			lastTerm = term
+
+			tvTerm := &tvTerm{term: term, freq: freq}
+			var positionDeltas []int
+			if terms.hasPositions {
+				// delta-decode positions
+				positionDeltas = c.positions[posUpto : posUpto+freq]
+				posUpto += freq
+				tvTerm.positions = make([]int, freq)
+				position := 0
+				for k, delta := range positionDeltas {
+					position += delta
+					tvTerm.positions[k] = position
+				}
+			}
+			if terms.hasOffsets {
+				// patch offsets from positions, delta-decode start offsets and patch lengths using
+				// term lengths
+				charsPerTerm := c.charsPerTerm[fieldNumOff]
+				termLength := prefixLength + suffixLength
+				tvTerm.startOffsets = make([]int, freq)
+				tvTerm.endOffsets = make([]int, freq)
+				startOffset := 0
+				for k := 0; k < freq; k++ {
+					delta := c.startOffsets[offUpto+k]
+					if positionDeltas != nil {
+						delta += int(charsPerTerm * float32(positionDeltas[k]))
+					}
+					startOffset += delta
+					tvTerm.startOffsets[k] = startOffset
+					tvTerm.endOffsets[k] = startOffset + c.lengths[offUpto+k] + termLength
+				}
+				offUpto += freq
+			}
+			if terms.hasPayloads {
+				tvTerm.payloads = make([][]byte, freq)
+				for k := 0; k < freq; k++ {
+					length := c.payloadLengths[payUpto]
+					payUpto++
+					if payloadUpto+length > len(c.payloadBytes) {
+						return nil, fmt.Errorf("corrupted: invalid payload in field %s", info.Name())
+					}
+					if length > 0 {
+						tvTerm.payloads[k] = c.payloadBytes[payloadUpto : payloadUpto+length]
+					}
+					payloadUpto += length
+				}
+			}
+			terms.terms = append(terms.terms, tvTerm)
+		}
+		fields.add(info.Name(), terms)
+	}
+	return fields, nil
+}
+
+func (t *TermVectorsReader) CheckIntegrity() error {
+	if err := t.indexReader.CheckIntegrity(); err != nil {
+		return err
+	}
+	_, err := utils.ChecksumEntireFile(t.vectorsStream)
+	return err
+}
+
+func (t *TermVectorsReader) Clone(ctx context.Context) index.TermVectorsReader {
+	return &TermVectorsReader{
+		version:           t.version,
+		fieldInfos:        t.fieldInfos,
+		indexReader:       t.indexReader.Clone(),
+		vectorsStream:     t.vectorsStream.Clone().(store.IndexInput),
+		packedIntsVersion: t.packedIntsVersion,
+		chunkSize:         t.chunkSize,
+		numDocs:           t.numDocs,
+		maxPointer:        t.maxPointer,
+		compressionMode:   t.compressionMode,
+		decompressor:      t.decompressor.Clone(),
+		numChunks:         t.numChunks,
+		numDirtyChunks:    t.numDirtyChunks,
+		numDirtyDocs:      t.numDirtyDocs,
+	}
+}
+
+func (t *TermVectorsReader) GetMergeInstance() index.TermVectorsReader {
+	return t
+}
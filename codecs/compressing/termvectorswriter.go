@@ -0,0 +1,651 @@
+package compressing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/packed"
+)
+
+const (
+	// VECTORS_EXTENSION Extension of term vectors data file
+	VECTORS_EXTENSION = "tvd"
+
+	// VECTORS_INDEX_EXTENSION Extension of term vectors index file
+	VECTORS_INDEX_EXTENSION = "tvx"
+
+	// VECTORS_META_EXTENSION Extension of term vectors meta file
+	VECTORS_META_EXTENSION = "tvm"
+
+	// VECTORS_INDEX_CODEC_NAME Codec name for the index.
+	VECTORS_INDEX_CODEC_NAME = "Lucene85TermVectorsIndex"
+
+	// PACKED_BLOCK_SIZE Number of values per block of the block-packed metadata streams
+	PACKED_BLOCK_SIZE = 64
+
+	POSITIONS  = 0x01
+	OFFSETS    = 0x02
+	PAYLOADS   = 0x04
+	FLAGS_BITS = 3 // bits required by POSITIONS | OFFSETS | PAYLOADS
+)
+
+var _ index.TermVectorsWriter = &TermVectorsWriter{}
+
+// TermVectorsWriter TermVectorsWriter for CompressingTermVectorsFormat.
+//
+// Every chunk is written as:
+//   - DocBase, ChunkDocs --> VInt
+//   - NumFields of every document --> VInt if the chunk has a single document, else BlockPackedInts
+//   - the sorted distinct field numbers of the chunk, then the index of the field number of every
+//     field, then the flags either per distinct field number or per field --> PackedInts
+//   - NumTerms of every field --> PackedInts
+//   - PrefixLength, SuffixLength and Freq-1 of every term, then the positions, start offsets,
+//     offset lengths and payload lengths of the fields that have them --> BlockPackedInts. Start
+//     offsets are predicted from the positions and the average number of chars per term of the
+//     field, offset lengths are stored minus the length of the term
+//   - the term suffixes and payloads of all documents of the chunk, compressed together
+//
+// lucene.experimental
+type TermVectorsWriter struct {
+	segment         string
+	indexWriter     *FieldsIndexWriter
+	vectorsStream   store.IndexOutput
+	metaStream      store.IndexOutput
+	compressor      Compressor
+	chunkSize       int
+	maxDocsPerChunk int
+
+	numChunks      int64 // number of chunks
+	numDirtyChunks int64 // number of incomplete compressed blocks written
+	numDirtyDocs   int64 // cumulative number of docs in incomplete chunks
+
+	numDocs     int // total number of docs seen
+	pendingDocs []*tvDocData
+	curDoc      *tvDocData
+	curField    *tvFieldData
+	lastTerm    []byte
+
+	// positions, start offsets, lengths and payload lengths of the buffered fields, in the order
+	// they were added
+	positionsBuf      []int
+	startOffsetsBuf   []int
+	lengthsBuf        []int
+	payloadLengthsBuf []int
+
+	termSuffixes *store.BufferOutput // buffered term suffixes
+	payloadBytes *store.BufferOutput // buffered term payloads
+
+	closed bool
+}
+
+// tvDocData a pending doc
+type tvDocData struct {
+	numFields int
+	fields    []*tvFieldData
+}
+
+// tvFieldData a pending field
+type tvFieldData struct {
+	hasPositions, hasOffsets, hasPayloads bool
+	fieldNum, flags, numTerms             int
+	freqs, prefixLengths, suffixLengths   []int
+	posStart, offStart, payStart          int
+	totalPositions                        int
+}
+
+// NewTermVectorsWriter Sole constructor.
+func NewTermVectorsWriter(ctx context.Context, directory store.Directory, si index.SegmentInfo, segmentSuffix string,
+	ioContext *store.IOContext, formatName string, compressionMode CompressionMode,
+	chunkSize, maxDocsPerChunk, blockShift int) (*TermVectorsWriter, error) {
+
+	w := &TermVectorsWriter{
+		segment:         si.Name(),
+		compressor:      compressionMode.NewCompressor(),
+		chunkSize:       chunkSize,
+		maxDocsPerChunk: maxDocsPerChunk,
+		termSuffixes:    store.NewBufferDataOutput(),
+		payloadBytes:    store.NewBufferDataOutput(),
+	}
+
+	success := false
+	defer func() {
+		if !success {
+			_ = w.closeOutputs()
+		}
+	}()
+
+	var err error
+	w.metaStream, err = store.CreateOutputWithContext(ctx, directory, store.SegmentFileName(w.segment, segmentSuffix, VECTORS_META_EXTENSION), ioContext)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.WriteIndexHeader(ctx, w.metaStream, VECTORS_INDEX_CODEC_NAME+"Meta", VERSION_CURRENT, si.GetID(), segmentSuffix); err != nil {
+		return nil, err
+	}
+
+	w.vectorsStream, err = store.CreateOutputWithContext(ctx, directory, store.SegmentFileName(w.segment, segmentSuffix, VECTORS_EXTENSION), ioContext)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.WriteIndexHeader(ctx, w.vectorsStream, formatName, VERSION_CURRENT, si.GetID(), segmentSuffix); err != nil {
+		return nil, err
+	}
+
+	w.indexWriter = NewFieldsIndexWriter(directory, w.segment, segmentSuffix, VECTORS_INDEX_EXTENSION,
+		VECTORS_INDEX_CODEC_NAME, si.GetID(), blockShift, ioContext)
+
+	if err := w.metaStream.WriteUvarint(ctx, packed.VERSION_CURRENT); err != nil {
+		return nil, err
+	}
+	if err := w.metaStream.WriteUvarint(ctx, uint64(chunkSize)); err != nil {
+		return nil, err
+	}
+
+	success = true
+	return w, nil
+}
+
+func (t *TermVectorsWriter) Close() error {
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	return t.closeOutputs()
+}
+
+func (t *TermVectorsWriter) closeOutputs() error {
+	var errs []error
+	for _, closer := range []store.IndexOutput{t.metaStream, t.vectorsStream} {
+		if closer == nil {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.metaStream, t.vectorsStream = nil, nil
+	return errors.Join(errs...)
+}
+
+func (t *TermVectorsWriter) StartDocument(ctx context.Context, numVectorFields int) error {
+	t.curDoc = &tvDocData{
+		numFields: numVectorFields,
+		fields:    make([]*tvFieldData, 0, numVectorFields),
+	}
+	t.pendingDocs = append(t.pendingDocs, t.curDoc)
+	return nil
+}
+
+func (t *TermVectorsWriter) FinishDocument(ctx context.Context) error {
+	// append the payload bytes of the doc after its terms
+	if _, err := t.termSuffixes.Write(t.payloadBytes.Bytes()); err != nil {
+		return err
+	}
+	t.payloadBytes.Reset()
+	t.numDocs++
+	if t.triggerFlush() {
+		if err := t.flush(ctx); err != nil {
+			return err
+		}
+	}
+	t.curDoc = nil
+	return nil
+}
+
+func (t *TermVectorsWriter) StartField(ctx context.Context, info *document.FieldInfo, numTerms int,
+	positions, offsets, payloads bool) error {
+
+	if t.curDoc == nil {
+		return errors.New("StartField called outside of a document")
+	}
+
+	flags := 0
+	if positions {
+		flags |= POSITIONS
+	}
+	if offsets {
+		flags |= OFFSETS
+	}
+	if payloads {
+		flags |= PAYLOADS
+	}
+	t.curField = &tvFieldData{
+		hasPositions:  positions,
+		hasOffsets:    offsets,
+		hasPayloads:   payloads,
+		fieldNum:      info.Number(),
+		flags:         flags,
+		numTerms:      numTerms,
+		freqs:         make([]int, 0, numTerms),
+		prefixLengths: make([]int, 0, numTerms),
+		suffixLengths: make([]int, 0, numTerms),
+		posStart:      len(t.positionsBuf),
+		offStart:      len(t.startOffsetsBuf),
+		payStart:      len(t.payloadLengthsBuf),
+	}
+	t.curDoc.fields = append(t.curDoc.fields, t.curField)
+	t.lastTerm = t.lastTerm[:0]
+	return nil
+}
+
+func (t *TermVectorsWriter) FinishField(ctx context.Context) error {
+	t.curField = nil
+	return nil
+}
+
+func (t *TermVectorsWriter) StartTerm(ctx context.Context, term []byte, freq int) error {
+	if freq < 1 {
+		return fmt.Errorf("freq must be >= 1, got %d", freq)
+	}
+
+	prefix := commonPrefixLength(t.lastTerm, term)
+	field := t.curField
+	field.freqs = append(field.freqs, freq)
+	field.prefixLengths = append(field.prefixLengths, prefix)
+	field.suffixLengths = append(field.suffixLengths, len(term)-prefix)
+	if _, err := t.termSuffixes.Write(term[prefix:]); err != nil {
+		return err
+	}
+
+	t.lastTerm = append(t.lastTerm[:0], term...)
+	return nil
+}
+
+func (t *TermVectorsWriter) FinishTerm(ctx context.Context) error {
+	return nil
+}
+
+func (t *TermVectorsWriter) AddPosition(ctx context.Context, position, startOffset, endOffset int, payload []byte) error {
+	field := t.curField
+	if field.flags == 0 {
+		return errors.New("AddPosition called on a field without positions, offsets or payloads")
+	}
+	if field.hasPositions {
+		t.positionsBuf = append(t.positionsBuf, position)
+	}
+	if field.hasOffsets {
+		t.startOffsetsBuf = append(t.startOffsetsBuf, startOffset)
+		t.lengthsBuf = append(t.lengthsBuf, endOffset-startOffset)
+	}
+	if field.hasPayloads {
+		t.payloadLengthsBuf = append(t.payloadLengthsBuf, len(payload))
+		if _, err := t.payloadBytes.Write(payload); err != nil {
+			return err
+		}
+	}
+	field.totalPositions++
+	return nil
+}
+
+// commonPrefixLength Returns the number of leading bytes a and b have in common.
+func commonPrefixLength(a, b []byte) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+func (t *TermVectorsWriter) triggerFlush() bool {
+	return int(t.termSuffixes.GetFilePointer()) >= t.chunkSize ||
+		len(t.pendingDocs) >= t.maxDocsPerChunk
+}
+
+func (t *TermVectorsWriter) flush(ctx context.Context) error {
+	t.numChunks++
+	chunkDocs := len(t.pendingDocs)
+
+	// write the index file
+	if err := t.indexWriter.WriteIndex(chunkDocs, t.vectorsStream.GetFilePointer()); err != nil {
+		return err
+	}
+
+	docBase := t.numDocs - chunkDocs
+	if err := t.vectorsStream.WriteUvarint(ctx, uint64(docBase)); err != nil {
+		return err
+	}
+	if err := t.vectorsStream.WriteUvarint(ctx, uint64(chunkDocs)); err != nil {
+		return err
+	}
+
+	// total number of fields of the chunk
+	totalFields, err := t.flushNumFields(ctx, chunkDocs)
+	if err != nil {
+		return err
+	}
+
+	if totalFields > 0 {
+		if err := t.flushFields(ctx, totalFields); err != nil {
+			return err
+		}
+
+		// compress terms and payloads and write them to the output
+		if err := t.compressor.Compress(ctx, t.termSuffixes.Bytes(), t.vectorsStream); err != nil {
+			return err
+		}
+	}
+
+	// reset
+	t.pendingDocs = t.pendingDocs[:0]
+	t.curDoc = nil
+	t.curField = nil
+	t.termSuffixes.Reset()
+	t.positionsBuf = t.positionsBuf[:0]
+	t.startOffsetsBuf = t.startOffsetsBuf[:0]
+	t.lengthsBuf = t.lengthsBuf[:0]
+	t.payloadLengthsBuf = t.payloadLengthsBuf[:0]
+	return nil
+}
+
+func (t *TermVectorsWriter) flushFields(ctx context.Context, totalFields int) error {
+	// unique field numbers (sorted)
+	fieldNums, err := t.flushFieldNums(ctx)
+	if err != nil {
+		return err
+	}
+	// offsets in the array of unique field numbers
+	if err := t.flushFieldNumOffs(totalFields, fieldNums); err != nil {
+		return err
+	}
+	// flags (does the field have positions, offsets, payloads?)
+	if err := t.flushFlags(ctx, totalFields, fieldNums); err != nil {
+		return err
+	}
+	// number of terms of each field
+	if err := t.flushNumTerms(ctx, totalFields); err != nil {
+		return err
+	}
+	// prefix and suffix lengths for each field
+	if err := t.flushTermLengths(ctx); err != nil {
+		return err
+	}
+	// term freqs - 1 (because termFreq is always >=1) for each term
+	if err := t.flushTermFreqs(ctx); err != nil {
+		return err
+	}
+	// positions for all terms, when enabled
+	if err := t.flushPositions(ctx); err != nil {
+		return err
+	}
+	// offsets for all terms, when enabled
+	if err := t.flushOffsets(ctx, fieldNums); err != nil {
+		return err
+	}
+	// payload lengths for all terms, when enabled
+	return t.flushPayloadLengths(ctx)
+}
+
+// forEachField Calls fn for every buffered field, in order.
+func (t *TermVectorsWriter) forEachField(fn func(fd *tvFieldData)) {
+	for _, dd := range t.pendingDocs {
+		for _, fd := range dd.fields {
+			fn(fd)
+		}
+	}
+}
+
+func (t *TermVectorsWriter) flushNumFields(ctx context.Context, chunkDocs int) (int, error) {
+	if chunkDocs == 1 {
+		numFields := t.pendingDocs[0].numFields
+		return numFields, t.vectorsStream.WriteUvarint(ctx, uint64(numFields))
+	}
+
+	totalFields := 0
+	numFields := make([]int, 0, chunkDocs)
+	for _, dd := range t.pendingDocs {
+		numFields = append(numFields, dd.numFields)
+		totalFields += dd.numFields
+	}
+	return totalFields, writeBlockPacked(ctx, t.vectorsStream, numFields)
+}
+
+// flushFieldNums Returns a sorted array containing unique field numbers
+func (t *TermVectorsWriter) flushFieldNums(ctx context.Context) ([]int, error) {
+	var fieldNums []int
+	t.forEachField(func(fd *tvFieldData) {
+		fieldNums = append(fieldNums, fd.fieldNum)
+	})
+	sort.Ints(fieldNums)
+	fieldNums = slices.Compact(fieldNums)
+
+	numDistinctFields := len(fieldNums)
+	bitsRequired := packed.UnsignedBitsRequired(uint64(fieldNums[numDistinctFields-1]))
+	token := min(numDistinctFields-1, 0x07)<<5 | bitsRequired
+	if err := t.vectorsStream.WriteByte(byte(token)); err != nil {
+		return nil, err
+	}
+	if numDistinctFields-1 >= 0x07 {
+		if err := t.vectorsStream.WriteUvarint(ctx, uint64(numDistinctFields-1-0x07)); err != nil {
+			return nil, err
+		}
+	}
+	return fieldNums, writePackedInts(t.vectorsStream, fieldNums, bitsRequired)
+}
+
+func (t *TermVectorsWriter) flushFieldNumOffs(totalFields int, fieldNums []int) error {
+	fieldNumOffs := make([]int, 0, totalFields)
+	t.forEachField(func(fd *tvFieldData) {
+		fieldNumOffs = append(fieldNumOffs, sort.SearchInts(fieldNums, fd.fieldNum))
+	})
+	return writePackedInts(t.vectorsStream, fieldNumOffs, packed.UnsignedBitsRequired(uint64(len(fieldNums)-1)))
+}
+
+func (t *TermVectorsWriter) flushFlags(ctx context.Context, totalFields int, fieldNums []int) error {
+	// check if fields always have the same flags
+	nonChangingFlags := true
+	fieldFlags := make([]int, len(fieldNums))
+	for i := range fieldFlags {
+		fieldFlags[i] = -1
+	}
+	t.forEachField(func(fd *tvFieldData) {
+		fieldNumOff := sort.SearchInts(fieldNums, fd.fieldNum)
+		if fieldFlags[fieldNumOff] == -1 {
+			fieldFlags[fieldNumOff] = fd.flags
+		} else if fieldFlags[fieldNumOff] != fd.flags {
+			nonChangingFlags = false
+		}
+	})
+
+	if nonChangingFlags {
+		// write one flag per field num
+		if err := t.vectorsStream.WriteUvarint(ctx, 0); err != nil {
+			return err
+		}
+		return writePackedInts(t.vectorsStream, fieldFlags, FLAGS_BITS)
+	}
+
+	// write one flag for every field instance
+	if err := t.vectorsStream.WriteUvarint(ctx, 1); err != nil {
+		return err
+	}
+	flags := make([]int, 0, totalFields)
+	t.forEachField(func(fd *tvFieldData) {
+		flags = append(flags, fd.flags)
+	})
+	return writePackedInts(t.vectorsStream, flags, FLAGS_BITS)
+}
+
+func (t *TermVectorsWriter) flushNumTerms(ctx context.Context, totalFields int) error {
+	maxNumTerms := 0
+	numTerms := make([]int, 0, totalFields)
+	t.forEachField(func(fd *tvFieldData) {
+		maxNumTerms |= fd.numTerms
+		numTerms = append(numTerms, fd.numTerms)
+	})
+	bitsRequired := packed.UnsignedBitsRequired(uint64(maxNumTerms))
+	if err := t.vectorsStream.WriteUvarint(ctx, uint64(bitsRequired)); err != nil {
+		return err
+	}
+	return writePackedInts(t.vectorsStream, numTerms, bitsRequired)
+}
+
+func (t *TermVectorsWriter) flushTermLengths(ctx context.Context) error {
+	var prefixLengths, suffixLengths []int
+	t.forEachField(func(fd *tvFieldData) {
+		prefixLengths = append(prefixLengths, fd.prefixLengths...)
+		suffixLengths = append(suffixLengths, fd.suffixLengths...)
+	})
+	if err := writeBlockPacked(ctx, t.vectorsStream, prefixLengths); err != nil {
+		return err
+	}
+	return writeBlockPacked(ctx, t.vectorsStream, suffixLengths)
+}
+
+func (t *TermVectorsWriter) flushTermFreqs(ctx context.Context) error {
+	var freqs []int
+	t.forEachField(func(fd *tvFieldData) {
+		for _, freq := range fd.freqs {
+			freqs = append(freqs, freq-1)
+		}
+	})
+	return writeBlockPacked(ctx, t.vectorsStream, freqs)
+}
+
+func (t *TermVectorsWriter) flushPositions(ctx context.Context) error {
+	var positions []int
+	t.forEachField(func(fd *tvFieldData) {
+		if !fd.hasPositions {
+			return
+		}
+		pos := 0
+		for _, freq := range fd.freqs {
+			previousPosition := 0
+			for j := 0; j < freq; j++ {
+				position := t.positionsBuf[fd.posStart+pos]
+				pos++
+				positions = append(positions, position-previousPosition)
+				previousPosition = position
+			}
+		}
+	})
+	return writeBlockPacked(ctx, t.vectorsStream, positions)
+}
+
+func (t *TermVectorsWriter) flushOffsets(ctx context.Context, fieldNums []int) error {
+	hasOffsets := false
+	sumPos := make([]int64, len(fieldNums))
+	sumOffsets := make([]int64, len(fieldNums))
+	t.forEachField(func(fd *tvFieldData) {
+		hasOffsets = hasOffsets || fd.hasOffsets
+		if fd.hasOffsets && fd.hasPositions {
+			fieldNumOff := sort.SearchInts(fieldNums, fd.fieldNum)
+			pos := 0
+			for _, freq := range fd.freqs {
+				sumPos[fieldNumOff] += int64(t.positionsBuf[fd.posStart+freq-1+pos])
+				sumOffsets[fieldNumOff] += int64(t.startOffsetsBuf[fd.offStart+freq-1+pos])
+				pos += freq
+			}
+		}
+	})
+
+	if !hasOffsets {
+		// nothing to do
+		return nil
+	}
+
+	charsPerTerm := make([]float32, len(fieldNums))
+	for i := range fieldNums {
+		if sumPos[i] > 0 && sumOffsets[i] > 0 {
+			charsPerTerm[i] = float32(float64(sumOffsets[i]) / float64(sumPos[i]))
+		}
+	}
+
+	// start offsets
+	for _, cpt := range charsPerTerm {
+		if err := t.vectorsStream.WriteUint32(ctx, math.Float32bits(cpt)); err != nil {
+			return err
+		}
+	}
+
+	var startOffsets []int
+	t.forEachField(func(fd *tvFieldData) {
+		if fd.flags&OFFSETS == 0 {
+			return
+		}
+		cpt := charsPerTerm[sort.SearchInts(fieldNums, fd.fieldNum)]
+		pos := 0
+		for _, freq := range fd.freqs {
+			previousPos, previousOff := 0, 0
+			for j := 0; j < freq; j++ {
+				position := 0
+				if fd.hasPositions {
+					position = t.positionsBuf[fd.posStart+pos]
+				}
+				startOffset := t.startOffsetsBuf[fd.offStart+pos]
+				startOffsets = append(startOffsets, startOffset-previousOff-int(cpt*float32(position-previousPos)))
+				previousPos = position
+				previousOff = startOffset
+				pos++
+			}
+		}
+	})
+	if err := writeBlockPacked(ctx, t.vectorsStream, startOffsets); err != nil {
+		return err
+	}
+
+	// lengths
+	var lengths []int
+	t.forEachField(func(fd *tvFieldData) {
+		if fd.flags&OFFSETS == 0 {
+			return
+		}
+		pos := 0
+		for i, freq := range fd.freqs {
+			for j := 0; j < freq; j++ {
+				lengths = append(lengths, t.lengthsBuf[fd.offStart+pos]-fd.prefixLengths[i]-fd.suffixLengths[i])
+				pos++
+			}
+		}
+	})
+	return writeBlockPacked(ctx, t.vectorsStream, lengths)
+}
+
+func (t *TermVectorsWriter) flushPayloadLengths(ctx context.Context) error {
+	var payloadLengths []int
+	t.forEachField(func(fd *tvFieldData) {
+		if fd.hasPayloads {
+			payloadLengths = append(payloadLengths, t.payloadLengthsBuf[fd.payStart:fd.payStart+fd.totalPositions]...)
+		}
+	})
+	return writeBlockPacked(ctx, t.vectorsStream, payloadLengths)
+}
+
+func (t *TermVectorsWriter) Finish(ctx context.Context, fieldInfos index.FieldInfos, numDocs int) error {
+	if len(t.pendingDocs) > 0 {
+		t.numDirtyChunks++ // incomplete: we had to force this flush
+		t.numDirtyDocs += int64(len(t.pendingDocs))
+		if err := t.flush(ctx); err != nil {
+			return err
+		}
+	}
+	if numDocs != t.numDocs {
+		return fmt.Errorf("wrote %d docs, finish called with numDocs=%d", t.numDocs, numDocs)
+	}
+
+	if err := t.indexWriter.Finish(ctx, numDocs, t.vectorsStream.GetFilePointer(), t.metaStream); err != nil {
+		return err
+	}
+	if err := t.metaStream.WriteUvarint(ctx, uint64(t.numChunks)); err != nil {
+		return err
+	}
+	if err := t.metaStream.WriteUvarint(ctx, uint64(t.numDirtyChunks)); err != nil {
+		return err
+	}
+	if err := t.metaStream.WriteUvarint(ctx, uint64(t.numDirtyDocs)); err != nil {
+		return err
+	}
+	if err := utils.WriteFooter(t.metaStream); err != nil {
+		return err
+	}
+	return utils.WriteFooter(t.vectorsStream)
+}
@@ -0,0 +1,196 @@
+package lucene50
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+)
+
+const (
+	// DATA_EXTENSION Extension of compound file
+	DATA_EXTENSION = "cfs"
+
+	// ENTRIES_EXTENSION Extension of compound file entries
+	ENTRIES_EXTENSION = "cfe"
+
+	DATA_CODEC  = "Lucene50CompoundData"
+	ENTRY_CODEC = "Lucene50CompoundEntries"
+
+	COMPOUND_VERSION_START   = 0
+	COMPOUND_VERSION_CURRENT = COMPOUND_VERSION_START
+)
+
+var _ index.CompoundFormat = &CompoundFormat{}
+
+// CompoundFormat Lucene 5.0 compound file format
+//
+// Files:
+//   - .cfs: An optional "virtual" file consisting of all the other index files for systems that
+//     frequently run out of file handles.
+//   - .cfe: The "virtual" compound file's entry table holding all entries in the corresponding
+//     .cfs file.
+//
+// Description:
+//   - Compound (.cfs) --> Header, FileData^FileCount, Footer
+//   - Compound Entry Table (.cfe) --> Header, FileCount, <FileName, DataOffset, DataLength>^FileCount,
+//     Footer
+//   - Header --> IndexHeader
+//   - FileCount --> VInt
+//   - DataOffset,DataLength --> Uint64
+//   - FileName --> String
+//   - FileData --> raw file data
+//   - Footer --> CodecFooter
+//
+// Notes:
+//   - FileCount indicates how many files are contained in this compound file. The entry table
+//     that follows has that many entries.
+//   - Each directory entry contains a long pointer to the start of this file's data section, the
+//     files length, and a String with that file's name.
+//   - Every sub-file is copied verbatim, including its own header and footer, after its header id
+//     and its checksum were verified.
+type CompoundFormat struct {
+}
+
+func NewCompoundFormat() *CompoundFormat {
+	return &CompoundFormat{}
+}
+
+func (c *CompoundFormat) GetCompoundReader(ctx context.Context, dir store.Directory, si index.SegmentInfo,
+	ioContext *store.IOContext) (index.CompoundDirectory, error) {
+
+	return NewCompoundReader(ctx, dir, si, ioContext)
+}
+
+func (c *CompoundFormat) Write(ctx context.Context, dir store.Directory, si index.SegmentInfo, ioContext *store.IOContext) error {
+	dataFile := store.SegmentFileName(si.Name(), "", DATA_EXTENSION)
+	entriesFile := store.SegmentFileName(si.Name(), "", ENTRIES_EXTENSION)
+
+	data, err := store.CreateOutputWithContext(ctx, dir, dataFile, ioContext)
+	if err != nil {
+		return err
+	}
+	entries, err := store.CreateOutputWithContext(ctx, dir, entriesFile, ioContext)
+	if err != nil {
+		_ = data.Close()
+		return err
+	}
+
+	if err := c.write(ctx, dir, si, data, entries); err != nil {
+		_ = data.Close()
+		_ = entries.Close()
+		return err
+	}
+	return errors.Join(data.Close(), entries.Close())
+}
+
+func (c *CompoundFormat) write(ctx context.Context, dir store.Directory, si index.SegmentInfo,
+	data, entries store.IndexOutput) error {
+
+	if err := utils.WriteIndexHeader(ctx, data, DATA_CODEC, COMPOUND_VERSION_CURRENT, si.GetID(), ""); err != nil {
+		return err
+	}
+	if err := utils.WriteIndexHeader(ctx, entries, ENTRY_CODEC, COMPOUND_VERSION_CURRENT, si.GetID(), ""); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, si.FilesNum())
+	for name := range si.Files() {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	// write number of files
+	if err := entries.WriteUvarint(ctx, uint64(len(names))); err != nil {
+		return err
+	}
+	for _, name := range names {
+		startOffset := data.GetFilePointer()
+		if err := verifyAndCopy(ctx, dir, name, si.GetID(), data); err != nil {
+			return err
+		}
+		endOffset := data.GetFilePointer()
+
+		// write entry for file
+		if err := entries.WriteString(ctx, coreIndex.StripSegmentName(name)); err != nil {
+			return err
+		}
+		if err := entries.WriteUint64(ctx, uint64(startOffset)); err != nil {
+			return err
+		}
+		if err := entries.WriteUint64(ctx, uint64(endOffset-startOffset)); err != nil {
+			return err
+		}
+	}
+
+	if err := utils.WriteFooter(data); err != nil {
+		return err
+	}
+	return utils.WriteFooter(entries)
+}
+
+// verifyAndCopy copies the file name to out, checking that its header carries the segment id
+// and that its content matches the checksum of its footer.
+func verifyAndCopy(ctx context.Context, dir store.Directory, name string, id []byte, out store.IndexOutput) error {
+	in, err := dir.OpenInput(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := verifyIndexHeaderID(ctx, in, id); err != nil {
+		return fmt.Errorf("%w (resource=%s)", err, name)
+	}
+	if in.Length() < int64(utils.FooterLength()) {
+		return fmt.Errorf("misplaced codec footer (file truncated?): length=%d (resource=%s)", in.Length(), name)
+	}
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	checksumIn := store.NewBufferedChecksumIndexInput(in)
+	numBytesToCopy := int(in.Length()) - utils.FooterLength()
+	if err := out.CopyBytes(ctx, checksumIn, numBytesToCopy); err != nil {
+		return err
+	}
+	checksum, err := utils.CheckCodecFooter(checksumIn)
+	if err != nil {
+		return fmt.Errorf("%w (resource=%s)", err, name)
+	}
+
+	// copy the footer
+	if err := out.WriteUint32(ctx, utils.FOOTER_MAGIC); err != nil {
+		return err
+	}
+	if err := out.WriteUint32(ctx, 0); err != nil {
+		return err
+	}
+	return out.WriteUint64(ctx, checksum)
+}
+
+// verifyIndexHeaderID reads the index header of in, without knowing its codec, and checks that
+// it was written for the segment id.
+func verifyIndexHeaderID(ctx context.Context, in store.DataInput, id []byte) error {
+	magic, err := in.ReadUint32(ctx)
+	if err != nil {
+		return err
+	}
+	if magic != utils.CODEC_MAGIC {
+		return fmt.Errorf("codec header mismatch: actual header=%d vs expected header=%d", magic, utils.CODEC_MAGIC)
+	}
+	// codec name
+	if _, err := in.ReadString(ctx); err != nil {
+		return err
+	}
+	// version
+	if _, err := in.ReadUint32(ctx); err != nil {
+		return err
+	}
+	return utils.CheckIndexHeaderID(in, id)
+}
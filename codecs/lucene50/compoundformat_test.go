@@ -0,0 +1,146 @@
+package lucene50
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestFile writes a file with an index header, size random bytes and a footer, and
+// returns its whole content.
+func writeTestFile(t *testing.T, dir store.Directory, name string, id []byte, size int, r *rand.Rand) []byte {
+	ctx := context.Background()
+
+	out, err := dir.CreateOutput(ctx, name)
+	assert.Nil(t, err)
+	assert.Nil(t, utils.WriteIndexHeader(ctx, out, "TestCompound", 0, id, ""))
+	data := make([]byte, size)
+	r.Read(data)
+	_, err = out.Write(data)
+	assert.Nil(t, err)
+	assert.Nil(t, utils.WriteFooter(out))
+	assert.Nil(t, out.Close())
+
+	in, err := dir.OpenInput(ctx, name)
+	assert.Nil(t, err)
+	defer in.Close()
+	content := make([]byte, in.Length())
+	_, err = io.ReadFull(in, content)
+	assert.Nil(t, err)
+	return content
+}
+
+func TestCompoundFormat(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(5))
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	id := []byte("0123456789abcdef")
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_1", 10, false, nil,
+		map[string]string{}, id, map[string]string{}, nil)
+
+	contents := map[string][]byte{}
+	for name, size := range map[string]int{"_1.fnm": 0, "_1.si": 10, "_1_Lucene84_0.doc": 5000, "_1.fdt": 70000} {
+		contents[name] = writeTestFile(t, dir, name, id, size, r)
+		assert.Nil(t, si.AddFile(name))
+	}
+
+	format := NewCompoundFormat()
+	assert.Nil(t, format.Write(ctx, dir, si, store.DEFAULT))
+
+	cfs, err := format.GetCompoundReader(ctx, dir, si, store.DEFAULT)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, cfs.CheckIntegrity())
+
+	names, err := cfs.ListAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"_1.fdt", "_1.fnm", "_1.si", "_1_Lucene84_0.doc"}, names)
+
+	for name, expected := range contents {
+		length, err := cfs.FileLength(ctx, name)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(len(expected)), length)
+
+		in, err := cfs.OpenInput(ctx, name)
+		if !assert.Nil(t, err) {
+			continue
+		}
+		actual := make([]byte, in.Length())
+		_, err = io.ReadFull(in, actual)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual, name)
+
+		// sub-files keep their own header and footer
+		_, err = utils.ChecksumEntireFile(in)
+		assert.Nil(t, err)
+		assert.Nil(t, in.Close())
+	}
+
+	_, err = cfs.OpenInput(ctx, "_1.missing")
+	assert.NotNil(t, err)
+	_, err = cfs.CreateOutput(ctx, "_1.new")
+	assert.NotNil(t, err)
+	assert.NotNil(t, cfs.DeleteFile(ctx, "_1.si"))
+
+	assert.Nil(t, cfs.Close())
+	_, err = cfs.OpenInput(ctx, "_1.si")
+	assert.NotNil(t, err)
+}
+
+func TestCompoundFormatCorruption(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(5))
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	id := []byte("0123456789abcdef")
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_2", 10, false, nil,
+		map[string]string{}, id, map[string]string{}, nil)
+	writeTestFile(t, dir, "_2.fdt", id, 1000, r)
+	assert.Nil(t, si.AddFile("_2.fdt"))
+
+	format := NewCompoundFormat()
+	assert.Nil(t, format.Write(ctx, dir, si, store.DEFAULT))
+
+	// flip a byte in the middle of the compound file
+	cfsName := store.SegmentFileName("_2", "", DATA_EXTENSION)
+	in, err := dir.OpenInput(ctx, cfsName)
+	assert.Nil(t, err)
+	data := make([]byte, in.Length())
+	_, err = io.ReadFull(in, data)
+	assert.Nil(t, err)
+	assert.Nil(t, in.Close())
+	data[len(data)/2] ^= 0xFF
+	assert.Nil(t, dir.DeleteFile(ctx, cfsName))
+	out, err := dir.CreateOutput(ctx, cfsName)
+	assert.Nil(t, err)
+	_, err = out.Write(data)
+	assert.Nil(t, err)
+	assert.Nil(t, out.Close())
+
+	cfs, err := format.GetCompoundReader(ctx, dir, si, store.DEFAULT)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.NotNil(t, cfs.CheckIntegrity())
+	assert.Nil(t, cfs.Close())
+
+	// a file of another segment can not be packed
+	other := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_3", 10, false, nil,
+		map[string]string{}, []byte("fedcba9876543210"), map[string]string{}, nil)
+	writeTestFile(t, dir, "_3.fdt", id, 10, r)
+	assert.Nil(t, other.AddFile("_3.fdt"))
+	assert.NotNil(t, format.Write(ctx, dir, other, store.DEFAULT))
+}
@@ -0,0 +1,216 @@
+package lucene50
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+)
+
+var _ index.CompoundDirectory = &CompoundReader{}
+
+// CompoundReader Class for accessing a compound stream. This class implements a directory, but is
+// limited to only read operations. Directory methods that would normally modify data return an error.
+//
+// Sub-files are returned as slices of the single handle that is kept open on the .cfs file, no
+// data is copied and no additional file handle is opened.
+// lucene.experimental
+type CompoundReader struct {
+	*coreIndex.BaseCompoundDirectory
+
+	directory   store.Directory
+	segmentName string
+	entries     map[string]*fileEntry
+	handle      store.IndexInput
+	version     int
+	closed      bool
+}
+
+// fileEntry Offset/Length for a slice inside of a compound file
+type fileEntry struct {
+	offset int64
+	length int64
+}
+
+// NewCompoundReader Create a new CompoundFileDirectory.
+func NewCompoundReader(ctx context.Context, directory store.Directory, si index.SegmentInfo,
+	ioContext *store.IOContext) (*CompoundReader, error) {
+
+	dataFileName := store.SegmentFileName(si.Name(), "", DATA_EXTENSION)
+	entriesFileName := store.SegmentFileName(si.Name(), "", ENTRIES_EXTENSION)
+
+	reader := &CompoundReader{
+		BaseCompoundDirectory: &coreIndex.BaseCompoundDirectory{},
+		directory:             directory,
+		segmentName:           si.Name(),
+	}
+
+	var err error
+	reader.version, reader.entries, err = readEntries(ctx, si.GetID(), directory, entriesFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedLength := int64(utils.IndexHeaderLength(DATA_CODEC, ""))
+	for _, entry := range reader.entries {
+		expectedLength = max(expectedLength, entry.offset+entry.length)
+	}
+	expectedLength += int64(utils.FooterLength())
+
+	if reader.handle, err = store.OpenInputWithContext(ctx, directory, dataFileName, ioContext); err != nil {
+		return nil, err
+	}
+	closeOnError := func(err error) (*CompoundReader, error) {
+		_ = reader.handle.Close()
+		return nil, err
+	}
+
+	if _, err := utils.CheckIndexHeader(ctx, reader.handle, DATA_CODEC, reader.version, reader.version,
+		si.GetID(), ""); err != nil {
+		return closeOnError(err)
+	}
+
+	// NOTE: data file is too costly to verify checksum against all the bytes on open,
+	// but for now we at least verify proper structure of the checksum footer: which looks
+	// for FOOTER_MAGIC + algorithmID. This is cheap and can detect some forms of corruption
+	// such as file truncation.
+	if _, err := utils.RetrieveChecksum(reader.handle); err != nil {
+		return closeOnError(err)
+	}
+
+	// We also validate length, because e.g. if you strip 16 bytes off the .cfs we otherwise
+	// would not detect it:
+	if reader.handle.Length() != expectedLength {
+		return closeOnError(fmt.Errorf("length should be %d bytes, but is %d instead",
+			expectedLength, reader.handle.Length()))
+	}
+	return reader, nil
+}
+
+// readEntries Helper method that reads CFS entries from an input stream
+func readEntries(ctx context.Context, segmentID []byte, dir store.Directory,
+	entriesFileName string) (int, map[string]*fileEntry, error) {
+
+	entriesStream, err := store.OpenChecksumInput(dir, entriesFileName)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer entriesStream.Close()
+
+	version, err := utils.CheckIndexHeader(ctx, entriesStream, ENTRY_CODEC, COMPOUND_VERSION_START,
+		COMPOUND_VERSION_CURRENT, segmentID, "")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	numEntries, err := entriesStream.ReadUvarint(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	mapping := make(map[string]*fileEntry, numEntries)
+	for i := 0; i < int(numEntries); i++ {
+		id, err := entriesStream.ReadString(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+		if _, ok := mapping[id]; ok {
+			return 0, nil, fmt.Errorf("duplicate cfs entry id=%s in CFS", id)
+		}
+		offset, err := entriesStream.ReadUint64(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+		length, err := entriesStream.ReadUint64(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+		mapping[id] = &fileEntry{offset: int64(offset), length: int64(length)}
+	}
+
+	if _, err := utils.CheckCodecFooter(entriesStream); err != nil {
+		return 0, nil, err
+	}
+	return version, mapping, nil
+}
+
+func (c *CompoundReader) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.handle.Close()
+}
+
+func (c *CompoundReader) EnsureOpen() error {
+	if c.closed {
+		return errors.New("this Directory is closed")
+	}
+	return nil
+}
+
+func (c *CompoundReader) OpenInput(ctx context.Context, name string) (store.IndexInput, error) {
+	if err := c.EnsureOpen(); err != nil {
+		return nil, err
+	}
+	id := coreIndex.StripSegmentName(name)
+	entry, ok := c.entries[id]
+	if !ok {
+		return nil, fmt.Errorf("no sub-file with id %s found in compound file \"%s\" (fileName=%s files: %v)",
+			id, store.SegmentFileName(c.segmentName, "", DATA_EXTENSION), name, c.ids())
+	}
+	return c.handle.Slice(name, entry.offset, entry.length)
+}
+
+func (c *CompoundReader) ids() []string {
+	ids := make([]string, 0, len(c.entries))
+	for id := range c.entries {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// ListAll Returns an array of strings, one for each file in the directory.
+func (c *CompoundReader) ListAll(ctx context.Context) ([]string, error) {
+	if err := c.EnsureOpen(); err != nil {
+		return nil, err
+	}
+	ids := c.ids()
+	// Add the segment name
+	for i, id := range ids {
+		ids[i] = c.segmentName + id
+	}
+	return ids, nil
+}
+
+// FileLength Returns the length of a file in the directory.
+func (c *CompoundReader) FileLength(ctx context.Context, name string) (int64, error) {
+	if err := c.EnsureOpen(); err != nil {
+		return 0, err
+	}
+	entry, ok := c.entries[coreIndex.StripSegmentName(name)]
+	if !ok {
+		return 0, fmt.Errorf("file %s not found", name)
+	}
+	return entry.length, nil
+}
+
+func (c *CompoundReader) CopyFrom(ctx context.Context, from store.Directory, src, dest string, ioContext *store.IOContext) error {
+	return coreIndex.ErrUnsupportedOperation
+}
+
+// CheckIntegrity Verifies the checksum of the whole compound file, the checksums of the
+// sub-files were verified when they were copied into it.
+func (c *CompoundReader) CheckIntegrity() error {
+	_, err := utils.ChecksumEntireFile(c.handle)
+	return err
+}
+
+func (c *CompoundReader) String() string {
+	return fmt.Sprintf("CompoundFileDirectory(segment=\"%s\" in dir=%v)", c.segmentName, c.directory)
+}
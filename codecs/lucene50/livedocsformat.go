@@ -0,0 +1,143 @@
+package lucene50
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/bits-and-blooms/bitset"
+	"github.com/geange/lucene-go/codecs/utils"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util"
+)
+
+const (
+	// LIVEDOCS_EXTENSION extension of deletes
+	LIVEDOCS_EXTENSION = "liv"
+
+	// LIVEDOCS_CODEC_NAME codec of deletes
+	LIVEDOCS_CODEC_NAME = "Lucene50LiveDocs"
+
+	LIVEDOCS_VERSION_START   = 0
+	LIVEDOCS_VERSION_CURRENT = LIVEDOCS_VERSION_START
+)
+
+var _ index.LiveDocsFormat = &LiveDocsFormat{}
+
+// LiveDocsFormat Lucene 5.0 live docs format
+//
+// The .liv file is optional, and only exists when a segment contains deletions.
+//
+// Although per-segment, this file is maintained exterior to compound segment files.
+//
+// Deletions (.liv) --> IndexHeader,Generation,Bits
+//   - SegmentHeader --> IndexHeader
+//   - Bits --> <Int64> LongCount
+type LiveDocsFormat struct {
+}
+
+func NewLiveDocsFormat() *LiveDocsFormat {
+	return &LiveDocsFormat{}
+}
+
+func (l *LiveDocsFormat) ReadLiveDocs(ctx context.Context, dir store.Directory, info index.SegmentCommitInfo, ioContext *store.IOContext) (util.Bits, error) {
+	if !info.HasDeletions() {
+		return nil, errors.New("segment has no deletions")
+	}
+
+	gen := info.GetDelGen()
+	name := coreIndex.FileNameFromGeneration(info.Info().Name(), LIVEDOCS_EXTENSION, gen)
+	length, err := info.Info().MaxDoc()
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := store.OpenChecksumInput(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	if _, err := utils.CheckIndexHeader(ctx, input, LIVEDOCS_CODEC_NAME, LIVEDOCS_VERSION_START,
+		LIVEDOCS_VERSION_CURRENT, info.Info().GetID(), strconv.FormatInt(gen, 36)); err != nil {
+		return nil, err
+	}
+
+	words := make([]uint64, bits2words(length))
+	for i := range words {
+		if words[i], err = input.ReadUint64(ctx); err != nil {
+			return nil, err
+		}
+	}
+	bits := bitset.FromWithLength(uint(length), words)
+
+	if deleted := length - int(bits.Count()); deleted != info.GetDelCount() {
+		return nil, fmt.Errorf("bits.deleted=%d info.delcount=%d", deleted, info.GetDelCount())
+	}
+	if _, err := utils.CheckCodecFooter(input); err != nil {
+		return nil, err
+	}
+	return bits, nil
+}
+
+func (l *LiveDocsFormat) WriteLiveDocs(ctx context.Context, bits util.Bits, dir store.Directory,
+	info index.SegmentCommitInfo, newDelCount int, ioContext *store.IOContext) error {
+
+	gen := info.GetNextDelGen()
+	name := coreIndex.FileNameFromGeneration(info.Info().Name(), LIVEDOCS_EXTENSION, gen)
+
+	length := int(bits.Len())
+	words := make([]uint64, bits2words(length))
+	delCount := 0
+	for i := 0; i < length; i++ {
+		if bits.Test(uint(i)) {
+			words[i>>6] |= 1 << (i & 63)
+		} else {
+			delCount++
+		}
+	}
+	if delCount != info.GetDelCount()+newDelCount {
+		return fmt.Errorf("bits.deleted=%d info.delcount=%d newdelcount=%d",
+			delCount, info.GetDelCount(), newDelCount)
+	}
+
+	output, err := store.CreateOutputWithContext(ctx, dir, name, ioContext)
+	if err != nil {
+		return err
+	}
+	if err := l.writeBits(ctx, output, info, gen, words); err != nil {
+		_ = output.Close()
+		return err
+	}
+	return output.Close()
+}
+
+func (l *LiveDocsFormat) writeBits(ctx context.Context, output store.IndexOutput,
+	info index.SegmentCommitInfo, gen int64, words []uint64) error {
+
+	if err := utils.WriteIndexHeader(ctx, output, LIVEDOCS_CODEC_NAME, LIVEDOCS_VERSION_CURRENT,
+		info.Info().GetID(), strconv.FormatInt(gen, 36)); err != nil {
+		return err
+	}
+	for _, word := range words {
+		if err := output.WriteUint64(ctx, word); err != nil {
+			return err
+		}
+	}
+	return utils.WriteFooter(output)
+}
+
+func (l *LiveDocsFormat) Files(ctx context.Context, info index.SegmentCommitInfo, files map[string]struct{}) (map[string]struct{}, error) {
+	if info.HasDeletions() {
+		files[coreIndex.FileNameFromGeneration(info.Info().Name(), LIVEDOCS_EXTENSION, info.GetDelGen())] = struct{}{}
+	}
+	return files, nil
+}
+
+// bits2words returns the number of 64 bit words it would take to hold numBits
+func bits2words(numBits int) int {
+	return (numBits + 63) >> 6
+}
@@ -0,0 +1,70 @@
+package lucene50
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/bits-and-blooms/bitset"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiveDocsFormat(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(3))
+
+	for _, maxDoc := range []int{1, 63, 64, 65, 1000} {
+		dir, err := store.NewNIOFSDirectory(t.TempDir())
+		assert.Nil(t, err)
+		id := make([]byte, 16)
+		r.Read(id)
+		si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", maxDoc,
+			false, nil, map[string]string{}, id, map[string]string{}, nil)
+		info := index.NewSegmentCommitInfo(si, 0, 0, -1, -1, -1, nil)
+
+		format := NewLiveDocsFormat()
+		files, err := format.Files(ctx, info, map[string]struct{}{})
+		assert.Nil(t, err)
+		assert.Len(t, files, 0)
+
+		// two generations of deletes
+		bits := bitset.New(uint(maxDoc))
+		for i := 0; i < maxDoc; i++ {
+			bits.Set(uint(i))
+		}
+		for gen := int64(1); gen <= 2; gen++ {
+			newDelCount := 0
+			for i := 0; i < maxDoc; i++ {
+				if bits.Test(uint(i)) && r.Intn(4) == 0 {
+					bits.Clear(uint(i))
+					newDelCount++
+				}
+			}
+
+			// the number of deletions must match
+			assert.NotNil(t, format.WriteLiveDocs(ctx, bits, dir, info, newDelCount+1, store.DEFAULT))
+
+			assert.Nil(t, format.WriteLiveDocs(ctx, bits, dir, info, newDelCount, store.DEFAULT))
+			info.AdvanceDelGen()
+			info.SetDelCount(info.GetDelCount() + newDelCount)
+			assert.Equal(t, gen, info.GetDelGen())
+
+			files, err := format.Files(ctx, info, map[string]struct{}{})
+			assert.Nil(t, err)
+			assert.Contains(t, files, coreIndex.FileNameFromGeneration("_0", LIVEDOCS_EXTENSION, gen))
+
+			liveDocs, err := format.ReadLiveDocs(ctx, dir, info, store.DEFAULT)
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, uint(maxDoc), liveDocs.Len())
+			for i := 0; i < maxDoc; i++ {
+				assert.Equal(t, bits.Test(uint(i)), liveDocs.Test(uint(i)))
+			}
+		}
+	}
+}
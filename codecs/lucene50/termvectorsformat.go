@@ -0,0 +1,76 @@
+package lucene50
+
+import (
+	"context"
+
+	"github.com/geange/lucene-go/codecs/compressing"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+)
+
+const (
+	TERM_VECTORS_FORMAT_NAME = "Lucene50TermVectorsData"
+
+	// TERM_VECTORS_CHUNK_SIZE Term vectors are mostly read one document at a time, small chunks
+	// keep the amount of data to decompress per document low.
+	TERM_VECTORS_CHUNK_SIZE         = 1 << 12
+	TERM_VECTORS_MAX_DOCS_PER_CHUNK = 128
+
+	// TERM_VECTORS_BLOCK_SHIFT Number of chunks per block of the term vectors index, as a power of 2.
+	TERM_VECTORS_BLOCK_SHIFT = 10
+)
+
+var _ index.TermVectorsFormat = &TermVectorsFormat{}
+
+// TermVectorsFormat Lucene 5.0 term vectors format.
+//
+// Very similarly to the stored fields format, this format is based on compressed chunks of data,
+// with document-level granularity so that a document can never span across distinct chunks.
+// Moreover, data is made as compact as possible:
+//   - textual data is compressed with LZ4,
+//   - the per-field and per-term metadata (field numbers, flags, term counts, prefix and suffix
+//     lengths, frequencies, positions and offsets) is stored with packed ints,
+//   - start offsets are predicted from the positions and offset lengths from the term lengths,
+//     only the differences are stored.
+//
+// Files:
+//   - A vector data file (extension .tvd). This file stores terms, frequencies, positions,
+//     offsets and payloads for every document, one compressed chunk after the other.
+//   - An index file (extension .tvx). This file stores the first document and the start pointer
+//     of every chunk as monotonic values, so that loading the vectors of a document only needs to
+//     read and decompress the chunk that contains it.
+//   - A meta file (extension .tvm). This file stores metadata about the index and the data files,
+//     such as the chunk size, the number of chunks and the number of incomplete chunks.
+//
+// lucene.experimental
+type TermVectorsFormat struct {
+}
+
+func NewTermVectorsFormat() *TermVectorsFormat {
+	return &TermVectorsFormat{}
+}
+
+func (t *TermVectorsFormat) VectorsReader(ctx context.Context, directory store.Directory, segmentInfo index.SegmentInfo,
+	fieldInfos index.FieldInfos, ioContext *store.IOContext) (index.TermVectorsReader, error) {
+
+	format, err := t.impl()
+	if err != nil {
+		return nil, err
+	}
+	return format.VectorsReader(ctx, directory, segmentInfo, fieldInfos, ioContext)
+}
+
+func (t *TermVectorsFormat) VectorsWriter(ctx context.Context, directory store.Directory, segmentInfo index.SegmentInfo,
+	ioContext *store.IOContext) (index.TermVectorsWriter, error) {
+
+	format, err := t.impl()
+	if err != nil {
+		return nil, err
+	}
+	return format.VectorsWriter(ctx, directory, segmentInfo, ioContext)
+}
+
+func (t *TermVectorsFormat) impl() (*compressing.TermVectorsFormat, error) {
+	return compressing.NewTermVectorsFormat(TERM_VECTORS_FORMAT_NAME, "", compressing.FAST,
+		TERM_VECTORS_CHUNK_SIZE, TERM_VECTORS_MAX_DOCS_PER_CHUNK, TERM_VECTORS_BLOCK_SHIFT)
+}
@@ -0,0 +1,368 @@
+package lucene60
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+)
+
+const (
+	// FIELD_INFOS_EXTENSION Extension of field infos
+	FIELD_INFOS_EXTENSION = "fnm"
+
+	// FIELD_INFOS_CODEC_NAME Codec header
+	FIELD_INFOS_CODEC_NAME = "Lucene60FieldInfos"
+
+	FORMAT_START              = 0
+	FORMAT_SOFT_DELETES       = 1
+	FORMAT_SELECTIVE_INDEXING = 2
+	FORMAT_CURRENT            = FORMAT_SELECTIVE_INDEXING
+
+	// Field flags
+	STORE_TERMVECTOR   = 0x1
+	OMIT_NORMS         = 0x2
+	STORE_PAYLOADS     = 0x4
+	SOFT_DELETES_FIELD = 0x8
+)
+
+var _ index.FieldInfosFormat = &FieldInfosFormat{}
+
+// FieldInfosFormat Lucene 6.0 Field Infos format.
+//
+// Field names are stored in the field info file, with suffix .fnm.
+//
+// FieldInfos (.fnm) --> Header,FieldsCount, <FieldName,FieldNumber, FieldBits,DocValuesBits,DocValuesGen,
+// Attributes,DimensionCount,DimensionNumBytes> FieldsCount,Footer
+//
+// Data types:
+//   - Header --> IndexHeader
+//   - FieldsCount --> VInt
+//   - FieldName --> String
+//   - FieldBits, IndexOptions, DocValuesBits --> Byte
+//   - FieldNumber, DimensionCount, DimensionNumBytes --> VInt
+//   - Attributes --> Map<String,String>
+//   - DocValuesGen --> Int64
+//   - Footer --> CodecFooter
+//
+// Field Descriptions:
+//   - FieldsCount: the number of fields in this file.
+//   - FieldName: name of the field as a UTF-8 String.
+//   - FieldNumber: the field's number. Note that unlike previous versions of Lucene, the fields are
+//     not numbered implicitly by their order in the file, instead explicitly.
+//   - FieldBits: a byte containing field options.
+//     The low order bit (0x1) is one for fields that have term vectors stored, and zero for fields
+//     without term vectors.
+//     If the second lowest order-bit is set (0x2), norms are omitted for the indexed field.
+//     If the third lowest-order bit is set (0x4), payloads are stored for the indexed field.
+//   - IndexOptions: a byte containing index options.
+//     0: not indexed
+//     1: indexed as DOCS_ONLY
+//     2: indexed as DOCS_AND_FREQS
+//     3: indexed as DOCS_AND_FREQS_AND_POSITIONS
+//     4: indexed as DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS
+//   - DocValuesBits: a byte containing per-document value types:
+//     0: no DocValues for this field.
+//     1: NumericDocValues. (DocValuesType.NUMERIC)
+//     2: BinaryDocValues. (DocValuesType.BINARY)
+//     3: SortedDocValues. (DocValuesType.SORTED)
+//     4: SortedSetDocValues. (DocValuesType.SORTED_SET)
+//     5: SortedNumericDocValues. (DocValuesType.SORTED_NUMERIC)
+//   - DocValuesGen is the generation count of the field's DocValues. If this is -1, there are no
+//     DocValues updates to that field. Anything above zero means there are updates stored by
+//     DocValuesFormat.
+//   - Attributes: a key-value map of codec-private attributes.
+//   - PointDimensionCount, PointNumBytes: these are non-zero only if the field is indexed as points,
+//     e.g. using LongPoint
+//   - SoftDeletesFlag: true if this field is used as a soft-deletes field
+//
+// lucene.experimental
+type FieldInfosFormat struct {
+}
+
+func NewFieldInfosFormat() *FieldInfosFormat {
+	return &FieldInfosFormat{}
+}
+
+func (f *FieldInfosFormat) Read(ctx context.Context, directory store.Directory, segmentInfo index.SegmentInfo,
+	segmentSuffix string, ioContext *store.IOContext) (index.FieldInfos, error) {
+
+	fileName := store.SegmentFileName(segmentInfo.Name(), segmentSuffix, FIELD_INFOS_EXTENSION)
+	input, err := store.OpenChecksumInput(directory, fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	version, err := utils.CheckIndexHeader(ctx, input, FIELD_INFOS_CODEC_NAME, FORMAT_START, FORMAT_CURRENT,
+		segmentInfo.GetID(), segmentSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := input.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]*document.FieldInfo, 0, size)
+	for i := 0; i < int(size); i++ {
+		info, err := ReadFieldInfo(ctx, input, version)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+
+	if _, err := utils.CheckCodecFooter(input); err != nil {
+		return nil, err
+	}
+	return coreIndex.NewFieldInfos(infos), nil
+}
+
+// ReadFieldInfo
+// Reads the entry of a field written with the format version, the later formats append their own
+// attributes to it.
+func ReadFieldInfo(ctx context.Context, input store.DataInput, version int) (*document.FieldInfo, error) {
+	name, err := input.ReadString(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fieldNumber, err := input.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if int32(fieldNumber) < 0 {
+		return nil, fmt.Errorf("invalid field number for field: %s, fieldNumber=%d", name, int32(fieldNumber))
+	}
+
+	bits, err := input.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	storeTermVector := bits&STORE_TERMVECTOR != 0
+	omitNorms := bits&OMIT_NORMS != 0
+	storePayloads := bits&STORE_PAYLOADS != 0
+	isSoftDeletesField := bits&SOFT_DELETES_FIELD != 0
+
+	b, err := input.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	indexOptions, err := getIndexOptions(b)
+	if err != nil {
+		return nil, err
+	}
+
+	// DV Types are packed in one byte
+	if b, err = input.ReadByte(); err != nil {
+		return nil, err
+	}
+	docValuesType, err := getDocValuesType(b)
+	if err != nil {
+		return nil, err
+	}
+
+	dvGen, err := input.ReadUint64(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := input.ReadMapOfStrings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pointDataDimensionCount, err := input.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pointIndexDimensionCount, pointNumBytes := uint64(0), uint64(0)
+	if pointDataDimensionCount != 0 {
+		if version >= FORMAT_SELECTIVE_INDEXING {
+			if pointIndexDimensionCount, err = input.ReadUvarint(ctx); err != nil {
+				return nil, err
+			}
+		} else {
+			pointIndexDimensionCount = pointDataDimensionCount
+		}
+		if pointNumBytes, err = input.ReadUvarint(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return document.NewFieldInfo(name, int(fieldNumber), storeTermVector, omitNorms, storePayloads,
+		indexOptions, docValuesType, int64(dvGen), attributes, int(pointDataDimensionCount),
+		int(pointIndexDimensionCount), int(pointNumBytes), isSoftDeletesField), nil
+}
+
+func (f *FieldInfosFormat) Write(ctx context.Context, directory store.Directory, segmentInfo index.SegmentInfo,
+	segmentSuffix string, infos index.FieldInfos, ioContext *store.IOContext) error {
+
+	fileName := store.SegmentFileName(segmentInfo.Name(), segmentSuffix, FIELD_INFOS_EXTENSION)
+	output, err := store.CreateOutputWithContext(ctx, directory, fileName, ioContext)
+	if err != nil {
+		return err
+	}
+	if err := f.write(ctx, output, segmentInfo, segmentSuffix, infos); err != nil {
+		_ = output.Close()
+		return err
+	}
+	return output.Close()
+}
+
+func (f *FieldInfosFormat) write(ctx context.Context, output store.IndexOutput, segmentInfo index.SegmentInfo,
+	segmentSuffix string, infos index.FieldInfos) error {
+
+	if err := utils.WriteIndexHeader(ctx, output, FIELD_INFOS_CODEC_NAME, FORMAT_CURRENT,
+		segmentInfo.GetID(), segmentSuffix); err != nil {
+		return err
+	}
+	if err := output.WriteUvarint(ctx, uint64(infos.Size())); err != nil {
+		return err
+	}
+	for _, fi := range infos.List() {
+		if err := WriteFieldInfo(ctx, output, fi); err != nil {
+			return err
+		}
+	}
+	return utils.WriteFooter(output)
+}
+
+// WriteFieldInfo
+// Writes the entry of a field with FORMAT_CURRENT, the later formats append their own attributes to it.
+func WriteFieldInfo(ctx context.Context, output store.DataOutput, fi *document.FieldInfo) error {
+	if err := output.WriteString(ctx, fi.Name()); err != nil {
+		return err
+	}
+	if err := output.WriteUvarint(ctx, uint64(fi.Number())); err != nil {
+		return err
+	}
+
+	bits := byte(0)
+	if fi.HasVectors() {
+		bits |= STORE_TERMVECTOR
+	}
+	if fi.OmitsNorms() {
+		bits |= OMIT_NORMS
+	}
+	if fi.HasPayloads() {
+		bits |= STORE_PAYLOADS
+	}
+	if fi.IsSoftDeletesField() {
+		bits |= SOFT_DELETES_FIELD
+	}
+	if err := output.WriteByte(bits); err != nil {
+		return err
+	}
+
+	indexOptions, err := indexOptionsByte(fi.GetIndexOptions())
+	if err != nil {
+		return err
+	}
+	if err := output.WriteByte(indexOptions); err != nil {
+		return err
+	}
+
+	docValuesType, err := docValuesByte(fi.GetDocValuesType())
+	if err != nil {
+		return err
+	}
+	if err := output.WriteByte(docValuesType); err != nil {
+		return err
+	}
+	if err := output.WriteUint64(ctx, uint64(fi.GetDocValuesGen())); err != nil {
+		return err
+	}
+	if err := output.WriteMapOfStrings(ctx, fi.Attributes()); err != nil {
+		return err
+	}
+
+	if err := output.WriteUvarint(ctx, uint64(fi.GetPointDimensionCount())); err != nil {
+		return err
+	}
+	if fi.GetPointDimensionCount() != 0 {
+		if err := output.WriteUvarint(ctx, uint64(fi.GetPointIndexDimensionCount())); err != nil {
+			return err
+		}
+		if err := output.WriteUvarint(ctx, uint64(fi.GetPointNumBytes())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func docValuesByte(docValuesType document.DocValuesType) (byte, error) {
+	switch docValuesType {
+	case document.DOC_VALUES_TYPE_NONE:
+		return 0, nil
+	case document.DOC_VALUES_TYPE_NUMERIC:
+		return 1, nil
+	case document.DOC_VALUES_TYPE_BINARY:
+		return 2, nil
+	case document.DOC_VALUES_TYPE_SORTED:
+		return 3, nil
+	case document.DOC_VALUES_TYPE_SORTED_SET:
+		return 4, nil
+	case document.DOC_VALUES_TYPE_SORTED_NUMERIC:
+		return 5, nil
+	default:
+		return 0, fmt.Errorf("unhandled DocValuesType: %s", docValuesType)
+	}
+}
+
+func getDocValuesType(b byte) (document.DocValuesType, error) {
+	switch b {
+	case 0:
+		return document.DOC_VALUES_TYPE_NONE, nil
+	case 1:
+		return document.DOC_VALUES_TYPE_NUMERIC, nil
+	case 2:
+		return document.DOC_VALUES_TYPE_BINARY, nil
+	case 3:
+		return document.DOC_VALUES_TYPE_SORTED, nil
+	case 4:
+		return document.DOC_VALUES_TYPE_SORTED_SET, nil
+	case 5:
+		return document.DOC_VALUES_TYPE_SORTED_NUMERIC, nil
+	default:
+		return 0, fmt.Errorf("invalid docvalues byte: %d", b)
+	}
+}
+
+func indexOptionsByte(indexOptions document.IndexOptions) (byte, error) {
+	switch indexOptions {
+	case document.INDEX_OPTIONS_NONE:
+		return 0, nil
+	case document.INDEX_OPTIONS_DOCS:
+		return 1, nil
+	case document.INDEX_OPTIONS_DOCS_AND_FREQS:
+		return 2, nil
+	case document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS:
+		return 3, nil
+	case document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS:
+		return 4, nil
+	default:
+		return 0, fmt.Errorf("unhandled IndexOptions: %s", indexOptions)
+	}
+}
+
+func getIndexOptions(b byte) (document.IndexOptions, error) {
+	switch b {
+	case 0:
+		return document.INDEX_OPTIONS_NONE, nil
+	case 1:
+		return document.INDEX_OPTIONS_DOCS, nil
+	case 2:
+		return document.INDEX_OPTIONS_DOCS_AND_FREQS, nil
+	case 3:
+		return document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS, nil
+	case 4:
+		return document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS, nil
+	default:
+		return 0, fmt.Errorf("invalid IndexOptions byte: %d", b)
+	}
+}
@@ -0,0 +1,72 @@
+package lucene60
+
+import (
+	"context"
+	"testing"
+
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldInfosFormat(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	id := []byte("0123456789abcdef")
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", 10, false, nil,
+		map[string]string{}, id, map[string]string{}, nil)
+
+	infos := []*document.FieldInfo{
+		document.NewFieldInfo("title", 0, true, false, true,
+			document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS, document.DOC_VALUES_TYPE_NONE,
+			-1, map[string]string{"PerFieldPostingsFormat.format": "Lucene84"}, 0, 0, 0, false),
+		document.NewFieldInfo("id", 1, false, true, false,
+			document.INDEX_OPTIONS_DOCS, document.DOC_VALUES_TYPE_SORTED,
+			3, map[string]string{}, 0, 0, 0, false),
+		document.NewFieldInfo("location", 2, false, false, false,
+			document.INDEX_OPTIONS_NONE, document.DOC_VALUES_TYPE_SORTED_NUMERIC,
+			-1, map[string]string{}, 3, 2, 4, false),
+		document.NewFieldInfo("tags", 5, false, false, false,
+			document.INDEX_OPTIONS_NONE, document.DOC_VALUES_TYPE_SORTED_SET,
+			-1, map[string]string{}, 0, 0, 0, false),
+		document.NewFieldInfo("soft_deletes", 6, false, false, false,
+			document.INDEX_OPTIONS_NONE, document.DOC_VALUES_TYPE_NUMERIC,
+			-1, map[string]string{}, 0, 0, 0, true),
+	}
+
+	format := NewFieldInfosFormat()
+	assert.Nil(t, format.Write(ctx, dir, si, "suffix", coreIndex.NewFieldInfos(infos), store.DEFAULT))
+
+	// the segment suffix must match
+	_, err = format.Read(ctx, dir, si, "other", store.DEFAULT)
+	assert.NotNil(t, err)
+
+	read, err := format.Read(ctx, dir, si, "suffix", store.DEFAULT)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, len(infos), read.Size())
+	for _, expected := range infos {
+		actual := read.FieldInfo(expected.Name())
+		if !assert.NotNil(t, actual, expected.Name()) {
+			continue
+		}
+		assert.Equal(t, expected.Number(), actual.Number())
+		assert.Equal(t, expected.HasVectors(), actual.HasVectors())
+		assert.Equal(t, expected.OmitsNorms(), actual.OmitsNorms())
+		assert.Equal(t, expected.HasPayloads(), actual.HasPayloads())
+		assert.Equal(t, expected.GetIndexOptions(), actual.GetIndexOptions())
+		assert.Equal(t, expected.GetDocValuesType(), actual.GetDocValuesType())
+		assert.Equal(t, expected.GetDocValuesGen(), actual.GetDocValuesGen())
+		assert.Equal(t, expected.Attributes(), actual.Attributes())
+		assert.Equal(t, expected.GetPointDimensionCount(), actual.GetPointDimensionCount())
+		assert.Equal(t, expected.GetPointIndexDimensionCount(), actual.GetPointIndexDimensionCount())
+		assert.Equal(t, expected.GetPointNumBytes(), actual.GetPointNumBytes())
+		assert.Equal(t, expected.IsSoftDeletesField(), actual.IsSoftDeletesField())
+	}
+}
@@ -0,0 +1,548 @@
+package lucene80
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/automaton"
+	"github.com/geange/lucene-go/core/util/compress/lz4"
+	"github.com/geange/lucene-go/core/util/packed"
+)
+
+var _ index.SortedNumericDocValues = &singletonSortedNumericDocValues{}
+
+// singletonSortedNumericDocValues exposes multi-valued view over a single-valued instance.
+type singletonSortedNumericDocValues struct {
+	in index.NumericDocValues
+}
+
+func newSingletonSortedNumeric(in index.NumericDocValues) *singletonSortedNumericDocValues {
+	return &singletonSortedNumericDocValues{in: in}
+}
+
+func (s *singletonSortedNumericDocValues) DocID() int {
+	return s.in.DocID()
+}
+
+func (s *singletonSortedNumericDocValues) NextDoc() (int, error) {
+	return s.in.NextDoc()
+}
+
+func (s *singletonSortedNumericDocValues) Advance(target int) (int, error) {
+	return s.in.Advance(target)
+}
+
+func (s *singletonSortedNumericDocValues) SlowAdvance(target int) (int, error) {
+	return s.in.SlowAdvance(target)
+}
+
+func (s *singletonSortedNumericDocValues) Cost() int64 {
+	return s.in.Cost()
+}
+
+func (s *singletonSortedNumericDocValues) AdvanceExact(target int) (bool, error) {
+	return s.in.AdvanceExact(target)
+}
+
+func (s *singletonSortedNumericDocValues) NextValue() (int64, error) {
+	return s.in.LongValue()
+}
+
+func (s *singletonSortedNumericDocValues) DocValueCount() int {
+	return 1
+}
+
+var _ index.SortedSetDocValues = &singletonSortedSetDocValues{}
+
+// singletonSortedSetDocValues exposes multi-valued view over a single-valued instance.
+type singletonSortedSetDocValues struct {
+	in  index.SortedDocValues
+	ord int64
+}
+
+func newSingletonSortedSet(in index.SortedDocValues) *singletonSortedSetDocValues {
+	return &singletonSortedSetDocValues{in: in, ord: coreIndex.NO_MORE_ORDS}
+}
+
+func (s *singletonSortedSetDocValues) DocID() int {
+	return s.in.DocID()
+}
+
+func (s *singletonSortedSetDocValues) NextDoc() (int, error) {
+	doc, err := s.in.NextDoc()
+	if err != nil {
+		return 0, err
+	}
+	return doc, s.loadOrd(doc != types.NO_MORE_DOCS)
+}
+
+func (s *singletonSortedSetDocValues) Advance(target int) (int, error) {
+	doc, err := s.in.Advance(target)
+	if err != nil {
+		return 0, err
+	}
+	return doc, s.loadOrd(doc != types.NO_MORE_DOCS)
+}
+
+func (s *singletonSortedSetDocValues) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(s, target)
+}
+
+func (s *singletonSortedSetDocValues) Cost() int64 {
+	return s.in.Cost()
+}
+
+func (s *singletonSortedSetDocValues) AdvanceExact(target int) (bool, error) {
+	found, err := s.in.AdvanceExact(target)
+	if err != nil {
+		return false, err
+	}
+	return found, s.loadOrd(found)
+}
+
+func (s *singletonSortedSetDocValues) loadOrd(positioned bool) error {
+	if !positioned {
+		s.ord = coreIndex.NO_MORE_ORDS
+		return nil
+	}
+	ord, err := s.in.OrdValue()
+	if err != nil {
+		return err
+	}
+	s.ord = int64(ord)
+	return nil
+}
+
+func (s *singletonSortedSetDocValues) NextOrd() (int64, error) {
+	ord := s.ord
+	s.ord = coreIndex.NO_MORE_ORDS
+	return ord, nil
+}
+
+func (s *singletonSortedSetDocValues) LookupOrd(ord int64) ([]byte, error) {
+	return s.in.LookupOrd(int(ord))
+}
+
+func (s *singletonSortedSetDocValues) GetValueCount() int64 {
+	return int64(s.in.GetValueCount())
+}
+
+// nextOrd returns the next ord of the current document, mapping the end of the ords to NO_MORE_ORDS
+func nextOrd(values index.SortedSetDocValues) (int64, error) {
+	ord, err := values.NextOrd()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return coreIndex.NO_MORE_ORDS, nil
+		}
+		return 0, err
+	}
+	return ord, nil
+}
+
+// longValues returns the value at the given index
+type longValues func(index int64) (int64, error)
+
+// docsWithField iterates over the documents that have a value for a field and knows the index of the
+// current document in that set.
+type docsWithField interface {
+	types.DocValuesIterator
+
+	// Index Returns the index of the current document in the set of documents that have a value.
+	Index() int
+}
+
+var _ docsWithField = &emptyDocs{}
+
+// emptyDocs no document has a value
+type emptyDocs struct {
+	doc int
+}
+
+func (e *emptyDocs) DocID() int {
+	return e.doc
+}
+
+func (e *emptyDocs) NextDoc() (int, error) {
+	e.doc = types.NO_MORE_DOCS
+	return e.doc, nil
+}
+
+func (e *emptyDocs) Advance(target int) (int, error) {
+	return e.NextDoc()
+}
+
+func (e *emptyDocs) SlowAdvance(target int) (int, error) {
+	return e.NextDoc()
+}
+
+func (e *emptyDocs) Cost() int64 {
+	return 0
+}
+
+func (e *emptyDocs) AdvanceExact(target int) (bool, error) {
+	e.doc = target
+	return false, nil
+}
+
+func (e *emptyDocs) Index() int {
+	return -1
+}
+
+var _ docsWithField = &denseDocs{}
+
+// denseDocs all documents have a value, the index of a document is its doc ID
+type denseDocs struct {
+	maxDoc int
+	doc    int
+}
+
+func newDenseDocs(maxDoc int) *denseDocs {
+	return &denseDocs{maxDoc: maxDoc, doc: -1}
+}
+
+func (d *denseDocs) DocID() int {
+	return d.doc
+}
+
+func (d *denseDocs) NextDoc() (int, error) {
+	return d.Advance(d.doc + 1)
+}
+
+func (d *denseDocs) Advance(target int) (int, error) {
+	if target >= d.maxDoc {
+		d.doc = types.NO_MORE_DOCS
+	} else {
+		d.doc = target
+	}
+	return d.doc, nil
+}
+
+func (d *denseDocs) SlowAdvance(target int) (int, error) {
+	return d.Advance(target)
+}
+
+func (d *denseDocs) Cost() int64 {
+	return int64(d.maxDoc)
+}
+
+func (d *denseDocs) AdvanceExact(target int) (bool, error) {
+	d.doc = target
+	return true, nil
+}
+
+func (d *denseDocs) Index() int {
+	return d.doc
+}
+
+var _ index.NumericDocValues = &numericDocValues{}
+
+type numericDocValues struct {
+	docsWithField
+
+	values longValues
+}
+
+func (n *numericDocValues) LongValue() (int64, error) {
+	return n.values(int64(n.Index()))
+}
+
+var _ index.BinaryDocValues = &binaryDocValues{}
+
+type binaryDocValues struct {
+	docsWithField
+
+	bytesSlice store.IndexInput
+	buffer     []byte
+
+	// address returns the start and the length of the value at the given index
+	address func(index int64) (int64, int64, error)
+}
+
+func (b *binaryDocValues) BinaryValue() ([]byte, error) {
+	start, length, err := b.address(int64(b.Index()))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := b.bytesSlice.Seek(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(b.bytesSlice, b.buffer[:length]); err != nil {
+		return nil, err
+	}
+	return b.buffer[:length], nil
+}
+
+var _ index.BinaryDocValues = &compressedBinaryDocValues{}
+
+type compressedBinaryDocValues struct {
+	docsWithField
+
+	decoder *binaryDecoder
+}
+
+func (b *compressedBinaryDocValues) BinaryValue() ([]byte, error) {
+	return b.decoder.decode(b.Index())
+}
+
+// binaryDecoder decompresses the block of binary values that holds a document and keeps it until a
+// document of another block is requested.
+type binaryDecoder struct {
+	addresses             *packed.DirectMonotonicReader
+	compressedData        store.IndexInput
+	docsPerChunkShift     int
+	docsPerChunk          int
+	lastBlockID           int
+	uncompressedDocStarts []int
+	uncompressedBlock     []byte
+}
+
+func newBinaryDecoder(addresses *packed.DirectMonotonicReader, compressedData store.IndexInput,
+	biggestUncompressedBlockSize, docsPerChunkShift int) *binaryDecoder {
+
+	docsPerChunk := 1 << docsPerChunkShift
+	return &binaryDecoder{
+		addresses:             addresses,
+		compressedData:        compressedData,
+		docsPerChunkShift:     docsPerChunkShift,
+		docsPerChunk:          docsPerChunk,
+		lastBlockID:           -1,
+		uncompressedDocStarts: make([]int, docsPerChunk+1),
+		// add 7 padding bytes, this can help decompression run faster
+		uncompressedBlock: make([]byte, biggestUncompressedBlockSize+7),
+	}
+}
+
+func (d *binaryDecoder) decode(index int) ([]byte, error) {
+	blockID := index >> d.docsPerChunkShift
+	docInBlockID := index & (d.docsPerChunk - 1)
+
+	// already read and uncompressed?
+	if blockID != d.lastBlockID {
+		if err := d.decompressBlock(blockID); err != nil {
+			return nil, err
+		}
+		d.lastBlockID = blockID
+	}
+	return d.uncompressedBlock[d.uncompressedDocStarts[docInBlockID]:d.uncompressedDocStarts[docInBlockID+1]], nil
+}
+
+func (d *binaryDecoder) decompressBlock(blockID int) error {
+	blockStartOffset, err := d.addresses.Get(int64(blockID))
+	if err != nil {
+		return err
+	}
+	if _, err := d.compressedData.Seek(int64(blockStartOffset), io.SeekStart); err != nil {
+		return err
+	}
+
+	// the lowest bit of the first length tells whether all values have the same length
+	lengthPlusSameInd, err := d.compressedData.ReadUvarint(context.Background())
+	if err != nil {
+		return err
+	}
+	allLengthsSame := lengthPlusSameInd&1 == 1
+	length := int(lengthPlusSameInd >> 1)
+
+	uncompressedBlockLength := 0
+	for i := 0; i < d.docsPerChunk; i++ {
+		if i > 0 && !allLengthsSame {
+			n, err := d.compressedData.ReadUvarint(context.Background())
+			if err != nil {
+				return err
+			}
+			length = int(n)
+		}
+		uncompressedBlockLength += length
+		d.uncompressedDocStarts[i+1] = uncompressedBlockLength
+	}
+
+	if uncompressedBlockLength == 0 {
+		return nil
+	}
+	if uncompressedBlockLength > len(d.uncompressedBlock) {
+		return fmt.Errorf("corrupted binary doc values: block of %d bytes > %d", uncompressedBlockLength, len(d.uncompressedBlock))
+	}
+	_, err = lz4.Decompress(d.compressedData, uncompressedBlockLength, d.uncompressedBlock)
+	return err
+}
+
+var _ index.SortedDocValues = &sortedDocValues{}
+
+type sortedDocValues struct {
+	docsWithField
+
+	ords      longValues
+	termsDict *termsDict
+}
+
+func (s *sortedDocValues) BinaryValue() ([]byte, error) {
+	ord, err := s.OrdValue()
+	if err != nil {
+		return nil, err
+	}
+	return s.LookupOrd(ord)
+}
+
+func (s *sortedDocValues) OrdValue() (int, error) {
+	ord, err := s.ords(int64(s.Index()))
+	return int(ord), err
+}
+
+func (s *sortedDocValues) LookupOrd(ord int) ([]byte, error) {
+	return s.termsDict.lookupOrd(int64(ord))
+}
+
+func (s *sortedDocValues) GetValueCount() int {
+	return int(s.termsDict.entry.termsDictSize)
+}
+
+func (s *sortedDocValues) LookupTerm(key []byte) (int, error) {
+	ord, err := s.termsDict.lookupTerm(key)
+	return int(ord), err
+}
+
+func (s *sortedDocValues) TermsEnum() (index.TermsEnum, error) {
+	return s.termsDict.clone()
+}
+
+func (s *sortedDocValues) Intersect(automaton *automaton.CompiledAutomaton) (index.TermsEnum, error) {
+	return nil, coreIndex.ErrUnsupportedOperation
+}
+
+var _ index.SortedNumericDocValues = &sortedNumericDocValues{}
+
+type sortedNumericDocValues struct {
+	docsWithField
+
+	addresses *packed.DirectMonotonicReader
+	values    longValues
+	start     int64
+	end       int64
+}
+
+func (s *sortedNumericDocValues) NextDoc() (int, error) {
+	doc, err := s.docsWithField.NextDoc()
+	if err != nil {
+		return 0, err
+	}
+	return doc, s.load(doc != types.NO_MORE_DOCS)
+}
+
+func (s *sortedNumericDocValues) Advance(target int) (int, error) {
+	doc, err := s.docsWithField.Advance(target)
+	if err != nil {
+		return 0, err
+	}
+	return doc, s.load(doc != types.NO_MORE_DOCS)
+}
+
+func (s *sortedNumericDocValues) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(s, target)
+}
+
+func (s *sortedNumericDocValues) AdvanceExact(target int) (bool, error) {
+	found, err := s.docsWithField.AdvanceExact(target)
+	if err != nil {
+		return false, err
+	}
+	return found, s.load(found)
+}
+
+func (s *sortedNumericDocValues) load(positioned bool) (err error) {
+	s.start, s.end, err = loadAddresses(s.addresses, s.Index(), positioned)
+	return err
+}
+
+func (s *sortedNumericDocValues) NextValue() (int64, error) {
+	v, err := s.values(s.start)
+	s.start++
+	return v, err
+}
+
+func (s *sortedNumericDocValues) DocValueCount() int {
+	return int(s.end - s.start)
+}
+
+var _ index.SortedSetDocValues = &sortedSetDocValues{}
+
+type sortedSetDocValues struct {
+	docsWithField
+
+	ords      packed.LongValuesReader
+	addresses *packed.DirectMonotonicReader
+	termsDict *termsDict
+	start     int64
+	end       int64
+}
+
+func (s *sortedSetDocValues) NextDoc() (int, error) {
+	doc, err := s.docsWithField.NextDoc()
+	if err != nil {
+		return 0, err
+	}
+	return doc, s.load(doc != types.NO_MORE_DOCS)
+}
+
+func (s *sortedSetDocValues) Advance(target int) (int, error) {
+	doc, err := s.docsWithField.Advance(target)
+	if err != nil {
+		return 0, err
+	}
+	return doc, s.load(doc != types.NO_MORE_DOCS)
+}
+
+func (s *sortedSetDocValues) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(s, target)
+}
+
+func (s *sortedSetDocValues) AdvanceExact(target int) (bool, error) {
+	found, err := s.docsWithField.AdvanceExact(target)
+	if err != nil {
+		return false, err
+	}
+	return found, s.load(found)
+}
+
+func (s *sortedSetDocValues) load(positioned bool) (err error) {
+	s.start, s.end, err = loadAddresses(s.addresses, s.Index(), positioned)
+	return err
+}
+
+func (s *sortedSetDocValues) NextOrd() (int64, error) {
+	if s.start == s.end {
+		return coreIndex.NO_MORE_ORDS, nil
+	}
+	ord, err := s.ords.Get(s.start)
+	s.start++
+	return int64(ord), err
+}
+
+func (s *sortedSetDocValues) LookupOrd(ord int64) ([]byte, error) {
+	return s.termsDict.lookupOrd(ord)
+}
+
+func (s *sortedSetDocValues) GetValueCount() int64 {
+	return s.termsDict.entry.termsDictSize
+}
+
+// loadAddresses returns the range of values of the document at the given index
+func loadAddresses(addresses *packed.DirectMonotonicReader, index int, positioned bool) (int64, int64, error) {
+	if !positioned {
+		return 0, 0, nil
+	}
+	start, err := addresses.Get(int64(index))
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := addresses.Get(int64(index) + 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int64(start), int64(end), nil
+}
@@ -0,0 +1,1239 @@
+package lucene80
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util"
+	"github.com/geange/lucene-go/core/util/compress/lz4"
+	"github.com/geange/lucene-go/core/util/packed"
+)
+
+var _ index.DocValuesConsumer = &DocValuesConsumer{}
+
+// DocValuesConsumer writer for DocValuesFormat
+type DocValuesConsumer struct {
+	data   store.IndexOutput
+	meta   store.IndexOutput
+	maxDoc int
+	mode   Mode
+}
+
+// NewDocValuesConsumer expert: Creates a new writer
+func NewDocValuesConsumer(ctx context.Context, state *index.SegmentWriteState,
+	dataCodec, dataExtension, metaCodec, metaExtension string, mode Mode) (*DocValuesConsumer, error) {
+
+	consumer := &DocValuesConsumer{mode: mode}
+
+	closeOnError := func(err error) (*DocValuesConsumer, error) {
+		_ = consumer.closeOutputs()
+		return nil, err
+	}
+
+	segmentName := state.SegmentInfo.Name()
+	segmentID := state.SegmentInfo.GetID()
+
+	dataName := store.SegmentFileName(segmentName, state.SegmentSuffix, dataExtension)
+	data, err := store.CreateOutputWithContext(ctx, state.Directory, dataName, state.Context)
+	if err != nil {
+		return nil, err
+	}
+	consumer.data = data
+	if err := utils.WriteIndexHeader(ctx, data, dataCodec, VERSION_CURRENT, segmentID, state.SegmentSuffix); err != nil {
+		return closeOnError(err)
+	}
+
+	metaName := store.SegmentFileName(segmentName, state.SegmentSuffix, metaExtension)
+	if consumer.meta, err = store.CreateOutputWithContext(ctx, state.Directory, metaName, state.Context); err != nil {
+		return closeOnError(err)
+	}
+	if err := utils.WriteIndexHeader(ctx, consumer.meta, metaCodec, VERSION_CURRENT, segmentID, state.SegmentSuffix); err != nil {
+		return closeOnError(err)
+	}
+
+	if consumer.maxDoc, err = state.SegmentInfo.MaxDoc(); err != nil {
+		return closeOnError(err)
+	}
+	return consumer, nil
+}
+
+func (c *DocValuesConsumer) Close() error {
+	if c.meta != nil {
+		// write EOF marker
+		if err := c.meta.WriteUint32(nil, math.MaxUint32); err != nil {
+			_ = c.closeOutputs()
+			return err
+		}
+		if err := utils.WriteFooter(c.meta); err != nil {
+			_ = c.closeOutputs()
+			return err
+		}
+	}
+	if c.data != nil {
+		if err := utils.WriteFooter(c.data); err != nil {
+			_ = c.closeOutputs()
+			return err
+		}
+	}
+	return c.closeOutputs()
+}
+
+func (c *DocValuesConsumer) closeOutputs() error {
+	var errs []error
+	for _, closer := range []store.IndexOutput{c.data, c.meta} {
+		if closer != nil {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	c.data, c.meta = nil, nil
+	return errors.Join(errs...)
+}
+
+func (c *DocValuesConsumer) writeFieldEntry(ctx context.Context, field *document.FieldInfo, docValuesType byte) error {
+	if err := c.meta.WriteUint32(ctx, uint32(field.Number())); err != nil {
+		return err
+	}
+	return c.meta.WriteByte(docValuesType)
+}
+
+func (c *DocValuesConsumer) AddNumericField(ctx context.Context, field *document.FieldInfo, valuesProducer index.DocValuesProducer) error {
+	if err := c.writeFieldEntry(ctx, field, NUMERIC); err != nil {
+		return err
+	}
+	_, _, err := c.writeValues(ctx, func() (index.SortedNumericDocValues, error) {
+		values, err := valuesProducer.GetNumeric(ctx, field)
+		if err != nil {
+			return nil, err
+		}
+		return newSingletonSortedNumeric(values), nil
+	})
+	return err
+}
+
+// minMaxTracker tracks the range of the values of a field, and the space in bits that is needed
+// to store them for a given split into blocks.
+type minMaxTracker struct {
+	min         int64
+	max         int64
+	numValues   int64
+	spaceInBits int64
+}
+
+func newMinMaxTracker() *minMaxTracker {
+	tracker := &minMaxTracker{}
+	tracker.reset()
+	return tracker
+}
+
+func (m *minMaxTracker) reset() {
+	m.min = math.MaxInt64
+	m.max = math.MinInt64
+	m.numValues = 0
+}
+
+// update Accumulate a new value.
+func (m *minMaxTracker) update(v int64) {
+	m.min = min(m.min, v)
+	m.max = max(m.max, v)
+	m.numValues++
+}
+
+// finish Update the required space.
+func (m *minMaxTracker) finish() {
+	if m.max > m.min {
+		m.spaceInBits += int64(packed.DirectUnsignedBitsRequired(uint64(m.max-m.min))) * m.numValues
+	}
+}
+
+// nextBlock Update space usage and get ready for accumulating values for the next block.
+func (m *minMaxTracker) nextBlock() {
+	m.finish()
+	m.reset()
+}
+
+// writeDocsWithField writes the set of documents that have a value. Fields that have a value for no
+// document or for all documents are only flagged in the metadata.
+func (c *DocValuesConsumer) writeDocsWithField(ctx context.Context, numDocsWithField int,
+	iterator func() (types.DocIdSetIterator, error)) error {
+
+	offset, length := int64(-2), int64(0)
+	jumpTableEntryCount, denseRankPower := -1, -1
+
+	switch numDocsWithField {
+	case 0:
+		// meta[-2, 0]: No documents with values
+	case c.maxDoc:
+		// meta[-1, 0]: All documents have values
+		offset = -1
+	default:
+		// meta[data.offset, data.length]: IndexedDISI structure for documents with values
+		it, err := iterator()
+		if err != nil {
+			return err
+		}
+		offset = c.data.GetFilePointer()
+		if jumpTableEntryCount, err = WriteBitSet(ctx, it, c.data, DEFAULT_DENSE_RANK_POWER); err != nil {
+			return err
+		}
+		length = c.data.GetFilePointer() - offset
+		denseRankPower = DEFAULT_DENSE_RANK_POWER
+	}
+
+	if err := c.meta.WriteUint64(ctx, uint64(offset)); err != nil { // docsWithFieldOffset
+		return err
+	}
+	if err := c.meta.WriteUint64(ctx, uint64(length)); err != nil { // docsWithFieldLength
+		return err
+	}
+	if err := c.meta.WriteUint16(ctx, uint16(jumpTableEntryCount)); err != nil { // jumpTableEntryCount
+		return err
+	}
+	return c.meta.WriteByte(byte(denseRankPower)) // denseRankPower
+}
+
+// writeValues writes the values of a numeric or sorted numeric field and returns the number of
+// documents that have a value and the total number of values.
+func (c *DocValuesConsumer) writeValues(ctx context.Context,
+	getValues func() (index.SortedNumericDocValues, error)) (int, int64, error) {
+
+	values, err := getValues()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	numDocsWithValue := 0
+	minMax := newMinMaxTracker()
+	blockMinMax := newMinMaxTracker()
+	gcd := int64(0)
+	uniqueValues := make(map[int64]struct{})
+
+	for {
+		doc, err := nextDoc(values)
+		if err != nil {
+			return 0, 0, err
+		}
+		if doc == types.NO_MORE_DOCS {
+			break
+		}
+
+		for i, count := 0, values.DocValueCount(); i < count; i++ {
+			v, err := values.NextValue()
+			if err != nil {
+				return 0, 0, err
+			}
+
+			if gcd != 1 {
+				if v < math.MinInt64/2 || v > math.MaxInt64/2 {
+					// in that case v - minValue might overflow and make the GCD computation return
+					// wrong results. Since these extreme values are unlikely, we just discard
+					// GCD computation for them
+					gcd = 1
+				} else if minMax.numValues != 0 { // minValue needs to be set first
+					gcd = util.Gcd(gcd, v-minMax.min)
+				}
+			}
+
+			minMax.update(v)
+			blockMinMax.update(v)
+			if blockMinMax.numValues == NUMERIC_BLOCK_SIZE {
+				blockMinMax.nextBlock()
+			}
+
+			if uniqueValues != nil {
+				uniqueValues[v] = struct{}{}
+				if len(uniqueValues) > 256 {
+					uniqueValues = nil
+				}
+			}
+		}
+		numDocsWithValue++
+	}
+
+	minMax.finish()
+	blockMinMax.finish()
+
+	numValues := minMax.numValues
+	minValue := minMax.min
+	maxValue := minMax.max
+
+	if err := c.writeDocsWithField(ctx, numDocsWithValue, func() (types.DocIdSetIterator, error) {
+		return getValues()
+	}); err != nil {
+		return 0, 0, err
+	}
+
+	if err := c.meta.WriteUint64(ctx, uint64(numValues)); err != nil {
+		return 0, 0, err
+	}
+
+	numBitsPerValue := 0
+	doBlocks := false
+	var encode map[int64]int
+	tableSize := -1
+
+	if minValue < maxValue {
+		numBitsRequired := packed.DirectUnsignedBitsRequired(uint64(maxValue-minValue) / uint64(gcd))
+
+		if uniqueValues != nil && len(uniqueValues) > 1 &&
+			packed.DirectUnsignedBitsRequired(uint64(len(uniqueValues)-1)) < numBitsRequired {
+
+			numBitsPerValue = packed.DirectUnsignedBitsRequired(uint64(len(uniqueValues) - 1))
+			sortedUniqueValues := make([]int64, 0, len(uniqueValues))
+			for v := range uniqueValues {
+				sortedUniqueValues = append(sortedUniqueValues, v)
+			}
+			slices.Sort(sortedUniqueValues)
+
+			encode = make(map[int64]int, len(sortedUniqueValues))
+			for i, v := range sortedUniqueValues {
+				encode[v] = i
+			}
+			tableSize = len(sortedUniqueValues)
+			if err := c.meta.WriteUint32(ctx, uint32(tableSize)); err != nil { // tablesize
+				return 0, 0, err
+			}
+			for _, v := range sortedUniqueValues {
+				if err := c.meta.WriteUint64(ctx, uint64(v)); err != nil { // table[] entry
+					return 0, 0, err
+				}
+			}
+			minValue = 0
+			gcd = 1
+		} else {
+			// we do blocks if that appears to save 10+% storage
+			doBlocks = minMax.spaceInBits > 0 &&
+				float64(blockMinMax.spaceInBits)/float64(minMax.spaceInBits) <= 0.9
+			if doBlocks {
+				numBitsPerValue = 0xFF
+				tableSize = -2 - NUMERIC_BLOCK_SHIFT
+			} else {
+				numBitsPerValue = numBitsRequired
+				if gcd == 1 && minValue > 0 &&
+					packed.DirectUnsignedBitsRequired(uint64(maxValue)) == packed.DirectUnsignedBitsRequired(uint64(maxValue-minValue)) {
+					minValue = 0
+				}
+			}
+		}
+	}
+	if encode == nil {
+		if err := c.meta.WriteUint32(ctx, uint32(tableSize)); err != nil { // tablesize
+			return 0, 0, err
+		}
+	}
+
+	if err := c.meta.WriteByte(byte(numBitsPerValue)); err != nil {
+		return 0, 0, err
+	}
+	if err := c.meta.WriteUint64(ctx, uint64(minValue)); err != nil {
+		return 0, 0, err
+	}
+	if err := c.meta.WriteUint64(ctx, uint64(gcd)); err != nil {
+		return 0, 0, err
+	}
+	startOffset := c.data.GetFilePointer()
+	if err := c.meta.WriteUint64(ctx, uint64(startOffset)); err != nil { // valueOffset
+		return 0, 0, err
+	}
+
+	jumpTableOffset := int64(-1)
+	if doBlocks {
+		values, err := getValues()
+		if err != nil {
+			return 0, 0, err
+		}
+		if jumpTableOffset, err = c.writeValuesMultipleBlocks(ctx, values, gcd); err != nil {
+			return 0, 0, err
+		}
+	} else if numBitsPerValue != 0 {
+		values, err := getValues()
+		if err != nil {
+			return 0, 0, err
+		}
+		if err := c.writeValuesSingleBlock(values, numValues, numBitsPerValue, minValue, gcd, encode); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if err := c.meta.WriteUint64(ctx, uint64(c.data.GetFilePointer()-startOffset)); err != nil { // valuesLength
+		return 0, 0, err
+	}
+	if err := c.meta.WriteUint64(ctx, uint64(jumpTableOffset)); err != nil {
+		return 0, 0, err
+	}
+	return numDocsWithValue, numValues, nil
+}
+
+func (c *DocValuesConsumer) writeValuesSingleBlock(values index.SortedNumericDocValues, numValues int64,
+	numBitsPerValue int, minValue, gcd int64, encode map[int64]int) error {
+
+	writer, err := packed.NewDirectWriter(c.data, int(numValues), numBitsPerValue)
+	if err != nil {
+		return err
+	}
+	for {
+		doc, err := nextDoc(values)
+		if err != nil {
+			return err
+		}
+		if doc == types.NO_MORE_DOCS {
+			break
+		}
+		for i, count := 0, values.DocValueCount(); i < count; i++ {
+			v, err := values.NextValue()
+			if err != nil {
+				return err
+			}
+			if encode == nil {
+				err = writer.Add(uint64((v - minValue) / gcd))
+			} else {
+				err = writer.Add(uint64(encode[v]))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return writer.Finish()
+}
+
+// writeValuesMultipleBlocks returns the offset to the jump-table for the blocks
+func (c *DocValuesConsumer) writeValuesMultipleBlocks(ctx context.Context,
+	values index.SortedNumericDocValues, gcd int64) (int64, error) {
+
+	offsets := make([]int64, 0)
+	buffer := make([]int64, 0, NUMERIC_BLOCK_SIZE)
+	encodeBuffer := store.NewBufferDataOutput()
+
+	for {
+		doc, err := nextDoc(values)
+		if err != nil {
+			return 0, err
+		}
+		if doc == types.NO_MORE_DOCS {
+			break
+		}
+		for i, count := 0, values.DocValueCount(); i < count; i++ {
+			v, err := values.NextValue()
+			if err != nil {
+				return 0, err
+			}
+			buffer = append(buffer, v)
+			if len(buffer) == NUMERIC_BLOCK_SIZE {
+				offsets = append(offsets, c.data.GetFilePointer())
+				if err := c.writeBlock(ctx, buffer, gcd, encodeBuffer); err != nil {
+					return 0, err
+				}
+				buffer = buffer[:0]
+			}
+		}
+	}
+	if len(buffer) > 0 {
+		offsets = append(offsets, c.data.GetFilePointer())
+		if err := c.writeBlock(ctx, buffer, gcd, encodeBuffer); err != nil {
+			return 0, err
+		}
+	}
+
+	// All blocks has been written. Flush the offset jump-table
+	offsetsOrigo := c.data.GetFilePointer()
+	for _, offset := range offsets {
+		if err := c.data.WriteUint64(ctx, uint64(offset)); err != nil {
+			return 0, err
+		}
+	}
+	if err := c.data.WriteUint64(ctx, uint64(offsetsOrigo)); err != nil {
+		return 0, err
+	}
+	return offsetsOrigo, nil
+}
+
+func (c *DocValuesConsumer) writeBlock(ctx context.Context, values []int64, gcd int64, buffer *store.BufferOutput) error {
+	minValue, maxValue := slices.Min(values), slices.Max(values)
+
+	if minValue == maxValue {
+		if err := c.data.WriteByte(0); err != nil {
+			return err
+		}
+		return c.data.WriteUint64(ctx, uint64(minValue))
+	}
+
+	bitsPerValue := packed.DirectUnsignedBitsRequired(uint64(maxValue-minValue) / uint64(gcd))
+	buffer.Reset()
+	writer, err := packed.NewDirectWriter(buffer, len(values), bitsPerValue)
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		if err := writer.Add(uint64((v - minValue) / gcd)); err != nil {
+			return err
+		}
+	}
+	if err := writer.Finish(); err != nil {
+		return err
+	}
+
+	if err := c.data.WriteByte(byte(bitsPerValue)); err != nil {
+		return err
+	}
+	if err := c.data.WriteUint64(ctx, uint64(minValue)); err != nil {
+		return err
+	}
+	if err := c.data.WriteUint32(ctx, uint32(len(buffer.Bytes()))); err != nil {
+		return err
+	}
+	return buffer.CopyTo(c.data)
+}
+
+func (c *DocValuesConsumer) AddBinaryField(ctx context.Context, field *document.FieldInfo, valuesProducer index.DocValuesProducer) error {
+	field.PutAttribute(MODE_KEY, c.mode.String())
+	if err := c.writeFieldEntry(ctx, field, BINARY); err != nil {
+		return err
+	}
+	if c.mode == BEST_COMPRESSION {
+		return c.addCompressedBinaryField(ctx, field, valuesProducer)
+	}
+
+	values, err := valuesProducer.GetBinary(ctx, field)
+	if err != nil {
+		return err
+	}
+
+	start := c.data.GetFilePointer()
+	if err := c.meta.WriteUint64(ctx, uint64(start)); err != nil { // dataOffset
+		return err
+	}
+	numDocsWithField := 0
+	minLength := math.MaxInt32
+	maxLength := 0
+	for {
+		doc, err := nextDoc(values)
+		if err != nil {
+			return err
+		}
+		if doc == types.NO_MORE_DOCS {
+			break
+		}
+		numDocsWithField++
+		v, err := values.BinaryValue()
+		if err != nil {
+			return err
+		}
+		if _, err := c.data.Write(v); err != nil {
+			return err
+		}
+		minLength = min(minLength, len(v))
+		maxLength = max(maxLength, len(v))
+	}
+	if err := c.meta.WriteUint64(ctx, uint64(c.data.GetFilePointer()-start)); err != nil { // dataLength
+		return err
+	}
+
+	if err := c.writeDocsWithField(ctx, numDocsWithField, func() (types.DocIdSetIterator, error) {
+		return valuesProducer.GetBinary(ctx, field)
+	}); err != nil {
+		return err
+	}
+
+	if err := c.meta.WriteUint32(ctx, uint32(numDocsWithField)); err != nil {
+		return err
+	}
+	if err := c.meta.WriteUint32(ctx, uint32(minLength)); err != nil {
+		return err
+	}
+	if err := c.meta.WriteUint32(ctx, uint32(maxLength)); err != nil {
+		return err
+	}
+	if maxLength <= minLength {
+		return nil
+	}
+
+	values, err = valuesProducer.GetBinary(ctx, field)
+	if err != nil {
+		return err
+	}
+	return c.writeAddresses(ctx, numDocsWithField, values, func() (int64, error) {
+		v, err := values.BinaryValue()
+		return int64(len(v)), err
+	})
+}
+
+func (c *DocValuesConsumer) addCompressedBinaryField(ctx context.Context, field *document.FieldInfo, valuesProducer index.DocValuesProducer) error {
+	values, err := valuesProducer.GetBinary(ctx, field)
+	if err != nil {
+		return err
+	}
+
+	start := c.data.GetFilePointer()
+	if err := c.meta.WriteUint64(ctx, uint64(start)); err != nil { // dataOffset
+		return err
+	}
+	blockWriter := newCompressedBinaryBlockWriter(c.data)
+	numDocsWithField := 0
+	minLength := math.MaxInt32
+	maxLength := 0
+	for {
+		doc, err := nextDoc(values)
+		if err != nil {
+			return err
+		}
+		if doc == types.NO_MORE_DOCS {
+			break
+		}
+		numDocsWithField++
+		v, err := values.BinaryValue()
+		if err != nil {
+			return err
+		}
+		if err := blockWriter.addDoc(ctx, v); err != nil {
+			return err
+		}
+		minLength = min(minLength, len(v))
+		maxLength = max(maxLength, len(v))
+	}
+	if err := blockWriter.flushData(ctx); err != nil {
+		return err
+	}
+	if err := c.meta.WriteUint64(ctx, uint64(c.data.GetFilePointer()-start)); err != nil { // dataLength
+		return err
+	}
+
+	if err := c.writeDocsWithField(ctx, numDocsWithField, func() (types.DocIdSetIterator, error) {
+		return valuesProducer.GetBinary(ctx, field)
+	}); err != nil {
+		return err
+	}
+
+	if err := c.meta.WriteUint32(ctx, uint32(numDocsWithField)); err != nil {
+		return err
+	}
+	if err := c.meta.WriteUint32(ctx, uint32(minLength)); err != nil {
+		return err
+	}
+	if err := c.meta.WriteUint32(ctx, uint32(maxLength)); err != nil {
+		return err
+	}
+	return blockWriter.writeMetaData(ctx, c.meta)
+}
+
+// compressedBinaryBlockWriter compresses the binary values of BINARY_DOCS_PER_COMPRESSED_BLOCK
+// documents at a time with LZ4. Every block starts with the lengths of its values, the first
+// length is shifted and its lowest bit tells whether all values of the block have the same length.
+type compressedBinaryBlockWriter struct {
+	data                       store.IndexOutput
+	ht                         *lz4.FastCompressionHashTable
+	block                      []byte
+	docLengths                 [BINARY_DOCS_PER_COMPRESSED_BLOCK]int
+	numDocsInCurrentBlock      int
+	maxUncompressedBlockLength int
+	blockAddresses             []int64 // start pointers of the blocks in the data
+}
+
+func newCompressedBinaryBlockWriter(data store.IndexOutput) *compressedBinaryBlockWriter {
+	return &compressedBinaryBlockWriter{
+		data: data,
+		ht:   lz4.NewFastCompressionHashTable(),
+	}
+}
+
+func (w *compressedBinaryBlockWriter) addDoc(ctx context.Context, v []byte) error {
+	w.docLengths[w.numDocsInCurrentBlock] = len(v)
+	w.block = append(w.block, v...)
+	w.numDocsInCurrentBlock++
+	if w.numDocsInCurrentBlock == BINARY_DOCS_PER_COMPRESSED_BLOCK {
+		return w.flushData(ctx)
+	}
+	return nil
+}
+
+func (w *compressedBinaryBlockWriter) flushData(ctx context.Context) error {
+	if w.numDocsInCurrentBlock == 0 {
+		return nil
+	}
+	w.blockAddresses = append(w.blockAddresses, w.data.GetFilePointer())
+
+	// the lengths of all BINARY_DOCS_PER_COMPRESSED_BLOCK docs are written, the ones that are
+	// missing from the last block are 0
+	allLengthsSame := true
+	for _, length := range w.docLengths[1:] {
+		if length != w.docLengths[0] {
+			allLengthsSame = false
+			break
+		}
+	}
+	if allLengthsSame {
+		// only write one value, shifted, the stolen bit tells that all lengths are the same
+		if err := w.data.WriteUvarint(ctx, uint64(w.docLengths[0]<<1|1)); err != nil {
+			return err
+		}
+	} else {
+		if err := w.data.WriteUvarint(ctx, uint64(w.docLengths[0]<<1)); err != nil {
+			return err
+		}
+		for _, length := range w.docLengths[1:] {
+			if err := w.data.WriteUvarint(ctx, uint64(length)); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.maxUncompressedBlockLength = max(w.maxUncompressedBlockLength, len(w.block))
+	if err := lz4.Compress(w.block, w.data, w.ht); err != nil {
+		return err
+	}
+	w.numDocsInCurrentBlock = 0
+	w.docLengths = [BINARY_DOCS_PER_COMPRESSED_BLOCK]int{}
+	w.block = w.block[:0]
+	return nil
+}
+
+// writeMetaData writes the addresses of the blocks, there are none if no document has a value
+func (w *compressedBinaryBlockWriter) writeMetaData(ctx context.Context, meta store.IndexOutput) error {
+	totalChunks := len(w.blockAddresses)
+	if totalChunks == 0 {
+		return nil
+	}
+
+	start := w.data.GetFilePointer()
+	if err := meta.WriteUint64(ctx, uint64(start)); err != nil { // addressesOffset
+		return err
+	}
+	if err := meta.WriteUvarint(ctx, uint64(totalChunks)); err != nil {
+		return err
+	}
+	if err := meta.WriteUvarint(ctx, BINARY_BLOCK_SHIFT); err != nil {
+		return err
+	}
+	if err := meta.WriteUvarint(ctx, uint64(w.maxUncompressedBlockLength)); err != nil {
+		return err
+	}
+	if err := meta.WriteUvarint(ctx, DIRECT_MONOTONIC_BLOCK_SHIFT); err != nil {
+		return err
+	}
+
+	writer, err := packed.NewDirectMonotonicWriter(meta, w.data, int64(totalChunks), DIRECT_MONOTONIC_BLOCK_SHIFT)
+	if err != nil {
+		return err
+	}
+	for _, address := range w.blockAddresses {
+		if err := writer.Add(ctx, address); err != nil {
+			return err
+		}
+	}
+	if err := writer.Finish(ctx); err != nil {
+		return err
+	}
+	return meta.WriteUint64(ctx, uint64(w.data.GetFilePointer()-start)) // addressesLength
+}
+
+// writeAddresses writes the monotonic start addresses of the values of every document that has a
+// value, plus the end address of the last document. count returns the size of the current document.
+func (c *DocValuesConsumer) writeAddresses(ctx context.Context, numDocsWithField int,
+	it types.DocIdSetIterator, count func() (int64, error)) error {
+
+	start := c.data.GetFilePointer()
+	if err := c.meta.WriteUint64(ctx, uint64(start)); err != nil { // addressesOffset
+		return err
+	}
+	if err := c.meta.WriteUvarint(ctx, DIRECT_MONOTONIC_BLOCK_SHIFT); err != nil {
+		return err
+	}
+
+	writer, err := packed.NewDirectMonotonicWriter(c.meta, c.data, int64(numDocsWithField)+1, DIRECT_MONOTONIC_BLOCK_SHIFT)
+	if err != nil {
+		return err
+	}
+	addr := int64(0)
+	if err := writer.Add(ctx, addr); err != nil {
+		return err
+	}
+	for {
+		doc, err := nextDoc(it)
+		if err != nil {
+			return err
+		}
+		if doc == types.NO_MORE_DOCS {
+			break
+		}
+		n, err := count()
+		if err != nil {
+			return err
+		}
+		addr += n
+		if err := writer.Add(ctx, addr); err != nil {
+			return err
+		}
+	}
+	if err := writer.Finish(ctx); err != nil {
+		return err
+	}
+	return c.meta.WriteUint64(ctx, uint64(c.data.GetFilePointer()-start)) // addressesLength
+}
+
+func (c *DocValuesConsumer) AddSortedField(ctx context.Context, field *document.FieldInfo, valuesProducer index.DocValuesProducer) error {
+	if err := c.writeFieldEntry(ctx, field, SORTED); err != nil {
+		return err
+	}
+	return c.doAddSortedField(ctx, func() (index.SortedSetDocValues, error) {
+		values, err := valuesProducer.GetSorted(ctx, field)
+		if err != nil {
+			return nil, err
+		}
+		return newSingletonSortedSet(values), nil
+	})
+}
+
+// doAddSortedField writes a single-valued field, the first ord of every document is its value.
+func (c *DocValuesConsumer) doAddSortedField(ctx context.Context, getValues func() (index.SortedSetDocValues, error)) error {
+	values, err := getValues()
+	if err != nil {
+		return err
+	}
+	numDocsWithField := 0
+	for {
+		doc, err := nextDoc(values)
+		if err != nil {
+			return err
+		}
+		if doc == types.NO_MORE_DOCS {
+			break
+		}
+		numDocsWithField++
+	}
+
+	if err := c.writeDocsWithField(ctx, numDocsWithField, func() (types.DocIdSetIterator, error) {
+		return getValues()
+	}); err != nil {
+		return err
+	}
+
+	if err := c.meta.WriteUint32(ctx, uint32(numDocsWithField)); err != nil {
+		return err
+	}
+	if values.GetValueCount() <= 1 {
+		if err := c.meta.WriteByte(0); err != nil { // bitsPerValue
+			return err
+		}
+		if err := c.meta.WriteUint64(ctx, 0); err != nil { // ordsOffset
+			return err
+		}
+		if err := c.meta.WriteUint64(ctx, 0); err != nil { // ordsLength
+			return err
+		}
+	} else {
+		numberOfBitsPerOrd := packed.DirectUnsignedBitsRequired(uint64(values.GetValueCount() - 1))
+		if err := c.meta.WriteByte(byte(numberOfBitsPerOrd)); err != nil { // bitsPerValue
+			return err
+		}
+		start := c.data.GetFilePointer()
+		if err := c.meta.WriteUint64(ctx, uint64(start)); err != nil { // ordsOffset
+			return err
+		}
+		writer, err := packed.NewDirectWriter(c.data, numDocsWithField, numberOfBitsPerOrd)
+		if err != nil {
+			return err
+		}
+		values, err := getValues()
+		if err != nil {
+			return err
+		}
+		for {
+			doc, err := nextDoc(values)
+			if err != nil {
+				return err
+			}
+			if doc == types.NO_MORE_DOCS {
+				break
+			}
+			ord, err := nextOrd(values)
+			if err != nil {
+				return err
+			}
+			if err := writer.Add(uint64(ord)); err != nil {
+				return err
+			}
+		}
+		if err := writer.Finish(); err != nil {
+			return err
+		}
+		if err := c.meta.WriteUint64(ctx, uint64(c.data.GetFilePointer()-start)); err != nil { // ordsLength
+			return err
+		}
+	}
+
+	return c.addTermsDict(ctx, values)
+}
+
+func (c *DocValuesConsumer) addTermsDict(ctx context.Context, values index.SortedSetDocValues) error {
+	size := values.GetValueCount()
+	if err := c.meta.WriteUvarint(ctx, uint64(size)); err != nil {
+		return err
+	}
+
+	compress := c.mode == BEST_COMPRESSION && size > TERMS_DICT_BLOCK_COMPRESSION_THRESHOLD
+	code, blockMask, shift := TERMS_DICT_BLOCK_SHIFT, int64(TERMS_DICT_BLOCK_MASK), TERMS_DICT_BLOCK_SHIFT
+	if compress {
+		code, blockMask, shift = TERMS_DICT_BLOCK_LZ4_CODE, TERMS_DICT_BLOCK_LZ4_MASK, TERMS_DICT_BLOCK_LZ4_SHIFT
+	}
+	if err := c.meta.WriteUint32(ctx, uint32(code)); err != nil {
+		return err
+	}
+	if err := c.meta.WriteUint32(ctx, DIRECT_MONOTONIC_BLOCK_SHIFT); err != nil {
+		return err
+	}
+
+	addressBuffer := store.NewBufferDataOutput()
+	numBlocks := (size + blockMask) >> shift
+	writer, err := packed.NewDirectMonotonicWriter(c.meta, addressBuffer, numBlocks, DIRECT_MONOTONIC_BLOCK_SHIFT)
+	if err != nil {
+		return err
+	}
+
+	// when compressing, all terms of a block but the first one are buffered, and the buffer is
+	// compressed when the block is complete
+	var blockOutput store.DataOutput = c.data
+	var bufferedOutput *store.BufferOutput
+	var ht *lz4.FastCompressionHashTable
+	if compress {
+		bufferedOutput = store.NewBufferDataOutput()
+		blockOutput = bufferedOutput
+		ht = lz4.NewFastCompressionHashTable()
+	}
+
+	var previous []byte
+	start := c.data.GetFilePointer()
+	maxLength, maxBlockLength := 0, 0
+	for ord := int64(0); ord < size; ord++ {
+		term, err := values.LookupOrd(ord)
+		if err != nil {
+			return err
+		}
+
+		if ord&blockMask == 0 {
+			if compress && bufferedOutput.GetFilePointer() > 0 {
+				blockLength, err := c.compressTermsDictBlock(ctx, bufferedOutput, ht)
+				if err != nil {
+					return err
+				}
+				maxBlockLength = max(maxBlockLength, blockLength)
+			}
+			if err := writer.Add(ctx, c.data.GetFilePointer()-start); err != nil {
+				return err
+			}
+			if err := c.data.WriteUvarint(ctx, uint64(len(term))); err != nil {
+				return err
+			}
+			if _, err := c.data.Write(term); err != nil {
+				return err
+			}
+		} else {
+			prefixLength, err := util.BytesDifference(previous, term)
+			if err != nil {
+				return err
+			}
+			suffixLength := len(term) - prefixLength
+			if suffixLength <= 0 {
+				return fmt.Errorf("terms are not unique: %q", term)
+			}
+
+			if err := blockOutput.WriteByte(byte(min(prefixLength, 15) | (min(15, suffixLength-1) << 4))); err != nil {
+				return err
+			}
+			if prefixLength >= 15 {
+				if err := blockOutput.WriteUvarint(ctx, uint64(prefixLength-15)); err != nil {
+					return err
+				}
+			}
+			if suffixLength >= 16 {
+				if err := blockOutput.WriteUvarint(ctx, uint64(suffixLength-16)); err != nil {
+					return err
+				}
+			}
+			if _, err := blockOutput.Write(term[prefixLength:]); err != nil {
+				return err
+			}
+		}
+		maxLength = max(maxLength, len(term))
+		previous = append(previous[:0], term...)
+	}
+	// Compress and write out the last block
+	if compress && bufferedOutput.GetFilePointer() > 0 {
+		blockLength, err := c.compressTermsDictBlock(ctx, bufferedOutput, ht)
+		if err != nil {
+			return err
+		}
+		maxBlockLength = max(maxBlockLength, blockLength)
+	}
+	if err := writer.Finish(ctx); err != nil {
+		return err
+	}
+
+	if err := c.meta.WriteUint32(ctx, uint32(maxLength)); err != nil {
+		return err
+	}
+	if compress {
+		// the reader needs the max block length to size its decompression buffer
+		if err := c.meta.WriteUint32(ctx, uint32(maxBlockLength)); err != nil {
+			return err
+		}
+	}
+	if err := c.writeDataRange(ctx, start); err != nil {
+		return err
+	}
+	if err := c.copyBuffer(ctx, addressBuffer); err != nil {
+		return err
+	}
+
+	// Now write the reverse terms index
+	return c.writeTermsIndex(ctx, values)
+}
+
+// compressTermsDictBlock writes the uncompressed length of the buffered terms followed by the terms
+// compressed with LZ4, and resets the buffer. It returns the size of the buffer that the reader
+// needs to decompress the block, which is larger than the uncompressed length if the terms are not
+// compressible.
+func (c *DocValuesConsumer) compressTermsDictBlock(ctx context.Context, buffer *store.BufferOutput,
+	ht *lz4.FastCompressionHashTable) (int, error) {
+
+	uncompressedLength := len(buffer.Bytes())
+	if err := c.data.WriteUvarint(ctx, uint64(uncompressedLength)); err != nil {
+		return 0, err
+	}
+	before := c.data.GetFilePointer()
+	if err := lz4.Compress(buffer.Bytes(), c.data, ht); err != nil {
+		return 0, err
+	}
+	compressedLength := int(c.data.GetFilePointer() - before)
+	buffer.Reset()
+	return max(uncompressedLength, compressedLength), nil
+}
+
+func (c *DocValuesConsumer) writeTermsIndex(ctx context.Context, values index.SortedSetDocValues) error {
+	size := values.GetValueCount()
+	if err := c.meta.WriteUint32(ctx, TERMS_DICT_REVERSE_INDEX_SHIFT); err != nil {
+		return err
+	}
+	start := c.data.GetFilePointer()
+
+	numBlocks := 1 + ((size + TERMS_DICT_REVERSE_INDEX_MASK) >> TERMS_DICT_REVERSE_INDEX_SHIFT)
+	addressBuffer := store.NewBufferDataOutput()
+	writer, err := packed.NewDirectMonotonicWriter(c.meta, addressBuffer, numBlocks, DIRECT_MONOTONIC_BLOCK_SHIFT)
+	if err != nil {
+		return err
+	}
+
+	var previous []byte
+	offset := int64(0)
+	for ord := int64(0); ord < size; ord++ {
+		switch ord & TERMS_DICT_REVERSE_INDEX_MASK {
+		case 0:
+			term, err := values.LookupOrd(ord)
+			if err != nil {
+				return err
+			}
+			if err := writer.Add(ctx, offset); err != nil {
+				return err
+			}
+			sortKeyLength := 0
+			if ord > 0 {
+				sortKeyLength = util.SortKeyLength(previous, term)
+			}
+			offset += int64(sortKeyLength)
+			if _, err := c.data.Write(term[:sortKeyLength]); err != nil {
+				return err
+			}
+		case TERMS_DICT_REVERSE_INDEX_MASK:
+			term, err := values.LookupOrd(ord)
+			if err != nil {
+				return err
+			}
+			previous = append(previous[:0], term...)
+		}
+	}
+	if err := writer.Add(ctx, offset); err != nil {
+		return err
+	}
+	if err := writer.Finish(ctx); err != nil {
+		return err
+	}
+
+	if err := c.writeDataRange(ctx, start); err != nil {
+		return err
+	}
+	return c.copyBuffer(ctx, addressBuffer)
+}
+
+// writeDataRange writes the offset and the length of the data written since start
+func (c *DocValuesConsumer) writeDataRange(ctx context.Context, start int64) error {
+	if err := c.meta.WriteUint64(ctx, uint64(start)); err != nil {
+		return err
+	}
+	return c.meta.WriteUint64(ctx, uint64(c.data.GetFilePointer()-start))
+}
+
+// copyBuffer appends buffer to the data and writes its range
+func (c *DocValuesConsumer) copyBuffer(ctx context.Context, buffer *store.BufferOutput) error {
+	start := c.data.GetFilePointer()
+	if err := buffer.CopyTo(c.data); err != nil {
+		return err
+	}
+	return c.writeDataRange(ctx, start)
+}
+
+func (c *DocValuesConsumer) AddSortedNumericField(ctx context.Context, field *document.FieldInfo, valuesProducer index.DocValuesProducer) error {
+	if err := c.writeFieldEntry(ctx, field, SORTED_NUMERIC); err != nil {
+		return err
+	}
+
+	getValues := func() (index.SortedNumericDocValues, error) {
+		return valuesProducer.GetSortedNumeric(ctx, field)
+	}
+	numDocsWithField, numValues, err := c.writeValues(ctx, getValues)
+	if err != nil {
+		return err
+	}
+	if err := c.meta.WriteUint32(ctx, uint32(numDocsWithField)); err != nil {
+		return err
+	}
+	if numValues <= int64(numDocsWithField) {
+		return nil
+	}
+
+	values, err := getValues()
+	if err != nil {
+		return err
+	}
+	return c.writeAddresses(ctx, numDocsWithField, values, func() (int64, error) {
+		return int64(values.DocValueCount()), nil
+	})
+}
+
+func (c *DocValuesConsumer) AddSortedSetField(ctx context.Context, field *document.FieldInfo, valuesProducer index.DocValuesProducer) error {
+	if err := c.writeFieldEntry(ctx, field, SORTED_SET); err != nil {
+		return err
+	}
+
+	getValues := func() (index.SortedSetDocValues, error) {
+		return valuesProducer.GetSortedSet(ctx, field)
+	}
+
+	values, err := getValues()
+	if err != nil {
+		return err
+	}
+	numDocsWithField := 0
+	numOrds := int64(0)
+	for {
+		doc, err := nextDoc(values)
+		if err != nil {
+			return err
+		}
+		if doc == types.NO_MORE_DOCS {
+			break
+		}
+		numDocsWithField++
+		for {
+			ord, err := nextOrd(values)
+			if err != nil {
+				return err
+			}
+			if ord == coreIndex.NO_MORE_ORDS {
+				break
+			}
+			numOrds++
+		}
+	}
+
+	if int64(numDocsWithField) == numOrds {
+		if err := c.meta.WriteByte(0); err != nil { // multiValued (0 = singleValued)
+			return err
+		}
+		return c.doAddSortedField(ctx, getValues)
+	}
+	if err := c.meta.WriteByte(1); err != nil { // multiValued (1 = multiValued)
+		return err
+	}
+
+	if err := c.writeDocsWithField(ctx, numDocsWithField, func() (types.DocIdSetIterator, error) {
+		return getValues()
+	}); err != nil {
+		return err
+	}
+
+	numberOfBitsPerOrd := packed.DirectUnsignedBitsRequired(uint64(values.GetValueCount() - 1))
+	if err := c.meta.WriteByte(byte(numberOfBitsPerOrd)); err != nil { // bitsPerValue
+		return err
+	}
+	start := c.data.GetFilePointer()
+	if err := c.meta.WriteUint64(ctx, uint64(start)); err != nil { // ordsOffset
+		return err
+	}
+	writer, err := packed.NewDirectWriter(c.data, int(numOrds), numberOfBitsPerOrd)
+	if err != nil {
+		return err
+	}
+	if values, err = getValues(); err != nil {
+		return err
+	}
+	for {
+		doc, err := nextDoc(values)
+		if err != nil {
+			return err
+		}
+		if doc == types.NO_MORE_DOCS {
+			break
+		}
+		for {
+			ord, err := nextOrd(values)
+			if err != nil {
+				return err
+			}
+			if ord == coreIndex.NO_MORE_ORDS {
+				break
+			}
+			if err := writer.Add(uint64(ord)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := writer.Finish(); err != nil {
+		return err
+	}
+	if err := c.meta.WriteUint64(ctx, uint64(c.data.GetFilePointer()-start)); err != nil { // ordsLength
+		return err
+	}
+
+	if err := c.meta.WriteUint32(ctx, uint32(numDocsWithField)); err != nil {
+		return err
+	}
+	if values, err = getValues(); err != nil {
+		return err
+	}
+	if err := c.writeAddresses(ctx, numDocsWithField, values, func() (int64, error) {
+		count := int64(0)
+		for {
+			ord, err := nextOrd(values)
+			if err != nil {
+				return 0, err
+			}
+			if ord == coreIndex.NO_MORE_ORDS {
+				return count, nil
+			}
+			count++
+		}
+	}); err != nil {
+		return err
+	}
+
+	return c.addTermsDict(ctx, values)
+}
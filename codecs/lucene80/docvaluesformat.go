@@ -0,0 +1,204 @@
+package lucene80
+
+import (
+	"context"
+	"fmt"
+
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+)
+
+const (
+	DATA_CODEC     = "Lucene80DocValuesData"
+	DATA_EXTENSION = "dvd"
+	META_CODEC     = "Lucene80DocValuesMetadata"
+	META_EXTENSION = "dvm"
+
+	VERSION_START                    = 0
+	VERSION_BIN_COMPRESSED           = 1
+	VERSION_CONFIGURABLE_COMPRESSION = 2
+	VERSION_CURRENT                  = VERSION_CONFIGURABLE_COMPRESSION
+
+	// MODE_KEY Attribute key for compression mode.
+	MODE_KEY = "Lucene80DocValuesFormat.mode"
+
+	// indicates docvalues type
+	NUMERIC        = 0
+	BINARY         = 1
+	SORTED         = 2
+	SORTED_SET     = 3
+	SORTED_NUMERIC = 4
+
+	DIRECT_MONOTONIC_BLOCK_SHIFT = 16
+
+	NUMERIC_BLOCK_SHIFT = 14
+	NUMERIC_BLOCK_SIZE  = 1 << NUMERIC_BLOCK_SHIFT
+
+	BINARY_BLOCK_SHIFT = 5
+	// BINARY_DOCS_PER_COMPRESSED_BLOCK number of documents whose binary values are compressed together
+	BINARY_DOCS_PER_COMPRESSED_BLOCK = 1 << BINARY_BLOCK_SHIFT
+
+	TERMS_DICT_BLOCK_SHIFT = 4
+	TERMS_DICT_BLOCK_SIZE  = 1 << TERMS_DICT_BLOCK_SHIFT
+	TERMS_DICT_BLOCK_MASK  = TERMS_DICT_BLOCK_SIZE - 1
+
+	// TERMS_DICT_BLOCK_COMPRESSION_THRESHOLD terms dictionaries with fewer terms are not compressed
+	TERMS_DICT_BLOCK_COMPRESSION_THRESHOLD = 32
+	TERMS_DICT_BLOCK_LZ4_SHIFT             = 6
+	TERMS_DICT_BLOCK_LZ4_SIZE              = 1 << TERMS_DICT_BLOCK_LZ4_SHIFT
+	TERMS_DICT_BLOCK_LZ4_MASK              = TERMS_DICT_BLOCK_LZ4_SIZE - 1
+	TERMS_DICT_COMPRESSOR_LZ4_CODE         = 1
+	// TERMS_DICT_BLOCK_LZ4_CODE Written in place of the block shift, so we know the blocks are
+	// LZ4-compressed.
+	TERMS_DICT_BLOCK_LZ4_CODE = TERMS_DICT_BLOCK_LZ4_SHIFT<<16 | TERMS_DICT_COMPRESSOR_LZ4_CODE
+
+	TERMS_DICT_REVERSE_INDEX_SHIFT = 10
+	TERMS_DICT_REVERSE_INDEX_SIZE  = 1 << TERMS_DICT_REVERSE_INDEX_SHIFT
+	TERMS_DICT_REVERSE_INDEX_MASK  = TERMS_DICT_REVERSE_INDEX_SIZE - 1
+)
+
+// Mode Configuration option for doc values.
+type Mode int
+
+const (
+	// BEST_SPEED Trade compression ratio for retrieval speed.
+	BEST_SPEED = Mode(iota)
+
+	// BEST_COMPRESSION Trade retrieval speed for compression ratio.
+	BEST_COMPRESSION
+)
+
+func (m Mode) String() string {
+	switch m {
+	case BEST_SPEED:
+		return "BEST_SPEED"
+	case BEST_COMPRESSION:
+		return "BEST_COMPRESSION"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// ParseMode Returns the Mode with the given name.
+func ParseMode(name string) (Mode, error) {
+	switch name {
+	case "BEST_SPEED":
+		return BEST_SPEED, nil
+	case "BEST_COMPRESSION":
+		return BEST_COMPRESSION, nil
+	default:
+		return 0, fmt.Errorf("unknown doc values mode: %s", name)
+	}
+}
+
+func init() {
+	coreIndex.RegisterDocValuesFormat(NewDocValuesFormat())
+}
+
+var _ index.DocValuesFormat = &DocValuesFormat{}
+
+// DocValuesFormat Lucene 8.0 DocValues format.
+//
+// Documents that have a value for the field are encoded in a way that it is always possible to know
+// the ordinal of the current document in the set of documents that have a value. For instance, say
+// the set of documents that have a value for the field is {1, 5, 6, 11}. When the iterator is on 6,
+// it knows that this is the 3rd item of the set. This way, values can be stored densely and accessed
+// based on their index at search time. If all documents in a segment have a value for the field, the
+// index is the same as the doc ID, so this case is encoded implicitly and is very fast at query time.
+// On the other hand if some documents are missing a value for the field then the set of documents
+// that have a value is encoded into blocks. All doc IDs that share the same upper 16 bits are encoded
+// into the same block with the following strategies:
+//   - SPARSE: This strategy is used when a block contains at most 4095 documents. The lower 16 bits of
+//     doc IDs are stored as shorts while the upper 16 bits are given by the block ID.
+//   - DENSE: This strategy is used when a block contains between 4096 and 65535 documents. The lower
+//     bits of doc IDs are stored in a bit set. Advancing < 512 documents is performed using
+//     ntz operations while the index is computed by accumulating the bit counts of the visited longs.
+//     Advancing >= 512 documents is performed by skipping to the start of the needed 512 document
+//     sub-block and iterating to the specific document within that block. The index for the sub-block
+//     that is skipped to is retrieved from a rank-table positioned before the bit set. The rank-table
+//     holds the origo index numbers for all 128 512 document sub-blocks, represented as an unsigned
+//     short for each 128 blocks.
+//   - ALL: This strategy is used when a block contains exactly 65536 documents, meaning that the block
+//     is full. In that case doc IDs do not need to be stored explicitly. This is typically faster than
+//     both SPARSE and DENSE which is a reason why it is preferable to have all documents that have a
+//     value for a field using contiguous doc IDs, for instance by using index sorting.
+//
+// Skipping blocks to arrive at a wanted document is either done on an iterative basis or by using the
+// jump-table stored at the end of the chain of blocks. The jump-table holds the offset as well as the
+// index for all blocks, as a pair of ints per block.
+//
+// Then the five per-document value types (Numeric,Binary,Sorted,SortedSet,SortedNumeric) are encoded
+// using the following strategies:
+//
+// NUMERIC:
+//   - Delta-compressed: per-document integers written as deltas from the minimum value, compressed with
+//     bitpacking. For more information, see DirectWriter.
+//   - Table-compressed: when the number of unique values is very small (< 256), and when there are
+//     unused "gaps" in the range of values used (such as SmallFloat), a lookup table is written instead.
+//     Each per-document entry is instead the ordinal to this table, and those ordinals are compressed
+//     with bitpacking (DirectWriter).
+//   - GCD-compressed: when all numbers share a common divisor, such as dates, the greatest common
+//     denominator (GCD) is computed, and quotients are stored using Delta-compressed Numerics.
+//   - Monotonic-compressed: when all numbers are monotonically increasing offsets, they are written as
+//     blocks of bitpacked integers, encoding the deviation from the expected delta.
+//   - Const-compressed: when there is only one possible value, no per-document data is needed and this
+//     value is encoded alone.
+//
+// Depending on calculated gains, the numbers might be split into blocks of 16384 values. In that case,
+// a jump-table with block offsets is appended to the blocks for O(1) access to the needed block.
+//
+// BINARY:
+//   - Fixed-width Binary: one large concatenated byte[] is written, along with the fixed length. Each
+//     document's value can be addressed directly with multiplication (docID * length).
+//   - Variable-width Binary: one large concatenated byte[] is written, along with end addresses for each
+//     document. The addresses are written as Monotonic-compressed numerics.
+//   - Compressed Binary: with BEST_COMPRESSION, the values of every 32 documents are compressed
+//     together with LZ4, after the lengths of the values. The start addresses of the blocks are
+//     written as Monotonic-compressed numerics.
+//
+// SORTED: an ordinal for each document, compressed with bitpacking, plus a terms dictionary.
+//
+// SORTED_SET: a SortedSet encodes an ordinal list and offsets for each document. If all documents
+// have 0 or 1 value, then data are written like SORTED.
+//
+// SORTED_NUMERIC: a SortedNumeric is encoded like NUMERIC for the values, plus monotonic addresses
+// into the values for each document. If all documents have 0 or 1 value, then data are written like
+// NUMERIC.
+//
+// Terms dictionary: terms are written in blocks of 16, the first term of a block is written in full
+// and the other ones are prefix-compressed against the previous term. A reverse index stores the
+// shortest prefix that sorts after the previous term of every 1024th term, which allows for binary
+// searching blocks on seekCeil. With BEST_COMPRESSION, dictionaries of more than 32 terms are written
+// in blocks of 64 terms instead, and all terms of a block but the first one are compressed with LZ4.
+//
+// Files:
+//   - .dvd: DocValues data
+//   - .dvm: DocValues metadata
+//
+// lucene.experimental
+type DocValuesFormat struct {
+	name string
+	mode Mode
+}
+
+// NewDocValuesFormat Default constructor, which uses BEST_SPEED.
+func NewDocValuesFormat() *DocValuesFormat {
+	return NewDocValuesFormatWithMode(BEST_SPEED)
+}
+
+// NewDocValuesFormatWithMode Creates a DocValuesFormat with the given compression mode.
+func NewDocValuesFormatWithMode(mode Mode) *DocValuesFormat {
+	return &DocValuesFormat{name: "Lucene80", mode: mode}
+}
+
+func (d *DocValuesFormat) GetName() string {
+	return d.name
+}
+
+func (d *DocValuesFormat) FieldsConsumer(ctx context.Context, state *index.SegmentWriteState) (index.DocValuesConsumer, error) {
+	return NewDocValuesConsumer(ctx, state, DATA_CODEC, DATA_EXTENSION, META_CODEC, META_EXTENSION, d.mode)
+}
+
+func (d *DocValuesFormat) FieldsProducer(ctx context.Context, state *index.SegmentReadState) (index.DocValuesProducer, error) {
+	return NewDocValuesProducer(ctx, state, DATA_CODEC, DATA_EXTENSION, META_CODEC, META_EXTENSION)
+}
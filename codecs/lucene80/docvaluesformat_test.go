@@ -0,0 +1,607 @@
+package lucene80
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/automaton"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ index.SortedNumericDocValues = &testSortedNumeric{}
+
+// testSortedNumeric in-memory values for numeric and sorted numeric fields
+type testSortedNumeric struct {
+	*testDocsIterator
+
+	values [][]int64
+	doc    int
+	i      int
+}
+
+func (s *testSortedNumeric) LongValue() (int64, error) {
+	return s.values[s.upto][0], nil
+}
+
+func (s *testSortedNumeric) NextValue() (int64, error) {
+	if s.doc != s.DocID() {
+		s.doc, s.i = s.DocID(), 0
+	}
+	s.i++
+	return s.values[s.upto][s.i-1], nil
+}
+
+func (s *testSortedNumeric) DocValueCount() int {
+	return len(s.values[s.upto])
+}
+
+var _ index.SortedDocValues = &testSortedSet{}
+
+var _ index.SortedSetDocValues = &testSortedSetValues{}
+
+// testSortedSet in-memory values for binary, sorted and sorted set fields
+type testSortedSet struct {
+	*testDocsIterator
+
+	ords  [][]int64
+	terms [][]byte
+	doc   int
+	i     int
+}
+
+func (s *testSortedSet) BinaryValue() ([]byte, error) {
+	return s.terms[s.ords[s.upto][0]], nil
+}
+
+func (s *testSortedSet) OrdValue() (int, error) {
+	return int(s.ords[s.upto][0]), nil
+}
+
+func (s *testSortedSet) LookupOrd(ord int) ([]byte, error) {
+	return s.terms[ord], nil
+}
+
+func (s *testSortedSet) GetValueCount() int {
+	return len(s.terms)
+}
+
+func (s *testSortedSet) LookupTerm(key []byte) (int, error) {
+	return 0, coreIndex.ErrUnsupportedOperation
+}
+
+func (s *testSortedSet) TermsEnum() (index.TermsEnum, error) {
+	return nil, coreIndex.ErrUnsupportedOperation
+}
+
+func (s *testSortedSet) Intersect(automaton *automaton.CompiledAutomaton) (index.TermsEnum, error) {
+	return nil, coreIndex.ErrUnsupportedOperation
+}
+
+// testSortedSetValues exposes a testSortedSet as index.SortedSetDocValues
+type testSortedSetValues struct {
+	*testSortedSet
+}
+
+func (s *testSortedSetValues) NextOrd() (int64, error) {
+	if s.doc != s.DocID() {
+		s.doc, s.i = s.DocID(), 0
+	}
+	if s.i == len(s.ords[s.upto]) {
+		return coreIndex.NO_MORE_ORDS, nil
+	}
+	s.i++
+	return s.ords[s.upto][s.i-1], nil
+}
+
+func (s *testSortedSetValues) LookupOrd(ord int64) ([]byte, error) {
+	return s.terms[ord], nil
+}
+
+func (s *testSortedSetValues) GetValueCount() int64 {
+	return int64(len(s.terms))
+}
+
+// testField the documents that have a value for a field and their values
+type testField struct {
+	docs    []int
+	numbers [][]int64
+	ords    [][]int64
+	terms   [][]byte
+}
+
+func (f *testField) numeric() *testSortedNumeric {
+	return &testSortedNumeric{testDocsIterator: newTestDocsIterator(f.docs), values: f.numbers, doc: -1}
+}
+
+func (f *testField) sortedSet() *testSortedSet {
+	return &testSortedSet{testDocsIterator: newTestDocsIterator(f.docs), ords: f.ords, terms: f.terms, doc: -1}
+}
+
+func randomDocs(r *rand.Rand, maxDoc int, density float64) []int {
+	docs := make([]int, 0)
+	for doc := 0; doc < maxDoc; doc++ {
+		if r.Float64() < density {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+func randomNumbers(r *rand.Rand, docs []int, maxValues int, next func(i int) int64) [][]int64 {
+	numbers := make([][]int64, len(docs))
+	for i := range docs {
+		values := make([]int64, 1+r.Intn(maxValues))
+		for j := range values {
+			values[j] = next(i)
+		}
+		slices.Sort(values)
+		numbers[i] = values
+	}
+	return numbers
+}
+
+func randomTerms(r *rand.Rand, size int) [][]byte {
+	unique := make(map[string]struct{}, size)
+	for len(unique) < size {
+		var term string
+		switch r.Intn(3) {
+		case 0:
+			term = fmt.Sprintf("%d", r.Intn(size*10))
+		case 1:
+			// long shared prefixes and suffixes
+			term = fmt.Sprintf("a-long-shared-prefix-for-terms/%d/%s", r.Intn(size), bytes.Repeat([]byte("x"), r.Intn(40)))
+		default:
+			term = string([]byte{byte('a' + r.Intn(26)), byte('a' + r.Intn(26))})
+		}
+		unique[term] = struct{}{}
+	}
+	terms := make([][]byte, 0, size)
+	for term := range unique {
+		terms = append(terms, []byte(term))
+	}
+	slices.SortFunc(terms, bytes.Compare)
+	return terms
+}
+
+func randomOrds(r *rand.Rand, docs []int, size, maxValues int) [][]int64 {
+	ords := make([][]int64, len(docs))
+	for i := range docs {
+		unique := make(map[int64]struct{})
+		for j, n := 0, 1+r.Intn(maxValues); j < n; j++ {
+			unique[int64(r.Intn(size))] = struct{}{}
+		}
+		for ord := range unique {
+			ords[i] = append(ords[i], ord)
+		}
+		slices.Sort(ords[i])
+	}
+	return ords
+}
+
+func TestDocValuesFormat(t *testing.T) {
+	for _, mode := range []Mode{BEST_SPEED, BEST_COMPRESSION} {
+		t.Run(mode.String(), func(t *testing.T) {
+			testDocValuesFormat(t, mode)
+		})
+	}
+}
+
+func testDocValuesFormat(t *testing.T, mode Mode) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(7))
+	maxDoc := NUMERIC_BLOCK_SIZE + 5000
+
+	densities := map[string]float64{"dense": 1, "sparse": 0.1, "empty": 0}
+
+	numerics := map[string]func(i int) int64{
+		"constant": func(int) int64 { return 42 },
+		"table":    func(int) int64 { return []int64{-5, 1000, 1 << 40}[r.Intn(3)] },
+		"gcd":      func(int) int64 { return 1700000000000 + int64(r.Intn(100000))*1000 },
+		"delta":    func(int) int64 { return r.Int63n(1 << 20) },
+		"extremes": func(int) int64 { return []int64{math.MinInt64, math.MaxInt64, 0, r.Int63()}[r.Intn(4)] },
+		"blocks": func(i int) int64 {
+			if i < NUMERIC_BLOCK_SIZE {
+				return int64(r.Intn(4))
+			}
+			return r.Int63n(1 << 50)
+		},
+	}
+
+	fields := make(map[string]*testField)
+	infos := make([]*document.FieldInfo, 0)
+	addField := func(name string, dvType document.DocValuesType, field *testField) {
+		fields[name] = field
+		infos = append(infos, document.NewFieldInfo(name, len(infos), false, true, false,
+			document.INDEX_OPTIONS_NONE, dvType, -1, map[string]string{}, 0, 0, 0, false))
+	}
+
+	for _, density := range []string{"dense", "sparse", "empty"} {
+		p := densities[density]
+		for _, kind := range []string{"constant", "table", "gcd", "delta", "extremes", "blocks"} {
+			next := numerics[kind]
+			docs := randomDocs(r, maxDoc, p)
+			addField("numeric_"+kind+"_"+density, document.DOC_VALUES_TYPE_NUMERIC,
+				&testField{docs: docs, numbers: randomNumbers(r, docs, 1, next)})
+			docs = randomDocs(r, maxDoc, p)
+			addField("sortednumeric_"+kind+"_"+density, document.DOC_VALUES_TYPE_SORTED_NUMERIC,
+				&testField{docs: docs, numbers: randomNumbers(r, docs, 3, next)})
+		}
+
+		for _, size := range []int{1, 2, 3000} {
+			terms := randomTerms(r, size)
+			docs := randomDocs(r, maxDoc, p)
+			addField(fmt.Sprintf("binary_%d_%s", size, density), document.DOC_VALUES_TYPE_BINARY,
+				&testField{docs: docs, ords: randomOrds(r, docs, size, 1), terms: terms})
+			docs = randomDocs(r, maxDoc, p)
+			addField(fmt.Sprintf("sorted_%d_%s", size, density), document.DOC_VALUES_TYPE_SORTED,
+				&testField{docs: docs, ords: randomOrds(r, docs, size, 1), terms: terms})
+			docs = randomDocs(r, maxDoc, p)
+			addField(fmt.Sprintf("sortedset_single_%d_%s", size, density), document.DOC_VALUES_TYPE_SORTED_SET,
+				&testField{docs: docs, ords: randomOrds(r, docs, size, 1), terms: terms})
+			docs = randomDocs(r, maxDoc, p)
+			addField(fmt.Sprintf("sortedset_%d_%s", size, density), document.DOC_VALUES_TYPE_SORTED_SET,
+				&testField{docs: docs, ords: randomOrds(r, docs, size, 4), terms: terms})
+		}
+	}
+	// fixed length binary values
+	docs := randomDocs(r, maxDoc, 0.5)
+	fixed := make([][]byte, 100)
+	for i := range fixed {
+		fixed[i] = []byte(fmt.Sprintf("%08d", i))
+	}
+	addField("binary_fixed", document.DOC_VALUES_TYPE_BINARY,
+		&testField{docs: docs, ords: randomOrds(r, docs, len(fixed), 1), terms: fixed})
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+	id := make([]byte, 16)
+	r.Read(id)
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", maxDoc,
+		false, nil, map[string]string{}, id, map[string]string{}, nil)
+	fieldInfos := coreIndex.NewFieldInfos(infos)
+
+	format := NewDocValuesFormatWithMode(mode)
+	consumer, err := format.FieldsConsumer(ctx, &index.SegmentWriteState{
+		Directory:   dir,
+		SegmentInfo: si,
+		FieldInfos:  fieldInfos,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	source := &coreIndex.EmptyDocValuesProducer{
+		FnGetNumeric: func(ctx context.Context, field *document.FieldInfo) (index.NumericDocValues, error) {
+			return fields[field.Name()].numeric(), nil
+		},
+		FnGetBinary: func(ctx context.Context, field *document.FieldInfo) (index.BinaryDocValues, error) {
+			return fields[field.Name()].sortedSet(), nil
+		},
+		FnGetSorted: func(ctx context.Context, field *document.FieldInfo) (index.SortedDocValues, error) {
+			return fields[field.Name()].sortedSet(), nil
+		},
+		FnGetSortedNumeric: func(ctx context.Context, field *document.FieldInfo) (index.SortedNumericDocValues, error) {
+			return fields[field.Name()].numeric(), nil
+		},
+		FnGetSortedSet: func(ctx context.Context, field *document.FieldInfo) (index.SortedSetDocValues, error) {
+			return &testSortedSetValues{fields[field.Name()].sortedSet()}, nil
+		},
+	}
+	for _, info := range infos {
+		switch info.GetDocValuesType() {
+		case document.DOC_VALUES_TYPE_NUMERIC:
+			err = consumer.AddNumericField(ctx, info, source)
+		case document.DOC_VALUES_TYPE_BINARY:
+			err = consumer.AddBinaryField(ctx, info, source)
+		case document.DOC_VALUES_TYPE_SORTED:
+			err = consumer.AddSortedField(ctx, info, source)
+		case document.DOC_VALUES_TYPE_SORTED_NUMERIC:
+			err = consumer.AddSortedNumericField(ctx, info, source)
+		case document.DOC_VALUES_TYPE_SORTED_SET:
+			err = consumer.AddSortedSetField(ctx, info, source)
+		}
+		assert.Nil(t, err, info.Name())
+	}
+	assert.Nil(t, consumer.Close())
+
+	producer, err := format.FieldsProducer(ctx, &index.SegmentReadState{
+		Directory:   dir,
+		SegmentInfo: si,
+		FieldInfos:  fieldInfos,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer producer.Close()
+	assert.Nil(t, producer.CheckIntegrity())
+
+	// the numeric fields use the expected encodings
+	numericEntries := producer.(*DocValuesProducer).numerics
+	assert.Equal(t, 0, numericEntries["numeric_constant_dense"].bitsPerValue)
+	assert.Len(t, numericEntries["numeric_table_dense"].table, 3)
+	assert.Equal(t, int64(1000), numericEntries["numeric_gcd_dense"].gcd)
+	assert.Equal(t, NUMERIC_BLOCK_SHIFT, numericEntries["numeric_blocks_dense"].blockShift)
+	assert.Equal(t, int64(-1), numericEntries["numeric_delta_dense"].docsWithFieldOffset)
+	assert.Equal(t, int64(-2), numericEntries["numeric_delta_empty"].docsWithFieldOffset)
+	assert.Less(t, int64(-1), numericEntries["numeric_delta_sparse"].docsWithFieldOffset)
+
+	// binary values and large terms dictionaries are only compressed with BEST_COMPRESSION
+	assert.Equal(t, mode.String(), fieldInfos.FieldInfo("binary_2_dense").GetAttribute(MODE_KEY))
+	assert.Equal(t, mode == BEST_COMPRESSION, producer.(*DocValuesProducer).binaries["binary_2_dense"].compressed)
+	assert.Equal(t, mode == BEST_COMPRESSION, producer.(*DocValuesProducer).sorted["sorted_3000_dense"].compressed)
+	assert.False(t, producer.(*DocValuesProducer).sorted["sorted_2_dense"].compressed)
+
+	for _, info := range infos {
+		t.Run(info.Name(), func(t *testing.T) {
+			field := fields[info.Name()]
+			switch info.GetDocValuesType() {
+			case document.DOC_VALUES_TYPE_NUMERIC:
+				checkNumeric(t, r, producer, info, field, maxDoc)
+			case document.DOC_VALUES_TYPE_BINARY:
+				checkBinary(t, r, producer, info, field, maxDoc)
+			case document.DOC_VALUES_TYPE_SORTED:
+				checkSorted(t, r, producer, info, field, maxDoc)
+			case document.DOC_VALUES_TYPE_SORTED_NUMERIC:
+				checkSortedNumeric(t, r, producer, info, field, maxDoc)
+			case document.DOC_VALUES_TYPE_SORTED_SET:
+				checkSortedSet(t, r, producer, info, field, maxDoc)
+			}
+		})
+	}
+}
+
+// checkIterator checks that values iterates over the docs of field with nextDoc, advance and
+// advanceExact, calling check on every document that has a value.
+func checkIterator(t *testing.T, r *rand.Rand, newValues func() types.DocValuesIterator, field *testField,
+	maxDoc int, check func(values types.DocValuesIterator, i int)) {
+
+	values := newValues()
+	for i, expected := range field.docs {
+		doc, err := values.NextDoc()
+		assert.Nil(t, err)
+		if !assert.Equal(t, expected, doc) {
+			return
+		}
+		check(values, i)
+	}
+	doc, err := values.NextDoc()
+	assert.Nil(t, err)
+	assert.Equal(t, types.NO_MORE_DOCS, doc)
+
+	values = newValues()
+	for target := r.Intn(10); target < maxDoc; target += 1 + r.Intn(500) {
+		doc, err := values.Advance(target)
+		assert.Nil(t, err)
+		i := ceil(field.docs, target)
+		if i == len(field.docs) {
+			assert.Equal(t, types.NO_MORE_DOCS, doc)
+			break
+		}
+		if !assert.Equal(t, field.docs[i], doc) {
+			return
+		}
+		check(values, i)
+		target = doc
+	}
+
+	index := make(map[int]int, len(field.docs))
+	for i, doc := range field.docs {
+		index[doc] = i
+	}
+	values = newValues()
+	for target := r.Intn(10); target < maxDoc; target += 1 + r.Intn(100) {
+		found, err := values.AdvanceExact(target)
+		assert.Nil(t, err)
+		i, ok := index[target]
+		if !assert.Equal(t, ok, found, "target %d", target) {
+			return
+		}
+		if ok {
+			check(values, i)
+		}
+	}
+}
+
+func checkNumeric(t *testing.T, r *rand.Rand, producer index.DocValuesProducer, info *document.FieldInfo,
+	field *testField, maxDoc int) {
+
+	newValues := func() types.DocValuesIterator {
+		values, err := producer.GetNumeric(context.Background(), info)
+		assert.Nil(t, err)
+		return values
+	}
+	checkIterator(t, r, newValues, field, maxDoc, func(values types.DocValuesIterator, i int) {
+		v, err := values.(index.NumericDocValues).LongValue()
+		assert.Nil(t, err)
+		assert.Equal(t, field.numbers[i][0], v)
+	})
+}
+
+func checkSortedNumeric(t *testing.T, r *rand.Rand, producer index.DocValuesProducer, info *document.FieldInfo,
+	field *testField, maxDoc int) {
+
+	newValues := func() types.DocValuesIterator {
+		values, err := producer.GetSortedNumeric(context.Background(), info)
+		assert.Nil(t, err)
+		return values
+	}
+	checkIterator(t, r, newValues, field, maxDoc, func(it types.DocValuesIterator, i int) {
+		values := it.(index.SortedNumericDocValues)
+		actual := make([]int64, values.DocValueCount())
+		for j := range actual {
+			v, err := values.NextValue()
+			assert.Nil(t, err)
+			actual[j] = v
+		}
+		assert.Equal(t, field.numbers[i], actual)
+	})
+}
+
+func checkBinary(t *testing.T, r *rand.Rand, producer index.DocValuesProducer, info *document.FieldInfo,
+	field *testField, maxDoc int) {
+
+	newValues := func() types.DocValuesIterator {
+		values, err := producer.GetBinary(context.Background(), info)
+		assert.Nil(t, err)
+		return values
+	}
+	checkIterator(t, r, newValues, field, maxDoc, func(values types.DocValuesIterator, i int) {
+		v, err := values.(index.BinaryDocValues).BinaryValue()
+		assert.Nil(t, err)
+		assert.Equal(t, field.terms[field.ords[i][0]], v)
+	})
+}
+
+func checkSorted(t *testing.T, r *rand.Rand, producer index.DocValuesProducer, info *document.FieldInfo,
+	field *testField, maxDoc int) {
+
+	ctx := context.Background()
+	newValues := func() types.DocValuesIterator {
+		values, err := producer.GetSorted(ctx, info)
+		assert.Nil(t, err)
+		return values
+	}
+	checkIterator(t, r, newValues, field, maxDoc, func(it types.DocValuesIterator, i int) {
+		values := it.(index.SortedDocValues)
+		ord, err := values.OrdValue()
+		assert.Nil(t, err)
+		assert.Equal(t, field.ords[i][0], int64(ord))
+		v, err := values.BinaryValue()
+		assert.Nil(t, err)
+		assert.Equal(t, field.terms[ord], v)
+	})
+
+	values, err := producer.GetSorted(ctx, info)
+	assert.Nil(t, err)
+	assert.Equal(t, len(field.terms), values.GetValueCount())
+	checkTermsDict(t, r, field.terms, func(ord int64) ([]byte, error) {
+		return values.LookupOrd(int(ord))
+	})
+
+	for ord, term := range field.terms {
+		actual, err := values.LookupTerm(term)
+		assert.Nil(t, err)
+		assert.Equal(t, ord, actual)
+
+		// a term that sorts right after the current one
+		actual, err = values.LookupTerm(append(slices.Clone(term), 0))
+		assert.Nil(t, err)
+		assert.Equal(t, -2-ord, actual)
+	}
+	actual, err := values.LookupTerm(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, -1, actual)
+
+	termsEnum, err := values.TermsEnum()
+	assert.Nil(t, err)
+	for ord, expected := range field.terms {
+		term, err := termsEnum.Next(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, term)
+		actual, err := termsEnum.Ord()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(ord), actual)
+	}
+	term, err := termsEnum.Next(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, term)
+
+	status, err := termsEnum.SeekCeil(ctx, []byte{0xFF})
+	assert.Nil(t, err)
+	assert.Equal(t, index.SeekStatus(index.SEEK_STATUS_END), status)
+	if len(field.terms) > 0 {
+		last := field.terms[len(field.terms)-1]
+		found, err := termsEnum.SeekExact(ctx, last)
+		assert.Nil(t, err)
+		assert.True(t, found)
+	}
+}
+
+func checkSortedSet(t *testing.T, r *rand.Rand, producer index.DocValuesProducer, info *document.FieldInfo,
+	field *testField, maxDoc int) {
+
+	newValues := func() types.DocValuesIterator {
+		values, err := producer.GetSortedSet(context.Background(), info)
+		assert.Nil(t, err)
+		return values
+	}
+	checkIterator(t, r, newValues, field, maxDoc, func(it types.DocValuesIterator, i int) {
+		values := it.(index.SortedSetDocValues)
+		actual := make([]int64, 0)
+		for {
+			ord, err := values.NextOrd()
+			assert.Nil(t, err)
+			if ord == coreIndex.NO_MORE_ORDS {
+				break
+			}
+			actual = append(actual, ord)
+		}
+		assert.Equal(t, field.ords[i], actual)
+	})
+
+	values, err := producer.GetSortedSet(context.Background(), info)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(field.terms)), values.GetValueCount())
+	checkTermsDict(t, r, field.terms, values.LookupOrd)
+}
+
+func checkTermsDict(t *testing.T, r *rand.Rand, terms [][]byte, lookupOrd func(ord int64) ([]byte, error)) {
+	for _, ord := range r.Perm(len(terms)) {
+		term, err := lookupOrd(int64(ord))
+		assert.Nil(t, err)
+		assert.Equal(t, terms[ord], term)
+	}
+}
+
+func TestDocValuesFormatEmptyTermsDict(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", 10,
+		false, nil, map[string]string{}, make([]byte, 16), map[string]string{}, nil)
+	info := document.NewFieldInfo("sorted", 0, false, true, false, document.INDEX_OPTIONS_NONE,
+		document.DOC_VALUES_TYPE_SORTED, -1, map[string]string{}, 0, 0, 0, false)
+	fieldInfos := coreIndex.NewFieldInfos([]*document.FieldInfo{info})
+
+	format := NewDocValuesFormat()
+	consumer, err := format.FieldsConsumer(ctx, &index.SegmentWriteState{Directory: dir, SegmentInfo: si, FieldInfos: fieldInfos})
+	assert.Nil(t, err)
+	field := &testField{}
+	assert.Nil(t, consumer.AddSortedField(ctx, info, &coreIndex.EmptyDocValuesProducer{
+		FnGetSorted: func(ctx context.Context, _ *document.FieldInfo) (index.SortedDocValues, error) {
+			return field.sortedSet(), nil
+		},
+	}))
+	assert.Nil(t, consumer.Close())
+
+	producer, err := format.FieldsProducer(ctx, &index.SegmentReadState{Directory: dir, SegmentInfo: si, FieldInfos: fieldInfos})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer producer.Close()
+
+	values, err := producer.GetSorted(ctx, info)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, values.GetValueCount())
+	ord, err := values.LookupTerm([]byte("a"))
+	assert.Nil(t, err)
+	assert.Equal(t, -1, ord)
+	doc, err := values.NextDoc()
+	assert.Nil(t, err)
+	assert.Equal(t, types.NO_MORE_DOCS, doc)
+
+	_, err = producer.GetNumeric(ctx, info)
+	assert.NotNil(t, err)
+}
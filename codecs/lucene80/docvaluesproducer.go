@@ -0,0 +1,926 @@
+package lucene80
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/packed"
+)
+
+var _ index.DocValuesProducer = &DocValuesProducer{}
+
+// DocValuesProducer reader for DocValuesFormat
+type DocValuesProducer struct {
+	numerics       map[string]*numericEntry
+	binaries       map[string]*binaryEntry
+	sorted         map[string]*sortedEntry
+	sortedSets     map[string]*sortedSetEntry
+	sortedNumerics map[string]*sortedNumericEntry
+	data           store.IndexInput
+	maxDoc         int
+	version        int
+}
+
+// NewDocValuesProducer expert: instantiates a new reader
+func NewDocValuesProducer(ctx context.Context, state *index.SegmentReadState,
+	dataCodec, dataExtension, metaCodec, metaExtension string) (*DocValuesProducer, error) {
+
+	maxDoc, err := state.SegmentInfo.MaxDoc()
+	if err != nil {
+		return nil, err
+	}
+
+	producer := &DocValuesProducer{
+		numerics:       make(map[string]*numericEntry),
+		binaries:       make(map[string]*binaryEntry),
+		sorted:         make(map[string]*sortedEntry),
+		sortedSets:     make(map[string]*sortedSetEntry),
+		sortedNumerics: make(map[string]*sortedNumericEntry),
+		maxDoc:         maxDoc,
+	}
+
+	segmentName := state.SegmentInfo.Name()
+	segmentID := state.SegmentInfo.GetID()
+
+	// read in the entries from the metadata file.
+	metaName := store.SegmentFileName(segmentName, state.SegmentSuffix, metaExtension)
+	metaIn, err := store.OpenChecksumInput(state.Directory, metaName)
+	if err != nil {
+		return nil, err
+	}
+	defer metaIn.Close()
+
+	version, err := utils.CheckIndexHeader(ctx, metaIn, metaCodec, VERSION_START, VERSION_CURRENT,
+		segmentID, state.SegmentSuffix)
+	if err != nil {
+		return nil, err
+	}
+	producer.version = version
+	if err := producer.readFields(ctx, metaIn, state.FieldInfos); err != nil {
+		return nil, err
+	}
+	if _, err := utils.CheckCodecFooter(metaIn); err != nil {
+		return nil, err
+	}
+
+	dataName := store.SegmentFileName(segmentName, state.SegmentSuffix, dataExtension)
+	if producer.data, err = store.OpenInputWithContext(ctx, state.Directory, dataName, state.Context); err != nil {
+		return nil, err
+	}
+
+	closeOnError := func(err error) (*DocValuesProducer, error) {
+		_ = producer.Close()
+		return nil, err
+	}
+
+	version2, err := utils.CheckIndexHeader(ctx, producer.data, dataCodec, VERSION_START, VERSION_CURRENT,
+		segmentID, state.SegmentSuffix)
+	if err != nil {
+		return closeOnError(err)
+	}
+	if version != version2 {
+		return closeOnError(fmt.Errorf("format versions mismatch: meta=%d, data=%d", version, version2))
+	}
+
+	// NOTE: data file is too costly to verify checksum against all the bytes on open,
+	// but for now we at least verify proper structure of the checksum footer: which looks
+	// for FOOTER_MAGIC + algorithmID. This is cheap and can detect some forms of corruption
+	// such as file truncation.
+	if _, err := utils.RetrieveChecksum(producer.data); err != nil {
+		return closeOnError(err)
+	}
+	return producer, nil
+}
+
+func (r *DocValuesProducer) readFields(ctx context.Context, meta store.DataInput, infos index.FieldInfos) error {
+	for {
+		fieldNumber, err := meta.ReadUint32(ctx)
+		if err != nil {
+			return err
+		}
+		if int32(fieldNumber) == -1 {
+			return nil
+		}
+
+		info := infos.FieldInfoByNumber(int(fieldNumber))
+		if info == nil {
+			return fmt.Errorf("invalid field number: %d", fieldNumber)
+		}
+		docValuesType, err := meta.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		switch docValuesType {
+		case NUMERIC:
+			entry := &numericEntry{}
+			if err := r.readNumeric(ctx, meta, entry); err != nil {
+				return err
+			}
+			r.numerics[info.Name()] = entry
+		case BINARY:
+			compressed := r.version >= VERSION_BIN_COMPRESSED
+			if r.version >= VERSION_CONFIGURABLE_COMPRESSION {
+				value := info.GetAttribute(MODE_KEY)
+				if value == "" {
+					return fmt.Errorf("missing value for %s for field: %s", MODE_KEY, info.Name())
+				}
+				mode, err := ParseMode(value)
+				if err != nil {
+					return err
+				}
+				compressed = mode == BEST_COMPRESSION
+			}
+			entry, err := r.readBinary(ctx, meta, compressed)
+			if err != nil {
+				return err
+			}
+			r.binaries[info.Name()] = entry
+		case SORTED:
+			entry, err := r.readSorted(ctx, meta)
+			if err != nil {
+				return err
+			}
+			r.sorted[info.Name()] = entry
+		case SORTED_SET:
+			entry, err := r.readSortedSet(ctx, meta)
+			if err != nil {
+				return err
+			}
+			r.sortedSets[info.Name()] = entry
+		case SORTED_NUMERIC:
+			entry, err := r.readSortedNumeric(ctx, meta)
+			if err != nil {
+				return err
+			}
+			r.sortedNumerics[info.Name()] = entry
+		default:
+			return fmt.Errorf("invalid docvalues type: %d", docValuesType)
+		}
+	}
+}
+
+type docsWithFieldEntry struct {
+	docsWithFieldOffset int64
+	docsWithFieldLength int64
+	jumpTableEntryCount int
+	denseRankPower      int
+}
+
+func (e *docsWithFieldEntry) read(ctx context.Context, meta store.DataInput) error {
+	offset, err := meta.ReadUint64(ctx)
+	if err != nil {
+		return err
+	}
+	length, err := meta.ReadUint64(ctx)
+	if err != nil {
+		return err
+	}
+	jumpTableEntryCount, err := meta.ReadUint16(ctx)
+	if err != nil {
+		return err
+	}
+	denseRankPower, err := meta.ReadByte()
+	if err != nil {
+		return err
+	}
+	e.docsWithFieldOffset = int64(offset)
+	e.docsWithFieldLength = int64(length)
+	e.jumpTableEntryCount = int(int16(jumpTableEntryCount))
+	e.denseRankPower = int(int8(denseRankPower))
+	return nil
+}
+
+// iterator returns the documents that have a value, the sparse case is read from data.
+func (e *docsWithFieldEntry) iterator(data store.IndexInput, maxDoc int, cost int64) (docsWithField, error) {
+	switch e.docsWithFieldOffset {
+	case -2:
+		// empty
+		return &emptyDocs{}, nil
+	case -1:
+		// dense
+		return newDenseDocs(maxDoc), nil
+	default:
+		// sparse
+		return NewIndexedDISI(data, e.docsWithFieldOffset, e.docsWithFieldLength,
+			e.jumpTableEntryCount, e.denseRankPower, cost)
+	}
+}
+
+type numericEntry struct {
+	docsWithFieldEntry
+
+	table                []int64
+	blockShift           int
+	bitsPerValue         int
+	numValues            int64
+	minValue             int64
+	gcd                  int64
+	valuesOffset         int64
+	valuesLength         int64
+	valueJumpTableOffset int64 // -1 if no jump-table
+}
+
+type binaryEntry struct {
+	docsWithFieldEntry
+
+	dataOffset       int64
+	dataLength       int64
+	numDocsWithField int
+	minLength        int
+	maxLength        int
+	addressesOffset  int64
+	addressesLength  int64
+	addressesMeta    *packed.DirectMonotonicMeta
+
+	// the values are compressed in blocks of 1<<docsPerChunkShift documents
+	compressed               bool
+	numCompressedChunks      int
+	docsPerChunkShift        int
+	maxUncompressedChunkSize int
+}
+
+type termsDictEntry struct {
+	termsDictSize             int64
+	termsDictBlockShift       int
+	termsAddressesMeta        *packed.DirectMonotonicMeta
+	maxTermLength             int
+	termsDataOffset           int64
+	termsDataLength           int64
+	termsAddressesOffset      int64
+	termsAddressesLength      int64
+	termsDictIndexShift       int
+	termsIndexAddressesMeta   *packed.DirectMonotonicMeta
+	termsIndexOffset          int64
+	termsIndexLength          int64
+	termsIndexAddressesOffset int64
+	termsIndexAddressesLength int64
+
+	// all terms of a block but the first one are compressed with LZ4
+	compressed     bool
+	maxBlockLength int
+}
+
+type sortedEntry struct {
+	docsWithFieldEntry
+	termsDictEntry
+
+	numDocsWithField int
+	bitsPerValue     int
+	ordsOffset       int64
+	ordsLength       int64
+}
+
+type sortedSetEntry struct {
+	docsWithFieldEntry
+	termsDictEntry
+
+	singleValueEntry *sortedEntry
+	numDocsWithField int
+	bitsPerValue     int
+	ordsOffset       int64
+	ordsLength       int64
+	addressesMeta    *packed.DirectMonotonicMeta
+	addressesOffset  int64
+	addressesLength  int64
+}
+
+type sortedNumericEntry struct {
+	numericEntry
+
+	numDocsWithField int
+	addressesMeta    *packed.DirectMonotonicMeta
+	addressesOffset  int64
+	addressesLength  int64
+}
+
+// readInt64s reads consecutive longs from meta into dst
+func readInt64s(ctx context.Context, meta store.DataInput, dst ...*int64) error {
+	for _, v := range dst {
+		n, err := meta.ReadUint64(ctx)
+		if err != nil {
+			return err
+		}
+		*v = int64(n)
+	}
+	return nil
+}
+
+// readInt32 reads a signed int from meta
+func readInt32(ctx context.Context, meta store.DataInput) (int, error) {
+	n, err := meta.ReadUint32(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int(int32(n)), nil
+}
+
+func (r *DocValuesProducer) readNumeric(ctx context.Context, meta store.DataInput, entry *numericEntry) error {
+	if err := entry.docsWithFieldEntry.read(ctx, meta); err != nil {
+		return err
+	}
+	if err := readInt64s(ctx, meta, &entry.numValues); err != nil {
+		return err
+	}
+
+	tableSize, err := readInt32(ctx, meta)
+	if err != nil {
+		return err
+	}
+	if tableSize > 256 {
+		return fmt.Errorf("invalid table size: %d", tableSize)
+	}
+	entry.blockShift = -1
+	if tableSize >= 0 {
+		entry.table = make([]int64, tableSize)
+		for i := range entry.table {
+			if err := readInt64s(ctx, meta, &entry.table[i]); err != nil {
+				return err
+			}
+		}
+	} else if tableSize < -1 {
+		entry.blockShift = -2 - tableSize
+	}
+
+	bitsPerValue, err := meta.ReadByte()
+	if err != nil {
+		return err
+	}
+	entry.bitsPerValue = int(bitsPerValue)
+	return readInt64s(ctx, meta, &entry.minValue, &entry.gcd, &entry.valuesOffset,
+		&entry.valuesLength, &entry.valueJumpTableOffset)
+}
+
+func (r *DocValuesProducer) readBinary(ctx context.Context, meta store.DataInput, compressed bool) (*binaryEntry, error) {
+	entry := &binaryEntry{compressed: compressed}
+	if err := readInt64s(ctx, meta, &entry.dataOffset, &entry.dataLength); err != nil {
+		return nil, err
+	}
+	if err := entry.docsWithFieldEntry.read(ctx, meta); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if entry.numDocsWithField, err = readInt32(ctx, meta); err != nil {
+		return nil, err
+	}
+	if entry.minLength, err = readInt32(ctx, meta); err != nil {
+		return nil, err
+	}
+	if entry.maxLength, err = readInt32(ctx, meta); err != nil {
+		return nil, err
+	}
+	if !entry.compressed {
+		if entry.minLength < entry.maxLength {
+			if entry.addressesMeta, entry.addressesOffset, entry.addressesLength, err =
+				readAddresses(ctx, meta, entry.numDocsWithField); err != nil {
+				return nil, err
+			}
+		}
+		return entry, nil
+	}
+
+	if entry.numDocsWithField == 0 {
+		return entry, nil
+	}
+	// the addresses are the start pointers of the compressed blocks
+	if err := readInt64s(ctx, meta, &entry.addressesOffset); err != nil {
+		return nil, err
+	}
+	var blockShift int
+	for _, dst := range []*int{&entry.numCompressedChunks, &entry.docsPerChunkShift, &entry.maxUncompressedChunkSize, &blockShift} {
+		v, err := meta.ReadUvarint(ctx)
+		if err != nil {
+			return nil, err
+		}
+		*dst = int(v)
+	}
+	if entry.addressesMeta, err = packed.LoadDirectMonotonicMeta(ctx, meta, int64(entry.numCompressedChunks), blockShift); err != nil {
+		return nil, err
+	}
+	if err := readInt64s(ctx, meta, &entry.addressesLength); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// readAddresses reads the metadata written by DocValuesConsumer.writeAddresses
+func readAddresses(ctx context.Context, meta store.DataInput, numDocsWithField int) (*packed.DirectMonotonicMeta, int64, int64, error) {
+	var offset, length int64
+	if err := readInt64s(ctx, meta, &offset); err != nil {
+		return nil, 0, 0, err
+	}
+	blockShift, err := meta.ReadUvarint(ctx)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	addressesMeta, err := packed.LoadDirectMonotonicMeta(ctx, meta, int64(numDocsWithField)+1, int(blockShift))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if err := readInt64s(ctx, meta, &length); err != nil {
+		return nil, 0, 0, err
+	}
+	return addressesMeta, offset, length, nil
+}
+
+func (r *DocValuesProducer) readSorted(ctx context.Context, meta store.DataInput) (*sortedEntry, error) {
+	entry := &sortedEntry{}
+	if err := entry.docsWithFieldEntry.read(ctx, meta); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if entry.numDocsWithField, err = readInt32(ctx, meta); err != nil {
+		return nil, err
+	}
+	bitsPerValue, err := meta.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	entry.bitsPerValue = int(bitsPerValue)
+	if err := readInt64s(ctx, meta, &entry.ordsOffset, &entry.ordsLength); err != nil {
+		return nil, err
+	}
+	if err := entry.termsDictEntry.read(ctx, meta); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (r *DocValuesProducer) readSortedSet(ctx context.Context, meta store.DataInput) (*sortedSetEntry, error) {
+	entry := &sortedSetEntry{}
+	multiValued, err := meta.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch multiValued {
+	case 0: // singlevalued
+		if entry.singleValueEntry, err = r.readSorted(ctx, meta); err != nil {
+			return nil, err
+		}
+		return entry, nil
+	case 1: // multivalued
+	default:
+		return nil, fmt.Errorf("invalid multiValued flag: %d", multiValued)
+	}
+
+	if err := entry.docsWithFieldEntry.read(ctx, meta); err != nil {
+		return nil, err
+	}
+	bitsPerValue, err := meta.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	entry.bitsPerValue = int(bitsPerValue)
+	if err := readInt64s(ctx, meta, &entry.ordsOffset, &entry.ordsLength); err != nil {
+		return nil, err
+	}
+	if entry.numDocsWithField, err = readInt32(ctx, meta); err != nil {
+		return nil, err
+	}
+	if entry.addressesMeta, entry.addressesOffset, entry.addressesLength, err =
+		readAddresses(ctx, meta, entry.numDocsWithField); err != nil {
+		return nil, err
+	}
+	if err := entry.termsDictEntry.read(ctx, meta); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (e *termsDictEntry) read(ctx context.Context, meta store.DataInput) error {
+	termsDictSize, err := meta.ReadUvarint(ctx)
+	if err != nil {
+		return err
+	}
+	e.termsDictSize = int64(termsDictSize)
+	termsDictBlockCode, err := readInt32(ctx, meta)
+	if err != nil {
+		return err
+	}
+	e.termsDictBlockShift = termsDictBlockCode
+	if termsDictBlockCode == TERMS_DICT_BLOCK_LZ4_CODE {
+		e.compressed = true
+		e.termsDictBlockShift = TERMS_DICT_BLOCK_LZ4_SHIFT
+	}
+	blockShift, err := readInt32(ctx, meta)
+	if err != nil {
+		return err
+	}
+	addressesSize := (e.termsDictSize + (1 << e.termsDictBlockShift) - 1) >> e.termsDictBlockShift
+	if e.termsAddressesMeta, err = packed.LoadDirectMonotonicMeta(ctx, meta, addressesSize, blockShift); err != nil {
+		return err
+	}
+	if e.maxTermLength, err = readInt32(ctx, meta); err != nil {
+		return err
+	}
+	if e.compressed {
+		if e.maxBlockLength, err = readInt32(ctx, meta); err != nil {
+			return err
+		}
+	}
+	if err := readInt64s(ctx, meta, &e.termsDataOffset, &e.termsDataLength,
+		&e.termsAddressesOffset, &e.termsAddressesLength); err != nil {
+		return err
+	}
+
+	if e.termsDictIndexShift, err = readInt32(ctx, meta); err != nil {
+		return err
+	}
+	indexSize := (e.termsDictSize + (1 << e.termsDictIndexShift) - 1) >> e.termsDictIndexShift
+	if e.termsIndexAddressesMeta, err = packed.LoadDirectMonotonicMeta(ctx, meta, 1+indexSize, blockShift); err != nil {
+		return err
+	}
+	return readInt64s(ctx, meta, &e.termsIndexOffset, &e.termsIndexLength,
+		&e.termsIndexAddressesOffset, &e.termsIndexAddressesLength)
+}
+
+func (r *DocValuesProducer) readSortedNumeric(ctx context.Context, meta store.DataInput) (*sortedNumericEntry, error) {
+	entry := &sortedNumericEntry{}
+	if err := r.readNumeric(ctx, meta, &entry.numericEntry); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if entry.numDocsWithField, err = readInt32(ctx, meta); err != nil {
+		return nil, err
+	}
+	if entry.numValues != int64(entry.numDocsWithField) {
+		if entry.addressesMeta, entry.addressesOffset, entry.addressesLength, err =
+			readAddresses(ctx, meta, entry.numDocsWithField); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+func (r *DocValuesProducer) Close() error {
+	if r.data == nil {
+		return nil
+	}
+	err := r.data.Close()
+	r.data = nil
+	return err
+}
+
+func (r *DocValuesProducer) CheckIntegrity() error {
+	_, err := utils.ChecksumEntireFile(r.data)
+	return err
+}
+
+func (r *DocValuesProducer) GetMergeInstance() index.DocValuesProducer {
+	return r
+}
+
+func (r *DocValuesProducer) GetNumeric(ctx context.Context, field *document.FieldInfo) (index.NumericDocValues, error) {
+	entry, ok := r.numerics[field.Name()]
+	if !ok {
+		return nil, fmt.Errorf("field %s has no numeric doc values", field.Name())
+	}
+	return r.getNumeric(entry)
+}
+
+// getDocsWithField returns an iterator over the documents that have a value together with the index
+// of the current document among them.
+func (r *DocValuesProducer) getDocsWithField(entry *docsWithFieldEntry, cost int64) (docsWithField, error) {
+	return entry.iterator(r.data, r.maxDoc, cost)
+}
+
+func (r *DocValuesProducer) getNumeric(entry *numericEntry) (*numericDocValues, error) {
+	docs, err := r.getDocsWithField(&entry.docsWithFieldEntry, entry.numValues)
+	if err != nil {
+		return nil, err
+	}
+	values, err := r.getNumericValues(entry)
+	if err != nil {
+		return nil, err
+	}
+	return &numericDocValues{docsWithField: docs, values: values}, nil
+}
+
+// getNumericValues returns the values of a numeric entry by their index
+func (r *DocValuesProducer) getNumericValues(entry *numericEntry) (longValues, error) {
+	if entry.bitsPerValue == 0 {
+		return func(int64) (int64, error) {
+			return entry.minValue, nil
+		}, nil
+	}
+
+	slice, err := r.data.RandomAccessSlice(entry.valuesOffset, entry.valuesLength)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.blockShift >= 0 {
+		// dense but split into blocks of different bits per value
+		reader := &varyingBPVReader{
+			entry: entry,
+			slice: slice,
+			block: -1,
+		}
+		if entry.valueJumpTableOffset > 0 {
+			if reader.rankSlice, err = r.data.RandomAccessSlice(entry.valueJumpTableOffset,
+				r.data.Length()-entry.valueJumpTableOffset); err != nil {
+				return nil, err
+			}
+		}
+		return reader.get, nil
+	}
+
+	reader, err := packed.NewDirectReader().GetInstance(slice, entry.bitsPerValue, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.table != nil {
+		table := entry.table
+		return func(index int64) (int64, error) {
+			v, err := reader.Get(index)
+			if err != nil {
+				return 0, err
+			}
+			return table[v], nil
+		}, nil
+	}
+
+	mul, delta := entry.gcd, entry.minValue
+	return func(index int64) (int64, error) {
+		v, err := reader.Get(index)
+		if err != nil {
+			return 0, err
+		}
+		return mul*int64(v) + delta, nil
+	}, nil
+}
+
+// varyingBPVReader reads values that have been written in blocks of NUMERIC_BLOCK_SIZE values
+// with different bits per value.
+type varyingBPVReader struct {
+	entry     *numericEntry
+	slice     store.RandomAccessInput // 2 slices to avoid cache thrashing when using rank
+	rankSlice store.RandomAccessInput
+
+	block          int64
+	delta          int64
+	offset         int64
+	blockEndOffset int64
+	values         packed.LongValuesReader
+}
+
+func (v *varyingBPVReader) get(index int64) (int64, error) {
+	block := index >> v.entry.blockShift
+	if v.block != block {
+		bitsPerValue := byte(0)
+		for {
+			// If the needed block is the one directly following the current block, it is cheaper to avoid the cache
+			if v.rankSlice != nil && block != v.block+1 {
+				blockEndOffset, err := v.rankSlice.ReadU64(block * 8)
+				if err != nil {
+					return 0, err
+				}
+				v.blockEndOffset = int64(blockEndOffset) - v.entry.valuesOffset
+				v.block = block - 1
+			}
+			v.offset = v.blockEndOffset
+
+			var err error
+			if bitsPerValue, err = v.slice.ReadU8(v.offset); err != nil {
+				return 0, err
+			}
+			v.offset++
+			delta, err := v.slice.ReadU64(v.offset)
+			if err != nil {
+				return 0, err
+			}
+			v.delta = int64(delta)
+			v.offset += 8
+			if bitsPerValue == 0 {
+				v.blockEndOffset = v.offset
+			} else {
+				length, err := v.slice.ReadU32(v.offset)
+				if err != nil {
+					return 0, err
+				}
+				v.offset += 4
+				v.blockEndOffset = v.offset + int64(length)
+			}
+			v.block++
+			if v.block == block {
+				break
+			}
+		}
+
+		if bitsPerValue == 0 {
+			v.values = nil
+		} else {
+			var err error
+			if v.values, err = packed.NewDirectReader().GetInstance(v.slice, int(bitsPerValue), v.offset); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if v.values == nil {
+		return v.delta, nil
+	}
+	n, err := v.values.Get(index & (1<<v.entry.blockShift - 1))
+	if err != nil {
+		return 0, err
+	}
+	return v.entry.gcd*int64(n) + v.delta, nil
+}
+
+func (r *DocValuesProducer) GetBinary(ctx context.Context, field *document.FieldInfo) (index.BinaryDocValues, error) {
+	entry, ok := r.binaries[field.Name()]
+	if !ok {
+		return nil, fmt.Errorf("field %s has no binary doc values", field.Name())
+	}
+
+	docs, err := r.getDocsWithField(&entry.docsWithFieldEntry, int64(entry.numDocsWithField))
+	if err != nil {
+		return nil, err
+	}
+	if entry.compressed {
+		return r.getCompressedBinary(entry, docs)
+	}
+	bytesSlice, err := r.data.Slice("binary", entry.dataOffset, entry.dataLength)
+	if err != nil {
+		return nil, err
+	}
+	values := &binaryDocValues{
+		docsWithField: docs,
+		bytesSlice:    bytesSlice,
+		buffer:        make([]byte, entry.maxLength),
+	}
+
+	if entry.addressesMeta == nil {
+		// fixed length
+		length := int64(entry.maxLength)
+		values.address = func(index int64) (int64, int64, error) {
+			return index * length, length, nil
+		}
+		return values, nil
+	}
+
+	// variable length
+	addressesData, err := r.data.RandomAccessSlice(entry.addressesOffset, entry.addressesLength)
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := packed.NewDirectMonotonicReader(entry.addressesMeta, addressesData)
+	if err != nil {
+		return nil, err
+	}
+	values.address = func(index int64) (int64, int64, error) {
+		start, err := addresses.Get(index)
+		if err != nil {
+			return 0, 0, err
+		}
+		end, err := addresses.Get(index + 1)
+		if err != nil {
+			return 0, 0, err
+		}
+		return int64(start), int64(end - start), nil
+	}
+	return values, nil
+}
+
+func (r *DocValuesProducer) getCompressedBinary(entry *binaryEntry, docs docsWithField) (index.BinaryDocValues, error) {
+	if entry.numDocsWithField == 0 {
+		return &compressedBinaryDocValues{docsWithField: docs}, nil
+	}
+	addressesData, err := r.data.RandomAccessSlice(entry.addressesOffset, entry.addressesLength)
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := packed.NewDirectMonotonicReader(entry.addressesMeta, addressesData)
+	if err != nil {
+		return nil, err
+	}
+	return &compressedBinaryDocValues{
+		docsWithField: docs,
+		decoder: newBinaryDecoder(addresses, r.data.Clone().(store.IndexInput),
+			entry.maxUncompressedChunkSize, entry.docsPerChunkShift),
+	}, nil
+}
+
+func (r *DocValuesProducer) GetSorted(ctx context.Context, field *document.FieldInfo) (index.SortedDocValues, error) {
+	entry, ok := r.sorted[field.Name()]
+	if !ok {
+		return nil, fmt.Errorf("field %s has no sorted doc values", field.Name())
+	}
+	return r.getSorted(entry)
+}
+
+func (r *DocValuesProducer) getSorted(entry *sortedEntry) (*sortedDocValues, error) {
+	docs, err := r.getDocsWithField(&entry.docsWithFieldEntry, int64(entry.numDocsWithField))
+	if err != nil {
+		return nil, err
+	}
+
+	ords := func(int64) (int64, error) { return 0, nil }
+	if entry.bitsPerValue > 0 {
+		slice, err := r.data.RandomAccessSlice(entry.ordsOffset, entry.ordsLength)
+		if err != nil {
+			return nil, err
+		}
+		reader, err := packed.NewDirectReader().GetInstance(slice, entry.bitsPerValue, 0)
+		if err != nil {
+			return nil, err
+		}
+		ords = func(index int64) (int64, error) {
+			ord, err := reader.Get(index)
+			return int64(ord), err
+		}
+	}
+
+	dict, err := r.newTermsDict(&entry.termsDictEntry)
+	if err != nil {
+		return nil, err
+	}
+	return &sortedDocValues{docsWithField: docs, ords: ords, termsDict: dict}, nil
+}
+
+func (r *DocValuesProducer) GetSortedNumeric(ctx context.Context, field *document.FieldInfo) (index.SortedNumericDocValues, error) {
+	entry, ok := r.sortedNumerics[field.Name()]
+	if !ok {
+		return nil, fmt.Errorf("field %s has no sorted numeric doc values", field.Name())
+	}
+
+	if entry.numValues == int64(entry.numDocsWithField) {
+		values, err := r.getNumeric(&entry.numericEntry)
+		if err != nil {
+			return nil, err
+		}
+		return newSingletonSortedNumeric(values), nil
+	}
+
+	docs, err := r.getDocsWithField(&entry.docsWithFieldEntry, int64(entry.numDocsWithField))
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := r.getAddresses(entry.addressesMeta, entry.addressesOffset, entry.addressesLength)
+	if err != nil {
+		return nil, err
+	}
+	values, err := r.getNumericValues(&entry.numericEntry)
+	if err != nil {
+		return nil, err
+	}
+	return &sortedNumericDocValues{docsWithField: docs, addresses: addresses, values: values}, nil
+}
+
+func (r *DocValuesProducer) getAddresses(meta *packed.DirectMonotonicMeta, offset, length int64) (*packed.DirectMonotonicReader, error) {
+	slice, err := r.data.RandomAccessSlice(offset, length)
+	if err != nil {
+		return nil, err
+	}
+	return packed.NewDirectMonotonicReader(meta, slice)
+}
+
+func (r *DocValuesProducer) GetSortedSet(ctx context.Context, field *document.FieldInfo) (index.SortedSetDocValues, error) {
+	entry, ok := r.sortedSets[field.Name()]
+	if !ok {
+		return nil, fmt.Errorf("field %s has no sorted set doc values", field.Name())
+	}
+
+	if entry.singleValueEntry != nil {
+		values, err := r.getSorted(entry.singleValueEntry)
+		if err != nil {
+			return nil, err
+		}
+		return newSingletonSortedSet(values), nil
+	}
+
+	docs, err := r.getDocsWithField(&entry.docsWithFieldEntry, int64(entry.numDocsWithField))
+	if err != nil {
+		return nil, err
+	}
+	slice, err := r.data.RandomAccessSlice(entry.ordsOffset, entry.ordsLength)
+	if err != nil {
+		return nil, err
+	}
+	ords, err := packed.NewDirectReader().GetInstance(slice, entry.bitsPerValue, 0)
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := r.getAddresses(entry.addressesMeta, entry.addressesOffset, entry.addressesLength)
+	if err != nil {
+		return nil, err
+	}
+	dict, err := r.newTermsDict(&entry.termsDictEntry)
+	if err != nil {
+		return nil, err
+	}
+	return &sortedSetDocValues{
+		docsWithField: docs,
+		ords:          ords,
+		addresses:     addresses,
+		termsDict:     dict,
+	}, nil
+}
@@ -0,0 +1,632 @@
+package lucene80
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+)
+
+const (
+	// BLOCK_SIZE The number of docs in a block
+	BLOCK_SIZE = 65536
+
+	// MAX_ARRAY_LENGTH The number of docs at and below which a block is encoded as SPARSE
+	MAX_ARRAY_LENGTH = (1 << 12) - 1
+
+	// DENSE_BLOCK_LONGS The number of longs in a DENSE bitmap
+	DENSE_BLOCK_LONGS = BLOCK_SIZE / 64
+
+	// DEFAULT_DENSE_RANK_POWER Every 512 docs (8 longs) a rank entry is stored in DENSE blocks
+	DEFAULT_DENSE_RANK_POWER = 9
+)
+
+// disiMethod the encoding of a single block of an IndexedDISI
+type disiMethod int
+
+const (
+	disiSparse = disiMethod(iota)
+	disiDense
+	disiAll
+)
+
+var _ types.DocIdSetIterator = &IndexedDISI{}
+
+// IndexedDISI Disk-based implementation of a DocIdSetIterator which can return the index of the current
+// document, i.e. the ordinal of the current document among the list of documents that this iterator
+// can return. This is useful to implement sparse doc values by only having to encode values for
+// documents that actually have a value.
+//
+// Implementation-wise, this DocIdSetIterator is inspired of roaring bitmaps and encodes ranges of
+// 65536 documents independently and picks between 3 encodings depending on the density of the range:
+//   - ALL if the range contains 65536 documents exactly,
+//   - DENSE if the range contains 4096 documents or more; in that case documents are stored in a bit set,
+//   - SPARSE otherwise, and the lower 16 bits of the doc IDs are stored in a short.
+//
+// Only ranges that contain at least one value are encoded.
+//
+// This implementation uses 6 bytes per document in the worst-case, which happens in the case that all
+// ranges contain exactly one document.
+//
+// To avoid O(n) lookup time complexity, with n being the number of documents, two lookup tables are used:
+// A lookup table for block offset and index, and a rank structure for DENSE block index lookups.
+//
+// The lookup table is an array of int-pairs, with a pair for each block. It allows for direct jumping to
+// the block, as opposed to iteration from the current position and forward one block at a time. Each
+// int-pair entry consists of 2 logical parts: The first 32 bit int holds the index (number of set bits
+// in the blocks) up to just before the wanted block. The second 32 bit int holds the offset in bytes
+// into the underlying slice. As there is a maximum of 2^16 blocks, the jump table is at most 512KB.
+//
+// The rank structure for DENSE blocks is an array of byte-pairs with an entry for each sub-block
+// (default 512 bits) out of the 65536 bits in the outer DENSE block. Each rank-entry states the number
+// of set bits within the block up to the bit before the bit positioned at the start of the sub-block.
+// Note that that the rank entry of the first sub-block is always 0 and that the last entry can at most
+// be 65536-2 = 65634 and thus will always fit into an byte-pair of 16 bits.
+//
+// The rank structure for a given DENSE block is stored at the beginning of the DENSE block. This ensures
+// locality and keeps logistics simple.
+// lucene.internal
+type IndexedDISI struct {
+	// slice is the block data, jumpTable the optional lookup table at its end
+	slice               store.IndexInput
+	jumpTableEntryCount int
+	jumpTable           store.RandomAccessInput
+	denseRankPower      int
+	denseRankTable      []byte
+	cost                int64
+
+	// block is the upper 16 bits of the doc IDs of the current block, shifted left by 16
+	block               int
+	blockEnd            int64
+	denseBitmapOffset   int64
+	nextBlockIndex      int
+	method              disiMethod
+	doc                 int
+	index               int
+	exists              bool
+	word                uint64
+	wordIndex           int
+	numberOfOnes        int
+	denseOrigoIndex     int
+	gap                 int
+	nextExistDocInBlock int
+
+	buf [8]byte
+}
+
+// NewIndexedDISI
+// This constructor always creates a new blockSlice and a new jumpTable from in, from the offset and
+// length of the data written by WriteBitSet.
+// jumpTableEntryCount and denseRankPower are the values returned by and passed to WriteBitSet,
+// cost is normally the number of logical docIDs.
+func NewIndexedDISI(in store.IndexInput, offset, length int64, jumpTableEntryCount, denseRankPower int,
+	cost int64) (*IndexedDISI, error) {
+
+	if denseRankPower != -1 && (denseRankPower < 7 || denseRankPower > 15) {
+		return nil, fmt.Errorf("acceptable values for denseRankPower are 7-15 (every 128-32768 docIDs), got %d", denseRankPower)
+	}
+
+	jumpTableBytes := int64(0)
+	if jumpTableEntryCount > 0 {
+		jumpTableBytes = int64(jumpTableEntryCount) * 4 * 2
+	}
+	slice, err := in.Slice("docs", offset, length-jumpTableBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	disi := &IndexedDISI{
+		slice:               slice,
+		jumpTableEntryCount: jumpTableEntryCount,
+		denseRankPower:      denseRankPower,
+		cost:                cost,
+		block:               -1,
+		denseBitmapOffset:   -1,
+		nextBlockIndex:      -1,
+		doc:                 -1,
+		index:               -1,
+		wordIndex:           -1,
+		nextExistDocInBlock: -1,
+	}
+	if jumpTableBytes > 0 {
+		if disi.jumpTable, err = in.RandomAccessSlice(offset+length-jumpTableBytes, jumpTableBytes); err != nil {
+			return nil, err
+		}
+	}
+	if denseRankPower != -1 {
+		disi.denseRankTable = make([]byte, DENSE_BLOCK_LONGS>>(denseRankPower-7))
+	}
+	return disi, nil
+}
+
+func (d *IndexedDISI) DocID() int {
+	return d.doc
+}
+
+func (d *IndexedDISI) NextDoc() (int, error) {
+	return d.Advance(d.doc + 1)
+}
+
+func (d *IndexedDISI) Advance(target int) (int, error) {
+	targetBlock := target & 0xFFFF0000
+	if d.block < targetBlock {
+		if err := d.advanceBlock(targetBlock); err != nil {
+			return 0, err
+		}
+	}
+	if d.block == targetBlock {
+		found, err := d.advanceWithinBlock(target)
+		if err != nil {
+			return 0, err
+		}
+		if found {
+			return d.doc, nil
+		}
+		if err := d.readBlockHeader(); err != nil {
+			return 0, err
+		}
+	}
+	found, err := d.advanceWithinBlock(d.block)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, errors.New("IndexedDISI: block without docs")
+	}
+	return d.doc, nil
+}
+
+// AdvanceExact
+// Advance to exactly target and return whether target is in the set.
+func (d *IndexedDISI) AdvanceExact(target int) (bool, error) {
+	targetBlock := target & 0xFFFF0000
+	if d.block < targetBlock {
+		if err := d.advanceBlock(targetBlock); err != nil {
+			return false, err
+		}
+	}
+	found := false
+	if d.block == targetBlock {
+		var err error
+		if found, err = d.advanceExactWithinBlock(target); err != nil {
+			return false, err
+		}
+	}
+	d.doc = target
+	return found, nil
+}
+
+func (d *IndexedDISI) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(d, target)
+}
+
+func (d *IndexedDISI) Cost() int64 {
+	return d.cost
+}
+
+// Index
+// Returns the ordinal of the current document among the documents of this iterator
+func (d *IndexedDISI) Index() int {
+	return d.index
+}
+
+func (d *IndexedDISI) advanceBlock(targetBlock int) error {
+	blockIndex := targetBlock >> 16
+	// If the destination block is 2 blocks or more ahead, we use the jump-table.
+	if d.jumpTable != nil && blockIndex >= (d.block>>16)+2 {
+		// If the jumpTableEntryCount is exceeded, there are no further bits. Last entry is always NO_MORE_DOCS
+		inRangeBlockIndex := min(blockIndex, d.jumpTableEntryCount-1)
+		index, err := d.jumpTable.ReadU32(int64(inRangeBlockIndex) * 4 * 2)
+		if err != nil {
+			return err
+		}
+		offset, err := d.jumpTable.ReadU32(int64(inRangeBlockIndex)*4*2 + 4)
+		if err != nil {
+			return err
+		}
+		d.nextBlockIndex = int(index) - 1 // -1 to compensate for the always-added 1 in readBlockHeader
+		if _, err := d.slice.Seek(int64(offset), io.SeekStart); err != nil {
+			return err
+		}
+		return d.readBlockHeader()
+	}
+
+	// Fallback to iteration of blocks
+	for {
+		if _, err := d.slice.Seek(d.blockEnd, io.SeekStart); err != nil {
+			return err
+		}
+		if err := d.readBlockHeader(); err != nil {
+			return err
+		}
+		if d.block >= targetBlock {
+			return nil
+		}
+	}
+}
+
+func (d *IndexedDISI) readBlockHeader() error {
+	block, err := d.slice.ReadUint16(nil)
+	if err != nil {
+		return err
+	}
+	cardinality, err := d.slice.ReadUint16(nil)
+	if err != nil {
+		return err
+	}
+	d.block = int(block) << 16
+	numValues := 1 + int(cardinality)
+	d.index = d.nextBlockIndex
+	d.nextBlockIndex = d.index + numValues
+
+	switch {
+	case numValues <= MAX_ARRAY_LENGTH:
+		d.method = disiSparse
+		d.blockEnd = d.slice.GetFilePointer() + int64(numValues<<1)
+		d.nextExistDocInBlock = -1
+	case numValues == BLOCK_SIZE:
+		d.method = disiAll
+		d.blockEnd = d.slice.GetFilePointer()
+		d.gap = d.block - d.index - 1
+	default:
+		d.method = disiDense
+		d.denseBitmapOffset = d.slice.GetFilePointer() + int64(len(d.denseRankTable))
+		d.blockEnd = d.denseBitmapOffset + (1 << 13)
+		// All rank entries are loaded up front, they are needed as soon as an advance within the
+		// block is longer than a rank interval.
+		if d.denseRankPower != -1 {
+			if _, err := io.ReadFull(d.slice, d.denseRankTable); err != nil {
+				return err
+			}
+		}
+		d.wordIndex = -1
+		d.numberOfOnes = d.index + 1
+		d.denseOrigoIndex = d.numberOfOnes
+	}
+	return nil
+}
+
+func (d *IndexedDISI) advanceWithinBlock(target int) (bool, error) {
+	switch d.method {
+	case disiSparse:
+		targetInBlock := target & 0xFFFF
+		// TODO: binary search
+		for d.index < d.nextBlockIndex {
+			doc, err := d.slice.ReadUint16(nil)
+			if err != nil {
+				return false, err
+			}
+			d.index++
+			if int(doc) >= targetInBlock {
+				d.doc = d.block | int(doc)
+				d.exists = true
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case disiDense:
+		targetInBlock := target & 0xFFFF
+		targetWordIndex := targetInBlock >> 6
+
+		// If possible, skip ahead using the rank cache. If the distance between the current position
+		// and the target is < rank-longs there is no sense in using rank
+		if d.denseRankPower != -1 && targetWordIndex-d.wordIndex >= (1<<(d.denseRankPower-6)) {
+			if err := d.rankSkip(targetInBlock); err != nil {
+				return false, err
+			}
+		}
+
+		for i := d.wordIndex + 1; i <= targetWordIndex; i++ {
+			word, err := d.readWord()
+			if err != nil {
+				return false, err
+			}
+			d.word = word
+			d.numberOfOnes += bits.OnesCount64(word)
+		}
+		d.wordIndex = targetWordIndex
+
+		leftBits := d.word >> (target & 63)
+		if leftBits != 0 {
+			d.doc = target + bits.TrailingZeros64(leftBits)
+			d.index = d.numberOfOnes - bits.OnesCount64(leftBits)
+			return true, nil
+		}
+
+		// There were no set bits at the wanted position. Move forward until one is reached
+		for d.wordIndex++; d.wordIndex < DENSE_BLOCK_LONGS; d.wordIndex++ {
+			word, err := d.readWord()
+			if err != nil {
+				return false, err
+			}
+			d.word = word
+			if word != 0 {
+				d.index = d.numberOfOnes
+				d.numberOfOnes += bits.OnesCount64(word)
+				d.doc = d.block | (d.wordIndex << 6) | bits.TrailingZeros64(word)
+				return true, nil
+			}
+		}
+		// No set bits in the block at or after the wanted position.
+		return false, nil
+
+	default:
+		d.doc = target
+		d.index = target - d.gap
+		return true, nil
+	}
+}
+
+func (d *IndexedDISI) advanceExactWithinBlock(target int) (bool, error) {
+	switch d.method {
+	case disiSparse:
+		targetInBlock := target & 0xFFFF
+		// TODO: binary search
+		if d.nextExistDocInBlock > targetInBlock {
+			return false, nil
+		}
+		if target == d.doc {
+			return d.exists, nil
+		}
+		for d.index < d.nextBlockIndex {
+			doc, err := d.slice.ReadUint16(nil)
+			if err != nil {
+				return false, err
+			}
+			d.index++
+			if int(doc) >= targetInBlock {
+				d.nextExistDocInBlock = int(doc)
+				if int(doc) != targetInBlock {
+					d.index--
+					if _, err := d.slice.Seek(d.slice.GetFilePointer()-2, io.SeekStart); err != nil {
+						return false, err
+					}
+					break
+				}
+				d.exists = true
+				return true, nil
+			}
+		}
+		d.exists = false
+		return false, nil
+
+	case disiDense:
+		targetInBlock := target & 0xFFFF
+		targetWordIndex := targetInBlock >> 6
+
+		// If possible, skip ahead using the rank cache
+		if d.denseRankPower != -1 && targetWordIndex-d.wordIndex >= (1<<(d.denseRankPower-6)) {
+			if err := d.rankSkip(targetInBlock); err != nil {
+				return false, err
+			}
+		}
+
+		for i := d.wordIndex + 1; i <= targetWordIndex; i++ {
+			word, err := d.readWord()
+			if err != nil {
+				return false, err
+			}
+			d.word = word
+			d.numberOfOnes += bits.OnesCount64(word)
+		}
+		d.wordIndex = targetWordIndex
+
+		leftBits := d.word >> (target & 63)
+		d.index = d.numberOfOnes - bits.OnesCount64(leftBits)
+		return leftBits&1 != 0, nil
+
+	default:
+		d.index = target - d.gap
+		return true, nil
+	}
+}
+
+// rankSkip
+// If the distance between the current position and the target is > 8 words, the rank cache will
+// be used to guarantee a worst-case of 1 rank-lookup and 7 word-read-and-count-bits operations.
+// Note: This does not guarantee a skip up to target, only up to nearest rank boundary. It is the
+// responsibility of the caller to iterate further to reach target.
+func (d *IndexedDISI) rankSkip(targetInBlock int) error {
+	// Resolve the rank as close to targetInBlock as possible (maximum distance is 8 longs)
+	// Note: rankOrigoOffset is tracked on block open, so it is absolute (e.g. don't add origo)
+	rankIndex := targetInBlock >> d.denseRankPower // Default is 9 (8 longs: 2^3 * 2^6 bits)
+
+	rank := int(d.denseRankTable[rankIndex<<1])<<8 | int(d.denseRankTable[(rankIndex<<1)+1])
+
+	// Position the counting logic just after the rank point
+	rankAlignedWordIndex := rankIndex << d.denseRankPower >> 6
+	if _, err := d.slice.Seek(d.denseBitmapOffset+int64(rankAlignedWordIndex)*8, io.SeekStart); err != nil {
+		return err
+	}
+	rankWord, err := d.readWord()
+	if err != nil {
+		return err
+	}
+
+	d.wordIndex = rankAlignedWordIndex
+	d.word = rankWord
+	d.numberOfOnes = d.denseOrigoIndex + rank + bits.OnesCount64(rankWord)
+	return nil
+}
+
+func (d *IndexedDISI) readWord() (uint64, error) {
+	if _, err := io.ReadFull(d.slice, d.buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(d.buf[:]), nil
+}
+
+// WriteBitSet
+// Writes the docIDs from it to out, in logical blocks, one for each 65536 docIDs in monotonically
+// increasing gap-less order. The caller must keep track of the number of jump-table entries
+// (returned by this method) as well as the denseRankPower and provide them when constructing
+// an IndexedDISI for reading.
+//
+// denseRankPower is for DENSE blocks: 2^denseRankPower docIDs are covered by each rank entry.
+// Must be between 7 and 15 (inclusive) or -1 for no rank. A value of 9 (one rank entry for every
+// 512 docIDs) is a good trade-off between space and speed.
+func WriteBitSet(ctx context.Context, it types.DocIdSetIterator, out store.IndexOutput, denseRankPower int) (int, error) {
+	if denseRankPower != -1 && (denseRankPower < 7 || denseRankPower > 15) {
+		return 0, fmt.Errorf("acceptable values for denseRankPower are 7-15 (every 128-32768 docIDs), got %d", denseRankPower)
+	}
+
+	origo := out.GetFilePointer() // All jumps are relative to the origo
+	totalCardinality := 0
+	blockCardinality := 0
+	buffer := make([]uint64, DENSE_BLOCK_LONGS)
+	jumps := make([]int, 0, 2)
+	prevBlock := -1
+	jumpBlockIndex := 0
+
+	for {
+		doc, err := nextDoc(it)
+		if err != nil {
+			return 0, err
+		}
+		if doc == types.NO_MORE_DOCS {
+			break
+		}
+
+		block := doc >> 16
+		if prevBlock != -1 && block != prevBlock {
+			// Track offset+index from previous block up to current
+			jumps = addJumps(jumps, out.GetFilePointer()-origo, totalCardinality, jumpBlockIndex, prevBlock+1)
+			jumpBlockIndex = prevBlock + 1
+			// Flush block
+			if err := flushBlock(ctx, prevBlock, buffer, blockCardinality, denseRankPower, out); err != nil {
+				return 0, err
+			}
+			// Reset for next block
+			clear(buffer)
+			totalCardinality += blockCardinality
+			blockCardinality = 0
+		}
+		buffer[(doc&0xFFFF)>>6] |= 1 << (doc & 63)
+		blockCardinality++
+		prevBlock = block
+	}
+	if blockCardinality > 0 {
+		jumps = addJumps(jumps, out.GetFilePointer()-origo, totalCardinality, jumpBlockIndex, prevBlock+1)
+		totalCardinality += blockCardinality
+		if err := flushBlock(ctx, prevBlock, buffer, blockCardinality, denseRankPower, out); err != nil {
+			return 0, err
+		}
+		clear(buffer)
+		prevBlock++
+	}
+	lastBlock := max(prevBlock, 0) // There will always be at least 1 block (NO_MORE_DOCS)
+	// Last entry is a SPARSE with blockIndex == 32767 and the single entry 65535, which becomes the
+	// docID NO_MORE_DOCS. To avoid special casing, the jump table entry points to the start of the block
+	jumps = addJumps(jumps, out.GetFilePointer()-origo, totalCardinality, lastBlock, lastBlock+1)
+	buffer[(types.NO_MORE_DOCS&0xFFFF)>>6] |= 1 << (types.NO_MORE_DOCS & 63)
+	if err := flushBlock(ctx, types.NO_MORE_DOCS>>16, buffer, 1, denseRankPower, out); err != nil {
+		return 0, err
+	}
+	// offset+index jump-table stored at the end
+	return flushBlockJumps(ctx, jumps, lastBlock+1, out)
+}
+
+func addJumps(jumps []int, offset int64, index, startBlock, endBlock int) []int {
+	if need := (endBlock + 1) * 2; len(jumps) < need {
+		jumps = append(jumps, make([]int, need-len(jumps))...)
+	}
+	for b := startBlock; b < endBlock; b++ {
+		jumps[b*2] = index
+		jumps[b*2+1] = int(offset)
+	}
+	return jumps
+}
+
+func flushBlockJumps(ctx context.Context, jumps []int, blockCount int, out store.IndexOutput) (int, error) {
+	if blockCount == 2 {
+		// Jumps with a single real entry + NO_MORE_DOCS is just wasted space so we ignore that
+		blockCount = 0
+	}
+	for i := 0; i < blockCount; i++ {
+		if err := out.WriteUint32(ctx, uint32(jumps[i*2])); err != nil { // index
+			return 0, err
+		}
+		if err := out.WriteUint32(ctx, uint32(jumps[i*2+1])); err != nil { // offset
+			return 0, err
+		}
+	}
+	return blockCount, nil
+}
+
+func flushBlock(ctx context.Context, block int, buffer []uint64, cardinality, denseRankPower int,
+	out store.IndexOutput) error {
+
+	if err := out.WriteUint16(ctx, uint16(block)); err != nil {
+		return err
+	}
+	if err := out.WriteUint16(ctx, uint16(cardinality-1)); err != nil {
+		return err
+	}
+
+	if cardinality > MAX_ARRAY_LENGTH {
+		if cardinality == BLOCK_SIZE {
+			// all docs are set
+			return nil
+		}
+		if denseRankPower != -1 {
+			if _, err := out.Write(createRank(buffer, denseRankPower)); err != nil {
+				return err
+			}
+		}
+		for _, word := range buffer {
+			if err := out.WriteUint64(ctx, word); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i, word := range buffer {
+		for word != 0 {
+			doc := i<<6 | bits.TrailingZeros64(word)
+			if err := out.WriteUint16(ctx, uint16(doc)); err != nil {
+				return err
+			}
+			word &= word - 1
+		}
+	}
+	return nil
+}
+
+// createRank
+// Creates a DENSE rank-entry (the number of set bits up to a given point) for the buffer.
+// One rank-entry for every 2^denseRankPower bits, with each rank-entry using 2 bytes.
+func createRank(buffer []uint64, denseRankPower int) []byte {
+	longsPerRank := 1 << (denseRankPower - 6)
+	rankMark := longsPerRank - 1
+	rankIndexShift := denseRankPower - 7 // 6 for the long (2^6) + 1 for 2 bytes/entry
+	rank := make([]byte, DENSE_BLOCK_LONGS>>rankIndexShift)
+	bitCount := 0
+	for word := 0; word < DENSE_BLOCK_LONGS; word++ {
+		if word&rankMark == 0 { // Every longsPerRank longs
+			rank[word>>rankIndexShift] = byte(bitCount >> 8)
+			rank[(word>>rankIndexShift)+1] = byte(bitCount)
+		}
+		bitCount += bits.OnesCount64(buffer[word])
+	}
+	return rank
+}
+
+// nextDoc advances it and maps an exhausted iterator to NO_MORE_DOCS, iterators in this tree
+// report the end of the docs either way.
+func nextDoc(it types.DocIdSetIterator) (int, error) {
+	doc, err := it.NextDoc()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return types.NO_MORE_DOCS, nil
+		}
+		return 0, err
+	}
+	return doc, nil
+}
@@ -0,0 +1,177 @@
+package lucene80
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ types.DocValuesIterator = &testDocsIterator{}
+
+// testDocsIterator iterates over a sorted list of docs
+type testDocsIterator struct {
+	docs []int
+	upto int
+	doc  int
+}
+
+func newTestDocsIterator(docs []int) *testDocsIterator {
+	return &testDocsIterator{docs: docs, upto: -1, doc: -1}
+}
+
+func (t *testDocsIterator) DocID() int {
+	return t.doc
+}
+
+func (t *testDocsIterator) NextDoc() (int, error) {
+	t.upto++
+	if t.upto >= len(t.docs) {
+		t.doc = types.NO_MORE_DOCS
+	} else {
+		t.doc = t.docs[t.upto]
+	}
+	return t.doc, nil
+}
+
+func (t *testDocsIterator) Advance(target int) (int, error) {
+	return t.SlowAdvance(target)
+}
+
+func (t *testDocsIterator) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(t, target)
+}
+
+func (t *testDocsIterator) Cost() int64 {
+	return int64(len(t.docs))
+}
+
+func TestIndexedDISI(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+
+	randomDocs := func(maxDoc int, density float64) []int {
+		docs := make([]int, 0)
+		for doc := 0; doc < maxDoc; doc++ {
+			if r.Float64() < density {
+				docs = append(docs, doc)
+			}
+		}
+		return docs
+	}
+
+	all := make([]int, BLOCK_SIZE*2+10)
+	for i := range all {
+		all[i] = i
+	}
+
+	cases := map[string][]int{
+		"empty":      {},
+		"single":     {BLOCK_SIZE*3 + 5},
+		"sparse":     randomDocs(BLOCK_SIZE*5, 0.01),
+		"dense":      randomDocs(BLOCK_SIZE*3, 0.5),
+		"all":        all,
+		"twoBlocks":  {1, 2, BLOCK_SIZE + 1},
+		"mixed":      append(append(randomDocs(BLOCK_SIZE, 0.02), all[BLOCK_SIZE:2*BLOCK_SIZE]...), BLOCK_SIZE*7+3),
+		"lastBlocks": randomDocs(BLOCK_SIZE*12, 0.0003),
+	}
+
+	for name, docs := range cases {
+		t.Run(name, func(t *testing.T) {
+			for _, denseRankPower := range []int{-1, 7, DEFAULT_DENSE_RANK_POWER, 15} {
+				testIndexedDISI(t, r, docs, denseRankPower)
+			}
+		})
+	}
+}
+
+func testIndexedDISI(t *testing.T, r *rand.Rand, docs []int, denseRankPower int) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	out, err := dir.CreateOutput(ctx, "disi")
+	assert.Nil(t, err)
+	// leading bytes check that the data doesn't need to start at offset 0
+	assert.Nil(t, out.WriteUint32(ctx, 42))
+	jumpTableEntryCount, err := WriteBitSet(ctx, newTestDocsIterator(docs), out, denseRankPower)
+	assert.Nil(t, err)
+	length := out.GetFilePointer() - 4
+	assert.Nil(t, out.Close())
+
+	in, err := dir.OpenInput(ctx, "disi")
+	assert.Nil(t, err)
+	defer in.Close()
+
+	newDISI := func() *IndexedDISI {
+		disi, err := NewIndexedDISI(in, 4, length, jumpTableEntryCount, denseRankPower, int64(len(docs)))
+		assert.Nil(t, err)
+		return disi
+	}
+
+	// nextDoc
+	disi := newDISI()
+	for i, expected := range docs {
+		doc, err := disi.NextDoc()
+		assert.Nil(t, err)
+		assert.Equal(t, expected, doc)
+		assert.Equal(t, i, disi.Index())
+	}
+	doc, err := disi.NextDoc()
+	assert.Nil(t, err)
+	assert.Equal(t, types.NO_MORE_DOCS, doc)
+
+	maxDoc := BLOCK_SIZE * 13
+	if len(docs) > 0 {
+		maxDoc = max(maxDoc, docs[len(docs)-1]+1)
+	}
+	index := make(map[int]int, len(docs))
+	for i, doc := range docs {
+		index[doc] = i
+	}
+
+	// advance
+	disi = newDISI()
+	for target := r.Intn(100); target < maxDoc; target += 1 + r.Intn(BLOCK_SIZE/3) {
+		doc, err := disi.Advance(target)
+		assert.Nil(t, err)
+		i := ceil(docs, target)
+		if i == len(docs) {
+			assert.Equal(t, types.NO_MORE_DOCS, doc)
+			break
+		}
+		assert.Equal(t, docs[i], doc)
+		assert.Equal(t, i, disi.Index())
+		target = doc
+	}
+
+	// advanceExact
+	disi = newDISI()
+	for target := r.Intn(100); target < maxDoc; target += 1 + r.Intn(2000) {
+		found, err := disi.AdvanceExact(target)
+		assert.Nil(t, err)
+		i, ok := index[target]
+		assert.Equal(t, ok, found, "target %d", target)
+		if ok {
+			assert.Equal(t, i, disi.Index())
+		}
+		assert.Equal(t, target, disi.DocID())
+	}
+}
+
+// ceil returns the index of the first doc >= target
+func ceil(docs []int, target int) int {
+	for i, doc := range docs {
+		if doc >= target {
+			return i
+		}
+	}
+	return len(docs)
+}
+
+func (t *testDocsIterator) AdvanceExact(target int) (bool, error) {
+	doc, err := t.Advance(target)
+	return doc == target, err
+}
@@ -0,0 +1,230 @@
+package lucene80
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+)
+
+var _ index.NormsConsumer = &NormsConsumer{}
+
+// NormsConsumer Writer for NormsFormat
+type NormsConsumer struct {
+	*coreIndex.NormsConsumerDefault
+
+	data   store.IndexOutput
+	meta   store.IndexOutput
+	maxDoc int
+}
+
+func NewNormsConsumer(ctx context.Context, state *index.SegmentWriteState,
+	dataCodec, dataExtension, metaCodec, metaExtension string) (*NormsConsumer, error) {
+
+	consumer := &NormsConsumer{}
+	consumer.NormsConsumerDefault = &coreIndex.NormsConsumerDefault{
+		FnAddNormsField: consumer.AddNormsField,
+	}
+
+	closeOnError := func(err error) (*NormsConsumer, error) {
+		_ = consumer.closeOutputs()
+		return nil, err
+	}
+
+	segmentName := state.SegmentInfo.Name()
+	segmentID := state.SegmentInfo.GetID()
+
+	var err error
+	dataName := store.SegmentFileName(segmentName, state.SegmentSuffix, dataExtension)
+	if consumer.data, err = store.CreateOutputWithContext(ctx, state.Directory, dataName, state.Context); err != nil {
+		return nil, err
+	}
+	if err := utils.WriteIndexHeader(ctx, consumer.data, dataCodec, NORMS_VERSION_CURRENT, segmentID, state.SegmentSuffix); err != nil {
+		return closeOnError(err)
+	}
+
+	metaName := store.SegmentFileName(segmentName, state.SegmentSuffix, metaExtension)
+	if consumer.meta, err = store.CreateOutputWithContext(ctx, state.Directory, metaName, state.Context); err != nil {
+		return closeOnError(err)
+	}
+	if err := utils.WriteIndexHeader(ctx, consumer.meta, metaCodec, NORMS_VERSION_CURRENT, segmentID, state.SegmentSuffix); err != nil {
+		return closeOnError(err)
+	}
+
+	if consumer.maxDoc, err = state.SegmentInfo.MaxDoc(); err != nil {
+		return closeOnError(err)
+	}
+	return consumer, nil
+}
+
+func (n *NormsConsumer) Close() error {
+	if n.meta != nil {
+		// write EOF marker
+		if err := n.meta.WriteUint32(nil, math.MaxUint32); err != nil {
+			_ = n.closeOutputs()
+			return err
+		}
+		if err := utils.WriteFooter(n.meta); err != nil {
+			_ = n.closeOutputs()
+			return err
+		}
+	}
+	if n.data != nil {
+		if err := utils.WriteFooter(n.data); err != nil {
+			_ = n.closeOutputs()
+			return err
+		}
+	}
+	return n.closeOutputs()
+}
+
+func (n *NormsConsumer) closeOutputs() error {
+	var errs []error
+	for _, closer := range []store.IndexOutput{n.data, n.meta} {
+		if closer != nil {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	n.data, n.meta = nil, nil
+	return errors.Join(errs...)
+}
+
+func (n *NormsConsumer) AddNormsField(ctx context.Context, field *document.FieldInfo, normsProducer index.NormsProducer) error {
+	values, err := normsProducer.GetNorms(field)
+	if err != nil {
+		return err
+	}
+
+	numDocsWithValue := 0
+	minValue, maxValue := int64(math.MaxInt64), int64(math.MinInt64)
+	for {
+		doc, err := nextDoc(values)
+		if err != nil {
+			return err
+		}
+		if doc == types.NO_MORE_DOCS {
+			break
+		}
+		numDocsWithValue++
+		v, err := values.LongValue()
+		if err != nil {
+			return err
+		}
+		minValue = min(minValue, v)
+		maxValue = max(maxValue, v)
+	}
+
+	if err := n.meta.WriteUint32(ctx, uint32(field.Number())); err != nil {
+		return err
+	}
+
+	offset, length := int64(-2), int64(0)
+	jumpTableEntryCount, denseRankPower := -1, -1
+	switch numDocsWithValue {
+	case 0:
+		// no documents have a norm
+	case n.maxDoc:
+		// all documents have a norm
+		offset = -1
+	default:
+		offset = n.data.GetFilePointer()
+		if values, err = normsProducer.GetNorms(field); err != nil {
+			return err
+		}
+		if jumpTableEntryCount, err = WriteBitSet(ctx, values, n.data, DEFAULT_DENSE_RANK_POWER); err != nil {
+			return err
+		}
+		length = n.data.GetFilePointer() - offset
+		denseRankPower = DEFAULT_DENSE_RANK_POWER
+	}
+
+	if err := n.meta.WriteUint64(ctx, uint64(offset)); err != nil { // docsWithFieldOffset
+		return err
+	}
+	if err := n.meta.WriteUint64(ctx, uint64(length)); err != nil { // docsWithFieldLength
+		return err
+	}
+	if err := n.meta.WriteUint16(ctx, uint16(jumpTableEntryCount)); err != nil {
+		return err
+	}
+	if err := n.meta.WriteByte(byte(denseRankPower)); err != nil {
+		return err
+	}
+	if err := n.meta.WriteUint32(ctx, uint32(numDocsWithValue)); err != nil {
+		return err
+	}
+
+	numBytesPerValue := numBytesPerNorm(minValue, maxValue)
+	if err := n.meta.WriteByte(byte(numBytesPerValue)); err != nil {
+		return err
+	}
+	if numBytesPerValue == 0 {
+		return n.meta.WriteUint64(ctx, uint64(minValue))
+	}
+
+	if err := n.meta.WriteUint64(ctx, uint64(n.data.GetFilePointer())); err != nil { // normsOffset
+		return err
+	}
+	if values, err = normsProducer.GetNorms(field); err != nil {
+		return err
+	}
+	return n.writeValues(ctx, values, numBytesPerValue)
+}
+
+// numBytesPerNorm returns the minimum number of bytes needed to represent all values in [minValue, maxValue],
+// 0 when all values are equal.
+func numBytesPerNorm(minValue, maxValue int64) int {
+	switch {
+	case minValue >= maxValue:
+		return 0
+	case minValue >= math.MinInt8 && maxValue <= math.MaxInt8:
+		return 1
+	case minValue >= math.MinInt16 && maxValue <= math.MaxInt16:
+		return 2
+	case minValue >= math.MinInt32 && maxValue <= math.MaxInt32:
+		return 4
+	default:
+		return 8
+	}
+}
+
+func (n *NormsConsumer) writeValues(ctx context.Context, values index.NumericDocValues, numBytesPerValue int) error {
+	for {
+		doc, err := nextDoc(values)
+		if err != nil {
+			return err
+		}
+		if doc == types.NO_MORE_DOCS {
+			return nil
+		}
+		value, err := values.LongValue()
+		if err != nil {
+			return err
+		}
+
+		switch numBytesPerValue {
+		case 1:
+			err = n.data.WriteByte(byte(value))
+		case 2:
+			err = n.data.WriteUint16(ctx, uint16(value))
+		case 4:
+			err = n.data.WriteUint32(ctx, uint32(value))
+		case 8:
+			err = n.data.WriteUint64(ctx, uint64(value))
+		default:
+			err = fmt.Errorf("illegal number of bytes per norm: %d", numBytesPerValue)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
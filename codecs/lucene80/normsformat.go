@@ -0,0 +1,81 @@
+package lucene80
+
+import (
+	"context"
+
+	"github.com/geange/lucene-go/core/interface/index"
+)
+
+const (
+	NORMS_DATA_CODEC         = "Lucene80NormsData"
+	NORMS_DATA_EXTENSION     = "nvd"
+	NORMS_METADATA_CODEC     = "Lucene80NormsMetadata"
+	NORMS_METADATA_EXTENSION = "nvm"
+
+	NORMS_VERSION_START   = 0
+	NORMS_VERSION_CURRENT = NORMS_VERSION_START
+)
+
+var _ index.NormsFormat = &NormsFormat{}
+
+// NormsFormat Lucene 8.0 Score normalization format.
+//
+// Encodes normalization values by encoding each value with the minimum number of bytes needed to
+// represent the range (which can be zero).
+//
+// Files:
+//   - .nvd: Norms data
+//   - .nvm: Norms metadata
+//
+// The Norms metadata or .nvm file.
+// For each norms field, this stores metadata, such as the offset into the Norms data (.nvd)
+//
+// Norms metadata (.nvm) --> Header,<Entry>NumFields,Footer
+//   - Header --> IndexHeader
+//   - Entry --> FieldNumber, DocsWithFieldAddress, DocsWithFieldLength, NumDocsWithField, BytesPerNorm, NormsAddress
+//   - FieldNumber --> int
+//   - DocsWithFieldAddress --> long
+//   - DocsWithFieldLength --> long
+//   - NumDocsWithField --> int
+//   - BytesPerNorm --> byte
+//   - NormsAddress --> long
+//   - Footer --> CodecFooter
+//
+// FieldNumber of -1 indicates the end of metadata.
+//
+// NormsAddress is the pointer to the start of the data in the norms data (.nvd), or the singleton
+// value when BytesPerValue = 0. If BytesPerValue is different from 0 then there are NumDocsWithField
+// values to read at that offset.
+//
+// DocsWithFieldAddress is the pointer to the start of the bit set containing documents that have a
+// norm in the norms data (.nvd), or -2 if no documents have a norm value, or -1 if all documents
+// have a norm value.
+//
+// DocsWithFieldLength is the number of bytes used to encode the set of documents that have a norm.
+//
+// The Norms data or .nvd file.
+// For each Norms field, this stores the actual per-document data (the heavy-lifting)
+//
+// Norms data (.nvd) --> Header,< Data >NumFields,Footer
+//   - Header --> IndexHeader
+//   - DocsWithFieldData --> Bit set of MaxDoc bits
+//   - NormsData --> byte^(NumDocsWithField * BytesPerValue)
+//   - Footer --> CodecFooter
+//
+// lucene.experimental
+type NormsFormat struct {
+}
+
+func NewNormsFormat() *NormsFormat {
+	return &NormsFormat{}
+}
+
+func (n *NormsFormat) NormsConsumer(ctx context.Context, state *index.SegmentWriteState) (index.NormsConsumer, error) {
+	return NewNormsConsumer(ctx, state, NORMS_DATA_CODEC, NORMS_DATA_EXTENSION,
+		NORMS_METADATA_CODEC, NORMS_METADATA_EXTENSION)
+}
+
+func (n *NormsFormat) NormsProducer(ctx context.Context, state *index.SegmentReadState) (index.NormsProducer, error) {
+	return NewNormsProducer(ctx, state, NORMS_DATA_CODEC, NORMS_DATA_EXTENSION,
+		NORMS_METADATA_CODEC, NORMS_METADATA_EXTENSION)
+}
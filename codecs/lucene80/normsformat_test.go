@@ -0,0 +1,228 @@
+package lucene80
+
+import (
+	"context"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+// testNormsProducer serves the in-memory norms of the test fields
+type testNormsProducer struct {
+	fields map[string]*testField
+}
+
+func (p *testNormsProducer) Close() error {
+	return nil
+}
+
+func (p *testNormsProducer) GetNorms(field *document.FieldInfo) (index.NumericDocValues, error) {
+	return p.fields[field.Name()].numeric(), nil
+}
+
+func (p *testNormsProducer) CheckIntegrity() error {
+	return nil
+}
+
+func (p *testNormsProducer) GetMergeInstance() index.NormsProducer {
+	return p
+}
+
+func TestNormsFormat(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(11))
+	maxDoc := 70000
+
+	norms := map[string]func() int64{
+		"constant": func() int64 { return 7 },
+		"byte":     func() int64 { return int64(r.Intn(256) - 128) },
+		"short":    func() int64 { return int64(r.Intn(1 << 15)) },
+		"int":      func() int64 { return int64(r.Int31()) - math.MaxInt16 },
+		"long":     func() int64 { return r.Int63() - math.MaxInt32 },
+	}
+	expectedBytesPerNorm := map[string]int{"constant": 0, "byte": 1, "short": 2, "int": 4, "long": 8}
+
+	fields := make(map[string]*testField)
+	kinds := make(map[string]string)
+	infos := make([]*document.FieldInfo, 0)
+	for _, density := range []string{"dense", "sparse", "empty"} {
+		p := map[string]float64{"dense": 1, "sparse": 0.3, "empty": 0}[density]
+		for _, kind := range []string{"constant", "byte", "short", "int", "long"} {
+			name := kind + "_" + density
+			docs := randomDocs(r, maxDoc, p)
+			numbers := make([][]int64, len(docs))
+			for i := range numbers {
+				numbers[i] = []int64{norms[kind]()}
+			}
+			fields[name] = &testField{docs: docs, numbers: numbers}
+			kinds[name] = kind
+			infos = append(infos, document.NewFieldInfo(name, len(infos), false, false, false,
+				document.INDEX_OPTIONS_DOCS_AND_FREQS, document.DOC_VALUES_TYPE_NONE, -1,
+				map[string]string{}, 0, 0, 0, false))
+		}
+	}
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+	id := make([]byte, 16)
+	r.Read(id)
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", maxDoc,
+		false, nil, map[string]string{}, id, map[string]string{}, nil)
+	fieldInfos := coreIndex.NewFieldInfos(infos)
+
+	format := NewNormsFormat()
+	consumer, err := format.NormsConsumer(ctx, &index.SegmentWriteState{
+		Directory:   dir,
+		SegmentInfo: si,
+		FieldInfos:  fieldInfos,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	source := &testNormsProducer{fields: fields}
+	for _, info := range infos {
+		assert.Nil(t, consumer.AddNormsField(ctx, info, source), info.Name())
+	}
+	assert.Nil(t, consumer.Close())
+
+	producer, err := format.NormsProducer(ctx, &index.SegmentReadState{
+		Directory:   dir,
+		SegmentInfo: si,
+		FieldInfos:  fieldInfos,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer producer.Close()
+	assert.Nil(t, producer.CheckIntegrity())
+
+	entries := producer.(*NormsProducer).norms
+	for _, info := range infos {
+		name := info.Name()
+		field := fields[name]
+		entry := entries[info.Number()]
+		if len(field.docs) > 0 {
+			assert.Equal(t, expectedBytesPerNorm[kinds[name]], entry.bytesPerNorm, name)
+		}
+
+		// iterate over all values
+		values, err := producer.GetNorms(info)
+		assert.Nil(t, err)
+		for i, doc := range field.docs {
+			next, err := values.NextDoc()
+			assert.Nil(t, err)
+			if !assert.Equal(t, doc, next, name) {
+				break
+			}
+			value, err := values.LongValue()
+			assert.Nil(t, err)
+			assert.Equal(t, field.numbers[i][0], value, name)
+		}
+		next, err := values.NextDoc()
+		assert.Nil(t, err)
+		assert.Equal(t, types.NO_MORE_DOCS, next, name)
+
+		// random access
+		values, err = producer.GetNorms(info)
+		assert.Nil(t, err)
+		expected := make(map[int]int64, len(field.docs))
+		for i, doc := range field.docs {
+			expected[doc] = field.numbers[i][0]
+		}
+		for target := r.Intn(100); target < maxDoc; target += 1 + r.Intn(500) {
+			exists, err := values.AdvanceExact(target)
+			assert.Nil(t, err)
+			value, ok := expected[target]
+			if !assert.Equal(t, ok, exists, name) {
+				break
+			}
+			if exists {
+				actual, err := values.LongValue()
+				assert.Nil(t, err)
+				assert.Equal(t, value, actual, name)
+			}
+		}
+	}
+}
+
+// testEOFNumeric ends its docs with io.EOF like the in-memory norms of the indexing chain
+type testEOFNumeric struct {
+	*testSortedNumeric
+}
+
+func (s *testEOFNumeric) NextDoc() (int, error) {
+	doc, err := s.testSortedNumeric.NextDoc()
+	if err == nil && doc == types.NO_MORE_DOCS {
+		return 0, io.EOF
+	}
+	return doc, err
+}
+
+type testEOFNormsProducer struct {
+	*testNormsProducer
+}
+
+func (p *testEOFNormsProducer) GetNorms(field *document.FieldInfo) (index.NumericDocValues, error) {
+	return &testEOFNumeric{p.fields[field.Name()].numeric()}, nil
+}
+
+func TestNormsFormat_EOFTerminatedNorms(t *testing.T) {
+	ctx := context.Background()
+	maxDoc := 6
+	field := &testField{docs: []int{0, 2, 5}, numbers: [][]int64{{3}, {-1}, {300}}}
+	info := document.NewFieldInfo("body", 0, false, false, false,
+		document.INDEX_OPTIONS_DOCS_AND_FREQS, document.DOC_VALUES_TYPE_NONE, -1,
+		map[string]string{}, 0, 0, 0, false)
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", maxDoc,
+		false, nil, map[string]string{}, make([]byte, 16), map[string]string{}, nil)
+	fieldInfos := coreIndex.NewFieldInfos([]*document.FieldInfo{info})
+
+	format := NewNormsFormat()
+	consumer, err := format.NormsConsumer(ctx, &index.SegmentWriteState{
+		Directory:   dir,
+		SegmentInfo: si,
+		FieldInfos:  fieldInfos,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	source := &testEOFNormsProducer{&testNormsProducer{fields: map[string]*testField{"body": field}}}
+	assert.Nil(t, consumer.AddNormsField(ctx, info, source))
+	assert.Nil(t, consumer.Close())
+
+	producer, err := format.NormsProducer(ctx, &index.SegmentReadState{
+		Directory:   dir,
+		SegmentInfo: si,
+		FieldInfos:  fieldInfos,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer producer.Close()
+
+	values, err := producer.GetNorms(info)
+	assert.Nil(t, err)
+	for i, doc := range field.docs {
+		next, err := values.NextDoc()
+		assert.Nil(t, err)
+		assert.Equal(t, doc, next)
+		value, err := values.LongValue()
+		assert.Nil(t, err)
+		assert.Equal(t, field.numbers[i][0], value)
+	}
+	next, err := values.NextDoc()
+	assert.Nil(t, err)
+	assert.Equal(t, types.NO_MORE_DOCS, next)
+}
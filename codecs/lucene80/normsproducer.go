@@ -0,0 +1,211 @@
+package lucene80
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+)
+
+var _ index.NormsProducer = &NormsProducer{}
+
+// NormsProducer Reader for NormsFormat
+type NormsProducer struct {
+	// metadata maps (just file pointers and minimal stuff)
+	norms  map[int]*normsEntry
+	data   store.IndexInput
+	maxDoc int
+}
+
+type normsEntry struct {
+	docsWithFieldEntry
+
+	numDocsWithField int
+	bytesPerNorm     int
+	normsOffset      int64
+}
+
+func NewNormsProducer(ctx context.Context, state *index.SegmentReadState,
+	dataCodec, dataExtension, metaCodec, metaExtension string) (*NormsProducer, error) {
+
+	maxDoc, err := state.SegmentInfo.MaxDoc()
+	if err != nil {
+		return nil, err
+	}
+
+	producer := &NormsProducer{
+		norms:  make(map[int]*normsEntry),
+		maxDoc: maxDoc,
+	}
+
+	segmentName := state.SegmentInfo.Name()
+	segmentID := state.SegmentInfo.GetID()
+
+	metaName := store.SegmentFileName(segmentName, state.SegmentSuffix, metaExtension)
+	metaIn, err := store.OpenChecksumInput(state.Directory, metaName)
+	if err != nil {
+		return nil, err
+	}
+	defer metaIn.Close()
+
+	version, err := utils.CheckIndexHeader(ctx, metaIn, metaCodec, NORMS_VERSION_START, NORMS_VERSION_CURRENT,
+		segmentID, state.SegmentSuffix)
+	if err != nil {
+		return nil, err
+	}
+	if err := producer.readFields(ctx, metaIn, state.FieldInfos); err != nil {
+		return nil, err
+	}
+	if _, err := utils.CheckCodecFooter(metaIn); err != nil {
+		return nil, err
+	}
+
+	dataName := store.SegmentFileName(segmentName, state.SegmentSuffix, dataExtension)
+	if producer.data, err = store.OpenInputWithContext(ctx, state.Directory, dataName, state.Context); err != nil {
+		return nil, err
+	}
+
+	closeOnError := func(err error) (*NormsProducer, error) {
+		_ = producer.Close()
+		return nil, err
+	}
+
+	version2, err := utils.CheckIndexHeader(ctx, producer.data, dataCodec, NORMS_VERSION_START, NORMS_VERSION_CURRENT,
+		segmentID, state.SegmentSuffix)
+	if err != nil {
+		return closeOnError(err)
+	}
+	if version != version2 {
+		return closeOnError(fmt.Errorf("format versions mismatch: meta=%d, data=%d", version, version2))
+	}
+
+	// NOTE: data file is too costly to verify checksum against all the bytes on open,
+	// but for now we at least verify proper structure of the checksum footer: which looks
+	// for FOOTER_MAGIC + algorithmID. This is cheap and can detect some forms of corruption
+	// such as file truncation.
+	if _, err := utils.RetrieveChecksum(producer.data); err != nil {
+		return closeOnError(err)
+	}
+	return producer, nil
+}
+
+func (n *NormsProducer) readFields(ctx context.Context, meta store.DataInput, infos index.FieldInfos) error {
+	for {
+		fieldNumber, err := readInt32(ctx, meta)
+		if err != nil {
+			return err
+		}
+		if fieldNumber == -1 {
+			return nil
+		}
+
+		info := infos.FieldInfoByNumber(fieldNumber)
+		if info == nil {
+			return fmt.Errorf("invalid field number: %d", fieldNumber)
+		}
+		if !info.HasNorms() {
+			return fmt.Errorf("invalid field: %s", info.Name())
+		}
+
+		entry := &normsEntry{}
+		if err := entry.read(ctx, meta); err != nil {
+			return err
+		}
+		if entry.numDocsWithField, err = readInt32(ctx, meta); err != nil {
+			return err
+		}
+		bytesPerNorm, err := meta.ReadByte()
+		if err != nil {
+			return err
+		}
+		entry.bytesPerNorm = int(bytesPerNorm)
+		switch entry.bytesPerNorm {
+		case 0, 1, 2, 4, 8:
+		default:
+			return fmt.Errorf("invalid bytesPerValue: %d, field: %s", entry.bytesPerNorm, info.Name())
+		}
+		if err := readInt64s(ctx, meta, &entry.normsOffset); err != nil {
+			return err
+		}
+		n.norms[info.Number()] = entry
+	}
+}
+
+func (n *NormsProducer) GetNorms(field *document.FieldInfo) (index.NumericDocValues, error) {
+	entry, ok := n.norms[field.Number()]
+	if !ok {
+		return nil, fmt.Errorf("field %s has no norms", field.Name())
+	}
+
+	docs, err := entry.iterator(n.data, n.maxDoc, int64(entry.numDocsWithField))
+	if err != nil {
+		return nil, err
+	}
+	values, err := n.getNormValues(entry)
+	if err != nil {
+		return nil, err
+	}
+	return &numericDocValues{docsWithField: docs, values: values}, nil
+}
+
+// getNormValues returns the norms of an entry by their index in the set of documents that have a norm
+func (n *NormsProducer) getNormValues(entry *normsEntry) (longValues, error) {
+	if entry.bytesPerNorm == 0 {
+		value := entry.normsOffset
+		return func(int64) (int64, error) {
+			return value, nil
+		}, nil
+	}
+
+	length := int64(entry.numDocsWithField) * int64(entry.bytesPerNorm)
+	slice, err := n.data.RandomAccessSlice(entry.normsOffset, length)
+	if err != nil {
+		return nil, err
+	}
+
+	switch entry.bytesPerNorm {
+	case 1:
+		return func(index int64) (int64, error) {
+			v, err := slice.ReadU8(index)
+			return int64(int8(v)), err
+		}, nil
+	case 2:
+		return func(index int64) (int64, error) {
+			v, err := slice.ReadU16(index << 1)
+			return int64(int16(v)), err
+		}, nil
+	case 4:
+		return func(index int64) (int64, error) {
+			v, err := slice.ReadU32(index << 2)
+			return int64(int32(v)), err
+		}, nil
+	case 8:
+		return func(index int64) (int64, error) {
+			v, err := slice.ReadU64(index << 3)
+			return int64(v), err
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid bytesPerNorm: %d", entry.bytesPerNorm)
+	}
+}
+
+func (n *NormsProducer) Close() error {
+	if n.data == nil {
+		return nil
+	}
+	err := n.data.Close()
+	n.data = nil
+	return err
+}
+
+func (n *NormsProducer) CheckIntegrity() error {
+	_, err := utils.ChecksumEntireFile(n.data)
+	return err
+}
+
+func (n *NormsProducer) GetMergeInstance() index.NormsProducer {
+	return n
+}
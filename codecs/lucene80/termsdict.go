@@ -0,0 +1,384 @@
+package lucene80
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/compress/lz4"
+	"github.com/geange/lucene-go/core/util/packed"
+)
+
+var _ index.TermsEnum = &termsDict{}
+
+// termsDict iterates over the prefix-compressed terms dictionary of a sorted or sorted set field.
+// Blocks of terms are located with the block addresses, and seekCeil binary searches the reverse
+// terms index first in order to only decode the blocks of a small range of ords.
+// When the dictionary is compressed, the first term of every block is stored as is and the other
+// terms of the block are decompressed into blockBuffer.
+type termsDict struct {
+	*coreIndex.BaseTermsEnum
+
+	entry          *termsDictEntry
+	data           store.IndexInput
+	blockMask      int64
+	blockAddresses *packed.DirectMonotonicReader
+	bytes          store.IndexInput
+	indexAddresses *packed.DirectMonotonicReader
+	indexBytes     store.IndexInput
+	term           []byte
+	ord            int64
+
+	blockBuffer                 []byte
+	blockInput                  *store.BytesInput
+	currentCompressedBlockStart int64
+	currentCompressedBlockEnd   int64
+}
+
+func (r *DocValuesProducer) newTermsDict(entry *termsDictEntry) (*termsDict, error) {
+	return newTermsDict(entry, r.data)
+}
+
+func newTermsDict(entry *termsDictEntry, data store.IndexInput) (*termsDict, error) {
+	dict := &termsDict{
+		entry:     entry,
+		data:      data,
+		blockMask: 1<<entry.termsDictBlockShift - 1,
+		term:      make([]byte, 0, entry.maxTermLength),
+		ord:       -1,
+
+		currentCompressedBlockStart: -1,
+		currentCompressedBlockEnd:   -1,
+	}
+	if entry.compressed {
+		// add 7 padding bytes, this can help decompression run faster
+		dict.blockBuffer = make([]byte, entry.maxBlockLength+7)
+	}
+	dict.BaseTermsEnum = coreIndex.NewBaseTermsEnum(&coreIndex.BaseTermsEnumConfig{SeekCeil: dict.SeekCeil})
+
+	addressesSlice, err := data.RandomAccessSlice(entry.termsAddressesOffset, entry.termsAddressesLength)
+	if err != nil {
+		return nil, err
+	}
+	if dict.blockAddresses, err = packed.NewDirectMonotonicReader(entry.termsAddressesMeta, addressesSlice); err != nil {
+		return nil, err
+	}
+	if dict.bytes, err = data.Slice("terms", entry.termsDataOffset, entry.termsDataLength); err != nil {
+		return nil, err
+	}
+
+	indexAddressesSlice, err := data.RandomAccessSlice(entry.termsIndexAddressesOffset, entry.termsIndexAddressesLength)
+	if err != nil {
+		return nil, err
+	}
+	if dict.indexAddresses, err = packed.NewDirectMonotonicReader(entry.termsIndexAddressesMeta, indexAddressesSlice); err != nil {
+		return nil, err
+	}
+	if dict.indexBytes, err = data.Slice("terms-index", entry.termsIndexOffset, entry.termsIndexLength); err != nil {
+		return nil, err
+	}
+	return dict, nil
+}
+
+// clone returns a new unpositioned enum over the same terms
+func (t *termsDict) clone() (*termsDict, error) {
+	return newTermsDict(t.entry, t.data)
+}
+
+func (t *termsDict) Next(ctx context.Context) ([]byte, error) {
+	t.ord++
+	if t.ord >= t.entry.termsDictSize {
+		return nil, nil
+	}
+
+	if t.ord&t.blockMask == 0 {
+		if err := t.readBlockStart(ctx); err != nil {
+			return nil, err
+		}
+		return t.term, nil
+	}
+
+	var input store.DataInput = t.bytes
+	if t.entry.compressed {
+		input = t.blockInput
+	}
+	token, err := input.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	prefixLength := int(token & 0x0F)
+	suffixLength := 1 + int(token>>4)
+	if prefixLength == 15 {
+		n, err := input.ReadUvarint(ctx)
+		if err != nil {
+			return nil, err
+		}
+		prefixLength += int(n)
+	}
+	if suffixLength == 16 {
+		n, err := input.ReadUvarint(ctx)
+		if err != nil {
+			return nil, err
+		}
+		suffixLength += int(n)
+	}
+	if prefixLength > len(t.term) || prefixLength+suffixLength > cap(t.term) {
+		return nil, fmt.Errorf("corrupted terms dictionary: prefix=%d, suffix=%d", prefixLength, suffixLength)
+	}
+	t.term = t.term[:prefixLength+suffixLength]
+	if _, err := io.ReadFull(input, t.term[prefixLength:]); err != nil {
+		return nil, err
+	}
+	return t.term, nil
+}
+
+// readBlockStart reads the first term of a block, and decompresses the other terms of the block
+// if the dictionary is compressed.
+func (t *termsDict) readBlockStart(ctx context.Context) error {
+	if err := t.readFirstTerm(ctx); err != nil {
+		return err
+	}
+	if !t.entry.compressed {
+		return nil
+	}
+
+	offset := t.bytes.GetFilePointer()
+	if offset >= t.entry.termsDataLength-1 {
+		// the last block only has its first term
+		return nil
+	}
+	if t.currentCompressedBlockStart != offset {
+		decompressLength, err := t.bytes.ReadUvarint(ctx)
+		if err != nil {
+			return err
+		}
+		if int(decompressLength) > len(t.blockBuffer) {
+			return fmt.Errorf("corrupted terms dictionary: block of %d bytes > %d", decompressLength, len(t.blockBuffer))
+		}
+		if _, err := lz4.Decompress(t.bytes, int(decompressLength), t.blockBuffer); err != nil {
+			return err
+		}
+		t.currentCompressedBlockStart = offset
+		t.currentCompressedBlockEnd = t.bytes.GetFilePointer()
+	} else {
+		// avoid decompressing the same block again, but skip it
+		if _, err := t.bytes.Seek(t.currentCompressedBlockEnd, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	t.blockInput = store.NewBytesInput(t.blockBuffer)
+	return nil
+}
+
+// readFirstTerm reads the first term of a block, which is not prefix-compressed
+func (t *termsDict) readFirstTerm(ctx context.Context) error {
+	length, err := t.bytes.ReadUvarint(ctx)
+	if err != nil {
+		return err
+	}
+	if int(length) > cap(t.term) {
+		return fmt.Errorf("corrupted terms dictionary: term length %d > %d", length, cap(t.term))
+	}
+	t.term = t.term[:length]
+	_, err = io.ReadFull(t.bytes, t.term)
+	return err
+}
+
+func (t *termsDict) SeekExactByOrd(ctx context.Context, ord int64) error {
+	if ord < 0 || ord >= t.entry.termsDictSize {
+		return fmt.Errorf("ord %d is out of bounds [0, %d)", ord, t.entry.termsDictSize)
+	}
+	blockIndex := ord >> t.entry.termsDictBlockShift
+	blockAddress, err := t.blockAddresses.Get(blockIndex)
+	if err != nil {
+		return err
+	}
+	if _, err := t.bytes.Seek(int64(blockAddress), io.SeekStart); err != nil {
+		return err
+	}
+	t.ord = blockIndex<<t.entry.termsDictBlockShift - 1
+	for t.ord < ord {
+		if _, err := t.Next(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seekTermsIndex returns the index of the last entry of the reverse terms index that is less than or
+// equal to text.
+func (t *termsDict) seekTermsIndex(text []byte) (int64, error) {
+	lo, hi := int64(0), (t.entry.termsDictSize-1)>>t.entry.termsDictIndexShift
+	for lo <= hi {
+		mid := int64(uint64(lo+hi) >> 1)
+		start, err := t.indexAddresses.Get(mid)
+		if err != nil {
+			return 0, err
+		}
+		end, err := t.indexAddresses.Get(mid + 1)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := t.indexBytes.Seek(int64(start), io.SeekStart); err != nil {
+			return 0, err
+		}
+		prefix := make([]byte, end-start)
+		if _, err := io.ReadFull(t.indexBytes, prefix); err != nil {
+			return 0, err
+		}
+
+		cmp := bytes.Compare(prefix, text)
+		if cmp < 0 {
+			lo = mid + 1
+		} else if cmp > 0 {
+			hi = mid - 1
+		} else {
+			return mid, nil
+		}
+	}
+
+	// the first entry of the index is the empty string, which is less than or equal to any term
+	return hi, nil
+}
+
+// seekBlock returns the index of the last block whose first term is less than or equal to text,
+// or -1 if text is before the first term.
+func (t *termsDict) seekBlock(ctx context.Context, text []byte) (int64, error) {
+	index, err := t.seekTermsIndex(text)
+	if err != nil {
+		return 0, err
+	}
+	if index == -1 {
+		return -1, nil
+	}
+
+	ordLo := index << t.entry.termsDictIndexShift
+	ordHi := min(t.entry.termsDictSize, ordLo+(1<<t.entry.termsDictIndexShift)) - 1
+
+	blockLo := ordLo >> t.entry.termsDictBlockShift
+	blockHi := ordHi >> t.entry.termsDictBlockShift
+
+	for blockLo <= blockHi {
+		blockMid := int64(uint64(blockLo+blockHi) >> 1)
+		blockAddress, err := t.blockAddresses.Get(blockMid)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := t.bytes.Seek(int64(blockAddress), io.SeekStart); err != nil {
+			return 0, err
+		}
+		if err := t.readFirstTerm(ctx); err != nil {
+			return 0, err
+		}
+
+		cmp := bytes.Compare(t.term, text)
+		if cmp < 0 {
+			blockLo = blockMid + 1
+		} else if cmp > 0 {
+			blockHi = blockMid - 1
+		} else {
+			return blockMid, nil
+		}
+	}
+
+	return blockHi, nil
+}
+
+func (t *termsDict) SeekCeil(ctx context.Context, text []byte) (index.SeekStatus, error) {
+	if t.entry.termsDictSize == 0 {
+		return index.SEEK_STATUS_END, nil
+	}
+
+	block, err := t.seekBlock(ctx, text)
+	if err != nil {
+		return 0, err
+	}
+	if block == -1 {
+		// before the first term
+		if err := t.SeekExactByOrd(ctx, 0); err != nil {
+			return 0, err
+		}
+		return index.SEEK_STATUS_NOT_FOUND, nil
+	}
+
+	blockAddress, err := t.blockAddresses.Get(block)
+	if err != nil {
+		return 0, err
+	}
+	t.ord = block << t.entry.termsDictBlockShift
+	if _, err := t.bytes.Seek(int64(blockAddress), io.SeekStart); err != nil {
+		return 0, err
+	}
+	if err := t.readBlockStart(ctx); err != nil {
+		return 0, err
+	}
+
+	for {
+		cmp := bytes.Compare(t.term, text)
+		if cmp == 0 {
+			return index.SEEK_STATUS_FOUND, nil
+		} else if cmp > 0 {
+			return index.SEEK_STATUS_NOT_FOUND, nil
+		}
+
+		term, err := t.Next(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if term == nil {
+			return index.SEEK_STATUS_END, nil
+		}
+	}
+}
+
+// lookupOrd returns the term of the given ord
+func (t *termsDict) lookupOrd(ord int64) ([]byte, error) {
+	if err := t.SeekExactByOrd(context.Background(), ord); err != nil {
+		return nil, err
+	}
+	return t.term, nil
+}
+
+// lookupTerm returns the ord of key if it exists, or -insertionPoint-1 otherwise
+func (t *termsDict) lookupTerm(key []byte) (int64, error) {
+	status, err := t.SeekCeil(context.Background(), key)
+	if err != nil {
+		return 0, err
+	}
+	switch status {
+	case index.SEEK_STATUS_FOUND:
+		return t.ord, nil
+	case index.SEEK_STATUS_NOT_FOUND:
+		return -1 - t.ord, nil
+	default:
+		return -1 - t.entry.termsDictSize, nil
+	}
+}
+
+func (t *termsDict) Term() ([]byte, error) {
+	return t.term, nil
+}
+
+func (t *termsDict) Ord() (int64, error) {
+	return t.ord, nil
+}
+
+func (t *termsDict) DocFreq() (int, error) {
+	return 0, coreIndex.ErrUnsupportedOperation
+}
+
+func (t *termsDict) TotalTermFreq() (int64, error) {
+	return 0, coreIndex.ErrUnsupportedOperation
+}
+
+func (t *termsDict) Postings(reuse index.PostingsEnum, flags int) (index.PostingsEnum, error) {
+	return nil, coreIndex.ErrUnsupportedOperation
+}
+
+func (t *termsDict) Impacts(flags int) (index.ImpactsEnum, error) {
+	return nil, coreIndex.ErrUnsupportedOperation
+}
@@ -0,0 +1,71 @@
+package lucene84
+
+import (
+	"fmt"
+
+	"github.com/geange/lucene-go/core/store"
+)
+
+// ForDeltaUtil Utility class to encode/decode increasing sequences of 128 integers.
+type ForDeltaUtil struct {
+	forUtil *ForUtil
+}
+
+func NewForDeltaUtil(forUtil *ForUtil) *ForDeltaUtil {
+	return &ForDeltaUtil{forUtil: forUtil}
+}
+
+// EncodeDeltas Encode deltas of a strictly monotonically increasing sequence of integers.
+// The provided longs are expected to be deltas between consecutive values.
+func (f *ForDeltaUtil) EncodeDeltas(longs []uint64, out store.DataOutput) error {
+	if longs[0] == 1 && allEqual(longs) {
+		// happens with very dense postings
+		return out.WriteByte(0)
+	}
+
+	or := uint64(0)
+	for _, l := range longs[:BLOCK_SIZE] {
+		or |= l
+	}
+	if or == 0 {
+		return fmt.Errorf("deltas must be positive")
+	}
+	bitsPerValue := bitsRequired(or)
+	if err := out.WriteByte(byte(bitsPerValue)); err != nil {
+		return err
+	}
+	return f.forUtil.Encode(longs, bitsPerValue, out)
+}
+
+// DecodeAndPrefixSum Decode deltas, compute the prefix sum and add base to all decoded longs.
+func (f *ForDeltaUtil) DecodeAndPrefixSum(in store.DataInput, base uint64, longs []uint64) error {
+	b, err := in.ReadByte()
+	if err != nil {
+		return err
+	}
+	bitsPerValue := int(b)
+	if bitsPerValue == 0 {
+		prefixSumOfOnes(longs, base)
+		return nil
+	}
+	return f.forUtil.DecodeAndPrefixSum(bitsPerValue, in, base, longs)
+}
+
+// Skip Skip a sequence of 128 longs.
+func (f *ForDeltaUtil) Skip(in store.DataInput) error {
+	b, err := in.ReadByte()
+	if err != nil {
+		return err
+	}
+	bitsPerValue := int(b)
+	if bitsPerValue == 0 {
+		return nil
+	}
+	return in.SkipBytes(nil, NumBytes(bitsPerValue))
+}
+
+func prefixSumOfOnes(longs []uint64, base uint64) {
+	for i := 0; i < BLOCK_SIZE; i++ {
+		longs[i] = base + uint64(i) + 1
+	}
+}
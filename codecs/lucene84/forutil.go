@@ -0,0 +1,306 @@
+package lucene84
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/geange/lucene-go/core/store"
+)
+
+const (
+	BLOCK_SIZE      = 128
+	BLOCK_SIZE_LOG2 = 7
+)
+
+var (
+	masks8  [9]uint64
+	masks16 [17]uint64
+	masks32 [33]uint64
+)
+
+func init() {
+	for i := 0; i <= 8; i++ {
+		masks8[i] = mask8(i)
+	}
+	for i := 0; i <= 16; i++ {
+		masks16[i] = mask16(i)
+	}
+	for i := 0; i <= 32; i++ {
+		masks32[i] = mask32(i)
+	}
+}
+
+func expandMask32(mask32 uint64) uint64 {
+	return mask32 | (mask32 << 32)
+}
+
+func expandMask16(mask16 uint64) uint64 {
+	return expandMask32(mask16 | (mask16 << 16))
+}
+
+func expandMask8(mask8 uint64) uint64 {
+	return expandMask16(mask8 | (mask8 << 8))
+}
+
+func mask32(bitsPerValue int) uint64 {
+	return expandMask32((uint64(1) << bitsPerValue) - 1)
+}
+
+func mask16(bitsPerValue int) uint64 {
+	return expandMask16((uint64(1) << bitsPerValue) - 1)
+}
+
+func mask8(bitsPerValue int) uint64 {
+	return expandMask8((uint64(1) << bitsPerValue) - 1)
+}
+
+// ForUtil Encodes multiple integers in a long to get SIMD-like speedups.
+// If bitsPerValue <= 8 then we pack 8 ints per long else if bitsPerValue <= 16 we pack 4 ints per long
+// else we pack 2 ints per long
+type ForUtil struct {
+	tmp     [BLOCK_SIZE / 2]uint64
+	scratch [BLOCK_SIZE]uint64
+	bytes   [8 * BLOCK_SIZE / 2]byte
+}
+
+func NewForUtil() *ForUtil {
+	return &ForUtil{}
+}
+
+// NumBytes Number of bytes required to encode 128 integers of bitsPerValue bits per value.
+func NumBytes(bitsPerValue int) int {
+	return bitsPerValue << (BLOCK_SIZE_LOG2 - 3)
+}
+
+// primitiveSize Returns the lane width (8, 16 or 32 bits) used to pack values of the given width.
+func primitiveSize(bitsPerValue int) int {
+	switch {
+	case bitsPerValue <= 8:
+		return 8
+	case bitsPerValue <= 16:
+		return 16
+	default:
+		return 32
+	}
+}
+
+func masksOf(primitive int) []uint64 {
+	switch primitive {
+	case 8:
+		return masks8[:]
+	case 16:
+		return masks16[:]
+	default:
+		return masks32[:]
+	}
+}
+
+// Encode 128 integers from longs into out. The input slice is left untouched.
+func (f *ForUtil) Encode(longs []uint64, bitsPerValue int, out store.DataOutput) error {
+	values := f.scratch[:]
+	copy(values, longs[:BLOCK_SIZE])
+
+	primitive := primitiveSize(bitsPerValue)
+	switch primitive {
+	case 8:
+		collapse8(values)
+	case 16:
+		collapse16(values)
+	default:
+		collapse32(values)
+	}
+	masks := masksOf(primitive)
+
+	numLongs := BLOCK_SIZE * primitive / 64
+	numLongsPerShift := bitsPerValue * 2
+	tmp := f.tmp[:]
+	idx := 0
+	shift := primitive - bitsPerValue
+	for i := 0; i < numLongsPerShift; i++ {
+		tmp[i] = values[idx] << shift
+		idx++
+	}
+	for shift = shift - bitsPerValue; shift >= 0; shift -= bitsPerValue {
+		for i := 0; i < numLongsPerShift; i++ {
+			tmp[i] |= values[idx] << shift
+			idx++
+		}
+	}
+
+	remainingBitsPerLong := shift + bitsPerValue
+	maskRemainingBitsPerLong := masks[remainingBitsPerLong]
+
+	tmpIdx := 0
+	remainingBitsPerValue := bitsPerValue
+	for idx < numLongs {
+		if remainingBitsPerValue >= remainingBitsPerLong {
+			remainingBitsPerValue -= remainingBitsPerLong
+			tmp[tmpIdx] |= (values[idx] >> remainingBitsPerValue) & maskRemainingBitsPerLong
+			tmpIdx++
+			if remainingBitsPerValue == 0 {
+				idx++
+				remainingBitsPerValue = bitsPerValue
+			}
+		} else {
+			mask1 := masks[remainingBitsPerValue]
+			mask2 := masks[remainingBitsPerLong-remainingBitsPerValue]
+			tmp[tmpIdx] |= (values[idx] & mask1) << (remainingBitsPerLong - remainingBitsPerValue)
+			idx++
+			remainingBitsPerValue = bitsPerValue - remainingBitsPerLong + remainingBitsPerValue
+			tmp[tmpIdx] |= (values[idx] >> remainingBitsPerValue) & mask2
+			tmpIdx++
+		}
+	}
+
+	buf := f.bytes[:8*numLongsPerShift]
+	for i := 0; i < numLongsPerShift; i++ {
+		// Java writes Long.reverseBytes(tmp[i]), i.e. the long in little-endian byte order
+		binary.LittleEndian.PutUint64(buf[i*8:], tmp[i])
+	}
+	_, err := out.Write(buf)
+	return err
+}
+
+// Decode Decode 128 integers into longs.
+func (f *ForUtil) Decode(bitsPerValue int, in store.DataInput, longs []uint64) error {
+	numLongsPerShift := bitsPerValue * 2
+	tmp := f.tmp[:numLongsPerShift]
+	if err := f.readLELongs(in, tmp); err != nil {
+		return err
+	}
+
+	primitive := primitiveSize(bitsPerValue)
+	masks := masksOf(primitive)
+	numLongs := BLOCK_SIZE * primitive / 64
+	mask := masks[bitsPerValue]
+
+	idx := 0
+	shift := primitive - bitsPerValue
+	for ; shift >= 0; shift -= bitsPerValue {
+		for i := 0; i < numLongsPerShift; i++ {
+			longs[idx] = (tmp[i] >> shift) & mask
+			idx++
+		}
+	}
+
+	remainingBitsPerLong := shift + bitsPerValue
+	maskRemainingBitsPerLong := masks[remainingBitsPerLong]
+	tmpIdx := 0
+	remainingBits := remainingBitsPerLong
+	for ; idx < numLongs; idx++ {
+		b := bitsPerValue - remainingBits
+		l := (tmp[tmpIdx] & masks[remainingBits]) << b
+		tmpIdx++
+		for b >= remainingBitsPerLong {
+			b -= remainingBitsPerLong
+			l |= (tmp[tmpIdx] & maskRemainingBitsPerLong) << b
+			tmpIdx++
+		}
+		if b > 0 {
+			l |= (tmp[tmpIdx] >> (remainingBitsPerLong - b)) & masks[b]
+			remainingBits = remainingBitsPerLong - b
+		} else {
+			remainingBits = remainingBitsPerLong
+		}
+		longs[idx] = l
+	}
+
+	switch primitive {
+	case 8:
+		expand8(longs)
+	case 16:
+		expand16(longs)
+	default:
+		expand32(longs)
+	}
+	return nil
+}
+
+// DecodeAndPrefixSum Delta-decode 128 integers into longs.
+func (f *ForUtil) DecodeAndPrefixSum(bitsPerValue int, in store.DataInput, base uint64, longs []uint64) error {
+	if err := f.Decode(bitsPerValue, in, longs); err != nil {
+		return err
+	}
+	prefixSum(longs, base)
+	return nil
+}
+
+// Skip Skip 128 integers.
+func (f *ForUtil) Skip(bitsPerValue int, in store.DataInput) error {
+	return in.SkipBytes(nil, NumBytes(bitsPerValue))
+}
+
+func (f *ForUtil) readLELongs(in store.DataInput, dst []uint64) error {
+	buf := f.bytes[:8*len(dst)]
+	if _, err := io.ReadFull(in, buf); err != nil {
+		return err
+	}
+	for i := range dst {
+		dst[i] = binary.LittleEndian.Uint64(buf[i*8:])
+	}
+	return nil
+}
+
+func prefixSum(longs []uint64, base uint64) {
+	longs[0] += base
+	for i := 1; i < BLOCK_SIZE; i++ {
+		longs[i] += longs[i-1]
+	}
+}
+
+func expand8(arr []uint64) {
+	for i := 0; i < 16; i++ {
+		l := arr[i]
+		arr[i] = (l >> 56) & 0xFF
+		arr[16+i] = (l >> 48) & 0xFF
+		arr[32+i] = (l >> 40) & 0xFF
+		arr[48+i] = (l >> 32) & 0xFF
+		arr[64+i] = (l >> 24) & 0xFF
+		arr[80+i] = (l >> 16) & 0xFF
+		arr[96+i] = (l >> 8) & 0xFF
+		arr[112+i] = l & 0xFF
+	}
+}
+
+func collapse8(arr []uint64) {
+	for i := 0; i < 16; i++ {
+		arr[i] = (arr[i] << 56) |
+			(arr[16+i] << 48) |
+			(arr[32+i] << 40) |
+			(arr[48+i] << 32) |
+			(arr[64+i] << 24) |
+			(arr[80+i] << 16) |
+			(arr[96+i] << 8) |
+			arr[112+i]
+	}
+}
+
+func expand16(arr []uint64) {
+	for i := 0; i < 32; i++ {
+		l := arr[i]
+		arr[i] = (l >> 48) & 0xFFFF
+		arr[32+i] = (l >> 32) & 0xFFFF
+		arr[64+i] = (l >> 16) & 0xFFFF
+		arr[96+i] = l & 0xFFFF
+	}
+}
+
+func collapse16(arr []uint64) {
+	for i := 0; i < 32; i++ {
+		arr[i] = (arr[i] << 48) | (arr[32+i] << 32) | (arr[64+i] << 16) | arr[96+i]
+	}
+}
+
+func expand32(arr []uint64) {
+	for i := 0; i < 64; i++ {
+		l := arr[i]
+		arr[i] = l >> 32
+		arr[64+i] = l & 0xFFFFFFFF
+	}
+}
+
+func collapse32(arr []uint64) {
+	for i := 0; i < 64; i++ {
+		arr[i] = (arr[i] << 32) | arr[64+i]
+	}
+}
@@ -0,0 +1,113 @@
+package lucene84
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/geange/lucene-go/core/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForUtil(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	forUtil := NewForUtil()
+
+	for bpv := 1; bpv <= 31; bpv++ {
+		values := make([]uint64, BLOCK_SIZE)
+		for i := range values {
+			values[i] = uint64(r.Int63n(int64(1) << bpv))
+		}
+
+		out := store.NewBufferDataOutput()
+		err := forUtil.Encode(values, bpv, out)
+		assert.Nil(t, err)
+		assert.Equal(t, NumBytes(bpv), len(out.Bytes()))
+
+		decoded := make([]uint64, BLOCK_SIZE)
+		err = forUtil.Decode(bpv, store.NewBytesInput(out.Bytes()), decoded)
+		assert.Nil(t, err)
+		assert.Equal(t, values, decoded, "bpv=%d", bpv)
+	}
+}
+
+func TestPForUtil(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	pforUtil := NewPForUtil(NewForUtil())
+
+	cases := map[string]func(i int) uint64{
+		"allEqual": func(i int) uint64 { return 3 },
+		"small":    func(i int) uint64 { return uint64(r.Intn(16)) },
+		"outliers": func(i int) uint64 {
+			v := uint64(r.Intn(4))
+			if i%40 == 0 {
+				v = 1 << 10
+			}
+			return v
+		},
+		"large": func(i int) uint64 { return uint64(r.Int63n(1 << 20)) },
+		"equalWith": func(i int) uint64 {
+			if i == 5 {
+				return 200
+			}
+			return 1
+		},
+	}
+
+	for name, gen := range cases {
+		t.Run(name, func(t *testing.T) {
+			values := make([]uint64, BLOCK_SIZE)
+			for i := range values {
+				values[i] = gen(i)
+			}
+
+			out := store.NewBufferDataOutput()
+			assert.Nil(t, pforUtil.Encode(values, out))
+			assert.Nil(t, out.WriteByte(42))
+
+			in := store.NewBytesInput(out.Bytes())
+			decoded := make([]uint64, BLOCK_SIZE)
+			assert.Nil(t, pforUtil.Decode(in, decoded))
+			assert.Equal(t, values, decoded)
+			b, err := in.ReadByte()
+			assert.Nil(t, err)
+			assert.Equal(t, byte(42), b)
+
+			in = store.NewBytesInput(out.Bytes())
+			assert.Nil(t, pforUtil.Skip(in))
+			b, err = in.ReadByte()
+			assert.Nil(t, err)
+			assert.Equal(t, byte(42), b)
+		})
+	}
+}
+
+func TestForDeltaUtil(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	forDeltaUtil := NewForDeltaUtil(NewForUtil())
+
+	for _, dense := range []bool{true, false} {
+		deltas := make([]uint64, BLOCK_SIZE)
+		expected := make([]uint64, BLOCK_SIZE)
+		base := uint64(100)
+		prev := base
+		for i := range deltas {
+			deltas[i] = 1
+			if !dense {
+				deltas[i] = uint64(1 + r.Intn(1000))
+			}
+			prev += deltas[i]
+			expected[i] = prev
+		}
+
+		out := store.NewBufferDataOutput()
+		assert.Nil(t, forDeltaUtil.EncodeDeltas(deltas, out))
+
+		decoded := make([]uint64, BLOCK_SIZE)
+		assert.Nil(t, forDeltaUtil.DecodeAndPrefixSum(store.NewBytesInput(out.Bytes()), base, decoded))
+		assert.Equal(t, expected, decoded)
+
+		in := store.NewBytesInput(out.Bytes())
+		assert.Nil(t, forDeltaUtil.Skip(in))
+		assert.Equal(t, int64(len(out.Bytes())), in.GetFilePointer())
+	}
+}
@@ -0,0 +1,157 @@
+package lucene84
+
+import (
+	"io"
+	"math/bits"
+
+	"github.com/geange/lucene-go/core/store"
+)
+
+const (
+	pforMaxExceptions = 7
+)
+
+// PForUtil Utility class to encode sequences of 128 small positive integers.
+type PForUtil struct {
+	forUtil *ForUtil
+	values  [BLOCK_SIZE]uint64
+}
+
+func NewPForUtil(forUtil *ForUtil) *PForUtil {
+	return &PForUtil{forUtil: forUtil}
+}
+
+// Encode 128 integers from longs into out.
+func (p *PForUtil) Encode(longs []uint64, out store.DataOutput) error {
+	// Determine the top MAX_EXCEPTIONS + 1 values
+	top := make([]uint64, pforMaxExceptions+1)
+	for i := 0; i < BLOCK_SIZE; i++ {
+		v := longs[i]
+		if v <= top[0] {
+			continue
+		}
+		// keep top sorted ascending, drop the smallest
+		j := 1
+		for j < len(top) && top[j] < v {
+			top[j-1] = top[j]
+			j++
+		}
+		top[j-1] = v
+	}
+
+	topValue := top[0]
+	maxValue := top[len(top)-1]
+	maxBitsRequired := bitsRequired(maxValue)
+	// We store the patch on a byte, so we can't decrease the number of bits required by more than 8
+	patchedBitsRequired := max(bitsRequired(topValue), maxBitsRequired-8)
+	numExceptions := 0
+	maxUnpatchedValue := (uint64(1) << patchedBitsRequired) - 1
+	for i := 1; i < len(top); i++ {
+		if top[i] > maxUnpatchedValue {
+			numExceptions++
+		}
+	}
+
+	exceptions := make([]byte, numExceptions*2)
+	values := p.values[:]
+	copy(values, longs[:BLOCK_SIZE])
+	if numExceptions > 0 {
+		exceptionCount := 0
+		for i := 0; i < BLOCK_SIZE; i++ {
+			if values[i] > maxUnpatchedValue {
+				exceptions[exceptionCount*2] = byte(i)
+				exceptions[exceptionCount*2+1] = byte(values[i] >> patchedBitsRequired)
+				values[i] &= maxUnpatchedValue
+				exceptionCount++
+			}
+		}
+	}
+
+	if allEqual(values) && maxBitsRequired <= 8 {
+		for i := 0; i < numExceptions; i++ {
+			exceptions[2*i+1] = byte(uint64(exceptions[2*i+1]) << patchedBitsRequired)
+		}
+		if err := out.WriteByte(byte(numExceptions << 5)); err != nil {
+			return err
+		}
+		if err := out.WriteUvarint(nil, values[0]); err != nil {
+			return err
+		}
+	} else {
+		token := numExceptions<<5 | patchedBitsRequired
+		if err := out.WriteByte(byte(token)); err != nil {
+			return err
+		}
+		if err := p.forUtil.Encode(values, patchedBitsRequired, out); err != nil {
+			return err
+		}
+	}
+	_, err := out.Write(exceptions)
+	return err
+}
+
+// Decode 128 integers into longs.
+func (p *PForUtil) Decode(in store.DataInput, longs []uint64) error {
+	token, err := in.ReadByte()
+	if err != nil {
+		return err
+	}
+	bitsPerValue := int(token & 0x1f)
+	numExceptions := int(token >> 5)
+	if bitsPerValue == 0 {
+		v, err := in.ReadUvarint(nil)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < BLOCK_SIZE; i++ {
+			longs[i] = v
+		}
+	} else {
+		if err := p.forUtil.Decode(bitsPerValue, in, longs); err != nil {
+			return err
+		}
+	}
+
+	if numExceptions == 0 {
+		return nil
+	}
+	exceptions := make([]byte, numExceptions*2)
+	if _, err := io.ReadFull(in, exceptions); err != nil {
+		return err
+	}
+	for i := 0; i < numExceptions; i++ {
+		longs[exceptions[i*2]] |= uint64(exceptions[i*2+1]) << bitsPerValue
+	}
+	return nil
+}
+
+// Skip 128 integers.
+func (p *PForUtil) Skip(in store.DataInput) error {
+	token, err := in.ReadByte()
+	if err != nil {
+		return err
+	}
+	bitsPerValue := int(token & 0x1f)
+	numExceptions := int(token >> 5)
+	if bitsPerValue == 0 {
+		if _, err := in.ReadUvarint(nil); err != nil {
+			return err
+		}
+		return in.SkipBytes(nil, numExceptions<<1)
+	}
+	return in.SkipBytes(nil, NumBytes(bitsPerValue)+(numExceptions<<1))
+}
+
+func allEqual(values []uint64) bool {
+	for i := 1; i < BLOCK_SIZE; i++ {
+		if values[i] != values[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// bitsRequired same as PackedInts.unsignedBitsRequired, returns at least 1.
+func bitsRequired(v uint64) int {
+	return max(1, 64-bits.LeadingZeros64(v))
+}
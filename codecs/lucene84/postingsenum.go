@@ -0,0 +1,889 @@
+package lucene84
+
+import (
+	"io"
+
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+)
+
+var _ index.PostingsEnum = &BlockDocsEnum{}
+
+// BlockDocsEnum Iterates over doc ids and frequencies, without positions.
+type BlockDocsEnum struct {
+	forDeltaUtil *ForDeltaUtil
+	pforUtil     *PForUtil
+
+	docBuffer     [BLOCK_SIZE + 1]uint64
+	freqBuffer    [BLOCK_SIZE]uint64
+	docBufferUpto int
+
+	skipper *SkipReader
+	skipped bool
+
+	startDocIn store.IndexInput
+	docIn      store.IndexInput
+
+	indexHasFreq     bool
+	indexHasPos      bool
+	indexHasOffsets  bool
+	indexHasPayloads bool
+
+	docFreq       int    // number of docs in this posting list
+	totalTermFreq int64  // sum of freqBuffer in this posting list (or docFreq when omitted)
+	docUpto       int    // how many docs we've read
+	doc           int    // doc we last read
+	accum         uint64 // accumulator for doc deltas
+
+	// Where this term's postings start in the .doc file:
+	docTermStartFP int64
+
+	// Where this term's skip data starts (after docTermStartFP) in the .doc file (or -1 if it has no skip data):
+	skipOffset int64
+
+	// docID for next skip point, we won't use skipper if target docID is not larger than this
+	nextSkipDoc int
+
+	needsFreq bool // true if the caller actually needs frequencies
+
+	// as we read freqBuffer lazily, isFreqsRead shows if freqBuffer are read for the current block
+	// always true when we don't have freqBuffer (indexHasFreq=false) or don't need freqBuffer
+	// (needsFreq=false)
+	isFreqsRead    bool
+	singletonDocID int // docid when there is a single pulsed posting, otherwise -1
+}
+
+func (p *PostingsReader) newBlockDocsEnum(fieldInfo *document.FieldInfo) *BlockDocsEnum {
+	indexOptions := fieldInfo.GetIndexOptions()
+	forUtil := NewForUtil()
+	enum := &BlockDocsEnum{
+		forDeltaUtil:     NewForDeltaUtil(forUtil),
+		pforUtil:         NewPForUtil(forUtil),
+		startDocIn:       p.docIn,
+		indexHasFreq:     indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS,
+		indexHasPos:      indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS,
+		indexHasOffsets:  indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS,
+		indexHasPayloads: fieldInfo.HasPayloads(),
+		doc:              -1,
+	}
+	// We set the last element of docBuffer to NO_MORE_DOCS, it helps save conditionals in advance()
+	enum.docBuffer[BLOCK_SIZE] = types.NO_MORE_DOCS
+	return enum
+}
+
+func (b *BlockDocsEnum) canReuse(docIn store.IndexInput, fieldInfo *document.FieldInfo) bool {
+	indexOptions := fieldInfo.GetIndexOptions()
+	return docIn == b.startDocIn &&
+		b.indexHasFreq == (indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS) &&
+		b.indexHasPos == (indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS) &&
+		b.indexHasPayloads == fieldInfo.HasPayloads()
+}
+
+func (b *BlockDocsEnum) reset(termState *IntBlockTermState, flags int) error {
+	b.docFreq = termState.DocFreq
+	b.totalTermFreq = int64(b.docFreq)
+	if b.indexHasFreq {
+		b.totalTermFreq = termState.TotalTermFreq
+	}
+	b.docTermStartFP = termState.DocStartFP
+	b.skipOffset = termState.SkipOffset
+	b.singletonDocID = termState.SingletonDocID
+	if b.docFreq > 1 {
+		if b.docIn == nil {
+			// lazy init
+			b.docIn = b.startDocIn.Clone().(store.IndexInput)
+		}
+		if _, err := b.docIn.Seek(b.docTermStartFP, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	b.doc = -1
+	b.needsFreq = coreIndex.FeatureRequested(flags, coreIndex.POSTINGS_ENUM_FREQS)
+	b.isFreqsRead = true
+	if !b.indexHasFreq || !b.needsFreq {
+		for i := range b.freqBuffer {
+			b.freqBuffer[i] = 1
+		}
+	}
+	b.accum = 0
+	b.docUpto = 0
+	b.nextSkipDoc = BLOCK_SIZE - 1 // we won't skip if target is found in first block
+	b.docBufferUpto = BLOCK_SIZE
+	b.skipped = false
+	return nil
+}
+
+func (b *BlockDocsEnum) Freq() (int, error) {
+	if !b.isFreqsRead {
+		// read freqBuffer for this block
+		if err := b.pforUtil.Decode(b.docIn, b.freqBuffer[:]); err != nil {
+			return 0, err
+		}
+		b.isFreqsRead = true
+	}
+	return int(b.freqBuffer[b.docBufferUpto-1]), nil
+}
+
+func (b *BlockDocsEnum) NextPosition() (int, error) {
+	return -1, nil
+}
+
+func (b *BlockDocsEnum) StartOffset() (int, error) {
+	return -1, nil
+}
+
+func (b *BlockDocsEnum) EndOffset() (int, error) {
+	return -1, nil
+}
+
+func (b *BlockDocsEnum) GetPayload() ([]byte, error) {
+	return nil, nil
+}
+
+func (b *BlockDocsEnum) DocID() int {
+	return b.doc
+}
+
+func (b *BlockDocsEnum) refillDocs() error {
+	// Check if we skipped reading the previous block of freqBuffer, and if yes, position docIn after it
+	if !b.isFreqsRead {
+		if err := b.pforUtil.Skip(b.docIn); err != nil {
+			return err
+		}
+		b.isFreqsRead = true
+	}
+
+	left := b.docFreq - b.docUpto
+	switch {
+	case left >= BLOCK_SIZE:
+		if err := b.forDeltaUtil.DecodeAndPrefixSum(b.docIn, b.accum, b.docBuffer[:]); err != nil {
+			return err
+		}
+		if b.indexHasFreq {
+			if b.needsFreq {
+				b.isFreqsRead = false
+			} else {
+				// skip over freqBuffer if we don't need them at all
+				if err := b.pforUtil.Skip(b.docIn); err != nil {
+					return err
+				}
+			}
+		}
+		b.docUpto += BLOCK_SIZE
+	case b.docFreq == 1:
+		b.docBuffer[0] = uint64(b.singletonDocID)
+		b.freqBuffer[0] = uint64(b.totalTermFreq)
+		b.docBuffer[1] = types.NO_MORE_DOCS
+		b.docUpto++
+	default:
+		// Read vInts:
+		if err := readVIntBlock(b.docIn, b.docBuffer[:], b.freqBuffer[:], left, b.indexHasFreq); err != nil {
+			return err
+		}
+		prefixSumN(b.docBuffer[:], left, b.accum)
+		b.docBuffer[left] = types.NO_MORE_DOCS
+		b.docUpto += left
+	}
+	b.accum = b.docBuffer[BLOCK_SIZE-1]
+	b.docBufferUpto = 0
+	return nil
+}
+
+func (b *BlockDocsEnum) NextDoc() (int, error) {
+	if b.doc == types.NO_MORE_DOCS {
+		return b.doc, nil
+	}
+	if b.docBufferUpto == BLOCK_SIZE && b.docUpto < b.docFreq {
+		// we don't need to load freqBuffer for now (will be loaded later if necessary)
+		if err := b.refillDocs(); err != nil {
+			return 0, err
+		}
+	}
+
+	b.doc = int(b.docBuffer[b.docBufferUpto])
+	b.docBufferUpto++
+	return b.doc, nil
+}
+
+func (b *BlockDocsEnum) Advance(target int) (int, error) {
+	// current skip docID < docIDs generated from current buffer <= next skip docID
+	// we don't need to skip if target is buffered already
+	if b.docFreq > BLOCK_SIZE && target > b.nextSkipDoc {
+		if b.skipper == nil {
+			// Lazy init: first time this enum has ever been used for skipping
+			b.skipper = NewSkipReader(b.docIn.Clone().(store.IndexInput), MAX_SKIP_LEVELS,
+				b.indexHasPos, b.indexHasOffsets, b.indexHasPayloads)
+		}
+
+		if !b.skipped {
+			// This is the first time this enum has skipped
+			// since reset() was called; load the skip data:
+			if err := b.skipper.InitV1(b.docTermStartFP+b.skipOffset, b.docTermStartFP, 0, 0, b.docFreq); err != nil {
+				return 0, err
+			}
+			b.skipped = true
+		}
+
+		// always plus one to fix the result, since skip position in Lucene84SkipReader
+		// is a little different from MultiLevelSkipListReader
+		upto, err := b.skipper.SkipTo(target)
+		if err != nil {
+			return 0, err
+		}
+		newDocUpto := upto + 1
+
+		if newDocUpto >= b.docUpto {
+			// Skipper moved
+			b.docUpto = newDocUpto
+
+			// Force to read next block
+			b.docBufferUpto = BLOCK_SIZE
+			b.accum = uint64(b.skipper.GetDoc()) // actually, this is just lastSkipEntry
+			// now point to the block we want to search
+			if _, err := b.docIn.Seek(b.skipper.GetDocPointer(), io.SeekStart); err != nil {
+				return 0, err
+			}
+			// even if freqBuffer were not read from the previous block, we will mark them as read,
+			// as we don't need to skip the previous block freqBuffer in refillDocs,
+			// as we have already positioned docIn where in needs to be.
+			b.isFreqsRead = true
+		}
+		// next time we call advance, this is used to
+		// foresee whether skipper is necessary.
+		b.nextSkipDoc = b.skipper.GetNextSkipDoc()
+	}
+	if b.docBufferUpto == BLOCK_SIZE && b.docUpto < b.docFreq {
+		if err := b.refillDocs(); err != nil {
+			return 0, err
+		}
+	}
+
+	// Now scan... this is an inlined/pared down version
+	// of nextDoc():
+	for {
+		doc := b.docBuffer[b.docBufferUpto]
+		b.docBufferUpto++
+		if doc >= uint64(target) {
+			b.doc = int(doc)
+			return b.doc, nil
+		}
+	}
+}
+
+func (b *BlockDocsEnum) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(b, target)
+}
+
+func (b *BlockDocsEnum) Cost() int64 {
+	return int64(b.docFreq)
+}
+
+var _ index.PostingsEnum = &EverythingEnum{}
+
+// EverythingEnum Also handles payloads + offsets
+type EverythingEnum struct {
+	forDeltaUtil *ForDeltaUtil
+	pforUtil     *PForUtil
+
+	docBuffer              [BLOCK_SIZE + 1]uint64
+	freqBuffer             [BLOCK_SIZE + 1]uint64
+	posDeltaBuffer         [BLOCK_SIZE]uint64
+	payloadLengthBuffer    []uint64
+	offsetStartDeltaBuffer []uint64
+	offsetLengthBuffer     []uint64
+
+	payloadBytes    []byte
+	payloadByteUpto int
+	payloadLength   int
+	payload         []byte
+
+	lastStartOffset int
+	startOffset     int
+	endOffset       int
+
+	docBufferUpto int
+	posBufferUpto int
+
+	skipper *SkipReader
+	skipped bool
+
+	startDocIn store.IndexInput
+	docIn      store.IndexInput
+	posIn      store.IndexInput
+	payIn      store.IndexInput
+
+	indexHasOffsets  bool
+	indexHasPayloads bool
+
+	docFreq       int    // number of docs in this posting list
+	totalTermFreq int64  // number of positions in this posting list
+	docUpto       int    // how many docs we've read
+	doc           int    // doc we last read
+	accum         uint64 // accumulator for doc deltas
+	freq          int    // freq we last read
+	position      int    // current position
+
+	// how many positions "behind" we are; nextPosition must
+	// skip these to "catch up":
+	posPendingCount int
+
+	// Lazy pos seek: if != -1 then we must seek to this FP
+	// before reading positions:
+	posPendingFP int64
+
+	// Lazy pay seek: if != -1 then we must seek to this FP
+	// before reading payloads/offsets:
+	payPendingFP int64
+
+	// Where this term's postings start in the .doc file:
+	docTermStartFP int64
+
+	// Where this term's postings start in the .pos file:
+	posTermStartFP int64
+
+	// Where this term's payloads/offsets start in the .pay file:
+	payTermStartFP int64
+
+	// File pointer where the last (vInt encoded) pos delta
+	// block is.  We need this to know whether to bulk
+	// decode vs vInt decode the block:
+	lastPosBlockFP int64
+
+	// Where this term's skip data starts (after
+	// docTermStartFP) in the .doc file (or -1 if it has
+	// no skip data):
+	skipOffset int64
+
+	nextSkipDoc int
+
+	needsOffsets   bool // true if we actually need offsets
+	needsPayloads  bool // true if we actually need payloads
+	singletonDocID int  // docid when there is a single pulsed posting, otherwise -1
+}
+
+func (p *PostingsReader) newEverythingEnum(fieldInfo *document.FieldInfo) *EverythingEnum {
+	indexOptions := fieldInfo.GetIndexOptions()
+	forUtil := NewForUtil()
+	enum := &EverythingEnum{
+		forDeltaUtil:     NewForDeltaUtil(forUtil),
+		pforUtil:         NewPForUtil(forUtil),
+		startDocIn:       p.docIn,
+		posIn:            p.posIn.Clone().(store.IndexInput),
+		indexHasOffsets:  indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS,
+		indexHasPayloads: fieldInfo.HasPayloads(),
+		doc:              -1,
+		startOffset:      -1,
+		endOffset:        -1,
+	}
+	if enum.indexHasOffsets || enum.indexHasPayloads {
+		enum.payIn = p.payIn.Clone().(store.IndexInput)
+	}
+	if enum.indexHasOffsets {
+		enum.offsetStartDeltaBuffer = make([]uint64, BLOCK_SIZE)
+		enum.offsetLengthBuffer = make([]uint64, BLOCK_SIZE)
+	}
+	if enum.indexHasPayloads {
+		enum.payloadLengthBuffer = make([]uint64, BLOCK_SIZE)
+		enum.payloadBytes = make([]byte, 128)
+	}
+
+	// We set the last element of docBuffer to NO_MORE_DOCS, it helps save conditionals in advance()
+	enum.docBuffer[BLOCK_SIZE] = types.NO_MORE_DOCS
+	return enum
+}
+
+func (e *EverythingEnum) canReuse(docIn store.IndexInput, fieldInfo *document.FieldInfo) bool {
+	return docIn == e.startDocIn &&
+		e.indexHasOffsets == (fieldInfo.GetIndexOptions() >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS) &&
+		e.indexHasPayloads == fieldInfo.HasPayloads()
+}
+
+func (e *EverythingEnum) reset(termState *IntBlockTermState, flags int) error {
+	e.docFreq = termState.DocFreq
+	e.docTermStartFP = termState.DocStartFP
+	e.posTermStartFP = termState.PosStartFP
+	e.payTermStartFP = termState.PayStartFP
+	e.skipOffset = termState.SkipOffset
+	e.totalTermFreq = termState.TotalTermFreq
+	e.singletonDocID = termState.SingletonDocID
+	if e.docFreq > 1 {
+		if e.docIn == nil {
+			// lazy init
+			e.docIn = e.startDocIn.Clone().(store.IndexInput)
+		}
+		if _, err := e.docIn.Seek(e.docTermStartFP, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	e.posPendingFP = e.posTermStartFP
+	e.payPendingFP = e.payTermStartFP
+	e.posPendingCount = 0
+	switch {
+	case termState.TotalTermFreq < BLOCK_SIZE:
+		e.lastPosBlockFP = e.posTermStartFP
+	case termState.TotalTermFreq == BLOCK_SIZE:
+		e.lastPosBlockFP = -1
+	default:
+		e.lastPosBlockFP = e.posTermStartFP + termState.LastPosBlockOffset
+	}
+
+	e.needsOffsets = coreIndex.FeatureRequested(flags, coreIndex.POSTINGS_ENUM_OFFSETS)
+	e.needsPayloads = coreIndex.FeatureRequested(flags, coreIndex.POSTINGS_ENUM_PAYLOADS)
+
+	e.doc = -1
+	e.accum = 0
+	e.docUpto = 0
+	if e.docFreq > BLOCK_SIZE {
+		e.nextSkipDoc = BLOCK_SIZE - 1 // we won't skip if target is found in first block
+	} else {
+		e.nextSkipDoc = types.NO_MORE_DOCS // not enough docs for skipping
+	}
+	e.docBufferUpto = BLOCK_SIZE
+	e.skipped = false
+	return nil
+}
+
+func (e *EverythingEnum) Freq() (int, error) {
+	return e.freq, nil
+}
+
+func (e *EverythingEnum) DocID() int {
+	return e.doc
+}
+
+func (e *EverythingEnum) refillDocs() error {
+	left := e.docFreq - e.docUpto
+
+	switch {
+	case left >= BLOCK_SIZE:
+		if err := e.forDeltaUtil.DecodeAndPrefixSum(e.docIn, e.accum, e.docBuffer[:]); err != nil {
+			return err
+		}
+		if err := e.pforUtil.Decode(e.docIn, e.freqBuffer[:]); err != nil {
+			return err
+		}
+		e.docUpto += BLOCK_SIZE
+	case e.docFreq == 1:
+		e.docBuffer[0] = uint64(e.singletonDocID)
+		e.freqBuffer[0] = uint64(e.totalTermFreq)
+		e.docBuffer[1] = types.NO_MORE_DOCS
+		e.docUpto++
+	default:
+		if err := readVIntBlock(e.docIn, e.docBuffer[:], e.freqBuffer[:], left, true); err != nil {
+			return err
+		}
+		prefixSumN(e.docBuffer[:], left, e.accum)
+		e.docBuffer[left] = types.NO_MORE_DOCS
+		e.docUpto += left
+	}
+	e.accum = e.docBuffer[BLOCK_SIZE-1]
+	e.docBufferUpto = 0
+	return nil
+}
+
+func (e *EverythingEnum) refillPositions() error {
+	if e.posIn.GetFilePointer() == e.lastPosBlockFP {
+		return e.readVIntPositions()
+	}
+
+	pforUtil := e.pforUtil
+	if err := pforUtil.Decode(e.posIn, e.posDeltaBuffer[:]); err != nil {
+		return err
+	}
+
+	if e.indexHasPayloads {
+		if e.needsPayloads {
+			if err := pforUtil.Decode(e.payIn, e.payloadLengthBuffer); err != nil {
+				return err
+			}
+			numBytes, err := e.payIn.ReadUvarint(nil)
+			if err != nil {
+				return err
+			}
+			if int(numBytes) > len(e.payloadBytes) {
+				e.payloadBytes = make([]byte, numBytes)
+			}
+			if _, err := io.ReadFull(e.payIn, e.payloadBytes[:numBytes]); err != nil {
+				return err
+			}
+		} else {
+			// this works, because when writing a vint block we always force the first length to be written
+			if err := pforUtil.Skip(e.payIn); err != nil { // skip over lengths
+				return err
+			}
+			numBytes, err := e.payIn.ReadUvarint(nil) // read length of payloadBytes
+			if err != nil {
+				return err
+			}
+			// skip over payloadBytes
+			if _, err := e.payIn.Seek(e.payIn.GetFilePointer()+int64(numBytes), io.SeekStart); err != nil {
+				return err
+			}
+		}
+		e.payloadByteUpto = 0
+	}
+
+	if e.indexHasOffsets {
+		if e.needsOffsets {
+			if err := pforUtil.Decode(e.payIn, e.offsetStartDeltaBuffer); err != nil {
+				return err
+			}
+			if err := pforUtil.Decode(e.payIn, e.offsetLengthBuffer); err != nil {
+				return err
+			}
+		} else {
+			// this works, because when writing a vint block we always force the first length to be written
+			if err := pforUtil.Skip(e.payIn); err != nil { // skip over starts
+				return err
+			}
+			if err := pforUtil.Skip(e.payIn); err != nil { // skip over lengths
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *EverythingEnum) readVIntPositions() error {
+	count := int(e.totalTermFreq % BLOCK_SIZE)
+	payloadLength := 0
+	offsetLength := 0
+	e.payloadByteUpto = 0
+	for i := 0; i < count; i++ {
+		code, err := e.posIn.ReadUvarint(nil)
+		if err != nil {
+			return err
+		}
+		if e.indexHasPayloads {
+			if code&1 != 0 {
+				length, err := e.posIn.ReadUvarint(nil)
+				if err != nil {
+					return err
+				}
+				payloadLength = int(length)
+			}
+			e.payloadLengthBuffer[i] = uint64(payloadLength)
+			e.posDeltaBuffer[i] = code >> 1
+			if payloadLength != 0 {
+				if e.payloadByteUpto+payloadLength > len(e.payloadBytes) {
+					grown := make([]byte, 2*(e.payloadByteUpto+payloadLength))
+					copy(grown, e.payloadBytes[:e.payloadByteUpto])
+					e.payloadBytes = grown
+				}
+				if _, err := io.ReadFull(e.posIn, e.payloadBytes[e.payloadByteUpto:e.payloadByteUpto+payloadLength]); err != nil {
+					return err
+				}
+				e.payloadByteUpto += payloadLength
+			}
+		} else {
+			e.posDeltaBuffer[i] = code
+		}
+
+		if e.indexHasOffsets {
+			deltaCode, err := e.posIn.ReadUvarint(nil)
+			if err != nil {
+				return err
+			}
+			if deltaCode&1 != 0 {
+				length, err := e.posIn.ReadUvarint(nil)
+				if err != nil {
+					return err
+				}
+				offsetLength = int(length)
+			}
+			e.offsetStartDeltaBuffer[i] = deltaCode >> 1
+			e.offsetLengthBuffer[i] = uint64(offsetLength)
+		}
+	}
+	e.payloadByteUpto = 0
+	return nil
+}
+
+func (e *EverythingEnum) NextDoc() (int, error) {
+	if e.doc == types.NO_MORE_DOCS {
+		return e.doc, nil
+	}
+	if e.docBufferUpto == BLOCK_SIZE && e.docUpto < e.docFreq {
+		if err := e.refillDocs(); err != nil {
+			return 0, err
+		}
+	}
+
+	e.doc = int(e.docBuffer[e.docBufferUpto])
+	e.freq = int(e.freqBuffer[e.docBufferUpto])
+	e.posPendingCount += e.freq
+	e.docBufferUpto++
+	e.position = 0
+	e.lastStartOffset = 0
+	return e.doc, nil
+}
+
+func (e *EverythingEnum) Advance(target int) (int, error) {
+	if target > e.nextSkipDoc {
+		if e.skipper == nil {
+			// Lazy init: first time this enum has ever been used for skipping
+			e.skipper = NewSkipReader(e.docIn.Clone().(store.IndexInput), MAX_SKIP_LEVELS,
+				true, e.indexHasOffsets, e.indexHasPayloads)
+		}
+
+		if !e.skipped {
+			// This is the first time this enum has skipped
+			// since reset() was called; load the skip data:
+			if err := e.skipper.InitV1(e.docTermStartFP+e.skipOffset,
+				e.docTermStartFP, e.posTermStartFP, e.payTermStartFP, e.docFreq); err != nil {
+				return 0, err
+			}
+			e.skipped = true
+		}
+
+		upto, err := e.skipper.SkipTo(target)
+		if err != nil {
+			return 0, err
+		}
+		newDocUpto := upto + 1
+
+		if newDocUpto >= e.docUpto {
+			// Skipper moved
+			e.docUpto = newDocUpto
+
+			// Force to read next block
+			e.docBufferUpto = BLOCK_SIZE
+			e.accum = uint64(e.skipper.GetDoc())
+			if _, err := e.docIn.Seek(e.skipper.GetDocPointer(), io.SeekStart); err != nil {
+				return 0, err
+			}
+			e.posPendingFP = e.skipper.GetPosPointer()
+			e.payPendingFP = e.skipper.GetPayPointer()
+			e.posPendingCount = e.skipper.GetPosBufferUpto()
+			e.lastStartOffset = 0 // new document
+			e.payloadByteUpto = e.skipper.GetPayloadByteUpto()
+		}
+		e.nextSkipDoc = e.skipper.GetNextSkipDoc()
+	}
+	if e.docBufferUpto == BLOCK_SIZE && e.docUpto < e.docFreq {
+		if err := e.refillDocs(); err != nil {
+			return 0, err
+		}
+	}
+
+	// Now scan:
+	for {
+		doc := e.docBuffer[e.docBufferUpto]
+		e.freq = int(e.freqBuffer[e.docBufferUpto])
+		e.posPendingCount += e.freq
+		e.docBufferUpto++
+
+		if doc >= uint64(target) {
+			e.doc = int(doc)
+			break
+		}
+	}
+
+	e.position = 0
+	e.lastStartOffset = 0
+	return e.doc, nil
+}
+
+// skipPositions
+// TODO: in theory we could avoid loading frq block
+// when not needed, ie, use skip data to load how far to
+// seek the pos pointer ... instead of having to load frq
+// blocks only to sum up how many positions to skip
+func (e *EverythingEnum) skipPositions() error {
+	// Skip positions now:
+	toSkip := e.posPendingCount - e.freq
+
+	leftInBlock := BLOCK_SIZE - e.posBufferUpto
+	if toSkip < leftInBlock {
+		end := e.posBufferUpto + toSkip
+		for e.posBufferUpto < end {
+			if e.indexHasPayloads {
+				e.payloadByteUpto += int(e.payloadLengthBuffer[e.posBufferUpto])
+			}
+			e.posBufferUpto++
+		}
+	} else {
+		toSkip -= leftInBlock
+		pforUtil := e.pforUtil
+		for toSkip >= BLOCK_SIZE {
+			if err := pforUtil.Skip(e.posIn); err != nil {
+				return err
+			}
+
+			if e.indexHasPayloads {
+				// Skip payloadLength block:
+				if err := pforUtil.Skip(e.payIn); err != nil {
+					return err
+				}
+
+				// Skip payloadBytes block:
+				numBytes, err := e.payIn.ReadUvarint(nil)
+				if err != nil {
+					return err
+				}
+				if _, err := e.payIn.Seek(e.payIn.GetFilePointer()+int64(numBytes), io.SeekStart); err != nil {
+					return err
+				}
+			}
+
+			if e.indexHasOffsets {
+				if err := pforUtil.Skip(e.payIn); err != nil {
+					return err
+				}
+				if err := pforUtil.Skip(e.payIn); err != nil {
+					return err
+				}
+			}
+			toSkip -= BLOCK_SIZE
+		}
+		if err := e.refillPositions(); err != nil {
+			return err
+		}
+		e.payloadByteUpto = 0
+		e.posBufferUpto = 0
+		for e.posBufferUpto < toSkip {
+			if e.indexHasPayloads {
+				e.payloadByteUpto += int(e.payloadLengthBuffer[e.posBufferUpto])
+			}
+			e.posBufferUpto++
+		}
+	}
+
+	e.position = 0
+	e.lastStartOffset = 0
+	return nil
+}
+
+func (e *EverythingEnum) NextPosition() (int, error) {
+	if e.posPendingFP != -1 {
+		if _, err := e.posIn.Seek(e.posPendingFP, io.SeekStart); err != nil {
+			return 0, err
+		}
+		e.posPendingFP = -1
+
+		if e.payPendingFP != -1 && e.payIn != nil {
+			if _, err := e.payIn.Seek(e.payPendingFP, io.SeekStart); err != nil {
+				return 0, err
+			}
+			e.payPendingFP = -1
+		}
+
+		// Force buffer refill:
+		e.posBufferUpto = BLOCK_SIZE
+	}
+
+	if e.posPendingCount > e.freq {
+		if err := e.skipPositions(); err != nil {
+			return 0, err
+		}
+		e.posPendingCount = e.freq
+	}
+
+	if e.posBufferUpto == BLOCK_SIZE {
+		if err := e.refillPositions(); err != nil {
+			return 0, err
+		}
+		e.posBufferUpto = 0
+	}
+	e.position += int(e.posDeltaBuffer[e.posBufferUpto])
+
+	if e.indexHasPayloads {
+		e.payloadLength = int(e.payloadLengthBuffer[e.posBufferUpto])
+		e.payload = e.payloadBytes[e.payloadByteUpto : e.payloadByteUpto+e.payloadLength]
+		e.payloadByteUpto += e.payloadLength
+	}
+
+	if e.indexHasOffsets {
+		e.startOffset = e.lastStartOffset + int(e.offsetStartDeltaBuffer[e.posBufferUpto])
+		e.endOffset = e.startOffset + int(e.offsetLengthBuffer[e.posBufferUpto])
+		e.lastStartOffset = e.startOffset
+	}
+
+	e.posBufferUpto++
+	e.posPendingCount--
+	return e.position, nil
+}
+
+func (e *EverythingEnum) StartOffset() (int, error) {
+	if !e.needsOffsets {
+		return -1, nil
+	}
+	return e.startOffset, nil
+}
+
+func (e *EverythingEnum) EndOffset() (int, error) {
+	if !e.needsOffsets {
+		return -1, nil
+	}
+	return e.endOffset, nil
+}
+
+func (e *EverythingEnum) GetPayload() ([]byte, error) {
+	if !e.needsPayloads || e.payloadLength == 0 {
+		return nil, nil
+	}
+	return e.payload, nil
+}
+
+func (e *EverythingEnum) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(e, target)
+}
+
+func (e *EverythingEnum) Cost() int64 {
+	return int64(e.docFreq)
+}
+
+var _ index.ImpactsEnum = &blockImpactsEnum{}
+
+// blockImpactsEnum Exposes the impacts stored in the skip data of a postings list next to
+// a regular postings enum over the same term.
+type blockImpactsEnum struct {
+	index.PostingsEnum
+
+	skipper     *SkipReader
+	nextSkipDoc int
+}
+
+func (p *PostingsReader) newBlockImpactsEnum(fieldInfo *document.FieldInfo,
+	termState *IntBlockTermState, postings index.PostingsEnum) (*blockImpactsEnum, error) {
+
+	indexOptions := fieldInfo.GetIndexOptions()
+	skipper := NewSkipReader(p.docIn.Clone().(store.IndexInput), MAX_SKIP_LEVELS,
+		indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS,
+		indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS,
+		fieldInfo.HasPayloads())
+
+	err := skipper.InitV1(termState.DocStartFP+termState.SkipOffset,
+		termState.DocStartFP, termState.PosStartFP, termState.PayStartFP, termState.DocFreq)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blockImpactsEnum{
+		PostingsEnum: postings,
+		skipper:      skipper,
+		nextSkipDoc:  -1,
+	}, nil
+}
+
+func (b *blockImpactsEnum) AdvanceShallow(target int) error {
+	if target > b.nextSkipDoc {
+		// always need to skip
+		if _, err := b.skipper.SkipTo(target); err != nil {
+			return err
+		}
+		b.nextSkipDoc = b.skipper.GetNextSkipDoc()
+	}
+	return nil
+}
+
+func (b *blockImpactsEnum) GetImpacts() (index.Impacts, error) {
+	if err := b.AdvanceShallow(max(b.DocID(), 0)); err != nil {
+		return nil, err
+	}
+	return b.skipper.GetImpacts(), nil
+}
@@ -0,0 +1,77 @@
+package lucene84
+
+import (
+	"context"
+
+	"github.com/geange/lucene-go/codecs/blocktree"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+)
+
+func init() {
+	coreIndex.RegisterPostingsFormat(NewPostingsFormat())
+}
+
+var _ index.PostingsFormat = &PostingsFormat{}
+
+// PostingsFormat Lucene 8.4 postings format, which encodes postings in packed integer blocks for
+// fast decode. The terms dictionary is the block-tree terms dictionary (.tim/.tip/.tmd), the
+// postings themselves are written by PostingsWriter (.doc/.pos/.pay).
+type PostingsFormat struct {
+	name string
+
+	minTermBlockSize int
+	maxTermBlockSize int
+}
+
+// NewPostingsFormat Creates PostingsFormat with default settings.
+func NewPostingsFormat() *PostingsFormat {
+	return &PostingsFormat{
+		name:             "Lucene84",
+		minTermBlockSize: blocktree.DEFAULT_MIN_BLOCK_SIZE,
+		maxTermBlockSize: blocktree.DEFAULT_MAX_BLOCK_SIZE,
+	}
+}
+
+// NewPostingsFormatWithBlockSize Creates PostingsFormat with custom values for minBlockSize and
+// maxBlockSize passed to block terms dictionary.
+func NewPostingsFormatWithBlockSize(minTermBlockSize, maxTermBlockSize int) (*PostingsFormat, error) {
+	if err := blocktree.ValidateSettings(minTermBlockSize, maxTermBlockSize); err != nil {
+		return nil, err
+	}
+	return &PostingsFormat{
+		name:             "Lucene84",
+		minTermBlockSize: minTermBlockSize,
+		maxTermBlockSize: maxTermBlockSize,
+	}, nil
+}
+
+func (p *PostingsFormat) GetName() string {
+	return p.name
+}
+
+func (p *PostingsFormat) FieldsConsumer(ctx context.Context, state *index.SegmentWriteState) (index.FieldsConsumer, error) {
+	postingsWriter, err := NewPostingsWriter(state)
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := blocktree.NewTermsWriter(state, postingsWriter, p.minTermBlockSize, p.maxTermBlockSize)
+	if err != nil {
+		_ = postingsWriter.Close()
+		return nil, err
+	}
+	return consumer, nil
+}
+
+func (p *PostingsFormat) FieldsProducer(ctx context.Context, state *index.SegmentReadState) (index.FieldsProducer, error) {
+	postingsReader, err := NewPostingsReader(state)
+	if err != nil {
+		return nil, err
+	}
+	producer, err := blocktree.NewTermsReader(ctx, postingsReader, state)
+	if err != nil {
+		_ = postingsReader.Close()
+		return nil, err
+	}
+	return producer, nil
+}
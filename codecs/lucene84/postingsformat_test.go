@@ -0,0 +1,297 @@
+package lucene84
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/geange/lucene-go/codecs/codectest"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/automaton"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTerms(r *rand.Rand, numTerms int) []codectest.Term {
+	seen := make(map[string]struct{}, numTerms)
+	terms := make([]codectest.Term, 0, numTerms)
+	for len(terms) < numTerms {
+		// a wide alphabet gives prefixes with more entries than fit into one block, and so
+		// floor blocks
+		length := 1 + r.Intn(4)
+		term := make([]byte, length)
+		for i := range term {
+			term[i] = 'A' + byte(r.Intn(60))
+		}
+		if _, ok := seen[string(term)]; ok {
+			continue
+		}
+		seen[string(term)] = struct{}{}
+		terms = append(terms, newTestTerm(r, string(term), 1+r.Intn(3), 50, 2))
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		return bytes.Compare(terms[i].Text, terms[j].Text) < 0
+	})
+	return terms
+}
+
+func TestPostingsFormat(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	bodyInfo := document.NewFieldInfo("body", 0, false, true, true,
+		document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS,
+		document.DOC_VALUES_TYPE_NONE, -1, map[string]string{}, 0, 0, 0, false)
+	idInfo := document.NewFieldInfo("id", 1, false, true, false,
+		document.INDEX_OPTIONS_DOCS,
+		document.DOC_VALUES_TYPE_NONE, -1, map[string]string{}, 0, 0, 0, false)
+	fieldInfos := coreIndex.NewFieldInfos([]*document.FieldInfo{bodyInfo, idInfo})
+
+	id := make([]byte, 16)
+	r.Read(id)
+	segmentInfo := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", 100000,
+		false, nil, map[string]string{}, id, map[string]string{}, nil)
+
+	ids := make([]codectest.Term, 0, 10)
+	for i := 0; i < 10; i++ {
+		ids = append(ids, codectest.Term{
+			Text: []byte(fmt.Sprintf("id%02d", i)),
+			Docs: []codectest.Doc{{ID: i, Positions: []codectest.Position{{}}}},
+		})
+	}
+	fields := codectest.NewFields(document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS,
+		map[string][]codectest.Term{
+			"body": newTestTerms(r, 5000),
+			"id":   ids,
+		})
+
+	format := NewPostingsFormat()
+	assert.Equal(t, "Lucene84", format.GetName())
+
+	writeState := index.NewSegmentWriteState(dir, segmentInfo, fieldInfos, nil, nil)
+	consumer, err := format.FieldsConsumer(context.Background(), writeState)
+	assert.Nil(t, err)
+	assert.Nil(t, consumer.Write(context.Background(), fields, nil))
+	assert.Nil(t, consumer.Close())
+
+	readState := index.NewSegmentReadState(dir, segmentInfo, fieldInfos, nil, "")
+	producer, err := format.FieldsProducer(context.Background(), readState)
+	assert.Nil(t, err)
+	defer producer.Close()
+	assert.Nil(t, producer.CheckIntegrity())
+	assert.Equal(t, []string{"body", "id"}, producer.Names())
+	assert.Equal(t, 2, producer.Size())
+
+	missing, err := producer.Terms("missing")
+	assert.Nil(t, err)
+	assert.Nil(t, missing)
+
+	t.Run("stats", func(t *testing.T) {
+		body, err := producer.Terms("body")
+		assert.Nil(t, err)
+		expected := fields.FieldTerms("body")
+		size, err := body.Size()
+		assert.Nil(t, err)
+		assert.Equal(t, len(expected), size)
+
+		sumDocFreq := int64(0)
+		for _, term := range expected {
+			sumDocFreq += int64(len(term.Docs))
+		}
+		actual, err := body.GetSumDocFreq()
+		assert.Nil(t, err)
+		assert.Equal(t, sumDocFreq, actual)
+
+		minTerm, err := body.GetMin()
+		assert.Nil(t, err)
+		assert.Equal(t, expected[0].Text, minTerm)
+		maxTerm, err := body.GetMax()
+		assert.Nil(t, err)
+		assert.Equal(t, expected[len(expected)-1].Text, maxTerm)
+
+		idTerms, err := producer.Terms("id")
+		assert.Nil(t, err)
+		assert.False(t, idTerms.HasFreqs())
+		sumTotalTermFreq, err := idTerms.GetSumTotalTermFreq()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(10), sumTotalTermFreq)
+	})
+
+	t.Run("next", func(t *testing.T) {
+		for _, field := range fields.Names() {
+			terms, err := producer.Terms(field)
+			assert.Nil(t, err)
+			termsEnum, err := terms.Iterator()
+			assert.Nil(t, err)
+			for _, expected := range fields.FieldTerms(field) {
+				term, err := termsEnum.Next(context.Background())
+				assert.Nil(t, err)
+				assert.Equal(t, expected.Text, term)
+				checkTermsEnumPostings(t, termsEnum, expected)
+			}
+			term, err := termsEnum.Next(context.Background())
+			assert.Nil(t, err)
+			assert.Nil(t, term)
+		}
+	})
+
+	t.Run("seekExact", func(t *testing.T) {
+		terms, err := producer.Terms("body")
+		assert.Nil(t, err)
+		termsEnum, err := terms.Iterator()
+		assert.Nil(t, err)
+
+		expected := fields.FieldTerms("body")
+		for _, i := range r.Perm(len(expected))[:500] {
+			found, err := termsEnum.SeekExact(context.Background(), expected[i].Text)
+			assert.Nil(t, err)
+			assert.True(t, found, string(expected[i].Text))
+			checkTermsEnumPostings(t, termsEnum, expected[i])
+		}
+
+		for _, target := range []string{"", "~", "AAAAAAA", "|||||||", "id"} {
+			found, err := termsEnum.SeekExact(context.Background(), []byte(target))
+			assert.Nil(t, err)
+			assert.Equal(t, indexOfTerm(expected, []byte(target)) >= 0, found, target)
+		}
+	})
+
+	t.Run("seekCeil", func(t *testing.T) {
+		terms, err := producer.Terms("body")
+		assert.Nil(t, err)
+		termsEnum, err := terms.Iterator()
+		assert.Nil(t, err)
+
+		expected := fields.FieldTerms("body")
+		for i := 0; i < 2000; i++ {
+			target := make([]byte, 1+r.Intn(5))
+			for j := range target {
+				target[j] = 'A' + byte(r.Intn(62))
+			}
+			ceil := sort.Search(len(expected), func(k int) bool {
+				return bytes.Compare(expected[k].Text, target) >= 0
+			})
+
+			status, err := termsEnum.SeekCeil(context.Background(), target)
+			assert.Nil(t, err)
+			switch {
+			case ceil == len(expected):
+				assert.EqualValues(t, index.SEEK_STATUS_END, status, string(target))
+				continue
+			case bytes.Equal(expected[ceil].Text, target):
+				assert.EqualValues(t, index.SEEK_STATUS_FOUND, status, string(target))
+			default:
+				assert.EqualValues(t, index.SEEK_STATUS_NOT_FOUND, status, string(target))
+			}
+			term, err := termsEnum.Term()
+			assert.Nil(t, err)
+			assert.Equal(t, expected[ceil].Text, term, string(target))
+			checkTermsEnumPostings(t, termsEnum, expected[ceil])
+
+			// the enum keeps iterating from the seek position
+			if ceil+1 < len(expected) {
+				term, err := termsEnum.Next(context.Background())
+				assert.Nil(t, err)
+				assert.Equal(t, expected[ceil+1].Text, term)
+			}
+		}
+	})
+
+	t.Run("intersect", func(t *testing.T) {
+		terms, err := producer.Terms("body")
+		assert.Nil(t, err)
+		expected := fields.FieldTerms("body")
+
+		// terms starting with "bc"
+		prefix := automaton.NewNewBuilder()
+		s0, s1, s2 := prefix.CreateState(), prefix.CreateState(), prefix.CreateState()
+		prefix.AddTransitionLabel(s0, s1, 'b')
+		prefix.AddTransitionLabel(s1, s2, 'c')
+		prefix.AddTransition(s2, s2, 0, 255)
+		prefix.SetAccept(s2, true)
+
+		// terms ending with "ea"
+		suffix := automaton.NewNewBuilder()
+		s0, s1, s2 = suffix.CreateState(), suffix.CreateState(), suffix.CreateState()
+		suffix.AddTransition(s0, s0, 0, 'e'-1)
+		suffix.AddTransitionLabel(s0, s1, 'e')
+		suffix.AddTransition(s0, s0, 'e'+1, 255)
+		suffix.AddTransition(s1, s0, 0, 'a'-1)
+		suffix.AddTransitionLabel(s1, s2, 'a')
+		suffix.AddTransition(s1, s0, 'b', 'd')
+		suffix.AddTransitionLabel(s1, s1, 'e')
+		suffix.AddTransition(s1, s0, 'e'+1, 255)
+		suffix.AddTransition(s2, s0, 0, 'e'-1)
+		suffix.AddTransitionLabel(s2, s1, 'e')
+		suffix.AddTransition(s2, s0, 'e'+1, 255)
+		suffix.SetAccept(s2, true)
+
+		cases := []struct {
+			name      string
+			automaton *automaton.Automaton
+			match     func(term []byte) bool
+			startTerm []byte
+		}{
+			{"prefix", prefix.Finish(), func(term []byte) bool { return bytes.HasPrefix(term, []byte("bc")) }, nil},
+			{"prefixStartTerm", prefix.Finish(), func(term []byte) bool { return bytes.HasPrefix(term, []byte("bc")) }, []byte("bcc")},
+			{"suffix", suffix.Finish(), func(term []byte) bool { return bytes.HasSuffix(term, []byte("ea")) }, nil},
+		}
+
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				compiled, err := automaton.NewCompiledAutomaton(c.automaton, nil, false, 10000, true)
+				assert.Nil(t, err)
+				termsEnum, err := terms.Intersect(compiled, c.startTerm)
+				assert.Nil(t, err)
+
+				for _, want := range expected {
+					if !c.match(want.Text) || (c.startTerm != nil && bytes.Compare(want.Text, c.startTerm) <= 0) {
+						continue
+					}
+					term, err := termsEnum.Next(context.Background())
+					assert.Nil(t, err)
+					assert.Equal(t, string(want.Text), string(term))
+					checkTermsEnumPostings(t, termsEnum, want)
+				}
+				term, err := termsEnum.Next(context.Background())
+				assert.Nil(t, err)
+				assert.Nil(t, term)
+			})
+		}
+	})
+}
+
+func indexOfTerm(terms []codectest.Term, term []byte) int {
+	for i := range terms {
+		if bytes.Equal(terms[i].Text, term) {
+			return i
+		}
+	}
+	return -1
+}
+
+func checkTermsEnumPostings(t *testing.T, termsEnum index.TermsEnum, expected codectest.Term) {
+	docFreq, err := termsEnum.DocFreq()
+	assert.Nil(t, err)
+	assert.Equal(t, len(expected.Docs), docFreq)
+
+	postings, err := termsEnum.Postings(nil, coreIndex.POSTINGS_ENUM_FREQS)
+	assert.Nil(t, err)
+	for _, doc := range expected.Docs {
+		docID, err := postings.NextDoc()
+		assert.Nil(t, err)
+		assert.Equal(t, doc.ID, docID)
+	}
+	docID, err := postings.NextDoc()
+	assert.Nil(t, err)
+	assert.Equal(t, types.NO_MORE_DOCS, docID)
+}
@@ -0,0 +1,301 @@
+package lucene84
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/geange/lucene-go/codecs"
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/zigzag"
+)
+
+var _ codecs.PostingsReaderBase = &PostingsReader{}
+
+// PostingsReader Concrete class that reads docId(maybe frq,pos,offset,payloads) list with postings format.
+type PostingsReader struct {
+	docIn   store.IndexInput
+	posIn   store.IndexInput
+	payIn   store.IndexInput
+	version int
+}
+
+// NewPostingsReader Sole constructor.
+func NewPostingsReader(state *index.SegmentReadState) (*PostingsReader, error) {
+	reader := &PostingsReader{}
+
+	segmentName := state.SegmentInfo.Name()
+	segmentID := state.SegmentInfo.GetID()
+
+	closeOnError := func(err error) (*PostingsReader, error) {
+		_ = reader.Close()
+		return nil, err
+	}
+
+	// NOTE: these data files are too costly to verify checksum against all the bytes on open,
+	// but for now we at least verify proper structure of the checksum footer: which looks
+	// for FOOTER_MAGIC + algorithmID. This is cheap and can detect some forms of corruption
+	// such as file truncation.
+	docName := store.SegmentFileName(segmentName, state.SegmentSuffix, DOC_EXTENSION)
+	docIn, err := store.OpenInputWithContext(nil, state.Directory, docName, state.Context)
+	if err != nil {
+		return nil, err
+	}
+	reader.docIn = docIn
+
+	reader.version, err = utils.CheckIndexHeader(nil, docIn, DOC_CODEC, VERSION_START, VERSION_CURRENT, segmentID, state.SegmentSuffix)
+	if err != nil {
+		return closeOnError(err)
+	}
+	if _, err := utils.RetrieveChecksum(docIn); err != nil {
+		return closeOnError(err)
+	}
+
+	hasProx, hasPayloads, hasOffsets := fieldInfosFeatures(state.FieldInfos)
+	if hasProx {
+		posName := store.SegmentFileName(segmentName, state.SegmentSuffix, POS_EXTENSION)
+		reader.posIn, err = store.OpenInputWithContext(nil, state.Directory, posName, state.Context)
+		if err != nil {
+			return closeOnError(err)
+		}
+		_, err = utils.CheckIndexHeader(nil, reader.posIn, POS_CODEC, reader.version, reader.version, segmentID, state.SegmentSuffix)
+		if err != nil {
+			return closeOnError(err)
+		}
+		if _, err := utils.RetrieveChecksum(reader.posIn); err != nil {
+			return closeOnError(err)
+		}
+
+		if hasPayloads || hasOffsets {
+			payName := store.SegmentFileName(segmentName, state.SegmentSuffix, PAY_EXTENSION)
+			reader.payIn, err = store.OpenInputWithContext(nil, state.Directory, payName, state.Context)
+			if err != nil {
+				return closeOnError(err)
+			}
+			_, err = utils.CheckIndexHeader(nil, reader.payIn, PAY_CODEC, reader.version, reader.version, segmentID, state.SegmentSuffix)
+			if err != nil {
+				return closeOnError(err)
+			}
+			if _, err := utils.RetrieveChecksum(reader.payIn); err != nil {
+				return closeOnError(err)
+			}
+		}
+	}
+	return reader, nil
+}
+
+func (p *PostingsReader) Init(termsIn store.IndexInput, state *index.SegmentReadState) error {
+	// Make sure we are talking to the matching postings writer
+	_, err := utils.CheckIndexHeader(nil, termsIn, TERMS_CODEC, VERSION_START, VERSION_CURRENT,
+		state.SegmentInfo.GetID(), state.SegmentSuffix)
+	if err != nil {
+		return err
+	}
+	indexBlockSize, err := termsIn.ReadUvarint(nil)
+	if err != nil {
+		return err
+	}
+	if indexBlockSize != BLOCK_SIZE {
+		return fmt.Errorf("index-time BLOCK_SIZE (%d) != read-time BLOCK_SIZE (%d)", indexBlockSize, BLOCK_SIZE)
+	}
+	return nil
+}
+
+func (p *PostingsReader) NewTermState() codecs.BlockTermState {
+	return NewIntBlockTermState()
+}
+
+func (p *PostingsReader) Close() error {
+	var firstErr error
+	for _, in := range []store.IndexInput{p.docIn, p.posIn, p.payIn} {
+		if in == nil {
+			continue
+		}
+		if err := in.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	p.docIn, p.posIn, p.payIn = nil, nil, nil
+	return firstErr
+}
+
+func (p *PostingsReader) DecodeTerm(in store.DataInput, fieldInfo *document.FieldInfo,
+	state codecs.BlockTermState, absolute bool) error {
+
+	termState, ok := state.(*IntBlockTermState)
+	if !ok {
+		return fmt.Errorf("unexpected term state %T", state)
+	}
+	indexOptions := fieldInfo.GetIndexOptions()
+	fieldHasPositions := indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS
+	fieldHasOffsets := indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS
+	fieldHasPayloads := fieldInfo.HasPayloads()
+
+	if absolute {
+		termState.DocStartFP = 0
+		termState.PosStartFP = 0
+		termState.PayStartFP = 0
+	}
+
+	l, err := in.ReadUvarint(nil)
+	if err != nil {
+		return err
+	}
+	if l&0x01 == 0 {
+		termState.DocStartFP += int64(l >> 1)
+		if termState.DocFreq == 1 {
+			singletonDocID, err := in.ReadUvarint(nil)
+			if err != nil {
+				return err
+			}
+			termState.SingletonDocID = int(singletonDocID)
+		} else {
+			termState.SingletonDocID = -1
+		}
+	} else {
+		if absolute || termState.SingletonDocID == -1 {
+			return errors.New("corrupt singleton doc id delta")
+		}
+		termState.SingletonDocID += int(zigzag.Decode(l >> 1))
+	}
+
+	if fieldHasPositions {
+		posDelta, err := in.ReadUvarint(nil)
+		if err != nil {
+			return err
+		}
+		termState.PosStartFP += int64(posDelta)
+		if fieldHasOffsets || fieldHasPayloads {
+			payDelta, err := in.ReadUvarint(nil)
+			if err != nil {
+				return err
+			}
+			termState.PayStartFP += int64(payDelta)
+		}
+		if termState.TotalTermFreq > BLOCK_SIZE {
+			offset, err := in.ReadUvarint(nil)
+			if err != nil {
+				return err
+			}
+			termState.LastPosBlockOffset = int64(offset)
+		} else {
+			termState.LastPosBlockOffset = -1
+		}
+	}
+
+	if termState.DocFreq > BLOCK_SIZE {
+		skipOffset, err := in.ReadUvarint(nil)
+		if err != nil {
+			return err
+		}
+		termState.SkipOffset = int64(skipOffset)
+	} else {
+		termState.SkipOffset = -1
+	}
+	return nil
+}
+
+func (p *PostingsReader) Postings(fieldInfo *document.FieldInfo, state codecs.BlockTermState,
+	reuse index.PostingsEnum, flags int) (index.PostingsEnum, error) {
+
+	termState, ok := state.(*IntBlockTermState)
+	if !ok {
+		return nil, fmt.Errorf("unexpected term state %T", state)
+	}
+
+	indexHasPositions := fieldInfo.GetIndexOptions() >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS
+
+	if !indexHasPositions || !coreIndex.FeatureRequested(flags, coreIndex.POSTINGS_ENUM_POSITIONS) {
+		docsEnum, ok := reuse.(*BlockDocsEnum)
+		if !ok || !docsEnum.canReuse(p.docIn, fieldInfo) {
+			docsEnum = p.newBlockDocsEnum(fieldInfo)
+		}
+		if err := docsEnum.reset(termState, flags); err != nil {
+			return nil, err
+		}
+		return docsEnum, nil
+	}
+
+	everythingEnum, ok := reuse.(*EverythingEnum)
+	if !ok || !everythingEnum.canReuse(p.docIn, fieldInfo) {
+		everythingEnum = p.newEverythingEnum(fieldInfo)
+	}
+	if err := everythingEnum.reset(termState, flags); err != nil {
+		return nil, err
+	}
+	return everythingEnum, nil
+}
+
+func (p *PostingsReader) Impacts(fieldInfo *document.FieldInfo, state codecs.BlockTermState,
+	flags int) (index.ImpactsEnum, error) {
+
+	postings, err := p.Postings(fieldInfo, state, nil, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	termState := state.(*IntBlockTermState)
+	if termState.DocFreq <= BLOCK_SIZE {
+		// no skip data
+		return coreIndex.NewSlowImpactsEnum(postings), nil
+	}
+	return p.newBlockImpactsEnum(fieldInfo, termState, postings)
+}
+
+func (p *PostingsReader) CheckIntegrity() error {
+	for _, in := range []store.IndexInput{p.docIn, p.posIn, p.payIn} {
+		if in == nil {
+			continue
+		}
+		if _, err := utils.ChecksumEntireFile(in); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *PostingsReader) String() string {
+	return fmt.Sprintf("PostingsReader(positions=%t,payloads=%t)", p.posIn != nil, p.payIn != nil)
+}
+
+// readVIntBlock Read values that have been written using variable-length encoding instead of bit-packing.
+func readVIntBlock(docIn store.IndexInput, docBuffer, freqBuffer []uint64, num int, indexHasFreq bool) error {
+	if indexHasFreq {
+		for i := 0; i < num; i++ {
+			code, err := docIn.ReadUvarint(nil)
+			if err != nil {
+				return err
+			}
+			docBuffer[i] = code >> 1
+			if code&1 != 0 {
+				freqBuffer[i] = 1
+			} else {
+				freq, err := docIn.ReadUvarint(nil)
+				if err != nil {
+					return err
+				}
+				freqBuffer[i] = freq
+			}
+		}
+		return nil
+	}
+
+	for i := 0; i < num; i++ {
+		doc, err := docIn.ReadUvarint(nil)
+		if err != nil {
+			return err
+		}
+		docBuffer[i] = doc
+	}
+	return nil
+}
+
+func prefixSumN(buffer []uint64, count int, base uint64) {
+	buffer[0] += base
+	for i := 1; i < count; i++ {
+		buffer[i] += buffer[i-1]
+	}
+}
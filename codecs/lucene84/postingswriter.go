@@ -0,0 +1,669 @@
+package lucene84
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bits-and-blooms/bitset"
+	"github.com/geange/lucene-go/codecs"
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/zigzag"
+)
+
+var _ codecs.PostingsWriterBase = &PostingsWriter{}
+
+// PostingsWriter Concrete class that writes docId(maybe frq,pos,offset,payloads) list with postings format.
+// Postings list for each term will be stored separately.
+// See Also: SkipWriter for details about skipping setting and postings layout.
+type PostingsWriter struct {
+	docOut store.IndexOutput
+	posOut store.IndexOutput
+	payOut store.IndexOutput
+
+	lastState *IntBlockTermState
+
+	// Holds starting file pointers for current term:
+	docStartFP int64
+	posStartFP int64
+	payStartFP int64
+
+	docDeltaBuffer []uint64
+	freqBuffer     []uint64
+	docBufferUpto  int
+
+	posDeltaBuffer         []uint64
+	payloadLengthBuffer    []uint64
+	offsetStartDeltaBuffer []uint64
+	offsetLengthBuffer     []uint64
+	posBufferUpto          int
+
+	payloadBytes    []byte
+	payloadByteUpto int
+
+	lastBlockDocID           int
+	lastBlockPosFP           int64
+	lastBlockPayFP           int64
+	lastBlockPosBufferUpto   int
+	lastBlockPayloadByteUpto int
+
+	lastDocID       int
+	lastPosition    int
+	lastStartOffset int
+	docCount        int
+
+	pforUtil     *PForUtil
+	forDeltaUtil *ForDeltaUtil
+	skipWriter   *SkipWriter
+
+	fieldHasNorms                  bool
+	norms                          index.NumericDocValues
+	competitiveFreqNormAccumulator *coreIndex.CompetitiveImpactAccumulator
+
+	// field settings, see setField
+	fieldInfo      *document.FieldInfo
+	writeFreqs     bool
+	writePositions bool
+	writeOffsets   bool
+	writePayloads  bool
+	enumFlags      int
+	postingsEnum   index.PostingsEnum
+}
+
+// NewPostingsWriter Creates a postings writer
+func NewPostingsWriter(state *index.SegmentWriteState) (*PostingsWriter, error) {
+	segmentName := state.SegmentInfo.Name()
+	segmentID := state.SegmentInfo.GetID()
+
+	docFileName := store.SegmentFileName(segmentName, state.SegmentSuffix, DOC_EXTENSION)
+	docOut, err := store.CreateOutputWithContext(nil, state.Directory, docFileName, state.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := &PostingsWriter{
+		docOut:                         docOut,
+		lastState:                      NewIntBlockTermState(),
+		docDeltaBuffer:                 make([]uint64, BLOCK_SIZE),
+		freqBuffer:                     make([]uint64, BLOCK_SIZE),
+		competitiveFreqNormAccumulator: coreIndex.NewCompetitiveImpactAccumulator(),
+	}
+
+	closeOnError := func(err error) (*PostingsWriter, error) {
+		_ = writer.closeOutputs()
+		return nil, err
+	}
+
+	if err := utils.WriteIndexHeader(nil, docOut, DOC_CODEC, VERSION_CURRENT, segmentID, state.SegmentSuffix); err != nil {
+		return closeOnError(err)
+	}
+	forUtil := NewForUtil()
+	writer.forDeltaUtil = NewForDeltaUtil(forUtil)
+	writer.pforUtil = NewPForUtil(forUtil)
+
+	hasProx, hasPayloads, hasOffsets := fieldInfosFeatures(state.FieldInfos)
+	if hasProx {
+		writer.posDeltaBuffer = make([]uint64, BLOCK_SIZE)
+		posFileName := store.SegmentFileName(segmentName, state.SegmentSuffix, POS_EXTENSION)
+		writer.posOut, err = store.CreateOutputWithContext(nil, state.Directory, posFileName, state.Context)
+		if err != nil {
+			return closeOnError(err)
+		}
+		if err := utils.WriteIndexHeader(nil, writer.posOut, POS_CODEC, VERSION_CURRENT, segmentID, state.SegmentSuffix); err != nil {
+			return closeOnError(err)
+		}
+
+		if hasPayloads {
+			writer.payloadBytes = make([]byte, 128)
+			writer.payloadLengthBuffer = make([]uint64, BLOCK_SIZE)
+		}
+
+		if hasOffsets {
+			writer.offsetStartDeltaBuffer = make([]uint64, BLOCK_SIZE)
+			writer.offsetLengthBuffer = make([]uint64, BLOCK_SIZE)
+		}
+
+		if hasPayloads || hasOffsets {
+			payFileName := store.SegmentFileName(segmentName, state.SegmentSuffix, PAY_EXTENSION)
+			writer.payOut, err = store.CreateOutputWithContext(nil, state.Directory, payFileName, state.Context)
+			if err != nil {
+				return closeOnError(err)
+			}
+			if err := utils.WriteIndexHeader(nil, writer.payOut, PAY_CODEC, VERSION_CURRENT, segmentID, state.SegmentSuffix); err != nil {
+				return closeOnError(err)
+			}
+		}
+	}
+
+	maxDoc, err := state.SegmentInfo.MaxDoc()
+	if err != nil {
+		return closeOnError(err)
+	}
+
+	// TODO: should we try skipping every 2/4 blocks...?
+	writer.skipWriter = NewSkipWriter(MAX_SKIP_LEVELS, BLOCK_SIZE, maxDoc, writer.docOut, writer.posOut, writer.payOut)
+	return writer, nil
+}
+
+// fieldInfosFeatures Returns whether any field has positions, payloads and offsets.
+func fieldInfosFeatures(fieldInfos index.FieldInfos) (hasProx, hasPayloads, hasOffsets bool) {
+	for _, fi := range fieldInfos.List() {
+		options := fi.GetIndexOptions()
+		hasProx = hasProx || options >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS
+		hasOffsets = hasOffsets || options >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS
+		hasPayloads = hasPayloads || fi.HasPayloads()
+	}
+	return
+}
+
+func (p *PostingsWriter) NewTermState() *IntBlockTermState {
+	return NewIntBlockTermState()
+}
+
+func (p *PostingsWriter) Init(termsOut store.IndexOutput, state *index.SegmentWriteState) error {
+	err := utils.WriteIndexHeader(nil, termsOut, TERMS_CODEC, VERSION_CURRENT, state.SegmentInfo.GetID(), state.SegmentSuffix)
+	if err != nil {
+		return err
+	}
+	return termsOut.WriteUvarint(nil, BLOCK_SIZE)
+}
+
+func (p *PostingsWriter) SetField(fieldInfo *document.FieldInfo) {
+	p.fieldInfo = fieldInfo
+	indexOptions := fieldInfo.GetIndexOptions()
+	p.writeFreqs = indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS
+	p.writePositions = indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS
+	p.writeOffsets = indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS
+	p.writePayloads = fieldInfo.HasPayloads()
+
+	switch {
+	case !p.writeFreqs:
+		p.enumFlags = 0
+	case !p.writePositions:
+		p.enumFlags = coreIndex.POSTINGS_ENUM_FREQS
+	case !p.writeOffsets:
+		if p.writePayloads {
+			p.enumFlags = coreIndex.POSTINGS_ENUM_PAYLOADS
+		} else {
+			p.enumFlags = coreIndex.POSTINGS_ENUM_POSITIONS
+		}
+	default:
+		if p.writePayloads {
+			p.enumFlags = coreIndex.POSTINGS_ENUM_PAYLOADS | coreIndex.POSTINGS_ENUM_OFFSETS
+		} else {
+			p.enumFlags = coreIndex.POSTINGS_ENUM_OFFSETS
+		}
+	}
+
+	p.skipWriter.SetField(p.writePositions, p.writeOffsets, p.writePayloads)
+	p.lastState = NewIntBlockTermState()
+	p.fieldHasNorms = fieldInfo.HasNorms()
+}
+
+func (p *PostingsWriter) WriteTerm(term []byte, termsEnum index.TermsEnum,
+	docsSeen *bitset.BitSet, norms index.NormsProducer) (codecs.BlockTermState, error) {
+
+	var normValues index.NumericDocValues
+	if p.fieldInfo.HasNorms() && norms != nil {
+		values, err := norms.GetNorms(p.fieldInfo)
+		if err != nil {
+			return nil, err
+		}
+		normValues = values
+	}
+	p.startTerm(normValues)
+
+	postingsEnum, err := termsEnum.Postings(p.postingsEnum, p.enumFlags)
+	if err != nil {
+		return nil, err
+	}
+	p.postingsEnum = postingsEnum
+
+	docFreq := 0
+	totalTermFreq := int64(0)
+	for {
+		docID, err := postingsEnum.NextDoc()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if docID == types.NO_MORE_DOCS {
+			break
+		}
+		docFreq++
+		docsSeen.Set(uint(docID))
+
+		freq := -1
+		if p.writeFreqs {
+			freq, err = postingsEnum.Freq()
+			if err != nil {
+				return nil, err
+			}
+			totalTermFreq += int64(freq)
+		}
+
+		if err := p.startDoc(docID, freq); err != nil {
+			return nil, err
+		}
+
+		if p.writePositions {
+			for i := 0; i < freq; i++ {
+				pos, err := postingsEnum.NextPosition()
+				if err != nil {
+					return nil, err
+				}
+				var payload []byte
+				if p.writePayloads {
+					payload, err = postingsEnum.GetPayload()
+					if err != nil {
+						return nil, err
+					}
+				}
+				startOffset, endOffset := -1, -1
+				if p.writeOffsets {
+					if startOffset, err = postingsEnum.StartOffset(); err != nil {
+						return nil, err
+					}
+					if endOffset, err = postingsEnum.EndOffset(); err != nil {
+						return nil, err
+					}
+				}
+				if err := p.addPosition(pos, payload, startOffset, endOffset); err != nil {
+					return nil, err
+				}
+			}
+		}
+		p.finishDoc()
+	}
+
+	if docFreq == 0 {
+		return nil, nil
+	}
+
+	state := p.NewTermState()
+	state.DocFreq = docFreq
+	state.TotalTermFreq = -1
+	if p.writeFreqs {
+		state.TotalTermFreq = totalTermFreq
+	}
+	if err := p.finishTerm(state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (p *PostingsWriter) startTerm(norms index.NumericDocValues) {
+	p.docStartFP = p.docOut.GetFilePointer()
+	if p.writePositions {
+		p.posStartFP = p.posOut.GetFilePointer()
+		if p.writePayloads || p.writeOffsets {
+			p.payStartFP = p.payOut.GetFilePointer()
+		}
+	}
+	p.lastDocID = 0
+	p.lastBlockDocID = -1
+	p.skipWriter.ResetSkip()
+	p.norms = norms
+	p.competitiveFreqNormAccumulator.Clear()
+}
+
+func (p *PostingsWriter) startDoc(docID, termDocFreq int) error {
+	// Have collected a block of docs, and get a new doc.
+	// Should write skip data as well as postings list for
+	// current block.
+	if p.lastBlockDocID != -1 && p.docBufferUpto == 0 {
+		if err := p.skipWriter.BufferSkipV1(p.lastBlockDocID, p.competitiveFreqNormAccumulator, p.docCount,
+			p.lastBlockPosFP, p.lastBlockPayFP, p.lastBlockPosBufferUpto, p.lastBlockPayloadByteUpto); err != nil {
+			return err
+		}
+		p.competitiveFreqNormAccumulator.Clear()
+	}
+
+	docDelta := docID - p.lastDocID
+
+	if docID < 0 || (p.docCount > 0 && docDelta <= 0) {
+		return fmt.Errorf("docs out of order (%d <= %d )", docID, p.lastDocID)
+	}
+
+	p.docDeltaBuffer[p.docBufferUpto] = uint64(docDelta)
+	if p.writeFreqs {
+		p.freqBuffer[p.docBufferUpto] = uint64(termDocFreq)
+	}
+
+	p.docBufferUpto++
+	p.docCount++
+
+	if p.docBufferUpto == BLOCK_SIZE {
+		if err := p.forDeltaUtil.EncodeDeltas(p.docDeltaBuffer, p.docOut); err != nil {
+			return err
+		}
+		if p.writeFreqs {
+			if err := p.pforUtil.Encode(p.freqBuffer, p.docOut); err != nil {
+				return err
+			}
+		}
+		// NOTE: don't set docBufferUpto back to 0 here;
+		// finishDoc will do so (because it needs to see that
+		// the block was filled so it can save skip data)
+	}
+
+	p.lastDocID = docID
+	p.lastPosition = 0
+	p.lastStartOffset = 0
+
+	norm := int64(1)
+	if p.fieldHasNorms && p.norms != nil {
+		found, err := p.norms.AdvanceExact(docID)
+		if err != nil {
+			return err
+		}
+		if found {
+			if norm, err = p.norms.LongValue(); err != nil {
+				return err
+			}
+		}
+	}
+
+	freq := 1
+	if p.writeFreqs {
+		freq = termDocFreq
+	}
+	p.competitiveFreqNormAccumulator.Add(freq, norm)
+	return nil
+}
+
+func (p *PostingsWriter) addPosition(position int, payload []byte, startOffset, endOffset int) error {
+	if position > coreIndex.MAX_POSITION {
+		return fmt.Errorf("position=%d is too large (> IndexWriter.MAX_POSITION=%d)", position, coreIndex.MAX_POSITION)
+	}
+	if position < 0 {
+		return fmt.Errorf("position=%d is < 0", position)
+	}
+	p.posDeltaBuffer[p.posBufferUpto] = uint64(position - p.lastPosition)
+	if p.writePayloads {
+		if len(payload) == 0 {
+			// no payload
+			p.payloadLengthBuffer[p.posBufferUpto] = 0
+		} else {
+			p.payloadLengthBuffer[p.posBufferUpto] = uint64(len(payload))
+			p.payloadBytes = append(p.payloadBytes[:p.payloadByteUpto], payload...)
+			p.payloadByteUpto += len(payload)
+		}
+	}
+
+	if p.writeOffsets {
+		p.offsetStartDeltaBuffer[p.posBufferUpto] = uint64(startOffset - p.lastStartOffset)
+		p.offsetLengthBuffer[p.posBufferUpto] = uint64(endOffset - startOffset)
+		p.lastStartOffset = startOffset
+	}
+
+	p.posBufferUpto++
+	p.lastPosition = position
+	if p.posBufferUpto == BLOCK_SIZE {
+		if err := p.pforUtil.Encode(p.posDeltaBuffer, p.posOut); err != nil {
+			return err
+		}
+
+		if p.writePayloads {
+			if err := p.pforUtil.Encode(p.payloadLengthBuffer, p.payOut); err != nil {
+				return err
+			}
+			if err := p.payOut.WriteUvarint(nil, uint64(p.payloadByteUpto)); err != nil {
+				return err
+			}
+			if _, err := p.payOut.Write(p.payloadBytes[:p.payloadByteUpto]); err != nil {
+				return err
+			}
+			p.payloadByteUpto = 0
+		}
+		if p.writeOffsets {
+			if err := p.pforUtil.Encode(p.offsetStartDeltaBuffer, p.payOut); err != nil {
+				return err
+			}
+			if err := p.pforUtil.Encode(p.offsetLengthBuffer, p.payOut); err != nil {
+				return err
+			}
+		}
+		p.posBufferUpto = 0
+	}
+	return nil
+}
+
+func (p *PostingsWriter) finishDoc() {
+	// Since we don't know df for current term, we had to buffer
+	// those skip data for each block, and when a new doc comes,
+	// write them to skip file.
+	if p.docBufferUpto == BLOCK_SIZE {
+		p.lastBlockDocID = p.lastDocID
+		if p.posOut != nil {
+			if p.payOut != nil {
+				p.lastBlockPayFP = p.payOut.GetFilePointer()
+			}
+			p.lastBlockPosFP = p.posOut.GetFilePointer()
+			p.lastBlockPosBufferUpto = p.posBufferUpto
+			p.lastBlockPayloadByteUpto = p.payloadByteUpto
+		}
+		p.docBufferUpto = 0
+	}
+}
+
+// finishTerm Called when we are done adding docs to this term
+func (p *PostingsWriter) finishTerm(state *IntBlockTermState) error {
+	if state.DocFreq != p.docCount {
+		return fmt.Errorf("docFreq %d vs docCount %d", state.DocFreq, p.docCount)
+	}
+
+	// docFreq == 1, don't write the single docid/freq to a separate file along with a pointer to it.
+	singletonDocID := -1
+	if state.DocFreq == 1 {
+		// pulse the singleton docid into the term dictionary, freq is implicitly totalTermFreq
+		singletonDocID = int(p.docDeltaBuffer[0])
+	} else {
+		// vInt encode the remaining doc deltas and freqs:
+		for i := 0; i < p.docBufferUpto; i++ {
+			docDelta := p.docDeltaBuffer[i]
+			freq := p.freqBuffer[i]
+			if !p.writeFreqs {
+				if err := p.docOut.WriteUvarint(nil, docDelta); err != nil {
+					return err
+				}
+			} else if freq == 1 {
+				if err := p.docOut.WriteUvarint(nil, (docDelta<<1)|1); err != nil {
+					return err
+				}
+			} else {
+				if err := p.docOut.WriteUvarint(nil, docDelta<<1); err != nil {
+					return err
+				}
+				if err := p.docOut.WriteUvarint(nil, freq); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	lastPosBlockOffset := int64(-1)
+	if p.writePositions {
+		// totalTermFreq is just total number of positions(or payloads, or offsets)
+		// associated with current term.
+		if state.TotalTermFreq > BLOCK_SIZE {
+			// record file offset for last pos in last block
+			lastPosBlockOffset = p.posOut.GetFilePointer() - p.posStartFP
+		}
+		if p.posBufferUpto > 0 {
+			if err := p.writeVIntPositions(); err != nil {
+				return err
+			}
+		}
+	}
+
+	skipOffset := int64(-1)
+	if p.docCount > BLOCK_SIZE {
+		skipPointer, err := p.skipWriter.WriteSkip(p.docOut)
+		if err != nil {
+			return err
+		}
+		skipOffset = skipPointer - p.docStartFP
+	}
+
+	state.DocStartFP = p.docStartFP
+	state.PosStartFP = p.posStartFP
+	state.PayStartFP = p.payStartFP
+	state.SingletonDocID = singletonDocID
+	state.SkipOffset = skipOffset
+	state.LastPosBlockOffset = lastPosBlockOffset
+	p.docBufferUpto = 0
+	p.posBufferUpto = 0
+	p.lastDocID = 0
+	p.docCount = 0
+	return nil
+}
+
+// writeVIntPositions vInt encode the remaining positions/payloads/offsets
+func (p *PostingsWriter) writeVIntPositions() error {
+	lastPayloadLength := -1 // force first payload length to be written
+	lastOffsetLength := -1  // force first offset length to be written
+	payloadBytesReadUpto := 0
+	for i := 0; i < p.posBufferUpto; i++ {
+		posDelta := p.posDeltaBuffer[i]
+		if p.writePayloads {
+			payloadLength := int(p.payloadLengthBuffer[i])
+			if payloadLength != lastPayloadLength {
+				lastPayloadLength = payloadLength
+				if err := p.posOut.WriteUvarint(nil, (posDelta<<1)|1); err != nil {
+					return err
+				}
+				if err := p.posOut.WriteUvarint(nil, uint64(payloadLength)); err != nil {
+					return err
+				}
+			} else {
+				if err := p.posOut.WriteUvarint(nil, posDelta<<1); err != nil {
+					return err
+				}
+			}
+
+			if payloadLength != 0 {
+				if _, err := p.posOut.Write(p.payloadBytes[payloadBytesReadUpto : payloadBytesReadUpto+payloadLength]); err != nil {
+					return err
+				}
+				payloadBytesReadUpto += payloadLength
+			}
+		} else {
+			if err := p.posOut.WriteUvarint(nil, posDelta); err != nil {
+				return err
+			}
+		}
+
+		if p.writeOffsets {
+			delta := p.offsetStartDeltaBuffer[i]
+			length := int(p.offsetLengthBuffer[i])
+			if length == lastOffsetLength {
+				if err := p.posOut.WriteUvarint(nil, delta<<1); err != nil {
+					return err
+				}
+			} else {
+				if err := p.posOut.WriteUvarint(nil, delta<<1|1); err != nil {
+					return err
+				}
+				if err := p.posOut.WriteUvarint(nil, uint64(length)); err != nil {
+					return err
+				}
+				lastOffsetLength = length
+			}
+		}
+	}
+
+	if p.writePayloads {
+		p.payloadByteUpto = 0
+	}
+	return nil
+}
+
+func (p *PostingsWriter) EncodeTerm(out store.DataOutput, fieldInfo *document.FieldInfo,
+	termState codecs.BlockTermState, absolute bool) error {
+
+	state, ok := termState.(*IntBlockTermState)
+	if !ok {
+		return fmt.Errorf("unexpected term state %T", termState)
+	}
+	if absolute {
+		p.lastState = NewIntBlockTermState()
+	}
+	lastState := p.lastState
+
+	if lastState.SingletonDocID != -1 && state.SingletonDocID != -1 && state.DocStartFP == lastState.DocStartFP {
+		// With runs of rare values such as ID fields, the increment of pointers in the docs file is often 0.
+		// Furthermore some ID schemes like auto-increment IDs or Flake IDs are monotonic, so we encode the delta
+		// between consecutive doc IDs to save space.
+		delta := int64(state.SingletonDocID) - int64(lastState.SingletonDocID)
+		if err := out.WriteUvarint(nil, (zigzag.Encode(delta)<<1)|0x01); err != nil {
+			return err
+		}
+	} else {
+		if err := out.WriteUvarint(nil, uint64(state.DocStartFP-lastState.DocStartFP)<<1); err != nil {
+			return err
+		}
+		if state.SingletonDocID != -1 {
+			if err := out.WriteUvarint(nil, uint64(state.SingletonDocID)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p.writePositions {
+		if err := out.WriteUvarint(nil, uint64(state.PosStartFP-lastState.PosStartFP)); err != nil {
+			return err
+		}
+		if p.writePayloads || p.writeOffsets {
+			if err := out.WriteUvarint(nil, uint64(state.PayStartFP-lastState.PayStartFP)); err != nil {
+				return err
+			}
+		}
+		if state.LastPosBlockOffset != -1 {
+			if err := out.WriteUvarint(nil, uint64(state.LastPosBlockOffset)); err != nil {
+				return err
+			}
+		}
+	}
+	if state.SkipOffset != -1 {
+		if err := out.WriteUvarint(nil, uint64(state.SkipOffset)); err != nil {
+			return err
+		}
+	}
+	p.lastState = state
+	return nil
+}
+
+func (p *PostingsWriter) Close() error {
+	for _, out := range []store.IndexOutput{p.docOut, p.posOut, p.payOut} {
+		if out == nil {
+			continue
+		}
+		if err := utils.WriteFooter(out); err != nil {
+			_ = p.closeOutputs()
+			return err
+		}
+	}
+	return p.closeOutputs()
+}
+
+func (p *PostingsWriter) closeOutputs() error {
+	var firstErr error
+	for _, out := range []store.IndexOutput{p.docOut, p.posOut, p.payOut} {
+		if out == nil {
+			continue
+		}
+		if err := out.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	p.docOut, p.posOut, p.payOut = nil, nil, nil
+	return firstErr
+}
@@ -0,0 +1,217 @@
+package lucene84
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/bits-and-blooms/bitset"
+	"github.com/geange/lucene-go/codecs"
+	"github.com/geange/lucene-go/codecs/codectest"
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTerm(r *rand.Rand, term string, docFreq, docGap, maxFreq int) codectest.Term {
+	docs := make([]codectest.Doc, 0, docFreq)
+	doc := 0
+	for i := 0; i < docFreq; i++ {
+		doc += 1 + r.Intn(docGap)
+		freq := 1 + r.Intn(maxFreq)
+		positions := make([]codectest.Position, 0, freq)
+		pos, offset := 0, 0
+		for j := 0; j < freq; j++ {
+			pos += r.Intn(10)
+			offset += r.Intn(20)
+			var payload []byte
+			if r.Intn(3) == 0 {
+				payload = make([]byte, 1+r.Intn(4))
+				r.Read(payload)
+			}
+			positions = append(positions, codectest.Position{
+				Pos:         pos,
+				StartOffset: offset,
+				EndOffset:   offset + 1 + r.Intn(10),
+				Payload:     payload,
+			})
+		}
+		docs = append(docs, codectest.Doc{ID: doc, Positions: positions})
+	}
+	return codectest.Term{Text: []byte(term), Docs: docs}
+}
+
+func TestPostingsWriterReader(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	fieldInfo := document.NewFieldInfo("body", 0, false, true, true,
+		document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS,
+		document.DOC_VALUES_TYPE_NONE, -1, map[string]string{}, 0, 0, 0, false)
+	fieldInfos := coreIndex.NewFieldInfos([]*document.FieldInfo{fieldInfo})
+
+	id := make([]byte, 16)
+	r.Read(id)
+	segmentInfo := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", 100000,
+		false, nil, map[string]string{}, id, map[string]string{}, nil)
+
+	terms := []codectest.Term{
+		{Text: []byte("single"), Docs: []codectest.Doc{{ID: 7, Positions: []codectest.Position{{Pos: 1, StartOffset: 2, EndOffset: 5}}}}},
+		{Text: []byte("single2"), Docs: []codectest.Doc{{ID: 3, Positions: []codectest.Position{{Pos: 4, StartOffset: 6, EndOffset: 9}}}}},
+		newTestTerm(r, "medium", 300, 3, 2),
+		newTestTerm(r, "large", 1000, 20, 6),
+	}
+
+	// write postings, encoding the term metadata into the terms file
+	writeState := index.NewSegmentWriteState(dir, segmentInfo, fieldInfos, nil, nil)
+	writer, err := NewPostingsWriter(writeState)
+	assert.Nil(t, err)
+	termsOut, err := dir.CreateOutput(nil, "_0.tim")
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Init(termsOut, writeState))
+	writer.SetField(fieldInfo)
+
+	docsSeen := bitset.New(0)
+	states := make([]codecs.BlockTermState, 0, len(terms))
+	for i := range terms {
+		termsEnum := codectest.NewTermsEnum(terms[i : i+1])
+		_, err := termsEnum.Next(context.Background())
+		assert.Nil(t, err)
+		state, err := writer.WriteTerm(terms[i].Text, termsEnum, docsSeen, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, len(terms[i].Docs), state.GetBlockTermState().DocFreq)
+		assert.Nil(t, writer.EncodeTerm(termsOut, fieldInfo, state, i == 0))
+		states = append(states, state)
+	}
+	assert.Nil(t, utils.WriteFooter(termsOut))
+	assert.Nil(t, termsOut.Close())
+	assert.Nil(t, writer.Close())
+
+	// read back
+	readState := index.NewSegmentReadState(dir, segmentInfo, fieldInfos, nil, "")
+	reader, err := NewPostingsReader(readState)
+	assert.Nil(t, err)
+	defer reader.Close()
+	assert.Nil(t, reader.CheckIntegrity())
+
+	termsIn, err := dir.OpenInput(nil, "_0.tim")
+	assert.Nil(t, err)
+	defer termsIn.Close()
+	assert.Nil(t, reader.Init(termsIn, readState))
+
+	state := reader.NewTermState()
+	for i := range terms {
+		state.GetBlockTermState().DocFreq = states[i].GetBlockTermState().DocFreq
+		state.GetBlockTermState().TotalTermFreq = states[i].GetBlockTermState().TotalTermFreq
+		assert.Nil(t, reader.DecodeTerm(termsIn, fieldInfo, state, i == 0))
+		termState := state.Clone()
+
+		t.Run(string(terms[i].Text), func(t *testing.T) {
+			checkAllPostings(t, reader, fieldInfo, termState, terms[i].Docs)
+			checkDocsAdvance(t, reader, fieldInfo, termState, terms[i].Docs)
+			checkImpacts(t, reader, fieldInfo, termState, terms[i].Docs)
+		})
+	}
+}
+
+func checkAllPostings(t *testing.T, reader *PostingsReader, fieldInfo *document.FieldInfo,
+	state codecs.BlockTermState, docs []codectest.Doc) {
+
+	postings, err := reader.Postings(fieldInfo, state, nil, coreIndex.POSTINGS_ENUM_ALL)
+	assert.Nil(t, err)
+	for _, doc := range docs {
+		docID, err := postings.NextDoc()
+		assert.Nil(t, err)
+		assert.Equal(t, doc.ID, docID)
+		freq, err := postings.Freq()
+		assert.Nil(t, err)
+		assert.Equal(t, len(doc.Positions), freq)
+		for _, position := range doc.Positions {
+			pos, err := postings.NextPosition()
+			assert.Nil(t, err)
+			assert.Equal(t, position.Pos, pos)
+			start, err := postings.StartOffset()
+			assert.Nil(t, err)
+			assert.Equal(t, position.StartOffset, start)
+			end, err := postings.EndOffset()
+			assert.Nil(t, err)
+			assert.Equal(t, position.EndOffset, end)
+			payload, err := postings.GetPayload()
+			assert.Nil(t, err)
+			assert.Equal(t, len(position.Payload), len(payload))
+			if len(position.Payload) > 0 {
+				assert.Equal(t, position.Payload, payload)
+			}
+		}
+	}
+	docID, err := postings.NextDoc()
+	assert.Nil(t, err)
+	assert.Equal(t, types.NO_MORE_DOCS, docID)
+
+	// advance, positions must stay in sync with the skipped documents
+	postings, err = reader.Postings(fieldInfo, state, postings, coreIndex.POSTINGS_ENUM_ALL)
+	assert.Nil(t, err)
+	for i := 0; i < len(docs); i += 97 {
+		docID, err := postings.Advance(docs[i].ID)
+		assert.Nil(t, err)
+		assert.Equal(t, docs[i].ID, docID)
+		pos, err := postings.NextPosition()
+		assert.Nil(t, err)
+		assert.Equal(t, docs[i].Positions[0].Pos, pos)
+	}
+}
+
+func checkDocsAdvance(t *testing.T, reader *PostingsReader, fieldInfo *document.FieldInfo,
+	state codecs.BlockTermState, docs []codectest.Doc) {
+
+	postings, err := reader.Postings(fieldInfo, state, nil, coreIndex.POSTINGS_ENUM_FREQS)
+	assert.Nil(t, err)
+	for i := 0; i < len(docs); i += 131 {
+		// target in the gap before the document lands on the document
+		docID, err := postings.Advance(docs[i].ID)
+		assert.Nil(t, err)
+		assert.Equal(t, docs[i].ID, docID)
+		freq, err := postings.Freq()
+		assert.Nil(t, err)
+		assert.Equal(t, len(docs[i].Positions), freq)
+	}
+	docID, err := postings.Advance(docs[len(docs)-1].ID + 1)
+	assert.Nil(t, err)
+	assert.Equal(t, types.NO_MORE_DOCS, docID)
+}
+
+func checkImpacts(t *testing.T, reader *PostingsReader, fieldInfo *document.FieldInfo,
+	state codecs.BlockTermState, docs []codectest.Doc) {
+
+	impactsEnum, err := reader.Impacts(fieldInfo, state, coreIndex.POSTINGS_ENUM_FREQS)
+	assert.Nil(t, err)
+
+	maxFreq := 0
+	for _, doc := range docs {
+		maxFreq = max(maxFreq, len(doc.Positions))
+	}
+
+	target := docs[len(docs)/2].ID
+	assert.Nil(t, impactsEnum.AdvanceShallow(target))
+	impacts, err := impactsEnum.GetImpacts()
+	assert.Nil(t, err)
+	assert.True(t, impacts.NumLevels() > 0)
+	assert.True(t, impacts.GetDocIdUpTo(0) >= target)
+	if len(docs) > BLOCK_SIZE {
+		// impacts are only recorded in skip data, smaller terms report a dummy impact
+		for _, impact := range impacts.GetImpacts(0) {
+			assert.True(t, impact.GetFreq() <= maxFreq)
+		}
+	}
+
+	docID, err := impactsEnum.Advance(target)
+	assert.Nil(t, err)
+	assert.Equal(t, target, docID)
+}
@@ -0,0 +1,321 @@
+package lucene84
+
+import (
+	"io"
+	"math"
+
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+)
+
+// SkipReader Implements the skip list reader for block postings format that stores positions and payloads.
+//
+// Although this skipper uses MultiLevelSkipListReader as an interface, its definition of skip position
+// will be a little different.
+//
+// For example, when skipInterval = blockSize = 3, df = 2*skipInterval = 6,
+//
+//	0 1 2 3 4 5
+//	d d d d d d    (posting list)
+//	    ^     ^    (skip point in MultiLeveSkipWriter)
+//	      ^        (skip point in Lucene84SkipWriter)
+//
+// In this case, MultiLevelSkipListReader will use the last document as a skip point, while
+// Lucene84SkipReader should assume no skip point will comes.
+//
+// If we use the interface directly in Lucene84SkipReader, it may silly try to read another skip data
+// after the only skip point is loaded.
+//
+// To illustrate this, we can call skipTo(d[5]), since skip point d[3] has smaller docId, and numSkipped+blockSize== df,
+// the MultiLevelSkipListReader will assume the skip list isn't exhausted yet, and try to load a non-existed skip point
+//
+// Therefore, we'll trim df before passing it to the interface. see trim(int)
+type SkipReader struct {
+	*coreIndex.BaseMultiLevelSkipListReader
+
+	docPointer      []int64
+	posPointer      []int64
+	payPointer      []int64
+	posBufferUpto   []int
+	payloadByteUpto []int
+
+	lastPosPointer      int64
+	lastPayPointer      int64
+	lastPayloadByteUpto int
+	lastDocPointer      int64
+	lastPosBufferUpto   int
+
+	// impacts, only decoded on demand
+	impactData       [][]byte
+	impactDataLength []int
+	perLevelImpacts  [][]index.Impact
+	numLevels        int
+	impacts          index.Impacts
+}
+
+func NewSkipReader(skipStream store.IndexInput, maxSkipLevels int,
+	hasPos, hasOffsets, hasPayloads bool) *SkipReader {
+
+	reader := &SkipReader{
+		docPointer:       make([]int64, maxSkipLevels),
+		impactData:       make([][]byte, maxSkipLevels),
+		impactDataLength: make([]int, maxSkipLevels),
+		perLevelImpacts:  make([][]index.Impact, maxSkipLevels),
+		numLevels:        1,
+	}
+	if hasPos {
+		reader.posPointer = make([]int64, maxSkipLevels)
+		reader.posBufferUpto = make([]int, maxSkipLevels)
+		if hasPayloads {
+			reader.payloadByteUpto = make([]int, maxSkipLevels)
+		}
+		if hasOffsets || hasPayloads {
+			reader.payPointer = make([]int64, maxSkipLevels)
+		}
+	}
+	for i := range reader.perLevelImpacts {
+		reader.perLevelImpacts[i] = []index.Impact{coreIndex.NewImpact(math.MaxInt32, 1)}
+	}
+	reader.impacts = &skipImpacts{reader}
+
+	reader.BaseMultiLevelSkipListReader = coreIndex.NewBaseMultiLevelSkipListReaderV1(&coreIndex.BaseMultiLevelSkipListReaderConfig{
+		SkipStream:      skipStream,
+		MaxSkipLevels:   maxSkipLevels,
+		SkipInterval:    BLOCK_SIZE,
+		SkipMultiplier:  8,
+		ReadSkipData:    reader.readSkipData,
+		SeekChild:       reader.seekChild,
+		SetLastSkipData: reader.setLastSkipData,
+	})
+	return reader
+}
+
+// Trim size of block; if size is exactly a multiple of the block size, the last skip point
+// would never be used, so it is removed.
+func trim(df int) int {
+	if df%BLOCK_SIZE == 0 {
+		return df - 1
+	}
+	return df
+}
+
+func (s *SkipReader) InitV1(skipPointer, docBasePointer, posBasePointer, payBasePointer int64, df int) error {
+	if err := s.Init(skipPointer, trim(df)); err != nil {
+		return err
+	}
+	s.lastDocPointer = docBasePointer
+	s.lastPosPointer = posBasePointer
+	s.lastPayPointer = payBasePointer
+
+	for i := range s.docPointer {
+		s.docPointer[i] = docBasePointer
+	}
+	if s.posPointer != nil {
+		for i := range s.posPointer {
+			s.posPointer[i] = posBasePointer
+		}
+		if s.payPointer != nil {
+			for i := range s.payPointer {
+				s.payPointer[i] = payBasePointer
+			}
+		}
+	}
+	for i := range s.impactDataLength {
+		s.impactDataLength[i] = 0
+		s.perLevelImpacts[i] = append(s.perLevelImpacts[i][:0], coreIndex.NewImpact(math.MaxInt32, 1))
+	}
+	return nil
+}
+
+// GetDocPointer Returns the doc pointer of the doc to which the last call of
+// MultiLevelSkipListReader.skipTo(int) has skipped.
+func (s *SkipReader) GetDocPointer() int64 {
+	return s.lastDocPointer
+}
+
+func (s *SkipReader) GetPosPointer() int64 {
+	return s.lastPosPointer
+}
+
+func (s *SkipReader) GetPosBufferUpto() int {
+	return s.lastPosBufferUpto
+}
+
+func (s *SkipReader) GetPayPointer() int64 {
+	return s.lastPayPointer
+}
+
+func (s *SkipReader) GetPayloadByteUpto() int {
+	return s.lastPayloadByteUpto
+}
+
+func (s *SkipReader) GetNextSkipDoc() int {
+	return s.GetSkipDoc(0)
+}
+
+func (s *SkipReader) SkipTo(target int) (int, error) {
+	result, err := s.BaseMultiLevelSkipListReader.SkipTo(target)
+	if err != nil {
+		return 0, err
+	}
+	if n := s.NumberOfSkipLevels(); n > 0 {
+		s.numLevels = n
+	} else {
+		// End of postings don't have skip data anymore, so we fill with dummy data
+		// like SlowImpactsEnum.
+		s.numLevels = 1
+		s.perLevelImpacts[0] = append(s.perLevelImpacts[0][:0], coreIndex.NewImpact(math.MaxInt32, 1))
+		s.impactDataLength[0] = 0
+	}
+	return result, nil
+}
+
+func (s *SkipReader) GetImpacts() index.Impacts {
+	return s.impacts
+}
+
+func (s *SkipReader) seekChild(level int) {
+	s.docPointer[level] = s.lastDocPointer
+	if s.posPointer != nil {
+		s.posPointer[level] = s.lastPosPointer
+		s.posBufferUpto[level] = s.lastPosBufferUpto
+		if s.payloadByteUpto != nil {
+			s.payloadByteUpto[level] = s.lastPayloadByteUpto
+		}
+		if s.payPointer != nil {
+			s.payPointer[level] = s.lastPayPointer
+		}
+	}
+}
+
+func (s *SkipReader) setLastSkipData(level int) {
+	s.lastDocPointer = s.docPointer[level]
+
+	if s.posPointer != nil {
+		s.lastPosPointer = s.posPointer[level]
+		s.lastPosBufferUpto = s.posBufferUpto[level]
+		if s.payPointer != nil {
+			s.lastPayPointer = s.payPointer[level]
+		}
+		if s.payloadByteUpto != nil {
+			s.lastPayloadByteUpto = s.payloadByteUpto[level]
+		}
+	}
+}
+
+func (s *SkipReader) readSkipData(level int, skipStream store.IndexInput) (int, error) {
+	delta, err := skipStream.ReadUvarint(nil)
+	if err != nil {
+		return 0, err
+	}
+	docDelta, err := skipStream.ReadUvarint(nil)
+	if err != nil {
+		return 0, err
+	}
+	s.docPointer[level] += int64(docDelta)
+
+	if s.posPointer != nil {
+		posDelta, err := skipStream.ReadUvarint(nil)
+		if err != nil {
+			return 0, err
+		}
+		s.posPointer[level] += int64(posDelta)
+
+		posBufferUpto, err := skipStream.ReadUvarint(nil)
+		if err != nil {
+			return 0, err
+		}
+		s.posBufferUpto[level] = int(posBufferUpto)
+
+		if s.payloadByteUpto != nil {
+			payloadByteUpto, err := skipStream.ReadUvarint(nil)
+			if err != nil {
+				return 0, err
+			}
+			s.payloadByteUpto[level] = int(payloadByteUpto)
+		}
+
+		if s.payPointer != nil {
+			payDelta, err := skipStream.ReadUvarint(nil)
+			if err != nil {
+				return 0, err
+			}
+			s.payPointer[level] += int64(payDelta)
+		}
+	}
+
+	if err := s.readImpacts(level, skipStream); err != nil {
+		return 0, err
+	}
+	return int(delta), nil
+}
+
+func (s *SkipReader) readImpacts(level int, skipStream store.IndexInput) error {
+	length, err := skipStream.ReadUvarint(nil)
+	if err != nil {
+		return err
+	}
+	if len(s.impactData[level]) < int(length) {
+		s.impactData[level] = make([]byte, length)
+	}
+	if _, err := io.ReadFull(skipStream, s.impactData[level][:length]); err != nil {
+		return err
+	}
+	s.impactDataLength[level] = int(length)
+	return nil
+}
+
+// decodeImpacts Decodes impacts written by writeImpacts.
+func decodeImpacts(in *store.BytesInput, length int, reuse []index.Impact) ([]index.Impact, error) {
+	reuse = reuse[:0]
+	freq := 0
+	norm := int64(0)
+	for in.GetFilePointer() < int64(length) {
+		freqDelta, err := in.ReadUvarint(nil)
+		if err != nil {
+			return nil, err
+		}
+		if freqDelta&0x01 != 0 {
+			freq += 1 + int(freqDelta>>1)
+			normDelta, err := in.ReadZInt64(nil)
+			if err != nil {
+				return nil, err
+			}
+			norm += 1 + normDelta
+		} else {
+			freq += 1 + int(freqDelta>>1)
+			norm++
+		}
+		reuse = append(reuse, coreIndex.NewImpact(freq, norm))
+	}
+	return reuse, nil
+}
+
+var _ index.Impacts = &skipImpacts{}
+
+type skipImpacts struct {
+	r *SkipReader
+}
+
+func (i *skipImpacts) NumLevels() int {
+	return i.r.numLevels
+}
+
+func (i *skipImpacts) GetDocIdUpTo(level int) int {
+	return i.r.GetSkipDoc(level)
+}
+
+func (i *skipImpacts) GetImpacts(level int) []index.Impact {
+	r := i.r
+	if r.impactDataLength[level] > 0 {
+		in := store.NewBytesInput(r.impactData[level][:r.impactDataLength[level]])
+		impacts, err := decodeImpacts(in, r.impactDataLength[level], r.perLevelImpacts[level])
+		if err != nil {
+			impacts = append(r.perLevelImpacts[level][:0], coreIndex.NewImpact(math.MaxInt32, 1))
+		}
+		r.perLevelImpacts[level] = impacts
+		r.impactDataLength[level] = 0
+	}
+	return r.perLevelImpacts[level]
+}
@@ -0,0 +1,233 @@
+package lucene84
+
+import (
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+)
+
+// SkipWriter Write skip lists with multiple levels, and support skip within block ints.
+//
+// Assume that docFreq = 28, skipInterval = blockSize = 12
+//
+//	|       block#0       | |      block#1        | |vInts|
+//	d d d d d d d d d d d d d d d d d d d d d d d d d d d d (posting list)
+//	                        ^                       ^       (level 0 skip point)
+//
+// Note that skipWriter will ignore first document in block#0, since it is useless as a skip point.
+// Also, we'll never skip into the vInts block, only record skip data at the start its start point(if it exist).
+//
+// For each skip point, we will record:
+//  1. docID in former position, i.e. for position 12, record docID[11], etc.
+//  2. its related file points(position, payload),
+//  3. related numbers or uptos(position, payload).
+//  4. start offset.
+type SkipWriter struct {
+	*coreIndex.BaseMultiLevelSkipListWriter
+
+	lastSkipDoc         []int
+	lastSkipDocPointer  []int64
+	lastSkipPosPointer  []int64
+	lastSkipPayPointer  []int64
+	lastPayloadByteUpto []int
+
+	docOut store.IndexOutput
+	posOut store.IndexOutput
+	payOut store.IndexOutput
+
+	curDoc             int
+	curDocPointer      int64
+	curPosPointer      int64
+	curPayPointer      int64
+	curPosBufferUpto   int
+	curPayloadByteUpto int
+
+	curCompetitiveFreqNorms []*coreIndex.CompetitiveImpactAccumulator
+	fieldHasPositions       bool
+	fieldHasOffsets         bool
+	fieldHasPayloads        bool
+
+	lastDocFP   int64
+	lastPosFP   int64
+	lastPayFP   int64
+	initialized bool
+
+	freqNormOut *store.BufferOutput
+}
+
+func NewSkipWriter(maxSkipLevels, blockSize, docCount int, docOut, posOut, payOut store.IndexOutput) *SkipWriter {
+	writer := &SkipWriter{
+		lastSkipDoc:             make([]int, maxSkipLevels),
+		lastSkipDocPointer:      make([]int64, maxSkipLevels),
+		docOut:                  docOut,
+		posOut:                  posOut,
+		payOut:                  payOut,
+		curCompetitiveFreqNorms: make([]*coreIndex.CompetitiveImpactAccumulator, maxSkipLevels),
+		freqNormOut:             store.NewBufferDataOutput(),
+	}
+	if posOut != nil {
+		writer.lastSkipPosPointer = make([]int64, maxSkipLevels)
+		if payOut != nil {
+			writer.lastSkipPayPointer = make([]int64, maxSkipLevels)
+		}
+		writer.lastPayloadByteUpto = make([]int, maxSkipLevels)
+	}
+	for i := range writer.curCompetitiveFreqNorms {
+		writer.curCompetitiveFreqNorms[i] = coreIndex.NewCompetitiveImpactAccumulator()
+	}
+
+	writer.BaseMultiLevelSkipListWriter = coreIndex.NewBaseMultiLevelSkipListWriter(&coreIndex.BaseMultiLevelSkipListWriterConfig{
+		SkipInterval:   blockSize,
+		SkipMultiplier: 8,
+		MaxSkipLevels:  maxSkipLevels,
+		DF:             docCount,
+		WriteSkipData:  writer.WriteSkipData,
+	})
+	return writer
+}
+
+func (s *SkipWriter) SetField(fieldHasPositions, fieldHasOffsets, fieldHasPayloads bool) {
+	s.fieldHasPositions = fieldHasPositions
+	s.fieldHasOffsets = fieldHasOffsets
+	s.fieldHasPayloads = fieldHasPayloads
+}
+
+// ResetSkip tricky: we only skip data for blocks (terms with more than 128 docs), but re-init'ing the
+// skipper is pretty slow for rare terms in large segments as we have to fill O(log #docs in segment)
+// of junk. this is the vast majority of terms (worst case: ID field or similar). so in resetSkip() we
+// save away the previous pointers, and lazy-init only if we need to buffer skip data for the term.
+func (s *SkipWriter) ResetSkip() {
+	s.lastDocFP = s.docOut.GetFilePointer()
+	if s.fieldHasPositions {
+		s.lastPosFP = s.posOut.GetFilePointer()
+		if s.fieldHasOffsets || s.fieldHasPayloads {
+			s.lastPayFP = s.payOut.GetFilePointer()
+		}
+	}
+	s.initialized = false
+}
+
+func (s *SkipWriter) initSkip() {
+	if s.initialized {
+		return
+	}
+
+	s.BaseMultiLevelSkipListWriter.ResetSkip()
+	for i := range s.lastSkipDoc {
+		s.lastSkipDoc[i] = 0
+		s.lastSkipDocPointer[i] = s.lastDocFP
+	}
+	if s.fieldHasPositions {
+		for i := range s.lastSkipPosPointer {
+			s.lastSkipPosPointer[i] = s.lastPosFP
+		}
+		if s.fieldHasPayloads {
+			for i := range s.lastPayloadByteUpto {
+				s.lastPayloadByteUpto[i] = 0
+			}
+		}
+		if s.fieldHasOffsets || s.fieldHasPayloads {
+			for i := range s.lastSkipPayPointer {
+				s.lastSkipPayPointer[i] = s.lastPayFP
+			}
+		}
+	}
+	// sets of competitive freq,norm pairs should be empty at this point
+	for _, acc := range s.curCompetitiveFreqNorms {
+		acc.Clear()
+	}
+	s.initialized = true
+}
+
+// BufferSkipV1 Sets the values for the current skip data.
+func (s *SkipWriter) BufferSkipV1(doc int, competitiveFreqNorms *coreIndex.CompetitiveImpactAccumulator,
+	numDocs int, posFP, payFP int64, posBufferUpto, payloadByteUpto int) error {
+
+	s.initSkip()
+	s.curDoc = doc
+	s.curDocPointer = s.docOut.GetFilePointer()
+	s.curPosPointer = posFP
+	s.curPayPointer = payFP
+	s.curPosBufferUpto = posBufferUpto
+	s.curPayloadByteUpto = payloadByteUpto
+	s.curCompetitiveFreqNorms[0].AddAll(competitiveFreqNorms)
+	return s.BufferSkip(numDocs)
+}
+
+func (s *SkipWriter) WriteSkipData(level int, skipBuffer store.IndexOutput) error {
+	delta := s.curDoc - s.lastSkipDoc[level]
+
+	if err := skipBuffer.WriteUvarint(nil, uint64(delta)); err != nil {
+		return err
+	}
+	s.lastSkipDoc[level] = s.curDoc
+
+	if err := skipBuffer.WriteUvarint(nil, uint64(s.curDocPointer-s.lastSkipDocPointer[level])); err != nil {
+		return err
+	}
+	s.lastSkipDocPointer[level] = s.curDocPointer
+
+	if s.fieldHasPositions {
+		if err := skipBuffer.WriteUvarint(nil, uint64(s.curPosPointer-s.lastSkipPosPointer[level])); err != nil {
+			return err
+		}
+		s.lastSkipPosPointer[level] = s.curPosPointer
+		if err := skipBuffer.WriteUvarint(nil, uint64(s.curPosBufferUpto)); err != nil {
+			return err
+		}
+
+		if s.fieldHasPayloads {
+			if err := skipBuffer.WriteUvarint(nil, uint64(s.curPayloadByteUpto)); err != nil {
+				return err
+			}
+		}
+
+		if s.fieldHasOffsets || s.fieldHasPayloads {
+			if err := skipBuffer.WriteUvarint(nil, uint64(s.curPayPointer-s.lastSkipPayPointer[level])); err != nil {
+				return err
+			}
+			s.lastSkipPayPointer[level] = s.curPayPointer
+		}
+	}
+
+	competitiveFreqNorms := s.curCompetitiveFreqNorms[level]
+	if level+1 < s.NumberOfSkipLevels() {
+		s.curCompetitiveFreqNorms[level+1].AddAll(competitiveFreqNorms)
+	}
+	if err := writeImpacts(competitiveFreqNorms, s.freqNormOut); err != nil {
+		return err
+	}
+	if err := skipBuffer.WriteUvarint(nil, uint64(s.freqNormOut.GetFilePointer())); err != nil {
+		return err
+	}
+	if err := s.freqNormOut.CopyTo(skipBuffer); err != nil {
+		return err
+	}
+	s.freqNormOut.Reset()
+	competitiveFreqNorms.Clear()
+	return nil
+}
+
+func writeImpacts(acc *coreIndex.CompetitiveImpactAccumulator, out store.DataOutput) error {
+	impacts := acc.GetCompetitiveFreqNormPairs()
+	var previous index.Impact = coreIndex.NewImpact(0, 0)
+	for _, impact := range impacts {
+		freqDelta := impact.GetFreq() - previous.GetFreq() - 1
+		normDelta := impact.GetNorm() - previous.GetNorm() - 1
+		if normDelta == 0 {
+			// most of time, norm only increases by 1, so we can fold everything in a single byte
+			if err := out.WriteUvarint(nil, uint64(freqDelta<<1)); err != nil {
+				return err
+			}
+		} else {
+			if err := out.WriteUvarint(nil, uint64((freqDelta<<1)|1)); err != nil {
+				return err
+			}
+			if err := out.WriteZInt64(nil, normDelta); err != nil {
+				return err
+			}
+		}
+		previous = impact
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package lucene84
+
+import (
+	"fmt"
+
+	"github.com/geange/lucene-go/codecs"
+	"github.com/geange/lucene-go/core/interface/index"
+)
+
+const (
+	// DOC_EXTENSION Filename extension for document number, frequencies, and skip data.
+	DOC_EXTENSION = "doc"
+
+	// POS_EXTENSION Filename extension for positions.
+	POS_EXTENSION = "pos"
+
+	// PAY_EXTENSION Filename extension for payloads and offsets.
+	PAY_EXTENSION = "pay"
+
+	// MAX_SKIP_LEVELS Expert: The maximum number of skip levels. Smaller values result in slightly
+	// smaller indexes, but slower skipping in big posting lists.
+	MAX_SKIP_LEVELS = 10
+
+	TERMS_CODEC = "Lucene84PostingsWriterTerms"
+	DOC_CODEC   = "Lucene84PostingsWriterDoc"
+	POS_CODEC   = "Lucene84PostingsWriterPos"
+	PAY_CODEC   = "Lucene84PostingsWriterPay"
+
+	VERSION_START                     = 0
+	VERSION_COMPRESSED_TERMS_DICT_IDS = 1
+	VERSION_CURRENT                   = VERSION_COMPRESSED_TERMS_DICT_IDS
+)
+
+var _ codecs.BlockTermState = &IntBlockTermState{}
+
+// IntBlockTermState Holds all state required for PostingsReader to produce a PostingsEnum
+// without re-seeking the terms dict.
+type IntBlockTermState struct {
+	*codecs.BaseBlockTermState
+
+	// DocStartFP file pointer to the start of the doc ids enumeration, in DOC_EXTENSION file
+	DocStartFP int64
+
+	// PosStartFP file pointer to the start of the positions enumeration, in POS_EXTENSION file
+	PosStartFP int64
+
+	// PayStartFP file pointer to the start of the payloads enumeration, in PAY_EXTENSION file
+	PayStartFP int64
+
+	// SkipOffset file offset for the start of the skip list, relative to docStartFP, if there are more
+	// than BLOCK_SIZE docs; otherwise -1
+	SkipOffset int64
+
+	// LastPosBlockOffset file offset for the last position in the last block, if there are more than
+	// BLOCK_SIZE positions; otherwise -1
+	LastPosBlockOffset int64
+
+	// SingletonDocID docid when there is a single pulsed posting, otherwise -1.
+	// freq is always implicitly totalTermFreq in this case.
+	SingletonDocID int
+}
+
+func NewIntBlockTermState() *IntBlockTermState {
+	return &IntBlockTermState{
+		BaseBlockTermState: codecs.NewBaseBlockTermState(),
+		SkipOffset:         -1,
+		LastPosBlockOffset: -1,
+		SingletonDocID:     -1,
+	}
+}
+
+func (s *IntBlockTermState) Clone() codecs.BlockTermState {
+	other := NewIntBlockTermState()
+	other.CopyFrom(s)
+	return other
+}
+
+func (s *IntBlockTermState) CopyFrom(other index.TermState) {
+	s.BaseBlockTermState.CopyFrom(other)
+	if v, ok := other.(*IntBlockTermState); ok {
+		s.DocStartFP = v.DocStartFP
+		s.PosStartFP = v.PosStartFP
+		s.PayStartFP = v.PayStartFP
+		s.LastPosBlockOffset = v.LastPosBlockOffset
+		s.SkipOffset = v.SkipOffset
+		s.SingletonDocID = v.SingletonDocID
+	}
+}
+
+func (s *IntBlockTermState) String() string {
+	return fmt.Sprintf("%s docStartFP=%d posStartFP=%d payStartFP=%d lastPosStartFP=%d singletonDocID=%d",
+		s.BaseBlockTermState.String(), s.DocStartFP, s.PosStartFP, s.PayStartFP, s.LastPosBlockOffset, s.SingletonDocID)
+}
@@ -0,0 +1,364 @@
+package lucene86
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/bkd"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPointsFormat(t *testing.T) {
+	testPointsFormat(t, bkd.DEFAULT_MAX_POINTS_IN_LEAF_NODE, bkd.DEFAULT_MAX_MB_SORT_IN_HEAP)
+}
+
+func TestPointsFormatOfflineSorter(t *testing.T) {
+	// small leaves and a tiny heap budget, so that points added one by one spill to disk
+	testPointsFormat(t, 50, 0.01)
+}
+
+func testPointsFormat(t *testing.T, maxPointsInLeafNode int, maxMBSortInHeap float64) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+	maxDoc := 3000
+
+	fields := map[string]*testPoints{
+		"point_1d":       randomPoints(r, maxDoc, 5000, 1, 8),
+		"point_1d_plain": randomPoints(r, maxDoc, 5000, 1, 8),
+		"point_2d":       randomPoints(r, maxDoc, 5000, 2, 4),
+		"point_2d_plain": randomPoints(r, maxDoc, 5000, 2, 4),
+		"point_3d":       randomPoints(r, maxDoc, 5000, 3, 2),
+		"point_3d_plain": randomPoints(r, maxDoc, 5000, 3, 2),
+		"point_empty":    randomPoints(r, maxDoc, 0, 1, 4),
+	}
+	names := []string{"point_1d", "point_1d_plain", "point_2d", "point_2d_plain",
+		"point_3d", "point_3d_plain", "point_empty"}
+
+	infos := make([]*document.FieldInfo, 0, len(names))
+	for i, name := range names {
+		p := fields[name]
+		infos = append(infos, document.NewFieldInfo(name, i, false, true, false,
+			document.INDEX_OPTIONS_NONE, document.DOC_VALUES_TYPE_NONE, -1, map[string]string{},
+			p.numDims, p.numDims, p.bytesPerDim, false))
+	}
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+	id := make([]byte, 16)
+	r.Read(id)
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", maxDoc,
+		false, nil, map[string]string{}, id, map[string]string{}, nil)
+	fieldInfos := coreIndex.NewFieldInfos(infos)
+
+	format := NewPointsFormat()
+	writer, err := NewPointsWriter(ctx, &index.SegmentWriteState{
+		Directory:   dir,
+		SegmentInfo: si,
+		FieldInfos:  fieldInfos,
+	}, maxPointsInLeafNode, maxMBSortInHeap)
+	if !assert.Nil(t, err) {
+		return
+	}
+	for _, info := range infos {
+		var values types.PointValues = fields[info.Name()]
+		if strings.HasSuffix(info.Name(), "_plain") {
+			// hide the MutablePointValues methods so that points are added one by one
+			values = &plainPoints{fields[info.Name()]}
+		}
+		assert.Nil(t, writer.WriteField(ctx, info, &testPointsReader{values: values}), info.Name())
+	}
+	assert.Nil(t, writer.Finish())
+	assert.Nil(t, writer.Close())
+
+	reader, err := format.FieldsReader(ctx, &index.SegmentReadState{
+		Directory:   dir,
+		SegmentInfo: si,
+		FieldInfos:  fieldInfos,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer reader.Close()
+	assert.Nil(t, reader.CheckIntegrity())
+
+	// no points were written for the field
+	values, err := reader.GetValues(ctx, "point_empty")
+	assert.Nil(t, err)
+	assert.Nil(t, values)
+
+	_, err = reader.GetValues(ctx, "unknown")
+	assert.NotNil(t, err)
+
+	for _, name := range names[:len(names)-1] {
+		expected := fields[name]
+		values, err := reader.GetValues(ctx, name)
+		if !assert.Nil(t, err) || !assert.NotNil(t, values) {
+			continue
+		}
+
+		assert.Equal(t, len(expected.docs), values.Size(), name)
+		assert.Equal(t, expected.docCount(), values.GetDocCount(), name)
+		numDims, err := values.GetNumDimensions()
+		assert.Nil(t, err)
+		assert.Equal(t, expected.numDims, numDims, name)
+		bytesPerDim, err := values.GetBytesPerDimension()
+		assert.Nil(t, err)
+		assert.Equal(t, expected.bytesPerDim, bytesPerDim, name)
+
+		minPackedValue, err := values.GetMinPackedValue()
+		assert.Nil(t, err)
+		expectedMin, _ := expected.GetMinPackedValue()
+		assert.Equal(t, expectedMin, minPackedValue, name)
+		maxPackedValue, err := values.GetMaxPackedValue()
+		assert.Nil(t, err)
+		expectedMax, _ := expected.GetMaxPackedValue()
+		assert.Equal(t, expectedMax, maxPackedValue, name)
+
+		for i := 0; i < 20; i++ {
+			lower, upper := expected.randomRange(r)
+			hits := 0
+			visitor := newRangeVisitor(expected.numDims, expected.bytesPerDim, lower, upper, func(docID int) {
+				hits++
+			})
+			assert.Nil(t, values.Intersect(ctx, visitor), name)
+			assert.Equal(t, expected.count(lower, upper), hits, name)
+		}
+	}
+}
+
+// newRangeVisitor returns a visitor that collects the points within [lower, upper] in all dimensions
+func newRangeVisitor(numDims, bytesPerDim int, lower, upper []byte, collect func(docID int)) *types.BytesVisitor {
+	return &types.BytesVisitor{
+		VisitFn: func(docID int) error {
+			collect(docID)
+			return nil
+		},
+		VisitLeafFn: func(docID int, packedValue []byte) error {
+			if relate(numDims, bytesPerDim, lower, upper, packedValue, packedValue) == types.CELL_INSIDE_QUERY {
+				collect(docID)
+			}
+			return nil
+		},
+		CompareFn: func(minPackedValue, maxPackedValue []byte) types.Relation {
+			return relate(numDims, bytesPerDim, lower, upper, minPackedValue, maxPackedValue)
+		},
+		GrowFn: func(count int) {},
+	}
+}
+
+func relate(numDims, bytesPerDim int, lower, upper, minPackedValue, maxPackedValue []byte) types.Relation {
+	crosses := false
+	for dim := 0; dim < numDims; dim++ {
+		from, to := dim*bytesPerDim, (dim+1)*bytesPerDim
+		if bytes.Compare(minPackedValue[from:to], upper[from:to]) > 0 ||
+			bytes.Compare(maxPackedValue[from:to], lower[from:to]) < 0 {
+			return types.CELL_OUTSIDE_QUERY
+		}
+		crosses = crosses || bytes.Compare(minPackedValue[from:to], lower[from:to]) < 0 ||
+			bytes.Compare(maxPackedValue[from:to], upper[from:to]) > 0
+	}
+	if crosses {
+		return types.CELL_CROSSES_QUERY
+	}
+	return types.CELL_INSIDE_QUERY
+}
+
+var _ index.PointsReader = &testPointsReader{}
+
+type testPointsReader struct {
+	values types.PointValues
+}
+
+func (r *testPointsReader) Close() error {
+	return nil
+}
+
+func (r *testPointsReader) CheckIntegrity() error {
+	return nil
+}
+
+func (r *testPointsReader) GetValues(ctx context.Context, field string) (types.PointValues, error) {
+	return r.values, nil
+}
+
+func (r *testPointsReader) GetMergeInstance() index.PointsReader {
+	return r
+}
+
+// plainPoints only exposes the PointValues methods of the wrapped points
+type plainPoints struct {
+	types.PointValues
+}
+
+var _ types.MutablePointValues = &testPoints{}
+
+type testPoints struct {
+	numDims     int
+	bytesPerDim int
+	docs        []int
+	values      [][]byte
+	temp        []int
+	tempValues  [][]byte
+}
+
+func randomPoints(r *rand.Rand, maxDoc, numPoints, numDims, bytesPerDim int) *testPoints {
+	points := &testPoints{
+		numDims:     numDims,
+		bytesPerDim: bytesPerDim,
+		docs:        make([]int, numPoints),
+		values:      make([][]byte, numPoints),
+	}
+	for i := range points.docs {
+		points.docs[i] = r.Intn(maxDoc)
+		value := make([]byte, numDims*bytesPerDim)
+		r.Read(value)
+		// narrow the first byte of each dimension to get duplicate values
+		for dim := 0; dim < numDims; dim++ {
+			value[dim*bytesPerDim] %= 16
+		}
+		points.values[i] = value
+	}
+	return points
+}
+
+// randomRange returns the bounds of a random range in all dimensions
+func (p *testPoints) randomRange(r *rand.Rand) ([]byte, []byte) {
+	lower := make([]byte, p.numDims*p.bytesPerDim)
+	upper := make([]byte, p.numDims*p.bytesPerDim)
+	for dim := 0; dim < p.numDims; dim++ {
+		from, to := dim*p.bytesPerDim, (dim+1)*p.bytesPerDim
+		a, b := make([]byte, p.bytesPerDim), make([]byte, p.bytesPerDim)
+		r.Read(a)
+		r.Read(b)
+		a[0] %= 16
+		b[0] %= 16
+		if bytes.Compare(a, b) > 0 {
+			a, b = b, a
+		}
+		copy(lower[from:to], a)
+		copy(upper[from:to], b)
+	}
+	return lower, upper
+}
+
+// count returns the number of points within [lower, upper]
+func (p *testPoints) count(lower, upper []byte) int {
+	count := 0
+	for _, value := range p.values {
+		if relate(p.numDims, p.bytesPerDim, lower, upper, value, value) == types.CELL_INSIDE_QUERY {
+			count++
+		}
+	}
+	return count
+}
+
+func (p *testPoints) docCount() int {
+	docs := make(map[int]struct{})
+	for _, doc := range p.docs {
+		docs[doc] = struct{}{}
+	}
+	return len(docs)
+}
+
+func (p *testPoints) Intersect(ctx context.Context, visitor types.IntersectVisitor) error {
+	for i, value := range p.values {
+		if err := visitor.VisitLeaf(ctx, p.docs[i], value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *testPoints) EstimatePointCount(ctx context.Context, visitor types.IntersectVisitor) (int, error) {
+	return len(p.values), nil
+}
+
+func (p *testPoints) EstimateDocCount(visitor types.IntersectVisitor) (int, error) {
+	return types.EstimateDocCount(p, visitor)
+}
+
+func (p *testPoints) GetMinPackedValue() ([]byte, error) {
+	return p.bound(-1), nil
+}
+
+func (p *testPoints) GetMaxPackedValue() ([]byte, error) {
+	return p.bound(1), nil
+}
+
+// bound returns the minimum (sign < 0) or maximum (sign > 0) packed value over all points
+func (p *testPoints) bound(sign int) []byte {
+	if len(p.values) == 0 {
+		return nil
+	}
+	result := bytes.Clone(p.values[0])
+	for _, value := range p.values[1:] {
+		for dim := 0; dim < p.numDims; dim++ {
+			from, to := dim*p.bytesPerDim, (dim+1)*p.bytesPerDim
+			if bytes.Compare(value[from:to], result[from:to])*sign > 0 {
+				copy(result[from:to], value[from:to])
+			}
+		}
+	}
+	return result
+}
+
+func (p *testPoints) GetNumDimensions() (int, error) {
+	return p.numDims, nil
+}
+
+func (p *testPoints) GetNumIndexDimensions() (int, error) {
+	return p.numDims, nil
+}
+
+func (p *testPoints) GetBytesPerDimension() (int, error) {
+	return p.bytesPerDim, nil
+}
+
+func (p *testPoints) Size() int {
+	return len(p.values)
+}
+
+func (p *testPoints) GetDocCount() int {
+	return p.docCount()
+}
+
+func (p *testPoints) GetValue(i int, packedValue *bytes.Buffer) {
+	packedValue.Reset()
+	packedValue.Write(p.values[i])
+}
+
+func (p *testPoints) GetByteAt(i, k int) byte {
+	return p.values[i][k]
+}
+
+func (p *testPoints) GetDocID(i int) int {
+	return p.docs[i]
+}
+
+func (p *testPoints) Swap(i, j int) {
+	p.docs[i], p.docs[j] = p.docs[j], p.docs[i]
+	p.values[i], p.values[j] = p.values[j], p.values[i]
+}
+
+func (p *testPoints) Save(i, j int) {
+	if p.temp == nil {
+		p.temp = make([]int, len(p.docs))
+		p.tempValues = make([][]byte, len(p.values))
+	}
+	p.temp[j] = p.docs[i]
+	p.tempValues[j] = p.values[i]
+}
+
+func (p *testPoints) Restore(i, j int) {
+	copy(p.docs[i:j], p.temp[i:j])
+	copy(p.values[i:j], p.tempValues[i:j])
+}
@@ -0,0 +1,166 @@
+package lucene86
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/bkd"
+)
+
+var _ index.PointsReader = &PointsReader{}
+
+// PointsReader Reads point values previously written with PointsWriter
+type PointsReader struct {
+	readState *index.SegmentReadState
+	indexIn   store.IndexInput
+	dataIn    store.IndexInput
+	readers   map[int]*bkd.Reader
+}
+
+// NewPointsReader Sole constructor
+func NewPointsReader(ctx context.Context, readState *index.SegmentReadState) (*PointsReader, error) {
+	reader := &PointsReader{
+		readState: readState,
+		readers:   make(map[int]*bkd.Reader),
+	}
+
+	closeOnError := func(err error) (*PointsReader, error) {
+		_ = reader.Close()
+		return nil, err
+	}
+
+	var err error
+	if reader.indexIn, err = reader.openInput(ctx, INDEX_EXTENSION, INDEX_CODEC_NAME); err != nil {
+		return closeOnError(err)
+	}
+	if reader.dataIn, err = reader.openInput(ctx, DATA_EXTENSION, DATA_CODEC_NAME); err != nil {
+		return closeOnError(err)
+	}
+
+	metaFileName := store.SegmentFileName(readState.SegmentInfo.Name(), readState.SegmentSuffix, META_EXTENSION)
+	metaIn, err := store.OpenChecksumInput(readState.Directory, metaFileName)
+	if err != nil {
+		return closeOnError(err)
+	}
+	defer metaIn.Close()
+
+	if _, err := utils.CheckIndexHeader(ctx, metaIn, META_CODEC_NAME, VERSION_START, VERSION_CURRENT,
+		readState.SegmentInfo.GetID(), readState.SegmentSuffix); err != nil {
+		return closeOnError(err)
+	}
+
+	for {
+		fieldNumber, err := metaIn.ReadUint32(ctx)
+		if err != nil {
+			return closeOnError(err)
+		}
+		if int32(fieldNumber) == -1 {
+			break
+		}
+		if int32(fieldNumber) < 0 {
+			return closeOnError(fmt.Errorf("illegal field number: %d", int32(fieldNumber)))
+		}
+		bkdReader, err := bkd.NewReader(ctx, metaIn, reader.indexIn, reader.dataIn)
+		if err != nil {
+			return closeOnError(err)
+		}
+		reader.readers[int(fieldNumber)] = bkdReader
+	}
+
+	indexLength, err := metaIn.ReadUint64(ctx)
+	if err != nil {
+		return closeOnError(err)
+	}
+	dataLength, err := metaIn.ReadUint64(ctx)
+	if err != nil {
+		return closeOnError(err)
+	}
+	if _, err := utils.CheckCodecFooter(metaIn); err != nil {
+		return closeOnError(err)
+	}
+
+	// At this point, checksums of the meta file have been validated so we
+	// know that indexLength and dataLength are very likely correct.
+	if length := uint64(reader.indexIn.Length()); length != indexLength {
+		return closeOnError(fmt.Errorf("truncated index file: expected length=%d, actual=%d", indexLength, length))
+	}
+	if length := uint64(reader.dataIn.Length()); length != dataLength {
+		return closeOnError(fmt.Errorf("truncated data file: expected length=%d, actual=%d", dataLength, length))
+	}
+	return reader, nil
+}
+
+func (p *PointsReader) openInput(ctx context.Context, extension, codecName string) (store.IndexInput, error) {
+	state := p.readState
+	fileName := store.SegmentFileName(state.SegmentInfo.Name(), state.SegmentSuffix, extension)
+	in, err := store.OpenInputWithContext(ctx, state.Directory, fileName, state.Context)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := utils.CheckIndexHeader(ctx, in, codecName, VERSION_START, VERSION_CURRENT,
+		state.SegmentInfo.GetID(), state.SegmentSuffix); err != nil {
+		_ = in.Close()
+		return nil, err
+	}
+
+	// NOTE: data file is too costly to verify checksum against all the bytes on open,
+	// but for now we at least verify proper structure of the checksum footer: which looks
+	// for FOOTER_MAGIC + algorithmID. This is cheap and can detect some forms of corruption
+	// such as file truncation.
+	if _, err := utils.RetrieveChecksum(in); err != nil {
+		_ = in.Close()
+		return nil, err
+	}
+	return in, nil
+}
+
+// GetValues Returns the underlying bkd.Reader.
+func (p *PointsReader) GetValues(ctx context.Context, field string) (types.PointValues, error) {
+	fieldInfo := p.readState.FieldInfos.FieldInfo(field)
+	if fieldInfo == nil {
+		return nil, fmt.Errorf("field=%s is unrecognized", field)
+	}
+	if fieldInfo.GetPointDimensionCount() == 0 {
+		return nil, fmt.Errorf("field=%s did not index point values", field)
+	}
+
+	reader, ok := p.readers[fieldInfo.Number()]
+	if !ok {
+		// Schema ghost corner case, where point values were indexed on this field, but later all
+		// docs indexing that field were deleted
+		return nil, nil
+	}
+	return reader, nil
+}
+
+func (p *PointsReader) CheckIntegrity() error {
+	if _, err := utils.ChecksumEntireFile(p.indexIn); err != nil {
+		return err
+	}
+	_, err := utils.ChecksumEntireFile(p.dataIn)
+	return err
+}
+
+func (p *PointsReader) GetMergeInstance() index.PointsReader {
+	return p
+}
+
+func (p *PointsReader) Close() error {
+	var errs []error
+	for _, in := range []store.IndexInput{p.indexIn, p.dataIn} {
+		if in != nil {
+			if err := in.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	p.indexIn, p.dataIn = nil, nil
+	// Free up heap:
+	p.readers = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,181 @@
+package lucene86
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/bkd"
+)
+
+var _ index.PointsWriter = &PointsWriter{}
+
+// PointsWriter Writes dimensional values
+type PointsWriter struct {
+	*coreIndex.BasePointsWriter
+
+	// Outputs used to write the BKD tree data files.
+	metaOut  store.IndexOutput
+	indexOut store.IndexOutput
+	dataOut  store.IndexOutput
+
+	writeState          *index.SegmentWriteState
+	maxPointsInLeafNode int
+	maxMBSortInHeap     float64
+	finished            bool
+}
+
+// NewPointsWriter Full constructor
+func NewPointsWriter(ctx context.Context, writeState *index.SegmentWriteState,
+	maxPointsInLeafNode int, maxMBSortInHeap float64) (*PointsWriter, error) {
+
+	writer := &PointsWriter{
+		writeState:          writeState,
+		maxPointsInLeafNode: maxPointsInLeafNode,
+		maxMBSortInHeap:     maxMBSortInHeap,
+	}
+	writer.BasePointsWriter = &coreIndex.BasePointsWriter{
+		WriteField: writer.WriteField,
+		Finish:     writer.Finish,
+	}
+
+	closeOnError := func(err error) (*PointsWriter, error) {
+		_ = writer.Close()
+		return nil, err
+	}
+
+	var err error
+	if writer.dataOut, err = writer.createOutput(ctx, DATA_EXTENSION, DATA_CODEC_NAME); err != nil {
+		return closeOnError(err)
+	}
+	if writer.metaOut, err = writer.createOutput(ctx, META_EXTENSION, META_CODEC_NAME); err != nil {
+		return closeOnError(err)
+	}
+	if writer.indexOut, err = writer.createOutput(ctx, INDEX_EXTENSION, INDEX_CODEC_NAME); err != nil {
+		return closeOnError(err)
+	}
+	return writer, nil
+}
+
+func (p *PointsWriter) createOutput(ctx context.Context, extension, codecName string) (store.IndexOutput, error) {
+	state := p.writeState
+	fileName := store.SegmentFileName(state.SegmentInfo.Name(), state.SegmentSuffix, extension)
+	out, err := store.CreateOutputWithContext(ctx, state.Directory, fileName, state.Context)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.WriteIndexHeader(ctx, out, codecName, VERSION_CURRENT,
+		state.SegmentInfo.GetID(), state.SegmentSuffix); err != nil {
+		_ = out.Close()
+		return nil, err
+	}
+	return out, nil
+}
+
+func (p *PointsWriter) WriteField(ctx context.Context, fieldInfo *document.FieldInfo, reader index.PointsReader) error {
+	values, err := reader.GetValues(ctx, fieldInfo.Name())
+	if err != nil {
+		return err
+	}
+
+	config, err := bkd.NewConfig(
+		fieldInfo.GetPointDimensionCount(),
+		fieldInfo.GetPointIndexDimensionCount(),
+		fieldInfo.GetPointNumBytes(),
+		p.maxPointsInLeafNode,
+	)
+	if err != nil {
+		return err
+	}
+
+	maxDoc, err := p.writeState.SegmentInfo.MaxDoc()
+	if err != nil {
+		return err
+	}
+	writer, err := bkd.NewWriter(maxDoc, p.writeState.Directory, p.writeState.SegmentInfo.Name(),
+		config, p.maxMBSortInHeap, values.Size())
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	var finalizer bkd.Runnable
+	if mutable, ok := values.(types.MutablePointValues); ok {
+		// the points are buffered in memory, they can be sorted in place
+		if finalizer, err = writer.WriteField(ctx, p.metaOut, p.indexOut, p.dataOut, fieldInfo.Name(), mutable); err != nil {
+			return err
+		}
+	} else {
+		if err := values.Intersect(ctx, &types.BytesVisitor{
+			VisitFn: func(docID int) error {
+				return errors.New("illegal state")
+			},
+			VisitLeafFn: func(docID int, packedValue []byte) error {
+				return writer.Add(packedValue, docID)
+			},
+			CompareFn: func(minPackedValue, maxPackedValue []byte) types.Relation {
+				return types.CELL_CROSSES_QUERY
+			},
+			GrowFn: func(count int) {},
+		}); err != nil {
+			return err
+		}
+
+		// We could have 0 points on merge since all docs with dimensional fields may be deleted:
+		if finalizer, err = writer.Finish(ctx, p.metaOut, p.indexOut, p.dataOut); err != nil {
+			return err
+		}
+	}
+
+	if finalizer == nil {
+		return nil
+	}
+	if err := p.metaOut.WriteUint32(ctx, uint32(fieldInfo.Number())); err != nil {
+		return err
+	}
+	return finalizer(ctx)
+}
+
+func (p *PointsWriter) Finish() error {
+	if p.finished {
+		return errors.New("already finished")
+	}
+	p.finished = true
+
+	// write EOF marker
+	if err := p.metaOut.WriteUint32(nil, math.MaxUint32); err != nil {
+		return err
+	}
+	if err := utils.WriteFooter(p.indexOut); err != nil {
+		return err
+	}
+	if err := utils.WriteFooter(p.dataOut); err != nil {
+		return err
+	}
+	if err := p.metaOut.WriteUint64(nil, uint64(p.indexOut.GetFilePointer())); err != nil {
+		return err
+	}
+	if err := p.metaOut.WriteUint64(nil, uint64(p.dataOut.GetFilePointer())); err != nil {
+		return err
+	}
+	return utils.WriteFooter(p.metaOut)
+}
+
+func (p *PointsWriter) Close() error {
+	var errs []error
+	for _, out := range []store.IndexOutput{p.metaOut, p.indexOut, p.dataOut} {
+		if out != nil {
+			if err := out.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	p.metaOut, p.indexOut, p.dataOut = nil, nil, nil
+	return errors.Join(errs...)
+}
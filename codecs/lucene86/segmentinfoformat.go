@@ -0,0 +1,298 @@
+package lucene86
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/version"
+)
+
+const (
+	// SI_EXTENSION File extension used to store SegmentInfo.
+	SI_EXTENSION = "si"
+
+	SI_CODEC_NAME = "Lucene86SegmentInfo"
+
+	SI_VERSION_START   = 0
+	SI_VERSION_CURRENT = SI_VERSION_START
+)
+
+var _ index.SegmentInfoFormat = &SegmentInfoFormat{}
+
+// SegmentInfoFormat Lucene 8.6 Segment info format.
+//
+// Files:
+//   - .si: Header, SegVersion, SegSize, IsCompoundFile, Diagnostics, Files, Attributes, IndexSort, Footer
+//
+// Data types:
+//   - Header --> IndexHeader
+//   - SegSize --> Int32
+//   - SegVersion --> String
+//   - SegMinVersion --> String
+//   - Files --> Set<String>
+//   - Diagnostics,Attributes --> Map<String,String>
+//   - IsCompoundFile --> Int8
+//   - IndexSort --> VInt Count, SortField^Count
+//   - SortField --> String sort class, followed by a per-sort bytestream (see SortFieldProvider)
+//   - Footer --> CodecFooter
+//
+// Field Descriptions:
+//   - SegVersion is the code version that created the segment.
+//   - SegMinVersion is the minimum code version that contributed documents to the segment.
+//   - SegSize is the number of documents contained in the segment index.
+//   - IsCompoundFile records whether the segment is written as a compound file or not. If this is -1,
+//     the segment is not a compound file. If it is 1, the segment is a compound file.
+//   - The Diagnostics Map is privately written by IndexWriter, as a debugging aid, for each segment it
+//     creates. It includes metadata like the current Lucene version, OS, Java version, why the segment
+//     was created (merge, flush, addIndexes), etc.
+//   - Files is a list of files referred to by this segment.
+//
+// lucene.experimental
+type SegmentInfoFormat struct {
+}
+
+func NewSegmentInfoFormat() *SegmentInfoFormat {
+	return &SegmentInfoFormat{}
+}
+
+func (s *SegmentInfoFormat) Read(ctx context.Context, dir store.Directory, segmentName string,
+	segmentID []byte, ioContext *store.IOContext) (index.SegmentInfo, error) {
+
+	fileName := store.SegmentFileName(segmentName, "", SI_EXTENSION)
+	input, err := store.OpenChecksumInput(dir, fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	if _, err := utils.CheckIndexHeader(ctx, input, SI_CODEC_NAME, SI_VERSION_START, SI_VERSION_CURRENT,
+		segmentID, ""); err != nil {
+		return nil, err
+	}
+
+	si, err := s.parseSegmentInfo(ctx, dir, input, segmentName, segmentID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := utils.CheckCodecFooter(input); err != nil {
+		return nil, err
+	}
+	return si, nil
+}
+
+func (s *SegmentInfoFormat) parseSegmentInfo(ctx context.Context, dir store.Directory, input store.DataInput,
+	segmentName string, segmentID []byte) (index.SegmentInfo, error) {
+
+	ver, err := readVersion(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMinVersion, err := input.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	var minVersion *version.Version
+	switch hasMinVersion {
+	case 0:
+	case 1:
+		if minVersion, err = readVersion(ctx, input); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("illegal boolean value %d", hasMinVersion)
+	}
+
+	docCount, err := input.ReadUint32(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if int32(docCount) < 0 {
+		return nil, fmt.Errorf("invalid docCount: %d", int32(docCount))
+	}
+
+	isCompoundFile, err := input.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics, err := input.ReadMapOfStrings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	files, err := input.ReadSetOfStrings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := input.ReadMapOfStrings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	numSortFields, err := input.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var indexSort index.Sort
+	if numSortFields > 0 {
+		sortFields := make([]index.SortField, 0, numSortFields)
+		for i := 0; i < int(numSortFields); i++ {
+			name, err := input.ReadString(ctx)
+			if err != nil {
+				return nil, err
+			}
+			provider := coreIndex.GetSortFieldProviderByName(name)
+			if provider == nil {
+				return nil, fmt.Errorf("SortFieldProvider: %s not found", name)
+			}
+			sortField, err := provider.ReadSortField(ctx, input)
+			if err != nil {
+				return nil, err
+			}
+			sortFields = append(sortFields, sortField)
+		}
+		indexSort = coreIndex.NewSort(sortFields)
+	}
+
+	si := coreIndex.NewSegmentInfo(dir, ver, minVersion, segmentName, int(docCount),
+		int8(isCompoundFile) == coreIndex.SegmentInfoYES, nil, diagnostics, segmentID, attributes, indexSort)
+	si.SetFiles(files)
+	return si, nil
+}
+
+func readVersion(ctx context.Context, input store.DataInput) (*version.Version, error) {
+	parts := make([]uint32, 3)
+	for i := range parts {
+		v, err := input.ReadUint32(ctx)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = v
+	}
+	return version.New(
+		version.WithMajor(uint8(parts[0])),
+		version.WithMinor(uint8(parts[1])),
+		version.WithBugfix(uint8(parts[2])),
+	)
+}
+
+func writeVersion(ctx context.Context, output store.DataOutput, v *version.Version) error {
+	for _, part := range []uint8{v.Major(), v.Minor(), v.Bugfix()} {
+		if err := output.WriteUint32(ctx, uint32(part)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SegmentInfoFormat) Write(ctx context.Context, dir store.Directory, si index.SegmentInfo, ioContext *store.IOContext) error {
+	fileName := store.SegmentFileName(si.Name(), "", SI_EXTENSION)
+
+	output, err := store.CreateOutputWithContext(ctx, dir, fileName, ioContext)
+	if err != nil {
+		return err
+	}
+	// Only add the file once we've successfully created it, else IFD assert can trip:
+	if err := si.AddFile(fileName); err != nil {
+		_ = output.Close()
+		return err
+	}
+	if err := s.write(ctx, output, si); err != nil {
+		_ = output.Close()
+		return err
+	}
+	return output.Close()
+}
+
+func (s *SegmentInfoFormat) write(ctx context.Context, output store.IndexOutput, si index.SegmentInfo) error {
+	if err := utils.WriteIndexHeader(ctx, output, SI_CODEC_NAME, SI_VERSION_CURRENT, si.GetID(), ""); err != nil {
+		return err
+	}
+
+	ver := si.GetVersion()
+	if ver == nil {
+		return errors.New("segment version is nil")
+	}
+	if ver.Major() < 7 {
+		return fmt.Errorf("invalid major version: should be >= 7 but got: %d segment=%s", ver.Major(), si.Name())
+	}
+	// Write the Lucene version that created this segment, since 3.1
+	if err := writeVersion(ctx, output, ver); err != nil {
+		return err
+	}
+
+	minVersion := si.GetMinVersion()
+	if minVersion == nil {
+		if err := output.WriteByte(0); err != nil {
+			return err
+		}
+	} else {
+		if err := output.WriteByte(1); err != nil {
+			return err
+		}
+		if err := writeVersion(ctx, output, minVersion); err != nil {
+			return err
+		}
+	}
+
+	maxDoc, err := si.MaxDoc()
+	if err != nil {
+		return err
+	}
+	if err := output.WriteUint32(ctx, uint32(maxDoc)); err != nil {
+		return err
+	}
+
+	isCompoundFile := int8(coreIndex.SegmentInfoNO)
+	if si.GetUseCompoundFile() {
+		isCompoundFile = coreIndex.SegmentInfoYES
+	}
+	if err := output.WriteByte(byte(isCompoundFile)); err != nil {
+		return err
+	}
+	if err := output.WriteMapOfStrings(ctx, si.GetDiagnostics()); err != nil {
+		return err
+	}
+
+	files := si.Files()
+	for file := range files {
+		if coreIndex.ParseSegmentName(file) != si.Name() {
+			return fmt.Errorf("invalid files: expected segment=%s, got=%s", si.Name(), file)
+		}
+	}
+	if err := output.WriteSetOfStrings(ctx, files); err != nil {
+		return err
+	}
+	if err := output.WriteMapOfStrings(ctx, si.GetAttributes()); err != nil {
+		return err
+	}
+
+	numSortFields := 0
+	indexSort := si.GetIndexSort()
+	if indexSort != nil {
+		numSortFields = len(indexSort.GetSort())
+	}
+	if err := output.WriteUvarint(ctx, uint64(numSortFields)); err != nil {
+		return err
+	}
+	for i := 0; i < numSortFields; i++ {
+		sortField := indexSort.GetSort()[i]
+		sorter := sortField.GetIndexSorter()
+		if sorter == nil {
+			return fmt.Errorf("cannot serialize SortField %s", sortField)
+		}
+		if err := output.WriteString(ctx, sorter.GetProviderName()); err != nil {
+			return err
+		}
+		if err := coreIndex.WriteSortField(sortField, output); err != nil {
+			return err
+		}
+	}
+
+	return utils.WriteFooter(output)
+}
@@ -0,0 +1,91 @@
+package lucene86
+
+import (
+	"context"
+	"testing"
+
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegmentInfoFormat(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	sortField := coreIndex.NewSortFieldV1("sort", index.STRING, true)
+	assert.Nil(t, sortField.SetMissingValue(coreIndex.STRING_LAST))
+
+	minVersion, err := version.New(version.WithMajor(8), version.WithMinor(6))
+	assert.Nil(t, err)
+
+	id := []byte("0123456789abcdef")
+	si := coreIndex.NewSegmentInfo(dir, version.Last, minVersion, "_3", 1234, true, nil,
+		map[string]string{"source": "flush"}, id, map[string]string{"key": "value"},
+		coreIndex.NewSort([]index.SortField{sortField}))
+	si.SetFiles(map[string]struct{}{"_3.fnm": {}, "_3_Lucene84_0.doc": {}})
+
+	format := NewSegmentInfoFormat()
+	assert.Nil(t, format.Write(ctx, dir, si, store.DEFAULT))
+	assert.Contains(t, si.Files(), "_3.si")
+
+	// files of other segments are rejected
+	other := coreIndex.NewSegmentInfo(dir, version.Last, nil, "_4", 1, false, nil,
+		map[string]string{}, id, map[string]string{}, nil)
+	other.SetFiles(map[string]struct{}{"_3.fnm": {}})
+	assert.NotNil(t, format.Write(ctx, dir, other, store.DEFAULT))
+
+	// the id must match
+	_, err = format.Read(ctx, dir, "_3", []byte("fedcba9876543210"), store.DEFAULT)
+	assert.NotNil(t, err)
+
+	read, err := format.Read(ctx, dir, "_3", id, store.DEFAULT)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "_3", read.Name())
+	assert.Equal(t, id, read.GetID())
+	assert.Equal(t, version.Last.String(), read.GetVersion().String())
+	assert.Equal(t, minVersion.String(), read.GetMinVersion().String())
+	maxDoc, err := read.MaxDoc()
+	assert.Nil(t, err)
+	assert.Equal(t, 1234, maxDoc)
+	assert.True(t, read.GetUseCompoundFile())
+	assert.Equal(t, map[string]string{"source": "flush"}, read.GetDiagnostics())
+	assert.Equal(t, map[string]string{"key": "value"}, read.GetAttributes())
+	assert.Equal(t, si.Files(), read.Files())
+
+	indexSort := read.GetIndexSort()
+	if assert.NotNil(t, indexSort) && assert.Len(t, indexSort.GetSort(), 1) {
+		assert.Equal(t, "sort", indexSort.GetSort()[0].GetField())
+		assert.Equal(t, index.STRING, indexSort.GetSort()[0].GetType())
+		assert.True(t, indexSort.GetSort()[0].GetReverse())
+	}
+}
+
+func TestSegmentInfoFormatNoMinVersion(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	id := []byte("0123456789abcdef")
+	si := coreIndex.NewSegmentInfo(dir, version.Last, nil, "_0", 1, false, nil,
+		map[string]string{}, id, map[string]string{}, nil)
+
+	format := NewSegmentInfoFormat()
+	assert.Nil(t, format.Write(ctx, dir, si, store.DEFAULT))
+
+	read, err := format.Read(ctx, dir, "_0", id, store.DEFAULT)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, read.GetMinVersion())
+	assert.False(t, read.GetUseCompoundFile())
+	assert.Nil(t, read.GetIndexSort())
+	assert.Equal(t, map[string]struct{}{"_0.si": {}}, read.Files())
+}
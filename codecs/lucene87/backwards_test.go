@@ -0,0 +1,295 @@
+package lucene87_test
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	_ "github.com/geange/lucene-go/codecs/lucene87"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/search"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/numeric"
+	"github.com/stretchr/testify/assert"
+)
+
+// The zip files under testdata are the backward compatibility indexes of Apache Lucene, written by
+// the Java implementation. Each one holds 35 documents, the 8th of them deleted, and a 36th
+// document without positions.
+const (
+	lucene811DocsCount = 35
+	lucene811DeletedID = 7
+	lucene811UTF8      = "Lu\U0001D11Ece\U0001D160ne \u0000 ☠ ab\U00055C17cd"
+)
+
+func unzipIndex(t *testing.T, name string) string {
+	dir := t.TempDir()
+	r, err := zip.OpenReader(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		in, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := os.Create(filepath.Join(dir, f.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			t.Fatal(err)
+		}
+		in.Close()
+		out.Close()
+	}
+	return dir
+}
+
+// TestReadLucene811Index Runs the checks of Lucene's TestBasicBackwardsCompatibility.searchIndex on
+// the indexes written by Lucene 8.11.4, with and without compound files.
+func TestReadLucene811Index(t *testing.T) {
+	for _, name := range []string{"index.8.11.4-cfs.zip", "index.8.11.4-nocfs.zip"} {
+		t.Run(name, func(t *testing.T) {
+			searchLucene811Index(t, name)
+		})
+	}
+}
+
+func searchLucene811Index(t *testing.T, name string) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(unzipIndex(t, name))
+	assert.Nil(t, err)
+	reader, err := coreIndex.OpenDirectoryReader(ctx, dir, nil, nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer reader.DecRef()
+
+	assert.Equal(t, lucene811DocsCount+1, reader.MaxDoc())
+	assert.Equal(t, lucene811DocsCount, reader.NumDocs())
+
+	leaves, err := reader.Leaves()
+	assert.Nil(t, err)
+	for _, leaf := range leaves {
+		leafReader := leaf.LeafReader()
+		liveDocs := leafReader.GetLiveDocs()
+		for doc := 0; doc < min(leafReader.MaxDoc(), lucene811DocsCount-leaf.DocBase()); doc++ {
+			id := leaf.DocBase() + doc
+			if liveDocs != nil && !liveDocs.Test(uint(doc)) {
+				assert.Equal(t, lucene811DeletedID, id)
+				continue
+			}
+			checkLucene811Document(t, leafReader, doc, id)
+		}
+		checkLucene811DocValues(t, leafReader, leaf.DocBase())
+	}
+
+	searcher, err := search.NewIndexSearcher(reader)
+	assert.Nil(t, err)
+	assertCount := func(query index.Query, expected int) {
+		count, err := searcher.Count(query)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, count, query.String(""))
+	}
+
+	assertCount(search.NewTermQuery(coreIndex.NewTerm("content", []byte("aaa"))), 34)
+	assertCount(search.NewTermQuery(coreIndex.NewTerm("content5", []byte("aaa"))), 34)
+	assertCount(search.NewTermQuery(coreIndex.NewTerm("content6", []byte("aaa"))), 34)
+	assertCount(search.NewTermQuery(coreIndex.NewTerm("utf8", []byte("\u0000"))), 34)
+	assertCount(search.NewTermQuery(coreIndex.NewTerm("utf8", []byte("lu\U0001D11Ece\U0001D160ne"))), 34)
+	assertCount(search.NewTermQuery(coreIndex.NewTerm("utf8", []byte("ab\U00055C17cd"))), 34)
+
+	assertRange := func(field string, numDims int, lower, upper []byte) {
+		query, err := search.NewPointRangeQuery(field, lower, upper, numDims)
+		if assert.Nil(t, err) {
+			assertCount(query, 34)
+		}
+	}
+	assertRange("intPoint1d", 1, packInts(0), packInts(34))
+	assertRange("intPoint2d", 2, packInts(0, 0), packInts(34, 68))
+	assertRange("floatPoint1d", 1, packFloats(0), packFloats(34))
+	assertRange("floatPoint2d", 2, packFloats(0, 0), packFloats(34, 68))
+	assertRange("longPoint1d", 1, packLongs(0), packLongs(34))
+	assertRange("longPoint2d", 2, packLongs(0, 0), packLongs(34, 68))
+	assertRange("doublePoint1d", 1, packDoubles(0), packDoubles(34))
+	assertRange("doublePoint2d", 2, packDoubles(0, 0), packDoubles(34, 68))
+	assertRange("binaryPoint1d", 1, []byte{0, 0, 0, 0}, []byte{0, 0, 0, 34})
+	assertRange("binaryPoint2d", 2, []byte{0, 0, 0, 0, 0, 0, 0, 0}, []byte{0, 0, 0, 34, 0, 0, 0, 68})
+}
+
+func checkLucene811Document(t *testing.T, reader index.LeafReader, doc, id int) {
+	visitor := document.NewDocumentStoredFieldVisitor()
+	if !assert.Nil(t, reader.DocumentWithVisitor(context.Background(), doc, visitor)) {
+		return
+	}
+	d := visitor.GetDocument()
+	if _, err := d.GetField("content3"); err != nil {
+		// the documents with positions
+		assert.Equal(t, 7, len(d.Fields()))
+		assertStored := func(field, expected string) {
+			value, err := d.Get(field)
+			assert.Nil(t, err)
+			assert.Equal(t, expected, value, field)
+		}
+		assertStored("id", strconv.Itoa(id))
+		assertStored("utf8", lucene811UTF8)
+		assertStored("autf8", lucene811UTF8)
+		assertStored("content2", "here is more content with aaa aaa aaa")
+		assertStored("fieⱷld", "field with non-ascii name")
+	}
+
+	vectors, err := reader.GetTermVectors(doc)
+	if assert.Nil(t, err) && assert.NotNil(t, vectors, "doc=%d", id) {
+		terms, err := vectors.Terms("utf8")
+		assert.Nil(t, err)
+		if assert.NotNil(t, terms, "doc=%d", id) {
+			termsEnum, err := terms.Iterator()
+			assert.Nil(t, err)
+			values := make([]string, 0)
+			for {
+				term, err := termsEnum.Next(context.Background())
+				if errors.Is(err, io.EOF) || !assert.Nil(t, err) {
+					break
+				}
+				values = append(values, string(term))
+			}
+			assert.Equal(t, []string{"\u0000", "ab\U00055C17cd", "lu\U0001D11Ece\U0001D160ne", "☠"}, values, "doc=%d", id)
+		}
+	}
+}
+
+func checkLucene811DocValues(t *testing.T, reader index.LeafReader, docBase int) {
+	numDocs := min(reader.MaxDoc(), lucene811DocsCount-docBase)
+	if numDocs <= 0 {
+		return
+	}
+
+	numerics := map[string]func(id int) int64{
+		"dvByte":   func(id int) int64 { return int64(id) },
+		"dvShort":  func(id int) int64 { return int64(id) },
+		"dvInt":    func(id int) int64 { return int64(id) },
+		"dvLong":   func(id int) int64 { return int64(id) },
+		"dvPacked": func(id int) int64 { return int64(id) },
+		"dvFloat":  func(id int) int64 { return int64(math.Float32bits(float32(id))) },
+		"dvDouble": func(id int) int64 { return int64(math.Float64bits(float64(id))) },
+	}
+	for field, expected := range numerics {
+		values, err := reader.GetNumericDocValues(field)
+		if !assert.Nil(t, err) || !assert.NotNil(t, values, field) {
+			continue
+		}
+		for doc := 0; doc < numDocs; doc++ {
+			next, err := values.NextDoc()
+			assert.Nil(t, err)
+			assert.Equal(t, doc, next, field)
+			value, err := values.LongValue()
+			assert.Nil(t, err)
+			assert.Equal(t, expected(docBase+doc), value, field)
+		}
+	}
+
+	expectedBytes := func(id int) []byte {
+		return binary.BigEndian.AppendUint32(nil, uint32(id))
+	}
+	for _, field := range []string{"dvBytesDerefFixed", "dvBytesDerefVar", "dvBytesStraightFixed", "dvBytesStraightVar"} {
+		values, err := reader.GetBinaryDocValues(field)
+		if !assert.Nil(t, err) || !assert.NotNil(t, values, field) {
+			continue
+		}
+		for doc := 0; doc < numDocs; doc++ {
+			next, err := values.NextDoc()
+			assert.Nil(t, err)
+			assert.Equal(t, doc, next, field)
+			value, err := values.BinaryValue()
+			assert.Nil(t, err)
+			assert.Equal(t, expectedBytes(docBase+doc), value, field)
+		}
+	}
+	for _, field := range []string{"dvBytesSortedFixed", "dvBytesSortedVar"} {
+		values, err := reader.GetSortedDocValues(field)
+		if !assert.Nil(t, err) || !assert.NotNil(t, values, field) {
+			continue
+		}
+		for doc := 0; doc < numDocs; doc++ {
+			next, err := values.NextDoc()
+			assert.Nil(t, err)
+			assert.Equal(t, doc, next, field)
+			ord, err := values.OrdValue()
+			assert.Nil(t, err)
+			value, err := values.LookupOrd(ord)
+			assert.Nil(t, err)
+			assert.Equal(t, expectedBytes(docBase+doc), value, field)
+		}
+	}
+
+	sortedSet, err := reader.GetSortedSetDocValues("dvSortedSet")
+	if assert.Nil(t, err) && assert.NotNil(t, sortedSet) {
+		for doc := 0; doc < numDocs; doc++ {
+			next, err := sortedSet.NextDoc()
+			assert.Nil(t, err)
+			assert.Equal(t, doc, next)
+			ord, err := sortedSet.NextOrd()
+			assert.Nil(t, err)
+			value, err := sortedSet.LookupOrd(ord)
+			assert.Nil(t, err)
+			assert.Equal(t, expectedBytes(docBase+doc), value)
+		}
+	}
+
+	sortedNumeric, err := reader.GetSortedNumericDocValues("dvSortedNumeric")
+	if assert.Nil(t, err) && assert.NotNil(t, sortedNumeric) {
+		for doc := 0; doc < numDocs; doc++ {
+			next, err := sortedNumeric.NextDoc()
+			assert.Nil(t, err)
+			assert.Equal(t, doc, next)
+			assert.Equal(t, 1, sortedNumeric.DocValueCount())
+			value, err := sortedNumeric.NextValue()
+			assert.Nil(t, err)
+			assert.Equal(t, int64(docBase+doc), value)
+		}
+	}
+}
+
+func packInts(values ...int32) []byte {
+	packed := make([]byte, 4*len(values))
+	for i, value := range values {
+		numeric.IntToSortableBytes(value, packed[4*i:])
+	}
+	return packed
+}
+
+func packFloats(values ...float32) []byte {
+	packed := make([]byte, 4*len(values))
+	for i, value := range values {
+		bits := int32(math.Float32bits(value))
+		numeric.IntToSortableBytes(bits^((bits>>31)&0x7fffffff), packed[4*i:])
+	}
+	return packed
+}
+
+func packLongs(values ...int64) []byte {
+	packed := make([]byte, 8*len(values))
+	for i, value := range values {
+		numeric.LongToSortableBytes(uint64(value), packed[8*i:])
+	}
+	return packed
+}
+
+func packDoubles(values ...float64) []byte {
+	packed := make([]byte, 8*len(values))
+	for i, value := range values {
+		numeric.LongToSortableBytes(numeric.DoubleToSortableLong(value), packed[8*i:])
+	}
+	return packed
+}
@@ -0,0 +1,145 @@
+package lucene87
+
+import (
+	"github.com/geange/lucene-go/codecs/lucene50"
+	"github.com/geange/lucene-go/codecs/lucene60"
+	"github.com/geange/lucene-go/codecs/lucene80"
+	"github.com/geange/lucene-go/codecs/lucene84"
+	"github.com/geange/lucene-go/codecs/lucene86"
+	"github.com/geange/lucene-go/codecs/perfield"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+)
+
+const (
+	CODEC_NAME = "Lucene87"
+)
+
+func init() {
+	coreIndex.RegisterCodec(NewCodec(BEST_SPEED))
+}
+
+var _ index.Codec = &Codec{}
+
+// Codec Implements the Lucene 8.7 index format: block-tree terms with Lucene84 postings and Lucene80 doc
+// values, both chosen per field, Lucene80 norms, Lucene86 points and segment infos, Lucene60 field
+// infos, Lucene50 live docs, term vectors and compound files, and compressed stored fields.
+//
+// lucene.experimental
+type Codec struct {
+	vectorsFormat      *lucene50.TermVectorsFormat
+	fieldInfosFormat   *lucene60.FieldInfosFormat
+	segmentInfosFormat *lucene86.SegmentInfoFormat
+	liveDocsFormat     *lucene50.LiveDocsFormat
+	compoundFormat     *lucene50.CompoundFormat
+	postingsFormat     *perfield.PostingsFormat
+	docValuesFormat    *perfield.DocValuesFormat
+	storedFieldsFormat *StoredFieldsFormat
+	normsFormat        *lucene80.NormsFormat
+	pointsFormat       *lucene86.PointsFormat
+	mode               Mode
+
+	defaultPostingsFormat  *lucene84.PostingsFormat
+	defaultDocValuesFormat *lucene80.DocValuesFormat
+}
+
+// NewCodec Instantiates a new codec, specifying the stored fields compression mode to use.
+func NewCodec(mode Mode) *Codec {
+	codec := &Codec{
+		vectorsFormat:          lucene50.NewTermVectorsFormat(),
+		fieldInfosFormat:       lucene60.NewFieldInfosFormat(),
+		segmentInfosFormat:     lucene86.NewSegmentInfoFormat(),
+		liveDocsFormat:         lucene50.NewLiveDocsFormat(),
+		compoundFormat:         lucene50.NewCompoundFormat(),
+		storedFieldsFormat:     NewStoredFieldsFormat(mode),
+		normsFormat:            lucene80.NewNormsFormat(),
+		pointsFormat:           lucene86.NewPointsFormat(),
+		mode:                   mode,
+		defaultPostingsFormat:  lucene84.NewPostingsFormat(),
+		defaultDocValuesFormat: lucene80.NewDocValuesFormatWithMode(mode.docValuesMode()),
+	}
+	codec.postingsFormat = perfield.NewPostingsFormat(codec.GetPostingsFormatForField)
+	codec.docValuesFormat = perfield.NewDocValuesFormat(codec.GetDocValuesFormatForField)
+	return codec
+}
+
+func (c *Codec) GetName() string {
+	return CODEC_NAME
+}
+
+// docValuesMode Returns the doc values compression mode that goes with the stored fields mode.
+func (m Mode) docValuesMode() lucene80.Mode {
+	if m == BEST_COMPRESSION {
+		return lucene80.BEST_COMPRESSION
+	}
+	return lucene80.BEST_SPEED
+}
+
+// Mode Returns the stored fields compression mode of this codec.
+func (c *Codec) Mode() Mode {
+	return c.mode
+}
+
+func (c *Codec) PostingsFormat() index.PostingsFormat {
+	return c.postingsFormat
+}
+
+func (c *Codec) DocValuesFormat() index.DocValuesFormat {
+	return c.docValuesFormat
+}
+
+// GetPostingsFormatForField Returns the postings format that should be used for writing new
+// segments of field. The default implementation always returns "Lucene84".
+//
+// To use a different format for some fields, wrap the codec and return a perfield.PostingsFormat
+// built with your own callback from PostingsFormat.
+func (c *Codec) GetPostingsFormatForField(field string) index.PostingsFormat {
+	return c.defaultPostingsFormat
+}
+
+// GetDocValuesFormatForField Returns the docvalues format that should be used for writing new
+// segments of field. The default implementation always returns "Lucene80".
+//
+// To use a different format for some fields, wrap the codec and return a perfield.DocValuesFormat
+// built with your own callback from DocValuesFormat.
+func (c *Codec) GetDocValuesFormatForField(field string) index.DocValuesFormat {
+	return c.defaultDocValuesFormat
+}
+
+func (c *Codec) StoredFieldsFormat() index.StoredFieldsFormat {
+	return c.storedFieldsFormat
+}
+
+func (c *Codec) TermVectorsFormat() index.TermVectorsFormat {
+	return c.vectorsFormat
+}
+
+func (c *Codec) FieldInfosFormat() index.FieldInfosFormat {
+	return c.fieldInfosFormat
+}
+
+func (c *Codec) SegmentInfoFormat() index.SegmentInfoFormat {
+	return c.segmentInfosFormat
+}
+
+func (c *Codec) NormsFormat() index.NormsFormat {
+	return c.normsFormat
+}
+
+func (c *Codec) LiveDocsFormat() index.LiveDocsFormat {
+	return c.liveDocsFormat
+}
+
+func (c *Codec) CompoundFormat() index.CompoundFormat {
+	return c.compoundFormat
+}
+
+func (c *Codec) PointsFormat() index.PointsFormat {
+	return c.pointsFormat
+}
+
+// KnnVectorsFormat Returns nil: the Lucene 8.7 format has no vectors, use the Lucene90 codec to index
+// KnnVectorField.
+func (c *Codec) KnnVectorsFormat() index.KnnVectorsFormat {
+	return nil
+}
@@ -0,0 +1,39 @@
+package lucene87
+
+import (
+	"testing"
+
+	"github.com/geange/lucene-go/codecs/lucene50"
+	"github.com/geange/lucene-go/codecs/lucene60"
+	"github.com/geange/lucene-go/codecs/lucene80"
+	"github.com/geange/lucene-go/codecs/lucene84"
+	"github.com/geange/lucene-go/codecs/lucene86"
+	"github.com/geange/lucene-go/codecs/perfield"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodecRegistered(t *testing.T) {
+	codec, ok := coreIndex.GetCodecByName(CODEC_NAME)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, CODEC_NAME, codec.GetName())
+	assert.IsType(t, &lucene60.FieldInfosFormat{}, codec.FieldInfosFormat())
+	assert.IsType(t, &lucene86.SegmentInfoFormat{}, codec.SegmentInfoFormat())
+	assert.IsType(t, &lucene50.LiveDocsFormat{}, codec.LiveDocsFormat())
+	assert.IsType(t, &lucene50.TermVectorsFormat{}, codec.TermVectorsFormat())
+	assert.IsType(t, &lucene50.CompoundFormat{}, codec.CompoundFormat())
+	assert.IsType(t, &lucene80.NormsFormat{}, codec.NormsFormat())
+	assert.IsType(t, &perfield.PostingsFormat{}, codec.PostingsFormat())
+	assert.IsType(t, &perfield.DocValuesFormat{}, codec.DocValuesFormat())
+	assert.IsType(t, &lucene84.PostingsFormat{}, codec.(*Codec).GetPostingsFormatForField("body"))
+	assert.IsType(t, &lucene80.DocValuesFormat{}, codec.(*Codec).GetDocValuesFormatForField("body"))
+	assert.IsType(t, &lucene86.PointsFormat{}, codec.PointsFormat())
+	assert.Equal(t, BEST_SPEED, codec.(*Codec).Mode())
+	assert.Equal(t, lucene80.NewDocValuesFormat(), codec.(*Codec).GetDocValuesFormatForField("body"))
+
+	// the doc values compression follows the stored fields compression
+	assert.Equal(t, lucene80.NewDocValuesFormatWithMode(lucene80.BEST_COMPRESSION),
+		NewCodec(BEST_COMPRESSION).GetDocValuesFormatForField("body"))
+}
@@ -0,0 +1,160 @@
+package lucene87
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/geange/lucene-go/codecs/compressing"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+)
+
+// Mode Configuration option for stored fields.
+type Mode int
+
+const (
+	// BEST_SPEED Trade compression ratio for retrieval speed.
+	BEST_SPEED = Mode(iota)
+
+	// BEST_COMPRESSION Trade retrieval speed for compression ratio.
+	BEST_COMPRESSION
+)
+
+func (m Mode) String() string {
+	switch m {
+	case BEST_SPEED:
+		return "BEST_SPEED"
+	case BEST_COMPRESSION:
+		return "BEST_COMPRESSION"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// ParseMode Returns the Mode with the given name.
+func ParseMode(name string) (Mode, error) {
+	switch name {
+	case "BEST_SPEED":
+		return BEST_SPEED, nil
+	case "BEST_COMPRESSION":
+		return BEST_COMPRESSION, nil
+	default:
+		return 0, fmt.Errorf("unknown stored fields mode: %s", name)
+	}
+}
+
+const (
+	// MODE_KEY Attribute key for compression mode.
+	MODE_KEY = "Lucene87StoredFieldsFormat.mode"
+
+	// BEST_SPEED_CHUNK_SIZE Shoot for 10 sub blocks of 8kB each.
+	BEST_SPEED_CHUNK_SIZE = 10 * 8 * 1024
+
+	// BEST_SPEED_MAX_DOCS_CHUNK Upper bound of the number of documents of a chunk.
+	BEST_SPEED_MAX_DOCS_CHUNK = 1024
+
+	// BEST_COMPRESSION_CHUNK_SIZE Shoot for 10 sub blocks of 48kB each.
+	BEST_COMPRESSION_CHUNK_SIZE = 10 * 48 * 1024
+
+	// BEST_COMPRESSION_MAX_DOCS_CHUNK Sub blocks of 48kB might contain many documents, allow more of
+	// them in a chunk.
+	BEST_COMPRESSION_MAX_DOCS_CHUNK = 4096
+
+	// BLOCK_SHIFT Number of chunks per block of the fields index, as a power of 2.
+	BLOCK_SHIFT = 10
+)
+
+var _ index.StoredFieldsFormat = &StoredFieldsFormat{}
+
+// StoredFieldsFormat Lucene 8.7 stored fields format.
+//
+// Principle
+// This StoredFieldsFormat compresses blocks of documents in order to improve the compression
+// ratio compared to document-level compression. It uses the LZ4 compression algorithm by default
+// in 8KB sub blocks that share a preset dictionary, which is fast to compress and very fast to
+// decompress data. Although the default compression method that is used (BEST_SPEED) focuses
+// more on speed than on compression ratio, it should provide interesting compression ratios for
+// redundant inputs (such as log files, HTML or plain text). For higher compression, you can
+// choose (BEST_COMPRESSION), which uses the DEFLATE algorithm with 48KB sub blocks and a preset
+// dictionary for better ratio at the expense of slower performance. These two options can be
+// configured like this:
+//
+//	// the default: for high performance
+//	format := NewStoredFieldsFormat(BEST_SPEED)
+//	// instead for higher performance (but slower):
+//	format := NewStoredFieldsFormat(BEST_COMPRESSION)
+//
+// File formats
+// Stored fields are represented by three files:
+//
+//   - A fields data file (extension .fdt). This file stores a compact representation of documents
+//     in compressed blocks of 80KB or more. When writing a segment, documents are appended to an
+//     in-memory byte buffer. When its size reaches 80KB or more, some metadata about the documents
+//     is flushed to disk, immediately followed by a compressed representation of the buffer.
+//   - A fields index file (extension .fdx). This file stores two monotonic arrays, one for the
+//     first doc IDs of each block of compressed documents, and another one for the corresponding
+//     offsets on disk, so that a single chunk needs to be decompressed to load a document.
+//   - A fields meta file (extension .fdm). This file stores metadata about the index and the data
+//     files, such as the number of documents and chunks.
+//
+// The mode is recorded in the segment attributes under MODE_KEY, the reader picks the matching
+// decompressor from there.
+// lucene.experimental
+type StoredFieldsFormat struct {
+	mode Mode
+}
+
+// NewStoredFieldsFormat Stored fields format with specified mode
+func NewStoredFieldsFormat(mode Mode) *StoredFieldsFormat {
+	return &StoredFieldsFormat{mode: mode}
+}
+
+func (s *StoredFieldsFormat) Mode() Mode {
+	return s.mode
+}
+
+func (s *StoredFieldsFormat) FieldsReader(ctx context.Context, directory store.Directory, si index.SegmentInfo,
+	fn index.FieldInfos, ioContext *store.IOContext) (index.StoredFieldsReader, error) {
+
+	value, ok := si.GetAttributes()[MODE_KEY]
+	if !ok {
+		return nil, fmt.Errorf("missing value for %s for segment: %s", MODE_KEY, si.Name())
+	}
+	mode, err := ParseMode(value)
+	if err != nil {
+		return nil, err
+	}
+	format, err := impl(mode)
+	if err != nil {
+		return nil, err
+	}
+	return format.FieldsReader(ctx, directory, si, fn, ioContext)
+}
+
+func (s *StoredFieldsFormat) FieldsWriter(ctx context.Context, directory store.Directory, si index.SegmentInfo,
+	ioContext *store.IOContext) (index.StoredFieldsWriter, error) {
+
+	previous := si.PutAttribute(MODE_KEY, s.mode.String())
+	if previous != "" && previous != s.mode.String() {
+		return nil, fmt.Errorf("found existing value for %s for segment: %s old=%s, new=%s",
+			MODE_KEY, si.Name(), previous, s.mode)
+	}
+	format, err := impl(s.mode)
+	if err != nil {
+		return nil, err
+	}
+	return format.FieldsWriter(ctx, directory, si, ioContext)
+}
+
+func impl(mode Mode) (*compressing.StoredFieldsFormat, error) {
+	switch mode {
+	case BEST_SPEED:
+		return compressing.NewStoredFieldsFormat("Lucene87StoredFieldsFastData", "",
+			compressing.LZ4_WITH_PRESET_DICT, BEST_SPEED_CHUNK_SIZE, BEST_SPEED_MAX_DOCS_CHUNK, BLOCK_SHIFT)
+	case BEST_COMPRESSION:
+		return compressing.NewStoredFieldsFormat("Lucene87StoredFieldsHighData", "",
+			compressing.HIGH_COMPRESSION, BEST_COMPRESSION_CHUNK_SIZE, BEST_COMPRESSION_MAX_DOCS_CHUNK, BLOCK_SHIFT)
+	default:
+		return nil, fmt.Errorf("unsupported stored fields mode: %s", mode)
+	}
+}
@@ -0,0 +1,224 @@
+package lucene87
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ document.StoredFieldVisitor = &testVisitor{}
+
+// testVisitor collects the visited values by field name, skipping the fields in skip and
+// stopping at the field named stop.
+type testVisitor struct {
+	values map[string]any
+	skip   string
+	stop   string
+}
+
+func newTestVisitor() *testVisitor {
+	return &testVisitor{values: make(map[string]any)}
+}
+
+func (v *testVisitor) BinaryField(fieldInfo *document.FieldInfo, value []byte) error {
+	v.values[fieldInfo.Name()] = value
+	return nil
+}
+
+func (v *testVisitor) StringField(fieldInfo *document.FieldInfo, value []byte) error {
+	v.values[fieldInfo.Name()] = string(value)
+	return nil
+}
+
+func (v *testVisitor) Int32Field(fieldInfo *document.FieldInfo, value int32) error {
+	v.values[fieldInfo.Name()] = value
+	return nil
+}
+
+func (v *testVisitor) Int64Field(fieldInfo *document.FieldInfo, value int64) error {
+	v.values[fieldInfo.Name()] = value
+	return nil
+}
+
+func (v *testVisitor) Float32Field(fieldInfo *document.FieldInfo, value float32) error {
+	v.values[fieldInfo.Name()] = value
+	return nil
+}
+
+func (v *testVisitor) Float64Field(fieldInfo *document.FieldInfo, value float64) error {
+	v.values[fieldInfo.Name()] = value
+	return nil
+}
+
+func (v *testVisitor) NeedsField(fieldInfo *document.FieldInfo) (document.STORED_FIELD_VISITOR_STATUS, error) {
+	switch fieldInfo.Name() {
+	case v.skip:
+		return document.STORED_FIELD_VISITOR_NO, nil
+	case v.stop:
+		return document.STORED_FIELD_VISITOR_STOP, nil
+	default:
+		return document.STORED_FIELD_VISITOR_YES, nil
+	}
+}
+
+var testFieldNames = []string{"message", "level", "status", "timestamp", "ratio", "latency", "raw"}
+
+func newTestDoc(r *rand.Rand, i int) map[string]any {
+	doc := map[string]any{
+		"message":   fmt.Sprintf("GET /api/v1/items/%d served in %dms", r.Intn(1000), r.Intn(500)),
+		"level":     []string{"INFO", "WARN", "ERROR"}[r.Intn(3)],
+		"status":    int32(200 + r.Intn(300)),
+		"timestamp": int64(1700000000000 + i*1000),
+		"ratio":     r.Float32(),
+		"latency":   r.Float64() * 1000,
+	}
+	if r.Intn(4) == 0 {
+		// small integral values are stored in a single byte
+		doc["ratio"] = float32(r.Intn(100))
+		doc["latency"] = float64(r.Intn(100))
+	}
+	if r.Intn(10) == 0 {
+		// some large documents span several sub blocks
+		raw := make([]byte, r.Intn(20000))
+		r.Read(raw)
+		doc["raw"] = raw
+	}
+	return doc
+}
+
+func newStoredField(name string, value any) document.IndexableField {
+	switch v := value.(type) {
+	case string:
+		return document.NewStoredField(name, v)
+	case []byte:
+		return document.NewStoredField(name, v)
+	case int32:
+		return document.NewStoredField(name, v)
+	case int64:
+		return document.NewStoredField(name, v)
+	case float32:
+		return document.NewStoredField(name, v)
+	case float64:
+		return document.NewStoredField(name, v)
+	}
+	panic("unsupported stored field type")
+}
+
+func TestStoredFieldsFormat(t *testing.T) {
+	for _, mode := range []Mode{BEST_SPEED, BEST_COMPRESSION} {
+		t.Run(mode.String(), func(t *testing.T) {
+			testStoredFieldsFormat(t, mode)
+		})
+	}
+}
+
+func testStoredFieldsFormat(t *testing.T, mode Mode) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(5))
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	infos := make([]*document.FieldInfo, 0, len(testFieldNames))
+	for i, name := range testFieldNames {
+		infos = append(infos, document.NewFieldInfo(name, i, false, true, false,
+			document.INDEX_OPTIONS_NONE, document.DOC_VALUES_TYPE_NONE, -1,
+			map[string]string{}, 0, 0, 0, false))
+	}
+	fieldInfos := coreIndex.NewFieldInfos(infos)
+
+	numDocs := 3000
+	id := make([]byte, 16)
+	r.Read(id)
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", numDocs,
+		false, nil, map[string]string{}, id, map[string]string{}, nil)
+
+	docs := make([]map[string]any, 0, numDocs)
+	format := NewStoredFieldsFormat(mode)
+	writer, err := format.FieldsWriter(ctx, dir, si, nil)
+	assert.Nil(t, err)
+	for i := 0; i < numDocs; i++ {
+		doc := newTestDoc(r, i)
+		if i == numDocs/2 {
+			// a document that makes its chunk larger than twice the chunk size, which is then
+			// compressed in slices
+			raw := make([]byte, 2*BEST_COMPRESSION_CHUNK_SIZE+r.Intn(1000))
+			r.Read(raw)
+			doc["raw"] = raw
+		}
+		docs = append(docs, doc)
+
+		assert.Nil(t, writer.StartDocument(ctx))
+		for _, info := range infos {
+			if value, ok := doc[info.Name()]; ok {
+				assert.Nil(t, writer.WriteField(ctx, info, newStoredField(info.Name(), value)))
+			}
+		}
+		assert.Nil(t, writer.FinishDocument(ctx))
+	}
+	assert.Nil(t, writer.Finish(ctx, fieldInfos, numDocs))
+	assert.Nil(t, writer.Close())
+
+	assert.Equal(t, mode.String(), si.GetAttributes()[MODE_KEY])
+
+	// a format configured with the other mode reads the mode from the segment attributes
+	reader, err := NewStoredFieldsFormat(1-mode).FieldsReader(ctx, dir, si, fieldInfos, nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer reader.Close()
+	assert.Nil(t, reader.CheckIntegrity())
+
+	for _, docID := range r.Perm(numDocs) {
+		visitor := newTestVisitor()
+		assert.Nil(t, reader.VisitDocument(ctx, docID, visitor))
+		assert.Equal(t, docs[docID], visitor.values, "doc %d", docID)
+	}
+
+	clone := reader.Clone(ctx)
+	visitor := newTestVisitor()
+	visitor.skip = "level"
+	visitor.stop = "ratio"
+	assert.Nil(t, clone.VisitDocument(ctx, 42, visitor))
+	assert.Equal(t, map[string]any{
+		"message":   docs[42]["message"],
+		"status":    docs[42]["status"],
+		"timestamp": docs[42]["timestamp"],
+	}, visitor.values)
+
+	assert.NotNil(t, reader.VisitDocument(ctx, numDocs, newTestVisitor()))
+}
+
+func TestStoredFieldsFormatMode(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", 0,
+		false, nil, map[string]string{}, make([]byte, 16), map[string]string{}, nil)
+
+	// reading requires the mode attribute
+	_, err = NewStoredFieldsFormat(BEST_SPEED).FieldsReader(ctx, dir, si, coreIndex.NewFieldInfos(nil), nil)
+	assert.NotNil(t, err)
+
+	writer, err := NewStoredFieldsFormat(BEST_COMPRESSION).FieldsWriter(ctx, dir, si, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+
+	// a segment can't be written with two different modes
+	_, err = NewStoredFieldsFormat(BEST_SPEED).FieldsWriter(ctx, dir, si, nil)
+	assert.NotNil(t, err)
+
+	mode, err := ParseMode("BEST_COMPRESSION")
+	assert.Nil(t, err)
+	assert.Equal(t, BEST_COMPRESSION, mode)
+	_, err = ParseMode("FASTEST")
+	assert.NotNil(t, err)
+}
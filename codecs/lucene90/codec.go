@@ -0,0 +1,50 @@
+package lucene90
+
+import (
+	"github.com/geange/lucene-go/codecs/lucene87"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+)
+
+const (
+	CODEC_NAME = "Lucene90"
+)
+
+func init() {
+	coreIndex.RegisterCodec(NewCodec(lucene87.BEST_SPEED))
+}
+
+var _ index.Codec = &Codec{}
+
+// Codec Implements the Lucene 9.0 index format: the formats of the Lucene87 codec, except for the
+// Lucene90 field infos, which record the vector dimension and similarity function of the fields, and the
+// Lucene90 HNSW vectors.
+//
+// lucene.experimental
+type Codec struct {
+	*lucene87.Codec
+
+	fieldInfosFormat *FieldInfosFormat
+	knnVectorsFormat *HnswVectorsFormat
+}
+
+// NewCodec Instantiates a new codec, specifying the stored fields compression mode to use.
+func NewCodec(mode lucene87.Mode) *Codec {
+	return &Codec{
+		Codec:            lucene87.NewCodec(mode),
+		fieldInfosFormat: NewFieldInfosFormat(),
+		knnVectorsFormat: NewHnswVectorsFormat(),
+	}
+}
+
+func (c *Codec) GetName() string {
+	return CODEC_NAME
+}
+
+func (c *Codec) FieldInfosFormat() index.FieldInfosFormat {
+	return c.fieldInfosFormat
+}
+
+func (c *Codec) KnnVectorsFormat() index.KnnVectorsFormat {
+	return c.knnVectorsFormat
+}
@@ -0,0 +1,31 @@
+package lucene90
+
+import (
+	"testing"
+
+	"github.com/geange/lucene-go/codecs/lucene50"
+	"github.com/geange/lucene-go/codecs/lucene86"
+	"github.com/geange/lucene-go/codecs/lucene87"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodecRegistered(t *testing.T) {
+	codec, ok := coreIndex.GetCodecByName(CODEC_NAME)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, CODEC_NAME, codec.GetName())
+	assert.IsType(t, &FieldInfosFormat{}, codec.FieldInfosFormat())
+	assert.IsType(t, &HnswVectorsFormat{}, codec.KnnVectorsFormat())
+	assert.IsType(t, &lucene86.SegmentInfoFormat{}, codec.SegmentInfoFormat())
+	assert.IsType(t, &lucene50.LiveDocsFormat{}, codec.LiveDocsFormat())
+	assert.IsType(t, &lucene86.PointsFormat{}, codec.PointsFormat())
+	assert.Equal(t, lucene87.BEST_SPEED, codec.(*Codec).Mode())
+
+	// the Lucene87 codec has no vectors
+	lucene87Codec, ok := coreIndex.GetCodecByName(lucene87.CODEC_NAME)
+	if assert.True(t, ok) {
+		assert.Nil(t, lucene87Codec.KnnVectorsFormat())
+	}
+}
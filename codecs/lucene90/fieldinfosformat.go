@@ -0,0 +1,207 @@
+package lucene90
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/geange/lucene-go/codecs/lucene60"
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+)
+
+const (
+	// FIELD_INFOS_EXTENSION Extension of field infos
+	FIELD_INFOS_EXTENSION = "fnm"
+
+	// FIELD_INFOS_CODEC_NAME Codec header
+	FIELD_INFOS_CODEC_NAME = "Lucene90FieldInfos"
+
+	FORMAT_START   = 0
+	FORMAT_CURRENT = FORMAT_START
+)
+
+var _ index.FieldInfosFormat = &FieldInfosFormat{}
+
+// FieldInfosFormat Lucene 9.0 Field Infos format.
+//
+// Field names are stored in the field info file, with suffix .fnm.
+//
+// FieldInfos (.fnm) --> Header,FieldsCount, <FieldName,FieldNumber, FieldBits,DocValuesBits,DocValuesGen,
+// Attributes,DimensionCount,DimensionNumBytes,VectorDimension,VectorSimilarityFunction> FieldsCount,Footer
+//
+// Data types:
+//   - Header --> IndexHeader
+//   - FieldsCount --> VInt
+//   - FieldName --> String
+//   - FieldBits, IndexOptions, DocValuesBits, VectorSimilarityFunction --> Byte
+//   - FieldNumber, DimensionCount, DimensionNumBytes, VectorDimension --> VInt
+//   - Attributes --> Map<String,String>
+//   - DocValuesGen --> Int64
+//   - Footer --> CodecFooter
+//
+// Field Descriptions:
+//   - FieldsCount: the number of fields in this file.
+//   - FieldName: name of the field as a UTF-8 String.
+//   - FieldNumber: the field's number. Note that unlike previous versions of Lucene, the fields are
+//     not numbered implicitly by their order in the file, instead explicitly.
+//   - FieldBits: a byte containing field options.
+//     The low order bit (0x1) is one for fields that have term vectors stored, and zero for fields
+//     without term vectors.
+//     If the second lowest order-bit is set (0x2), norms are omitted for the indexed field.
+//     If the third lowest-order bit is set (0x4), payloads are stored for the indexed field.
+//   - IndexOptions: a byte containing index options.
+//     0: not indexed
+//     1: indexed as DOCS_ONLY
+//     2: indexed as DOCS_AND_FREQS
+//     3: indexed as DOCS_AND_FREQS_AND_POSITIONS
+//     4: indexed as DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS
+//   - DocValuesBits: a byte containing per-document value types:
+//     0: no DocValues for this field.
+//     1: NumericDocValues. (DocValuesType.NUMERIC)
+//     2: BinaryDocValues. (DocValuesType.BINARY)
+//     3: SortedDocValues. (DocValuesType.SORTED)
+//     4: SortedSetDocValues. (DocValuesType.SORTED_SET)
+//     5: SortedNumericDocValues. (DocValuesType.SORTED_NUMERIC)
+//   - DocValuesGen is the generation count of the field's DocValues. If this is -1, there are no
+//     DocValues updates to that field. Anything above zero means there are updates stored by
+//     DocValuesFormat.
+//   - Attributes: a key-value map of codec-private attributes.
+//   - PointDimensionCount, PointNumBytes: these are non-zero only if the field is indexed as points,
+//     e.g. using LongPoint
+//   - SoftDeletesFlag: true if this field is used as a soft-deletes field
+//   - VectorDimension: the number of dimensions of the field's vectors, non-zero only if the field
+//     is indexed as KnnVectorField.
+//   - VectorSimilarityFunction: a byte containing the similarity function used to compare the
+//     field's vectors:
+//     0: EUCLIDEAN
+//     1: DOT_PRODUCT
+//     2: COSINE
+//
+// lucene.experimental
+type FieldInfosFormat struct {
+}
+
+func NewFieldInfosFormat() *FieldInfosFormat {
+	return &FieldInfosFormat{}
+}
+
+func (f *FieldInfosFormat) Read(ctx context.Context, directory store.Directory, segmentInfo index.SegmentInfo,
+	segmentSuffix string, ioContext *store.IOContext) (index.FieldInfos, error) {
+
+	fileName := store.SegmentFileName(segmentInfo.Name(), segmentSuffix, FIELD_INFOS_EXTENSION)
+	input, err := store.OpenChecksumInput(directory, fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	if _, err := utils.CheckIndexHeader(ctx, input, FIELD_INFOS_CODEC_NAME, FORMAT_START, FORMAT_CURRENT,
+		segmentInfo.GetID(), segmentSuffix); err != nil {
+		return nil, err
+	}
+
+	size, err := input.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]*document.FieldInfo, 0, size)
+	for i := 0; i < int(size); i++ {
+		info, err := readFieldInfo(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+
+	if _, err := utils.CheckCodecFooter(input); err != nil {
+		return nil, err
+	}
+	return coreIndex.NewFieldInfos(infos), nil
+}
+
+// readFieldInfo the entry of a field is the one of the Lucene 6.0 format followed by its vector attributes
+func readFieldInfo(ctx context.Context, input store.DataInput) (*document.FieldInfo, error) {
+	info, err := lucene60.ReadFieldInfo(ctx, input, lucene60.FORMAT_CURRENT)
+	if err != nil {
+		return nil, err
+	}
+
+	vectorDimension, err := input.ReadUvarint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, err := input.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	similarityFunction, err := getVectorSimilarityFunction(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if vectorDimension != 0 {
+		if err := info.SetVectorDimensionAndSimilarityFunction(int(vectorDimension), similarityFunction); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+func (f *FieldInfosFormat) Write(ctx context.Context, directory store.Directory, segmentInfo index.SegmentInfo,
+	segmentSuffix string, infos index.FieldInfos, ioContext *store.IOContext) error {
+
+	fileName := store.SegmentFileName(segmentInfo.Name(), segmentSuffix, FIELD_INFOS_EXTENSION)
+	output, err := store.CreateOutputWithContext(ctx, directory, fileName, ioContext)
+	if err != nil {
+		return err
+	}
+	if err := f.write(ctx, output, segmentInfo, segmentSuffix, infos); err != nil {
+		_ = output.Close()
+		return err
+	}
+	return output.Close()
+}
+
+func (f *FieldInfosFormat) write(ctx context.Context, output store.IndexOutput, segmentInfo index.SegmentInfo,
+	segmentSuffix string, infos index.FieldInfos) error {
+
+	if err := utils.WriteIndexHeader(ctx, output, FIELD_INFOS_CODEC_NAME, FORMAT_CURRENT,
+		segmentInfo.GetID(), segmentSuffix); err != nil {
+		return err
+	}
+	if err := output.WriteUvarint(ctx, uint64(infos.Size())); err != nil {
+		return err
+	}
+	for _, fi := range infos.List() {
+		if err := writeFieldInfo(ctx, output, fi); err != nil {
+			return err
+		}
+	}
+	return utils.WriteFooter(output)
+}
+
+// writeFieldInfo the entry of a field is the one of the Lucene 6.0 format followed by its vector attributes
+func writeFieldInfo(ctx context.Context, output store.DataOutput, fi *document.FieldInfo) error {
+	if err := lucene60.WriteFieldInfo(ctx, output, fi); err != nil {
+		return err
+	}
+	if err := output.WriteUvarint(ctx, uint64(fi.GetVectorDimension())); err != nil {
+		return err
+	}
+	return output.WriteByte(byte(fi.GetVectorSimilarityFunction()))
+}
+
+func getVectorSimilarityFunction(b byte) (document.VectorSimilarityFunction, error) {
+	switch similarityFunction := document.VectorSimilarityFunction(b); similarityFunction {
+	case document.VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN,
+		document.VECTOR_SIMILARITY_FUNCTION_DOT_PRODUCT,
+		document.VECTOR_SIMILARITY_FUNCTION_COSINE:
+		return similarityFunction, nil
+	default:
+		return 0, fmt.Errorf("invalid vector similarity function byte: %d", b)
+	}
+}
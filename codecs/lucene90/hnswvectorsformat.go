@@ -0,0 +1,103 @@
+package lucene90
+
+import (
+	"context"
+
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/util/hnsw"
+)
+
+const (
+	HNSW_VECTORS_FORMAT_NAME = "Lucene90HnswVectorsFormat"
+
+	META_CODEC_NAME         = "Lucene90HnswVectorsFormatMeta"
+	VECTOR_DATA_CODEC_NAME  = "Lucene90HnswVectorsFormatData"
+	VECTOR_INDEX_CODEC_NAME = "Lucene90HnswVectorsFormatIndex"
+
+	// META_EXTENSION Filename extension for the metadata per field
+	META_EXTENSION = "vem"
+
+	// VECTOR_DATA_EXTENSION Filename extension for the vector values
+	VECTOR_DATA_EXTENSION = "vec"
+
+	// VECTOR_INDEX_EXTENSION Filename extension for the HNSW graph
+	VECTOR_INDEX_EXTENSION = "vex"
+
+	VERSION_START   = 0
+	VERSION_CURRENT = VERSION_START
+)
+
+var _ index.KnnVectorsFormat = &HnswVectorsFormat{}
+
+// HnswVectorsFormat Lucene 9.0 vector format, which encodes numeric vector values and an optional
+// associated graph connecting the documents having values. The graph is used to power HNSW search.
+// The format consists of three files:
+//
+// .vec (vector data) file
+// This file stores all the floating-point vector data ordered by field, document ordinal, and
+// vector dimension. The floats are stored in big-endian byte order.
+//
+// .vex (vector index) file
+// Stores graphs connecting the documents for each field organized as a list of nodes' neighbours
+// as following:
+//   - For each level:
+//   - For each node:
+//   - [int32] the number of neighbor nodes
+//   - array[int32] the neighbor ordinals, padded with zeros up to maxConn so that the list of a
+//     node can be found by its position on the level
+//
+// .vem (vector metadata) file
+// For each field:
+//   - [int32] field number
+//   - [int32] vector similarity function ordinal
+//   - [int64] offset to this field's vectors in the .vec file
+//   - [int64] length of this field's vectors, in bytes
+//   - [int64] offset to this field's index in the .vex file
+//   - [int64] length of this field's index data, in bytes
+//   - [int32] dimension of this field's vectors
+//   - [int32] the number of documents having values for this field
+//   - array[int32] the docids of documents having vectors, in order
+//   - [int32] maxConn, the maximum number of connections of a node
+//   - [int32] the number of levels in the graph
+//   - Graph nodes by level. For each level above the bottom one:
+//   - [int32] the number of nodes on this level
+//   - array[int32] the nodes on this level, in increasing order
+//
+// The list of fields is terminated by a field number of -1.
+//
+// lucene.experimental
+type HnswVectorsFormat struct {
+	// Controls how many of the nearest neighbor candidates are connected to the new node. Defaults
+	// to hnsw.DEFAULT_MAX_CONN.
+	maxConn int
+
+	// The number of candidate neighbors to track while searching the graph for each newly inserted
+	// node. Defaults to hnsw.DEFAULT_BEAM_WIDTH.
+	beamWidth int
+}
+
+func NewHnswVectorsFormat() *HnswVectorsFormat {
+	return NewHnswVectorsFormatWithParams(hnsw.DEFAULT_MAX_CONN, hnsw.DEFAULT_BEAM_WIDTH)
+}
+
+// NewHnswVectorsFormatWithParams
+// maxConn: the maximum number of connections to a node in the HNSW graph
+// beamWidth: the size of the queue maintained during graph construction.
+func NewHnswVectorsFormatWithParams(maxConn, beamWidth int) *HnswVectorsFormat {
+	return &HnswVectorsFormat{
+		maxConn:   maxConn,
+		beamWidth: beamWidth,
+	}
+}
+
+func (h *HnswVectorsFormat) GetName() string {
+	return HNSW_VECTORS_FORMAT_NAME
+}
+
+func (h *HnswVectorsFormat) FieldsWriter(ctx context.Context, state *index.SegmentWriteState) (index.KnnVectorsWriter, error) {
+	return NewHnswVectorsWriter(ctx, state, h.maxConn, h.beamWidth)
+}
+
+func (h *HnswVectorsFormat) FieldsReader(ctx context.Context, state *index.SegmentReadState) (index.KnnVectorsReader, error) {
+	return NewHnswVectorsReader(ctx, state)
+}
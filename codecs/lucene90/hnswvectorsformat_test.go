@@ -0,0 +1,211 @@
+package lucene90
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHnswVectorsFormat(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+	maxDoc := 1500
+
+	names := []string{"euclidean", "cosine", "empty"}
+	sims := []document.VectorSimilarityFunction{
+		document.VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN,
+		document.VECTOR_SIMILARITY_FUNCTION_COSINE,
+		document.VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN,
+	}
+	numDocs := []int{1000, 700, 0}
+	dimension := 8
+
+	infos := make([]*document.FieldInfo, 0, len(names))
+	expected := make(map[string]map[int][]float32)
+	for i, name := range names {
+		info := document.NewFieldInfo(name, i, false, true, false,
+			document.INDEX_OPTIONS_NONE, document.DOC_VALUES_TYPE_NONE, -1, map[string]string{},
+			0, 0, 0, false)
+		assert.Nil(t, info.SetVectorDimensionAndSimilarityFunction(dimension, sims[i]))
+		infos = append(infos, info)
+		expected[name] = randomVectors(r, maxDoc, numDocs[i], dimension)
+	}
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+	id := make([]byte, 16)
+	r.Read(id)
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", maxDoc,
+		false, nil, map[string]string{}, id, map[string]string{}, nil)
+	fieldInfos := coreIndex.NewFieldInfos(infos)
+
+	format := NewHnswVectorsFormatWithParams(8, 50)
+	writer, err := format.FieldsWriter(ctx, &index.SegmentWriteState{
+		Directory:   dir,
+		SegmentInfo: si,
+		FieldInfos:  fieldInfos,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	for _, info := range infos {
+		values := coreIndex.NewVectorValuesWriter(info)
+		docs := sortedDocs(expected[info.Name()])
+		for _, doc := range docs {
+			assert.Nil(t, values.AddValue(doc, expected[info.Name()][doc]))
+		}
+		assert.Nil(t, values.Flush(ctx, nil, writer), info.Name())
+	}
+	assert.Nil(t, writer.Finish())
+	assert.Nil(t, writer.Close())
+
+	reader, err := format.FieldsReader(ctx, &index.SegmentReadState{
+		Directory:   dir,
+		SegmentInfo: si,
+		FieldInfos:  fieldInfos,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer reader.Close()
+	assert.Nil(t, reader.CheckIntegrity())
+
+	values, err := reader.GetVectorValues("unknown")
+	assert.Nil(t, err)
+	assert.Nil(t, values)
+
+	for i, name := range names {
+		vectors := expected[name]
+		docs := sortedDocs(vectors)
+
+		// iterate over the vectors
+		values, err := reader.GetVectorValues(name)
+		if !assert.Nil(t, err) || !assert.NotNil(t, values) {
+			continue
+		}
+		assert.Equal(t, dimension, values.Dimension())
+		assert.Equal(t, len(docs), values.Size())
+		for _, doc := range docs {
+			next, err := values.NextDoc()
+			assert.Nil(t, err)
+			assert.Equal(t, doc, next)
+			value, err := values.VectorValue()
+			assert.Nil(t, err)
+			assert.Equal(t, vectors[doc], value)
+		}
+		doc, err := values.NextDoc()
+		assert.True(t, errors.Is(err, io.EOF))
+		assert.Equal(t, types.NO_MORE_DOCS, doc)
+
+		// advance to a document without vector
+		if len(docs) > 10 {
+			values, _ := reader.GetVectorValues(name)
+			target := docs[5] + 1
+			for vectors[target] != nil {
+				target++
+			}
+			doc, err := values.Advance(target)
+			assert.Nil(t, err)
+			assert.Equal(t, docs[sort.SearchInts(docs, target)], doc)
+		}
+
+		// search the nearest neighbors
+		query := randomVector(r, dimension)
+		topDocs, err := reader.Search(ctx, name, query, 10, nil)
+		if !assert.Nil(t, err) {
+			continue
+		}
+		scoreDocs := topDocs.GetScoreDocs()
+		assert.Equal(t, min(10, len(docs)), len(scoreDocs))
+		for j, scoreDoc := range scoreDocs {
+			assert.Equal(t, float64(sims[i].Compare(query, vectors[scoreDoc.GetDoc()])), scoreDoc.GetScore())
+			if j > 0 {
+				assert.LessOrEqual(t, scoreDoc.GetScore(), scoreDocs[j-1].GetScore())
+			}
+		}
+		if len(docs) > 0 {
+			// the nearest document is found
+			assert.Equal(t, bruteForce(query, vectors, sims[i], nil), scoreDocs[0].GetDoc(), name)
+		}
+
+		// only the accepted documents are returned
+		acceptDocs := &oddDocs{maxDoc: maxDoc}
+		topDocs, err = reader.Search(ctx, name, query, 5, acceptDocs)
+		if !assert.Nil(t, err) {
+			continue
+		}
+		for _, scoreDoc := range topDocs.GetScoreDocs() {
+			assert.True(t, acceptDocs.Test(uint(scoreDoc.GetDoc())))
+		}
+		if len(docs) > 0 {
+			assert.Equal(t, bruteForce(query, vectors, sims[i], acceptDocs), topDocs.GetScoreDocs()[0].GetDoc(), name)
+		}
+	}
+
+	// the query must have the dimension of the field
+	_, err = reader.Search(ctx, "euclidean", []float32{1, 2, 3}, 10, nil)
+	assert.NotNil(t, err)
+}
+
+func randomVector(r *rand.Rand, dimension int) []float32 {
+	vector := make([]float32, dimension)
+	for i := range vector {
+		vector[i] = r.Float32()*2 - 1
+	}
+	return vector
+}
+
+func randomVectors(r *rand.Rand, maxDoc, numDocs, dimension int) map[int][]float32 {
+	vectors := make(map[int][]float32, numDocs)
+	for _, doc := range r.Perm(maxDoc)[:numDocs] {
+		vectors[doc] = randomVector(r, dimension)
+	}
+	return vectors
+}
+
+func sortedDocs(vectors map[int][]float32) []int {
+	docs := make([]int, 0, len(vectors))
+	for doc := range vectors {
+		docs = append(docs, doc)
+	}
+	sort.Ints(docs)
+	return docs
+}
+
+func bruteForce(query []float32, vectors map[int][]float32,
+	similarityFunction document.VectorSimilarityFunction, acceptDocs *oddDocs) int {
+
+	best, bestScore := -1, float32(-1)
+	for _, doc := range sortedDocs(vectors) {
+		if acceptDocs != nil && !acceptDocs.Test(uint(doc)) {
+			continue
+		}
+		if score := similarityFunction.Compare(query, vectors[doc]); score > bestScore {
+			best, bestScore = doc, score
+		}
+	}
+	return best
+}
+
+type oddDocs struct {
+	maxDoc int
+}
+
+func (o *oddDocs) Test(index uint) bool {
+	return index%2 == 1
+}
+
+func (o *oddDocs) Len() uint {
+	return uint(o.maxDoc)
+}
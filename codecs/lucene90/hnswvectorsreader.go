@@ -0,0 +1,564 @@
+package lucene90
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/search"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util"
+	"github.com/geange/lucene-go/core/util/hnsw"
+)
+
+var _ index.KnnVectorsReader = &HnswVectorsReader{}
+
+// HnswVectorsReader Reads vectors from the index segments along with index data structures
+// supporting KNN search.
+type HnswVectorsReader struct {
+	fieldInfos  index.FieldInfos
+	fields      map[string]*fieldEntry
+	vectorData  store.IndexInput
+	vectorIndex store.IndexInput
+}
+
+func NewHnswVectorsReader(ctx context.Context, state *index.SegmentReadState) (*HnswVectorsReader, error) {
+	reader := &HnswVectorsReader{
+		fieldInfos: state.FieldInfos,
+		fields:     make(map[string]*fieldEntry),
+	}
+
+	closeOnError := func(err error) (*HnswVectorsReader, error) {
+		_ = reader.Close()
+		return nil, err
+	}
+
+	if err := reader.readMetadata(ctx, state); err != nil {
+		return closeOnError(err)
+	}
+
+	var err error
+	if reader.vectorData, err = openDataInput(ctx, state, VECTOR_DATA_EXTENSION, VECTOR_DATA_CODEC_NAME); err != nil {
+		return closeOnError(err)
+	}
+	if reader.vectorIndex, err = openDataInput(ctx, state, VECTOR_INDEX_EXTENSION, VECTOR_INDEX_CODEC_NAME); err != nil {
+		return closeOnError(err)
+	}
+
+	for name, entry := range reader.fields {
+		if end := entry.vectorDataOffset + entry.vectorDataLength; end > reader.vectorData.Length() {
+			return closeOnError(fmt.Errorf("field=%s: vector data ends at %d, beyond the file length %d",
+				name, end, reader.vectorData.Length()))
+		}
+		if end := entry.vectorIndexOffset + entry.vectorIndexLength; end > reader.vectorIndex.Length() {
+			return closeOnError(fmt.Errorf("field=%s: vector index ends at %d, beyond the file length %d",
+				name, end, reader.vectorIndex.Length()))
+		}
+	}
+	return reader, nil
+}
+
+func (h *HnswVectorsReader) readMetadata(ctx context.Context, state *index.SegmentReadState) error {
+	metaFileName := store.SegmentFileName(state.SegmentInfo.Name(), state.SegmentSuffix, META_EXTENSION)
+	meta, err := store.OpenChecksumInput(state.Directory, metaFileName)
+	if err != nil {
+		return err
+	}
+	defer meta.Close()
+
+	if _, err := utils.CheckIndexHeader(ctx, meta, META_CODEC_NAME, VERSION_START, VERSION_CURRENT,
+		state.SegmentInfo.GetID(), state.SegmentSuffix); err != nil {
+		return err
+	}
+
+	for {
+		fieldNumber, err := meta.ReadUint32(ctx)
+		if err != nil {
+			return err
+		}
+		if int32(fieldNumber) == -1 {
+			break
+		}
+
+		info := state.FieldInfos.FieldInfoByNumber(int(fieldNumber))
+		if info == nil {
+			return fmt.Errorf("invalid field number: %d", fieldNumber)
+		}
+		entry, err := readFieldEntry(ctx, meta)
+		if err != nil {
+			return err
+		}
+		if err := validateFieldEntry(info, entry); err != nil {
+			return err
+		}
+		h.fields[info.Name()] = entry
+	}
+
+	_, err = utils.CheckCodecFooter(meta)
+	return err
+}
+
+func openDataInput(ctx context.Context, state *index.SegmentReadState, extension, codecName string) (store.IndexInput, error) {
+	fileName := store.SegmentFileName(state.SegmentInfo.Name(), state.SegmentSuffix, extension)
+	in, err := store.OpenInputWithContext(ctx, state.Directory, fileName, state.Context)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := utils.CheckIndexHeader(ctx, in, codecName, VERSION_START, VERSION_CURRENT,
+		state.SegmentInfo.GetID(), state.SegmentSuffix); err != nil {
+		_ = in.Close()
+		return nil, err
+	}
+
+	// NOTE: data file is too costly to verify checksum against all the bytes on open,
+	// but for now we at least verify proper structure of the checksum footer: which looks
+	// for FOOTER_MAGIC + algorithmID. This is cheap and can detect some forms of corruption
+	// such as file truncation.
+	if _, err := utils.RetrieveChecksum(in); err != nil {
+		_ = in.Close()
+		return nil, err
+	}
+	return in, nil
+}
+
+func validateFieldEntry(info *document.FieldInfo, entry *fieldEntry) error {
+	dimension := info.GetVectorDimension()
+	if dimension != entry.dimension {
+		return fmt.Errorf("inconsistent vector dimension for field=\"%s\"; %d != %d",
+			info.Name(), dimension, entry.dimension)
+	}
+
+	if info.GetVectorSimilarityFunction() != entry.similarityFunction {
+		return fmt.Errorf("inconsistent vector similarity function for field=\"%s\"; %s != %s",
+			info.Name(), info.GetVectorSimilarityFunction(), entry.similarityFunction)
+	}
+
+	if expected := int64(entry.size()) * int64(dimension) * 4; expected != entry.vectorDataLength {
+		return fmt.Errorf("vector data length %d not matching size=%d * dim=%d * 4 = %d",
+			entry.vectorDataLength, entry.size(), dimension, expected)
+	}
+
+	if expected := entry.levelOffset(entry.numLevels()); expected != entry.vectorIndexLength {
+		return fmt.Errorf("vector index length %d not matching the graph of %d levels = %d",
+			entry.vectorIndexLength, entry.numLevels(), expected)
+	}
+	return nil
+}
+
+func (h *HnswVectorsReader) CheckIntegrity() error {
+	if _, err := utils.ChecksumEntireFile(h.vectorData); err != nil {
+		return err
+	}
+	_, err := utils.ChecksumEntireFile(h.vectorIndex)
+	return err
+}
+
+func (h *HnswVectorsReader) GetVectorValues(field string) (index.VectorValues, error) {
+	entry, ok := h.fields[field]
+	if !ok {
+		return nil, nil
+	}
+	return h.getOffHeapVectorValues(entry)
+}
+
+func (h *HnswVectorsReader) getOffHeapVectorValues(entry *fieldEntry) (*offHeapVectorValues, error) {
+	in, err := h.vectorData.Slice("vector-data", entry.vectorDataOffset, entry.vectorDataLength)
+	if err != nil {
+		return nil, err
+	}
+	return newOffHeapVectorValues(entry.dimension, entry.ordToDoc, in), nil
+}
+
+func (h *HnswVectorsReader) Search(ctx context.Context, field string, target []float32, k int, acceptDocs util.Bits) (index.TopDocs, error) {
+	entry, ok := h.fields[field]
+	if !ok {
+		return nil, nil
+	}
+	if len(target) != entry.dimension {
+		return nil, fmt.Errorf("vector query dimension: %d differs from field dimension: %d",
+			len(target), entry.dimension)
+	}
+
+	if entry.size() == 0 || k <= 0 {
+		return search.NewTopDocs(index.NewTotalHits(0, index.EQUAL_TO), []index.ScoreDoc{}), nil
+	}
+
+	// bound k by total number of vectors to prevent oversizing data structures
+	k = min(k, entry.size())
+
+	vectorValues, err := h.getOffHeapVectorValues(entry)
+	if err != nil {
+		return nil, err
+	}
+	graph, err := h.getGraph(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	var acceptOrds util.Bits
+	if acceptDocs != nil {
+		acceptOrds = &acceptOrdsBits{acceptDocs: acceptDocs, ordToDoc: entry.ordToDoc}
+	}
+
+	results, err := hnsw.Search(target, k, vectorValues, entry.similarityFunction, graph, acceptOrds)
+	if err != nil {
+		return nil, err
+	}
+
+	// the least similar node is on the top of the queue
+	scoreDocs := make([]index.ScoreDoc, results.Size())
+	for i := len(scoreDocs) - 1; i >= 0; i-- {
+		score := results.TopScore()
+		node := results.Pop()
+		scoreDocs[i] = search.NewScoreDoc(entry.ordToDoc[node], float64(score))
+	}
+	totalHits := index.NewTotalHits(int64(results.VisitedCount()), index.GREATER_THAN_OR_EQUAL_TO)
+	return search.NewTopDocs(totalHits, scoreDocs), nil
+}
+
+// GetGraph Get knn graph values; used for testing
+func (h *HnswVectorsReader) GetGraph(field string) (hnsw.Graph, error) {
+	entry, ok := h.fields[field]
+	if !ok {
+		return nil, fmt.Errorf("field=%s has no vectors", field)
+	}
+	return h.getGraph(entry)
+}
+
+func (h *HnswVectorsReader) getGraph(entry *fieldEntry) (*offHeapGraph, error) {
+	in, err := h.vectorIndex.Slice("graph-data", entry.vectorIndexOffset, entry.vectorIndexLength)
+	if err != nil {
+		return nil, err
+	}
+	return &offHeapGraph{
+		entry: entry,
+		in:    in,
+		buf:   make([]byte, entry.neighborsEntrySize()),
+	}, nil
+}
+
+func (h *HnswVectorsReader) Close() error {
+	var errs []error
+	for _, in := range []store.IndexInput{h.vectorData, h.vectorIndex} {
+		if in != nil {
+			if err := in.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	h.vectorData, h.vectorIndex = nil, nil
+	h.fields = nil
+	return errors.Join(errs...)
+}
+
+// fieldEntry the metadata of a field with vectors
+type fieldEntry struct {
+	similarityFunction document.VectorSimilarityFunction
+	vectorDataOffset   int64
+	vectorDataLength   int64
+	vectorIndexOffset  int64
+	vectorIndexLength  int64
+	dimension          int
+	ordToDoc           []int
+	maxConn            int
+
+	// nodesByLevel[level] the nodes of a level above the bottom one, in increasing order;
+	// nodesByLevel[0] is always nil since level 0 holds every node
+	nodesByLevel [][]int
+}
+
+func readFieldEntry(ctx context.Context, in store.DataInput) (*fieldEntry, error) {
+	readInts := func(n int) ([]int, error) {
+		values := make([]int, n)
+		for i := range values {
+			v, err := in.ReadUint32(ctx)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = int(v)
+		}
+		return values, nil
+	}
+
+	header, err := readInts(1)
+	if err != nil {
+		return nil, err
+	}
+	similarityFunction := document.VectorSimilarityFunction(header[0])
+
+	offsets := make([]int64, 4)
+	for i := range offsets {
+		v, err := in.ReadUint64(ctx)
+		if err != nil {
+			return nil, err
+		}
+		offsets[i] = int64(v)
+	}
+
+	sizes, err := readInts(2)
+	if err != nil {
+		return nil, err
+	}
+	dimension, size := sizes[0], sizes[1]
+	ordToDoc, err := readInts(size)
+	if err != nil {
+		return nil, err
+	}
+
+	graphSizes, err := readInts(2)
+	if err != nil {
+		return nil, err
+	}
+	maxConn, numLevels := graphSizes[0], graphSizes[1]
+
+	nodesByLevel := make([][]int, numLevels)
+	for level := 1; level < numLevels; level++ {
+		numNodes, err := readInts(1)
+		if err != nil {
+			return nil, err
+		}
+		if nodesByLevel[level], err = readInts(numNodes[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	return &fieldEntry{
+		similarityFunction: similarityFunction,
+		vectorDataOffset:   offsets[0],
+		vectorDataLength:   offsets[1],
+		vectorIndexOffset:  offsets[2],
+		vectorIndexLength:  offsets[3],
+		dimension:          dimension,
+		ordToDoc:           ordToDoc,
+		maxConn:            maxConn,
+		nodesByLevel:       nodesByLevel,
+	}, nil
+}
+
+func (f *fieldEntry) size() int {
+	return len(f.ordToDoc)
+}
+
+func (f *fieldEntry) numLevels() int {
+	return len(f.nodesByLevel)
+}
+
+func (f *fieldEntry) numNodesOnLevel(level int) int {
+	if level == 0 {
+		return f.size()
+	}
+	return len(f.nodesByLevel[level])
+}
+
+// neighborsEntrySize the size of the neighbors of a node in the index: the number of neighbors
+// followed by maxConn ordinals
+func (f *fieldEntry) neighborsEntrySize() int {
+	return 4 * (f.maxConn + 1)
+}
+
+// levelOffset the offset of the level in the field's index data
+func (f *fieldEntry) levelOffset(level int) int64 {
+	offset := int64(0)
+	for i := 0; i < level; i++ {
+		offset += int64(f.numNodesOnLevel(i)) * int64(f.neighborsEntrySize())
+	}
+	return offset
+}
+
+var _ index.VectorValues = &offHeapVectorValues{}
+var _ index.RandomAccessVectorValues = &offHeapVectorValues{}
+var _ index.RandomAccessVectorValuesProducer = &offHeapVectorValues{}
+
+// offHeapVectorValues Read the vector values from the index input. This supports both iterated
+// and random access.
+type offHeapVectorValues struct {
+	dimension int
+	ordToDoc  []int
+	in        store.IndexInput
+	buf       []byte
+	value     []float32
+	ord       int
+	doc       int
+}
+
+func newOffHeapVectorValues(dimension int, ordToDoc []int, in store.IndexInput) *offHeapVectorValues {
+	return &offHeapVectorValues{
+		dimension: dimension,
+		ordToDoc:  ordToDoc,
+		in:        in,
+		buf:       make([]byte, 4*dimension),
+		value:     make([]float32, dimension),
+		ord:       -1,
+		doc:       -1,
+	}
+}
+
+func (v *offHeapVectorValues) Dimension() int {
+	return v.dimension
+}
+
+func (v *offHeapVectorValues) Size() int {
+	return len(v.ordToDoc)
+}
+
+func (v *offHeapVectorValues) VectorValue() ([]float32, error) {
+	if v.ord < 0 || v.ord >= len(v.ordToDoc) {
+		return nil, errors.New("the iterator is not positioned on a document")
+	}
+	return v.VectorValueByOrd(v.ord)
+}
+
+func (v *offHeapVectorValues) DocID() int {
+	return v.doc
+}
+
+func (v *offHeapVectorValues) NextDoc() (int, error) {
+	v.ord++
+	return v.positionOnOrd()
+}
+
+func (v *offHeapVectorValues) Advance(target int) (int, error) {
+	// We could use the skip list (the ordToDoc) to do a binary search
+	from := v.ord + 1
+	v.ord = from + sort.SearchInts(v.ordToDoc[min(from, len(v.ordToDoc)):], target)
+	return v.positionOnOrd()
+}
+
+func (v *offHeapVectorValues) positionOnOrd() (int, error) {
+	if v.ord >= len(v.ordToDoc) {
+		v.ord = len(v.ordToDoc)
+		v.doc = types.NO_MORE_DOCS
+		return types.NO_MORE_DOCS, io.EOF
+	}
+	v.doc = v.ordToDoc[v.ord]
+	return v.doc, nil
+}
+
+func (v *offHeapVectorValues) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(v, target)
+}
+
+func (v *offHeapVectorValues) Cost() int64 {
+	return int64(len(v.ordToDoc))
+}
+
+func (v *offHeapVectorValues) VectorValueByOrd(targetOrd int) ([]float32, error) {
+	if _, err := v.in.Seek(int64(targetOrd)*int64(len(v.buf)), io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(v.in, v.buf); err != nil {
+		return nil, err
+	}
+	for i := range v.value {
+		v.value[i] = math.Float32frombits(binary.BigEndian.Uint32(v.buf[4*i:]))
+	}
+	return v.value, nil
+}
+
+func (v *offHeapVectorValues) RandomAccess() (index.RandomAccessVectorValues, error) {
+	return newOffHeapVectorValues(v.dimension, v.ordToDoc, v.in.Clone().(store.IndexInput)), nil
+}
+
+var _ hnsw.Graph = &offHeapGraph{}
+
+// offHeapGraph Read the nearest-neighbors graph from the index input
+type offHeapGraph struct {
+	entry     *fieldEntry
+	in        store.IndexInput
+	buf       []byte
+	neighbors []int
+}
+
+func (g *offHeapGraph) Size() int {
+	return g.entry.size()
+}
+
+func (g *offHeapGraph) NumLevels() int {
+	return g.entry.numLevels()
+}
+
+func (g *offHeapGraph) EntryNode() int {
+	numLevels := g.entry.numLevels()
+	switch {
+	case numLevels == 0:
+		return -1
+	case numLevels == 1:
+		return 0
+	default:
+		// the nodes are added in increasing order, the first node of the top level is the entry point
+		return g.entry.nodesByLevel[numLevels-1][0]
+	}
+}
+
+func (g *offHeapGraph) Neighbors(level, node int) ([]int, error) {
+	if level >= g.entry.numLevels() {
+		return nil, fmt.Errorf("level %d does not exist", level)
+	}
+
+	idx := node
+	if level > 0 {
+		nodes := g.entry.nodesByLevel[level]
+		idx = sort.SearchInts(nodes, node)
+		if idx >= len(nodes) || nodes[idx] != node {
+			return nil, fmt.Errorf("node %d is not on level %d", node, level)
+		}
+	} else if node < 0 || node >= g.entry.size() {
+		return nil, fmt.Errorf("node %d is out of bounds", node)
+	}
+
+	offset := g.entry.levelOffset(level) + int64(idx)*int64(len(g.buf))
+	if _, err := g.in.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(g.in, g.buf); err != nil {
+		return nil, err
+	}
+
+	size := int(binary.BigEndian.Uint32(g.buf))
+	if size > g.entry.maxConn {
+		return nil, fmt.Errorf("node %d has %d neighbors, more than maxConn=%d", node, size, g.entry.maxConn)
+	}
+	g.neighbors = g.neighbors[:0]
+	for i := 0; i < size; i++ {
+		g.neighbors = append(g.neighbors, int(binary.BigEndian.Uint32(g.buf[4*(i+1):])))
+	}
+	return g.neighbors, nil
+}
+
+func (g *offHeapGraph) NodesOnLevel(level int) ([]int, error) {
+	if level >= g.entry.numLevels() {
+		return nil, fmt.Errorf("level %d does not exist", level)
+	}
+	if level > 0 {
+		return g.entry.nodesByLevel[level], nil
+	}
+	nodes := make([]int, g.entry.size())
+	for i := range nodes {
+		nodes[i] = i
+	}
+	return nodes, nil
+}
+
+var _ util.Bits = &acceptOrdsBits{}
+
+// acceptOrdsBits the accepted documents, addressed by vector ordinal
+type acceptOrdsBits struct {
+	acceptDocs util.Bits
+	ordToDoc   []int
+}
+
+func (a *acceptOrdsBits) Test(index uint) bool {
+	return a.acceptDocs.Test(uint(a.ordToDoc[index]))
+}
+
+func (a *acceptOrdsBits) Len() uint {
+	return uint(len(a.ordToDoc))
+}
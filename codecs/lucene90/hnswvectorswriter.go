@@ -0,0 +1,320 @@
+package lucene90
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/hnsw"
+)
+
+var _ index.KnnVectorsWriter = &HnswVectorsWriter{}
+
+// HnswVectorsWriter Writes vector values and knn graphs to index segments.
+type HnswVectorsWriter struct {
+	segmentWriteState *index.SegmentWriteState
+	meta              store.IndexOutput
+	vectorData        store.IndexOutput
+	vectorIndex       store.IndexOutput
+	maxConn           int
+	beamWidth         int
+	finished          bool
+}
+
+func NewHnswVectorsWriter(ctx context.Context, state *index.SegmentWriteState, maxConn, beamWidth int) (*HnswVectorsWriter, error) {
+	writer := &HnswVectorsWriter{
+		segmentWriteState: state,
+		maxConn:           maxConn,
+		beamWidth:         beamWidth,
+	}
+
+	closeOnError := func(err error) (*HnswVectorsWriter, error) {
+		_ = writer.Close()
+		return nil, err
+	}
+
+	var err error
+	if writer.meta, err = writer.createOutput(ctx, META_EXTENSION, META_CODEC_NAME); err != nil {
+		return closeOnError(err)
+	}
+	if writer.vectorData, err = writer.createOutput(ctx, VECTOR_DATA_EXTENSION, VECTOR_DATA_CODEC_NAME); err != nil {
+		return closeOnError(err)
+	}
+	if writer.vectorIndex, err = writer.createOutput(ctx, VECTOR_INDEX_EXTENSION, VECTOR_INDEX_CODEC_NAME); err != nil {
+		return closeOnError(err)
+	}
+	return writer, nil
+}
+
+func (h *HnswVectorsWriter) createOutput(ctx context.Context, extension, codecName string) (store.IndexOutput, error) {
+	state := h.segmentWriteState
+	fileName := store.SegmentFileName(state.SegmentInfo.Name(), state.SegmentSuffix, extension)
+	out, err := store.CreateOutputWithContext(ctx, state.Directory, fileName, state.Context)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.WriteIndexHeader(ctx, out, codecName, VERSION_CURRENT,
+		state.SegmentInfo.GetID(), state.SegmentSuffix); err != nil {
+		_ = out.Close()
+		return nil, err
+	}
+	return out, nil
+}
+
+func (h *HnswVectorsWriter) WriteField(ctx context.Context, fieldInfo *document.FieldInfo, reader index.KnnVectorsReader) error {
+	vectors, err := reader.GetVectorValues(fieldInfo.Name())
+	if err != nil {
+		return err
+	}
+	if vectors == nil {
+		return nil
+	}
+	if vectors.Dimension() != fieldInfo.GetVectorDimension() {
+		return fmt.Errorf("field=%s has vector dimension %d, but the vectors have dimension %d",
+			fieldInfo.Name(), fieldInfo.GetVectorDimension(), vectors.Dimension())
+	}
+
+	values, err := newBufferedVectorValues(vectors)
+	if err != nil {
+		return err
+	}
+
+	vectorDataOffset := h.vectorData.GetFilePointer()
+	if err := h.writeVectorData(values); err != nil {
+		return err
+	}
+	vectorDataLength := h.vectorData.GetFilePointer() - vectorDataOffset
+
+	var graph *hnsw.OnHeapGraph
+	if values.Size() > 0 {
+		builder, err := hnsw.NewGraphBuilder(values, fieldInfo.GetVectorSimilarityFunction(),
+			h.maxConn, h.beamWidth, hnsw.DEFAULT_RAND_SEED)
+		if err != nil {
+			return err
+		}
+		buildValues, err := values.RandomAccess()
+		if err != nil {
+			return err
+		}
+		if graph, err = builder.Build(buildValues); err != nil {
+			return err
+		}
+	}
+
+	vectorIndexOffset := h.vectorIndex.GetFilePointer()
+	if err := h.writeGraph(ctx, graph); err != nil {
+		return err
+	}
+	vectorIndexLength := h.vectorIndex.GetFilePointer() - vectorIndexOffset
+
+	return h.writeMeta(ctx, fieldInfo, vectorDataOffset, vectorDataLength,
+		vectorIndexOffset, vectorIndexLength, values.docIDs, graph)
+}
+
+func (h *HnswVectorsWriter) writeVectorData(values *bufferedVectorValues) error {
+	buf := make([]byte, 4*values.Dimension())
+	for _, vector := range values.vectors {
+		for i, v := range vector {
+			binary.BigEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+		}
+		if _, err := h.vectorData.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeGraph writes the neighbors of every node, level by level. The neighbor lists are padded to
+// maxConn so that the reader can locate them without an offsets table.
+func (h *HnswVectorsWriter) writeGraph(ctx context.Context, graph *hnsw.OnHeapGraph) error {
+	if graph == nil {
+		return nil
+	}
+
+	for level := 0; level < graph.NumLevels(); level++ {
+		nodes, err := graph.NodesOnLevel(level)
+		if err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			neighbors, err := graph.Neighbors(level, node)
+			if err != nil {
+				return err
+			}
+			if len(neighbors) > h.maxConn {
+				return fmt.Errorf("node %d has %d neighbors, more than maxConn=%d", node, len(neighbors), h.maxConn)
+			}
+			if err := h.vectorIndex.WriteUint32(ctx, uint32(len(neighbors))); err != nil {
+				return err
+			}
+			for _, neighbor := range neighbors {
+				if err := h.vectorIndex.WriteUint32(ctx, uint32(neighbor)); err != nil {
+					return err
+				}
+			}
+			for i := len(neighbors); i < h.maxConn; i++ {
+				if err := h.vectorIndex.WriteUint32(ctx, 0); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (h *HnswVectorsWriter) writeMeta(ctx context.Context, fieldInfo *document.FieldInfo,
+	vectorDataOffset, vectorDataLength, vectorIndexOffset, vectorIndexLength int64,
+	docIDs []int, graph *hnsw.OnHeapGraph) error {
+
+	meta := h.meta
+	if err := meta.WriteUint32(ctx, uint32(fieldInfo.Number())); err != nil {
+		return err
+	}
+	if err := meta.WriteUint32(ctx, uint32(fieldInfo.GetVectorSimilarityFunction())); err != nil {
+		return err
+	}
+	for _, v := range []int64{vectorDataOffset, vectorDataLength, vectorIndexOffset, vectorIndexLength} {
+		if err := meta.WriteUint64(ctx, uint64(v)); err != nil {
+			return err
+		}
+	}
+	if err := meta.WriteUint32(ctx, uint32(fieldInfo.GetVectorDimension())); err != nil {
+		return err
+	}
+	if err := meta.WriteUint32(ctx, uint32(len(docIDs))); err != nil {
+		return err
+	}
+	for _, docID := range docIDs {
+		if err := meta.WriteUint32(ctx, uint32(docID)); err != nil {
+			return err
+		}
+	}
+
+	if err := meta.WriteUint32(ctx, uint32(h.maxConn)); err != nil {
+		return err
+	}
+	numLevels := 0
+	if graph != nil {
+		numLevels = graph.NumLevels()
+	}
+	if err := meta.WriteUint32(ctx, uint32(numLevels)); err != nil {
+		return err
+	}
+	// level 0 holds every node, only the upper levels are recorded
+	for level := 1; level < numLevels; level++ {
+		nodes, err := graph.NodesOnLevel(level)
+		if err != nil {
+			return err
+		}
+		if err := meta.WriteUint32(ctx, uint32(len(nodes))); err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			if err := meta.WriteUint32(ctx, uint32(node)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (h *HnswVectorsWriter) Finish() error {
+	if h.finished {
+		return errors.New("already finished")
+	}
+	h.finished = true
+
+	// write end of fields marker
+	if err := h.meta.WriteUint32(nil, math.MaxUint32); err != nil {
+		return err
+	}
+	if err := utils.WriteFooter(h.meta); err != nil {
+		return err
+	}
+	if err := utils.WriteFooter(h.vectorData); err != nil {
+		return err
+	}
+	return utils.WriteFooter(h.vectorIndex)
+}
+
+func (h *HnswVectorsWriter) Close() error {
+	var errs []error
+	for _, out := range []store.IndexOutput{h.meta, h.vectorData, h.vectorIndex} {
+		if out != nil {
+			if err := out.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	h.meta, h.vectorData, h.vectorIndex = nil, nil, nil
+	return errors.Join(errs...)
+}
+
+var _ index.RandomAccessVectorValues = &bufferedVectorValues{}
+var _ index.RandomAccessVectorValuesProducer = &bufferedVectorValues{}
+
+// bufferedVectorValues the vectors of a field copied in memory in document order, so that the
+// graph can be built with random access to them
+type bufferedVectorValues struct {
+	dimension int
+	docIDs    []int
+	vectors   [][]float32
+}
+
+func newBufferedVectorValues(values index.VectorValues) (*bufferedVectorValues, error) {
+	buffered := &bufferedVectorValues{
+		dimension: values.Dimension(),
+		docIDs:    make([]int, 0, values.Size()),
+		vectors:   make([][]float32, 0, values.Size()),
+	}
+	for {
+		doc, err := values.NextDoc()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if doc == types.NO_MORE_DOCS {
+			break
+		}
+
+		value, err := values.VectorValue()
+		if err != nil {
+			return nil, err
+		}
+		if len(value) != buffered.dimension {
+			return nil, fmt.Errorf("doc=%d has vector dimension %d, expected %d", doc, len(value), buffered.dimension)
+		}
+		vector := make([]float32, len(value))
+		copy(vector, value)
+		buffered.docIDs = append(buffered.docIDs, doc)
+		buffered.vectors = append(buffered.vectors, vector)
+	}
+	return buffered, nil
+}
+
+func (b *bufferedVectorValues) Size() int {
+	return len(b.vectors)
+}
+
+func (b *bufferedVectorValues) Dimension() int {
+	return b.dimension
+}
+
+func (b *bufferedVectorValues) VectorValueByOrd(targetOrd int) ([]float32, error) {
+	return b.vectors[targetOrd], nil
+}
+
+// RandomAccess the vectors are never modified, so the values can be shared
+func (b *bufferedVectorValues) RandomAccess() (index.RandomAccessVectorValues, error) {
+	return b, nil
+}
@@ -0,0 +1,360 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+
+	"github.com/geange/lucene-go/codecs/lucene84"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/fst"
+	"github.com/geange/lucene-go/core/util/packed"
+)
+
+const (
+	DIRECT_POSTINGS_NAME = "Direct"
+
+	// DEFAULT_MAX_RAM_BYTES_PER_FIELD fields whose terms and postings would need more memory than
+	// this are left on disk
+	DEFAULT_MAX_RAM_BYTES_PER_FIELD = 16 * 1024 * 1024
+
+	offsetsPageSize = 1024
+)
+
+func init() {
+	coreIndex.RegisterPostingsFormat(NewDirectPostingsFormat())
+}
+
+var _ index.PostingsFormat = &DirectPostingsFormat{}
+
+// DirectPostingsFormat Wraps Lucene84 postings format for on-disk storage, but then at read time
+// loads and stores all terms and postings directly in RAM: the terms of a field are compiled into
+// an FST mapping each term to its ordinal, and the postings are decoded into int arrays. Once a
+// segment is open, reads on a loaded field never touch the Directory.
+//
+// WARNING: This is exceptionally RAM intensive: it makes no effort to compress the postings data,
+// storing terms as separate byte arrays and postings as int arrays. The memory used by each field
+// is bounded by maxRAMBytesPerField: a field that does not fit is read from the Lucene84 terms
+// dictionary like any other field. This makes the format a good fit for small, query-heavy fields
+// such as ids and tags.
+//
+// lucene.experimental
+type DirectPostingsFormat struct {
+	name                string
+	delegate            index.PostingsFormat
+	maxRAMBytesPerField int64
+}
+
+// NewDirectPostingsFormat Creates DirectPostingsFormat with the default per field RAM budget.
+func NewDirectPostingsFormat() *DirectPostingsFormat {
+	return NewDirectPostingsFormatWithRAMBudget(DEFAULT_MAX_RAM_BYTES_PER_FIELD)
+}
+
+// NewDirectPostingsFormatWithRAMBudget Creates DirectPostingsFormat which only loads the fields
+// whose terms and postings fit in maxRAMBytesPerField bytes.
+func NewDirectPostingsFormatWithRAMBudget(maxRAMBytesPerField int64) *DirectPostingsFormat {
+	return &DirectPostingsFormat{
+		name:                DIRECT_POSTINGS_NAME,
+		delegate:            lucene84.NewPostingsFormat(),
+		maxRAMBytesPerField: maxRAMBytesPerField,
+	}
+}
+
+func (d *DirectPostingsFormat) GetName() string {
+	return d.name
+}
+
+func (d *DirectPostingsFormat) FieldsConsumer(ctx context.Context, state *index.SegmentWriteState) (index.FieldsConsumer, error) {
+	return d.delegate.FieldsConsumer(ctx, state)
+}
+
+func (d *DirectPostingsFormat) FieldsProducer(ctx context.Context, state *index.SegmentReadState) (index.FieldsProducer, error) {
+	delegate, err := d.delegate.FieldsProducer(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	producer, err := newDirectFields(ctx, state, delegate, d.maxRAMBytesPerField)
+	if err != nil {
+		_ = delegate.Close()
+		return nil, err
+	}
+	return producer, nil
+}
+
+var _ index.FieldsProducer = &directFields{}
+
+type directFields struct {
+	names  []string
+	fields map[string]index.Terms
+
+	// delegate is only kept open while some fields are still read from disk
+	delegate index.FieldsProducer
+}
+
+func newDirectFields(ctx context.Context, state *index.SegmentReadState,
+	delegate index.FieldsProducer, maxRAMBytesPerField int64) (*directFields, error) {
+
+	names := slices.Clone(delegate.Names())
+	fields := make(map[string]index.Terms, len(names))
+	onDisk := 0
+	for _, field := range names {
+		terms, err := delegate.Terms(field)
+		if err != nil {
+			return nil, err
+		}
+		if terms == nil {
+			continue
+		}
+		fieldInfo := state.FieldInfos.FieldInfo(field)
+		if fieldInfo == nil {
+			return nil, errors.New("field " + field + " not found in field infos")
+		}
+		loaded, err := loadDirectField(ctx, fieldInfo, terms, maxRAMBytesPerField)
+		if err != nil {
+			return nil, err
+		}
+		if loaded == nil {
+			// over budget: keep reading this field from the terms dictionary
+			fields[field] = terms
+			onDisk++
+			continue
+		}
+		fields[field] = loaded
+	}
+
+	producer := &directFields{names: names, fields: fields}
+	if onDisk == 0 {
+		// everything is in RAM
+		if err := delegate.Close(); err != nil {
+			return nil, err
+		}
+	} else {
+		producer.delegate = delegate
+	}
+	return producer, nil
+}
+
+func (d *directFields) Names() []string {
+	return d.names
+}
+
+func (d *directFields) Terms(field string) (index.Terms, error) {
+	return d.fields[field], nil
+}
+
+func (d *directFields) Size() int {
+	return len(d.fields)
+}
+
+func (d *directFields) Close() error {
+	if d.delegate == nil {
+		return nil
+	}
+	return d.delegate.Close()
+}
+
+func (d *directFields) CheckIntegrity() error {
+	// if we read entirely into ram, we already validated.
+	// otherwise returned the raw postings reader
+	if d.delegate == nil {
+		return nil
+	}
+	return d.delegate.CheckIntegrity()
+}
+
+func (d *directFields) GetMergeInstance() index.FieldsProducer {
+	return d
+}
+
+// directFieldLoader accumulates the terms and postings of a field while keeping track of the
+// memory they use.
+type directFieldLoader struct {
+	field       *directField
+	termStarts  *packed.MonotonicLongValuesBuilder
+	docStarts   *packed.MonotonicLongValuesBuilder
+	posStarts   *packed.MonotonicLongValuesBuilder
+	fstBuilder  *fst.Builder
+	ramBytes    int64
+	maxRAMBytes int64
+}
+
+// loadDirectField Loads all terms and postings of a field in RAM. It returns nil if the field
+// needs more than maxRAMBytes bytes.
+func loadDirectField(ctx context.Context, fieldInfo *document.FieldInfo,
+	terms index.Terms, maxRAMBytes int64) (*directField, error) {
+
+	fstBuilder, err := fst.NewBuilder(fst.BYTE1, fst.NewBoxManager[int64]())
+	if err != nil {
+		return nil, err
+	}
+	field, err := newDirectField(fieldInfo, terms)
+	if err != nil {
+		return nil, err
+	}
+	loader := &directFieldLoader{
+		field:       field,
+		termStarts:  packed.NewMonotonicLongValuesBuilder(offsetsPageSize, packed.COMPACT),
+		docStarts:   packed.NewMonotonicLongValuesBuilder(offsetsPageSize, packed.COMPACT),
+		posStarts:   packed.NewMonotonicLongValuesBuilder(offsetsPageSize, packed.COMPACT),
+		fstBuilder:  fstBuilder,
+		maxRAMBytes: maxRAMBytes,
+	}
+
+	termsEnum, err := terms.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	var postingsEnum index.PostingsEnum
+	for {
+		term, err := termsEnum.Next(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if term == nil {
+			break
+		}
+		postingsEnum, err = termsEnum.Postings(postingsEnum, field.postingsFlags())
+		if err != nil {
+			return nil, err
+		}
+		ok, err := loader.addTerm(ctx, term, postingsEnum)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, nil
+		}
+	}
+	return loader.finish(ctx)
+}
+
+// addTerm Adds a term and its postings, returns false once the field is over budget.
+func (l *directFieldLoader) addTerm(ctx context.Context, term []byte, postingsEnum index.PostingsEnum) (bool, error) {
+	f := l.field
+	ord := int64(f.termCount)
+
+	if err := l.termStarts.Add(int64(len(f.termBytes))); err != nil {
+		return false, err
+	}
+	if err := l.docStarts.Add(int64(len(f.docs))); err != nil {
+		return false, err
+	}
+	if err := l.posStarts.Add(int64(len(f.positions))); err != nil {
+		return false, err
+	}
+	f.termBytes = append(f.termBytes, term...)
+
+	input := make([]int, len(term))
+	for i, b := range term {
+		input[i] = int(b)
+	}
+	if err := l.fstBuilder.AddInts(ctx, input, fst.NewIntBox(ord)); err != nil {
+		return false, err
+	}
+
+	// the terms are stored twice: once in the FST and once in termBytes
+	ramBytes := int64(2 * len(term))
+	totalTermFreq := int64(0)
+	for {
+		doc, err := postingsEnum.NextDoc()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return false, err
+		}
+		if doc == types.NO_MORE_DOCS {
+			break
+		}
+		f.docs = append(f.docs, doc)
+		ramBytes += 8
+
+		freq := 1
+		if f.hasFreqs {
+			if freq, err = postingsEnum.Freq(); err != nil {
+				return false, err
+			}
+			f.freqs = append(f.freqs, freq)
+			ramBytes += 8
+		}
+		totalTermFreq += int64(freq)
+
+		if !f.hasPositions {
+			continue
+		}
+		for i := 0; i < freq; i++ {
+			pos, err := postingsEnum.NextPosition()
+			if err != nil {
+				return false, err
+			}
+			f.positions = append(f.positions, pos)
+			ramBytes += 8
+			if f.hasOffsets {
+				startOffset, err := postingsEnum.StartOffset()
+				if err != nil {
+					return false, err
+				}
+				endOffset, err := postingsEnum.EndOffset()
+				if err != nil {
+					return false, err
+				}
+				f.startOffsets = append(f.startOffsets, startOffset)
+				f.endOffsets = append(f.endOffsets, endOffset)
+				ramBytes += 16
+			}
+			if f.hasPayloads {
+				payload, err := postingsEnum.GetPayload()
+				if err != nil {
+					return false, err
+				}
+				f.payloads = append(f.payloads, slices.Clone(payload))
+				ramBytes += int64(len(payload)) + 24
+			}
+		}
+	}
+	f.totalTermFreqs = append(f.totalTermFreqs, totalTermFreq)
+	ramBytes += 8
+
+	f.termCount++
+	l.ramBytes += ramBytes
+	return l.ramBytes <= l.maxRAMBytes, nil
+}
+
+func (l *directFieldLoader) finish(ctx context.Context) (*directField, error) {
+	f := l.field
+
+	// one more entry, so that the postings of ord end where the ones of ord+1 start
+	if err := l.termStarts.Add(int64(len(f.termBytes))); err != nil {
+		return nil, err
+	}
+	if err := l.docStarts.Add(int64(len(f.docs))); err != nil {
+		return nil, err
+	}
+	if err := l.posStarts.Add(int64(len(f.positions))); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if f.termStarts, err = l.termStarts.Build(); err != nil {
+		return nil, err
+	}
+	if f.docStarts, err = l.docStarts.Build(); err != nil {
+		return nil, err
+	}
+	if f.posStarts, err = l.posStarts.Build(); err != nil {
+		return nil, err
+	}
+	if f.termCount > 0 {
+		if f.fst, err = l.fstBuilder.Finish(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
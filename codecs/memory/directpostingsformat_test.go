@@ -0,0 +1,211 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/geange/lucene-go/codecs/codectest"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestTerms numTerms terms, the i-th term is in the documents that are multiples of i+1
+func newTestTerms(prefix string, numTerms, numDocs int) []codectest.Term {
+	terms := make([]codectest.Term, 0, numTerms)
+	for i := 0; i < numTerms; i++ {
+		term := codectest.Term{Text: []byte(fmt.Sprintf("%s%04d", prefix, i))}
+		for doc := 0; doc < numDocs; doc += i + 1 {
+			positions := make([]codectest.Position, 0, doc%3+1)
+			for j := 0; j <= doc%3; j++ {
+				positions = append(positions, codectest.Position{Pos: j * (i + 2)})
+			}
+			term.Docs = append(term.Docs, codectest.Doc{ID: doc, Positions: positions})
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+func writeTestSegment(t *testing.T, dir store.Directory, fields *codectest.Fields) (*coreIndex.SegmentInfo, index.FieldInfos) {
+	infos := make([]*document.FieldInfo, 0, fields.Size())
+	for i, name := range fields.Names() {
+		infos = append(infos, document.NewFieldInfo(name, i, false, true, false,
+			document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS, document.DOC_VALUES_TYPE_NONE, -1,
+			map[string]string{}, 0, 0, 0, false))
+	}
+	fieldInfos := coreIndex.NewFieldInfos(infos)
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", 100, false, nil,
+		map[string]string{}, []byte("0123456789abcdef"), map[string]string{}, nil)
+
+	ctx := context.Background()
+	consumer, err := NewDirectPostingsFormat().FieldsConsumer(ctx, index.NewSegmentWriteState(dir, si, fieldInfos, nil, nil))
+	assert.Nil(t, err)
+	assert.Nil(t, consumer.Write(ctx, fields, nil))
+	assert.Nil(t, consumer.Close())
+	return si, fieldInfos
+}
+
+func TestDirectPostingsFormat(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	fields := codectest.NewFields(document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS, map[string][]codectest.Term{
+		"body": newTestTerms("body", 500, 100),
+		"tag":  newTestTerms("tag", 20, 100),
+	})
+	si, fieldInfos := writeTestSegment(t, dir, fields)
+
+	// body does not fit in 16KB, tag does
+	format := NewDirectPostingsFormatWithRAMBudget(16 * 1024)
+	producer, err := format.FieldsProducer(ctx, index.NewSegmentReadState(dir, si, fieldInfos, nil, ""))
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer producer.Close()
+	assert.Nil(t, producer.CheckIntegrity())
+	assert.Equal(t, []string{"body", "tag"}, producer.Names())
+
+	body, err := producer.Terms("body")
+	assert.Nil(t, err)
+	_, loaded := body.(*directField)
+	assert.False(t, loaded)
+
+	terms, err := producer.Terms("tag")
+	assert.Nil(t, err)
+	if !assert.IsType(t, &directField{}, terms) {
+		return
+	}
+	size, err := terms.Size()
+	assert.Nil(t, err)
+	assert.Equal(t, 20, size)
+	minTerm, err := terms.GetMin()
+	assert.Nil(t, err)
+	assert.Equal(t, "tag0000", string(minTerm))
+	maxTerm, err := terms.GetMax()
+	assert.Nil(t, err)
+	assert.Equal(t, "tag0019", string(maxTerm))
+
+	// every term and its postings can be read back
+	termsEnum, err := terms.Iterator()
+	assert.Nil(t, err)
+	var postings index.PostingsEnum
+	for i, expected := range fields.FieldTerms("tag") {
+		term, err := termsEnum.Next(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, expected.Text, term)
+		ord, err := termsEnum.Ord()
+		assert.Nil(t, err)
+		assert.EqualValues(t, i, ord)
+		docFreq, err := termsEnum.DocFreq()
+		assert.Nil(t, err)
+		assert.Equal(t, len(expected.Docs), docFreq)
+
+		postings, err = termsEnum.Postings(postings, coreIndex.POSTINGS_ENUM_POSITIONS)
+		assert.Nil(t, err)
+		for _, expectedDoc := range expected.Docs {
+			doc, err := postings.NextDoc()
+			assert.Nil(t, err)
+			assert.Equal(t, expectedDoc.ID, doc)
+			freq, err := postings.Freq()
+			assert.Nil(t, err)
+			assert.Equal(t, len(expectedDoc.Positions), freq)
+			for _, position := range expectedDoc.Positions {
+				pos, err := postings.NextPosition()
+				assert.Nil(t, err)
+				assert.Equal(t, position.Pos, pos)
+			}
+		}
+		doc, err := postings.NextDoc()
+		assert.Nil(t, err)
+		assert.Equal(t, types.NO_MORE_DOCS, doc)
+	}
+	term, err := termsEnum.Next(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, term)
+
+	// seeks go through the FST
+	found, err := termsEnum.SeekExact(ctx, []byte("tag0007"))
+	assert.Nil(t, err)
+	assert.True(t, found)
+	ord, err := termsEnum.Ord()
+	assert.Nil(t, err)
+	assert.EqualValues(t, 7, ord)
+
+	found, err = termsEnum.SeekExact(ctx, []byte("tag0007a"))
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	status, err := termsEnum.SeekCeil(ctx, []byte("tag0007a"))
+	assert.Nil(t, err)
+	assert.EqualValues(t, index.SEEK_STATUS_NOT_FOUND, status)
+	term, err = termsEnum.Term()
+	assert.Nil(t, err)
+	assert.Equal(t, "tag0008", string(term))
+
+	status, err = termsEnum.SeekCeil(ctx, []byte("tah"))
+	assert.Nil(t, err)
+	assert.EqualValues(t, index.SEEK_STATUS_END, status)
+
+	// advance skips the positions of the skipped docs
+	assert.Nil(t, termsEnum.SeekExactByOrd(ctx, 2))
+	postings, err = termsEnum.Postings(postings, coreIndex.POSTINGS_ENUM_POSITIONS)
+	assert.Nil(t, err)
+	doc, err := postings.Advance(10)
+	assert.Nil(t, err)
+	assert.Equal(t, 12, doc)
+	pos, err := postings.NextPosition()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, pos)
+	doc, err = postings.Advance(100)
+	assert.Nil(t, err)
+	assert.Equal(t, types.NO_MORE_DOCS, doc)
+}
+
+func TestDirectPostingsFormatRegistered(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	fields := codectest.NewFields(document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS, map[string][]codectest.Term{
+		"id": newTestTerms("id", 100, 100),
+	})
+	si, fieldInfos := writeTestSegment(t, dir, fields)
+
+	format, ok := coreIndex.GetPostingsFormatByName(DIRECT_POSTINGS_NAME)
+	assert.True(t, ok)
+	producer, err := format.FieldsProducer(ctx, index.NewSegmentReadState(dir, si, fieldInfos, nil, ""))
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer producer.Close()
+
+	// every field is loaded with the default budget, so the files are no longer needed
+	assert.Nil(t, producer.(*directFields).delegate)
+
+	terms, err := producer.Terms("id")
+	assert.Nil(t, err)
+	assert.IsType(t, &directField{}, terms)
+	termsEnum, err := terms.Iterator()
+	assert.Nil(t, err)
+	for _, expected := range fields.FieldTerms("id") {
+		found, err := termsEnum.SeekExact(ctx, expected.Text)
+		assert.Nil(t, err)
+		assert.True(t, found, string(expected.Text))
+		totalTermFreq, err := termsEnum.TotalTermFreq()
+		assert.Nil(t, err)
+		sum := 0
+		for _, doc := range expected.Docs {
+			sum += doc.Freq()
+		}
+		assert.EqualValues(t, sum, totalTermFreq)
+	}
+}
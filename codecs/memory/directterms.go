@@ -0,0 +1,423 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/fst"
+	"github.com/geange/lucene-go/core/util/packed"
+)
+
+var _ index.Terms = &directField{}
+
+// directField the terms and postings of a field, entirely in RAM. The postings of the term with
+// ordinal ord are docs[docStarts[ord]:docStarts[ord+1]], its positions (and offsets and payloads)
+// are positions[posStarts[ord]:posStarts[ord+1]].
+type directField struct {
+	*coreIndex.BaseTerms
+
+	fieldInfo    *document.FieldInfo
+	hasFreqs     bool
+	hasPositions bool
+	hasOffsets   bool
+	hasPayloads  bool
+
+	sumTotalTermFreq int64
+	sumDocFreq       int64
+	docCount         int
+
+	// fst maps each term to its ordinal, nil if the field has no terms
+	fst       *fst.FST
+	termCount int
+	termBytes []byte
+
+	termStarts *packed.MonotonicLongValues
+	docStarts  *packed.MonotonicLongValues
+	posStarts  *packed.MonotonicLongValues
+
+	docs           []int
+	freqs          []int
+	totalTermFreqs []int64
+	positions      []int
+	startOffsets   []int
+	endOffsets     []int
+	payloads       [][]byte
+}
+
+func newDirectField(fieldInfo *document.FieldInfo, terms index.Terms) (*directField, error) {
+	sumTotalTermFreq, err := terms.GetSumTotalTermFreq()
+	if err != nil {
+		return nil, err
+	}
+	sumDocFreq, err := terms.GetSumDocFreq()
+	if err != nil {
+		return nil, err
+	}
+	docCount, err := terms.GetDocCount()
+	if err != nil {
+		return nil, err
+	}
+
+	indexOptions := fieldInfo.GetIndexOptions()
+	field := &directField{
+		fieldInfo:        fieldInfo,
+		hasFreqs:         indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS,
+		hasPositions:     indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS,
+		hasOffsets:       indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS,
+		sumTotalTermFreq: sumTotalTermFreq,
+		sumDocFreq:       sumDocFreq,
+		docCount:         docCount,
+	}
+	field.hasPayloads = field.hasPositions && fieldInfo.HasPayloads()
+	field.BaseTerms = coreIndex.NewTerms(field)
+	return field, nil
+}
+
+// postingsFlags the flags needed to load everything the field indexed
+func (f *directField) postingsFlags() int {
+	switch {
+	case f.hasPositions:
+		flags := coreIndex.POSTINGS_ENUM_POSITIONS
+		if f.hasOffsets {
+			flags |= coreIndex.POSTINGS_ENUM_OFFSETS
+		}
+		if f.hasPayloads {
+			flags |= coreIndex.POSTINGS_ENUM_PAYLOADS
+		}
+		return flags
+	case f.hasFreqs:
+		return coreIndex.POSTINGS_ENUM_FREQS
+	default:
+		return coreIndex.POSTINGS_ENUM_NONE
+	}
+}
+
+func (f *directField) term(ord int) ([]byte, error) {
+	start, err := f.termStarts.Get(ord)
+	if err != nil {
+		return nil, err
+	}
+	end, err := f.termStarts.Get(ord + 1)
+	if err != nil {
+		return nil, err
+	}
+	return f.termBytes[start:end], nil
+}
+
+func (f *directField) Iterator() (index.TermsEnum, error) {
+	termsEnum := &directTermsEnum{field: f, ord: -1}
+	termsEnum.BaseTermsEnum = coreIndex.NewBaseTermsEnum(&coreIndex.BaseTermsEnumConfig{SeekCeil: termsEnum.SeekCeil})
+	return termsEnum, nil
+}
+
+func (f *directField) Size() (int, error) {
+	return f.termCount, nil
+}
+
+func (f *directField) GetSumTotalTermFreq() (int64, error) {
+	return f.sumTotalTermFreq, nil
+}
+
+func (f *directField) GetSumDocFreq() (int64, error) {
+	return f.sumDocFreq, nil
+}
+
+func (f *directField) GetDocCount() (int, error) {
+	return f.docCount, nil
+}
+
+func (f *directField) HasFreqs() bool {
+	return f.hasFreqs
+}
+
+func (f *directField) HasOffsets() bool {
+	return f.hasOffsets
+}
+
+func (f *directField) HasPositions() bool {
+	return f.hasPositions
+}
+
+func (f *directField) HasPayloads() bool {
+	return f.hasPayloads
+}
+
+func (f *directField) GetMin() ([]byte, error) {
+	if f.termCount == 0 {
+		return nil, nil
+	}
+	return f.term(0)
+}
+
+func (f *directField) GetMax() ([]byte, error) {
+	if f.termCount == 0 {
+		return nil, nil
+	}
+	return f.term(f.termCount - 1)
+}
+
+var _ index.TermsEnum = &directTermsEnum{}
+
+// directTermsEnum seeks through the FST of the field, and steps through the terms by ordinal.
+type directTermsEnum struct {
+	*coreIndex.BaseTermsEnum
+
+	field   *directField
+	fstEnum *fst.Enum[byte]
+	ord     int
+}
+
+func (e *directTermsEnum) getFSTEnum() (*fst.Enum[byte], error) {
+	if e.fstEnum == nil {
+		fstEnum, err := fst.NewEnum[byte](e.field.fst)
+		if err != nil {
+			return nil, err
+		}
+		e.fstEnum = fstEnum
+	}
+	return e.fstEnum, nil
+}
+
+func (e *directTermsEnum) Next(context.Context) ([]byte, error) {
+	if e.ord+1 >= e.field.termCount {
+		e.ord = e.field.termCount
+		return nil, nil
+	}
+	e.ord++
+	return e.field.term(e.ord)
+}
+
+func (e *directTermsEnum) SeekExact(ctx context.Context, text []byte) (bool, error) {
+	if e.field.termCount == 0 {
+		return false, nil
+	}
+	fstEnum, err := e.getFSTEnum()
+	if err != nil {
+		return false, err
+	}
+	result, ok, err := fstEnum.SeekExact(ctx, text)
+	if err != nil {
+		return false, err
+	}
+	if !ok || result == nil {
+		return false, nil
+	}
+	return true, e.setOrd(result.GetOutput())
+}
+
+func (e *directTermsEnum) SeekCeil(ctx context.Context, text []byte) (index.SeekStatus, error) {
+	if e.field.termCount == 0 {
+		return index.SEEK_STATUS_END, nil
+	}
+	fstEnum, err := e.getFSTEnum()
+	if err != nil {
+		return 0, err
+	}
+	result, found, err := fstEnum.SeekCeil(ctx, text)
+	if err != nil {
+		return 0, err
+	}
+	if !found || result == nil {
+		e.ord = e.field.termCount
+		return index.SEEK_STATUS_END, nil
+	}
+	if err := e.setOrd(result.GetOutput()); err != nil {
+		return 0, err
+	}
+	if bytes.Equal(result.GetInput(), text) {
+		return index.SEEK_STATUS_FOUND, nil
+	}
+	return index.SEEK_STATUS_NOT_FOUND, nil
+}
+
+func (e *directTermsEnum) setOrd(output fst.Output) error {
+	box, ok := output.(*fst.IntBox[int64])
+	if !ok {
+		return errors.New("invalid fst output")
+	}
+	e.ord = int(box.Value())
+	return nil
+}
+
+func (e *directTermsEnum) SeekExactByOrd(ctx context.Context, ord int64) error {
+	if ord < 0 || ord >= int64(e.field.termCount) {
+		return errors.New("ord out of bounds")
+	}
+	e.ord = int(ord)
+	return nil
+}
+
+func (e *directTermsEnum) Term() ([]byte, error) {
+	return e.field.term(e.ord)
+}
+
+func (e *directTermsEnum) Ord() (int64, error) {
+	return int64(e.ord), nil
+}
+
+func (e *directTermsEnum) DocFreq() (int, error) {
+	start, end, err := e.docRange()
+	if err != nil {
+		return 0, err
+	}
+	return end - start, nil
+}
+
+func (e *directTermsEnum) TotalTermFreq() (int64, error) {
+	return e.field.totalTermFreqs[e.ord], nil
+}
+
+func (e *directTermsEnum) docRange() (int, int, error) {
+	start, err := e.field.docStarts.Get(e.ord)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := e.field.docStarts.Get(e.ord + 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(start), int(end), nil
+}
+
+func (e *directTermsEnum) Postings(reuse index.PostingsEnum, flags int) (index.PostingsEnum, error) {
+	docStart, docEnd, err := e.docRange()
+	if err != nil {
+		return nil, err
+	}
+	posStart, err := e.field.posStarts.Get(e.ord)
+	if err != nil {
+		return nil, err
+	}
+
+	postingsEnum, ok := reuse.(*directPostingsEnum)
+	if !ok || postingsEnum.field != e.field {
+		postingsEnum = &directPostingsEnum{field: e.field}
+	}
+	postingsEnum.reset(docStart, docEnd, int(posStart))
+	return postingsEnum, nil
+}
+
+func (e *directTermsEnum) Impacts(flags int) (index.ImpactsEnum, error) {
+	postings, err := e.Postings(nil, flags)
+	if err != nil {
+		return nil, err
+	}
+	return coreIndex.NewSlowImpactsEnum(postings), nil
+}
+
+var _ index.PostingsEnum = &directPostingsEnum{}
+
+// directPostingsEnum iterates over docs[docStart:docEnd] of a field. Positions are only
+// available if the field indexed them.
+type directPostingsEnum struct {
+	field *directField
+
+	docStart int
+	docEnd   int
+	upto     int
+	doc      int
+
+	// posUpto the position of the next call to NextPosition, posEnd the end of the positions of
+	// the current doc
+	posUpto int
+	posEnd  int
+}
+
+func (p *directPostingsEnum) reset(docStart, docEnd, posStart int) {
+	p.docStart = docStart
+	p.docEnd = docEnd
+	p.upto = docStart - 1
+	p.doc = -1
+	p.posUpto = posStart
+	p.posEnd = posStart
+}
+
+func (p *directPostingsEnum) DocID() int {
+	return p.doc
+}
+
+func (p *directPostingsEnum) NextDoc() (int, error) {
+	return p.Advance(p.doc + 1)
+}
+
+func (p *directPostingsEnum) Advance(target int) (int, error) {
+	// docs are sorted: binary search the first doc >= target in the rest of the postings
+	from := p.upto + 1
+	if from >= p.docEnd {
+		p.upto = p.docEnd
+		p.doc = types.NO_MORE_DOCS
+		return p.doc, nil
+	}
+	idx := from + sort.SearchInts(p.field.docs[from:p.docEnd], target)
+	if p.field.hasPositions {
+		// skip the positions of the docs we jumped over
+		p.posUpto = p.posEnd
+		for i := from; i < idx; i++ {
+			p.posUpto += p.field.freqs[i]
+		}
+	}
+	p.upto = idx
+	if idx >= p.docEnd {
+		p.doc = types.NO_MORE_DOCS
+		return p.doc, nil
+	}
+	p.doc = p.field.docs[idx]
+	if p.field.hasPositions {
+		p.posEnd = p.posUpto + p.field.freqs[idx]
+	}
+	return p.doc, nil
+}
+
+func (p *directPostingsEnum) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(p, target)
+}
+
+func (p *directPostingsEnum) Cost() int64 {
+	return int64(p.docEnd - p.docStart)
+}
+
+func (p *directPostingsEnum) Freq() (int, error) {
+	if !p.field.hasFreqs {
+		return 1, nil
+	}
+	return p.field.freqs[p.upto], nil
+}
+
+func (p *directPostingsEnum) NextPosition() (int, error) {
+	if !p.field.hasPositions {
+		return -1, nil
+	}
+	if p.posUpto >= p.posEnd {
+		return 0, errors.New("read past last position")
+	}
+	pos := p.field.positions[p.posUpto]
+	p.posUpto++
+	return pos, nil
+}
+
+func (p *directPostingsEnum) StartOffset() (int, error) {
+	if !p.field.hasOffsets || p.posUpto == 0 {
+		return -1, nil
+	}
+	return p.field.startOffsets[p.posUpto-1], nil
+}
+
+func (p *directPostingsEnum) EndOffset() (int, error) {
+	if !p.field.hasOffsets || p.posUpto == 0 {
+		return -1, nil
+	}
+	return p.field.endOffsets[p.posUpto-1], nil
+}
+
+func (p *directPostingsEnum) GetPayload() ([]byte, error) {
+	if !p.field.hasPayloads || p.posUpto == 0 {
+		return nil, nil
+	}
+	return p.field.payloads[p.posUpto-1], nil
+}
@@ -0,0 +1,315 @@
+package perfield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+)
+
+const (
+	// PER_FIELD_DOC_VALUES_NAME Name of this DocValuesFormat.
+	PER_FIELD_DOC_VALUES_NAME = "PerFieldDV40"
+
+	// PER_FIELD_DOC_VALUES_FORMAT_KEY FieldInfo attribute name used to store the format name for each field.
+	PER_FIELD_DOC_VALUES_FORMAT_KEY = "PerFieldDocValuesFormat.format"
+
+	// PER_FIELD_DOC_VALUES_SUFFIX_KEY FieldInfo attribute name used to store the segment suffix name for each field.
+	PER_FIELD_DOC_VALUES_SUFFIX_KEY = "PerFieldDocValuesFormat.suffix"
+)
+
+var _ index.DocValuesFormat = &DocValuesFormat{}
+
+// DocValuesFormat Enables per field docvalues support.
+//
+// Note, when extending this class, the name (GetName) is written into the index. In order for the
+// field to be read, the name must resolve to your implementation via coreIndex.GetDocValuesFormatByName,
+// so every format returned by the callback must have been registered with coreIndex.RegisterDocValuesFormat.
+//
+// Files written by each docvalues format have an additional suffix containing the format name. For
+// example, in a per-field configuration instead of _1.dat filenames would look like _1_Lucene40_0.dat.
+//
+// lucene.experimental
+type DocValuesFormat struct {
+	name                    string
+	docValuesFormatForField func(field string) index.DocValuesFormat
+}
+
+// NewDocValuesFormat Creates a DocValuesFormat that writes every field with the format returned by
+// docValuesFormatForField. The same format may be returned for multiple fields.
+func NewDocValuesFormat(docValuesFormatForField func(field string) index.DocValuesFormat) *DocValuesFormat {
+	return &DocValuesFormat{
+		name:                    PER_FIELD_DOC_VALUES_NAME,
+		docValuesFormatForField: docValuesFormatForField,
+	}
+}
+
+func (d *DocValuesFormat) GetName() string {
+	return d.name
+}
+
+func (d *DocValuesFormat) FieldsConsumer(ctx context.Context, state *index.SegmentWriteState) (index.DocValuesConsumer, error) {
+	return &docValuesWriter{
+		format:   d,
+		state:    state,
+		formats:  make(map[index.DocValuesFormat]*consumerAndSuffix),
+		suffixes: make(map[string]int),
+	}, nil
+}
+
+func (d *DocValuesFormat) FieldsProducer(ctx context.Context, state *index.SegmentReadState) (index.DocValuesProducer, error) {
+	return newDocValuesReader(ctx, state)
+}
+
+type consumerAndSuffix struct {
+	consumer index.DocValuesConsumer
+	suffix   int
+}
+
+var _ index.DocValuesConsumer = &docValuesWriter{}
+
+type docValuesWriter struct {
+	format   *DocValuesFormat
+	state    *index.SegmentWriteState
+	formats  map[index.DocValuesFormat]*consumerAndSuffix
+	suffixes map[string]int
+}
+
+func (w *docValuesWriter) AddNumericField(ctx context.Context, field *document.FieldInfo, valuesProducer index.DocValuesProducer) error {
+	consumer, err := w.getInstance(ctx, field)
+	if err != nil {
+		return err
+	}
+	return consumer.AddNumericField(ctx, field, valuesProducer)
+}
+
+func (w *docValuesWriter) AddBinaryField(ctx context.Context, field *document.FieldInfo, valuesProducer index.DocValuesProducer) error {
+	consumer, err := w.getInstance(ctx, field)
+	if err != nil {
+		return err
+	}
+	return consumer.AddBinaryField(ctx, field, valuesProducer)
+}
+
+func (w *docValuesWriter) AddSortedField(ctx context.Context, field *document.FieldInfo, valuesProducer index.DocValuesProducer) error {
+	consumer, err := w.getInstance(ctx, field)
+	if err != nil {
+		return err
+	}
+	return consumer.AddSortedField(ctx, field, valuesProducer)
+}
+
+func (w *docValuesWriter) AddSortedNumericField(ctx context.Context, field *document.FieldInfo, valuesProducer index.DocValuesProducer) error {
+	consumer, err := w.getInstance(ctx, field)
+	if err != nil {
+		return err
+	}
+	return consumer.AddSortedNumericField(ctx, field, valuesProducer)
+}
+
+func (w *docValuesWriter) AddSortedSetField(ctx context.Context, field *document.FieldInfo, valuesProducer index.DocValuesProducer) error {
+	consumer, err := w.getInstance(ctx, field)
+	if err != nil {
+		return err
+	}
+	return consumer.AddSortedSetField(ctx, field, valuesProducer)
+}
+
+func (w *docValuesWriter) getInstance(ctx context.Context, field *document.FieldInfo) (index.DocValuesConsumer, error) {
+	var format index.DocValuesFormat
+	if field.GetDocValuesGen() != -1 {
+		// this means the field never existed in that segment, yet is applied updates
+		if formatName := field.GetAttribute(PER_FIELD_DOC_VALUES_FORMAT_KEY); formatName != "" {
+			recorded, ok := coreIndex.GetDocValuesFormatByName(formatName)
+			if !ok {
+				return nil, fmt.Errorf("unknown doc values format: %s for field: %s", formatName, field.Name())
+			}
+			format = recorded
+		}
+	}
+	if format == nil {
+		format = w.format.docValuesFormatForField(field.Name())
+	}
+	if format == nil {
+		return nil, fmt.Errorf("invalid nil DocValuesFormat for field=%s", field.Name())
+	}
+	formatName := format.GetName()
+
+	if err := w.putAttribute(field, PER_FIELD_DOC_VALUES_FORMAT_KEY, formatName); err != nil {
+		return nil, err
+	}
+
+	consumer, ok := w.formats[format]
+	if !ok {
+		// First time we are seeing this format; create a new instance
+		suffix := -1
+		if field.GetDocValuesGen() != -1 {
+			// even when dvGen is != -1, it can still be a new field, that never existed in the
+			// segment, and therefore doesn't have the recorded attributes yet.
+			if suffixAtt := field.GetAttribute(PER_FIELD_DOC_VALUES_SUFFIX_KEY); suffixAtt != "" {
+				recorded, err := strconv.Atoi(suffixAtt)
+				if err != nil {
+					return nil, err
+				}
+				suffix = recorded
+			}
+		}
+		if suffix == -1 {
+			suffix = 0
+			if last, ok := w.suffixes[formatName]; ok {
+				suffix = last + 1
+			}
+		}
+		w.suffixes[formatName] = suffix
+
+		state := *w.state
+		state.SegmentSuffix = getFullSegmentSuffix(w.state.SegmentSuffix, getSuffix(formatName, strconv.Itoa(suffix)))
+		fieldsConsumer, err := format.FieldsConsumer(ctx, &state)
+		if err != nil {
+			return nil, err
+		}
+		consumer = &consumerAndSuffix{consumer: fieldsConsumer, suffix: suffix}
+		w.formats[format] = consumer
+	}
+
+	if err := w.putAttribute(field, PER_FIELD_DOC_VALUES_SUFFIX_KEY, strconv.Itoa(consumer.suffix)); err != nil {
+		return nil, err
+	}
+	return consumer.consumer, nil
+}
+
+// putAttribute records value under key. Fields that receive doc values updates may be written
+// again under the attributes recorded by a previous generation.
+func (w *docValuesWriter) putAttribute(field *document.FieldInfo, key, value string) error {
+	if field.GetDocValuesGen() == -1 {
+		return putAttribute(field, key, value)
+	}
+	field.PutAttribute(key, value)
+	return nil
+}
+
+func (w *docValuesWriter) Close() error {
+	errs := make([]error, 0, len(w.formats))
+	for _, consumer := range w.formats {
+		errs = append(errs, consumer.consumer.Close())
+	}
+	return errors.Join(errs...)
+}
+
+var _ index.DocValuesProducer = &docValuesReader{}
+
+type docValuesReader struct {
+	fields  map[string]index.DocValuesProducer
+	formats map[string]index.DocValuesProducer
+}
+
+func newDocValuesReader(ctx context.Context, state *index.SegmentReadState) (*docValuesReader, error) {
+	reader := &docValuesReader{
+		fields:  make(map[string]index.DocValuesProducer),
+		formats: make(map[string]index.DocValuesProducer),
+	}
+
+	// Read field name -> format name
+	for _, fieldInfo := range state.FieldInfos.List() {
+		if fieldInfo.GetDocValuesType() == document.DOC_VALUES_TYPE_NONE {
+			continue
+		}
+		fieldName := fieldInfo.Name()
+		formatName := fieldInfo.GetAttribute(PER_FIELD_DOC_VALUES_FORMAT_KEY)
+		if formatName == "" {
+			// no format name means the field is in fieldInfos, but has no docvalues!
+			continue
+		}
+		suffix := fieldInfo.GetAttribute(PER_FIELD_DOC_VALUES_SUFFIX_KEY)
+		if suffix == "" {
+			_ = reader.Close()
+			return nil, fmt.Errorf("missing attribute: %s for field: %s", PER_FIELD_DOC_VALUES_SUFFIX_KEY, fieldName)
+		}
+		format, ok := coreIndex.GetDocValuesFormatByName(formatName)
+		if !ok {
+			_ = reader.Close()
+			return nil, fmt.Errorf("unknown doc values format: %s for field: %s", formatName, fieldName)
+		}
+
+		segmentSuffix := getFullSegmentSuffix(state.SegmentSuffix, getSuffix(formatName, suffix))
+		producer, ok := reader.formats[segmentSuffix]
+		if !ok {
+			subState := *state
+			subState.SegmentSuffix = segmentSuffix
+			var err error
+			producer, err = format.FieldsProducer(ctx, &subState)
+			if err != nil {
+				_ = reader.Close()
+				return nil, err
+			}
+			reader.formats[segmentSuffix] = producer
+		}
+		reader.fields[fieldName] = producer
+	}
+	return reader, nil
+}
+
+func (r *docValuesReader) GetNumeric(ctx context.Context, field *document.FieldInfo) (index.NumericDocValues, error) {
+	producer, ok := r.fields[field.Name()]
+	if !ok {
+		return nil, nil
+	}
+	return producer.GetNumeric(ctx, field)
+}
+
+func (r *docValuesReader) GetBinary(ctx context.Context, field *document.FieldInfo) (index.BinaryDocValues, error) {
+	producer, ok := r.fields[field.Name()]
+	if !ok {
+		return nil, nil
+	}
+	return producer.GetBinary(ctx, field)
+}
+
+func (r *docValuesReader) GetSorted(ctx context.Context, field *document.FieldInfo) (index.SortedDocValues, error) {
+	producer, ok := r.fields[field.Name()]
+	if !ok {
+		return nil, nil
+	}
+	return producer.GetSorted(ctx, field)
+}
+
+func (r *docValuesReader) GetSortedNumeric(ctx context.Context, field *document.FieldInfo) (index.SortedNumericDocValues, error) {
+	producer, ok := r.fields[field.Name()]
+	if !ok {
+		return nil, nil
+	}
+	return producer.GetSortedNumeric(ctx, field)
+}
+
+func (r *docValuesReader) GetSortedSet(ctx context.Context, field *document.FieldInfo) (index.SortedSetDocValues, error) {
+	producer, ok := r.fields[field.Name()]
+	if !ok {
+		return nil, nil
+	}
+	return producer.GetSortedSet(ctx, field)
+}
+
+func (r *docValuesReader) Close() error {
+	errs := make([]error, 0, len(r.formats))
+	for _, producer := range r.formats {
+		errs = append(errs, producer.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (r *docValuesReader) CheckIntegrity() error {
+	for _, producer := range r.formats {
+		if err := producer.CheckIntegrity(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *docValuesReader) GetMergeInstance() index.DocValuesProducer {
+	return r
+}
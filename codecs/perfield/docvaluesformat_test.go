@@ -0,0 +1,151 @@
+package perfield
+
+import (
+	"context"
+	"testing"
+
+	"github.com/geange/lucene-go/codecs/lucene80"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ index.NumericDocValues = &testNumeric{}
+
+// testNumeric in-memory numeric doc values, every document has a value
+type testNumeric struct {
+	values []int64
+	doc    int
+}
+
+func (n *testNumeric) DocID() int {
+	return n.doc
+}
+
+func (n *testNumeric) NextDoc() (int, error) {
+	return n.Advance(n.doc + 1)
+}
+
+func (n *testNumeric) Advance(target int) (int, error) {
+	n.doc = target
+	if n.doc >= len(n.values) {
+		n.doc = types.NO_MORE_DOCS
+	}
+	return n.doc, nil
+}
+
+func (n *testNumeric) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(n, target)
+}
+
+func (n *testNumeric) Cost() int64 {
+	return int64(len(n.values))
+}
+
+func (n *testNumeric) AdvanceExact(target int) (bool, error) {
+	n.doc = target
+	return true, nil
+}
+
+func (n *testNumeric) LongValue() (int64, error) {
+	return n.values[n.doc], nil
+}
+
+func TestDocValuesFormat(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	const maxDoc = 50
+	values := map[string][]int64{
+		"debug":  make([]int64, maxDoc),
+		"price":  make([]int64, maxDoc),
+		"rating": make([]int64, maxDoc),
+	}
+	for doc := 0; doc < maxDoc; doc++ {
+		values["debug"][doc] = int64(doc)
+		values["price"][doc] = int64(doc * 1000)
+		values["rating"][doc] = int64(doc % 5)
+	}
+
+	newInfo := func(name string, number int) *document.FieldInfo {
+		return document.NewFieldInfo(name, number, false, false, false, document.INDEX_OPTIONS_NONE,
+			document.DOC_VALUES_TYPE_NUMERIC, -1, map[string]string{}, 0, 0, 0, false)
+	}
+	infos := []*document.FieldInfo{newInfo("debug", 0), newInfo("price", 1), newInfo("rating", 2)}
+	fieldInfos := coreIndex.NewFieldInfos(infos)
+
+	id := []byte("0123456789abcdef")
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", maxDoc, false, nil,
+		map[string]string{}, id, map[string]string{}, nil)
+
+	defaultFormat := lucene80.NewDocValuesFormat()
+	// a second instance of the same format is written to its own files
+	debugFormat := lucene80.NewDocValuesFormat()
+	format := NewDocValuesFormat(func(field string) index.DocValuesFormat {
+		if field == "debug" {
+			return debugFormat
+		}
+		return defaultFormat
+	})
+	assert.Equal(t, PER_FIELD_DOC_VALUES_NAME, format.GetName())
+
+	consumer, err := format.FieldsConsumer(ctx, index.NewSegmentWriteState(dir, si, fieldInfos, nil, nil))
+	assert.Nil(t, err)
+	for _, info := range infos {
+		producer := &coreIndex.EmptyDocValuesProducer{
+			FnGetNumeric: func(ctx context.Context, field *document.FieldInfo) (index.NumericDocValues, error) {
+				return &testNumeric{values: values[field.Name()], doc: -1}, nil
+			},
+		}
+		assert.Nil(t, consumer.AddNumericField(ctx, info, producer))
+	}
+	assert.Nil(t, consumer.Close())
+
+	for _, info := range infos {
+		assert.Equal(t, "Lucene80", info.GetAttribute(PER_FIELD_DOC_VALUES_FORMAT_KEY))
+	}
+	assert.Equal(t, "0", infos[0].GetAttribute(PER_FIELD_DOC_VALUES_SUFFIX_KEY))
+	assert.Equal(t, "1", infos[1].GetAttribute(PER_FIELD_DOC_VALUES_SUFFIX_KEY))
+	assert.Equal(t, "1", infos[2].GetAttribute(PER_FIELD_DOC_VALUES_SUFFIX_KEY))
+
+	files, err := dir.ListAll(ctx)
+	assert.Nil(t, err)
+	assert.Contains(t, files, "_0_Lucene80_0.dvd")
+	assert.Contains(t, files, "_0_Lucene80_1.dvd")
+	assert.Contains(t, files, "_0_Lucene80_1.dvm")
+
+	producer, err := format.FieldsProducer(ctx, index.NewSegmentReadState(dir, si, fieldInfos, nil, ""))
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer producer.Close()
+	assert.Nil(t, producer.CheckIntegrity())
+
+	for _, info := range infos {
+		numeric, err := producer.GetNumeric(ctx, info)
+		assert.Nil(t, err)
+		if !assert.NotNil(t, numeric, info.Name()) {
+			continue
+		}
+		for doc := 0; doc < maxDoc; doc++ {
+			ok, err := numeric.AdvanceExact(doc)
+			assert.Nil(t, err)
+			assert.True(t, ok)
+			value, err := numeric.LongValue()
+			assert.Nil(t, err)
+			assert.Equal(t, values[info.Name()][doc], value, info.Name())
+		}
+	}
+
+	missing := document.NewFieldInfo("missing", 3, false, false, false, document.INDEX_OPTIONS_NONE,
+		document.DOC_VALUES_TYPE_NUMERIC, -1, map[string]string{}, 0, 0, 0, false)
+	numeric, err := producer.GetNumeric(ctx, missing)
+	assert.Nil(t, err)
+	assert.Nil(t, numeric)
+}
@@ -0,0 +1,104 @@
+package perfield_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/geange/lucene-go/codecs/lucene87"
+	"github.com/geange/lucene-go/codecs/perfield"
+	"github.com/geange/lucene-go/codecs/simpletext"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/search"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// textTagCodec writes the postings of the "tag" field with the SimpleText format and the other
+// fields with the Lucene84 format of the Lucene87 codec
+type textTagCodec struct {
+	*lucene87.Codec
+
+	postingsFormat *perfield.PostingsFormat
+}
+
+func newTextTagCodec() *textTagCodec {
+	codec := &textTagCodec{Codec: lucene87.NewCodec(lucene87.BEST_SPEED)}
+	postingsFormat := simpletext.NewPostingsFormat()
+	codec.postingsFormat = perfield.NewPostingsFormat(func(field string) index.PostingsFormat {
+		if field == "tag" {
+			return postingsFormat
+		}
+		return codec.GetPostingsFormatForField(field)
+	})
+	return codec
+}
+
+func (c *textTagCodec) PostingsFormat() index.PostingsFormat {
+	return c.postingsFormat
+}
+
+func TestPostingsFormat_IndexWriter(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir()
+	dir, err := store.NewNIOFSDirectory(path)
+	assert.Nil(t, err)
+
+	similarity, err := search.NewBM25Similarity()
+	assert.Nil(t, err)
+	config := coreIndex.NewIndexWriterConfig(newTextTagCodec(), similarity)
+	config.SetUseCompoundFile(false)
+	writer, err := coreIndex.NewIndexWriter(ctx, dir, config)
+	assert.Nil(t, err)
+
+	for i := 0; i < 20; i++ {
+		doc := document.NewDocument()
+		doc.Add(document.NewStringField("id", fmt.Sprintf("id%02d", i), false))
+		doc.Add(document.NewStringField("tag", fmt.Sprintf("tag%d", i%3), false))
+		_, err := writer.AddDocument(ctx, doc)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, writer.Commit(ctx))
+	assert.Nil(t, writer.Close())
+
+	// each format writes its own files, named after the format and its suffix
+	files, err := dir.ListAll(ctx)
+	assert.Nil(t, err)
+	assert.Contains(t, files, "_0_Lucene84_0.tim")
+	assert.Contains(t, files, "_0_Lucene84_0.doc")
+	assert.Contains(t, files, "_0_SimpleText_0.pst")
+	assert.NotContains(t, files, "_0.tim")
+
+	// the terms of each field are only in the files of its format
+	text, err := os.ReadFile(filepath.Join(path, "_0_SimpleText_0.pst"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(text), "tag1")
+	assert.NotContains(t, string(text), "id01")
+
+	reader, err := coreIndex.OpenDirectoryReader(ctx, dir, nil, nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer reader.DecRef()
+
+	leaves, err := reader.Leaves()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(leaves))
+	fieldInfos := leaves[0].LeafReader().GetFieldInfos()
+	assert.Equal(t, "Lucene84", fieldInfos.FieldInfo("id").GetAttribute(perfield.PER_FIELD_POSTINGS_FORMAT_KEY))
+	assert.Equal(t, "SimpleText", fieldInfos.FieldInfo("tag").GetAttribute(perfield.PER_FIELD_POSTINGS_FORMAT_KEY))
+
+	// the reader resolves the format of each field from the field infos, with the default Lucene87 codec
+	searcher, err := search.NewIndexSearcher(reader)
+	assert.Nil(t, err)
+	count, err := searcher.Count(search.NewTermQuery(coreIndex.NewTerm("tag", []byte("tag1"))))
+	assert.Nil(t, err)
+	assert.Equal(t, 7, count)
+	count, err = searcher.Count(search.NewTermQuery(coreIndex.NewTerm("id", []byte("id13"))))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+}
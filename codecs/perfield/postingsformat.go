@@ -0,0 +1,287 @@
+package perfield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+)
+
+const (
+	// PER_FIELD_POSTINGS_NAME Name of this PostingsFormat.
+	PER_FIELD_POSTINGS_NAME = "PerField40"
+
+	// PER_FIELD_POSTINGS_FORMAT_KEY FieldInfo attribute name used to store the format name for each field.
+	PER_FIELD_POSTINGS_FORMAT_KEY = "PerFieldPostingsFormat.format"
+
+	// PER_FIELD_POSTINGS_SUFFIX_KEY FieldInfo attribute name used to store the segment suffix name for each field.
+	PER_FIELD_POSTINGS_SUFFIX_KEY = "PerFieldPostingsFormat.suffix"
+)
+
+var _ index.PostingsFormat = &PostingsFormat{}
+
+// PostingsFormat Enables per field postings support.
+//
+// Note, when extending this class, the name (GetName) is written into the index. In order for the
+// field to be read, the name must resolve to your implementation via coreIndex.GetPostingsFormatByName,
+// so every format returned by the callback must have been registered with coreIndex.RegisterPostingsFormat.
+//
+// Files written by each postings format have an additional suffix containing the format name. For
+// example, in a per-field configuration instead of _1.prx filenames would look like _1_Lucene40_0.prx.
+//
+// lucene.experimental
+type PostingsFormat struct {
+	name                   string
+	postingsFormatForField func(field string) index.PostingsFormat
+}
+
+// NewPostingsFormat Creates a PostingsFormat that writes every field with the format returned by
+// postingsFormatForField. The same format may be returned for multiple fields.
+func NewPostingsFormat(postingsFormatForField func(field string) index.PostingsFormat) *PostingsFormat {
+	return &PostingsFormat{
+		name:                   PER_FIELD_POSTINGS_NAME,
+		postingsFormatForField: postingsFormatForField,
+	}
+}
+
+func (p *PostingsFormat) GetName() string {
+	return p.name
+}
+
+func (p *PostingsFormat) FieldsConsumer(ctx context.Context, state *index.SegmentWriteState) (index.FieldsConsumer, error) {
+	return &fieldsWriter{format: p, state: state}, nil
+}
+
+func (p *PostingsFormat) FieldsProducer(ctx context.Context, state *index.SegmentReadState) (index.FieldsProducer, error) {
+	return newFieldsReader(ctx, state)
+}
+
+func getSuffix(formatName, suffix string) string {
+	return formatName + "_" + suffix
+}
+
+func getFullSegmentSuffix(outerSegmentSuffix, segmentSuffix string) string {
+	if len(outerSegmentSuffix) == 0 {
+		return segmentSuffix
+	}
+	return outerSegmentSuffix + "_" + segmentSuffix
+}
+
+// fieldsGroup the fields of a segment that are written with the same postings format
+type fieldsGroup struct {
+	format index.PostingsFormat
+	suffix int
+	fields []string
+	state  *index.SegmentWriteState
+}
+
+var _ index.FieldsConsumer = &fieldsWriter{}
+
+type fieldsWriter struct {
+	format  *PostingsFormat
+	state   *index.SegmentWriteState
+	toClose []io.Closer
+}
+
+func (w *fieldsWriter) Write(ctx context.Context, fields index.Fields, norms index.NormsProducer) error {
+	groups, err := w.buildFieldsGroupMapping(fields)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		consumer, err := group.format.FieldsConsumer(ctx, group.state)
+		if err != nil {
+			return err
+		}
+		w.toClose = append(w.toClose, consumer)
+
+		// Delegate the write to the appropriate postings format, hiding the fields that belong to
+		// the other formats.
+		if err := consumer.Write(ctx, &maskedFields{Fields: fields, names: group.fields}, norms); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *fieldsWriter) buildFieldsGroupMapping(fields index.Fields) ([]*fieldsGroup, error) {
+	groups := make([]*fieldsGroup, 0)
+	formatToGroup := make(map[index.PostingsFormat]*fieldsGroup)
+	suffixes := make(map[string]int)
+
+	for _, field := range fields.Names() {
+		fieldInfo := w.state.FieldInfos.FieldInfo(field)
+		if fieldInfo == nil {
+			return nil, fmt.Errorf("field %s not found in field infos", field)
+		}
+
+		format := w.format.postingsFormatForField(field)
+		if format == nil {
+			return nil, fmt.Errorf("invalid nil PostingsFormat for field=%s", field)
+		}
+		formatName := format.GetName()
+
+		group, ok := formatToGroup[format]
+		if !ok {
+			// First time we are seeing this format; create a new instance
+			suffix := 0
+			if last, ok := suffixes[formatName]; ok {
+				suffix = last + 1
+			}
+			suffixes[formatName] = suffix
+
+			state := *w.state
+			state.SegmentSuffix = getFullSegmentSuffix(w.state.SegmentSuffix, getSuffix(formatName, strconv.Itoa(suffix)))
+			group = &fieldsGroup{format: format, suffix: suffix, state: &state}
+			formatToGroup[format] = group
+			groups = append(groups, group)
+		}
+		group.fields = append(group.fields, field)
+
+		// Record the format and suffix in the field infos, so that the reader can find them
+		if err := putAttribute(fieldInfo, PER_FIELD_POSTINGS_FORMAT_KEY, formatName); err != nil {
+			return nil, err
+		}
+		if err := putAttribute(fieldInfo, PER_FIELD_POSTINGS_SUFFIX_KEY, strconv.Itoa(group.suffix)); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+// putAttribute records value under key, failing if the field already carries a different one.
+func putAttribute(fieldInfo *document.FieldInfo, key, value string) error {
+	if previous := fieldInfo.GetAttribute(key); previous != "" && previous != value {
+		return fmt.Errorf("found existing value for %s, field=%s, old=%s, new=%s",
+			key, fieldInfo.Name(), previous, value)
+	}
+	fieldInfo.PutAttribute(key, value)
+	return nil
+}
+
+func (w *fieldsWriter) Close() error {
+	errs := make([]error, 0, len(w.toClose))
+	for _, closer := range w.toClose {
+		errs = append(errs, closer.Close())
+	}
+	w.toClose = nil
+	return errors.Join(errs...)
+}
+
+var _ index.Fields = &maskedFields{}
+
+// maskedFields only exposes the fields written by one postings format.
+type maskedFields struct {
+	index.Fields
+
+	names []string
+}
+
+func (m *maskedFields) Names() []string {
+	return m.names
+}
+
+func (m *maskedFields) Size() int {
+	return len(m.names)
+}
+
+var _ index.FieldsProducer = &fieldsReader{}
+
+type fieldsReader struct {
+	names   []string
+	fields  map[string]index.FieldsProducer
+	formats map[string]index.FieldsProducer
+}
+
+func newFieldsReader(ctx context.Context, state *index.SegmentReadState) (*fieldsReader, error) {
+	reader := &fieldsReader{
+		names:   make([]string, 0),
+		fields:  make(map[string]index.FieldsProducer),
+		formats: make(map[string]index.FieldsProducer),
+	}
+
+	// Read field name -> format name
+	for _, fieldInfo := range state.FieldInfos.List() {
+		if fieldInfo.GetIndexOptions() == document.INDEX_OPTIONS_NONE {
+			continue
+		}
+		fieldName := fieldInfo.Name()
+		formatName := fieldInfo.GetAttribute(PER_FIELD_POSTINGS_FORMAT_KEY)
+		if formatName == "" {
+			// no format name means the field is in fieldInfos, but has no postings!
+			continue
+		}
+		suffix := fieldInfo.GetAttribute(PER_FIELD_POSTINGS_SUFFIX_KEY)
+		if suffix == "" {
+			_ = reader.Close()
+			return nil, fmt.Errorf("missing attribute: %s for field: %s", PER_FIELD_POSTINGS_SUFFIX_KEY, fieldName)
+		}
+		format, ok := coreIndex.GetPostingsFormatByName(formatName)
+		if !ok {
+			_ = reader.Close()
+			return nil, fmt.Errorf("unknown postings format: %s for field: %s", formatName, fieldName)
+		}
+
+		segmentSuffix := getFullSegmentSuffix(state.SegmentSuffix, getSuffix(formatName, suffix))
+		producer, ok := reader.formats[segmentSuffix]
+		if !ok {
+			subState := *state
+			subState.SegmentSuffix = segmentSuffix
+			var err error
+			producer, err = format.FieldsProducer(ctx, &subState)
+			if err != nil {
+				_ = reader.Close()
+				return nil, err
+			}
+			reader.formats[segmentSuffix] = producer
+		}
+		reader.fields[fieldName] = producer
+		reader.names = append(reader.names, fieldName)
+	}
+	slices.Sort(reader.names)
+	return reader, nil
+}
+
+func (r *fieldsReader) Names() []string {
+	return r.names
+}
+
+func (r *fieldsReader) Terms(field string) (index.Terms, error) {
+	producer, ok := r.fields[field]
+	if !ok {
+		return nil, nil
+	}
+	return producer.Terms(field)
+}
+
+func (r *fieldsReader) Size() int {
+	return len(r.fields)
+}
+
+func (r *fieldsReader) Close() error {
+	errs := make([]error, 0, len(r.formats))
+	for _, producer := range r.formats {
+		errs = append(errs, producer.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (r *fieldsReader) CheckIntegrity() error {
+	for _, producer := range r.formats {
+		if err := producer.CheckIntegrity(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fieldsReader) GetMergeInstance() index.FieldsProducer {
+	return r
+}
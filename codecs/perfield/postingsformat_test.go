@@ -0,0 +1,173 @@
+package perfield
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/geange/lucene-go/codecs/codectest"
+	"github.com/geange/lucene-go/codecs/lucene84"
+	"github.com/geange/lucene-go/codecs/simpletext"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostingsFormat(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	newInfo := func(name string, number int) *document.FieldInfo {
+		return document.NewFieldInfo(name, number, false, true, false, document.INDEX_OPTIONS_DOCS_AND_FREQS,
+			document.DOC_VALUES_TYPE_NONE, -1, map[string]string{}, 0, 0, 0, false)
+	}
+	fieldInfos := coreIndex.NewFieldInfos([]*document.FieldInfo{
+		newInfo("body", 0), newInfo("debug", 1), newInfo("id", 2), newInfo("title", 3),
+	})
+
+	id := []byte("0123456789abcdef")
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", 100, false, nil,
+		map[string]string{}, id, map[string]string{}, nil)
+
+	ids := make([]codectest.Term, 0, 10)
+	for i := 0; i < 10; i++ {
+		ids = append(ids, codectest.NewTerm(fmt.Sprintf("id%02d", i), i))
+	}
+	fields := codectest.NewFields(document.INDEX_OPTIONS_DOCS_AND_FREQS, map[string][]codectest.Term{
+		"body": {
+			codectest.NewTerm("lucene", 0, 3, 5), codectest.NewTerm("search", 1, 3), codectest.NewTerm("go", 2),
+		},
+		"debug": {codectest.NewTerm("trace", 4), codectest.NewTerm("info", 0, 1, 2)},
+		"id":    ids,
+		"title": {codectest.NewTerm("index", 7, 99)},
+	})
+
+	defaultFormat := lucene84.NewPostingsFormat()
+	idFormat, err := lucene84.NewPostingsFormatWithBlockSize(10, 20)
+	assert.Nil(t, err)
+	debugFormat := simpletext.NewPostingsFormat()
+
+	format := NewPostingsFormat(func(field string) index.PostingsFormat {
+		switch field {
+		case "debug":
+			return debugFormat
+		case "id":
+			return idFormat
+		default:
+			return defaultFormat
+		}
+	})
+	assert.Equal(t, PER_FIELD_POSTINGS_NAME, format.GetName())
+
+	writeState := index.NewSegmentWriteState(dir, si, fieldInfos, nil, nil)
+	consumer, err := format.FieldsConsumer(ctx, writeState)
+	assert.Nil(t, err)
+	assert.Nil(t, consumer.Write(ctx, fields, nil))
+	assert.Nil(t, consumer.Close())
+
+	expectedAttributes := map[string][2]string{
+		"body":  {"Lucene84", "0"},
+		"debug": {"SimpleText", "0"},
+		"id":    {"Lucene84", "1"},
+		"title": {"Lucene84", "0"},
+	}
+	for field, expected := range expectedAttributes {
+		fieldInfo := fieldInfos.FieldInfo(field)
+		assert.Equal(t, expected[0], fieldInfo.GetAttribute(PER_FIELD_POSTINGS_FORMAT_KEY), field)
+		assert.Equal(t, expected[1], fieldInfo.GetAttribute(PER_FIELD_POSTINGS_SUFFIX_KEY), field)
+	}
+
+	files, err := dir.ListAll(ctx)
+	assert.Nil(t, err)
+	assert.Contains(t, files, "_0_Lucene84_0.tim")
+	assert.Contains(t, files, "_0_Lucene84_1.tim")
+	assert.Contains(t, files, "_0_SimpleText_0.pst")
+
+	readState := index.NewSegmentReadState(dir, si, fieldInfos, nil, "")
+	producer, err := format.FieldsProducer(ctx, readState)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer producer.Close()
+	assert.Nil(t, producer.CheckIntegrity())
+	assert.Equal(t, []string{"body", "debug", "id", "title"}, producer.Names())
+	assert.Equal(t, 4, producer.Size())
+
+	missing, err := producer.Terms("missing")
+	assert.Nil(t, err)
+	assert.Nil(t, missing)
+
+	for _, field := range fields.Names() {
+		expectedTerms := fields.FieldTerms(field)
+
+		terms, err := producer.Terms(field)
+		assert.Nil(t, err)
+		if !assert.NotNil(t, terms, field) {
+			continue
+		}
+		termsEnum, err := terms.Iterator()
+		assert.Nil(t, err)
+		for _, term := range expectedTerms {
+			found, err := termsEnum.SeekExact(ctx, term.Text)
+			assert.Nil(t, err)
+			assert.True(t, found, string(term.Text))
+
+			postings, err := termsEnum.Postings(nil, coreIndex.POSTINGS_ENUM_FREQS)
+			assert.Nil(t, err)
+			docs := make([]int, 0)
+			for {
+				doc, err := postings.NextDoc()
+				if err != nil {
+					// the SimpleText docs enum reports its end with io.EOF
+					assert.ErrorIs(t, err, io.EOF)
+					break
+				}
+				if doc == types.NO_MORE_DOCS {
+					break
+				}
+				docs = append(docs, doc)
+			}
+			expectedDocs := make([]int, 0, len(term.Docs))
+			for _, doc := range term.Docs {
+				expectedDocs = append(expectedDocs, doc.ID)
+			}
+			assert.Equal(t, expectedDocs, docs, string(term.Text))
+		}
+	}
+}
+
+func TestPostingsFormatUnknownFormat(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	fieldInfo := document.NewFieldInfo("body", 0, false, true, false, document.INDEX_OPTIONS_DOCS,
+		document.DOC_VALUES_TYPE_NONE, -1, map[string]string{
+			PER_FIELD_POSTINGS_FORMAT_KEY: "Unknown",
+			PER_FIELD_POSTINGS_SUFFIX_KEY: "0",
+		}, 0, 0, 0, false)
+	fieldInfos := coreIndex.NewFieldInfos([]*document.FieldInfo{fieldInfo})
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, "_0", 1, false, nil,
+		map[string]string{}, []byte("0123456789abcdef"), map[string]string{}, nil)
+
+	format := NewPostingsFormat(func(field string) index.PostingsFormat {
+		return nil
+	})
+	_, err = format.FieldsProducer(ctx, index.NewSegmentReadState(dir, si, fieldInfos, nil, ""))
+	assert.NotNil(t, err)
+
+	consumer, err := format.FieldsConsumer(ctx, index.NewSegmentWriteState(dir, si, fieldInfos, nil, nil))
+	assert.Nil(t, err)
+	fields := codectest.NewFields(document.INDEX_OPTIONS_DOCS, map[string][]codectest.Term{"body": nil})
+	err = consumer.Write(ctx, fields, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, consumer.Close())
+}
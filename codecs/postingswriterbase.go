@@ -1,8 +1,38 @@
 package codecs
 
+import (
+	"io"
+
+	"github.com/bits-and-blooms/bitset"
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+)
+
 // PostingsWriterBase
 // Class that plugs into term dictionaries, such as BlockTreeTermsWriter,
 // and handles writing postings.
 // See Also: PostingsReaderBase
 type PostingsWriterBase interface {
+	io.Closer
+
+	// Init Called once after startup, before any terms have been added. Implementations typically
+	// write a header to the provided termsOut.
+	Init(termsOut store.IndexOutput, state *index.SegmentWriteState) error
+
+	// WriteTerm Write all postings for one term; use the provided TermsEnum to pull a PostingsEnum.
+	// This method should not re-position the TermsEnum! It is already positioned on the term that
+	// should be written. This method must set the bit in the provided FixedBitSet for every docID
+	// written. If no docs were written, this method should return nil, and the terms dict will skip
+	// the term.
+	WriteTerm(term []byte, termsEnum index.TermsEnum, docsSeen *bitset.BitSet, norms index.NormsProducer) (BlockTermState, error)
+
+	// EncodeTerm Encode metadata as long[] and byte[]. absolute controls whether current term is delta
+	// encoded according to latest term. Usually elements in longs are file pointers, so each one
+	// always increases when a new term is consumed. out is used to write generic bytes, which are not
+	// monotonic.
+	EncodeTerm(out store.DataOutput, fieldInfo *document.FieldInfo, state BlockTermState, absolute bool) error
+
+	// SetField Sets the current field for writing.
+	SetField(fieldInfo *document.FieldInfo)
 }
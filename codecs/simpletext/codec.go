@@ -25,6 +25,7 @@ type Codec struct {
 	dvFormat         *DocValuesFormat
 	compoundFormat   *CompoundFormat
 	pointsFormat     *PointsFormat
+	knnVectors       *KnnVectorsFormat
 }
 
 func NewCodec() *Codec {
@@ -39,6 +40,7 @@ func NewCodec() *Codec {
 		dvFormat:         NewSimpleTextDocValuesFormat(),
 		compoundFormat:   NewCompoundFormat(),
 		pointsFormat:     NewPointsFormat(),
+		knnVectors:       NewKnnVectorsFormat(),
 	}
 }
 
@@ -85,3 +87,7 @@ func (s *Codec) CompoundFormat() index.CompoundFormat {
 func (s *Codec) PointsFormat() index.PointsFormat {
 	return s.pointsFormat
 }
+
+func (s *Codec) KnnVectorsFormat() index.KnnVectorsFormat {
+	return s.knnVectors
+}
@@ -55,7 +55,7 @@ func (s *CompoundFormat) Write(ctx context.Context, dir store.Directory, si inde
 	}
 	slices.Sort(names)
 
-	out, err := dir.CreateOutput(ctx, dataFile)
+	out, err := store.CreateOutputWithContext(ctx, dir, dataFile, ioContext)
 	if err != nil {
 		return err
 	}
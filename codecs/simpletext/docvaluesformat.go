@@ -3,9 +3,14 @@ package simpletext
 import (
 	"context"
 
+	coreIndex "github.com/geange/lucene-go/core/index"
 	"github.com/geange/lucene-go/core/interface/index"
 )
 
+func init() {
+	coreIndex.RegisterDocValuesFormat(NewSimpleTextDocValuesFormat())
+}
+
 var _ index.DocValuesFormat = &DocValuesFormat{}
 
 // DocValuesFormat
@@ -43,7 +43,7 @@ type DocValuesWriter struct {
 
 func NewDocValuesWriter(ctx context.Context, state *index.SegmentWriteState, ext string) (*DocValuesWriter, error) {
 	fileName := store.SegmentFileName(state.SegmentInfo.Name(), state.SegmentSuffix, ext)
-	output, err := state.Directory.CreateOutput(ctx, fileName)
+	output, err := store.CreateOutputWithContext(ctx, state.Directory, fileName, state.Context)
 	if err != nil {
 		return nil, err
 	}
@@ -3,6 +3,7 @@ package simpletext
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"strconv"
 
 	"github.com/geange/lucene-go/codecs/utils"
@@ -38,6 +39,8 @@ var (
 	INDEX_DIM_COUNT = []byte("  index dimensional count ")
 	DIM_NUM_BYTES   = []byte("  dimensional num bytes ")
 	SOFT_DELETES    = []byte("  soft-deletes ")
+	VECTOR_NUM_DIMS = []byte("  vector number of dimensions ")
+	VECTOR_SIM_FUNC = []byte("  vector similarity function ")
 )
 
 // FieldInfosFormat
@@ -157,9 +160,26 @@ func (s *FieldInfosFormat) Read(ctx context.Context, directory store.Directory,
 			return nil, err
 		}
 
+		vectorNumDimensions, err := r.ParseInt(VECTOR_NUM_DIMS)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err = r.ReadLabel(VECTOR_SIM_FUNC)
+		if err != nil {
+			return nil, err
+		}
+		vectorSimilarityFunction, ok := document.StringToVectorSimilarityFunction(value)
+		if !ok {
+			return nil, fmt.Errorf("invalid vector similarity function: %s", value)
+		}
+
 		info := document.NewFieldInfo(name, fieldNumber, storeTermVector,
 			omitNorms, storePayloads, indexOptions, docValuesType, int64(dvGen), atts,
 			dimensionalCount, indexDimensionalCount, dimensionalNumBytes, isSoftDeletesField)
+		if err := info.SetVectorDimensionAndSimilarityFunction(vectorNumDimensions, vectorSimilarityFunction); err != nil {
+			return nil, err
+		}
 		infos = append(infos, info)
 	}
 
@@ -173,7 +193,7 @@ func (s *FieldInfosFormat) Read(ctx context.Context, directory store.Directory,
 
 func (s *FieldInfosFormat) Write(ctx context.Context, directory store.Directory, segmentInfo index.SegmentInfo, segmentSuffix string, infos index.FieldInfos, ioContext *store.IOContext) error {
 	fileName := store.SegmentFileName(segmentInfo.Name(), segmentSuffix, FIELD_INFOS_EXTENSION)
-	out, err := directory.CreateOutput(nil, fileName)
+	out, err := store.CreateOutputWithContext(nil, directory, fileName, ioContext)
 	if err != nil {
 		return err
 	}
@@ -254,6 +274,14 @@ func (s *FieldInfosFormat) Write(ctx context.Context, directory store.Directory,
 		if err := w.WriteLabelBool(SOFT_DELETES, fi.IsSoftDeletesField()); err != nil {
 			return err
 		}
+
+		if err := w.WriteLabelInt(VECTOR_NUM_DIMS, fi.GetVectorDimension()); err != nil {
+			return err
+		}
+
+		if err := w.WriteLabelString(VECTOR_SIM_FUNC, fi.GetVectorSimilarityFunction().String()); err != nil {
+			return err
+		}
 	}
 
 	return utils.WriteChecksum(out)
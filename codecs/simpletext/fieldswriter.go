@@ -10,6 +10,7 @@ import (
 	coreIndex "github.com/geange/lucene-go/core/index"
 	"github.com/geange/lucene-go/core/interface/index"
 	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
 )
 
 var (
@@ -39,7 +40,7 @@ type TextFieldsWriter struct {
 
 func NewFieldsWriter(writeState *index.SegmentWriteState) (*TextFieldsWriter, error) {
 	fileName := getPostingsFileName(writeState.SegmentInfo.Name(), writeState.SegmentSuffix)
-	out, err := writeState.Directory.CreateOutput(nil, fileName)
+	out, err := store.CreateOutputWithContext(nil, writeState.Directory, fileName, writeState.Context)
 	if err != nil {
 		return nil, err
 	}
@@ -160,6 +161,9 @@ func (s *TextFieldsWriter) WriteV1(fieldInfos index.FieldInfos, fields index.Fie
 					}
 					return err
 				}
+				if doc == types.NO_MORE_DOCS {
+					break
+				}
 
 				if !wroteTerm {
 
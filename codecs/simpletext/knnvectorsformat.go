@@ -0,0 +1,37 @@
+package simpletext
+
+import (
+	"context"
+
+	"github.com/geange/lucene-go/core/interface/index"
+)
+
+const (
+	// VECTOR_EXTENSION Extension of vectors data file
+	VECTOR_EXTENSION = "vec"
+)
+
+var _ index.KnnVectorsFormat = &KnnVectorsFormat{}
+
+// KnnVectorsFormat For debugging, curiosity, transparency only!! Do not use this codec in production.
+// This codec stores all vectors in a single human-readable text file (_N.vec) and does not build
+// any graph: the nearest neighbors are found by comparing the query with every vector.
+// lucene.experimental
+type KnnVectorsFormat struct {
+}
+
+func NewKnnVectorsFormat() *KnnVectorsFormat {
+	return &KnnVectorsFormat{}
+}
+
+func (s *KnnVectorsFormat) GetName() string {
+	return "SimpleTextKnnVectorsFormat"
+}
+
+func (s *KnnVectorsFormat) FieldsWriter(ctx context.Context, state *index.SegmentWriteState) (index.KnnVectorsWriter, error) {
+	return NewKnnVectorsWriter(ctx, state)
+}
+
+func (s *KnnVectorsFormat) FieldsReader(ctx context.Context, state *index.SegmentReadState) (index.KnnVectorsReader, error) {
+	return NewKnnVectorsReader(ctx, state)
+}
@@ -0,0 +1,242 @@
+package simpletext
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/search"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util"
+)
+
+var _ index.KnnVectorsReader = &KnnVectorsReader{}
+
+// KnnVectorsReader Reads vector values from a simple text format. All vectors are read into RAM
+// when the reader is opened, and searched exhaustively.
+// FOR RECREATIONAL USE ONLY
+// lucene.experimental
+type KnnVectorsReader struct {
+	fields map[string]*vectorFieldEntry
+}
+
+type vectorFieldEntry struct {
+	similarityFunction document.VectorSimilarityFunction
+	dimension          int
+	docIDs             []int
+	vectors            [][]float32
+}
+
+func NewKnnVectorsReader(ctx context.Context, state *index.SegmentReadState) (*KnnVectorsReader, error) {
+	fileName := store.SegmentFileName(state.SegmentInfo.Name(), state.SegmentSuffix, VECTOR_EXTENSION)
+	in, err := store.OpenChecksumInput(state.Directory, fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	reader := &KnnVectorsReader{fields: make(map[string]*vectorFieldEntry)}
+	scratch := new(bytes.Buffer)
+	r := utils.NewTextReader(in, scratch)
+
+	for {
+		if err := r.ReadLine(); err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(scratch.Bytes(), VECTOR_END) {
+			break
+		}
+		if !bytes.HasPrefix(scratch.Bytes(), VECTOR_FIELD_NUMBER) {
+			return nil, fmt.Errorf("label not found:%s", string(VECTOR_FIELD_NUMBER))
+		}
+
+		name, err := r.ParseString(VECTOR_FIELD_NAME)
+		if err != nil {
+			return nil, err
+		}
+		entry, err := readVectorFieldEntry(r)
+		if err != nil {
+			return nil, err
+		}
+
+		info := state.FieldInfos.FieldInfo(name)
+		if info == nil {
+			return nil, fmt.Errorf("unknown field: %s", name)
+		}
+		if info.GetVectorDimension() != entry.dimension {
+			return nil, fmt.Errorf("inconsistent vector dimension for field=\"%s\"; %d != %d",
+				name, info.GetVectorDimension(), entry.dimension)
+		}
+		reader.fields[name] = entry
+	}
+
+	if err := utils.CheckFooter(in); err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+func readVectorFieldEntry(r *utils.TextReader) (*vectorFieldEntry, error) {
+	value, err := r.ParseString(VECTOR_SIMILARITY)
+	if err != nil {
+		return nil, err
+	}
+	similarityFunction, ok := document.StringToVectorSimilarityFunction(value)
+	if !ok {
+		return nil, fmt.Errorf("unknown vector similarity function: %s", value)
+	}
+	dimension, err := r.ParseInt(VECTOR_DIMENSION)
+	if err != nil {
+		return nil, err
+	}
+	size, err := r.ParseInt(VECTOR_SIZE)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &vectorFieldEntry{
+		similarityFunction: similarityFunction,
+		dimension:          dimension,
+		docIDs:             make([]int, size),
+		vectors:            make([][]float32, size),
+	}
+	for i := 0; i < size; i++ {
+		if entry.docIDs[i], err = r.ParseInt(VECTOR_DOC); err != nil {
+			return nil, err
+		}
+		value, err := r.ParseString(VECTOR_VALUE)
+		if err != nil {
+			return nil, err
+		}
+		if entry.vectors[i], err = parseVector(value, dimension); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+func parseVector(value string, dimension int) ([]float32, error) {
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	parts := strings.Split(value, ",")
+	if len(parts) != dimension {
+		return nil, fmt.Errorf("vector has %d values, expected %d", len(parts), dimension)
+	}
+	vector := make([]float32, dimension)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return nil, err
+		}
+		vector[i] = float32(v)
+	}
+	return vector, nil
+}
+
+func (s *KnnVectorsReader) CheckIntegrity() error {
+	// the checksum of the whole file is verified when the reader is opened
+	return nil
+}
+
+func (s *KnnVectorsReader) GetVectorValues(field string) (index.VectorValues, error) {
+	entry, ok := s.fields[field]
+	if !ok {
+		return nil, nil
+	}
+	return &simpleTextVectorValues{entry: entry, ord: -1, doc: -1}, nil
+}
+
+func (s *KnnVectorsReader) Search(ctx context.Context, field string, target []float32, k int, acceptDocs util.Bits) (index.TopDocs, error) {
+	entry, ok := s.fields[field]
+	if !ok {
+		return nil, nil
+	}
+	if len(target) != entry.dimension {
+		return nil, fmt.Errorf("vector query dimension: %d differs from field dimension: %d",
+			len(target), entry.dimension)
+	}
+
+	scoreDocs := make([]index.ScoreDoc, 0, len(entry.docIDs))
+	for i, doc := range entry.docIDs {
+		if acceptDocs != nil && !acceptDocs.Test(uint(doc)) {
+			continue
+		}
+		score := entry.similarityFunction.Compare(target, entry.vectors[i])
+		scoreDocs = append(scoreDocs, search.NewScoreDoc(doc, float64(score)))
+	}
+	visited := len(scoreDocs)
+
+	sort.SliceStable(scoreDocs, func(i, j int) bool {
+		return scoreDocs[i].GetScore() > scoreDocs[j].GetScore()
+	})
+	if len(scoreDocs) > k {
+		scoreDocs = scoreDocs[:max(k, 0)]
+	}
+	return search.NewTopDocs(index.NewTotalHits(int64(visited), index.EQUAL_TO), scoreDocs), nil
+}
+
+func (s *KnnVectorsReader) Close() error {
+	return nil
+}
+
+var _ index.VectorValues = &simpleTextVectorValues{}
+
+type simpleTextVectorValues struct {
+	entry *vectorFieldEntry
+	ord   int
+	doc   int
+}
+
+func (v *simpleTextVectorValues) Dimension() int {
+	return v.entry.dimension
+}
+
+func (v *simpleTextVectorValues) Size() int {
+	return len(v.entry.docIDs)
+}
+
+func (v *simpleTextVectorValues) VectorValue() ([]float32, error) {
+	if v.ord < 0 || v.ord >= len(v.entry.docIDs) {
+		return nil, fmt.Errorf("the iterator is not positioned on a document")
+	}
+	return v.entry.vectors[v.ord], nil
+}
+
+func (v *simpleTextVectorValues) DocID() int {
+	return v.doc
+}
+
+func (v *simpleTextVectorValues) NextDoc() (int, error) {
+	v.ord++
+	if v.ord >= len(v.entry.docIDs) {
+		v.ord = len(v.entry.docIDs)
+		v.doc = types.NO_MORE_DOCS
+		return types.NO_MORE_DOCS, io.EOF
+	}
+	v.doc = v.entry.docIDs[v.ord]
+	return v.doc, nil
+}
+
+func (v *simpleTextVectorValues) Advance(target int) (int, error) {
+	for v.doc < target {
+		if _, err := v.NextDoc(); err != nil {
+			return types.NO_MORE_DOCS, err
+		}
+	}
+	return v.doc, nil
+}
+
+func (v *simpleTextVectorValues) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(v, target)
+}
+
+func (v *simpleTextVectorValues) Cost() int64 {
+	return int64(len(v.entry.docIDs))
+}
@@ -0,0 +1,125 @@
+package simpletext
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/geange/lucene-go/codecs/utils"
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+)
+
+var (
+	VECTOR_FIELD_NUMBER = []byte("field number ")
+	VECTOR_FIELD_NAME   = []byte("field name ")
+	VECTOR_SIMILARITY   = []byte("similarity function ")
+	VECTOR_DIMENSION    = []byte("dimension ")
+	VECTOR_SIZE         = []byte("size ")
+	VECTOR_DOC          = []byte("  doc ")
+	VECTOR_VALUE        = []byte("  vector ")
+	VECTOR_END          = []byte("END")
+)
+
+var _ index.KnnVectorsWriter = &KnnVectorsWriter{}
+
+// KnnVectorsWriter Writes vector-valued fields in a plain text format
+type KnnVectorsWriter struct {
+	out *utils.TextWriter
+	raw store.IndexOutput
+}
+
+func NewKnnVectorsWriter(ctx context.Context, state *index.SegmentWriteState) (*KnnVectorsWriter, error) {
+	fileName := store.SegmentFileName(state.SegmentInfo.Name(), state.SegmentSuffix, VECTOR_EXTENSION)
+	out, err := store.CreateOutputWithContext(ctx, state.Directory, fileName, state.Context)
+	if err != nil {
+		return nil, err
+	}
+	return &KnnVectorsWriter{
+		out: utils.NewTextWriter(out),
+		raw: out,
+	}, nil
+}
+
+func (s *KnnVectorsWriter) WriteField(ctx context.Context, fieldInfo *document.FieldInfo, reader index.KnnVectorsReader) error {
+	vectors, err := reader.GetVectorValues(fieldInfo.Name())
+	if err != nil {
+		return err
+	}
+	if vectors == nil {
+		return nil
+	}
+
+	if err := s.out.WriteLabelInt(VECTOR_FIELD_NUMBER, fieldInfo.Number()); err != nil {
+		return err
+	}
+	if err := s.out.WriteLabelString(VECTOR_FIELD_NAME, fieldInfo.Name()); err != nil {
+		return err
+	}
+	if err := s.out.WriteLabelString(VECTOR_SIMILARITY, fieldInfo.GetVectorSimilarityFunction().String()); err != nil {
+		return err
+	}
+	if err := s.out.WriteLabelInt(VECTOR_DIMENSION, fieldInfo.GetVectorDimension()); err != nil {
+		return err
+	}
+	if err := s.out.WriteLabelInt(VECTOR_SIZE, vectors.Size()); err != nil {
+		return err
+	}
+
+	for {
+		doc, err := vectors.NextDoc()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if doc == types.NO_MORE_DOCS {
+			return nil
+		}
+
+		value, err := vectors.VectorValue()
+		if err != nil {
+			return err
+		}
+		if err := s.out.WriteLabelInt(VECTOR_DOC, doc); err != nil {
+			return err
+		}
+		if err := s.out.WriteLabelString(VECTOR_VALUE, formatVector(value)); err != nil {
+			return err
+		}
+	}
+}
+
+func formatVector(value []float32) string {
+	sb := new(strings.Builder)
+	sb.WriteByte('[')
+	for i, v := range value {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32))
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+func (s *KnnVectorsWriter) Finish() error {
+	if err := s.out.WriteLabelBytes(VECTOR_END, nil); err != nil {
+		return err
+	}
+	return s.out.Checksum()
+}
+
+func (s *KnnVectorsWriter) Close() error {
+	if s.raw == nil {
+		return nil
+	}
+	err := s.raw.Close()
+	s.raw = nil
+	return err
+}
@@ -88,7 +88,7 @@ func (s *LiveDocsFormat) WriteLiveDocs(ctx context.Context, bits util.Bits, dir
 
 	fileName := coreIndex.FileNameFromGeneration(info.Info().Name(), LIVEDOCS_EXTENSION, info.GetNextDelGen())
 
-	out, err := dir.CreateOutput(ctx, fileName)
+	out, err := store.CreateOutputWithContext(ctx, dir, fileName, ioContext)
 	if err != nil {
 		return err
 	}
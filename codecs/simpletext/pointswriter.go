@@ -50,7 +50,7 @@ type PointsWriter struct {
 
 func NewSimpleTextPointsWriter(ctx context.Context, writeState *index.SegmentWriteState) (*PointsWriter, error) {
 	fileName := store.SegmentFileName(writeState.SegmentInfo.Name(), writeState.SegmentSuffix, POINT_EXTENSION)
-	out, err := writeState.Directory.CreateOutput(ctx, fileName)
+	out, err := store.CreateOutputWithContext(ctx, writeState.Directory, fileName, writeState.Context)
 	if err != nil {
 		return nil, err
 	}
@@ -81,7 +81,7 @@ func (s *PointsWriter) Close() error {
 	fileName := store.SegmentFileName(s.writeState.SegmentInfo.Name(),
 		s.writeState.SegmentSuffix, POINT_INDEX_EXTENSION)
 
-	indexOut, err := s.writeState.Directory.CreateOutput(nil, fileName)
+	indexOut, err := store.CreateOutputWithContext(nil, s.writeState.Directory, fileName, s.writeState.Context)
 	if err != nil {
 		return err
 	}
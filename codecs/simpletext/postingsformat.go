@@ -3,6 +3,7 @@ package simpletext
 import (
 	"context"
 
+	coreIndex "github.com/geange/lucene-go/core/index"
 	"github.com/geange/lucene-go/core/interface/index"
 	"github.com/geange/lucene-go/core/store"
 )
@@ -11,6 +12,10 @@ const (
 	POSTINGS_EXTENSION = "pst"
 )
 
+func init() {
+	coreIndex.RegisterPostingsFormat(NewPostingsFormat())
+}
+
 var _ index.PostingsFormat = &PostingsFormat{}
 
 // PostingsFormat For debugging, curiosity, transparency only!! Do not use this codec in production.
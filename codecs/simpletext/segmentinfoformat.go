@@ -206,7 +206,7 @@ func (s *SegmentInfoFormat) Write(ctx context.Context, dir store.Directory, si i
 
 	segFileName := store.SegmentFileName(si.Name(), "", SI_EXTENSION)
 
-	output, err := dir.CreateOutput(ctx, segFileName)
+	output, err := store.CreateOutputWithContext(ctx, dir, segFileName, ioContext)
 	if err != nil {
 		return err
 	}
@@ -43,7 +43,7 @@ func newStoredFieldsWriter() *StoredFieldsWriter {
 
 func NewStoredFieldsWriter(ctx context.Context, dir store.Directory, segment string, ioContext *store.IOContext) (*StoredFieldsWriter, error) {
 	writer := newStoredFieldsWriter()
-	out, err := dir.CreateOutput(ctx, store.SegmentFileName(segment, "", FIELDS_EXTENSION))
+	out, err := store.CreateOutputWithContext(ctx, dir, store.SegmentFileName(segment, "", FIELDS_EXTENSION), ioContext)
 	if err != nil {
 		return nil, err
 	}
@@ -46,7 +46,7 @@ type TermVectorsWriter struct {
 func NewTermVectorsWriter(ctx context.Context, dir store.Directory, segment string, ioContext *store.IOContext) (*TermVectorsWriter, error) {
 
 	fileName := store.SegmentFileName(segment, "", VECTORS_EXTENSION)
-	out, err := dir.CreateOutput(ctx, fileName)
+	out, err := store.CreateOutputWithContext(ctx, dir, fileName, ioContext)
 	if err != nil {
 		return nil, err
 	}
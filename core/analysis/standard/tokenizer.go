@@ -143,7 +143,6 @@ func (r *stdTokenizer) GetNextToken() (string, error) {
 			r.buff = append(r.buff, char)
 		} else {
 			r.fast += n
-			r.fast++
 			break
 		}
 	}
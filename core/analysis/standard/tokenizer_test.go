@@ -46,3 +46,34 @@ func TestTokenizer_IncrementToken(t *testing.T) {
 	assert.Nil(t, err)
 	assert.False(t, ok)
 }
+
+func TestTokenizer_Term2Bytes(t *testing.T) {
+	tokenizer := NewTokenizer()
+
+	err := tokenizer.SetReader(bytes.NewReader([]byte("aaaa bbbb")))
+	assert.Nil(t, err)
+
+	// the indexing chain reads the term of the token from Term2Bytes
+	for _, term := range []string{"aaaa", "bbbb"} {
+		ok, err := tokenizer.IncrementToken()
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, []byte(term), tokenizer.AttributeSource().Term2Bytes().GetBytes())
+	}
+}
+
+func TestTokenizer_Offsets(t *testing.T) {
+	tokenizer := NewTokenizer()
+
+	err := tokenizer.SetReader(bytes.NewReader([]byte("aaaa bb cccccc")))
+	assert.Nil(t, err)
+
+	offsets := [][]int{{0, 4}, {5, 7}, {8, 14}}
+	for _, expected := range offsets {
+		ok, err := tokenizer.IncrementToken()
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		offset := tokenizer.AttributeSource().Offset()
+		assert.Equal(t, expected, []int{offset.StartOffset(), offset.EndOffset()})
+	}
+}
@@ -16,7 +16,7 @@ type Tokenizer interface {
 
 func NewBaseTokenizer() *BaseTokenizer {
 	return &BaseTokenizer{
-		source:       attribute.NewSource(),
+		source:       attribute.NewSource(attribute.WithCharTerm()),
 		input:        nil,
 		inputPending: nil,
 	}
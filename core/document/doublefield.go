@@ -16,10 +16,13 @@ type DoublePoint struct {
 	*Field[[]byte]
 }
 
-func NewDoublePoint(name string, points ...float64) *DoublePoint {
+func NewDoublePoint(name string, points ...float64) (*DoublePoint, error) {
+	fieldType, err := genDoublePointType(len(points))
+	if err != nil {
+		return nil, err
+	}
 	packed := packDoublePoint(points)
-	fieldType := genDoublePointType(len(points))
-	return &DoublePoint{NewField(name, packed, fieldType)}
+	return &DoublePoint{NewField(name, packed, fieldType)}, nil
 }
 
 func (r *DoublePoint) Number() (any, bool) {
@@ -46,11 +49,13 @@ func decodeDimensionFloat64(value []byte) float64 {
 	return numeric.SortableLongToDouble(numeric.SortableBytesToUint64(value))
 }
 
-func genDoublePointType(numDims int) *FieldType {
+func genDoublePointType(numDims int) (*FieldType, error) {
 	fieldType := NewFieldType()
-	_ = fieldType.SetDimensions(numDims, DOUBLE_BYTES)
+	if err := fieldType.SetDimensions(numDims, DOUBLE_BYTES); err != nil {
+		return nil, err
+	}
 	fieldType.Freeze()
-	return fieldType
+	return fieldType, nil
 }
 
 // DoubleDocValuesField
@@ -52,7 +52,7 @@ func (r *Field[T]) TokenStream(analyzer analysis.Analyzer, reuse analysis.TokenS
 				return stream, nil
 			}
 
-			stream, err := NewStringTokenStream(attribute.NewSource())
+			stream, err := NewStringTokenStream(attribute.NewSource(attribute.WithCharTerm()))
 			if err != nil {
 				return nil, err
 			}
@@ -112,8 +112,14 @@ func (r *Field[T]) Set(v any) error {
 	return nil
 }
 
+// Number Returns the value of the field if it is a number
 func (r *Field[T]) Number() (any, bool) {
-	return 0, false
+	switch v := any(r.fieldsData).(type) {
+	case int32, int64, float32, float64:
+		return v, true
+	default:
+		return 0, false
+	}
 }
 
 var _ analysis.TokenStream = &StringTokenStream{}
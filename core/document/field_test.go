@@ -0,0 +1,45 @@
+package document
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestField_TokenStream(t *testing.T) {
+	t.Run("string", func(t *testing.T) {
+		field := NewStringField("id", "value", false)
+		stream, err := field.TokenStream(nil, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, stream.Reset())
+
+		ok, err := stream.IncrementToken()
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		// the indexing chain reads the term of the token from Term2Bytes
+		assert.Equal(t, []byte("value"), stream.AttributeSource().Term2Bytes().GetBytes())
+
+		ok, err = stream.IncrementToken()
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		field := NewField("id", []byte{0, 1, 2}, binaryFieldType(t))
+		stream, err := field.TokenStream(nil, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, stream.Reset())
+
+		ok, err := stream.IncrementToken()
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, []byte{0, 1, 2}, stream.AttributeSource().Term2Bytes().GetBytes())
+	})
+}
+
+func binaryFieldType(t *testing.T) *FieldType {
+	fieldType := NewFieldType()
+	assert.Nil(t, fieldType.SetIndexOptions(INDEX_OPTIONS_DOCS))
+	assert.Nil(t, fieldType.SetTokenized(false))
+	return fieldType
+}
@@ -25,6 +25,10 @@ type FieldInfo struct {
 	pointDimensionCount      int
 	pointIndexDimensionCount int
 	pointNumBytes            int
+
+	// If vectorDimension is positive it means this field indexed vectors (see KnnVectorsFormat).
+	vectorDimension          int
+	vectorSimilarityFunction VectorSimilarityFunction
 }
 
 func NewFieldInfo(name string, number int, storeTermVector, omitNorms, storePayloads bool,
@@ -101,6 +105,40 @@ func (f *FieldInfo) GetPointNumBytes() int {
 	return f.pointNumBytes
 }
 
+// SetVectorDimensionAndSimilarityFunction
+// Record that this field is indexed with vectors, with the specified number of dimensions and
+// similarity function.
+func (f *FieldInfo) SetVectorDimensionAndSimilarityFunction(dimension int, similarityFunction VectorSimilarityFunction) error {
+	if dimension < 0 {
+		return fmt.Errorf("vector dimension must be >= 0; got %d", dimension)
+	}
+	if dimension > MaxVectorDimensions {
+		return fmt.Errorf("vector dimension must be <= %d; got %d", MaxVectorDimensions, dimension)
+	}
+	if f.vectorDimension != 0 && (f.vectorDimension != dimension || f.vectorSimilarityFunction != similarityFunction) {
+		return fmt.Errorf(`cannot change field "%s" from vector dimension=%d, similarity function=%s to inconsistent vector dimension=%d, similarity function=%s`,
+			f.name, f.vectorDimension, f.vectorSimilarityFunction, dimension, similarityFunction)
+	}
+	f.vectorDimension = dimension
+	f.vectorSimilarityFunction = similarityFunction
+	return f.checkConsistency()
+}
+
+// GetVectorDimension Returns the number of dimensions of the vector value
+func (f *FieldInfo) GetVectorDimension() int {
+	return f.vectorDimension
+}
+
+// GetVectorSimilarityFunction Returns VectorSimilarityFunction for the field
+func (f *FieldInfo) GetVectorSimilarityFunction() VectorSimilarityFunction {
+	return f.vectorSimilarityFunction
+}
+
+// HasVectorValues Returns true if any vector values exist for this field.
+func (f *FieldInfo) HasVectorValues() bool {
+	return f.vectorDimension > 0
+}
+
 // SetDocValuesType Record that this field is indexed with docvalues, with the specified types
 func (f *FieldInfo) SetDocValuesType(_type DocValuesType) error {
 	f.docValuesType = _type
@@ -193,6 +231,9 @@ func (f *FieldInfo) GetAttribute(key string) string {
 // If the value of the attributes for a same field is changed between the documents, the behaviour
 // after merge is undefined.
 func (f *FieldInfo) PutAttribute(key, value string) {
+	if f.attributes == nil {
+		f.attributes = make(map[string]string)
+	}
 	f.attributes[key] = value
 }
 
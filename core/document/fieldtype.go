@@ -21,6 +21,8 @@ type FieldType struct {
 	dimensionCount           int
 	indexDimensionCount      int
 	dimensionNumBytes        int
+	vectorDimension          int
+	vectorSimilarityFunction VectorSimilarityFunction
 	attributes               map[string]string
 }
 
@@ -42,6 +44,8 @@ func NewFieldTypeFrom(fieldType IndexableFieldType) *FieldType {
 	t.dimensionCount = fieldType.PointDimensionCount()
 	t.indexDimensionCount = fieldType.PointIndexDimensionCount()
 	t.dimensionNumBytes = fieldType.PointNumBytes()
+	t.vectorDimension = fieldType.VectorDimension()
+	t.vectorSimilarityFunction = fieldType.VectorSimilarityFunction()
 	for k, v := range fieldType.GetAttributes() {
 		t.attributes[k] = v
 	}
@@ -63,6 +67,8 @@ func newFieldType() *FieldType {
 		dimensionCount:           0,
 		indexDimensionCount:      0,
 		dimensionNumBytes:        0,
+		vectorDimension:          0,
+		vectorSimilarityFunction: VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN,
 		attributes:               make(map[string]string),
 	}
 }
@@ -214,7 +220,7 @@ func (f *FieldType) SetDimensionsV1(dimensionCount, indexDimensionCount, dimensi
 	if indexDimensionCount > dimensionCount {
 		return errors.New("indexDimensionCount must be <= dimensionCount")
 	}
-	if indexDimensionCount < MaxIndexDimensions {
+	if indexDimensionCount > MaxIndexDimensions {
 		return fmt.Errorf("indexDimensionCount must be <= %d", MaxIndexDimensions)
 	}
 	if dimensionNumBytes < 0 {
@@ -255,6 +261,31 @@ func (f *FieldType) PointNumBytes() int {
 	return f.dimensionNumBytes
 }
 
+// SetVectorDimensionsAndSimilarityFunction Enable vector indexing, with the specified number of
+// dimensions and distance function.
+func (f *FieldType) SetVectorDimensionsAndSimilarityFunction(numDimensions int, similarity VectorSimilarityFunction) error {
+	if err := f.checkIfFrozen(); err != nil {
+		return err
+	}
+	if numDimensions <= 0 {
+		return errors.New("vector numDimensions must be > 0")
+	}
+	if numDimensions > MaxVectorDimensions {
+		return fmt.Errorf("vector numDimensions must be <= %d", MaxVectorDimensions)
+	}
+	f.vectorDimension = numDimensions
+	f.vectorSimilarityFunction = similarity
+	return nil
+}
+
+func (f *FieldType) VectorDimension() int {
+	return f.vectorDimension
+}
+
+func (f *FieldType) VectorSimilarityFunction() VectorSimilarityFunction {
+	return f.vectorSimilarityFunction
+}
+
 // PutAttribute
 // Puts an attribute value.
 // This is a key-value mapping for the field that the codec can use to store additional metadata.
@@ -98,6 +98,14 @@ type IndexableFieldType interface {
 	// The number of bytes in each dimension's values.
 	PointNumBytes() int
 
+	// VectorDimension
+	// The number of dimensions of the field's vector value
+	VectorDimension() int
+
+	// VectorSimilarityFunction
+	// The VectorSimilarityFunction of the field's vector value
+	VectorSimilarityFunction() VectorSimilarityFunction
+
 	// GetAttributes
 	// Attributes for the field types. Attributes are not thread-safe, user must not add
 	// attributes while other threads are indexing documents with this field types.
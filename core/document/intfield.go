@@ -11,10 +11,13 @@ type IntPoint struct {
 	*Field[[]byte]
 }
 
-func NewIntPoint(name string, points ...int32) IntPoint {
+func NewIntPoint(name string, points ...int32) (IntPoint, error) {
+	fieldType, err := genIntPointType(len(points))
+	if err != nil {
+		return IntPoint{}, err
+	}
 	packed := packIntPoint(points)
-	fieldType := genIntPointType(len(points))
-	return IntPoint{NewField(name, packed, fieldType)}
+	return IntPoint{NewField(name, packed, fieldType)}, nil
 }
 
 func (r *IntPoint) String() string {
@@ -54,11 +57,13 @@ func packIntPoint(points []int32) []byte {
 	return packed
 }
 
-func genIntPointType(numDims int) *FieldType {
+func genIntPointType(numDims int) (*FieldType, error) {
 	fieldType := NewFieldType()
-	_ = fieldType.SetDimensions(numDims, INTEGER_BYTES)
+	if err := fieldType.SetDimensions(numDims, INTEGER_BYTES); err != nil {
+		return nil, err
+	}
 	fieldType.Freeze()
-	return fieldType
+	return fieldType, nil
 }
 
 func encodeDimensionInt32(value int32, dest []byte) {
@@ -0,0 +1,77 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+)
+
+// KnnVectorField
+// A field that contains a single floating-point numeric vector (or none) for each document. Vectors
+// are dense - that is, every dimension of a vector contains an explicit value, stored packed into
+// an array (of type []float32) whose length is the vector dimension. Values can be retrieved using
+// VectorValues, which is a forward-only docID-based iterator and also offers random-access by dense
+// ordinal (not docId). VectorSimilarityFunction may be used to compare vectors at query time (for
+// example as part of result ranking). A KnnVectorField may be associated with a search similarity
+// function defining the metric used for nearest-neighbor search among vectors of that field.
+type KnnVectorField struct {
+	*Field[[]float32]
+}
+
+// NewKnnVectorField
+// Creates a numeric vector field. Fields are single-valued: each document has either one value or
+// no value. Vectors of a single field share the same dimension and similarity function.
+// name: field name
+// vector: value
+// similarityFunction: a function defining vector proximity.
+func NewKnnVectorField(name string, vector []float32, similarityFunction VectorSimilarityFunction) (*KnnVectorField, error) {
+	fieldType, err := CreateKnnVectorFieldType(len(vector), similarityFunction)
+	if err != nil {
+		return nil, err
+	}
+	return &KnnVectorField{NewField(name, vector, fieldType)}, nil
+}
+
+// NewKnnVectorFieldWithType
+// Creates a numeric vector field. Fields are single-valued: each document has either one value or
+// no value. Vectors of a single field share the same dimension and similarity function.
+// name: field name
+// vector: value
+// fieldType: field type, its vector dimension must match the length of the vector
+func NewKnnVectorFieldWithType(name string, vector []float32, fieldType IndexableFieldType) (*KnnVectorField, error) {
+	if fieldType.VectorDimension() == 0 {
+		return nil, errors.New("field type must have a vector dimension")
+	}
+	if fieldType.VectorDimension() != len(vector) {
+		return nil, fmt.Errorf("the number of vector dimensions does not match the field type: %d != %d",
+			len(vector), fieldType.VectorDimension())
+	}
+	return &KnnVectorField{NewField(name, vector, fieldType)}, nil
+}
+
+// CreateKnnVectorFieldType
+// A convenience method for creating a vector field type.
+// dimension: dimension of vectors
+// similarityFunction: a function defining vector proximity.
+func CreateKnnVectorFieldType(dimension int, similarityFunction VectorSimilarityFunction) (*FieldType, error) {
+	fieldType := NewFieldType()
+	if err := fieldType.SetVectorDimensionsAndSimilarityFunction(dimension, similarityFunction); err != nil {
+		return nil, err
+	}
+	fieldType.Freeze()
+	return fieldType, nil
+}
+
+// VectorValue Return the vector value of this field
+func (r *KnnVectorField) VectorValue() []float32 {
+	return r.fieldsData
+}
+
+// SetVectorValue Set the vector value of this field
+func (r *KnnVectorField) SetVectorValue(value []float32) error {
+	if len(value) != r.fieldType.VectorDimension() {
+		return fmt.Errorf("the number of vector dimensions does not match the field type: %d != %d",
+			len(value), r.fieldType.VectorDimension())
+	}
+	r.fieldsData = value
+	return nil
+}
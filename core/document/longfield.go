@@ -22,10 +22,14 @@ type LongPoint struct {
 // NewLongPoint
 // Creates a new LongPoint, indexing the provided N-dimensional long point.
 // Params: name – field name point – long[] value
-// Throws: IllegalArgumentException – if the field name or value is null.
-func NewLongPoint(name string, points ...int64) LongPoint {
+// Returns an error if the number of dimensions is invalid.
+func NewLongPoint(name string, points ...int64) (LongPoint, error) {
+	fieldType, err := genLongPointType(len(points))
+	if err != nil {
+		return LongPoint{}, err
+	}
 	packed := packLongPoint(points)
-	return LongPoint{NewField(name, packed, genLongPointType(len(points)))}
+	return LongPoint{NewField(name, packed, fieldType)}, nil
 }
 
 func (r *LongPoint) String() string {
@@ -65,9 +69,11 @@ func packLongPoint(points []int64) []byte {
 	return packed
 }
 
-func genLongPointType(numDims int) *FieldType {
+func genLongPointType(numDims int) (*FieldType, error) {
 	fieldType := NewFieldType()
-	_ = fieldType.SetDimensions(numDims, LONG_BYTES)
+	if err := fieldType.SetDimensions(numDims, LONG_BYTES); err != nil {
+		return nil, err
+	}
 	fieldType.Freeze()
-	return fieldType
+	return fieldType, nil
 }
@@ -1,7 +1,87 @@
 package document
 
-type SortedSetDocValuesField Field[[]byte]
+import "sync"
 
-type SortedNumericDocValuesField Field[[]byte]
+var (
+	sortedDocValuesFieldTypeOnce sync.Once
+	sortedDocValuesFieldType     *FieldType
 
-type SortedDocValuesField Field[[]byte]
+	sortedSetDocValuesFieldTypeOnce sync.Once
+	sortedSetDocValuesFieldType     *FieldType
+
+	sortedNumericDocValuesFieldTypeOnce sync.Once
+	sortedNumericDocValuesFieldType     *FieldType
+)
+
+// SortedDocValuesField
+// Field that stores a per-document []byte value, indexed for sorting. Here's an example usage:
+//
+//	document.Add(NewSortedDocValuesField(name, []byte("hello")));
+//
+// If you also need to store the value, you should add a separate StoredField instance.
+type SortedDocValuesField struct {
+	*Field[[]byte]
+}
+
+// NewSortedDocValuesField
+// Create a new sorted DocValues field.
+// name: field name
+// bytes: binary content
+func NewSortedDocValuesField(name string, bytes []byte) *SortedDocValuesField {
+	sortedDocValuesFieldTypeOnce.Do(func() {
+		sortedDocValuesFieldType = NewFieldType()
+		_ = sortedDocValuesFieldType.SetDocValuesType(DOC_VALUES_TYPE_SORTED)
+		sortedDocValuesFieldType.Freeze()
+	})
+	return &SortedDocValuesField{NewField(name, bytes, sortedDocValuesFieldType)}
+}
+
+// SortedSetDocValuesField
+// Field that stores a set of per-document []byte values, indexed for faceting,grouping,joining.
+// Here's an example usage:
+//
+//	document.Add(NewSortedSetDocValuesField(name, []byte("hello")));
+//	document.Add(NewSortedSetDocValuesField(name, []byte("world")));
+//
+// If you also need to store the value, you should add a separate StoredField instance.
+type SortedSetDocValuesField struct {
+	*Field[[]byte]
+}
+
+// NewSortedSetDocValuesField
+// Create a new sorted DocValues field.
+// name: field name
+// bytes: binary content
+func NewSortedSetDocValuesField(name string, bytes []byte) *SortedSetDocValuesField {
+	sortedSetDocValuesFieldTypeOnce.Do(func() {
+		sortedSetDocValuesFieldType = NewFieldType()
+		_ = sortedSetDocValuesFieldType.SetDocValuesType(DOC_VALUES_TYPE_SORTED_SET)
+		sortedSetDocValuesFieldType.Freeze()
+	})
+	return &SortedSetDocValuesField{NewField(name, bytes, sortedSetDocValuesFieldType)}
+}
+
+// SortedNumericDocValuesField
+// Field that stores a per-document long values for scoring, sorting or value retrieval.
+// Here's an example usage:
+//
+//	document.Add(NewSortedNumericDocValuesField(name, 5));
+//	document.Add(NewSortedNumericDocValuesField(name, 14));
+//
+// If you also need to store the value, you should add a separate StoredField instance.
+type SortedNumericDocValuesField struct {
+	*Field[int64]
+}
+
+// NewSortedNumericDocValuesField
+// Creates a new DocValues field with the specified 64-bit long value
+// name: field name
+// value: 64-bit long value
+func NewSortedNumericDocValuesField(name string, value int64) *SortedNumericDocValuesField {
+	sortedNumericDocValuesFieldTypeOnce.Do(func() {
+		sortedNumericDocValuesFieldType = NewFieldType()
+		_ = sortedNumericDocValuesFieldType.SetDocValuesType(DOC_VALUES_TYPE_SORTED_NUMERIC)
+		sortedNumericDocValuesFieldType.Freeze()
+	})
+	return &SortedNumericDocValuesField{NewField(name, value, sortedNumericDocValuesFieldType)}
+}
@@ -53,7 +53,12 @@ type DocStoredFieldVisitor struct {
 	fieldsToAdd map[string]struct{}
 }
 
+// NewDocumentStoredFieldVisitor Loads only the stored fields listed in fields, or all of them when none are given.
 func NewDocumentStoredFieldVisitor(fields ...string) *DocStoredFieldVisitor {
+	if len(fields) == 0 {
+		return newDocumentStoredFieldVisitor(nil)
+	}
+
 	fieldsToAdd := make(map[string]struct{}, len(fields))
 	for _, field := range fields {
 		fieldsToAdd[field] = struct{}{}
@@ -0,0 +1,32 @@
+package document
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocStoredFieldVisitor_NeedsField(t *testing.T) {
+	id := NewFieldInfo("id", 0, false, false, false, INDEX_OPTIONS_NONE, DOC_VALUES_TYPE_NONE, -1, map[string]string{}, 0, 0, 0, false)
+	body := NewFieldInfo("body", 1, false, false, false, INDEX_OPTIONS_NONE, DOC_VALUES_TYPE_NONE, -1, map[string]string{}, 0, 0, 0, false)
+
+	t.Run("all fields", func(t *testing.T) {
+		visitor := NewDocumentStoredFieldVisitor()
+		status, err := visitor.NeedsField(id)
+		assert.Nil(t, err)
+		assert.Equal(t, STORED_FIELD_VISITOR_YES, status)
+		status, err = visitor.NeedsField(body)
+		assert.Nil(t, err)
+		assert.Equal(t, STORED_FIELD_VISITOR_YES, status)
+	})
+
+	t.Run("listed fields", func(t *testing.T) {
+		visitor := NewDocumentStoredFieldVisitor("id")
+		status, err := visitor.NeedsField(id)
+		assert.Nil(t, err)
+		assert.Equal(t, STORED_FIELD_VISITOR_YES, status)
+		status, err = visitor.NeedsField(body)
+		assert.Nil(t, err)
+		assert.Equal(t, STORED_FIELD_VISITOR_NO, status)
+	})
+}
@@ -9,4 +9,7 @@ const (
 
 	// MaxIndexDimensions Maximum number of index dimensions
 	MaxIndexDimensions = 8
+
+	// MaxVectorDimensions Maximum number of dimensions of an indexed vector
+	MaxVectorDimensions = 1024
 )
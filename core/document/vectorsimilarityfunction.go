@@ -0,0 +1,66 @@
+package document
+
+import (
+	"github.com/geange/lucene-go/core/util"
+)
+
+// VectorSimilarityFunction Vector similarity function; used in search to return top K most similar
+// vectors to a target vector. This is a label describing the method used during indexing and
+// searching of the vectors in order to determine the nearest neighbors.
+type VectorSimilarityFunction int
+
+const (
+	// VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN Euclidean distance
+	VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN = VectorSimilarityFunction(iota)
+
+	// VECTOR_SIMILARITY_FUNCTION_DOT_PRODUCT Dot product. NOTE: this similarity is intended as an
+	// optimized way to perform cosine similarity. In order to use it, all vectors must be of unit
+	// length, including both document and query vectors. Using dot product with vectors that are
+	// not unit length can result in errors or poor search results.
+	VECTOR_SIMILARITY_FUNCTION_DOT_PRODUCT
+
+	// VECTOR_SIMILARITY_FUNCTION_COSINE Cosine similarity. NOTE: the preferred way to perform cosine
+	// similarity is to normalize all vectors to unit length, and instead use
+	// VECTOR_SIMILARITY_FUNCTION_DOT_PRODUCT. You should only use this function if you need to
+	// preserve the original vectors and cannot normalize them in advance.
+	VECTOR_SIMILARITY_FUNCTION_COSINE
+)
+
+// Compare Calculates a similarity score between the two vectors with a specified function. Higher
+// similarity scores correspond to closer vectors, and the scores are never negative.
+func (v VectorSimilarityFunction) Compare(v1, v2 []float32) float32 {
+	switch v {
+	case VECTOR_SIMILARITY_FUNCTION_DOT_PRODUCT:
+		return (1 + util.DotProduct(v1, v2)) / 2
+	case VECTOR_SIMILARITY_FUNCTION_COSINE:
+		return (1 + util.Cosine(v1, v2)) / 2
+	default:
+		return 1 / (1 + util.SquareDistance(v1, v2))
+	}
+}
+
+func (v VectorSimilarityFunction) String() string {
+	switch v {
+	case VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN:
+		return "EUCLIDEAN"
+	case VECTOR_SIMILARITY_FUNCTION_DOT_PRODUCT:
+		return "DOT_PRODUCT"
+	case VECTOR_SIMILARITY_FUNCTION_COSINE:
+		return "COSINE"
+	default:
+		return "EUCLIDEAN"
+	}
+}
+
+func StringToVectorSimilarityFunction(value string) (VectorSimilarityFunction, bool) {
+	switch value {
+	case "EUCLIDEAN":
+		return VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN, true
+	case "DOT_PRODUCT":
+		return VECTOR_SIMILARITY_FUNCTION_DOT_PRODUCT, true
+	case "COSINE":
+		return VECTOR_SIMILARITY_FUNCTION_COSINE, true
+	default:
+		return 0, false
+	}
+}
@@ -6,11 +6,10 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/bits-and-blooms/bitset"
 	"github.com/geange/lucene-go/core/document"
 	"github.com/geange/lucene-go/core/interface/index"
 	"github.com/geange/lucene-go/core/types"
-	"github.com/geange/lucene-go/core/util/bytesref"
-	"github.com/geange/lucene-go/core/util/packed"
 )
 
 type BaseBinaryDocValues struct {
@@ -62,9 +61,16 @@ var _ DocValuesFieldUpdates = &BinaryDocValuesFieldUpdates{}
 type BinaryDocValuesFieldUpdates struct {
 	*BaseDocValuesFieldUpdates
 
-	offsets, lengths *packed.PagedGrowableWriter
+	values [][]byte
+}
 
-	values bytesref.Builder
+func NewBinaryDocValuesFieldUpdates(delGen int64, field string, maxDoc int) *BinaryDocValuesFieldUpdates {
+	updates := &BinaryDocValuesFieldUpdates{}
+	updates.BaseDocValuesFieldUpdates = newBaseDocValuesFieldUpdates(maxDoc, delGen, field,
+		document.DOC_VALUES_TYPE_BINARY, func(i, j int) {
+			updates.values[i], updates.values[j] = updates.values[j], updates.values[i]
+		})
+	return updates
 }
 
 func (b *BinaryDocValuesFieldUpdates) AddInt64(doc int, value int64) error {
@@ -72,13 +78,10 @@ func (b *BinaryDocValuesFieldUpdates) AddInt64(doc int, value int64) error {
 }
 
 func (b *BinaryDocValuesFieldUpdates) AddBytes(doc int, value []byte) error {
-	idx, err := b.add(doc)
-	if err != nil {
+	if _, err := b.addInternal(doc, HAS_VALUE_MASK); err != nil {
 		return err
 	}
-	b.offsets.Set(idx, uint64(b.values.Length()))
-	b.lengths.Set(idx, uint64(len(value)))
-	b.values.AppendBytes(value)
+	b.values = append(b.values, value)
 	return nil
 }
 
@@ -90,71 +93,37 @@ func (b *BinaryDocValuesFieldUpdates) AddIterator(doc int, it DocValuesFieldUpda
 	return b.AddBytes(doc, bytes)
 }
 
-func (b *BinaryDocValuesFieldUpdates) Iterator() (DocValuesFieldUpdatesIterator, error) {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (b *BinaryDocValuesFieldUpdates) Finish() error {
-	//TODO implement me
-	panic("implement me")
-}
-
 func (b *BinaryDocValuesFieldUpdates) Reset(doc int) error {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (b *BinaryDocValuesFieldUpdates) Swap(i, j int) error {
-	if err := b.BaseDocValuesFieldUpdates.Swap(i, j); err != nil {
+	if _, err := b.addInternal(doc, HAS_NO_VALUE_MASK); err != nil {
 		return err
 	}
-
-	tmpOffset, err := b.offsets.Get(j)
-	if err != nil {
-		return err
-	}
-	v1, err := b.offsets.Get(i)
-	if err != nil {
-		return err
-	}
-	b.offsets.Set(j, v1)
-	b.offsets.Set(i, tmpOffset)
-
-	tmpLength, err := b.lengths.Get(j)
-	if err != nil {
-		return err
-	}
-	v2, err := b.lengths.Get(i)
-	if err != nil {
-		return err
-	}
-	b.lengths.Set(j, v2)
-	b.lengths.Set(i, tmpLength)
+	b.values = append(b.values, nil)
 	return nil
 }
 
-func (b *BinaryDocValuesFieldUpdates) Grow(size int) error {
-	if err := b.BaseDocValuesFieldUpdates.Grow(size); err != nil {
-		return err
+func (b *BinaryDocValuesFieldUpdates) Iterator() (DocValuesFieldUpdatesIterator, error) {
+	if !b.finished {
+		return nil, errors.New("call finish first")
 	}
-	b.offsets = b.offsets.Grow(size).(*packed.PagedGrowableWriter)
-	b.lengths = b.lengths.Grow(size).(*packed.PagedGrowableWriter)
-	return nil
+	it := &binaryDocValuesFieldUpdatesIterator{}
+	it.abstractIterator = newAbstractIterator(b.docs, b.delGen, func(idx int) {
+		it.value = b.values[idx]
+	})
+	return it, nil
 }
 
-func (b *BinaryDocValuesFieldUpdates) Resize(size int) error {
-	if err := b.BaseDocValuesFieldUpdates.Resize(size); err != nil {
-		return err
-	}
-	b.offsets = b.offsets.Resize(size).(*packed.PagedGrowableWriter)
-	b.lengths = b.lengths.Resize(size).(*packed.PagedGrowableWriter)
-	return nil
+type binaryDocValuesFieldUpdatesIterator struct {
+	*abstractIterator
+
+	value []byte
+}
+
+func (b *binaryDocValuesFieldUpdatesIterator) LongValue() (int64, error) {
+	return 0, errors.New("unsupported operation exception")
 }
 
-func (b *BinaryDocValuesFieldUpdates) EnsureFinished() error {
-	//TODO implement me
-	panic("implement me")
+func (b *binaryDocValuesFieldUpdatesIterator) BinaryValue() ([]byte, error) {
+	return b.value, nil
 }
 
 var _ DocValuesWriter = &BinaryDocValuesWriter{}
@@ -186,8 +155,22 @@ func (b *BinaryDocValuesWriter) AddValue(docID int, value []byte) error {
 }
 
 func (b *BinaryDocValuesWriter) Flush(state *index.SegmentWriteState, sortMap index.DocMap, consumer index.DocValuesConsumer) error {
+	var sorted *BinaryDVs
+	if sortMap != nil {
+		maxDoc, err := state.SegmentInfo.MaxDoc()
+		if err != nil {
+			return err
+		}
+		if sorted, err = sortBinaryDocValues(maxDoc, sortMap, b.GetDocValues().(index.BinaryDocValues)); err != nil {
+			return err
+		}
+	}
+
 	return consumer.AddBinaryField(context.TODO(), b.fieldInfo, &EmptyDocValuesProducer{
 		FnGetBinary: func(ctx context.Context, field *document.FieldInfo) (index.BinaryDocValues, error) {
+			if sorted != nil {
+				return NewSortingBinaryDocValues(sorted), nil
+			}
 			iterator, err := b.docsWithField.Iterator()
 			if err != nil {
 				return nil, err
@@ -253,3 +236,83 @@ func (b *BufferedBinaryDocValues) BinaryValue() ([]byte, error) {
 	}
 	return b.values[b.pos], nil
 }
+
+// sortBinaryDocValues Reads oldValues in the order of the documents of the sorted segment
+func sortBinaryDocValues(maxDoc int, sortMap index.DocMap, oldValues index.BinaryDocValues) (*BinaryDVs, error) {
+	docsWithField := bitset.New(uint(maxDoc))
+	values := make([][]byte, maxDoc)
+	for {
+		docID, err := nextDocOrExhausted(oldValues)
+		if err != nil {
+			return nil, err
+		}
+		if docID == types.NO_MORE_DOCS {
+			break
+		}
+		newDocID := sortMap.OldToNew(docID)
+		docsWithField.Set(uint(newDocID))
+		if values[newDocID], err = oldValues.BinaryValue(); err != nil {
+			return nil, err
+		}
+	}
+	return NewBinaryDVs(values, docsWithField), nil
+}
+
+var _ index.BinaryDocValues = &SortingBinaryDocValues{}
+
+type SortingBinaryDocValues struct {
+	dvs   *BinaryDVs
+	docID int
+	cost  int
+}
+
+func NewSortingBinaryDocValues(dvs *BinaryDVs) *SortingBinaryDocValues {
+	return &SortingBinaryDocValues{
+		dvs:   dvs,
+		docID: -1,
+		cost:  int(dvs.docsWithField.Count()),
+	}
+}
+
+func (s *SortingBinaryDocValues) DocID() int {
+	return s.docID
+}
+
+func (s *SortingBinaryDocValues) NextDoc() (int, error) {
+	value, ok := s.dvs.docsWithField.NextSet(uint(s.docID + 1))
+	if !ok {
+		return 0, io.EOF
+	}
+	s.docID = int(value)
+	return s.docID, nil
+}
+
+func (s *SortingBinaryDocValues) Advance(target int) (int, error) {
+	return 0, errors.New("unsupported Operation")
+}
+
+func (s *SortingBinaryDocValues) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(s, target)
+}
+
+func (s *SortingBinaryDocValues) Cost() int64 {
+	return int64(s.cost)
+}
+
+func (s *SortingBinaryDocValues) AdvanceExact(target int) (bool, error) {
+	s.docID = target
+	return s.dvs.docsWithField.Test(uint(target)), nil
+}
+
+func (s *SortingBinaryDocValues) BinaryValue() ([]byte, error) {
+	return s.dvs.values[s.docID], nil
+}
+
+type BinaryDVs struct {
+	values        [][]byte
+	docsWithField *bitset.BitSet
+}
+
+func NewBinaryDVs(values [][]byte, docsWithField *bitset.BitSet) *BinaryDVs {
+	return &BinaryDVs{values: values, docsWithField: docsWithField}
+}
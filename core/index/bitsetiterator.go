@@ -33,6 +33,11 @@ func (b *BitSetIterator) DocID() int {
 	return b.doc
 }
 
+// SetDocID Set the current doc id that this iterator is on.
+func (b *BitSetIterator) SetDocID(docID int) {
+	b.doc = docID
+}
+
 func (b *BitSetIterator) NextDoc() (int, error) {
 	return b.Advance(b.doc + 1)
 }
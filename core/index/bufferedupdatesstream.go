@@ -1,11 +1,14 @@
 package index
 
 import (
+	"cmp"
 	"context"
+	"slices"
 	"sync"
 	"sync/atomic"
 
 	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/store"
 )
 
 // BufferedUpdatesStream
@@ -33,10 +36,87 @@ type BufferedUpdatesStream struct {
 func NewBufferedUpdatesStream() *BufferedUpdatesStream {
 	return &BufferedUpdatesStream{
 		updates:          make(map[*FrozenBufferedUpdates]struct{}),
+		nextGen:          1,
 		finishedSegments: NewFinishedSegments(),
+		numTerms:         new(atomic.Int32),
 	}
 }
 
+// Appends a new packet of buffered deletes to the stream, setting its generation:
+func (b *BufferedUpdatesStream) push(packet *FrozenBufferedUpdates) int64 {
+	b.Lock()
+	defer b.Unlock()
+
+	// The insert operation must be atomic. If we let threads increment the gen
+	// and push the packet afterwards we risk that packets are out of order.
+	// With DWPT this is possible if two or more flushes are racing for pushing
+	// updates. If the pushed packets get our of order would loose documents
+	// since deletes are applied to the wrong segments.
+	packet.delGen = b.nextGen
+	b.nextGen++
+	b.updates[packet] = struct{}{}
+	b.numTerms.Add(int32(packet.numTermDeletes))
+	return packet.delGen
+}
+
+func (b *BufferedUpdatesStream) getPendingUpdatesCount() int {
+	b.Lock()
+	defer b.Unlock()
+	return len(b.updates)
+}
+
+// Returns the packets not applied yet, sorted by generation.
+func (b *BufferedUpdatesStream) getPendingUpdates() []*FrozenBufferedUpdates {
+	b.Lock()
+	defer b.Unlock()
+
+	packets := make([]*FrozenBufferedUpdates, 0, len(b.updates))
+	for packet := range b.updates {
+		packets = append(packets, packet)
+	}
+	slices.SortFunc(packets, func(a, b *FrozenBufferedUpdates) int {
+		return cmp.Compare(a.delGen, b.delGen)
+	})
+	return packets
+}
+
+// Returns true if the packet was pushed and is not applied yet.
+func (b *BufferedUpdatesStream) isPending(packet *FrozenBufferedUpdates) bool {
+	b.Lock()
+	defer b.Unlock()
+	_, ok := b.updates[packet]
+	return ok
+}
+
+// Any Only used by IW (when it checks if there are any pending updates)
+func (b *BufferedUpdatesStream) Any() bool {
+	b.Lock()
+	defer b.Unlock()
+	return len(b.updates) > 0
+}
+
+func (b *BufferedUpdatesStream) getNextGen() int64 {
+	b.Lock()
+	defer b.Unlock()
+
+	gen := b.nextGen
+	b.nextGen++
+	return gen
+}
+
+func (b *BufferedUpdatesStream) finished(packet *FrozenBufferedUpdates) {
+	b.Lock()
+	defer b.Unlock()
+
+	delete(b.updates, packet)
+	b.numTerms.Add(-int32(packet.numTermDeletes))
+	b.finishedSegments.finishedSegment(packet.delGen)
+}
+
+func (b *BufferedUpdatesStream) finishedSegment(delGen int64) {
+	b.finishedSegments.finishedSegment(delGen)
+}
+
 // GetCompletedDelGen
 // All frozen packets up to and including this del gen are guaranteed to be finished.
 func (b *BufferedUpdatesStream) GetCompletedDelGen() int64 {
@@ -71,6 +151,20 @@ func (f *FinishedSegments) Clear() {
 	f.finishedDelGens = map[int64]struct{}{}
 }
 
+func (f *FinishedSegments) finishedSegment(delGen int64) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.finishedDelGens[delGen] = struct{}{}
+	for {
+		if _, ok := f.finishedDelGens[f.completedDelGen+1]; !ok {
+			break
+		}
+		delete(f.finishedDelGens, f.completedDelGen+1)
+		f.completedDelGen++
+	}
+}
+
 func (f *FinishedSegments) GetCompletedDelGen() int64 {
 	f.RLock()
 	defer f.RUnlock()
@@ -89,10 +183,10 @@ type SegmentState struct {
 	onClose func(*ReadersAndUpdates) error
 }
 
-func newSegmentState(rld *ReadersAndUpdates, onClose func(*ReadersAndUpdates) error, info index.SegmentCommitInfo) *SegmentState {
-	reader, err := rld.GetReader(context.TODO(), nil)
+func newSegmentState(rld *ReadersAndUpdates, onClose func(*ReadersAndUpdates) error, info index.SegmentCommitInfo) (*SegmentState, error) {
+	reader, err := rld.GetReader(context.TODO(), store.READ)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 	state := &SegmentState{
 		delGen:        info.GetBufferedDeletesGen(),
@@ -100,12 +194,8 @@ func newSegmentState(rld *ReadersAndUpdates, onClose func(*ReadersAndUpdates) er
 		reader:        reader,
 		startDelCount: rld.GetDelCount(),
 		onClose:       onClose,
-		//termsEnum:     nil,
-		//postingsEnum:  nil,
-		//term:          nil,
-
 	}
-	return state
+	return state, nil
 }
 
 func (s *SegmentState) Close() error {
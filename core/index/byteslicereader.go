@@ -2,7 +2,7 @@ package index
 
 import (
 	"encoding/binary"
-	"errors"
+	"io"
 	"slices"
 
 	"github.com/geange/lucene-go/core/store"
@@ -45,7 +45,9 @@ func (b *ByteSliceReader) Clone() store.CloneReader {
 }
 
 func NewByteSliceReader() *ByteSliceReader {
-	return &ByteSliceReader{}
+	reader := &ByteSliceReader{}
+	reader.BaseDataInput = store.NewBaseDataInput(reader)
+	return reader
 }
 
 func (b *ByteSliceReader) init(pool *bytesref.BlockPool, startIndex, endIndex int) error {
@@ -55,7 +57,7 @@ func (b *ByteSliceReader) init(pool *bytesref.BlockPool, startIndex, endIndex in
 	b.level = 0
 	b.bufferUpto = startIndex / bytesref.BYTE_BLOCK_SIZE
 	b.bufferOffset = b.bufferUpto * bytesref.BYTE_BLOCK_SIZE
-	b.buffer = pool.GetBytes(uint32(b.bufferUpto))
+	b.buffer = pool.Get(b.bufferUpto)
 	b.upto = startIndex & bytesref.BYTE_BLOCK_MASK
 
 	firstSize := bytesref.LEVEL_SIZE_ARRAY[0]
@@ -69,23 +71,24 @@ func (b *ByteSliceReader) init(pool *bytesref.BlockPool, startIndex, endIndex in
 	return nil
 }
 
+// EOF Returns true if all the bytes of the slices were read
+func (b *ByteSliceReader) EOF() bool {
+	return b.upto+b.bufferOffset == b.endIndex
+}
+
 func (b *ByteSliceReader) Read(bs []byte) (n int, err error) {
-	offset := 0
-	size := len(bs)
-	for size > 0 {
-		numLeft := b.limit - b.upto
-		if numLeft < size {
-			copy(bs[offset:], b.buffer[b.upto:b.upto+numLeft])
-			offset += numLeft
-			size -= numLeft
+	for n < len(bs) {
+		if b.EOF() {
+			return n, io.EOF
+		}
+		if b.upto == b.limit {
 			b.nextSlice()
-		} else {
-			copy(bs[offset:], b.buffer[b.upto:])
-			b.upto += size
-			return size, nil
 		}
+		size := copy(bs[n:], b.buffer[b.upto:b.limit])
+		b.upto += size
+		n += size
 	}
-	return 0, errors.New("size of bs is zero")
+	return n, nil
 }
 
 func (b *ByteSliceReader) nextSlice() {
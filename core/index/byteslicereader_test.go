@@ -0,0 +1,54 @@
+package index
+
+import (
+	"io"
+	"testing"
+
+	"github.com/geange/lucene-go/core/util/bytesref"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByteSliceReader(t *testing.T) {
+	pool := bytesref.NewBlockPool(bytesref.GetAllocatorBuilder().NewDirect(bytesref.BYTE_BLOCK_SIZE))
+	pool.NextBuffer()
+
+	// write one stream of bytes like the terms hash does
+	size := 100000
+	start := pool.NewSlice(bytesref.FIRST_LEVEL_SIZE) + pool.ByteOffset()
+	upto := start
+	for i := 0; i < size; i++ {
+		bytes := pool.Get(upto >> bytesref.BYTE_BLOCK_SHIFT)
+		offset := upto & bytesref.BYTE_BLOCK_MASK
+		if bytes[offset] != 0 {
+			offset = pool.AllocSlice(bytes, offset)
+			bytes = pool.Current()
+			upto = offset + pool.ByteOffset()
+		}
+		bytes[offset] = byte(i)
+		upto++
+	}
+
+	reader := NewByteSliceReader()
+	assert.Nil(t, reader.init(pool, start, upto))
+
+	// the reads span the ends of the slices
+	bs := make([]byte, 7)
+	for i := 0; i < size-size%len(bs); i += len(bs) {
+		n, err := reader.Read(bs)
+		assert.Nil(t, err)
+		assert.Equal(t, len(bs), n)
+		for j, b := range bs {
+			if !assert.Equal(t, byte(i+j), b, "byte %d", i+j) {
+				return
+			}
+		}
+	}
+
+	n, err := reader.Read(bs)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, size%len(bs), n)
+	assert.True(t, reader.EOF())
+
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
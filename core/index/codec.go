@@ -8,6 +8,7 @@ import (
 	"github.com/geange/lucene-go/core/interface/index"
 	"github.com/geange/lucene-go/core/store"
 	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util"
 )
 
 var codesPool = make(map[string]index.Codec)
@@ -21,6 +22,32 @@ func GetCodecByName(name string) (index.Codec, bool) {
 	return codec, exist
 }
 
+var postingsFormatsPool = make(map[string]index.PostingsFormat)
+
+// RegisterPostingsFormat Registers format under its name, so that per-field postings can resolve
+// the format recorded in the field infos when the segment is read.
+func RegisterPostingsFormat(format index.PostingsFormat) {
+	postingsFormatsPool[format.GetName()] = format
+}
+
+func GetPostingsFormatByName(name string) (index.PostingsFormat, bool) {
+	format, exist := postingsFormatsPool[name]
+	return format, exist
+}
+
+var docValuesFormatsPool = make(map[string]index.DocValuesFormat)
+
+// RegisterDocValuesFormat Registers format under its name, so that per-field doc values can
+// resolve the format recorded in the field infos when the segment is read.
+func RegisterDocValuesFormat(format index.DocValuesFormat) {
+	docValuesFormatsPool[format.GetName()] = format
+}
+
+func GetDocValuesFormatByName(name string) (index.DocValuesFormat, bool) {
+	format, exist := docValuesFormatsPool[name]
+	return format, exist
+}
+
 type BaseCompoundDirectory struct {
 }
 
@@ -186,6 +213,26 @@ func (c *BaseCodecReader) GetPointValues(field string) (types.PointValues, bool)
 	return values, true
 }
 
+func (c *BaseCodecReader) GetVectorValues(field string) (index.VectorValues, error) {
+	//ensureOpen();
+	fi := c.GetFieldInfos().FieldInfo(field)
+	if fi == nil || fi.GetVectorDimension() == 0 {
+		// Field does not exist or does not index vectors
+		return nil, nil
+	}
+	return c.GetVectorReader().GetVectorValues(field)
+}
+
+func (c *BaseCodecReader) SearchNearestVectors(ctx context.Context, field string, target []float32, k int, acceptDocs util.Bits) (index.TopDocs, error) {
+	//ensureOpen();
+	fi := c.GetFieldInfos().FieldInfo(field)
+	if fi == nil || fi.GetVectorDimension() == 0 {
+		// Field does not exist or does not index vectors
+		return nil, nil
+	}
+	return c.GetVectorReader().Search(ctx, field, target, k, acceptDocs)
+}
+
 func (c *BaseCodecReader) CheckIntegrity() error {
 	return nil
 }
@@ -46,6 +46,9 @@ func NewDefaultIndexingChain(indexCreatedVersionMajor int, segmentInfo *SegmentI
 	if segmentInfo.GetIndexSort() == nil {
 		storedFieldsConsumer = NewStoredFieldsConsumer(indexWriterConfig.GetCodec(), dir, segmentInfo)
 		termVectorsWriter = NewTermVectorsConsumer(intBlockAllocator, byteBlockAllocator, dir, segmentInfo, indexWriterConfig.GetCodec())
+	} else {
+		storedFieldsConsumer = NewSortingStoredFieldsConsumer(indexWriterConfig.GetCodec(), dir, segmentInfo)
+		termVectorsWriter = NewSortingTermVectorsConsumer(intBlockAllocator, byteBlockAllocator, dir, segmentInfo, indexWriterConfig.GetCodec())
 	}
 
 	indexChain := &DefaultIndexingChain{
@@ -207,6 +210,9 @@ func (d *DefaultIndexingChain) Flush(ctx context.Context, state *index.SegmentWr
 	if err := d.writePoints(ctx, state, sortMap); err != nil {
 		return nil, err
 	}
+	if err := d.writeVectors(ctx, state, sortMap); err != nil {
+		return nil, err
+	}
 
 	if err := d.storedFieldsConsumer.Finish(ctx, maxDoc); err != nil {
 		return nil, err
@@ -218,22 +224,25 @@ func (d *DefaultIndexingChain) Flush(ctx context.Context, state *index.SegmentWr
 	fieldsToFlush := make(map[string]TermsHashPerField)
 
 	for _, perField := range d.fieldHash {
-		fieldsToFlush[perField.fieldInfo.Name()] = perField.termsHashPerField
+		if perField.invertState != nil {
+			fieldsToFlush[perField.fieldInfo.Name()] = perField.termsHashPerField
+		}
 	}
 
 	readState := index.NewSegmentReadState(state.Directory, state.SegmentInfo, state.FieldInfos, state.Context, state.SegmentSuffix)
 
-	//var norms NormsProducer
+	var normsMergeInstance index.NormsProducer
 	if readState.FieldInfos.HasNorms() {
 		norms, err := state.SegmentInfo.GetCodec().NormsFormat().NormsProducer(ctx, readState)
 		if err != nil {
 			return nil, err
 		}
+		defer norms.Close()
 
-		normsMergeInstance := norms.GetMergeInstance()
-		if err := d.termsHash.Flush(fieldsToFlush, state, sortMap, normsMergeInstance); err != nil {
-			return nil, err
-		}
+		normsMergeInstance = norms.GetMergeInstance()
+	}
+	if err := d.termsHash.Flush(fieldsToFlush, state, sortMap, normsMergeInstance); err != nil {
+		return nil, err
 	}
 
 	if err := d.indexWriterConfig.GetCodec().FieldInfosFormat().
@@ -284,6 +293,49 @@ func (d *DefaultIndexingChain) writePoints(ctx context.Context, state *index.Seg
 	return nil
 }
 
+// Writes all buffered vectors.
+func (d *DefaultIndexingChain) writeVectors(ctx context.Context, state *index.SegmentWriteState, sortMap index.DocMap) error {
+	var knnVectorsWriter index.KnnVectorsWriter
+	var err error
+
+	for _, perField := range d.fieldHash {
+		if perField.vectorValuesWriter != nil {
+			if perField.fieldInfo.GetVectorDimension() == 0 {
+				return fmt.Errorf(`segment=%s: field="%s" has no vectors but wrote them`,
+					state.SegmentInfo.Name(), perField.fieldInfo.Name())
+			}
+			if knnVectorsWriter == nil {
+				// lazy init
+				format := state.SegmentInfo.GetCodec().KnnVectorsFormat()
+				if format == nil {
+					return fmt.Errorf("codec %s does not support vectors", state.SegmentInfo.GetCodec().GetName())
+				}
+				knnVectorsWriter, err = format.FieldsWriter(ctx, state)
+				if err != nil {
+					return err
+				}
+			}
+
+			if err := perField.vectorValuesWriter.Flush(ctx, sortMap, knnVectorsWriter); err != nil {
+				_ = knnVectorsWriter.Close()
+				return err
+			}
+			perField.vectorValuesWriter = nil
+		}
+	}
+
+	if knnVectorsWriter != nil {
+		if err := knnVectorsWriter.Finish(); err != nil {
+			_ = knnVectorsWriter.Close()
+			return err
+		}
+		if err := knnVectorsWriter.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Writes all buffered doc values (called from Flush).
 func (d *DefaultIndexingChain) writeDocValues(state *index.SegmentWriteState, sortMap index.DocMap) error {
 	var dvConsumer index.DocValuesConsumer
@@ -377,22 +429,26 @@ func (d *DefaultIndexingChain) ProcessDocument(ctx context.Context, docId int, d
 		return err
 	}
 
+	// the fields inverted for this document
+	d.fields = d.fields[:0]
+
 	for _, field := range doc.Fields() {
 		count, err := d.processField(ctx, docId, field, fieldGen, fieldCount)
 		if err != nil {
 			return err
 		}
 		fieldCount = count
+	}
 
-		for i := 0; i < fieldCount; i++ {
-			if err := d.fields[i].Finish(docId); err != nil {
-				return err
-			}
-		}
-		if err := d.finishStoredFields(); err != nil {
+	// Finish each indexed field name seen in the document:
+	for i := 0; i < fieldCount; i++ {
+		if err := d.fields[i].Finish(docId); err != nil {
 			return err
 		}
 	}
+	if err := d.finishStoredFields(); err != nil {
+		return err
+	}
 
 	return d.termsHash.FinishDocument(docId)
 }
@@ -475,6 +531,18 @@ func (d *DefaultIndexingChain) processField(ctx context.Context, docId int, fiel
 			return 0, err
 		}
 	}
+
+	if fieldType.VectorDimension() != 0 {
+		if fp == nil {
+			fp, err = d.getOrAddField(fieldName, fieldType, false)
+			if err != nil {
+				return 0, err
+			}
+		}
+		if err := d.indexVector(docId, fp, field); err != nil {
+			return 0, err
+		}
+	}
 	return fieldCount, nil
 }
 
@@ -508,12 +576,102 @@ func (d *DefaultIndexingChain) indexPoint(docID int, fp *PerField, field documen
 	return fp.pointValuesWriter.AddPackedValue(docID, bs)
 }
 
+// Called from processDocument to index one field's vector value
+func (d *DefaultIndexingChain) indexVector(docID int, fp *PerField, field document.IndexableField) error {
+	dimension := field.FieldType().VectorDimension()
+	similarityFunction := field.FieldType().VectorSimilarityFunction()
+
+	// the vectors could not be written when the segment is flushed
+	if codec := d.indexWriterConfig.GetCodec(); codec.KnnVectorsFormat() == nil {
+		return fmt.Errorf(`codec %s does not support vectors: field "%s"`, codec.GetName(), fp.fieldInfo.Name())
+	}
+
+	// Record dimensions and distance function for this field; this setter will return an error if
+	// the dimensions or distance function were already set to something different:
+	if fp.fieldInfo.GetVectorDimension() == 0 {
+		if err := d.fieldInfos.globalFieldNumbers.SetVectorDimensionsAndSimilarityFunction(
+			fp.fieldInfo.Number(), fp.fieldInfo.Name(), dimension, similarityFunction); err != nil {
+			return err
+		}
+	}
+	if err := fp.fieldInfo.SetVectorDimensionAndSimilarityFunction(dimension, similarityFunction); err != nil {
+		return err
+	}
+
+	if fp.vectorValuesWriter == nil {
+		fp.vectorValuesWriter = NewVectorValuesWriter(fp.fieldInfo)
+	}
+	value, ok := field.Get().([]float32)
+	if !ok {
+		return fmt.Errorf(`field "%s" has vector dimension %d but its value is not a []float32`,
+			fp.fieldInfo.Name(), dimension)
+	}
+	return fp.vectorValuesWriter.AddValue(docID, value)
+}
+
+// Checks that the doc values type of a field involved in the index sort is the one its sorter reads.
 func (d *DefaultIndexingChain) validateIndexSortDVType(indexSort index.Sort, fieldToValidate string, dvType document.DocValuesType) error {
-	// TODO: fix it
+	for _, sortField := range indexSort.GetSort() {
+		sorter := sortField.GetIndexSorter()
+		if sorter == nil {
+			return fmt.Errorf("cannot sort index with sort order %v", sortField)
+		}
 
+		// the sorter reads the doc values it sorts by, which fails on the field to validate
+		// if they are not of its type
+		reader := &indexSortValidationLeafReader{
+			DocValuesLeafReader: NewDocValuesLeafReader(),
+			sortField:           sortField,
+			field:               fieldToValidate,
+			dvType:              dvType,
+		}
+		if _, err := sorter.GetDocComparator(reader, 0); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+var _ index.LeafReader = &indexSortValidationLeafReader{}
+
+// indexSortValidationLeafReader has no doc values, reading the doc values of field fails unless they
+// are of type dvType
+type indexSortValidationLeafReader struct {
+	*DocValuesLeafReader
+
+	sortField index.SortField
+	field     string
+	dvType    document.DocValuesType
+}
+
+func (r *indexSortValidationLeafReader) validate(field string, expected document.DocValuesType) error {
+	if field == r.field && r.dvType != expected {
+		return fmt.Errorf("SortField %v expected field [%s] to be %s but it is [%s]",
+			r.sortField, field, expected, r.dvType)
+	}
+	return nil
+}
+
+func (r *indexSortValidationLeafReader) GetNumericDocValues(field string) (index.NumericDocValues, error) {
+	return nil, r.validate(field, document.DOC_VALUES_TYPE_NUMERIC)
+}
+
+func (r *indexSortValidationLeafReader) GetBinaryDocValues(field string) (index.BinaryDocValues, error) {
+	return nil, r.validate(field, document.DOC_VALUES_TYPE_BINARY)
+}
+
+func (r *indexSortValidationLeafReader) GetSortedDocValues(field string) (index.SortedDocValues, error) {
+	return nil, r.validate(field, document.DOC_VALUES_TYPE_SORTED)
+}
+
+func (r *indexSortValidationLeafReader) GetSortedNumericDocValues(field string) (index.SortedNumericDocValues, error) {
+	return nil, r.validate(field, document.DOC_VALUES_TYPE_SORTED_NUMERIC)
+}
+
+func (r *indexSortValidationLeafReader) GetSortedSetDocValues(field string) (index.SortedSetDocValues, error) {
+	return nil, r.validate(field, document.DOC_VALUES_TYPE_SORTED_SET)
+}
+
 // Called from processDocument to index one field's doc item
 func (d *DefaultIndexingChain) indexDocValue(docID int,
 	fp *PerField, dvType document.DocValuesType, field document.IndexableField) error {
@@ -560,7 +718,7 @@ func (d *DefaultIndexingChain) indexDocValue(docID int,
 		}
 
 	case document.DOC_VALUES_TYPE_BINARY:
-		if fp.docValuesWriter != nil {
+		if fp.docValuesWriter == nil {
 			fp.docValuesWriter = NewBinaryDocValuesWriter(fp.fieldInfo)
 		}
 
@@ -574,15 +732,63 @@ func (d *DefaultIndexingChain) indexDocValue(docID int,
 		}
 
 	case document.DOC_VALUES_TYPE_SORTED:
-		return errors.New("unsupported DocValues.Type")
+		if fp.docValuesWriter == nil {
+			writer, err := NewSortedDocValuesWriter(fp.fieldInfo, d.docValuesBytePool)
+			if err != nil {
+				return err
+			}
+			fp.docValuesWriter = writer
+		}
+
+		bs, err := document.Bytes(field.Get())
+		if err != nil {
+			return err
+		}
+
+		if err := fp.docValuesWriter.(*SortedDocValuesWriter).AddValue(docID, bs); err != nil {
+			return err
+		}
+
 	case document.DOC_VALUES_TYPE_SORTED_NUMERIC:
-		return errors.New("unsupported DocValues.Type")
+		if fp.docValuesWriter == nil {
+			fp.docValuesWriter = NewSortedNumericDocValuesWriter(fp.fieldInfo)
+		}
+
+		obj, ok := field.Number()
+		if !ok {
+			return errors.New("field value is not number")
+		}
+		num, err := document.Int64(obj)
+		if err != nil {
+			return err
+		}
+
+		if err := fp.docValuesWriter.(*SortedNumericDocValuesWriter).AddValue(docID, num); err != nil {
+			return err
+		}
+
 	case document.DOC_VALUES_TYPE_SORTED_SET:
-		return errors.New("unsupported DocValues.Type")
+		if fp.docValuesWriter == nil {
+			writer, err := NewSortedSetDocValuesWriter(fp.fieldInfo, d.docValuesBytePool)
+			if err != nil {
+				return err
+			}
+			fp.docValuesWriter = writer
+		}
+
+		bs, err := document.Bytes(field.Get())
+		if err != nil {
+			return err
+		}
+
+		if err := fp.docValuesWriter.(*SortedSetDocValuesWriter).AddValue(docID, bs); err != nil {
+			return err
+		}
+
 	default:
-		return errors.New("unsupported DocValues.Type")
+		return errors.New("unrecognized DocValues.Type")
 	}
-	return errors.New("unrecognized DocValues.Type")
+	return nil
 }
 
 // Returns a previously created DefaultIndexingChain.PerField, absorbing the type information from FieldType,
@@ -682,6 +888,7 @@ type PerField struct {
 	termsHashPerField        TermsHashPerField
 	docValuesWriter          DocValuesWriter
 	pointValuesWriter        *PointValuesWriter
+	vectorValuesWriter       *VectorValuesWriter
 
 	// We use this to know when a PerField is seen for the first time in the current document.
 	fieldGen int64
@@ -703,6 +910,7 @@ func (d *DefaultIndexingChain) NewPerField(indexCreatedVersionMajor int, fieldIn
 		fieldInfo:                fieldInfo,
 		similarity:               similarity,
 		analyzer:                 analyzer,
+		fieldGen:                 -1,
 	}
 
 	if invert {
@@ -0,0 +1,110 @@
+package index
+
+import (
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/structure"
+)
+
+// docIDMergerSub is a sub of a docIDMerger
+type docIDMergerSub interface {
+	// nextMappedDoc advances the sub to its next live document, and returns the ID of the document in
+	// the merged segment, NO_MORE_DOCS once the sub is exhausted
+	nextMappedDoc() (int, error)
+}
+
+type docIDMergerEntry[T docIDMergerSub] struct {
+	sub       T
+	mappedDoc int
+}
+
+// docIDMerger walks the documents of the subs of a merge in the document order of the merged
+// segment. When the index is not sorted the subs are concatenated, else the documents of the subs
+// may interleave, they are merge sorted as the documents of each sub are in the index sort order.
+type docIDMerger[T docIDMergerSub] struct {
+	subs          []*docIDMergerEntry[T]
+	indexIsSorted bool
+
+	upto    int // the next sub to concatenate when the index is not sorted
+	current *docIDMergerEntry[T]
+	queue   *structure.PriorityQueue[*docIDMergerEntry[T]]
+}
+
+func newDocIDMerger[T docIDMergerSub](subs []T, indexIsSorted bool) *docIDMerger[T] {
+	entries := make([]*docIDMergerEntry[T], len(subs))
+	for i, sub := range subs {
+		entries[i] = &docIDMergerEntry[T]{sub: sub, mappedDoc: -1}
+	}
+	return &docIDMerger[T]{subs: entries, indexIsSorted: indexIsSorted}
+}
+
+// next returns the next document of the merged segment, NO_MORE_DOCS once all the subs are
+// exhausted. The sub holding the document is then returned by currentSub.
+func (d *docIDMerger[T]) next() (int, error) {
+	if d.indexIsSorted {
+		return d.nextSorted()
+	}
+
+	for {
+		if d.current == nil {
+			if d.upto == len(d.subs) {
+				return types.NO_MORE_DOCS, nil
+			}
+			d.current = d.subs[d.upto]
+			d.upto++
+		}
+
+		doc, err := d.current.sub.nextMappedDoc()
+		if err != nil {
+			return 0, err
+		}
+		if doc == types.NO_MORE_DOCS {
+			d.current = nil
+			continue
+		}
+		d.current.mappedDoc = doc
+		return doc, nil
+	}
+}
+
+func (d *docIDMerger[T]) nextSorted() (int, error) {
+	if d.queue == nil {
+		d.queue = structure.NewPriorityQueue(len(d.subs), func(a, b *docIDMergerEntry[T]) bool {
+			return a.mappedDoc < b.mappedDoc
+		})
+		for _, entry := range d.subs {
+			doc, err := entry.sub.nextMappedDoc()
+			if err != nil {
+				return 0, err
+			}
+			if doc != types.NO_MORE_DOCS {
+				entry.mappedDoc = doc
+				d.queue.Add(entry)
+			}
+		}
+	} else if d.current != nil {
+		doc, err := d.current.sub.nextMappedDoc()
+		if err != nil {
+			return 0, err
+		}
+		if doc == types.NO_MORE_DOCS {
+			if _, err := d.queue.Pop(); err != nil {
+				return 0, err
+			}
+		} else {
+			d.current.mappedDoc = doc
+			d.queue.UpdateTop()
+		}
+	}
+
+	if d.queue.Size() == 0 {
+		d.current = nil
+		return types.NO_MORE_DOCS, nil
+	}
+	d.current = d.queue.Top()
+	return d.current.mappedDoc, nil
+}
+
+// currentSub returns the sub holding the last document returned by next
+func (d *docIDMerger[T]) currentSub() T {
+	return d.current.sub
+}
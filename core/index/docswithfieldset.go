@@ -41,5 +41,6 @@ func (d *DocsWithFieldSet) Add(docID int) error {
 	}
 	d.lastDocId = docID
 	d.set.Set(uint(docID))
+	d.cost++
 	return nil
 }
@@ -19,8 +19,8 @@ import (
 // to the Directory. Threads: Multiple threads are allowed into addDocument at once. There is an
 // initial synchronized call to DocumentsWriterFlushControl.ObtainAndLock() which allocates a DWPT
 // for this indexing thread. The same thread will not necessarily get the same DWPT over time.
-// Then updateDocuments is called on that DWPT without synchronization (most of the "heavy lifting"
-// is in this call). Once a DWPT fills up enough RAM or hold enough documents in memory the DWPT
+// Then updateDocuments is called on that DWPT while holding its lock (most of the "heavy lifting"
+// is in this call), there is a single DWPT so the documents are added one goroutine at a time. Once a DWPT fills up enough RAM or hold enough documents in memory the DWPT
 // is checked out for Flush and all changes are written to the directory. Each DWPT corresponds to
 // one segment being written. When Flush is called by IndexWriter we check out all DWPTs that are
 // associated with the current DocumentsWriterDeleteQueue out of the DocumentsWriterPerThreadPool
@@ -59,11 +59,9 @@ type DocumentsWriter struct {
 }
 
 func NewDocumentsWriter(flushNotifications index.FlushNotifications, indexCreatedVersionMajor int, pendingNumDocs *atomic.Int64, enableTestPoints bool,
-	segmentName string, config *liveIndexWriterConfig, directoryOrig, directory store.Directory,
+	segmentNameSupplier func() string, config *liveIndexWriterConfig, directoryOrig, directory store.Directory,
 	globalFieldNumberMap *FieldNumbers) *DocumentsWriter {
 
-	infos := NewFieldInfosBuilder(globalFieldNumberMap)
-
 	deleteQueue := NewDocumentsWriterDeleteQueue()
 
 	docWriter := &DocumentsWriter{
@@ -73,15 +71,19 @@ func NewDocumentsWriter(flushNotifications index.FlushNotifications, indexCreate
 		config:                           config,
 		numDocsInRAM:                     new(atomic.Int64),
 		deleteQueue:                      deleteQueue,
-		ticketQueue:                      nil,
+		ticketQueue:                      NewDocumentsWriterFlushQueue(),
 		pendingChangesInCurrentFullFlush: false,
 		perThreadPool:                    nil,
-		flushControl: &DocumentsWriterFlushControl{
-			flushDeletes: new(atomic.Bool),
-			perThread: NewDocumentsWriterPerThread(indexCreatedVersionMajor,
-				segmentName, directoryOrig,
-				directory, config, deleteQueue, infos,
-				pendingNumDocs, enableTestPoints)},
+	}
+	docWriter.flushControl = &DocumentsWriterFlushControl{
+		flushDeletes:    new(atomic.Bool),
+		documentsWriter: docWriter,
+		newPerThread: func() *DocumentsWriterPerThread {
+			return NewDocumentsWriterPerThread(indexCreatedVersionMajor,
+				segmentNameSupplier(), directoryOrig,
+				directory, config, docWriter.deleteQueue, NewFieldInfosBuilder(globalFieldNumberMap),
+				pendingNumDocs, enableTestPoints)
+		},
 	}
 	return docWriter
 }
@@ -90,16 +92,48 @@ func (d *DocumentsWriter) purgeFlushTickets(forced bool, consumer func(*FlushTic
 	if forced {
 		return d.ticketQueue.forcePurge(consumer)
 	}
-	return nil
+	return d.ticketQueue.tryPurge(consumer)
+}
+
+func (d *DocumentsWriter) getNextSequenceNumber() int64 {
+	// this must be called under the lock since the delete queue is the source of sequence numbers
+	return d.deleteQueue.getNextSequenceNumber()
+}
+
+func (d *DocumentsWriter) deleteTerms(terms ...index.Term) (int64, error) {
+	return d.applyDeleteOrUpdate(func(deleteQueue *DocumentsWriterDeleteQueue) int64 {
+		return deleteQueue.addDeleteTerms(terms...)
+	})
+}
+
+func (d *DocumentsWriter) deleteQueries(queries ...index.Query) (int64, error) {
+	return d.applyDeleteOrUpdate(func(deleteQueue *DocumentsWriterDeleteQueue) int64 {
+		return deleteQueue.addDeleteQueries(queries...)
+	})
+}
+
+func (d *DocumentsWriter) updateDocValues(updates ...index.DocValuesUpdate) (int64, error) {
+	return d.applyDeleteOrUpdate(func(deleteQueue *DocumentsWriterDeleteQueue) int64 {
+		return deleteQueue.addDocValuesUpdates(updates...)
+	})
 }
 
-func (d *DocumentsWriter) preUpdate() (bool, error) {
-	panic("")
+func (d *DocumentsWriter) applyDeleteOrUpdate(function func(deleteQueue *DocumentsWriterDeleteQueue) int64) (int64, error) {
+	seqNo := function(d.deleteQueue)
+	applied, err := d.applyAllDeletes()
+	if err != nil {
+		return 0, err
+	}
+	if applied {
+		seqNo = -seqNo
+	}
+	return seqNo, nil
 }
 
-// TODO: fix it
 func (d *DocumentsWriter) updateDocuments(ctx context.Context, docs []*document.Document, delNode *Node) (int64, error) {
 	dwpt := d.flushControl.ObtainAndLock()
+	defer dwpt.lock.Unlock()
+
 	dwptNumDocs := dwpt.GetNumDocsInRAM()
 	seqNo, err := dwpt.updateDocuments(ctx, docs, delNode)
 	if err != nil {
@@ -110,48 +144,45 @@ func (d *DocumentsWriter) updateDocuments(ctx context.Context, docs []*document.
 	return seqNo, nil
 }
 
-func (d *DocumentsWriter) Flush(ctx context.Context) error {
-	dwpt := d.flushControl.ObtainAndLock()
-	_, err := d.doFlush(ctx, dwpt)
-	return err
-}
-
-// FIXME: 处理flush
 func (d *DocumentsWriter) doFlush(ctx context.Context, flushingDWPT *DocumentsWriterPerThread) (bool, error) {
 	hasEvents := false
 
 	for flushingDWPT != nil {
 		hasEvents = true
 
-		dwptSuccess := true
-
-		//ticket, err := d.ticketQueue.AddFlushTicket(flushingDWPT)
-		//if err != nil {
-		//	return err
-		//}
-		//flushingDocsInRam := flushingDWPT.GetNumDocsInRAM()
-
-		// flush concurrently without locking
-		//newSegment, err := flushingDWPT.flush(ctx, d.flushNotifications)
-		if _, err := flushingDWPT.flush(ctx, d.flushNotifications); err != nil {
-			dwptSuccess = false
+		// waits for the documents still being added to the checked out DWPT
+		flushingDWPT.lock.Lock()
+		// Each flush is assigned a ticket in the order they acquire the ticketQueue lock
+		ticket, err := d.ticketQueue.AddFlushTicket(flushingDWPT)
+		if err != nil {
+			flushingDWPT.lock.Unlock()
+			return false, err
+		}
+		flushingDocsInRam := flushingDWPT.GetNumDocsInRAM()
+		newSegment, flushErr := flushingDWPT.flush(ctx, d.flushNotifications)
+		flushingDWPT.lock.Unlock()
+		if flushErr != nil {
+			d.ticketQueue.markTicketFailed(ticket)
+		} else {
+			d.ticketQueue.AddSegment(ticket, newSegment)
 		}
-		//d.ticketQueue.AddSegment(ticket, newSegment)
-		//
-		//d.subtractFlushedNumDocs(int64(flushingDocsInRam))
-		if (len(flushingDWPT.PendingFilesToDelete()) == 0) == false {
+		// flush was successful once we reached this point - new seg. has been assigned to the ticket!
+		d.subtractFlushedNumDocs(int64(flushingDocsInRam))
+
+		if len(flushingDWPT.PendingFilesToDelete()) != 0 {
 			files := flushingDWPT.PendingFilesToDelete()
 			d.flushNotifications.DeleteUnusedFiles(files)
-			hasEvents = true
 		}
-		if dwptSuccess == false {
+		if flushErr != nil {
 			d.flushNotifications.FlushFailed(flushingDWPT.GetSegmentInfo())
-			hasEvents = true
 		}
 
 		if err := d.flushControl.DoAfterFlush(flushingDWPT); err != nil {
 			return false, err
 		}
+		if flushErr != nil {
+			return false, flushErr
+		}
 		flushingDWPT = d.flushControl.NextPendingFlush()
 	}
 
@@ -185,54 +216,40 @@ func (d *DocumentsWriter) anyDeletions() bool {
 // FlushAllThreads is synced by IW fullFlushLock. Flushing all threads is a
 // two stage operation; the caller must ensure (in try/finally) that finishFlush
 // is called after this method, to release the flush lock in DWFlushControl
-func (d *DocumentsWriter) flushAllThreads() int64 {
-	var flushingDeleteQueue *DocumentsWriterDeleteQueue
-
-	var seqNo int64
-
+func (d *DocumentsWriter) flushAllThreads(ctx context.Context) (int64, error) {
 	d.pendingChangesInCurrentFullFlush = d.anyChanges()
-	flushingDeleteQueue = d.deleteQueue
+	flushingDeleteQueue := d.deleteQueue
 
-	// Cutover to a new delete queue.  This must be synced on the flush control
-	// otherwise a new DWPT could sneak into the loop with an already flushing
-	// delete queue
-	seqNo = d.flushControl.MarkForFullFlush() // swaps this.deleteQueue synced on FlushControl
+	// Unlike Lucene the delete queue is not swapped: the buffered documents and deletes
+	// are checked out at once and no document can sneak in while they are flushed.
+	seqNo := d.flushControl.MarkForFullFlush()
 
 	anythingFlushed := false
 
-	ctx := context.Background()
-
-	var flushingDWPT *DocumentsWriterPerThread
 	for {
-		flushingDWPT = d.flushControl.NextPendingFlush()
+		flushingDWPT := d.flushControl.NextPendingFlush()
 		if flushingDWPT == nil {
 			break
 		}
 
 		hasEvent, err := d.doFlush(ctx, flushingDWPT)
 		if err != nil {
-			return 0
+			return 0, err
 		}
 
 		anythingFlushed = anythingFlushed || hasEvent
 	}
 
-	// If a concurrent flush is still in flight wait for it
-	//d.flushControl.WaitForFlush();
 	if anythingFlushed == false && flushingDeleteQueue.anyChanges() { // apply deletes if we did not flush any document
-		_, err := d.ticketQueue.AddDeletes(flushingDeleteQueue)
-		if err != nil {
-			return 0
+		if _, err := d.ticketQueue.AddDeletes(flushingDeleteQueue); err != nil {
+			return 0, err
 		}
 	}
 
-	flushingDeleteQueue.Close() // all DWPT have been processed and this queue has been fully flushed to the ticket-queue
-
 	if anythingFlushed {
-		return -seqNo
-	} else {
-		return seqNo
+		return -seqNo, nil
 	}
+	return seqNo, nil
 }
 
 func (d *DocumentsWriter) finishFullFlush(success bool) error {
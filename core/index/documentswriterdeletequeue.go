@@ -39,6 +39,10 @@ type DocumentsWriterDeleteQueue struct {
 	tail   *Node
 	closed bool
 
+	// guards tail: nodes are appended by concurrent deletes and updates while the DWPTs
+	// and the global slice read the tail to update their slices
+	tailLock sync.Mutex
+
 	// Used to record deletes against all prior (already written to disk) segments.
 	// Whenever any segment flushes, we bundle up this set of deletes and insert into
 	// the buffered updates stream before the newly flushed segment(s).
@@ -105,16 +109,28 @@ func (d *DocumentsWriterDeleteQueue) Add(deleteNode *Node, slice *DeleteSlice) i
 }
 
 func (d *DocumentsWriterDeleteQueue) add(newNode *Node) int64 {
+	d.tailLock.Lock()
+	defer d.tailLock.Unlock()
+
 	d.tail.next = newNode
 	d.tail = newNode
 	return d.getNextSequenceNumber()
 }
 
+// currentTail
+// Returns the latest node added to the queue.
+func (d *DocumentsWriterDeleteQueue) currentTail() *Node {
+	d.tailLock.Lock()
+	defer d.tailLock.Unlock()
+
+	return d.tail
+}
+
 // UpdateSlice Negative result means there were new deletes since we last applied
 func (d *DocumentsWriterDeleteQueue) UpdateSlice(slice *DeleteSlice) int64 {
 	seqNo := d.getNextSequenceNumber()
-	if slice.sliceTail != d.tail {
-		slice.sliceTail = d.tail
+	if tail := d.currentTail(); slice.sliceTail != tail {
+		slice.sliceTail = tail
 		seqNo = -seqNo
 	}
 	return seqNo
@@ -129,38 +145,105 @@ func (d *DocumentsWriterDeleteQueue) tryApplyGlobalSlice() {
 	defer d.globalBufferLock.Unlock()
 
 	if d.updateSliceNoSeqNo(d.globalSlice) {
-		d.globalSlice.Apply(d.globalBufferedUpdates, math.MaxInt32)
+		// the nodes of the delete queue only fail on unsupported doc values updates,
+		// which are rejected before they are added
+		_ = d.globalSlice.Apply(d.globalBufferedUpdates, math.MaxInt32)
 	}
 }
 
 // Just like updateSlice, but does not assign a sequence number
 func (d *DocumentsWriterDeleteQueue) updateSliceNoSeqNo(slice *DeleteSlice) bool {
-	if slice.sliceTail != d.tail {
+	if tail := d.currentTail(); slice.sliceTail != tail {
 		// new deletes arrived since we last checked
-		slice.sliceTail = d.tail
+		slice.sliceTail = tail
 		return true
 	}
 	return false
 }
 
 func (d *DocumentsWriterDeleteQueue) newSlice() *DeleteSlice {
-	return NewDeleteSlice(d.tail)
+	return NewDeleteSlice(d.currentTail())
+}
+
+func (d *DocumentsWriterDeleteQueue) addDeleteTerms(terms ...index.Term) int64 {
+	seqNo := d.add(deleteQueueNewNodeTerms(terms))
+	d.tryApplyGlobalSlice()
+	return seqNo
+}
+
+func (d *DocumentsWriterDeleteQueue) addDeleteQueries(queries ...index.Query) int64 {
+	seqNo := d.add(deleteQueueNewNodeQueries(queries))
+	d.tryApplyGlobalSlice()
+	return seqNo
+}
+
+func (d *DocumentsWriterDeleteQueue) addDocValuesUpdates(updates ...index.DocValuesUpdate) int64 {
+	seqNo := d.add(deleteQueueNewNodeDocValuesUpdates(updates))
+	d.tryApplyGlobalSlice()
+	return seqNo
+}
+
+func (d *DocumentsWriterDeleteQueue) getLastSequenceNumber() int64 {
+	return d.nextSeqNo.Load()
 }
 
 func (d *DocumentsWriterDeleteQueue) anyChanges() bool {
 	d.globalBufferLock.Lock()
 	defer d.globalBufferLock.Unlock()
 
-	// TODO: fix it
-	return false
+	tail := d.currentTail()
+
+	// check if all items in the global slice were applied
+	// and if the global slice is up-to-date
+	// and if globalBufferedUpdates has changes
+	return d.globalBufferedUpdates.Any() ||
+		d.globalSlice.sliceHead != d.globalSlice.sliceTail ||
+		d.globalSlice.sliceTail != tail
 }
 
-func (d *DocumentsWriterDeleteQueue) freezeGlobalBuffer(slice *DeleteSlice) *FrozenBufferedUpdates {
-	panic("")
+// Freezes the global deletes not frozen yet, the given slice of the DWPT being flushed is
+// moved to the tail so that it sees all the deletes preceding the flush. Returns nil if there
+// are no global deletes.
+func (d *DocumentsWriterDeleteQueue) freezeGlobalBuffer(callerSlice *DeleteSlice) (*FrozenBufferedUpdates, error) {
+	d.globalBufferLock.Lock()
+	defer d.globalBufferLock.Unlock()
+
+	// Here we freeze the global buffer so we need to lock it, apply all
+	// deletes in the queue and reset the global slice to let the GC prune the
+	// queue.
+	currentTail := d.currentTail() // take the current tail make this local any
+	// Changes after this call are applied later
+	// and not relevant here
+	if callerSlice != nil {
+		// Update the callers slices so we are on the same page
+		callerSlice.sliceTail = currentTail
+	}
+	return d.freezeGlobalBufferLocked(currentTail)
+}
+
+// maybeFreezeGlobalBuffer
+// Freezes the global deletes not frozen yet, returns nil if there are none.
+func (d *DocumentsWriterDeleteQueue) maybeFreezeGlobalBuffer() (*FrozenBufferedUpdates, error) {
+	d.globalBufferLock.Lock()
+	defer d.globalBufferLock.Unlock()
+
+	return d.freezeGlobalBufferLocked(d.currentTail())
 }
 
-func (d *DocumentsWriterDeleteQueue) Close() {
+func (d *DocumentsWriterDeleteQueue) freezeGlobalBufferLocked(currentTail *Node) (*FrozenBufferedUpdates, error) {
+	if d.globalSlice.sliceTail != currentTail {
+		d.globalSlice.sliceTail = currentTail
+		if err := d.globalSlice.Apply(d.globalBufferedUpdates, math.MaxInt32); err != nil {
+			return nil, err
+		}
+	}
 
+	if d.globalBufferedUpdates.Any() {
+		packet := NewFrozenBufferedUpdates(d.globalBufferedUpdates, nil)
+		d.globalBufferedUpdates.Clear()
+		return packet, nil
+	}
+	return nil, nil
 }
 
 func (d *DocumentsWriterDeleteQueue) isOpen() bool {
@@ -219,3 +302,13 @@ func deleteQueueNewNodeDocValuesUpdates(updates []index.DocValuesUpdate) *Node {
 	node := NewDocValuesUpdatesNode(updates)
 	return NewNode(updates, node)
 }
+
+func deleteQueueNewNodeTerms(terms []index.Term) *Node {
+	node := NewTermArrayNode(terms)
+	return NewNode(terms, node)
+}
+
+func deleteQueueNewNodeQueries(queries []index.Query) *Node {
+	node := NewQueryArrayNode(queries)
+	return NewNode(queries, node)
+}
@@ -2,6 +2,7 @@ package index
 
 import (
 	"slices"
+	"sync"
 	"sync/atomic"
 )
 
@@ -14,6 +15,9 @@ import (
 // Flush pending iff a DocumentsWriterPerThread exceeds the IndexWriterConfig.getRAMPerThreadHardLimitMB()
 // to prevent address space exhaustion.
 type DocumentsWriterFlushControl struct {
+	// guards perThread, fullFlush and the queues of the DWPTs checked out for flush
+	mu sync.Mutex
+
 	hardMaxBytesPerDWPT int64
 	activeBytes         int64
 	flushBytes          int64
@@ -48,32 +52,70 @@ type DocumentsWriterFlushControl struct {
 	closed          bool
 	documentsWriter *DocumentsWriter
 	config          *LiveIndexWriterConfig
-}
 
-//
-//func NewDocumentsWriterFlushControl(documentsWriter *DocumentsWriter,
-//	config *liveIndexWriterConfig) *DocumentsWriterFlushControl {
-//
-//	return &DocumentsWriterFlushControl{
-//		perThread: NewDocumentsWriterPerThread(),
-//	}
-//
-//}
+	// creates the DWPT receiving the next documents, the previous one was checked out for flush
+	newPerThread func() *DocumentsWriterPerThread
+}
 
+// ObtainAndLock
+// Returns the DWPT receiving the added documents, locked: the caller adds its documents and
+// unlocks it. The DWPT is created with a new segment name once the previous one was checked
+// out for flush.
 func (d *DocumentsWriterFlushControl) ObtainAndLock() *DocumentsWriterPerThread {
-	return d.perThread
+	for {
+		d.mu.Lock()
+		if d.perThread == nil {
+			d.perThread = d.newPerThread()
+		}
+		perThread := d.perThread
+		d.mu.Unlock()
+
+		perThread.lock.Lock()
+		d.mu.Lock()
+		current := d.perThread == perThread
+		d.mu.Unlock()
+		if current {
+			return perThread
+		}
+		// the DWPT was checked out for flush while we waited for its lock
+		perThread.lock.Unlock()
+	}
 }
 
 func (d *DocumentsWriterFlushControl) DoAfterFlush(dwpt *DocumentsWriterPerThread) error {
-	return nil
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.doAfterFlush(dwpt)
 }
 
+// NextPendingFlush
+// Returns the next DWPT to flush, or nil if no flush is pending.
 func (d *DocumentsWriterFlushControl) NextPendingFlush() *DocumentsWriterPerThread {
-	return nil
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.flushQueue) == 0 {
+		return nil
+	}
+	dwpt := d.flushQueue[0]
+	d.flushQueue = d.flushQueue[1:]
+	return dwpt
 }
 
+// MarkForFullFlush
+// Checks out the DWPT holding documents for flush and returns the sequence number of the
+// last operation included in the full flush.
 func (d *DocumentsWriterFlushControl) MarkForFullFlush() int64 {
-	return 0
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.fullFlush = true
+
+	if d.perThread != nil && d.perThread.GetNumDocsInRAM() > 0 {
+		d.flushQueue = append(d.flushQueue, d.perThread)
+		d.addFlushingDWPT(d.perThread)
+		d.perThread = nil
+	}
+	d.fullFlushMarkDone = true
+	return d.documentsWriter.deleteQueue.getLastSequenceNumber()
 }
 
 // Prunes the blockedQueue by removing all DWPTs that are associated with the given flush queue.
@@ -92,14 +134,16 @@ func (d *DocumentsWriterFlushControl) pruneBlockedQueue(flushingQueue *Documents
 }
 
 func (d *DocumentsWriterFlushControl) finishFullFlush() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	if len(d.blockedFlushes) > 0 {
 		err := d.pruneBlockedQueue(d.documentsWriter.deleteQueue)
 		if err != nil {
 			return err
 		}
-		d.fullFlushMarkDone = false
-		d.fullFlush = false
 	}
+	d.fullFlushMarkDone = false
+	d.fullFlush = false
 	return nil
 }
 
@@ -108,6 +152,8 @@ func (d *DocumentsWriterFlushControl) addFlushingDWPT(perThread *DocumentsWriter
 }
 
 func (d *DocumentsWriterFlushControl) abortFullFlushes() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	if err := d.abortPendingFlushes(); err != nil {
 		return err
 	}
@@ -141,19 +187,21 @@ func (d *DocumentsWriterFlushControl) abortPendingFlushes() error {
 		}
 	}
 
-	clear(d.flushQueue)
-	clear(d.blockedFlushes)
+	d.flushQueue = d.flushQueue[:0]
+	d.blockedFlushes = d.blockedFlushes[:0]
 	return nil
 }
 
 func (d *DocumentsWriterFlushControl) doAfterFlush(dwpt *DocumentsWriterPerThread) error {
-	slices.DeleteFunc(d.flushingWriters, func(thread *DocumentsWriterPerThread) bool {
+	d.flushingWriters = slices.DeleteFunc(d.flushingWriters, func(thread *DocumentsWriterPerThread) bool {
 		return thread == dwpt
 	})
 	return nil
 }
 
 func (d *DocumentsWriterFlushControl) isFullFlush() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	return d.fullFlush
 }
 
@@ -11,17 +11,52 @@ import (
 // lucene.internal
 type DocumentsWriterFlushQueue struct {
 	purgeLock   sync.Mutex
+	queueLock   sync.Mutex // guards queue
 	queue       linked.List[*FlushTicket]
 	ticketCount *atomic.Int32
 }
 
+func NewDocumentsWriterFlushQueue() *DocumentsWriterFlushQueue {
+	return &DocumentsWriterFlushQueue{
+		ticketCount: new(atomic.Int32),
+	}
+}
+
+// AddDeletes
+// Adds a ticket holding the frozen global updates of the delete queue, returns false if
+// there were no updates to freeze.
+func (q *DocumentsWriterFlushQueue) AddDeletes(deleteQueue *DocumentsWriterDeleteQueue) (bool, error) {
+	q.incTickets() // first inc the ticket count - freeze opens a window for #anyChanges to fail
+
+	frozenUpdates, err := deleteQueue.maybeFreezeGlobalBuffer()
+	if err != nil {
+		q.decTickets()
+		return false, err
+	}
+	if frozenUpdates == nil {
+		// no need to publish anything if we don't have any frozen updates
+		q.decTickets()
+		return false, nil
+	}
+
+	q.queueLock.Lock()
+	defer q.queueLock.Unlock()
+	q.queue.Add(NewFlushTicket(frozenUpdates, false))
+	return true, nil
+}
+
+func (q *DocumentsWriterFlushQueue) incTickets() {
+	q.ticketCount.Add(1)
+}
+
 func (q *DocumentsWriterFlushQueue) hasTickets() bool {
-	// TODO: fix it
-	// return ticketCount.get() != 0
-	return false
+	return q.ticketCount.Load() != 0
 }
 
 func (q *DocumentsWriterFlushQueue) AddFlushTicket(dwpt *DocumentsWriterPerThread) (*FlushTicket, error) {
+	q.queueLock.Lock()
+	defer q.queueLock.Unlock()
+
 	// Each flush is assigned a ticket in the order they acquire the ticketQueue lock
 	q.ticketCount.Add(1)
 
@@ -51,6 +86,11 @@ func (q *DocumentsWriterFlushQueue) AddSegment(ticket *FlushTicket, segment *Flu
 	ticket.setSegment(segment)
 }
 
+func (q *DocumentsWriterFlushQueue) markTicketFailed(ticket *FlushTicket) {
+	// to free the queue we mark tickets as failed just to clean up the queue.
+	ticket.setFailed()
+}
+
 func (q *DocumentsWriterFlushQueue) forcePurge(consumer func(*FlushTicket) error) error {
 	q.purgeLock.Lock()
 	defer q.purgeLock.Unlock()
@@ -69,29 +109,36 @@ func (q *DocumentsWriterFlushQueue) tryPurge(consumer func(*FlushTicket) error)
 
 func (q *DocumentsWriterFlushQueue) innerPurge(consumer func(ticket *FlushTicket) error) error {
 	for {
-		head, ok := q.queue.Get(0)
-		if !ok {
+		head := q.pollPublishable()
+		if head == nil {
 			break
 		}
-		canPublish := head != nil && head.canPublish() // do this synced
 
-		if canPublish {
-			err := consumer(head)
-			if err != nil {
-				return err
-			}
+		err := consumer(head)
+		q.decTickets()
+		if err != nil {
+			return err
 		}
-		q.queue.Remove(0)
 	}
 	return nil
 }
 
-func (q *DocumentsWriterFlushQueue) decTickets() {
-	q.ticketCount.Add(-1)
+// Removes and returns the head ticket if it can be published, the tickets are published in
+// order: a ticket whose segment is still flushing holds back the ones behind it.
+func (q *DocumentsWriterFlushQueue) pollPublishable() *FlushTicket {
+	q.queueLock.Lock()
+	defer q.queueLock.Unlock()
+
+	head, ok := q.queue.Get(0)
+	if !ok || !head.canPublish() {
+		return nil
+	}
+	q.queue.Remove(0)
+	return head
 }
 
-func (q *DocumentsWriterFlushQueue) AddDeletes(queue *DocumentsWriterDeleteQueue) (bool, error) {
-	panic("")
+func (q *DocumentsWriterFlushQueue) decTickets() {
+	q.ticketCount.Add(-1)
 }
 
 type FlushTicket struct {
@@ -112,6 +159,8 @@ func NewFlushTicket(frozenUpdates *FrozenBufferedUpdates, hasSegment bool) *Flus
 }
 
 func (t *FlushTicket) canPublish() bool {
+	t.Lock()
+	defer t.Unlock()
 	return t.hasSegment == false || t.segment != nil || t.failed
 }
 
@@ -122,16 +171,22 @@ func (t *FlushTicket) markPublished() {
 }
 
 func (t *FlushTicket) setSegment(segment *FlushedSegment) {
+	t.Lock()
+	defer t.Unlock()
 	t.segment = segment
 }
 
 func (t *FlushTicket) setFailed() {
+	t.Lock()
+	defer t.Unlock()
 	t.failed = true
 }
 
 // Returns the flushed segment or null if this flush ticket doesn't have a segment.
 // This can be the case if this ticket represents a flushed global frozen updates package.
 func (t *FlushTicket) getFlushedSegment() *FlushedSegment {
+	t.Lock()
+	defer t.Unlock()
 	return t.segment
 }
 
@@ -13,7 +13,6 @@ import (
 	"github.com/geange/lucene-go/core/types"
 	"github.com/geange/lucene-go/core/util"
 	"github.com/geange/lucene-go/core/util/version"
-	"github.com/google/uuid"
 )
 
 const (
@@ -54,13 +53,15 @@ func NewDocumentsWriterPerThread(indexVersionCreated int, segmentName string, di
 		false, codec, map[string]string{}, util.RandomId(),
 		map[string]string{}, indexWriterConfig.GetIndexSort())
 
+	// the files written by the indexing chain are the files of the segment
+	directory := store.NewTrackingDirectoryWrapper(dir)
 	consumer := indexWriterConfig.GetIndexingChain().
-		GetChain(indexVersionCreated, segmentInfo, dir, fieldInfos, indexWriterConfig)
+		GetChain(indexVersionCreated, segmentInfo, directory, fieldInfos, indexWriterConfig)
 
 	return &DocumentsWriterPerThread{
 		lock:                   sync.RWMutex{},
 		codec:                  codec,
-		directory:              store.NewTrackingDirectoryWrapper(dir),
+		directory:              directory,
 		consumer:               consumer,
 		pendingUpdates:         index.NewBufferedUpdates(index.WithSegmentName(segmentName)),
 		segmentInfo:            segmentInfo,
@@ -72,7 +73,7 @@ func NewDocumentsWriterPerThread(indexVersionCreated int, segmentName string, di
 		numDocsInRAM:           new(atomic.Int64),
 		deleteQueue:            deleteQueue,
 		deleteSlice:            deleteQueue.newSlice(),
-		pendingNumDocs:         new(atomic.Int64),
+		pendingNumDocs:         pendingNumDocs,
 		indexWriterConfig:      indexWriterConfig,
 		enableTestPoints:       false,
 		deleteDocIDs:           make([]int, 0),
@@ -276,7 +277,7 @@ func (d *DocumentsWriterPerThread) flush(ctx context.Context, flushNotifications
 	d.pendingUpdates.ClearDeleteTerms()
 	d.segmentInfo.SetFiles(d.directory.(*store.TrackingDirectoryWrapper).GetCreatedFiles())
 
-	segmentInfoPerCommit := index.NewSegmentCommitInfo(d.segmentInfo, 0, flushState.SoftDelCountOnFlush, -1, -1, -1, []byte(uuid.New().String()))
+	segmentInfoPerCommit := index.NewSegmentCommitInfo(d.segmentInfo, 0, flushState.SoftDelCountOnFlush, -1, -1, -1, util.RandomId())
 
 	var segmentDeletes *index.BufferedUpdates
 	if d.pendingUpdates.GetDeleteQueries().Size() == 0 && d.pendingUpdates.GetNumFieldUpdates() == 0 {
@@ -378,7 +379,10 @@ func (d *DocumentsWriterPerThread) GetSegmentInfo() *SegmentInfo {
 }
 
 func (d *DocumentsWriterPerThread) prepareFlush() (*FrozenBufferedUpdates, error) {
-	globalUpdates := d.deleteQueue.freezeGlobalBuffer(d.deleteSlice)
+	globalUpdates, err := d.deleteQueue.freezeGlobalBuffer(d.deleteSlice)
+	if err != nil {
+		return nil, err
+	}
 	// deleteSlice can possibly be null if we have hit non-aborting exceptions during indexing and
 	// never succeeded adding a document.
 	if d.deleteSlice != nil {
@@ -10,6 +10,8 @@ import (
 type DocValues struct {
 }
 
+// GetNumeric
+// Returns the NumericDocValues of the field, nil if the reader has none.
 func GetNumeric(reader index.LeafReader, field string) (index.NumericDocValues, error) {
 	dv, err := reader.GetNumericDocValues(field)
 	if err != nil {
@@ -18,16 +20,14 @@ func GetNumeric(reader index.LeafReader, field string) (index.NumericDocValues,
 	return dv, nil
 }
 
+// GetSorted
+// Returns the SortedDocValues of the field, nil if the reader has none.
 func GetSorted(reader index.LeafReader, field string) (index.SortedDocValues, error) {
 	dv, err := reader.GetSortedDocValues(field)
 	if err != nil {
 		return nil, err
 	}
-	if dv != nil {
-		return dv, nil
-	}
-
-	panic("")
+	return dv, nil
 }
 
 var _ sort.Interface = &DocValueSorter{}
@@ -2,13 +2,13 @@ package index
 
 import (
 	"errors"
-	"fmt"
-	"github.com/geange/lucene-go/core/interface/index"
-	"github.com/geange/lucene-go/core/types"
+	"io"
 	"math"
+	"sort"
 
 	"github.com/geange/lucene-go/core/document"
-	"github.com/geange/lucene-go/core/util/packed"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/types"
 )
 
 const (
@@ -21,6 +21,15 @@ const (
 // holds updates of a single docvalues field, for a set of documents within one segment.
 type DocValuesFieldUpdates interface {
 	Field() string
+
+	// Type
+	// Returns the DocValuesType of the updated field.
+	Type() document.DocValuesType
+
+	// DelGen
+	// Returns the delGen of the packet the updates were resolved from.
+	DelGen() int64
+
 	AddInt64(doc int, value int64) error
 	AddBytes(doc int, value []byte) error
 
@@ -49,133 +58,113 @@ type DocValuesFieldUpdates interface {
 	// Params: doc – the doc to update
 	Reset(doc int) error
 
-	Swap(i, j int) error
-
-	Grow(i int) error
-
-	Resize(i int) error
-
 	EnsureFinished() error
 	GetFinished() bool
 }
 
+// BaseDocValuesFieldUpdates
+// Holds the documents of the updates, the implementations hold the values in parallel slices
+// and swap them with the documents when the updates are sorted.
 type BaseDocValuesFieldUpdates struct {
-	field        string
-	_type        document.DocValuesType
-	delGen       int64
-	bitsPerValue int
-	finished     bool
-	maxDoc       int
-	docs         *packed.FixSizePagedMutable
-	size         int
+	field    string
+	_type    document.DocValuesType
+	delGen   int64
+	finished bool
+	maxDoc   int
+	// doc << SHIFT | hasValueMask of each update
+	docs []int64
+	// swaps the values of the updates i and j
+	swapValues func(i, j int)
+}
+
+func newBaseDocValuesFieldUpdates(maxDoc int, delGen int64, field string,
+	dvType document.DocValuesType, swapValues func(i, j int)) *BaseDocValuesFieldUpdates {
+
+	return &BaseDocValuesFieldUpdates{
+		field:      field,
+		_type:      dvType,
+		delGen:     delGen,
+		maxDoc:     maxDoc,
+		swapValues: swapValues,
+	}
 }
 
 func (d *BaseDocValuesFieldUpdates) Field() string {
 	return d.field
 }
 
+func (d *BaseDocValuesFieldUpdates) Type() document.DocValuesType {
+	return d._type
+}
+
+func (d *BaseDocValuesFieldUpdates) DelGen() int64 {
+	return d.delGen
+}
+
 func (d *BaseDocValuesFieldUpdates) Finish() error {
 	if d.finished {
 		return errors.New("already finished")
 	}
 	d.finished = true
-	// shrink wrap
-	if d.size < d.docs.Size() {
-		if err := d.Resize(d.size); err != nil {
-			return err
-		}
-	}
-	if d.size > 0 {
-		// We need a stable sort but InPlaceMergeSorter performs lots of swaps
-		// which hurts performance due to all the packed ints we are using.
-		// Another option would be TimSorter, but it needs additional API (copy to
-		// temp storage, compare with item in temp storage, etc.) so we instead
-		// use quicksort and record ords of each update to guarantee stability.
-		bitsRequired, err := packed.BitsRequired(int64(d.size - 1))
-		if err != nil {
-			return err
-		}
-
-		ords := packed.DefaultGetMutable(d.size, bitsRequired, packed.DEFAULT)
-		for i := 0; i < d.size; i++ {
-			ords.Set(i, uint64(i))
-		}
-
-	}
-
+	// a stable sort keeps the updates of a document in the order they were added,
+	// the iterator returns the last one
+	sort.Stable(&docValuesFieldUpdatesSorter{d})
 	return nil
 }
 
-// Any Returns true if this instance contains any updates.
-func (d *BaseDocValuesFieldUpdates) Any() bool {
-	return d.size > 0
+type docValuesFieldUpdatesSorter struct {
+	*BaseDocValuesFieldUpdates
 }
 
-func (d *BaseDocValuesFieldUpdates) Size() int {
-	return d.size
+func (s *docValuesFieldUpdatesSorter) Len() int {
+	return len(s.docs)
 }
 
-func (d *BaseDocValuesFieldUpdates) Swap(i, j int) error {
-	doc1, err := d.docs.Get(j)
-	if err != nil {
-		return err
-	}
-	doc2, err := d.docs.Get(i)
-	if err != nil {
-		return err
-	}
-	d.docs.Set(j, doc2)
-	d.docs.Set(i, doc1)
-	return nil
+func (s *docValuesFieldUpdatesSorter) Less(i, j int) bool {
+	return s.docs[i]>>SHIFT < s.docs[j]>>SHIFT
 }
 
-func (d *BaseDocValuesFieldUpdates) Grow(size int) error {
-	d.docs = d.docs.Grow(size).(*packed.FixSizePagedMutable)
-	return nil
+func (s *docValuesFieldUpdatesSorter) Swap(i, j int) {
+	s.docs[i], s.docs[j] = s.docs[j], s.docs[i]
+	s.swapValues(i, j)
 }
 
-func (d *BaseDocValuesFieldUpdates) Resize(size int) error {
-	docs, ok := d.docs.Resize(size).(*packed.FixSizePagedMutable)
-	if !ok {
-		return fmt.Errorf("type is not *packed.FixSizePagedMutable")
-	}
-	d.docs = docs
-	return nil
+// Any Returns true if this instance contains any updates.
+func (d *BaseDocValuesFieldUpdates) Any() bool {
+	return len(d.docs) > 0
+}
+
+func (d *BaseDocValuesFieldUpdates) Size() int {
+	return len(d.docs)
 }
 
 func (d *BaseDocValuesFieldUpdates) GetFinished() bool {
 	return d.finished
 }
 
-func (b *BinaryDocValuesFieldUpdates) add(doc int) (int, error) {
-	return b.addInternal(doc, HAS_VALUE_MASK)
+func (d *BaseDocValuesFieldUpdates) EnsureFinished() error {
+	if !d.finished {
+		return d.Finish()
+	}
+	return nil
 }
 
-func (b *BinaryDocValuesFieldUpdates) addInternal(doc int, hasValueMask int64) (int, error) {
-	if b.finished {
+// Adds the document of an update, returns the index of the update
+func (d *BaseDocValuesFieldUpdates) addInternal(doc int, hasValueMask int64) (int, error) {
+	if d.finished {
 		return 0, errors.New("already finished")
 	}
 
-	if doc >= b.maxDoc {
+	if doc >= d.maxDoc {
 		return 0, errors.New("doc too big")
 	}
 
-	// TODO: if the Sorter interface changes to take long indexes, we can remove that limitation
-	if b.size == math.MaxInt32 {
+	if len(d.docs) == math.MaxInt32 {
 		return 0, errors.New("cannot support more than Integer.MAX_VALUE doc/item entries")
 	}
 
-	// grow the structures to have room for more elements
-	if b.docs.Size() == b.size {
-		if err := b.Grow(b.size + 1); err != nil {
-			return 0, err
-		}
-	}
-
-	value := (int64(doc) << SHIFT) | hasValueMask
-	b.docs.Set(b.size, uint64(value))
-	b.size++
-	return b.size - 1, nil
+	d.docs = append(d.docs, (int64(doc)<<SHIFT)|hasValueMask)
+	return len(d.docs) - 1, nil
 }
 
 // DocValuesFieldUpdatesIterator
@@ -216,6 +205,161 @@ func (*DVFUIterator) Cost() int64 {
 	return 0
 }
 
+// abstractIterator
+// Iterates the sorted updates of a DocValuesFieldUpdates, the last update of a document wins.
+type abstractIterator struct {
+	DVFUIterator
+
+	docs     []int64
+	delGen   int64
+	idx      int
+	doc      int
+	hasValue bool
+
+	// loads the value of the update idx
+	set func(idx int)
+}
+
+func newAbstractIterator(docs []int64, delGen int64, set func(idx int)) *abstractIterator {
+	return &abstractIterator{
+		docs:   docs,
+		delGen: delGen,
+		doc:    -1,
+		set:    set,
+	}
+}
+
+func (a *abstractIterator) NextDoc() (int, error) {
+	if a.idx >= len(a.docs) {
+		a.doc = types.NO_MORE_DOCS
+		return a.doc, io.EOF
+	}
+
+	longDoc := a.docs[a.idx]
+	a.idx++
+	for ; a.idx < len(a.docs); a.idx++ {
+		// scan forward to last update to this doc
+		nextLongDoc := a.docs[a.idx]
+		if longDoc>>SHIFT != nextLongDoc>>SHIFT {
+			break
+		}
+		longDoc = nextLongDoc
+	}
+	a.hasValue = longDoc&HAS_VALUE_MASK > 0
+	if a.hasValue {
+		a.set(a.idx - 1)
+	}
+	a.doc = int(longDoc >> SHIFT)
+	return a.doc, nil
+}
+
+func (a *abstractIterator) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(a, target)
+}
+
+func (a *abstractIterator) DocID() int {
+	return a.doc
+}
+
+func (a *abstractIterator) DelGen() int64 {
+	return a.delGen
+}
+
+func (a *abstractIterator) HasValue() bool {
+	return a.hasValue
+}
+
+// MergedDocValuesFieldUpdatesIterator
+// Merge-sorts multiple iterators, one per delGen, favoring the largest delGen that has updates for
+// a given docID. Returns nil if none of the iterators has an update.
+func MergedDocValuesFieldUpdatesIterator(subs []DocValuesFieldUpdatesIterator) (DocValuesFieldUpdatesIterator, error) {
+	if len(subs) == 1 {
+		return subs[0], nil
+	}
+
+	positioned := make([]DocValuesFieldUpdatesIterator, 0, len(subs))
+	for _, sub := range subs {
+		if _, err := sub.NextDoc(); err != nil {
+			if errors.Is(err, io.EOF) {
+				continue
+			}
+			return nil, err
+		}
+		positioned = append(positioned, sub)
+	}
+	if len(positioned) == 0 {
+		return nil, nil
+	}
+	return &mergedIterator{subs: positioned, doc: -1}, nil
+}
+
+type mergedIterator struct {
+	DVFUIterator
+
+	// the iterators which are not exhausted
+	subs []DocValuesFieldUpdatesIterator
+	// the iterator holding the value of the current document
+	top DocValuesFieldUpdatesIterator
+	doc int
+}
+
+func (m *mergedIterator) NextDoc() (int, error) {
+	// advance all sub iterators past current doc
+	subs := m.subs[:0]
+	for _, sub := range m.subs {
+		if sub.DocID() == m.doc {
+			if _, err := sub.NextDoc(); err != nil {
+				if errors.Is(err, io.EOF) {
+					continue
+				}
+				return 0, err
+			}
+		}
+		subs = append(subs, sub)
+	}
+	m.subs = subs
+
+	if len(m.subs) == 0 {
+		m.top = nil
+		m.doc = types.NO_MORE_DOCS
+		return m.doc, io.EOF
+	}
+
+	m.top = m.subs[0]
+	for _, sub := range m.subs[1:] {
+		// when two iterators have same docID, the newest one wins
+		if sub.DocID() < m.top.DocID() || (sub.DocID() == m.top.DocID() && sub.DelGen() > m.top.DelGen()) {
+			m.top = sub
+		}
+	}
+	m.doc = m.top.DocID()
+	return m.doc, nil
+}
+
+func (m *mergedIterator) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(m, target)
+}
+
+func (m *mergedIterator) DocID() int {
+	return m.doc
+}
+
+func (m *mergedIterator) LongValue() (int64, error) {
+	return m.top.LongValue()
+}
+
+func (m *mergedIterator) BinaryValue() ([]byte, error) {
+	return m.top.BinaryValue()
+}
+
+func (m *mergedIterator) DelGen() int64 {
+	return -1
+}
+
+func (m *mergedIterator) HasValue() bool {
+	return m.top.HasValue()
+}
+
 func AsBinaryDocValues(iterator DocValuesFieldUpdatesIterator) index.BinaryDocValues {
 	return &BaseBinaryDocValues{
 		FnDocID:        iterator.DocID,
@@ -239,6 +383,3 @@ func AsNumericDocValues(iterator DocValuesFieldUpdatesIterator) index.NumericDoc
 		FnLongValue:    iterator.LongValue,
 	}
 }
-
-type SingleValueDocValuesFieldUpdates struct {
-}
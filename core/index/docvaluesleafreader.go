@@ -86,6 +86,14 @@ func (d *DocValuesLeafReader) GetPointValues(field string) (types.PointValues, b
 	return nil, false
 }
 
+func (d *DocValuesLeafReader) GetVectorValues(field string) (index.VectorValues, error) {
+	return nil, errors.New("func GetVectorValues is not yet implemented")
+}
+
+func (d *DocValuesLeafReader) SearchNearestVectors(ctx context.Context, field string, target []float32, k int, acceptDocs util.Bits) (index.TopDocs, error) {
+	return nil, errors.New("func SearchNearestVectors is not yet implemented")
+}
+
 func (d *DocValuesLeafReader) CheckIntegrity() error {
 	return errors.New("func CheckIntegrity is not yet implemented")
 }
@@ -21,6 +21,7 @@ type fieldInfos struct {
 	hasNorms         bool
 	hasDocValues     bool
 	hasPointValues   bool
+	hasVectorValues  bool
 	softDeletesField string
 
 	// used only by fieldInfo(int)
@@ -39,6 +40,7 @@ func NewFieldInfos(infos []*document.FieldInfo) index.FieldInfos {
 	hasNorms := false
 	hasDocValues := false
 	hasPointValues := false
+	hasVectorValues := false
 	softDeletesField := ""
 
 	tmap := treeset.NewWith[*document.FieldInfo](func(info1, info2 *document.FieldInfo) int {
@@ -88,6 +90,7 @@ func NewFieldInfos(infos []*document.FieldInfo) index.FieldInfos {
 		hasDocValues = hasDocValues || info.GetDocValuesType() != document.DOC_VALUES_TYPE_NONE
 		hasPayloads = hasPayloads || info.HasPayloads()
 		hasPointValues = hasPointValues || info.GetPointDimensionCount() != 0
+		hasVectorValues = hasVectorValues || info.GetVectorDimension() != 0
 
 		if info.IsSoftDeletesField() {
 			if softDeletesField == info.Name() {
@@ -105,6 +108,7 @@ func NewFieldInfos(infos []*document.FieldInfo) index.FieldInfos {
 	this.hasNorms = hasNorms
 	this.hasDocValues = hasDocValues
 	this.hasPointValues = hasPointValues
+	this.hasVectorValues = hasVectorValues
 	this.softDeletesField = softDeletesField
 
 	values := tmap.Values()
@@ -112,7 +116,12 @@ func NewFieldInfos(infos []*document.FieldInfo) index.FieldInfos {
 	for _, value := range values {
 		items = append(items, value)
 	}
-	this.byNumber = items
+	// the field numbers can be sparse, e.g. the FieldInfos of a doc values update only hold the updated field
+	byNumber := make([]*document.FieldInfo, maxNum+1)
+	for _, item := range items {
+		byNumber[item.Number()] = item
+	}
+	this.byNumber = byNumber
 	this.fieldInfos = items
 
 	return this
@@ -122,7 +131,11 @@ func (f *fieldInfos) FieldInfo(fieldName string) *document.FieldInfo {
 	return f.byName[fieldName]
 }
 
+// FieldInfoByNumber Return the fieldinfo object referenced by the fieldNumber, nil if the field does not exist
 func (f *fieldInfos) FieldInfoByNumber(fieldNumber int) *document.FieldInfo {
+	if fieldNumber < 0 || fieldNumber >= len(f.byNumber) {
+		return nil
+	}
 	return f.byNumber[fieldNumber]
 }
 
@@ -150,6 +163,10 @@ func (f *fieldInfos) HasPointValues() bool {
 	return f.hasPointValues
 }
 
+func (f *fieldInfos) HasVectorValues() bool {
+	return f.hasVectorValues
+}
+
 type FieldInfosBuilder struct {
 	byName             map[string]*document.FieldInfo
 	globalFieldNumbers *FieldNumbers
@@ -217,12 +234,27 @@ func (f *FieldInfosBuilder) AddFieldInfo(fi *document.FieldInfo) (*document.Fiel
 
 func (f *FieldInfosBuilder) AddFieldInfoV(fi *document.FieldInfo, dvGen int64) (*document.FieldInfo, error) {
 	// IMPORTANT - reuse the field number if possible for consistent field numbers across segments
-	return f.addOrUpdateInternal(fi.Name(), fi.Number(), fi.HasVectors(),
+	added, err := f.addOrUpdateInternal(fi.Name(), fi.Number(), fi.HasVectors(),
 		fi.OmitsNorms(), fi.HasPayloads(),
 		fi.GetIndexOptions(), fi.GetDocValuesType(), dvGen,
 		fi.Attributes(),
 		fi.GetPointDimensionCount(), fi.GetPointIndexDimensionCount(), fi.GetPointNumBytes(),
 		fi.IsSoftDeletesField())
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.HasVectorValues() {
+		if err := f.globalFieldNumbers.SetVectorDimensionsAndSimilarityFunction(added.Number(), added.Name(),
+			fi.GetVectorDimension(), fi.GetVectorSimilarityFunction()); err != nil {
+			return nil, err
+		}
+		if err := added.SetVectorDimensionAndSimilarityFunction(
+			fi.GetVectorDimension(), fi.GetVectorSimilarityFunction()); err != nil {
+			return nil, err
+		}
+	}
+	return added, nil
 }
 
 func (f *FieldInfosBuilder) addOrUpdateInternal(name string, preferredFieldNumber int,
@@ -322,6 +354,8 @@ type FieldNumbers struct {
 
 	dimensions map[string]*FieldDimensions
 
+	vectorProps map[string]*FieldVectorProperties
+
 	// TODO: we should similarly catch an attempt to turn
 	// norms back on after they were already committed; today
 	// we silently discard the norm but this is badly trappy
@@ -337,6 +371,7 @@ func NewFieldNumbers(softDeletesFieldName string) *FieldNumbers {
 		indexOptions:                map[string]document.IndexOptions{},
 		docValuesType:               map[string]document.DocValuesType{},
 		dimensions:                  map[string]*FieldDimensions{},
+		vectorProps:                 map[string]*FieldVectorProperties{},
 		lowestUnassignedFieldNumber: -1,
 		softDeletesFieldName:        softDeletesFieldName,
 	}
@@ -364,7 +399,7 @@ func (f *FieldNumbers) AddOrGet(fieldName string, preferredFieldNumber int,
 
 	if dvType != document.DOC_VALUES_TYPE_NONE {
 		currentDVType, ok := f.docValuesType[fieldName]
-		if ok {
+		if !ok {
 			f.docValuesType[fieldName] = dvType
 		} else if currentDVType != document.DOC_VALUES_TYPE_NONE && currentDVType != dvType {
 			return 0, fmt.Errorf(
@@ -466,6 +501,14 @@ func (f *FieldNumbers) verifyConsistentIndexOptions(number int, name string, ind
 }
 
 func (f *FieldNumbers) verifyConsistentDocValuesType(number int, name string, dvType document.DocValuesType) error {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.verifyConsistentDocValuesTypeLocked(number, name, dvType)
+}
+
+// f must be locked
+func (f *FieldNumbers) verifyConsistentDocValuesTypeLocked(number int, name string, dvType document.DocValuesType) error {
 	if f.numberToName[number] != name {
 		return fmt.Errorf(`field number %d is already mapped to field name "%s" not "%s"`,
 			number, f.numberToName[number], name)
@@ -487,6 +530,9 @@ func (f *FieldNumbers) verifyConsistentDocValuesType(number int, name string, dv
 }
 
 func (f *FieldNumbers) setIndexOptions(number int, name string, indexOptions document.IndexOptions) error {
+	f.Lock()
+	defer f.Unlock()
+
 	if err := f.verifyConsistentIndexOptions(number, name, indexOptions); err != nil {
 		return err
 	}
@@ -495,7 +541,10 @@ func (f *FieldNumbers) setIndexOptions(number int, name string, indexOptions doc
 }
 
 func (f *FieldNumbers) setDocValuesType(number int, name string, dvType document.DocValuesType) error {
-	if err := f.verifyConsistentDocValuesType(number, name, dvType); err != nil {
+	f.Lock()
+	defer f.Unlock()
+
+	if err := f.verifyConsistentDocValuesTypeLocked(number, name, dvType); err != nil {
 		return err
 	}
 	f.docValuesType[name] = dvType
@@ -503,11 +552,40 @@ func (f *FieldNumbers) setDocValuesType(number int, name string, dvType document
 }
 
 func (f *FieldNumbers) SetDimensions(number int, name string, dimensionCount, indexDimensionCount, dimensionNumBytes int) {
+	f.Lock()
+	defer f.Unlock()
+
 	//f.verifyConsistentDimensions(number, name, dimensionCount, indexDimensionCount, dimensionNumBytes);
 	f.dimensions[name] = NewFieldDimensions(dimensionCount, indexDimensionCount, dimensionNumBytes)
 }
 
+// SetVectorDimensionsAndSimilarityFunction Records the vector dimension and similarity function of
+// a field, which must not change across segments / IndexWriter sessions.
+func (f *FieldNumbers) SetVectorDimensionsAndSimilarityFunction(number int, name string,
+	numDimensions int, similarityFunction document.VectorSimilarityFunction) error {
+
+	f.Lock()
+	defer f.Unlock()
+
+	if f.numberToName[number] != name {
+		return fmt.Errorf(`field number %d is already mapped to field name "%s" not "%s"`,
+			number, f.numberToName[number], name)
+	}
+	if props, ok := f.vectorProps[name]; ok {
+		if props.NumDimensions != numDimensions || props.SimilarityFunction != similarityFunction {
+			return fmt.Errorf(`cannot change field "%s" from vector dimension=%d, similarity function=%s to inconsistent vector dimension=%d, similarity function=%s`,
+				name, props.NumDimensions, props.SimilarityFunction, numDimensions, similarityFunction)
+		}
+		return nil
+	}
+	f.vectorProps[name] = NewFieldVectorProperties(numDimensions, similarityFunction)
+	return nil
+}
+
 func (f *FieldNumbers) contains(fieldName string, dvType document.DocValuesType) bool {
+	f.Lock()
+	defer f.Unlock()
+
 	if _, ok := f.nameToNumber[fieldName]; !ok {
 		return false
 	}
@@ -527,3 +605,15 @@ func NewFieldDimensions(dimensionCount, indexDimensionCount, dimensionNumBytes i
 		DimensionNumBytes:   dimensionNumBytes,
 	}
 }
+
+type FieldVectorProperties struct {
+	NumDimensions      int
+	SimilarityFunction document.VectorSimilarityFunction
+}
+
+func NewFieldVectorProperties(numDimensions int, similarityFunction document.VectorSimilarityFunction) *FieldVectorProperties {
+	return &FieldVectorProperties{
+		NumDimensions:      numDimensions,
+		SimilarityFunction: similarityFunction,
+	}
+}
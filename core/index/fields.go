@@ -27,5 +27,5 @@ func (f *BaseFieldsConsumer) Merge(ctx context.Context, mergeState *MergeState,
 // maps around deleted documents, and calls write(Fields, NormsProducer). Implementations can override
 // this method for more sophisticated merging (bulk-byte copying, etc).
 func MergeFromReaders(ctx context.Context, consumer index.FieldsConsumer, mergeState *MergeState, norms index.NormsProducer) error {
-	return nil
+	return consumer.Write(ctx, NewMappedMultiFields(mergeState), norms)
 }
@@ -3,9 +3,14 @@ package index
 import (
 	"bytes"
 	"context"
+	"errors"
+	"io"
+	"math"
+	"slices"
+
 	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/types"
 	"golang.org/x/exp/maps"
-	"io"
 
 	"github.com/geange/lucene-go/core/document"
 	"github.com/geange/lucene-go/core/util/automaton"
@@ -224,11 +229,331 @@ func (f *FreqProxTermsEnum) TotalTermFreq() (int64, error) {
 }
 
 func (f *FreqProxTermsEnum) Postings(reuse index.PostingsEnum, flags int) (index.PostingsEnum, error) {
-	//TODO implement me
-	panic("implement me")
+	termID := f.sortedTermIDs[f.ord]
+
+	if FeatureRequested(flags, POSTINGS_ENUM_POSITIONS) {
+		if !f.terms.hasProx {
+			// Caller wants positions but we didn't index them;
+			// don't lie:
+			return nil, errors.New("did not index positions")
+		}
+
+		if !f.terms.hasOffsets && FeatureRequested(flags, POSTINGS_ENUM_OFFSETS) {
+			// Caller wants offsets but we didn't index them;
+			// don't lie:
+			return nil, errors.New("did not index offsets")
+		}
+
+		posEnum, ok := reuse.(*FreqProxPostingsEnum)
+		if !ok || posEnum.postingsArray != f.postingsArray {
+			posEnum = NewFreqProxPostingsEnum(f.terms, f.postingsArray)
+		}
+		if err := posEnum.reset(termID); err != nil {
+			return nil, err
+		}
+		return posEnum, nil
+	}
+
+	if !f.terms.hasFreq && FeatureRequested(flags, POSTINGS_ENUM_FREQS) {
+		// Caller wants freqs but we didn't index them;
+		// don't lie:
+		return nil, errors.New("did not index freq")
+	}
+
+	docsEnum, ok := reuse.(*FreqProxDocsEnum)
+	if !ok || docsEnum.postingsArray != f.postingsArray {
+		docsEnum = NewFreqProxDocsEnum(f.terms, f.postingsArray)
+	}
+	if err := docsEnum.reset(termID); err != nil {
+		return nil, err
+	}
+	return docsEnum, nil
 }
 
 func (f *FreqProxTermsEnum) Impacts(flags int) (index.ImpactsEnum, error) {
 	//TODO implement me
 	panic("implement me")
 }
+
+var _ index.PostingsEnum = &FreqProxDocsEnum{}
+
+// FreqProxDocsEnum
+// Iterates the docs, and the freqs if they were indexed, buffered for a term. The doc and the freq of
+// the last doc of the term are not written in the slices yet but held by the postings array.
+type FreqProxDocsEnum struct {
+	terms         *FreqProxTermsWriterPerField
+	postingsArray *FreqProxPostingsArray
+	reader        *ByteSliceReader
+	readTermFreq  bool
+	docID         int
+	freq          int
+	ended         bool
+	termID        int
+}
+
+func NewFreqProxDocsEnum(terms *FreqProxTermsWriterPerField, postingsArray *FreqProxPostingsArray) *FreqProxDocsEnum {
+	return &FreqProxDocsEnum{
+		terms:         terms,
+		postingsArray: postingsArray,
+		reader:        NewByteSliceReader(),
+		readTermFreq:  terms.hasFreq,
+		docID:         -1,
+	}
+}
+
+func (f *FreqProxDocsEnum) reset(termID int) error {
+	f.termID = termID
+	if err := f.terms.initReader(f.reader, termID, 0); err != nil {
+		return err
+	}
+	f.ended = false
+	f.docID = -1
+	return nil
+}
+
+func (f *FreqProxDocsEnum) DocID() int {
+	return f.docID
+}
+
+func (f *FreqProxDocsEnum) NextDoc() (int, error) {
+	if f.docID == -1 {
+		f.docID = 0
+	}
+
+	if f.reader.EOF() {
+		if f.ended {
+			f.docID = types.NO_MORE_DOCS
+			return f.docID, nil
+		}
+		f.ended = true
+		f.docID = f.postingsArray.lastDocIDs[f.termID]
+		if f.readTermFreq {
+			f.freq = f.postingsArray.termFreqs[f.termID]
+		}
+		return f.docID, nil
+	}
+
+	code, err := f.reader.ReadUvarint(nil)
+	if err != nil {
+		return 0, err
+	}
+	if !f.readTermFreq {
+		f.docID += int(code)
+		return f.docID, nil
+	}
+
+	f.docID += int(code >> 1)
+	if code&1 != 0 {
+		f.freq = 1
+	} else {
+		freq, err := f.reader.ReadUvarint(nil)
+		if err != nil {
+			return 0, err
+		}
+		f.freq = int(freq)
+	}
+	return f.docID, nil
+}
+
+func (f *FreqProxDocsEnum) Advance(target int) (int, error) {
+	return f.SlowAdvance(target)
+}
+
+func (f *FreqProxDocsEnum) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(f, target)
+}
+
+func (f *FreqProxDocsEnum) Cost() int64 {
+	return math.MaxInt64
+}
+
+func (f *FreqProxDocsEnum) Freq() (int, error) {
+	// Don't lie here ... don't want codecs writings lots
+	// of wasted 1s into the index:
+	if !f.readTermFreq {
+		return 0, errors.New("freq was not indexed")
+	}
+	return f.freq, nil
+}
+
+func (f *FreqProxDocsEnum) NextPosition() (int, error) {
+	return -1, nil
+}
+
+func (f *FreqProxDocsEnum) StartOffset() (int, error) {
+	return -1, nil
+}
+
+func (f *FreqProxDocsEnum) EndOffset() (int, error) {
+	return -1, nil
+}
+
+func (f *FreqProxDocsEnum) GetPayload() ([]byte, error) {
+	return nil, nil
+}
+
+var _ index.PostingsEnum = &FreqProxPostingsEnum{}
+
+// FreqProxPostingsEnum
+// Iterates the docs, freqs, positions, and the offsets and payloads if they were indexed, buffered for a term.
+type FreqProxPostingsEnum struct {
+	terms         *FreqProxTermsWriterPerField
+	postingsArray *FreqProxPostingsArray
+	reader        *ByteSliceReader
+	posReader     *ByteSliceReader
+	readOffsets   bool
+	docID         int
+	freq          int
+	pos           int
+	startOffset   int
+	endOffset     int
+	posLeft       int
+	termID        int
+	ended         bool
+	hasPayload    bool
+	payload       []byte
+}
+
+func NewFreqProxPostingsEnum(terms *FreqProxTermsWriterPerField, postingsArray *FreqProxPostingsArray) *FreqProxPostingsEnum {
+	return &FreqProxPostingsEnum{
+		terms:         terms,
+		postingsArray: postingsArray,
+		reader:        NewByteSliceReader(),
+		posReader:     NewByteSliceReader(),
+		readOffsets:   terms.hasOffsets,
+		docID:         -1,
+	}
+}
+
+func (f *FreqProxPostingsEnum) reset(termID int) error {
+	f.termID = termID
+	if err := f.terms.initReader(f.reader, termID, 0); err != nil {
+		return err
+	}
+	if err := f.terms.initReader(f.posReader, termID, 1); err != nil {
+		return err
+	}
+	f.ended = false
+	f.docID = -1
+	f.posLeft = 0
+	return nil
+}
+
+func (f *FreqProxPostingsEnum) DocID() int {
+	return f.docID
+}
+
+func (f *FreqProxPostingsEnum) NextDoc() (int, error) {
+	if f.docID == -1 {
+		f.docID = 0
+	}
+	for f.posLeft != 0 {
+		if _, err := f.NextPosition(); err != nil {
+			return 0, err
+		}
+	}
+
+	if f.reader.EOF() {
+		if f.ended {
+			f.docID = types.NO_MORE_DOCS
+			return f.docID, nil
+		}
+		f.ended = true
+		f.docID = f.postingsArray.lastDocIDs[f.termID]
+		f.freq = f.postingsArray.termFreqs[f.termID]
+	} else {
+		code, err := f.reader.ReadUvarint(nil)
+		if err != nil {
+			return 0, err
+		}
+		f.docID += int(code >> 1)
+		if code&1 != 0 {
+			f.freq = 1
+		} else {
+			freq, err := f.reader.ReadUvarint(nil)
+			if err != nil {
+				return 0, err
+			}
+			f.freq = int(freq)
+		}
+	}
+
+	f.posLeft = f.freq
+	f.pos = 0
+	f.startOffset = 0
+	return f.docID, nil
+}
+
+func (f *FreqProxPostingsEnum) Advance(target int) (int, error) {
+	return f.SlowAdvance(target)
+}
+
+func (f *FreqProxPostingsEnum) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(f, target)
+}
+
+func (f *FreqProxPostingsEnum) Cost() int64 {
+	return math.MaxInt64
+}
+
+func (f *FreqProxPostingsEnum) Freq() (int, error) {
+	return f.freq, nil
+}
+
+func (f *FreqProxPostingsEnum) NextPosition() (int, error) {
+	f.posLeft--
+	code, err := f.posReader.ReadUvarint(nil)
+	if err != nil {
+		return 0, err
+	}
+	f.pos += int(code >> 1)
+	if code&1 != 0 {
+		// has a payload
+		f.hasPayload = true
+		size, err := f.posReader.ReadUvarint(nil)
+		if err != nil {
+			return 0, err
+		}
+		f.payload = slices.Grow(f.payload[:0], int(size))[:size]
+		if _, err := io.ReadFull(f.posReader, f.payload); err != nil {
+			return 0, err
+		}
+	} else {
+		f.hasPayload = false
+	}
+
+	if f.readOffsets {
+		startOffset, err := f.posReader.ReadUvarint(nil)
+		if err != nil {
+			return 0, err
+		}
+		endOffset, err := f.posReader.ReadUvarint(nil)
+		if err != nil {
+			return 0, err
+		}
+		f.startOffset += int(startOffset)
+		f.endOffset = f.startOffset + int(endOffset)
+	}
+	return f.pos, nil
+}
+
+func (f *FreqProxPostingsEnum) StartOffset() (int, error) {
+	if !f.readOffsets {
+		return 0, errors.New("offsets were not indexed")
+	}
+	return f.startOffset, nil
+}
+
+func (f *FreqProxPostingsEnum) EndOffset() (int, error) {
+	if !f.readOffsets {
+		return 0, errors.New("offsets were not indexed")
+	}
+	return f.endOffset, nil
+}
+
+func (f *FreqProxPostingsEnum) GetPayload() ([]byte, error) {
+	if f.hasPayload {
+		return f.payload, nil
+	}
+	return nil, nil
+}
@@ -1,8 +1,15 @@
 package index
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"slices"
+
+	"github.com/bits-and-blooms/bitset"
 	"github.com/geange/lucene-go/core/document"
 	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/types"
 	"github.com/geange/lucene-go/core/util/bytesref"
 	"github.com/geange/lucene-go/core/util/ints"
 )
@@ -42,11 +49,14 @@ func (f *FreqProxTermsWriter) Flush(fieldsToFlush map[string]TermsHashPerField,
 	// Sort by field name
 	SortFreqProxTermsWriterPerField(allFields)
 
-	fields := NewFreqProxFields(allFields)
+	var fields index.Fields = NewFreqProxFields(allFields)
 	err = f.applyDeletes(state, fields)
 	if err != nil {
 		return err
 	}
+	if sortMap != nil {
+		fields = &sortingFields{Fields: fields, fieldInfos: state.FieldInfos, docMap: sortMap}
+	}
 
 	consumer, err := state.SegmentInfo.GetCodec().PostingsFormat().FieldsConsumer(nil, state)
 	if err != nil {
@@ -68,6 +78,375 @@ func (f *FreqProxTermsWriter) SetTermBytePool(termBytePool *bytesref.BlockPool)
 	f.termBytePool = termBytePool
 }
 
+// Process any pending deletes for this segment, the terms of the deletes are resolved against the
+// postings buffered in RAM before they are written.
 func (f *FreqProxTermsWriter) applyDeletes(state *index.SegmentWriteState, fields index.Fields) error {
+	if state.SegUpdates == nil || state.SegUpdates.GetDeleteTerms().Size() == 0 {
+		return nil
+	}
+
+	maxDoc, err := state.SegmentInfo.MaxDoc()
+	if err != nil {
+		return err
+	}
+
+	// the delete terms are sorted by field and then by term
+	segDeletes := state.SegUpdates.GetDeleteTerms()
+	lastField := ""
+	var termsEnum index.TermsEnum
+	var postingsEnum index.PostingsEnum
+
+	it := segDeletes.Iterator()
+	for it.Next() {
+		deleteTerm, delDocLimit := it.Key(), it.Value()
+		if termsEnum == nil || deleteTerm.Field() != lastField {
+			lastField = deleteTerm.Field()
+			terms, err := fields.Terms(lastField)
+			if err != nil {
+				return err
+			}
+			termsEnum = nil
+			if terms != nil {
+				if termsEnum, err = terms.Iterator(); err != nil {
+					return err
+				}
+			}
+		}
+
+		if termsEnum == nil {
+			continue
+		}
+		found, err := termsEnum.SeekExact(context.Background(), deleteTerm.Bytes())
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+
+		postingsEnum, err = termsEnum.Postings(postingsEnum, 0)
+		if err != nil {
+			return err
+		}
+		for {
+			doc, err := postingsEnum.NextDoc()
+			if err != nil {
+				return err
+			}
+			if doc >= delDocLimit {
+				break
+			}
+
+			if state.LiveDocs == nil {
+				state.LiveDocs = bitset.New(uint(maxDoc))
+				state.LiveDocs.FlipRange(0, uint(maxDoc))
+			}
+			if state.LiveDocs.Test(uint(doc)) {
+				state.DelCountOnFlush++
+				state.LiveDocs.Clear(uint(doc))
+			}
+		}
+	}
 	return nil
 }
+
+var _ index.Fields = &sortingFields{}
+
+// sortingFields exposes the postings buffered for a segment with an index sort in the order of the
+// documents of the sorted segment
+type sortingFields struct {
+	index.Fields
+
+	fieldInfos index.FieldInfos
+	docMap     index.DocMap
+}
+
+func (s *sortingFields) Terms(field string) (index.Terms, error) {
+	terms, err := s.Fields.Terms(field)
+	if err != nil || terms == nil {
+		return nil, err
+	}
+	return &sortingTerms{
+		Terms:        terms,
+		indexOptions: s.fieldInfos.FieldInfo(field).GetIndexOptions(),
+		docMap:       s.docMap,
+	}, nil
+}
+
+var _ index.Terms = &sortingTerms{}
+
+type sortingTerms struct {
+	index.Terms
+
+	indexOptions document.IndexOptions
+	docMap       index.DocMap
+}
+
+func (s *sortingTerms) Iterator() (index.TermsEnum, error) {
+	termsEnum, err := s.Terms.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	return &sortingTermsEnum{
+		TermsEnum:    termsEnum,
+		indexOptions: s.indexOptions,
+		hasPositions: s.Terms.HasPositions(),
+		docMap:       s.docMap,
+	}, nil
+}
+
+var _ index.TermsEnum = &sortingTermsEnum{}
+
+type sortingTermsEnum struct {
+	index.TermsEnum
+
+	indexOptions document.IndexOptions
+	hasPositions bool
+	docMap       index.DocMap
+}
+
+func (s *sortingTermsEnum) Postings(reuse index.PostingsEnum, flags int) (index.PostingsEnum, error) {
+	if s.hasPositions && FeatureRequested(flags, POSTINGS_ENUM_POSITIONS) {
+		in, err := s.TermsEnum.Postings(nil, flags)
+		if err != nil {
+			return nil, err
+		}
+		storeOffsets := s.indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS
+		return newSortingPostingsEnum(in, s.docMap, storeOffsets)
+	}
+
+	in, err := s.TermsEnum.Postings(nil, flags)
+	if err != nil {
+		return nil, err
+	}
+	withFreqs := s.indexOptions >= document.INDEX_OPTIONS_DOCS_AND_FREQS && FeatureRequested(flags, POSTINGS_ENUM_FREQS)
+	return newSortingDocsEnum(in, s.docMap, withFreqs)
+}
+
+var _ index.PostingsEnum = &sortingDocsEnum{}
+
+// sortingDocsEnum reads all the docs, and their freqs, of a term to return them in the order of the
+// documents of the sorted segment
+type sortingDocsEnum struct {
+	docs      []int
+	freqs     []int
+	withFreqs bool
+	upto      int
+	docID     int
+}
+
+func newSortingDocsEnum(in index.PostingsEnum, docMap index.DocMap, withFreqs bool) (*sortingDocsEnum, error) {
+	type docAndFreq struct {
+		doc, freq int
+	}
+
+	postings := make([]docAndFreq, 0)
+	for {
+		doc, err := nextDocOrExhausted(in)
+		if err != nil {
+			return nil, err
+		}
+		if doc == types.NO_MORE_DOCS {
+			break
+		}
+		posting := docAndFreq{doc: docMap.OldToNew(doc), freq: 1}
+		if withFreqs {
+			if posting.freq, err = in.Freq(); err != nil {
+				return nil, err
+			}
+		}
+		postings = append(postings, posting)
+	}
+	slices.SortFunc(postings, func(a, b docAndFreq) int {
+		return a.doc - b.doc
+	})
+
+	docs := make([]int, len(postings))
+	freqs := make([]int, len(postings))
+	for i, posting := range postings {
+		docs[i], freqs[i] = posting.doc, posting.freq
+	}
+	return &sortingDocsEnum{
+		docs:      docs,
+		freqs:     freqs,
+		withFreqs: withFreqs,
+		upto:      -1,
+		docID:     -1,
+	}, nil
+}
+
+func (s *sortingDocsEnum) DocID() int {
+	return s.docID
+}
+
+func (s *sortingDocsEnum) NextDoc() (int, error) {
+	s.upto++
+	if s.upto >= len(s.docs) {
+		s.docID = types.NO_MORE_DOCS
+	} else {
+		s.docID = s.docs[s.upto]
+	}
+	return s.docID, nil
+}
+
+func (s *sortingDocsEnum) Advance(target int) (int, error) {
+	return s.SlowAdvance(target)
+}
+
+func (s *sortingDocsEnum) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(s, target)
+}
+
+func (s *sortingDocsEnum) Cost() int64 {
+	return int64(len(s.docs))
+}
+
+func (s *sortingDocsEnum) Freq() (int, error) {
+	if !s.withFreqs {
+		return 1, nil
+	}
+	return s.freqs[s.upto], nil
+}
+
+func (s *sortingDocsEnum) NextPosition() (int, error) {
+	return -1, nil
+}
+
+func (s *sortingDocsEnum) StartOffset() (int, error) {
+	return -1, nil
+}
+
+func (s *sortingDocsEnum) EndOffset() (int, error) {
+	return -1, nil
+}
+
+func (s *sortingDocsEnum) GetPayload() ([]byte, error) {
+	return nil, nil
+}
+
+var _ index.PostingsEnum = &sortingPostingsEnum{}
+
+// sortingPostingsEnum reads all the docs, with their positions, of a term to return them in the
+// order of the documents of the sorted segment
+type sortingPostingsEnum struct {
+	docs         []sortingPostingsDoc
+	storeOffsets bool
+	upto         int
+	docID        int
+	posUpto      int
+}
+
+type sortingPostingsDoc struct {
+	doc       int
+	positions []sortingPosition
+}
+
+type sortingPosition struct {
+	position    int
+	startOffset int
+	endOffset   int
+	payload     []byte
+}
+
+func newSortingPostingsEnum(in index.PostingsEnum, docMap index.DocMap, storeOffsets bool) (*sortingPostingsEnum, error) {
+	docs := make([]sortingPostingsDoc, 0)
+	for {
+		doc, err := nextDocOrExhausted(in)
+		if err != nil {
+			return nil, err
+		}
+		if doc == types.NO_MORE_DOCS {
+			break
+		}
+		freq, err := in.Freq()
+		if err != nil {
+			return nil, err
+		}
+
+		positions := make([]sortingPosition, freq)
+		for i := range positions {
+			position := &positions[i]
+			if position.position, err = in.NextPosition(); err != nil {
+				return nil, err
+			}
+			position.startOffset, position.endOffset = -1, -1
+			if storeOffsets {
+				if position.startOffset, err = in.StartOffset(); err != nil {
+					return nil, err
+				}
+				if position.endOffset, err = in.EndOffset(); err != nil {
+					return nil, err
+				}
+			}
+			payload, err := in.GetPayload()
+			if err != nil {
+				return nil, err
+			}
+			// the payload is a view on the buffer of in
+			position.payload = bytes.Clone(payload)
+		}
+		docs = append(docs, sortingPostingsDoc{doc: docMap.OldToNew(doc), positions: positions})
+	}
+	slices.SortFunc(docs, func(a, b sortingPostingsDoc) int {
+		return a.doc - b.doc
+	})
+
+	return &sortingPostingsEnum{
+		docs:         docs,
+		storeOffsets: storeOffsets,
+		upto:         -1,
+		docID:        -1,
+	}, nil
+}
+
+func (s *sortingPostingsEnum) DocID() int {
+	return s.docID
+}
+
+func (s *sortingPostingsEnum) NextDoc() (int, error) {
+	s.upto++
+	s.posUpto = -1
+	if s.upto >= len(s.docs) {
+		s.docID = types.NO_MORE_DOCS
+	} else {
+		s.docID = s.docs[s.upto].doc
+	}
+	return s.docID, nil
+}
+
+func (s *sortingPostingsEnum) Advance(target int) (int, error) {
+	return s.SlowAdvance(target)
+}
+
+func (s *sortingPostingsEnum) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(s, target)
+}
+
+func (s *sortingPostingsEnum) Cost() int64 {
+	return int64(len(s.docs))
+}
+
+func (s *sortingPostingsEnum) Freq() (int, error) {
+	return len(s.docs[s.upto].positions), nil
+}
+
+func (s *sortingPostingsEnum) NextPosition() (int, error) {
+	positions := s.docs[s.upto].positions
+	if s.posUpto+1 >= len(positions) {
+		return 0, io.EOF
+	}
+	s.posUpto++
+	return positions[s.posUpto].position, nil
+}
+
+func (s *sortingPostingsEnum) StartOffset() (int, error) {
+	return s.docs[s.upto].positions[s.posUpto].startOffset, nil
+}
+
+func (s *sortingPostingsEnum) EndOffset() (int, error) {
+	return s.docs[s.upto].positions[s.posUpto].endOffset, nil
+}
+
+func (s *sortingPostingsEnum) GetPayload() ([]byte, error) {
+	return s.docs[s.upto].positions[s.posUpto].payload, nil
+}
@@ -139,6 +139,7 @@ func (f *FreqProxTermsWriterPerField) NewTerm(termID, docID int) error {
 		if f.hasProx {
 			f.writeProx(termID, f.fieldState.Position)
 			if f.hasOffsets {
+				postings.SetLastOffsets(termID, 0)
 				f.writeOffsets(termID, f.fieldState.Offset)
 			}
 		} else {
@@ -1,11 +1,28 @@
 package index
 
 import (
+	"context"
 	"errors"
-	"github.com/geange/lucene-go/core/interface/index"
+	"io"
+	"math"
 	"sync"
 	"sync/atomic"
 	"unsafe"
+
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/types"
+)
+
+// completeNoScores the ScoreMode search.COMPLETE_NO_SCORES: visits all the matches without scores
+const completeNoScores = index.ScoreMode(1)
+
+// IndexSearcherFactory
+// Creates the IndexSearcher resolving the deletes by query against a segment, usually search.NewIndexSearcher.
+// See IndexWriterConfig.SetIndexSearcherFactory.
+type IndexSearcherFactory func(reader index.IndexReader) (index.IndexSearcher, error)
+
+var (
+	errNoIndexSearcherFactory = errors.New("deleting documents by query requires IndexWriterConfig.SetIndexSearcherFactory")
 )
 
 // FrozenBufferedUpdates
@@ -16,7 +33,7 @@ type FrozenBufferedUpdates struct {
 	sync.Mutex
 
 	// Terms, in sorted order:
-	deleteTerms *PrefixCodedTerms
+	deleteTerms []index.Term
 
 	// Parallel array of deleted query, and the docIDUpto for each
 	deleteQueries     []index.Query
@@ -33,14 +50,36 @@ type FrozenBufferedUpdates struct {
 
 	delGen int64 // assigned by BufferedUpdatesStream once pushed
 
-	privateSegment *index.SegmentCommitInfo // non-null iff this frozen packet represents
+	privateSegment index.SegmentCommitInfo // non-null iff this frozen packet represents
+	// a segment private deletes. in that case is should
+	// only have Queries and doc values updates
 }
 
-// NewFrozenBufferedUpdates
-// TODO: fix it
 func NewFrozenBufferedUpdates(updates *index.BufferedUpdates, privateSegment index.SegmentCommitInfo) *FrozenBufferedUpdates {
+	// the delete terms and queries are sorted by the tree maps of the BufferedUpdates
+	deleteTerms := updates.GetDeleteTerms().Keys()
+
+	deleteQueries := make([]index.Query, 0, updates.GetDeleteQueries().Size())
+	deleteQueryLimits := make([]int, 0, updates.GetDeleteQueries().Size())
+	updates.GetDeleteQueries().Each(func(query index.Query, limit int) {
+		deleteQueries = append(deleteQueries, query)
+		deleteQueryLimits = append(deleteQueryLimits, limit)
+	})
+
+	fieldUpdates := make(map[string]*index.FieldUpdatesBuffer, len(updates.GetFieldUpdates()))
+	for field, buffer := range updates.GetFieldUpdates() {
+		fieldUpdates[field] = buffer
+	}
+
 	return &FrozenBufferedUpdates{
-		delGen: -1,
+		deleteTerms:       deleteTerms,
+		deleteQueries:     deleteQueries,
+		deleteQueryLimits: deleteQueryLimits,
+		fieldUpdates:      fieldUpdates,
+		fieldUpdatesCount: int(updates.GetNumFieldUpdates()),
+		numTermDeletes:    len(deleteTerms),
+		delGen:            -1,
+		privateSegment:    privateSegment,
 	}
 }
 
@@ -62,8 +101,9 @@ func (f *FrozenBufferedUpdates) isApplied() bool {
 
 // Apply
 // Applies pending delete-by-term, delete-by-query and doc values updates to all segments in the index,
-// returning the number of new deleted or updated documents.
-func (f *FrozenBufferedUpdates) Apply(segStates []*SegmentState) (int, error) {
+// returning the number of new deleted or updated documents. The deletes by query are resolved with the
+// IndexSearcher created by searcherFactory.
+func (f *FrozenBufferedUpdates) Apply(segStates []*SegmentState, searcherFactory IndexSearcherFactory) (int, error) {
 
 	if f.delGen == -1 {
 		// we were not yet pushed
@@ -76,7 +116,7 @@ func (f *FrozenBufferedUpdates) Apply(segStates []*SegmentState) (int, error) {
 	}
 	f.totalDelCount += termDeletesCount
 
-	queryDeletesCount, err := f.applyQueryDeletes(segStates)
+	queryDeletesCount, err := f.applyQueryDeletes(segStates, searcherFactory)
 	if err != nil {
 		return 0, err
 	}
@@ -93,11 +133,17 @@ func (f *FrozenBufferedUpdates) Apply(segStates []*SegmentState) (int, error) {
 }
 
 func (f *FrozenBufferedUpdates) applyTermDeletes(segStates []*SegmentState) (int, error) {
-	if f.deleteTerms.Size() == 0 {
+	if len(f.deleteTerms) == 0 {
 		return 0, nil
 	}
 
-	//delCount := 0
+	// We apply segment-private deletes on flush:
+	if f.privateSegment != nil {
+		return 0, errors.New("segment private deletes hold delete terms")
+	}
+
+	ctx := context.Background()
+	delCount := 0
 
 	for _, segState := range segStates {
 		if segState.delGen > f.delGen {
@@ -112,24 +158,234 @@ func (f *FrozenBufferedUpdates) applyTermDeletes(segStates []*SegmentState) (int
 			continue
 		}
 
-		// iter := f.deleteTerms.Iterator();
-		//BytesRef delTerm;
-		//TermDocsIterator termDocsIterator = new TermDocsIterator(segState.reader, true);
+		for _, delTerm := range f.deleteTerms {
+			postings, err := segState.reader.Postings(ctx, delTerm, POSTINGS_ENUM_NONE)
+			if err != nil {
+				return 0, err
+			}
+			if postings == nil {
+				continue
+			}
+
+			n, err := deleteDocs(segState, postings, math.MaxInt32)
+			if err != nil {
+				return 0, err
+			}
+			delCount += n
+		}
 	}
-	panic("")
+	return delCount, nil
 }
 
-func (f *FrozenBufferedUpdates) applyQueryDeletes(segStates []*SegmentState) (int, error) {
-	panic("")
+func (f *FrozenBufferedUpdates) applyQueryDeletes(segStates []*SegmentState, searcherFactory IndexSearcherFactory) (int, error) {
+	if len(f.deleteQueries) == 0 {
+		return 0, nil
+	}
+
+	if searcherFactory == nil {
+		return 0, errNoIndexSearcherFactory
+	}
+
+	delCount := 0
+	for _, segState := range segStates {
+		if segState.delGen > f.delGen {
+			// our deletes don't apply to this segment
+			continue
+		}
+		if segState.rld.RefCount() == 1 {
+			// This means we are the only remaining reference to this segment, meaning
+			// it was merged away while we were running, so we can safely skip running
+			// because we will run on the newly merged segment next:
+			continue
+		}
+
+		readerContext, err := segState.reader.GetContext()
+		if err != nil {
+			return 0, err
+		}
+		leaves, err := readerContext.Leaves()
+		if err != nil {
+			return 0, err
+		}
+
+		searcher, err := searcherFactory(segState.reader)
+		if err != nil {
+			return 0, err
+		}
+		searcher.SetQueryCache(nil)
+
+		for i, query := range f.deleteQueries {
+			limit := math.MaxInt32
+			if f.delGen == segState.delGen {
+				// the queries of the segment private deletes only apply to
+				// the documents added before them
+				limit = f.deleteQueryLimits[i]
+			}
+
+			query, err := searcher.Rewrite(query)
+			if err != nil {
+				return 0, err
+			}
+			weight, err := searcher.CreateWeight(query, completeNoScores, 1)
+			if err != nil {
+				return 0, err
+			}
+			scorer, err := weight.Scorer(leaves[0])
+			if err != nil {
+				return 0, err
+			}
+			if scorer == nil {
+				continue
+			}
+
+			n, err := deleteDocs(segState, scorer.Iterator(), limit)
+			if err != nil {
+				return 0, err
+			}
+			delCount += n
+		}
+	}
+	return delCount, nil
+}
+
+// Deletes the documents of iterator below limit, returns the number of documents which were live
+func deleteDocs(segState *SegmentState, iterator types.DocIdSetIterator, limit int) (int, error) {
+	delCount := 0
+	for {
+		doc, err := iterator.NextDoc()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return 0, err
+		}
+		if doc >= limit {
+			break
+		}
+
+		deleted, err := segState.rld.Delete(doc)
+		if err != nil {
+			return 0, err
+		}
+		if deleted {
+			delCount++
+		}
+	}
+	return delCount, nil
 }
 
 func (f *FrozenBufferedUpdates) applyDocValuesUpdates(segStates []*SegmentState) (int, error) {
-	panic("")
+	if len(f.fieldUpdates) == 0 {
+		return 0, nil
+	}
+
+	updateCount := 0
+	for _, segState := range segStates {
+		if segState.delGen > f.delGen {
+			// our updates don't apply to this segment
+			continue
+		}
+		if segState.rld.RefCount() == 1 {
+			// This means we are the only remaining reference to this segment, meaning
+			// it was merged away while we were running, so we can safely skip running
+			// because we will run on the newly merged segment next:
+			continue
+		}
+
+		n, err := f.applySegmentDocValuesUpdates(segState)
+		if err != nil {
+			return 0, err
+		}
+		updateCount += n
+	}
+	return updateCount, nil
 }
 
-func (f *FrozenBufferedUpdates) Any() bool {
-	return f.deleteTerms.Size() > 0 || len(f.deleteQueries) > 0 || f.fieldUpdatesCount > 0
+// Resolves the updates of each field to the documents of the segment, the resolved updates are only
+// published to the ReadersAndUpdates once all the fields are resolved
+func (f *FrozenBufferedUpdates) applySegmentDocValuesUpdates(segState *SegmentState) (int, error) {
+	ctx := context.Background()
+	maxDoc := segState.reader.MaxDoc()
+	acceptDocs := segState.rld.GetLiveDocs()
+
+	resolvedUpdates := make([]DocValuesFieldUpdates, 0, len(f.fieldUpdates))
+	for updateField, buffer := range f.fieldUpdates {
+		var dvUpdates DocValuesFieldUpdates
+		if buffer.IsNumeric() {
+			dvUpdates = NewNumericDocValuesFieldUpdates(f.delGen, updateField, maxDoc)
+		} else {
+			dvUpdates = NewBinaryDocValuesFieldUpdates(f.delGen, updateField, maxDoc)
+		}
+
+		// the updates are applied in the order they arrived, so that the last update
+		// of a document wins, irrespective of the terms lexical order
+		err := buffer.Range(func(update *index.BufferedUpdate) error {
+			postings, err := segState.reader.Postings(ctx, NewTerm(update.TermField, update.TermValue), POSTINGS_ENUM_NONE)
+			if err != nil {
+				return err
+			}
+			if postings == nil {
+				return nil
+			}
+
+			limit := math.MaxInt32
+			if f.delGen == segState.delGen {
+				// the updates of the segment private packet only apply to
+				// the documents added before them
+				limit = update.DocUpTo
+			}
+
+			for {
+				doc, err := postings.NextDoc()
+				if err != nil {
+					if errors.Is(err, io.EOF) {
+						return nil
+					}
+					return err
+				}
+				if doc >= limit {
+					return nil
+				}
+				if acceptDocs != nil && !acceptDocs.Test(uint(doc)) {
+					continue
+				}
+
+				switch {
+				case !update.HasValue:
+					err = dvUpdates.Reset(doc)
+				case buffer.IsNumeric():
+					err = dvUpdates.AddInt64(doc, update.NumericValue)
+				default:
+					err = dvUpdates.AddBytes(doc, update.BinaryValue)
+				}
+				if err != nil {
+					return err
+				}
+			}
+		})
+		if err != nil {
+			return 0, err
+		}
+		resolvedUpdates = append(resolvedUpdates, dvUpdates)
+	}
+
+	// now freeze & publish:
+	updateCount := 0
+	for _, update := range resolvedUpdates {
+		if !update.Any() {
+			continue
+		}
+		if err := update.Finish(); err != nil {
+			return 0, err
+		}
+		if err := segState.rld.AddDVUpdate(update); err != nil {
+			return 0, err
+		}
+		updateCount += update.Size()
+	}
+	return updateCount, nil
 }
 
-type TermDocsIterator struct {
+func (f *FrozenBufferedUpdates) Any() bool {
+	return len(f.deleteTerms) > 0 || len(f.deleteQueries) > 0 || f.fieldUpdatesCount > 0
 }
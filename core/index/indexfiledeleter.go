@@ -84,6 +84,10 @@ type IndexFileDeleter struct {
 	lastSegmentInfos *SegmentInfos
 
 	writer *IndexWriter
+
+	// guards the ref counts and the commits, the writer checkpoints while concurrently
+	// flushing segments or dropping the files of failed flushes
+	mu sync.Mutex
 }
 
 // NewIndexFileDeleter
@@ -112,11 +116,7 @@ func NewIndexFileDeleter(ctx context.Context, files []string, directoryOrig, dir
 
 	if currentSegmentsFile != "" {
 		for _, fileName := range files {
-			if !CODEC_FILE_PATTERN.MatchString(fileName) {
-				continue
-			}
-
-			if strings.HasSuffix(fileName, "write.lock") &&
+			if !strings.HasSuffix(fileName, WRITE_LOCK_NAME) &&
 				(CODEC_FILE_PATTERN.MatchString(fileName) ||
 					strings.HasPrefix(fileName, SEGMENTS) ||
 					strings.HasPrefix(fileName, PENDING_SEGMENTS)) {
@@ -291,7 +291,7 @@ func inflateGens(infos *SegmentInfos, files map[string]struct{}) {
 				return
 			}
 
-			maxSegmentName = max(maxSegmentGen, int(parseInt))
+			maxSegmentName = max(maxSegmentName, int(parseInt))
 
 			curGen, ok := maxPerSegmentGen[segmentName]
 			if !ok {
@@ -339,6 +339,12 @@ func (r *IndexFileDeleter) getRefCount(fileName string) *RefCount {
 }
 
 func (r *IndexFileDeleter) IncRef(segmentInfos *SegmentInfos, isCommit bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.incRef(segmentInfos, isCommit)
+}
+
+func (r *IndexFileDeleter) incRef(segmentInfos *SegmentInfos, isCommit bool) error {
 
 	files, err := segmentInfos.Files(isCommit)
 	if err != nil {
@@ -355,6 +361,9 @@ func (r *IndexFileDeleter) IncRef(segmentInfos *SegmentInfos, isCommit bool) err
 }
 
 func (r *IndexFileDeleter) IncRefFiles(files map[string]struct{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	for file := range files {
 		err := r.incRefFileName(file)
 		if err != nil {
@@ -377,7 +386,10 @@ func (r *IndexFileDeleter) incRefFileName(fileName string) error {
 // seen (if any). If this is a commit, we also call the policy to give it a chance to remove other commits.
 // If any commits are removed, we decref their files as well.
 func (r *IndexFileDeleter) Checkpoint(segmentInfos *SegmentInfos, isCommit bool) error {
-	err := r.IncRef(segmentInfos, isCommit)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.incRef(segmentInfos, isCommit)
 	if err != nil {
 		return err
 	}
@@ -399,7 +411,7 @@ func (r *IndexFileDeleter) Checkpoint(segmentInfos *SegmentInfos, isCommit bool)
 		return r.deleteCommits()
 	}
 
-	if err := r.DecRef(r.lastFiles); err != nil {
+	if err := r.decRefFiles(r.lastFiles); err != nil {
 		return err
 	}
 	r.lastFiles = map[string]struct{}{}
@@ -419,7 +431,7 @@ func (r *IndexFileDeleter) Checkpoint(segmentInfos *SegmentInfos, isCommit bool)
 // Remove the IndexCommits in the commitsToDelete List by DecRef'ing all files from each SegmentInfos.
 func (r *IndexFileDeleter) deleteCommits() error {
 	for _, commit := range r.commitsToDelete {
-		if err := r.DecRef(commit.files); err != nil {
+		if err := r.decRefFiles(commit.files); err != nil {
 			return err
 		}
 	}
@@ -449,6 +461,12 @@ func (r *IndexFileDeleter) deleteCommits() error {
 }
 
 func (r *IndexFileDeleter) DecRef(files map[string]struct{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.decRefFiles(files)
+}
+
+func (r *IndexFileDeleter) decRefFiles(files map[string]struct{}) error {
 	toDelete := make(map[string]struct{})
 	for file := range files {
 		if r.decRef(file) {
@@ -500,6 +518,9 @@ func (r *IndexFileDeleter) deleteFile(name string) error {
 }
 
 func (r *IndexFileDeleter) deleteNewFiles(files map[string]struct{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	toDelete := make(map[string]struct{})
 
 	for fileName := range files {
@@ -29,11 +29,16 @@ type baseIndexReader struct {
 }
 
 func newBaseIndexReader(spi IndexReaderSPI) *baseIndexReader {
-	return &baseIndexReader{
+	reader := &baseIndexReader{
 		spi:           spi,
+		closedByChild: new(atomic.Bool),
 		refCount:      new(atomic.Int64),
 		parentReaders: make(map[index.IndexReader]struct{}),
+		closed:        new(atomic.Bool),
 	}
+	// the reader is created with a ref for its creator
+	reader.refCount.Store(1)
+	return reader
 }
 
 func (r *baseIndexReader) Close() error {
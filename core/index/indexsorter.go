@@ -1,8 +1,7 @@
 package index
 
 import (
-	"errors"
-	"io"
+	"context"
 	"math"
 
 	"github.com/geange/lucene-go/core/interface/index"
@@ -41,68 +40,112 @@ func (e *EmptySortedDocValuesProvider) Get(reader index.LeafReader) (index.Sorte
 	return e.FnGet(reader)
 }
 
-var _ index.IndexSorter = &IntSorter{}
-
-// IntSorter Sorts documents based on integer values from a NumericDocValues instance
-type IntSorter struct {
-	missingValue   *int32
-	reverseMul     int
-	valuesProvider NumericDocValuesProvider
-	providerName   string
-}
-
-func NewIntSorter(providerName string, missingValue int32, reverse bool, valuesProvider NumericDocValuesProvider) *IntSorter {
-	reverseMul := 1
-	if reverse {
-		reverseMul = -1
+// Calls fn with the value of every document of values, values is nil if no document has a value.
+func forEachNumericValue(values index.NumericDocValues, fn func(docID int, value int64)) error {
+	if values == nil {
+		return nil
 	}
-
-	return &IntSorter{
-		missingValue:   &missingValue,
-		reverseMul:     reverseMul,
-		valuesProvider: valuesProvider,
-		providerName:   providerName,
+	for {
+		docID, err := nextDocOrExhausted(values)
+		if err != nil {
+			return err
+		}
+		if docID == types.NO_MORE_DOCS {
+			return nil
+		}
+		value, err := values.LongValue()
+		if err != nil {
+			return err
+		}
+		fn(docID, value)
 	}
 }
 
-var _ index.ComparableProvider = &IntComparableProvider{}
+var _ index.ComparableProvider = &numericComparableProvider{}
 
-type IntComparableProvider struct {
+// numericComparableProvider returns the comparable long of the value of a document, or of
+// missingValue if the document has no value
+type numericComparableProvider struct {
 	values       index.NumericDocValues
 	missingValue int64
+	toComparable func(value int64) int64
 }
 
-func (r *IntComparableProvider) GetAsComparableLong(docID int) (int64, error) {
+func (r *numericComparableProvider) GetAsComparableLong(docID int) (int64, error) {
+	if r.values == nil {
+		return r.toComparable(r.missingValue), nil
+	}
 	ok, err := r.values.AdvanceExact(docID)
 	if err != nil {
 		return 0, err
 	}
-	if ok {
-		return r.values.LongValue()
+	if !ok {
+		return r.toComparable(r.missingValue), nil
+	}
+	value, err := r.values.LongValue()
+	if err != nil {
+		return 0, err
 	}
-	return r.missingValue, nil
+	return r.toComparable(value), nil
 }
 
-func (i *IntSorter) GetComparableProviders(readers []index.LeafReader) ([]index.ComparableProvider, error) {
-	providers := make([]index.ComparableProvider, 0)
-	missingValue := int64(0)
-	if i.missingValue != nil {
-		missingValue = int64(*i.missingValue)
-	}
+func getNumericComparableProviders(readers []index.LeafReader, valuesProvider NumericDocValuesProvider,
+	missingValue int64, toComparable func(value int64) int64) ([]index.ComparableProvider, error) {
 
+	providers := make([]index.ComparableProvider, len(readers))
 	for readerIndex, reader := range readers {
-		values, err := i.valuesProvider.Get(reader)
+		values, err := valuesProvider.Get(reader)
 		if err != nil {
 			return nil, err
 		}
-		providers[readerIndex] = &IntComparableProvider{
+		providers[readerIndex] = &numericComparableProvider{
 			values:       values,
 			missingValue: missingValue,
+			toComparable: toComparable,
 		}
 	}
 	return providers, nil
 }
 
+func reverseMultiplier(reverse bool) int {
+	if reverse {
+		return -1
+	}
+	return 1
+}
+
+var _ index.IndexSorter = &IntSorter{}
+
+// IntSorter Sorts documents based on integer values from a NumericDocValues instance
+type IntSorter struct {
+	missingValue   *int32
+	reverseMul     int
+	valuesProvider NumericDocValuesProvider
+	providerName   string
+}
+
+// NewIntSorter
+// Creates a new IntSorter, missingValue is the value used for the documents without a value,
+// nil means 0.
+func NewIntSorter(providerName string, missingValue *int32, reverse bool, valuesProvider NumericDocValuesProvider) *IntSorter {
+	return &IntSorter{
+		missingValue:   missingValue,
+		reverseMul:     reverseMultiplier(reverse),
+		valuesProvider: valuesProvider,
+		providerName:   providerName,
+	}
+}
+
+func (i *IntSorter) GetComparableProviders(readers []index.LeafReader) ([]index.ComparableProvider, error) {
+	missingValue := int64(0)
+	if i.missingValue != nil {
+		missingValue = int64(*i.missingValue)
+	}
+	return getNumericComparableProviders(readers, i.valuesProvider, missingValue, func(value int64) int64 {
+		return int64(int32(value))
+	})
+}
+
 var _ index.DocComparator = &IntDocComparator{}
 
 type IntDocComparator struct {
@@ -126,20 +169,10 @@ func (i *IntSorter) GetDocComparator(reader index.LeafReader, maxDoc int) (index
 		}
 	}
 
-	for {
-		docID, err := dvs.NextDoc()
-		if err != nil {
-			return nil, err
-		}
-		if docID == types.NO_MORE_DOCS {
-			break
-		}
-		value, err := dvs.LongValue()
-		if err != nil {
-			return nil, err
-		}
-
+	if err := forEachNumericValue(dvs, func(docID int, value int64) {
 		values[docID] = int32(value)
+	}); err != nil {
+		return nil, err
 	}
 
 	return &IntDocComparator{
@@ -162,57 +195,28 @@ type LongSorter struct {
 	providerName   string
 }
 
-func NewLongSorter(providerName string, missingValue int64,
+// NewLongSorter
+// Creates a new LongSorter, missingValue is the value used for the documents without a value,
+// nil means 0.
+func NewLongSorter(providerName string, missingValue *int64,
 	reverse bool, valuesProvider NumericDocValuesProvider) *LongSorter {
-	reverseMul := 1
-	if reverse {
-		reverseMul = -1
-	}
 
 	return &LongSorter{
-		missingValue:   &missingValue,
-		reverseMul:     reverseMul,
+		missingValue:   missingValue,
+		reverseMul:     reverseMultiplier(reverse),
 		valuesProvider: valuesProvider,
 		providerName:   providerName,
 	}
 }
 
-var _ index.ComparableProvider = &LongComparableProvider{}
-
-type LongComparableProvider struct {
-	values       index.NumericDocValues
-	missingValue int64
-}
-
-func (r *LongComparableProvider) GetAsComparableLong(docID int) (int64, error) {
-	ok, err := r.values.AdvanceExact(docID)
-	if err != nil {
-		return 0, err
-	}
-	if ok {
-		return r.values.LongValue()
-	}
-	return r.missingValue, nil
-}
-
 func (i *LongSorter) GetComparableProviders(readers []index.LeafReader) ([]index.ComparableProvider, error) {
-	providers := make([]index.ComparableProvider, 0)
 	missingValue := int64(0)
 	if i.missingValue != nil {
 		missingValue = *i.missingValue
 	}
-
-	for readerIndex, reader := range readers {
-		values, err := i.valuesProvider.Get(reader)
-		if err != nil {
-			return nil, err
-		}
-		providers[readerIndex] = &LongComparableProvider{
-			values:       values,
-			missingValue: missingValue,
-		}
-	}
-	return providers, nil
+	return getNumericComparableProviders(readers, i.valuesProvider, missingValue, func(value int64) int64 {
+		return value
+	})
 }
 
 var _ index.DocComparator = &LongDocComparator{}
@@ -223,8 +227,7 @@ type LongDocComparator struct {
 }
 
 func (r *LongDocComparator) Compare(docID1, docID2 int) int {
-	//TODO implement me
-	panic("implement me")
+	return r.reverseMul * Compare(r.values[docID1], r.values[docID2])
 }
 
 func (i *LongSorter) GetDocComparator(reader index.LeafReader, maxDoc int) (index.DocComparator, error) {
@@ -239,20 +242,10 @@ func (i *LongSorter) GetDocComparator(reader index.LeafReader, maxDoc int) (inde
 		}
 	}
 
-	for {
-		docID, err := dvs.NextDoc()
-		if err != nil {
-			return nil, err
-		}
-		if docID == types.NO_MORE_DOCS {
-			break
-		}
-		value, err := dvs.LongValue()
-		if err != nil {
-			return nil, err
-		}
-
+	if err := forEachNumericValue(dvs, func(docID int, value int64) {
 		values[docID] = value
+	}); err != nil {
+		return nil, err
 	}
 
 	return &LongDocComparator{
@@ -275,62 +268,30 @@ type FloatSorter struct {
 	providerName   string
 }
 
-func NewFloatSorter(providerName string, missingValue float32,
+// NewFloatSorter
+// Creates a new FloatSorter, missingValue is the value used for the documents without a value,
+// nil means 0.
+func NewFloatSorter(providerName string, missingValue *float32,
 	reverse bool, valuesProvider NumericDocValuesProvider) *FloatSorter {
-	reverseMul := 1
-	if reverse {
-		reverseMul = -1
-	}
 
 	return &FloatSorter{
-		missingValue:   &missingValue,
-		reverseMul:     reverseMul,
+		missingValue:   missingValue,
+		reverseMul:     reverseMultiplier(reverse),
 		valuesProvider: valuesProvider,
 		providerName:   providerName,
 	}
 }
 
-var _ index.ComparableProvider = &FloatComparableProvider{}
-
-type FloatComparableProvider struct {
-	values       index.NumericDocValues
-	missingValue float32
-}
-
-func (r *FloatComparableProvider) GetAsComparableLong(docID int) (int64, error) {
-	value := r.missingValue
-	ok, err := r.values.AdvanceExact(docID)
-	if err != nil {
-		return 0, err
-	}
-	if ok {
-		v, err := r.values.LongValue()
-		if err != nil {
-			return 0, err
-		}
-		value = math.Float32frombits(uint32(v))
-	}
-	return int64(math.Float32bits(value)), nil
-}
-
 func (f *FloatSorter) GetComparableProviders(readers []index.LeafReader) ([]index.ComparableProvider, error) {
-	providers := make([]index.ComparableProvider, 0)
-	missingValue := float32(0)
+	missingValue := int64(0)
 	if f.missingValue != nil {
-		missingValue = *f.missingValue
+		missingValue = int64(math.Float32bits(*f.missingValue))
 	}
-
-	for readerIndex, reader := range readers {
-		values, err := f.valuesProvider.Get(reader)
-		if err != nil {
-			return nil, err
-		}
-		providers[readerIndex] = &FloatComparableProvider{
-			values:       values,
-			missingValue: missingValue,
-		}
-	}
-	return providers, nil
+	// the IEEE 754 bits are flipped so that the order of the signed ints is the order of the floats
+	return getNumericComparableProviders(readers, f.valuesProvider, missingValue, func(value int64) int64 {
+		bits := int32(value)
+		return int64(bits ^ (bits>>31)&0x7fffffff)
+	})
 }
 
 var _ index.DocComparator = &FloatDocComparator{}
@@ -356,19 +317,10 @@ func (f *FloatSorter) GetDocComparator(reader index.LeafReader, maxDoc int) (ind
 		}
 	}
 
-	for {
-		docID, err := dvs.NextDoc()
-		if err != nil {
-			return nil, err
-		}
-		if docID == types.NO_MORE_DOCS {
-			break
-		}
-		value, err := dvs.LongValue()
-		if err != nil {
-			return nil, err
-		}
+	if err := forEachNumericValue(dvs, func(docID int, value int64) {
 		values[docID] = math.Float32frombits(uint32(value))
+	}); err != nil {
+		return nil, err
 	}
 
 	return &FloatDocComparator{
@@ -391,62 +343,29 @@ type DoubleSorter struct {
 	providerName   string
 }
 
-func NewDoubleSorter(providerName string, missingValue float64,
+// NewDoubleSorter
+// Creates a new DoubleSorter, missingValue is the value used for the documents without a value,
+// nil means 0.
+func NewDoubleSorter(providerName string, missingValue *float64,
 	reverse bool, valuesProvider NumericDocValuesProvider) *DoubleSorter {
-	reverseMul := 1
-	if reverse {
-		reverseMul = -1
-	}
 
 	return &DoubleSorter{
-		missingValue:   &missingValue,
-		reverseMul:     reverseMul,
+		missingValue:   missingValue,
+		reverseMul:     reverseMultiplier(reverse),
 		valuesProvider: valuesProvider,
 		providerName:   providerName,
 	}
 }
 
-var _ index.ComparableProvider = &DoubleComparableProvider{}
-
-type DoubleComparableProvider struct {
-	values       index.NumericDocValues
-	missingValue float64
-}
-
-func (d *DoubleComparableProvider) GetAsComparableLong(docID int) (int64, error) {
-	value := d.missingValue
-	ok, err := d.values.AdvanceExact(docID)
-	if err != nil {
-		return 0, err
-	}
-	if ok {
-		v, err := d.values.LongValue()
-		if err != nil {
-			return 0, err
-		}
-		value = math.Float64frombits(uint64(v))
-	}
-	return int64(math.Float64bits(value)), nil
-}
-
 func (d *DoubleSorter) GetComparableProviders(readers []index.LeafReader) ([]index.ComparableProvider, error) {
-	providers := make([]index.ComparableProvider, 0)
-	missingValue := float64(0)
+	missingValue := int64(0)
 	if d.missingValue != nil {
-		missingValue = *d.missingValue
+		missingValue = int64(math.Float64bits(*d.missingValue))
 	}
-
-	for readerIndex, reader := range readers {
-		values, err := d.valuesProvider.Get(reader)
-		if err != nil {
-			return nil, err
-		}
-		providers[readerIndex] = &DoubleComparableProvider{
-			values:       values,
-			missingValue: missingValue,
-		}
-	}
-	return providers, nil
+	// the IEEE 754 bits are flipped so that the order of the signed longs is the order of the doubles
+	return getNumericComparableProviders(readers, d.valuesProvider, missingValue, func(value int64) int64 {
+		return value ^ (value>>63)&0x7fffffffffffffff
+	})
 }
 
 var _ index.DocComparator = &DoubleDocComparator{}
@@ -472,22 +391,10 @@ func (d *DoubleSorter) GetDocComparator(reader index.LeafReader, maxDoc int) (in
 		}
 	}
 
-	for {
-		docID, err := dvs.NextDoc()
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				break
-			}
-			return nil, err
-		}
-		if docID == types.NO_MORE_DOCS {
-			break
-		}
-		value, err := dvs.LongValue()
-		if err != nil {
-			return nil, err
-		}
+	if err := forEachNumericValue(dvs, func(docID int, value int64) {
 		values[docID] = math.Float64frombits(uint64(value))
+	}); err != nil {
+		return nil, err
 	}
 
 	return &DoubleDocComparator{
@@ -502,6 +409,7 @@ func (d *DoubleSorter) GetProviderName() string {
 
 var _ index.IndexSorter = &StringSorter{}
 
+// StringSorter Sorts documents based on terms from a SortedDocValues instance
 type StringSorter struct {
 	providerName   string
 	missingValue   string
@@ -509,38 +417,87 @@ type StringSorter struct {
 	valuesProvider SortedDocValuesProvider
 }
 
-func (s *StringSorter) GetComparableProviders(readers []index.LeafReader) ([]index.ComparableProvider, error) {
-	//readersNum := len(readers)
-	//
-	//providers := make([]ComparableProvider, readersNum)
-	//values := make([]SortedDocValues, readersNum)
-	//
-	//for i, reader := range readers {
-	//	sorted, err := s.valuesProvider.Get(reader)
-	//	if err != nil {
-	//		return nil, err
-	//	}
-	//	values[i] = sorted
-	//}
-
-	// TODO
-	panic("")
-}
-
+// NewStringSorter
+// Creates a new StringSorter, missingValue is STRING_FIRST or STRING_LAST, any other value
+// sorts the documents without a value first.
 func NewStringSorter(providerName, missingValue string, reverse bool, valuesProvider SortedDocValuesProvider) *StringSorter {
 	return &StringSorter{
-		providerName: providerName,
-		missingValue: missingValue,
-		reverseMul: func() int {
-			if reverse {
-				return -1
-			}
-			return 1
-		}(),
+		providerName:   providerName,
+		missingValue:   missingValue,
+		reverseMul:     reverseMultiplier(reverse),
 		valuesProvider: valuesProvider,
 	}
 }
 
+func (s *StringSorter) missingOrd() int {
+	if s.missingValue == STRING_LAST {
+		return math.MaxInt32
+	}
+	return math.MinInt32
+}
+
+func (s *StringSorter) GetComparableProviders(readers []index.LeafReader) ([]index.ComparableProvider, error) {
+	values := make([]index.SortedDocValues, len(readers))
+	subs := make([]index.TermsEnum, len(readers))
+	weights := make([]int64, len(readers))
+	for i, reader := range readers {
+		sorted, err := s.valuesProvider.Get(reader)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = sorted
+		subs[i] = EmptyTermsEnum
+		if sorted != nil {
+			if subs[i], err = sorted.TermsEnum(); err != nil {
+				return nil, err
+			}
+			weights[i] = int64(sorted.GetValueCount())
+		}
+	}
+
+	// the ords of the segments are compared through the ords of the merged terms
+	ordinalMap, err := BuildOrdinalMap(context.Background(), subs, weights)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := make([]index.ComparableProvider, len(readers))
+	for i := range readers {
+		providers[i] = &stringComparableProvider{
+			values:     values[i],
+			globalOrds: ordinalMap.GetGlobalOrds(i),
+			missingOrd: int64(s.missingOrd()),
+		}
+	}
+	return providers, nil
+}
+
+var _ index.ComparableProvider = &stringComparableProvider{}
+
+type stringComparableProvider struct {
+	values     index.SortedDocValues
+	globalOrds []int64
+	missingOrd int64
+}
+
+func (r *stringComparableProvider) GetAsComparableLong(docID int) (int64, error) {
+	if r.values == nil {
+		return r.missingOrd, nil
+	}
+	ok, err := r.values.AdvanceExact(docID)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return r.missingOrd, nil
+	}
+	ord, err := r.values.OrdValue()
+	if err != nil {
+		return 0, err
+	}
+	return r.globalOrds[ord], nil
+}
+
 var _ index.DocComparator = &StringDocComparator{}
 
 type StringDocComparator struct {
@@ -558,33 +515,29 @@ func (s *StringSorter) GetDocComparator(reader index.LeafReader, maxDoc int) (in
 		return nil, err
 	}
 
-	missingOrd := math.MinInt32
-	if s.missingValue == "STRING_LAST" {
-		missingOrd = math.MaxInt32
-	}
-
 	ords := make([]int, maxDoc)
 	for i := range ords {
-		ords[i] = missingOrd
+		ords[i] = s.missingOrd()
 	}
 
-	for {
-		docID, err := sorted.NextDoc()
-		if err != nil {
-			if err == io.EOF {
+	if sorted != nil {
+		for {
+			docID, err := nextDocOrExhausted(sorted)
+			if err != nil {
+				return nil, err
+			}
+			if docID == types.NO_MORE_DOCS {
 				break
 			}
+			if ords[docID], err = sorted.OrdValue(); err != nil {
+				return nil, err
+			}
 		}
-		value, err := sorted.OrdValue()
-		if err != nil {
-			return nil, err
-		}
-		ords[docID] = value
 	}
 
 	return &StringDocComparator{
-		ords:       nil,
-		reverseMul: 0,
+		ords:       ords,
+		reverseMul: s.reverseMul,
 	}, nil
 }
 
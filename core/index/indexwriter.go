@@ -9,6 +9,7 @@ import (
 	"github.com/geange/lucene-go/core/interface/index"
 	"maps"
 	"math"
+	"slices"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -24,6 +25,8 @@ var (
 	// Use package-private instance var to enforce the limit so testing
 	// can use less electricity:
 	actualMaxDocs = MAX_POSITION
+
+	errIndexWriterClosed = errors.New("this IndexWriter is already closed")
 )
 
 const (
@@ -88,19 +91,23 @@ type IndexWriter struct {
 	mergeSource              MergeSource
 	writeDocValuesLock       sync.RWMutex
 	deleter                  *IndexFileDeleter
-	//segmentsToMerge          *hashmap.Map
-	mergeMaxNumSegments int
-	writeLock           store.Lock
-	closed              bool
-	closing             bool
-	atomMaybeMerge      *atomic.Bool
-	commitUserData      map[string]string
-	//mergingSegments          *hashset.Set
-	mergeScheduler MergeScheduler
+	segmentsToMerge          map[index.SegmentCommitInfo]bool
+	mergeMaxNumSegments      int
+	writeLock                store.Lock
+	mu                       sync.Mutex // guards closed, closing, the merge bookkeeping and the changes of segmentInfos
+	closeCond                *sync.Cond // signaled when closing ends, see shouldClose
+	commitLock               sync.Mutex // serializes the commits
+	fullFlushLock            sync.Mutex // serializes the full flushes of the docWriter
+	closed                   bool
+	closing                  bool
+	commitUserData           map[string]string
+	mergingSegments          map[index.SegmentCommitInfo]struct{}
+	mergeScheduler           MergeScheduler
 	//runningAddIndexesMerges  *hashset.Set
-	pendingMerges []*OneMerge
-	//runningMerges            *hashset.Set
+	pendingMerges         []*OneMerge
+	runningMerges         map[*OneMerge]struct{}
 	mergeExceptions       []*OneMerge
+	mergeErr              error // failures of the merges triggered implicitly, see recordMergeError
 	mergeGen              int64
 	merges                *Merges
 	didMessageState       bool
@@ -123,8 +130,12 @@ func NewIndexWriter(ctx context.Context, dir store.Directory, conf *IndexWriterC
 		lastCommitChangeCount: new(atomic.Int64),
 		pendingNumDocs:        new(atomic.Int64),
 		flushCount:            new(atomic.Int64),
-		atomMaybeMerge:        new(atomic.Bool),
+		merges:                &Merges{mergesEnabled: true},
+		segmentsToMerge:       make(map[index.SegmentCommitInfo]bool),
+		mergingSegments:       make(map[index.SegmentCommitInfo]struct{}),
+		runningMerges:         make(map[*OneMerge]struct{}),
 	}
+	writer.closeCond = sync.NewCond(&writer.mu)
 	conf.setIndexWriter(writer)
 	writer.config = conf
 	writer.softDeletesEnabled = conf.getSoftDeletesField() != ""
@@ -133,6 +144,7 @@ func NewIndexWriter(ctx context.Context, dir store.Directory, conf *IndexWriterC
 	writer.directory = dir
 	writer.mergeScheduler = writer.config.GetMergeScheduler()
 	writer.mergeScheduler.Initialize(writer.directoryOrig)
+	writer.mergeSource = newIndexWriterMergeSource(writer)
 
 	mode := conf.GetOpenMode()
 	var err error
@@ -301,7 +313,7 @@ func NewIndexWriter(ctx context.Context, dir store.Directory, conf *IndexWriterC
 	writer.flushNotifications = writer.newFlushNotifications()
 
 	writer.docWriter = NewDocumentsWriter(writer.flushNotifications, writer.segmentInfos.getIndexCreatedVersionMajor(), writer.pendingNumDocs,
-		writer.enableTestPoints, writer.newSegmentName(),
+		writer.enableTestPoints, writer.newSegmentName,
 		writer.config.liveIndexWriterConfig, writer.directoryOrig, writer.directory, writer.globalFieldNumberMap)
 
 	writer.bufferedUpdatesStream.GetCompletedDelGen()
@@ -446,6 +458,101 @@ func (w *IndexWriter) SoftUpdateDocument(ctx context.Context, term index.Term, d
 	return w.updateDocuments(ctx, delNode, []*document.Document{doc})
 }
 
+// DeleteDocuments
+// Deletes the document(s) containing any of the terms. All given deletes are applied and flushed atomically
+// at the same time.
+//
+// terms: array of terms to identify the documents to be deleted
+//
+// Returns: The sequence number for this operation
+func (w *IndexWriter) DeleteDocuments(ctx context.Context, terms ...index.Term) (int64, error) {
+	if err := w.ensureOpen(); err != nil {
+		return 0, err
+	}
+	seqNo, err := w.docWriter.deleteTerms(terms...)
+	if err != nil {
+		return 0, err
+	}
+	return w.maybeProcessEvents(seqNo)
+}
+
+// DeleteDocumentsByQuery
+// Deletes the document(s) matching any of the provided queries. All given deletes are applied and flushed
+// atomically at the same time. The queries are resolved against the segment readers when the deletes are
+// applied, so they also delete the matching documents which are not flushed yet.
+//
+// queries: array of queries to identify the documents to be deleted
+//
+// Returns: The sequence number for this operation
+func (w *IndexWriter) DeleteDocumentsByQuery(ctx context.Context, queries ...index.Query) (int64, error) {
+	if err := w.ensureOpen(); err != nil {
+		return 0, err
+	}
+	if w.config.GetIndexSearcherFactory() == nil {
+		return 0, errNoIndexSearcherFactory
+	}
+	seqNo, err := w.docWriter.deleteQueries(queries...)
+	if err != nil {
+		return 0, err
+	}
+	return w.maybeProcessEvents(seqNo)
+}
+
+// UpdateNumericDocValue
+// Updates a document's NumericDocValues for field to the given value. You can only update fields that
+// already exist in the index, not add new fields through this method. The documents are updated in place,
+// the new values are written to a new generation of the doc values files of the affected segments.
+//
+// term: the term to identify the document(s) to be updated
+// field: field name of the NumericDocValues field
+// value: new value for the field
+//
+// Returns: The sequence number for this operation
+func (w *IndexWriter) UpdateNumericDocValue(ctx context.Context, term index.Term, field string, value int64) (int64, error) {
+	return w.UpdateDocValues(ctx, term, document.NewNumericDocValuesField(field, value))
+}
+
+// UpdateBinaryDocValue
+// Updates a document's BinaryDocValues for field to the given value. You can only update fields that
+// already exist in the index, not add new fields through this method. The documents are updated in place,
+// the new values are written to a new generation of the doc values files of the affected segments.
+//
+// term: the term to identify the document(s) to be updated
+// field: field name of the BinaryDocValues field
+// value: new value for the field
+//
+// Returns: The sequence number for this operation
+func (w *IndexWriter) UpdateBinaryDocValue(ctx context.Context, term index.Term, field string, value []byte) (int64, error) {
+	if value == nil {
+		return 0, errors.New("cannot update a field to a null value")
+	}
+	return w.UpdateDocValues(ctx, term, document.NewBinaryDocValuesField(field, value))
+}
+
+// UpdateDocValues
+// Updates documents' DocValues fields to the given values. Each field update is applied to the set of
+// documents that are associated with the Term to the same value. All updates are atomically applied and
+// flushed together. Only NUMERIC and BINARY doc values fields can be updated.
+//
+// term: the term to identify the document(s) to be updated
+// updates: the updates to apply
+//
+// Returns: The sequence number for this operation
+func (w *IndexWriter) UpdateDocValues(ctx context.Context, term index.Term, updates ...document.IndexableField) (int64, error) {
+	if err := w.ensureOpen(); err != nil {
+		return 0, err
+	}
+	dvUpdates, err := w.buildDocValuesUpdate(term, updates)
+	if err != nil {
+		return 0, err
+	}
+	seqNo, err := w.docWriter.updateDocValues(dvUpdates...)
+	if err != nil {
+		return 0, err
+	}
+	return w.maybeProcessEvents(seqNo)
+}
+
 func (w *IndexWriter) buildDocValuesUpdate(term index.Term, updates []document.IndexableField) ([]index.DocValuesUpdate, error) {
 	dvUpdates := make([]index.DocValuesUpdate, 0, len(updates))
 
@@ -468,14 +575,11 @@ func (w *IndexWriter) buildDocValuesUpdate(term index.Term, updates []document.I
 
 		switch dvType {
 		case document.DOC_VALUES_TYPE_NUMERIC:
-			switch v := field.Get().(type) {
-			case int32:
-				dvUpdates = append(dvUpdates, index.NewNumericDocValuesUpdate(term, field.Name(), int64(v)))
-			case int64:
-				dvUpdates = append(dvUpdates, index.NewNumericDocValuesUpdate(term, field.Name(), v))
-			default:
-				panic("TODO")
+			value, err := document.Int64(field.Get())
+			if err != nil {
+				return nil, err
 			}
+			dvUpdates = append(dvUpdates, index.NewNumericDocValuesUpdate(term, field.Name(), value))
 
 		case document.DOC_VALUES_TYPE_BINARY:
 			value, err := document.Bytes(field.Get())
@@ -491,9 +595,11 @@ func (w *IndexWriter) buildDocValuesUpdate(term index.Term, updates []document.I
 }
 
 func (w *IndexWriter) Commit(ctx context.Context) error {
-	//return w.docWriter.Flush(ctx)
+	if err := w.ensureOpen(); err != nil {
+		return err
+	}
 	_, err := w.commitInternal(ctx, w.config.GetMergePolicy())
-	return err
+	return errors.Join(w.takeMergeError(), err)
 }
 
 // Close
@@ -511,13 +617,16 @@ func (w *IndexWriter) Commit(ctx context.Context) error {
 // NOTE: You must ensure no other threads are still making changes at the same time that this method is invoked.
 func (w *IndexWriter) Close() error {
 	if w.config.GetCommitOnClose() {
-		return w.shutdown(context.Background())
+		return errors.Join(w.shutdown(context.Background()), w.takeMergeError())
 	}
 	// TODO: rollback
-	return w.shutdown(context.Background())
+	return errors.Join(w.shutdown(context.Background()), w.takeMergeError())
 }
 
 func (w *IndexWriter) updateDocuments(ctx context.Context, delNode *Node, docs []*document.Document) (int64, error) {
+	if err := w.ensureOpen(); err != nil {
+		return 0, err
+	}
 	seqNo, err := w.docWriter.updateDocuments(ctx, docs, delNode)
 	if err != nil {
 		return 0, err
@@ -546,84 +655,628 @@ func (w *IndexWriter) processEvents(triggerMerge bool) error {
 	}
 
 	if triggerMerge {
-		return w.maybeMerge(w.config.GetMergePolicy(), MERGE_TRIGGER_EXPLICIT, UNBOUNDED_MAX_MERGE_SEGMENTS)
+		// the document or delete that triggered the merge was applied, a failing merge must not fail it
+		w.recordMergeError(w.maybeMerge(w.config.GetMergePolicy(), MERGE_TRIGGER_EXPLICIT, UNBOUNDED_MAX_MERGE_SEGMENTS))
 	}
 	return nil
 }
 
-func (w *IndexWriter) MaybeMerge() error {
-	return w.maybeMerge(w.config.GetMergePolicy(), MERGE_TRIGGER_EXPLICIT, UNBOUNDED_MAX_MERGE_SEGMENTS)
+// Keeps the failure of a merge that was not explicitly requested, it is reported by the next
+// Commit, Close, MaybeMerge, ForceMerge or ForceMergeDeletes call.
+func (w *IndexWriter) recordMergeError(err error) {
+	if err != nil {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.mergeErr = errors.Join(w.mergeErr, err)
+	}
+}
+
+// Returns and clears the failures of the merges that were not explicitly requested.
+func (w *IndexWriter) takeMergeError() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.mergeErr
+	w.mergeErr = nil
+	return err
+}
+
+func (w *IndexWriter) MaybeMerge() error {
+	err := w.maybeMerge(w.config.GetMergePolicy(), MERGE_TRIGGER_EXPLICIT, UNBOUNDED_MAX_MERGE_SEGMENTS)
+	return errors.Join(w.takeMergeError(), err)
+}
+
+func (w *IndexWriter) maybeMerge(mergePolicy MergePolicy, trigger MergeTrigger, maxNumSegments int) error {
+	err := w.ensureOpenV1(false)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.updatePendingMerges(mergePolicy, trigger, maxNumSegments); err != nil {
+		return err
+	}
+	return w.executeMerge(trigger)
+}
+
+// Returns an error if this IndexWriter is closed or in the process of closing.
+func (w *IndexWriter) ensureOpen() error {
+	return w.ensureOpenV1(true)
+}
+
+// Returns an error if this IndexWriter is closed, or, if failIfClosing is true, in the process of closing.
+func (w *IndexWriter) ensureOpenV1(failIfClosing bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed || (failIfClosing && w.closing) {
+		return errIndexWriterClosed
+	}
+	return nil
+}
+
+func (w *IndexWriter) executeMerge(trigger MergeTrigger) error {
+	return w.mergeScheduler.Merge(w.mergeSource, trigger)
+}
+
+func (w *IndexWriter) updatePendingMerges(mergePolicy MergePolicy, trigger MergeTrigger, maxNumSegments int) (*MergeSpecification, error) {
+	if !w.merges.areEnabled() {
+		return nil, nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	mergeContext := &lockedMergeContext{w}
+	var spec *MergeSpecification
+	var err error
+	if maxNumSegments != UNBOUNDED_MAX_MERGE_SEGMENTS {
+		if trigger != MERGE_TRIGGER_EXPLICIT && trigger != MERGE_TRIGGER_MERGE_FINISHED {
+			return nil, fmt.Errorf("expected EXPLICIT or MERGE_FINISHED as trigger even with maxNumSegments set but was: %d", trigger)
+		}
+
+		spec, err = mergePolicy.FindForcedMerges(w.segmentInfos, maxNumSegments, maps.Clone(w.segmentsToMerge), mergeContext)
+		if err != nil {
+			return nil, err
+		}
+		if spec != nil {
+			for _, merge := range spec.merges {
+				merge.maxNumSegments = maxNumSegments
+			}
+		}
+	} else {
+		switch trigger {
+		case MERGE_TRIGGER_GET_READER, MERGE_TRIGGER_COMMIT:
+			spec, err = mergePolicy.FindFullFlushMerges(trigger, w.segmentInfos, mergeContext)
+		default:
+			spec, err = mergePolicy.FindMerges(trigger, w.segmentInfos, mergeContext)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if spec != nil {
+		for _, merge := range spec.merges {
+			if _, err := w.registerMerge(merge); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return spec, nil
+}
+
+// ForceMerge
+// Forces merge policy to merge segments until there are <= maxNumSegments. The actual merges to be
+// executed are determined by the MergePolicy.
+//
+// This is a horribly costly operation, especially when you pass a small maxNumSegments; usually you
+// should only call this if the index is static (will no longer be changed).
+func (w *IndexWriter) ForceMerge(ctx context.Context, maxNumSegments int) error {
+	if maxNumSegments < 1 {
+		return fmt.Errorf("maxNumSegments must be >= 1; got %d", maxNumSegments)
+	}
+	if err := w.flush(ctx, true, true); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.mergeMaxNumSegments = maxNumSegments
+	clear(w.segmentsToMerge)
+	for _, info := range w.segmentInfos.AsList() {
+		w.segmentsToMerge[info] = true
+	}
+	for merge := range w.runningMerges {
+		merge.maxNumSegments = maxNumSegments
+		if merge.info != nil {
+			w.segmentsToMerge[merge.info] = true
+		}
+	}
+	for _, merge := range w.pendingMerges {
+		merge.maxNumSegments = maxNumSegments
+		if merge.info != nil {
+			w.segmentsToMerge[merge.info] = true
+		}
+	}
+	w.mu.Unlock()
+
+	// the merge scheduler runs the merges, and the merges they cascade to, before returning
+	err := w.maybeMerge(w.config.GetMergePolicy(), MERGE_TRIGGER_EXPLICIT, maxNumSegments)
+	w.mu.Lock()
+	clear(w.segmentsToMerge)
+	w.mu.Unlock()
+	return errors.Join(w.takeMergeError(), err)
+}
+
+// ForceMergeDeletes
+// Forces merging of all segments that have deleted documents. The actual merges to be executed
+// are determined by the MergePolicy.
+func (w *IndexWriter) ForceMergeDeletes(ctx context.Context) error {
+	if err := w.flush(ctx, true, true); err != nil {
+		return err
+	}
+
+	if err := w.registerForcedDeletesMerges(); err != nil {
+		return err
+	}
+	return errors.Join(w.takeMergeError(), w.executeMerge(MERGE_TRIGGER_EXPLICIT))
+}
+
+func (w *IndexWriter) registerForcedDeletesMerges() error {
+	if !w.merges.areEnabled() {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	spec, err := w.config.GetMergePolicy().FindForcedDeletesMerges(w.segmentInfos, &lockedMergeContext{w})
+	if err != nil {
+		return err
+	}
+	if spec != nil {
+		for _, merge := range spec.merges {
+			if _, err := w.registerMerge(merge); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Checks whether this merge involves any segments already participating in a merge. If not, this
+// merge is "registered", meaning we record that its segments are now participating in a merge, and
+// true is returned. Else (the merge conflicts) false is returned. w.mu must be held.
+func (w *IndexWriter) registerMerge(merge *OneMerge) (bool, error) {
+	if merge.registerDone {
+		return true, nil
+	}
+
+	for _, info := range merge.segments {
+		if _, ok := w.mergingSegments[info]; ok {
+			return false, nil
+		}
+		if !slices.Contains(w.segmentInfos.AsList(), info) {
+			return false, nil
+		}
+	}
+
+	totalMergeBytes := int64(0)
+	estimatedMergeBytes := int64(0)
+	for _, info := range merge.segments {
+		sizeInBytes, err := info.SizeInBytes()
+		if err != nil {
+			return false, err
+		}
+		maxDoc, err := info.Info().MaxDoc()
+		if err != nil {
+			return false, err
+		}
+		delCount, err := w.NumDeletedDocs(info)
+		if err != nil {
+			return false, err
+		}
+
+		totalMergeBytes += sizeInBytes
+		delRatio := 0.0
+		if maxDoc > 0 {
+			delRatio = float64(delCount) / float64(maxDoc)
+		}
+		estimatedMergeBytes += int64(float64(sizeInBytes) * (1.0 - delRatio))
+	}
+
+	w.pendingMerges = append(w.pendingMerges, merge)
+	for _, info := range merge.segments {
+		w.mergingSegments[info] = struct{}{}
+	}
+	merge.totalMergeBytes = totalMergeBytes
+	merge.estimatedMergeBytes = estimatedMergeBytes
+	merge.registerDone = true
+	return true, nil
+}
+
+// Returns the next pending merge and marks it as running, nil if there are no pending merges.
+func (w *IndexWriter) getNextMerge() *OneMerge {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pendingMerges) == 0 {
+		return nil
+	}
+	merge := w.pendingMerges[0]
+	w.pendingMerges = slices.Delete(w.pendingMerges, 0, 1)
+	w.runningMerges[merge] = struct{}{}
+	return merge
+}
+
+func (w *IndexWriter) hasPendingMerges() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.pendingMerges) != 0
+}
+
+// Merges the indicated segments, replacing them in the stack with a single segment.
+func (w *IndexWriter) merge(merge *OneMerge) error {
+	ctx := context.Background()
+	mergePolicy := w.config.GetMergePolicy()
+
+	ok, err := w.mergeInit(merge)
+	if err == nil && ok {
+		err = w.mergeMiddle(ctx, merge, mergePolicy)
+	}
+	w.mergeFinish(merge)
+	if err != nil || !ok {
+		return err
+	}
+
+	// the merged segment may be merged again, e.g. to reach maxNumSegments
+	w.mu.Lock()
+	closing := w.closed || w.closing
+	w.mu.Unlock()
+	if merge.maxNumSegments != UNBOUNDED_MAX_MERGE_SEGMENTS || !closing {
+		if _, err := w.updatePendingMerges(mergePolicy, MERGE_TRIGGER_MERGE_FINISHED, merge.maxNumSegments); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Does initial setup for a merge: applies the pending deletes and doc values updates to the segments
+// of the merge and creates the SegmentCommitInfo of the merged segment. Returns false if the merge
+// must be skipped because some of its segments were dropped.
+func (w *IndexWriter) mergeInit(merge *OneMerge) (bool, error) {
+	if merge.info != nil {
+		// mergeInit already done
+		return true, nil
+	}
+
+	// the merged segment must hold all the deletes of the segments to merge
+	if err := w.applyAllDeletesAndUpdates(); err != nil {
+		return false, err
+	}
+
+	w.mu.Lock()
+	minVersion, ok, err := w.mergeInitSegments(merge)
+	w.mu.Unlock()
+	if err != nil || !ok {
+		return false, err
+	}
+
+	// Bind a new segment name here so even with
+	// ConcurrentMergePolicy we keep deterministic segment
+	// names.
+	si := NewSegmentInfo(w.directoryOrig, version.Last, minVersion, w.newSegmentName(), -1,
+		false, w.config.GetCodec(), map[string]string{}, util.RandomId(), map[string]string{}, w.config.GetIndexSort())
+	details := map[string]string{
+		"mergeMaxNumSegments": strconv.Itoa(merge.maxNumSegments),
+		"mergeFactor":         strconv.Itoa(len(merge.segments)),
+	}
+	if err := SetDiagnostics(si, SOURCE_MERGE, details); err != nil {
+		return false, err
+	}
+	merge.info = index.NewSegmentCommitInfo(si, 0, 0, -1, -1, -1, util.RandomId())
+
+	// the deletes buffered from now on apply to the merged segment too
+	nextGen := w.bufferedUpdatesStream.getNextGen()
+	w.bufferedUpdatesStream.finishedSegment(nextGen)
+	merge.info.SetBufferedDeletesGen(nextGen)
+	return true, nil
+}
+
+// Writes the pending doc values updates of the segments of the merge and returns their smallest
+// version, false if some of the segments were dropped. w.mu must be held.
+func (w *IndexWriter) mergeInitSegments(merge *OneMerge) (*version.Version, bool, error) {
+	var minVersion *version.Version
+	for _, info := range merge.segments {
+		if !slices.Contains(w.segmentInfos.AsList(), info) {
+			// the segment was fully deleted
+			return nil, false, nil
+		}
+
+		rld, err := w.readerPool.Get(info, false)
+		if err != nil {
+			return nil, false, err
+		}
+		if rld != nil {
+			written, err := rld.writeFieldUpdates(w.directory, w.globalFieldNumberMap,
+				w.bufferedUpdatesStream.GetCompletedDelGen())
+			if err != nil {
+				return nil, false, err
+			}
+			if written {
+				if err := w.checkpointNoSIS(); err != nil {
+					return nil, false, err
+				}
+			}
+		}
+
+		segmentMinVersion := info.Info().GetMinVersion()
+		if segmentMinVersion != nil && (minVersion == nil || minVersion.OnOrAfter(segmentMinVersion)) {
+			minVersion = segmentMinVersion
+		}
+	}
+	return minVersion, true, nil
+}
+
+// Does the actual (time-consuming) work of the merge, but without holding synchronized lock on
+// IndexWriter instance
+func (w *IndexWriter) mergeMiddle(ctx context.Context, merge *OneMerge, mergePolicy MergePolicy) (err error) {
+	mergeInfo := store.NewMergeInfo(int(merge.totalMaxDoc), int(merge.estimatedMergeBytes), false, merge.maxNumSegments)
+	ioContext := store.NewIOContext(store.WithMergeInfo(mergeInfo))
+
+	defer func() {
+		err = errors.Join(err, w.closeMergeReaders(merge))
+	}()
+
+	readers := make([]index.CodecReader, 0, len(merge.segments))
+	for _, info := range merge.segments {
+		rld, err := w.getPooledInstance(info, true)
+		if err != nil {
+			return err
+		}
+		reader, err := rld.GetReadOnlyClone(ctx, ioContext)
+		if err != nil {
+			return errors.Join(err, w.release(rld, true))
+		}
+		merge.mergeReaders = append(merge.mergeReaders, *NewMergeReader(reader, reader.GetHardLiveDocs()))
+		readers = append(readers, reader)
+		if err := w.release(rld, true); err != nil {
+			return err
+		}
+	}
+
+	si, ok := merge.info.Info().(*SegmentInfo)
+	if !ok {
+		return errors.New("merged segment info must be a *SegmentInfo")
+	}
+
+	trackingDir := store.NewTrackingDirectoryWrapper(w.directory)
+	merger, err := NewSegmentMerger(readers, si, trackingDir, w.globalFieldNumberMap, ioContext)
+	if err != nil {
+		return err
+	}
+
+	if !merger.ShouldMerge() {
+		// all the documents of the merged segments were deleted
+		return w.commitMerge(merge, nil)
+	}
+
+	mergeState, err := merger.Merge(ctx)
+	if err != nil {
+		return errors.Join(err, deleteCreatedFiles(ctx, w.directory, trackingDir))
+	}
+	si.SetFiles(trackingDir.GetCreatedFiles())
+
+	w.mu.Lock()
+	useCompoundFile, err := mergePolicy.UseCompoundFile(w.segmentInfos, merge.info, &lockedMergeContext{w})
+	w.mu.Unlock()
+	if err != nil {
+		return errors.Join(err, w.deleter.deleteNewFiles(si.Files()))
+	}
+	if useCompoundFile {
+		originalFiles := si.Files()
+		deleteFiles := func(files map[string]struct{}) {
+			_ = w.deleter.deleteNewFiles(files)
+		}
+		if err := CreateCompoundFile(ctx, store.NewTrackingDirectoryWrapper(w.directory), si, ioContext, deleteFiles); err != nil {
+			return errors.Join(err, w.deleter.deleteNewFiles(originalFiles))
+		}
+
+		if err := w.deleter.deleteNewFiles(originalFiles); err != nil {
+			return errors.Join(err, w.deleter.deleteNewFiles(si.Files()))
+		}
+		si.SetUseCompoundFile(true)
+	}
+
+	// Have codec write SegmentInfo.  Must do this after
+	// creating CFS so that 1) .si isn't slurped into CFS,
+	// and 2) .si reflects useCompoundFile=true change
+	// above:
+	if err := si.GetCodec().SegmentInfoFormat().Write(ctx, w.directory, si, ioContext); err != nil {
+		return errors.Join(err, w.deleter.deleteNewFiles(si.Files()))
+	}
+
+	if err := w.commitMerge(merge, mergeState); err != nil {
+		return errors.Join(err, w.deleter.deleteNewFiles(si.Files()))
+	}
+	return nil
+}
+
+// Carries the deletes done while merging over to the merged segment, then replaces the segments of the
+// merge by the merged segment. mergeState is nil if all the merged documents were deleted, in which
+// case the merged segment is dropped.
+func (w *IndexWriter) commitMerge(merge *OneMerge, mergeState *MergeState) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, info := range merge.segments {
+		if !slices.Contains(w.segmentInfos.AsList(), info) {
+			return fmt.Errorf("segment %s was dropped while merging", info.Info().Name())
+		}
+	}
+
+	dropSegment := mergeState == nil
+	if !dropSegment {
+		mergedDeletes, err := w.commitMergedDeletes(merge, mergeState)
+		if err != nil {
+			return err
+		}
+		if mergedDeletes != nil {
+			if err := w.release(mergedDeletes, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.segmentInfos.applyMergeChanges(merge, dropSegment)
+
+	// Now deduct the deleted docs that we just reclaimed from this merge:
+	delDocCount := merge.totalMaxDoc
+	if !dropSegment {
+		maxDoc, err := merge.info.Info().MaxDoc()
+		if err != nil {
+			return err
+		}
+		delDocCount -= int64(maxDoc)
+	}
+	w.pendingNumDocs.Add(-delDocCount)
+
+	for _, info := range merge.segments {
+		if _, err := w.readerPool.drop(info); err != nil {
+			return err
+		}
+	}
+	if dropSegment {
+		if _, err := w.readerPool.drop(merge.info); err != nil {
+			return err
+		}
+	}
+
+	if err := w.checkpoint(); err != nil {
+		return err
+	}
+
+	if merge.maxNumSegments != UNBOUNDED_MAX_MERGE_SEGMENTS && !dropSegment {
+		// cascaded merges of a forced merge must not treat the merged segment as an original segment
+		if _, ok := w.segmentsToMerge[merge.info]; !ok {
+			w.segmentsToMerge[merge.info] = false
+		}
+	}
+	return nil
+}
+
+// Deletes from the merged segment the documents deleted from the segments of the merge since the merge
+// started. Returns the ReadersAndUpdates of the merged segment if any document was deleted, the caller
+// must release it.
+func (w *IndexWriter) commitMergedDeletes(merge *OneMerge, mergeState *MergeState) (*ReadersAndUpdates, error) {
+	var mergedDeletes *ReadersAndUpdates
+	for i, info := range merge.segments {
+		rld, err := w.readerPool.Get(info, false)
+		if err != nil {
+			return nil, err
+		}
+		if rld == nil {
+			continue
+		}
+		prevLiveDocs := merge.mergeReaders[i].reader.GetLiveDocs()
+		for _, docID := range rld.deletedDocsSince(prevLiveDocs, mergeState.MaxDocs[i]) {
+			if mergedDeletes == nil {
+				mergedDeletes, err = w.getPooledInstance(merge.info, true)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if _, err := mergedDeletes.Delete(mergeState.DocMaps[i].Get(docID)); err != nil {
+				return nil, errors.Join(err, w.release(mergedDeletes, false))
+			}
+		}
+	}
+	return mergedDeletes, nil
+}
+
+// Does finishing for a merge: the segments of the merge can take part in other merges again.
+func (w *IndexWriter) mergeFinish(merge *OneMerge) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.mergeFinishLocked(merge)
+}
+
+// w.mu must be held.
+func (w *IndexWriter) mergeFinishLocked(merge *OneMerge) {
+	if merge.registerDone {
+		for _, info := range merge.segments {
+			delete(w.mergingSegments, info)
+		}
+		merge.registerDone = false
+	}
+	delete(w.runningMerges, merge)
+}
+
+func (w *IndexWriter) closeMergeReaders(merge *OneMerge) error {
+	var errs []error
+	for _, mergeReader := range merge.mergeReaders {
+		errs = append(errs, mergeReader.reader.DecRef())
+	}
+	merge.mergeReaders = nil
+	return errors.Join(errs...)
+}
+
+// Aborts the merges which are registered but were not run by the merge scheduler.
+func (w *IndexWriter) abortPendingMerges() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, merge := range w.pendingMerges {
+		w.mergeFinishLocked(merge)
+	}
+	w.pendingMerges = w.pendingMerges[:0]
+}
+
+// NumDeletesToMerge
+// Returns the number of deletes a merge would claim back if the given segment is merged.
+func (w *IndexWriter) NumDeletesToMerge(info index.SegmentCommitInfo) (int, error) {
+	return w.NumDeletedDocs(info)
 }
 
-func (w *IndexWriter) maybeMerge(mergePolicy MergePolicy, trigger MergeTrigger, maxNumSegments int) error {
-	err := w.ensureOpenV1(false)
+// NumDeletedDocs
+// Obtain the number of deleted docs for a pooled reader. If the reader isn't being pooled,
+// the segmentInfo's delCount is returned.
+func (w *IndexWriter) NumDeletedDocs(info index.SegmentCommitInfo) (int, error) {
+	rld, err := w.readerPool.Get(info, false)
 	if err != nil {
-		return err
+		return 0, err
 	}
-
-	if _, err := w.updatePendingMerges(mergePolicy, trigger, maxNumSegments); err != nil {
-		return w.executeMerge(trigger)
+	if rld != nil {
+		return rld.GetDelCount(), nil
 	}
-	return nil
+	return info.GetDelCount() + info.GetSoftDelCount(), nil
 }
 
-func (w *IndexWriter) ensureOpen() error {
-	// TODO: fix it
-	return nil
+// GetMergingSegments
+// Returns the segments that are currently merging.
+func (w *IndexWriter) GetMergingSegments() []index.SegmentCommitInfo {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.mergingSegmentsLocked()
 }
 
-func (w *IndexWriter) ensureOpenV1(failIfClosing bool) error {
-	// TODO: fix it
-	return nil
+// w.mu must be held.
+func (w *IndexWriter) mergingSegmentsLocked() []index.SegmentCommitInfo {
+	infos := make([]index.SegmentCommitInfo, 0, len(w.mergingSegments))
+	for info := range w.mergingSegments {
+		infos = append(infos, info)
+	}
+	return infos
 }
 
-func (w *IndexWriter) executeMerge(trigger MergeTrigger) error {
-	return w.mergeScheduler.Merge(w.mergeSource, trigger)
-}
+var _ MergeContext = &lockedMergeContext{}
 
-func (w *IndexWriter) updatePendingMerges(mergePolicy MergePolicy, trigger MergeTrigger, maxNumSegments int) (*MergeSpecification, error) {
-	panic("")
-	/*
-		// In case infoStream was disabled on init, but then enabled at some
-		// point, try again to log the config here:
-		//if err := w.messageState(); err != nil {
-		//	return nil, err
-		//}
-
-		//assert maxNumSegments == UNBOUNDED_MAX_MERGE_SEGMENTS || maxNumSegments > 0;
-		//assert trigger != null;
-		if w.merges.areEnabled() == false {
-			return nil, errors.New("merges is disable")
-		}
-
-		// Do not start new merges if disaster struck
-		//if w.tragedy != null {
-		//	return null
-		//}
-
-		var spec *MergeSpecification
-		var err error
-		if maxNumSegments != UNBOUNDED_MAX_MERGE_SEGMENTS {
-			// TODO:
-		} else {
-			switch trigger {
-			case GET_READER:
-			case COMMIT:
-				spec, err = mergePolicy.FindFullFlushMerges(trigger, w.segmentInfos, w)
-				if err != nil {
-					return nil, err
-				}
-				break
-			default:
-				spec, err = mergePolicy.FindMerges(trigger, w.segmentInfos, w)
-			}
-		}
+// lockedMergeContext is the MergeContext given to the MergePolicy while w.mu is held.
+type lockedMergeContext struct {
+	*IndexWriter
+}
 
-		return nil
-	*/
+func (c *lockedMergeContext) GetMergingSegments() []index.SegmentCommitInfo {
+	return c.mergingSegmentsLocked()
 }
 
 func (w *IndexWriter) newSegmentName() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	w.changeCount.Add(1)
 	w.segmentInfos.Changed()
 	v := w.segmentInfos.counter
@@ -657,24 +1310,32 @@ func (w *IndexWriter) shutdown(ctx context.Context) error {
 		return errors.New("cannot close: prepareCommit was already called with no corresponding call to commit")
 	}
 
-	if w.shouldClose(true) {
-		if err := w.flush(true, true); err != nil {
-			return err
-		}
-		if err := w.waitForMerges(); err != nil {
-			return err
-		}
-		if _, err := w.commitInternal(ctx, w.config.GetMergePolicy()); err != nil {
-			return err
-		}
+	// Ensure that only one thread actually gets to do the closing
+	if !w.shouldClose(true) {
+		return nil
 	}
 
-	// Ensure that only one thread actually gets to do the closing
+	err := w.flush(ctx, true, true)
+	if err == nil {
+		err = w.waitForMerges()
+	}
+	if err == nil {
+		_, err = w.commitInternal(ctx, w.config.GetMergePolicy())
+	}
 
-	err := w.docWriter.Flush(nil)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	defer w.closeCond.Broadcast()
+	w.closing = false
 	if err != nil {
 		return err
 	}
+	for _, info := range w.segmentInfos.AsList() {
+		if _, err := w.readerPool.drop(info); err != nil {
+			return err
+		}
+	}
+	w.closed = true
 	return nil
 }
 
@@ -682,6 +1343,8 @@ func (w *IndexWriter) shutdown(ctx context.Context) error {
 // false if IndexWriter is now closed; else,
 // waits until another thread finishes closing
 func (w *IndexWriter) shouldClose(waitForClose bool) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	for {
 		if w.closed == false {
 			if w.closing == false {
@@ -712,42 +1375,24 @@ func (w *IndexWriter) waitForMerges() error {
 		return err
 	}
 
-	// FIXME: w.runningMerges.size() > 0
-	for len(w.pendingMerges) > 0 {
-		w.doWait()
-	}
+	// the merge scheduler may leave merges behind, e.g. NoMergeScheduler never runs them
+	w.abortPendingMerges()
 
 	return nil
 }
 
-// FIXME: how to
+// Waits until the closing IndexWriter is closed or failed to close. w.mu must be held.
 func (w *IndexWriter) doWait() {
-	// NOTE: the callers of this method should in theory
-	// be able to do simply wait(), but, as a defense
-	// against thread timing hazards where notifyAll()
-	// fails to be called, we wait for at most 1 second
-	// and then return so caller can check if wait
-	// conditions are satisfied:
-
+	w.closeCond.Wait()
 }
 
 func (w *IndexWriter) commitInternal(ctx context.Context, mergePolicy MergePolicy) (int64, error) {
-	var seqNo int64
-	var err error
-	if w.pendingCommit == nil {
-		seqNo, err = w.prepareCommitInternal()
-		if err != nil {
-			return 0, err
-		}
-	} else {
-		seqNo = w.pendingSeqNo
-	}
-
-	if err := w.finishCommit(ctx); err != nil {
+	seqNo, err := w.prepareAndFinishCommit(ctx)
+	if err != nil {
 		return 0, err
 	}
 
-	if w.atomMaybeMerge.Swap(false) {
+	if w.boolMaybeMerge.Swap(false) {
 		err := w.maybeMerge(mergePolicy, MERGE_TRIGGER_FULL_FLUSH, UNBOUNDED_MAX_MERGE_SEGMENTS)
 		if err != nil {
 			return 0, err
@@ -757,12 +1402,31 @@ func (w *IndexWriter) commitInternal(ctx context.Context, mergePolicy MergePolic
 	return seqNo, nil
 }
 
+func (w *IndexWriter) prepareAndFinishCommit(ctx context.Context) (int64, error) {
+	w.commitLock.Lock()
+	defer w.commitLock.Unlock()
+
+	var seqNo int64
+	var err error
+	if w.pendingCommit == nil {
+		seqNo, err = w.prepareCommitInternal(ctx)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		seqNo = w.pendingSeqNo
+	}
+	return seqNo, w.finishCommit(ctx)
+}
+
 func (w *IndexWriter) Changed() {
 	w.changeCount.Add(1)
 	w.segmentInfos.Changed()
 }
 
 func (w *IndexWriter) IsClosed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return w.closed
 }
 
@@ -783,28 +1447,84 @@ func (w *IndexWriter) GetReader(ctx context.Context, applyAllDeletes bool, write
 		return nil, errors.New("applyAllDeletes must be true when writeAllDeletes=true")
 	}
 
+	// Set up our initial SegmentInfos, the readers of the NRT reader are shared with the reader pool:
+	w.readerPool.enableReaderPooling()
+
 	// this function is used to control which SR are opened in order to keep track of them
 	// and to reuse them in the case we wait for merges in this getReader call.
-
-	maxFullFlushMergeWaitMillis := w.config.GetMaxFullFlushMergeWaitMillis()
-	openedReadOnlyClones := make(map[string]*SegmentReader)
-
 	readerFactory := func(sci index.SegmentCommitInfo) (*SegmentReader, error) {
 		rld, err := w.getPooledInstance(sci, true)
 		if err != nil {
 			return nil, err
 		}
 
-		segmentReader, err := rld.GetReader(ctx, nil)
+		segmentReader, err := rld.GetReadOnlyClone(ctx, store.READ)
 		if err != nil {
-			return nil, err
+			return nil, errors.Join(err, w.release(rld, true))
 		}
-		if maxFullFlushMergeWaitMillis > 0 { // only track this if we actually do fullFlush merges
-			openedReadOnlyClones[sci.Info().Name()] = segmentReader
+		if err := w.release(rld, true); err != nil {
+			return nil, err
 		}
 		return segmentReader, nil
 	}
 
+	if err := w.doBeforeFlush(); err != nil {
+		return nil, err
+	}
+
+	seqNo, err := w.docWriter.flushAllThreads(ctx)
+	if err != nil {
+		return nil, err
+	}
+	anyChanges := seqNo < 0
+	if !anyChanges {
+		// prevent double increment since docWriter#doFlush increments the flushcount
+		// if we flushed anything.
+		w.flushCount.Add(1)
+	}
+
+	reader, err := w.openNRTReader(readerFactory, applyAllDeletes, writeAllDeletes)
+	if err := errors.Join(err, w.docWriter.finishFullFlush(err == nil)); err != nil {
+		return nil, err
+	}
+	if err := w.processEvents(false); err != nil {
+		return nil, err
+	}
+	if err := w.doAfterFlush(); err != nil {
+		return nil, err
+	}
+
+	if anyChanges {
+		// the reader is already open, a failing merge must not fail it
+		w.recordMergeError(w.maybeMerge(w.config.GetMergePolicy(), MERGE_TRIGGER_GET_READER, UNBOUNDED_MAX_MERGE_SEGMENTS))
+	}
+	return reader, nil
+}
+
+// Publishes the flushed segments and the deletes, then opens the NRT reader on the current segments,
+// while the full flush is still running.
+func (w *IndexWriter) openNRTReader(readerFactory func(index.SegmentCommitInfo) (*SegmentReader, error),
+	applyAllDeletes, writeAllDeletes bool) (index.DirectoryReader, error) {
+
+	if err := w.publishFlushedSegments(true); err != nil {
+		return nil, err
+	}
+	if err := w.processEvents(false); err != nil {
+		return nil, err
+	}
+
+	if applyAllDeletes {
+		if err := w.applyAllDeletesAndUpdates(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Prevent segmentInfos from changing while opening the
+	// reader; in theory we could instead do similar retry logic,
+	// just like we do when loading segments_N
+	if err := w.writeReaderPool(writeAllDeletes); err != nil {
+		return nil, err
+	}
 	return OpenStandardDirectoryReader(w, readerFactory, w.segmentInfos, applyAllDeletes, writeAllDeletes)
 }
 
@@ -813,8 +1533,14 @@ func (w *IndexWriter) Release(readersAndUpdates *ReadersAndUpdates) error {
 }
 
 func (w *IndexWriter) release(readersAndUpdates *ReadersAndUpdates, assertLiveInfo bool) error {
-	//if i.readerPool.
-	panic("")
+	changed, err := w.readerPool.release(readersAndUpdates, assertLiveInfo)
+	if err != nil {
+		return err
+	}
+	if changed {
+		return w.checkpointNoSIS()
+	}
+	return nil
 }
 
 func (w *IndexWriter) doBeforeFlush() error {
@@ -833,7 +1559,7 @@ func (w *IndexWriter) getPooledInstance(info index.SegmentCommitInfo, create boo
 // if false the call will try to acquire the queue lock and exits if it's held by another thread.
 // FIXME: 需要完善
 func (w *IndexWriter) publishFlushedSegments(forced bool) error {
-	err := w.docWriter.purgeFlushTickets(false, func(ticket *FlushTicket) error {
+	err := w.docWriter.purgeFlushTickets(forced, func(ticket *FlushTicket) error {
 		newSegment := ticket.getFlushedSegment()
 		bufferedUpdates := ticket.getFrozenUpdates()
 		ticket.markPublished()
@@ -859,11 +1585,113 @@ func (w *IndexWriter) publishFlushedSegments(forced bool) error {
 
 func (w *IndexWriter) applyAllDeletesAndUpdates() error {
 	w.flushDeletesCount.Add(1)
+
+	// the events applying the packets may have been dropped by a full event queue,
+	// so all the pending packets are applied here, in the order they were pushed
+	for _, packet := range w.bufferedUpdatesStream.getPendingUpdates() {
+		if err := w.forceApply(packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Applies the packet to the segments it relates to: all the segments flushed before the packet,
+// or only its private segment, then drops the segments which are now fully deleted.
+func (w *IndexWriter) forceApply(packet *FrozenBufferedUpdates) error {
+	if !w.bufferedUpdatesStream.isPending(packet) {
+		// already applied
+		return nil
+	}
+
+	w.mu.Lock()
+	infos := make([]index.SegmentCommitInfo, 0, w.segmentInfos.Size())
+	if packet.privateSegment != nil {
+		// the segment may have been dropped since it was flushed
+		if slices.Contains(w.segmentInfos.AsList(), packet.privateSegment) {
+			infos = append(infos, packet.privateSegment)
+		}
+	} else {
+		for _, info := range w.segmentInfos.AsList() {
+			if info.GetBufferedDeletesGen() <= packet.delGen {
+				infos = append(infos, info)
+			}
+		}
+	}
+	w.mu.Unlock()
+
+	segStates := make([]*SegmentState, 0, len(infos))
+	for _, info := range infos {
+		rld, err := w.getPooledInstance(info, true)
+		if err != nil {
+			return errors.Join(err, closeSegmentStates(segStates))
+		}
+		segState, err := newSegmentState(rld, w.Release, info)
+		if err != nil {
+			return errors.Join(err, w.release(rld, true), closeSegmentStates(segStates))
+		}
+		segStates = append(segStates, segState)
+	}
+
+	delCount, err := packet.Apply(segStates, w.config.GetIndexSearcherFactory())
+	if err != nil {
+		return errors.Join(err, closeSegmentStates(segStates))
+	}
+
+	allDeleted := make([]index.SegmentCommitInfo, 0)
+	for _, segState := range segStates {
+		if segState.rld.GetDelCount() == segState.startDelCount {
+			continue
+		}
+		fullyDeleted, err := w.isFullyDeleted(segState.rld)
+		if err != nil {
+			return errors.Join(err, closeSegmentStates(segStates))
+		}
+		if fullyDeleted {
+			allDeleted = append(allDeleted, segState.rld.info)
+		}
+	}
+	if err := closeSegmentStates(segStates); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if delCount > 0 {
+		w.boolMaybeMerge.Store(true)
+		if err := w.checkpoint(); err != nil {
+			return err
+		}
+	}
+
+	for _, info := range allDeleted {
+		if err := w.dropDeletedSegment(info); err != nil {
+			return err
+		}
+	}
+	if len(allDeleted) > 0 {
+		if err := w.checkpoint(); err != nil {
+			return err
+		}
+	}
+
+	w.bufferedUpdatesStream.finished(packet)
 	return nil
 }
 
+func closeSegmentStates(segStates []*SegmentState) error {
+	var errs []error
+	for _, segState := range segStates {
+		errs = append(errs, segState.Close())
+	}
+	return errors.Join(errs...)
+}
+
 // Ensures that all changes in the reader-pool are written to disk.
 func (w *IndexWriter) writeReaderPool(writeDeletes bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	if writeDeletes {
 		ok, err := w.readerPool.commit(w.segmentInfos)
 		if err != nil {
@@ -881,7 +1709,7 @@ func (w *IndexWriter) writeReaderPool(writeDeletes bool) error {
 		}
 		if ok {
 			if err := w.checkpoint(); err != nil {
-				return nil
+				return err
 			}
 		}
 	}
@@ -966,7 +1794,7 @@ func (w *IndexWriter) AddIndexesFromReaders(readers ...index.CodecReader) (int64
 	var numDocs int
 
 	// FIXME:
-	if err := w.flush(false, true); err != nil {
+	if err := w.flush(context.Background(), false, true); err != nil {
 		return 0, err
 	}
 
@@ -1038,7 +1866,7 @@ func (w *IndexWriter) tooManyDocs(addedNumDocs int64) error {
 }
 
 // Flush all in-memory buffered updates (adds and deletes) to the Directory.
-func (w *IndexWriter) flush(triggerMerge, applyAllDeletes bool) error {
+func (w *IndexWriter) flush(ctx context.Context, triggerMerge, applyAllDeletes bool) error {
 	// NOTE: this method cannot be sync'd because
 	// maybeMerge() in turn calls mergeScheduler.merge which
 	// in turn can take a long time to run and we don't want
@@ -1047,7 +1875,7 @@ func (w *IndexWriter) flush(triggerMerge, applyAllDeletes bool) error {
 	// when it stalls due to too many running merges.
 
 	// We can be called during close, when closing==true, so we must pass false to ensureOpen:
-	doFlush, err := w.doFlush(applyAllDeletes)
+	doFlush, err := w.doFlush(ctx, applyAllDeletes)
 	if err != nil {
 		return err
 	}
@@ -1057,31 +1885,21 @@ func (w *IndexWriter) flush(triggerMerge, applyAllDeletes bool) error {
 	return nil
 }
 
-func (w *IndexWriter) doFlush(applyAllDeletes bool) (bool, error) {
+func (w *IndexWriter) doFlush(ctx context.Context, applyAllDeletes bool) (bool, error) {
+	w.fullFlushLock.Lock()
+	defer w.fullFlushLock.Unlock()
+
 	err := w.doBeforeFlush()
 	if err != nil {
 		return false, err
 	}
 
-	/*
-		long seqNo = docWriter.flushAllThreads() ;
-		          if (seqNo < 0) {
-		            seqNo = -seqNo;
-		            anyChanges = true;
-		          } else {
-		            anyChanges = false;
-		          }
-		          if (!anyChanges) {
-		            // flushCount is incremented in flushAllThreads
-		            flushCount.incrementAndGet();
-		          }
-		          publishFlushedSegments(true);
-		          flushSuccess = true;
-	*/
 	anyChanges := false
-	seqNo := w.docWriter.flushAllThreads()
+	seqNo, err := w.docWriter.flushAllThreads(ctx)
+	if err != nil {
+		return false, errors.Join(err, w.docWriter.finishFullFlush(false))
+	}
 	if seqNo < 0 {
-		seqNo = -seqNo
 		anyChanges = true
 	}
 
@@ -1090,11 +1908,7 @@ func (w *IndexWriter) doFlush(applyAllDeletes bool) (bool, error) {
 		w.flushCount.Add(1)
 	}
 	err = w.publishFlushedSegments(true)
-	if err != nil {
-		return false, err
-	}
-	err = w.docWriter.finishFullFlush(true)
-	if err != nil {
+	if err := errors.Join(err, w.docWriter.finishFullFlush(err == nil)); err != nil {
 		return false, err
 	}
 	err = w.processEvents(false)
@@ -1110,19 +1924,12 @@ func (w *IndexWriter) doFlush(applyAllDeletes bool) (bool, error) {
 	}
 
 	anyChanges = anyChanges || w.boolMaybeMerge.Swap(false)
-	if anyChanges {
-		err := w.maybeMerge(w.config.GetMergePolicy(), MERGE_TRIGGER_FULL_FLUSH, UNBOUNDED_MAX_MERGE_SEGMENTS)
-		if err != nil {
-			return false, err
-		}
-	}
-
-	return false, nil
+	return anyChanges, w.doAfterFlush()
 }
 
 // Tries to delete the given files if unreferenced
 func (w *IndexWriter) deleteNewFiles(files map[string]struct{}) error {
-	return w.deleter.deleteFiles(files)
+	return w.deleter.deleteNewFiles(files)
 }
 
 func (w *IndexWriter) flushFailed(info *SegmentInfo) error {
@@ -1139,13 +1946,19 @@ func readFieldInfos(si index.SegmentCommitInfo) (index.FieldInfos, error) {
 	reader := codec.FieldInfosFormat()
 
 	if si.HasFieldUpdates() {
-
+		// there is no reader for the updated fieldinfos, they are always written outside of CFS
+		segmentSuffix := strconv.FormatInt(si.GetFieldInfosGen(), 36)
+		return reader.Read(nil, si.Info().Dir(), si.Info(), segmentSuffix, store.READONCE)
 	} else if si.Info().GetUseCompoundFile() {
-		cfs, err := codec.CompoundFormat().GetCompoundReader(nil, si.Info().Dir(), si.Info(), nil)
+		cfs, err := codec.CompoundFormat().GetCompoundReader(nil, si.Info().Dir(), si.Info(), store.READONCE)
 		if err != nil {
 			return nil, err
 		}
-		return reader.Read(nil, cfs, si.Info(), "", nil)
+		infos, err := reader.Read(nil, cfs, si.Info(), "", store.READONCE)
+		if err := errors.Join(err, cfs.Close()); err != nil {
+			return nil, err
+		}
+		return infos, nil
 	}
 
 	return reader.Read(nil, si.Info().Dir(), si.Info(), "", nil)
@@ -1164,45 +1977,42 @@ func (w *IndexWriter) startCommit(toSync *SegmentInfos) error {
 	}
 
 	if w.pendingCommitChangeCount == w.lastCommitChangeCount.Load() {
-		err := w.deleter.DecRef(w.filesToCommit)
-		if err != nil {
-			return err
-		}
-		w.filesToCommit = nil
-		return nil
+		return w.dropPendingCommit()
 	}
 
 	// Exception here means nothing is prepared
 	// (this method unwinds everything it did on
 	// an exception)
-	err := toSync.prepareCommit(context.Background(), w.directory)
-	if err != nil {
-		return err
+	if err := toSync.prepareCommit(context.Background(), w.directory); err != nil {
+		return errors.Join(err, w.dropPendingCommit())
 	}
 	w.pendingCommit = toSync
 
 	filesToSync, err := toSync.Files(false)
-	err = w.directory.Sync(filesToSync)
+	if err == nil {
+		err = w.directory.Sync(filesToSync)
+	}
 	if err != nil {
-		return err
+		return errors.Join(err, toSync.RollbackCommit(w.directory), w.dropPendingCommit())
 	}
 
 	w.segmentInfos.UpdateGeneration(toSync)
 	return nil
 }
 
+// Releases the files protected by the pending commit once it finished or failed, after a
+// failure the changes are committed by the next commit.
+func (w *IndexWriter) dropPendingCommit() error {
+	err := w.deleter.DecRef(w.filesToCommit)
+	w.pendingCommit = nil
+	w.filesToCommit = nil
+	return err
+}
+
 func (w *IndexWriter) finishCommit(ctx context.Context) error {
 	if w.pendingCommit != nil {
-		commitFiles := w.filesToCommit
-
-		err := w.deleter.DecRef(commitFiles)
-		if err != nil {
-			return err
-		}
-
-		_, err = w.pendingCommit.finishCommit(ctx, w.directory)
-		if err != nil {
-			return err
+		if _, err := w.pendingCommit.finishCommit(ctx, w.directory); err != nil {
+			return errors.Join(err, w.dropPendingCommit())
 		}
 
 		// we committed, if anything goes wrong after this, we are screwed and it's a tragedy:
@@ -1210,9 +2020,8 @@ func (w *IndexWriter) finishCommit(ctx context.Context) error {
 
 		// NOTE: don't use this.checkpoint() here, because
 		// we do not want to increment changeCount:
-		err = w.deleter.Checkpoint(w.pendingCommit, true)
-		if err != nil {
-			return err
+		if err := w.deleter.Checkpoint(w.pendingCommit, true); err != nil {
+			return errors.Join(err, w.dropPendingCommit())
 		}
 
 		// Carry over generation to our master SegmentInfos:
@@ -1221,13 +2030,15 @@ func (w *IndexWriter) finishCommit(ctx context.Context) error {
 		w.lastCommitChangeCount.Store(w.pendingCommitChangeCount)
 		w.rollbackSegments = w.pendingCommit.CreateBackupSegmentInfos()
 
-		w.pendingCommit = nil
-		w.filesToCommit = nil
+		return w.dropPendingCommit()
 	}
 	return nil
 }
 
-func (w *IndexWriter) prepareCommitInternal() (int64, error) {
+func (w *IndexWriter) prepareCommitInternal(ctx context.Context) (int64, error) {
+	w.fullFlushLock.Lock()
+	defer w.fullFlushLock.Unlock()
+
 	err := w.doBeforeFlush()
 	if err != nil {
 		return 0, err
@@ -1235,7 +2046,10 @@ func (w *IndexWriter) prepareCommitInternal() (int64, error) {
 
 	var anyChanges bool
 
-	seqNo := w.docWriter.flushAllThreads()
+	seqNo, err := w.docWriter.flushAllThreads(ctx)
+	if err != nil {
+		return 0, errors.Join(err, w.docWriter.finishFullFlush(false))
+	}
 	if seqNo < 0 {
 		anyChanges = true
 		seqNo = -seqNo
@@ -1267,6 +2081,7 @@ func (w *IndexWriter) prepareCommitInternal() (int64, error) {
 		return 0, err
 	}
 
+	w.mu.Lock()
 	if w.changeCount.Load() != w.lastCommitChangeCount.Load() {
 		// There are changes to commit, so we will write a new segments_N in startCommit.
 		// The act of committing is itself an NRT-visible change (an NRT reader that was
@@ -1294,10 +2109,10 @@ func (w *IndexWriter) prepareCommitInternal() (int64, error) {
 	// merge completes which would otherwise have
 	// removed the files we are now syncing.
 	files, err := toCommit.Files(false)
-	if err != nil {
-		return 0, err
+	if err == nil {
+		err = w.deleter.IncRefFiles(files)
 	}
-	err = w.deleter.IncRefFiles(files)
+	w.mu.Unlock()
 	if err != nil {
 		return 0, err
 	}
@@ -1344,27 +2159,105 @@ type KV struct {
 	Value string
 }
 
-func (w *IndexWriter) publishFrozenUpdates(updates *FrozenBufferedUpdates) int64 {
-	// TODO: fixme
-	return -1
+func (w *IndexWriter) publishFrozenUpdates(packet *FrozenBufferedUpdates) int64 {
+	nextGen := w.bufferedUpdatesStream.push(packet)
+	// Do this as an event so it applies higher in the stack when we are not holding DocumentsWriterFlushQueue.purgeLock:
+	w.eventQueue.Add(func(writer *IndexWriter) error {
+		if err := writer.forceApply(packet); err != nil {
+			return err
+		}
+		writer.flushDeletesCount.Add(1)
+		return nil
+	})
+	return nextGen
 }
 
-func (w *IndexWriter) publishFlushedSegment(info index.SegmentCommitInfo, infos index.FieldInfos,
-	updates *FrozenBufferedUpdates, updates2 *FrozenBufferedUpdates, sortMap index.DocMap) error {
+// Atomically adds the segment private delete packet and publishes the flushed segments SegmentInfo to the index writer.
+func (w *IndexWriter) publishFlushedSegment(newSegment index.SegmentCommitInfo, fieldInfos index.FieldInfos,
+	packet *FrozenBufferedUpdates, globalPacket *FrozenBufferedUpdates, sortMap index.DocMap) error {
 
-	panic("")
-}
+	if globalPacket != nil && globalPacket.Any() {
+		w.publishFrozenUpdates(globalPacket)
+	}
 
-func (w *IndexWriter) checkpointNoSIS() error {
-	panic("")
+	// Publishing the segment must be sync'd on IW -> BDS to make the sure
+	// that no merge prunes away the seg. private delete packet
+	var nextGen int64
+	if packet != nil && packet.Any() {
+		nextGen = w.publishFrozenUpdates(packet)
+	} else {
+		// Since we don't have a delete packet to apply we can get a new
+		// generation right away
+		nextGen = w.bufferedUpdatesStream.getNextGen()
+		// No deletes/updates here, so marked finished immediately:
+		w.bufferedUpdatesStream.finishedSegment(nextGen)
+	}
+	newSegment.SetBufferedDeletesGen(nextGen)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.segmentInfos.Add(newSegment); err != nil {
+		return err
+	}
+	if err := w.checkpoint(); err != nil {
+		return err
+	}
+
+	// the docs deleted in the DWPT while flushing may be all its docs
+	maxDoc, err := newSegment.Info().MaxDoc()
+	if err != nil {
+		return err
+	}
+	if newSegment.GetDelCount() == maxDoc {
+		rld, err := w.getPooledInstance(newSegment, true)
+		if err != nil {
+			return err
+		}
+		fullyDeleted, err := w.isFullyDeleted(rld)
+		if err != nil {
+			return errors.Join(err, w.release(rld, true))
+		}
+		if fullyDeleted {
+			if err := w.dropDeletedSegment(newSegment); err != nil {
+				return errors.Join(err, w.release(rld, true))
+			}
+			if err := w.checkpoint(); err != nil {
+				return errors.Join(err, w.release(rld, true))
+			}
+		}
+		return w.release(rld, true)
+	}
+	return nil
 }
 
+// Called whenever the SegmentInfos has been updated and the index files referenced exist (correctly) in the
+// index directory. w.mu must be held.
 func (w *IndexWriter) checkpoint() error {
-	panic("")
+	w.Changed()
+	return w.deleter.Checkpoint(w.segmentInfos, false)
+}
+
+// Checkpoints with IndexFileDeleter, so it's aware of new files, and increments changeCount, so on
+// close/commit we will write a new segments file, but does NOT bump segmentInfos.version. w.mu must be held.
+func (w *IndexWriter) checkpointNoSIS() error {
+	w.changeCount.Add(1)
+	return w.deleter.Checkpoint(w.segmentInfos, false)
 }
 
+// Drops the fully deleted segment from the index. w.mu must be held.
 func (w *IndexWriter) dropDeletedSegment(info index.SegmentCommitInfo) error {
-	panic("")
+	// it's possible that we invoke this method more than once for the same SCI
+	// we must only remove the docs once!
+	dropPendingDocs := w.segmentInfos.RemoveInfo(info)
+	dropped, err := w.readerPool.drop(info)
+	if dropPendingDocs || dropped {
+		maxDoc, maxDocErr := info.Info().MaxDoc()
+		if maxDocErr != nil {
+			return errors.Join(err, maxDocErr)
+		}
+		w.pendingNumDocs.Add(-int64(maxDoc))
+	}
+	return err
 }
 
 func (w *IndexWriter) isFullyDeleted(readersAndUpdates *ReadersAndUpdates) (bool, error) {
@@ -1398,6 +2291,9 @@ type IndexWriterConfig struct {
 	writer *IndexWriter
 
 	flushPolicy FlushPolicy
+
+	// creates the IndexSearcher resolving the deletes by query
+	indexSearcherFactory IndexSearcherFactory
 }
 
 func NewIndexWriterConfig(codec index.Codec, similarity index.Similarity) *IndexWriterConfig {
@@ -1450,6 +2346,27 @@ func (c *IndexWriterConfig) GetMergeScheduler() MergeScheduler {
 	return c.mergeScheduler
 }
 
+// SetMergeScheduler
+// Expert: sets the merge scheduler used by this writer. The default is SerialMergeScheduler.
+// Only takes effect when IndexWriter is first created.
+func (c *IndexWriterConfig) SetMergeScheduler(mergeScheduler MergeScheduler) *IndexWriterConfig {
+	c.mergeScheduler = mergeScheduler
+	return c
+}
+
+func (c *IndexWriterConfig) GetIndexSearcherFactory() IndexSearcherFactory {
+	return c.indexSearcherFactory
+}
+
+// SetIndexSearcherFactory
+// Sets the constructor of the IndexSearcher that resolves the queries of DeleteDocumentsByQuery against the
+// segments, usually search.NewIndexSearcher. Deleting documents by query fails if it is not set.
+// Only takes effect when IndexWriter is first created.
+func (c *IndexWriterConfig) SetIndexSearcherFactory(factory IndexSearcherFactory) *IndexWriterConfig {
+	c.indexSearcherFactory = factory
+	return c
+}
+
 func (c *IndexWriterConfig) GetOpenMode() OpenMode {
 	return c.openMode
 }
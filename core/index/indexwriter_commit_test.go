@@ -0,0 +1,124 @@
+package index_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/stretchr/testify/assert"
+)
+
+var errTestCommit = errors.New("failed commit")
+
+// failingCommitDirectory fails the next Sync or Rename of the pending segments file
+type failingCommitDirectory struct {
+	store.Directory
+	failSync   bool
+	failRename bool
+}
+
+func (d *failingCommitDirectory) Sync(names map[string]struct{}) error {
+	for name := range names {
+		if d.failSync && strings.HasPrefix(name, coreIndex.PENDING_SEGMENTS) {
+			d.failSync = false
+			return errTestCommit
+		}
+	}
+	return d.Directory.Sync(names)
+}
+
+func (d *failingCommitDirectory) Rename(ctx context.Context, source, dest string) error {
+	if d.failRename && strings.HasPrefix(source, coreIndex.PENDING_SEGMENTS) {
+		d.failRename = false
+		return errTestCommit
+	}
+	return d.Directory.Rename(ctx, source, dest)
+}
+
+func listSegmentsFiles(t *testing.T, dir store.Directory) []string {
+	names, err := dir.ListAll(context.Background())
+	assert.Nil(t, err)
+	files := make([]string, 0)
+	for _, name := range names {
+		if strings.HasPrefix(name, coreIndex.SEGMENTS) || strings.HasPrefix(name, coreIndex.PENDING_SEGMENTS) {
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+func openCommittedReader(t *testing.T, dir store.Directory) (int, []int) {
+	reader, err := coreIndex.OpenDirectoryReader(context.Background(), dir, nil, nil)
+	if !assert.Nil(t, err) {
+		return 0, nil
+	}
+	numDocs := reader.NumDocs()
+	subReaders := reader.GetSequentialSubReaders()
+	assert.Nil(t, reader.DecRef())
+
+	refCounts := make([]int, 0, len(subReaders))
+	for _, subReader := range subReaders {
+		refCounts = append(refCounts, subReader.GetRefCount())
+	}
+	return numDocs, refCounts
+}
+
+func TestIndexWriter_CommitDeletesOldSegmentsFiles(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := newTestIndexWriter(t, dir)
+	for i := 0; i < 3; i++ {
+		addTestDocuments(t, writer, i*10, i*10+10)
+		assert.Nil(t, writer.Commit(ctx))
+	}
+	assert.Equal(t, []string{"segments_3"}, listSegmentsFiles(t, dir))
+	assert.Nil(t, writer.Close())
+
+	// a new writer does not reuse the names of the existing segments
+	writer = newTestIndexWriter(t, dir)
+	addTestDocuments(t, writer, 30, 40)
+	assert.Nil(t, writer.Commit(ctx))
+	assert.Nil(t, writer.Close())
+
+	numDocs, refCounts := openCommittedReader(t, dir)
+	assert.Equal(t, 40, numDocs)
+	// closing the reader released its segment readers
+	for _, refCount := range refCounts {
+		assert.Equal(t, 0, refCount)
+	}
+}
+
+func TestIndexWriter_FailedCommitIsRetried(t *testing.T) {
+	ctx := context.Background()
+
+	for _, op := range []string{"sync", "rename"} {
+		nioDir, err := store.NewNIOFSDirectory(t.TempDir())
+		assert.Nil(t, err)
+		dir := &failingCommitDirectory{Directory: nioDir}
+
+		writer := newTestIndexWriter(t, dir)
+		addTestDocuments(t, writer, 0, 10)
+		assert.Nil(t, writer.Commit(ctx))
+
+		addTestDocuments(t, writer, 10, 20)
+		dir.failSync = op == "sync"
+		dir.failRename = op == "rename"
+		assert.ErrorIs(t, writer.Commit(ctx), errTestCommit, op)
+
+		// the pending segments file is rolled back and the last commit is intact
+		assert.Equal(t, []string{"segments_1"}, listSegmentsFiles(t, dir), op)
+		numDocs, _ := openCommittedReader(t, dir)
+		assert.Equal(t, 10, numDocs, op)
+
+		// the next commit publishes the changes
+		assert.Nil(t, writer.Commit(ctx))
+		numDocs, _ = openCommittedReader(t, dir)
+		assert.Equal(t, 20, numDocs, op)
+		assert.Nil(t, writer.Close())
+	}
+}
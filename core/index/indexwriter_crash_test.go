@@ -0,0 +1,101 @@
+package index_test
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/store/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMockDirectory(seed int64) *storetest.MockDirectoryWrapper {
+	dir := storetest.NewMockDirectoryWrapper(rand.New(rand.NewSource(seed)), store.NewByteBuffersDirectory())
+	// the near real-time readers read the flushed segments before they are committed
+	dir.SetAllowReadingUnsyncedFiles(true)
+	return dir
+}
+
+// asserts the last commit of dir holds numDocs documents
+func assertCommittedDocs(t *testing.T, dir store.Directory, numDocs int) {
+	reader, err := coreIndex.OpenDirectoryReader(context.Background(), dir, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, numDocs, reader.NumDocs())
+	assert.Nil(t, reader.DecRef())
+}
+
+func TestIndexWriter_Crash(t *testing.T) {
+	ctx := context.Background()
+	for seed := int64(0); seed < 10; seed++ {
+		dir := newTestMockDirectory(seed)
+
+		writer := newTestIndexWriter(t, dir)
+		addTestDocuments(t, writer, 0, 10)
+		assert.Nil(t, writer.Commit(ctx))
+		// flushed but not committed
+		addTestDocuments(t, writer, 10, 20)
+		assertNumDocs(t, writer, 20)
+		_, err := writer.DeleteDocuments(ctx, idTerm(3))
+		assert.Nil(t, err)
+		addTestDocuments(t, writer, 20, 25)
+
+		// the files which were not synced are lost or truncated, the last commit is intact
+		assert.Nil(t, dir.Crash(ctx))
+		dir.ClearCrash()
+		assertCommittedDocs(t, dir, 10)
+
+		// a new writer recovers from the last commit
+		writer = newTestIndexWriter(t, dir)
+		assertNumDocs(t, writer, 10)
+		addTestDocuments(t, writer, 10, 15)
+		assert.Nil(t, writer.Commit(ctx))
+		assertCommittedDocs(t, dir, 15)
+		assert.Nil(t, writer.Close())
+		assert.Nil(t, dir.Close())
+	}
+}
+
+func TestIndexWriter_FailedCommit(t *testing.T) {
+	ctx := context.Background()
+	errFailedSync := errors.New("failed sync")
+
+	for _, op := range []string{"createOutput", "sync", "rename"} {
+		dir := newTestMockDirectory(1)
+		writer := newTestIndexWriter(t, dir)
+		addTestDocuments(t, writer, 0, 10)
+		assert.Nil(t, writer.Commit(ctx))
+
+		// the commit fails while it writes, syncs or publishes the segments file
+		addTestDocuments(t, writer, 10, 20)
+		dir.FailOn(func(failedOp, name string) error {
+			if failedOp == op && strings.HasPrefix(name, "pending_segments") {
+				return errFailedSync
+			}
+			return nil
+		})
+		assert.ErrorIs(t, writer.Commit(ctx), errFailedSync, op)
+		dir.ClearFailures()
+
+		// the commit is rolled back: the last commit is intact and no pending segments file is left
+		assertCommittedDocs(t, dir, 10)
+		names, err := dir.ListAll(ctx)
+		assert.Nil(t, err)
+		for _, name := range names {
+			assert.False(t, strings.HasPrefix(name, "pending_segments"), name)
+		}
+
+		// the changes are committed by the next commit
+		assert.Nil(t, writer.Commit(ctx))
+		assertCommittedDocs(t, dir, 20)
+
+		// and survive a crash
+		assert.Nil(t, dir.Crash(ctx))
+		dir.ClearCrash()
+		assertCommittedDocs(t, dir, 20)
+		assert.Nil(t, dir.Close())
+	}
+}
@@ -0,0 +1,82 @@
+//go:build linux || darwin
+
+package index_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/geange/lucene-go/core/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// readAdviceDirectory records the madvise hint of the files opened through OpenInputWithContext
+type readAdviceDirectory struct {
+	*store.MMapDirectory
+
+	sync.Mutex
+	advices map[string][]store.ReadAdvice
+}
+
+func (r *readAdviceDirectory) OpenInputWithContext(ctx context.Context, name string, ioContext *store.IOContext) (store.IndexInput, error) {
+	in, err := r.MMapDirectory.OpenInputWithContext(ctx, name, ioContext)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.advices[name] = append(r.advices[name], in.(*store.MMapIndexInput).GetReadAdvice())
+	return in, nil
+}
+
+func (r *readAdviceDirectory) reset() map[string][]store.ReadAdvice {
+	r.Lock()
+	defer r.Unlock()
+	advices := r.advices
+	r.advices = make(map[string][]store.ReadAdvice)
+	return advices
+}
+
+func TestIndexWriter_MergeReadAdvice(t *testing.T) {
+	ctx := context.Background()
+	mmapDir, err := store.NewMMapDirectory(t.TempDir())
+	assert.Nil(t, err)
+	dir := &readAdviceDirectory{MMapDirectory: mmapDir, advices: make(map[string][]store.ReadAdvice)}
+
+	writer := newTestIndexWriter(t, dir)
+	for i := 0; i < 30; i += 10 {
+		addTestMergeDocuments(t, writer, i, i+10)
+		assert.Nil(t, writer.Commit(ctx))
+	}
+	flushed, err := dir.ListAll(ctx)
+	assert.Nil(t, err)
+	dir.reset()
+
+	// the readers of the merge map the files of the flushed segments sequentially
+	assert.Nil(t, writer.ForceMerge(ctx, 1))
+	merged := 0
+	for name, advices := range dir.reset() {
+		for _, file := range flushed {
+			if file == name {
+				merged++
+				for _, advice := range advices {
+					assert.Equal(t, store.READ_ADVICE_SEQUENTIAL, advice, name)
+				}
+			}
+		}
+	}
+	assert.Greater(t, merged, 0)
+
+	// the readers of searches map the merged segment randomly
+	assertNumDocs(t, writer, 30)
+	searched := dir.reset()
+	assert.NotEmpty(t, searched)
+	for name, advices := range searched {
+		for _, advice := range advices {
+			assert.Equal(t, store.READ_ADVICE_RANDOM, advice, name)
+		}
+	}
+	assert.Nil(t, writer.Close())
+}
@@ -0,0 +1,1454 @@
+package index_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/geange/lucene-go/codecs/lucene87"
+	"github.com/geange/lucene-go/codecs/lucene90"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/search"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestIndexWriter(t *testing.T, dir store.Directory) *coreIndex.IndexWriter {
+	similarity, err := search.NewBM25Similarity()
+	assert.Nil(t, err)
+	config := coreIndex.NewIndexWriterConfig(lucene87.NewCodec(lucene87.BEST_SPEED), similarity)
+	config.SetIndexSearcherFactory(search.NewIndexSearcher)
+	writer, err := coreIndex.NewIndexWriter(context.Background(), dir, config)
+	assert.Nil(t, err)
+	return writer
+}
+
+func addTestDocuments(t *testing.T, writer *coreIndex.IndexWriter, from, to int) {
+	for i := from; i < to; i++ {
+		doc := document.NewDocument()
+		doc.Add(document.NewStringField("id", strconv.Itoa(i), true))
+		_, err := writer.AddDocument(context.Background(), doc)
+		assert.Nil(t, err)
+	}
+}
+
+func assertNumDocs(t *testing.T, writer *coreIndex.IndexWriter, numDocs int) {
+	reader, err := coreIndex.DirectoryReaderOpen(context.Background(), writer)
+	assert.Nil(t, err)
+	assert.Equal(t, numDocs, reader.NumDocs())
+	assert.Nil(t, reader.DecRef())
+}
+
+func idTerm(id int) index.Term {
+	return coreIndex.NewTerm("id", []byte(strconv.Itoa(id)))
+}
+
+func TestIndexWriter_DeleteDocuments(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := newTestIndexWriter(t, dir)
+	addTestDocuments(t, writer, 0, 10)
+	assert.Nil(t, writer.Commit(ctx))
+	addTestDocuments(t, writer, 10, 15)
+
+	// deletes a flushed and a buffered document, the unknown term deletes nothing
+	seqNo, err := writer.DeleteDocuments(ctx, idTerm(3), idTerm(12), idTerm(100))
+	assert.Nil(t, err)
+	assertNumDocs(t, writer, 13)
+
+	// the deletes only apply to the documents added before them
+	addTestDocuments(t, writer, 3, 4)
+	nextSeqNo, err := writer.DeleteDocuments(ctx, idTerm(4))
+	assert.Nil(t, err)
+	assert.Greater(t, nextSeqNo, seqNo)
+	assertNumDocs(t, writer, 13)
+
+	assert.Nil(t, writer.Close())
+
+	writer = newTestIndexWriter(t, dir)
+	assertNumDocs(t, writer, 13)
+	_, err = writer.DeleteDocuments(ctx, idTerm(3))
+	assert.Nil(t, err)
+	assertNumDocs(t, writer, 12)
+	assert.Nil(t, writer.Close())
+}
+
+func TestIndexWriter_DeleteDocumentsByQuery(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := newTestIndexWriter(t, dir)
+	addTestDocuments(t, writer, 0, 10)
+	assert.Nil(t, writer.Commit(ctx))
+	addTestDocuments(t, writer, 10, 15)
+
+	_, err = writer.DeleteDocumentsByQuery(ctx, search.NewTermQuery(idTerm(5)), search.NewTermQuery(idTerm(13)))
+	assert.Nil(t, err)
+	// the query only deletes the documents added before it
+	addTestDocuments(t, writer, 13, 14)
+	assertNumDocs(t, writer, 14)
+
+	// deletes all the documents of the buffered segment
+	for id := 10; id < 15; id++ {
+		_, err = writer.DeleteDocumentsByQuery(ctx, search.NewTermQuery(idTerm(id)))
+		assert.Nil(t, err)
+	}
+	assertNumDocs(t, writer, 9)
+	assert.Nil(t, writer.Close())
+
+	writer = newTestIndexWriter(t, dir)
+	assertNumDocs(t, writer, 9)
+	assert.Nil(t, writer.Close())
+
+	// the queries can not be resolved without an IndexSearcher
+	similarity, err := search.NewBM25Similarity()
+	assert.Nil(t, err)
+	config := coreIndex.NewIndexWriterConfig(lucene87.NewCodec(lucene87.BEST_SPEED), similarity)
+	writer, err = coreIndex.NewIndexWriter(ctx, dir, config)
+	assert.Nil(t, err)
+	_, err = writer.DeleteDocumentsByQuery(ctx, search.NewTermQuery(idTerm(0)))
+	assert.NotNil(t, err)
+	assertNumDocs(t, writer, 9)
+	assert.Nil(t, writer.Close())
+}
+
+func addTestDocValuesDocuments(t *testing.T, writer *coreIndex.IndexWriter, from, to int) {
+	for i := from; i < to; i++ {
+		doc := document.NewDocument()
+		doc.Add(document.NewStringField("id", strconv.Itoa(i), true))
+		doc.Add(document.NewNumericDocValuesField("number", int64(i)))
+		doc.Add(document.NewBinaryDocValuesField("binary", []byte(strconv.Itoa(i))))
+		_, err := writer.AddDocument(context.Background(), doc)
+		assert.Nil(t, err)
+	}
+}
+
+// returns the numeric and binary doc values of the documents of a NRT reader, in the order of the documents
+func readTestDocValues(t *testing.T, writer *coreIndex.IndexWriter) ([]int64, []string) {
+	reader, err := coreIndex.DirectoryReaderOpen(context.Background(), writer)
+	assert.Nil(t, err)
+	leaves, err := reader.Leaves()
+	assert.Nil(t, err)
+
+	numbers := make([]int64, 0)
+	binaries := make([]string, 0)
+	for _, leaf := range leaves {
+		leafReader := leaf.LeafReader()
+		numericValues, err := leafReader.GetNumericDocValues("number")
+		assert.Nil(t, err)
+		binaryValues, err := leafReader.GetBinaryDocValues("binary")
+		assert.Nil(t, err)
+		for doc := 0; doc < leafReader.MaxDoc(); doc++ {
+			ok, err := numericValues.AdvanceExact(doc)
+			assert.Nil(t, err)
+			assert.True(t, ok)
+			number, err := numericValues.LongValue()
+			assert.Nil(t, err)
+			numbers = append(numbers, number)
+
+			ok, err = binaryValues.AdvanceExact(doc)
+			assert.Nil(t, err)
+			assert.True(t, ok)
+			binary, err := binaryValues.BinaryValue()
+			assert.Nil(t, err)
+			binaries = append(binaries, string(binary))
+		}
+	}
+	assert.Nil(t, reader.DecRef())
+	return numbers, binaries
+}
+
+func TestIndexWriter_UpdateNumericDocValue(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := newTestIndexWriter(t, dir)
+	addTestDocValuesDocuments(t, writer, 0, 3)
+	assert.Nil(t, writer.Commit(ctx))
+	addTestDocValuesDocuments(t, writer, 3, 5)
+
+	// updates a flushed and a buffered document
+	_, err = writer.UpdateNumericDocValue(ctx, idTerm(1), "number", 10)
+	assert.Nil(t, err)
+	_, err = writer.UpdateNumericDocValue(ctx, idTerm(4), "number", 40)
+	assert.Nil(t, err)
+	numbers, _ := readTestDocValues(t, writer)
+	assert.Equal(t, []int64{0, 10, 2, 3, 40}, numbers)
+
+	// the last update wins, and the updates only apply to the documents added before them
+	_, err = writer.UpdateNumericDocValue(ctx, idTerm(1), "number", 100)
+	assert.Nil(t, err)
+	_, err = writer.UpdateNumericDocValue(ctx, idTerm(3), "number", 30)
+	assert.Nil(t, err)
+	addTestDocValuesDocuments(t, writer, 3, 4)
+	numbers, _ = readTestDocValues(t, writer)
+	assert.Equal(t, []int64{0, 100, 2, 30, 40, 3}, numbers)
+
+	// the updated fields must exist with the same doc values type
+	_, err = writer.UpdateNumericDocValue(ctx, idTerm(1), "binary", 1)
+	assert.NotNil(t, err)
+	assert.Nil(t, writer.Close())
+
+	writer = newTestIndexWriter(t, dir)
+	numbers, _ = readTestDocValues(t, writer)
+	assert.Equal(t, []int64{0, 100, 2, 30, 40, 3}, numbers)
+	assert.Nil(t, writer.Close())
+}
+
+func TestIndexWriter_UpdateDocValues(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := newTestIndexWriter(t, dir)
+	addTestDocValuesDocuments(t, writer, 0, 3)
+	assert.Nil(t, writer.Commit(ctx))
+
+	_, err = writer.UpdateBinaryDocValue(ctx, idTerm(0), "binary", []byte("a"))
+	assert.Nil(t, err)
+	_, err = writer.UpdateDocValues(ctx, idTerm(2),
+		document.NewNumericDocValuesField("number", 20), document.NewBinaryDocValuesField("binary", []byte("b")))
+	assert.Nil(t, err)
+	numbers, binaries := readTestDocValues(t, writer)
+	assert.Equal(t, []int64{0, 1, 20}, numbers)
+	assert.Equal(t, []string{"a", "1", "b"}, binaries)
+
+	// the updates of the segment are written to a new generation again
+	_, err = writer.UpdateBinaryDocValue(ctx, idTerm(2), "binary", []byte("c"))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Commit(ctx))
+	assert.Nil(t, writer.Close())
+
+	writer = newTestIndexWriter(t, dir)
+	numbers, binaries = readTestDocValues(t, writer)
+	assert.Equal(t, []int64{0, 1, 20}, numbers)
+	assert.Equal(t, []string{"a", "1", "c"}, binaries)
+	assert.Nil(t, writer.Close())
+}
+
+func TestIndexWriter_ConcurrentDeletesAndUpdates(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := newTestIndexWriter(t, dir)
+	addTestDocValuesDocuments(t, writer, 0, 40)
+	assert.Nil(t, writer.Commit(ctx))
+
+	// every goroutine appends to the delete queue, each one on its own documents
+	var wg sync.WaitGroup
+	run := func(from, to int, fn func(id int) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := from; id < to; id++ {
+				assert.Nil(t, fn(id))
+			}
+		}()
+	}
+	run(0, 10, func(id int) error {
+		_, err := writer.DeleteDocuments(ctx, idTerm(id))
+		return err
+	})
+	run(10, 20, func(id int) error {
+		_, err := writer.DeleteDocumentsByQuery(ctx, search.NewTermQuery(idTerm(id)))
+		return err
+	})
+	run(20, 30, func(id int) error {
+		_, err := writer.UpdateNumericDocValue(ctx, idTerm(id), "number", int64(id*10))
+		return err
+	})
+	run(20, 30, func(id int) error {
+		_, err := writer.UpdateDocValues(ctx, idTerm(id),
+			document.NewBinaryDocValuesField("binary", []byte("u"+strconv.Itoa(id))))
+		return err
+	})
+	run(30, 40, func(id int) error {
+		_, err := writer.UpdateBinaryDocValue(ctx, idTerm(id), "binary", []byte("b"+strconv.Itoa(id)))
+		return err
+	})
+	wg.Wait()
+
+	expectedNumbers := make([]int64, 0)
+	expectedBinaries := make([]string, 0)
+	for id := 0; id < 40; id++ {
+		switch {
+		case id < 20:
+			expectedNumbers = append(expectedNumbers, int64(id))
+			expectedBinaries = append(expectedBinaries, strconv.Itoa(id))
+		case id < 30:
+			expectedNumbers = append(expectedNumbers, int64(id*10))
+			expectedBinaries = append(expectedBinaries, "u"+strconv.Itoa(id))
+		default:
+			expectedNumbers = append(expectedNumbers, int64(id))
+			expectedBinaries = append(expectedBinaries, "b"+strconv.Itoa(id))
+		}
+	}
+	assertNumDocs(t, writer, 20)
+	numbers, binaries := readTestDocValues(t, writer)
+	assert.Equal(t, expectedNumbers, numbers)
+	assert.Equal(t, expectedBinaries, binaries)
+	assert.Nil(t, writer.Close())
+}
+
+func addTestMergeDocuments(t *testing.T, writer *coreIndex.IndexWriter, from, to int) {
+	for i := from; i < to; i++ {
+		doc := document.NewDocument()
+		doc.Add(document.NewStringField("id", strconv.Itoa(i), true))
+		doc.Add(document.NewTextField("body", "common word"+strconv.Itoa(i%3), false))
+		doc.Add(document.NewNumericDocValuesField("number", int64(i)))
+		_, err := writer.AddDocument(context.Background(), doc)
+		assert.Nil(t, err)
+	}
+}
+
+func countTestSegments(t *testing.T, writer *coreIndex.IndexWriter) int {
+	reader, err := coreIndex.DirectoryReaderOpen(context.Background(), writer)
+	assert.Nil(t, err)
+	leaves, err := reader.Leaves()
+	assert.Nil(t, err)
+	assert.Nil(t, reader.DecRef())
+	return len(leaves)
+}
+
+// asserts the segments hold the stored fields, postings and doc values of the documents which were not deleted
+func assertTestMergedDocuments(t *testing.T, writer *coreIndex.IndexWriter, deleted ...int) {
+	ctx := context.Background()
+	reader, err := coreIndex.DirectoryReaderOpen(ctx, writer)
+	assert.Nil(t, err)
+	searcher, err := search.NewIndexSearcher(reader)
+	assert.Nil(t, err)
+
+	expectedIDs := make([]string, 0)
+	for id := 0; id < 300; id++ {
+		if !slices.Contains(deleted, id) {
+			expectedIDs = append(expectedIDs, strconv.Itoa(id))
+		}
+	}
+
+	count, err := searcher.Count(search.NewTermQuery(coreIndex.NewTerm("body", []byte("common"))))
+	assert.Nil(t, err)
+	assert.Equal(t, len(expectedIDs), count)
+	count, err = searcher.Count(search.NewTermQuery(coreIndex.NewTerm("body", []byte("word0"))))
+	assert.Nil(t, err)
+	assert.Equal(t, 100-len(deleted), count)
+
+	ids := make([]string, 0)
+	liveDocs := make([]bool, reader.MaxDoc())
+	leaves, err := reader.Leaves()
+	assert.Nil(t, err)
+	for _, leaf := range leaves {
+		leafLiveDocs := leaf.LeafReader().GetLiveDocs()
+		for doc := 0; doc < leaf.LeafReader().MaxDoc(); doc++ {
+			if leafLiveDocs == nil || leafLiveDocs.Test(uint(doc)) {
+				liveDocs[leaf.DocBase()+doc] = true
+			}
+		}
+	}
+	for doc := 0; doc < reader.MaxDoc(); doc++ {
+		if !liveDocs[doc] {
+			continue
+		}
+		visitor := document.NewDocumentStoredFieldVisitor("id")
+		assert.Nil(t, searcher.DocWithVisitor(ctx, doc, visitor))
+		id, err := visitor.GetDocument().Get("id")
+		assert.Nil(t, err)
+		ids = append(ids, id)
+	}
+	assert.Nil(t, reader.DecRef())
+	slices.SortFunc(ids, func(a, b string) int {
+		x, _ := strconv.Atoi(a)
+		y, _ := strconv.Atoi(b)
+		return x - y
+	})
+	assert.Equal(t, expectedIDs, ids)
+
+	numbers, missing := readTestNumbers(t, writer)
+	assert.Equal(t, 0, missing)
+	assert.Equal(t, len(expectedIDs), len(numbers))
+	for _, id := range deleted {
+		assert.NotContains(t, numbers, int64(id))
+	}
+}
+
+// returns the numeric doc values and the number of documents without a value
+func readTestNumbers(t *testing.T, writer *coreIndex.IndexWriter) ([]int64, int) {
+	reader, err := coreIndex.DirectoryReaderOpen(context.Background(), writer)
+	assert.Nil(t, err)
+	leaves, err := reader.Leaves()
+	assert.Nil(t, err)
+
+	numbers := make([]int64, 0)
+	missing := 0
+	for _, leaf := range leaves {
+		leafReader := leaf.LeafReader()
+		numericValues, err := leafReader.GetNumericDocValues("number")
+		assert.Nil(t, err)
+		liveDocs := leafReader.GetLiveDocs()
+		for doc := 0; doc < leafReader.MaxDoc(); doc++ {
+			if liveDocs != nil && !liveDocs.Test(uint(doc)) {
+				continue
+			}
+			ok, err := numericValues.AdvanceExact(doc)
+			assert.Nil(t, err)
+			if !ok {
+				missing++
+				continue
+			}
+			number, err := numericValues.LongValue()
+			assert.Nil(t, err)
+			numbers = append(numbers, number)
+		}
+	}
+	assert.Nil(t, reader.DecRef())
+	return numbers, missing
+}
+
+func TestIndexWriter_Merge(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := newTestIndexWriter(t, dir)
+	for i := 0; i < 300; i += 10 {
+		addTestMergeDocuments(t, writer, i, i+10)
+		assert.Nil(t, writer.Commit(ctx))
+	}
+	// the merge policy merged some of the 30 flushed segments
+	assert.Less(t, countTestSegments(t, writer), 30)
+	assertTestMergedDocuments(t, writer)
+
+	_, err = writer.DeleteDocuments(ctx, idTerm(42), idTerm(150))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.ForceMerge(ctx, 1))
+	assert.Equal(t, 1, countTestSegments(t, writer))
+	assertTestMergedDocuments(t, writer, 42, 150)
+	assert.Nil(t, writer.Close())
+
+	writer = newTestIndexWriter(t, dir)
+	assert.Equal(t, 1, countTestSegments(t, writer))
+	assertTestMergedDocuments(t, writer, 42, 150)
+	assert.NotNil(t, writer.ForceMerge(ctx, 0))
+	assert.Nil(t, writer.Close())
+}
+
+func TestIndexWriter_MergeKnnVectors(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	similarity, err := search.NewBM25Similarity()
+	assert.Nil(t, err)
+	config := coreIndex.NewIndexWriterConfig(lucene90.NewCodec(lucene87.BEST_SPEED), similarity)
+	writer, err := coreIndex.NewIndexWriter(ctx, dir, config)
+	assert.Nil(t, err)
+
+	// document i has the vector [i, 1]
+	for i := 0; i < 30; i++ {
+		doc := document.NewDocument()
+		doc.Add(document.NewStringField("id", strconv.Itoa(i), true))
+		doc.Add(document.NewNumericDocValuesField("number", int64(i)))
+		field, err := document.NewKnnVectorField("vector", []float32{float32(i), 1},
+			document.VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN)
+		assert.Nil(t, err)
+		doc.Add(field)
+		_, err = writer.AddDocument(ctx, doc)
+		assert.Nil(t, err)
+		if i%10 == 9 {
+			assert.Nil(t, writer.Commit(ctx))
+		}
+	}
+	_, err = writer.DeleteDocuments(ctx, idTerm(0), idTerm(15))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.ForceMerge(ctx, 1))
+
+	reader, err := coreIndex.DirectoryReaderOpen(ctx, writer)
+	assert.Nil(t, err)
+	leaves, err := reader.Leaves()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(leaves))
+
+	// the merged vectors follow the documents they belong to
+	leafReader := leaves[0].LeafReader()
+	assert.Equal(t, 28, leafReader.MaxDoc())
+	vectorValues, err := leafReader.GetVectorValues("vector")
+	assert.Nil(t, err)
+	assert.Equal(t, 28, vectorValues.Size())
+	assert.Equal(t, 2, vectorValues.Dimension())
+	numericValues, err := leafReader.GetNumericDocValues("number")
+	assert.Nil(t, err)
+	for doc := 0; doc < leafReader.MaxDoc(); doc++ {
+		next, err := vectorValues.NextDoc()
+		assert.Nil(t, err)
+		assert.Equal(t, doc, next)
+		vector, err := vectorValues.VectorValue()
+		assert.Nil(t, err)
+		ok, err := numericValues.AdvanceExact(doc)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		number, err := numericValues.LongValue()
+		assert.Nil(t, err)
+		assert.Equal(t, []float32{float32(number), 1}, vector)
+	}
+
+	// the merged graph finds the nearest vectors of the live documents
+	searcher, err := search.NewIndexSearcher(reader)
+	assert.Nil(t, err)
+	query, err := search.NewKnnVectorQuery("vector", []float32{0, 1}, 3)
+	assert.Nil(t, err)
+	topDocs, err := searcher.SearchTopN(ctx, query, 3)
+	assert.Nil(t, err)
+	ids := make([]string, 0)
+	for _, scoreDoc := range topDocs.GetScoreDocs() {
+		visitor := document.NewDocumentStoredFieldVisitor("id")
+		assert.Nil(t, searcher.DocWithVisitor(ctx, scoreDoc.GetDoc(), visitor))
+		id, err := visitor.GetDocument().Get("id")
+		assert.Nil(t, err)
+		ids = append(ids, id)
+	}
+	assert.Equal(t, []string{"1", "2", "3"}, ids)
+	assert.Nil(t, reader.DecRef())
+	assert.Nil(t, writer.Close())
+}
+
+func TestIndexWriter_KnnVectorsUnsupportedCodec(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	// the Lucene87 codec has no vectors format
+	writer := newTestIndexWriter(t, dir)
+	doc := document.NewDocument()
+	doc.Add(document.NewStringField("id", "0", true))
+	field, err := document.NewKnnVectorField("vector", []float32{0, 1},
+		document.VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN)
+	assert.Nil(t, err)
+	doc.Add(field)
+	_, err = writer.AddDocument(ctx, doc)
+	assert.ErrorContains(t, err, `codec Lucene87 does not support vectors: field "vector"`)
+
+	// the writer keeps indexing the other documents
+	addTestDocuments(t, writer, 1, 10)
+	assert.Nil(t, writer.Commit(ctx))
+	assertNumDocs(t, writer, 9)
+	assert.Nil(t, writer.Close())
+}
+
+func TestIndexWriter_KnnVectorsCosineZeroVector(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	similarity, err := search.NewBM25Similarity()
+	assert.Nil(t, err)
+	config := coreIndex.NewIndexWriterConfig(lucene90.NewCodec(lucene87.BEST_SPEED), similarity)
+	writer, err := coreIndex.NewIndexWriter(ctx, dir, config)
+	assert.Nil(t, err)
+
+	addVector := func(vector []float32) error {
+		doc := document.NewDocument()
+		field, err := document.NewKnnVectorField("vector", vector, document.VECTOR_SIMILARITY_FUNCTION_COSINE)
+		assert.Nil(t, err)
+		doc.Add(field)
+		_, err = writer.AddDocument(ctx, doc)
+		return err
+	}
+
+	assert.Nil(t, addVector([]float32{1, 0}))
+	// the cosine similarity of a zero vector is NaN
+	assert.ErrorContains(t, addVector([]float32{0, 0}),
+		`attempt to index a zero vector into "vector", which uses the COSINE similarity`)
+	assert.Nil(t, addVector([]float32{0, 1}))
+	assert.Nil(t, writer.Commit(ctx))
+	assertNumDocs(t, writer, 2)
+	assert.Nil(t, writer.Close())
+}
+
+func TestIndexWriter_ForceMergeDeletes(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := newTestIndexWriter(t, dir)
+	addTestMergeDocuments(t, writer, 0, 150)
+	assert.Nil(t, writer.Commit(ctx))
+	addTestMergeDocuments(t, writer, 150, 300)
+	assert.Nil(t, writer.Commit(ctx))
+	assert.Equal(t, 2, countTestSegments(t, writer))
+
+	for id := 0; id < 50; id++ {
+		_, err = writer.DeleteDocuments(ctx, idTerm(id))
+		assert.Nil(t, err)
+	}
+	// only the segment holding the deletes is rewritten
+	assert.Nil(t, writer.ForceMergeDeletes(ctx))
+	assert.Equal(t, 2, countTestSegments(t, writer))
+	_, missing := readTestNumbers(t, writer)
+	assert.Equal(t, 0, missing)
+
+	reader, err := coreIndex.DirectoryReaderOpen(ctx, writer)
+	assert.Nil(t, err)
+	assert.Equal(t, 250, reader.NumDocs())
+	assert.Equal(t, 250, reader.MaxDoc())
+	assert.Nil(t, reader.DecRef())
+	assert.Nil(t, writer.Close())
+}
+
+// runs every merge serially and records the segments the writer reports as merging while it runs
+type testMergingSegmentsScheduler struct {
+	t      *testing.T
+	writer *coreIndex.IndexWriter
+	merges int
+}
+
+func (s *testMergingSegmentsScheduler) Merge(mergeSource coreIndex.MergeSource, trigger coreIndex.MergeTrigger) error {
+	for {
+		merge, err := mergeSource.GetNextMerge()
+		if err != nil || merge == nil {
+			return err
+		}
+		assert.ElementsMatch(s.t, merge.Segments(), s.writer.GetMergingSegments())
+		if err := mergeSource.Merge(merge); err != nil {
+			return err
+		}
+		s.merges++
+	}
+}
+
+func (s *testMergingSegmentsScheduler) Close() error {
+	return nil
+}
+
+func (s *testMergingSegmentsScheduler) Initialize(dir store.Directory) {}
+
+func TestIndexWriter_GetMergingSegments(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	similarity, err := search.NewBM25Similarity()
+	assert.Nil(t, err)
+	scheduler := &testMergingSegmentsScheduler{t: t}
+	config := coreIndex.NewIndexWriterConfig(lucene87.NewCodec(lucene87.BEST_SPEED), similarity)
+	config.SetMergeScheduler(scheduler)
+	writer, err := coreIndex.NewIndexWriter(ctx, dir, config)
+	assert.Nil(t, err)
+	scheduler.writer = writer
+
+	for i := 0; i < 50; i += 10 {
+		addTestMergeDocuments(t, writer, i, i+10)
+		assert.Nil(t, writer.Commit(ctx))
+	}
+	assert.Empty(t, writer.GetMergingSegments())
+	assert.Nil(t, writer.ForceMerge(ctx, 1))
+	assert.Equal(t, 1, scheduler.merges)
+	assert.Empty(t, writer.GetMergingSegments())
+	assert.Nil(t, writer.Close())
+}
+
+func TestIndexWriter_NRTCachingDirectory(t *testing.T) {
+	ctx := context.Background()
+	dir := store.NewNRTCachingDirectory(store.NewByteBuffersDirectory(), 5, 60)
+
+	writer := newTestIndexWriter(t, dir)
+	for i := 0; i < 50; i += 10 {
+		addTestMergeDocuments(t, writer, i, i+10)
+		// the flushed segments are written with a FlushInfo and cached
+		assertNumDocs(t, writer, i+10)
+		assert.NotEmpty(t, dir.ListCachedFiles())
+		assert.Nil(t, writer.Commit(ctx))
+		assert.Empty(t, dir.ListCachedFiles())
+	}
+
+	// the merged segment is written with a MergeInfo and cached
+	assert.Nil(t, writer.ForceMerge(ctx, 1))
+	assert.Equal(t, 1, countTestSegments(t, writer))
+	assert.NotEmpty(t, dir.ListCachedFiles())
+	assert.Nil(t, writer.Commit(ctx))
+	assert.Empty(t, dir.ListCachedFiles())
+	assertNumDocs(t, writer, 50)
+	assert.Nil(t, writer.Close())
+}
+
+func newTestTermVectorsField(value string) *document.Field[string] {
+	fieldType := document.NewFieldType()
+	fieldType.SetIndexOptions(document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS)
+	fieldType.SetTokenized(true)
+	fieldType.SetStoreTermVectors(true)
+	fieldType.SetStoreTermVectorPositions(true)
+	return document.NewField("vectors", value, fieldType)
+}
+
+// returns the positions of each term in the term vectors of doc, or nil if doc has no term vectors
+func readTestTermVectors(t *testing.T, reader index.IndexReader, doc int) map[string][]int {
+	ctx := context.Background()
+	fields, err := reader.GetTermVectors(doc)
+	assert.Nil(t, err)
+	if fields == nil {
+		return nil
+	}
+	terms, err := fields.Terms("vectors")
+	assert.Nil(t, err)
+	if terms == nil {
+		return nil
+	}
+
+	vectors := make(map[string][]int)
+	termsEnum, err := terms.Iterator()
+	assert.Nil(t, err)
+	for {
+		term, err := termsEnum.Next(ctx)
+		if err != nil || term == nil {
+			break
+		}
+		postings, err := termsEnum.Postings(nil, coreIndex.POSTINGS_ENUM_POSITIONS)
+		assert.Nil(t, err)
+		_, err = postings.NextDoc()
+		assert.Nil(t, err)
+		freq, err := postings.Freq()
+		assert.Nil(t, err)
+		positions := make([]int, 0, freq)
+		for i := 0; i < freq; i++ {
+			position, err := postings.NextPosition()
+			assert.Nil(t, err)
+			positions = append(positions, position)
+		}
+		vectors[string(term)] = positions
+	}
+	return vectors
+}
+
+func TestIndexWriter_TermVectors(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := newTestIndexWriter(t, dir)
+	for i := 0; i < 20; i++ {
+		doc := document.NewDocument()
+		doc.Add(document.NewStringField("id", strconv.Itoa(i), true))
+		// every third document has no term vectors
+		if i%3 != 0 {
+			doc.Add(newTestTermVectorsField("common word" + strconv.Itoa(i) + " common"))
+		}
+		_, err := writer.AddDocument(ctx, doc)
+		assert.Nil(t, err)
+		if i == 9 {
+			assert.Nil(t, writer.Commit(ctx))
+		}
+	}
+	assert.Nil(t, writer.Commit(ctx))
+
+	reader, err := coreIndex.DirectoryReaderOpen(ctx, writer)
+	assert.Nil(t, err)
+	assert.Equal(t, 20, reader.MaxDoc())
+	for doc := 0; doc < 20; doc++ {
+		vectors := readTestTermVectors(t, reader, doc)
+		if doc%3 == 0 {
+			assert.Nil(t, vectors, doc)
+			continue
+		}
+		assert.Equal(t, map[string][]int{
+			"common":                   {0, 2},
+			"word" + strconv.Itoa(doc): {1},
+		}, vectors, doc)
+	}
+	assert.Nil(t, reader.DecRef())
+	assert.Nil(t, writer.Close())
+}
+
+func TestIndexWriter_PostingsOffsets(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	fieldType := document.NewFieldType()
+	assert.Nil(t, fieldType.SetIndexOptions(document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS))
+	assert.Nil(t, fieldType.SetTokenized(true))
+
+	writer := newTestIndexWriter(t, dir)
+	for _, value := range []string{"one two one", "two three"} {
+		doc := document.NewDocument()
+		doc.Add(document.NewField("body", value, fieldType))
+		_, err := writer.AddDocument(ctx, doc)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, writer.Commit(ctx))
+
+	reader, err := coreIndex.DirectoryReaderOpen(ctx, writer)
+	assert.Nil(t, err)
+	leaves, err := reader.Leaves()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(leaves))
+	terms, err := leaves[0].LeafReader().Terms("body")
+	assert.Nil(t, err)
+	termsEnum, err := terms.Iterator()
+	assert.Nil(t, err)
+
+	// the start and end offsets of each term per document
+	offsets := make(map[string][][]int)
+	for {
+		term, err := termsEnum.Next(ctx)
+		if err != nil || term == nil {
+			break
+		}
+		postings, err := termsEnum.Postings(nil, coreIndex.POSTINGS_ENUM_OFFSETS)
+		assert.Nil(t, err)
+		for {
+			doc, err := postings.NextDoc()
+			if err != nil || doc == types.NO_MORE_DOCS {
+				break
+			}
+			freq, err := postings.Freq()
+			assert.Nil(t, err)
+			for i := 0; i < freq; i++ {
+				_, err := postings.NextPosition()
+				assert.Nil(t, err)
+				start, err := postings.StartOffset()
+				assert.Nil(t, err)
+				end, err := postings.EndOffset()
+				assert.Nil(t, err)
+				offsets[string(term)] = append(offsets[string(term)], []int{doc, start, end})
+			}
+		}
+	}
+	assert.Equal(t, map[string][][]int{
+		"one":   {{0, 0, 3}, {0, 8, 11}},
+		"two":   {{0, 4, 7}, {1, 0, 3}},
+		"three": {{1, 4, 9}},
+	}, offsets)
+	assert.Nil(t, reader.DecRef())
+	assert.Nil(t, writer.Close())
+}
+
+func TestIndexWriter_MergeTermVectors(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := newTestIndexWriter(t, dir)
+	for i := 0; i < 100; i++ {
+		doc := document.NewDocument()
+		doc.Add(document.NewStringField("id", strconv.Itoa(i), true))
+		if i%3 != 0 {
+			doc.Add(newTestTermVectorsField("common word" + strconv.Itoa(i) + " common"))
+		}
+		_, err := writer.AddDocument(ctx, doc)
+		assert.Nil(t, err)
+		if i%10 == 9 {
+			assert.Nil(t, writer.Commit(ctx))
+		}
+	}
+	_, err = writer.DeleteDocuments(ctx, idTerm(5), idTerm(60))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.ForceMerge(ctx, 1))
+	assert.Equal(t, 1, countTestSegments(t, writer))
+
+	reader, err := coreIndex.DirectoryReaderOpen(ctx, writer)
+	assert.Nil(t, err)
+	assert.Equal(t, 98, reader.MaxDoc())
+	searcher, err := search.NewIndexSearcher(reader)
+	assert.Nil(t, err)
+	for doc := 0; doc < reader.MaxDoc(); doc++ {
+		visitor := document.NewDocumentStoredFieldVisitor("id")
+		assert.Nil(t, searcher.DocWithVisitor(ctx, doc, visitor))
+		value, err := visitor.GetDocument().Get("id")
+		assert.Nil(t, err)
+		id, err := strconv.Atoi(value)
+		assert.Nil(t, err)
+
+		vectors := readTestTermVectors(t, reader, doc)
+		if id%3 == 0 {
+			assert.Nil(t, vectors, id)
+			continue
+		}
+		assert.Equal(t, map[string][]int{
+			"common":       {0, 2},
+			"word" + value: {1},
+		}, vectors, id)
+	}
+	assert.Nil(t, reader.DecRef())
+	assert.Nil(t, writer.Close())
+}
+
+func addTestSortedDocValuesDocument(t *testing.T, writer *coreIndex.IndexWriter, i int) {
+	doc := document.NewDocument()
+	doc.Add(document.NewStringField("id", strconv.Itoa(i), true))
+	// every fifth document has no sorted doc values
+	if i%5 != 0 {
+		doc.Add(document.NewSortedDocValuesField("category", []byte("category"+strconv.Itoa(i%4))))
+		doc.Add(document.NewSortedSetDocValuesField("tags", []byte("tag"+strconv.Itoa(i%3))))
+		doc.Add(document.NewSortedSetDocValuesField("tags", []byte("tag"+strconv.Itoa(i%2+5))))
+		doc.Add(document.NewSortedSetDocValuesField("tags", []byte("tag"+strconv.Itoa(i%3))))
+		doc.Add(document.NewSortedNumericDocValuesField("numbers", int64(i)))
+		doc.Add(document.NewSortedNumericDocValuesField("numbers", int64(-i)))
+	}
+	if i%3 != 0 {
+		doc.Add(newTestTermVectorsField("common word" + strconv.Itoa(i) + " common"))
+	}
+	_, err := writer.AddDocument(context.Background(), doc)
+	assert.Nil(t, err)
+}
+
+type testSortedDocValues struct {
+	category string
+	tags     []string
+	numbers  []int64
+}
+
+// returns the sorted doc values of the live documents, by id
+func readTestSortedDocValues(t *testing.T, writer *coreIndex.IndexWriter) map[int]testSortedDocValues {
+	ctx := context.Background()
+	reader, err := coreIndex.DirectoryReaderOpen(ctx, writer)
+	assert.Nil(t, err)
+	searcher, err := search.NewIndexSearcher(reader)
+	assert.Nil(t, err)
+	leaves, err := reader.Leaves()
+	assert.Nil(t, err)
+
+	values := make(map[int]testSortedDocValues)
+	for _, leaf := range leaves {
+		leafReader := leaf.LeafReader()
+		categories, err := leafReader.GetSortedDocValues("category")
+		assert.Nil(t, err)
+		tags, err := leafReader.GetSortedSetDocValues("tags")
+		assert.Nil(t, err)
+		numbers, err := leafReader.GetSortedNumericDocValues("numbers")
+		assert.Nil(t, err)
+
+		liveDocs := leafReader.GetLiveDocs()
+		for doc := 0; doc < leafReader.MaxDoc(); doc++ {
+			if liveDocs != nil && !liveDocs.Test(uint(doc)) {
+				continue
+			}
+			visitor := document.NewDocumentStoredFieldVisitor("id")
+			assert.Nil(t, searcher.DocWithVisitor(ctx, leaf.DocBase()+doc, visitor))
+			value, err := visitor.GetDocument().Get("id")
+			assert.Nil(t, err)
+			id, err := strconv.Atoi(value)
+			assert.Nil(t, err)
+
+			var docValues testSortedDocValues
+			ok, err := categories.AdvanceExact(doc)
+			assert.Nil(t, err)
+			if ok {
+				category, err := categories.BinaryValue()
+				assert.Nil(t, err)
+				docValues.category = string(category)
+			}
+			ok, err = tags.AdvanceExact(doc)
+			assert.Nil(t, err)
+			for ok {
+				ord, err := tags.NextOrd()
+				assert.Nil(t, err)
+				if ord == coreIndex.NO_MORE_ORDS {
+					break
+				}
+				tag, err := tags.LookupOrd(ord)
+				assert.Nil(t, err)
+				docValues.tags = append(docValues.tags, string(tag))
+			}
+			ok, err = numbers.AdvanceExact(doc)
+			assert.Nil(t, err)
+			if ok {
+				for i, count := 0, numbers.DocValueCount(); i < count; i++ {
+					number, err := numbers.NextValue()
+					assert.Nil(t, err)
+					docValues.numbers = append(docValues.numbers, number)
+				}
+			}
+			values[id] = docValues
+		}
+	}
+	assert.Nil(t, reader.DecRef())
+	return values
+}
+
+func assertTestSortedDocValues(t *testing.T, values map[int]testSortedDocValues, ids ...int) {
+	assert.Equal(t, len(ids), len(values))
+	for _, id := range ids {
+		docValues, ok := values[id]
+		assert.True(t, ok, id)
+		if id%5 == 0 {
+			assert.Equal(t, testSortedDocValues{}, docValues, id)
+			continue
+		}
+		tags := []string{"tag" + strconv.Itoa(id%3), "tag" + strconv.Itoa(id%2+5)}
+		assert.Equal(t, testSortedDocValues{
+			category: "category" + strconv.Itoa(id%4),
+			tags:     tags,
+			numbers:  []int64{int64(-id), int64(id)},
+		}, docValues, id)
+	}
+}
+
+func TestIndexWriter_SortedDocValues(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := newTestIndexWriter(t, dir)
+	ids := make([]int, 0)
+	for i := 0; i < 30; i++ {
+		addTestSortedDocValuesDocument(t, writer, i)
+		ids = append(ids, i)
+		if i%10 == 9 {
+			assert.Nil(t, writer.Commit(ctx))
+		}
+	}
+	assertTestSortedDocValues(t, readTestSortedDocValues(t, writer), ids...)
+	assert.Nil(t, writer.Close())
+}
+
+func TestIndexWriter_MergeSortedDocValues(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := newTestIndexWriter(t, dir)
+	for i := 0; i < 100; i++ {
+		addTestSortedDocValuesDocument(t, writer, i)
+		if i%10 == 9 {
+			assert.Nil(t, writer.Commit(ctx))
+		}
+	}
+	// the values of a deleted document are not merged
+	doc := document.NewDocument()
+	doc.Add(document.NewStringField("id", "100", true))
+	doc.Add(document.NewSortedDocValuesField("category", []byte("deleted")))
+	doc.Add(document.NewSortedSetDocValuesField("tags", []byte("deleted")))
+	_, err = writer.AddDocument(ctx, doc)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Commit(ctx))
+
+	_, err = writer.DeleteDocuments(ctx, idTerm(7), idTerm(61), idTerm(62), idTerm(100))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.ForceMerge(ctx, 1))
+	assert.Equal(t, 1, countTestSegments(t, writer))
+
+	ids := make([]int, 0)
+	for i := 0; i < 100; i++ {
+		if !slices.Contains([]int{7, 61, 62}, i) {
+			ids = append(ids, i)
+		}
+	}
+	assertTestSortedDocValues(t, readTestSortedDocValues(t, writer), ids...)
+
+	reader, err := coreIndex.DirectoryReaderOpen(ctx, writer)
+	assert.Nil(t, err)
+	leaves, err := reader.Leaves()
+	assert.Nil(t, err)
+	categories, err := leaves[0].LeafReader().GetSortedDocValues("category")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, categories.GetValueCount())
+	tags, err := leaves[0].LeafReader().GetSortedSetDocValues("tags")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), tags.GetValueCount())
+
+	searcher, err := search.NewIndexSearcher(reader)
+	assert.Nil(t, err)
+	for doc := 0; doc < reader.MaxDoc(); doc++ {
+		visitor := document.NewDocumentStoredFieldVisitor("id")
+		assert.Nil(t, searcher.DocWithVisitor(ctx, doc, visitor))
+		value, err := visitor.GetDocument().Get("id")
+		assert.Nil(t, err)
+		id, err := strconv.Atoi(value)
+		assert.Nil(t, err)
+
+		vectors := readTestTermVectors(t, reader, doc)
+		if id%3 == 0 {
+			assert.Nil(t, vectors, id)
+			continue
+		}
+		assert.Equal(t, map[string][]int{
+			"common":       {0, 2},
+			"word" + value: {1},
+		}, vectors, id)
+	}
+	assert.Nil(t, reader.DecRef())
+	assert.Nil(t, writer.Close())
+}
+
+// asserts the term vectors of every document match its id
+func assertTestDocTermVectors(t *testing.T, writer *coreIndex.IndexWriter) {
+	ctx := context.Background()
+	reader, err := coreIndex.DirectoryReaderOpen(ctx, writer)
+	assert.Nil(t, err)
+	searcher, err := search.NewIndexSearcher(reader)
+	assert.Nil(t, err)
+	for doc := 0; doc < reader.MaxDoc(); doc++ {
+		visitor := document.NewDocumentStoredFieldVisitor("id")
+		assert.Nil(t, searcher.DocWithVisitor(ctx, doc, visitor))
+		value, err := visitor.GetDocument().Get("id")
+		assert.Nil(t, err)
+		id, err := strconv.Atoi(value)
+		assert.Nil(t, err)
+
+		vectors := readTestTermVectors(t, reader, doc)
+		if id%3 == 0 {
+			assert.Nil(t, vectors, id)
+			continue
+		}
+		assert.Equal(t, map[string][]int{
+			"common":       {0, 2},
+			"word" + value: {1},
+		}, vectors, id)
+	}
+	assert.Nil(t, reader.DecRef())
+}
+
+func TestIndexWriter_DefaultMergePolicy(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	similarity, err := search.NewBM25Similarity()
+	assert.Nil(t, err)
+	config := coreIndex.NewIndexWriterConfig(lucene87.NewCodec(lucene87.BEST_SPEED), similarity)
+	config.SetIndexSearcherFactory(search.NewIndexSearcher)
+	writer, err := coreIndex.NewIndexWriter(ctx, dir, config)
+	assert.Nil(t, err)
+
+	ids := make([]int, 0)
+	for i := 0; i < 150; i++ {
+		addTestSortedDocValuesDocument(t, writer, i)
+		ids = append(ids, i)
+		if i%10 == 9 {
+			assert.Nil(t, writer.Commit(ctx))
+		}
+	}
+	// the default merge policy merged some of the 15 flushed segments
+	assert.Less(t, countTestSegments(t, writer), 15)
+	assertTestSortedDocValues(t, readTestSortedDocValues(t, writer), ids...)
+	assertTestDocTermVectors(t, writer)
+	assert.Nil(t, writer.Close())
+}
+
+func newTestIndexSortWriter(t *testing.T, dir store.Directory) *coreIndex.IndexWriter {
+	similarity, err := search.NewBM25Similarity()
+	assert.Nil(t, err)
+	config := coreIndex.NewIndexWriterConfig(lucene87.NewCodec(lucene87.BEST_SPEED), similarity)
+	config.SetIndexSearcherFactory(search.NewIndexSearcher)
+	// sorts by category, then by descending rank
+	assert.Nil(t, config.SetIndexSort(coreIndex.NewSort([]index.SortField{
+		coreIndex.NewSortField("category", index.STRING),
+		coreIndex.NewSortFieldV1("rank", index.LONG, true),
+	})))
+	writer, err := coreIndex.NewIndexWriter(context.Background(), dir, config)
+	assert.Nil(t, err)
+	return writer
+}
+
+func testRank(id int) int64 {
+	return int64(id * 37 % 101)
+}
+
+func addTestIndexSortDocument(t *testing.T, writer *coreIndex.IndexWriter, id int) {
+	doc := document.NewDocument()
+	doc.Add(document.NewStringField("id", strconv.Itoa(id), true))
+	doc.Add(document.NewSortedDocValuesField("category", []byte("category"+strconv.Itoa(id%3))))
+	doc.Add(document.NewNumericDocValuesField("rank", testRank(id)))
+	if id%4 != 0 {
+		doc.Add(newTestTermVectorsField("common word" + strconv.Itoa(id) + " common"))
+	}
+	_, err := writer.AddDocument(context.Background(), doc)
+	assert.Nil(t, err)
+}
+
+// asserts the documents of every segment are in the index sort order, and their stored fields,
+// doc values, postings and term vectors belong to the same document. Returns the ids of the live
+// documents.
+func assertTestIndexSortedSegments(t *testing.T, writer *coreIndex.IndexWriter) []int {
+	ctx := context.Background()
+	reader, err := coreIndex.DirectoryReaderOpen(ctx, writer)
+	assert.Nil(t, err)
+	searcher, err := search.NewIndexSearcher(reader)
+	assert.Nil(t, err)
+	leaves, err := reader.Leaves()
+	assert.Nil(t, err)
+
+	liveIDs := make([]int, 0)
+	for _, leaf := range leaves {
+		leafReader := leaf.LeafReader()
+		categories, err := leafReader.GetSortedDocValues("category")
+		assert.Nil(t, err)
+		ranks, err := leafReader.GetNumericDocValues("rank")
+		assert.Nil(t, err)
+
+		ids := make([]int, leafReader.MaxDoc())
+		lastCategory, lastRank := "", int64(0)
+		for doc := range ids {
+			visitor := document.NewDocumentStoredFieldVisitor("id")
+			assert.Nil(t, searcher.DocWithVisitor(ctx, leaf.DocBase()+doc, visitor))
+			value, err := visitor.GetDocument().Get("id")
+			assert.Nil(t, err)
+			id, err := strconv.Atoi(value)
+			assert.Nil(t, err)
+			ids[doc] = id
+
+			ok, err := categories.AdvanceExact(doc)
+			assert.Nil(t, err)
+			assert.True(t, ok, id)
+			category, err := categories.BinaryValue()
+			assert.Nil(t, err)
+			assert.Equal(t, "category"+strconv.Itoa(id%3), string(category), id)
+			ok, err = ranks.AdvanceExact(doc)
+			assert.Nil(t, err)
+			assert.True(t, ok, id)
+			rank, err := ranks.LongValue()
+			assert.Nil(t, err)
+			assert.Equal(t, testRank(id), rank, id)
+
+			if doc > 0 {
+				assert.LessOrEqual(t, lastCategory, string(category), id)
+				if lastCategory == string(category) {
+					assert.Greater(t, lastRank, rank, id)
+				}
+			}
+			lastCategory, lastRank = string(category), rank
+
+			vectors := readTestTermVectors(t, reader, leaf.DocBase()+doc)
+			if id%4 == 0 {
+				assert.Nil(t, vectors, id)
+			} else {
+				assert.Equal(t, map[string][]int{
+					"common":       {0, 2},
+					"word" + value: {1},
+				}, vectors, id)
+			}
+
+			liveDocs := leafReader.GetLiveDocs()
+			if liveDocs == nil || liveDocs.Test(uint(doc)) {
+				liveIDs = append(liveIDs, id)
+			}
+		}
+
+		// the postings of a term are the documents holding it
+		terms, err := leafReader.Terms("vectors")
+		assert.Nil(t, err)
+		termsEnum, err := terms.Iterator()
+		assert.Nil(t, err)
+		for {
+			term, err := termsEnum.Next(ctx)
+			if err != nil || term == nil {
+				break
+			}
+			postings, err := termsEnum.Postings(nil, coreIndex.POSTINGS_ENUM_FREQS)
+			assert.Nil(t, err)
+			docs := make([]int, 0)
+			for {
+				doc, err := postings.NextDoc()
+				if err != nil || doc == types.NO_MORE_DOCS {
+					break
+				}
+				docs = append(docs, doc)
+			}
+			assert.True(t, slices.IsSorted(docs), string(term))
+			for _, doc := range docs {
+				if string(term) != "common" {
+					assert.Equal(t, "word"+strconv.Itoa(ids[doc]), string(term))
+				}
+				assert.NotEqual(t, 0, ids[doc]%4, string(term))
+			}
+		}
+	}
+	assert.Nil(t, reader.DecRef())
+	slices.Sort(liveIDs)
+	return liveIDs
+}
+
+func TestIndexWriter_IndexSort(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := newTestIndexSortWriter(t, dir)
+	ids := make([]int, 0)
+	for i := 0; i < 60; i++ {
+		addTestIndexSortDocument(t, writer, i)
+		if i%10 == 9 {
+			assert.Nil(t, writer.Commit(ctx))
+		}
+		if i != 13 && i != 42 {
+			ids = append(ids, i)
+		}
+	}
+	_, err = writer.DeleteDocuments(ctx, idTerm(13), idTerm(42))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Commit(ctx))
+	// the flushed segments are sorted
+	assert.Equal(t, ids, assertTestIndexSortedSegments(t, writer))
+
+	assert.Nil(t, writer.ForceMerge(ctx, 1))
+	assert.Equal(t, 1, countTestSegments(t, writer))
+	assert.Equal(t, ids, assertTestIndexSortedSegments(t, writer))
+	assert.Nil(t, writer.Close())
+
+	writer = newTestIndexSortWriter(t, dir)
+	assert.Equal(t, ids, assertTestIndexSortedSegments(t, writer))
+	assert.Nil(t, writer.Close())
+}
+
+// fails every merge it is asked to run until it is healed
+type testFailingMergeScheduler struct {
+	calls  int
+	healed bool
+}
+
+func (s *testFailingMergeScheduler) Merge(mergeSource coreIndex.MergeSource, trigger coreIndex.MergeTrigger) error {
+	s.calls++
+	if s.healed {
+		return nil
+	}
+	return errors.New("merge failed")
+}
+
+func (s *testFailingMergeScheduler) Close() error {
+	return nil
+}
+
+func (s *testFailingMergeScheduler) Initialize(dir store.Directory) {}
+
+func TestIndexWriter_MergeErrors(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	similarity, err := search.NewBM25Similarity()
+	assert.Nil(t, err)
+	scheduler := &testFailingMergeScheduler{}
+	config := coreIndex.NewIndexWriterConfig(lucene87.NewCodec(lucene87.BEST_SPEED), similarity)
+	config.SetMergeScheduler(scheduler)
+	writer, err := coreIndex.NewIndexWriter(ctx, dir, config)
+	assert.Nil(t, err)
+
+	// the readers are opened even though the merges triggered by their flushes fail
+	for i := 0; i < 3; i++ {
+		addTestDocuments(t, writer, i*10, (i+1)*10)
+		assertNumDocs(t, writer, (i+1)*10)
+	}
+	assert.Equal(t, 3, scheduler.calls)
+
+	// the failures are reported once, by the next explicit merge
+	assert.ErrorContains(t, writer.MaybeMerge(), "merge failed")
+	assert.Equal(t, 4, scheduler.calls)
+
+	scheduler.healed = true
+	assert.Nil(t, writer.MaybeMerge())
+	assert.Nil(t, writer.Close())
+}
+
+func TestIndexWriter_Closed(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := newTestIndexWriter(t, dir)
+	addTestDocuments(t, writer, 0, 10)
+	assert.Nil(t, writer.Close())
+	assert.True(t, writer.IsClosed())
+
+	_, err = writer.AddDocument(ctx, document.NewDocument())
+	assert.ErrorContains(t, err, "already closed")
+	_, err = writer.DeleteDocuments(ctx, idTerm(3))
+	assert.ErrorContains(t, err, "already closed")
+	assert.ErrorContains(t, writer.Commit(ctx), "already closed")
+	assert.ErrorContains(t, writer.MaybeMerge(), "already closed")
+	// closing again is a no-op
+	assert.Nil(t, writer.Close())
+}
+
+func TestIndexWriter_ConcurrentMergeBookkeeping(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := newTestIndexWriter(t, dir)
+	for i := 0; i < 5; i++ {
+		addTestDocuments(t, writer, i*10, (i+1)*10)
+		assert.Nil(t, writer.Commit(ctx))
+	}
+
+	// the merging segments are read while the merges are registered, run and finished
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for !writer.IsClosed() {
+			for _, info := range writer.GetMergingSegments() {
+				assert.NotNil(t, info)
+			}
+		}
+	}()
+	assert.Nil(t, writer.ForceMerge(ctx, 1))
+	assert.Nil(t, writer.Close())
+	<-done
+}
+
+func TestIndexWriter_MergeErrorsOnCommitAndClose(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	similarity, err := search.NewBM25Similarity()
+	assert.Nil(t, err)
+	scheduler := &testFailingMergeScheduler{}
+	config := coreIndex.NewIndexWriterConfig(lucene87.NewCodec(lucene87.BEST_SPEED), similarity)
+	config.SetMergeScheduler(scheduler)
+	writer, err := coreIndex.NewIndexWriter(ctx, dir, config)
+	assert.Nil(t, err)
+
+	// the merge triggered by the flush of the reader fails, the merges of the commit succeed
+	addTestDocuments(t, writer, 0, 10)
+	assertNumDocs(t, writer, 10)
+	scheduler.healed = true
+	assert.ErrorContains(t, writer.Commit(ctx), "merge failed")
+	assert.Nil(t, writer.Commit(ctx))
+
+	scheduler.healed = false
+	addTestDocuments(t, writer, 10, 20)
+	assertNumDocs(t, writer, 20)
+	scheduler.healed = true
+	assert.ErrorContains(t, writer.Close(), "merge failed")
+	assert.True(t, writer.IsClosed())
+}
+
+func TestIndexWriter_ConcurrentAddsAndDeletes(t *testing.T) {
+	ctx := context.Background()
+	dir, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := newTestIndexWriter(t, dir)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := g * 100; i < (g+1)*100; i++ {
+				doc := document.NewDocument()
+				doc.Add(document.NewStringField("id", strconv.Itoa(i), true))
+				_, err := writer.AddDocument(ctx, doc)
+				assert.Nil(t, err)
+				if i%2 == 0 {
+					_, err := writer.DeleteDocuments(ctx, idTerm(i))
+					assert.Nil(t, err)
+				}
+				if i%50 == 0 {
+					assert.Nil(t, writer.Commit(ctx))
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	assertNumDocs(t, writer, 200)
+	assert.Nil(t, writer.Close())
+}
@@ -207,10 +207,10 @@ func newLiveIndexWriterConfig(analyzer analysis.Analyzer, codec index.Codec, sim
 		openMode:                    CREATE_OR_APPEND,
 		createdVersionMajor:         int(version.Last.Major()),
 		similarity:                  similarity,
-		mergeScheduler:              NewNoMergeScheduler(),
+		mergeScheduler:              NewSerialMergeScheduler(),
 		indexingChain:               defaultIndexingChainInstance,
 		codec:                       codec,
-		mergePolicy:                 NewNoMergePolicy(),
+		mergePolicy:                 NewTieredMergePolicy(),
 		readerPooling:               DEFAULT_READER_POOLING,
 		flushPolicy:                 nil,
 		perThreadHardLimitMB:        DEFAULT_RAM_PER_THREAD_HARD_LIMIT_MB,
@@ -0,0 +1,396 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"slices"
+
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/types"
+)
+
+var _ index.Fields = &MappedMultiFields{}
+
+// MappedMultiFields
+// A Fields implementation that merges the postings of the segments of a MergeState, mapping the
+// documents to their document IDs in the merged segment and skipping the deleted ones.
+type MappedMultiFields struct {
+	mergeState *MergeState
+	names      []string
+}
+
+func NewMappedMultiFields(mergeState *MergeState) *MappedMultiFields {
+	names := make([]string, 0)
+	for _, producer := range mergeState.FieldsProducers {
+		if producer == nil {
+			continue
+		}
+		for _, name := range producer.Names() {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return &MappedMultiFields{mergeState: mergeState, names: names}
+}
+
+func (m *MappedMultiFields) Names() []string {
+	return m.names
+}
+
+func (m *MappedMultiFields) Terms(field string) (index.Terms, error) {
+	fieldInfo := m.mergeState.MergeFieldInfos.FieldInfo(field)
+	if fieldInfo == nil {
+		return nil, nil
+	}
+
+	subs := make([]*mappedTermsSub, 0, len(m.mergeState.FieldsProducers))
+	for i, producer := range m.mergeState.FieldsProducers {
+		if producer == nil {
+			continue
+		}
+		terms, err := producer.Terms(field)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				continue
+			}
+			return nil, err
+		}
+		if terms == nil {
+			continue
+		}
+		subs = append(subs, &mappedTermsSub{terms: terms, docMap: m.mergeState.DocMaps[i]})
+	}
+	if len(subs) == 0 {
+		return nil, nil
+	}
+	return newMappedMultiTerms(fieldInfo, subs, m.mergeState.NeedsIndexSort), nil
+}
+
+func (m *MappedMultiFields) Size() int {
+	return len(m.names)
+}
+
+type mappedTermsSub struct {
+	terms  index.Terms
+	docMap MergeStateDocMap
+}
+
+var _ index.Terms = &mappedMultiTerms{}
+
+type mappedMultiTerms struct {
+	*BaseTerms
+
+	fieldInfo     *document.FieldInfo
+	subs          []*mappedTermsSub
+	indexIsSorted bool
+}
+
+func newMappedMultiTerms(fieldInfo *document.FieldInfo, subs []*mappedTermsSub, indexIsSorted bool) *mappedMultiTerms {
+	terms := &mappedMultiTerms{fieldInfo: fieldInfo, subs: subs, indexIsSorted: indexIsSorted}
+	terms.BaseTerms = NewTerms(terms)
+	return terms
+}
+
+func (m *mappedMultiTerms) Iterator() (index.TermsEnum, error) {
+	subs := make([]*mappedTermsEnumSub, 0, len(m.subs))
+	for _, sub := range m.subs {
+		termsEnum, err := sub.terms.Iterator()
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, &mappedTermsEnumSub{termsEnum: termsEnum, docMap: sub.docMap})
+	}
+	return newMappedMultiTermsEnum(subs, m.indexIsSorted), nil
+}
+
+func (m *mappedMultiTerms) Size() (int, error) {
+	// the number of unique terms across the segments is unknown
+	return -1, nil
+}
+
+func (m *mappedMultiTerms) GetSumTotalTermFreq() (int64, error) {
+	sum := int64(0)
+	for _, sub := range m.subs {
+		v, err := sub.terms.GetSumTotalTermFreq()
+		if err != nil {
+			return 0, err
+		}
+		sum += v
+	}
+	return sum, nil
+}
+
+func (m *mappedMultiTerms) GetSumDocFreq() (int64, error) {
+	sum := int64(0)
+	for _, sub := range m.subs {
+		v, err := sub.terms.GetSumDocFreq()
+		if err != nil {
+			return 0, err
+		}
+		sum += v
+	}
+	return sum, nil
+}
+
+func (m *mappedMultiTerms) GetDocCount() (int, error) {
+	sum := 0
+	for _, sub := range m.subs {
+		v, err := sub.terms.GetDocCount()
+		if err != nil {
+			return 0, err
+		}
+		sum += v
+	}
+	return sum, nil
+}
+
+func (m *mappedMultiTerms) HasFreqs() bool {
+	return m.fieldInfo.GetIndexOptions() >= document.INDEX_OPTIONS_DOCS_AND_FREQS
+}
+
+func (m *mappedMultiTerms) HasOffsets() bool {
+	return m.fieldInfo.GetIndexOptions() >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS_AND_OFFSETS
+}
+
+func (m *mappedMultiTerms) HasPositions() bool {
+	return m.fieldInfo.GetIndexOptions() >= document.INDEX_OPTIONS_DOCS_AND_FREQS_AND_POSITIONS
+}
+
+func (m *mappedMultiTerms) HasPayloads() bool {
+	return m.fieldInfo.HasPayloads()
+}
+
+type mappedTermsEnumSub struct {
+	termsEnum index.TermsEnum
+	docMap    MergeStateDocMap
+	term      []byte // current term, nil once the sub is exhausted
+}
+
+func (s *mappedTermsEnumSub) next(ctx context.Context) error {
+	term, err := s.termsEnum.Next(ctx)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			s.term = nil
+			return nil
+		}
+		return err
+	}
+	// the sub enums may reuse their term buffer
+	s.term = bytes.Clone(term)
+	return nil
+}
+
+var _ index.TermsEnum = &mappedMultiTermsEnum{}
+
+// mappedMultiTermsEnum walks the union of the terms of the segments in term order; the postings
+// of a term merge the postings of the segments holding it.
+type mappedMultiTermsEnum struct {
+	*BaseTermsEnum
+
+	subs          []*mappedTermsEnumSub
+	top           []*mappedTermsEnumSub // the subs positioned on the current term, in segment order
+	current       []byte
+	started       bool
+	indexIsSorted bool
+}
+
+func newMappedMultiTermsEnum(subs []*mappedTermsEnumSub, indexIsSorted bool) *mappedMultiTermsEnum {
+	termsEnum := &mappedMultiTermsEnum{subs: subs, indexIsSorted: indexIsSorted}
+	termsEnum.BaseTermsEnum = NewBaseTermsEnum(&BaseTermsEnumConfig{SeekCeil: termsEnum.SeekCeil})
+	return termsEnum
+}
+
+func (m *mappedMultiTermsEnum) Next(ctx context.Context) ([]byte, error) {
+	toAdvance := m.top
+	if !m.started {
+		toAdvance = m.subs
+		m.started = true
+	}
+	for _, sub := range toAdvance {
+		if err := sub.next(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	m.top = m.top[:0]
+	m.current = nil
+	for _, sub := range m.subs {
+		if sub.term == nil {
+			continue
+		}
+		if m.current == nil {
+			m.current = sub.term
+			m.top = append(m.top, sub)
+			continue
+		}
+		switch cmp := bytes.Compare(sub.term, m.current); {
+		case cmp < 0:
+			m.current = sub.term
+			m.top = append(m.top[:0], sub)
+		case cmp == 0:
+			m.top = append(m.top, sub)
+		}
+	}
+
+	if m.current == nil {
+		return nil, io.EOF
+	}
+	return m.current, nil
+}
+
+func (m *mappedMultiTermsEnum) SeekCeil(ctx context.Context, text []byte) (index.SeekStatus, error) {
+	return 0, ErrUnsupportedOperation
+}
+
+func (m *mappedMultiTermsEnum) SeekExactByOrd(ctx context.Context, ord int64) error {
+	return ErrUnsupportedOperation
+}
+
+func (m *mappedMultiTermsEnum) Term() ([]byte, error) {
+	return m.current, nil
+}
+
+func (m *mappedMultiTermsEnum) Ord() (int64, error) {
+	return 0, ErrUnsupportedOperation
+}
+
+func (m *mappedMultiTermsEnum) DocFreq() (int, error) {
+	sum := 0
+	for _, sub := range m.top {
+		v, err := sub.termsEnum.DocFreq()
+		if err != nil {
+			return 0, err
+		}
+		sum += v
+	}
+	return sum, nil
+}
+
+func (m *mappedMultiTermsEnum) TotalTermFreq() (int64, error) {
+	sum := int64(0)
+	for _, sub := range m.top {
+		v, err := sub.termsEnum.TotalTermFreq()
+		if err != nil {
+			return 0, err
+		}
+		sum += v
+	}
+	return sum, nil
+}
+
+func (m *mappedMultiTermsEnum) Postings(reuse index.PostingsEnum, flags int) (index.PostingsEnum, error) {
+	subs := make([]*mappedPostingsSub, 0, len(m.top))
+	for _, sub := range m.top {
+		postings, err := sub.termsEnum.Postings(nil, flags)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, &mappedPostingsSub{postings: postings, docMap: sub.docMap})
+	}
+	return newMappingMultiPostingsEnum(subs, m.indexIsSorted), nil
+}
+
+func (m *mappedMultiTermsEnum) Impacts(flags int) (index.ImpactsEnum, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+type mappedPostingsSub struct {
+	postings index.PostingsEnum
+	docMap   MergeStateDocMap
+}
+
+func (s *mappedPostingsSub) nextMappedDoc() (int, error) {
+	for {
+		doc, err := nextDocOrExhausted(s.postings)
+		if err != nil || doc == types.NO_MORE_DOCS {
+			return doc, err
+		}
+		if mapped := s.docMap.Get(doc); mapped != -1 {
+			return mapped, nil
+		}
+		// deleted document
+	}
+}
+
+var _ index.PostingsEnum = &mappingMultiPostingsEnum{}
+
+// mappingMultiPostingsEnum merges the postings of the segments, mapping the documents to the
+// merged segment and skipping the deleted ones.
+type mappingMultiPostingsEnum struct {
+	subs    []*mappedPostingsSub
+	merger  *docIDMerger[*mappedPostingsSub]
+	current *mappedPostingsSub
+	doc     int
+}
+
+func newMappingMultiPostingsEnum(subs []*mappedPostingsSub, indexIsSorted bool) *mappingMultiPostingsEnum {
+	return &mappingMultiPostingsEnum{
+		subs:   subs,
+		merger: newDocIDMerger(subs, indexIsSorted),
+		doc:    -1,
+	}
+}
+
+func (m *mappingMultiPostingsEnum) DocID() int {
+	return m.doc
+}
+
+func (m *mappingMultiPostingsEnum) NextDoc() (int, error) {
+	doc, err := m.merger.next()
+	if err != nil {
+		return 0, err
+	}
+	m.doc = doc
+	if doc != types.NO_MORE_DOCS {
+		m.current = m.merger.currentSub()
+	}
+	return doc, nil
+}
+
+func (m *mappingMultiPostingsEnum) Advance(target int) (int, error) {
+	return m.SlowAdvance(target)
+}
+
+func (m *mappingMultiPostingsEnum) SlowAdvance(target int) (int, error) {
+	doc := m.doc
+	for doc < target {
+		var err error
+		if doc, err = m.NextDoc(); err != nil {
+			return 0, err
+		}
+	}
+	return doc, nil
+}
+
+func (m *mappingMultiPostingsEnum) Cost() int64 {
+	cost := int64(0)
+	for _, sub := range m.subs {
+		cost += sub.postings.Cost()
+	}
+	return cost
+}
+
+func (m *mappingMultiPostingsEnum) Freq() (int, error) {
+	return m.current.postings.Freq()
+}
+
+func (m *mappingMultiPostingsEnum) NextPosition() (int, error) {
+	return m.current.postings.NextPosition()
+}
+
+func (m *mappingMultiPostingsEnum) StartOffset() (int, error) {
+	return m.current.postings.StartOffset()
+}
+
+func (m *mappingMultiPostingsEnum) EndOffset() (int, error) {
+	return m.current.postings.EndOffset()
+}
+
+func (m *mappingMultiPostingsEnum) GetPayload() ([]byte, error) {
+	return m.current.postings.GetPayload()
+}
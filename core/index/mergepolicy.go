@@ -1,10 +1,13 @@
 package index
 
 import (
-	"github.com/geange/lucene-go/core/interface/index"
+	"errors"
 	"math"
+	"slices"
 	"sync"
 
+	"github.com/geange/lucene-go/core/interface/index"
+
 	"github.com/geange/lucene-go/core/util"
 )
 
@@ -222,7 +225,7 @@ type MergeContext interface {
 	NumDeletesToMerge(info index.SegmentCommitInfo) (int, error)
 
 	// NumDeletedDocs Returns the number of deleted documents in the given segments.
-	NumDeletedDocs(info index.SegmentCommitInfo) int
+	NumDeletedDocs(info index.SegmentCommitInfo) (int, error)
 
 	// Returns the info stream that can be used to log messages
 	//getInfoStream() util.InfoStream
@@ -262,6 +265,41 @@ type OneMerge struct {
 	totalMaxDoc int64
 }
 
+// NewOneMerge
+// segments: List of SegmentCommitInfos to be merged.
+func NewOneMerge(segments []index.SegmentCommitInfo) (*OneMerge, error) {
+	if len(segments) == 0 {
+		return nil, errors.New("segments must include at least one segment")
+	}
+
+	// clone the list, as the in list may be based off original SegmentInfos and may be modified
+	segments = slices.Clone(segments)
+	totalMaxDoc := int64(0)
+	for _, info := range segments {
+		maxDoc, err := info.Info().MaxDoc()
+		if err != nil {
+			return nil, err
+		}
+		totalMaxDoc += int64(maxDoc)
+	}
+
+	return &OneMerge{
+		maxNumSegments: -1,
+		segments:       segments,
+		totalMaxDoc:    totalMaxDoc,
+	}, nil
+}
+
+// Segments Returns the segments to be merged.
+func (m *OneMerge) Segments() []index.SegmentCommitInfo {
+	return m.segments
+}
+
+// TotalMaxDoc Returns the total number of documents in the segments to be merged, not accounting for deletions.
+func (m *OneMerge) TotalMaxDoc() int64 {
+	return m.totalMaxDoc
+}
+
 // A MergeSpecification instance provides the information necessary to perform multiple merges.
 // It simply contains a list of MergePolicy.OneMerge instances.
 type MergeSpecification struct {
@@ -278,6 +316,11 @@ func (m *MergeSpecification) Add(merge *OneMerge) {
 	m.merges = append(m.merges, merge)
 }
 
+// Merges Returns the merges of this specification.
+func (m *MergeSpecification) Merges() []*OneMerge {
+	return m.merges
+}
+
 // OneMergeProgress Progress and state for an executing merge. This class encapsulates the logic to pause
 // and resume the merge thread or to abort the merge entirely.
 // lucene.experimental
@@ -7,7 +7,7 @@ import (
 
 // MergeScheduler
 // Expert: IndexWriter uses an instance implementing this interface to execute the merges selected by a MergePolicy.
-// The default MergeScheduler is ConcurrentMergeScheduler.
+// The default MergeScheduler is SerialMergeScheduler.
 // lucene.experimental
 type MergeScheduler interface {
 	io.Closer
@@ -47,23 +47,20 @@ type indexWriterMergeSource struct {
 }
 
 func (i *indexWriterMergeSource) GetNextMerge() (*OneMerge, error) {
-	//TODO implement me
-	panic("implement me")
+	return i.writer.getNextMerge(), nil
 }
 
 func (i *indexWriterMergeSource) OnMergeFinished(merge *OneMerge) error {
-	//TODO implement me
-	panic("implement me")
+	i.writer.mergeFinish(merge)
+	return nil
 }
 
 func (i *indexWriterMergeSource) HasPendingMerges() bool {
-	//TODO implement me
-	panic("implement me")
+	return i.writer.hasPendingMerges()
 }
 
 func (i *indexWriterMergeSource) Merge(merge *OneMerge) error {
-	//TODO implement me
-	panic("implement me")
+	return i.writer.merge(merge)
 }
 
 func newIndexWriterMergeSource(writer *IndexWriter) *indexWriterMergeSource {
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"github.com/geange/lucene-go/core/interface/index"
 	"github.com/geange/lucene-go/core/util"
-	"github.com/geange/lucene-go/core/util/packed"
 )
 
 // MergeState
@@ -43,6 +42,9 @@ type MergeState struct {
 	// Point readers to merge
 	PointsReaders []index.PointsReader
 
+	// Vector readers to merge
+	KnnVectorsReaders []index.KnnVectorsReader
+
 	// Max docs per reader
 	MaxDocs []int
 
@@ -68,6 +70,7 @@ func NewMergeState(readers []index.CodecReader, segmentInfo *SegmentInfo) (*Merg
 		LiveDocs:            make([]util.Bits, numReaders),
 		FieldsProducers:     make([]index.FieldsProducer, numReaders),
 		PointsReaders:       make([]index.PointsReader, numReaders),
+		KnnVectorsReaders:   make([]index.KnnVectorsReader, numReaders),
 		MaxDocs:             make([]int, numReaders),
 	}
 
@@ -97,11 +100,15 @@ func NewMergeState(readers []index.CodecReader, segmentInfo *SegmentInfo) (*Merg
 			state.TermVectorsReaders[i] = state.TermVectorsReaders[i].GetMergeInstance()
 		}
 
-		state.FieldsProducers[i] = reader.GetPostingsReader().GetMergeInstance()
+		state.FieldsProducers[i] = reader.GetPostingsReader()
+		if state.FieldsProducers[i] != nil {
+			state.FieldsProducers[i] = state.FieldsProducers[i].GetMergeInstance()
+		}
 		state.PointsReaders[i] = reader.GetPointsReader()
 		if state.PointsReaders[i] != nil {
 			state.PointsReaders[i] = state.PointsReaders[i].GetMergeInstance()
 		}
+		state.KnnVectorsReaders[i] = reader.GetVectorReader()
 		numDocs += reader.NumDocs()
 	}
 
@@ -109,7 +116,12 @@ func NewMergeState(readers []index.CodecReader, segmentInfo *SegmentInfo) (*Merg
 		return nil, err
 	}
 	state.SegmentInfo = segmentInfo
-	state.DocMaps = buildDocMaps(readers, segmentInfo.GetIndexSort())
+	docMaps, needsIndexSort, err := buildDocMaps(readers, segmentInfo.GetIndexSort())
+	if err != nil {
+		return nil, err
+	}
+	state.DocMaps = docMaps
+	state.NeedsIndexSort = needsIndexSort
 	return &state, nil
 }
 
@@ -128,15 +140,24 @@ func verifyIndexSort(readers []index.CodecReader, segmentInfo *SegmentInfo) erro
 	return nil
 }
 
-func buildDocMaps(readers []index.CodecReader, indexSort index.Sort) []MergeStateDocMap {
+// buildDocMaps returns the doc maps of the readers, and whether the documents of the readers
+// interleave in the merged segment because of the index sort
+func buildDocMaps(readers []index.CodecReader, indexSort index.Sort) ([]MergeStateDocMap, bool, error) {
 	if indexSort == nil {
 		// no index sort ... we only must map around deletions, and rebase to the merged segment's docID space
-		return buildDeletionDocMaps(readers)
+		return buildDeletionDocMaps(readers), false, nil
 	}
 
 	// do a merge sort of the incoming leaves:
-	//to := time.Now().UnixNano()
-	panic("")
+	docMaps, err := SortCodecReader(indexSort, readers)
+	if err != nil {
+		return nil, false, err
+	}
+	if docMaps == nil {
+		// already sorted, so we can switch back to map around deletions
+		return buildDeletionDocMaps(readers), false, nil
+	}
+	return docMaps, true, nil
 }
 
 func buildDeletionDocMaps(readers []index.CodecReader) []MergeStateDocMap {
@@ -145,48 +166,38 @@ func buildDeletionDocMaps(readers []index.CodecReader) []MergeStateDocMap {
 
 	for _, reader := range readers {
 		liveDocs := reader.GetLiveDocs()
-
-		var delDocMap *packed.PackedLongValues
-		if liveDocs != nil {
-			delDocMap = removeDeletes(reader.MaxDoc(), liveDocs)
-		} else {
-			delDocMap = nil
-		}
-
 		docBase := totalDocs
 
-		docMaps = append(docMaps, MergeStateDocMap{func(docID int) int {
-			if liveDocs == nil {
+		if liveDocs == nil {
+			docMaps = append(docMaps, MergeStateDocMap{func(docID int) int {
 				return docBase + docID
-			} else if liveDocs.Test(uint(docID)) {
-				value, err := delDocMap.Get(docID)
-				if err != nil {
-					return 0
+			}})
+		} else {
+			delDocMap := removeDeletes(reader.MaxDoc(), liveDocs)
+			docMaps = append(docMaps, MergeStateDocMap{func(docID int) int {
+				if !liveDocs.Test(uint(docID)) {
+					return -1
 				}
-				return docBase + int(value)
-			} else {
-				return -1
-			}
-		}})
+				return docBase + delDocMap[docID]
+			}})
+		}
 
 		totalDocs += reader.NumDocs()
 	}
 	return docMaps
 }
 
-func removeDeletes(maxDoc int, liveDocs util.Bits) *packed.PackedLongValues {
-	// TODO: fix it
-	panic("")
-	//docMapBuilder := packed.NewLongValuesBuilder()
-	//del := 0
-	//
-	//for i := 0; i < maxDoc; i++ {
-	//	docMapBuilder.Add(int64(i - del))
-	//	if !liveDocs.Test(uint(i)) {
-	//		del++
-	//	}
-	//}
-	//return docMapBuilder.Build()
+// removeDeletes maps each document of a segment to its document ID once the deleted documents are removed
+func removeDeletes(maxDoc int, liveDocs util.Bits) []int {
+	docMap := make([]int, maxDoc)
+	del := 0
+	for i := 0; i < maxDoc; i++ {
+		docMap[i] = i - del
+		if !liveDocs.Test(uint(i)) {
+			del++
+		}
+	}
+	return docMap
 }
 
 type MergeStateDocMap struct {
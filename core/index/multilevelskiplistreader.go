@@ -67,9 +67,39 @@ type BaseMultiLevelSkipListReader struct {
 	// childPointer of last read skip entry with docId <= target.
 	inputIsBuffered bool
 	skipMultiplier  int
+
+	fnReadSkipData    func(level int, skipStream store.IndexInput) (int, error)
+	fnSeekChild       func(level int)
+	fnSetLastSkipData func(level int)
 }
 
 func NewBaseMultiLevelSkipListReader(skipStream store.IndexInput, maxSkipLevels, skipInterval, skipMultiplier int) *BaseMultiLevelSkipListReader {
+	return NewBaseMultiLevelSkipListReaderV1(&BaseMultiLevelSkipListReaderConfig{
+		SkipStream:     skipStream,
+		MaxSkipLevels:  maxSkipLevels,
+		SkipInterval:   skipInterval,
+		SkipMultiplier: skipMultiplier,
+	})
+}
+
+type BaseMultiLevelSkipListReaderConfig struct {
+	SkipStream     store.IndexInput
+	MaxSkipLevels  int
+	SkipInterval   int
+	SkipMultiplier int
+
+	// ReadSkipData decodes one skip entry and returns the doc delta. Defaults to a single VInt.
+	ReadSkipData func(level int, skipStream store.IndexInput) (int, error)
+
+	// SeekChild is called after the base reader moved the given level to the last child pointer.
+	SeekChild func(level int)
+
+	// SetLastSkipData is called after the base reader copied the skip data of the given level.
+	SetLastSkipData func(level int)
+}
+
+func NewBaseMultiLevelSkipListReaderV1(cfg *BaseMultiLevelSkipListReaderConfig) *BaseMultiLevelSkipListReader {
+	maxSkipLevels := cfg.MaxSkipLevels
 	reader := &BaseMultiLevelSkipListReader{
 		skipStream:            make([]store.IndexInput, maxSkipLevels),
 		skipPointer:           make([]int64, maxSkipLevels),
@@ -77,20 +107,19 @@ func NewBaseMultiLevelSkipListReader(skipStream store.IndexInput, maxSkipLevels,
 		numSkipped:            make([]int, maxSkipLevels),
 		maxNumberOfSkipLevels: maxSkipLevels,
 		skipInterval:          make([]int, maxSkipLevels),
-		skipMultiplier:        skipMultiplier,
+		skipMultiplier:        cfg.SkipMultiplier,
 		skipDoc:               make([]int, maxSkipLevels),
+		fnReadSkipData:        cfg.ReadSkipData,
+		fnSeekChild:           cfg.SeekChild,
+		fnSetLastSkipData:     cfg.SetLastSkipData,
 	}
-	reader.skipStream[0] = skipStream
-	reader.skipInterval[0] = skipInterval
-	//if _, ok := skipStream.(store.BufferedIndexInput); ok {
-	//	reader.inputIsBuffered = true
-	//}
+	reader.skipStream[0] = cfg.SkipStream
+	reader.skipInterval[0] = cfg.SkipInterval
 
 	for i := 1; i < maxSkipLevels; i++ {
-		reader.skipInterval[i] = reader.skipInterval[i-1] * skipMultiplier
+		reader.skipInterval[i] = reader.skipInterval[i-1] * cfg.SkipMultiplier
 	}
 	return reader
-
 }
 
 func (m *BaseMultiLevelSkipListReader) MaxNumberOfSkipLevels() int {
@@ -105,6 +134,11 @@ func (m *BaseMultiLevelSkipListReader) GetDoc() int {
 	return m.lastDoc
 }
 
+// NumberOfSkipLevels Returns the number of levels of the current skip list.
+func (m *BaseMultiLevelSkipListReader) NumberOfSkipLevels() int {
+	return m.numberOfSkipLevels
+}
+
 func (m *BaseMultiLevelSkipListReader) SkipTo(target int) (int, error) {
 	// walk up the levels until highest level is found that has a skip
 	// for this target
@@ -115,8 +149,8 @@ func (m *BaseMultiLevelSkipListReader) SkipTo(target int) (int, error) {
 
 	for level >= 0 {
 		if target > m.skipDoc[level] {
-			if ok, err := m.loadNextSkip(level); err == nil && !ok {
-				continue
+			if _, err := m.loadNextSkip(level); err != nil {
+				return 0, err
 			}
 		} else {
 			// no more skips on this level, go down one level
@@ -181,11 +215,17 @@ func (m *BaseMultiLevelSkipListReader) seekChild(level int) error {
 		}
 		m.childPointer[level] = pointer + m.skipPointer[level-1]
 	}
+	if m.fnSeekChild != nil {
+		m.fnSeekChild(level)
+	}
 	return nil
 }
 
 func (m *BaseMultiLevelSkipListReader) Close() error {
 	for _, input := range m.skipStream {
+		if input == nil {
+			continue
+		}
 		if err := input.Close(); err != nil {
 			return err
 		}
@@ -278,6 +318,10 @@ func (m *BaseMultiLevelSkipListReader) loadSkipLevels() error {
 // level – the level skip data shall be read from
 // skipStream – the skip stream to read from
 func (m *BaseMultiLevelSkipListReader) readSkipData(level int, skipStream store.IndexInput) (int64, error) {
+	if m.fnReadSkipData != nil {
+		delta, err := m.fnReadSkipData(level, skipStream)
+		return int64(delta), err
+	}
 	num, err := skipStream.ReadUvarint(context.Background())
 	return int64(num), err
 }
@@ -301,6 +345,9 @@ func (m *BaseMultiLevelSkipListReader) readChildPointer(skipStream store.IndexIn
 func (m *BaseMultiLevelSkipListReader) setLastSkipData(level int) {
 	m.lastDoc = m.skipDoc[level]
 	m.lastChildPointer = m.childPointer[level]
+	if m.fnSetLastSkipData != nil {
+		m.fnSetLastSkipData(level)
+	}
 }
 
 var _ store.IndexInput = &SkipBuffer{}
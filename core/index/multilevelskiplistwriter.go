@@ -1,6 +1,8 @@
 package index
 
 import (
+	"fmt"
+
 	"github.com/geange/lucene-go/core/store"
 	"github.com/geange/lucene-go/core/util"
 )
@@ -83,8 +85,7 @@ type BaseMultiLevelSkipListWriter struct {
 	skipMultiplier int
 
 	// for every skip level a different buffer is used
-	// TODO: fix
-	//skipBuffer []*store.RAMOutputStream
+	skipBuffer []*store.BufferOutput
 
 	// Subclasses must implement the actual skip data encoding in this method.
 	// level: the level skip data shall be writing for
@@ -96,7 +97,11 @@ type BaseMultiLevelSkipListWriter struct {
 }
 
 func NewBaseMultiLevelSkipListWriter(cfg *BaseMultiLevelSkipListWriterConfig) *BaseMultiLevelSkipListWriter {
-	this := &BaseMultiLevelSkipListWriter{}
+	this := &BaseMultiLevelSkipListWriter{
+		writeSkipData:       cfg.WriteSkipData,
+		fnWriteLevelLength:  cfg.WriteLevelLength,
+		fnWriteChildPointer: cfg.WriteChildPointer,
+	}
 
 	this.skipInterval = cfg.SkipInterval
 	this.skipMultiplier = cfg.SkipMultiplier
@@ -125,62 +130,44 @@ func (m *BaseMultiLevelSkipListWriter) SetNumberOfSkipLevels(numberOfSkipLevels
 	m.numberOfSkipLevels = numberOfSkipLevels
 }
 
-/*
-func (m *MultiLevelSkipListWriterDefault) Init() {
-	m.skipBuffer = make([]*store.RAMOutputStream, 0, m.NumberOfSkipLevels)
-	for i := 0; i < m.NumberOfSkipLevels; i++ {
-		m.skipBuffer = append(m.skipBuffer, store.NewRAMOutputStream())
+// Init Allocates internal skip buffers.
+func (m *BaseMultiLevelSkipListWriter) Init() {
+	m.skipBuffer = make([]*store.BufferOutput, 0, m.numberOfSkipLevels)
+	for i := 0; i < m.numberOfSkipLevels; i++ {
+		m.skipBuffer = append(m.skipBuffer, store.NewBufferDataOutput())
 	}
 }
 
 // ResetSkip Creates new buffers or empties the existing ones
-func (m *MultiLevelSkipListWriterDefault) ResetSkip() {
+func (m *BaseMultiLevelSkipListWriter) ResetSkip() {
 	if len(m.skipBuffer) == 0 {
 		m.Init()
-	} else {
-		for i := 0; i < len(m.skipBuffer); i++ {
-			m.skipBuffer[i].Reset()
-		}
+		return
 	}
-}
 
-// WriteSkip Creates new buffers or empties the existing ones
-func (m *MultiLevelSkipListWriterDefault) WriteSkip(output store.IndexOutput) (int64, error) {
-	skipPointer := output.GetFilePointer()
-	if len(m.skipBuffer) == 0 {
-		return skipPointer, nil
+	for _, buffer := range m.skipBuffer {
+		buffer.Reset()
 	}
+}
 
-	for level := m.NumberOfSkipLevels - 1; level > 0; level-- {
-		length := m.skipBuffer[level].GetFilePointer()
-		if length > 0 {
-			if err := m.writeLevelLength(length, output); err != nil {
-				return 0, err
-			}
-			if err := m.skipBuffer[level].WriteTo(output); err != nil {
-				return 0, err
-			}
-		}
+// BufferSkip Writes the current skip data to the buffers. The current document frequency
+// determines the max level is skip data is to be written to.
+// df: the current document frequency
+func (m *BaseMultiLevelSkipListWriter) BufferSkip(df int) error {
+	if df%m.skipInterval != 0 {
+		return fmt.Errorf("df=%d is not a multiple of skipInterval=%d", df, m.skipInterval)
 	}
-	if err := m.skipBuffer[0].WriteTo(output); err != nil {
-		return 0, err
-	}
-
-	return skipPointer, nil
-}
 
-func (m *MultiLevelSkipListWriterDefault) BufferSkip(df int) error {
-	//assert df % skipInterval == 0;
 	numLevels := 1
 	df /= m.skipInterval
 
 	// determine max level
-	for (df%m.skipMultiplier) == 0 && numLevels < m.NumberOfSkipLevels {
+	for (df%m.skipMultiplier) == 0 && numLevels < m.numberOfSkipLevels {
 		numLevels++
 		df /= m.skipMultiplier
 	}
 
-	childPointer := 0
+	childPointer := int64(0)
 
 	for level := 0; level < numLevels; level++ {
 		if err := m.writeSkipData(level, m.skipBuffer[level]); err != nil {
@@ -191,21 +178,48 @@ func (m *MultiLevelSkipListWriterDefault) BufferSkip(df int) error {
 
 		if level != 0 {
 			// store child pointers for all levels except the lowest
-			if err := m.writeChildPointer(int64(childPointer), m.skipBuffer[level]); err != nil {
+			if err := m.writeChildPointer(childPointer, m.skipBuffer[level]); err != nil {
 				return err
 			}
 		}
 
 		//remember the childPointer for the next level
-		childPointer = int(newChildPointer)
+		childPointer = newChildPointer
 	}
 	return nil
 }
 
+// WriteSkip Writes the buffered skip lists to the given output.
+// output: the IndexOutput the skip lists shall be written to
+// Returns: the pointer the skip list starts
+func (m *BaseMultiLevelSkipListWriter) WriteSkip(output store.IndexOutput) (int64, error) {
+	skipPointer := output.GetFilePointer()
+	if len(m.skipBuffer) == 0 {
+		return skipPointer, nil
+	}
+
+	for level := m.numberOfSkipLevels - 1; level > 0; level-- {
+		length := m.skipBuffer[level].GetFilePointer()
+		if length > 0 {
+			if err := m.writeLevelLength(length, output); err != nil {
+				return 0, err
+			}
+			if err := m.skipBuffer[level].CopyTo(output); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := m.skipBuffer[0].CopyTo(output); err != nil {
+		return 0, err
+	}
+
+	return skipPointer, nil
+}
+
 // Writes the length of a level to the given output.
 // levelLength – the length of a level
 // output – the IndexOutput the length shall be written to
-func (m *MultiLevelSkipListWriterDefault) writeLevelLength(levelLength int64, output store.IndexOutput) error {
+func (m *BaseMultiLevelSkipListWriter) writeLevelLength(levelLength int64, output store.IndexOutput) error {
 	if m.fnWriteLevelLength != nil {
 		return m.fnWriteLevelLength(levelLength, output)
 	}
@@ -215,11 +229,9 @@ func (m *MultiLevelSkipListWriterDefault) writeLevelLength(levelLength int64, ou
 // Writes the child pointer of a block to the given output.
 // childPointer – block of higher level point to the lower level
 // skipBuffer – the skip buffer to write to
-func (m *MultiLevelSkipListWriterDefault) writeChildPointer(childPointer int64, skipBuffer store.DataOutput) error {
+func (m *BaseMultiLevelSkipListWriter) writeChildPointer(childPointer int64, skipBuffer store.DataOutput) error {
 	if m.fnWriteChildPointer != nil {
 		return m.fnWriteChildPointer(childPointer, skipBuffer)
 	}
 	return skipBuffer.WriteUvarint(nil, uint64(childPointer))
 }
-
-*/
@@ -1,27 +1,109 @@
 package index
 
 import (
+	"cmp"
+	"fmt"
+
 	"github.com/geange/lucene-go/core/interface/index"
 	"github.com/geange/lucene-go/core/util"
+	"github.com/geange/lucene-go/core/util/structure"
 )
 
 // SortCodecReader
 // Does a merge sort of the leaves of the incoming reader, returning MergeState.DocMap to map each leaf's documents into the merged segment. The documents for each incoming leaf reader must already be sorted by the same sort! Returns null if the merge sort is not needed (segments are already in index sort order).
 func SortCodecReader(sort index.Sort, readers []index.CodecReader) ([]MergeStateDocMap, error) {
-	//fields := sort.GetSort()
-	//
-	//comparables := make([][]ComparableProvider, len(fields))
-	//reverseMuls := make([]int, len(fields))
-	//for _, field := range fields {
-	//	sorter := field.GetIndexSorter()
-	//	if sorter == nil {
-	//		return nil, fmt.Errorf("cannot use sort field:%s for index sorting", field)
-	//	}
-	//	comparables[i] = sorter.get
-	//}
-
-	// TODO
-	panic("")
+	fields := sort.GetSort()
+
+	leafReaders := make([]index.LeafReader, len(readers))
+	for i, reader := range readers {
+		leafReaders[i] = reader
+	}
+
+	comparables := make([][]index.ComparableProvider, len(fields))
+	reverseMuls := make([]int, len(fields))
+	for i, field := range fields {
+		sorter := field.GetIndexSorter()
+		if sorter == nil {
+			return nil, fmt.Errorf("cannot use sort field %v for index sorting", field)
+		}
+		providers, err := sorter.GetComparableProviders(leafReaders)
+		if err != nil {
+			return nil, err
+		}
+		comparables[i] = providers
+		reverseMuls[i] = reverseMultiplier(field.GetReverse())
+	}
+
+	queue := structure.NewPriorityQueue[*LeafAndDocID](len(readers), func(a, b *LeafAndDocID) bool {
+		for i := range comparables {
+			c := reverseMuls[i] * cmp.Compare(a.valuesAsComparableLongs[i], b.valuesAsComparableLongs[i])
+			if c != 0 {
+				return c < 0
+			}
+		}
+
+		// tie-break by docID natural order:
+		if a.readerIndex != b.readerIndex {
+			return a.readerIndex < b.readerIndex
+		}
+		return a.docId < b.docId
+	})
+
+	// the document of the merged segment of every document of each reader
+	docMaps := make([][]int, len(readers))
+	for i, reader := range readers {
+		docMaps[i] = make([]int, reader.MaxDoc())
+		if reader.MaxDoc() == 0 {
+			continue
+		}
+		leaf := NewLeafAndDocID(i, reader.GetLiveDocs(), reader.MaxDoc(), len(comparables))
+		if err := leaf.readComparables(comparables); err != nil {
+			return nil, err
+		}
+		queue.Add(leaf)
+	}
+
+	mappedDocID := 0
+	lastReaderIndex := 0
+	isSorted := true
+	for queue.Size() != 0 {
+		top := queue.Top()
+		if lastReaderIndex > top.readerIndex {
+			// merge sort is needed
+			isSorted = false
+		}
+		lastReaderIndex = top.readerIndex
+		docMaps[top.readerIndex][top.docId] = mappedDocID
+		if top.liveDocs == nil || top.liveDocs.Test(uint(top.docId)) {
+			mappedDocID++
+		}
+		top.docId++
+		if top.docId < top.maxDoc {
+			if err := top.readComparables(comparables); err != nil {
+				return nil, err
+			}
+			queue.UpdateTop()
+		} else {
+			if _, err := queue.Pop(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if isSorted {
+		return nil, nil
+	}
+
+	res := make([]MergeStateDocMap, len(readers))
+	for i, reader := range readers {
+		liveDocs, docMap := reader.GetLiveDocs(), docMaps[i]
+		res[i] = MergeStateDocMap{func(docID int) int {
+			if liveDocs == nil || liveDocs.Test(uint(docID)) {
+				return docMap[docID]
+			}
+			return -1
+		}}
+	}
+	return res, nil
 }
 
 type LeafAndDocID struct {
@@ -40,3 +122,15 @@ func NewLeafAndDocID(readerIndex int, liveDocs util.Bits, maxDoc int, numCompara
 		valuesAsComparableLongs: make([]int64, numComparables),
 	}
 }
+
+// Reads the comparable longs of the current document of the leaf
+func (l *LeafAndDocID) readComparables(comparables [][]index.ComparableProvider) error {
+	for i, providers := range comparables {
+		value, err := providers[l.readerIndex].GetAsComparableLong(l.docId)
+		if err != nil {
+			return err
+		}
+		l.valuesAsComparableLongs[i] = value
+	}
+	return nil
+}
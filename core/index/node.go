@@ -48,6 +48,48 @@ func (t *TermNode) IsDelete() bool {
 	return true
 }
 
+var _ NodeApply = &TermArrayNode{}
+
+type TermArrayNode struct {
+	items []index.Term
+}
+
+func NewTermArrayNode(items []index.Term) *TermArrayNode {
+	return &TermArrayNode{items: items}
+}
+
+func (t *TermArrayNode) Apply(bufferedDeletes *index.BufferedUpdates, docIDUpto int) error {
+	for _, term := range t.items {
+		bufferedDeletes.AddTerm(term, docIDUpto)
+	}
+	return nil
+}
+
+func (t *TermArrayNode) IsDelete() bool {
+	return true
+}
+
+var _ NodeApply = &QueryArrayNode{}
+
+type QueryArrayNode struct {
+	items []index.Query
+}
+
+func NewQueryArrayNode(items []index.Query) *QueryArrayNode {
+	return &QueryArrayNode{items: items}
+}
+
+func (q *QueryArrayNode) Apply(bufferedDeletes *index.BufferedUpdates, docIDUpto int) error {
+	for _, query := range q.items {
+		bufferedDeletes.AddQuery(query, docIDUpto)
+	}
+	return nil
+}
+
+func (q *QueryArrayNode) IsDelete() bool {
+	return true
+}
+
 var _ NodeApply = &DocValuesUpdatesNode{}
 
 type DocValuesUpdatesNode struct {
@@ -60,18 +102,22 @@ func NewDocValuesUpdatesNode(updates []index.DocValuesUpdate) *DocValuesUpdatesN
 
 func (d *DocValuesUpdatesNode) Apply(bufferedDeletes *index.BufferedUpdates, docIDUpto int) error {
 	for _, update := range d.updates {
+		var err error
 		switch update.GetType() {
 		case document.DOC_VALUES_TYPE_NUMERIC:
-			return bufferedDeletes.AddNumericUpdate(update.(*index.NumericDocValuesUpdate), docIDUpto)
+			err = bufferedDeletes.AddNumericUpdate(update.(*index.NumericDocValuesUpdate), docIDUpto)
 		case document.DOC_VALUES_TYPE_BINARY:
-			return bufferedDeletes.AddBinaryUpdate(update.(*index.BinaryDocValuesUpdate), docIDUpto)
+			err = bufferedDeletes.AddBinaryUpdate(update.(*index.BinaryDocValuesUpdate), docIDUpto)
 		default:
-			return errors.New("type not supported yet")
+			err = errors.New("type not supported yet")
+		}
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
 func (d *DocValuesUpdatesNode) IsDelete() bool {
-	return true
+	return false
 }
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"github.com/geange/lucene-go/core/interface/index"
-	"github.com/geange/lucene-go/core/util/packed"
 	"io"
 
 	"github.com/geange/lucene-go/core/document"
@@ -131,20 +130,26 @@ type NumericDocValuesSub struct {
 // Buffers up pending long per doc, then flushes when segment flushes.
 type NormValuesWriter struct {
 	docsWithField *DocsWithFieldSet
-	pending       *packed.PackedLongValuesBuilder
+	pending       []int64
 	fieldInfo     *document.FieldInfo
 	lastDocID     int
 }
 
+func NewNormValuesWriter(fieldInfo *document.FieldInfo) *NormValuesWriter {
+	return &NormValuesWriter{
+		docsWithField: NewDocsWithFieldSet(),
+		pending:       make([]int64, 0),
+		fieldInfo:     fieldInfo,
+		lastDocID:     -1,
+	}
+}
+
 func (n *NormValuesWriter) AddValue(docID int, value int64) error {
 	if n.lastDocID >= docID {
 		return errors.New("docID too small")
 	}
 
-	if err := n.pending.Add(value); err != nil {
-		return err
-	}
-
+	n.pending = append(n.pending, value)
 	n.lastDocID = docID
 	return n.docsWithField.Add(docID)
 }
@@ -154,18 +159,57 @@ func (n *NormValuesWriter) Finish(maxDoc int) {
 }
 
 func (n *NormValuesWriter) Flush(state *index.SegmentWriteState, sortMap index.DocMap, normsConsumer index.NormsConsumer) error {
-	//values := n.pending.Build()
-	// TODO: impl it
-	panic("")
+	var sorted *NumericDVs
+	if sortMap != nil {
+		maxDoc, err := state.SegmentInfo.MaxDoc()
+		if err != nil {
+			return err
+		}
+		iterator, err := n.docsWithField.Iterator()
+		if err != nil {
+			return err
+		}
+		if sorted, err = sortDocValues(maxDoc, sortMap, NewBufferedNumericDocValues(n.pending, iterator)); err != nil {
+			return err
+		}
+	}
+
+	return normsConsumer.AddNormsField(context.TODO(), n.fieldInfo, &normValuesProducer{
+		writer: n,
+		sorted: sorted,
+	})
 }
 
-func NewNormValuesWriter(fieldInfo *document.FieldInfo) *NormValuesWriter {
-	//return &NormValuesWriter{
-	//	docsWithField: NewDocsWithFieldSet(),
-	//	pending:       packed.NewLongValuesBuilder(make([]uint64, 0)...),
-	//	fieldInfo:     fieldInfo,
-	//	lastDocID:     -1,
-	//}
-	// TODO: impl it
-	panic("")
+var _ index.NormsProducer = &normValuesProducer{}
+
+// normValuesProducer Serves the buffered norms of a NormValuesWriter to the NormsConsumer
+type normValuesProducer struct {
+	writer *NormValuesWriter
+	sorted *NumericDVs
+}
+
+func (p *normValuesProducer) Close() error {
+	return nil
+}
+
+func (p *normValuesProducer) GetNorms(field *document.FieldInfo) (index.NumericDocValues, error) {
+	if field != p.writer.fieldInfo {
+		return nil, errors.New("wrong fieldInfo")
+	}
+	if p.sorted != nil {
+		return NewSortingNumericDocValues(p.sorted), nil
+	}
+	iterator, err := p.writer.docsWithField.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	return NewBufferedNumericDocValues(p.writer.pending, iterator), nil
+}
+
+func (p *normValuesProducer) CheckIntegrity() error {
+	return nil
+}
+
+func (p *normValuesProducer) GetMergeInstance() index.NormsProducer {
+	return p
 }
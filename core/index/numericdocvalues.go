@@ -1,14 +1,15 @@
 package index
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/geange/lucene-go/core/interface/index"
 	"io"
 
 	"github.com/bits-and-blooms/bitset"
 	"github.com/geange/lucene-go/core/document"
 	"github.com/geange/lucene-go/core/types"
-	"github.com/geange/lucene-go/core/util/packed"
 )
 
 var _ index.NumericDocValues = &NumericDocValuesDefault{}
@@ -51,27 +52,105 @@ func (n *NumericDocValuesDefault) LongValue() (int64, error) {
 	return n.FnLongValue()
 }
 
+var _ DocValuesFieldUpdates = &NumericDocValuesFieldUpdates{}
+
+// NumericDocValuesFieldUpdates
+// A DocValuesFieldUpdates which holds updates of documents, of a single NumericDocValuesField.
+// lucene.experimental
+type NumericDocValuesFieldUpdates struct {
+	*BaseDocValuesFieldUpdates
+
+	values []int64
+}
+
+func NewNumericDocValuesFieldUpdates(delGen int64, field string, maxDoc int) *NumericDocValuesFieldUpdates {
+	updates := &NumericDocValuesFieldUpdates{}
+	updates.BaseDocValuesFieldUpdates = newBaseDocValuesFieldUpdates(maxDoc, delGen, field,
+		document.DOC_VALUES_TYPE_NUMERIC, func(i, j int) {
+			updates.values[i], updates.values[j] = updates.values[j], updates.values[i]
+		})
+	return updates
+}
+
+func (n *NumericDocValuesFieldUpdates) AddInt64(doc int, value int64) error {
+	if _, err := n.addInternal(doc, HAS_VALUE_MASK); err != nil {
+		return err
+	}
+	n.values = append(n.values, value)
+	return nil
+}
+
+func (n *NumericDocValuesFieldUpdates) AddBytes(doc int, value []byte) error {
+	return errors.New("unsupported operation exception")
+}
+
+func (n *NumericDocValuesFieldUpdates) AddIterator(doc int, it DocValuesFieldUpdatesIterator) error {
+	value, err := it.LongValue()
+	if err != nil {
+		return err
+	}
+	return n.AddInt64(doc, value)
+}
+
+func (n *NumericDocValuesFieldUpdates) Reset(doc int) error {
+	if _, err := n.addInternal(doc, HAS_NO_VALUE_MASK); err != nil {
+		return err
+	}
+	n.values = append(n.values, 0)
+	return nil
+}
+
+func (n *NumericDocValuesFieldUpdates) Iterator() (DocValuesFieldUpdatesIterator, error) {
+	if !n.finished {
+		return nil, errors.New("call finish first")
+	}
+	it := &numericDocValuesFieldUpdatesIterator{}
+	it.abstractIterator = newAbstractIterator(n.docs, n.delGen, func(idx int) {
+		it.value = n.values[idx]
+	})
+	return it, nil
+}
+
+type numericDocValuesFieldUpdatesIterator struct {
+	*abstractIterator
+
+	value int64
+}
+
+func (n *numericDocValuesFieldUpdatesIterator) LongValue() (int64, error) {
+	return n.value, nil
+}
+
+func (n *numericDocValuesFieldUpdatesIterator) BinaryValue() ([]byte, error) {
+	return nil, errors.New("unsupported operation exception")
+}
+
 var _ DocValuesWriter = &NumericDocValuesWriter{}
 
+// NumericDocValuesWriter
+// Buffers up pending long per doc, then flushes when segment flushes.
 type NumericDocValuesWriter struct {
-	pending       *packed.PackedLongValuesBuilder
-	finalValues   *packed.PackedLongValues
+	pending       []int64
 	docsWithField *DocsWithFieldSet
 	fieldInfo     *document.FieldInfo
 	lastDocID     int
 }
 
 func NewNumericDocValuesWriter(fieldInfo *document.FieldInfo) *NumericDocValuesWriter {
-	panic("")
+	return &NumericDocValuesWriter{
+		pending:       make([]int64, 0),
+		docsWithField: NewDocsWithFieldSet(),
+		fieldInfo:     fieldInfo,
+		lastDocID:     -1,
+	}
 }
 
 func (n *NumericDocValuesWriter) AddValue(docID int, value int64) error {
 	if docID <= n.lastDocID {
-		panic("")
-	}
-	if err := n.pending.Add(value); err != nil {
-		return err
+		return fmt.Errorf(`DocValuesField "%s" appears more than once in this document (only one value is allowed per field)`,
+			n.fieldInfo.Name())
 	}
+	n.pending = append(n.pending, value)
 	if err := n.docsWithField.Add(docID); err != nil {
 		return err
 	}
@@ -80,42 +159,73 @@ func (n *NumericDocValuesWriter) AddValue(docID int, value int64) error {
 }
 
 func (n *NumericDocValuesWriter) Flush(state *index.SegmentWriteState, sortMap index.DocMap, consumer index.DocValuesConsumer) error {
-	// TODO: fix it
-	panic("")
-	/*
-		n.finalValues = n.pending.Build()
-
-		return consumer.AddNumericField(nil, n.fieldInfo, &EmptyDocValuesProducer{
-			FnGetNumeric: func(field *document.FieldInfo) (NumericDocValues, error) {
-				iterator, err := n.docsWithField.Iterator()
-				if err != nil {
-					return nil, err
-				}
-				return NewBufferedNumericDocValues(n.finalValues, iterator), nil
-			},
-		})
+	var sorted *NumericDVs
+	if sortMap != nil {
+		maxDoc, err := state.SegmentInfo.MaxDoc()
+		if err != nil {
+			return err
+		}
+		if sorted, err = sortDocValues(maxDoc, sortMap, n.GetDocValues().(index.NumericDocValues)); err != nil {
+			return err
+		}
+	}
 
-	*/
+	return consumer.AddNumericField(context.TODO(), n.fieldInfo, &EmptyDocValuesProducer{
+		FnGetNumeric: func(ctx context.Context, field *document.FieldInfo) (index.NumericDocValues, error) {
+			if field != n.fieldInfo {
+				return nil, errors.New("wrong fieldInfo")
+			}
+			if sorted != nil {
+				return NewSortingNumericDocValues(sorted), nil
+			}
+			return n.GetDocValues().(index.NumericDocValues), nil
+		},
+	})
 }
 
 func (n *NumericDocValuesWriter) GetDocValues() types.DocIdSetIterator {
-	//TODO implement me
-	panic("implement me")
+	iterator, _ := n.docsWithField.Iterator()
+	return NewBufferedNumericDocValues(n.pending, iterator)
+}
+
+// sortDocValues Reads oldValues in the order of the documents of the sorted segment
+func sortDocValues(maxDoc int, sortMap index.DocMap, oldValues index.NumericDocValues) (*NumericDVs, error) {
+	docsWithField := bitset.New(uint(maxDoc))
+	values := make([]int64, maxDoc)
+	for {
+		docID, err := oldValues.NextDoc()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if docID == types.NO_MORE_DOCS {
+			break
+		}
+		newDocID := sortMap.OldToNew(docID)
+		docsWithField.Set(uint(newDocID))
+		if values[newDocID], err = oldValues.LongValue(); err != nil {
+			return nil, err
+		}
+	}
+	return NewNumericDVs(values, docsWithField), nil
 }
 
 var _ index.NumericDocValues = &BufferedNumericDocValues{}
 
+// BufferedNumericDocValues Iterates over the values of the documents in docsWithField, in order
 type BufferedNumericDocValues struct {
-	iter          packed.PackedLongValuesIterator
+	values        []int64
+	pos           int
 	docsWithField types.DocIdSetIterator
 	value         int64
 }
 
-func NewBufferedNumericDocValues(values *packed.PackedLongValues,
-	docsWithFields types.DocIdSetIterator) *BufferedNumericDocValues {
-
+func NewBufferedNumericDocValues(values []int64, docsWithFields types.DocIdSetIterator) *BufferedNumericDocValues {
 	docValues := &BufferedNumericDocValues{
-		iter:          values.Iterator(),
+		values:        values,
+		pos:           -1,
 		docsWithField: docsWithFields,
 		value:         0,
 	}
@@ -131,12 +241,8 @@ func (b *BufferedNumericDocValues) NextDoc() (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	v, err := b.iter.Next()
-	if err != nil {
-		return 0, err
-	}
-	b.value = int64(v)
-
+	b.pos++
+	b.value = b.values[b.pos]
 	return docID, nil
 }
 
@@ -168,6 +274,14 @@ type SortingNumericDocValues struct {
 	cost  int
 }
 
+func NewSortingNumericDocValues(dvs *NumericDVs) *SortingNumericDocValues {
+	return &SortingNumericDocValues{
+		dvs:   dvs,
+		docID: -1,
+		cost:  int(dvs.docsWithField.Count()),
+	}
+}
+
 func (s *SortingNumericDocValues) DocID() int {
 	return s.docID
 }
@@ -190,8 +304,7 @@ func (s *SortingNumericDocValues) SlowAdvance(target int) (int, error) {
 }
 
 func (s *SortingNumericDocValues) Cost() int64 {
-	//TODO implement me
-	panic("implement me")
+	return int64(s.cost)
 }
 
 func (s *SortingNumericDocValues) AdvanceExact(target int) (bool, error) {
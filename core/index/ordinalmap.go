@@ -1,10 +1,14 @@
 package index
 
 import (
+	"bytes"
 	"context"
-	"github.com/geange/lucene-go/core/interface/index"
-	"github.com/geange/lucene-go/core/types"
+	"errors"
+	"io"
 	"slices"
+
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/util/structure"
 )
 
 // OrdinalMap
@@ -13,25 +17,129 @@ import (
 // It's better to operate in segment-private ordinal space instead when possible.
 // lucene.internal
 type OrdinalMap struct {
-	valueCount          int64 // number of global ordinals
-	globalOrdDeltas     types.LongValues
-	firstSegments       types.LongValues
-	segmentToGlobalOrds []types.LongValues
+	valueCount          int64     // number of global ordinals
+	globalOrdDeltas     []int64   // globalOrd -> (globalOrd - segmentOrd) where segmentOrd is the ordinal in the first segment that contains this term
+	firstSegments       []int     // globalOrd -> first segment container
+	segmentToGlobalOrds [][]int64 // for every segment, segmentOrd -> globalOrd
 	segmentMap          *SegmentMap
 }
 
-func NewOrdinalMap(subs []index.TermsEnum, segmentMap *SegmentMap, acceptableOverheadRatio float64) (*OrdinalMap, error) {
-	//res := &OrdinalMap{
-	//	segmentMap: segmentMap,
-	//}
-	//globalOrdDeltas := packed.NewPackedLongValuesBuilder()
-	//firstSegments := packed.NewPackedLongValuesBuilder()
-	//ordDeltas := make([]*packed.LongValuesBuilder, len(subs))
-	//firstSegmentBits := 0
-	//for i := 0; i < len(ordDeltas); i++ {
-	//	ordDeltas[i] = packed.
-	//}
-	panic("")
+// BuildOrdinalMap
+// Create an ordinal map that uses the number of unique values of each TermsEnum instance as a weight.
+// weights: the weight of each TermsEnum, the heaviest segments are iterated first
+func BuildOrdinalMap(ctx context.Context, subs []index.TermsEnum, weights []int64) (*OrdinalMap, error) {
+	if len(subs) != len(weights) {
+		return nil, errors.New("subs and weights must have the same length")
+	}
+	return NewOrdinalMap(ctx, subs, NewSegmentMap(weights))
+}
+
+func NewOrdinalMap(ctx context.Context, subs []index.TermsEnum, segmentMap *SegmentMap) (*OrdinalMap, error) {
+	res := &OrdinalMap{
+		globalOrdDeltas:     make([]int64, 0),
+		firstSegments:       make([]int, 0),
+		segmentToGlobalOrds: make([][]int64, len(subs)),
+		segmentMap:          segmentMap,
+	}
+
+	// In the case where some segments have not been processed yet, segmentOrds holds the next ordinal
+	// of every segment which is not mapped yet
+	segmentOrds := make([]int64, len(subs))
+	queue := structure.NewPriorityQueue[*TermsEnumIndex](len(subs), func(a, b *TermsEnumIndex) bool {
+		return bytes.Compare(a.currentTerm, b.currentTerm) < 0
+	})
+	for i := range subs {
+		res.segmentToGlobalOrds[i] = make([]int64, 0)
+		sub := NewTermsEnumIndex(subs[segmentMap.NewToOld(i)], i)
+		term, err := sub.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if term != nil {
+			queue.Add(sub)
+		}
+	}
+
+	globalOrd := int64(0)
+	for queue.Size() != 0 {
+		top := queue.Top()
+		scratch := bytes.Clone(top.currentTerm)
+
+		firstSegmentIndex := len(subs)
+		globalOrdDelta := int64(0)
+
+		// Advance past this term, recording the per-segment ord deltas:
+		for {
+			top = queue.Top()
+			segmentOrd, err := top.termsEnum.Ord()
+			if err != nil {
+				return nil, err
+			}
+			delta := globalOrd - segmentOrd
+			segmentIndex := top.subIndex
+			if segmentIndex < firstSegmentIndex {
+				firstSegmentIndex = segmentIndex
+				globalOrdDelta = delta
+			}
+
+			// for each per-segment ord, map it back to the global term; the while loop is needed
+			// in case the incoming TermsEnum filtered out some terms
+			for segmentOrds[segmentIndex] <= segmentOrd {
+				res.segmentToGlobalOrds[segmentIndex] = append(res.segmentToGlobalOrds[segmentIndex], globalOrd)
+				segmentOrds[segmentIndex]++
+			}
+
+			term, err := top.Next(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if term == nil {
+				if _, err := queue.Pop(); err != nil {
+					return nil, err
+				}
+				if queue.Size() == 0 {
+					break
+				}
+			} else {
+				queue.UpdateTop()
+			}
+			if !bytes.Equal(queue.Top().currentTerm, scratch) {
+				break
+			}
+		}
+
+		// for each unique term, just mark the first segment index/delta where it occurs
+		res.firstSegments = append(res.firstSegments, firstSegmentIndex)
+		res.globalOrdDeltas = append(res.globalOrdDeltas, globalOrdDelta)
+		globalOrd++
+	}
+	res.valueCount = globalOrd
+	return res, nil
+}
+
+// GetGlobalOrds
+// Given a segment number, return a slice that maps segment ordinals to global ordinals.
+func (r *OrdinalMap) GetGlobalOrds(segmentIndex int) []int64 {
+	return r.segmentToGlobalOrds[r.segmentMap.OldToNew(segmentIndex)]
+}
+
+// GetFirstSegmentOrd
+// Given global ordinal, returns the ordinal of the first segment which contains this ordinal
+// (the corresponding to the segment return getFirstSegmentNumber).
+func (r *OrdinalMap) GetFirstSegmentOrd(globalOrd int64) int64 {
+	return globalOrd - r.globalOrdDeltas[globalOrd]
+}
+
+// GetFirstSegmentNumber
+// Given a global ordinal, returns the index of the first segment that contains this term.
+func (r *OrdinalMap) GetFirstSegmentNumber(globalOrd int64) int {
+	return r.segmentMap.NewToOld(r.firstSegments[globalOrd])
+}
+
+// GetValueCount
+// Returns the total number of unique terms in global ord space.
+func (r *OrdinalMap) GetValueCount() int64 {
+	return r.valueCount
 }
 
 type TermsEnumIndex struct {
@@ -47,9 +155,14 @@ func NewTermsEnumIndex(termsEnum index.TermsEnum, subIndex int) *TermsEnumIndex
 	}
 }
 
+// Next moves to the next term, a nil term means the TermsEnum is exhausted
 func (t *TermsEnumIndex) Next(ctx context.Context) ([]byte, error) {
 	next, err := t.termsEnum.Next(ctx)
 	if err != nil {
+		if errors.Is(err, io.EOF) {
+			t.currentTerm = nil
+			return nil, nil
+		}
 		return nil, err
 	}
 	t.currentTerm = next
@@ -77,20 +190,23 @@ func NewSegmentMap(weight []int64) *SegmentMap {
 	}
 }
 
+// makeIndexMap sorts the segments by descending weight
 func makeIndexMap(weight []int64) []int {
 	newToOld := make([]int, len(weight))
 	for i := 0; i < len(weight); i++ {
 		newToOld[i] = i
 	}
 
-	slices.SortFunc(newToOld, func(i, j int) int {
-		return Compare(weight[newToOld[j]], weight[newToOld[i]])
+	slices.SortStableFunc(newToOld, func(i, j int) int {
+		return Compare(weight[j], weight[i])
 	})
 	return newToOld
 }
 
 func inverseInts(data []int) []int {
-	inverse := slices.Clone(data)
-	slices.Reverse(inverse)
+	inverse := make([]int, len(data))
+	for i, v := range data {
+		inverse[v] = i
+	}
 	return inverse
 }
@@ -141,6 +141,8 @@ func (p *pendingDeletes) Delete(docID int) (bool, error) {
 }
 
 func (p *pendingDeletes) GetLiveDocs() util.Bits {
+	// Prevent modifications to the returned live docs
+	p.writeableLiveDocs = nil
 	return p.liveDocs
 }
 
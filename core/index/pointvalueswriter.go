@@ -62,6 +62,7 @@ func (p *PointValuesWriter) Flush(ctx context.Context, state *index.SegmentWrite
 	points := &innerMutablePointValues{
 		bytesReader: bytesReader,
 		pw:          p,
+		docMap:      docMap,
 		numDocs:     p.numDocs,
 		numPoints:   p.numPoints,
 		ords:        make([]int, p.numPoints),
@@ -107,6 +108,7 @@ type innerMutablePointValues struct {
 	numPoints   int
 	ords        []int
 	temp        []int
+	docMap      index.DocMap // maps the buffered documents to the documents of the sorted segment, nil if not sorted
 }
 
 func (r *innerMutablePointValues) Intersect(ctx context.Context, visitor types.IntersectVisitor) error {
@@ -176,7 +178,11 @@ func (r *innerMutablePointValues) GetByteAt(i, k int) byte {
 }
 
 func (r *innerMutablePointValues) GetDocID(i int) int {
-	return r.pw.docIDs[r.ords[i]]
+	docID := r.pw.docIDs[r.ords[i]]
+	if r.docMap != nil {
+		return r.docMap.OldToNew(docID)
+	}
+	return docID
 }
 
 func (r *innerMutablePointValues) Swap(i, j int) {
@@ -192,6 +198,6 @@ func (r *innerMutablePointValues) Save(i, j int) {
 
 func (r *innerMutablePointValues) Restore(i, j int) {
 	if len(r.temp) != 0 {
-		copy(r.ords[i:], r.temp[i:j-i])
+		copy(r.ords[i:j], r.temp[i:j])
 	}
 }
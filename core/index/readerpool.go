@@ -3,6 +3,7 @@ package index
 import (
 	"errors"
 	"github.com/geange/lucene-go/core/interface/index"
+	"sync"
 	"sync/atomic"
 
 	"github.com/geange/lucene-go/core/store"
@@ -14,6 +15,8 @@ import (
 // of the SegmentReaders in all these places if it is in "near real-time mode" (getReader() has been
 // called on this instance).
 type ReaderPool struct {
+	sync.Mutex // guards readerMap and poolReaders
+
 	readerMap               map[index.SegmentCommitInfo]*ReadersAndUpdates // Map<SegmentCommitInfo,ReadersAndUpdates>
 	directory               store.Directory
 	originalDirectory       store.Directory
@@ -85,13 +88,15 @@ func NewReaderPool(directory, originalDirectory store.Directory, segmentInfos *S
 }
 
 func (p *ReaderPool) anyDocValuesChanges() bool {
-	//for (ReadersAndUpdates rld : readerMap.values()) {
-	//	// NOTE: we don't check for pending deletes because deletes carry over in RAM to NRT readers
-	//	if (rld.getNumDVUpdates() != 0) {
-	//		return true;
-	//	}
-	//}
-	// TODO: fix it
+	p.Lock()
+	defer p.Unlock()
+
+	for _, rld := range p.readerMap {
+		// NOTE: we don't check for pending deletes because deletes carry over in RAM to NRT readers
+		if rld.GetNumDVUpdates() != 0 {
+			return true
+		}
+	}
 	return false
 }
 
@@ -116,6 +121,9 @@ func (p *ReaderPool) newPendingDeletesV1(reader *SegmentReader, info index.Segme
 // get dropped via calls to drop(SegmentCommitInfo) or dropAll() or close(). IndexReader pooling is disabled
 // upon construction but can't be disabled again once it's enabled.
 func (p *ReaderPool) enableReaderPooling() {
+	p.Lock()
+	defer p.Unlock()
+
 	p.poolReaders = true
 }
 
@@ -123,6 +131,9 @@ func (p *ReaderPool) enableReaderPooling() {
 // Obtain a ReadersAndLiveDocs instance from the readerPool. If create is true,
 // you must later call release(ReadersAndUpdates, boolean).
 func (p *ReaderPool) Get(info index.SegmentCommitInfo, create bool) (*ReadersAndUpdates, error) {
+	p.Lock()
+	defer p.Unlock()
+
 	if p.closed.Load() {
 		return nil, errors.New("ReaderPool is already closed")
 	}
@@ -146,6 +157,9 @@ func (p *ReaderPool) Get(info index.SegmentCommitInfo, create bool) (*ReadersAnd
 }
 
 func (p *ReaderPool) commit(infos *SegmentInfos) (bool, error) {
+	p.Lock()
+	defer p.Unlock()
+
 	atLeastOneChange := false
 	for _, segment := range infos.segments {
 		rld, ok := p.readerMap[segment]
@@ -180,6 +194,79 @@ func (p *ReaderPool) commit(infos *SegmentInfos) (bool, error) {
 	return atLeastOneChange, nil
 }
 
+// Writes all doc values updates to disk if there are any.
+// Returns true iff any files where written
 func (p *ReaderPool) writeAllDocValuesUpdates() (bool, error) {
-	panic("")
+	p.Lock()
+	defer p.Unlock()
+
+	anyChanges := false
+	for _, rld := range p.readerMap {
+		updated, err := rld.writeFieldUpdates(p.directory, p.fieldNumbers, p.completedDelGenSupplier())
+		if err != nil {
+			return false, err
+		}
+		anyChanges = anyChanges || updated
+	}
+	return anyChanges, nil
+}
+
+// Release the ReadersAndUpdates. This method will write the live docs and doc values updates
+// to disk if reader pooling is not enabled, returns true if any files were written.
+func (p *ReaderPool) release(rld *ReadersAndUpdates, assertInfoLive bool) (bool, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	changed := false
+	rld.DecRef()
+
+	if rld.RefCount() == 0 {
+		// this happens if the reader pool is already closed and the pool drops the
+		// reference it held
+		return false, nil
+	}
+
+	if !p.poolReaders && rld.RefCount() == 1 {
+		if _, ok := p.readerMap[rld.info]; ok {
+			// This is the last ref to this RLD, and we're not
+			// pooling, so remove it:
+			written, err := rld.writeLiveDocs(p.directory)
+			if err != nil {
+				return false, err
+			}
+			changed = written
+
+			updated, err := rld.writeFieldUpdates(p.directory, p.fieldNumbers, p.completedDelGenSupplier())
+			if err != nil {
+				return false, err
+			}
+			changed = changed || updated
+
+			if rld.GetNumDVUpdates() == 0 {
+				if err := rld.dropReaders(); err != nil {
+					return false, err
+				}
+				delete(p.readerMap, rld.info)
+			}
+			// else: We are forced to pool this segment until its deletes fully apply (no delGen gaps)
+		}
+	}
+	return changed, nil
+}
+
+// Drops the ReadersAndUpdates of the segment, its pending changes are lost.
+// Returns true if the segment was in the pool.
+func (p *ReaderPool) drop(info index.SegmentCommitInfo) (bool, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	rld, ok := p.readerMap[info]
+	if !ok {
+		return false, nil
+	}
+	delete(p.readerMap, info)
+	if err := rld.dropReaders(); err != nil {
+		return false, err
+	}
+	return true, nil
 }
@@ -1,18 +1,32 @@
 package index
 
 import (
+	"cmp"
 	"context"
 	"errors"
-	"github.com/geange/lucene-go/core/interface/index"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
 	"sync/atomic"
 
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
 	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util"
 )
 
 // ReadersAndUpdates
 // Used by IndexWriter to hold open SegmentReaders (for searching or merging), plus pending deletes and updates,
 // for a given segment
 type ReadersAndUpdates struct {
+	// guards the reader, the pending deletes and the pending doc values updates
+	sync.Mutex
+
 	// Not final because we replace (clone) when we need to
 	// change it and it's been shared:
 	info index.SegmentCommitInfo
@@ -54,9 +68,13 @@ type ReadersAndUpdates struct {
 func NewReadersAndUpdates(indexCreatedVersionMajor int,
 	info index.SegmentCommitInfo, pendingDeletes PendingDeletes) *ReadersAndUpdates {
 
+	// the pool holds the initial reference
+	refCount := new(atomic.Int64)
+	refCount.Store(1)
+
 	return &ReadersAndUpdates{
 		info:                     info,
-		refCount:                 new(atomic.Int64),
+		refCount:                 refCount,
 		reader:                   nil,
 		pendingDeletes:           pendingDeletes,
 		indexCreatedVersionMajor: indexCreatedVersionMajor,
@@ -90,15 +108,54 @@ func (r *ReadersAndUpdates) RefCount() int64 {
 	return r.refCount.Load()
 }
 
+// GetLiveDocs
+// Returns the live docs of the segment including the pending deletes, nil if all documents are live.
+func (r *ReadersAndUpdates) GetLiveDocs() util.Bits {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.pendingDeletes.GetLiveDocs()
+}
+
 func (r *ReadersAndUpdates) GetDelCount() int {
+	r.Lock()
+	defer r.Unlock()
+
 	return r.pendingDeletes.GetDelCount()
 }
 
+// Returns the documents deleted since prevLiveDocs were taken, prevLiveDocs is nil if all the
+// documents were live then.
+func (r *ReadersAndUpdates) deletedDocsSince(prevLiveDocs util.Bits, maxDoc int) []int {
+	r.Lock()
+	defer r.Unlock()
+
+	currentLiveDocs := r.pendingDeletes.GetLiveDocs()
+	if currentLiveDocs == nil {
+		return nil
+	}
+	docs := make([]int, 0)
+	for docID := 0; docID < maxDoc; docID++ {
+		if currentLiveDocs.Test(uint(docID)) {
+			continue
+		}
+		if prevLiveDocs != nil && !prevLiveDocs.Test(uint(docID)) {
+			// already deleted when the merge started
+			continue
+		}
+		docs = append(docs, docID)
+	}
+	return docs
+}
+
 // AddDVUpdate
 // Adds a new resolved (meaning it maps docIDs to new values) doc values packet.
 // We buffer these in RAM and write to disk when too much RAM is used or when a merge needs
 // to kick off, or a commit/refresh.
 func (r *ReadersAndUpdates) AddDVUpdate(update DocValuesFieldUpdates) error {
+	r.Lock()
+	defer r.Unlock()
+
 	if update.GetFinished() == false {
 		return errors.New("call finish first")
 	}
@@ -122,6 +179,9 @@ func (r *ReadersAndUpdates) AddDVUpdate(update DocValuesFieldUpdates) error {
 }
 
 func (r *ReadersAndUpdates) GetNumDVUpdates() int {
+	r.Lock()
+	defer r.Unlock()
+
 	count := 0
 	for _, updates := range r.pendingDVUpdates {
 		count += len(updates)
@@ -130,6 +190,13 @@ func (r *ReadersAndUpdates) GetNumDVUpdates() int {
 }
 
 func (r *ReadersAndUpdates) GetReader(ctx context.Context, ioContext *store.IOContext) (*SegmentReader, error) {
+	r.Lock()
+	defer r.Unlock()
+	return r.getReaderLocked(ctx, ioContext)
+}
+
+// r.Mutex must be held.
+func (r *ReadersAndUpdates) getReaderLocked(ctx context.Context, ioContext *store.IOContext) (*SegmentReader, error) {
 	if r.reader == nil {
 		// We steal returned ref:
 		reader, err := NewSegmentReader(ctx, r.info, r.indexCreatedVersionMajor, ioContext)
@@ -154,18 +221,525 @@ func (r *ReadersAndUpdates) Release(sr *SegmentReader) error {
 	return sr.DecRef()
 }
 
+// Delete
+// Marks the document as deleted, returns true if it was live.
+func (r *ReadersAndUpdates) Delete(docID int) (bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.reader == nil && r.pendingDeletes.MustInitOnDelete() {
+		// pass a reader to initialize the pending deletes
+		reader, err := r.getReaderLocked(context.Background(), store.READ)
+		if err != nil {
+			return false, err
+		}
+		if err := reader.DecRef(); err != nil {
+			return false, err
+		}
+	}
+	return r.pendingDeletes.Delete(docID)
+}
+
+// GetReadOnlyClone
+// Returns a ref to a clone. NOTE: you should decRef() the reader when you're done (ie do not call close()).
+func (r *ReadersAndUpdates) GetReadOnlyClone(ctx context.Context, ioContext *store.IOContext) (*SegmentReader, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.reader == nil {
+		reader, err := r.getReaderLocked(ctx, ioContext)
+		if err != nil {
+			return nil, err
+		}
+		if err := reader.DecRef(); err != nil {
+			return nil, err
+		}
+	}
+
+	liveDocs := r.pendingDeletes.GetLiveDocs()
+	if liveDocs == nil {
+		if err := r.reader.IncRef(); err != nil {
+			return nil, err
+		}
+		return r.reader, nil
+	}
+
+	numDocs, err := r.pendingDeletes.NumDocs()
+	if err != nil {
+		return nil, err
+	}
+	return r.reader.New(r.info, liveDocs, r.pendingDeletes.GetHardLiveDocs(), numDocs, true)
+}
+
+// Drops the reader of this instance, the pending changes are lost.
+func (r *ReadersAndUpdates) dropReaders() error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.reader == nil {
+		return nil
+	}
+	reader := r.reader
+	r.reader = nil
+	return reader.DecRef()
+}
+
 func (r *ReadersAndUpdates) writeLiveDocs(directory store.Directory) (bool, error) {
-	panic("")
+	r.Lock()
+	defer r.Unlock()
+
+	return r.pendingDeletes.WriteLiveDocs(context.Background(), directory)
 }
 
-func (r *ReadersAndUpdates) writeFieldUpdates(directory store.Directory, numbers *FieldNumbers, supplier int64) (bool, error) {
-	panic("")
+// Writes the pending doc values updates of the packets up to maxDelGen to new generations of the doc
+// values files of the updated fields, and a new generation of the FieldInfos. Returns true if any file
+// was written.
+func (r *ReadersAndUpdates) writeFieldUpdates(dir store.Directory, fieldNumbers *FieldNumbers, maxDelGen int64) (bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	anyUpdates := false
+	for _, updates := range r.pendingDVUpdates {
+		// Sort by increasing delGen:
+		slices.SortStableFunc(updates, func(a, b DocValuesFieldUpdates) int {
+			return cmp.Compare(a.DelGen(), b.DelGen())
+		})
+		for _, update := range updates {
+			if update.DelGen() <= maxDelGen && update.Any() {
+				anyUpdates = true
+				break
+			}
+		}
+	}
+
+	if !anyUpdates {
+		// no updates
+		return false, nil
+	}
+
+	ctx := context.Background()
+	// Do this so we can delete any created files on
+	// exception; this saves all codecs from having to do it:
+	trackingDir := store.NewTrackingDirectoryWrapper(dir)
+	codec := r.info.Info().GetCodec()
+
+	// reader could be nil e.g. for a just merged segment
+	reader := r.reader
+	if reader == nil {
+		var err error
+		reader, err = NewSegmentReader(ctx, r.info, r.indexCreatedVersionMajor, store.READONCE)
+		if err != nil {
+			return false, err
+		}
+		defer reader.DecRef()
+		if err := r.pendingDeletes.OnNewReader(reader, r.info); err != nil {
+			return false, err
+		}
+	}
+
+	// clone FieldInfos so that we can update their dvGen separately from
+	// the reader's infos and write them to a new fieldInfos_gen file.
+	maxFieldNumber := -1
+	byName := make(map[string]*document.FieldInfo)
+	for _, fi := range reader.GetFieldInfos().List() {
+		// the local field numbers are preserved, they can be different from
+		// the global ones
+		byName[fi.Name()] = cloneFieldInfo(fi, fi.Number())
+		maxFieldNumber = max(fi.Number(), maxFieldNumber)
+	}
+
+	// create new fields with the right DV type
+	builder := NewFieldInfosBuilder(fieldNumbers)
+	for _, updates := range r.pendingDVUpdates {
+		update := updates[0]
+
+		fi, ok := byName[update.Field()]
+		if !ok {
+			// the field is not present in this segment so we clone the global field
+			// (which is guaranteed to exist) and remaps its field number locally.
+			globalFieldInfo, err := builder.GetOrAdd(update.Field())
+			if err != nil {
+				return false, err
+			}
+			maxFieldNumber++
+			fi = cloneFieldInfo(globalFieldInfo, maxFieldNumber)
+			byName[fi.Name()] = fi
+		}
+		if err := fi.SetDocValuesType(update.Type()); err != nil {
+			return false, err
+		}
+	}
+	infos := make([]*document.FieldInfo, 0, len(byName))
+	for _, fi := range byName {
+		infos = append(infos, fi)
+	}
+	fieldInfos := NewFieldInfos(infos)
+
+	newDVFiles, err := r.handleDVUpdates(ctx, fieldInfos, trackingDir, codec.DocValuesFormat(), reader, maxDelGen)
+	if err != nil {
+		return false, errors.Join(err, deleteCreatedFiles(ctx, dir, trackingDir))
+	}
+
+	fieldInfosFiles, err := r.writeFieldInfosGen(ctx, fieldInfos, trackingDir, codec.FieldInfosFormat())
+	if err != nil {
+		return false, errors.Join(err, deleteCreatedFiles(ctx, dir, trackingDir))
+	}
+
+	// Prune the now-written DV updates:
+	for field, updates := range r.pendingDVUpdates {
+		updates = slices.DeleteFunc(updates, func(update DocValuesFieldUpdates) bool {
+			return update.DelGen() <= maxDelGen
+		})
+		if len(updates) == 0 {
+			delete(r.pendingDVUpdates, field)
+		} else {
+			r.pendingDVUpdates[field] = updates
+		}
+	}
+
+	// writing field updates succeeded
+	r.info.SetFieldInfosFiles(fieldInfosFiles)
+
+	// update the doc-values updates files. the files map each field to its set
+	// of files, hence we copy from the existing map all fields w/ updates that
+	// were not updated in this session, and add new mappings for fields that
+	// were updated now.
+	for number, files := range r.info.GetDocValuesUpdatesFiles() {
+		if _, ok := newDVFiles[number]; !ok {
+			newDVFiles[number] = files
+		}
+	}
+	r.info.SetDocValuesUpdatesFiles(newDVFiles)
+
+	// if there is a reader open, reopen it to reflect the updates
+	if r.reader != nil {
+		if err := r.swapNewReaderWithLatestLiveDocs(); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func deleteCreatedFiles(ctx context.Context, dir store.Directory, trackingDir *store.TrackingDirectoryWrapper) error {
+	var errs []error
+	for file := range trackingDir.GetCreatedFiles() {
+		errs = append(errs, dir.DeleteFile(ctx, file))
+	}
+	return errors.Join(errs...)
+}
+
+func cloneFieldInfo(fi *document.FieldInfo, fieldNumber int) *document.FieldInfo {
+	clone := document.NewFieldInfo(fi.Name(), fieldNumber, fi.HasVectors(), fi.OmitsNorms(), fi.HasPayloads(),
+		fi.GetIndexOptions(), fi.GetDocValuesType(), fi.GetDocValuesGen(), maps.Clone(fi.Attributes()),
+		fi.GetPointDimensionCount(), fi.GetPointIndexDimensionCount(), fi.GetPointNumBytes(), fi.IsSoftDeletesField())
+	if fi.GetVectorDimension() > 0 {
+		_ = clone.SetVectorDimensionAndSimilarityFunction(fi.GetVectorDimension(), fi.GetVectorSimilarityFunction())
+	}
+	return clone
+}
+
+// Writes each updated field to its own doc values generation, returns the files written for each
+// field number
+func (r *ReadersAndUpdates) handleDVUpdates(ctx context.Context, infos index.FieldInfos, dir store.Directory,
+	dvFormat index.DocValuesFormat, reader *SegmentReader, maxDelGen int64) (map[int]map[string]struct{}, error) {
+
+	fieldFiles := make(map[int]map[string]struct{})
+	for field, updates := range r.pendingDVUpdates {
+		dvType := updates[0].Type()
+		if dvType != document.DOC_VALUES_TYPE_NUMERIC && dvType != document.DOC_VALUES_TYPE_BINARY {
+			return nil, fmt.Errorf("unsupported type: %s", dvType)
+		}
+
+		updatesToApply := make([]DocValuesFieldUpdates, 0, len(updates))
+		for _, update := range updates {
+			if update.DelGen() <= maxDelGen {
+				// safe to apply this one
+				updatesToApply = append(updatesToApply, update)
+			}
+		}
+		if len(updatesToApply) == 0 {
+			// nothing to apply yet
+			continue
+		}
+
+		nextDocValuesGen := r.info.GetNextDocValuesGen()
+		fieldInfo := infos.FieldInfo(field)
+		if err := fieldInfo.SetDocValuesGen(nextDocValuesGen); err != nil {
+			return nil, err
+		}
+
+		updateSupplier := func() (DocValuesFieldUpdatesIterator, error) {
+			subs := make([]DocValuesFieldUpdatesIterator, 0, len(updatesToApply))
+			for _, update := range updatesToApply {
+				it, err := update.Iterator()
+				if err != nil {
+					return nil, err
+				}
+				subs = append(subs, it)
+			}
+			return MergedDocValuesFieldUpdatesIterator(subs)
+		}
+
+		iterator, err := updateSupplier()
+		if err != nil {
+			return nil, err
+		}
+		if iterator != nil {
+			r.pendingDeletes.OnDocValuesUpdate(fieldInfo, iterator)
+		}
+
+		// separately also track which files were created for this gen
+		trackingDir := store.NewTrackingDirectoryWrapper(dir)
+		if err := writeDocValuesGen(ctx, trackingDir, r.info, fieldInfo, nextDocValuesGen, dvFormat, reader, updateSupplier); err != nil {
+			return nil, err
+		}
+		r.info.AdvanceDocValuesGen()
+		fieldFiles[fieldInfo.Number()] = trackingDir.GetCreatedFiles()
+	}
+	return fieldFiles, nil
+}
+
+// Writes the values of the field merged with its updates to the doc values generation dvGen
+func writeDocValuesGen(ctx context.Context, dir store.Directory, info index.SegmentCommitInfo, fieldInfo *document.FieldInfo,
+	dvGen int64, dvFormat index.DocValuesFormat, reader *SegmentReader,
+	updateSupplier func() (DocValuesFieldUpdatesIterator, error)) error {
+
+	maxDoc, err := info.Info().MaxDoc()
+	if err != nil {
+		return err
+	}
+	ioContext := store.NewIOContext(store.WithFlushInfo(store.NewFlushInfo(maxDoc, 0)))
+	state := index.NewSegmentWriteState(dir, info.Info(), NewFieldInfos([]*document.FieldInfo{fieldInfo}), nil, ioContext)
+	state.SegmentSuffix = strconv.FormatInt(dvGen, 36)
+
+	fieldsConsumer, err := dvFormat.FieldsConsumer(ctx, state)
+	if err != nil {
+		return err
+	}
+
+	field := fieldInfo.Name()
+	if fieldInfo.GetDocValuesType() == document.DOC_VALUES_TYPE_BINARY {
+		err = fieldsConsumer.AddBinaryField(ctx, fieldInfo, &EmptyDocValuesProducer{
+			FnGetBinary: func(ctx context.Context, _ *document.FieldInfo) (index.BinaryDocValues, error) {
+				onDisk, err := reader.GetBinaryDocValues(field)
+				if err != nil {
+					return nil, err
+				}
+				iterator, err := updateSupplier()
+				if err != nil {
+					return nil, err
+				}
+				var updates index.BinaryDocValues
+				if iterator != nil {
+					updates = AsBinaryDocValues(iterator)
+				}
+				// Merge sort of the original doc values with updated doc values:
+				merged := newMergedDocValues[index.BinaryDocValues](onDisk, updates, iterator)
+				return &BaseBinaryDocValues{
+					FnDocID:        merged.DocID,
+					FnNextDoc:      merged.NextDoc,
+					FnAdvance:      merged.Advance,
+					FnSlowAdvance:  merged.SlowAdvance,
+					FnCost:         merged.Cost,
+					FnAdvanceExact: merged.AdvanceExact,
+					FnBinaryValue: func() ([]byte, error) {
+						return merged.current.BinaryValue()
+					},
+				}, nil
+			},
+		})
+	} else {
+		// write the numeric updates to a new gen'd docvalues file
+		err = fieldsConsumer.AddNumericField(ctx, fieldInfo, &EmptyDocValuesProducer{
+			FnGetNumeric: func(ctx context.Context, _ *document.FieldInfo) (index.NumericDocValues, error) {
+				onDisk, err := reader.GetNumericDocValues(field)
+				if err != nil {
+					return nil, err
+				}
+				iterator, err := updateSupplier()
+				if err != nil {
+					return nil, err
+				}
+				var updates index.NumericDocValues
+				if iterator != nil {
+					updates = AsNumericDocValues(iterator)
+				}
+				// Merge sort of the original doc values with updated doc values:
+				merged := newMergedDocValues[index.NumericDocValues](onDisk, updates, iterator)
+				return &NumericDocValuesDefault{
+					FnDocID:        merged.DocID,
+					FnNextDoc:      merged.NextDoc,
+					FnAdvance:      merged.Advance,
+					FnSlowAdvance:  merged.SlowAdvance,
+					FnCost:         merged.Cost,
+					FnAdvanceExact: merged.AdvanceExact,
+					FnLongValue: func() (int64, error) {
+						return merged.current.LongValue()
+					},
+				}, nil
+			},
+		})
+	}
+	return errors.Join(err, fieldsConsumer.Close())
+}
+
+// mergedDocValues
+// Merges the current on-disk doc values with the incoming updates, the value of an update always wins
+// over the on-disk one, and the documents whose update has no value are skipped.
+type mergedDocValues[T types.DocValuesIterator] struct {
+	updateIterator DocValuesFieldUpdatesIterator
+	// merged docID
+	docIDOut int
+	// docID from our original doc values
+	docIDOnDisk int
+	// docID from our updates
+	updateDocID int
+
+	onDisk  T
+	updates T
+	// the doc values positioned on the current document
+	current T
+}
+
+// onDisk and updates may be nil
+func newMergedDocValues[T types.DocValuesIterator](onDisk, updates T, updateIterator DocValuesFieldUpdatesIterator) *mergedDocValues[T] {
+	return &mergedDocValues[T]{
+		updateIterator: updateIterator,
+		docIDOut:       -1,
+		docIDOnDisk:    -1,
+		updateDocID:    -1,
+		onDisk:         onDisk,
+		updates:        updates,
+	}
+}
+
+func (m *mergedDocValues[T]) DocID() int {
+	return m.docIDOut
+}
+
+func (m *mergedDocValues[T]) NextDoc() (int, error) {
+	for {
+		if m.docIDOnDisk == m.docIDOut {
+			doc, err := nextDocOrExhausted(m.onDisk)
+			if err != nil {
+				return 0, err
+			}
+			m.docIDOnDisk = doc
+		}
+		if m.updateDocID == m.docIDOut {
+			doc, err := nextDocOrExhausted(m.updates)
+			if err != nil {
+				return 0, err
+			}
+			m.updateDocID = doc
+		}
+
+		hasValue := true
+		if m.docIDOnDisk < m.updateDocID {
+			// no update to this doc - we use the on-disk values
+			m.docIDOut = m.docIDOnDisk
+			m.current = m.onDisk
+		} else {
+			m.docIDOut = m.updateDocID
+			if m.docIDOut != types.NO_MORE_DOCS {
+				m.current = m.updates
+				hasValue = m.updateIterator.HasValue()
+			}
+		}
+
+		if m.docIDOut == types.NO_MORE_DOCS {
+			return m.docIDOut, io.EOF
+		}
+		if hasValue {
+			return m.docIDOut, nil
+		}
+	}
+}
+
+// Returns the next document of the iterator, NO_MORE_DOCS if it is exhausted or nil
+func nextDocOrExhausted(iterator types.DocIdSetIterator) (int, error) {
+	if iterator == nil || reflect.ValueOf(iterator).IsNil() {
+		return types.NO_MORE_DOCS, nil
+	}
+	doc, err := iterator.NextDoc()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return types.NO_MORE_DOCS, nil
+		}
+		return 0, err
+	}
+	return doc, nil
+}
+
+func (m *mergedDocValues[T]) Advance(target int) (int, error) {
+	return 0, errors.New("unsupported operation exception")
+}
+
+func (m *mergedDocValues[T]) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(m, target)
+}
+
+func (m *mergedDocValues[T]) AdvanceExact(target int) (bool, error) {
+	return false, errors.New("unsupported operation exception")
+}
+
+func (m *mergedDocValues[T]) Cost() int64 {
+	if reflect.ValueOf(m.onDisk).IsNil() {
+		return 0
+	}
+	return m.onDisk.Cost()
+}
+
+// Writes the FieldInfos to a new generation, returns the files written
+func (r *ReadersAndUpdates) writeFieldInfosGen(ctx context.Context, fieldInfos index.FieldInfos, dir store.Directory,
+	infosFormat index.FieldInfosFormat) (map[string]struct{}, error) {
+
+	nextFieldInfosGen := r.info.GetNextFieldInfosGen()
+	segmentSuffix := strconv.FormatInt(nextFieldInfosGen, 36)
+	// separately also track which files were created for this gen
+	trackingDir := store.NewTrackingDirectoryWrapper(dir)
+	if err := infosFormat.Write(ctx, trackingDir, r.info.Info(), segmentSuffix, fieldInfos, store.DEFAULT); err != nil {
+		return nil, err
+	}
+	r.info.AdvanceFieldInfosGen()
+	return trackingDir.GetCreatedFiles(), nil
+}
+
+// Replaces the reader by a new one sharing its core, with the latest live docs and doc values updates
+func (r *ReadersAndUpdates) swapNewReaderWithLatestLiveDocs() error {
+	if r.reader == nil {
+		return errors.New("must load reader first")
+	}
+	numDocs, err := r.pendingDeletes.NumDocs()
+	if err != nil {
+		return err
+	}
+	newReader, err := r.reader.New(r.info, r.pendingDeletes.GetLiveDocs(), r.pendingDeletes.GetHardLiveDocs(), numDocs, true)
+	if err != nil {
+		return err
+	}
+	if err := r.pendingDeletes.OnNewReader(newReader, r.info); err != nil {
+		return errors.Join(err, newReader.DecRef())
+	}
+	reader := r.reader
+	r.reader = newReader
+	return reader.DecRef()
 }
 
 func (r *ReadersAndUpdates) IsFullyDeleted() (bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
 	return r.pendingDeletes.IsFullyDeleted(nil, r.getLatestReader)
 }
 
 func (r *ReadersAndUpdates) getLatestReader() index.CodecReader {
-	panic("")
+	if r.reader == nil {
+		reader, err := r.getReaderLocked(context.Background(), store.READ)
+		if err != nil {
+			return nil
+		}
+		_ = reader.DecRef()
+	}
+	return r.reader
 }
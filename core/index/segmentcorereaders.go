@@ -3,6 +3,7 @@ package index
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"sync/atomic"
 
@@ -27,6 +28,7 @@ type SegmentCoreReaders struct {
 	fieldsReaderOrig      index.StoredFieldsReader
 	termVectorsReaderOrig index.TermVectorsReader
 	pointsReader          index.PointsReader
+	knnVectorsReader      index.KnnVectorsReader
 	cfsReader             index.CompoundDirectory
 	segment               string
 
@@ -50,7 +52,9 @@ func NewSegmentCoreReaders(ctx context.Context, dir store.Directory, si index.Se
 	// confusing name: if (cfs) it's the cfsdir, otherwise it's the segment's directory.
 	var cfsDir store.Directory
 
-	r := &SegmentCoreReaders{}
+	r := &SegmentCoreReaders{ref: new(atomic.Int64)}
+	// the core is created with a ref for the first SegmentReader
+	r.ref.Store(1)
 
 	if si.Info().GetUseCompoundFile() {
 		reader, err := codec.CompoundFormat().GetCompoundReader(ctx, dir, si.Info(), ioContext)
@@ -123,11 +127,24 @@ func NewSegmentCoreReaders(ctx context.Context, dir store.Directory, si index.Se
 		r.pointsReader = nil
 	}
 
+	if r.coreFieldInfos.HasVectorValues() {
+		format := codec.KnnVectorsFormat()
+		if format == nil {
+			return nil, fmt.Errorf("codec %s does not support vectors", codec.GetName())
+		}
+		r.knnVectorsReader, err = format.FieldsReader(ctx, segmentReadState)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		r.knnVectorsReader = nil
+	}
+
 	return r, nil
 }
 
 func (s *SegmentCoreReaders) incRef() error {
-	if s.ref.Load() < 0 {
+	if s.ref.Load() <= 0 {
 		return errors.New("segmentCoreReaders is already closed")
 	}
 	s.ref.Add(1)
@@ -146,6 +163,7 @@ func (s *SegmentCoreReaders) decRef() error {
 			s.cfsReader,
 			s.normsProducer,
 			s.pointsReader,
+			s.knnVectorsReader,
 		}
 
 		if err := closeAll(closers...); err != nil {
@@ -157,6 +175,9 @@ func (s *SegmentCoreReaders) decRef() error {
 
 func closeAll(objects ...io.Closer) error {
 	for _, object := range objects {
+		if object == nil {
+			continue
+		}
 		if err := object.Close(); err != nil {
 			return err
 		}
@@ -2,9 +2,11 @@ package index
 
 import (
 	"context"
+	"errors"
 	"github.com/geange/lucene-go/core/document"
 	"github.com/geange/lucene-go/core/interface/index"
 	"strconv"
+	"sync"
 
 	"github.com/geange/lucene-go/core/store"
 	"github.com/geange/lucene-go/core/util"
@@ -13,12 +15,17 @@ import (
 // SegmentDocValues
 // Manages the DocValuesProducer held by SegmentReader and keeps track of their reference counting.
 type SegmentDocValues struct {
+	sync.Mutex
+
 	genDVProducers map[int64]*util.RefCount[index.DocValuesProducer]
 }
 
 func (s *SegmentDocValues) GetDocValuesProducer(gen int64,
 	si index.SegmentCommitInfo, dir store.Directory, infos index.FieldInfos) (index.DocValuesProducer, error) {
 
+	s.Lock()
+	defer s.Unlock()
+
 	dvp, ok := s.genDVProducers[gen]
 	if !ok {
 		var err error
@@ -62,6 +69,9 @@ func (s *SegmentDocValues) newDocValuesProducer(si index.SegmentCommitInfo,
 }
 
 func (s *SegmentDocValues) decRef(gens []int64) error {
+	s.Lock()
+	defer s.Unlock()
+
 	for _, gen := range gens {
 		dvp, ok := s.genDVProducers[gen]
 		if ok {
@@ -112,6 +122,7 @@ func NewSegmentDocValuesProducer(si index.SegmentCommitInfo, dir store.Directory
 				p.dvGens = append(p.dvGens, docValuesGen)
 				p.dvProducers = append(p.dvProducers, baseProducer)
 			}
+			p.dvProducersByField[fi.Name()] = baseProducer
 		} else {
 			//assert !dvGens.contains(docValuesGen);
 			// otherwise, producer sees only the one fieldinfo it wrote
@@ -131,37 +142,36 @@ func (s *SegmentDocValuesProducer) GetMergeInstance() index.DocValuesProducer {
 	return s
 }
 
+// Close The producers are closed by the SegmentDocValues once they are no longer referenced
 func (s *SegmentDocValuesProducer) Close() error {
-	//TODO implement me
-	panic("implement me")
+	return errors.New("unsupported operation exception")
 }
 
 func (s *SegmentDocValuesProducer) GetNumeric(ctx context.Context, field *document.FieldInfo) (index.NumericDocValues, error) {
-	//TODO implement me
-	panic("implement me")
+	return s.dvProducersByField[field.Name()].GetNumeric(ctx, field)
 }
 
 func (s *SegmentDocValuesProducer) GetBinary(ctx context.Context, field *document.FieldInfo) (index.BinaryDocValues, error) {
-	//TODO implement me
-	panic("implement me")
+	return s.dvProducersByField[field.Name()].GetBinary(ctx, field)
 }
 
 func (s *SegmentDocValuesProducer) GetSorted(ctx context.Context, fieldInfo *document.FieldInfo) (index.SortedDocValues, error) {
-	//TODO implement me
-	panic("implement me")
+	return s.dvProducersByField[fieldInfo.Name()].GetSorted(ctx, fieldInfo)
 }
 
 func (s *SegmentDocValuesProducer) GetSortedNumeric(ctx context.Context, field *document.FieldInfo) (index.SortedNumericDocValues, error) {
-	//TODO implement me
-	panic("implement me")
+	return s.dvProducersByField[field.Name()].GetSortedNumeric(ctx, field)
 }
 
 func (s *SegmentDocValuesProducer) GetSortedSet(ctx context.Context, field *document.FieldInfo) (index.SortedSetDocValues, error) {
-	//TODO implement me
-	panic("implement me")
+	return s.dvProducersByField[field.Name()].GetSortedSet(ctx, field)
 }
 
 func (s *SegmentDocValuesProducer) CheckIntegrity() error {
-	//TODO implement me
-	panic("implement me")
+	for _, producer := range s.dvProducers {
+		if err := producer.CheckIntegrity(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
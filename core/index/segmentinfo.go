@@ -78,6 +78,7 @@ func NewSegmentInfo(dir store.Directory, version, minVersion *version.Version, n
 		indexSort:      indexSort,
 		version:        version,
 		minVersion:     minVersion,
+		setFiles:       make(map[string]struct{}),
 	}
 }
 
@@ -236,12 +237,11 @@ func checkFileNames(files []string) error {
 // locates the boundary of the segment name, or -1
 func indexOfSegmentName(filename string) int {
 	// If it is a .del file, there's an '_' after the first character
-	idx := strings.Index(filename[1:], "_")
-	if idx == -1 {
-		// If it's not, strip everything that's before the '.'
-		idx = strings.Index(filename, ".")
+	if idx := strings.Index(filename[1:], "_"); idx != -1 {
+		return idx + 1
 	}
-	return idx
+	// If it's not, strip everything that's before the '.'
+	return strings.Index(filename, ".")
 }
 
 // StripSegmentName
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -255,17 +257,23 @@ func (s *SegmentInfos) Commit(ctx context.Context, dir store.Directory) error {
 
 // Returns the committed segments_N filename.
 func (s *SegmentInfos) finishCommit(ctx context.Context, dir store.Directory) (string, error) {
+	if !s.pendingCommit {
+		return "", errors.New("prepareCommit was not called")
+	}
+
 	src := FileNameFromGeneration(PENDING_SEGMENTS, "", s.generation)
 	dest := FileNameFromGeneration(SEGMENTS, "", s.generation)
 	if err := dir.Rename(ctx, src, dest); err != nil {
-		return "", err
+		return "", errors.Join(err, s.RollbackCommit(dir))
 	}
+	s.pendingCommit = false
+	s.lastGeneration = s.generation
 	return dest, nil
 }
 
 func (s *SegmentInfos) writeIndexOutput(ctx context.Context, out store.IndexOutput) error {
 	if err := codecUtil.WriteIndexHeader(ctx, out, "segments", VERSION_CURRENT,
-		util.RandomId(), fmt.Sprintf("%d", s.generation)); err != nil {
+		util.RandomId(), strconv.FormatInt(s.generation, 36)); err != nil {
 		return err
 	}
 
@@ -411,18 +419,26 @@ func (s *SegmentInfos) writeDir(ctx context.Context, directory store.Directory)
 	// Always advance the generation on write:
 	s.generation = nextGeneration
 
-	var segNOutput store.IndexOutput
-
 	segNOutput, err := directory.CreateOutput(ctx, segmentFileName)
 	if err != nil {
 		return err
 	}
 	if err := s.writeIndexOutput(ctx, segNOutput); err != nil {
+		_ = segNOutput.Close()
+		// best effort, the commit failed anyway
+		_ = directory.DeleteFile(ctx, segmentFileName)
 		return err
 	}
 	if err := segNOutput.Close(); err != nil {
+		_ = directory.DeleteFile(ctx, segmentFileName)
 		return err
 	}
+	// the segments file must be durable before it is renamed by finishCommit
+	if err := directory.Sync(map[string]struct{}{segmentFileName: {}}); err != nil {
+		_ = directory.DeleteFile(ctx, segmentFileName)
+		return err
+	}
+	s.pendingCommit = true
 	return nil
 }
 
@@ -466,7 +482,36 @@ func (s *SegmentInfos) GetVersion() int64 {
 }
 
 func (s *SegmentInfos) Remove(index int) {
-	s.segments[index] = nil
+	s.segments = slices.Delete(s.segments, index, index+1)
+}
+
+// RemoveInfo
+// Removes the segment, returns true if it was in the segments.
+func (s *SegmentInfos) RemoveInfo(si index.SegmentCommitInfo) bool {
+	idx := slices.Index(s.segments, si)
+	if idx == -1 {
+		return false
+	}
+	s.Remove(idx)
+	return true
+}
+
+// Replaces the segments of the merge by the merged segment, at the position of the first merged
+// segment, or only removes them if the merged segment is dropped.
+func (s *SegmentInfos) applyMergeChanges(merge *OneMerge, dropSegment bool) {
+	segments := make([]index.SegmentCommitInfo, 0, len(s.segments))
+	inserted := false
+	for _, info := range s.segments {
+		if slices.Contains(merge.segments, info) {
+			if !inserted && !dropSegment {
+				segments = append(segments, merge.info)
+				inserted = true
+			}
+			continue
+		}
+		segments = append(segments, info)
+	}
+	s.segments = segments
 }
 
 // return generation of the next pending_segments_N that will be written
@@ -478,12 +523,31 @@ func (s *SegmentInfos) getNextPendingGeneration() int64 {
 	}
 }
 
-func (s *SegmentInfos) SetUserData(data map[string]string, b bool) {
-
+// SetUserData Sets the commit data. If doIncrementVersion is true, the version of this
+// SegmentInfos is incremented so that NRT readers reopen on the change.
+func (s *SegmentInfos) SetUserData(data map[string]string, doIncrementVersion bool) {
+	if data == nil {
+		data = map[string]string{}
+	}
+	s.userData = data
+	if doIncrementVersion {
+		s.Changed()
+	}
 }
 
+// RollbackCommit
+// Deletes the pending segments file written by prepareCommit, the last commit stays the current one.
 func (s *SegmentInfos) RollbackCommit(directory store.Directory) error {
-	panic("")
+	if !s.pendingCommit {
+		return nil
+	}
+	s.pendingCommit = false
+
+	segmentFileName := FileNameFromGeneration(PENDING_SEGMENTS, "", s.generation)
+	if err := directory.DeleteFile(context.Background(), segmentFileName); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
 }
 
 func ReadCommit(ctx context.Context, directory store.Directory, segmentFileName string) (*SegmentInfos, error) {
@@ -496,6 +560,7 @@ func ReadCommit(ctx context.Context, directory store.Directory, segmentFileName
 	if err != nil {
 		return nil, err
 	}
+	defer input.Close()
 	return ReadCommitFromChecksumIndexInput(ctx, directory, input, generation)
 }
 
@@ -758,6 +823,15 @@ func ReadCommitFromChecksumIndexInput(ctx context.Context, directory store.Direc
 		}
 	}
 
+	userData, err := input.ReadMapOfStrings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	infos.userData = userData
+
+	if _, err := utils.CheckCodecFooter(input); err != nil {
+		return nil, err
+	}
 	return infos, nil
 }
 
@@ -800,7 +874,7 @@ func GenerationFromSegmentsFileName(fileName string) (int64, error) {
 
 	if strings.HasPrefix(fileName, SEGMENTS) {
 		v := fileName[len(SEGMENTS)+1:]
-		return strconv.ParseInt(v, 10, 64)
+		return strconv.ParseInt(v, 36, 64)
 	}
 
 	return 0, fmt.Errorf("fileName '%s' is not a segments file", fileName)
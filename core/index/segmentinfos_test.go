@@ -0,0 +1,37 @@
+package index_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegmentInfos_UserDataAndFooter(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir()
+	dir, err := store.NewNIOFSDirectory(path)
+	assert.Nil(t, err)
+
+	infos := coreIndex.NewSegmentInfos(8)
+	infos.SetUserData(map[string]string{"sync_id": "abc"}, true)
+	assert.Nil(t, infos.Commit(ctx, dir))
+
+	infos, err = coreIndex.ReadLatestCommit(ctx, dir)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"sync_id": "abc"}, infos.GetUserData())
+
+	// the footer checksum covers the user data
+	fileName := infos.GetSegmentsFileName()
+	bs, err := os.ReadFile(filepath.Join(path, fileName))
+	assert.Nil(t, err)
+	bs[len(bs)-20] ^= 0xff
+	assert.Nil(t, os.WriteFile(filepath.Join(path, fileName), bs, 0644))
+
+	_, err = coreIndex.ReadCommit(ctx, dir, fileName)
+	assert.NotNil(t, err)
+}
@@ -1,8 +1,16 @@
 package index
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bits-and-blooms/bitset"
+	"github.com/geange/lucene-go/core/document"
 	"github.com/geange/lucene-go/core/interface/index"
 	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
 )
 
 // The SegmentMerger class combines two or more Segments, represented by an IndexReader,
@@ -10,6 +18,7 @@ import (
 type SegmentMerger struct {
 	directory         store.Directory
 	codec             index.Codec
+	context           *store.IOContext
 	mergeState        *MergeState
 	fieldInfosBuilder *FieldInfosBuilder
 }
@@ -17,16 +26,1150 @@ type SegmentMerger struct {
 func NewSegmentMerger(readers []index.CodecReader, segmentInfo *SegmentInfo, dir store.Directory,
 	fieldNumbers *FieldNumbers, ioCtx *store.IOContext) (*SegmentMerger, error) {
 
-	//if ioCtx.Type != store.CONTEXT_MERGE {
-	//	return nil, errors.New("context type should be MERGE")
-	//}
-	//
-	//mergeState := store.NewMer
-	// TODO: fix it
-	panic("")
+	if ioCtx == nil || ioCtx.Type != store.CONTEXT_MERGE {
+		return nil, errors.New("IOContext type should be MERGE")
+	}
+
+	mergeState, err := NewMergeState(readers, segmentInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SegmentMerger{
+		directory:         dir,
+		codec:             segmentInfo.GetCodec(),
+		context:           ioCtx,
+		mergeState:        mergeState,
+		fieldInfosBuilder: NewFieldInfosBuilder(fieldNumbers),
+	}, nil
 }
 
+// ShouldMerge
+// True if any merging should happen
 func (s *SegmentMerger) ShouldMerge() bool {
 	maxDoc, _ := s.mergeState.SegmentInfo.MaxDoc()
 	return maxDoc > 0
 }
+
+// Merge
+// Merges the readers into the directory passed to the constructor
+// Returns: The number of documents that were merged
+func (s *SegmentMerger) Merge(ctx context.Context) (*MergeState, error) {
+	if !s.ShouldMerge() {
+		return nil, errors.New("merge would result in 0 document segment")
+	}
+
+	if err := s.mergeFieldInfos(); err != nil {
+		return nil, err
+	}
+
+	numMerged, err := s.mergeFields(ctx)
+	if err != nil {
+		return nil, err
+	}
+	maxDoc, err := s.mergeState.SegmentInfo.MaxDoc()
+	if err != nil {
+		return nil, err
+	}
+	if numMerged != maxDoc {
+		return nil, fmt.Errorf("numMerged=%d vs mergeState.segmentInfo.maxDoc()=%d", numMerged, maxDoc)
+	}
+
+	segmentWriteState := index.NewSegmentWriteState(s.directory, s.mergeState.SegmentInfo,
+		s.mergeState.MergeFieldInfos, nil, s.context)
+
+	if s.mergeState.MergeFieldInfos.HasNorms() {
+		if err := s.mergeNorms(ctx, segmentWriteState); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.mergeTerms(ctx, segmentWriteState); err != nil {
+		return nil, err
+	}
+
+	if s.mergeState.MergeFieldInfos.HasDocValues() {
+		if err := s.mergeDocValues(ctx, segmentWriteState); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.mergeState.MergeFieldInfos.HasPointValues() {
+		if err := s.mergePoints(ctx, segmentWriteState); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.mergeState.MergeFieldInfos.HasVectorValues() {
+		if err := s.mergeVectorValues(ctx, segmentWriteState); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.mergeState.MergeFieldInfos.HasVectors() {
+		numMerged, err := s.mergeTermVectors(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if numMerged != maxDoc {
+			return nil, fmt.Errorf("numMerged=%d vs mergeState.segmentInfo.maxDoc()=%d", numMerged, maxDoc)
+		}
+	}
+
+	// write the merged infos
+	if err := s.codec.FieldInfosFormat().Write(ctx, s.directory, s.mergeState.SegmentInfo, "",
+		s.mergeState.MergeFieldInfos, s.context); err != nil {
+		return nil, err
+	}
+	return s.mergeState, nil
+}
+
+func (s *SegmentMerger) mergeFieldInfos() error {
+	for _, readerFieldInfos := range s.mergeState.FieldInfos {
+		for _, fi := range readerFieldInfos.List() {
+			// the codec attributes of the merged field must not be shared with the merged segments
+			if _, err := s.fieldInfosBuilder.AddFieldInfo(cloneFieldInfo(fi, fi.Number())); err != nil {
+				return err
+			}
+		}
+	}
+	s.mergeState.MergeFieldInfos = s.fieldInfosBuilder.Finish()
+	return nil
+}
+
+// Merge stored fields from each of the segments into the new one.
+// Returns: The number of documents in all of the readers
+func (s *SegmentMerger) mergeFields(ctx context.Context) (int, error) {
+	writer, err := s.codec.StoredFieldsFormat().FieldsWriter(ctx, s.directory, s.mergeState.SegmentInfo, s.context)
+	if err != nil {
+		return 0, err
+	}
+
+	docCount, err := s.writeStoredFields(ctx, writer)
+	if err != nil {
+		return 0, errors.Join(err, writer.Close())
+	}
+	return docCount, writer.Close()
+}
+
+func (s *SegmentMerger) writeStoredFields(ctx context.Context, writer index.StoredFieldsWriter) (int, error) {
+	visitor := &storedFieldsMergeVisitor{ctx: ctx, writer: writer, fieldInfos: s.mergeState.MergeFieldInfos}
+
+	docCount := 0
+	if err := s.forEachMergedDoc(func(readerIndex, docID int) error {
+		if err := writer.StartDocument(ctx); err != nil {
+			return err
+		}
+		if reader := s.mergeState.StoredFieldsReaders[readerIndex]; reader != nil {
+			if err := reader.VisitDocument(ctx, docID, visitor); err != nil {
+				return err
+			}
+		}
+		if err := writer.FinishDocument(ctx); err != nil {
+			return err
+		}
+		docCount++
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	if err := writer.Finish(ctx, s.mergeState.MergeFieldInfos, docCount); err != nil {
+		return 0, err
+	}
+	return docCount, nil
+}
+
+// Merge the term vectors from each of the segments into the new one.
+// Returns: The number of documents in all of the readers
+func (s *SegmentMerger) mergeTermVectors(ctx context.Context) (int, error) {
+	writer, err := s.codec.TermVectorsFormat().VectorsWriter(ctx, s.directory, s.mergeState.SegmentInfo, s.context)
+	if err != nil {
+		return 0, err
+	}
+
+	docCount, err := s.writeTermVectors(ctx, writer)
+	if err != nil {
+		return 0, errors.Join(err, writer.Close())
+	}
+	return docCount, writer.Close()
+}
+
+func (s *SegmentMerger) writeTermVectors(ctx context.Context, writer index.TermVectorsWriter) (int, error) {
+	docCount := 0
+	if err := s.forEachMergedDoc(func(readerIndex, docID int) error {
+		var vectors index.Fields
+		if reader := s.mergeState.TermVectorsReaders[readerIndex]; reader != nil {
+			var err error
+			if vectors, err = reader.Get(ctx, docID); err != nil {
+				return err
+			}
+		}
+		if err := addAllDocVectors(ctx, writer, s.mergeState.MergeFieldInfos, vectors); err != nil {
+			return err
+		}
+		docCount++
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	if err := writer.Finish(ctx, s.mergeState.MergeFieldInfos, docCount); err != nil {
+		return 0, err
+	}
+	return docCount, nil
+}
+
+// forEachMergedDoc calls fn with the reader index and the document ID in the reader of every live
+// document of the merged segments, in the document order of the merged segment
+func (s *SegmentMerger) forEachMergedDoc(fn func(readerIndex, docID int) error) error {
+	if !s.mergeState.NeedsIndexSort {
+		for i, maxDoc := range s.mergeState.MaxDocs {
+			liveDocs := s.mergeState.LiveDocs[i]
+			for docID := 0; docID < maxDoc; docID++ {
+				if liveDocs != nil && !liveDocs.Test(uint(docID)) {
+					// skip deleted docs
+					continue
+				}
+				if err := fn(i, docID); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	// the documents of the readers interleave in the merged segment
+	numDocs, err := s.mergeState.SegmentInfo.MaxDoc()
+	if err != nil {
+		return err
+	}
+	readerIndexes := make([]int, numDocs)
+	docIDs := make([]int, numDocs)
+	for i, maxDoc := range s.mergeState.MaxDocs {
+		docMap := s.mergeState.DocMaps[i]
+		for docID := 0; docID < maxDoc; docID++ {
+			newDocID := docMap.Get(docID)
+			if newDocID == -1 {
+				// skip deleted docs
+				continue
+			}
+			readerIndexes[newDocID], docIDs[newDocID] = i, docID
+		}
+	}
+	for newDocID, readerIndex := range readerIndexes {
+		if err := fn(readerIndex, docIDs[newDocID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addAllDocVectors writes all the term vectors of a document, vectors is nil if the document has none
+func addAllDocVectors(ctx context.Context, writer index.TermVectorsWriter, fieldInfos index.FieldInfos, vectors index.Fields) error {
+	if vectors == nil {
+		if err := writer.StartDocument(ctx, 0); err != nil {
+			return err
+		}
+		return writer.FinishDocument(ctx)
+	}
+
+	fieldNames := vectors.Names()
+	if err := writer.StartDocument(ctx, len(fieldNames)); err != nil {
+		return err
+	}
+	for _, fieldName := range fieldNames {
+		terms, err := vectors.Terms(fieldName)
+		if err != nil {
+			return err
+		}
+		if terms == nil {
+			continue
+		}
+		if err := addFieldVectors(ctx, writer, fieldInfos.FieldInfo(fieldName), terms); err != nil {
+			return err
+		}
+	}
+	return writer.FinishDocument(ctx)
+}
+
+func addFieldVectors(ctx context.Context, writer index.TermVectorsWriter,
+	fieldInfo *document.FieldInfo, terms index.Terms) error {
+
+	hasPositions, hasOffsets, hasPayloads := terms.HasPositions(), terms.HasOffsets(), terms.HasPayloads()
+	numTerms, err := terms.Size()
+	if err != nil {
+		return err
+	}
+	if err := writer.StartField(ctx, fieldInfo, numTerms, hasPositions, hasOffsets, hasPayloads); err != nil {
+		return err
+	}
+
+	termsEnum, err := terms.Iterator()
+	if err != nil {
+		return err
+	}
+	for {
+		term, err := termsEnum.Next(ctx)
+		if errors.Is(err, io.EOF) || (err == nil && term == nil) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		freq, err := termsEnum.TotalTermFreq()
+		if err != nil {
+			return err
+		}
+		if err := writer.StartTerm(ctx, term, int(freq)); err != nil {
+			return err
+		}
+
+		if hasPositions || hasOffsets {
+			postings, err := termsEnum.Postings(nil, POSTINGS_ENUM_OFFSETS|POSTINGS_ENUM_PAYLOADS)
+			if err != nil {
+				return err
+			}
+			if _, err := postings.NextDoc(); err != nil {
+				return err
+			}
+			for i := 0; i < int(freq); i++ {
+				position, err := postings.NextPosition()
+				if err != nil {
+					return err
+				}
+				startOffset, err := postings.StartOffset()
+				if err != nil {
+					return err
+				}
+				endOffset, err := postings.EndOffset()
+				if err != nil {
+					return err
+				}
+				payload, err := postings.GetPayload()
+				if err != nil {
+					return err
+				}
+				if err := writer.AddPosition(ctx, position, startOffset, endOffset, payload); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := writer.FinishTerm(ctx); err != nil {
+			return err
+		}
+	}
+	return writer.FinishField(ctx)
+}
+
+func (s *SegmentMerger) mergeNorms(ctx context.Context, segmentWriteState *index.SegmentWriteState) error {
+	consumer, err := s.codec.NormsFormat().NormsConsumer(ctx, segmentWriteState)
+	if err != nil {
+		return err
+	}
+
+	normsProducer := &mappedNormsProducer{mergeState: s.mergeState}
+	for _, fi := range s.mergeState.MergeFieldInfos.List() {
+		if !fi.HasNorms() {
+			continue
+		}
+		if err := consumer.AddNormsField(ctx, fi, normsProducer); err != nil {
+			return errors.Join(err, consumer.Close())
+		}
+	}
+	return consumer.Close()
+}
+
+func (s *SegmentMerger) mergeTerms(ctx context.Context, segmentWriteState *index.SegmentWriteState) error {
+	var norms index.NormsProducer
+	if s.mergeState.MergeFieldInfos.HasNorms() {
+		// the postings read the norms back from the merged segment, like a flush does
+		readState := index.NewSegmentReadState(segmentWriteState.Directory, segmentWriteState.SegmentInfo,
+			segmentWriteState.FieldInfos, s.context, segmentWriteState.SegmentSuffix)
+		producer, err := s.codec.NormsFormat().NormsProducer(ctx, readState)
+		if err != nil {
+			return err
+		}
+		defer producer.Close()
+		norms = producer.GetMergeInstance()
+	}
+
+	consumer, err := s.codec.PostingsFormat().FieldsConsumer(ctx, segmentWriteState)
+	if err != nil {
+		return err
+	}
+	if err := MergeFromReaders(ctx, consumer, s.mergeState, norms); err != nil {
+		return errors.Join(err, consumer.Close())
+	}
+	return consumer.Close()
+}
+
+func (s *SegmentMerger) mergeDocValues(ctx context.Context, segmentWriteState *index.SegmentWriteState) error {
+	consumer, err := s.codec.DocValuesFormat().FieldsConsumer(ctx, segmentWriteState)
+	if err != nil {
+		return err
+	}
+	if err := s.writeDocValues(ctx, consumer); err != nil {
+		return errors.Join(err, consumer.Close())
+	}
+	return consumer.Close()
+}
+
+func (s *SegmentMerger) writeDocValues(ctx context.Context, consumer index.DocValuesConsumer) error {
+	valuesProducer := &EmptyDocValuesProducer{
+		FnGetNumeric: func(ctx context.Context, field *document.FieldInfo) (index.NumericDocValues, error) {
+			return s.mergeNumericDocValues(ctx, field.Name())
+		},
+		FnGetBinary: func(ctx context.Context, field *document.FieldInfo) (index.BinaryDocValues, error) {
+			return s.mergeBinaryDocValues(ctx, field.Name())
+		},
+		FnGetSortedNumeric: func(ctx context.Context, field *document.FieldInfo) (index.SortedNumericDocValues, error) {
+			return s.mergeSortedNumericDocValues(ctx, field.Name())
+		},
+		FnCheckIntegrity: func() error {
+			return nil
+		},
+	}
+
+	for _, fi := range s.mergeState.MergeFieldInfos.List() {
+		switch dvType := fi.GetDocValuesType(); dvType {
+		case document.DOC_VALUES_TYPE_NONE:
+		case document.DOC_VALUES_TYPE_NUMERIC:
+			if err := consumer.AddNumericField(ctx, fi, valuesProducer); err != nil {
+				return err
+			}
+		case document.DOC_VALUES_TYPE_BINARY:
+			if err := consumer.AddBinaryField(ctx, fi, valuesProducer); err != nil {
+				return err
+			}
+		case document.DOC_VALUES_TYPE_SORTED:
+			if err := s.mergeSortedField(ctx, consumer, fi); err != nil {
+				return err
+			}
+		case document.DOC_VALUES_TYPE_SORTED_NUMERIC:
+			if err := consumer.AddSortedNumericField(ctx, fi, valuesProducer); err != nil {
+				return err
+			}
+		case document.DOC_VALUES_TYPE_SORTED_SET:
+			if err := s.mergeSortedSetField(ctx, consumer, fi); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("merging doc values of type %s is not supported", dvType)
+		}
+	}
+	return nil
+}
+
+func (s *SegmentMerger) mergeNumericDocValues(ctx context.Context, field string) (index.NumericDocValues, error) {
+	subs := make([]*mappedDocValuesSub[index.NumericDocValues], 0, len(s.mergeState.DocValuesProducers))
+	for i, producer := range s.mergeState.DocValuesProducers {
+		readerFieldInfo := s.mergeState.FieldInfos[i].FieldInfo(field)
+		if producer == nil || readerFieldInfo == nil ||
+			readerFieldInfo.GetDocValuesType() != document.DOC_VALUES_TYPE_NUMERIC {
+			continue
+		}
+		values, err := producer.GetNumeric(ctx, readerFieldInfo)
+		if err != nil {
+			return nil, err
+		}
+		if values == nil {
+			continue
+		}
+		subs = append(subs, &mappedDocValuesSub[index.NumericDocValues]{values: values, docMap: s.mergeState.DocMaps[i]})
+	}
+	return &mappedNumericDocValues{newMappedDocValues(subs, s.mergeState.NeedsIndexSort)}, nil
+}
+
+func (s *SegmentMerger) mergeBinaryDocValues(ctx context.Context, field string) (index.BinaryDocValues, error) {
+	subs := make([]*mappedDocValuesSub[index.BinaryDocValues], 0, len(s.mergeState.DocValuesProducers))
+	for i, producer := range s.mergeState.DocValuesProducers {
+		readerFieldInfo := s.mergeState.FieldInfos[i].FieldInfo(field)
+		if producer == nil || readerFieldInfo == nil ||
+			readerFieldInfo.GetDocValuesType() != document.DOC_VALUES_TYPE_BINARY {
+			continue
+		}
+		values, err := producer.GetBinary(ctx, readerFieldInfo)
+		if err != nil {
+			return nil, err
+		}
+		if values == nil {
+			continue
+		}
+		subs = append(subs, &mappedDocValuesSub[index.BinaryDocValues]{values: values, docMap: s.mergeState.DocMaps[i]})
+	}
+	return &mappedBinaryDocValues{newMappedDocValues(subs, s.mergeState.NeedsIndexSort)}, nil
+}
+
+func (s *SegmentMerger) mergeSortedNumericDocValues(ctx context.Context, field string) (index.SortedNumericDocValues, error) {
+	subs := make([]*mappedDocValuesSub[index.SortedNumericDocValues], 0, len(s.mergeState.DocValuesProducers))
+	for i, producer := range s.mergeState.DocValuesProducers {
+		readerFieldInfo := s.mergeState.FieldInfos[i].FieldInfo(field)
+		if producer == nil || readerFieldInfo == nil ||
+			readerFieldInfo.GetDocValuesType() != document.DOC_VALUES_TYPE_SORTED_NUMERIC {
+			continue
+		}
+		values, err := producer.GetSortedNumeric(ctx, readerFieldInfo)
+		if err != nil {
+			return nil, err
+		}
+		if values == nil {
+			continue
+		}
+		subs = append(subs, &mappedDocValuesSub[index.SortedNumericDocValues]{values: values, docMap: s.mergeState.DocMaps[i]})
+	}
+	return &mappedSortedNumericDocValues{newMappedDocValues(subs, s.mergeState.NeedsIndexSort)}, nil
+}
+
+// mergeSortedField merges the SORTED doc values of a field: the terms which are still used by
+// a live document are merged into a global ordinal space, then the ordinals of the documents are
+// mapped to it.
+func (s *SegmentMerger) mergeSortedField(ctx context.Context, consumer index.DocValuesConsumer, fieldInfo *document.FieldInfo) error {
+	readers := make([]int, 0, len(s.mergeState.DocValuesProducers))
+	dvs := make([]index.SortedDocValues, 0, len(s.mergeState.DocValuesProducers))
+	liveTerms := make([]index.TermsEnum, 0, len(s.mergeState.DocValuesProducers))
+	weights := make([]int64, 0, len(s.mergeState.DocValuesProducers))
+	for i, producer := range s.mergeState.DocValuesProducers {
+		readerFieldInfo := s.mergeState.FieldInfos[i].FieldInfo(fieldInfo.Name())
+		if producer == nil || readerFieldInfo == nil ||
+			readerFieldInfo.GetDocValuesType() != document.DOC_VALUES_TYPE_SORTED {
+			continue
+		}
+		values, err := producer.GetSorted(ctx, readerFieldInfo)
+		if err != nil {
+			return err
+		}
+		if values == nil {
+			continue
+		}
+		termsEnum, err := values.TermsEnum()
+		if err != nil {
+			return err
+		}
+
+		// step 1: iterate thru each sub and mark terms still in use
+		liveDocs := s.mergeState.LiveDocs[i]
+		if liveDocs == nil {
+			liveTerms = append(liveTerms, termsEnum)
+			weights = append(weights, int64(values.GetValueCount()))
+		} else {
+			liveOrds := bitset.New(uint(values.GetValueCount()))
+			for {
+				doc, err := nextDocOrExhausted(values)
+				if err != nil {
+					return err
+				}
+				if doc == types.NO_MORE_DOCS {
+					break
+				}
+				if !liveDocs.Test(uint(doc)) {
+					continue
+				}
+				ord, err := values.OrdValue()
+				if err != nil {
+					return err
+				}
+				if ord >= 0 {
+					liveOrds.Set(uint(ord))
+				}
+			}
+			liveTerms = append(liveTerms, &liveOrdsTermsEnum{TermsEnum: termsEnum, liveOrds: liveOrds})
+			weights = append(weights, int64(liveOrds.Count()))
+		}
+		readers = append(readers, i)
+		dvs = append(dvs, values)
+	}
+
+	// step 2: create ordinal map (this conceptually does the "merging")
+	ordinalMap, err := BuildOrdinalMap(ctx, liveTerms, weights)
+	if err != nil {
+		return err
+	}
+
+	// step 3: add field
+	return consumer.AddSortedField(ctx, fieldInfo, &EmptyDocValuesProducer{
+		FnGetSorted: func(ctx context.Context, field *document.FieldInfo) (index.SortedDocValues, error) {
+			subs := make([]*mappedDocValuesSub[index.SortedDocValues], 0, len(readers))
+			for sub, i := range readers {
+				readerFieldInfo := s.mergeState.FieldInfos[i].FieldInfo(field.Name())
+				values, err := s.mergeState.DocValuesProducers[i].GetSorted(ctx, readerFieldInfo)
+				if err != nil {
+					return nil, err
+				}
+				subs = append(subs, &mappedDocValuesSub[index.SortedDocValues]{
+					values:     values,
+					docMap:     s.mergeState.DocMaps[i],
+					globalOrds: ordinalMap.GetGlobalOrds(sub),
+				})
+			}
+			return newMappedSortedDocValues(subs, s.mergeState.NeedsIndexSort, dvs, ordinalMap), nil
+		},
+	})
+}
+
+// mergeSortedSetField merges the SORTED_SET doc values of a field, like mergeSortedField.
+func (s *SegmentMerger) mergeSortedSetField(ctx context.Context, consumer index.DocValuesConsumer, fieldInfo *document.FieldInfo) error {
+	readers := make([]int, 0, len(s.mergeState.DocValuesProducers))
+	dvs := make([]index.SortedSetDocValues, 0, len(s.mergeState.DocValuesProducers))
+	liveTerms := make([]index.TermsEnum, 0, len(s.mergeState.DocValuesProducers))
+	weights := make([]int64, 0, len(s.mergeState.DocValuesProducers))
+	for i, producer := range s.mergeState.DocValuesProducers {
+		readerFieldInfo := s.mergeState.FieldInfos[i].FieldInfo(fieldInfo.Name())
+		if producer == nil || readerFieldInfo == nil ||
+			readerFieldInfo.GetDocValuesType() != document.DOC_VALUES_TYPE_SORTED_SET {
+			continue
+		}
+		values, err := producer.GetSortedSet(ctx, readerFieldInfo)
+		if err != nil {
+			return err
+		}
+		if values == nil {
+			continue
+		}
+		termsEnum := NewSortedSetDocValuesTermsEnum(values)
+
+		// step 1: iterate thru each sub and mark terms still in use
+		liveDocs := s.mergeState.LiveDocs[i]
+		if liveDocs == nil {
+			liveTerms = append(liveTerms, termsEnum)
+			weights = append(weights, values.GetValueCount())
+		} else {
+			liveOrds := bitset.New(uint(values.GetValueCount()))
+			for {
+				doc, err := nextDocOrExhausted(values)
+				if err != nil {
+					return err
+				}
+				if doc == types.NO_MORE_DOCS {
+					break
+				}
+				if !liveDocs.Test(uint(doc)) {
+					continue
+				}
+				for {
+					ord, err := values.NextOrd()
+					if err != nil {
+						return err
+					}
+					if ord == NO_MORE_ORDS {
+						break
+					}
+					liveOrds.Set(uint(ord))
+				}
+			}
+			liveTerms = append(liveTerms, &liveOrdsTermsEnum{TermsEnum: termsEnum, liveOrds: liveOrds})
+			weights = append(weights, int64(liveOrds.Count()))
+		}
+		readers = append(readers, i)
+		dvs = append(dvs, values)
+	}
+
+	// step 2: create ordinal map (this conceptually does the "merging")
+	ordinalMap, err := BuildOrdinalMap(ctx, liveTerms, weights)
+	if err != nil {
+		return err
+	}
+
+	// step 3: add field
+	return consumer.AddSortedSetField(ctx, fieldInfo, &EmptyDocValuesProducer{
+		FnGetSortedSet: func(ctx context.Context, field *document.FieldInfo) (index.SortedSetDocValues, error) {
+			subs := make([]*mappedDocValuesSub[index.SortedSetDocValues], 0, len(readers))
+			for sub, i := range readers {
+				readerFieldInfo := s.mergeState.FieldInfos[i].FieldInfo(field.Name())
+				values, err := s.mergeState.DocValuesProducers[i].GetSortedSet(ctx, readerFieldInfo)
+				if err != nil {
+					return nil, err
+				}
+				subs = append(subs, &mappedDocValuesSub[index.SortedSetDocValues]{
+					values:     values,
+					docMap:     s.mergeState.DocMaps[i],
+					globalOrds: ordinalMap.GetGlobalOrds(sub),
+				})
+			}
+			return &mappedSortedSetDocValues{
+				mappedDocValues: newMappedDocValues(subs, s.mergeState.NeedsIndexSort),
+				lookups:         dvs,
+				ordinalMap:      ordinalMap,
+			}, nil
+		},
+	})
+}
+
+func (s *SegmentMerger) mergePoints(ctx context.Context, segmentWriteState *index.SegmentWriteState) error {
+	writer, err := s.codec.PointsFormat().FieldsWriter(ctx, segmentWriteState)
+	if err != nil {
+		return err
+	}
+
+	pointsReader := &mappedPointsReader{mergeState: s.mergeState}
+	for _, fi := range s.mergeState.MergeFieldInfos.List() {
+		if fi.GetPointDimensionCount() == 0 {
+			continue
+		}
+		if err := writer.WriteField(ctx, fi, pointsReader); err != nil {
+			return errors.Join(err, writer.Close())
+		}
+	}
+	if err := writer.Finish(); err != nil {
+		return errors.Join(err, writer.Close())
+	}
+	return writer.Close()
+}
+
+func (s *SegmentMerger) mergeVectorValues(ctx context.Context, segmentWriteState *index.SegmentWriteState) error {
+	format := s.codec.KnnVectorsFormat()
+	if format == nil {
+		return fmt.Errorf("codec %s does not support vectors", s.codec.GetName())
+	}
+	writer, err := format.FieldsWriter(ctx, segmentWriteState)
+	if err != nil {
+		return err
+	}
+	if err := MergeKnnVectors(ctx, writer, s.mergeState); err != nil {
+		return errors.Join(err, writer.Close())
+	}
+	return writer.Close()
+}
+
+var _ document.StoredFieldVisitor = &storedFieldsMergeVisitor{}
+
+// storedFieldsMergeVisitor writes the stored fields it visits to the stored fields writer of the
+// merged segment
+type storedFieldsMergeVisitor struct {
+	ctx        context.Context
+	writer     index.StoredFieldsWriter
+	fieldInfos index.FieldInfos
+}
+
+func (v *storedFieldsMergeVisitor) writeField(fieldInfo *document.FieldInfo, field document.IndexableField) error {
+	return v.writer.WriteField(v.ctx, v.fieldInfos.FieldInfo(fieldInfo.Name()), field)
+}
+
+func (v *storedFieldsMergeVisitor) BinaryField(fieldInfo *document.FieldInfo, value []byte) error {
+	return v.writeField(fieldInfo, document.NewStoredField[[]byte](fieldInfo.Name(), value))
+}
+
+func (v *storedFieldsMergeVisitor) StringField(fieldInfo *document.FieldInfo, value []byte) error {
+	return v.writeField(fieldInfo, document.NewStoredField[string](fieldInfo.Name(), string(value)))
+}
+
+func (v *storedFieldsMergeVisitor) Int32Field(fieldInfo *document.FieldInfo, value int32) error {
+	return v.writeField(fieldInfo, document.NewStoredField[int32](fieldInfo.Name(), value))
+}
+
+func (v *storedFieldsMergeVisitor) Int64Field(fieldInfo *document.FieldInfo, value int64) error {
+	return v.writeField(fieldInfo, document.NewStoredField[int64](fieldInfo.Name(), value))
+}
+
+func (v *storedFieldsMergeVisitor) Float32Field(fieldInfo *document.FieldInfo, value float32) error {
+	return v.writeField(fieldInfo, document.NewStoredField[float32](fieldInfo.Name(), value))
+}
+
+func (v *storedFieldsMergeVisitor) Float64Field(fieldInfo *document.FieldInfo, value float64) error {
+	return v.writeField(fieldInfo, document.NewStoredField[float64](fieldInfo.Name(), value))
+}
+
+func (v *storedFieldsMergeVisitor) NeedsField(fieldInfo *document.FieldInfo) (document.STORED_FIELD_VISITOR_STATUS, error) {
+	return document.STORED_FIELD_VISITOR_YES, nil
+}
+
+var _ index.NormsProducer = &mappedNormsProducer{}
+
+// mappedNormsProducer exposes the norms of the merged segments in the document order of the
+// merged segment
+type mappedNormsProducer struct {
+	mergeState *MergeState
+}
+
+func (m *mappedNormsProducer) Close() error {
+	return nil
+}
+
+func (m *mappedNormsProducer) GetNorms(field *document.FieldInfo) (index.NumericDocValues, error) {
+	subs := make([]*mappedDocValuesSub[index.NumericDocValues], 0, len(m.mergeState.NormsProducers))
+	for i, producer := range m.mergeState.NormsProducers {
+		readerFieldInfo := m.mergeState.FieldInfos[i].FieldInfo(field.Name())
+		if producer == nil || readerFieldInfo == nil || !readerFieldInfo.HasNorms() {
+			continue
+		}
+		values, err := producer.GetNorms(readerFieldInfo)
+		if err != nil {
+			return nil, err
+		}
+		if values == nil {
+			continue
+		}
+		subs = append(subs, &mappedDocValuesSub[index.NumericDocValues]{values: values, docMap: m.mergeState.DocMaps[i]})
+	}
+	return &mappedNumericDocValues{newMappedDocValues(subs, m.mergeState.NeedsIndexSort)}, nil
+}
+
+func (m *mappedNormsProducer) CheckIntegrity() error {
+	return nil
+}
+
+func (m *mappedNormsProducer) GetMergeInstance() index.NormsProducer {
+	return m
+}
+
+type mappedDocValuesSub[T types.DocValuesIterator] struct {
+	values T
+	docMap MergeStateDocMap
+
+	// maps the ordinals of the sub to the global ordinals, only for sorted doc values
+	globalOrds []int64
+}
+
+func (s *mappedDocValuesSub[T]) nextMappedDoc() (int, error) {
+	for {
+		doc, err := nextDocOrExhausted(s.values)
+		if err != nil || doc == types.NO_MORE_DOCS {
+			return doc, err
+		}
+		if mapped := s.docMap.Get(doc); mapped != -1 {
+			return mapped, nil
+		}
+		// deleted document
+	}
+}
+
+// mappedDocValues merges the doc values of the merged segments, mapping the documents to the
+// merged segment and skipping the deleted ones.
+type mappedDocValues[T types.DocValuesIterator] struct {
+	subs    []*mappedDocValuesSub[T]
+	merger  *docIDMerger[*mappedDocValuesSub[T]]
+	current *mappedDocValuesSub[T]
+	doc     int
+}
+
+func newMappedDocValues[T types.DocValuesIterator](subs []*mappedDocValuesSub[T], indexIsSorted bool) *mappedDocValues[T] {
+	return &mappedDocValues[T]{
+		subs:   subs,
+		merger: newDocIDMerger(subs, indexIsSorted),
+		doc:    -1,
+	}
+}
+
+func (m *mappedDocValues[T]) DocID() int {
+	return m.doc
+}
+
+func (m *mappedDocValues[T]) NextDoc() (int, error) {
+	doc, err := m.merger.next()
+	if err != nil {
+		return 0, err
+	}
+	m.doc = doc
+	if doc != types.NO_MORE_DOCS {
+		m.current = m.merger.currentSub()
+	}
+	return doc, nil
+}
+
+func (m *mappedDocValues[T]) Advance(target int) (int, error) {
+	return m.SlowAdvance(target)
+}
+
+func (m *mappedDocValues[T]) SlowAdvance(target int) (int, error) {
+	doc := m.doc
+	for doc < target {
+		var err error
+		if doc, err = m.NextDoc(); err != nil {
+			return 0, err
+		}
+	}
+	return doc, nil
+}
+
+func (m *mappedDocValues[T]) AdvanceExact(target int) (bool, error) {
+	return false, ErrUnsupportedOperation
+}
+
+func (m *mappedDocValues[T]) Cost() int64 {
+	cost := int64(0)
+	for _, sub := range m.subs {
+		cost += sub.values.Cost()
+	}
+	return cost
+}
+
+var _ index.NumericDocValues = &mappedNumericDocValues{}
+
+type mappedNumericDocValues struct {
+	*mappedDocValues[index.NumericDocValues]
+}
+
+func (m *mappedNumericDocValues) LongValue() (int64, error) {
+	return m.current.values.LongValue()
+}
+
+var _ index.BinaryDocValues = &mappedBinaryDocValues{}
+
+type mappedBinaryDocValues struct {
+	*mappedDocValues[index.BinaryDocValues]
+}
+
+func (m *mappedBinaryDocValues) BinaryValue() ([]byte, error) {
+	return m.current.values.BinaryValue()
+}
+
+var _ index.SortedNumericDocValues = &mappedSortedNumericDocValues{}
+
+type mappedSortedNumericDocValues struct {
+	*mappedDocValues[index.SortedNumericDocValues]
+}
+
+func (m *mappedSortedNumericDocValues) NextValue() (int64, error) {
+	return m.current.values.NextValue()
+}
+
+func (m *mappedSortedNumericDocValues) DocValueCount() int {
+	return m.current.values.DocValueCount()
+}
+
+var _ index.SortedDocValues = &mappedSortedDocValues{}
+
+// mappedSortedDocValues maps the ordinals of the merged segments to the global ordinals of the OrdinalMap
+type mappedSortedDocValues struct {
+	*mappedDocValues[index.SortedDocValues]
+	*BaseSortedDocValues
+
+	lookups    []index.SortedDocValues
+	ordinalMap *OrdinalMap
+}
+
+func newMappedSortedDocValues(subs []*mappedDocValuesSub[index.SortedDocValues], indexIsSorted bool,
+	lookups []index.SortedDocValues, ordinalMap *OrdinalMap) *mappedSortedDocValues {
+
+	values := &mappedSortedDocValues{
+		mappedDocValues: newMappedDocValues(subs, indexIsSorted),
+		lookups:         lookups,
+		ordinalMap:      ordinalMap,
+	}
+	values.BaseSortedDocValues = NewBaseSortedDocValues(&SortedDocValuesDefaultConfig{
+		OrdValue:      values.OrdValue,
+		LookupOrd:     values.LookupOrd,
+		GetValueCount: values.GetValueCount,
+	})
+	return values
+}
+
+func (m *mappedSortedDocValues) OrdValue() (int, error) {
+	segmentOrd, err := m.current.values.OrdValue()
+	if err != nil {
+		return 0, err
+	}
+	if segmentOrd == -1 {
+		return -1, nil
+	}
+	return int(m.current.globalOrds[segmentOrd]), nil
+}
+
+func (m *mappedSortedDocValues) LookupOrd(ord int) ([]byte, error) {
+	segmentNumber := m.ordinalMap.GetFirstSegmentNumber(int64(ord))
+	segmentOrd := m.ordinalMap.GetFirstSegmentOrd(int64(ord))
+	return m.lookups[segmentNumber].LookupOrd(int(segmentOrd))
+}
+
+func (m *mappedSortedDocValues) GetValueCount() int {
+	return int(m.ordinalMap.GetValueCount())
+}
+
+func (m *mappedSortedDocValues) TermsEnum() (index.TermsEnum, error) {
+	return NewSortedDocValuesTermsEnum(m), nil
+}
+
+var _ index.SortedSetDocValues = &mappedSortedSetDocValues{}
+
+// mappedSortedSetDocValues maps the ordinals of the merged segments to the global ordinals of the OrdinalMap
+type mappedSortedSetDocValues struct {
+	*mappedDocValues[index.SortedSetDocValues]
+
+	lookups    []index.SortedSetDocValues
+	ordinalMap *OrdinalMap
+}
+
+func (m *mappedSortedSetDocValues) NextOrd() (int64, error) {
+	segmentOrd, err := m.current.values.NextOrd()
+	if err != nil {
+		return 0, err
+	}
+	if segmentOrd == NO_MORE_ORDS {
+		return NO_MORE_ORDS, nil
+	}
+	return m.current.globalOrds[segmentOrd], nil
+}
+
+func (m *mappedSortedSetDocValues) LookupOrd(ord int64) ([]byte, error) {
+	segmentNumber := m.ordinalMap.GetFirstSegmentNumber(ord)
+	segmentOrd := m.ordinalMap.GetFirstSegmentOrd(ord)
+	return m.lookups[segmentNumber].LookupOrd(segmentOrd)
+}
+
+func (m *mappedSortedSetDocValues) GetValueCount() int64 {
+	return m.ordinalMap.GetValueCount()
+}
+
+// liveOrdsTermsEnum only iterates over the terms whose ordinal is still used by a live document
+type liveOrdsTermsEnum struct {
+	index.TermsEnum
+
+	liveOrds *bitset.BitSet
+}
+
+func (l *liveOrdsTermsEnum) Next(ctx context.Context) ([]byte, error) {
+	for {
+		term, err := l.TermsEnum.Next(ctx)
+		if err != nil || term == nil {
+			return term, err
+		}
+		ord, err := l.TermsEnum.Ord()
+		if err != nil {
+			return nil, err
+		}
+		if l.liveOrds.Test(uint(ord)) {
+			return term, nil
+		}
+	}
+}
+
+var _ index.PointsReader = &mappedPointsReader{}
+
+// mappedPointsReader exposes the points of the merged segments with the document IDs of the
+// merged segment
+type mappedPointsReader struct {
+	mergeState *MergeState
+}
+
+func (m *mappedPointsReader) Close() error {
+	return nil
+}
+
+func (m *mappedPointsReader) CheckIntegrity() error {
+	return nil
+}
+
+func (m *mappedPointsReader) GetValues(ctx context.Context, field string) (types.PointValues, error) {
+	subs := make([]*mappedPointValuesSub, 0, len(m.mergeState.PointsReaders))
+	for i, reader := range m.mergeState.PointsReaders {
+		readerFieldInfo := m.mergeState.FieldInfos[i].FieldInfo(field)
+		if reader == nil || readerFieldInfo == nil || readerFieldInfo.GetPointDimensionCount() == 0 {
+			continue
+		}
+		values, err := reader.GetValues(ctx, field)
+		if err != nil {
+			return nil, err
+		}
+		if values == nil {
+			continue
+		}
+		subs = append(subs, &mappedPointValuesSub{values: values, docMap: m.mergeState.DocMaps[i]})
+	}
+	return &mappedPointValues{subs: subs}, nil
+}
+
+func (m *mappedPointsReader) GetMergeInstance() index.PointsReader {
+	return m
+}
+
+type mappedPointValuesSub struct {
+	values types.PointValues
+	docMap MergeStateDocMap
+}
+
+var _ types.PointValues = &mappedPointValues{}
+
+type mappedPointValues struct {
+	subs []*mappedPointValuesSub
+}
+
+func (m *mappedPointValues) Intersect(ctx context.Context, visitor types.IntersectVisitor) error {
+	for _, sub := range m.subs {
+		docMap := sub.docMap
+		if err := sub.values.Intersect(ctx, &types.BytesVisitor{
+			VisitFn: func(docID int) error {
+				newDocID := docMap.Get(docID)
+				if newDocID == -1 {
+					// deleted document
+					return nil
+				}
+				return visitor.Visit(ctx, newDocID)
+			},
+			VisitLeafFn: func(docID int, packedValue []byte) error {
+				newDocID := docMap.Get(docID)
+				if newDocID == -1 {
+					// deleted document
+					return nil
+				}
+				return visitor.VisitLeaf(ctx, newDocID, packedValue)
+			},
+			CompareFn: visitor.Compare,
+			GrowFn:    visitor.Grow,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mappedPointValues) EstimatePointCount(ctx context.Context, visitor types.IntersectVisitor) (int, error) {
+	count := 0
+	for _, sub := range m.subs {
+		v, err := sub.values.EstimatePointCount(ctx, visitor)
+		if err != nil {
+			return 0, err
+		}
+		count += v
+	}
+	return count, nil
+}
+
+func (m *mappedPointValues) EstimateDocCount(visitor types.IntersectVisitor) (int, error) {
+	return types.EstimateDocCount(m, visitor)
+}
+
+func (m *mappedPointValues) GetMinPackedValue() ([]byte, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+func (m *mappedPointValues) GetMaxPackedValue() ([]byte, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+func (m *mappedPointValues) GetNumDimensions() (int, error) {
+	if len(m.subs) == 0 {
+		return 0, nil
+	}
+	return m.subs[0].values.GetNumDimensions()
+}
+
+func (m *mappedPointValues) GetNumIndexDimensions() (int, error) {
+	if len(m.subs) == 0 {
+		return 0, nil
+	}
+	return m.subs[0].values.GetNumIndexDimensions()
+}
+
+func (m *mappedPointValues) GetBytesPerDimension() (int, error) {
+	if len(m.subs) == 0 {
+		return 0, nil
+	}
+	return m.subs[0].values.GetBytesPerDimension()
+}
+
+// Size returns the number of points of the merged segments, including the points of the deleted
+// documents
+func (m *mappedPointValues) Size() int {
+	size := 0
+	for _, sub := range m.subs {
+		size += sub.values.Size()
+	}
+	return size
+}
+
+func (m *mappedPointValues) GetDocCount() int {
+	docCount := 0
+	for _, sub := range m.subs {
+		docCount += sub.values.GetDocCount()
+	}
+	return docCount
+}
@@ -136,6 +136,7 @@ func (s *SegmentReader) New(si index.SegmentCommitInfo, liveDocs, hardLiveDocs u
 		docValuesProducer: nil,
 		fieldInfos:        nil,
 	}
+	reader.BaseCodecReader = NewBaseCodecReader(reader)
 
 	if err := reader.core.incRef(); err != nil {
 		return nil, err
@@ -183,11 +184,9 @@ func (s *SegmentReader) DoClose() error {
 	}
 
 	if producer, ok := s.docValuesProducer.(*SegmentDocValuesProducer); ok {
-		if err := s.segDocValues.decRef(producer.dvGens); err != nil {
-			return err
-		} else if s.docValuesProducer != nil {
-			return s.segDocValues.decRef([]int64{-1})
-		}
+		return s.segDocValues.decRef(producer.dvGens)
+	} else if s.docValuesProducer != nil {
+		return s.segDocValues.decRef([]int64{-1})
 	}
 	return nil
 }
@@ -236,6 +235,10 @@ func (s *SegmentReader) GetPointsReader() index.PointsReader {
 	return s.core.pointsReader
 }
 
+func (s *SegmentReader) GetVectorReader() index.KnnVectorsReader {
+	return s.core.knnVectorsReader
+}
+
 // GetOriginalSegmentInfo
 // Returns the original SegmentInfo passed to the segment reader on creation time.
 // getSegmentInfo() returns a clone of this instance.
@@ -0,0 +1,48 @@
+package index
+
+import (
+	"sync"
+
+	"github.com/geange/lucene-go/core/store"
+)
+
+var _ MergeScheduler = &SerialMergeScheduler{}
+
+// SerialMergeScheduler
+// A MergeScheduler that simply does each merge sequentially, using the current thread.
+type SerialMergeScheduler struct {
+	sync.Mutex
+}
+
+func NewSerialMergeScheduler() *SerialMergeScheduler {
+	return &SerialMergeScheduler{}
+}
+
+func (s *SerialMergeScheduler) Close() error {
+	return nil
+}
+
+// Merge
+// Just do the merges in sequence. We do this "synchronized" so that even if the application is using
+// multiple threads, only one merge may run at a time.
+func (s *SerialMergeScheduler) Merge(mergeSource MergeSource, trigger MergeTrigger) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for {
+		merge, err := mergeSource.GetNextMerge()
+		if err != nil {
+			return err
+		}
+		if merge == nil {
+			return nil
+		}
+		if err := mergeSource.Merge(merge); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *SerialMergeScheduler) Initialize(dir store.Directory) {
+	return
+}
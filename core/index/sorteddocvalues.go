@@ -2,9 +2,16 @@ package index
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/geange/lucene-go/core/document"
 	"github.com/geange/lucene-go/core/interface/index"
 	"github.com/geange/lucene-go/core/types"
 	"github.com/geange/lucene-go/core/util/automaton"
+	"github.com/geange/lucene-go/core/util/bytesref"
 )
 
 type SortedDocValuesDefaultConfig struct {
@@ -73,15 +80,289 @@ func (r *BaseSortedDocValues) Intersect(automaton *automaton.CompiledAutomaton)
 
 var _ DocValuesWriter = &SortedDocValuesWriter{}
 
+// SortedDocValuesWriter
+// Buffers up pending byte[] per doc, deref and sorting via int ord, then flushes when segment flushes.
 type SortedDocValuesWriter struct {
+	hash          *bytesref.BytesHash
+	pending       []int
+	docsWithField *DocsWithFieldSet
+	fieldInfo     *document.FieldInfo
+	lastDocID     int
+
+	// finalSortedValues and finalOrdMap are computed once the hash is sorted, no value can be added afterwards
+	finalSortedValues []int
+	finalOrdMap       []int
+}
+
+func NewSortedDocValuesWriter(fieldInfo *document.FieldInfo, pool *bytesref.BlockPool) (*SortedDocValuesWriter, error) {
+	hash, err := bytesref.NewBytesHash(pool)
+	if err != nil {
+		return nil, err
+	}
+	return &SortedDocValuesWriter{
+		hash:          hash,
+		pending:       make([]int, 0),
+		docsWithField: NewDocsWithFieldSet(),
+		fieldInfo:     fieldInfo,
+		lastDocID:     -1,
+	}, nil
+}
+
+func (s *SortedDocValuesWriter) AddValue(docID int, value []byte) error {
+	if docID <= s.lastDocID {
+		return fmt.Errorf(`DocValuesField "%s" appears more than once in this document (only one value is allowed per field)`,
+			s.fieldInfo.Name())
+	}
+	if value == nil {
+		return fmt.Errorf(`field "%s": null value not allowed`, s.fieldInfo.Name())
+	}
+	if len(value) > bytesref.BYTE_BLOCK_SIZE-2 {
+		return fmt.Errorf(`DocValuesField "%s" is too large, must be <= %d`, s.fieldInfo.Name(), bytesref.BYTE_BLOCK_SIZE-2)
+	}
+
+	termID, err := s.hash.Add(value)
+	if err != nil {
+		return err
+	}
+	if termID < 0 {
+		termID = -termID - 1
+	}
+	s.pending = append(s.pending, termID)
+	if err := s.docsWithField.Add(docID); err != nil {
+		return err
+	}
+	s.lastDocID = docID
+	return nil
+}
+
+// finish sorts the buffered values, the ordinal of a value is its rank in the sorted values
+func (s *SortedDocValuesWriter) finish() {
+	if s.finalSortedValues != nil {
+		return
+	}
+	valueCount := s.hash.Size()
+	s.finalSortedValues = s.hash.Sort()[:valueCount]
+	s.finalOrdMap = make([]int, valueCount)
+	for ord, termID := range s.finalSortedValues {
+		s.finalOrdMap[termID] = ord
+	}
 }
 
 func (s *SortedDocValuesWriter) Flush(state *index.SegmentWriteState, sortMap index.DocMap, consumer index.DocValuesConsumer) error {
-	//TODO implement me
-	panic("implement me")
+	var sorted []int
+	if sortMap != nil {
+		maxDoc, err := state.SegmentInfo.MaxDoc()
+		if err != nil {
+			return err
+		}
+		if sorted, err = sortDocValuesOrds(maxDoc, sortMap, s.GetDocValues().(index.SortedDocValues)); err != nil {
+			return err
+		}
+	}
+
+	return consumer.AddSortedField(context.TODO(), s.fieldInfo, &EmptyDocValuesProducer{
+		FnGetSorted: func(ctx context.Context, field *document.FieldInfo) (index.SortedDocValues, error) {
+			if field != s.fieldInfo {
+				return nil, errors.New("wrong fieldInfo")
+			}
+			values := s.GetDocValues().(index.SortedDocValues)
+			if sorted != nil {
+				return NewSortingSortedDocValues(values, sorted), nil
+			}
+			return values, nil
+		},
+	})
 }
 
 func (s *SortedDocValuesWriter) GetDocValues() types.DocIdSetIterator {
-	//TODO implement me
-	panic("implement me")
+	s.finish()
+	iterator, _ := s.docsWithField.Iterator()
+	return NewBufferedSortedDocValues(s.hash, s.pending, s.finalSortedValues, s.finalOrdMap, iterator)
+}
+
+// sortDocValuesOrds Reads the ordinals of oldValues in the order of the documents of the sorted segment,
+// documents without a value get the ordinal -1
+func sortDocValuesOrds(maxDoc int, sortMap index.DocMap, oldValues index.SortedDocValues) ([]int, error) {
+	ords := make([]int, maxDoc)
+	for i := range ords {
+		ords[i] = -1
+	}
+	for {
+		docID, err := oldValues.NextDoc()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if docID == types.NO_MORE_DOCS {
+			break
+		}
+		if ords[sortMap.OldToNew(docID)], err = oldValues.OrdValue(); err != nil {
+			return nil, err
+		}
+	}
+	return ords, nil
+}
+
+var _ index.SortedDocValues = &BufferedSortedDocValues{}
+
+// BufferedSortedDocValues Iterates over the ordinals of the documents in docsWithField, in order
+type BufferedSortedDocValues struct {
+	*BaseSortedDocValues
+
+	hash          *bytesref.BytesHash
+	ords          []int
+	sortedValues  []int
+	ordMap        []int
+	pos           int
+	ord           int
+	docsWithField types.DocIdSetIterator
+}
+
+func NewBufferedSortedDocValues(hash *bytesref.BytesHash, ords, sortedValues, ordMap []int,
+	docsWithField types.DocIdSetIterator) *BufferedSortedDocValues {
+
+	values := &BufferedSortedDocValues{
+		hash:          hash,
+		ords:          ords,
+		sortedValues:  sortedValues,
+		ordMap:        ordMap,
+		pos:           -1,
+		ord:           -1,
+		docsWithField: docsWithField,
+	}
+	values.BaseSortedDocValues = NewBaseSortedDocValues(&SortedDocValuesDefaultConfig{
+		OrdValue:      values.OrdValue,
+		LookupOrd:     values.LookupOrd,
+		GetValueCount: values.GetValueCount,
+	})
+	return values
+}
+
+func (b *BufferedSortedDocValues) DocID() int {
+	return b.docsWithField.DocID()
+}
+
+func (b *BufferedSortedDocValues) NextDoc() (int, error) {
+	docID, err := b.docsWithField.NextDoc()
+	if err != nil {
+		return 0, err
+	}
+	if docID != types.NO_MORE_DOCS {
+		b.pos++
+		b.ord = b.ordMap[b.ords[b.pos]]
+	}
+	return docID, nil
+}
+
+func (b *BufferedSortedDocValues) Advance(target int) (int, error) {
+	return 0, errors.New("unsupported Operation")
+}
+
+func (b *BufferedSortedDocValues) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(b, target)
+}
+
+func (b *BufferedSortedDocValues) Cost() int64 {
+	return b.docsWithField.Cost()
+}
+
+func (b *BufferedSortedDocValues) AdvanceExact(target int) (bool, error) {
+	return false, errors.New("unsupported Operation")
+}
+
+func (b *BufferedSortedDocValues) OrdValue() (int, error) {
+	return b.ord, nil
+}
+
+func (b *BufferedSortedDocValues) LookupOrd(ord int) ([]byte, error) {
+	if ord < 0 || ord >= len(b.sortedValues) {
+		return nil, fmt.Errorf("ord must be 0 .. %d; got %d", len(b.sortedValues)-1, ord)
+	}
+	return b.hash.Get(b.sortedValues[ord]), nil
+}
+
+func (b *BufferedSortedDocValues) GetValueCount() int {
+	return len(b.sortedValues)
+}
+
+func (b *BufferedSortedDocValues) TermsEnum() (index.TermsEnum, error) {
+	return NewSortedDocValuesTermsEnum(b), nil
+}
+
+var _ index.SortedDocValues = &SortingSortedDocValues{}
+
+// SortingSortedDocValues Iterates over the ordinals of a sorted segment, ords holds the ordinal
+// of every document of the sorted segment, -1 for the documents without a value
+type SortingSortedDocValues struct {
+	*BaseSortedDocValues
+
+	in    index.SortedDocValues
+	ords  []int
+	docID int
+}
+
+func NewSortingSortedDocValues(in index.SortedDocValues, ords []int) *SortingSortedDocValues {
+	values := &SortingSortedDocValues{
+		in:    in,
+		ords:  ords,
+		docID: -1,
+	}
+	values.BaseSortedDocValues = NewBaseSortedDocValues(&SortedDocValuesDefaultConfig{
+		OrdValue:      values.OrdValue,
+		LookupOrd:     values.LookupOrd,
+		GetValueCount: values.GetValueCount,
+	})
+	return values
+}
+
+func (s *SortingSortedDocValues) DocID() int {
+	return s.docID
+}
+
+func (s *SortingSortedDocValues) NextDoc() (int, error) {
+	for {
+		s.docID++
+		if s.docID >= len(s.ords) {
+			s.docID = types.NO_MORE_DOCS
+			return s.docID, nil
+		}
+		if s.ords[s.docID] != -1 {
+			return s.docID, nil
+		}
+	}
+}
+
+func (s *SortingSortedDocValues) Advance(target int) (int, error) {
+	return 0, errors.New("unsupported Operation")
+}
+
+func (s *SortingSortedDocValues) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(s, target)
+}
+
+func (s *SortingSortedDocValues) Cost() int64 {
+	return s.in.Cost()
+}
+
+func (s *SortingSortedDocValues) AdvanceExact(target int) (bool, error) {
+	s.docID = target
+	return s.ords[target] != -1, nil
+}
+
+func (s *SortingSortedDocValues) OrdValue() (int, error) {
+	return s.ords[s.docID], nil
+}
+
+func (s *SortingSortedDocValues) LookupOrd(ord int) ([]byte, error) {
+	return s.in.LookupOrd(ord)
+}
+
+func (s *SortingSortedDocValues) GetValueCount() int {
+	return s.in.GetValueCount()
+}
+
+func (s *SortingSortedDocValues) TermsEnum() (index.TermsEnum, error) {
+	return NewSortedDocValuesTermsEnum(s), nil
 }
@@ -1,80 +1,273 @@
 package index
 
 import (
+	"bytes"
 	"context"
+	"errors"
+
 	"github.com/geange/lucene-go/core/interface/index"
 	"github.com/geange/lucene-go/core/util/attribute"
 )
 
+var _ index.TermsEnum = &SortedDocValuesTermsEnum{}
+
 // SortedDocValuesTermsEnum Creates a new TermsEnum over the provided values
 type SortedDocValuesTermsEnum struct {
+	*BaseTermsEnum
+
+	values     index.SortedDocValues
+	currentOrd int
+	term       []byte
 }
 
 func NewSortedDocValuesTermsEnum(values index.SortedDocValues) *SortedDocValuesTermsEnum {
-	return &SortedDocValuesTermsEnum{}
+	termsEnum := &SortedDocValuesTermsEnum{
+		values:     values,
+		currentOrd: -1,
+	}
+	termsEnum.BaseTermsEnum = NewBaseTermsEnum(&BaseTermsEnumConfig{SeekCeil: termsEnum.SeekCeil})
+	return termsEnum
 }
 
 func (s *SortedDocValuesTermsEnum) Next(context.Context) ([]byte, error) {
-	//TODO implement me
-	panic("implement me")
+	s.currentOrd++
+	if s.currentOrd >= s.values.GetValueCount() {
+		return nil, nil
+	}
+	term, err := s.values.LookupOrd(s.currentOrd)
+	if err != nil {
+		return nil, err
+	}
+	s.term = term
+	return s.term, nil
 }
 
 func (s *SortedDocValuesTermsEnum) Attributes() *attribute.Source {
-	//TODO implement me
-	panic("implement me")
+	return s.BaseTermsEnum.Attributes()
 }
 
 func (s *SortedDocValuesTermsEnum) SeekExact(ctx context.Context, text []byte) (bool, error) {
-	//TODO implement me
-	panic("implement me")
+	ord, err := s.values.LookupTerm(text)
+	if err != nil {
+		return false, err
+	}
+	if ord < 0 {
+		return false, nil
+	}
+	s.currentOrd = ord
+	s.term = bytes.Clone(text)
+	return true, nil
 }
 
 func (s *SortedDocValuesTermsEnum) SeekCeil(ctx context.Context, text []byte) (index.SeekStatus, error) {
-	//TODO implement me
-	panic("implement me")
+	ord, err := s.values.LookupTerm(text)
+	if err != nil {
+		return 0, err
+	}
+	if ord >= 0 {
+		s.currentOrd = ord
+		s.term = bytes.Clone(text)
+		return index.SEEK_STATUS_FOUND, nil
+	}
+	s.currentOrd = -ord - 1
+	if s.currentOrd == s.values.GetValueCount() {
+		return index.SEEK_STATUS_END, nil
+	}
+	if s.term, err = s.values.LookupOrd(s.currentOrd); err != nil {
+		return 0, err
+	}
+	return index.SEEK_STATUS_NOT_FOUND, nil
 }
 
 func (s *SortedDocValuesTermsEnum) SeekExactByOrd(ctx context.Context, ord int64) error {
-	//TODO implement me
-	panic("implement me")
+	if ord < 0 || ord >= int64(s.values.GetValueCount()) {
+		return errors.New("ord is out of bounds")
+	}
+	term, err := s.values.LookupOrd(int(ord))
+	if err != nil {
+		return err
+	}
+	s.currentOrd = int(ord)
+	s.term = term
+	return nil
 }
 
 func (s *SortedDocValuesTermsEnum) SeekExactExpert(ctx context.Context, term []byte, state index.TermState) error {
-	//TODO implement me
-	panic("implement me")
+	ordState, ok := state.(*OrdTermState)
+	if !ok {
+		return errors.New("state is not OrdTermState")
+	}
+	return s.SeekExactByOrd(ctx, ordState.Ord)
 }
 
 func (s *SortedDocValuesTermsEnum) Term() ([]byte, error) {
-	//TODO implement me
-	panic("implement me")
+	return s.term, nil
 }
 
 func (s *SortedDocValuesTermsEnum) Ord() (int64, error) {
-	//TODO implement me
-	panic("implement me")
+	return int64(s.currentOrd), nil
 }
 
 func (s *SortedDocValuesTermsEnum) DocFreq() (int, error) {
-	//TODO implement me
-	panic("implement me")
+	return 0, ErrUnsupportedOperation
 }
 
 func (s *SortedDocValuesTermsEnum) TotalTermFreq() (int64, error) {
-	//TODO implement me
-	panic("implement me")
+	return 0, ErrUnsupportedOperation
 }
 
 func (s *SortedDocValuesTermsEnum) Postings(reuse index.PostingsEnum, flags int) (index.PostingsEnum, error) {
-	//TODO implement me
-	panic("implement me")
+	return nil, ErrUnsupportedOperation
 }
 
 func (s *SortedDocValuesTermsEnum) Impacts(flags int) (index.ImpactsEnum, error) {
-	//TODO implement me
-	panic("implement me")
+	return nil, ErrUnsupportedOperation
 }
 
 func (s *SortedDocValuesTermsEnum) TermState() (index.TermState, error) {
-	//TODO implement me
-	panic("implement me")
+	state := NewOrdTermState()
+	state.Ord = int64(s.currentOrd)
+	return state, nil
+}
+
+var _ index.TermsEnum = &SortedSetDocValuesTermsEnum{}
+
+// SortedSetDocValuesTermsEnum Creates a new TermsEnum over the provided values
+type SortedSetDocValuesTermsEnum struct {
+	*BaseTermsEnum
+
+	values     index.SortedSetDocValues
+	currentOrd int64
+	term       []byte
+}
+
+func NewSortedSetDocValuesTermsEnum(values index.SortedSetDocValues) *SortedSetDocValuesTermsEnum {
+	termsEnum := &SortedSetDocValuesTermsEnum{
+		values:     values,
+		currentOrd: -1,
+	}
+	termsEnum.BaseTermsEnum = NewBaseTermsEnum(&BaseTermsEnumConfig{SeekCeil: termsEnum.SeekCeil})
+	return termsEnum
+}
+
+// lookupTerm If key exists, returns its ordinal, else returns -insertionPoint-1
+func (s *SortedSetDocValuesTermsEnum) lookupTerm(key []byte) (int64, error) {
+	low, high := int64(0), s.values.GetValueCount()-1
+	for low <= high {
+		mid := (low + high) >> 1
+		term, err := s.values.LookupOrd(mid)
+		if err != nil {
+			return 0, err
+		}
+		cmp := bytes.Compare(term, key)
+		if cmp < 0 {
+			low = mid + 1
+		} else if cmp > 0 {
+			high = mid - 1
+		} else {
+			return mid, nil
+		}
+	}
+	return -(low + 1), nil // key not found.
+}
+
+func (s *SortedSetDocValuesTermsEnum) Next(context.Context) ([]byte, error) {
+	s.currentOrd++
+	if s.currentOrd >= s.values.GetValueCount() {
+		return nil, nil
+	}
+	term, err := s.values.LookupOrd(s.currentOrd)
+	if err != nil {
+		return nil, err
+	}
+	s.term = term
+	return s.term, nil
+}
+
+func (s *SortedSetDocValuesTermsEnum) Attributes() *attribute.Source {
+	return s.BaseTermsEnum.Attributes()
+}
+
+func (s *SortedSetDocValuesTermsEnum) SeekExact(ctx context.Context, text []byte) (bool, error) {
+	ord, err := s.lookupTerm(text)
+	if err != nil {
+		return false, err
+	}
+	if ord < 0 {
+		return false, nil
+	}
+	s.currentOrd = ord
+	s.term = bytes.Clone(text)
+	return true, nil
+}
+
+func (s *SortedSetDocValuesTermsEnum) SeekCeil(ctx context.Context, text []byte) (index.SeekStatus, error) {
+	ord, err := s.lookupTerm(text)
+	if err != nil {
+		return 0, err
+	}
+	if ord >= 0 {
+		s.currentOrd = ord
+		s.term = bytes.Clone(text)
+		return index.SEEK_STATUS_FOUND, nil
+	}
+	s.currentOrd = -ord - 1
+	if s.currentOrd == s.values.GetValueCount() {
+		return index.SEEK_STATUS_END, nil
+	}
+	if s.term, err = s.values.LookupOrd(s.currentOrd); err != nil {
+		return 0, err
+	}
+	return index.SEEK_STATUS_NOT_FOUND, nil
+}
+
+func (s *SortedSetDocValuesTermsEnum) SeekExactByOrd(ctx context.Context, ord int64) error {
+	if ord < 0 || ord >= s.values.GetValueCount() {
+		return errors.New("ord is out of bounds")
+	}
+	term, err := s.values.LookupOrd(ord)
+	if err != nil {
+		return err
+	}
+	s.currentOrd = ord
+	s.term = term
+	return nil
+}
+
+func (s *SortedSetDocValuesTermsEnum) SeekExactExpert(ctx context.Context, term []byte, state index.TermState) error {
+	ordState, ok := state.(*OrdTermState)
+	if !ok {
+		return errors.New("state is not OrdTermState")
+	}
+	return s.SeekExactByOrd(ctx, ordState.Ord)
+}
+
+func (s *SortedSetDocValuesTermsEnum) Term() ([]byte, error) {
+	return s.term, nil
+}
+
+func (s *SortedSetDocValuesTermsEnum) Ord() (int64, error) {
+	return s.currentOrd, nil
+}
+
+func (s *SortedSetDocValuesTermsEnum) DocFreq() (int, error) {
+	return 0, ErrUnsupportedOperation
+}
+
+func (s *SortedSetDocValuesTermsEnum) TotalTermFreq() (int64, error) {
+	return 0, ErrUnsupportedOperation
+}
+
+func (s *SortedSetDocValuesTermsEnum) Postings(reuse index.PostingsEnum, flags int) (index.PostingsEnum, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+func (s *SortedSetDocValuesTermsEnum) Impacts(flags int) (index.ImpactsEnum, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+func (s *SortedSetDocValuesTermsEnum) TermState() (index.TermState, error) {
+	state := NewOrdTermState()
+	state.Ord = s.currentOrd
+	return state, nil
 }
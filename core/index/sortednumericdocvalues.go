@@ -1,21 +1,262 @@
 package index
 
 import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+
+	"github.com/geange/lucene-go/core/document"
 	"github.com/geange/lucene-go/core/interface/index"
 	"github.com/geange/lucene-go/core/types"
 )
 
 var _ DocValuesWriter = &SortedNumericDocValuesWriter{}
 
+// SortedNumericDocValuesWriter
+// Buffers up pending long[] per doc, sorts, then flushes when segment flushes.
 type SortedNumericDocValuesWriter struct {
+	pending       []int64 // stream of all values
+	pendingCounts []int   // count of values per doc
+	docsWithField *DocsWithFieldSet
+	fieldInfo     *document.FieldInfo
+	currentDoc    int
+	currentValues []int64
+}
+
+func NewSortedNumericDocValuesWriter(fieldInfo *document.FieldInfo) *SortedNumericDocValuesWriter {
+	return &SortedNumericDocValuesWriter{
+		pending:       make([]int64, 0),
+		pendingCounts: make([]int, 0),
+		docsWithField: NewDocsWithFieldSet(),
+		fieldInfo:     fieldInfo,
+		currentDoc:    -1,
+		currentValues: make([]int64, 0),
+	}
+}
+
+func (s *SortedNumericDocValuesWriter) AddValue(docID int, value int64) error {
+	if docID != s.currentDoc {
+		if err := s.finishCurrentDoc(); err != nil {
+			return err
+		}
+		s.currentDoc = docID
+	}
+	s.currentValues = append(s.currentValues, value)
+	return nil
+}
+
+// finishCurrentDoc buffers the sorted values of the current document
+func (s *SortedNumericDocValuesWriter) finishCurrentDoc() error {
+	if s.currentDoc == -1 {
+		return nil
+	}
+	slices.Sort(s.currentValues)
+	s.pending = append(s.pending, s.currentValues...)
+	s.pendingCounts = append(s.pendingCounts, len(s.currentValues))
+	s.currentValues = s.currentValues[:0]
+	if err := s.docsWithField.Add(s.currentDoc); err != nil {
+		return err
+	}
+	s.currentDoc = -1
+	return nil
 }
 
 func (s *SortedNumericDocValuesWriter) Flush(state *index.SegmentWriteState, sortMap index.DocMap, consumer index.DocValuesConsumer) error {
-	//TODO implement me
-	panic("implement me")
+	if err := s.finishCurrentDoc(); err != nil {
+		return err
+	}
+
+	var sorted [][]int64
+	if sortMap != nil {
+		maxDoc, err := state.SegmentInfo.MaxDoc()
+		if err != nil {
+			return err
+		}
+		if sorted, err = sortDocValuesSets(maxDoc, sortMap, s.GetDocValues().(index.SortedNumericDocValues)); err != nil {
+			return err
+		}
+	}
+
+	return consumer.AddSortedNumericField(context.TODO(), s.fieldInfo, &EmptyDocValuesProducer{
+		FnGetSortedNumeric: func(ctx context.Context, field *document.FieldInfo) (index.SortedNumericDocValues, error) {
+			if field != s.fieldInfo {
+				return nil, errors.New("wrong fieldInfo")
+			}
+			if sorted != nil {
+				return NewSortingSortedNumericDocValues(sorted, int64(len(s.pendingCounts))), nil
+			}
+			return s.GetDocValues().(index.SortedNumericDocValues), nil
+		},
+	})
 }
 
 func (s *SortedNumericDocValuesWriter) GetDocValues() types.DocIdSetIterator {
-	//TODO implement me
-	panic("implement me")
+	_ = s.finishCurrentDoc()
+	iterator, _ := s.docsWithField.Iterator()
+	return NewBufferedSortedNumericDocValues(s.pending, s.pendingCounts, iterator)
+}
+
+// sortDocValuesSets Reads the values of oldValues in the order of the documents of the sorted segment
+func sortDocValuesSets(maxDoc int, sortMap index.DocMap, oldValues index.SortedNumericDocValues) ([][]int64, error) {
+	values := make([][]int64, maxDoc)
+	for {
+		docID, err := oldValues.NextDoc()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if docID == types.NO_MORE_DOCS {
+			break
+		}
+		newDocID := sortMap.OldToNew(docID)
+		docValues := make([]int64, oldValues.DocValueCount())
+		for i := range docValues {
+			if docValues[i], err = oldValues.NextValue(); err != nil {
+				return nil, err
+			}
+		}
+		values[newDocID] = docValues
+	}
+	return values, nil
+}
+
+var _ index.SortedNumericDocValues = &BufferedSortedNumericDocValues{}
+
+// BufferedSortedNumericDocValues Iterates over the values of the documents in docsWithField, in order
+type BufferedSortedNumericDocValues struct {
+	values        []int64
+	valueCounts   []int
+	countUpto     int
+	valueStart    int // offset of the first value of the current document
+	valueCount    int
+	valueUpto     int
+	docsWithField types.DocIdSetIterator
+}
+
+func NewBufferedSortedNumericDocValues(values []int64, valueCounts []int,
+	docsWithField types.DocIdSetIterator) *BufferedSortedNumericDocValues {
+
+	return &BufferedSortedNumericDocValues{
+		values:        values,
+		valueCounts:   valueCounts,
+		docsWithField: docsWithField,
+	}
+}
+
+func (b *BufferedSortedNumericDocValues) DocID() int {
+	return b.docsWithField.DocID()
+}
+
+func (b *BufferedSortedNumericDocValues) NextDoc() (int, error) {
+	docID, err := b.docsWithField.NextDoc()
+	if err != nil {
+		return 0, err
+	}
+	if docID != types.NO_MORE_DOCS {
+		b.valueStart += b.valueCount
+		b.valueCount = b.valueCounts[b.countUpto]
+		b.valueUpto = b.valueStart
+		b.countUpto++
+	}
+	return docID, nil
+}
+
+func (b *BufferedSortedNumericDocValues) Advance(target int) (int, error) {
+	return 0, errors.New("unsupported Operation")
+}
+
+func (b *BufferedSortedNumericDocValues) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(b, target)
+}
+
+func (b *BufferedSortedNumericDocValues) Cost() int64 {
+	return b.docsWithField.Cost()
+}
+
+func (b *BufferedSortedNumericDocValues) AdvanceExact(target int) (bool, error) {
+	return false, errors.New("unsupported Operation")
+}
+
+func (b *BufferedSortedNumericDocValues) NextValue() (int64, error) {
+	if b.valueUpto >= b.valueStart+b.valueCount {
+		return 0, io.EOF
+	}
+	value := b.values[b.valueUpto]
+	b.valueUpto++
+	return value, nil
+}
+
+func (b *BufferedSortedNumericDocValues) DocValueCount() int {
+	return b.valueCount
+}
+
+var _ index.SortedNumericDocValues = &SortingSortedNumericDocValues{}
+
+// SortingSortedNumericDocValues Iterates over the values of a sorted segment, values holds the values
+// of every document of the sorted segment
+type SortingSortedNumericDocValues struct {
+	values    [][]int64
+	cost      int64
+	docID     int
+	valueUpto int
+}
+
+func NewSortingSortedNumericDocValues(values [][]int64, cost int64) *SortingSortedNumericDocValues {
+	return &SortingSortedNumericDocValues{
+		values: values,
+		cost:   cost,
+		docID:  -1,
+	}
+}
+
+func (s *SortingSortedNumericDocValues) DocID() int {
+	return s.docID
+}
+
+func (s *SortingSortedNumericDocValues) NextDoc() (int, error) {
+	for {
+		s.docID++
+		if s.docID >= len(s.values) {
+			s.docID = types.NO_MORE_DOCS
+			return s.docID, nil
+		}
+		if len(s.values[s.docID]) > 0 {
+			s.valueUpto = 0
+			return s.docID, nil
+		}
+	}
+}
+
+func (s *SortingSortedNumericDocValues) Advance(target int) (int, error) {
+	return 0, errors.New("unsupported Operation")
+}
+
+func (s *SortingSortedNumericDocValues) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(s, target)
+}
+
+func (s *SortingSortedNumericDocValues) Cost() int64 {
+	return s.cost
+}
+
+func (s *SortingSortedNumericDocValues) AdvanceExact(target int) (bool, error) {
+	s.docID = target
+	s.valueUpto = 0
+	return len(s.values[target]) > 0, nil
+}
+
+func (s *SortingSortedNumericDocValues) NextValue() (int64, error) {
+	if s.valueUpto == len(s.values[s.docID]) {
+		return 0, io.EOF
+	}
+	value := s.values[s.docID][s.valueUpto]
+	s.valueUpto++
+	return value, nil
+}
+
+func (s *SortingSortedNumericDocValues) DocValueCount() int {
+	return len(s.values[s.docID])
 }
@@ -1,21 +1,335 @@
 package index
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/geange/lucene-go/core/document"
 	"github.com/geange/lucene-go/core/interface/index"
 	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util/bytesref"
 )
 
 var _ DocValuesWriter = &SortedSetDocValuesWriter{}
 
+// SortedSetDocValuesWriter
+// Buffers up pending byte[]s per doc, deref and sorting via int ord, then flushes when segment flushes.
 type SortedSetDocValuesWriter struct {
+	hash          *bytesref.BytesHash
+	pending       []int // stream of all termIDs
+	pendingCounts []int // termIDs per doc
+	docsWithField *DocsWithFieldSet
+	fieldInfo     *document.FieldInfo
+	currentDoc    int
+	currentValues []int
+
+	// finalSortedValues and finalOrdMap are computed once the hash is sorted, no value can be added afterwards
+	finalSortedValues []int
+	finalOrdMap       []int
+}
+
+func NewSortedSetDocValuesWriter(fieldInfo *document.FieldInfo, pool *bytesref.BlockPool) (*SortedSetDocValuesWriter, error) {
+	hash, err := bytesref.NewBytesHash(pool)
+	if err != nil {
+		return nil, err
+	}
+	return &SortedSetDocValuesWriter{
+		hash:          hash,
+		pending:       make([]int, 0),
+		pendingCounts: make([]int, 0),
+		docsWithField: NewDocsWithFieldSet(),
+		fieldInfo:     fieldInfo,
+		currentDoc:    -1,
+		currentValues: make([]int, 0),
+	}, nil
+}
+
+func (s *SortedSetDocValuesWriter) AddValue(docID int, value []byte) error {
+	if value == nil {
+		return fmt.Errorf(`field "%s": null value not allowed`, s.fieldInfo.Name())
+	}
+	if len(value) > bytesref.BYTE_BLOCK_SIZE-2 {
+		return fmt.Errorf(`DocValuesField "%s" is too large, must be <= %d`, s.fieldInfo.Name(), bytesref.BYTE_BLOCK_SIZE-2)
+	}
+
+	if docID != s.currentDoc {
+		if err := s.finishCurrentDoc(); err != nil {
+			return err
+		}
+		s.currentDoc = docID
+	}
+
+	termID, err := s.hash.Add(value)
+	if err != nil {
+		return err
+	}
+	if termID < 0 {
+		termID = -termID - 1
+	}
+	s.currentValues = append(s.currentValues, termID)
+	return nil
+}
+
+// finishCurrentDoc buffers the deduplicated termIDs of the current document
+func (s *SortedSetDocValuesWriter) finishCurrentDoc() error {
+	if s.currentDoc == -1 {
+		return nil
+	}
+	slices.Sort(s.currentValues)
+	s.currentValues = slices.Compact(s.currentValues)
+	s.pending = append(s.pending, s.currentValues...)
+	s.pendingCounts = append(s.pendingCounts, len(s.currentValues))
+	s.currentValues = s.currentValues[:0]
+	if err := s.docsWithField.Add(s.currentDoc); err != nil {
+		return err
+	}
+	s.currentDoc = -1
+	return nil
+}
+
+// finish sorts the buffered values, the ordinal of a value is its rank in the sorted values
+func (s *SortedSetDocValuesWriter) finish() error {
+	if s.finalSortedValues != nil {
+		return nil
+	}
+	if err := s.finishCurrentDoc(); err != nil {
+		return err
+	}
+	valueCount := s.hash.Size()
+	s.finalSortedValues = s.hash.Sort()[:valueCount]
+	s.finalOrdMap = make([]int, valueCount)
+	for ord, termID := range s.finalSortedValues {
+		s.finalOrdMap[termID] = ord
+	}
+	return nil
 }
 
 func (s *SortedSetDocValuesWriter) Flush(state *index.SegmentWriteState, sortMap index.DocMap, consumer index.DocValuesConsumer) error {
-	//TODO implement me
-	panic("implement me")
+	if err := s.finish(); err != nil {
+		return err
+	}
+
+	var sorted [][]int64
+	if sortMap != nil {
+		maxDoc, err := state.SegmentInfo.MaxDoc()
+		if err != nil {
+			return err
+		}
+		if sorted, err = sortDocValuesOrdSets(maxDoc, sortMap, s.GetDocValues().(index.SortedSetDocValues)); err != nil {
+			return err
+		}
+	}
+
+	return consumer.AddSortedSetField(context.TODO(), s.fieldInfo, &EmptyDocValuesProducer{
+		FnGetSortedSet: func(ctx context.Context, field *document.FieldInfo) (index.SortedSetDocValues, error) {
+			if field != s.fieldInfo {
+				return nil, errors.New("wrong fieldInfo")
+			}
+			values := s.GetDocValues().(index.SortedSetDocValues)
+			if sorted != nil {
+				return NewSortingSortedSetDocValues(values, sorted), nil
+			}
+			return values, nil
+		},
+	})
 }
 
 func (s *SortedSetDocValuesWriter) GetDocValues() types.DocIdSetIterator {
-	//TODO implement me
-	panic("implement me")
+	_ = s.finish()
+	iterator, _ := s.docsWithField.Iterator()
+	return NewBufferedSortedSetDocValues(s.hash, s.pending, s.pendingCounts, s.finalSortedValues, s.finalOrdMap, iterator)
+}
+
+// sortDocValuesOrdSets Reads the ordinals of oldValues in the order of the documents of the sorted segment,
+// documents without a value get no ordinal
+func sortDocValuesOrdSets(maxDoc int, sortMap index.DocMap, oldValues index.SortedSetDocValues) ([][]int64, error) {
+	ords := make([][]int64, maxDoc)
+	for {
+		docID, err := oldValues.NextDoc()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if docID == types.NO_MORE_DOCS {
+			break
+		}
+		newDocID := sortMap.OldToNew(docID)
+		for {
+			ord, err := oldValues.NextOrd()
+			if err != nil {
+				return nil, err
+			}
+			if ord == NO_MORE_ORDS {
+				break
+			}
+			ords[newDocID] = append(ords[newDocID], ord)
+		}
+	}
+	return ords, nil
+}
+
+var _ index.SortedSetDocValues = &BufferedSortedSetDocValues{}
+
+// BufferedSortedSetDocValues Iterates over the ordinals of the documents in docsWithField, in order
+type BufferedSortedSetDocValues struct {
+	hash          *bytesref.BytesHash
+	ords          []int
+	ordCounts     []int
+	sortedValues  []int
+	ordMap        []int
+	ordUpto       int
+	countUpto     int
+	currentDoc    []int64
+	currentUpto   int
+	docsWithField types.DocIdSetIterator
+}
+
+func NewBufferedSortedSetDocValues(hash *bytesref.BytesHash, ords, ordCounts, sortedValues, ordMap []int,
+	docsWithField types.DocIdSetIterator) *BufferedSortedSetDocValues {
+
+	return &BufferedSortedSetDocValues{
+		hash:          hash,
+		ords:          ords,
+		ordCounts:     ordCounts,
+		sortedValues:  sortedValues,
+		ordMap:        ordMap,
+		currentDoc:    make([]int64, 0),
+		docsWithField: docsWithField,
+	}
+}
+
+func (b *BufferedSortedSetDocValues) DocID() int {
+	return b.docsWithField.DocID()
+}
+
+func (b *BufferedSortedSetDocValues) NextDoc() (int, error) {
+	docID, err := b.docsWithField.NextDoc()
+	if err != nil {
+		return 0, err
+	}
+	if docID != types.NO_MORE_DOCS {
+		count := b.ordCounts[b.countUpto]
+		b.countUpto++
+		b.currentDoc = b.currentDoc[:0]
+		for _, termID := range b.ords[b.ordUpto : b.ordUpto+count] {
+			b.currentDoc = append(b.currentDoc, int64(b.ordMap[termID]))
+		}
+		b.ordUpto += count
+		slices.Sort(b.currentDoc)
+		b.currentUpto = 0
+	}
+	return docID, nil
+}
+
+func (b *BufferedSortedSetDocValues) Advance(target int) (int, error) {
+	return 0, errors.New("unsupported Operation")
+}
+
+func (b *BufferedSortedSetDocValues) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(b, target)
+}
+
+func (b *BufferedSortedSetDocValues) Cost() int64 {
+	return b.docsWithField.Cost()
+}
+
+func (b *BufferedSortedSetDocValues) AdvanceExact(target int) (bool, error) {
+	return false, errors.New("unsupported Operation")
+}
+
+func (b *BufferedSortedSetDocValues) NextOrd() (int64, error) {
+	if b.currentUpto == len(b.currentDoc) {
+		return NO_MORE_ORDS, nil
+	}
+	ord := b.currentDoc[b.currentUpto]
+	b.currentUpto++
+	return ord, nil
+}
+
+func (b *BufferedSortedSetDocValues) LookupOrd(ord int64) ([]byte, error) {
+	if ord < 0 || ord >= int64(len(b.sortedValues)) {
+		return nil, fmt.Errorf("ord must be 0 .. %d; got %d", len(b.sortedValues)-1, ord)
+	}
+	return b.hash.Get(b.sortedValues[ord]), nil
+}
+
+func (b *BufferedSortedSetDocValues) GetValueCount() int64 {
+	return int64(len(b.sortedValues))
+}
+
+var _ index.SortedSetDocValues = &SortingSortedSetDocValues{}
+
+// SortingSortedSetDocValues Iterates over the ordinals of a sorted segment, ords holds the ordinals
+// of every document of the sorted segment
+type SortingSortedSetDocValues struct {
+	in      index.SortedSetDocValues
+	ords    [][]int64
+	docID   int
+	ordUpto int
+}
+
+func NewSortingSortedSetDocValues(in index.SortedSetDocValues, ords [][]int64) *SortingSortedSetDocValues {
+	return &SortingSortedSetDocValues{
+		in:    in,
+		ords:  ords,
+		docID: -1,
+	}
+}
+
+func (s *SortingSortedSetDocValues) DocID() int {
+	return s.docID
+}
+
+func (s *SortingSortedSetDocValues) NextDoc() (int, error) {
+	for {
+		s.docID++
+		if s.docID >= len(s.ords) {
+			s.docID = types.NO_MORE_DOCS
+			return s.docID, nil
+		}
+		if len(s.ords[s.docID]) > 0 {
+			s.ordUpto = 0
+			return s.docID, nil
+		}
+	}
+}
+
+func (s *SortingSortedSetDocValues) Advance(target int) (int, error) {
+	return 0, errors.New("unsupported Operation")
+}
+
+func (s *SortingSortedSetDocValues) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(s, target)
+}
+
+func (s *SortingSortedSetDocValues) Cost() int64 {
+	return s.in.Cost()
+}
+
+func (s *SortingSortedSetDocValues) AdvanceExact(target int) (bool, error) {
+	s.docID = target
+	s.ordUpto = 0
+	return len(s.ords[target]) > 0, nil
+}
+
+func (s *SortingSortedSetDocValues) NextOrd() (int64, error) {
+	if s.ordUpto == len(s.ords[s.docID]) {
+		return NO_MORE_ORDS, nil
+	}
+	ord := s.ords[s.docID][s.ordUpto]
+	s.ordUpto++
+	return ord, nil
+}
+
+func (s *SortingSortedSetDocValues) LookupOrd(ord int64) ([]byte, error) {
+	return s.in.LookupOrd(ord)
+}
+
+func (s *SortingSortedSetDocValues) GetValueCount() int64 {
+	return s.in.GetValueCount()
 }
@@ -1,64 +1,59 @@
 package index
 
-import "github.com/geange/lucene-go/core/interface/index"
+import (
+	"slices"
 
-const (
-	BINARY_SORT_THRESHOLD    = 20
-	INSERTION_SORT_THRESHOLD = 16
+	"github.com/geange/lucene-go/core/interface/index"
 )
 
-type SorterDefault struct {
-	pivotIndex int
-	fnCompare  func(i, j int) int
-	fnSwap     func(i, j int)
-}
-
+// SortByComparator
+// Computes the old-to-new permutation over the given comparator, documents which compare equal keep
+// their order. Returns nil if the documents are already sorted.
 func SortByComparator(maxDoc int, comparator index.DocComparator) index.DocMap {
-	// TODO: fix it
-	panic("")
-	/*
-
-		// sort doc IDs
-		docs := make([]int, maxDoc)
-		for i := range docs {
-			docs[i] = i
-		}
-
-		sorter := NewDocValueSorter(docs, comparator)
-		sort.Sort(sorter)
-
-		newToOldBuilder := packed.NewPackedLongValuesBuilder()
-		for i := 0; i < maxDoc; i++ {
-			newToOldBuilder.Add(int64(docs[i]))
+	// check if the index is sorted
+	sorted := true
+	for i := 1; i < maxDoc; i++ {
+		if comparator.Compare(i-1, i) > 0 {
+			sorted = false
+			break
 		}
+	}
+	if sorted {
+		return nil
+	}
+
+	// sort doc IDs
+	newToOld := make([]int, maxDoc)
+	for i := range newToOld {
+		newToOld[i] = i
+	}
+	slices.SortStableFunc(newToOld, comparator.Compare)
+
+	// invert the docs mapping
+	oldToNew := make([]int, maxDoc)
+	for newDocID, oldDocID := range newToOld {
+		oldToNew[oldDocID] = newDocID
+	}
+	return &sorterDocMap{oldToNew: oldToNew, newToOld: newToOld}
+}
 
-		newToOld := newToOldBuilder.Build()
-
-		// invert the docs mapping:
-		for i := 0; i < maxDoc; i++ {
-			docs[newToOld.Get(int64(i))] = i
-		} // docs is now the oldToNew mapping
+var _ index.DocMap = &sorterDocMap{}
 
-		oldToNewBuilder := packed.NewPackedLongValuesBuilder()
-		for i := 0; i < maxDoc; i++ {
-			oldToNewBuilder.Add(int64(docs[i]))
-		}
-		oldToNew := oldToNewBuilder.Build()
+type sorterDocMap struct {
+	oldToNew []int
+	newToOld []int
+}
 
-		return &DocMap{
-			OldToNew: func(docID int) int {
-				return int(oldToNew.Get(int64(docID)))
-			},
-			NewToOld: func(docID int) int {
-				return int(newToOld.Get(int64(docID)))
-			},
-			Size: func() int {
-				return maxDoc
-			},
-		}
+func (s *sorterDocMap) OldToNew(docID int) int {
+	return s.oldToNew[docID]
+}
 
-	*/
+func (s *sorterDocMap) NewToOld(docID int) int {
+	return s.newToOld[docID]
+}
 
+func (s *sorterDocMap) Size() int {
+	return len(s.oldToNew)
 }
 
 var _ index.DocComparator = &EmptyDocComparator{}
@@ -71,25 +66,18 @@ func (e *EmptyDocComparator) Compare(docID1, docID2 int) int {
 	return e.FnCompare(docID1, docID2)
 }
 
+// SortByComparators
+// Computes the old-to-new permutation over the given comparators, the first comparator that does not
+// find the documents equal decides of their order. Returns nil if the documents are already sorted.
 func SortByComparators(maxDoc int, comparators []index.DocComparator) (index.DocMap, error) {
-	// TODO: fix it
-	/*
-		return SortByComparator(maxDoc, &EmptyDocComparator{
-			FnCompare: func(docID1, docID2 int) int {
-				for _, comparator := range comparators {
-					if cmp := comparator.Compare(docID1, docID2); cmp != 0 {
-						return cmp
-					}
-				}
-				if docID1 < docID2 {
-					return 1
-				} else if docID1 == docID2 {
-					return 0
-				} else {
-					return -1
+	return SortByComparator(maxDoc, &EmptyDocComparator{
+		FnCompare: func(docID1, docID2 int) int {
+			for _, comparator := range comparators {
+				if cmp := comparator.Compare(docID1, docID2); cmp != 0 {
+					return cmp
 				}
-			}}), nil
-
-	*/
-	panic("")
+			}
+			return Compare(docID1, docID2)
+		},
+	}), nil
 }
@@ -204,20 +204,30 @@ func (s *BaseSortField) GetIndexSorter() index.IndexSorter {
 				return GetSorted(reader, s.field)
 			},
 		}
-		return NewStringSorter(ProviderName, s.missingValue.(string), s.reverse, sorter)
+		missingValue, _ := s.missingValue.(string)
+		return NewStringSorter(ProviderName, missingValue, s.reverse, sorter)
 	case index.INT:
-		return NewIntSorter(ProviderName, s.missingValue.(int32), s.reverse, sorter)
+		return NewIntSorter(ProviderName, missingValueOf[int32](s.missingValue), s.reverse, sorter)
 	case index.LONG:
-		return NewLongSorter(ProviderName, s.missingValue.(int64), s.reverse, sorter)
+		return NewLongSorter(ProviderName, missingValueOf[int64](s.missingValue), s.reverse, sorter)
 	case index.DOUBLE:
-		return NewDoubleSorter(ProviderName, s.missingValue.(float64), s.reverse, sorter)
+		return NewDoubleSorter(ProviderName, missingValueOf[float64](s.missingValue), s.reverse, sorter)
 	case index.FLOAT:
-		return NewFloatSorter(ProviderName, s.missingValue.(float32), s.reverse, sorter)
+		return NewFloatSorter(ProviderName, missingValueOf[float32](s.missingValue), s.reverse, sorter)
 	default:
 		return nil
 	}
 }
 
+// Returns the missing value of a numeric sort field, nil if it has none
+func missingValueOf[T int32 | int64 | float32 | float64](missingValue any) *T {
+	value, ok := missingValue.(T)
+	if !ok {
+		return nil
+	}
+	return &value
+}
+
 func (s *BaseSortField) Serialize(ctx context.Context, out store.DataOutput) error {
 	if err := out.WriteString(ctx, s.field); err != nil {
 		return err
@@ -243,7 +253,7 @@ func (s *BaseSortField) Serialize(ctx context.Context, out store.DataOutput) err
 		return err
 	}
 	switch s._type {
-	case index.SCORE:
+	case index.STRING:
 		switch s.missingValue.(string) {
 		case STRING_FIRST:
 			if err := out.WriteUint32(ctx, 1); err != nil {
@@ -351,7 +361,7 @@ func (s *sortFieldProvider) ReadSortField(ctx context.Context, in store.DataInpu
 			if err != nil {
 				return nil, err
 			}
-			if err := sf.SetMissingValue(num); err != nil {
+			if err := sf.SetMissingValue(int32(num)); err != nil {
 				return nil, err
 			}
 		case index.LONG:
@@ -359,7 +369,7 @@ func (s *sortFieldProvider) ReadSortField(ctx context.Context, in store.DataInpu
 			if err != nil {
 				return nil, err
 			}
-			if err := sf.SetMissingValue(num); err != nil {
+			if err := sf.SetMissingValue(int64(num)); err != nil {
 				return nil, err
 			}
 		case index.FLOAT:
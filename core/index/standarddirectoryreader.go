@@ -31,13 +31,15 @@ func NewStandardDirectoryReader(directory store.Directory, readers []index.Index
 		return nil, err
 	}
 
-	return &StandardDirectoryReader{
+	standardReader := &StandardDirectoryReader{
 		baseDirectoryReader: reader,
 		writer:              writer,
 		segmentInfos:        sis,
 		applyAllDeletes:     applyAllDeletes,
 		writeAllDeletes:     writeAllDeletes,
-	}, nil
+	}
+	standardReader.spi = standardReader
+	return standardReader, nil
 }
 
 type CompareIndexReader func(a, b index.IndexReader) int
@@ -137,6 +139,18 @@ func OpenStandardDirectoryReader(writer *IndexWriter, readerFunction func(index.
 	return result, nil
 }
 
+// DoClose
+// Releases the segment readers, they are closed once the writer and the other readers dropped them as well.
+func (s *StandardDirectoryReader) DoClose() error {
+	var errs []error
+	for _, reader := range s.GetSequentialSubReaders() {
+		if err := reader.DecRef(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (s *StandardDirectoryReader) GetVersion() int64 {
 	return s.segmentInfos.GetVersion()
 }
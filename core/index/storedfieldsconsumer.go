@@ -2,6 +2,8 @@ package index
 
 import (
 	"context"
+	"errors"
+
 	"github.com/geange/lucene-go/core/document"
 	"github.com/geange/lucene-go/core/interface/index"
 	"github.com/geange/lucene-go/core/store"
@@ -13,6 +15,10 @@ type StoredFieldsConsumer struct {
 	info    *SegmentInfo
 	writer  index.StoredFieldsWriter
 	lastDoc int
+
+	// the stored fields of a sorted segment are written to temporary files, then copied in sorted
+	// order on flush, nil if the segment is not sorted
+	tmpDirectory *TrackingTmpOutputDirectoryWrapper
 }
 
 func NewStoredFieldsConsumer(codec index.Codec, dir store.Directory, info *SegmentInfo) *StoredFieldsConsumer {
@@ -25,6 +31,16 @@ func NewStoredFieldsConsumer(codec index.Codec, dir store.Directory, info *Segme
 	}
 }
 
+// NewSortingStoredFieldsConsumer
+// Creates the StoredFieldsConsumer of a segment with an index sort, the documents are written in
+// the order of the index sort on flush.
+func NewSortingStoredFieldsConsumer(codec index.Codec, dir store.Directory, info *SegmentInfo) *StoredFieldsConsumer {
+	tmpDirectory := NewTrackingTmpOutputDirectoryWrapper(dir)
+	consumer := NewStoredFieldsConsumer(codec, tmpDirectory, info)
+	consumer.tmpDirectory = tmpDirectory
+	return consumer
+}
+
 func (s *StoredFieldsConsumer) writeField(ctx context.Context, info *document.FieldInfo, field document.IndexableField) error {
 	return s.writer.WriteField(ctx, info, field)
 }
@@ -83,7 +99,52 @@ func (s *StoredFieldsConsumer) Flush(ctx context.Context, state *index.SegmentWr
 	}
 
 	if err := s.writer.Finish(ctx, state.FieldInfos, maxDoc); err != nil {
+		return errors.Join(err, s.writer.Close())
+	}
+	if err := s.writer.Close(); err != nil {
+		return err
+	}
+
+	if s.tmpDirectory == nil {
+		return nil
+	}
+	return errors.Join(s.copySorted(ctx, state, sortMap), s.tmpDirectory.DeleteTemporaryFiles(ctx))
+}
+
+// Copies the stored fields written to the temporary files to the segment, in the order of sortMap
+func (s *StoredFieldsConsumer) copySorted(ctx context.Context, state *index.SegmentWriteState, sortMap index.DocMap) error {
+	maxDoc, err := state.SegmentInfo.MaxDoc()
+	if err != nil {
 		return err
 	}
-	return s.writer.Close()
+
+	reader, err := s.codec.StoredFieldsFormat().FieldsReader(ctx, s.tmpDirectory, state.SegmentInfo, state.FieldInfos, state.Context)
+	if err != nil {
+		return err
+	}
+	writer, err := s.codec.StoredFieldsFormat().FieldsWriter(ctx, state.Directory, state.SegmentInfo, state.Context)
+	if err != nil {
+		return errors.Join(err, reader.Close())
+	}
+
+	visitor := &storedFieldsMergeVisitor{ctx: ctx, writer: writer, fieldInfos: state.FieldInfos}
+	for docID := 0; docID < maxDoc; docID++ {
+		oldDocID := docID
+		if sortMap != nil {
+			oldDocID = sortMap.NewToOld(docID)
+		}
+		if err := writer.StartDocument(ctx); err != nil {
+			return errors.Join(err, writer.Close(), reader.Close())
+		}
+		if err := reader.VisitDocument(ctx, oldDocID, visitor); err != nil {
+			return errors.Join(err, writer.Close(), reader.Close())
+		}
+		if err := writer.FinishDocument(ctx); err != nil {
+			return errors.Join(err, writer.Close(), reader.Close())
+		}
+	}
+	if err := writer.Finish(ctx, state.FieldInfos, maxDoc); err != nil {
+		return errors.Join(err, writer.Close(), reader.Close())
+	}
+	return errors.Join(writer.Close(), reader.Close())
 }
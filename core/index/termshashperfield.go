@@ -261,7 +261,7 @@ func (t *baseTermsHashPerField) Add(termBytes []byte, docID int) error {
 		}
 	}
 	if t.doNextCall {
-		return t.nextPerField.Add2nd(termID, docID)
+		return t.nextPerField.Add2nd(t.postingsArray.GetTextStarts(termID), docID)
 	}
 	return nil
 }
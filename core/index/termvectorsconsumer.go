@@ -1,6 +1,9 @@
 package index
 
 import (
+	"context"
+	"errors"
+
 	"github.com/geange/lucene-go/core/document"
 	"github.com/geange/lucene-go/core/interface/index"
 	"github.com/geange/lucene-go/core/store"
@@ -21,6 +24,13 @@ type TermVectorsConsumer struct {
 	numVectorFields int
 	lastDocID       int
 	perFields       []*TermVectorsConsumerPerField
+
+	vectorSliceReaderPos *ByteSliceReader
+	vectorSliceReaderOff *ByteSliceReader
+
+	// the term vectors of a sorted segment are written to temporary files, then copied in sorted
+	// order on flush, nil if the segment is not sorted
+	tmpDirectory *TrackingTmpOutputDirectoryWrapper
 }
 
 func NewTermVectorsConsumer(intBlockAllocator ints.IntsAllocator,
@@ -29,13 +39,28 @@ func NewTermVectorsConsumer(intBlockAllocator ints.IntsAllocator,
 
 	termsHashDefault := NewTermsHashDefault(intBlockAllocator, byteBlockAllocator, nil)
 	return &TermVectorsConsumer{
-		BaseTermsHash: termsHashDefault,
-		directory:     directory,
-		info:          info,
-		codec:         codec,
+		BaseTermsHash:        termsHashDefault,
+		directory:            directory,
+		info:                 info,
+		codec:                codec,
+		vectorSliceReaderPos: NewByteSliceReader(),
+		vectorSliceReaderOff: NewByteSliceReader(),
 	}
 }
 
+// NewSortingTermVectorsConsumer
+// Creates the TermVectorsConsumer of a segment with an index sort, the documents are written in
+// the order of the index sort on flush.
+func NewSortingTermVectorsConsumer(intBlockAllocator ints.IntsAllocator,
+	byteBlockAllocator bytesref.Allocator, directory store.Directory,
+	info *SegmentInfo, codec index.Codec) *TermVectorsConsumer {
+
+	tmpDirectory := NewTrackingTmpOutputDirectoryWrapper(directory)
+	consumer := NewTermVectorsConsumer(intBlockAllocator, byteBlockAllocator, tmpDirectory, info, codec)
+	consumer.tmpDirectory = tmpDirectory
+	return consumer
+}
+
 func (t *TermVectorsConsumer) SetTermBytePool(termBytePool *bytesref.BlockPool) {
 	t.termBytePool = termBytePool
 }
@@ -43,20 +68,74 @@ func (t *TermVectorsConsumer) SetTermBytePool(termBytePool *bytesref.BlockPool)
 func (t *TermVectorsConsumer) Flush(fieldsToFlush map[string]TermsHashPerField,
 	state *index.SegmentWriteState, sortMap index.DocMap, norms index.NormsProducer) error {
 
-	if t.writer != nil {
-		numDocs, err := state.SegmentInfo.MaxDoc()
-		if err != nil {
-			return err
+	if t.writer == nil {
+		return nil
+	}
+
+	writer := t.writer
+	defer func() {
+		t.writer = nil
+		t.lastDocID = 0
+		t.hasVectors = false
+	}()
+
+	numDocs, err := state.SegmentInfo.MaxDoc()
+	if err != nil {
+		writer.Close()
+		return err
+	}
+	if err := t.fill(numDocs); err != nil {
+		writer.Close()
+		return err
+	}
+	if err := writer.Finish(nil, state.FieldInfos, numDocs); err != nil {
+		writer.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	if t.tmpDirectory == nil {
+		return nil
+	}
+	ctx := context.Background()
+	return errors.Join(t.copySorted(ctx, state, sortMap), t.tmpDirectory.DeleteTemporaryFiles(ctx))
+}
+
+// Copies the term vectors written to the temporary files to the segment, in the order of sortMap
+func (t *TermVectorsConsumer) copySorted(ctx context.Context, state *index.SegmentWriteState, sortMap index.DocMap) error {
+	maxDoc, err := state.SegmentInfo.MaxDoc()
+	if err != nil {
+		return err
+	}
+
+	reader, err := t.codec.TermVectorsFormat().VectorsReader(ctx, t.tmpDirectory, state.SegmentInfo, state.FieldInfos, state.Context)
+	if err != nil {
+		return err
+	}
+	writer, err := t.codec.TermVectorsFormat().VectorsWriter(ctx, state.Directory, state.SegmentInfo, state.Context)
+	if err != nil {
+		return errors.Join(err, reader.Close())
+	}
+
+	for docID := 0; docID < maxDoc; docID++ {
+		oldDocID := docID
+		if sortMap != nil {
+			oldDocID = sortMap.NewToOld(docID)
 		}
-		if err := t.fill(numDocs); err != nil {
-			return err
+		vectors, err := reader.Get(ctx, oldDocID)
+		if err != nil {
+			return errors.Join(err, writer.Close(), reader.Close())
 		}
-		if err := t.writer.Finish(nil, state.FieldInfos, numDocs); err != nil {
-			return err
+		if err := addAllDocVectors(ctx, writer, state.FieldInfos, vectors); err != nil {
+			return errors.Join(err, writer.Close(), reader.Close())
 		}
-		return t.writer.Close()
 	}
-	return nil
+	if err := writer.Finish(ctx, state.FieldInfos, maxDoc); err != nil {
+		return errors.Join(err, writer.Close(), reader.Close())
+	}
+	return errors.Join(writer.Close(), reader.Close())
 }
 
 // Fills in no-term-vectors for all docs we haven't seen since the last doc that had term vectors.
@@ -93,6 +172,7 @@ func (t *TermVectorsConsumer) AddField(invertState *index.FieldInvertState,
 
 func (t *TermVectorsConsumer) addFieldToFlush(fieldToFlush *TermVectorsConsumerPerField) error {
 	t.perFields = append(t.perFields, fieldToFlush)
+	t.numVectorFields++
 	return nil
 }
 
@@ -112,14 +192,22 @@ func (t *TermVectorsConsumer) FinishDocument(docID int) error {
 		return err
 	}
 
-	t.writer.StartDocument(nil, t.numVectorFields)
+	if err := t.writer.StartDocument(nil, t.numVectorFields); err != nil {
+		return err
+	}
 	for i := 0; i < t.numVectorFields; i++ {
-		t.perFields[i].FinishDocument()
+		if err := t.perFields[i].FinishDocument(nil); err != nil {
+			return err
+		}
+	}
+	if err := t.writer.FinishDocument(nil); err != nil {
+		return err
 	}
-	t.writer.FinishDocument(nil)
 
 	t.lastDocID++
-	t.Reset()
+	if err := t.Reset(); err != nil {
+		return err
+	}
 	t.resetFields()
 	return nil
 }
@@ -1,6 +1,7 @@
 package index
 
 import (
+	"context"
 	"fmt"
 	"github.com/geange/lucene-go/core/interface/index"
 	"sort"
@@ -89,8 +90,117 @@ func (t *TermVectorsConsumerPerField) Finish() error {
 	return t.termsWriter.addFieldToFlush(t)
 }
 
-func (t *TermVectorsConsumerPerField) FinishDocument() error {
-	panic("")
+// FinishDocument is called once, after inverting all occurrences of the field in the document,
+// and writes the terms of the field to the TermVectorsWriter.
+func (t *TermVectorsConsumerPerField) FinishDocument(ctx context.Context) error {
+	if !t.doVectors {
+		return nil
+	}
+	t.doVectors = false
+
+	numPostings := t.getNumTerms()
+	postings := t.termVectorsPostingsArray
+	tv := t.termsWriter.writer
+
+	t.sortTerms()
+	termIDs := t.getSortedTermIDs()
+
+	if err := tv.StartField(ctx, t.fieldInfo, numPostings, t.doVectorPositions, t.doVectorOffsets, t.hasPayloads); err != nil {
+		return err
+	}
+
+	var posReader, offReader *ByteSliceReader
+	if t.doVectorPositions {
+		posReader = t.termsWriter.vectorSliceReaderPos
+	}
+	if t.doVectorOffsets {
+		offReader = t.termsWriter.vectorSliceReaderOff
+	}
+
+	for _, termID := range termIDs[:numPostings] {
+		freq := postings.freqs[termID]
+		term := t.termBytePool.GetBytes(postings.textStarts[termID])
+		if err := tv.StartTerm(ctx, term, freq); err != nil {
+			return err
+		}
+
+		if t.doVectorPositions || t.doVectorOffsets {
+			if posReader != nil {
+				if err := t.initReader(posReader, termID, 0); err != nil {
+					return err
+				}
+			}
+			if offReader != nil {
+				if err := t.initReader(offReader, termID, 1); err != nil {
+					return err
+				}
+			}
+			if err := addProx(ctx, tv, freq, posReader, offReader); err != nil {
+				return err
+			}
+		}
+
+		if err := tv.FinishTerm(ctx); err != nil {
+			return err
+		}
+	}
+	if err := tv.FinishField(ctx); err != nil {
+		return err
+	}
+
+	if err := t.Reset(); err != nil {
+		return err
+	}
+	t.fieldInfo.SetStoreTermVectors()
+	return nil
+}
+
+// addProx decodes the positions and offsets of a term buffered in the byte slices and adds them to the writer
+func addProx(ctx context.Context, tv index.TermVectorsWriter, numProx int, positions, offsets *ByteSliceReader) error {
+	position, lastOffset := 0, 0
+
+	for i := 0; i < numProx; i++ {
+		var payload []byte
+		if positions == nil {
+			position = -1
+		} else {
+			code, err := positions.ReadUvarint(ctx)
+			if err != nil {
+				return err
+			}
+			position += int(code >> 1)
+			if code&1 != 0 {
+				payloadLength, err := positions.ReadUvarint(ctx)
+				if err != nil {
+					return err
+				}
+				payload = make([]byte, payloadLength)
+				if _, err := positions.Read(payload); err != nil {
+					return err
+				}
+			}
+		}
+
+		startOffset, endOffset := -1, -1
+		if offsets != nil {
+			delta, err := offsets.ReadUvarint(ctx)
+			if err != nil {
+				return err
+			}
+			length, err := offsets.ReadUvarint(ctx)
+			if err != nil {
+				return err
+			}
+			startOffset = lastOffset + int(delta)
+			endOffset = startOffset + int(length)
+			lastOffset = endOffset
+		}
+
+		if err := tv.AddPosition(ctx, position, startOffset, endOffset, payload); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (t *TermVectorsConsumerPerField) Start(field document.IndexableField, first bool) bool {
@@ -106,6 +216,8 @@ func (t *TermVectorsConsumerPerField) Start(field document.IndexableField, first
 			t.Reset()
 		}
 
+		t.reinitHash()
+
 		t.hasPayloads = false
 
 		t.doVectors = field.FieldType().StoreTermVectors()
@@ -181,7 +293,7 @@ func (t *TermVectorsConsumerPerField) Start(field document.IndexableField, first
 }
 
 func (t *TermVectorsConsumerPerField) NewPostingsArray() {
-	t.termVectorsPostingsArray = t.postingsArray.(*TermVectorsPostingsArray)
+	t.termVectorsPostingsArray, _ = t.postingsArray.(*TermVectorsPostingsArray)
 }
 
 func (t *TermVectorsConsumerPerField) CreatePostingsArray(size int) ParallelPostingsArray {
@@ -0,0 +1,214 @@
+package index_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/geange/lucene-go/codecs/lucene87"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/search"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util"
+	"github.com/geange/lucene-go/core/util/version"
+	"github.com/stretchr/testify/assert"
+)
+
+type testMergeContext struct {
+	deletes map[index.SegmentCommitInfo]int
+	merging []index.SegmentCommitInfo
+}
+
+func (c *testMergeContext) NumDeletesToMerge(info index.SegmentCommitInfo) (int, error) {
+	return c.deletes[info], nil
+}
+
+func (c *testMergeContext) NumDeletedDocs(info index.SegmentCommitInfo) (int, error) {
+	return c.deletes[info], nil
+}
+
+func (c *testMergeContext) GetMergingSegments() []index.SegmentCommitInfo {
+	return c.merging
+}
+
+// adds a segment of 100 documents whose single file has the given size
+func addTestSegment(t *testing.T, dir store.Directory, infos *coreIndex.SegmentInfos, sizeInBytes int) index.SegmentCommitInfo {
+	ctx := context.Background()
+	name := "_" + strconv.FormatInt(int64(infos.Size()), 36)
+	out, err := dir.CreateOutput(ctx, name+".fdt")
+	assert.Nil(t, err)
+	_, err = out.Write(make([]byte, sizeInBytes))
+	assert.Nil(t, err)
+	assert.Nil(t, out.Close())
+
+	si := coreIndex.NewSegmentInfo(dir, version.Last, version.Last, name, 100, false,
+		lucene87.NewCodec(lucene87.BEST_SPEED), map[string]string{}, util.RandomId(), map[string]string{}, nil)
+	si.SetFiles(map[string]struct{}{name + ".fdt": {}})
+	info := index.NewSegmentCommitInfo(si, 0, 0, -1, -1, -1, util.RandomId())
+	assert.Nil(t, infos.Add(info))
+	return info
+}
+
+func newTestSegmentInfos(t *testing.T, sizes ...int) *coreIndex.SegmentInfos {
+	dir := store.NewByteBuffersDirectory()
+	infos := coreIndex.NewSegmentInfos(int(version.Last.Major()))
+	for _, size := range sizes {
+		addTestSegment(t, dir, infos, size)
+	}
+	return infos
+}
+
+func repeatSize(size, n int) []int {
+	sizes := make([]int, n)
+	for i := range sizes {
+		sizes[i] = size
+	}
+	return sizes
+}
+
+func TestTieredMergePolicy_FindMerges(t *testing.T) {
+	const mb = 1024 * 1024
+	policy := coreIndex.NewTieredMergePolicy()
+	mergeContext := &testMergeContext{}
+
+	// the index is under budget
+	infos := newTestSegmentInfos(t, repeatSize(mb, 10)...)
+	spec, err := policy.FindMerges(coreIndex.MERGE_TRIGGER_EXPLICIT, infos, mergeContext)
+	assert.Nil(t, err)
+	assert.Nil(t, spec)
+
+	// the 20 segments are below the floor size, a single merge brings the index back under budget
+	infos = newTestSegmentInfos(t, repeatSize(mb, 20)...)
+	spec, err = policy.FindMerges(coreIndex.MERGE_TRIGGER_EXPLICIT, infos, mergeContext)
+	assert.Nil(t, err)
+	assert.Len(t, spec.Merges(), 1)
+	assert.Len(t, spec.Merges()[0].Segments(), 10)
+	assert.Equal(t, int64(1000), spec.Merges()[0].TotalMaxDoc())
+
+	// the segments which are already merging are not selected again
+	mergeContext.merging = spec.Merges()[0].Segments()
+	spec, err = policy.FindMerges(coreIndex.MERGE_TRIGGER_EXPLICIT, infos, mergeContext)
+	assert.Nil(t, err)
+	assert.Nil(t, spec)
+
+	// the merged segments are not larger than maxMergedSegmentMB
+	mergeContext.merging = nil
+	assert.Nil(t, policy.SetMaxMergedSegmentMB(3))
+	spec, err = policy.FindMerges(coreIndex.MERGE_TRIGGER_EXPLICIT, infos, mergeContext)
+	assert.Nil(t, err)
+	assert.Len(t, spec.Merges(), 4)
+	for _, merge := range spec.Merges() {
+		assert.Len(t, merge.Segments(), 3)
+	}
+}
+
+func TestTieredMergePolicy_FindMergesDeletes(t *testing.T) {
+	const mb = 1024 * 1024
+	policy := coreIndex.NewTieredMergePolicy()
+	assert.Nil(t, policy.SetMaxMergedSegmentMB(10))
+
+	// the large segments are not merged while the index has few deletes
+	infos := newTestSegmentInfos(t, repeatSize(8*mb, 5)...)
+	mergeContext := &testMergeContext{deletes: map[index.SegmentCommitInfo]int{infos.Info(0): 10}}
+	spec, err := policy.FindMerges(coreIndex.MERGE_TRIGGER_EXPLICIT, infos, mergeContext)
+	assert.Nil(t, err)
+	assert.Nil(t, spec)
+
+	// over deletesPctAllowed, only the segments with many deletes are merged
+	mergeContext.deletes[infos.Info(1)] = 60
+	mergeContext.deletes[infos.Info(2)] = 60
+	mergeContext.deletes[infos.Info(3)] = 60
+	spec, err = policy.FindMerges(coreIndex.MERGE_TRIGGER_EXPLICIT, infos, mergeContext)
+	assert.Nil(t, err)
+	assert.NotNil(t, spec)
+	for _, merge := range spec.Merges() {
+		for _, info := range merge.Segments() {
+			assert.Equal(t, 60, mergeContext.deletes[info])
+		}
+	}
+}
+
+func TestTieredMergePolicy_FindForcedMerges(t *testing.T) {
+	const mb = 1024 * 1024
+	policy := coreIndex.NewTieredMergePolicy()
+	mergeContext := &testMergeContext{}
+
+	infos := newTestSegmentInfos(t, 4*mb, 3*mb, 2*mb, mb, mb)
+	segmentsToMerge := make(map[index.SegmentCommitInfo]bool)
+	for _, info := range infos.AsList() {
+		segmentsToMerge[info] = true
+	}
+
+	// merges down to a single segment
+	spec, err := policy.FindForcedMerges(infos, 1, segmentsToMerge, mergeContext)
+	assert.Nil(t, err)
+	assert.Len(t, spec.Merges(), 1)
+	assert.ElementsMatch(t, infos.AsList(), spec.Merges()[0].Segments())
+
+	// nothing to do if the index already has less segments than requested
+	spec, err = policy.FindForcedMerges(infos, 5, segmentsToMerge, mergeContext)
+	assert.Nil(t, err)
+	assert.Nil(t, spec)
+
+	// the merged segments are at most 25% larger than the ideal size for the requested count,
+	// so the largest segment is not rewritten
+	assert.Nil(t, policy.SetMaxMergedSegmentMB(1))
+	spec, err = policy.FindForcedMerges(infos, 3, segmentsToMerge, mergeContext)
+	assert.Nil(t, err)
+	assert.Len(t, spec.Merges(), 2)
+	for _, merge := range spec.Merges() {
+		assert.Len(t, merge.Segments(), 2)
+		assert.NotContains(t, merge.Segments(), infos.Info(0))
+	}
+
+	// a single segment without deletes is already merged
+	infos = newTestSegmentInfos(t, mb)
+	spec, err = policy.FindForcedMerges(infos, 1, map[index.SegmentCommitInfo]bool{infos.Info(0): true}, mergeContext)
+	assert.Nil(t, err)
+	assert.Nil(t, spec)
+}
+
+func TestTieredMergePolicy_FindForcedDeletesMerges(t *testing.T) {
+	const mb = 1024 * 1024
+	policy := coreIndex.NewTieredMergePolicy()
+
+	infos := newTestSegmentInfos(t, mb, mb, mb, mb)
+	mergeContext := &testMergeContext{deletes: map[index.SegmentCommitInfo]int{
+		infos.Info(0): 5,
+		infos.Info(1): 50,
+		infos.Info(2): 20,
+	}}
+
+	// only the segments over forceMergeDeletesPctAllowed are merged
+	spec, err := policy.FindForcedDeletesMerges(infos, mergeContext)
+	assert.Nil(t, err)
+	assert.Len(t, spec.Merges(), 1)
+	assert.ElementsMatch(t, []index.SegmentCommitInfo{infos.Info(1), infos.Info(2)}, spec.Merges()[0].Segments())
+
+	assert.Nil(t, policy.SetForceMergeDeletesPctAllowed(50))
+	spec, err = policy.FindForcedDeletesMerges(infos, mergeContext)
+	assert.Nil(t, err)
+	assert.Nil(t, spec)
+}
+
+func TestTieredMergePolicy_Settings(t *testing.T) {
+	policy := coreIndex.NewTieredMergePolicy()
+	assert.Equal(t, 5*1024.0, policy.GetMaxMergedSegmentMB())
+	assert.Equal(t, 10.0, policy.GetSegmentsPerTier())
+	assert.Equal(t, 2.0, policy.GetFloorSegmentMB())
+	assert.Equal(t, 33.0, policy.GetDeletesPctAllowed())
+
+	assert.NotNil(t, policy.SetMaxMergedSegmentMB(-1))
+	assert.NotNil(t, policy.SetSegmentsPerTier(1))
+	assert.NotNil(t, policy.SetFloorSegmentMB(0))
+	assert.NotNil(t, policy.SetDeletesPctAllowed(10))
+	assert.Nil(t, policy.SetDeletesPctAllowed(20))
+	assert.Equal(t, 20.0, policy.GetDeletesPctAllowed())
+
+	// the TieredMergePolicy is the default merge policy
+	similarity, err := search.NewBM25Similarity()
+	assert.Nil(t, err)
+	config := coreIndex.NewIndexWriterConfig(lucene87.NewCodec(lucene87.BEST_SPEED), similarity)
+	assert.IsType(t, &coreIndex.TieredMergePolicy{}, config.GetMergePolicy())
+}
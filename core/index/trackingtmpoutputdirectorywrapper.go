@@ -0,0 +1,95 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/geange/lucene-go/core/store"
+)
+
+var _ store.Directory = &TrackingTmpOutputDirectoryWrapper{}
+var _ store.ContextOutputCreator = &TrackingTmpOutputDirectoryWrapper{}
+var _ store.ContextInputOpener = &TrackingTmpOutputDirectoryWrapper{}
+
+// TrackingTmpOutputDirectoryWrapper
+// Writes the files it is asked to create as temporary files of the wrapped directory, and opens the
+// temporary file of a name when asked to read it. The flush of a sorted segment writes its stored
+// fields and term vectors through it before copying them in sorted order.
+type TrackingTmpOutputDirectoryWrapper struct {
+	store.Directory
+
+	// maps the names of the created files to the names of their temporary files
+	fileNames map[string]string
+}
+
+func NewTrackingTmpOutputDirectoryWrapper(directory store.Directory) *TrackingTmpOutputDirectoryWrapper {
+	return &TrackingTmpOutputDirectoryWrapper{
+		Directory: directory,
+		fileNames: make(map[string]string),
+	}
+}
+
+func (t *TrackingTmpOutputDirectoryWrapper) CreateOutput(ctx context.Context, name string) (store.IndexOutput, error) {
+	output, err := t.Directory.CreateTempOutput(ctx, name, "")
+	if err != nil {
+		return nil, err
+	}
+	t.fileNames[name] = output.GetName()
+	return output, nil
+}
+
+func (t *TrackingTmpOutputDirectoryWrapper) CreateOutputWithContext(ctx context.Context, name string, ioContext *store.IOContext) (store.IndexOutput, error) {
+	return t.CreateOutput(ctx, name)
+}
+
+func (t *TrackingTmpOutputDirectoryWrapper) OpenInput(ctx context.Context, name string) (store.IndexInput, error) {
+	tmpName, err := t.tmpName(name)
+	if err != nil {
+		return nil, err
+	}
+	return t.Directory.OpenInput(ctx, tmpName)
+}
+
+func (t *TrackingTmpOutputDirectoryWrapper) OpenInputWithContext(ctx context.Context, name string, ioContext *store.IOContext) (store.IndexInput, error) {
+	tmpName, err := t.tmpName(name)
+	if err != nil {
+		return nil, err
+	}
+	return store.OpenInputWithContext(ctx, t.Directory, tmpName, ioContext)
+}
+
+func (t *TrackingTmpOutputDirectoryWrapper) FileLength(ctx context.Context, name string) (int64, error) {
+	tmpName, err := t.tmpName(name)
+	if err != nil {
+		return 0, err
+	}
+	return t.Directory.FileLength(ctx, tmpName)
+}
+
+func (t *TrackingTmpOutputDirectoryWrapper) tmpName(name string) (string, error) {
+	tmpName, ok := t.fileNames[name]
+	if !ok {
+		return "", fmt.Errorf("file %s was not created through this directory", name)
+	}
+	return tmpName, nil
+}
+
+// GetTemporaryFiles
+// Returns the names of the created files mapped to the names of their temporary files
+func (t *TrackingTmpOutputDirectoryWrapper) GetTemporaryFiles() map[string]string {
+	return t.fileNames
+}
+
+// DeleteTemporaryFiles
+// Deletes the temporary files, they are forgotten even if their deletion fails
+func (t *TrackingTmpOutputDirectoryWrapper) DeleteTemporaryFiles(ctx context.Context) error {
+	var err error
+	for name, tmpName := range maps.Clone(t.fileNames) {
+		if deleteErr := t.Directory.DeleteFile(ctx, tmpName); deleteErr != nil && err == nil {
+			err = deleteErr
+		}
+		delete(t.fileNames, name)
+	}
+	return err
+}
@@ -0,0 +1,293 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/core/util"
+)
+
+// VectorValuesWriter Buffers up pending vector value(s) per doc, then flushes when segment flushes.
+type VectorValuesWriter struct {
+	fieldInfo *document.FieldInfo
+	docIDs    []int
+	vectors   [][]float32
+	lastDocID int
+}
+
+func NewVectorValuesWriter(fieldInfo *document.FieldInfo) *VectorValuesWriter {
+	return &VectorValuesWriter{
+		fieldInfo: fieldInfo,
+		docIDs:    make([]int, 0, 16),
+		vectors:   make([][]float32, 0, 16),
+		lastDocID: -1,
+	}
+}
+
+// AddValue Adds a value for the given document. Only a single value may be added.
+// docID: the value is added to this document
+// vectorValue: the value to add
+func (v *VectorValuesWriter) AddValue(docID int, vectorValue []float32) error {
+	if docID == v.lastDocID {
+		return fmt.Errorf(`VectorValuesField "%s" appears more than once in this document (only one value is allowed per field)`,
+			v.fieldInfo.Name())
+	}
+	if len(vectorValue) != v.fieldInfo.GetVectorDimension() {
+		return fmt.Errorf(`attempt to index a vector of dimension %d but "%s" has dimension %d`,
+			len(vectorValue), v.fieldInfo.Name(), v.fieldInfo.GetVectorDimension())
+	}
+	if v.fieldInfo.GetVectorSimilarityFunction() == document.VECTOR_SIMILARITY_FUNCTION_COSINE &&
+		util.DotProduct(vectorValue, vectorValue) == 0 {
+		// the cosine similarity of a zero vector is undefined
+		return fmt.Errorf(`attempt to index a zero vector into "%s", which uses the COSINE similarity`,
+			v.fieldInfo.Name())
+	}
+
+	// the caller may reuse the value, so keep a copy
+	value := make([]float32, len(vectorValue))
+	copy(value, vectorValue)
+	v.docIDs = append(v.docIDs, docID)
+	v.vectors = append(v.vectors, value)
+	v.lastDocID = docID
+	return nil
+}
+
+// Flush Flush this field's values to storage, sorting the values in accordance with sortMap
+// sortMap: specifies the order of documents being flushed, or null if they are to be flushed in docid order
+// knnVectorsWriter: the Codec's vector writer that handles the actual encoding and I/O
+func (v *VectorValuesWriter) Flush(ctx context.Context, sortMap index.DocMap, knnVectorsWriter index.KnnVectorsWriter) error {
+	values := newBufferedVectorValues(v.fieldInfo.GetVectorDimension(), v.docIDs, v.vectors)
+	if sortMap != nil {
+		values = values.sort(sortMap)
+	}
+	return knnVectorsWriter.WriteField(ctx, v.fieldInfo, &vectorValuesReader{
+		field:  v.fieldInfo.Name(),
+		values: values,
+	})
+}
+
+var _ index.KnnVectorsReader = &vectorValuesReader{}
+
+// vectorValuesReader presents the vectors of a single field to a KnnVectorsWriter
+type vectorValuesReader struct {
+	field  string
+	values *bufferedVectorValues
+}
+
+func (r *vectorValuesReader) Close() error {
+	return nil
+}
+
+func (r *vectorValuesReader) CheckIntegrity() error {
+	return nil
+}
+
+func (r *vectorValuesReader) GetVectorValues(field string) (index.VectorValues, error) {
+	if field != r.field {
+		return nil, fmt.Errorf("field %s has no vectors", field)
+	}
+	return r.values.copy(), nil
+}
+
+func (r *vectorValuesReader) Search(ctx context.Context, field string, target []float32, k int, acceptDocs util.Bits) (index.TopDocs, error) {
+	return nil, errors.New("unsupported operation")
+}
+
+var _ index.VectorValues = &bufferedVectorValues{}
+var _ index.RandomAccessVectorValues = &bufferedVectorValues{}
+var _ index.RandomAccessVectorValuesProducer = &bufferedVectorValues{}
+
+// bufferedVectorValues the buffered vectors of a field, in increasing docID order
+type bufferedVectorValues struct {
+	dimension int
+	docIDs    []int
+	vectors   [][]float32
+	ord       int
+	doc       int
+}
+
+func newBufferedVectorValues(dimension int, docIDs []int, vectors [][]float32) *bufferedVectorValues {
+	return &bufferedVectorValues{
+		dimension: dimension,
+		docIDs:    docIDs,
+		vectors:   vectors,
+		ord:       -1,
+		doc:       -1,
+	}
+}
+
+// sort returns the values in the order of the new document IDs
+func (b *bufferedVectorValues) sort(sortMap index.DocMap) *bufferedVectorValues {
+	ords := make([]int, len(b.docIDs))
+	for i := range ords {
+		ords[i] = i
+	}
+	sort.Slice(ords, func(i, j int) bool {
+		return sortMap.OldToNew(b.docIDs[ords[i]]) < sortMap.OldToNew(b.docIDs[ords[j]])
+	})
+
+	docIDs := make([]int, len(ords))
+	vectors := make([][]float32, len(ords))
+	for i, ord := range ords {
+		docIDs[i] = sortMap.OldToNew(b.docIDs[ord])
+		vectors[i] = b.vectors[ord]
+	}
+	return newBufferedVectorValues(b.dimension, docIDs, vectors)
+}
+
+func (b *bufferedVectorValues) copy() *bufferedVectorValues {
+	return newBufferedVectorValues(b.dimension, b.docIDs, b.vectors)
+}
+
+func (b *bufferedVectorValues) RandomAccess() (index.RandomAccessVectorValues, error) {
+	return b.copy(), nil
+}
+
+func (b *bufferedVectorValues) Dimension() int {
+	return b.dimension
+}
+
+func (b *bufferedVectorValues) Size() int {
+	return len(b.docIDs)
+}
+
+func (b *bufferedVectorValues) VectorValue() ([]float32, error) {
+	if b.ord < 0 || b.ord >= len(b.docIDs) {
+		return nil, errors.New("the iterator is not positioned on a document")
+	}
+	return b.vectors[b.ord], nil
+}
+
+func (b *bufferedVectorValues) VectorValueByOrd(targetOrd int) ([]float32, error) {
+	return b.vectors[targetOrd], nil
+}
+
+func (b *bufferedVectorValues) DocID() int {
+	return b.doc
+}
+
+func (b *bufferedVectorValues) NextDoc() (int, error) {
+	b.ord++
+	if b.ord >= len(b.docIDs) {
+		b.ord = len(b.docIDs)
+		b.doc = types.NO_MORE_DOCS
+		return types.NO_MORE_DOCS, io.EOF
+	}
+	b.doc = b.docIDs[b.ord]
+	return b.doc, nil
+}
+
+func (b *bufferedVectorValues) Advance(target int) (int, error) {
+	for b.doc < target {
+		if _, err := b.NextDoc(); err != nil {
+			return types.NO_MORE_DOCS, err
+		}
+	}
+	return b.doc, nil
+}
+
+func (b *bufferedVectorValues) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(b, target)
+}
+
+func (b *bufferedVectorValues) Cost() int64 {
+	return int64(len(b.docIDs))
+}
+
+// MergeKnnVectors Merges the vectors of every field of mergeState.MergeFieldInfos having vectors,
+// skipping deleted documents and mapping the others to their document IDs in the merged segment.
+// The writer builds a new graph per field from the merged values.
+func MergeKnnVectors(ctx context.Context, writer index.KnnVectorsWriter, mergeState *MergeState) error {
+	for _, fieldInfo := range mergeState.MergeFieldInfos.List() {
+		if !fieldInfo.HasVectorValues() {
+			continue
+		}
+		values, err := mergeVectorValues(fieldInfo, mergeState)
+		if err != nil {
+			return err
+		}
+		if err := writer.WriteField(ctx, fieldInfo, &vectorValuesReader{
+			field:  fieldInfo.Name(),
+			values: values,
+		}); err != nil {
+			return err
+		}
+	}
+	return writer.Finish()
+}
+
+// mergeVectorValues collects the live vectors of a field from all the merged segments, in the
+// order of the merged segment
+func mergeVectorValues(fieldInfo *document.FieldInfo, mergeState *MergeState) (*bufferedVectorValues, error) {
+	type mappedVector struct {
+		doc    int
+		vector []float32
+	}
+
+	mapped := make([]mappedVector, 0)
+	for i, reader := range mergeState.KnnVectorsReaders {
+		if reader == nil {
+			continue
+		}
+		if info := mergeState.FieldInfos[i].FieldInfo(fieldInfo.Name()); info == nil || !info.HasVectorValues() {
+			continue
+		}
+		values, err := reader.GetVectorValues(fieldInfo.Name())
+		if err != nil {
+			return nil, err
+		}
+		if values == nil {
+			continue
+		}
+		if values.Dimension() != fieldInfo.GetVectorDimension() {
+			return nil, fmt.Errorf(`field "%s" has vector dimension %d in segment %d, expected %d`,
+				fieldInfo.Name(), values.Dimension(), i, fieldInfo.GetVectorDimension())
+		}
+
+		docMap := mergeState.DocMaps[i]
+		for {
+			doc, err := values.NextDoc()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return nil, err
+			}
+			if doc == types.NO_MORE_DOCS {
+				break
+			}
+
+			newDoc := docMap.Get(doc)
+			if newDoc == -1 {
+				// deleted document
+				continue
+			}
+			value, err := values.VectorValue()
+			if err != nil {
+				return nil, err
+			}
+			vector := make([]float32, len(value))
+			copy(vector, value)
+			mapped = append(mapped, mappedVector{doc: newDoc, vector: vector})
+		}
+	}
+
+	// an index sort may interleave the documents of the merged segments
+	sort.SliceStable(mapped, func(i, j int) bool {
+		return mapped[i].doc < mapped[j].doc
+	})
+
+	docIDs := make([]int, len(mapped))
+	vectors := make([][]float32, len(mapped))
+	for i, v := range mapped {
+		docIDs[i] = v.doc
+		vectors[i] = v.vector
+	}
+	return newBufferedVectorValues(fieldInfo.GetVectorDimension(), docIDs, vectors), nil
+}
@@ -1,10 +1,11 @@
 package index
 
 import (
+	"cmp"
+	"fmt"
 	"sync/atomic"
 
 	"github.com/geange/gods-generic/maps/treemap"
-	"github.com/geange/lucene-go/core/util/hash"
 )
 
 // BufferedUpdates
@@ -52,8 +53,9 @@ func NewBufferedUpdates(options ...BufferedUpdatesOption) *BufferedUpdates {
 		numTermDeletes:  new(atomic.Int64),
 		numFieldUpdates: new(atomic.Int64),
 		deleteTerms:     treemap.NewWith[Term, int](TermCompare),
+		fieldUpdates:    map[string]*FieldUpdatesBuffer{},
 		segmentName:     opt.segmentName,
-		deleteQueries:   treemap.NewWith[Query, int](hash.Compare[Query]),
+		deleteQueries:   treemap.NewWith[Query, int](compareQuery),
 	}
 }
 
@@ -79,23 +81,30 @@ func (b *BufferedUpdates) AddTerm(term Term, docIDUpto int) {
 	b.numTermDeletes.Add(1)
 }
 
-func (b *BufferedUpdates) AddNumericUpdate(update *NumericDocValuesUpdate, docIDUpto int) error {
-	field := update.GetField()
-	buffer, ok := b.fieldUpdates[field]
-	if !ok {
-		newBuffer := NewNumberFieldUpdatesBuffer(update, docIDUpto)
-		b.fieldUpdates[field] = newBuffer
-		buffer = newBuffer
+// compareQuery Orders the queries by type and then by their string representation, the same
+// query added twice is buffered once.
+func compareQuery(a, b Query) int {
+	if c := cmp.Compare(fmt.Sprintf("%T", a), fmt.Sprintf("%T", b)); c != 0 {
+		return c
 	}
+	return cmp.Compare(a.String(""), b.String(""))
+}
 
-	if update.HasValue() {
-		err := buffer.addUpdateInt(update.term, update.GetValue(), docIDUpto)
-		if err != nil {
+func (b *BufferedUpdates) AddQuery(query Query, docIDUpto int) {
+	b.deleteQueries.Put(query, docIDUpto)
+}
+
+func (b *BufferedUpdates) AddNumericUpdate(update *NumericDocValuesUpdate, docIDUpto int) error {
+	field := update.GetField()
+	if buffer, ok := b.fieldUpdates[field]; !ok {
+		// the buffer holds the first update
+		b.fieldUpdates[field] = NewNumberFieldUpdatesBuffer(update, docIDUpto)
+	} else if update.HasValue() {
+		if err := buffer.addUpdateInt(update.term, update.GetValue(), docIDUpto); err != nil {
 			return err
 		}
 	} else {
-		err := buffer.addNoValue(update.term, docIDUpto)
-		if err != nil {
+		if err := buffer.addNoValue(update.term, docIDUpto); err != nil {
 			return err
 		}
 	}
@@ -105,21 +114,15 @@ func (b *BufferedUpdates) AddNumericUpdate(update *NumericDocValuesUpdate, docID
 
 func (b *BufferedUpdates) AddBinaryUpdate(update *BinaryDocValuesUpdate, docIDUpto int) error {
 	field := update.GetField()
-	buffer, ok := b.fieldUpdates[field]
-	if !ok {
-		newBuffer := NewBinaryFieldUpdatesBuffer(update, docIDUpto)
-		b.fieldUpdates[field] = newBuffer
-		buffer = newBuffer
-	}
-
-	if update.HasValue() {
-		err := buffer.addUpdateBytes(update.term, update.GetValue(), docIDUpto)
-		if err != nil {
+	if buffer, ok := b.fieldUpdates[field]; !ok {
+		// the buffer holds the first update
+		b.fieldUpdates[field] = NewBinaryFieldUpdatesBuffer(update, docIDUpto)
+	} else if update.HasValue() {
+		if err := buffer.addUpdateBytes(update.term, update.GetValue(), docIDUpto); err != nil {
 			return err
 		}
 	} else {
-		err := buffer.addNoValue(update.term, docIDUpto)
-		if err != nil {
+		if err := buffer.addNoValue(update.term, docIDUpto); err != nil {
 			return err
 		}
 	}
@@ -150,3 +153,11 @@ func (b *BufferedUpdates) Any() bool {
 func (b *BufferedUpdates) GetDeleteQueries() *treemap.Map[Query, int] {
 	return b.deleteQueries
 }
+
+func (b *BufferedUpdates) GetDeleteTerms() *treemap.Map[Term, int] {
+	return b.deleteTerms
+}
+
+func (b *BufferedUpdates) GetFieldUpdates() map[string]*FieldUpdatesBuffer {
+	return b.fieldUpdates
+}
@@ -59,6 +59,10 @@ type Codec interface {
 	// PointsFormat
 	// Encodes/decodes points index
 	PointsFormat() PointsFormat
+
+	// KnnVectorsFormat
+	// Encodes/decodes numeric vector fields
+	KnnVectorsFormat() KnnVectorsFormat
 }
 
 // TermVectorsFormat
@@ -597,6 +601,63 @@ type PointsFormat interface {
 	FieldsReader(ctx context.Context, state *SegmentReadState) (PointsReader, error)
 }
 
+// KnnVectorsFormat
+// Encodes/decodes per-document vector and any associated indexing structures required to support
+// nearest-neighbor search
+type KnnVectorsFormat interface {
+	Named
+
+	// FieldsWriter
+	// Returns a KnnVectorsWriter to write the vectors to the index.
+	FieldsWriter(ctx context.Context, state *SegmentWriteState) (KnnVectorsWriter, error)
+
+	// FieldsReader
+	// Returns a KnnVectorsReader to read the vectors from the index.
+	FieldsReader(ctx context.Context, state *SegmentReadState) (KnnVectorsReader, error)
+}
+
+// KnnVectorsReader
+// Reads vectors from an index.
+type KnnVectorsReader interface {
+	io.Closer
+
+	// CheckIntegrity
+	// Checks consistency of this reader.
+	// Note that this may be costly in terms of I/O, e.g. may involve computing a checksum
+	// item against large data files.
+	CheckIntegrity() error
+
+	// GetVectorValues
+	// Returns the VectorValues for the given field, or nil if the field has no vectors in
+	// this segment.
+	GetVectorValues(field string) (VectorValues, error)
+
+	// Search
+	// Return the k nearest neighbor documents as determined by comparison of their vector values
+	// for this field, to the given vector, by the field's similarity function. The score of each
+	// document is derived from the vector similarity in a way that ensures scores are positive
+	// and that a larger score corresponds to a higher ranking.
+	// The search is allowed to be approximate, meaning the results are not guaranteed to be the
+	// true k closest neighbors. Documents which are not set in acceptDocs are skipped; a nil
+	// acceptDocs accepts every document.
+	// Returns nil if the field has no vectors in this segment.
+	Search(ctx context.Context, field string, target []float32, k int, acceptDocs util.Bits) (TopDocs, error)
+}
+
+// KnnVectorsWriter
+// Writes vectors to an index.
+type KnnVectorsWriter interface {
+	io.Closer
+
+	// WriteField
+	// Write all values of the field contained in the provided reader
+	WriteField(ctx context.Context, fieldInfo *document.FieldInfo, values KnnVectorsReader) error
+
+	// Finish
+	// Called once at the end before close
+	Finish() error
+}
+
 // SortFieldProvider Reads/Writes a named SortField from a segment info file, used to record index sorts
 type SortFieldProvider interface {
 	Named
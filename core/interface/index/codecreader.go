@@ -9,6 +9,7 @@ type CodecReaderSPI interface {
 	GetDocValuesReader() DocValuesProducer
 	GetNormsReader() NormsProducer
 	GetPointsReader() PointsReader
+	GetVectorReader() KnnVectorsReader
 }
 
 type CodecReader interface {
@@ -43,4 +44,9 @@ type CodecReader interface {
 	// Expert: retrieve underlying PointsReader
 	// lucene.internal
 	GetPointsReader() PointsReader
+
+	// GetVectorReader
+	// Expert: retrieve underlying KnnVectorsReader
+	// lucene.internal
+	GetVectorReader() KnnVectorsReader
 }
@@ -112,7 +112,7 @@ func newBinaryDocValuesUpdate(term Term, field string, value []byte, docIDUpTo i
 			term:      term,
 			field:     field,
 			docIDUpto: docIDUpTo,
-			hasValue:  len(value) == 0,
+			hasValue:  value != nil,
 		},
 		value: value,
 	}
@@ -128,8 +128,7 @@ func (b *BinaryDocValuesUpdate) WriteTo(ctx context.Context, out store.DataOutpu
 }
 
 func (b *BinaryDocValuesUpdate) HasValue() bool {
-	//TODO implement me
-	panic("implement me")
+	return b.hasValue
 }
 
 func (b *BinaryDocValuesUpdate) GetValue() []byte {
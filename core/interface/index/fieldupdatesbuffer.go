@@ -2,7 +2,6 @@ package index
 
 import (
 	"math"
-	"slices"
 
 	"github.com/bits-and-blooms/bitset"
 )
@@ -17,18 +16,18 @@ import (
 // Along the same lines this impl optimizes the case when all updates have a item. Lastly, if all updates
 // share the same item for a numeric field we only store the item once.
 type FieldUpdatesBuffer struct {
-	numUpdates    int
-	termValues    [][]byte
-	termSortState []int
+	numUpdates int
+	termValues [][]byte
+	// the values of the binary updates which have a value
 	byteValues    [][]byte
 	docsUpTo      []int
 	numericValues []int64
-	hasValues     *bitset.BitSet
-	maxNumeric    int64
-	minNumeric    int64
-	fields        []string
-	isNumeric     bool
-	finished      bool
+	// nil if all the updates have a value
+	hasValues  *bitset.BitSet
+	maxNumeric int64
+	minNumeric int64
+	fields     []string
+	isNumeric  bool
 }
 
 func NewNumberFieldUpdatesBuffer(initialValue *NumericDocValuesUpdate, docUpTo int) *FieldUpdatesBuffer {
@@ -53,20 +52,14 @@ func NewBinaryFieldUpdatesBuffer(initialValue *BinaryDocValuesUpdate, docUpTo in
 }
 
 func newFieldUpdatesBuffer(initialValue DocValuesUpdate, docUpTo int, isNumeric bool) *FieldUpdatesBuffer {
-
 	buffer := &FieldUpdatesBuffer{
-		numUpdates:    1,
-		termValues:    [][]byte{initialValue.GetTerm().Bytes()},
-		termSortState: nil,
-		byteValues:    nil,
-		docsUpTo:      []int{docUpTo},
-		numericValues: nil,
-		hasValues:     nil,
-		maxNumeric:    math.MaxInt64,
-		minNumeric:    math.MinInt64,
-		fields:        []string{initialValue.GetTerm().Field()},
-		isNumeric:     isNumeric,
-		finished:      false,
+		numUpdates: 1,
+		termValues: [][]byte{initialValue.GetTerm().Bytes()},
+		docsUpTo:   []int{docUpTo},
+		maxNumeric: math.MinInt64,
+		minNumeric: math.MaxInt64,
+		fields:     []string{initialValue.GetTerm().Field()},
+		isNumeric:  isNumeric,
 	}
 
 	if !initialValue.HasValue() {
@@ -76,40 +69,59 @@ func newFieldUpdatesBuffer(initialValue DocValuesUpdate, docUpTo int, isNumeric
 	return buffer
 }
 
-func (f *FieldUpdatesBuffer) getMaxNumeric() int64 {
+// GetMaxNumeric Returns the largest value of the numeric updates, 0 if none of them has a value
+func (f *FieldUpdatesBuffer) GetMaxNumeric() int64 {
 	if f.minNumeric == math.MaxInt64 && f.maxNumeric == math.MinInt64 {
 		return 0
 	}
 	return f.maxNumeric
 }
 
-func (f *FieldUpdatesBuffer) getMinNumeric() int64 {
+// GetMinNumeric Returns the smallest value of the numeric updates, 0 if none of them has a value
+func (f *FieldUpdatesBuffer) GetMinNumeric() int64 {
 	if f.minNumeric == math.MaxInt64 && f.maxNumeric == math.MinInt64 {
 		return 0
 	}
 	return f.minNumeric
 }
 
-func (f *FieldUpdatesBuffer) add(field string, docUpTo, ord int, hasValue bool) error {
-	if f.fields[0] != (field) || len(f.fields) != 1 {
-		if len(f.fields) <= ord {
-			f.fields = slices.Grow(f.fields, ord+1)
+// growShared Grows values to hold the value of the update ord. A single value is shared by all the
+// previous updates, so it is copied to each of them.
+func growShared[T any](values []T, ord int) []T {
+	if len(values) > ord {
+		return values
+	}
+	grown := make([]T, ord+1)
+	if len(values) == 1 {
+		for i := 0; i < ord; i++ {
+			grown[i] = values[0]
 		}
+	} else {
+		copy(grown, values)
+	}
+	return grown
+}
+
+func (f *FieldUpdatesBuffer) add(field string, docUpTo, ord int, hasValue bool) error {
+	if f.fields[0] != field || len(f.fields) != 1 {
+		f.fields = growShared(f.fields, ord)
 		f.fields[ord] = field
 	}
 
 	if f.docsUpTo[0] != docUpTo || len(f.docsUpTo) != 1 {
-		if len(f.docsUpTo) <= ord {
-			f.docsUpTo = slices.Grow(f.docsUpTo, ord+1)
-		}
+		f.docsUpTo = growShared(f.docsUpTo, ord)
 		f.docsUpTo[ord] = docUpTo
 	}
 
-	if f.hasValues == nil {
-		f.hasValues = bitset.New(uint(ord + 1))
-	}
-	if hasValue {
-		f.hasValues.Set(uint(ord))
+	if !hasValue || f.hasValues != nil {
+		if f.hasValues == nil {
+			// all the previous updates have a value
+			f.hasValues = bitset.New(uint(ord + 1))
+			f.hasValues.FlipRange(0, uint(ord))
+		}
+		if hasValue {
+			f.hasValues.Set(uint(ord))
+		}
 	}
 	return nil
 }
@@ -124,9 +136,7 @@ func (f *FieldUpdatesBuffer) addUpdateInt(term Term, value int64, docUpTo int) e
 	f.maxNumeric = max(f.maxNumeric, value)
 
 	if f.numericValues[0] != value || len(f.numericValues) != 1 {
-		if len(f.numericValues) <= ord {
-			f.numericValues = slices.Grow(f.numericValues, ord+1)
-		}
+		f.numericValues = growShared(f.numericValues, ord)
 		f.numericValues[ord] = value
 	}
 	return nil
@@ -154,18 +164,64 @@ func (f *FieldUpdatesBuffer) IsNumeric() bool {
 	return f.isNumeric
 }
 
-func (f *FieldUpdatesBuffer) hasSingleValue() bool {
+// HasSingleValue Returns true if all the numeric updates share the same value
+func (f *FieldUpdatesBuffer) HasSingleValue() bool {
 	// we only do this optimization for numerics so far.
 	return f.isNumeric && len(f.numericValues) == 1
 }
 
-func (f *FieldUpdatesBuffer) getNumericValue(idx int) int64 {
-	if f.hasValues != nil && f.hasValues.Test(uint(idx)) == false {
+// GetNumericValue Returns the value of the numeric update idx
+func (f *FieldUpdatesBuffer) GetNumericValue(idx int) int64 {
+	if f.hasValues != nil && !f.hasValues.Test(uint(idx)) {
 		return 0
 	}
-	return f.numericValues[min(len(f.numericValues), idx)]
+	if len(f.numericValues) == 1 {
+		return f.numericValues[0]
+	}
+	return f.numericValues[idx]
 }
 
-func (f *FieldUpdatesBuffer) Range() {
+// BufferedUpdate
+// A single update of a FieldUpdatesBuffer
+type BufferedUpdate struct {
+	// the max document ID this update should be applied to
+	DocUpTo int
+	// a numeric value or 0 if this buffer holds binary updates
+	NumericValue int64
+	// a binary value or nil if this buffer holds numeric updates
+	BinaryValue []byte
+	// true if this update has a value
+	HasValue bool
+	// the field of the term of the update
+	TermField string
+	// the value of the term of the update
+	TermValue []byte
+}
 
+// Range
+// Calls fn for each update in the order the updates were added, the update is reused between
+// the calls. It stops at the first error returned by fn.
+func (f *FieldUpdatesBuffer) Range(fn func(update *BufferedUpdate) error) error {
+	update := &BufferedUpdate{}
+	byteValueIdx := 0
+	for i := 0; i < f.numUpdates; i++ {
+		update.TermValue = f.termValues[i]
+		update.TermField = f.fields[min(i, len(f.fields)-1)]
+		update.DocUpTo = f.docsUpTo[min(i, len(f.docsUpTo)-1)]
+		update.HasValue = f.hasValues == nil || f.hasValues.Test(uint(i))
+		update.NumericValue = 0
+		update.BinaryValue = nil
+		if update.HasValue {
+			if f.isNumeric {
+				update.NumericValue = f.GetNumericValue(i)
+			} else {
+				update.BinaryValue = f.byteValues[byteValueIdx]
+				byteValueIdx++
+			}
+		}
+		if err := fn(update); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -366,6 +366,21 @@ type LeafReader interface {
 	// if there are no point fields.
 	GetPointValues(field string) (types.PointValues, bool)
 
+	// GetVectorValues
+	// Returns VectorValues for this field, or null if no VectorValues were indexed. The returned
+	// instance should only be used by a single thread.
+	GetVectorValues(field string) (VectorValues, error)
+
+	// SearchNearestVectors
+	// Return the k nearest neighbor documents as determined by comparison of their vector values
+	// for this field, to the given vector, by the field's similarity function. The score of each
+	// document is derived from the vector similarity in a way that ensures scores are positive
+	// and that a larger score corresponds to a higher ranking.
+	// The search is allowed to be approximate, meaning the results are not guaranteed to be the
+	// true k closest neighbors. Documents which are not set in acceptDocs are skipped.
+	// Returns nil if the field has no vectors in this reader.
+	SearchNearestVectors(ctx context.Context, field string, target []float32, k int, acceptDocs util.Bits) (TopDocs, error)
+
 	// CheckIntegrity
 	// Checks consistency of this reader.
 	// Note that this may be costly in terms of I/O,
@@ -620,6 +635,7 @@ type FieldInfos interface {
 	HasDocValues() bool
 	HasVectors() bool
 	HasPointValues() bool
+	HasVectorValues() bool
 }
 
 // SortedSetDocValues A multi-valued version of SortedDocValues.
@@ -228,6 +228,7 @@ type IndexSearcher interface {
 	SetSimilarity(similarity Similarity)
 	GetSimilarity() Similarity
 	Count(query Query) (int, error)
+	Rewrite(query Query) (Query, error)
 	GetSlices() []LeafSlice
 	CreateWeight(query Query, scoreMode ScoreMode, boost float64) (Weight, error)
 	TermStatistics(term Term, docFreq, totalTermFreq int) (types.TermStatistics, error)
@@ -619,12 +620,12 @@ type ScoreMode uint8
 // NeedsScores
 // Whether this ScoreMode needs to compute scores.
 func (r ScoreMode) NeedsScores() bool {
-	return r&1<<needsScoresShift > 0
+	return r&(1<<needsScoresShift) != 0
 }
 
 // IsExhaustive
 // Returns true if for this ScoreMode it is necessary to process all documents, or false if
 // is enough to go through top documents only.
 func (r ScoreMode) IsExhaustive() bool {
-	return r&1<<isExhaustiveShift > 0
+	return r&(1<<isExhaustiveShift) != 0
 }
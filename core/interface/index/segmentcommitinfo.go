@@ -5,7 +5,7 @@ import (
 	"maps"
 	"slices"
 
-	"github.com/google/uuid"
+	"github.com/geange/lucene-go/core/util"
 )
 
 type SegmentCommitInfo interface {
@@ -36,7 +36,10 @@ type SegmentCommitInfo interface {
 	GetId() []byte
 	SizeInBytes() (int64, error)
 	AdvanceDelGen()
+	AdvanceFieldInfosGen()
+	AdvanceDocValuesGen()
 	GetBufferedDeletesGen() int64
+	SetBufferedDeletesGen(v int64)
 	GetFieldInfosFiles() map[string]struct{}
 	GetDocValuesUpdatesFiles() map[int]map[string]struct{}
 }
@@ -131,7 +134,7 @@ func newSegmentCommitInfo(info SegmentInfo, delCount, softDelCount int,
 		nextWriteDocValuesGen:  nextWriteDocValuesGen,
 		dvUpdatesFiles:         map[int]map[string]struct{}{},
 		fieldInfosFiles:        map[string]struct{}{},
-		sizeInBytes:            0,
+		sizeInBytes:            -1,
 		bufferedDeletesGen:     0,
 	}
 }
@@ -276,6 +279,7 @@ func (s *segmentCommitInfo) Clone() SegmentCommitInfo {
 	other.nextWriteDelGen = s.nextWriteDelGen
 	other.nextWriteFieldInfosGen = s.nextWriteFieldInfosGen
 	other.nextWriteDocValuesGen = s.nextWriteDocValuesGen
+	other.bufferedDeletesGen = s.bufferedDeletesGen
 
 	for k, files := range s.dvUpdatesFiles {
 		other.dvUpdatesFiles[k] = maps.Clone(files)
@@ -321,16 +325,39 @@ func (s *segmentCommitInfo) AdvanceDelGen() {
 	s.generationAdvanced()
 }
 
+// AdvanceFieldInfosGen
+// Called when we succeed in writing a new FieldInfos generation.
+func (s *segmentCommitInfo) AdvanceFieldInfosGen() {
+	s.fieldInfosGen = s.nextWriteFieldInfosGen
+	s.nextWriteFieldInfosGen = s.fieldInfosGen + 1
+	s.generationAdvanced()
+}
+
+// AdvanceDocValuesGen
+// Called when we succeed in writing a new DocValues generation.
+func (s *segmentCommitInfo) AdvanceDocValuesGen() {
+	s.docValuesGen = s.nextWriteDocValuesGen
+	s.nextWriteDocValuesGen = s.docValuesGen + 1
+	s.generationAdvanced()
+}
+
 func (s *segmentCommitInfo) generationAdvanced() {
 	s.sizeInBytes = -1
-	r, _ := uuid.NewRandom()
-	s.id = []byte(r.String())
+	s.id = util.RandomId()
 }
 
 func (s *segmentCommitInfo) GetBufferedDeletesGen() int64 {
 	return s.bufferedDeletesGen
 }
 
+// SetBufferedDeletesGen
+// Sets the generation of the buffered deletes the segment was published with, the packets of
+// the later generations apply to it.
+func (s *segmentCommitInfo) SetBufferedDeletesGen(v int64) {
+	s.bufferedDeletesGen = v
+	s.sizeInBytes = -1
+}
+
 func (s *segmentCommitInfo) GetFieldInfosFiles() map[string]struct{} {
 	return s.fieldInfosFiles
 }
@@ -64,6 +64,7 @@ func NewSegmentWriteState(directory store.Directory, segmentInfo SegmentInfo, fi
 		SegUpdates:          segUpdates,
 		LiveDocs:            nil,
 		SegmentSuffix:       "",
+		Context:             ioContext,
 	}
 }
 
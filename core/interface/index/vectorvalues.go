@@ -0,0 +1,55 @@
+package index
+
+import "github.com/geange/lucene-go/core/types"
+
+// VectorValues
+// This class provides access to per-document floating point vector values indexed as KnnVectorField.
+type VectorValues interface {
+	types.DocIdSetIterator
+
+	// Dimension
+	// Return the dimension of the vectors
+	Dimension() int
+
+	// Size
+	// Return the number of vectors for this field.
+	Size() int
+
+	// VectorValue
+	// Return the vector value for the current document ID. It is illegal to call this method when the
+	// iterator is not positioned: before advancing, or after failing to advance. The returned array
+	// may be shared across calls, re-used, and modified as the iterator advances.
+	VectorValue() ([]float32, error)
+}
+
+// RandomAccessVectorValues
+// Provides random access to vectors by dense ordinal.
+// lucene.experimental
+type RandomAccessVectorValues interface {
+
+	// Size
+	// Return the number of vector values
+	Size() int
+
+	// Dimension
+	// Return the dimension of the returned vector values
+	Dimension() int
+
+	// VectorValueByOrd
+	// Return the vector value indexed at the given ordinal. The provided floating point array may
+	// be shared and overwritten by subsequent calls to this method.
+	VectorValueByOrd(targetOrd int) ([]float32, error)
+}
+
+// RandomAccessVectorValuesProducer
+// Something (generally a VectorValues) that provides a RandomAccessVectorValues
+// lucene.experimental
+type RandomAccessVectorValuesProducer interface {
+
+	// RandomAccess
+	// Return a random access interface over this iterator's vectors. Calling the RandomAccess
+	// methods will have no effect on the progress of the iteration or the values returned by
+	// this iterator. Successive calls to RandomAccess return independent copies that can be
+	// iterated independently.
+	RandomAccess() (RandomAccessVectorValues, error)
+}
@@ -37,14 +37,18 @@ type AutomatonQuery struct {
 	rewriteMethod     RewriteMethod
 }
 
-func NewAutomatonQuery(term index.Term, auto *automaton.Automaton, determinizeWorkLimit int, isBinary bool) *AutomatonQuery {
+func NewAutomatonQuery(term index.Term, auto *automaton.Automaton, determinizeWorkLimit int, isBinary bool) (*AutomatonQuery, error) {
+	compiled, err := automaton.NewCompiledAutomaton(auto, nil, true, determinizeWorkLimit, isBinary)
+	if err != nil {
+		return nil, err
+	}
 	return &AutomatonQuery{
 		field:             term.Field(),
 		automaton:         auto,
 		term:              term,
 		automatonIsBinary: isBinary,
-		compiled:          automaton.NewCompiledAutomaton(auto, nil, true, determinizeWorkLimit, isBinary),
-	}
+		compiled:          compiled,
+	}, nil
 }
 
 func (r *AutomatonQuery) GetField() string {
@@ -113,8 +117,12 @@ func visit(auto *automaton.CompiledAutomaton, visitor index.QueryVisitor, parent
 			visitor.ConsumeTermsMatching(parent, field, auto.RunAutomaton)
 		case automaton.AUTOMATON_TYPE_NONE:
 		case automaton.AUTOMATON_TYPE_ALL:
+			run, err := automaton.NewByteRunAutomaton(automaton.MakeAnyString())
+			if err != nil {
+				return err
+			}
 			visitor.ConsumeTermsMatching(parent, field, func() *automaton.ByteRunAutomaton {
-				return automaton.NewByteRunAutomaton(automaton.MakeAnyString())
+				return run
 			})
 		case automaton.AUTOMATON_TYPE_SINGLE:
 			visitor.ConsumeTerms(parent, coreIndex.NewTerm(field, auto.Term()))
@@ -125,8 +125,36 @@ func disableScoring(scorer index.BulkScorer) index.BulkScorer {
 	}
 }
 
-func (*BooleanWeight) optionalBulkScorer(context index.LeafReaderContext) (index.BulkScorer, error) {
-	panic("")
+// Return a BulkScorer for the optional clauses only, or null if it is not applicable
+func (b *BooleanWeight) optionalBulkScorer(context index.LeafReaderContext) (index.BulkScorer, error) {
+	optional := make([]index.BulkScorer, 0)
+	for _, wc := range b.weightedClauses {
+		if wc.clause.GetOccur() != index.OccurShould {
+			continue
+		}
+		subScorer, err := wc.weight.BulkScorer(context)
+		if err != nil {
+			return nil, err
+		}
+		if subScorer != nil {
+			optional = append(optional, subScorer)
+		}
+	}
+
+	if len(optional) == 0 {
+		return nil, nil
+	}
+
+	if b.query.GetMinimumNumberShouldMatch() > len(optional) {
+		return nil, nil
+	}
+
+	if len(optional) == 1 {
+		return optional[0], nil
+	}
+
+	// TODO: BooleanScorer is not implemented, disjunctions are scored by the Scorer-based impl (BS2)
+	return nil, nil
 }
 
 // Return a BulkScorer for the required clauses only,
@@ -53,6 +53,14 @@ func NewTotalHitCountCollector() *TotalHitCountCollector {
 	return collector
 }
 
+func (t *TotalHitCountCollector) DoSetNextReader(context index.LeafReaderContext) error {
+	return nil
+}
+
+func (t *TotalHitCountCollector) SetScorer(scorer index.Scorable) error {
+	return nil
+}
+
 func (t *TotalHitCountCollector) Collect(ctx context.Context, doc int) error {
 	t.totalHits++
 	return nil
@@ -1,8 +1,12 @@
 package search
 
 import (
-	"github.com/geange/lucene-go/core/types"
+	"errors"
+	"io"
 	"sort"
+
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/types"
 )
 
 var _ types.DocIdSetIterator = &ConjunctionDISI{}
@@ -46,18 +50,23 @@ func (t TimSort) Swap(i, j int) {
 }
 
 func (c *ConjunctionDISI) DocID() int {
-	//TODO implement me
-	panic("implement me")
+	return c.lead1.DocID()
 }
 
 func (c *ConjunctionDISI) NextDoc() (int, error) {
-	//TODO implement me
-	panic("implement me")
+	doc, err := c.lead1.NextDoc()
+	if err != nil {
+		return exhaustedDoc(err)
+	}
+	return c.doNext(doc)
 }
 
 func (c *ConjunctionDISI) Advance(target int) (int, error) {
-	//TODO implement me
-	panic("implement me")
+	doc, err := c.lead1.Advance(target)
+	if err != nil {
+		return exhaustedDoc(err)
+	}
+	return c.doNext(doc)
 }
 
 func (c *ConjunctionDISI) SlowAdvance(target int) (int, error) {
@@ -65,15 +74,77 @@ func (c *ConjunctionDISI) SlowAdvance(target int) (int, error) {
 }
 
 func (c *ConjunctionDISI) Cost() int64 {
-	//TODO implement me
-	panic("implement me")
+	// the lead iterator has the lowest cost
+	return c.lead1.Cost()
 }
 
 // IntersectIterators Create a conjunction over the provided Scorers. Note that the returned DocIdSetIterator might leverage two-phase iteration in which case it is possible to retrieve the TwoPhaseIterator using TwoPhaseIterator.unwrap.
-func IntersectIterators(iterators []types.DocIdSetIterator) types.DocIdSetIterator {
-	panic("")
+func IntersectIterators(iterators []types.DocIdSetIterator) (types.DocIdSetIterator, error) {
+	if len(iterators) < 2 {
+		return nil, errors.New("cannot make a ConjunctionDISI of less than 2 iterators")
+	}
+
+	allIterators := make([]types.DocIdSetIterator, 0)
+	twoPhaseIterators := make([]index.TwoPhaseIterator, 0)
+	for _, iterator := range iterators {
+		allIterators, twoPhaseIterators = addIterator(iterator, allIterators, twoPhaseIterators)
+	}
+	return createConjunction(allIterators, twoPhaseIterators)
 }
 
 func (c *ConjunctionDISI) doNext(doc int) (int, error) {
-	panic("")
+advanceHead:
+	for {
+		if doc == types.NO_MORE_DOCS {
+			return types.NO_MORE_DOCS, io.EOF
+		}
+
+		// find agreement between the two iterators with the lower costs
+		// we special case them because they do not need the
+		// 'other.docID() < doc' check that the 'others' iterators need
+		next2, err := c.lead2.Advance(doc)
+		if err != nil {
+			return exhaustedDoc(err)
+		}
+		if next2 != doc {
+			doc, err = c.lead1.Advance(next2)
+			if err != nil {
+				return exhaustedDoc(err)
+			}
+			if next2 != doc {
+				continue
+			}
+		}
+
+		// then find agreement with other iterators
+		for _, other := range c.others {
+			// other.doc may already be equal to doc if we "continued advanceHead"
+			// on the previous iteration and the advance on the lead scorer exactly matched.
+			if other.DocID() < doc {
+				next, err := other.Advance(doc)
+				if err != nil {
+					return exhaustedDoc(err)
+				}
+				if next > doc {
+					// iterator beyond the current doc - advance lead and continue to the new highest doc.
+					doc, err = c.lead1.Advance(next)
+					if err != nil {
+						return exhaustedDoc(err)
+					}
+					continue advanceHead
+				}
+			}
+		}
+
+		// success - all iterators are on the same doc
+		return doc, nil
+	}
+}
+
+// exhaustedDoc returns NO_MORE_DOCS with io.EOF when a sub-iterator is exhausted, other errors are returned as is
+func exhaustedDoc(err error) (int, error) {
+	if errors.Is(err, io.EOF) {
+		return types.NO_MORE_DOCS, io.EOF
+	}
+	return 0, err
 }
@@ -2,6 +2,7 @@ package search
 
 import (
 	"errors"
+	"io"
 	"math"
 	"sort"
 
@@ -23,8 +24,8 @@ type ConjunctionScorer struct {
 	required []index.Scorer
 }
 
-func NewConjunctionScorer(weight index.Weight, scorers []index.Scorer, required []index.Scorer) (*ConjunctionScorer, error) {
-	disi, err := intersectScorers(scorers)
+func NewConjunctionScorer(weight index.Weight, required []index.Scorer, scorers []index.Scorer) (*ConjunctionScorer, error) {
+	disi, err := intersectScorers(required)
 	if err != nil {
 		return nil, err
 	}
@@ -93,7 +94,7 @@ func addScorer(scorer index.Scorer, allIterators []types.DocIdSetIterator,
 		allIterators, twoPhaseIterators = addTwoPhaseIterator(twoPhaseIter, allIterators, twoPhaseIterators)
 	} else {
 		// no approximation support, use the iterator as-is
-		// TODO
+		allIterators, twoPhaseIterators = addIterator(scorer.Iterator(), allIterators, twoPhaseIterators)
 	}
 	return allIterators, twoPhaseIterators
 }
@@ -118,7 +119,7 @@ func createConjunction(allIterators []types.DocIdSetIterator,
 	if len(allIterators) > 0 {
 		curDoc = allIterators[0].DocID()
 	} else {
-		twoPhaseIterators[0].Approximation().DocID()
+		curDoc = twoPhaseIterators[0].Approximation().DocID()
 	}
 
 	iteratorsOnTheSameDoc := true
@@ -251,13 +252,21 @@ func newConjunctionTwoPhaseIterator(approximation types.DocIdSetIterator,
 }
 
 func (c *ConjunctionTwoPhaseIterator) Approximation() types.DocIdSetIterator {
-	//TODO implement me
-	panic("implement me")
+	return c.approximation
 }
 
 func (c *ConjunctionTwoPhaseIterator) Matches() (bool, error) {
-	//TODO implement me
-	panic("implement me")
+	// match cheapest first
+	for _, twoPhaseIterator := range c.twoPhaseIterators {
+		ok, err := twoPhaseIterator.Matches()
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
 }
 
 func (c *ConjunctionTwoPhaseIterator) MatchCost() float64 {
@@ -300,33 +309,59 @@ func newBitSetConjunctionDISI(lead types.DocIdSetIterator, bitSetIterators []*co
 	for i, iterator := range disi.bitSetIterators {
 		bitSet := iterator.GetBitSet()
 		disi.bitSets[i] = bitSet
-		minLen = min(minLen, int(bitSet.Count()))
+		minLen = min(minLen, int(bitSet.Len()))
 	}
 	disi.minLength = minLen
 	return disi
 }
 
 func (b *BitSetConjunctionDISI) DocID() int {
-	//TODO implement me
-	panic("implement me")
+	return b.lead.DocID()
 }
 
 func (b *BitSetConjunctionDISI) NextDoc() (int, error) {
-	//TODO implement me
-	panic("implement me")
+	doc, err := b.lead.NextDoc()
+	if err != nil {
+		return exhaustedDoc(err)
+	}
+	return b.doNext(doc)
 }
 
 func (b *BitSetConjunctionDISI) Advance(target int) (int, error) {
-	//TODO implement me
-	panic("implement me")
+	doc, err := b.lead.Advance(target)
+	if err != nil {
+		return exhaustedDoc(err)
+	}
+	return b.doNext(doc)
+}
+
+func (b *BitSetConjunctionDISI) doNext(doc int) (int, error) {
+	var err error
+
+advanceLead:
+	for ; ; doc, err = b.lead.NextDoc() {
+		if err != nil {
+			return exhaustedDoc(err)
+		}
+		if doc >= b.minLength {
+			return types.NO_MORE_DOCS, io.EOF
+		}
+		for _, bitSet := range b.bitSets {
+			if !bitSet.Test(uint(doc)) {
+				continue advanceLead
+			}
+		}
+		for _, iterator := range b.bitSetIterators {
+			iterator.SetDocID(doc)
+		}
+		return doc, nil
+	}
 }
 
 func (b *BitSetConjunctionDISI) SlowAdvance(target int) (int, error) {
-	//TODO implement me
-	panic("implement me")
+	return types.SlowAdvance(b, target)
 }
 
 func (b *BitSetConjunctionDISI) Cost() int64 {
-	//TODO implement me
-	panic("implement me")
+	return b.lead.Cost()
 }
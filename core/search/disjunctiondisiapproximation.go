@@ -0,0 +1,80 @@
+package search
+
+import (
+	"errors"
+	"io"
+
+	"github.com/geange/lucene-go/core/types"
+)
+
+var _ types.DocIdSetIterator = &DisjunctionDISIApproximation{}
+
+// DisjunctionDISIApproximation
+// A DocIdSetIterator which is a disjunction of the approximations of the provided iterators.
+// lucene.internal
+type DisjunctionDISIApproximation struct {
+	subIterators *DisiPriorityQueue
+	cost         int64
+}
+
+func NewDisjunctionDISIApproximation(subIterators *DisiPriorityQueue) *DisjunctionDISIApproximation {
+	cost := int64(0)
+	for _, w := range subIterators.All() {
+		cost += w.cost
+	}
+	return &DisjunctionDISIApproximation{
+		subIterators: subIterators,
+		cost:         cost,
+	}
+}
+
+func (d *DisjunctionDISIApproximation) DocID() int {
+	return d.subIterators.Top().doc
+}
+
+func (d *DisjunctionDISIApproximation) NextDoc() (int, error) {
+	top := d.subIterators.Top()
+	doc := top.doc
+	if doc == types.NO_MORE_DOCS {
+		return types.NO_MORE_DOCS, nil
+	}
+
+	for top.doc == doc {
+		next, err := noMoreDocsOnEOF(top.approximation.NextDoc())
+		if err != nil {
+			return 0, err
+		}
+		top.doc = next
+		top = d.subIterators.UpdateTop()
+	}
+	return top.doc, nil
+}
+
+func (d *DisjunctionDISIApproximation) Advance(target int) (int, error) {
+	top := d.subIterators.Top()
+	for top.doc < target {
+		next, err := noMoreDocsOnEOF(top.approximation.Advance(target))
+		if err != nil {
+			return 0, err
+		}
+		top.doc = next
+		top = d.subIterators.UpdateTop()
+	}
+	return top.doc, nil
+}
+
+func (d *DisjunctionDISIApproximation) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(d, target)
+}
+
+func (d *DisjunctionDISIApproximation) Cost() int64 {
+	return d.cost
+}
+
+// noMoreDocsOnEOF returns NO_MORE_DOCS for the sub-iterators which report their exhaustion with io.EOF
+func noMoreDocsOnEOF(doc int, err error) (int, error) {
+	if errors.Is(err, io.EOF) {
+		return types.NO_MORE_DOCS, nil
+	}
+	return doc, err
+}
@@ -1,15 +1,26 @@
 package search
 
 import (
+	"errors"
+	"sort"
+
 	"github.com/geange/lucene-go/core/interface/index"
 	"github.com/geange/lucene-go/core/types"
 )
 
+type DisjunctionScorerSPI interface {
+	// ScoreList
+	// Compute the score for the given linked list of scorers.
+	ScoreList(topList *DisiWrapper) (float64, error)
+}
+
 // DisjunctionScorer
 // Base class for Scorers that score disjunctions.
 type DisjunctionScorer struct {
 	*BaseScorer
 
+	spi DisjunctionScorerSPI
+
 	needsScores bool
 
 	subScorers *DisiPriorityQueue
@@ -19,22 +30,170 @@ type DisjunctionScorer struct {
 	twoPhase *TwoPhase
 }
 
+func newBaseDisjunctionScorer(weight index.Weight, subScorers []index.Scorer, scoreMode index.ScoreMode,
+	spi DisjunctionScorerSPI) (*DisjunctionScorer, error) {
+
+	if len(subScorers) <= 1 {
+		return nil, errors.New("there must be at least 2 subScorers")
+	}
+
+	scorer := &DisjunctionScorer{
+		BaseScorer:  NewScorer(weight),
+		spi:         spi,
+		needsScores: scoreMode != COMPLETE_NO_SCORES,
+		subScorers:  NewDisiPriorityQueue(len(subScorers)),
+	}
+	for _, subScorer := range subScorers {
+		scorer.subScorers.Add(NewDisiWrapper(subScorer))
+	}
+	scorer.approximation = NewDisjunctionDISIApproximation(scorer.subScorers)
+
+	hasApproximation := false
+	sumMatchCost := 0.0
+	sumApproxCost := int64(0)
+	// Compute matchCost as the average over the matchCost of the subScorers.
+	// This is weighted by the cost, which is an expected number of matching documents.
+	for _, w := range scorer.subScorers.All() {
+		if w.twoPhaseView != nil {
+			hasApproximation = true
+			costWeight := max(w.cost, 1)
+			sumMatchCost += w.twoPhaseView.MatchCost() * float64(costWeight)
+			sumApproxCost += costWeight
+		}
+	}
+
+	if hasApproximation {
+		scorer.twoPhase = &TwoPhase{
+			approximation: scorer.approximation,
+			matchCost:     sumMatchCost / float64(sumApproxCost),
+			scorer:        scorer,
+		}
+	}
+	return scorer, nil
+}
+
+func (d *DisjunctionScorer) Iterator() types.DocIdSetIterator {
+	if d.twoPhase != nil {
+		return AsDocIdSetIterator(d.twoPhase)
+	}
+	return d.approximation
+}
+
+func (d *DisjunctionScorer) TwoPhaseIterator() index.TwoPhaseIterator {
+	if d.twoPhase != nil {
+		return d.twoPhase
+	}
+	return nil
+}
+
+func (d *DisjunctionScorer) DocID() int {
+	return d.subScorers.Top().doc
+}
+
+func (d *DisjunctionScorer) getSubMatches() (*DisiWrapper, error) {
+	if d.twoPhase != nil {
+		return d.twoPhase.getSubMatches()
+	}
+	return d.subScorers.TopList(), nil
+}
+
+func (d *DisjunctionScorer) Score() (float64, error) {
+	subMatches, err := d.getSubMatches()
+	if err != nil {
+		return 0, err
+	}
+	return d.spi.ScoreList(subMatches)
+}
+
+func (d *DisjunctionScorer) GetChildren() ([]index.ChildScorable, error) {
+	children := make([]index.ChildScorable, 0, d.subScorers.Size())
+	for _, w := range d.subScorers.All() {
+		children = append(children, NewChildScorable(w.scorer, "SHOULD"))
+	}
+	return children, nil
+}
+
 var _ index.TwoPhaseIterator = &TwoPhase{}
 
 type TwoPhase struct {
+	approximation types.DocIdSetIterator
+	matchCost     float64
+	scorer        *DisjunctionScorer
+
+	// list of verified matches on the current doc
+	verifiedMatches *DisiWrapper
+
+	// approximations on the current doc that have not been verified yet
+	unverifiedMatches []*DisiWrapper
+}
+
+func (t *TwoPhase) getSubMatches() (*DisiWrapper, error) {
+	// iteration order does not matter
+	for _, w := range t.unverifiedMatches {
+		ok, err := w.twoPhaseView.Matches()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			w.next = t.verifiedMatches
+			t.verifiedMatches = w
+		}
+	}
+	t.unverifiedMatches = t.unverifiedMatches[:0]
+	return t.verifiedMatches, nil
 }
 
 func (t *TwoPhase) Approximation() types.DocIdSetIterator {
-	//TODO implement me
-	panic("implement me")
+	return t.approximation
 }
 
 func (t *TwoPhase) Matches() (bool, error) {
-	//TODO implement me
-	panic("implement me")
+	t.verifiedMatches = nil
+	t.unverifiedMatches = t.unverifiedMatches[:0]
+
+	for w := t.scorer.subScorers.TopList(); w != nil; {
+		next := w.next
+
+		if w.twoPhaseView == nil {
+			// implicitly verified, move it to verifiedMatches
+			w.next = t.verifiedMatches
+			t.verifiedMatches = w
+
+			if !t.scorer.needsScores {
+				// we can stop here
+				return true, nil
+			}
+		} else {
+			t.unverifiedMatches = append(t.unverifiedMatches, w)
+		}
+		w = next
+	}
+
+	if t.verifiedMatches != nil {
+		return true, nil
+	}
+
+	// verify subs that have an two-phase iterator
+	// least-costly ones first
+	sort.SliceStable(t.unverifiedMatches, func(i, j int) bool {
+		return t.unverifiedMatches[i].matchCost < t.unverifiedMatches[j].matchCost
+	})
+	for len(t.unverifiedMatches) > 0 {
+		w := t.unverifiedMatches[0]
+		t.unverifiedMatches = t.unverifiedMatches[1:]
+		ok, err := w.twoPhaseView.Matches()
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			w.next = nil
+			t.verifiedMatches = w
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 func (t *TwoPhase) MatchCost() float64 {
-	//TODO implement me
-	panic("implement me")
+	return t.matchCost
 }
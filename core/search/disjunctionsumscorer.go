@@ -1,8 +1,9 @@
 package search
 
 import (
+	"math"
+
 	"github.com/geange/lucene-go/core/interface/index"
-	"github.com/geange/lucene-go/core/types"
 )
 
 var _ index.Scorer = &DisjunctionSumScorer{}
@@ -13,26 +14,33 @@ type DisjunctionSumScorer struct {
 	*DisjunctionScorer
 }
 
+// Construct a DisjunctionScorer.
+// weight: The weight to be used.
+// subScorers: Array of at least two subscorers.
 func newDisjunctionScorer(weight index.Weight, subScorers []index.Scorer, scoreMode index.ScoreMode) (*DisjunctionSumScorer, error) {
-	panic("")
-}
-
-func (d *DisjunctionSumScorer) Score() (float64, error) {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (d *DisjunctionSumScorer) DocID() int {
-	//TODO implement me
-	panic("implement me")
+	scorer := &DisjunctionSumScorer{}
+	disjunctionScorer, err := newBaseDisjunctionScorer(weight, subScorers, scoreMode, scorer)
+	if err != nil {
+		return nil, err
+	}
+	scorer.DisjunctionScorer = disjunctionScorer
+	return scorer, nil
 }
 
-func (d *DisjunctionSumScorer) Iterator() types.DocIdSetIterator {
-	//TODO implement me
-	panic("implement me")
+func (d *DisjunctionSumScorer) ScoreList(topList *DisiWrapper) (float64, error) {
+	score := 0.0
+	for w := topList; w != nil; w = w.next {
+		v, err := w.scorer.Score()
+		if err != nil {
+			return 0, err
+		}
+		score += v
+	}
+	return score, nil
 }
 
 func (d *DisjunctionSumScorer) GetMaxScore(upTo int) (float64, error) {
-	//TODO implement me
-	panic("implement me")
+	// It's ok to return a bad upper bound here since we use WANDScorer when
+	// we actually care about block scores.
+	return math.MaxFloat32, nil
 }
@@ -195,7 +195,7 @@ func (d *DocIdSetBuilder) addBuffer(size int) *Buffer {
 }
 
 func (d *DocIdSetBuilder) growBuffer(buffer *Buffer, additionalCapacity int) {
-	newArray := make([]int, len(d.buffers)+additionalCapacity)
+	newArray := make([]int, len(buffer.array)+additionalCapacity)
 	copy(newArray, buffer.array)
 	buffer.array = newArray
 	d.totalAllocated += additionalCapacity
@@ -224,9 +224,12 @@ func (d *DocIdSetBuilder) Build() DocIdSet {
 	}
 
 	concatenated := concatBuffers(d.buffers)
-	sort.Ints(concatenated.array)
-	//concatenated.array[l] = index.NO_MORE_DOCS
-	return NewIntArrayDocIdSet(concatenated.array[:concatenated.length])
+	l := concatenated.length
+	sort.Ints(concatenated.array[:l])
+	if d.multivalued {
+		l = dedup(concatenated.array, l)
+	}
+	return NewIntArrayDocIdSet(concatenated.array[:l])
 }
 
 // Concatenate the buffers in any order, leaving at least one empty slot in the end
@@ -252,7 +255,8 @@ func concatBuffers(buffers []*Buffer) *Buffer {
 	totalLength = largestBuffer.length
 	for _, buffer := range buffers {
 		if buffer != largestBuffer {
-			copy(docs[totalLength:], buffer.array)
+			copy(docs[totalLength:], buffer.array[:buffer.length])
+			totalLength += buffer.length
 		}
 	}
 	return NewBuffer(docs, totalLength)
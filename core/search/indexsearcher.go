@@ -108,7 +108,8 @@ func (r *IndexSearcher) Count(query index.Query) (int, error) {
 		return r.reader.NumDocs(), nil
 	}
 
-	if termQuery, ok := query.(*TermQuery); ok {
+	// the doc freq of a term also counts its deleted documents
+	if termQuery, ok := query.(*TermQuery); ok && !r.reader.HasDeletions() {
 		term := termQuery.GetTerm()
 		count := 0
 
@@ -303,7 +304,7 @@ func (r *IndexSearcher) SearchByCollectorManager(ctx context.Context,
 		if err := r.SearchCollector(nil, query, collector); err != nil {
 			return nil, err
 		}
-		return collectorManager.Reduce([]index.Collector{collector.(TopScoreDocCollector)})
+		return collectorManager.Reduce([]index.Collector{collector})
 	}
 
 	// TODO: fix it
@@ -0,0 +1,77 @@
+package search_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/geange/lucene-go/codecs/lucene87"
+	"github.com/geange/lucene-go/core/analysis"
+	"github.com/geange/lucene-go/core/analysis/standard"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/search"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/geange/lucene-go/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexSearcher_Count(t *testing.T) {
+	set := analysis.NewCharArraySet()
+	set.Add(" ")
+	analyzer := standard.NewAnalyzer(set)
+
+	memIndex, err := memory.NewIndex()
+	assert.Nil(t, err)
+	err = memIndex.AddIndexAbleField(document.NewTextField("f1", "some text", false), analyzer)
+	assert.Nil(t, err)
+	searcher := memIndex.CreateSearcher()
+
+	// a boosted query has no constant time count, its hits are counted by a TotalHitCountCollector
+	for term, expected := range map[string]int{"text": 1, "other": 0} {
+		query, err := search.NewBoostQuery(search.NewTermQuery(types.NewTerm("f1", []byte(term))), 2)
+		assert.Nil(t, err)
+		count, err := searcher.Count(query)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, count, term)
+	}
+}
+
+func TestIndexSearcher_CountWithDeletions(t *testing.T) {
+	ctx := context.Background()
+	dir := store.NewByteBuffersDirectory()
+	similarity, err := search.NewBM25Similarity()
+	assert.Nil(t, err)
+	config := coreIndex.NewIndexWriterConfig(lucene87.NewCodec(lucene87.BEST_SPEED), similarity)
+	writer, err := coreIndex.NewIndexWriter(ctx, dir, config)
+	assert.Nil(t, err)
+
+	for i := 0; i < 10; i++ {
+		doc := document.NewDocument()
+		doc.Add(document.NewStringField("id", strconv.Itoa(i), false))
+		doc.Add(document.NewStringField("type", "a", false))
+		_, err = writer.AddDocument(ctx, doc)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, writer.Commit(ctx))
+	_, err = writer.DeleteDocuments(ctx, coreIndex.NewTerm("id", []byte("3")))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Commit(ctx))
+	assert.Nil(t, writer.Close())
+
+	reader, err := coreIndex.OpenDirectoryReader(ctx, dir, nil, nil)
+	assert.Nil(t, err)
+	assert.True(t, reader.HasDeletions())
+	searcher, err := search.NewIndexSearcher(reader)
+	assert.Nil(t, err)
+
+	// the doc freq of the term still counts the deleted document
+	count, err := searcher.Count(search.NewTermQuery(coreIndex.NewTerm("type", []byte("a"))))
+	assert.Nil(t, err)
+	assert.Equal(t, 9, count)
+
+	count, err = searcher.Count(search.NewTermQuery(coreIndex.NewTerm("id", []byte("3"))))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
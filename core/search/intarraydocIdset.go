@@ -13,8 +13,7 @@ type IntArrayDocIdSet struct {
 }
 
 func (r *IntArrayDocIdSet) Iterator() types.DocIdSetIterator {
-	//TODO implement me
-	panic("implement me")
+	return NewIntArrayDocIdSetIterator(r.docs)
 }
 
 func (r *IntArrayDocIdSet) Bits() util.Bits {
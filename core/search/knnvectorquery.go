@@ -0,0 +1,347 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/geange/gods-generic/sets/treeset"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/types"
+)
+
+var _ index.Query = &KnnVectorQuery{}
+
+// KnnVectorQuery
+// Uses KnnVectorsReader.Search to perform nearest neighbour search.
+//
+// The query is rewritten into a query matching the k nearest documents of the whole index, each
+// one scored by its similarity to the target vector. The k nearest documents of every segment are
+// found using the vectors format of the segment, then the best k of them are kept.
+type KnnVectorQuery struct {
+	field  string
+	target []float32
+	k      int
+}
+
+// NewKnnVectorQuery
+// Find the k nearest documents to the target vector according to the vectors in the given field.
+// field: a field that has been indexed as a KnnVectorField.
+// target: the target of the search
+// k: the number of documents to find
+func NewKnnVectorQuery(field string, target []float32, k int) (*KnnVectorQuery, error) {
+	if k < 1 {
+		return nil, fmt.Errorf("k must be at least 1, got: %d", k)
+	}
+	return &KnnVectorQuery{
+		field:  field,
+		target: target,
+		k:      k,
+	}, nil
+}
+
+func (k *KnnVectorQuery) GetField() string {
+	return k.field
+}
+
+func (k *KnnVectorQuery) GetTarget() []float32 {
+	return k.target
+}
+
+func (k *KnnVectorQuery) GetK() int {
+	return k.k
+}
+
+func (k *KnnVectorQuery) String(field string) string {
+	return fmt.Sprintf("<vector:%s[%s,...][%d]>", k.field, formatVectorHead(k.target), k.k)
+}
+
+func formatVectorHead(target []float32) string {
+	if len(target) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%v", target[0])
+}
+
+func (k *KnnVectorQuery) CreateWeight(searcher index.IndexSearcher, scoreMode index.ScoreMode, boost float64) (index.Weight, error) {
+	return nil, errors.New("KnnVectorQuery must be rewritten before creating a weight")
+}
+
+func (k *KnnVectorQuery) Rewrite(reader index.IndexReader) (index.Query, error) {
+	leaves, err := reader.Leaves()
+	if err != nil {
+		return nil, err
+	}
+
+	perLeafResults := make([]index.ScoreDoc, 0)
+	for _, leaf := range leaves {
+		results, err := k.searchLeaf(leaf)
+		if err != nil {
+			return nil, err
+		}
+		perLeafResults = append(perLeafResults, results...)
+	}
+
+	// keep the k nearest documents of the whole index; ties are broken by docID
+	sort.SliceStable(perLeafResults, func(i, j int) bool {
+		a, b := perLeafResults[i], perLeafResults[j]
+		if a.GetScore() != b.GetScore() {
+			return a.GetScore() > b.GetScore()
+		}
+		return a.GetDoc() < b.GetDoc()
+	})
+	if len(perLeafResults) > k.k {
+		perLeafResults = perLeafResults[:k.k]
+	}
+
+	if len(perLeafResults) == 0 {
+		return NewMatchNoDocsQuery("no vector documents match"), nil
+	}
+	return createRewrittenQuery(reader, perLeafResults)
+}
+
+// searchLeaf returns the nearest documents of a segment, with docIDs rebased on the top reader
+func (k *KnnVectorQuery) searchLeaf(leaf index.LeafReaderContext) ([]index.ScoreDoc, error) {
+	reader := leaf.LeafReader()
+	topDocs, err := reader.SearchNearestVectors(context.Background(), k.field, k.target, k.k, reader.GetLiveDocs())
+	if err != nil {
+		return nil, err
+	}
+	if topDocs == nil {
+		return nil, nil
+	}
+
+	scoreDocs := topDocs.GetScoreDocs()
+	results := make([]index.ScoreDoc, 0, len(scoreDocs))
+	for _, scoreDoc := range scoreDocs {
+		results = append(results, NewScoreDoc(scoreDoc.GetDoc()+leaf.DocBase(), scoreDoc.GetScore()))
+	}
+	return results, nil
+}
+
+func createRewrittenQuery(reader index.IndexReader, scoreDocs []index.ScoreDoc) (index.Query, error) {
+	sort.Slice(scoreDocs, func(i, j int) bool {
+		return scoreDocs[i].GetDoc() < scoreDocs[j].GetDoc()
+	})
+
+	docs := make([]int, len(scoreDocs))
+	scores := make([]float64, len(scoreDocs))
+	for i, scoreDoc := range scoreDocs {
+		docs[i] = scoreDoc.GetDoc()
+		scores[i] = scoreDoc.GetScore()
+	}
+
+	readerContext, err := reader.GetContext()
+	if err != nil {
+		return nil, err
+	}
+	leaves, err := reader.Leaves()
+	if err != nil {
+		return nil, err
+	}
+	segmentStarts := findSegmentStarts(leaves, docs)
+	return newDocAndScoreQuery(docs, scores, segmentStarts, readerContext.Identity()), nil
+}
+
+// findSegmentStarts returns, for each leaf, the index of its first document in docs; the extra last
+// entry is len(docs)
+func findSegmentStarts(leaves []index.LeafReaderContext, docs []int) []int {
+	starts := make([]int, len(leaves)+1)
+	starts[len(starts)-1] = len(docs)
+	for i, leaf := range leaves {
+		docBase := leaf.DocBase()
+		starts[i] = sort.SearchInts(docs, docBase)
+	}
+	return starts
+}
+
+func (k *KnnVectorQuery) Visit(visitor index.QueryVisitor) error {
+	if visitor.AcceptField(k.field) {
+		return visitor.VisitLeaf(k)
+	}
+	return nil
+}
+
+var _ index.Query = &docAndScoreQuery{}
+
+// docAndScoreQuery a query matching a fixed list of documents, each one with its own score
+type docAndScoreQuery struct {
+	docs            []int
+	scores          []float64
+	segmentStarts   []int
+	contextIdentity string
+}
+
+// newDocAndScoreQuery
+// docs: the global docids of documents that match, in ascending order
+// scores: the scores of the matching documents
+// segmentStarts: the indexes in docs and scores corresponding to the first matching document in
+// each segment. If a segment has no matching documents, it should be assigned the index of the
+// next segment that does. The final entry is always len(docs).
+// contextIdentity: the identity of the top reader used to create this query
+func newDocAndScoreQuery(docs []int, scores []float64, segmentStarts []int, contextIdentity string) *docAndScoreQuery {
+	return &docAndScoreQuery{
+		docs:            docs,
+		scores:          scores,
+		segmentStarts:   segmentStarts,
+		contextIdentity: contextIdentity,
+	}
+}
+
+func (d *docAndScoreQuery) String(field string) string {
+	sb := new(strings.Builder)
+	sb.WriteString("DocAndScore[")
+	for i, doc := range d.docs {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(sb, "%d:%v", doc, d.scores[i])
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+func (d *docAndScoreQuery) CreateWeight(searcher index.IndexSearcher, scoreMode index.ScoreMode, boost float64) (index.Weight, error) {
+	if searcher.GetTopReaderContext().Identity() != d.contextIdentity {
+		return nil, errors.New("this DocAndScore query was created by a different reader")
+	}
+	weight := &docAndScoreWeight{
+		query: d,
+		boost: boost,
+	}
+	weight.BaseWeight = NewBaseWeight(d, weight)
+	return weight, nil
+}
+
+func (d *docAndScoreQuery) Rewrite(reader index.IndexReader) (index.Query, error) {
+	return d, nil
+}
+
+func (d *docAndScoreQuery) Visit(visitor index.QueryVisitor) error {
+	return visitor.VisitLeaf(d)
+}
+
+var _ index.Weight = &docAndScoreWeight{}
+
+type docAndScoreWeight struct {
+	*BaseWeight
+
+	query *docAndScoreQuery
+	boost float64
+}
+
+func (w *docAndScoreWeight) ExtractTerms(terms *treeset.Set[index.Term]) error {
+	return nil
+}
+
+func (w *docAndScoreWeight) IsCacheable(ctx index.LeafReaderContext) bool {
+	return true
+}
+
+func (w *docAndScoreWeight) Explain(ctx index.LeafReaderContext, doc int) (types.Explanation, error) {
+	q := w.query
+	lower, upper := q.segmentStarts[ctx.Ord()], q.segmentStarts[ctx.Ord()+1]
+	found := sort.SearchInts(q.docs[lower:upper], doc+ctx.DocBase())
+	if found == upper-lower || q.docs[lower+found] != doc+ctx.DocBase() {
+		return types.ExplanationNoMatch("not in top k"), nil
+	}
+	return types.ExplanationMatch(q.scores[lower+found]*w.boost, "within top k"), nil
+}
+
+func (w *docAndScoreWeight) Scorer(ctx index.LeafReaderContext) (index.Scorer, error) {
+	q := w.query
+	lower, upper := q.segmentStarts[ctx.Ord()], q.segmentStarts[ctx.Ord()+1]
+	if lower == upper {
+		return nil, nil
+	}
+
+	scorer := &docAndScoreScorer{
+		weight:  w,
+		docBase: ctx.DocBase(),
+		lower:   lower,
+		upper:   upper,
+		upTo:    -1,
+	}
+	scorer.BaseScorer = NewScorer(w)
+	return scorer, nil
+}
+
+var _ index.Scorer = &docAndScoreScorer{}
+
+type docAndScoreScorer struct {
+	*BaseScorer
+
+	weight  *docAndScoreWeight
+	docBase int
+	lower   int
+	upper   int
+	upTo    int
+}
+
+func (s *docAndScoreScorer) Score() (float64, error) {
+	return s.weight.query.scores[s.upTo] * s.weight.boost, nil
+}
+
+func (s *docAndScoreScorer) DocID() int {
+	switch {
+	case s.upTo == -1:
+		return -1
+	case s.upTo >= s.upper:
+		return types.NO_MORE_DOCS
+	default:
+		return s.weight.query.docs[s.upTo] - s.docBase
+	}
+}
+
+func (s *docAndScoreScorer) Iterator() types.DocIdSetIterator {
+	return s
+}
+
+func (s *docAndScoreScorer) NextDoc() (int, error) {
+	if s.upTo == -1 {
+		s.upTo = s.lower
+	} else {
+		s.upTo++
+	}
+	if s.upTo >= s.upper {
+		s.upTo = s.upper
+		return types.NO_MORE_DOCS, io.EOF
+	}
+	return s.DocID(), nil
+}
+
+func (s *docAndScoreScorer) Advance(target int) (int, error) {
+	if s.upTo >= s.upper {
+		return types.NO_MORE_DOCS, io.EOF
+	}
+	// the documents of the segment are sorted, search the first one at or after the target
+	from := max(s.upTo+1, s.lower)
+	s.upTo = from + sort.SearchInts(s.weight.query.docs[from:s.upper], target+s.docBase)
+	if s.upTo >= s.upper {
+		s.upTo = s.upper
+		return types.NO_MORE_DOCS, io.EOF
+	}
+	return s.DocID(), nil
+}
+
+func (s *docAndScoreScorer) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(s, target)
+}
+
+func (s *docAndScoreScorer) Cost() int64 {
+	return int64(s.upper - s.lower)
+}
+
+func (s *docAndScoreScorer) GetMaxScore(upTo int) (float64, error) {
+	maxScore := 0.0
+	for i := s.lower; i < s.upper; i++ {
+		if s.weight.query.docs[i]-s.docBase > upTo {
+			break
+		}
+		maxScore = max(maxScore, s.weight.query.scores[i])
+	}
+	return maxScore * s.weight.boost, nil
+}
@@ -0,0 +1,241 @@
+package search_test
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/geange/lucene-go/codecs/lucene87"
+	"github.com/geange/lucene-go/codecs/lucene90"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/search"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type testHit struct {
+	doc   int
+	score float64
+}
+
+// indexes 30 documents in two segments: document i has the vector [i, 1] and is tagged even or odd
+func newTestKnnSearcher(t *testing.T) index.IndexSearcher {
+	ctx := context.Background()
+	dir := store.NewByteBuffersDirectory()
+	similarity, err := search.NewBM25Similarity()
+	assert.Nil(t, err)
+	config := coreIndex.NewIndexWriterConfig(lucene90.NewCodec(lucene87.BEST_SPEED), similarity)
+	writer, err := coreIndex.NewIndexWriter(ctx, dir, config)
+	assert.Nil(t, err)
+
+	for i := 0; i < 30; i++ {
+		doc := document.NewDocument()
+		doc.Add(document.NewStringField("id", strconv.Itoa(i), true))
+		tag := "even"
+		if i%2 == 1 {
+			tag = "odd"
+		}
+		doc.Add(document.NewStringField("tag", tag, false))
+		field, err := document.NewKnnVectorField("vector", []float32{float32(i), 1},
+			document.VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN)
+		assert.Nil(t, err)
+		doc.Add(field)
+		_, err = writer.AddDocument(ctx, doc)
+		assert.Nil(t, err)
+		if i == 14 {
+			assert.Nil(t, writer.Commit(ctx))
+		}
+	}
+	assert.Nil(t, writer.Commit(ctx))
+	assert.Nil(t, writer.Close())
+
+	reader, err := coreIndex.OpenDirectoryReader(ctx, dir, nil, nil)
+	assert.Nil(t, err)
+	leaves, err := reader.Leaves()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(leaves))
+	searcher, err := search.NewIndexSearcher(reader)
+	assert.Nil(t, err)
+	return searcher
+}
+
+func searchTestHits(t *testing.T, searcher index.IndexSearcher, query index.Query) []testHit {
+	topDocs, err := searcher.SearchTopN(context.Background(), query, 100)
+	if !assert.Nil(t, err) {
+		return nil
+	}
+	hits := make([]testHit, 0)
+	for _, scoreDoc := range topDocs.GetScoreDocs() {
+		hits = append(hits, testHit{doc: scoreDoc.GetDoc(), score: scoreDoc.GetScore()})
+	}
+
+	count, err := searcher.Count(query)
+	assert.Nil(t, err)
+	assert.Equal(t, len(hits), count)
+	return hits
+}
+
+func hitDocs(hits []testHit) []int {
+	docs := make([]int, 0, len(hits))
+	for _, hit := range hits {
+		docs = append(docs, hit.doc)
+	}
+	return docs
+}
+
+// combines the hits of two clauses: both must match when required, a score of 0 is used for a missing
+// clause otherwise. Only the scores of the scoring clauses are summed.
+func combineTestHits(a, b []testHit, required, scoreA bool) []testHit {
+	scoresA, scoresB := make(map[int]float64), make(map[int]float64)
+	for _, hit := range a {
+		scoresA[hit.doc] = hit.score
+	}
+	for _, hit := range b {
+		scoresB[hit.doc] = hit.score
+	}
+
+	combined := make([]testHit, 0)
+	for doc, score := range scoresA {
+		scoreB, ok := scoresB[doc]
+		if required && !ok {
+			continue
+		}
+		if !scoreA {
+			score = 0
+		}
+		combined = append(combined, testHit{doc: doc, score: score + scoreB})
+	}
+	if !required {
+		for doc, score := range scoresB {
+			if _, ok := scoresA[doc]; !ok {
+				combined = append(combined, testHit{doc: doc, score: score})
+			}
+		}
+	}
+	sort.Slice(combined, func(i, j int) bool {
+		if combined[i].score != combined[j].score {
+			return combined[i].score > combined[j].score
+		}
+		return combined[i].doc < combined[j].doc
+	})
+	return combined
+}
+
+func assertTestHits(t *testing.T, expected, actual []testHit) {
+	if !assert.Equal(t, hitDocs(expected), hitDocs(actual)) {
+		return
+	}
+	for i := range expected {
+		assert.InDelta(t, expected[i].score, actual[i].score, 1e-5)
+	}
+}
+
+func TestKnnVectorQuery_AdvancePastLastDoc(t *testing.T) {
+	searcher := newTestKnnSearcher(t)
+
+	knnQuery, err := search.NewKnnVectorQuery("vector", []float32{0, 1}, 5)
+	assert.Nil(t, err)
+	query, err := knnQuery.Rewrite(searcher.GetIndexReader())
+	assert.Nil(t, err)
+	weight, err := searcher.CreateWeight(query, search.COMPLETE, 1)
+	assert.Nil(t, err)
+	leaves, err := searcher.GetIndexReader().Leaves()
+	assert.Nil(t, err)
+
+	// the nearest documents 0 to 4 are all in the first segment
+	scorer, err := weight.Scorer(leaves[0])
+	assert.Nil(t, err)
+	doc, err := scorer.Iterator().Advance(4)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, doc)
+	for i := 0; i < 2; i++ {
+		doc, err = scorer.Iterator().Advance(10)
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, types.NO_MORE_DOCS, doc)
+		assert.Equal(t, types.NO_MORE_DOCS, scorer.Iterator().DocID())
+	}
+}
+
+func TestKnnVectorQuery_BooleanQuery(t *testing.T) {
+	searcher := newTestKnnSearcher(t)
+
+	knnQuery, err := search.NewKnnVectorQuery("vector", []float32{0, 1}, 5)
+	assert.Nil(t, err)
+	evenQuery := search.NewTermQuery(coreIndex.NewTerm("tag", []byte("even")))
+	oddQuery := search.NewTermQuery(coreIndex.NewTerm("tag", []byte("odd")))
+
+	// the nearest vectors, the closest first
+	knnHits := searchTestHits(t, searcher, knnQuery)
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, hitDocs(knnHits))
+	for i, hit := range knnHits {
+		assert.InDelta(t, 1/(1+float64(i*i)), hit.score, 1e-5)
+	}
+	evenHits := searchTestHits(t, searcher, evenQuery)
+	assert.Equal(t, 15, len(evenHits))
+	oddHits := searchTestHits(t, searcher, oddQuery)
+	assert.Equal(t, 15, len(oddHits))
+
+	t.Run("must", func(t *testing.T) {
+		query, err := search.NewBooleanQueryBuilder().
+			AddQuery(knnQuery, index.OccurMust).
+			AddQuery(evenQuery, index.OccurMust).
+			Build()
+		assert.Nil(t, err)
+		hits := searchTestHits(t, searcher, query)
+		assert.Equal(t, []int{0, 2, 4}, hitDocs(hits))
+		assertTestHits(t, combineTestHits(knnHits, evenHits, true, true), hits)
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		query, err := search.NewBooleanQueryBuilder().
+			AddQuery(knnQuery, index.OccurFilter).
+			AddQuery(oddQuery, index.OccurMust).
+			Build()
+		assert.Nil(t, err)
+		hits := searchTestHits(t, searcher, query)
+		assert.Equal(t, []int{1, 3}, hitDocs(hits))
+		assertTestHits(t, combineTestHits(knnHits, oddHits, true, false), hits)
+	})
+
+	t.Run("should", func(t *testing.T) {
+		query, err := search.NewBooleanQueryBuilder().
+			AddQuery(knnQuery, index.OccurShould).
+			AddQuery(oddQuery, index.OccurShould).
+			Build()
+		assert.Nil(t, err)
+		hits := searchTestHits(t, searcher, query)
+		assert.Equal(t, 18, len(hits))
+		assert.Equal(t, []int{0, 1, 3}, hitDocs(hits)[:3])
+		assertTestHits(t, combineTestHits(knnHits, oddHits, false, true), hits)
+	})
+
+	t.Run("should with minimum should match", func(t *testing.T) {
+		query, err := search.NewBooleanQueryBuilder().
+			AddQuery(knnQuery, index.OccurShould).
+			AddQuery(oddQuery, index.OccurShould).
+			SetMinimumNumberShouldMatch(2).
+			Build()
+		assert.Nil(t, err)
+		hits := searchTestHits(t, searcher, query)
+		assertTestHits(t, combineTestHits(knnHits, oddHits, true, true), hits)
+	})
+
+	t.Run("should with minimum should match of three clauses", func(t *testing.T) {
+		query, err := search.NewBooleanQueryBuilder().
+			AddQuery(knnQuery, index.OccurShould).
+			AddQuery(evenQuery, index.OccurShould).
+			AddQuery(oddQuery, index.OccurShould).
+			SetMinimumNumberShouldMatch(2).
+			Build()
+		assert.Nil(t, err)
+		hits := searchTestHits(t, searcher, query)
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, hitDocs(hits))
+		tagHits := append(append([]testHit{}, evenHits...), oddHits...)
+		assertTestHits(t, combineTestHits(knnHits, tagHits, true, true), hits)
+	})
+}
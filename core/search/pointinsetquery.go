@@ -1,48 +1,64 @@
 package search
 
 import (
-	"github.com/geange/lucene-go/core/index"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	coreIndex "github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/types"
 )
 
-// TODO: fix
-//var _ Query = &PointInSetQuery{}
+var _ coreIndex.Query = &PointInSetQuery{}
 
+// PointInSetQuery
+// Abstract query class to find all documents whose single or multi-dimensional point values,
+// previously indexed with e.g. LongPoint, is contained in the specified set.
+// This is for subclasses and works on the underlying binary encoding: to create range queries for
+// lucene's standard Point types, refer to factory methods on those classes, e.g.
+// LongPoint.newSetQuery() for fields indexed with LongPoint.
 type PointInSetQuery struct {
-	sortedPackedPoints         *index.PrefixCodedTerms
-	sortedPackedPointsHashCode int
-	field                      string
-	numDims                    int
-	bytesPerDim                int
+	// sorted and deduplicated packed points
+	sortedPackedPoints [][]byte
+	field              string
+	numDims            int
+	bytesPerDim        int
 }
 
-/*
-
+// NewPointInSetQuery The packedPoints must be in sorted order, duplicates are removed.
 func NewPointInSetQuery(ctx context.Context, field string, numDims int, bytesPerDim int, packedPoints [][]byte) (*PointInSetQuery, error) {
-	builder := index.NewPrefixCodedTermsBuilder()
-	for i := 1; i < len(packedPoints); i++ {
-		previous := packedPoints[i-1]
-		current := packedPoints[i]
-
-		if v := bytes.Compare(previous, current); v == 0 {
-			continue // deduplicate
-		} else if v > 0 {
-			return nil, errors.New("values are out of order")
-		}
+	if numDims <= 0 {
+		return nil, errors.New("numDims must be positive")
+	}
+	if bytesPerDim <= 0 {
+		return nil, errors.New("bytesPerDim must be positive")
+	}
 
-		if i == 1 {
-			err := builder.AddBytes(ctx, field, previous)
-			if err != nil {
-				return nil, err
-			}
+	sortedPackedPoints := make([][]byte, 0, len(packedPoints))
+	for i, point := range packedPoints {
+		if len(point) != numDims*bytesPerDim {
+			return nil, fmt.Errorf("packed point length should be %d but got %d; field=%s numDims=%d bytesPerDim=%d",
+				numDims*bytesPerDim, len(point), field, numDims, bytesPerDim)
 		}
-		err := builder.AddBytes(ctx, field, current)
-		if err != nil {
-			return nil, err
+		if i > 0 {
+			cmp := bytes.Compare(packedPoints[i-1], point)
+			if cmp == 0 {
+				continue // deduplicate
+			}
+			if cmp > 0 {
+				return nil, errors.New("values are out of order: saw " +
+					base64.StdEncoding.EncodeToString(packedPoints[i-1]) + " before " +
+					base64.StdEncoding.EncodeToString(point))
+			}
 		}
+		sortedPackedPoints = append(sortedPackedPoints, bytes.Clone(point))
 	}
 
 	return &PointInSetQuery{
-		sortedPackedPoints: builder.Finish(),
+		sortedPackedPoints: sortedPackedPoints,
 		field:              field,
 		numDims:            numDims,
 		bytesPerDim:        bytesPerDim,
@@ -50,154 +66,153 @@ func NewPointInSetQuery(ctx context.Context, field string, numDims int, bytesPer
 }
 
 func (p *PointInSetQuery) String(field string) string {
-	return p.field
+	sb := new(bytes.Buffer)
+	if p.field != field {
+		sb.WriteString(p.field)
+		sb.WriteString(":")
+	}
+
+	sb.WriteString("{")
+	for i, point := range p.sortedPackedPoints {
+		if i > 0 {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(base64.StdEncoding.EncodeToString(point))
+	}
+	sb.WriteString("}")
+	return sb.String()
 }
 
-func (p *PointInSetQuery) CreateWeight(searcher *IndexSearcher, scoreMode *ScoreMode, boost float64) (Weight, error) {
+func (p *PointInSetQuery) CreateWeight(searcher coreIndex.IndexSearcher, scoreMode coreIndex.ScoreMode, boost float64) (coreIndex.Weight, error) {
+	// We don't use RandomAccessWeight here: it's no good to approximate with "match all docs".
+	// This is an inverted structure and should be used in the first pass:
 	weight := &pisQueryWeight{
-		ConstantScoreWeight: nil,
-		p:                   p,
-		scoreMode:           scoreMode,
+		p:         p,
+		scoreMode: scoreMode,
 	}
-
 	weight.ConstantScoreWeight = NewConstantScoreWeight(boost, p, weight)
 	return weight, nil
 }
 
+func (p *PointInSetQuery) IsPointQuery() bool {
+	return true
+}
+
+func (p *PointInSetQuery) Rewrite(reader coreIndex.IndexReader) (coreIndex.Query, error) {
+	return p, nil
+}
+
+func (p *PointInSetQuery) Visit(visitor coreIndex.QueryVisitor) error {
+	if visitor.AcceptField(p.field) {
+		return visitor.VisitLeaf(p)
+	}
+	return nil
+}
+
 type pisQueryWeight struct {
 	*ConstantScoreWeight
+
 	p         *PointInSetQuery
-	scoreMode *ScoreMode
+	scoreMode coreIndex.ScoreMode
 }
 
-func (r *pisQueryWeight) Scorer(ctx *index.LeafReaderContext) (Scorer, error) {
-	reader := ctx.Reader().(index.LeafReader)
-	values, ok := reader.GetPointValues(r.p.field)
+func (r *pisQueryWeight) Scorer(ctx coreIndex.LeafReaderContext) (coreIndex.Scorer, error) {
+	reader, ok := ctx.Reader().(coreIndex.LeafReader)
 	if !ok {
+		return nil, errors.New("get reader error")
+	}
+
+	field := r.p.field
+	values, exist := reader.GetPointValues(field)
+	if !exist {
 		// No docs in this segment/field indexed any points
 		return nil, nil
 	}
 
-	if dims, _ := values.GetNumIndexDimensions(); dims != r.p.numDims {
-		return nil, errors.New("numIndexDims not fit")
+	dimensions, err := values.GetNumIndexDimensions()
+	if err != nil {
+		return nil, err
+	}
+	if dimensions != r.p.numDims {
+		return nil, fmt.Errorf("field=%s numIndexDimensions not equal", field)
 	}
 
-	if dims, _ := values.GetBytesPerDimension(); dims != r.p.bytesPerDim {
-		return nil, errors.New("bytesPerDim not fit")
+	bytesPerDimension, err := values.GetBytesPerDimension()
+	if err != nil {
+		return nil, err
+	}
+	if bytesPerDimension != r.p.bytesPerDim {
+		return nil, fmt.Errorf("field=%s bytesPerDim not equal", field)
 	}
 
-	result := NewDocIdSetBuilderV2(reader.MaxDoc(), values, r.p.field)
+	result := NewDocIdSetBuilderV2(reader.MaxDoc(), values, field)
 	if r.p.numDims == 1 {
 		// We optimize this common case, effectively doing a merge sort of the indexed values vs the queried set:
-		visitor, err := r.p.NewMergePointVisitor(r.p.sortedPackedPoints, result)
-		if err != nil {
-			return nil, err
-		}
-		err = values.Intersect(nil, visitor)
-		if err != nil {
+		if err := values.Intersect(nil, &mergePointVisitor{p: r.p, result: result}); err != nil {
 			return nil, err
 		}
 	} else {
 		// NOTE: this is naive implementation, where for each point we re-walk the KD tree to intersect.
-		// We could instead do a similar
-		// optimization as the 1D case, but I think it'd mean building a query-time KD tree so we could
-		// efficiently intersect against the
-		// index, which is probably tricky!
-		visitor := r.p.NewSinglePointVisitor(result)
-		iterator, err := r.p.sortedPackedPoints.Iterator()
-		if err != nil {
-			return nil, err
-		}
-
-		for {
-			point, err := iterator.Next(nil)
-			if err != nil {
-				if errors.Is(err, io.EOF) {
-					break
-				}
-				return nil, err
-			}
-			visitor.SetPoint(point)
-			err = values.Intersect(nil, visitor)
-			if err != nil {
+		// We could instead do a similar optimization as the 1D case, but I think it'd mean building a
+		// query-time KD tree so we could efficiently intersect against the index, which is probably tricky!
+		visitor := &singlePointVisitor{p: r.p, result: result}
+		for _, point := range r.p.sortedPackedPoints {
+			visitor.pointBytes = point
+			if err := values.Intersect(nil, visitor); err != nil {
 				return nil, err
 			}
 		}
 	}
-	return NewConstantScoreScorer(r, r.score, r.scoreMode, result.Build().Iterator())
+	return NewConstantScoreScorer(r, r.Score(), r.scoreMode, result.Build().Iterator())
 }
 
-func (r *pisQueryWeight) IsCacheable(ctx *index.LeafReaderContext) bool {
+func (r *pisQueryWeight) IsCacheable(ctx coreIndex.LeafReaderContext) bool {
 	return true
 }
 
-func (p *PointInSetQuery) Rewrite(reader index.Reader) (Query, error) {
-	return p, nil
-}
-
-func (p *PointInSetQuery) Visit(visitor QueryVisitor) (err error) {
-	if visitor.AcceptField(p.field) {
-		return visitor.VisitLeaf(p)
-	}
-	return nil
-}
+var _ types.IntersectVisitor = &mergePointVisitor{}
 
-var _ types.IntersectVisitor = &MergePointVisitor{}
+// mergePointVisitor Essentially does a merge sort, only collecting hits when the indexed point and
+// query point are the same. This is an optimization, used in the 1D case.
+type mergePointVisitor struct {
+	p      *PointInSetQuery
+	result *DocIdSetBuilder
+	adder  BulkAdder
 
-type MergePointVisitor struct {
-	result             *DocIdSetBuilder
-	iterator           *index.TermIterator
-	nextQueryPoint     []byte
-	scratch            []byte
-	sortedPackedPoints *index.PrefixCodedTerms
-	adder              BulkAdder
+	// index of the next query point in sortedPackedPoints
+	upto int
 }
 
-func (p *PointInSetQuery) NewMergePointVisitor(sortedPackedPoints *index.PrefixCodedTerms,
-	result *DocIdSetBuilder) (*MergePointVisitor, error) {
-	iterator, err := sortedPackedPoints.Iterator()
-	if err != nil {
-		return nil, err
+func (m *mergePointVisitor) nextQueryPoint() []byte {
+	if m.upto < len(m.p.sortedPackedPoints) {
+		return m.p.sortedPackedPoints[m.upto]
 	}
-
-	next, err := iterator.Next(nil)
-	if err != nil {
-		return nil, err
-	}
-
-	return &MergePointVisitor{
-		result:             result,
-		sortedPackedPoints: sortedPackedPoints,
-		scratch:            make([]byte, p.bytesPerDim),
-		iterator:           iterator,
-		nextQueryPoint:     next,
-	}, nil
+	return nil
 }
 
-func (m *MergePointVisitor) Visit(docID int) error {
+func (m *mergePointVisitor) Visit(ctx context.Context, docID int) error {
 	m.adder.Add(docID)
 	return nil
 }
 
-func (m *MergePointVisitor) VisitLeaf(docID int, packedValue []byte) error {
+func (m *mergePointVisitor) VisitLeaf(ctx context.Context, docID int, packedValue []byte) error {
 	if m.matches(packedValue) {
-		return m.Visit(docID)
+		return m.Visit(nil, docID)
 	}
 	return nil
 }
 
-func (m *MergePointVisitor) VisitIterator(iterator types.DocValuesIterator, packedValue []byte) error {
+func (m *mergePointVisitor) VisitIterator(iterator types.DocValuesIterator, packedValue []byte) error {
 	if m.matches(packedValue) {
 		for {
-			docID, err := iterator.NextDoc()
+			doc, err := iterator.NextDoc()
 			if err != nil {
 				if errors.Is(err, io.EOF) {
-					break
+					return nil
 				}
 				return err
 			}
-			err = m.Visit(docID)
-			if err != nil {
+			if err := m.Visit(nil, doc); err != nil {
 				return err
 			}
 		}
@@ -205,43 +220,31 @@ func (m *MergePointVisitor) VisitIterator(iterator types.DocValuesIterator, pack
 	return nil
 }
 
-func (m *MergePointVisitor) matches(packedValue []byte) bool {
-	m.scratch = packedValue
-	for m.nextQueryPoint != nil {
-		cmp := bytes.Compare(m.nextQueryPoint, m.scratch)
-		switch {
-		case cmp == 0:
+func (m *mergePointVisitor) matches(packedValue []byte) bool {
+	for point := m.nextQueryPoint(); point != nil; point = m.nextQueryPoint() {
+		cmp := bytes.Compare(point, packedValue)
+		if cmp == 0 {
 			return true
-		case cmp < 0:
-			next, err := m.iterator.Next(nil)
-			if err != nil {
-				if errors.Is(err, io.EOF) {
-					break
-				}
-				return false
-			}
-			m.nextQueryPoint = next
-		default:
+		}
+		if cmp > 0 {
 			break
 		}
+		// query point is before the indexed value
+		m.upto++
 	}
 	return false
 }
 
-func (m *MergePointVisitor) Compare(minPackedValue, maxPackedValue []byte) types.Relation {
-	var err error
-	for m.nextQueryPoint != nil {
-		cmpMin := bytes.Compare(m.nextQueryPoint, minPackedValue)
+func (m *mergePointVisitor) Compare(minPackedValue, maxPackedValue []byte) types.Relation {
+	for point := m.nextQueryPoint(); point != nil; point = m.nextQueryPoint() {
+		cmpMin := bytes.Compare(point, minPackedValue)
 		if cmpMin < 0 {
 			// query point is before the start of this cell
-			m.nextQueryPoint, err = m.iterator.Next(nil)
-			if err != nil {
-				break
-			}
+			m.upto++
 			continue
 		}
 
-		cmpMax := bytes.Compare(m.nextQueryPoint, maxPackedValue)
+		cmpMax := bytes.Compare(point, maxPackedValue)
 		if cmpMax > 0 {
 			// query point is after the end of this cell
 			return types.CELL_OUTSIDE_QUERY
@@ -251,66 +254,56 @@ func (m *MergePointVisitor) Compare(minPackedValue, maxPackedValue []byte) types
 			// NOTE: we only hit this if we are on a cell whose min and max values are exactly equal to our point,
 			// which can easily happen if many (> 1024) docs share this one value
 			return types.CELL_INSIDE_QUERY
-		} else {
-			return types.CELL_CROSSES_QUERY
 		}
+		return types.CELL_CROSSES_QUERY
 	}
 
 	// We exhausted all points in the query:
 	return types.CELL_OUTSIDE_QUERY
 }
 
-func (m *MergePointVisitor) Grow(count int) {
+func (m *mergePointVisitor) Grow(count int) {
 	m.adder = m.result.Grow(count)
 }
 
-var _ types.IntersectVisitor = &SinglePointVisitor{}
+var _ types.IntersectVisitor = &singlePointVisitor{}
 
-type SinglePointVisitor struct {
+// singlePointVisitor IntersectVisitor that queries against a highly degenerate shape: a single
+// point. This is used in the > 1D case.
+type singlePointVisitor struct {
+	p          *PointInSetQuery
 	result     *DocIdSetBuilder
-	pointBytes []byte
 	adder      BulkAdder
-	p          *PointInSetQuery
-}
-
-func (p *PointInSetQuery) NewSinglePointVisitor(result *DocIdSetBuilder) *SinglePointVisitor {
-	return &SinglePointVisitor{
-		result:     result,
-		pointBytes: make([]byte, p.bytesPerDim*p.numDims),
-		p:          p,
-	}
-}
-
-func (s *SinglePointVisitor) SetPoint(point []byte) {
-	copy(s.pointBytes, point)
+	pointBytes []byte
 }
 
-func (s *SinglePointVisitor) Visit(docID int) error {
+func (s *singlePointVisitor) Visit(ctx context.Context, docID int) error {
 	s.adder.Add(docID)
 	return nil
 }
 
-func (s *SinglePointVisitor) VisitLeaf(docID int, packedValue []byte) error {
+func (s *singlePointVisitor) VisitLeaf(ctx context.Context, docID int, packedValue []byte) error {
 	if bytes.Equal(packedValue, s.pointBytes) {
 		// The point for this doc matches the point we are querying on
-		return s.Visit(docID)
+		return s.Visit(nil, docID)
 	}
 	return nil
 }
 
-func (s *SinglePointVisitor) Compare(minPackedValue, maxPackedValue []byte) types.Relation {
+func (s *singlePointVisitor) Compare(minPackedValue, maxPackedValue []byte) types.Relation {
 	crosses := false
 
 	bytesPerDim := s.p.bytesPerDim
 	for dim := 0; dim < s.p.numDims; dim++ {
 		offset := dim * bytesPerDim
+		toIndex := offset + bytesPerDim
 
-		cmpMin := bytes.Compare(minPackedValue[offset:offset+bytesPerDim], s.pointBytes[offset:offset+bytesPerDim])
+		cmpMin := bytes.Compare(minPackedValue[offset:toIndex], s.pointBytes[offset:toIndex])
 		if cmpMin > 0 {
 			return types.CELL_OUTSIDE_QUERY
 		}
 
-		cmpMax := bytes.Compare(maxPackedValue[offset:offset+bytesPerDim], s.pointBytes[offset:offset+bytesPerDim])
+		cmpMax := bytes.Compare(maxPackedValue[offset:toIndex], s.pointBytes[offset:toIndex])
 		if cmpMax < 0 {
 			return types.CELL_OUTSIDE_QUERY
 		}
@@ -329,9 +322,6 @@ func (s *SinglePointVisitor) Compare(minPackedValue, maxPackedValue []byte) type
 	return types.CELL_INSIDE_QUERY
 }
 
-func (s *SinglePointVisitor) Grow(count int) {
+func (s *singlePointVisitor) Grow(count int) {
 	s.adder = s.result.Grow(count)
 }
-
-
-*/
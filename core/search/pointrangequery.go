@@ -205,7 +205,11 @@ func (p *prQueryVisitor) Grow(count int) {
 }
 
 func (r *prQueryWeight) getInverseIntersectVisitor(result *bitset.BitSet, cost []int64) types.IntersectVisitor {
-	panic("")
+	return &invPrQueryVisitor{
+		result: result,
+		cost:   cost,
+		weight: r,
+	}
 }
 
 var _ types.IntersectVisitor = &invPrQueryVisitor{}
@@ -378,9 +382,7 @@ func (r *notAllDocsScorerSupplier) Get(leadCost int64) (coreIndex.Scorer, error)
 		// than half the leaf size then maybe we can make things faster
 		// by computing the set of documents that do NOT match the range
 		result := bitset.New(uint(r.reader.MaxDoc()))
-		for i := range result.Bytes() {
-			result.Bytes()[i] = math.MaxUint64
-		}
+		result.FlipRange(0, uint(r.reader.MaxDoc()))
 		cost := []int64{int64(r.reader.MaxDoc())}
 		err := r.values.Intersect(nil, r.weight.getInverseIntersectVisitor(result, cost))
 		if err != nil {
@@ -0,0 +1,109 @@
+package search_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/geange/lucene-go/codecs/lucene87"
+	"github.com/geange/lucene-go/core/document"
+	coreIndex "github.com/geange/lucene-go/core/index"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/search"
+	"github.com/geange/lucene-go/core/store"
+	"github.com/geange/lucene-go/core/util/numeric"
+	"github.com/stretchr/testify/assert"
+)
+
+// indexes 100 documents in two segments: document i has the long point i
+func newTestPointSearcher(t *testing.T) index.IndexSearcher {
+	ctx := context.Background()
+	dir := store.NewByteBuffersDirectory()
+	similarity, err := search.NewBM25Similarity()
+	assert.Nil(t, err)
+	config := coreIndex.NewIndexWriterConfig(lucene87.NewCodec(lucene87.BEST_SPEED), similarity)
+	writer, err := coreIndex.NewIndexWriter(ctx, dir, config)
+	assert.Nil(t, err)
+
+	for i := 0; i < 100; i++ {
+		doc := document.NewDocument()
+		point, err := document.NewLongPoint("p", int64(i))
+		assert.Nil(t, err)
+		doc.Add(&point)
+		_, err = writer.AddDocument(ctx, doc)
+		assert.Nil(t, err)
+		if i == 49 {
+			assert.Nil(t, writer.Commit(ctx))
+		}
+	}
+	assert.Nil(t, writer.Commit(ctx))
+	assert.Nil(t, writer.Close())
+
+	reader, err := coreIndex.OpenDirectoryReader(ctx, dir, nil, nil)
+	assert.Nil(t, err)
+	leaves, err := reader.Leaves()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(leaves))
+	searcher, err := search.NewIndexSearcher(reader)
+	assert.Nil(t, err)
+	return searcher
+}
+
+func packLong(value int64) []byte {
+	packed := make([]byte, 8)
+	numeric.LongToSortableBytes(uint64(value), packed)
+	return packed
+}
+
+func TestPointRangeQuery(t *testing.T) {
+	searcher := newTestPointSearcher(t)
+
+	tests := []struct {
+		lower, upper int64
+		expected     []int
+	}{
+		{10, 14, []int{10, 11, 12, 13, 14}},
+		// across the two segments
+		{48, 51, []int{48, 49, 50, 51}},
+		{99, 200, []int{99}},
+		// more than half of each segment matches, so the non-matching documents are collected instead
+		{5, 94, docsBetween(5, 94)},
+		{-10, -1, []int{}},
+	}
+	for _, test := range tests {
+		query, err := search.NewPointRangeQuery("p", packLong(test.lower), packLong(test.upper), 1)
+		assert.Nil(t, err)
+		assert.ElementsMatch(t, test.expected, hitDocs(searchTestHits(t, searcher, query)))
+	}
+
+	count, err := searcher.Count(mustPointRangeQuery(t, 0, 99))
+	assert.Nil(t, err)
+	assert.Equal(t, 100, count)
+}
+
+func docsBetween(from, to int) []int {
+	docs := make([]int, 0)
+	for doc := from; doc <= to; doc++ {
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func mustPointRangeQuery(t *testing.T, lower, upper int64) index.Query {
+	query, err := search.NewPointRangeQuery("p", packLong(lower), packLong(upper), 1)
+	assert.Nil(t, err)
+	return query
+}
+
+func TestPointInSetQuery(t *testing.T) {
+	searcher := newTestPointSearcher(t)
+
+	query, err := search.NewPointInSetQuery(context.Background(), "p", 1, 8,
+		[][]byte{packLong(3), packLong(50), packLong(99), packLong(200)})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []int{3, 50, 99}, hitDocs(searchTestHits(t, searcher, query)))
+}
+
+func TestNewPointInSetQueryOutOfOrder(t *testing.T) {
+	_, err := search.NewPointInSetQuery(context.Background(), "p", 1, 8, [][]byte{packLong(2), packLong(1)})
+	assert.NotNil(t, err)
+}
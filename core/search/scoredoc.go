@@ -44,6 +44,11 @@ func (s *baseScoreDoc) SetShardIndex(shardIndex int) {
 	s.shardIndex = shardIndex
 }
 
+// NewScoreDoc Constructs a ScoreDoc.
+func NewScoreDoc(doc int, score float64) index.ScoreDoc {
+	return newScoreDoc(doc, score)
+}
+
 func newScoreDoc(doc int, score float64) *baseScoreDoc {
 	return &baseScoreDoc{score: score, doc: doc, shardIndex: -1}
 }
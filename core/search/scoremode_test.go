@@ -0,0 +1,21 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoreMode(t *testing.T) {
+	assert.True(t, COMPLETE.NeedsScores())
+	assert.True(t, COMPLETE.IsExhaustive())
+
+	assert.False(t, COMPLETE_NO_SCORES.NeedsScores())
+	assert.True(t, COMPLETE_NO_SCORES.IsExhaustive())
+
+	assert.True(t, TOP_SCORES.NeedsScores())
+	assert.False(t, TOP_SCORES.IsExhaustive())
+
+	assert.False(t, TOP_DOCS.NeedsScores())
+	assert.False(t, TOP_DOCS.IsExhaustive())
+}
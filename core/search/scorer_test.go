@@ -0,0 +1,137 @@
+package search
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type testScoredDoc struct {
+	doc   int
+	score float64
+}
+
+// newTestScorer returns a scorer that matches docs, in order, with the same score on each of them
+func newTestScorer(t *testing.T, score float64, docs ...int) index.Scorer {
+	scorer, err := NewConstantScoreScorer(nil, score, COMPLETE, NewIntArrayDocIdSetIterator(docs))
+	assert.Nil(t, err)
+	return scorer
+}
+
+// collectTestScores iterates the scorer to the end and returns the docs it matched with their scores
+func collectTestScores(t *testing.T, scorer index.Scorer) []testScoredDoc {
+	it := scorer.Iterator()
+	docs := make([]testScoredDoc, 0)
+	for {
+		doc, err := it.NextDoc()
+		if errors.Is(err, io.EOF) || doc == types.NO_MORE_DOCS {
+			return docs
+		}
+		if !assert.Nil(t, err) {
+			return docs
+		}
+		score, err := scorer.Score()
+		assert.Nil(t, err)
+		docs = append(docs, testScoredDoc{doc: doc, score: score})
+	}
+}
+
+func TestConjunctionScorer(t *testing.T) {
+	a := newTestScorer(t, 1, 1, 3, 5, 7, 9)
+	b := newTestScorer(t, 2, 3, 4, 5, 9, 10)
+	filter := newTestScorer(t, 4, 0, 3, 9)
+
+	// the filter is required but does not contribute to the score
+	scorer, err := NewConjunctionScorer(nil, []index.Scorer{a, b, filter}, []index.Scorer{a, b})
+	assert.Nil(t, err)
+	assert.Equal(t, []testScoredDoc{{3, 3}, {9, 3}}, collectTestScores(t, scorer))
+}
+
+func TestConjunctionScorer_Advance(t *testing.T) {
+	a := newTestScorer(t, 1, 1, 3, 5, 7, 9)
+	b := newTestScorer(t, 1, 1, 5, 7, 8, 9)
+
+	scorer, err := NewConjunctionScorer(nil, []index.Scorer{a, b}, []index.Scorer{a, b})
+	assert.Nil(t, err)
+
+	doc, err := scorer.Iterator().Advance(6)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, doc)
+	assert.Equal(t, 7, scorer.DocID())
+}
+
+func TestDisjunctionSumScorer(t *testing.T) {
+	a := newTestScorer(t, 1, 1, 3, 5)
+	b := newTestScorer(t, 2, 3, 4)
+	c := newTestScorer(t, 4, 0, 3, 5)
+
+	scorer, err := newDisjunctionScorer(nil, []index.Scorer{a, b, c}, COMPLETE)
+	assert.Nil(t, err)
+	assert.Equal(t, []testScoredDoc{{0, 4}, {1, 1}, {3, 7}, {4, 2}, {5, 5}}, collectTestScores(t, scorer))
+}
+
+func TestDisjunctionSumScorer_TooFewScorers(t *testing.T) {
+	_, err := newDisjunctionScorer(nil, []index.Scorer{newTestScorer(t, 1, 1)}, COMPLETE)
+	assert.NotNil(t, err)
+}
+
+func TestDisjunctionDISIApproximation_Advance(t *testing.T) {
+	queue := NewDisiPriorityQueue(2)
+	queue.Add(NewDisiWrapper(newTestScorer(t, 1, 1, 4, 8)))
+	queue.Add(NewDisiWrapper(newTestScorer(t, 1, 2, 6)))
+	approximation := NewDisjunctionDISIApproximation(queue)
+	assert.EqualValues(t, 5, approximation.Cost())
+
+	doc, err := approximation.Advance(5)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, doc)
+	doc, err = approximation.NextDoc()
+	assert.Nil(t, err)
+	assert.Equal(t, 8, doc)
+	doc, err = approximation.NextDoc()
+	assert.Nil(t, err)
+	assert.Equal(t, types.NO_MORE_DOCS, doc)
+}
+
+func TestWANDScorer(t *testing.T) {
+	a := newTestScorer(t, 1, 1, 3, 5)
+	b := newTestScorer(t, 2, 3, 4)
+	c := newTestScorer(t, 4, 0, 3, 5)
+
+	t.Run("minShouldMatch 1", func(t *testing.T) {
+		scorer, err := newWANDScorer(nil, []index.Scorer{a, b, c}, 1, COMPLETE)
+		assert.Nil(t, err)
+		assert.Equal(t, []testScoredDoc{{0, 4}, {1, 1}, {3, 7}, {4, 2}, {5, 5}}, collectTestScores(t, scorer))
+	})
+
+	t.Run("minShouldMatch 2", func(t *testing.T) {
+		a := newTestScorer(t, 1, 1, 3, 5)
+		b := newTestScorer(t, 2, 3, 4)
+		c := newTestScorer(t, 4, 0, 3, 5)
+
+		scorer, err := newWANDScorer(nil, []index.Scorer{a, b, c}, 2, COMPLETE)
+		assert.Nil(t, err)
+		assert.Equal(t, []testScoredDoc{{3, 7}, {5, 5}}, collectTestScores(t, scorer))
+	})
+
+	t.Run("minShouldMatch must be less than the number of scorers", func(t *testing.T) {
+		_, err := newWANDScorer(nil, []index.Scorer{a, b}, 2, COMPLETE)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestWANDScorer_MinCompetitiveScore(t *testing.T) {
+	a := newTestScorer(t, 1, 1, 3, 5)
+	b := newTestScorer(t, 2, 3, 4)
+	c := newTestScorer(t, 4, 0, 3, 5)
+
+	scorer, err := newWANDScorer(nil, []index.Scorer{a, b, c}, 0, TOP_SCORES)
+	assert.Nil(t, err)
+	// only docs whose score can reach 5 are returned
+	assert.Nil(t, scorer.SetMinCompetitiveScore(5))
+	assert.Equal(t, []testScoredDoc{{3, 7}, {5, 5}}, collectTestScores(t, scorer))
+}
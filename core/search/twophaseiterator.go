@@ -52,7 +52,7 @@ func (t *twoPhaseIteratorAsDocIdSetIterator) Cost() int64 {
 func (t *twoPhaseIteratorAsDocIdSetIterator) doNext(doc int) (int, error) {
 	for {
 		if doc == types.NO_MORE_DOCS {
-			return 0, io.EOF
+			return types.NO_MORE_DOCS, io.EOF
 		}
 
 		isMatch, err := t.twoPhaseIterator.Matches()
@@ -63,7 +63,10 @@ func (t *twoPhaseIteratorAsDocIdSetIterator) doNext(doc int) (int, error) {
 			return doc, nil
 		}
 
-		doc = t.approximation.DocID()
+		doc, err = t.approximation.NextDoc()
+		if err != nil {
+			return exhaustedDoc(err)
+		}
 	}
 }
 
@@ -1,6 +1,9 @@
 package search
 
 import (
+	"errors"
+	"math"
+
 	"github.com/geange/lucene-go/core/interface/index"
 	"github.com/geange/lucene-go/core/types"
 )
@@ -21,55 +24,560 @@ var _ index.Scorer = &WANDScorer{}
 type WANDScorer struct {
 	*BaseScorer
 
-	scalingFactor       int
+	scalingFactor int
+	// scaled min competitive score
 	minCompetitiveScore int64
 
-	// TODO
+	// list of scorers which 'lead' the iteration and are currently
+	// positioned on 'doc'. This is sometimes called the 'pivot' in
+	// some descriptions of WAND (Weak AND).
+	lead         *DisiWrapper
+	doc          int   // current doc ID of the leads
+	leadMaxScore int64 // sum of the max scores of scorers in 'lead'
+
+	// priority queue of scorers that are too advanced compared to the current
+	// doc. Ordered by doc ID.
+	head *DisiPriorityQueue
+
+	// priority queue of scorers which are behind the current doc.
+	// Ordered by maxScore in descending order
+	tail         []*DisiWrapper
+	tailMaxScore int64 // sum of the max scores of scorers in 'tail'
+	tailSize     int
+
+	cost int64
+
+	upTo int // upper bound for which max scores are valid
+
+	minShouldMatch int
+	freq           int
+
+	scoreMode index.ScoreMode
+}
+
+const (
+	floatMantissaBits = 24
+
+	// scaled max scores are capped so that their sums can't overflow
+	maxScaledMaxScore = int64(1) << 53
+)
+
+// Return a scaling factor for the given float so that f x 2^scalingFactor would be in ]2^15, 2^16].
+// Special cases:
+// * scalingFactor(0) = scalingFactor(MIN_VALUE) + 1
+// * scalingFactor(+Infty) = scalingFactor(MAX_VALUE) - 1
+func scalingFactor(f float64) int {
+	switch {
+	case f == 0:
+		return scalingFactor(math.SmallestNonzeroFloat32) + 1
+	case math.IsInf(f, 1):
+		return scalingFactor(math.MaxFloat32) - 1
+	default:
+		_, exp := math.Frexp(f)
+		// Frexp returns an exponent which is one more than the unbiased exponent of f
+		return floatMantissaBits - 1 - (exp - 1)
+	}
+}
+
+// Scale max scores in an unsigned integer to avoid overflows (only the lower 32 bits of the long
+// are used) as well as floating-point arithmetic errors. Those are rounded up in order to make
+// sure we do not miss any matches.
+func scaleMaxScore(maxScore float64, scalingFactor int) int64 {
+	scaled := math.Ceil(math.Ldexp(maxScore, scalingFactor))
+	if scaled >= float64(maxScaledMaxScore) {
+		return maxScaledMaxScore
+	}
+	return int64(scaled)
+}
+
+// Scale min competitive scores the same way as max scores but this time by rounding down in order
+// to make sure that we do not miss any matches.
+func scaleMinScore(minScore float64, scalingFactor int) int64 {
+	scaled := math.Floor(math.Ldexp(minScore, scalingFactor))
+	if scaled >= float64(math.MaxInt64) {
+		return math.MaxInt64
+	}
+	return int64(scaled)
 }
 
 func newWANDScorer(weight index.Weight, scorers []index.Scorer, minShouldMatch int, scoreMode index.ScoreMode) (*WANDScorer, error) {
-	panic("")
+	if minShouldMatch >= len(scorers) {
+		return nil, errors.New("minShouldMatch should be < the number of scorers")
+	}
+
+	w := &WANDScorer{
+		BaseScorer:     NewScorer(weight),
+		minShouldMatch: minShouldMatch,
+		scoreMode:      scoreMode,
+		doc:            -1,
+		upTo:           -1, // will be computed on the first call to nextDoc/advance
+		head:           NewDisiPriorityQueue(len(scorers)),
+		// there can be at most num_scorers - 1 scorers beyond the current position
+		tail: make([]*DisiWrapper, len(scorers)),
+	}
+
+	if scoreMode == TOP_SCORES {
+		factor, found := math.MaxInt, false
+		for _, scorer := range scorers {
+			if _, err := scorer.AdvanceShallow(0); err != nil {
+				return nil, err
+			}
+			maxScore, err := scorer.GetMaxScore(types.NO_MORE_DOCS)
+			if err != nil {
+				return nil, err
+			}
+			if maxScore != 0 && !math.IsInf(maxScore, 0) {
+				// 0 and +Infty should not impact the scale
+				factor, found = min(factor, scalingFactor(maxScore)), true
+			}
+		}
+		// Use a scaling factor of 0 if all max scores are either 0 or +Infty
+		if found {
+			w.scalingFactor = factor
+		}
+	}
+
+	costs := make([]int64, 0, len(scorers))
+	for _, scorer := range scorers {
+		w.addLead(NewDisiWrapper(scorer))
+		costs = append(costs, scorer.Iterator().Cost())
+	}
+	w.cost = costWithMinShouldMatch(costs, len(scorers), minShouldMatch)
+	return w, nil
 }
 
-func (w *WANDScorer) Score() (float64, error) {
-	//TODO implement me
-	panic("implement me")
+func (w *WANDScorer) SetMinCompetitiveScore(minScore float64) error {
+	// Let this disjunction know about the new min score so that it can skip
+	// over clauses that produce low scores.
+	if w.scoreMode != TOP_SCORES {
+		return errors.New("minCompetitiveScore can only be set for ScoreMode.TOP_SCORES")
+	}
+	w.minCompetitiveScore = scaleMinScore(minScore, w.scalingFactor)
+	return nil
 }
 
-func (w *WANDScorer) DocID() int {
-	//TODO implement me
-	panic("implement me")
+func (w *WANDScorer) GetChildren() ([]index.ChildScorable, error) {
+	if err := w.updateFreq(); err != nil {
+		return nil, err
+	}
+	children := make([]index.ChildScorable, 0)
+	for s := w.lead; s != nil; s = s.next {
+		children = append(children, NewChildScorable(s.scorer, "SHOULD"))
+	}
+	return children, nil
 }
 
 func (w *WANDScorer) Iterator() types.DocIdSetIterator {
-	//TODO implement me
-	panic("implement me")
+	return AsDocIdSetIterator(w.TwoPhaseIterator())
 }
 
-func (w *WANDScorer) GetMaxScore(upTo int) (float64, error) {
-	//TODO implement me
-	panic("implement me")
-}
-
-// DisiWrapper
-// Wrapper used in DisiPriorityQueue.
-// lucene.internal
-type DisiWrapper struct {
-	iterator  types.DocIdSetIterator
-	scorer    index.Scorer
-	cost      int64
-	matchCost float64      // the match cost for two-phase iterators, 0 otherwise
-	doc       int          // the current doc, used for comparison
-	next      *DisiWrapper // reference to a next element, see #topList
-
-	// An approximation of the iterator, or the iterator itself if it does not
-	// support two-phase iteration
+func (w *WANDScorer) TwoPhaseIterator() index.TwoPhaseIterator {
+	return &wandTwoPhaseIterator{
+		approximation: &wandApproximation{w: w},
+		w:             w,
+	}
+}
+
+var _ types.DocIdSetIterator = &wandApproximation{}
+
+type wandApproximation struct {
+	w *WANDScorer
+}
+
+func (a *wandApproximation) DocID() int {
+	return a.w.doc
+}
+
+func (a *wandApproximation) NextDoc() (int, error) {
+	return a.Advance(a.w.doc + 1)
+}
+
+func (a *wandApproximation) Advance(target int) (int, error) {
+	w := a.w
+
+	// Move 'lead' iterators back to the tail
+	if err := w.pushBackLeads(target); err != nil {
+		return 0, err
+	}
+
+	// Advance 'head' as well
+	if err := w.advanceHead(target); err != nil {
+		return 0, err
+	}
+
+	// Pop the new 'lead' from 'head'
+	if err := w.moveToNextCandidate(target); err != nil {
+		return 0, err
+	}
+
+	if w.doc == types.NO_MORE_DOCS {
+		return types.NO_MORE_DOCS, nil
+	}
+
+	// Advance to the next possible match
+	return w.doNextCompetitiveCandidate()
+}
+
+func (a *wandApproximation) SlowAdvance(target int) (int, error) {
+	return types.SlowAdvance(a, target)
+}
+
+func (a *wandApproximation) Cost() int64 {
+	return a.w.cost
+}
+
+var _ index.TwoPhaseIterator = &wandTwoPhaseIterator{}
+
+type wandTwoPhaseIterator struct {
 	approximation types.DocIdSetIterator
+	w             *WANDScorer
+}
 
-	// A two-phase view of the iterator, or null if the iterator does not support
-	// two-phase iteration
-	twoPhaseView index.TwoPhaseIterator
+func (t *wandTwoPhaseIterator) Approximation() types.DocIdSetIterator {
+	return t.approximation
+}
+
+func (t *wandTwoPhaseIterator) Matches() (bool, error) {
+	w := t.w
+	for w.leadMaxScore < w.minCompetitiveScore || w.freq < w.minShouldMatch {
+		if w.leadMaxScore+w.tailMaxScore < w.minCompetitiveScore || w.freq+w.tailSize < w.minShouldMatch {
+			return false, nil
+		}
+		// a match on doc is still possible, try to
+		// advance scorers from the tail
+		if err := w.advanceTail(); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (t *wandTwoPhaseIterator) MatchCost() float64 {
+	// maximum number of scorer that matches() might advance
+	return float64(len(t.w.tail))
+}
+
+func (w *WANDScorer) addLead(lead *DisiWrapper) {
+	lead.next = w.lead
+	w.lead = lead
+	w.leadMaxScore += lead.maxScore
+	w.freq++
+}
+
+func (w *WANDScorer) pushBackLeads(target int) error {
+	for s := w.lead; s != nil; s = s.next {
+		evicted := w.insertTailWithOverFlow(s)
+		if evicted != nil {
+			doc, err := noMoreDocsOnEOF(evicted.iterator.Advance(target))
+			if err != nil {
+				return err
+			}
+			evicted.doc = doc
+			w.head.Add(evicted)
+		}
+	}
+	w.lead = nil
+	return nil
+}
+
+func (w *WANDScorer) advanceHead(target int) error {
+	headTop := w.head.Top()
+	for headTop != nil && headTop.doc < target {
+		evicted := w.insertTailWithOverFlow(headTop)
+		if evicted != nil {
+			doc, err := noMoreDocsOnEOF(evicted.iterator.Advance(target))
+			if err != nil {
+				return err
+			}
+			evicted.doc = doc
+			headTop = w.head.UpdateTopWith(evicted)
+		} else {
+			w.head.Pop()
+			headTop = w.head.Top()
+		}
+	}
+	return nil
+}
+
+func (w *WANDScorer) advanceTailEntry(disi *DisiWrapper) error {
+	doc, err := noMoreDocsOnEOF(disi.iterator.Advance(w.doc))
+	if err != nil {
+		return err
+	}
+	disi.doc = doc
+	if disi.doc == w.doc {
+		w.addLead(disi)
+	} else {
+		w.head.Add(disi)
+	}
+	return nil
+}
+
+func (w *WANDScorer) advanceTail() error {
+	return w.advanceTailEntry(w.popTail())
+}
+
+func (w *WANDScorer) updateMaxScores(target int) error {
+	if w.head.Size() == 0 {
+		// If the head is empty we use the greatest score contributor as a lead
+		// like for conjunctions.
+		upTo, err := w.tail[0].scorer.AdvanceShallow(target)
+		if err != nil {
+			return err
+		}
+		w.upTo = upTo
+	} else {
+		// If we still have entries in 'head', we treat them all as leads and
+		// take the minimum of their next block boundaries as a next boundary.
+		// We don't take entries in 'tail' into account on purpose: 'tail' is
+		// supposed to contain the least score contributors, and taking them
+		// into account might not move the boundary fast enough, so we'll waste
+		// CPU re-computing the next boundary all the time.
+		newUpTo := types.NO_MORE_DOCS
+		for _, disi := range w.head.All() {
+			if disi.doc <= newUpTo {
+				upTo, err := disi.scorer.AdvanceShallow(disi.doc)
+				if err != nil {
+					return err
+				}
+				newUpTo = min(upTo, newUpTo)
+				maxScore, err := disi.scorer.GetMaxScore(newUpTo)
+				if err != nil {
+					return err
+				}
+				disi.maxScore = scaleMaxScore(maxScore, w.scalingFactor)
+			}
+		}
+		w.upTo = newUpTo
+	}
+
+	w.tailMaxScore = 0
+	for i := 0; i < w.tailSize; i++ {
+		disi := w.tail[i]
+		if _, err := disi.scorer.AdvanceShallow(target); err != nil {
+			return err
+		}
+		maxScore, err := disi.scorer.GetMaxScore(w.upTo)
+		if err != nil {
+			return err
+		}
+		disi.maxScore = scaleMaxScore(maxScore, w.scalingFactor)
+		upHeapMaxScore(w.tail, i) // the heap might need to be reordered
+		w.tailMaxScore += disi.maxScore
+	}
+
+	// We need to make sure that entries in 'tail' alone cannot match
+	// a competitive hit.
+	for w.tailSize > 0 && w.tailMaxScore >= w.minCompetitiveScore {
+		disi := w.popTail()
+		doc, err := noMoreDocsOnEOF(disi.iterator.Advance(target))
+		if err != nil {
+			return err
+		}
+		disi.doc = doc
+		w.head.Add(disi)
+	}
+	return nil
+}
+
+// Update upTo and maximum scores of sub scorers so that upTo is greater than or equal to the next
+// candidate after target, i.e. the top of `head`.
+func (w *WANDScorer) updateMaxScoresIfNecessary(target int) error {
+	if w.head.Size() == 0 { // no matches in the current block
+		if w.upTo != types.NO_MORE_DOCS {
+			return w.updateMaxScores(max(target, w.upTo+1))
+		}
+	} else if w.head.Top().doc > w.upTo { // the next candidate is in a different block
+		return w.updateMaxScores(target)
+	}
+	return nil
+}
+
+// Set 'doc' to the next potential match, and move all matches of 'head' that are on this doc into
+// 'lead'.
+func (w *WANDScorer) moveToNextCandidate(target int) error {
+	if w.scoreMode == TOP_SCORES {
+		// Update score bounds if necessary so
+		if err := w.updateMaxScoresIfNecessary(target); err != nil {
+			return err
+		}
+
+		// If the head is empty, it means that the sum of all max scores is not
+		// enough to produce a competitive score. So we jump to the next block.
+		for w.head.Size() == 0 {
+			if w.upTo == types.NO_MORE_DOCS {
+				w.doc = types.NO_MORE_DOCS
+				return nil
+			}
+			if err := w.updateMaxScores(w.upTo + 1); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The top of `head` defines the next potential match
+	// pop all documents which are on this doc
+	w.lead = w.head.Pop()
+	w.lead.next = nil
+	w.leadMaxScore = w.lead.maxScore
+	w.freq = 1
+	w.doc = w.lead.doc
+	for w.head.Size() > 0 && w.head.Top().doc == w.doc {
+		w.addLead(w.head.Pop())
+	}
+	return nil
+}
+
+// Move iterators to the tail until the cumulated size of lead+tail is greater than or equal to
+// minShouldMath and the sum of the max scores of lead+tail is greater than or equal to the
+// minimum competitive score.
+func (w *WANDScorer) doNextCompetitiveCandidate() (int, error) {
+	for w.leadMaxScore+w.tailMaxScore < w.minCompetitiveScore || w.freq+w.tailSize < w.minShouldMatch {
+		// no match on doc is possible, move to the next potential match
+		if err := w.pushBackLeads(w.doc + 1); err != nil {
+			return 0, err
+		}
+		if err := w.moveToNextCandidate(w.doc + 1); err != nil {
+			return 0, err
+		}
+		if w.doc == types.NO_MORE_DOCS {
+			break
+		}
+	}
+	return w.doc, nil
+}
+
+// Advance all entries from the tail to know about all matches on the current doc.
+func (w *WANDScorer) updateFreq() error {
+	// we return the next doc when the sum of the scores of the potential
+	// matching clauses is high enough but some of the clauses in 'tail' might
+	// match as well
+	// since we are going to compute the score, we need to know about all matches
+	// so we advance all tail entries
+	for w.tailSize > 0 {
+		if err := w.advanceTail(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *WANDScorer) Score() (float64, error) {
+	// we need to know about all matches
+	if err := w.updateFreq(); err != nil {
+		return 0, err
+	}
+	score := 0.0
+	for s := w.lead; s != nil; s = s.next {
+		v, err := s.scorer.Score()
+		if err != nil {
+			return 0, err
+		}
+		score += v
+	}
+	return score, nil
+}
+
+func (w *WANDScorer) DocID() int {
+	return w.doc
+}
+
+func (w *WANDScorer) GetMaxScore(upTo int) (float64, error) {
+	// TODO: implement but be careful about floating-point errors.
+	return math.Inf(1), nil
+}
+
+// Insert an entry in 'tail' and evict the least-costly scorer if full.
+func (w *WANDScorer) insertTailWithOverFlow(s *DisiWrapper) *DisiWrapper {
+	if w.tailMaxScore+s.maxScore < w.minCompetitiveScore || w.tailSize+1 < w.minShouldMatch {
+		// we have free room for this new entry
+		w.addTail(s)
+		w.tailMaxScore += s.maxScore
+		return nil
+	} else if w.tailSize == 0 {
+		return s
+	} else {
+		top := w.tail[0]
+		if !greaterMaxScore(top, s) {
+			return s
+		}
+		// Swap top and s
+		w.tail[0] = s
+		downHeapMaxScore(w.tail, w.tailSize)
+		w.tailMaxScore = w.tailMaxScore - top.maxScore + s.maxScore
+		return top
+	}
+}
+
+// Add an entry to 'tail'. Fails if over capacity.
+func (w *WANDScorer) addTail(s *DisiWrapper) {
+	w.tail[w.tailSize] = s
+	upHeapMaxScore(w.tail, w.tailSize)
+	w.tailSize++
+}
+
+// Pop the least-costly scorer from 'tail'.
+func (w *WANDScorer) popTail() *DisiWrapper {
+	result := w.tail[0]
+	w.tailSize--
+	w.tail[0] = w.tail[w.tailSize]
+	w.tail[w.tailSize] = nil
+	downHeapMaxScore(w.tail, w.tailSize)
+	w.tailMaxScore -= result.maxScore
+	return result
+}
+
+// Heap helpers
+func upHeapMaxScore(heap []*DisiWrapper, i int) {
+	node := heap[i]
+	j := parentNode(i)
+	for j >= 0 && greaterMaxScore(node, heap[j]) {
+		heap[i] = heap[j]
+		i = j
+		j = parentNode(j)
+	}
+	heap[i] = node
+}
+
+func downHeapMaxScore(heap []*DisiWrapper, size int) {
+	if size == 0 {
+		return
+	}
+
+	i := 0
+	node := heap[0]
+	j := leftNode(i)
+	if j < size {
+		k := rightNode(j)
+		if k < size && greaterMaxScore(heap[k], heap[j]) {
+			j = k
+		}
+		if greaterMaxScore(heap[j], node) {
+			for {
+				heap[i] = heap[j]
+				i = j
+				j = leftNode(i)
+				k = rightNode(j)
+				if k < size && greaterMaxScore(heap[k], heap[j]) {
+					j = k
+				}
+				if !(j < size && greaterMaxScore(heap[j], node)) {
+					break
+				}
+			}
+			heap[i] = node
+		}
+	}
+}
 
-	// For WANDScorer
-	maxScore int64
+// In the tail, we want to get first entries that produce the maximum scores and in case of ties
+// (eg. constant-score queries), those that have the least cost so that they are likely to advance
+// further.
+func greaterMaxScore(w1, w2 *DisiWrapper) bool {
+	if w1.maxScore > w2.maxScore {
+		return true
+	} else if w1.maxScore < w2.maxScore {
+		return false
+	}
+	return w1.cost < w2.cost
 }
@@ -163,10 +163,13 @@ func (d *DefaultBulkScorer) ScoreRange(collector index.LeafCollector, acceptDocs
 			competitiveIterator = NewStartDISIWrapper(competitiveIterator)
 		}
 
-		filteredIterator = IntersectIterators([]types.DocIdSetIterator{
+		filteredIterator, err = IntersectIterators([]types.DocIdSetIterator{
 			scorerIterator,
 			competitiveIterator,
 		})
+		if err != nil {
+			return 0, err
+		}
 	}
 
 	if filteredIterator.DocID() == -1 && min == 0 && max == types.NO_MORE_DOCS {
@@ -199,7 +202,7 @@ func scoreAll(collector index.LeafCollector, iterator types.DocIdSetIterator,
 	}
 
 	if twoPhase == nil {
-		for {
+		for doc != types.NO_MORE_DOCS {
 			if acceptDocs == nil || acceptDocs.Test(uint(doc)) {
 				err := collector.Collect(nil, doc)
 				if err != nil {
@@ -217,7 +220,7 @@ func scoreAll(collector index.LeafCollector, iterator types.DocIdSetIterator,
 		}
 	} else {
 		// The scorer has an approximation, so run the approximation first, then check acceptDocs, then confirm
-		for {
+		for doc != types.NO_MORE_DOCS {
 			if ok, _ := twoPhase.Matches(); ok && (acceptDocs == nil || acceptDocs.Test(uint(doc))) {
 				if err := collector.Collect(nil, doc); err != nil {
 					return err
@@ -233,7 +236,7 @@ func scoreAll(collector index.LeafCollector, iterator types.DocIdSetIterator,
 			}
 		}
 	}
-
+	return nil
 }
 
 func scoreRange(collector index.LeafCollector, iterator types.DocIdSetIterator, twoPhase index.TwoPhaseIterator,
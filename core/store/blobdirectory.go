@@ -0,0 +1,689 @@
+package store
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// BLOB_DIRECTORY_DEFAULT_BLOCK_SIZE The size of the ranges read from the blob store by default
+	BLOB_DIRECTORY_DEFAULT_BLOCK_SIZE = 1 << 20
+
+	// BLOB_DIRECTORY_DEFAULT_CACHE_SIZE The number of bytes of the block cache by default
+	BLOB_DIRECTORY_DEFAULT_CACHE_SIZE = 64 << 20
+)
+
+var _ Directory = &BlobDirectory{}
+var _ ContextOutputCreator = &BlobDirectory{}
+
+// BlobDirectory
+// A Directory storing each file as an immutable blob of a BlobStore, so that an index can be opened
+// directly from an object storage.
+//
+// The outputs are buffered in memory, the file is uploaded at once when its output is closed, so it is
+// not listed nor readable before. The inputs read the blobs by ranges of blockSize bytes, which are kept
+// in a block cache of cacheSize bytes shared by all the inputs of the directory. The files written for a
+// flush are put in the block cache when they are uploaded, since near real-time readers open a flushed
+// segment right away, see CreateOutputWithContext.
+//
+// Once Put returned, a blob is durable, so Sync only checks that the files were uploaded. A blob store
+// cannot rename a blob: Rename copies it to dest then deletes source. Since a blob is put atomically,
+// readers see either no dest or its entire content, which is what IndexWriter needs to publish a commit
+// by renaming pending_segments_N to segments_N.
+type BlobDirectory struct {
+	sync.Mutex
+
+	store       BlobStore
+	lockFactory LockFactory
+	blockSize   int64
+	cache       *blobBlockCache
+	open        atomic.Bool
+
+	// the sizes of the blobs already looked up, a blob never changes until it is deleted
+	sizes map[string]int64
+	// the files whose output is not closed yet
+	pending map[string]struct{}
+
+	// Used to generate temp file names in CreateTempOutput.
+	nextTempFileCounter atomic.Int64
+}
+
+// NewBlobDirectory
+// Creates a directory with a SingleInstanceLockFactory, reading blocks of
+// BLOB_DIRECTORY_DEFAULT_BLOCK_SIZE bytes and caching up to BLOB_DIRECTORY_DEFAULT_CACHE_SIZE bytes.
+func NewBlobDirectory(store BlobStore) *BlobDirectory {
+	dir, _ := NewBlobDirectoryWithParams(store, NewSingleInstanceLockFactory(),
+		BLOB_DIRECTORY_DEFAULT_BLOCK_SIZE, BLOB_DIRECTORY_DEFAULT_CACHE_SIZE)
+	return dir
+}
+
+// NewBlobDirectoryWithParams
+// lockFactory: the blob store has no locks, the lock factory must be shared by all the writers of the index
+// blockSize: the size of the ranges read from the blob store
+// cacheSize: the maximum number of bytes of the block cache, 0 disables the cache
+func NewBlobDirectoryWithParams(store BlobStore, lockFactory LockFactory, blockSize int, cacheSize int64) (*BlobDirectory, error) {
+	if blockSize <= 0 {
+		return nil, fmt.Errorf("blockSize must be positive, got %d", blockSize)
+	}
+	if cacheSize < 0 {
+		return nil, fmt.Errorf("cacheSize must not be negative, got %d", cacheSize)
+	}
+
+	dir := &BlobDirectory{
+		store:       store,
+		lockFactory: lockFactory,
+		blockSize:   int64(blockSize),
+		cache:       newBlobBlockCache(cacheSize),
+		sizes:       make(map[string]int64),
+		pending:     make(map[string]struct{}),
+	}
+	dir.open.Store(true)
+	return dir, nil
+}
+
+func (b *BlobDirectory) GetBlobStore() BlobStore {
+	return b.store
+}
+
+// GetCacheSizeInBytes Returns the number of bytes in the block cache
+func (b *BlobDirectory) GetCacheSizeInBytes() int64 {
+	return b.cache.sizeInBytes()
+}
+
+func (b *BlobDirectory) ListAll(ctx context.Context) ([]string, error) {
+	if err := b.EnsureOpen(); err != nil {
+		return nil, err
+	}
+
+	blobs, err := b.store.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(blobs))
+	for _, blob := range blobs {
+		names = append(names, blob.Name)
+	}
+	return names, nil
+}
+
+func (b *BlobDirectory) DeleteFile(ctx context.Context, name string) error {
+	if err := b.EnsureOpen(); err != nil {
+		return err
+	}
+
+	if err := b.store.Delete(ctx, name); err != nil {
+		return err
+	}
+	b.forget(name)
+	return nil
+}
+
+// forget drops what is known about the blob name
+func (b *BlobDirectory) forget(name string) {
+	b.Lock()
+	delete(b.sizes, name)
+	b.Unlock()
+
+	b.cache.invalidate(name)
+}
+
+func (b *BlobDirectory) FileLength(ctx context.Context, name string) (int64, error) {
+	if err := b.EnsureOpen(); err != nil {
+		return 0, err
+	}
+	return b.fileLength(ctx, name)
+}
+
+func (b *BlobDirectory) fileLength(ctx context.Context, name string) (int64, error) {
+	b.Lock()
+	size, ok := b.sizes[name]
+	b.Unlock()
+	if ok {
+		return size, nil
+	}
+
+	blobs, err := b.store.List(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	for _, blob := range blobs {
+		if blob.Name == name {
+			b.Lock()
+			b.sizes[name] = blob.Size
+			b.Unlock()
+			return blob.Size, nil
+		}
+	}
+	return 0, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+}
+
+func (b *BlobDirectory) fileExists(ctx context.Context, name string) (bool, error) {
+	_, err := b.fileLength(ctx, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (b *BlobDirectory) CreateOutput(ctx context.Context, name string) (IndexOutput, error) {
+	return b.CreateOutputWithContext(ctx, name, DEFAULT)
+}
+
+// CreateOutputWithContext Creates an output whose blocks are put in the block cache on upload when
+// ioContext is the one of a flush. The files of a merge are not cached, they would evict the blocks of
+// the segments being searched.
+func (b *BlobDirectory) CreateOutputWithContext(ctx context.Context, name string, ioContext *IOContext) (IndexOutput, error) {
+	if err := b.EnsureOpen(); err != nil {
+		return nil, err
+	}
+
+	b.Lock()
+	if _, ok := b.pending[name]; ok {
+		b.Unlock()
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrExist)
+	}
+	b.pending[name] = struct{}{}
+	b.Unlock()
+
+	exists, err := b.fileExists(ctx, name)
+	if err == nil && exists {
+		err = fmt.Errorf("%s: %w", name, fs.ErrExist)
+	}
+	if err != nil {
+		b.removePending(name)
+		return nil, err
+	}
+	cacheBlocks := ioContext != nil && ioContext.Type == CONTEXT_FLUSH
+	return b.newOutput(ctx, name, cacheBlocks), nil
+}
+
+func (b *BlobDirectory) CreateTempOutput(ctx context.Context, prefix, suffix string) (IndexOutput, error) {
+	for {
+		output, err := b.CreateOutput(ctx, genTempFileName(prefix, suffix, b.nextTempFileCounter.Add(1)))
+		if err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				continue
+			}
+			return nil, err
+		}
+		return output, nil
+	}
+}
+
+func (b *BlobDirectory) removePending(name string) {
+	b.Lock()
+	defer b.Unlock()
+
+	delete(b.pending, name)
+}
+
+func (b *BlobDirectory) isPending(name string) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	_, ok := b.pending[name]
+	return ok
+}
+
+// Rename Copies source to dest, then deletes source
+func (b *BlobDirectory) Rename(ctx context.Context, source, dest string) error {
+	if err := b.EnsureOpen(); err != nil {
+		return err
+	}
+	if b.isPending(source) {
+		return fmt.Errorf("file %s is still open for write: cannot rename", source)
+	}
+	if b.isPending(dest) {
+		return fmt.Errorf("%s: %w", dest, fs.ErrExist)
+	}
+
+	size, err := b.fileLength(ctx, source)
+	if err != nil {
+		return err
+	}
+	exists, err := b.fileExists(ctx, dest)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%s: %w", dest, fs.ErrExist)
+	}
+
+	reader := &blobReader{ctx: ctx, store: b.store, name: source, size: size, chunkSize: b.blockSize}
+	if err := b.store.Put(ctx, dest, reader, size); err != nil {
+		return err
+	}
+	b.Lock()
+	b.sizes[dest] = size
+	b.Unlock()
+
+	if err := b.store.Delete(ctx, source); err != nil {
+		return err
+	}
+	b.forget(source)
+	return nil
+}
+
+func (b *BlobDirectory) OpenInput(ctx context.Context, name string) (IndexInput, error) {
+	if err := b.EnsureOpen(); err != nil {
+		return nil, err
+	}
+	if b.isPending(name) {
+		return nil, fmt.Errorf("file %s is still being written", name)
+	}
+
+	size, err := b.fileLength(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return newBlobIndexInput(b, name, size), nil
+}
+
+func (b *BlobDirectory) ObtainLock(name string) (Lock, error) {
+	return b.lockFactory.ObtainLock(b, name)
+}
+
+func (b *BlobDirectory) Close() error {
+	b.open.Store(false)
+	b.cache.clear()
+	return nil
+}
+
+func (b *BlobDirectory) CopyFrom(ctx context.Context, from Directory, src, dest string, ioContext *IOContext) error {
+	in, err := from.OpenInput(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := b.CreateOutputWithContext(ctx, dest, ioContext)
+	if err != nil {
+		return err
+	}
+	if err := out.CopyBytes(ctx, in, int(in.Length())); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (b *BlobDirectory) EnsureOpen() error {
+	if b.open.Load() {
+		return nil
+	}
+	return errors.New("directory is closed")
+}
+
+// Sync The files are durable once their output is closed, Sync fails if a file is still open for write or
+// was not uploaded
+func (b *BlobDirectory) Sync(files map[string]struct{}) error {
+	if err := b.EnsureOpen(); err != nil {
+		return err
+	}
+
+	for name := range files {
+		if b.isPending(name) {
+			return fmt.Errorf("file %s is still open for write: cannot sync", name)
+		}
+		if _, err := b.fileLength(context.Background(), name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readBlock returns the block index of the blob name of the given size
+func (b *BlobDirectory) readBlock(ctx context.Context, name string, size, index int64) ([]byte, error) {
+	key := blobBlockKey{name: name, index: index}
+	if block, ok := b.cache.get(key); ok {
+		return block, nil
+	}
+
+	offset := index * b.blockSize
+	length := min(b.blockSize, size-offset)
+	block, err := b.store.Get(ctx, name, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(block)) != length {
+		return nil, fmt.Errorf("read past EOF: %s block %d: expected %d bytes, got %d: %w",
+			name, index, length, len(block), io.ErrUnexpectedEOF)
+	}
+	b.cache.put(key, block)
+	return block, nil
+}
+
+func (b *BlobDirectory) newOutput(ctx context.Context, name string, cacheBlocks bool) *blobIndexOutput {
+	output := &blobIndexOutput{
+		ctx:         ctx,
+		dir:         b,
+		out:         NewByteBuffersDataOutput(),
+		crc:         NewHash(),
+		cacheBlocks: cacheBlocks,
+	}
+	output.BaseIndexOutput = NewBaseIndexOutput(name, output)
+	return output
+}
+
+var _ IndexOutput = &blobIndexOutput{}
+
+// blobIndexOutput buffers the content of the file, and uploads it when it is closed
+type blobIndexOutput struct {
+	*BaseIndexOutput
+
+	ctx    context.Context
+	dir    *BlobDirectory
+	out    *ByteBuffersDataOutput
+	crc    Hash
+	closed bool
+	// put the blocks of the file in the block cache once it is uploaded
+	cacheBlocks bool
+}
+
+func (o *blobIndexOutput) Write(p []byte) (int, error) {
+	if o.closed {
+		return 0, errors.New("output is closed")
+	}
+	o.crc.Write(p)
+	return o.out.Write(p)
+}
+
+func (o *blobIndexOutput) GetFilePointer() int64 {
+	return o.out.Size()
+}
+
+func (o *blobIndexOutput) GetChecksum() (uint32, error) {
+	return o.crc.Sum(), nil
+}
+
+func (o *blobIndexOutput) Close() error {
+	if o.closed {
+		return nil
+	}
+	o.closed = true
+
+	name := o.GetName()
+	defer o.dir.removePending(name)
+
+	size := o.out.Size()
+	content := o.out.ToBytes()
+	if err := o.dir.store.Put(o.ctx, name, bytes.NewReader(content), size); err != nil {
+		return err
+	}
+
+	o.dir.Lock()
+	o.dir.sizes[name] = size
+	o.dir.Unlock()
+
+	if o.cacheBlocks {
+		blockSize := o.dir.blockSize
+		for index := int64(0); index*blockSize < size; index++ {
+			block := content[index*blockSize : min((index+1)*blockSize, size)]
+			o.dir.cache.put(blobBlockKey{name: name, index: index}, block)
+		}
+	}
+	return nil
+}
+
+var _ IndexInput = &blobIndexInput{}
+
+// blobIndexInput reads the [off, end) range of a blob through the block cache of the directory. It keeps
+// the block holding the current position, so that sequential reads look up the cache once per block.
+type blobIndexInput struct {
+	*BaseIndexInput
+
+	dir  *BlobDirectory
+	name string
+	desc string
+	// the size of the blob
+	size int64
+
+	// off, end, pos are absolute positions in the blob
+	off int64
+	end int64
+	pos int64
+
+	block      []byte
+	blockIndex int64
+}
+
+func newBlobIndexInput(dir *BlobDirectory, name string, size int64) *blobIndexInput {
+	input := &blobIndexInput{
+		dir:        dir,
+		name:       name,
+		desc:       name,
+		size:       size,
+		off:        0,
+		end:        size,
+		pos:        0,
+		blockIndex: -1,
+	}
+	input.BaseIndexInput = NewBaseIndexInput(input)
+	return input
+}
+
+func (i *blobIndexInput) Read(p []byte) (int, error) {
+	if i.pos >= i.end {
+		return 0, io.EOF
+	}
+
+	size := int(min(int64(len(p)), i.end-i.pos))
+	n := 0
+	for n < size {
+		index := i.pos / i.dir.blockSize
+		if index != i.blockIndex {
+			block, err := i.dir.readBlock(context.Background(), i.name, i.size, index)
+			if err != nil {
+				return n, err
+			}
+			i.block = block
+			i.blockIndex = index
+		}
+
+		copied := copy(p[n:size], i.block[i.pos-index*i.dir.blockSize:])
+		n += copied
+		i.pos += int64(copied)
+	}
+	return n, nil
+}
+
+func (i *blobIndexInput) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = i.off + offset
+	case io.SeekCurrent:
+		pos = i.pos + offset
+	case io.SeekEnd:
+		pos = i.end - offset
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+
+	if pos < i.off || pos > i.end {
+		return 0, fmt.Errorf("seek position %d out of bounds [0, %d] of %s", pos-i.off, i.end-i.off, i.desc)
+	}
+	i.pos = pos
+	return pos - i.off, nil
+}
+
+func (i *blobIndexInput) GetFilePointer() int64 {
+	return i.pos - i.off
+}
+
+func (i *blobIndexInput) Length() int64 {
+	return i.end - i.off
+}
+
+func (i *blobIndexInput) Slice(desc string, offset, length int64) (IndexInput, error) {
+	if offset < 0 || length < 0 || offset+length > i.Length() {
+		return nil, fmt.Errorf("slice() %s out of bounds: offset=%d,length=%d,fileLength=%d of %s",
+			desc, offset, length, i.Length(), i.desc)
+	}
+
+	slice := i.clone()
+	slice.desc = desc
+	slice.off = i.off + offset
+	slice.end = slice.off + length
+	slice.pos = slice.off
+	return slice, nil
+}
+
+func (i *blobIndexInput) Clone() CloneReader {
+	return i.clone()
+}
+
+func (i *blobIndexInput) clone() *blobIndexInput {
+	input := &blobIndexInput{
+		dir:        i.dir,
+		name:       i.name,
+		desc:       i.desc,
+		size:       i.size,
+		off:        i.off,
+		end:        i.end,
+		pos:        i.pos,
+		block:      i.block,
+		blockIndex: i.blockIndex,
+	}
+	input.BaseIndexInput = NewBaseIndexInput(input)
+	return input
+}
+
+// Close The blocks are owned by the cache of the directory, there is nothing to release
+func (i *blobIndexInput) Close() error {
+	return nil
+}
+
+// blobReader reads a blob sequentially by chunks of chunkSize bytes
+type blobReader struct {
+	ctx       context.Context
+	store     BlobStore
+	name      string
+	size      int64
+	chunkSize int64
+	pos       int64
+}
+
+func (r *blobReader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+
+	length := min(int64(len(p)), r.chunkSize, r.size-r.pos)
+	chunk, err := r.store.Get(r.ctx, r.name, r.pos, length)
+	if err != nil {
+		return 0, err
+	}
+	if len(chunk) == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, chunk)
+	r.pos += int64(n)
+	return n, nil
+}
+
+type blobBlockKey struct {
+	name  string
+	index int64
+}
+
+type blobBlockEntry struct {
+	key   blobBlockKey
+	block []byte
+}
+
+// blobBlockCache an LRU cache of the blocks read from the blob store, bounded by the total size of the
+// blocks
+type blobBlockCache struct {
+	sync.Mutex
+
+	maxSize int64
+	size    int64
+	lru     *list.List // of *blobBlockEntry, the most recently used first
+	entries map[blobBlockKey]*list.Element
+}
+
+func newBlobBlockCache(maxSize int64) *blobBlockCache {
+	return &blobBlockCache{
+		maxSize: maxSize,
+		lru:     list.New(),
+		entries: make(map[blobBlockKey]*list.Element),
+	}
+}
+
+func (c *blobBlockCache) get(key blobBlockKey) ([]byte, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(element)
+	return element.Value.(*blobBlockEntry).block, true
+}
+
+func (c *blobBlockCache) put(key blobBlockKey, block []byte) {
+	if int64(len(block)) > c.maxSize {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&blobBlockEntry{key: key, block: block})
+	c.size += int64(len(block))
+
+	for c.size > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+}
+
+// invalidate removes the blocks of the blob name
+func (c *blobBlockCache) invalidate(name string) {
+	c.Lock()
+	defer c.Unlock()
+
+	for key, element := range c.entries {
+		if key.name == name {
+			c.remove(element)
+		}
+	}
+}
+
+// remove the caller holds the lock
+func (c *blobBlockCache) remove(element *list.Element) {
+	entry := c.lru.Remove(element).(*blobBlockEntry)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.block))
+}
+
+func (c *blobBlockCache) clear() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.lru.Init()
+	clear(c.entries)
+	c.size = 0
+}
+
+func (c *blobBlockCache) sizeInBytes() int64 {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.size
+}
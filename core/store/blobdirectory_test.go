@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingBlobStore counts the calls to Get
+type countingBlobStore struct {
+	BlobStore
+
+	gets atomic.Int64
+}
+
+func (c *countingBlobStore) Get(ctx context.Context, name string, offset, length int64) ([]byte, error) {
+	c.gets.Add(1)
+	return c.BlobStore.Get(ctx, name, offset, length)
+}
+
+func newTestBlobDirectory(t *testing.T, blockSize int, cacheSize int64) (*BlobDirectory, *countingBlobStore) {
+	local, err := NewLocalBlobStore(t.TempDir())
+	assert.Nil(t, err)
+	store := &countingBlobStore{BlobStore: local}
+	dir, err := NewBlobDirectoryWithParams(store, NewSingleInstanceLockFactory(), blockSize, cacheSize)
+	assert.Nil(t, err)
+	return dir, store
+}
+
+func TestBlobDirectory_ReadWrite(t *testing.T) {
+	ctx := context.Background()
+	dir, store := newTestBlobDirectory(t, 16, 1024)
+
+	content := make([]byte, 100)
+	rand.New(rand.NewSource(1)).Read(content)
+
+	out, err := dir.CreateOutput(ctx, "a")
+	assert.Nil(t, err)
+	_, err = out.Write(content)
+	assert.Nil(t, err)
+
+	// the file is uploaded when its output is closed
+	names, err := dir.ListAll(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, names)
+	_, err = dir.OpenInput(ctx, "a")
+	assert.NotNil(t, err)
+	_, err = dir.CreateOutput(ctx, "a")
+	assert.ErrorIs(t, err, fs.ErrExist)
+	assert.NotNil(t, dir.Sync(map[string]struct{}{"a": {}}))
+	assert.Nil(t, out.Close())
+
+	assert.Nil(t, dir.Sync(map[string]struct{}{"a": {}}))
+	names, err = dir.ListAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, names)
+	length, err := dir.FileLength(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100), length)
+	_, err = dir.CreateOutput(ctx, "a")
+	assert.ErrorIs(t, err, fs.ErrExist)
+
+	in, err := dir.OpenInput(ctx, "a")
+	assert.Nil(t, err)
+	read, err := io.ReadAll(in)
+	assert.Nil(t, err)
+	assert.Equal(t, content, read)
+	// 7 blocks of 16 bytes
+	assert.Equal(t, int64(7), store.gets.Load())
+	assert.Equal(t, int64(100), dir.GetCacheSizeInBytes())
+
+	// slices and clones are served by the cache
+	_, err = in.Seek(10, io.SeekStart)
+	assert.Nil(t, err)
+	clone := in.Clone().(IndexInput)
+	slice, err := clone.Slice("slice", 30, 50)
+	assert.Nil(t, err)
+	bs := make([]byte, 5)
+	_, err = io.ReadFull(clone, bs)
+	assert.Nil(t, err)
+	assert.Equal(t, content[10:15], bs)
+	read, err = io.ReadAll(slice)
+	assert.Nil(t, err)
+	assert.Equal(t, content[30:80], read)
+	_, err = slice.Seek(20, io.SeekStart)
+	assert.Nil(t, err)
+	v, err := slice.ReadByte()
+	assert.Nil(t, err)
+	assert.Equal(t, content[50], v)
+	_, err = slice.Seek(51, io.SeekStart)
+	assert.NotNil(t, err)
+	assert.Equal(t, int64(7), store.gets.Load())
+	assert.Nil(t, in.Close())
+
+	assert.Nil(t, dir.DeleteFile(ctx, "a"))
+	assert.Equal(t, int64(0), dir.GetCacheSizeInBytes())
+	_, err = dir.OpenInput(ctx, "a")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.Nil(t, dir.Close())
+}
+
+func TestBlobDirectory_CacheEviction(t *testing.T) {
+	ctx := context.Background()
+	dir, store := newTestBlobDirectory(t, 10, 30)
+
+	content := make([]byte, 100)
+	rand.New(rand.NewSource(2)).Read(content)
+	out, err := dir.CreateOutput(ctx, "a")
+	assert.Nil(t, err)
+	_, err = out.Write(content)
+	assert.Nil(t, err)
+	assert.Nil(t, out.Close())
+
+	in, err := dir.OpenInput(ctx, "a")
+	assert.Nil(t, err)
+	read, err := io.ReadAll(in)
+	assert.Nil(t, err)
+	assert.Equal(t, content, read)
+	assert.Equal(t, int64(10), store.gets.Load())
+	assert.Equal(t, int64(30), dir.GetCacheSizeInBytes())
+
+	// the last 3 blocks are cached, the first one was evicted
+	_, err = in.Seek(75, io.SeekStart)
+	assert.Nil(t, err)
+	_, err = io.ReadAll(in)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), store.gets.Load())
+	_, err = in.Seek(0, io.SeekStart)
+	assert.Nil(t, err)
+	_, err = in.ReadByte()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(11), store.gets.Load())
+	assert.Nil(t, dir.Close())
+}
+
+func TestBlobDirectory_CreateOutputWithContext(t *testing.T) {
+	ctx := context.Background()
+	dir, store := newTestBlobDirectory(t, 16, 1024)
+
+	content := make([]byte, 100)
+	rand.New(rand.NewSource(3)).Read(content)
+	writeFile := func(name string, ioContext *IOContext) {
+		out, err := dir.CreateOutputWithContext(ctx, name, ioContext)
+		assert.Nil(t, err)
+		_, err = out.Write(content)
+		assert.Nil(t, err)
+		assert.Nil(t, out.Close())
+	}
+	readFile := func(name string) {
+		in, err := dir.OpenInput(ctx, name)
+		assert.Nil(t, err)
+		read, err := io.ReadAll(in)
+		assert.Nil(t, err)
+		assert.Equal(t, content, read)
+		assert.Nil(t, in.Close())
+	}
+
+	// the blocks of a flushed file are cached when it is uploaded
+	writeFile("_0.tim", NewIOContext(WithFlushInfo(NewFlushInfo(10, 100))))
+	assert.Equal(t, int64(100), dir.GetCacheSizeInBytes())
+	readFile("_0.tim")
+	assert.Equal(t, int64(0), store.gets.Load())
+
+	// the blocks of a merged file are read from the blob store
+	writeFile("_1.tim", NewIOContext(WithMergeInfo(NewMergeInfo(10, 100, false, -1))))
+	assert.Equal(t, int64(100), dir.GetCacheSizeInBytes())
+	readFile("_1.tim")
+	assert.Equal(t, int64(7), store.gets.Load())
+
+	// a copy is cached depending on its IOContext too
+	writeFile("_2.tim", DEFAULT)
+	assert.Equal(t, int64(200), dir.GetCacheSizeInBytes())
+	assert.Nil(t, dir.CopyFrom(ctx, dir, "_2.tim", "_3.tim", NewIOContext(WithFlushInfo(NewFlushInfo(10, 100)))))
+	assert.Equal(t, int64(7+7), store.gets.Load())
+	readFile("_3.tim")
+	assert.Equal(t, int64(7+7), store.gets.Load())
+	assert.Nil(t, dir.Close())
+}
+
+func TestBlobDirectory_Rename(t *testing.T) {
+	ctx := context.Background()
+	dir, _ := newTestBlobDirectory(t, 8, 1024)
+
+	content := []byte("the content of pending_segments_1")
+	out, err := dir.CreateOutput(ctx, "pending_segments_1")
+	assert.Nil(t, err)
+	_, err = out.Write(content)
+	assert.Nil(t, err)
+	assert.NotNil(t, dir.Rename(ctx, "pending_segments_1", "segments_1"))
+	assert.Nil(t, out.Close())
+
+	assert.Nil(t, dir.Rename(ctx, "pending_segments_1", "segments_1"))
+	names, err := dir.ListAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"segments_1"}, names)
+
+	in, err := dir.OpenInput(ctx, "segments_1")
+	assert.Nil(t, err)
+	read, err := io.ReadAll(in)
+	assert.Nil(t, err)
+	assert.Equal(t, content, read)
+
+	// dest must not exist
+	out, err = dir.CreateOutput(ctx, "b")
+	assert.Nil(t, err)
+	assert.Nil(t, out.Close())
+	assert.ErrorIs(t, dir.Rename(ctx, "b", "segments_1"), fs.ErrExist)
+	assert.ErrorIs(t, dir.Rename(ctx, "c", "d"), fs.ErrNotExist)
+	assert.Nil(t, dir.Close())
+}
@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// BlobInfo The name and the size of a blob
+type BlobInfo struct {
+	Name string
+	Size int64
+}
+
+// BlobStore
+// A flat store of immutable blobs, such as an object storage bucket. A blob is written at once by Put and
+// is never modified afterward; it can only be read, by ranges, or deleted.
+//
+// Implementations must be safe for concurrent use.
+type BlobStore interface {
+	// Put Stores the size bytes of content as the blob name, replacing any blob with the same name. The blob
+	// must become visible at once with its entire content, or not at all.
+	Put(ctx context.Context, name string, content io.Reader, size int64) error
+
+	// Get Returns the length bytes of the blob name starting at offset. It returns fewer bytes only when
+	// the blob ends before offset+length. The error wraps fs.ErrNotExist if the blob does not exist.
+	Get(ctx context.Context, name string, offset, length int64) ([]byte, error)
+
+	// List Returns the blobs whose name starts with prefix, sorted by name.
+	List(ctx context.Context, prefix string) ([]BlobInfo, error)
+
+	// Delete Removes the blob name. The error wraps fs.ErrNotExist if the blob does not exist.
+	Delete(ctx context.Context, name string) error
+}
+
+var _ BlobStore = &LocalBlobStore{}
+
+// LocalBlobStore
+// A BlobStore keeping each blob as a file of a local directory. It stands in for a remote object storage
+// in tests and on development machines.
+//
+// Put writes the blob to a temporary file which is then renamed, so that a blob is never visible
+// partially written.
+type LocalBlobStore struct {
+	dir string
+
+	// Used to generate the names of the temporary files of Put.
+	nextTempFileCounter atomic.Int64
+}
+
+// localBlobTempSuffix the suffix of the temporary files of Put, they are not listed as blobs
+const localBlobTempSuffix = ".uploading"
+
+func NewLocalBlobStore(path string) (*LocalBlobStore, error) {
+	dirPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return nil, err
+	}
+	return &LocalBlobStore{dir: dirPath}, nil
+}
+
+func (s *LocalBlobStore) resolvePath(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid blob name %q", name)
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+func (s *LocalBlobStore) Put(ctx context.Context, name string, content io.Reader, size int64) error {
+	path, err := s.resolvePath(name)
+	if err != nil {
+		return err
+	}
+
+	tempPath := fmt.Sprintf("%s.%d%s", path, s.nextTempFileCounter.Add(1), localBlobTempSuffix)
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return err
+	}
+
+	written, err := io.Copy(file, io.LimitReader(content, size))
+	if err == nil && written != size {
+		err = fmt.Errorf("blob %s: expected %d bytes, got %d: %w", name, size, written, io.ErrUnexpectedEOF)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if err := errors.Join(err, file.Close()); err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+func (s *LocalBlobStore) Get(ctx context.Context, name string, offset, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("blob %s: invalid range offset=%d,length=%d", name, offset, length)
+	}
+	path, err := s.resolvePath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	bs := make([]byte, length)
+	n, err := file.ReadAt(bs, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return bs[:n], nil
+}
+
+func (s *LocalBlobStore) List(ctx context.Context, prefix string) ([]BlobInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := make([]BlobInfo, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !strings.HasPrefix(name, prefix) || strings.HasSuffix(name, localBlobTempSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// deleted since ReadDir
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		blobs = append(blobs, BlobInfo{Name: name, Size: info.Size()})
+	}
+	sort.Slice(blobs, func(i, j int) bool {
+		return blobs[i].Name < blobs[j].Name
+	})
+	return blobs, nil
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, name string) error {
+	path, err := s.resolvePath(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
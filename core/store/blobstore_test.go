@@ -0,0 +1,45 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalBlobStore(t.TempDir())
+	assert.Nil(t, err)
+
+	content := []byte("0123456789")
+	assert.Nil(t, store.Put(ctx, "b", bytes.NewReader(content), int64(len(content))))
+	assert.Nil(t, store.Put(ctx, "a1", bytes.NewReader(content[:4]), 4))
+	assert.Nil(t, store.Put(ctx, "a2", bytes.NewReader(content[:4]), 4))
+	assert.NotNil(t, store.Put(ctx, "c", bytes.NewReader(content[:2]), 4))
+	assert.NotNil(t, store.Put(ctx, "../c", bytes.NewReader(content), int64(len(content))))
+
+	bs, err := store.Get(ctx, "b", 3, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("3456"), bs)
+	bs, err = store.Get(ctx, "b", 8, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("89"), bs)
+	_, err = store.Get(ctx, "c", 0, 1)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	blobs, err := store.List(ctx, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []BlobInfo{{"a1", 4}, {"a2", 4}, {"b", 10}}, blobs)
+	blobs, err = store.List(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, []BlobInfo{{"a1", 4}, {"a2", 4}}, blobs)
+
+	assert.Nil(t, store.Delete(ctx, "a1"))
+	assert.ErrorIs(t, store.Delete(ctx, "a1"), fs.ErrNotExist)
+	blobs, err = store.List(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, []BlobInfo{{"a2", 4}}, blobs)
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"fmt"
+)
+
+const (
+	// DEFAULT_MIN_BITS_PER_BLOCK Default minimum block size: 1 KiB
+	DEFAULT_MIN_BITS_PER_BLOCK = 10
+
+	// DEFAULT_MAX_BITS_PER_BLOCK Default maximum block size: 32 MiB
+	DEFAULT_MAX_BITS_PER_BLOCK = 25
+
+	// MAX_BLOCKS_BEFORE_BLOCK_EXPANSION Maximum number of blocks at the current block size before we
+	// increase the block size (and thus decrease the number of blocks).
+	MAX_BLOCKS_BEFORE_BLOCK_EXPANSION = 100
+
+	// LIMIT_MIN_BITS_PER_BLOCK Smallest and largest allowed block sizes
+	LIMIT_MIN_BITS_PER_BLOCK = 1
+	LIMIT_MAX_BITS_PER_BLOCK = 31
+)
+
+var _ DataOutput = &ByteBuffersDataOutput{}
+
+// ByteBuffersDataOutput
+// A DataOutput storing data in a list of blocks.
+//
+// Blocks start at 1 << minBitsPerBlock bytes. Once MAX_BLOCKS_BEFORE_BLOCK_EXPANSION blocks are written,
+// the written data is rewritten to blocks twice as large, until blocks are 1 << maxBitsPerBlock bytes,
+// so that large outputs need few blocks. All the blocks have the same size, except the last one which
+// may be partially filled.
+type ByteBuffersDataOutput struct {
+	*BaseDataOutput
+
+	maxBitsPerBlock int
+	blockBits       int
+	blocks          [][]byte
+}
+
+func NewByteBuffersDataOutput() *ByteBuffersDataOutput {
+	output, _ := NewByteBuffersDataOutputWithBlockBits(DEFAULT_MIN_BITS_PER_BLOCK, DEFAULT_MAX_BITS_PER_BLOCK)
+	return output
+}
+
+// NewByteBuffersDataOutputWithBlockBits
+// minBitsPerBlock: the block size of a new output, as a power of 2
+// maxBitsPerBlock: the maximum block size, as a power of 2
+func NewByteBuffersDataOutputWithBlockBits(minBitsPerBlock, maxBitsPerBlock int) (*ByteBuffersDataOutput, error) {
+	if minBitsPerBlock < LIMIT_MIN_BITS_PER_BLOCK {
+		return nil, fmt.Errorf("minBitsPerBlock (%d) too small, must be at least %d",
+			minBitsPerBlock, LIMIT_MIN_BITS_PER_BLOCK)
+	}
+	if maxBitsPerBlock > LIMIT_MAX_BITS_PER_BLOCK {
+		return nil, fmt.Errorf("maxBitsPerBlock (%d) too large, must be at most %d",
+			maxBitsPerBlock, LIMIT_MAX_BITS_PER_BLOCK)
+	}
+	if minBitsPerBlock > maxBitsPerBlock {
+		return nil, fmt.Errorf("minBitsPerBlock (%d) cannot exceed maxBitsPerBlock (%d)",
+			minBitsPerBlock, maxBitsPerBlock)
+	}
+
+	output := &ByteBuffersDataOutput{
+		maxBitsPerBlock: maxBitsPerBlock,
+		blockBits:       minBitsPerBlock,
+	}
+	output.BaseDataOutput = NewBaseDataOutput(output)
+	return output, nil
+}
+
+func (o *ByteBuffersDataOutput) Write(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(o.blocks) == 0 || o.lastBlockFull() {
+			o.appendBlock()
+		}
+		last := len(o.blocks) - 1
+		block := o.blocks[last]
+		copied := copy(block[len(block):cap(block)], p[n:])
+		o.blocks[last] = block[:len(block)+copied]
+		n += copied
+	}
+	return n, nil
+}
+
+func (o *ByteBuffersDataOutput) lastBlockFull() bool {
+	last := o.blocks[len(o.blocks)-1]
+	return len(last) == cap(last)
+}
+
+func (o *ByteBuffersDataOutput) appendBlock() {
+	if len(o.blocks) >= MAX_BLOCKS_BEFORE_BLOCK_EXPANSION && o.blockBits < o.maxBitsPerBlock {
+		o.rewriteToBlockSize(o.blockBits + 1)
+		if !o.lastBlockFull() {
+			return
+		}
+	}
+	o.blocks = append(o.blocks, make([]byte, 0, 1<<o.blockBits))
+}
+
+// rewriteToBlockSize copies the written data to blocks of 1 << targetBlockBits bytes
+func (o *ByteBuffersDataOutput) rewriteToBlockSize(targetBlockBits int) {
+	rewritten := &ByteBuffersDataOutput{
+		maxBitsPerBlock: targetBlockBits,
+		blockBits:       targetBlockBits,
+	}
+	for _, block := range o.blocks {
+		_, _ = rewritten.Write(block)
+	}
+	o.blocks = rewritten.blocks
+	o.blockBits = targetBlockBits
+}
+
+// Size Returns the number of bytes written to this output.
+func (o *ByteBuffersDataOutput) Size() int64 {
+	if len(o.blocks) == 0 {
+		return 0
+	}
+	last := len(o.blocks) - 1
+	return int64(last)<<o.blockBits + int64(len(o.blocks[last]))
+}
+
+// BlockBits Returns the current block size, as a power of 2
+func (o *ByteBuffersDataOutput) BlockBits() int {
+	return o.blockBits
+}
+
+// ToBlocks Returns the written blocks, without copying them. Every block but the last one is full.
+// The blocks must not be modified, and the output must not be written to anymore.
+func (o *ByteBuffersDataOutput) ToBlocks() [][]byte {
+	blocks := make([][]byte, len(o.blocks))
+	copy(blocks, o.blocks)
+	return blocks
+}
+
+// ToBytes Returns a copy of the written bytes in a single array.
+func (o *ByteBuffersDataOutput) ToBytes() []byte {
+	bs := make([]byte, 0, o.Size())
+	for _, block := range o.blocks {
+		bs = append(bs, block...)
+	}
+	return bs
+}
+
+// CopyTo Copies the written bytes to output.
+func (o *ByteBuffersDataOutput) CopyTo(output DataOutput) error {
+	for _, block := range o.blocks {
+		if _, err := output.Write(block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reset This method resets this object to a clean (zero-size) state and releases any buffers
+// previously allocated.
+func (o *ByteBuffersDataOutput) Reset() {
+	o.blocks = nil
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByteBuffersDataOutput(t *testing.T) {
+	output, err := NewByteBuffersDataOutputWithBlockBits(4, 6)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), output.Size())
+	assert.Empty(t, output.ToBlocks())
+
+	payload := make([]byte, 10_000)
+	rand.New(rand.NewSource(1)).Read(payload)
+
+	r := rand.New(rand.NewSource(2))
+	for written := 0; written < len(payload); {
+		n := min(r.Intn(40)+1, len(payload)-written)
+		_, err := output.Write(payload[written : written+n])
+		assert.Nil(t, err)
+		written += n
+		assert.Equal(t, int64(written), output.Size())
+	}
+
+	// the blocks grew up to the maximum block size
+	assert.Equal(t, 6, output.BlockBits())
+	blocks := output.ToBlocks()
+	for i, block := range blocks {
+		if i < len(blocks)-1 {
+			assert.Equal(t, 1<<6, len(block))
+		}
+	}
+	assert.Equal(t, payload, bytes.Join(blocks, nil))
+	assert.Equal(t, payload, output.ToBytes())
+
+	copied := NewByteBuffersDataOutput()
+	assert.Nil(t, output.CopyTo(copied))
+	assert.Equal(t, payload, copied.ToBytes())
+	assert.Equal(t, DEFAULT_MIN_BITS_PER_BLOCK, copied.BlockBits())
+
+	output.Reset()
+	assert.Equal(t, int64(0), output.Size())
+	assert.Nil(t, output.WriteUint32(context.Background(), 7))
+	assert.Equal(t, []byte{0, 0, 0, 7}, output.ToBytes())
+
+	_, err = NewByteBuffersDataOutputWithBlockBits(0, 10)
+	assert.NotNil(t, err)
+	_, err = NewByteBuffersDataOutputWithBlockBits(10, 32)
+	assert.NotNil(t, err)
+	_, err = NewByteBuffersDataOutputWithBlockBits(11, 10)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,305 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/bits"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// OutputToInput Converts the content of a closed output of a ByteBuffersDirectory to the input shared by
+// all the readers of the file.
+type OutputToInput func(fileName string, output *ByteBuffersDataOutput) (*ByteBuffersIndexInput, error)
+
+var (
+	// OUTPUT_AS_MANY_BUFFERS Shares the blocks of the output with the inputs, without copying them. The
+	// unused end of the last block is kept.
+	OUTPUT_AS_MANY_BUFFERS OutputToInput = func(fileName string, output *ByteBuffersDataOutput) (*ByteBuffersIndexInput, error) {
+		return NewByteBuffersIndexInput(fileName, output.ToBlocks(), output.BlockBits())
+	}
+
+	// OUTPUT_AS_COPIED_BUFFERS Copies the blocks of the output, so that the last block has no unused end.
+	OUTPUT_AS_COPIED_BUFFERS OutputToInput = func(fileName string, output *ByteBuffersDataOutput) (*ByteBuffersIndexInput, error) {
+		blocks := output.ToBlocks()
+		for i, block := range blocks {
+			blocks[i] = append(make([]byte, 0, len(block)), block...)
+		}
+		return NewByteBuffersIndexInput(fileName, blocks, output.BlockBits())
+	}
+
+	// OUTPUT_AS_ONE_BUFFER Compacts the output to a single block.
+	OUTPUT_AS_ONE_BUFFER OutputToInput = func(fileName string, output *ByteBuffersDataOutput) (*ByteBuffersIndexInput, error) {
+		bs := output.ToBytes()
+		// the block is larger than the content, so every position is in it
+		return NewByteBuffersIndexInput(fileName, [][]byte{bs}, bits.Len64(uint64(len(bs))))
+	}
+)
+
+var _ Directory = &ByteBuffersDirectory{}
+
+// ByteBuffersDirectory
+// A Directory implementation storing files in RAM, in blocks growing with the size of the files.
+//
+// Reads are lock-free: once its output is closed, the content of a file never changes, and the inputs
+// opened on it share it. Files can be opened and read by any number of goroutines while other files
+// are written.
+//
+// How the content of a closed output is turned into the content read by the inputs is configurable, see
+// OUTPUT_AS_MANY_BUFFERS, OUTPUT_AS_COPIED_BUFFERS and OUTPUT_AS_ONE_BUFFER.
+type ByteBuffersDirectory struct {
+	files         sync.Map // name -> *byteBuffersFileEntry
+	lockFactory   LockFactory
+	outputToInput OutputToInput
+	open          atomic.Bool
+
+	// Used to generate temp file names in CreateTempOutput.
+	nextTempFileCounter atomic.Int64
+}
+
+// NewByteBuffersDirectory
+// Creates a directory with a SingleInstanceLockFactory, sharing the blocks of the outputs with the
+// inputs.
+func NewByteBuffersDirectory() *ByteBuffersDirectory {
+	return NewByteBuffersDirectoryWithParams(NewSingleInstanceLockFactory(), OUTPUT_AS_MANY_BUFFERS)
+}
+
+func NewByteBuffersDirectoryWithParams(lockFactory LockFactory, outputToInput OutputToInput) *ByteBuffersDirectory {
+	dir := &ByteBuffersDirectory{
+		lockFactory:   lockFactory,
+		outputToInput: outputToInput,
+	}
+	dir.open.Store(true)
+	return dir
+}
+
+// byteBuffersFileEntry a file, its content is nil until its output is closed
+type byteBuffersFileEntry struct {
+	content atomic.Pointer[ByteBuffersIndexInput]
+}
+
+func (d *ByteBuffersDirectory) getFile(name string) (*byteBuffersFileEntry, bool) {
+	entry, ok := d.files.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return entry.(*byteBuffersFileEntry), true
+}
+
+func (d *ByteBuffersDirectory) fileExists(name string) bool {
+	_, ok := d.files.Load(name)
+	return ok
+}
+
+// RamBytesUsed Returns the number of bytes of the files whose output is closed
+func (d *ByteBuffersDirectory) RamBytesUsed() int64 {
+	size := int64(0)
+	d.files.Range(func(key, value any) bool {
+		if content := value.(*byteBuffersFileEntry).content.Load(); content != nil {
+			size += content.Length()
+		}
+		return true
+	})
+	return size
+}
+
+func (d *ByteBuffersDirectory) ListAll(ctx context.Context) ([]string, error) {
+	if err := d.EnsureOpen(); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	d.files.Range(func(key, value any) bool {
+		names = append(names, key.(string))
+		return true
+	})
+	sort.Strings(names)
+	return names, nil
+}
+
+func (d *ByteBuffersDirectory) DeleteFile(ctx context.Context, name string) error {
+	if err := d.EnsureOpen(); err != nil {
+		return err
+	}
+
+	if _, ok := d.files.LoadAndDelete(name); !ok {
+		return fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	return nil
+}
+
+func (d *ByteBuffersDirectory) FileLength(ctx context.Context, name string) (int64, error) {
+	if err := d.EnsureOpen(); err != nil {
+		return 0, err
+	}
+
+	entry, ok := d.getFile(name)
+	if !ok {
+		return 0, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	content := entry.content.Load()
+	if content == nil {
+		return 0, fmt.Errorf("file %s is still being written", name)
+	}
+	return content.Length(), nil
+}
+
+func (d *ByteBuffersDirectory) CreateOutput(ctx context.Context, name string) (IndexOutput, error) {
+	if err := d.EnsureOpen(); err != nil {
+		return nil, err
+	}
+
+	entry := &byteBuffersFileEntry{}
+	if _, loaded := d.files.LoadOrStore(name, entry); loaded {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrExist)
+	}
+	return d.newOutput(name, entry), nil
+}
+
+func (d *ByteBuffersDirectory) CreateTempOutput(ctx context.Context, prefix, suffix string) (IndexOutput, error) {
+	if err := d.EnsureOpen(); err != nil {
+		return nil, err
+	}
+
+	for {
+		name := genTempFileName(prefix, suffix, d.nextTempFileCounter.Add(1))
+		entry := &byteBuffersFileEntry{}
+		if _, loaded := d.files.LoadOrStore(name, entry); !loaded {
+			return d.newOutput(name, entry), nil
+		}
+	}
+}
+
+func (d *ByteBuffersDirectory) Rename(ctx context.Context, source, dest string) error {
+	if err := d.EnsureOpen(); err != nil {
+		return err
+	}
+
+	entry, ok := d.files.Load(source)
+	if !ok {
+		return fmt.Errorf("%s: %w", source, fs.ErrNotExist)
+	}
+	if _, loaded := d.files.LoadOrStore(dest, entry); loaded {
+		return fmt.Errorf("%s: %w", dest, fs.ErrExist)
+	}
+	if !d.files.CompareAndDelete(source, entry) {
+		return fmt.Errorf("concurrent modification of %s", source)
+	}
+	return nil
+}
+
+func (d *ByteBuffersDirectory) OpenInput(ctx context.Context, name string) (IndexInput, error) {
+	if err := d.EnsureOpen(); err != nil {
+		return nil, err
+	}
+
+	entry, ok := d.getFile(name)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	content := entry.content.Load()
+	if content == nil {
+		return nil, fmt.Errorf("file %s is still being written", name)
+	}
+	return content.Clone().(IndexInput), nil
+}
+
+func (d *ByteBuffersDirectory) ObtainLock(name string) (Lock, error) {
+	return d.lockFactory.ObtainLock(d, name)
+}
+
+func (d *ByteBuffersDirectory) Close() error {
+	d.open.Store(false)
+	d.files.Range(func(key, value any) bool {
+		d.files.Delete(key)
+		return true
+	})
+	return nil
+}
+
+func (d *ByteBuffersDirectory) CopyFrom(ctx context.Context, from Directory, src, dest string, ioContext *IOContext) error {
+	in, err := from.OpenInput(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := d.CreateOutput(ctx, dest)
+	if err != nil {
+		return err
+	}
+	if err := out.CopyBytes(ctx, in, int(in.Length())); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (d *ByteBuffersDirectory) EnsureOpen() error {
+	if d.open.Load() {
+		return nil
+	}
+	return errors.New("directory is closed")
+}
+
+// Sync Files in RAM have nothing to sync
+func (d *ByteBuffersDirectory) Sync(files map[string]struct{}) error {
+	return nil
+}
+
+func (d *ByteBuffersDirectory) newOutput(name string, entry *byteBuffersFileEntry) *byteBuffersIndexOutput {
+	output := &byteBuffersIndexOutput{
+		dir:   d,
+		entry: entry,
+		out:   NewByteBuffersDataOutput(),
+		crc:   NewHash(),
+	}
+	output.BaseIndexOutput = NewBaseIndexOutput(name, output)
+	return output
+}
+
+var _ IndexOutput = &byteBuffersIndexOutput{}
+
+// byteBuffersIndexOutput publishes its content to the file entry when it is closed
+type byteBuffersIndexOutput struct {
+	*BaseIndexOutput
+
+	dir    *ByteBuffersDirectory
+	entry  *byteBuffersFileEntry
+	out    *ByteBuffersDataOutput
+	crc    Hash
+	closed bool
+}
+
+func (o *byteBuffersIndexOutput) Write(p []byte) (int, error) {
+	if o.closed {
+		return 0, errors.New("output is closed")
+	}
+	o.crc.Write(p)
+	return o.out.Write(p)
+}
+
+func (o *byteBuffersIndexOutput) GetFilePointer() int64 {
+	return o.out.Size()
+}
+
+func (o *byteBuffersIndexOutput) GetChecksum() (uint32, error) {
+	return o.crc.Sum(), nil
+}
+
+func (o *byteBuffersIndexOutput) Close() error {
+	if o.closed {
+		return nil
+	}
+	o.closed = true
+
+	content, err := o.dir.outputToInput(o.GetName(), o.out)
+	if err != nil {
+		return err
+	}
+	o.entry.content.Store(content)
+	return nil
+}
@@ -0,0 +1,202 @@
+package store
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByteBuffersDirectory(t *testing.T) {
+	ctx := context.Background()
+	payload := make([]byte, 300_000)
+	rand.New(rand.NewSource(1)).Read(payload)
+
+	conversions := map[string]OutputToInput{
+		"many":   OUTPUT_AS_MANY_BUFFERS,
+		"copied": OUTPUT_AS_COPIED_BUFFERS,
+		"one":    OUTPUT_AS_ONE_BUFFER,
+	}
+	for name, outputToInput := range conversions {
+		t.Run(name, func(t *testing.T) {
+			dir := NewByteBuffersDirectoryWithParams(NewSingleInstanceLockFactory(), outputToInput)
+			defer dir.Close()
+
+			out, err := dir.CreateOutput(ctx, "test.bin")
+			assert.Nil(t, err)
+			_, err = out.Write(payload)
+			assert.Nil(t, err)
+			checksum, err := out.GetChecksum()
+			assert.Nil(t, err)
+			assert.Equal(t, int64(len(payload)), out.GetFilePointer())
+
+			// the file is not readable before its output is closed
+			_, err = dir.OpenInput(ctx, "test.bin")
+			assert.NotNil(t, err)
+			assert.Nil(t, out.Close())
+			assert.Equal(t, int64(len(payload)), dir.RamBytesUsed())
+
+			length, err := dir.FileLength(ctx, "test.bin")
+			assert.Nil(t, err)
+			assert.Equal(t, int64(len(payload)), length)
+
+			in, err := OpenChecksumInput(dir, "test.bin")
+			assert.Nil(t, err)
+			read := make([]byte, len(payload))
+			_, err = io.ReadFull(in, read)
+			assert.Nil(t, err)
+			assert.Equal(t, payload, read)
+			assert.Equal(t, checksum, in.GetChecksum())
+
+			input, err := dir.OpenInput(ctx, "test.bin")
+			assert.Nil(t, err)
+			r := rand.New(rand.NewSource(2))
+			for i := 0; i < 100; i++ {
+				pos := r.Intn(len(payload) - 100)
+				_, err := input.Seek(int64(pos), io.SeekStart)
+				assert.Nil(t, err)
+				buf := make([]byte, r.Intn(100)+1)
+				_, err = io.ReadFull(input, buf)
+				assert.Nil(t, err)
+				assert.Equal(t, payload[pos:pos+len(buf)], buf)
+			}
+
+			slice, err := input.Slice("slice", 1000, 50_000)
+			assert.Nil(t, err)
+			random, err := slice.RandomAccessSlice(1021, 100)
+			assert.Nil(t, err)
+			v, err := random.ReadU64(3)
+			assert.Nil(t, err)
+			assert.Equal(t, binary.BigEndian.Uint64(payload[2024:]), v)
+			_, err = input.Slice("slice", 1, int64(len(payload)))
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestByteBuffersDirectory_Files(t *testing.T) {
+	ctx := context.Background()
+	dir := NewByteBuffersDirectory()
+
+	for _, name := range []string{"b", "a", "c"} {
+		out, err := dir.CreateOutput(ctx, name)
+		assert.Nil(t, err)
+		assert.Nil(t, out.WriteUint32(ctx, 1))
+		assert.Nil(t, out.Close())
+	}
+	_, err := dir.CreateOutput(ctx, "a")
+	assert.ErrorIs(t, err, fs.ErrExist)
+
+	files, err := dir.ListAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, files)
+
+	assert.Nil(t, dir.Rename(ctx, "a", "d"))
+	assert.ErrorIs(t, dir.Rename(ctx, "a", "e"), fs.ErrNotExist)
+	assert.ErrorIs(t, dir.Rename(ctx, "b", "c"), fs.ErrExist)
+	assert.Nil(t, dir.DeleteFile(ctx, "b"))
+	assert.ErrorIs(t, dir.DeleteFile(ctx, "b"), fs.ErrNotExist)
+	_, err = dir.FileLength(ctx, "b")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	files, err = dir.ListAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"c", "d"}, files)
+	assert.Equal(t, int64(8), dir.RamBytesUsed())
+
+	temp, err := dir.CreateTempOutput(ctx, "_0", "sort")
+	assert.Nil(t, err)
+	assert.Nil(t, temp.Close())
+	assert.NotEqual(t, "", temp.GetName())
+
+	other := NewByteBuffersDirectory()
+	assert.Nil(t, other.CopyFrom(ctx, dir, "d", "copied", nil))
+	in, err := other.OpenInput(ctx, "copied")
+	assert.Nil(t, err)
+	v, err := in.ReadUint32(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), v)
+
+	lock, err := dir.ObtainLock("write.lock")
+	assert.Nil(t, err)
+	_, err = dir.ObtainLock("write.lock")
+	assert.NotNil(t, err)
+	assert.Nil(t, lock.Close())
+
+	assert.Nil(t, dir.Close())
+	assert.NotNil(t, dir.EnsureOpen())
+	_, err = dir.OpenInput(ctx, "c")
+	assert.NotNil(t, err)
+}
+
+func TestByteBuffersDirectory_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	dir := NewByteBuffersDirectory()
+	defer dir.Close()
+
+	content := func(i int) []byte {
+		return []byte(fmt.Sprintf("file %d content", i))
+	}
+	write := func(i int) error {
+		out, err := dir.CreateOutput(ctx, fmt.Sprintf("_%d.bin", i))
+		if err != nil {
+			return err
+		}
+		if _, err := out.Write(content(i)); err != nil {
+			return err
+		}
+		return out.Close()
+	}
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, write(i))
+	}
+
+	// readers open and read the existing files while writers add new ones
+	wg := &sync.WaitGroup{}
+	errs := make(chan error, 100)
+	for g := 0; g < 8; g++ {
+		wg.Add(2)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				errs <- write(10 + g*10 + i)
+			}
+		}(g)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				in, err := dir.OpenInput(ctx, fmt.Sprintf("_%d.bin", i%10))
+				if err != nil {
+					errs <- err
+					return
+				}
+				bs, err := io.ReadAll(in)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if string(bs) != string(content(i%10)) {
+					errs <- fmt.Errorf("unexpected content %s", bs)
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(errs)
+	}()
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+
+	files, err := dir.ListAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 90, len(files))
+}
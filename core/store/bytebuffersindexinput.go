@@ -0,0 +1,203 @@
+package store
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+var _ IndexInput = &ByteBuffersIndexInput{}
+var _ RandomAccessInput = &ByteBuffersIndexInput{}
+
+// ByteBuffersIndexInput
+// An IndexInput reading a list of blocks of 1 << blockBits bytes, the last block may be shorter. The
+// blocks are never modified, so any number of clones and slices can read them at once without locking;
+// each one only holds its own position. The positions off, end and pos are absolute positions in the
+// blocks; a slice reads the [off, end) range of them.
+type ByteBuffersIndexInput struct {
+	*BaseIndexInput
+
+	desc      string
+	blocks    [][]byte
+	blockBits int
+	blockMask int64
+	off       int64
+	end       int64
+	pos       int64
+	scratch   [8]byte
+}
+
+// NewByteBuffersIndexInput
+// desc: the description of the input, used in error messages
+// blocks: the content, every block but the last one must have 1 << blockBits bytes
+func NewByteBuffersIndexInput(desc string, blocks [][]byte, blockBits int) (*ByteBuffersIndexInput, error) {
+	length := int64(0)
+	for i, block := range blocks {
+		if i < len(blocks)-1 && len(block) != 1<<blockBits {
+			return nil, fmt.Errorf("block %d of %s has %d bytes, expected %d", i, desc, len(block), 1<<blockBits)
+		}
+		if len(block) > 1<<blockBits {
+			return nil, fmt.Errorf("last block of %s has %d bytes, more than %d", desc, len(block), 1<<blockBits)
+		}
+		length += int64(len(block))
+	}
+	return newByteBuffersIndexInput(desc, blocks, blockBits, 0, length), nil
+}
+
+func newByteBuffersIndexInput(desc string, blocks [][]byte, blockBits int, off, length int64) *ByteBuffersIndexInput {
+	input := &ByteBuffersIndexInput{
+		desc:      desc,
+		blocks:    blocks,
+		blockBits: blockBits,
+		blockMask: (int64(1) << blockBits) - 1,
+		off:       off,
+		end:       off + length,
+		pos:       off,
+	}
+	input.BaseIndexInput = NewBaseIndexInput(input)
+	return input
+}
+
+// readAt copies the bytes at the absolute position pos, the caller checks the bounds
+func (b *ByteBuffersIndexInput) readAt(p []byte, pos int64) int {
+	n := 0
+	for n < len(p) {
+		block := b.blocks[pos>>b.blockBits]
+		copied := copy(p[n:], block[pos&b.blockMask:])
+		n += copied
+		pos += int64(copied)
+	}
+	return n
+}
+
+// bytesAt returns size bytes at the position pos relative to the slice start. The bytes are read in
+// place when they are in a single block.
+func (b *ByteBuffersIndexInput) bytesAt(pos int64, size int) ([]byte, error) {
+	if pos < 0 || pos+int64(size) > b.end-b.off {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	absolute := b.off + pos
+	inBlock := absolute & b.blockMask
+	if inBlock+int64(size) <= b.blockMask+1 {
+		return b.blocks[absolute>>b.blockBits][inBlock : inBlock+int64(size)], nil
+	}
+	b.readAt(b.scratch[:size], absolute)
+	return b.scratch[:size], nil
+}
+
+func (b *ByteBuffersIndexInput) Read(p []byte) (int, error) {
+	if b.pos >= b.end {
+		return 0, io.EOF
+	}
+
+	size := min(int64(len(p)), b.end-b.pos)
+	n := b.readAt(p[:size], b.pos)
+	b.pos += int64(n)
+	return n, nil
+}
+
+func (b *ByteBuffersIndexInput) ReadByte() (byte, error) {
+	if b.pos >= b.end {
+		return 0, io.EOF
+	}
+
+	v := b.blocks[b.pos>>b.blockBits][b.pos&b.blockMask]
+	b.pos++
+	return v, nil
+}
+
+func (b *ByteBuffersIndexInput) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative position %d in %s", off, b.desc)
+	}
+	if off >= b.end-b.off {
+		return 0, io.EOF
+	}
+
+	size := min(int64(len(p)), b.end-b.off-off)
+	n := b.readAt(p[:size], b.off+off)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (b *ByteBuffersIndexInput) ReadU8(pos int64) (byte, error) {
+	bs, err := b.bytesAt(pos, 1)
+	if err != nil {
+		return 0, err
+	}
+	return bs[0], nil
+}
+
+func (b *ByteBuffersIndexInput) ReadU16(pos int64) (uint16, error) {
+	bs, err := b.bytesAt(pos, 2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(bs), nil
+}
+
+func (b *ByteBuffersIndexInput) ReadU32(pos int64) (uint32, error) {
+	bs, err := b.bytesAt(pos, 4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(bs), nil
+}
+
+func (b *ByteBuffersIndexInput) ReadU64(pos int64) (uint64, error) {
+	bs, err := b.bytesAt(pos, 8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(bs), nil
+}
+
+func (b *ByteBuffersIndexInput) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = b.off + offset
+	case io.SeekCurrent:
+		pos = b.pos + offset
+	case io.SeekEnd:
+		pos = b.end - offset
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+
+	if pos < b.off || pos > b.end {
+		return 0, fmt.Errorf("seek position %d out of bounds [0, %d] of %s", pos-b.off, b.end-b.off, b.desc)
+	}
+	b.pos = pos
+	return pos - b.off, nil
+}
+
+func (b *ByteBuffersIndexInput) GetFilePointer() int64 {
+	return b.pos - b.off
+}
+
+func (b *ByteBuffersIndexInput) Length() int64 {
+	return b.end - b.off
+}
+
+func (b *ByteBuffersIndexInput) Slice(desc string, offset, length int64) (IndexInput, error) {
+	if offset < 0 || length < 0 || offset+length > b.Length() {
+		return nil, fmt.Errorf("slice() %s out of bounds: offset=%d,length=%d,fileLength=%d of %s",
+			desc, offset, length, b.Length(), b.desc)
+	}
+	return newByteBuffersIndexInput(desc, b.blocks, b.blockBits, b.off+offset, length), nil
+}
+
+func (b *ByteBuffersIndexInput) Clone() CloneReader {
+	input := newByteBuffersIndexInput(b.desc, b.blocks, b.blockBits, b.off, b.end-b.off)
+	input.pos = b.pos
+	return input
+}
+
+// Close The blocks are released by the garbage collector once no input reads them
+func (b *ByteBuffersIndexInput) Close() error {
+	return nil
+}
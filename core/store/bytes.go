@@ -57,6 +57,9 @@ func NewBytesInput(bs []byte) *BytesInput {
 
 func (b *BytesInput) Read(p []byte) (n int, err error) {
 	less := len(b.bs) - b.pos
+	if less <= 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
 
 	copySize := len(p)
 	if len(p) > less {
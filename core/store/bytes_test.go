@@ -1,6 +1,7 @@
 package store
 
 import (
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -17,6 +18,9 @@ func TestBytesInput(t *testing.T) {
 	n, err := input.Read(b10)
 	assert.Nil(t, err)
 	assert.Equal(t, 9, n)
+
+	_, err = input.Read(b10)
+	assert.ErrorIs(t, err, io.EOF)
 }
 
 func TestBytesOutput(t *testing.T) {
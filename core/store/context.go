@@ -3,7 +3,7 @@ package store
 var (
 	DEFAULT  = NewIOContext(WithContextType(CONTEXT_DEFAULT))
 	READONCE = NewIOContext(WithReadOnce(true))
-	READ     = NewIOContext(WithReadOnce(true))
+	READ     = NewIOContext(WithReadOnce(false))
 )
 
 // IOContext holds additional details on the merge/search context.
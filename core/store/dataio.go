@@ -124,22 +124,21 @@ type BaseDataInput struct {
 }
 
 func (d *BaseDataInput) ReadByte() (byte, error) {
-	_, err := d.reader.Read(d.buff[:1])
-	if err != nil {
+	if _, err := io.ReadFull(d.reader, d.buff[:1]); err != nil {
 		return 0, err
 	}
 	return d.buff[0], nil
 }
 
 func (d *BaseDataInput) ReadUint16(ctx context.Context) (uint16, error) {
-	if _, err := d.reader.Read(d.buff[:2]); err != nil {
+	if _, err := io.ReadFull(d.reader, d.buff[:2]); err != nil {
 		return 0, err
 	}
 	return d.endian.Uint16(d.buff), nil
 }
 
 func (d *BaseDataInput) ReadUint32(context.Context) (uint32, error) {
-	if _, err := d.reader.Read(d.buff[:4]); err != nil {
+	if _, err := io.ReadFull(d.reader, d.buff[:4]); err != nil {
 		return 0, err
 	}
 	return d.endian.Uint32(d.buff), nil
@@ -153,13 +152,16 @@ func (d *BaseDataInput) ReadUvarint(context.Context) (uint64, error) {
 	return num, err
 }
 
-func (d *BaseDataInput) ReadZInt32(context.Context) (int64, error) {
-	//TODO implement me
-	panic("implement me")
+func (d *BaseDataInput) ReadZInt32(ctx context.Context) (int64, error) {
+	num, err := d.ReadUvarint(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return zigzag.Decode(num), nil
 }
 
 func (d *BaseDataInput) ReadUint64(context.Context) (uint64, error) {
-	if _, err := d.reader.Read(d.buff[:8]); err != nil {
+	if _, err := io.ReadFull(d.reader, d.buff[:8]); err != nil {
 		return 0, err
 	}
 	return d.endian.Uint64(d.buff), nil
@@ -188,11 +190,10 @@ func (d *BaseDataInput) ReadString(ctx context.Context) (string, error) {
 		buf = make([]byte, length)
 	}
 
-	_, err = d.reader.Read(buf)
-	if err != nil {
+	if _, err := io.ReadFull(d.reader, buf); err != nil {
 		return "", err
 	}
-	return string(d.buff[:length]), nil
+	return string(buf), nil
 }
 
 func (d *BaseDataInput) ReadMapOfStrings(ctx context.Context) (map[string]string, error) {
@@ -255,7 +256,7 @@ func (d *BaseDataInput) SkipBytes(ctx context.Context, numBytes int) error {
 	}
 	for skipped := 0; skipped < numBytes; {
 		step := min(SKIP_BUFFER_SIZE, numBytes-skipped)
-		if _, err := d.reader.Read(d.skipBuffer[0:step]); err != nil {
+		if _, err := io.ReadFull(d.reader, d.skipBuffer[0:step]); err != nil {
 			return err
 		}
 		skipped += step
@@ -383,8 +384,8 @@ func (d *BaseDataOutput) WriteUvarint(ctx context.Context, i uint64) error {
 }
 
 func (d *BaseDataOutput) WriteZInt32(ctx context.Context, i int32) error {
-	//TODO implement me
-	panic("implement me")
+	num := zigzag.Encode(int64(i))
+	return d.WriteUvarint(ctx, num)
 }
 
 func (d *BaseDataOutput) WriteUint64(ctx context.Context, i uint64) error {
@@ -401,7 +402,7 @@ func (d *BaseDataOutput) WriteZInt64(ctx context.Context, i int64) error {
 }
 
 func (d *BaseDataOutput) WriteString(ctx context.Context, s string) error {
-	if err := d.WriteUvarint(ctx, uint64(len([]rune(s)))); err != nil {
+	if err := d.WriteUvarint(ctx, uint64(len(s))); err != nil {
 		return err
 	}
 	if _, err := d.writer.Write([]byte(s)); err != nil {
@@ -425,7 +426,7 @@ func (d *BaseDataOutput) CopyBytes(ctx context.Context, input DataInput, numByte
 		if left > COPY_BUFFER_SIZE {
 			toCopy = COPY_BUFFER_SIZE
 		}
-		if _, err := input.Read(d.copyBuffer[:toCopy]); err != nil {
+		if _, err := io.ReadFull(input, d.copyBuffer[:toCopy]); err != nil {
 			return err
 		}
 		if _, err := d.writer.Write(d.copyBuffer[:toCopy]); err != nil {
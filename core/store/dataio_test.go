@@ -3,6 +3,7 @@ package store
 import (
 	"bytes"
 	"context"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -94,6 +95,62 @@ func TestRWString(t *testing.T) {
 	assert.Equal(t, "1024", num)
 }
 
+func TestRWString_NonASCII(t *testing.T) {
+	source := NewBuffer()
+	reader := NewBaseDataInput(source)
+	writer := NewBaseDataOutput(source)
+
+	values := []string{"héllo", "中文字符", "emoji 🙂", ""}
+	for _, v := range values {
+		err := writer.WriteString(context.Background(), v)
+		assert.Nil(t, err)
+	}
+	// the length prefix counts bytes, not runes, so the next value is not read from the middle of this one
+	err := writer.WriteUvarint(context.Background(), 1024)
+	assert.Nil(t, err)
+
+	for _, v := range values {
+		s, err := reader.ReadString(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, v, s)
+	}
+	num, err := reader.ReadUvarint(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1024), num)
+}
+
+func TestRWZInt32(t *testing.T) {
+	source := NewBuffer()
+	reader := NewBaseDataInput(source)
+	writer := NewBaseDataOutput(source)
+
+	values := []int32{0, 1, -1, 63, -64, math.MaxInt32, math.MinInt32}
+	for _, v := range values {
+		err := writer.WriteZInt32(context.Background(), v)
+		assert.Nil(t, err)
+	}
+	for _, v := range values {
+		num, err := reader.ReadZInt32(context.Background())
+		assert.Nil(t, err)
+		assert.EqualValues(t, v, num)
+	}
+}
+
+func TestReadString_ShortRead(t *testing.T) {
+	source := NewBuffer()
+	reader := NewBaseDataInput(source)
+	writer := NewBaseDataOutput(source)
+
+	// the length prefix promises more bytes than the input holds
+	err := writer.WriteUvarint(context.Background(), 8)
+	assert.Nil(t, err)
+	_, err = source.Write([]byte("abc"))
+	assert.Nil(t, err)
+
+	_, err = reader.ReadString(context.Background())
+	assert.NotNil(t, err)
+}
+
 func TestRWMapOfStrings(t *testing.T) {
 	source := NewBuffer()
 	reader := NewBaseDataInput(source)
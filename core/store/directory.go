@@ -116,6 +116,40 @@ func OpenChecksumInput(dir Directory, name string) (ChecksumIndexInput, error) {
 	return NewBufferedChecksumIndexInput(input), nil
 }
 
+// ContextOutputCreator
+// Implemented by the directories which create an output depending on the IOContext of the write,
+// like NRTCachingDirectory which caches the small files of a flush or a merge.
+type ContextOutputCreator interface {
+	CreateOutputWithContext(ctx context.Context, name string, ioContext *IOContext) (IndexOutput, error)
+}
+
+// CreateOutputWithContext
+// Creates a new, empty file in dir for a write with ioContext. The IOContext is ignored by the
+// directories which do not implement ContextOutputCreator.
+func CreateOutputWithContext(ctx context.Context, dir Directory, name string, ioContext *IOContext) (IndexOutput, error) {
+	if creator, ok := dir.(ContextOutputCreator); ok {
+		return creator.CreateOutputWithContext(ctx, name, ioContext)
+	}
+	return dir.CreateOutput(ctx, name)
+}
+
+// ContextInputOpener
+// Implemented by the directories which open an input depending on the IOContext of the read,
+// like MMapDirectory which gives the kernel a hint about how a file will be read.
+type ContextInputOpener interface {
+	OpenInputWithContext(ctx context.Context, name string, ioContext *IOContext) (IndexInput, error)
+}
+
+// OpenInputWithContext
+// Opens a file in dir for a read with ioContext. The IOContext is ignored by the directories which do
+// not implement ContextInputOpener.
+func OpenInputWithContext(ctx context.Context, dir Directory, name string, ioContext *IOContext) (IndexInput, error) {
+	if opener, ok := dir.(ContextInputOpener); ok {
+		return opener.OpenInputWithContext(ctx, name, ioContext)
+	}
+	return dir.OpenInput(ctx, name)
+}
+
 type BaseDirectory struct {
 	DeleteFile func(ctx context.Context, name string) error
 }
@@ -0,0 +1,631 @@
+package store
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+const (
+	// ENCRYPTION_MAGIC The first bytes of every encrypted file
+	ENCRYPTION_MAGIC = uint32(0x4c454e43)
+
+	ENCRYPTION_VERSION_START   = 0
+	ENCRYPTION_VERSION_CURRENT = ENCRYPTION_VERSION_START
+
+	// ENCRYPTION_MAX_KEY_ID_LENGTH The maximum length of a key ID, which is stored on one byte
+	ENCRYPTION_MAX_KEY_ID_LENGTH = 255
+)
+
+// KeyProvider
+// Supplies the AES keys of an EncryptedDirectory. New files are encrypted with the current key, and
+// the ID of that key is written in their header, so that the key can still be looked up to read
+// the file after the current key changed.
+type KeyProvider interface {
+	// CurrentKey Returns the ID and the key to encrypt new files with
+	CurrentKey() (keyID string, key []byte, err error)
+
+	// GetKey Returns the key with the given ID, to decrypt a file encrypted with it
+	GetKey(keyID string) ([]byte, error)
+}
+
+var _ KeyProvider = &StaticKeyProvider{}
+
+// StaticKeyProvider
+// A KeyProvider holding a fixed set of keys. To rotate keys, create a provider with a new current
+// key that still holds the previous keys, until no file encrypted with them is left.
+type StaticKeyProvider struct {
+	currentKeyID string
+	keys         map[string][]byte
+}
+
+// NewStaticKeyProvider
+// currentKeyID: the ID of the key to encrypt new files with, it must be one of the keys
+// keys: the AES-128, AES-192 or AES-256 keys by ID
+func NewStaticKeyProvider(currentKeyID string, keys map[string][]byte) (*StaticKeyProvider, error) {
+	for keyID, key := range keys {
+		if err := checkKey(keyID, key); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := keys[currentKeyID]; !ok {
+		return nil, fmt.Errorf("current key %s not found", currentKeyID)
+	}
+
+	copied := make(map[string][]byte, len(keys))
+	for keyID, key := range keys {
+		copied[keyID] = append([]byte(nil), key...)
+	}
+	return &StaticKeyProvider{
+		currentKeyID: currentKeyID,
+		keys:         copied,
+	}, nil
+}
+
+func (s *StaticKeyProvider) CurrentKey() (string, []byte, error) {
+	return s.currentKeyID, s.keys[s.currentKeyID], nil
+}
+
+func (s *StaticKeyProvider) GetKey(keyID string) ([]byte, error) {
+	key, ok := s.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found", keyID)
+	}
+	return key, nil
+}
+
+func checkKey(keyID string, key []byte) error {
+	if len(keyID) == 0 || len(keyID) > ENCRYPTION_MAX_KEY_ID_LENGTH {
+		return fmt.Errorf("key ID must have between 1 and %d bytes, got %d", ENCRYPTION_MAX_KEY_ID_LENGTH, len(keyID))
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return nil
+	default:
+		return fmt.Errorf("key %s must have 16, 24 or 32 bytes, got %d", keyID, len(key))
+	}
+}
+
+var errNotEncrypted = errors.New("not an encrypted file")
+
+var _ Directory = &EncryptedDirectory{}
+var _ ContextOutputCreator = &EncryptedDirectory{}
+var _ ContextInputOpener = &EncryptedDirectory{}
+
+// EncryptedDirectory
+// A Directory wrapper that encrypts the content of every file with AES in CTR mode, so that an
+// index is encrypted at rest without changing any codec.
+//
+// Every file starts with a clear header, followed by the encrypted content:
+//   - [uint32] ENCRYPTION_MAGIC
+//   - [byte] format version
+//   - [byte] length of the key ID, then the key ID
+//   - [16 bytes] the random IV of the file
+//
+// The CTR mode allows to decrypt from any position, so the inputs support Seek, Clone and Slice like
+// the inputs of the wrapped directory. Lengths, file pointers and checksums are those of the clear
+// content, so codec headers and footers are unchanged.
+type EncryptedDirectory struct {
+	Directory
+
+	keyProvider KeyProvider
+
+	// the header lengths of the files written or read through this directory, they depend on the
+	// key ID of each file
+	sync.Mutex
+	headerLengths map[string]int64
+}
+
+func NewEncryptedDirectory(in Directory, keyProvider KeyProvider) *EncryptedDirectory {
+	return &EncryptedDirectory{
+		Directory:     in,
+		keyProvider:   keyProvider,
+		headerLengths: make(map[string]int64),
+	}
+}
+
+// GetDelegate Returns the wrapped directory
+func (e *EncryptedDirectory) GetDelegate() Directory {
+	return e.Directory
+}
+
+func (e *EncryptedDirectory) CreateOutput(ctx context.Context, name string) (IndexOutput, error) {
+	return e.CreateOutputWithContext(ctx, name, DEFAULT)
+}
+
+// CreateOutputWithContext Creates the file in the wrapped directory for a write with ioContext, and
+// encrypts its content
+func (e *EncryptedDirectory) CreateOutputWithContext(ctx context.Context, name string, ioContext *IOContext) (IndexOutput, error) {
+	out, err := CreateOutputWithContext(ctx, e.Directory, name, ioContext)
+	if err != nil {
+		return nil, err
+	}
+	return e.newEncryptedOutput(ctx, out)
+}
+
+func (e *EncryptedDirectory) CreateTempOutput(ctx context.Context, prefix, suffix string) (IndexOutput, error) {
+	out, err := e.Directory.CreateTempOutput(ctx, prefix, suffix)
+	if err != nil {
+		return nil, err
+	}
+	return e.newEncryptedOutput(ctx, out)
+}
+
+func (e *EncryptedDirectory) newEncryptedOutput(ctx context.Context, out IndexOutput) (IndexOutput, error) {
+	output, err := newEncryptedIndexOutput(ctx, out, e.keyProvider)
+	if err != nil {
+		_ = out.Close()
+		return nil, err
+	}
+	e.setHeaderLength(out.GetName(), output.headerLength)
+	return output, nil
+}
+
+func (e *EncryptedDirectory) OpenInput(ctx context.Context, name string) (IndexInput, error) {
+	return e.OpenInputWithContext(ctx, name, DEFAULT)
+}
+
+// OpenInputWithContext Opens the file of the wrapped directory for a read with ioContext, and decrypts it
+func (e *EncryptedDirectory) OpenInputWithContext(ctx context.Context, name string, ioContext *IOContext) (IndexInput, error) {
+	in, err := OpenInputWithContext(ctx, e.Directory, name, ioContext)
+	if err != nil {
+		return nil, err
+	}
+	input, err := newEncryptedIndexInput(ctx, name, in, e.keyProvider)
+	if err != nil {
+		_ = in.Close()
+		return nil, err
+	}
+	e.setHeaderLength(name, input.dataOffset)
+	return input, nil
+}
+
+// FileLength Returns the length of the clear content of the file. The header is only read for the
+// files which were neither written nor read through this directory yet.
+func (e *EncryptedDirectory) FileLength(ctx context.Context, name string) (int64, error) {
+	length, err := e.Directory.FileLength(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	headerLength, err := e.getHeaderLength(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	return length - headerLength, nil
+}
+
+func (e *EncryptedDirectory) getHeaderLength(ctx context.Context, name string) (int64, error) {
+	e.Lock()
+	headerLength, ok := e.headerLengths[name]
+	e.Unlock()
+	if ok {
+		return headerLength, nil
+	}
+
+	in, err := e.Directory.OpenInput(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	header, err := readEncryptionHeader(ctx, name, in)
+	if err != nil {
+		return 0, err
+	}
+	e.setHeaderLength(name, header.length)
+	return header.length, nil
+}
+
+func (e *EncryptedDirectory) setHeaderLength(name string, headerLength int64) {
+	e.Lock()
+	defer e.Unlock()
+	e.headerLengths[name] = headerLength
+}
+
+func (e *EncryptedDirectory) DeleteFile(ctx context.Context, name string) error {
+	if err := e.Directory.DeleteFile(ctx, name); err != nil {
+		return err
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	delete(e.headerLengths, name)
+	return nil
+}
+
+func (e *EncryptedDirectory) Rename(ctx context.Context, source, dest string) error {
+	if err := e.Directory.Rename(ctx, source, dest); err != nil {
+		return err
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	if headerLength, ok := e.headerLengths[source]; ok {
+		e.headerLengths[dest] = headerLength
+		delete(e.headerLengths, source)
+	} else {
+		delete(e.headerLengths, dest)
+	}
+	return nil
+}
+
+// CopyFrom Copies the clear content of src, then encrypts it in dest
+func (e *EncryptedDirectory) CopyFrom(ctx context.Context, from Directory, src, dest string, ioContext *IOContext) error {
+	in, err := from.OpenInput(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := e.CreateOutputWithContext(ctx, dest, ioContext)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, io.LimitReader(in, in.Length())); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// encryptionHeader the clear header of an encrypted file
+type encryptionHeader struct {
+	keyID string
+	iv    []byte
+
+	// the length of the header in the file
+	length int64
+}
+
+func writeEncryptionHeader(ctx context.Context, out IndexOutput, keyID string, iv []byte) error {
+	if err := out.WriteUint32(ctx, ENCRYPTION_MAGIC); err != nil {
+		return err
+	}
+	if err := out.WriteByte(ENCRYPTION_VERSION_CURRENT); err != nil {
+		return err
+	}
+	if err := out.WriteByte(byte(len(keyID))); err != nil {
+		return err
+	}
+	if _, err := out.Write([]byte(keyID)); err != nil {
+		return err
+	}
+	_, err := out.Write(iv)
+	return err
+}
+
+func readEncryptionHeader(ctx context.Context, name string, in IndexInput) (*encryptionHeader, error) {
+	magic, err := in.ReadUint32(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if magic != ENCRYPTION_MAGIC {
+		return nil, fmt.Errorf("%w: %s, expected magic %x, got %x", errNotEncrypted, name, ENCRYPTION_MAGIC, magic)
+	}
+
+	version, err := in.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if version < ENCRYPTION_VERSION_START || version > ENCRYPTION_VERSION_CURRENT {
+		return nil, fmt.Errorf("%s: unsupported encryption version %d", name, version)
+	}
+
+	keyIDLength, err := in.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, int(keyIDLength)+aes.BlockSize)
+	if _, err := io.ReadFull(in, buf); err != nil {
+		return nil, err
+	}
+	return &encryptionHeader{
+		keyID:  string(buf[:keyIDLength]),
+		iv:     buf[keyIDLength:],
+		length: in.GetFilePointer(),
+	}, nil
+}
+
+var _ IndexOutput = &encryptedIndexOutput{}
+
+// encryptedIndexOutput encrypts the content written to the wrapped output. The checksum is the one
+// of the clear content.
+type encryptedIndexOutput struct {
+	*BaseIndexOutput
+
+	out          IndexOutput
+	block        cipher.Block
+	iv           []byte
+	headerLength int64
+	stream       cipher.Stream
+	crc          Hash
+	bytesWritten int64
+	buf          []byte
+}
+
+func newEncryptedIndexOutput(ctx context.Context, out IndexOutput, keyProvider KeyProvider) (*encryptedIndexOutput, error) {
+	keyID, key, err := keyProvider.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKey(keyID, key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	if err := writeEncryptionHeader(ctx, out, keyID, iv); err != nil {
+		return nil, err
+	}
+
+	output := &encryptedIndexOutput{
+		out:          out,
+		block:        block,
+		iv:           iv,
+		headerLength: out.GetFilePointer(),
+		stream:       cipher.NewCTR(block, iv),
+		crc:          NewHash(),
+	}
+	output.BaseIndexOutput = NewBaseIndexOutput(out.GetName(), output)
+	return output, nil
+}
+
+func (e *encryptedIndexOutput) Write(p []byte) (int, error) {
+	if cap(e.buf) < len(p) {
+		e.buf = make([]byte, len(p))
+	}
+	buf := e.buf[:len(p)]
+	e.stream.XORKeyStream(buf, p)
+
+	n, err := e.out.Write(buf)
+	e.crc.Write(p[:n])
+	e.bytesWritten += int64(n)
+	if n < len(p) {
+		// the key stream went past the bytes which were written, the next write continues after them
+		e.stream = newCTRStream(e.block, e.iv, e.bytesWritten)
+		if err == nil {
+			err = io.ErrShortWrite
+		}
+	}
+	return n, err
+}
+
+func (e *encryptedIndexOutput) GetFilePointer() int64 {
+	return e.bytesWritten
+}
+
+func (e *encryptedIndexOutput) GetChecksum() (uint32, error) {
+	return e.crc.Sum(), nil
+}
+
+func (e *encryptedIndexOutput) Close() error {
+	return e.out.Close()
+}
+
+var _ IndexInput = &encryptedIndexInput{}
+
+// encryptedIndexInput decrypts the content of the wrapped input. Positions are those of the clear
+// content; the key stream is positioned again after every seek.
+type encryptedIndexInput struct {
+	*BaseIndexInput
+
+	desc  string
+	in    IndexInput
+	block cipher.Block
+	iv    []byte
+
+	// dataOffset the offset of the encrypted content in the wrapped input
+	dataOffset int64
+
+	// off, end, pos are positions in the clear content
+	off int64
+	end int64
+	pos int64
+
+	// stream is positioned at pos, it is nil when it must be created again
+	stream  cipher.Stream
+	isClone bool
+}
+
+func newEncryptedIndexInput(ctx context.Context, name string, in IndexInput, keyProvider KeyProvider) (*encryptedIndexInput, error) {
+	header, err := readEncryptionHeader(ctx, name, in)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keyProvider.GetKey(header.keyID)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &encryptedIndexInput{
+		desc:       name,
+		in:         in,
+		block:      block,
+		iv:         header.iv,
+		dataOffset: header.length,
+		off:        0,
+		end:        in.Length() - header.length,
+		pos:        0,
+	}
+	input.BaseIndexInput = NewBaseIndexInput(input)
+	return input, nil
+}
+
+func (e *encryptedIndexInput) Read(p []byte) (int, error) {
+	if e.pos >= e.end {
+		return 0, io.EOF
+	}
+	if left := e.end - e.pos; int64(len(p)) > left {
+		p = p[:left]
+	}
+
+	if e.stream == nil {
+		if err := e.resetStream(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := e.in.Read(p)
+	e.stream.XORKeyStream(p[:n], p[:n])
+	e.pos += int64(n)
+	return n, err
+}
+
+// resetStream positions the wrapped input and the key stream at pos
+func (e *encryptedIndexInput) resetStream() error {
+	if _, err := e.in.Seek(e.dataOffset+e.pos, io.SeekStart); err != nil {
+		return err
+	}
+	e.stream = newCTRStream(e.block, e.iv, e.pos)
+	return nil
+}
+
+// newCTRStream returns the key stream of the file with the given IV, positioned at pos of the clear content
+func newCTRStream(block cipher.Block, iv []byte, pos int64) cipher.Stream {
+	// the counter of the block holding pos is the IV plus the block index
+	blockIndex := pos / aes.BlockSize
+	counter := new(big.Int).SetBytes(iv)
+	counter.Add(counter, big.NewInt(blockIndex))
+	counterBytes := counter.Bytes()
+
+	blockIV := make([]byte, aes.BlockSize)
+	if len(counterBytes) > aes.BlockSize {
+		// the counter wraps around
+		counterBytes = counterBytes[len(counterBytes)-aes.BlockSize:]
+	}
+	copy(blockIV[aes.BlockSize-len(counterBytes):], counterBytes)
+
+	stream := cipher.NewCTR(block, blockIV)
+	if skip := pos % aes.BlockSize; skip > 0 {
+		discard := make([]byte, skip)
+		stream.XORKeyStream(discard, discard)
+	}
+	return stream
+}
+
+func (e *encryptedIndexInput) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = e.off + offset
+	case io.SeekCurrent:
+		pos = e.pos + offset
+	case io.SeekEnd:
+		pos = e.end - offset
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+
+	if pos < e.off || pos > e.end {
+		return 0, fmt.Errorf("seek position %d out of bounds [0, %d] of %s", pos-e.off, e.end-e.off, e.desc)
+	}
+	if pos != e.pos {
+		e.pos = pos
+		e.stream = nil
+	}
+	return pos - e.off, nil
+}
+
+func (e *encryptedIndexInput) GetFilePointer() int64 {
+	return e.pos - e.off
+}
+
+func (e *encryptedIndexInput) Length() int64 {
+	return e.end - e.off
+}
+
+func (e *encryptedIndexInput) Slice(desc string, offset, length int64) (IndexInput, error) {
+	if offset < 0 || length < 0 || offset+length > e.Length() {
+		return nil, fmt.Errorf("slice() %s out of bounds: offset=%d,length=%d,fileLength=%d of %s",
+			desc, offset, length, e.Length(), e.desc)
+	}
+
+	slice := e.clone()
+	slice.desc = desc
+	slice.off = e.off + offset
+	slice.end = slice.off + length
+	slice.pos = slice.off
+	return slice, nil
+}
+
+func (e *encryptedIndexInput) Clone() CloneReader {
+	return e.clone()
+}
+
+func (e *encryptedIndexInput) clone() *encryptedIndexInput {
+	input := &encryptedIndexInput{
+		desc:       e.desc,
+		in:         e.in.Clone().(IndexInput),
+		block:      e.block,
+		iv:         e.iv,
+		dataOffset: e.dataOffset,
+		off:        e.off,
+		end:        e.end,
+		pos:        e.pos,
+		isClone:    true,
+	}
+	input.BaseIndexInput = NewBaseIndexInput(input)
+	return input
+}
+
+func (e *encryptedIndexInput) Close() error {
+	// clones share the wrapped input of the original
+	if e.isClone {
+		return nil
+	}
+	return e.in.Close()
+}
+
+// ListEncryptionKeyIDs Returns the sorted IDs of the keys used to encrypt the files of the directory,
+// so that the keys which are no longer needed can be removed from the KeyProvider after a rotation.
+func ListEncryptionKeyIDs(ctx context.Context, dir *EncryptedDirectory) ([]string, error) {
+	names, err := dir.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keyIDs := make(map[string]struct{})
+	for _, name := range names {
+		in, err := dir.GetDelegate().OpenInput(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		header, err := readEncryptionHeader(ctx, name, in)
+		closeErr := in.Close()
+		if err != nil {
+			// lock files and other files not written through the directory are not encrypted
+			if errors.Is(err, io.EOF) || errors.Is(err, errNotEncrypted) {
+				continue
+			}
+			return nil, err
+		}
+		if closeErr != nil {
+			return nil, closeErr
+		}
+		keyIDs[header.keyID] = struct{}{}
+	}
+
+	ids := make([]string, 0, len(keyIDs))
+	for keyID := range keyIDs {
+		ids = append(ids, keyID)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
@@ -0,0 +1,307 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestKeyProvider(t *testing.T, currentKeyID string) *StaticKeyProvider {
+	keyProvider, err := NewStaticKeyProvider(currentKeyID, map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 16),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	})
+	assert.Nil(t, err)
+	return keyProvider
+}
+
+func TestEncryptedDirectory(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir()
+	inner, err := NewNIOFSDirectory(path)
+	assert.Nil(t, err)
+	dir := NewEncryptedDirectory(inner, newTestKeyProvider(t, "k1"))
+	defer dir.Close()
+
+	payload := make([]byte, 100_000)
+	rand.New(rand.NewSource(1)).Read(payload)
+
+	out, err := dir.CreateOutput(ctx, "test.bin")
+	assert.Nil(t, err)
+	assert.Nil(t, out.WriteUint32(ctx, 0xCAFEBABE))
+	assert.Nil(t, out.WriteUint64(ctx, 1234567890123))
+	_, err = out.Write(payload)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(12+len(payload)), out.GetFilePointer())
+	checksum, err := out.GetChecksum()
+	assert.Nil(t, err)
+	assert.Nil(t, out.Close())
+
+	// the clear content is not in the file
+	raw, err := os.ReadFile(filepath.Join(path, "test.bin"))
+	assert.Nil(t, err)
+	assert.False(t, bytes.Contains(raw, payload[:64]))
+
+	length, err := dir.FileLength(ctx, "test.bin")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(12+len(payload)), length)
+
+	in, err := dir.OpenInput(ctx, "test.bin")
+	assert.Nil(t, err)
+	defer in.Close()
+	assert.Equal(t, length, in.Length())
+
+	v32, err := in.ReadUint32(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(0xCAFEBABE), v32)
+	v64, err := in.ReadUint64(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1234567890123), v64)
+	read := make([]byte, len(payload))
+	_, err = io.ReadFull(in, read)
+	assert.Nil(t, err)
+	assert.Equal(t, payload, read)
+
+	// random seeks
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 100; i++ {
+		pos := r.Intn(len(payload) - 100)
+		_, err := in.Seek(int64(12+pos), io.SeekStart)
+		assert.Nil(t, err)
+		buf := make([]byte, r.Intn(100)+1)
+		_, err = io.ReadFull(in, buf)
+		assert.Nil(t, err)
+		assert.Equal(t, payload[pos:pos+len(buf)], buf)
+		assert.Equal(t, int64(12+pos+len(buf)), in.GetFilePointer())
+	}
+
+	// clones and slices have their own position
+	_, err = in.Seek(12, io.SeekStart)
+	assert.Nil(t, err)
+	clone := in.Clone().(IndexInput)
+	_, err = in.Seek(1000, io.SeekStart)
+	assert.Nil(t, err)
+	buf := make([]byte, 16)
+	_, err = io.ReadFull(clone, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, payload[:16], buf)
+	assert.Nil(t, clone.Close())
+
+	slice, err := in.Slice("slice", 12+5000, 3000)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3000), slice.Length())
+	_, err = slice.Seek(17, io.SeekStart)
+	assert.Nil(t, err)
+	_, err = io.ReadFull(slice, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, payload[5017:5033], buf)
+	_, err = slice.Seek(2990, io.SeekStart)
+	assert.Nil(t, err)
+	_, err = io.ReadFull(slice, buf)
+	assert.NotNil(t, err)
+	_, err = in.Slice("slice", 0, length+1)
+	assert.NotNil(t, err)
+
+	// the checksum is the one of the clear content
+	checksumIn, err := dir.OpenInput(ctx, "test.bin")
+	assert.Nil(t, err)
+	defer checksumIn.Close()
+	checksumInput := NewBufferedChecksumIndexInput(checksumIn)
+	_, err = io.Copy(io.Discard, io.LimitReader(checksumInput, length))
+	assert.Nil(t, err)
+	assert.Equal(t, checksum, checksumInput.GetChecksum())
+}
+
+func TestEncryptedDirectory_KeyRotation(t *testing.T) {
+	ctx := context.Background()
+	inner, err := NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+	defer inner.Close()
+
+	writeFile := func(dir Directory, name string, content []byte) {
+		out, err := dir.CreateOutput(ctx, name)
+		assert.Nil(t, err)
+		_, err = out.Write(content)
+		assert.Nil(t, err)
+		assert.Nil(t, out.Close())
+	}
+	readFile := func(dir Directory, name string) ([]byte, error) {
+		in, err := dir.OpenInput(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		defer in.Close()
+		return io.ReadAll(io.LimitReader(in, in.Length()))
+	}
+
+	writeFile(NewEncryptedDirectory(inner, newTestKeyProvider(t, "k1")), "old", []byte("old content"))
+
+	rotated := NewEncryptedDirectory(inner, newTestKeyProvider(t, "k2"))
+	writeFile(rotated, "new", []byte("new content"))
+
+	content, err := readFile(rotated, "old")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("old content"), content)
+	content, err = readFile(rotated, "new")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("new content"), content)
+
+	keyIDs, err := ListEncryptionKeyIDs(ctx, rotated)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"k1", "k2"}, keyIDs)
+
+	// once k1 is removed, the files encrypted with it can not be read
+	onlyK2, err := NewStaticKeyProvider("k2", map[string][]byte{"k2": bytes.Repeat([]byte{2}, 32)})
+	assert.Nil(t, err)
+	_, err = readFile(NewEncryptedDirectory(inner, onlyK2), "old")
+	assert.NotNil(t, err)
+
+	// a file written without encryption is rejected
+	writeFile(inner, "plain", []byte("plain content, not encrypted"))
+	_, err = readFile(rotated, "plain")
+	assert.ErrorIs(t, err, errNotEncrypted)
+
+	// copying from another directory encrypts the content
+	assert.Nil(t, rotated.CopyFrom(ctx, inner, "plain", "copied", nil))
+	content, err = readFile(rotated, "copied")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("plain content, not encrypted"), content)
+}
+
+// openCountingDirectory counts the files opened in the wrapped directory
+type openCountingDirectory struct {
+	Directory
+
+	opened int
+}
+
+func (o *openCountingDirectory) OpenInput(ctx context.Context, name string) (IndexInput, error) {
+	o.opened++
+	return o.Directory.OpenInput(ctx, name)
+}
+
+func TestEncryptedDirectory_FileLength(t *testing.T) {
+	ctx := context.Background()
+	inner := &openCountingDirectory{Directory: NewByteBuffersDirectory()}
+	defer inner.Close()
+
+	writeFile := func(dir Directory, name string, content string) {
+		out, err := dir.CreateOutput(ctx, name)
+		assert.Nil(t, err)
+		_, err = out.Write([]byte(content))
+		assert.Nil(t, err)
+		assert.Nil(t, out.Close())
+	}
+
+	dir := NewEncryptedDirectory(inner, newTestKeyProvider(t, "k1"))
+	writeFile(dir, "old", "old content")
+	length, err := dir.FileLength(ctx, "old")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len("old content")), length)
+	assert.Equal(t, 0, inner.opened)
+
+	// the header of a file depends on the length of its key ID
+	keyProvider, err := NewStaticKeyProvider("rotated-key", map[string][]byte{
+		"k1":          bytes.Repeat([]byte{1}, 16),
+		"rotated-key": bytes.Repeat([]byte{3}, 24),
+	})
+	assert.Nil(t, err)
+	rotated := NewEncryptedDirectory(inner, keyProvider)
+	writeFile(rotated, "new", "new content, longer")
+	length, err = rotated.FileLength(ctx, "new")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len("new content, longer")), length)
+	assert.Equal(t, 0, inner.opened)
+
+	// the header of a file written before is read once
+	for i := 0; i < 2; i++ {
+		length, err = rotated.FileLength(ctx, "old")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(len("old content")), length)
+		assert.Equal(t, 1, inner.opened)
+	}
+
+	assert.Nil(t, rotated.Rename(ctx, "new", "renamed"))
+	length, err = rotated.FileLength(ctx, "renamed")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len("new content, longer")), length)
+	assert.Equal(t, 1, inner.opened)
+
+	assert.Nil(t, rotated.DeleteFile(ctx, "renamed"))
+	_, err = rotated.FileLength(ctx, "renamed")
+	assert.NotNil(t, err)
+}
+
+// shortWriteOutput writes at most limit bytes at once
+type shortWriteOutput struct {
+	IndexOutput
+
+	limit int
+}
+
+func (s *shortWriteOutput) Write(p []byte) (int, error) {
+	if len(p) <= s.limit {
+		return s.IndexOutput.Write(p)
+	}
+	n, err := s.IndexOutput.Write(p[:s.limit])
+	if err != nil {
+		return n, err
+	}
+	return n, io.ErrShortWrite
+}
+
+func TestEncryptedDirectory_ShortWrite(t *testing.T) {
+	ctx := context.Background()
+	inner := NewByteBuffersDirectory()
+	dir := NewEncryptedDirectory(inner, newTestKeyProvider(t, "k1"))
+	defer dir.Close()
+
+	payload := make([]byte, 1000)
+	rand.New(rand.NewSource(1)).Read(payload)
+
+	innerOut, err := inner.CreateOutput(ctx, "test.bin")
+	assert.Nil(t, err)
+	out, err := newEncryptedIndexOutput(ctx, &shortWriteOutput{IndexOutput: innerOut, limit: 37}, dir.keyProvider)
+	assert.Nil(t, err)
+
+	// the bytes which were not written are encrypted again by the next write
+	for written := 0; written < len(payload); {
+		n, err := out.Write(payload[written:])
+		if n < len(payload)-written {
+			assert.ErrorIs(t, err, io.ErrShortWrite)
+		}
+		written += n
+	}
+	assert.Equal(t, int64(len(payload)), out.GetFilePointer())
+	assert.Nil(t, out.Close())
+
+	in, err := dir.OpenInput(ctx, "test.bin")
+	assert.Nil(t, err)
+	defer in.Close()
+	read, err := io.ReadAll(io.LimitReader(in, in.Length()))
+	assert.Nil(t, err)
+	assert.Equal(t, payload, read)
+}
+
+func TestNewStaticKeyProvider(t *testing.T) {
+	_, err := NewStaticKeyProvider("k1", map[string][]byte{"k1": make([]byte, 15)})
+	assert.NotNil(t, err)
+
+	_, err = NewStaticKeyProvider("k3", map[string][]byte{"k1": make([]byte, 16)})
+	assert.NotNil(t, err)
+
+	keyProvider, err := NewStaticKeyProvider("k1", map[string][]byte{"k1": make([]byte, 24)})
+	assert.Nil(t, err)
+	keyID, key, err := keyProvider.CurrentKey()
+	assert.Nil(t, err)
+	assert.Equal(t, "k1", keyID)
+	assert.Equal(t, 24, len(key))
+	_, err = keyProvider.GetKey("k2")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var _ Directory = &FileSwitchDirectory{}
+var _ ContextInputOpener = &FileSwitchDirectory{}
+
+// FileSwitchDirectory
+// Expert: A Directory instance that switches files between two other Directory instances.
+//
+// Files with the specified extensions are placed in the primary directory; others are placed in the
+// secondary directory. The provided set must not change once passed to this class, and must allow
+// multiple threads to call contains at once.
+//
+// Files without an extension, such as segments_N, go to the secondary directory. A file can only be
+// renamed within one of the directories, so the source and the destination of Rename must have
+// extensions going to the same directory.
+type FileSwitchDirectory struct {
+	primaryExtensions map[string]struct{}
+	primaryDir        Directory
+	secondaryDir      Directory
+	doClose           bool
+}
+
+// NewFileSwitchDirectory
+// primaryExtensions: the extensions, without the dot, of the files placed in primaryDir
+// doClose: whether Close also closes both directories
+func NewFileSwitchDirectory(primaryExtensions map[string]struct{},
+	primaryDir, secondaryDir Directory, doClose bool) *FileSwitchDirectory {
+
+	return &FileSwitchDirectory{
+		primaryExtensions: primaryExtensions,
+		primaryDir:        primaryDir,
+		secondaryDir:      secondaryDir,
+		doClose:           doClose,
+	}
+}
+
+// GetPrimaryDir Return the primary directory
+func (f *FileSwitchDirectory) GetPrimaryDir() Directory {
+	return f.primaryDir
+}
+
+// GetSecondaryDir Return the secondary directory
+func (f *FileSwitchDirectory) GetSecondaryDir() Directory {
+	return f.secondaryDir
+}
+
+// GetExtension Utility method to return a file's extension.
+func GetExtension(name string) string {
+	i := strings.LastIndexByte(name, '.')
+	if i == -1 {
+		return ""
+	}
+	return name[i+1:]
+}
+
+func (f *FileSwitchDirectory) getDirectory(name string) Directory {
+	if _, ok := f.primaryExtensions[GetExtension(name)]; ok {
+		return f.primaryDir
+	}
+	return f.secondaryDir
+}
+
+func (f *FileSwitchDirectory) ListAll(ctx context.Context) ([]string, error) {
+	primaryFiles, err := f.primaryDir.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	secondaryFiles, err := f.secondaryDir.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]struct{}, len(primaryFiles)+len(secondaryFiles))
+	for _, name := range primaryFiles {
+		names[name] = struct{}{}
+	}
+	for _, name := range secondaryFiles {
+		names[name] = struct{}{}
+	}
+
+	files := make([]string, 0, len(names))
+	for name := range names {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (f *FileSwitchDirectory) DeleteFile(ctx context.Context, name string) error {
+	return f.getDirectory(name).DeleteFile(ctx, name)
+}
+
+func (f *FileSwitchDirectory) FileLength(ctx context.Context, name string) (int64, error) {
+	return f.getDirectory(name).FileLength(ctx, name)
+}
+
+func (f *FileSwitchDirectory) CreateOutput(ctx context.Context, name string) (IndexOutput, error) {
+	return f.getDirectory(name).CreateOutput(ctx, name)
+}
+
+func (f *FileSwitchDirectory) CreateOutputWithContext(ctx context.Context, name string, ioContext *IOContext) (IndexOutput, error) {
+	return CreateOutputWithContext(ctx, f.getDirectory(name), name, ioContext)
+}
+
+func (f *FileSwitchDirectory) CreateTempOutput(ctx context.Context, prefix, suffix string) (IndexOutput, error) {
+	// this is best effort - it's ok to create a tmp file with any prefix and suffix. Yet if this file is
+	// then in-turn used to rename they must match to the same directory hence we use the full file-name
+	// to find the right directory.
+	return f.getDirectory(SegmentFileName(prefix, suffix, "tmp")).CreateTempOutput(ctx, prefix, suffix)
+}
+
+func (f *FileSwitchDirectory) Rename(ctx context.Context, source, dest string) error {
+	sourceDir := f.getDirectory(source)
+	// won't happen with standard lucene index files since pending and commit will
+	// always have the same extension ("")
+	if sourceDir != f.getDirectory(dest) {
+		return fmt.Errorf("source and dest are in different directories: %s -> %s", source, dest)
+	}
+	return sourceDir.Rename(ctx, source, dest)
+}
+
+func (f *FileSwitchDirectory) OpenInput(ctx context.Context, name string) (IndexInput, error) {
+	return f.getDirectory(name).OpenInput(ctx, name)
+}
+
+func (f *FileSwitchDirectory) OpenInputWithContext(ctx context.Context, name string, ioContext *IOContext) (IndexInput, error) {
+	return OpenInputWithContext(ctx, f.getDirectory(name), name, ioContext)
+}
+
+func (f *FileSwitchDirectory) ObtainLock(name string) (Lock, error) {
+	return f.getDirectory(name).ObtainLock(name)
+}
+
+func (f *FileSwitchDirectory) Close() error {
+	if !f.doClose {
+		return nil
+	}
+	return errors.Join(f.primaryDir.Close(), f.secondaryDir.Close())
+}
+
+func (f *FileSwitchDirectory) CopyFrom(ctx context.Context, from Directory, src, dest string, ioContext *IOContext) error {
+	return f.getDirectory(dest).CopyFrom(ctx, from, src, dest, ioContext)
+}
+
+func (f *FileSwitchDirectory) EnsureOpen() error {
+	if err := f.primaryDir.EnsureOpen(); err != nil {
+		return err
+	}
+	return f.secondaryDir.EnsureOpen()
+}
+
+// Sync syncs every file in the directory it was placed in
+func (f *FileSwitchDirectory) Sync(files map[string]struct{}) error {
+	primaryFiles := make(map[string]struct{})
+	secondaryFiles := make(map[string]struct{})
+	for name := range files {
+		if f.getDirectory(name) == f.primaryDir {
+			primaryFiles[name] = struct{}{}
+		} else {
+			secondaryFiles[name] = struct{}{}
+		}
+	}
+
+	if err := f.primaryDir.Sync(primaryFiles); err != nil {
+		return err
+	}
+	return f.secondaryDir.Sync(secondaryFiles)
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSwitchDirectory(t *testing.T) {
+	ctx := context.Background()
+	primaryPath, secondaryPath := t.TempDir(), t.TempDir()
+	primary, err := NewNIOFSDirectory(primaryPath)
+	assert.Nil(t, err)
+	secondary, err := NewNIOFSDirectory(secondaryPath)
+	assert.Nil(t, err)
+
+	dir := NewFileSwitchDirectory(map[string]struct{}{"tim": {}, "doc": {}}, primary, secondary, true)
+
+	for _, name := range []string{"_0.tim", "_0.doc", "_0.fdt", "pending_segments_1"} {
+		out, err := dir.CreateOutput(ctx, name)
+		assert.Nil(t, err)
+		assert.Nil(t, out.WriteUint32(ctx, 42))
+		assert.Nil(t, out.Close())
+	}
+	exists := func(path, name string) bool {
+		_, err := os.Stat(filepath.Join(path, name))
+		return err == nil
+	}
+	assert.True(t, exists(primaryPath, "_0.tim"))
+	assert.True(t, exists(primaryPath, "_0.doc"))
+	assert.True(t, exists(secondaryPath, "_0.fdt"))
+	assert.True(t, exists(secondaryPath, "pending_segments_1"))
+	assert.False(t, exists(primaryPath, "_0.fdt"))
+
+	files, err := dir.ListAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"_0.doc", "_0.fdt", "_0.tim", "pending_segments_1"}, files)
+
+	for _, name := range files {
+		length, err := dir.FileLength(ctx, name)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(4), length)
+
+		in, err := dir.OpenInput(ctx, name)
+		assert.Nil(t, err)
+		v, err := in.ReadUint32(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(42), v)
+		assert.Nil(t, in.Close())
+	}
+
+	assert.Nil(t, dir.Sync(map[string]struct{}{"_0.tim": {}, "_0.fdt": {}}))
+
+	assert.Nil(t, dir.Rename(ctx, "pending_segments_1", "segments_1"))
+	assert.True(t, exists(secondaryPath, "segments_1"))
+	// the two names go to different directories
+	assert.NotNil(t, dir.Rename(ctx, "_0.fdt", "_1.tim"))
+	assert.NotNil(t, dir.Rename(ctx, "_0.tim", "_1.fdt"))
+
+	temp, err := dir.CreateTempOutput(ctx, "_0", "sort")
+	assert.Nil(t, err)
+	assert.Nil(t, temp.Close())
+	assert.True(t, exists(secondaryPath, temp.GetName()))
+
+	assert.Nil(t, dir.DeleteFile(ctx, "_0.tim"))
+	assert.Nil(t, dir.DeleteFile(ctx, "_0.fdt"))
+	assert.False(t, exists(primaryPath, "_0.tim"))
+	assert.False(t, exists(secondaryPath, "_0.fdt"))
+
+	assert.Nil(t, dir.EnsureOpen())
+	assert.Nil(t, dir.Close())
+	assert.NotNil(t, primary.EnsureOpen())
+	assert.NotNil(t, secondary.EnsureOpen())
+}
+
+func TestGetExtension(t *testing.T) {
+	assert.Equal(t, "tim", GetExtension("_0.tim"))
+	assert.Equal(t, "liv", GetExtension("_0_1.liv"))
+	assert.Equal(t, "", GetExtension("segments_1"))
+	assert.Equal(t, "", GetExtension("_0."))
+}
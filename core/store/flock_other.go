@@ -0,0 +1,18 @@
+//go:build !linux && !darwin
+
+package store
+
+import (
+	"errors"
+	"os"
+)
+
+var errNativeLockUnsupported = errors.New("native file locks are not supported on this platform")
+
+func tryLockFile(file *os.File) (bool, error) {
+	return false, errNativeLockUnsupported
+}
+
+func unlockFile(file *os.File) error {
+	return errNativeLockUnsupported
+}
@@ -0,0 +1,287 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/bits"
+	"sort"
+	"sync/atomic"
+)
+
+// ErrReadOnlyDirectory is returned by the methods of an IOFSDirectory which would modify it.
+var ErrReadOnlyDirectory = errors.New("read-only directory")
+
+var _ Directory = &IOFSDirectory{}
+
+// IOFSDirectory
+// A read-only Directory over the files of an fs.FS, such as an embed.FS, so that a small prebuilt index
+// can be shipped inside a binary and searched without extracting it to disk:
+//
+//	//go:embed index
+//	var indexFS embed.FS
+//
+//	dir, err := store.NewIOFSDirectory(indexFS, "index")
+//
+// The inputs read the files through io.ReaderAt when the fs.File implements it, as the files of embed.FS
+// and os.DirFS do, so that clones and slices read at their own positions without copying the file.
+// Otherwise the whole file is read in memory when it is opened.
+//
+// All the methods modifying the directory return ErrReadOnlyDirectory.
+type IOFSDirectory struct {
+	fsys fs.FS
+	open atomic.Bool
+}
+
+// NewIOFSDirectory
+// fsys: the file system holding the index
+// root: the directory of the index in fsys, "." for its root
+func NewIOFSDirectory(fsys fs.FS, root string) (*IOFSDirectory, error) {
+	if root != "." {
+		sub, err := fs.Sub(fsys, root)
+		if err != nil {
+			return nil, err
+		}
+		fsys = sub
+	}
+
+	stat, err := fs.Stat(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("%s is not dir", root)
+	}
+
+	dir := &IOFSDirectory{fsys: fsys}
+	dir.open.Store(true)
+	return dir, nil
+}
+
+func (d *IOFSDirectory) ListAll(ctx context.Context) ([]string, error) {
+	if err := d.EnsureOpen(); err != nil {
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(d.fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (d *IOFSDirectory) DeleteFile(ctx context.Context, name string) error {
+	return fmt.Errorf("cannot delete %s: %w", name, ErrReadOnlyDirectory)
+}
+
+func (d *IOFSDirectory) FileLength(ctx context.Context, name string) (int64, error) {
+	if err := d.EnsureOpen(); err != nil {
+		return 0, err
+	}
+
+	stat, err := fs.Stat(d.fsys, name)
+	if err != nil {
+		return 0, err
+	}
+	return stat.Size(), nil
+}
+
+func (d *IOFSDirectory) CreateOutput(ctx context.Context, name string) (IndexOutput, error) {
+	return nil, fmt.Errorf("cannot create %s: %w", name, ErrReadOnlyDirectory)
+}
+
+func (d *IOFSDirectory) CreateTempOutput(ctx context.Context, prefix, suffix string) (IndexOutput, error) {
+	return nil, fmt.Errorf("cannot create a temp file %s: %w", prefix, ErrReadOnlyDirectory)
+}
+
+func (d *IOFSDirectory) Rename(ctx context.Context, source, dest string) error {
+	return fmt.Errorf("cannot rename %s: %w", source, ErrReadOnlyDirectory)
+}
+
+func (d *IOFSDirectory) OpenInput(ctx context.Context, name string) (IndexInput, error) {
+	if err := d.EnsureOpen(); err != nil {
+		return nil, err
+	}
+
+	file, err := d.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if !stat.Mode().IsRegular() {
+		_ = file.Close()
+		return nil, fmt.Errorf("%s is not a regular file", name)
+	}
+
+	if readerAt, ok := file.(io.ReaderAt); ok {
+		return newIOFSIndexInput(name, file, readerAt, 0, stat.Size()), nil
+	}
+
+	// the file can only be read sequentially
+	bs, err := io.ReadAll(io.LimitReader(file, stat.Size()))
+	if err := errors.Join(err, file.Close()); err != nil {
+		return nil, err
+	}
+	// the block is larger than the content, so every position is in it
+	return NewByteBuffersIndexInput(name, [][]byte{bs}, bits.Len64(uint64(len(bs))))
+}
+
+// ObtainLock Locks are only needed to write
+func (d *IOFSDirectory) ObtainLock(name string) (Lock, error) {
+	return nil, fmt.Errorf("cannot obtain lock %s: %w", name, ErrReadOnlyDirectory)
+}
+
+func (d *IOFSDirectory) Close() error {
+	d.open.Store(false)
+	return nil
+}
+
+func (d *IOFSDirectory) CopyFrom(ctx context.Context, from Directory, src, dest string, ioContext *IOContext) error {
+	return fmt.Errorf("cannot copy to %s: %w", dest, ErrReadOnlyDirectory)
+}
+
+func (d *IOFSDirectory) EnsureOpen() error {
+	if d.open.Load() {
+		return nil
+	}
+	return errors.New("directory is closed")
+}
+
+// Sync Nothing was written, so syncing files is a write attempt
+func (d *IOFSDirectory) Sync(files map[string]struct{}) error {
+	if len(files) == 0 {
+		return nil
+	}
+	return fmt.Errorf("cannot sync %d files: %w", len(files), ErrReadOnlyDirectory)
+}
+
+var _ IndexInput = &IOFSIndexInput{}
+
+// IOFSIndexInput
+// An IndexInput reading a file of an fs.FS with io.ReaderAt. The clones and slices share the file, and
+// only hold their own position; closing them does nothing.
+type IOFSIndexInput struct {
+	*BaseIndexInput
+
+	desc     string
+	file     fs.File
+	readerAt io.ReaderAt
+
+	// off, end, pos are absolute positions in the file
+	off     int64
+	end     int64
+	pos     int64
+	isClone bool
+}
+
+func newIOFSIndexInput(desc string, file fs.File, readerAt io.ReaderAt, off, length int64) *IOFSIndexInput {
+	input := &IOFSIndexInput{
+		desc:     desc,
+		file:     file,
+		readerAt: readerAt,
+		off:      off,
+		end:      off + length,
+		pos:      off,
+	}
+	input.BaseIndexInput = NewBaseIndexInput(input)
+	return input
+}
+
+func (i *IOFSIndexInput) Read(p []byte) (int, error) {
+	if i.pos >= i.end {
+		return 0, io.EOF
+	}
+
+	size := min(int64(len(p)), i.end-i.pos)
+	n, err := i.readerAt.ReadAt(p[:size], i.pos)
+	i.pos += int64(n)
+	if err != nil && (!errors.Is(err, io.EOF) || int64(n) < size) {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return n, err
+	}
+	return n, nil
+}
+
+func (i *IOFSIndexInput) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative position %d in %s", off, i.desc)
+	}
+	if off >= i.end-i.off {
+		return 0, io.EOF
+	}
+
+	size := min(int64(len(p)), i.end-i.off-off)
+	n, err := i.readerAt.ReadAt(p[:size], i.off+off)
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (i *IOFSIndexInput) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = i.off + offset
+	case io.SeekCurrent:
+		pos = i.pos + offset
+	case io.SeekEnd:
+		pos = i.end - offset
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+
+	if pos < i.off || pos > i.end {
+		return 0, fmt.Errorf("seek position %d out of bounds [0, %d] of %s", pos-i.off, i.end-i.off, i.desc)
+	}
+	i.pos = pos
+	return pos - i.off, nil
+}
+
+func (i *IOFSIndexInput) GetFilePointer() int64 {
+	return i.pos - i.off
+}
+
+func (i *IOFSIndexInput) Length() int64 {
+	return i.end - i.off
+}
+
+func (i *IOFSIndexInput) Slice(desc string, offset, length int64) (IndexInput, error) {
+	if offset < 0 || length < 0 || offset+length > i.Length() {
+		return nil, fmt.Errorf("slice() %s out of bounds: offset=%d,length=%d,fileLength=%d of %s",
+			desc, offset, length, i.Length(), i.desc)
+	}
+
+	slice := newIOFSIndexInput(desc, i.file, i.readerAt, i.off+offset, length)
+	slice.isClone = true
+	return slice, nil
+}
+
+func (i *IOFSIndexInput) Clone() CloneReader {
+	input := newIOFSIndexInput(i.desc, i.file, i.readerAt, i.off, i.end-i.off)
+	input.pos = i.pos
+	input.isClone = true
+	return input
+}
+
+func (i *IOFSIndexInput) Close() error {
+	if i.isClone {
+		return nil
+	}
+	return i.file.Close()
+}
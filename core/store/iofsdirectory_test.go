@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"embed"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//go:embed iofsdirectory.go
+var iofsDirectoryTestFS embed.FS
+
+// sequentialFS hides the io.ReaderAt of the regular files of the wrapped fs.FS
+type sequentialFS struct {
+	fs.FS
+}
+
+type sequentialFile struct {
+	fs.File
+}
+
+func (s sequentialFS) Open(name string) (fs.File, error) {
+	file, err := s.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := file.(fs.ReadDirFile); ok {
+		return file, nil
+	}
+	return sequentialFile{File: file}, nil
+}
+
+func TestIOFSDirectory_Read(t *testing.T) {
+	ctx := context.Background()
+	content := []byte("0123456789abcdefghij")
+	mapFS := fstest.MapFS{
+		"index/b":     {Data: content},
+		"index/a":     {Data: []byte("a")},
+		"index/sub/c": {Data: []byte("c")},
+	}
+
+	tempDir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(tempDir, "a"), []byte("a"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(tempDir, "b"), content, 0644))
+
+	fileSystems := map[string]fs.FS{
+		"map":        mapFS,
+		"dir":        os.DirFS(filepath.Dir(tempDir)),
+		"sequential": sequentialFS{FS: mapFS},
+	}
+	roots := map[string]string{
+		"map":        "index",
+		"dir":        filepath.Base(tempDir),
+		"sequential": "index",
+	}
+
+	for name, fsys := range fileSystems {
+		t.Run(name, func(t *testing.T) {
+			dir, err := NewIOFSDirectory(fsys, roots[name])
+			assert.Nil(t, err)
+
+			names, err := dir.ListAll(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"a", "b"}, names)
+			length, err := dir.FileLength(ctx, "b")
+			assert.Nil(t, err)
+			assert.Equal(t, int64(len(content)), length)
+			_, err = dir.FileLength(ctx, "c")
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+
+			in, err := dir.OpenInput(ctx, "b")
+			assert.Nil(t, err)
+			read, err := io.ReadAll(in)
+			assert.Nil(t, err)
+			assert.Equal(t, content, read)
+
+			_, err = in.Seek(5, io.SeekStart)
+			assert.Nil(t, err)
+			clone := in.Clone().(IndexInput)
+			slice, err := in.Slice("slice", 10, 5)
+			assert.Nil(t, err)
+			v, err := clone.ReadByte()
+			assert.Nil(t, err)
+			assert.Equal(t, content[5], v)
+			read, err = io.ReadAll(slice)
+			assert.Nil(t, err)
+			assert.Equal(t, content[10:15], read)
+			_, err = slice.Seek(6, io.SeekStart)
+			assert.NotNil(t, err)
+			assert.Equal(t, int64(5), in.GetFilePointer())
+			assert.Nil(t, slice.Close())
+			assert.Nil(t, clone.Close())
+			assert.Nil(t, in.Close())
+
+			_, err = dir.OpenInput(ctx, "sub")
+			assert.NotNil(t, err)
+			assert.Nil(t, dir.Close())
+			_, err = dir.OpenInput(ctx, "a")
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestIOFSDirectory_ReadOnly(t *testing.T) {
+	ctx := context.Background()
+	dir, err := NewIOFSDirectory(iofsDirectoryTestFS, ".")
+	assert.Nil(t, err)
+
+	_, err = dir.CreateOutput(ctx, "a")
+	assert.ErrorIs(t, err, ErrReadOnlyDirectory)
+	_, err = dir.CreateTempOutput(ctx, "a", "b")
+	assert.ErrorIs(t, err, ErrReadOnlyDirectory)
+	assert.ErrorIs(t, dir.DeleteFile(ctx, "iofsdirectory.go"), ErrReadOnlyDirectory)
+	assert.ErrorIs(t, dir.Rename(ctx, "iofsdirectory.go", "a"), ErrReadOnlyDirectory)
+	assert.ErrorIs(t, dir.Sync(map[string]struct{}{"iofsdirectory.go": {}}), ErrReadOnlyDirectory)
+	assert.ErrorIs(t, dir.CopyFrom(ctx, NewByteBuffersDirectory(), "a", "b", nil), ErrReadOnlyDirectory)
+	_, err = dir.ObtainLock("write.lock")
+	assert.ErrorIs(t, err, ErrReadOnlyDirectory)
+
+	// the files of embed.FS are read with io.ReaderAt
+	in, err := dir.OpenInput(ctx, "iofsdirectory.go")
+	assert.Nil(t, err)
+	assert.IsType(t, &IOFSIndexInput{}, in)
+	expected, err := os.ReadFile("iofsdirectory.go")
+	assert.Nil(t, err)
+	read, err := io.ReadAll(in)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, read)
+	assert.Nil(t, in.Close())
+
+	_, err = NewIOFSDirectory(iofsDirectoryTestFS, "iofsdirectory.go")
+	assert.NotNil(t, err)
+}
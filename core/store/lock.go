@@ -171,7 +171,7 @@ var _ LockFactory = &SingleInstanceLockFactory{}
 // SingleInstanceLockFactory Implements LockFactory for a single in-process instance, meaning all
 // locking will take place through this one instance. Only use this LockFactory when you are certain
 // all IndexWriters for a given index are running against a single shared in-process Directory instance.
-// This is currently the default locking for RAMDirectory.
+// This is currently the default locking for ByteBuffersDirectory.
 // See Also: LockFactory
 type SingleInstanceLockFactory struct {
 	sync.RWMutex
@@ -241,8 +241,6 @@ func (s *SingleInstanceLock) EnsureValid() error {
 	return nil
 }
 
-/*
-
 const (
 	MSG_LOCK_RELEASED = 0
 	MSG_LOCK_ACQUIRED = 1
@@ -256,20 +254,20 @@ var _ LockFactory = &VerifyingLockFactory{}
 // holds the lock at a time. To use this, you should also run LockVerifyServer on the host and port
 // matching what you pass to the constructor.
 //
-// See Also: 	LockVerifyServer,
-//
-//	LockStressTest
+// See Also: 	LockVerifyServer
 type VerifyingLockFactory struct {
+	sync.Mutex
+
 	lf  LockFactory
-	in  io.ByteReader
-	out io.ByteWriter
+	in  io.Reader
+	out io.Writer
 }
 
 // NewVerifyingLockFactory
 // lf: the LockFactory that we are testing
 // in: the socket's input to LockVerifyServer
 // out: the socket's output to LockVerifyServer
-func NewVerifyingLockFactory(lf LockFactory, in io.ByteReader, out io.ByteWriter) *VerifyingLockFactory {
+func NewVerifyingLockFactory(lf LockFactory, in io.Reader, out io.Writer) *VerifyingLockFactory {
 	return &VerifyingLockFactory{lf: lf, in: in, out: out}
 }
 
@@ -278,32 +276,35 @@ func (v *VerifyingLockFactory) ObtainLock(dir Directory, lockName string) (Lock,
 	if err != nil {
 		return nil, err
 	}
-	return NewCheckedLock(v, lock), nil
+
+	checked := NewCheckedLock(v, lock)
+	if err := checked.verify(MSG_LOCK_ACQUIRED); err != nil {
+		return nil, errors.Join(err, lock.Close())
+	}
+	return checked, nil
 }
 
 var _ Lock = &CheckedLock{}
 
 type CheckedLock struct {
-	*VerifyingLockFactory
-
-	lock Lock
-
-	buff []byte
+	factory *VerifyingLockFactory
+	lock    Lock
 }
 
 func NewCheckedLock(factory *VerifyingLockFactory, lock Lock) *CheckedLock {
 	return &CheckedLock{
-		VerifyingLockFactory: factory,
-		lock:                 lock,
-		buff:                 make([]byte, 1),
+		factory: factory,
+		lock:    lock,
 	}
 }
 
+// Close tells the server the lock is released, then releases it
 func (c *CheckedLock) Close() error {
-	if err := c.lock.EnsureValid(); err != nil {
-		return err
+	err := c.lock.EnsureValid()
+	if err == nil {
+		err = c.verify(MSG_LOCK_RELEASED)
 	}
-	return c.verify(MSG_LOCK_RELEASED)
+	return errors.Join(err, c.lock.Close())
 }
 
 func (c *CheckedLock) EnsureValid() error {
@@ -311,22 +312,19 @@ func (c *CheckedLock) EnsureValid() error {
 }
 
 func (c *CheckedLock) verify(message byte) error {
-	err := c.out.WriteByte(message)
-	if err != nil {
-		return err
-	}
+	c.factory.Lock()
+	defer c.factory.Unlock()
 
-	ret, err := c.in.ReadByte()
-	if err != nil {
+	if _, err := c.factory.out.Write([]byte{message}); err != nil {
 		return err
 	}
-	if ret < 0 {
-		return errors.New("lock server died because of locking error")
+
+	ret := make([]byte, 1)
+	if _, err := io.ReadFull(c.factory.in, ret); err != nil {
+		return fmt.Errorf("lock server died because of locking error: %w", err)
 	}
-	if ret != message {
+	if ret[0] != message {
 		return errors.New("protocol violation")
 	}
 	return nil
 }
-
-*/
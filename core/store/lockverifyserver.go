@@ -0,0 +1,93 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// LockVerifyServer
+// Simple standalone server that must be running when you use VerifyingLockFactory. This server
+// verifies at most one process holds the lock at a time.
+//
+// Each client connects, sends its id as a big-endian uint32, then sends MSG_LOCK_ACQUIRED and
+// MSG_LOCK_RELEASED messages through a VerifyingLockFactory. The server echoes each message, unless it
+// detects two clients holding the lock at once, in which case it closes the connection.
+//
+// See Also: VerifyingLockFactory
+type LockVerifyServer struct {
+	sync.Mutex
+
+	lockedID int
+	hasLock  bool
+}
+
+func NewLockVerifyServer() *LockVerifyServer {
+	return &LockVerifyServer{}
+}
+
+// Serve accepts clients on the listener until it is closed, then returns the error of Accept.
+func (s *LockVerifyServer) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+
+			var id [4]byte
+			if _, err := io.ReadFull(conn, id[:]); err != nil {
+				return
+			}
+			_ = s.Handle(int(binary.BigEndian.Uint32(id[:])), conn)
+		}()
+	}
+}
+
+// Handle answers the messages of the client until its connection is closed. It returns an error without
+// answering as soon as the client violates the lock, the caller is expected to close the connection.
+func (s *LockVerifyServer) Handle(clientID int, conn io.ReadWriter) error {
+	message := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(conn, message); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		if err := s.verify(clientID, message[0]); err != nil {
+			return err
+		}
+		if _, err := conn.Write(message); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *LockVerifyServer) verify(clientID int, message byte) error {
+	s.Lock()
+	defer s.Unlock()
+
+	switch message {
+	case MSG_LOCK_ACQUIRED:
+		if s.hasLock {
+			return fmt.Errorf("client %d got lock, but %d already holds the lock", clientID, s.lockedID)
+		}
+		s.hasLock = true
+		s.lockedID = clientID
+	case MSG_LOCK_RELEASED:
+		if !s.hasLock || s.lockedID != clientID {
+			return fmt.Errorf("client %d released the lock, but it does not hold it", clientID)
+		}
+		s.hasLock = false
+	default:
+		return fmt.Errorf("unrecognized message from client %d: %d", clientID, message)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build darwin
+
+package store
+
+// madvise the syscall package has no madvise on darwin, the hint is ignored
+func madvise(b []byte, advice ReadAdvice) error {
+	return nil
+}
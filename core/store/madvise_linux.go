@@ -0,0 +1,17 @@
+//go:build linux
+
+package store
+
+import "syscall"
+
+// madvise gives the kernel a hint about how the mapped chunk will be read
+func madvise(b []byte, advice ReadAdvice) error {
+	switch advice {
+	case READ_ADVICE_RANDOM:
+		return syscall.Madvise(b, syscall.MADV_RANDOM)
+	case READ_ADVICE_SEQUENTIAL:
+		return syscall.Madvise(b, syscall.MADV_SEQUENTIAL)
+	default:
+		return syscall.Madvise(b, syscall.MADV_NORMAL)
+	}
+}
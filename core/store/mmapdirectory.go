@@ -0,0 +1,431 @@
+//go:build linux || darwin
+
+package store
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"syscall"
+)
+
+// ReadAdvice a hint given to the kernel about how a mapped file will be read
+type ReadAdvice int
+
+const (
+	READ_ADVICE_NORMAL = ReadAdvice(iota)
+	READ_ADVICE_RANDOM
+	READ_ADVICE_SEQUENTIAL
+)
+
+// DEFAULT_MAX_CHUNK_SIZE Default max chunk size: 1 GiB
+const DEFAULT_MAX_CHUNK_SIZE = int64(1) << 30
+
+var errMMapInputClosed = errors.New("already closed: MMapIndexInput")
+
+var _ FSDirectory = &MMapDirectory{}
+var _ ContextInputOpener = &MMapDirectory{}
+
+// MMapDirectory
+// File-based Directory implementation that uses mmap for reading, and NIOFSDirectory for writing.
+//
+// Files are mapped in chunks of at most maxChunkSize bytes. The IndexInputs returned by OpenInput, their
+// clones and their slices all read the same mapping without copying it, and they implement
+// RandomAccessInput for absolute reads.
+//
+// A madvise hint is given for every mapped chunk: files opened to be read once, or to be merged, are read
+// sequentially, other files are read randomly, see OpenInputWithContext. The codecs open their files
+// through OpenInputWithContext with the IOContext of the SegmentReadState, so the readers of a merge map
+// their files with the sequential hint.
+//
+// MMapDirectory is only built on Linux and macOS, use NIOFSDirectory on the other platforms. The madvise
+// hints are only given on Linux: the syscall package has no madvise on macOS, where the files are mapped
+// without a hint.
+//
+// NOTE: the mapping is released when the IndexInput returned by OpenInput is closed. The reads of the
+// clones and slices read straight from the mapping and are counted while they run, Close marks the
+// mapping closed and waits for the running reads before unmapping it, so a read that runs concurrently
+// with Close either completes or returns an error, it never reads unmapped memory.
+type MMapDirectory struct {
+	*NIOFSDirectory
+
+	chunkSizePower int
+}
+
+// NewMMapDirectory
+// Create a new MMapDirectory for the named location, with DEFAULT_MAX_CHUNK_SIZE.
+func NewMMapDirectory(path string) (*MMapDirectory, error) {
+	return NewMMapDirectoryWithMaxChunkSize(path, DEFAULT_MAX_CHUNK_SIZE)
+}
+
+// NewMMapDirectoryWithMaxChunkSize
+// Create a new MMapDirectory for the named location.
+// path: the path of the directory
+// maxChunkSize: maximum chunk size used for memory mapping. It is rounded down to a power of 2, and
+// must be at least the page size since chunks are mapped at multiples of it.
+func NewMMapDirectoryWithMaxChunkSize(path string, maxChunkSize int64) (*MMapDirectory, error) {
+	if maxChunkSize < int64(os.Getpagesize()) {
+		return nil, fmt.Errorf("maximum chunk size for mmap must be at least the page size %d, got %d",
+			os.Getpagesize(), maxChunkSize)
+	}
+
+	dir, err := NewNIOFSDirectory(path)
+	if err != nil {
+		return nil, err
+	}
+	return &MMapDirectory{
+		NIOFSDirectory: dir,
+		chunkSizePower: 63 - bits.LeadingZeros64(uint64(maxChunkSize)),
+	}, nil
+}
+
+// GetMaxChunkSize Returns the current mmap chunk size.
+func (m *MMapDirectory) GetMaxChunkSize() int64 {
+	return int64(1) << m.chunkSizePower
+}
+
+// CopyFrom copies through the inputs of this directory, so that the source is mapped when it is also
+// a MMapDirectory
+func (m *MMapDirectory) CopyFrom(ctx context.Context, from Directory, src, dest string, ioContext *IOContext) error {
+	return CopyFrom(ctx, m, from, src, dest, ioContext)
+}
+
+// OpenInput Creates an IndexInput for the file with the given name, to be read randomly.
+func (m *MMapDirectory) OpenInput(ctx context.Context, name string) (IndexInput, error) {
+	return m.OpenInputWithContext(ctx, name, DEFAULT)
+}
+
+// OpenInputWithContext Creates an IndexInput for the file with the given name. The madvise hint is
+// chosen from ioContext.
+func (m *MMapDirectory) OpenInputWithContext(ctx context.Context, name string, ioContext *IOContext) (IndexInput, error) {
+	// the directory lock isn't held while mapping, so a large file doesn't block the other operations
+	if err := m.EnsureOpen(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(m.resolveFilePath(name), os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	// the mapping stays valid after the file is closed
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	advice := getReadAdvice(ioContext)
+	chunks, err := m.mapFile(name, file, info.Size(), advice)
+	if err != nil {
+		return nil, err
+	}
+	buffers := &mmapBuffers{chunks: chunks, advice: advice}
+	return newMMapIndexInput(name, buffers, m.chunkSizePower, 0, info.Size(), false), nil
+}
+
+// getReadAdvice files read once or merged are read sequentially, other files are read randomly
+func getReadAdvice(ioContext *IOContext) ReadAdvice {
+	if ioContext == nil {
+		return READ_ADVICE_RANDOM
+	}
+	if ioContext.ReadOnce || ioContext.Type == CONTEXT_MERGE {
+		return READ_ADVICE_SEQUENTIAL
+	}
+	return READ_ADVICE_RANDOM
+}
+
+// mapFile maps the file in chunks of 1 << chunkSizePower bytes, the last one may be smaller
+func (m *MMapDirectory) mapFile(name string, file *os.File, length int64, advice ReadAdvice) ([][]byte, error) {
+	chunkSize := int64(1) << m.chunkSizePower
+	nrChunks := int((length + chunkSize - 1) >> m.chunkSizePower)
+
+	chunks := make([][]byte, 0, nrChunks)
+	for i := 0; i < nrChunks; i++ {
+		offset := int64(i) << m.chunkSizePower
+		size := min(chunkSize, length-offset)
+
+		chunk, err := syscall.Mmap(int(file.Fd()), offset, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+		if err != nil {
+			buffers := &mmapBuffers{chunks: chunks}
+			_ = buffers.unmap()
+			return nil, fmt.Errorf("map failed for %s, chunk %d: %w", name, i, err)
+		}
+		// the advice is only a hint, the chunk can be read without it
+		_ = madvise(chunk, advice)
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// mmapBuffers the mapped chunks of a file, shared by an input, its clones and its slices. A read is
+// counted in readers while it accesses the chunks, unmap waits for the running reads once it has set
+// closed, so that no read starts or runs on unmapped chunks.
+type mmapBuffers struct {
+	chunks  [][]byte
+	advice  ReadAdvice
+	closed  atomic.Bool
+	readers atomic.Int64
+}
+
+// acquire registers a read of the chunks, it must be followed by release unless it returns an error
+func (b *mmapBuffers) acquire() error {
+	b.readers.Add(1)
+	if b.closed.Load() {
+		b.readers.Add(-1)
+		return errMMapInputClosed
+	}
+	return nil
+}
+
+func (b *mmapBuffers) release() {
+	b.readers.Add(-1)
+}
+
+func (b *mmapBuffers) unmap() error {
+	if !b.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	// a read registered before closed was set may still access the chunks
+	for b.readers.Load() > 0 {
+		runtime.Gosched()
+	}
+
+	var err error
+	for _, chunk := range b.chunks {
+		err = errors.Join(err, syscall.Munmap(chunk))
+	}
+	b.chunks = nil
+	return err
+}
+
+var _ IndexInput = &MMapIndexInput{}
+var _ RandomAccessInput = &MMapIndexInput{}
+
+// MMapIndexInput
+// An IndexInput reading a memory mapped file. The positions off, end and pos are absolute positions in
+// the file; a slice reads the [off, end) range of it.
+type MMapIndexInput struct {
+	*BaseIndexInput
+
+	desc           string
+	buffers        *mmapBuffers
+	chunkSizePower int
+	chunkSizeMask  int64
+	off            int64
+	end            int64
+	pos            int64
+	isClone        bool
+	scratch        [8]byte
+}
+
+func newMMapIndexInput(desc string, buffers *mmapBuffers, chunkSizePower int, off, length int64, isClone bool) *MMapIndexInput {
+	input := &MMapIndexInput{
+		desc:           desc,
+		buffers:        buffers,
+		chunkSizePower: chunkSizePower,
+		chunkSizeMask:  (int64(1) << chunkSizePower) - 1,
+		off:            off,
+		end:            off + length,
+		pos:            off,
+		isClone:        isClone,
+	}
+	input.BaseIndexInput = NewBaseIndexInput(input)
+	return input
+}
+
+// GetReadAdvice Returns the madvise hint given for the mapped file
+func (m *MMapIndexInput) GetReadAdvice() ReadAdvice {
+	return m.buffers.advice
+}
+
+// readAt copies the bytes at the absolute position pos, the caller has acquired the buffers and checks the bounds
+func (m *MMapIndexInput) readAt(p []byte, pos int64) int {
+	n := 0
+	for n < len(p) {
+		chunk := m.buffers.chunks[pos>>m.chunkSizePower]
+		copied := copy(p[n:], chunk[pos&m.chunkSizeMask:])
+		n += copied
+		pos += int64(copied)
+	}
+	return n
+}
+
+// bytesAt returns size bytes at the position pos relative to the slice start, the caller has acquired
+// the buffers and must not use the bytes once it releases them. The bytes are the mapped memory itself,
+// unless they cross a chunk boundary, then they are copied to the scratch buffer.
+func (m *MMapIndexInput) bytesAt(pos int64, size int) ([]byte, error) {
+	if pos < 0 || pos+int64(size) > m.end-m.off {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	pos += m.off
+	offset := pos & m.chunkSizeMask
+	chunk := m.buffers.chunks[pos>>m.chunkSizePower]
+	if offset+int64(size) <= int64(len(chunk)) {
+		return chunk[offset : offset+int64(size)], nil
+	}
+	m.readAt(m.scratch[:size], pos)
+	return m.scratch[:size], nil
+}
+
+func (m *MMapIndexInput) Read(p []byte) (int, error) {
+	if err := m.buffers.acquire(); err != nil {
+		return 0, err
+	}
+	defer m.buffers.release()
+
+	if m.pos >= m.end {
+		return 0, io.EOF
+	}
+
+	size := min(int64(len(p)), m.end-m.pos)
+	n := m.readAt(p[:size], m.pos)
+	m.pos += int64(n)
+	return n, nil
+}
+
+func (m *MMapIndexInput) ReadByte() (byte, error) {
+	if err := m.buffers.acquire(); err != nil {
+		return 0, err
+	}
+	defer m.buffers.release()
+
+	if m.pos >= m.end {
+		return 0, io.EOF
+	}
+
+	b := m.buffers.chunks[m.pos>>m.chunkSizePower][m.pos&m.chunkSizeMask]
+	m.pos++
+	return b, nil
+}
+
+func (m *MMapIndexInput) ReadAt(p []byte, off int64) (int, error) {
+	if err := m.buffers.acquire(); err != nil {
+		return 0, err
+	}
+	defer m.buffers.release()
+
+	if off < 0 {
+		return 0, fmt.Errorf("negative position %d in %s", off, m.desc)
+	}
+	if off >= m.end-m.off {
+		return 0, io.EOF
+	}
+
+	size := min(int64(len(p)), m.end-m.off-off)
+	n := m.readAt(p[:size], m.off+off)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (m *MMapIndexInput) ReadU8(pos int64) (byte, error) {
+	if err := m.buffers.acquire(); err != nil {
+		return 0, err
+	}
+	defer m.buffers.release()
+
+	b, err := m.bytesAt(pos, 1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (m *MMapIndexInput) ReadU16(pos int64) (uint16, error) {
+	if err := m.buffers.acquire(); err != nil {
+		return 0, err
+	}
+	defer m.buffers.release()
+
+	b, err := m.bytesAt(pos, 2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+func (m *MMapIndexInput) ReadU32(pos int64) (uint32, error) {
+	if err := m.buffers.acquire(); err != nil {
+		return 0, err
+	}
+	defer m.buffers.release()
+
+	b, err := m.bytesAt(pos, 4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+func (m *MMapIndexInput) ReadU64(pos int64) (uint64, error) {
+	if err := m.buffers.acquire(); err != nil {
+		return 0, err
+	}
+	defer m.buffers.release()
+
+	b, err := m.bytesAt(pos, 8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+func (m *MMapIndexInput) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = m.off + offset
+	case io.SeekCurrent:
+		pos = m.pos + offset
+	case io.SeekEnd:
+		pos = m.end - offset
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+
+	if pos < m.off || pos > m.end {
+		return 0, fmt.Errorf("seek position %d out of bounds [0, %d] of %s", pos-m.off, m.end-m.off, m.desc)
+	}
+	m.pos = pos
+	return pos - m.off, nil
+}
+
+func (m *MMapIndexInput) GetFilePointer() int64 {
+	return m.pos - m.off
+}
+
+func (m *MMapIndexInput) Length() int64 {
+	return m.end - m.off
+}
+
+func (m *MMapIndexInput) Slice(desc string, offset, length int64) (IndexInput, error) {
+	if offset < 0 || length < 0 || offset+length > m.Length() {
+		return nil, fmt.Errorf("slice() %s out of bounds: offset=%d,length=%d,fileLength=%d of %s",
+			desc, offset, length, m.Length(), m.desc)
+	}
+	return newMMapIndexInput(desc, m.buffers, m.chunkSizePower, m.off+offset, length, true), nil
+}
+
+func (m *MMapIndexInput) Clone() CloneReader {
+	input := newMMapIndexInput(m.desc, m.buffers, m.chunkSizePower, m.off, m.end-m.off, true)
+	input.pos = m.pos
+	return input
+}
+
+// Close unmaps the file when called on the input returned by OpenInput, closing a clone or a slice
+// does nothing
+func (m *MMapIndexInput) Close() error {
+	if m.isClone {
+		return nil
+	}
+	return m.buffers.unmap()
+}
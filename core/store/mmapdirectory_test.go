@@ -0,0 +1,253 @@
+//go:build linux || darwin
+
+package store
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMMapDirectory(t *testing.T) {
+	ctx := context.Background()
+	chunkSize := int64(os.Getpagesize())
+	dir, err := NewMMapDirectoryWithMaxChunkSize(t.TempDir(), chunkSize+100)
+	assert.Nil(t, err)
+	defer dir.Close()
+	assert.Equal(t, chunkSize, dir.GetMaxChunkSize())
+
+	// the file spans several chunks
+	payload := make([]byte, int(chunkSize)*3+123)
+	rand.New(rand.NewSource(1)).Read(payload)
+
+	out, err := dir.CreateOutput(ctx, "test.bin")
+	assert.Nil(t, err)
+	_, err = out.Write(payload)
+	assert.Nil(t, err)
+	assert.Nil(t, out.Close())
+
+	in, err := dir.OpenInput(ctx, "test.bin")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(payload)), in.Length())
+
+	read := make([]byte, len(payload))
+	_, err = io.ReadFull(in, read)
+	assert.Nil(t, err)
+	assert.Equal(t, payload, read)
+	_, err = in.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+
+	// reads across chunk boundaries
+	_, err = in.Seek(chunkSize-3, io.SeekStart)
+	assert.Nil(t, err)
+	v, err := in.ReadUint64(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, binary.BigEndian.Uint64(payload[chunkSize-3:]), v)
+	assert.Equal(t, chunkSize+5, in.GetFilePointer())
+
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 100; i++ {
+		pos := r.Intn(len(payload) - 200)
+		_, err := in.Seek(int64(pos), io.SeekStart)
+		assert.Nil(t, err)
+		buf := make([]byte, r.Intn(200)+1)
+		_, err = io.ReadFull(in, buf)
+		assert.Nil(t, err)
+		assert.Equal(t, payload[pos:pos+len(buf)], buf)
+	}
+	_, err = in.Seek(int64(len(payload))+1, io.SeekStart)
+	assert.NotNil(t, err)
+
+	// clones and slices share the mapping, with their own position
+	_, err = in.Seek(10, io.SeekStart)
+	assert.Nil(t, err)
+	clone := in.Clone().(IndexInput)
+	_, err = in.Seek(1000, io.SeekStart)
+	assert.Nil(t, err)
+	b, err := clone.ReadByte()
+	assert.Nil(t, err)
+	assert.Equal(t, payload[10], b)
+	assert.Nil(t, clone.Close())
+
+	slice, err := in.Slice("slice", chunkSize-100, chunkSize+200)
+	assert.Nil(t, err)
+	assert.Equal(t, chunkSize+200, slice.Length())
+	assert.Equal(t, int64(0), slice.GetFilePointer())
+	b, err = slice.ReadByte()
+	assert.Nil(t, err)
+	assert.Equal(t, payload[chunkSize-100], b)
+	_, err = slice.Seek(0, io.SeekEnd)
+	assert.Nil(t, err)
+	_, err = slice.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+	_, err = in.Slice("slice", 10, int64(len(payload)))
+	assert.NotNil(t, err)
+
+	// absolute reads
+	random, err := in.RandomAccessSlice(chunkSize-2, 100)
+	assert.Nil(t, err)
+	u8, err := random.ReadU8(1)
+	assert.Nil(t, err)
+	assert.Equal(t, payload[chunkSize-1], u8)
+	u16, err := random.ReadU16(1)
+	assert.Nil(t, err)
+	assert.Equal(t, binary.BigEndian.Uint16(payload[chunkSize-1:]), u16)
+	u32, err := random.ReadU32(0)
+	assert.Nil(t, err)
+	assert.Equal(t, binary.BigEndian.Uint32(payload[chunkSize-2:]), u32)
+	u64, err := random.ReadU64(50)
+	assert.Nil(t, err)
+	assert.Equal(t, binary.BigEndian.Uint64(payload[chunkSize+48:]), u64)
+	_, err = random.ReadU64(95)
+	assert.NotNil(t, err)
+
+	buf := make([]byte, 10)
+	n, err := random.ReadAt(buf, 95)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, payload[chunkSize+93:chunkSize+98], buf[:5])
+
+	// the mapping is released when the input is closed
+	assert.Nil(t, in.Close())
+	_, err = slice.Seek(0, io.SeekStart)
+	assert.Nil(t, err)
+	_, err = slice.ReadByte()
+	assert.ErrorIs(t, err, errMMapInputClosed)
+	_, err = random.ReadU32(0)
+	assert.ErrorIs(t, err, errMMapInputClosed)
+	assert.Nil(t, in.Close())
+}
+
+func TestMMapDirectory_OpenInputWithContext(t *testing.T) {
+	ctx := context.Background()
+	dir, err := NewMMapDirectory(t.TempDir())
+	assert.Nil(t, err)
+	defer dir.Close()
+	assert.Equal(t, DEFAULT_MAX_CHUNK_SIZE, dir.GetMaxChunkSize())
+
+	out, err := dir.CreateOutput(ctx, "empty")
+	assert.Nil(t, err)
+	assert.Nil(t, out.Close())
+	out, err = dir.CreateOutput(ctx, "small")
+	assert.Nil(t, err)
+	assert.Nil(t, out.WriteUint32(ctx, 42))
+	assert.Nil(t, out.Close())
+
+	// an empty file has no mapping
+	in, err := dir.OpenInputWithContext(ctx, "empty", READONCE)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), in.Length())
+	_, err = in.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Nil(t, in.Close())
+
+	advices := map[*IOContext]ReadAdvice{
+		DEFAULT:                          READ_ADVICE_RANDOM,
+		READ:                             READ_ADVICE_RANDOM,
+		READONCE:                         READ_ADVICE_SEQUENTIAL,
+		NewIOContext(WithMergeInfo(nil)): READ_ADVICE_SEQUENTIAL,
+		nil:                              READ_ADVICE_RANDOM,
+	}
+	// the wrappers pass the IOContext of the read to the MMapDirectory
+	wrappers := []Directory{
+		dir,
+		NewTrackingDirectoryWrapper(dir),
+		NewNRTCachingDirectory(dir, 5, 60),
+		NewFileSwitchDirectory(map[string]struct{}{}, NewByteBuffersDirectory(), dir, false),
+	}
+	for _, wrapper := range wrappers {
+		for ioContext, advice := range advices {
+			in, err := OpenInputWithContext(ctx, wrapper, "small", ioContext)
+			assert.Nil(t, err)
+			assert.Equal(t, advice, in.(*MMapIndexInput).GetReadAdvice())
+			// clones and slices share the mapping of the input
+			assert.Equal(t, advice, in.Clone().(*MMapIndexInput).GetReadAdvice())
+			v, err := in.ReadUint32(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, uint32(42), v)
+			assert.Nil(t, in.Close())
+		}
+	}
+	in, err = dir.OpenInput(ctx, "small")
+	assert.Nil(t, err)
+	assert.Equal(t, READ_ADVICE_RANDOM, in.(*MMapIndexInput).GetReadAdvice())
+	assert.Nil(t, in.Close())
+
+	_, err = dir.OpenInput(ctx, "missing")
+	assert.NotNil(t, err)
+
+	_, err = NewMMapDirectoryWithMaxChunkSize(t.TempDir(), 100)
+	assert.NotNil(t, err)
+}
+
+func TestMMapDirectory_CloseWhileReading(t *testing.T) {
+	ctx := context.Background()
+	chunkSize := int64(os.Getpagesize())
+	dir, err := NewMMapDirectoryWithMaxChunkSize(t.TempDir(), chunkSize)
+	assert.Nil(t, err)
+	defer dir.Close()
+
+	payload := make([]byte, 4*chunkSize)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	out, err := dir.CreateOutput(ctx, "_0.bin")
+	assert.Nil(t, err)
+	_, err = out.Write(payload)
+	assert.Nil(t, err)
+	assert.Nil(t, out.Close())
+
+	for round := 0; round < 20; round++ {
+		input, err := dir.OpenInput(ctx, "_0.bin")
+		assert.Nil(t, err)
+
+		var started, wg sync.WaitGroup
+		for r := 0; r < 4; r++ {
+			clone := input.Clone().(*MMapIndexInput)
+			started.Add(1)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				started.Done()
+				bs := make([]byte, 64)
+				for i := 0; ; i++ {
+					pos := int64(i*61) % (int64(len(payload)) - 64)
+					if _, err := clone.ReadAt(bs, pos); err != nil {
+						assert.ErrorIs(t, err, errMMapInputClosed)
+						return
+					}
+					assert.Equal(t, payload[pos:pos+64], bs)
+
+					// crosses a chunk boundary
+					v, err := clone.ReadU64(chunkSize - 3)
+					if err != nil {
+						assert.ErrorIs(t, err, errMMapInputClosed)
+						return
+					}
+					assert.Equal(t, binary.BigEndian.Uint64(payload[chunkSize-3:]), v)
+
+					if _, err := clone.Seek(pos, io.SeekStart); err != nil {
+						return
+					}
+					b, err := clone.ReadByte()
+					if err != nil {
+						assert.ErrorIs(t, err, errMMapInputClosed)
+						return
+					}
+					assert.Equal(t, payload[pos], b)
+				}
+			}()
+		}
+
+		// close while the clones are reading
+		started.Wait()
+		assert.Nil(t, input.Close())
+		wg.Wait()
+	}
+}
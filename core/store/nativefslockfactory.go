@@ -0,0 +1,200 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var _ FSLockFactory = &NativeFSLockFactory{}
+
+// NativeFSLockFactory
+// Implements LockFactory using an exclusive advisory lock (flock) on the lock file.
+//
+// When using NativeFSLockFactory, the lock file is never deleted, but the lock is released by the
+// operating system when the process exits, even abnormally, so a crashed process does not leave a
+// stale lock behind. Locks are held by open files, so this implementation also checks for locks held
+// by this process, in which case the OS lock could not be relied upon.
+//
+// Special care needs to be taken if you change the locking implementation: First be certain that no
+// writer is in fact writing to the index otherwise you can easily corrupt your index. Be sure to do the
+// LockFactory change on all Lucene instances and clean up all leftover lock files before starting the new
+// configuration for the first time. Different implementations can not work together!
+//
+// If you suspect that this or any other LockFactory is not working properly in your environment, you can
+// easily test it by using VerifyingLockFactory and LockVerifyServer.
+type NativeFSLockFactory struct {
+	*FSLockFactoryBase
+}
+
+// the real paths of the lock files locked by this process
+var (
+	nativeLocksHeldMutex sync.Mutex
+	nativeLocksHeld      = make(map[string]struct{})
+)
+
+func NewNativeFSLockFactory() *NativeFSLockFactory {
+	factory := &NativeFSLockFactory{}
+	factory.FSLockFactoryBase = NewFSLockFactoryBase(factory)
+	return factory
+}
+
+func (n *NativeFSLockFactory) ObtainFSLock(dir FSDirectory, lockName string) (Lock, error) {
+	lockDir, err := dir.GetDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure that lockDir exists and is a directory.
+	if err := os.MkdirAll(lockDir, 0755); err != nil {
+		return nil, err
+	}
+	lockFile := filepath.Join(lockDir, lockName)
+
+	// create the file if it does not exist yet, it is never deleted
+	file, err := os.OpenFile(lockFile, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	// fails if the lock file does not exist
+	realPath, err := filepath.EvalSymlinks(lockFile)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	_, creationTime, _ := FileTime(info)
+
+	if !markNativeLockHeld(realPath) {
+		_ = file.Close()
+		return nil, fmt.Errorf("lock held by this process: %s", realPath)
+	}
+
+	locked, err := tryLockFile(file)
+	if err != nil || !locked {
+		_ = file.Close()
+		clearNativeLockHeld(realPath)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("lock held by another program: %s", realPath)
+	}
+	return newNativeFSLock(file, realPath, info, creationTime), nil
+}
+
+func markNativeLockHeld(path string) bool {
+	nativeLocksHeldMutex.Lock()
+	defer nativeLocksHeldMutex.Unlock()
+
+	if _, ok := nativeLocksHeld[path]; ok {
+		return false
+	}
+	nativeLocksHeld[path] = struct{}{}
+	return true
+}
+
+func clearNativeLockHeld(path string) bool {
+	nativeLocksHeldMutex.Lock()
+	defer nativeLocksHeldMutex.Unlock()
+
+	if _, ok := nativeLocksHeld[path]; !ok {
+		return false
+	}
+	delete(nativeLocksHeld, path)
+	return true
+}
+
+func isNativeLockHeld(path string) bool {
+	nativeLocksHeldMutex.Lock()
+	defer nativeLocksHeldMutex.Unlock()
+
+	_, ok := nativeLocksHeld[path]
+	return ok
+}
+
+var _ Lock = &NativeFSLock{}
+
+type NativeFSLock struct {
+	sync.Mutex
+
+	file         *os.File
+	path         string
+	info         os.FileInfo
+	creationTime time.Time
+	closed       bool
+}
+
+func newNativeFSLock(file *os.File, path string, info os.FileInfo, creationTime time.Time) *NativeFSLock {
+	return &NativeFSLock{
+		file:         file,
+		path:         path,
+		info:         info,
+		creationTime: creationTime,
+	}
+}
+
+// EnsureValid checks that the lock file was not deleted, replaced or modified since the lock was obtained
+func (n *NativeFSLock) EnsureValid() error {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.closed {
+		return fmt.Errorf("lock instance already released: %s", n.path)
+	}
+	// check we are still in the locks map (some debugger or something crazy didn't remove us)
+	if !isNativeLockHeld(n.path) {
+		return fmt.Errorf("lock path unexpectedly cleared from map: %s", n.path)
+	}
+
+	info, err := n.file.Stat()
+	if err != nil {
+		return err
+	}
+	// we are the only ones who should be writing to the lock file
+	if info.Size() != 0 {
+		return fmt.Errorf("unexpected lock file size: %d, (lock=%s)", info.Size(), n.path)
+	}
+
+	// the file we hold the lock on must still be the file at the lock path
+	pathInfo, err := os.Stat(n.path)
+	if err != nil {
+		return fmt.Errorf("lock file is no longer accessible, (lock=%s): %w", n.path, err)
+	}
+	if !os.SameFile(n.info, pathInfo) {
+		return fmt.Errorf("underlying file changed by an external force, (lock=%s)", n.path)
+	}
+	_, ctime, _ := FileTime(pathInfo)
+	if !n.creationTime.Equal(ctime) {
+		return fmt.Errorf("underlying file changed by an external force at %s, (lock=%s)", ctime, n.path)
+	}
+	return nil
+}
+
+// Close releases the lock, the lock file is kept
+func (n *NativeFSLock) Close() error {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.closed {
+		return nil
+	}
+	n.closed = true
+
+	err := errors.Join(unlockFile(n.file), n.file.Close())
+	if !clearNativeLockHeld(n.path) {
+		err = errors.Join(err, fmt.Errorf("lock path was cleared but never marked as held: %s", n.path))
+	}
+	return err
+}
+
+func (n *NativeFSLock) String() string {
+	return fmt.Sprintf("NativeFSLock(path=%s,creationTime=%s)", n.path, n.creationTime)
+}
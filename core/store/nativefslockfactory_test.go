@@ -0,0 +1,138 @@
+//go:build linux || darwin
+
+package store
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNativeFSLockFactory(t *testing.T) {
+	path := t.TempDir()
+	dir, err := NewNIOFSDirectoryWithLockFactory(path, NewNativeFSLockFactory())
+	assert.Nil(t, err)
+
+	lock, err := dir.ObtainLock("write.lock")
+	assert.Nil(t, err)
+	assert.Nil(t, lock.EnsureValid())
+
+	// the lock is held by this process
+	_, err = dir.ObtainLock("write.lock")
+	assert.NotNil(t, err)
+
+	// the lock is held by another open file
+	file, err := os.Open(filepath.Join(path, "write.lock"))
+	assert.Nil(t, err)
+	defer file.Close()
+	locked, err := tryLockFile(file)
+	assert.Nil(t, err)
+	assert.False(t, locked)
+
+	// the lock file is kept, but the lock is released
+	assert.Nil(t, lock.Close())
+	assert.NotNil(t, lock.EnsureValid())
+	assert.FileExists(t, filepath.Join(path, "write.lock"))
+	locked, err = tryLockFile(file)
+	assert.Nil(t, err)
+	assert.True(t, locked)
+	assert.Nil(t, unlockFile(file))
+
+	// a stale lock file does not prevent obtaining the lock
+	lock, err = dir.ObtainLock("write.lock")
+	assert.Nil(t, err)
+	assert.Nil(t, lock.Close())
+}
+
+func TestNativeFSLock_EnsureValid(t *testing.T) {
+	path := t.TempDir()
+	dir, err := NewNIOFSDirectoryWithLockFactory(path, NewNativeFSLockFactory())
+	assert.Nil(t, err)
+	lockFile := filepath.Join(path, "write.lock")
+
+	// deleted
+	lock, err := dir.ObtainLock("write.lock")
+	assert.Nil(t, err)
+	assert.Nil(t, os.Remove(lockFile))
+	assert.NotNil(t, lock.EnsureValid())
+	assert.Nil(t, lock.Close())
+
+	// replaced
+	lock, err = dir.ObtainLock("write.lock")
+	assert.Nil(t, err)
+	assert.Nil(t, os.Remove(lockFile))
+	assert.Nil(t, os.WriteFile(lockFile, nil, 0644))
+	assert.NotNil(t, lock.EnsureValid())
+	assert.Nil(t, lock.Close())
+
+	// written
+	lock, err = dir.ObtainLock("write.lock")
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(lockFile, []byte("x"), 0644))
+	assert.NotNil(t, lock.EnsureValid())
+	assert.Nil(t, lock.Close())
+}
+
+func TestVerifyingLockFactory(t *testing.T) {
+	server := NewLockVerifyServer()
+	dir := NewByteBuffersDirectory()
+
+	newFactory := func(clientID int, lf LockFactory) *VerifyingLockFactory {
+		client, conn := net.Pipe()
+		go func() {
+			defer conn.Close()
+			_ = server.Handle(clientID, conn)
+		}()
+		t.Cleanup(func() { client.Close() })
+		return NewVerifyingLockFactory(lf, client, client)
+	}
+
+	// two clients sharing a working lock factory
+	lf := NewNativeFSLockFactory()
+	path := t.TempDir()
+	first, err := NewNIOFSDirectoryWithLockFactory(path, newFactory(1, lf))
+	assert.Nil(t, err)
+	second, err := NewNIOFSDirectoryWithLockFactory(path, newFactory(2, lf))
+	assert.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		lock, err := first.ObtainLock("write.lock")
+		assert.Nil(t, err)
+		_, err = second.ObtainLock("write.lock")
+		assert.NotNil(t, err)
+		assert.Nil(t, lock.Close())
+
+		lock, err = second.ObtainLock("write.lock")
+		assert.Nil(t, err)
+		assert.Nil(t, lock.Close())
+	}
+
+	// two clients with their own lock factories, both obtain the lock
+	lock, err := newFactory(3, NewSingleInstanceLockFactory()).ObtainLock(dir, "write.lock")
+	assert.Nil(t, err)
+	_, err = newFactory(4, NewSingleInstanceLockFactory()).ObtainLock(dir, "write.lock")
+	assert.NotNil(t, err)
+	assert.Nil(t, lock.Close())
+}
+
+func TestLockVerifyServer_Serve(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		_ = NewLockVerifyServer().Serve(listener)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte{0, 0, 0, 1})
+	assert.Nil(t, err)
+
+	factory := NewVerifyingLockFactory(NewSingleInstanceLockFactory(), conn, conn)
+	lock, err := factory.ObtainLock(NewByteBuffersDirectory(), "write.lock")
+	assert.Nil(t, err)
+	assert.Nil(t, lock.Close())
+}
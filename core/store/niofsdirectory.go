@@ -44,6 +44,12 @@ func (n *NIOFSDirectory) CopyFrom(ctx context.Context, from Directory, src, dest
 }
 
 func NewNIOFSDirectory(path string) (*NIOFSDirectory, error) {
+	return NewNIOFSDirectoryWithLockFactory(path, NewSimpleFSLockFactory())
+}
+
+// NewNIOFSDirectoryWithLockFactory Creates a directory using the given LockFactory, e.g. a
+// NativeFSLockFactory to not leave stale locks behind crashed processes.
+func NewNIOFSDirectoryWithLockFactory(path string, lockFactory LockFactory) (*NIOFSDirectory, error) {
 	dirPath, err := filepath.Abs(path)
 	if err != nil {
 		return nil, err
@@ -66,7 +72,7 @@ func NewNIOFSDirectory(path string) (*NIOFSDirectory, error) {
 
 	dir := &NIOFSDirectory{
 		open:                &atomic.Bool{},
-		lockFactory:         NewSimpleFSLockFactory(),
+		lockFactory:         lockFactory,
 		dir:                 dirPath,
 		nextTempFileCounter: &atomic.Int64{},
 	}
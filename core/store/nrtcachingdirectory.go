@@ -0,0 +1,351 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+var _ Directory = &NRTCachingDirectory{}
+var _ ContextOutputCreator = &NRTCachingDirectory{}
+var _ ContextInputOpener = &NRTCachingDirectory{}
+
+// NRTCachingDirectory
+// Wraps a RAM-resident directory around any provided delegate directory, to be used during NRT search.
+//
+// This class is likely only useful in a near-real-time context, where indexing rate is lowish but
+// reopen rate is highish, resulting in many tiny files being written. This directory keeps such segments
+// (as well as the segments produced by merging them, as long as they are small enough), in RAM.
+//
+// This is safe to use: when your app calls IndexWriter.Commit, all cached files will be flushed from the
+// cache and sync'd.
+//
+// Whether a new file is cached is decided from the IOContext given to CreateOutputWithContext: the
+// estimated size of the flushed segment (FlushInfo) or of the merged segment (MergeInfo) must be at most
+// maxMergeSizeMB, and must fit in the cache with the files already cached, which are at most
+// maxCachedMB. The bytes of the cached outputs which are still being written count as cached. Once
+// written, a cached file that makes the cache exceed maxCachedMB is evicted: it is written through to
+// the delegate directory.
+type NRTCachingDirectory struct {
+	sync.Mutex
+
+	Directory
+
+	cache             *ByteBuffersDirectory
+	maxMergeSizeBytes int64
+	maxCachedBytes    int64
+
+	// openOutputBytes the bytes written to the cached outputs which aren't closed yet, the cache only
+	// counts the files once their output is closed
+	openOutputBytes atomic.Int64
+}
+
+// NewNRTCachingDirectory
+// We will cache a newly created output if 1) it's a flush or a merge and the estimated size of the
+// merged segment is <= maxMergeSizeMB, and 2) the total cached bytes is <= maxCachedMB
+func NewNRTCachingDirectory(delegate Directory, maxMergeSizeMB, maxCachedMB float64) *NRTCachingDirectory {
+	return &NRTCachingDirectory{
+		Directory:         delegate,
+		cache:             NewByteBuffersDirectory(),
+		maxMergeSizeBytes: int64(maxMergeSizeMB * 1024 * 1024),
+		maxCachedBytes:    int64(maxCachedMB * 1024 * 1024),
+	}
+}
+
+// GetDelegate Returns the wrapped directory
+func (n *NRTCachingDirectory) GetDelegate() Directory {
+	return n.Directory
+}
+
+func (n *NRTCachingDirectory) ListAll(ctx context.Context) ([]string, error) {
+	n.Lock()
+	defer n.Unlock()
+
+	files, err := n.Directory.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]struct{}, len(files))
+	for _, file := range files {
+		names[file] = struct{}{}
+	}
+	cachedFiles, err := n.cache.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range cachedFiles {
+		if _, ok := names[file]; ok {
+			return nil, fmt.Errorf(`file "%s" is in both dirs`, file)
+		}
+		names[file] = struct{}{}
+	}
+
+	all := make([]string, 0, len(names))
+	for name := range names {
+		all = append(all, name)
+	}
+	sort.Strings(all)
+	return all, nil
+}
+
+// ListCachedFiles Returns the sorted names of the files currently held in RAM
+func (n *NRTCachingDirectory) ListCachedFiles() []string {
+	// the cache is only closed with this directory
+	files, _ := n.cache.ListAll(context.Background())
+	return files
+}
+
+// CachedBytes Returns the number of bytes held in RAM, including the cached outputs still being written
+func (n *NRTCachingDirectory) CachedBytes() int64 {
+	return n.cache.RamBytesUsed() + n.openOutputBytes.Load()
+}
+
+func (n *NRTCachingDirectory) DeleteFile(ctx context.Context, name string) error {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.cache.fileExists(name) {
+		return n.cache.DeleteFile(ctx, name)
+	}
+	return n.Directory.DeleteFile(ctx, name)
+}
+
+func (n *NRTCachingDirectory) FileLength(ctx context.Context, name string) (int64, error) {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.cache.fileExists(name) {
+		return n.cache.FileLength(ctx, name)
+	}
+	return n.Directory.FileLength(ctx, name)
+}
+
+// CreateOutput Creates an output with IOContext DEFAULT, which is never cached.
+func (n *NRTCachingDirectory) CreateOutput(ctx context.Context, name string) (IndexOutput, error) {
+	return n.CreateOutputWithContext(ctx, name, DEFAULT)
+}
+
+// CreateOutputWithContext Creates an output, in RAM when doCacheWrite accepts ioContext, otherwise in the
+// delegate directory.
+func (n *NRTCachingDirectory) CreateOutputWithContext(ctx context.Context, name string, ioContext *IOContext) (IndexOutput, error) {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.doCacheWrite(ioContext) {
+		if _, err := n.Directory.FileLength(ctx, name); err == nil {
+			return nil, fmt.Errorf("%s: %w", name, fs.ErrExist)
+		}
+		return n.createCachedOutput(ctx, name)
+	}
+	if n.cache.fileExists(name) {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrExist)
+	}
+	return CreateOutputWithContext(ctx, n.Directory, name, ioContext)
+}
+
+// CreateTempOutput Creates the temporary output in the delegate directory: it is written without a
+// FlushInfo or MergeInfo.
+func (n *NRTCachingDirectory) CreateTempOutput(ctx context.Context, prefix, suffix string) (IndexOutput, error) {
+	return n.Directory.CreateTempOutput(ctx, prefix, suffix)
+}
+
+func (n *NRTCachingDirectory) createCachedOutput(ctx context.Context, name string) (IndexOutput, error) {
+	output, err := n.cache.CreateOutput(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	cached := &nrtCachedOutput{
+		out: output,
+		dir: n,
+	}
+	cached.BaseIndexOutput = NewBaseIndexOutput(name, cached)
+	return cached, nil
+}
+
+// doCacheWrite Returns true if a file created with ioContext should be written to the RAM-based cache
+// first. Only the files of a flush or a merge are cached.
+func (n *NRTCachingDirectory) doCacheWrite(ioContext *IOContext) bool {
+	if ioContext == nil {
+		return false
+	}
+
+	var bytes int64
+	if ioContext.MergeInfo != nil {
+		bytes = int64(ioContext.MergeInfo.EstimatedMergeBytes)
+	} else if ioContext.FlushInfo != nil {
+		bytes = ioContext.FlushInfo.EstimatedSegmentSize
+	} else {
+		return false
+	}
+	return bytes <= n.maxMergeSizeBytes && bytes+n.CachedBytes() <= n.maxCachedBytes
+}
+
+// onCachedOutputClose evicts the file which was just written when the cache became too large. The
+// written bytes of the output are counted by the cache from now on.
+func (n *NRTCachingDirectory) onCachedOutputClose(name string, written int64) error {
+	n.Lock()
+	defer n.Unlock()
+
+	n.openOutputBytes.Add(-written)
+	if n.CachedBytes() <= n.maxCachedBytes {
+		return nil
+	}
+	return n.unCache(context.Background(), name)
+}
+
+func (n *NRTCachingDirectory) Rename(ctx context.Context, source, dest string) error {
+	n.Lock()
+	defer n.Unlock()
+
+	// the rename publishes a commit, so the file must be durable in the delegate directory
+	if err := n.unCache(ctx, source); err != nil {
+		return err
+	}
+	if n.cache.fileExists(dest) {
+		return fmt.Errorf("target file %s already exists", dest)
+	}
+	return n.Directory.Rename(ctx, source, dest)
+}
+
+func (n *NRTCachingDirectory) OpenInput(ctx context.Context, name string) (IndexInput, error) {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.cache.fileExists(name) {
+		return n.cache.OpenInput(ctx, name)
+	}
+	return n.Directory.OpenInput(ctx, name)
+}
+
+// OpenInputWithContext Opens the cached file, or the file of the delegate directory for a read with ioContext
+func (n *NRTCachingDirectory) OpenInputWithContext(ctx context.Context, name string, ioContext *IOContext) (IndexInput, error) {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.cache.fileExists(name) {
+		return n.cache.OpenInput(ctx, name)
+	}
+	return OpenInputWithContext(ctx, n.Directory, name, ioContext)
+}
+
+// Sync writes the cached files through to the delegate directory, then syncs them
+func (n *NRTCachingDirectory) Sync(files map[string]struct{}) error {
+	n.Lock()
+	defer n.Unlock()
+
+	for name := range files {
+		if err := n.unCache(context.Background(), name); err != nil {
+			return err
+		}
+	}
+	return n.Directory.Sync(files)
+}
+
+func (n *NRTCachingDirectory) CopyFrom(ctx context.Context, from Directory, src, dest string, ioContext *IOContext) error {
+	in, err := from.OpenInput(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := n.CreateOutputWithContext(ctx, dest, ioContext)
+	if err != nil {
+		return err
+	}
+	if err := out.CopyBytes(ctx, in, int(in.Length())); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Close writes all the cached files through to the delegate directory, then closes it
+func (n *NRTCachingDirectory) Close() error {
+	n.Lock()
+	defer n.Unlock()
+
+	// NOTE: technically we shouldn't have to do this, ie, IndexWriter should have sync'd all files,
+	// but we do it for defensive reasons, or in case the app is doing something custom (creating
+	// outputs directly w/o using IndexWriter)
+	cachedFiles, err := n.cache.ListAll(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, name := range cachedFiles {
+		if err := n.unCache(context.Background(), name); err != nil {
+			return err
+		}
+	}
+	return n.Directory.Close()
+}
+
+// unCache writes the cached file through to the delegate directory, then removes it from the cache. The
+// file is in both directories until it is removed, so the caller must hold the lock.
+func (n *NRTCachingDirectory) unCache(ctx context.Context, name string) error {
+	if !n.cache.fileExists(name) {
+		return nil
+	}
+	in, err := n.cache.OpenInput(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := CreateOutputWithContext(ctx, n.Directory, name, DEFAULT)
+	if err != nil {
+		return err
+	}
+	if err := out.CopyBytes(ctx, in, int(in.Length())); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return n.cache.DeleteFile(ctx, name)
+}
+
+var _ IndexOutput = &nrtCachedOutput{}
+
+// nrtCachedOutput counts its written bytes as cached while it is open, and evicts its file when the
+// cache is too large once it is written
+type nrtCachedOutput struct {
+	*BaseIndexOutput
+
+	out     IndexOutput
+	dir     *NRTCachingDirectory
+	written int64
+	closed  bool
+}
+
+func (o *nrtCachedOutput) Write(p []byte) (int, error) {
+	n, err := o.out.Write(p)
+	o.written += int64(n)
+	o.dir.openOutputBytes.Add(int64(n))
+	return n, err
+}
+
+func (o *nrtCachedOutput) GetFilePointer() int64 {
+	return o.out.GetFilePointer()
+}
+
+func (o *nrtCachedOutput) GetChecksum() (uint32, error) {
+	return o.out.GetChecksum()
+}
+
+func (o *nrtCachedOutput) Close() error {
+	if o.closed {
+		return nil
+	}
+	o.closed = true
+
+	if err := o.out.Close(); err != nil {
+		o.dir.openOutputBytes.Add(-o.written)
+		return err
+	}
+	return o.dir.onCachedOutputClose(o.GetName(), o.written)
+}
@@ -0,0 +1,307 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNRTCachingDirectory(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir()
+	delegate, err := NewNIOFSDirectory(path)
+	assert.Nil(t, err)
+	dir := NewNRTCachingDirectory(delegate, 1, 2)
+
+	writeFile := func(name string, ioContext *IOContext, size int) {
+		out, err := dir.CreateOutputWithContext(ctx, name, ioContext)
+		assert.Nil(t, err)
+		_, err = out.Write(make([]byte, size))
+		assert.Nil(t, err)
+		assert.Nil(t, out.WriteUint32(ctx, 42))
+		assert.Nil(t, out.Close())
+	}
+	inDelegate := func(name string) bool {
+		_, err := os.Stat(filepath.Join(path, name))
+		return err == nil
+	}
+
+	// a small flush is cached
+	smallFlush := NewIOContext(WithFlushInfo(NewFlushInfo(10, 1024)))
+	writeFile("_0.tim", smallFlush, 100)
+	assert.Equal(t, []string{"_0.tim"}, dir.ListCachedFiles())
+	assert.False(t, inDelegate("_0.tim"))
+	assert.Equal(t, int64(104), dir.CachedBytes())
+
+	length, err := dir.FileLength(ctx, "_0.tim")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(104), length)
+
+	in, err := dir.OpenInput(ctx, "_0.tim")
+	assert.Nil(t, err)
+	_, err = in.Seek(100, io.SeekStart)
+	assert.Nil(t, err)
+	v, err := in.ReadUint32(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(42), v)
+	assert.Nil(t, in.Close())
+
+	// a large merge goes to the delegate directory
+	largeMerge := NewIOContext(WithMergeInfo(NewMergeInfo(1000, 10*1024*1024, false, -1)))
+	writeFile("_1.tim", largeMerge, 100)
+	assert.Equal(t, []string{"_0.tim"}, dir.ListCachedFiles())
+	assert.True(t, inDelegate("_1.tim"))
+
+	// a file can not be created twice
+	_, err = dir.CreateOutputWithContext(ctx, "_0.tim", smallFlush)
+	assert.NotNil(t, err)
+	_, err = dir.CreateOutputWithContext(ctx, "_1.tim", smallFlush)
+	assert.NotNil(t, err)
+
+	files, err := dir.ListAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"_0.tim", "_1.tim"}, files)
+
+	// a file exceeding the cache size is evicted once written
+	writeFile("_2.tim", smallFlush, 3*1024*1024)
+	assert.Equal(t, []string{"_0.tim"}, dir.ListCachedFiles())
+	assert.True(t, inDelegate("_2.tim"))
+	length, err = dir.FileLength(ctx, "_2.tim")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3*1024*1024+4), length)
+
+	// sync writes the cached files through
+	assert.Nil(t, dir.Sync(map[string]struct{}{"_0.tim": {}}))
+	assert.Empty(t, dir.ListCachedFiles())
+	assert.Equal(t, int64(0), dir.CachedBytes())
+	assert.True(t, inDelegate("_0.tim"))
+	raw, err := os.ReadFile(filepath.Join(path, "_0.tim"))
+	assert.Nil(t, err)
+	assert.Equal(t, 104, len(raw))
+
+	// a cached file is deleted from the cache only
+	writeFile("_3.tim", smallFlush, 10)
+	assert.Nil(t, dir.DeleteFile(ctx, "_3.tim"))
+	assert.Empty(t, dir.ListCachedFiles())
+	assert.False(t, inDelegate("_3.tim"))
+
+	// rename publishes the file to the delegate directory
+	writeFile("pending_segments_1", smallFlush, 10)
+	assert.Equal(t, []string{"pending_segments_1"}, dir.ListCachedFiles())
+	assert.Nil(t, dir.Rename(ctx, "pending_segments_1", "segments_1"))
+	assert.Empty(t, dir.ListCachedFiles())
+	assert.True(t, inDelegate("segments_1"))
+
+	// the IOContext is given through a TrackingDirectoryWrapper
+	tracking := NewTrackingDirectoryWrapper(dir)
+	out, err := CreateOutputWithContext(ctx, tracking, "_5.tim", smallFlush)
+	assert.Nil(t, err)
+	assert.Nil(t, out.Close())
+	assert.Equal(t, []string{"_5.tim"}, dir.ListCachedFiles())
+	assert.Contains(t, tracking.GetCreatedFiles(), "_5.tim")
+	assert.Nil(t, dir.DeleteFile(ctx, "_5.tim"))
+
+	// close writes the remaining cached files through
+	writeFile("_4.tim", smallFlush, 10)
+	assert.Nil(t, dir.Close())
+	assert.True(t, inDelegate("_4.tim"))
+}
+
+func TestNRTCachingDirectory_OpenOutputs(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir()
+	delegate, err := NewNIOFSDirectory(path)
+	assert.Nil(t, err)
+	dir := NewNRTCachingDirectory(delegate, 1, 2)
+	defer dir.Close()
+
+	flush := NewIOContext(WithFlushInfo(NewFlushInfo(10, 1024*1024)))
+	out0, err := dir.CreateOutputWithContext(ctx, "_0.tim", flush)
+	assert.Nil(t, err)
+	_, err = out0.Write(make([]byte, 1536*1024))
+	assert.Nil(t, err)
+	// the output is still open, its bytes count as cached
+	assert.Equal(t, int64(1536*1024), dir.CachedBytes())
+
+	// the flush doesn't fit next to the open output
+	out1, err := dir.CreateOutputWithContext(ctx, "_1.tim", flush)
+	assert.Nil(t, err)
+	assert.Nil(t, out1.Close())
+	_, err = os.Stat(filepath.Join(path, "_1.tim"))
+	assert.Nil(t, err)
+
+	assert.Nil(t, out0.Close())
+	assert.Equal(t, []string{"_0.tim"}, dir.ListCachedFiles())
+	assert.Equal(t, int64(1536*1024), dir.CachedBytes())
+}
+
+func TestNRTCachingDirectory_Default(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir()
+	delegate, err := NewNIOFSDirectory(path)
+	assert.Nil(t, err)
+	dir := NewNRTCachingDirectory(delegate, 1, 2)
+	defer dir.Close()
+
+	// files written without a FlushInfo or MergeInfo go to the delegate directory, however small
+	for _, create := range []func(name string) (IndexOutput, error){
+		func(name string) (IndexOutput, error) { return dir.CreateOutput(ctx, name) },
+		func(name string) (IndexOutput, error) { return dir.CreateOutputWithContext(ctx, name, DEFAULT) },
+		func(name string) (IndexOutput, error) { return dir.CreateOutputWithContext(ctx, name, nil) },
+		func(name string) (IndexOutput, error) { return CreateOutputWithContext(ctx, dir, name, DEFAULT) },
+		func(name string) (IndexOutput, error) { return dir.CreateTempOutput(ctx, name, "sort") },
+	} {
+		out, err := create("_0")
+		assert.Nil(t, err)
+		assert.Nil(t, out.WriteUint32(ctx, 42))
+		assert.Nil(t, out.Close())
+		assert.Empty(t, dir.ListCachedFiles())
+		_, err = os.Stat(filepath.Join(path, out.GetName()))
+		assert.Nil(t, err)
+		assert.Nil(t, dir.DeleteFile(ctx, out.GetName()))
+	}
+}
+
+func TestNRTCachingDirectory_Checksum(t *testing.T) {
+	ctx := context.Background()
+	delegate, err := NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+	dir := NewNRTCachingDirectory(delegate, 1, 1)
+	defer dir.Close()
+
+	out, err := dir.CreateOutput(ctx, "_0.cfs")
+	assert.Nil(t, err)
+	assert.Nil(t, out.WriteUint64(ctx, 123456789))
+	checksum, err := out.GetChecksum()
+	assert.Nil(t, err)
+	assert.Nil(t, out.Close())
+
+	in, err := OpenChecksumInput(dir, "_0.cfs")
+	assert.Nil(t, err)
+	v, err := in.ReadUint64(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(123456789), v)
+	assert.Equal(t, checksum, in.GetChecksum())
+}
+
+func TestNRTCachingDirectory_Wrapped(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir()
+	delegate, err := NewNIOFSDirectory(path)
+	assert.Nil(t, err)
+	dir := NewNRTCachingDirectory(delegate, 1, 2)
+	defer dir.Close()
+
+	// the wrappers pass the IOContext of the write to the NRTCachingDirectory
+	smallFlush := NewIOContext(WithFlushInfo(NewFlushInfo(10, 1024)))
+	for _, wrapper := range []Directory{
+		NewEncryptedDirectory(dir, newTestKeyProvider(t, "k1")),
+		NewTrackingDirectoryWrapper(dir),
+		NewFileSwitchDirectory(map[string]struct{}{}, NewByteBuffersDirectory(), dir, false),
+	} {
+		out, err := CreateOutputWithContext(ctx, wrapper, "_0.tim", smallFlush)
+		assert.Nil(t, err)
+		assert.Nil(t, out.WriteUint32(ctx, 42))
+		assert.Nil(t, out.Close())
+		assert.Equal(t, []string{"_0.tim"}, dir.ListCachedFiles())
+		_, err = os.Stat(filepath.Join(path, "_0.tim"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		in, err := wrapper.OpenInput(ctx, "_0.tim")
+		assert.Nil(t, err)
+		v, err := in.ReadUint32(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(42), v)
+		assert.Nil(t, in.Close())
+		assert.Nil(t, wrapper.DeleteFile(ctx, "_0.tim"))
+		assert.Empty(t, dir.ListCachedFiles())
+	}
+}
+
+func TestNRTCachingDirectory_UncachedContext(t *testing.T) {
+	ctx := context.Background()
+	delegate, err := NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+	inner := NewNRTCachingDirectory(delegate, 1, 2)
+	// the outer directory never caches, it passes the IOContext of the write to the inner one
+	dir := NewNRTCachingDirectory(inner, 0, 0)
+	defer dir.Close()
+
+	smallFlush := NewIOContext(WithFlushInfo(NewFlushInfo(10, 1024)))
+	out, err := dir.CreateOutputWithContext(ctx, "_0.tim", smallFlush)
+	assert.Nil(t, err)
+	assert.Nil(t, out.WriteUint32(ctx, 42))
+	assert.Nil(t, out.Close())
+	assert.Empty(t, dir.ListCachedFiles())
+	assert.Equal(t, []string{"_0.tim"}, inner.ListCachedFiles())
+}
+
+func TestNRTCachingDirectory_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	delegate, err := NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+	dir := NewNRTCachingDirectory(delegate, 1, 2)
+	defer dir.Close()
+
+	smallFlush := NewIOContext(WithFlushInfo(NewFlushInfo(10, 1024)))
+	var done atomic.Bool
+	var written sync.Map
+	var wg sync.WaitGroup
+
+	// the files are written to the cache, then synced: they move to the delegate directory
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer done.Store(true)
+		for i := 0; i < 200; i++ {
+			name := fmt.Sprintf("_%d.tim", i)
+			out, err := dir.CreateOutputWithContext(ctx, name, smallFlush)
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Nil(t, out.WriteUint32(ctx, 42))
+			assert.Nil(t, out.Close())
+			written.Store(name, struct{}{})
+			assert.Nil(t, dir.Sync(map[string]struct{}{name: {}}))
+		}
+	}()
+
+	// the readers see each file either in the cache or in the delegate directory
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !done.Load() {
+				names, err := dir.ListAll(ctx)
+				if !assert.Nil(t, err) {
+					return
+				}
+				for _, name := range names {
+					if _, ok := written.Load(name); !ok {
+						continue
+					}
+					length, err := dir.FileLength(ctx, name)
+					assert.Nil(t, err, name)
+					assert.Equal(t, int64(4), length, name)
+
+					in, err := dir.OpenInputWithContext(ctx, name, READ)
+					if !assert.Nil(t, err, name) {
+						continue
+					}
+					v, err := in.ReadUint32(ctx)
+					assert.Nil(t, err, name)
+					assert.Equal(t, uint32(42), v, name)
+					assert.Nil(t, in.Close())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
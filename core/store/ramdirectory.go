@@ -13,6 +13,10 @@ import (
 
 var _ Directory = &RAMDirectory{}
 
+// RAMDirectory A memory-resident Directory implementation.
+//
+// Deprecated: This class uses inefficient synchronization and is discouraged in favor of
+// ByteBuffersDirectory.
 type RAMDirectory struct {
 	sync.RWMutex
 
@@ -0,0 +1,747 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/geange/lucene-go/core/store"
+)
+
+var (
+	// ErrFakeDiskFull is returned by the outputs of a MockDirectoryWrapper when the files would exceed
+	// its maximum size.
+	ErrFakeDiskFull = errors.New("fake disk full")
+
+	// ErrFakeIOException is returned by a MockDirectoryWrapper when it throws a random IO exception.
+	ErrFakeIOException = errors.New("a random IO exception")
+)
+
+// Failure Is called by a MockDirectoryWrapper before each operation on a file, the operation fails with
+// the returned error when it is not nil. op is one of "createOutput", "openInput", "write", "close",
+// "sync", "rename" and "deleteFile".
+type Failure func(op, name string) error
+
+var _ store.Directory = &MockDirectoryWrapper{}
+var _ store.ContextOutputCreator = &MockDirectoryWrapper{}
+var _ store.ContextInputOpener = &MockDirectoryWrapper{}
+
+// MockDirectoryWrapper
+// This is a Directory Wrapper that adds methods intended to be used only by unit tests. It also adds a
+// number of features useful for testing:
+//
+//   - Simulates a disk full condition once the files written through it exceed SetMaxSizeInBytes.
+//   - Returns random IO errors from writes and opens, see SetRandomIOExceptionRate and
+//     SetRandomIOExceptionRateOnOpen, or deterministic errors, see FailOn.
+//   - Refuses to read a file that is still open for write, or that was not synced yet unless
+//     SetAllowReadingUnsyncedFiles is set.
+//   - Close fails if any file or lock is still open.
+//   - Crash simulates a machine crash: the open files are closed and the files that were not synced are
+//     deleted or truncated.
+//
+// The random decisions are taken by the given rand.Rand, so a failing test can be reproduced with the
+// same seed.
+type MockDirectoryWrapper struct {
+	sync.Mutex
+
+	in     store.Directory
+	random *rand.Rand
+
+	maxSize                     int64
+	maxUsedSize                 int64
+	randomIOExceptionRate       float64
+	randomIOExceptionRateOnOpen float64
+	allowReadingUnsyncedFiles   bool
+	failures                    []Failure
+	crashed                     bool
+
+	// the sizes of the files written through this wrapper
+	fileSizes map[string]int64
+	// the files created through this wrapper and not synced since
+	unSyncedFiles map[string]struct{}
+	// the number of open handles of each file
+	openFiles         map[string]int
+	openFilesForWrite map[string]struct{}
+	openHandles       map[mockHandle]struct{}
+	openLocks         map[string]struct{}
+}
+
+// mockHandle an input or output opened through the MockDirectoryWrapper
+type mockHandle interface {
+	// crash closes the handle without notifying the directory
+	crash()
+}
+
+func NewMockDirectoryWrapper(random *rand.Rand, delegate store.Directory) *MockDirectoryWrapper {
+	return &MockDirectoryWrapper{
+		in:                delegate,
+		random:            random,
+		fileSizes:         make(map[string]int64),
+		unSyncedFiles:     make(map[string]struct{}),
+		openFiles:         make(map[string]int),
+		openFilesForWrite: make(map[string]struct{}),
+		openHandles:       make(map[mockHandle]struct{}),
+		openLocks:         make(map[string]struct{}),
+	}
+}
+
+func (m *MockDirectoryWrapper) GetDelegate() store.Directory {
+	return m.in
+}
+
+// SetMaxSizeInBytes Writes fail with ErrFakeDiskFull once the files written through this directory
+// would exceed maxSize bytes, 0 means no limit.
+func (m *MockDirectoryWrapper) SetMaxSizeInBytes(maxSize int64) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.maxSize = maxSize
+}
+
+func (m *MockDirectoryWrapper) GetMaxSizeInBytes() int64 {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.maxSize
+}
+
+// GetMaxUsedSizeInBytes Returns the peak size of the files written through this directory since the
+// last ResetMaxUsedSizeInBytes.
+func (m *MockDirectoryWrapper) GetMaxUsedSizeInBytes() int64 {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.maxUsedSize
+}
+
+func (m *MockDirectoryWrapper) ResetMaxUsedSizeInBytes() {
+	m.Lock()
+	defer m.Unlock()
+
+	m.maxUsedSize = m.sizeInBytes()
+}
+
+// GetSizeInBytes Returns the size of the files written through this directory.
+func (m *MockDirectoryWrapper) GetSizeInBytes() int64 {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.sizeInBytes()
+}
+
+func (m *MockDirectoryWrapper) sizeInBytes() int64 {
+	size := int64(0)
+	for _, fileSize := range m.fileSizes {
+		size += fileSize
+	}
+	return size
+}
+
+// SetRandomIOExceptionRate Each write fails with ErrFakeIOException with the probability rate.
+func (m *MockDirectoryWrapper) SetRandomIOExceptionRate(rate float64) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.randomIOExceptionRate = rate
+}
+
+// SetRandomIOExceptionRateOnOpen Each CreateOutput, CreateTempOutput and OpenInput fails with
+// ErrFakeIOException with the probability rate.
+func (m *MockDirectoryWrapper) SetRandomIOExceptionRateOnOpen(rate float64) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.randomIOExceptionRateOnOpen = rate
+}
+
+// SetAllowReadingUnsyncedFiles If set, files can be opened for read before they are synced. Files that
+// are still open for write can never be read.
+func (m *MockDirectoryWrapper) SetAllowReadingUnsyncedFiles(allow bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.allowReadingUnsyncedFiles = allow
+}
+
+// FailOn Adds a Failure called before each operation on a file.
+func (m *MockDirectoryWrapper) FailOn(failure Failure) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.failures = append(m.failures, failure)
+}
+
+// ClearFailures Removes the failures added by FailOn.
+func (m *MockDirectoryWrapper) ClearFailures() {
+	m.Lock()
+	defer m.Unlock()
+
+	m.failures = nil
+}
+
+// GetUnSyncedFiles Returns the files created through this directory that were not synced since.
+func (m *MockDirectoryWrapper) GetUnSyncedFiles() []string {
+	m.Lock()
+	defer m.Unlock()
+
+	return sortedKeys(m.unSyncedFiles)
+}
+
+// GetOpenFiles Returns the files that have an open input or output.
+func (m *MockDirectoryWrapper) GetOpenFiles() []string {
+	m.Lock()
+	defer m.Unlock()
+
+	return sortedKeys(m.openFiles)
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Crash Simulates a crash of the machine: all the open inputs and outputs are closed, their later
+// operations fail, and each file that was not synced is either deleted or truncated to a random
+// length. Until ClearCrash is called, the directory can be read but not modified.
+func (m *MockDirectoryWrapper) Crash(ctx context.Context) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.crashed = true
+	for handle := range m.openHandles {
+		handle.crash()
+	}
+	clear(m.openHandles)
+	clear(m.openFiles)
+	clear(m.openFilesForWrite)
+
+	var err error
+	for _, name := range sortedKeys(m.unSyncedFiles) {
+		if m.random.Intn(2) == 0 {
+			err = errors.Join(err, m.in.DeleteFile(ctx, name))
+			delete(m.fileSizes, name)
+		} else {
+			err = errors.Join(err, m.truncateFile(ctx, name))
+		}
+	}
+	clear(m.unSyncedFiles)
+	return err
+}
+
+// truncateFile keeps a random prefix of the file, the bytes that happened to reach the disk
+func (m *MockDirectoryWrapper) truncateFile(ctx context.Context, name string) error {
+	length, err := m.in.FileLength(ctx, name)
+	if err != nil {
+		return err
+	}
+	prefix := make([]byte, m.random.Int63n(length+1))
+
+	in, err := m.in.OpenInput(ctx, name)
+	if err != nil {
+		return err
+	}
+	_, err = io.ReadFull(in, prefix)
+	if err := errors.Join(err, in.Close()); err != nil {
+		return err
+	}
+
+	if err := m.in.DeleteFile(ctx, name); err != nil {
+		return err
+	}
+	out, err := m.in.CreateOutput(ctx, name)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(prefix)
+	if err := errors.Join(err, out.Close()); err != nil {
+		return err
+	}
+	m.fileSizes[name] = int64(len(prefix))
+	return nil
+}
+
+// ClearCrash Allows the directory to be modified again after Crash.
+func (m *MockDirectoryWrapper) ClearCrash() {
+	m.Lock()
+	defer m.Unlock()
+
+	m.crashed = false
+}
+
+func (m *MockDirectoryWrapper) ensureNotCrashed(op, name string) error {
+	if m.crashed {
+		return fmt.Errorf("cannot %s %s after crash", op, name)
+	}
+	return nil
+}
+
+// maybeThrowDeterministicException calls the failures, the caller holds the lock
+func (m *MockDirectoryWrapper) maybeThrowDeterministicException(op, name string) error {
+	for _, failure := range m.failures {
+		if err := failure(op, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockDirectoryWrapper) maybeThrowIOException(rate float64, op, name string) error {
+	if rate > 0 && m.random.Float64() < rate {
+		return fmt.Errorf("%s %s: %w", op, name, ErrFakeIOException)
+	}
+	return nil
+}
+
+func (m *MockDirectoryWrapper) checkOpen(op, name string) error {
+	if err := m.ensureNotCrashed(op, name); err != nil {
+		return err
+	}
+	if err := m.maybeThrowDeterministicException(op, name); err != nil {
+		return err
+	}
+	return m.maybeThrowIOException(m.randomIOExceptionRateOnOpen, op, name)
+}
+
+func (m *MockDirectoryWrapper) ListAll(ctx context.Context) ([]string, error) {
+	return m.in.ListAll(ctx)
+}
+
+func (m *MockDirectoryWrapper) DeleteFile(ctx context.Context, name string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if err := m.ensureNotCrashed("deleteFile", name); err != nil {
+		return err
+	}
+	if err := m.maybeThrowDeterministicException("deleteFile", name); err != nil {
+		return err
+	}
+	if err := m.in.DeleteFile(ctx, name); err != nil {
+		return err
+	}
+
+	delete(m.fileSizes, name)
+	delete(m.unSyncedFiles, name)
+	return nil
+}
+
+func (m *MockDirectoryWrapper) FileLength(ctx context.Context, name string) (int64, error) {
+	return m.in.FileLength(ctx, name)
+}
+
+func (m *MockDirectoryWrapper) CreateOutput(ctx context.Context, name string) (store.IndexOutput, error) {
+	return m.CreateOutputWithContext(ctx, name, store.DEFAULT)
+}
+
+func (m *MockDirectoryWrapper) CreateOutputWithContext(ctx context.Context, name string, ioContext *store.IOContext) (store.IndexOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if err := m.checkOpen("createOutput", name); err != nil {
+		return nil, err
+	}
+	output, err := store.CreateOutputWithContext(ctx, m.in, name, ioContext)
+	if err != nil {
+		return nil, err
+	}
+	return m.newOutput(output), nil
+}
+
+func (m *MockDirectoryWrapper) CreateTempOutput(ctx context.Context, prefix, suffix string) (store.IndexOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if err := m.checkOpen("createOutput", prefix); err != nil {
+		return nil, err
+	}
+	output, err := m.in.CreateTempOutput(ctx, prefix, suffix)
+	if err != nil {
+		return nil, err
+	}
+	return m.newOutput(output), nil
+}
+
+func (m *MockDirectoryWrapper) newOutput(output store.IndexOutput) *mockIndexOutput {
+	name := output.GetName()
+	m.fileSizes[name] = 0
+	m.unSyncedFiles[name] = struct{}{}
+	m.openFilesForWrite[name] = struct{}{}
+	m.openFiles[name]++
+
+	mock := &mockIndexOutput{
+		dir:  m,
+		out:  output,
+		name: name,
+	}
+	mock.BaseIndexOutput = store.NewBaseIndexOutput(name, mock)
+	m.openHandles[mock] = struct{}{}
+	return mock
+}
+
+func (m *MockDirectoryWrapper) Rename(ctx context.Context, source, dest string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if err := m.ensureNotCrashed("rename", source); err != nil {
+		return err
+	}
+	if err := m.maybeThrowDeterministicException("rename", source); err != nil {
+		return err
+	}
+	if _, ok := m.openFilesForWrite[source]; ok {
+		return fmt.Errorf("file %s is still open for write: cannot rename", source)
+	}
+	if err := m.in.Rename(ctx, source, dest); err != nil {
+		return err
+	}
+
+	if size, ok := m.fileSizes[source]; ok {
+		m.fileSizes[dest] = size
+		delete(m.fileSizes, source)
+	}
+	if _, ok := m.unSyncedFiles[source]; ok {
+		m.unSyncedFiles[dest] = struct{}{}
+		delete(m.unSyncedFiles, source)
+	}
+	return nil
+}
+
+func (m *MockDirectoryWrapper) OpenInput(ctx context.Context, name string) (store.IndexInput, error) {
+	return m.OpenInputWithContext(ctx, name, store.DEFAULT)
+}
+
+func (m *MockDirectoryWrapper) OpenInputWithContext(ctx context.Context, name string, ioContext *store.IOContext) (store.IndexInput, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if err := m.maybeThrowDeterministicException("openInput", name); err != nil {
+		return nil, err
+	}
+	if err := m.maybeThrowIOException(m.randomIOExceptionRateOnOpen, "openInput", name); err != nil {
+		return nil, err
+	}
+	if _, ok := m.openFilesForWrite[name]; ok {
+		return nil, fmt.Errorf("file %s is still open for write: cannot read", name)
+	}
+	if _, ok := m.unSyncedFiles[name]; ok && !m.allowReadingUnsyncedFiles {
+		return nil, fmt.Errorf("file %s is not synced: cannot read", name)
+	}
+
+	input, err := store.OpenInputWithContext(ctx, m.in, name, ioContext)
+	if err != nil {
+		return nil, err
+	}
+
+	mock := newMockIndexInput(m, input, name, &atomic.Bool{}, false)
+	m.openFiles[name]++
+	m.openHandles[mock] = struct{}{}
+	return mock, nil
+}
+
+func (m *MockDirectoryWrapper) ObtainLock(name string) (store.Lock, error) {
+	lock, err := m.in.ObtainLock(name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.openLocks[name] = struct{}{}
+	return &mockLock{Lock: lock, dir: m, name: name}, nil
+}
+
+// Close Fails if any input, output or lock is still open, the delegate is closed anyway.
+func (m *MockDirectoryWrapper) Close() error {
+	m.Lock()
+	defer m.Unlock()
+
+	var err error
+	if len(m.openFiles) > 0 {
+		err = fmt.Errorf("MockDirectoryWrapper: cannot close: there are still %d open files: %s",
+			len(m.openFiles), strings.Join(sortedKeys(m.openFiles), ", "))
+	}
+	if len(m.openLocks) > 0 {
+		err = errors.Join(err, fmt.Errorf("MockDirectoryWrapper: cannot close: there are still %d open locks: %s",
+			len(m.openLocks), strings.Join(sortedKeys(m.openLocks), ", ")))
+	}
+	return errors.Join(err, m.in.Close())
+}
+
+// CopyFrom copies through an output of this directory, so that the copy can fail like any other write
+func (m *MockDirectoryWrapper) CopyFrom(ctx context.Context, from store.Directory, src, dest string, ioContext *store.IOContext) error {
+	in, err := from.OpenInput(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := m.CreateOutputWithContext(ctx, dest, ioContext)
+	if err != nil {
+		return err
+	}
+	if err := out.CopyBytes(ctx, in, int(in.Length())); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (m *MockDirectoryWrapper) EnsureOpen() error {
+	return m.in.EnsureOpen()
+}
+
+// Sync Marks the files as synced, the files that are still open for write stay unsynced.
+func (m *MockDirectoryWrapper) Sync(files map[string]struct{}) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, name := range sortedKeys(files) {
+		if err := m.ensureNotCrashed("sync", name); err != nil {
+			return err
+		}
+		if err := m.maybeThrowDeterministicException("sync", name); err != nil {
+			return err
+		}
+	}
+	if err := m.in.Sync(files); err != nil {
+		return err
+	}
+
+	for name := range files {
+		if _, ok := m.openFilesForWrite[name]; !ok {
+			delete(m.unSyncedFiles, name)
+		}
+	}
+	return nil
+}
+
+// removeOpenFile the caller holds the lock
+func (m *MockDirectoryWrapper) removeOpenFile(handle mockHandle, name string) {
+	if _, ok := m.openHandles[handle]; !ok {
+		return
+	}
+	delete(m.openHandles, handle)
+
+	m.openFiles[name]--
+	if m.openFiles[name] <= 0 {
+		delete(m.openFiles, name)
+	}
+	if _, ok := handle.(*mockIndexOutput); ok {
+		delete(m.openFilesForWrite, name)
+	}
+}
+
+var _ store.IndexOutput = &mockIndexOutput{}
+
+type mockIndexOutput struct {
+	*store.BaseIndexOutput
+
+	dir    *MockDirectoryWrapper
+	out    store.IndexOutput
+	name   string
+	closed atomic.Bool
+}
+
+func (o *mockIndexOutput) Write(p []byte) (int, error) {
+	if o.closed.Load() {
+		return 0, fmt.Errorf("output %s is closed", o.name)
+	}
+
+	allowed, err := o.reserve(len(p))
+	n := 0
+	if allowed > 0 {
+		var writeErr error
+		n, writeErr = o.out.Write(p[:allowed])
+		err = errors.Join(writeErr, err)
+	}
+	o.release(allowed - n)
+	return n, err
+}
+
+// reserve returns the number of bytes of the write that fit on the disk
+func (o *mockIndexOutput) reserve(size int) (int, error) {
+	o.dir.Lock()
+	defer o.dir.Unlock()
+
+	if err := o.dir.ensureNotCrashed("write", o.name); err != nil {
+		return 0, err
+	}
+	if err := o.dir.maybeThrowDeterministicException("write", o.name); err != nil {
+		return 0, err
+	}
+	if err := o.dir.maybeThrowIOException(o.dir.randomIOExceptionRate, "write", o.name); err != nil {
+		return 0, err
+	}
+
+	var err error
+	used := o.dir.sizeInBytes()
+	if o.dir.maxSize > 0 && used+int64(size) > o.dir.maxSize {
+		free := int(max(o.dir.maxSize-used, 0))
+		err = fmt.Errorf("fake disk full at %d bytes when writing %s (file length=%d): %w",
+			o.dir.maxSize, o.name, o.out.GetFilePointer()+int64(free), ErrFakeDiskFull)
+		size = free
+	}
+
+	if _, ok := o.dir.fileSizes[o.name]; ok {
+		o.dir.fileSizes[o.name] += int64(size)
+	}
+	o.dir.maxUsedSize = max(o.dir.maxUsedSize, used+int64(size))
+	return size, err
+}
+
+// release gives back the reserved bytes that were not written
+func (o *mockIndexOutput) release(size int) {
+	if size == 0 {
+		return
+	}
+
+	o.dir.Lock()
+	defer o.dir.Unlock()
+
+	if _, ok := o.dir.fileSizes[o.name]; ok {
+		o.dir.fileSizes[o.name] -= int64(size)
+	}
+}
+
+func (o *mockIndexOutput) GetFilePointer() int64 {
+	return o.out.GetFilePointer()
+}
+
+func (o *mockIndexOutput) GetChecksum() (uint32, error) {
+	return o.out.GetChecksum()
+}
+
+func (o *mockIndexOutput) Close() error {
+	if o.closed.Swap(true) {
+		return nil
+	}
+
+	o.dir.Lock()
+	defer o.dir.Unlock()
+
+	o.dir.removeOpenFile(o, o.name)
+	err := o.dir.maybeThrowDeterministicException("close", o.name)
+	return errors.Join(err, o.out.Close())
+}
+
+func (o *mockIndexOutput) crash() {
+	if !o.closed.Swap(true) {
+		_ = o.out.Close()
+	}
+}
+
+var _ store.IndexInput = &mockIndexInput{}
+
+// mockIndexInput the clones and slices of an input share its closed flag, they are not tracked and
+// closing them does nothing
+type mockIndexInput struct {
+	*store.BaseIndexInput
+
+	dir     *MockDirectoryWrapper
+	in      store.IndexInput
+	name    string
+	closed  *atomic.Bool
+	isClone bool
+}
+
+func newMockIndexInput(dir *MockDirectoryWrapper, in store.IndexInput, name string, closed *atomic.Bool, isClone bool) *mockIndexInput {
+	input := &mockIndexInput{
+		dir:     dir,
+		in:      in,
+		name:    name,
+		closed:  closed,
+		isClone: isClone,
+	}
+	input.BaseIndexInput = store.NewBaseIndexInput(input)
+	return input
+}
+
+func (i *mockIndexInput) ensureOpen() error {
+	if i.closed.Load() {
+		return fmt.Errorf("input %s is closed", i.name)
+	}
+	return nil
+}
+
+func (i *mockIndexInput) Read(p []byte) (int, error) {
+	if err := i.ensureOpen(); err != nil {
+		return 0, err
+	}
+	return i.in.Read(p)
+}
+
+func (i *mockIndexInput) Seek(offset int64, whence int) (int64, error) {
+	if err := i.ensureOpen(); err != nil {
+		return 0, err
+	}
+	return i.in.Seek(offset, whence)
+}
+
+func (i *mockIndexInput) GetFilePointer() int64 {
+	return i.in.GetFilePointer()
+}
+
+func (i *mockIndexInput) Length() int64 {
+	return i.in.Length()
+}
+
+func (i *mockIndexInput) Slice(sliceDescription string, offset, length int64) (store.IndexInput, error) {
+	if err := i.ensureOpen(); err != nil {
+		return nil, err
+	}
+	slice, err := i.in.Slice(sliceDescription, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	return newMockIndexInput(i.dir, slice, i.name, i.closed, true), nil
+}
+
+func (i *mockIndexInput) Clone() store.CloneReader {
+	return newMockIndexInput(i.dir, i.in.Clone().(store.IndexInput), i.name, i.closed, true)
+}
+
+func (i *mockIndexInput) Close() error {
+	if i.isClone || i.closed.Swap(true) {
+		return nil
+	}
+
+	i.dir.Lock()
+	defer i.dir.Unlock()
+
+	i.dir.removeOpenFile(i, i.name)
+	return i.in.Close()
+}
+
+func (i *mockIndexInput) crash() {
+	if !i.closed.Swap(true) {
+		_ = i.in.Close()
+	}
+}
+
+var _ store.Lock = &mockLock{}
+
+type mockLock struct {
+	store.Lock
+
+	dir  *MockDirectoryWrapper
+	name string
+}
+
+func (l *mockLock) Close() error {
+	l.dir.Lock()
+	delete(l.dir.openLocks, l.name)
+	l.dir.Unlock()
+
+	return l.Lock.Close()
+}
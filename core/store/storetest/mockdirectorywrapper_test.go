@@ -0,0 +1,211 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand"
+	"testing"
+
+	"github.com/geange/lucene-go/core/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeMockFile(ctx context.Context, dir store.Directory, name string, content []byte) error {
+	out, err := dir.CreateOutput(ctx, name)
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(content); err != nil {
+		return errors.Join(err, out.Close())
+	}
+	return out.Close()
+}
+
+func TestMockDirectoryWrapper_DiskFull(t *testing.T) {
+	ctx := context.Background()
+	dir := NewMockDirectoryWrapper(rand.New(rand.NewSource(1)), store.NewByteBuffersDirectory())
+	dir.SetMaxSizeInBytes(100)
+
+	assert.Nil(t, writeMockFile(ctx, dir, "a", make([]byte, 60)))
+
+	out, err := dir.CreateOutput(ctx, "b")
+	assert.Nil(t, err)
+	n, err := out.Write(make([]byte, 60))
+	assert.ErrorIs(t, err, ErrFakeDiskFull)
+	assert.Equal(t, 40, n)
+	assert.Equal(t, int64(40), out.GetFilePointer())
+	assert.Nil(t, out.Close())
+	assert.Equal(t, int64(100), dir.GetMaxUsedSizeInBytes())
+
+	// deleting a file frees its space
+	assert.Nil(t, dir.DeleteFile(ctx, "a"))
+	assert.Equal(t, int64(40), dir.GetSizeInBytes())
+	assert.Nil(t, writeMockFile(ctx, dir, "c", make([]byte, 60)))
+	assert.Nil(t, dir.Close())
+}
+
+func TestMockDirectoryWrapper_RandomIOExceptions(t *testing.T) {
+	ctx := context.Background()
+	dir := NewMockDirectoryWrapper(rand.New(rand.NewSource(1)), store.NewByteBuffersDirectory())
+
+	dir.SetRandomIOExceptionRateOnOpen(1)
+	_, err := dir.CreateOutput(ctx, "a")
+	assert.ErrorIs(t, err, ErrFakeIOException)
+	dir.SetRandomIOExceptionRateOnOpen(0)
+
+	dir.SetRandomIOExceptionRate(0.5)
+	out, err := dir.CreateOutput(ctx, "a")
+	assert.Nil(t, err)
+	failed := 0
+	for i := 0; i < 100; i++ {
+		if err := out.WriteByte(1); err != nil {
+			assert.ErrorIs(t, err, ErrFakeIOException)
+			failed++
+		}
+	}
+	assert.Greater(t, failed, 0)
+	assert.Less(t, failed, 100)
+	assert.Equal(t, int64(100-failed), out.GetFilePointer())
+	assert.Nil(t, out.Close())
+
+	// deterministic failures
+	dir.FailOn(func(op, name string) error {
+		if op == "sync" {
+			return errors.New("sync failed")
+		}
+		return nil
+	})
+	assert.NotNil(t, dir.Sync(map[string]struct{}{"a": {}}))
+	assert.Equal(t, []string{"a"}, dir.GetUnSyncedFiles())
+	dir.ClearFailures()
+	assert.Nil(t, dir.Sync(map[string]struct{}{"a": {}}))
+	assert.Empty(t, dir.GetUnSyncedFiles())
+	assert.Nil(t, dir.Close())
+}
+
+func TestMockDirectoryWrapper_OpenFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := NewMockDirectoryWrapper(rand.New(rand.NewSource(1)), store.NewByteBuffersDirectory())
+
+	out, err := dir.CreateOutput(ctx, "a")
+	assert.Nil(t, err)
+	_, err = out.Write([]byte("content"))
+	assert.Nil(t, err)
+
+	// files still open for write or not synced can not be read
+	_, err = dir.OpenInput(ctx, "a")
+	assert.NotNil(t, err)
+	assert.Nil(t, out.Close())
+	_, err = dir.OpenInput(ctx, "a")
+	assert.NotNil(t, err)
+	assert.Nil(t, dir.Sync(map[string]struct{}{"a": {}}))
+
+	in, err := dir.OpenInput(ctx, "a")
+	assert.Nil(t, err)
+	clone := in.Clone().(store.IndexInput)
+	lock, err := dir.ObtainLock("write.lock")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, dir.GetOpenFiles())
+
+	err = dir.Close()
+	assert.ErrorContains(t, err, "open files: a")
+	assert.ErrorContains(t, err, "open locks: write.lock")
+
+	// closing the clone does not close the input, closing the input closes the clone
+	assert.Nil(t, clone.Close())
+	assert.Equal(t, []string{"a"}, dir.GetOpenFiles())
+	assert.Nil(t, in.Close())
+	assert.Empty(t, dir.GetOpenFiles())
+	_, err = clone.ReadByte()
+	assert.NotNil(t, err)
+	assert.Nil(t, lock.Close())
+}
+
+func TestMockDirectoryWrapper_Crash(t *testing.T) {
+	ctx := context.Background()
+	delegate, err := store.NewNIOFSDirectory(t.TempDir())
+	assert.Nil(t, err)
+	dir := NewMockDirectoryWrapper(rand.New(rand.NewSource(1)), delegate)
+	dir.SetAllowReadingUnsyncedFiles(true)
+
+	synced := []byte("synced content")
+	assert.Nil(t, writeMockFile(ctx, dir, "synced", synced))
+	assert.Nil(t, dir.Sync(map[string]struct{}{"synced": {}}))
+	unSynced := make([]byte, 1000)
+	rand.New(rand.NewSource(2)).Read(unSynced)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		assert.Nil(t, writeMockFile(ctx, dir, name, unSynced))
+	}
+	assert.Nil(t, dir.Rename(ctx, "d", "renamed"))
+	out, err := dir.CreateOutput(ctx, "open")
+	assert.Nil(t, err)
+	_, err = out.Write(unSynced)
+	assert.Nil(t, err)
+	in, err := dir.OpenInput(ctx, "synced")
+	assert.Nil(t, err)
+
+	assert.Nil(t, dir.Crash(ctx))
+	assert.Empty(t, dir.GetOpenFiles())
+	assert.Empty(t, dir.GetUnSyncedFiles())
+
+	// the open files are closed
+	_, err = out.Write([]byte{1})
+	assert.NotNil(t, err)
+	_, err = in.ReadByte()
+	assert.NotNil(t, err)
+
+	// the synced file survives, the unsynced ones are deleted or truncated
+	read, err := readMockFile(ctx, dir, "synced")
+	assert.Nil(t, err)
+	assert.Equal(t, synced, read)
+	for _, name := range []string{"a", "b", "c", "renamed", "open"} {
+		read, err := readMockFile(ctx, dir, name)
+		if err != nil {
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+			continue
+		}
+		assert.Equal(t, unSynced[:len(read)], read)
+	}
+
+	// the directory can not be modified until the crash is cleared
+	_, err = dir.CreateOutput(ctx, "new")
+	assert.NotNil(t, err)
+	dir.ClearCrash()
+	assert.Nil(t, writeMockFile(ctx, dir, "new", synced))
+	assert.Nil(t, dir.Close())
+}
+
+func readMockFile(ctx context.Context, dir store.Directory, name string) ([]byte, error) {
+	in, err := dir.OpenInput(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	return io.ReadAll(in)
+}
+
+func TestMockDirectoryWrapper_CreateOutputWithContext(t *testing.T) {
+	ctx := context.Background()
+	nrtDir := store.NewNRTCachingDirectory(store.NewByteBuffersDirectory(), 1, 2)
+	dir := NewMockDirectoryWrapper(rand.New(rand.NewSource(1)), nrtDir)
+
+	// the IOContext of the write is passed to the wrapped directory
+	smallFlush := store.NewIOContext(store.WithFlushInfo(store.NewFlushInfo(10, 1024)))
+	out, err := store.CreateOutputWithContext(ctx, dir, "_0.tim", smallFlush)
+	assert.Nil(t, err)
+	_, err = out.Write([]byte{1, 2, 3})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"_0.tim"}, dir.GetOpenFiles())
+	assert.Nil(t, out.Close())
+	assert.Equal(t, []string{"_0.tim"}, nrtDir.ListCachedFiles())
+	assert.Equal(t, []string{"_0.tim"}, dir.GetUnSyncedFiles())
+
+	assert.Nil(t, dir.Sync(map[string]struct{}{"_0.tim": {}}))
+	assert.Empty(t, nrtDir.ListCachedFiles())
+	read, err := readMockFile(ctx, dir, "_0.tim")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, read)
+	assert.Nil(t, dir.Close())
+}
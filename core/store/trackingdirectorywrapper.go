@@ -6,6 +6,8 @@ import (
 )
 
 var _ Directory = &TrackingDirectoryWrapper{}
+var _ ContextOutputCreator = &TrackingDirectoryWrapper{}
+var _ ContextInputOpener = &TrackingDirectoryWrapper{}
 
 type TrackingDirectoryWrapper struct {
 	sync.RWMutex
@@ -50,6 +52,24 @@ func (t *TrackingDirectoryWrapper) CreateOutput(ctx context.Context, name string
 	return output, nil
 }
 
+func (t *TrackingDirectoryWrapper) CreateOutputWithContext(ctx context.Context, name string, ioContext *IOContext) (IndexOutput, error) {
+	output, err := CreateOutputWithContext(ctx, t.Directory, name, ioContext)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	t.createdFileNames[output.GetName()] = struct{}{}
+
+	return output, nil
+}
+
+func (t *TrackingDirectoryWrapper) OpenInputWithContext(ctx context.Context, name string, ioContext *IOContext) (IndexInput, error) {
+	return OpenInputWithContext(ctx, t.Directory, name, ioContext)
+}
+
 func (t *TrackingDirectoryWrapper) CreateTempOutput(ctx context.Context, prefix, suffix string) (IndexOutput, error) {
 	tempOutput, err := t.Directory.CreateTempOutput(ctx, prefix, suffix)
 	if err != nil {
@@ -62,16 +62,19 @@ const (
 	NO_MORE_DOCS = math.MaxInt32
 )
 
+// SlowAdvance
+// Advances with NextDoc until the iterator is on or beyond target. The iterator is always moved at least
+// once, like Advance, even when target is not beyond the current doc.
 func SlowAdvance(m interface{ NextDoc() (int, error) }, target int) (int, error) {
-	doc := 0
-	var err error
-	for doc < target {
-		doc, err = m.NextDoc()
+	for {
+		doc, err := m.NextDoc()
 		if err != nil {
 			return 0, err
 		}
+		if doc >= target {
+			return doc, nil
+		}
 	}
-	return doc, nil
 }
 
 func DocIdSetIteratorAll(maxDoc int) DocIdSetIterator {
@@ -0,0 +1,26 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowAdvance(t *testing.T) {
+	it := DocIdSetIteratorAll(10)
+
+	// an unpositioned iterator is moved to the first doc, even for target 0
+	doc, err := SlowAdvance(it, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, doc)
+	assert.Equal(t, 0, it.DocID())
+
+	doc, err = SlowAdvance(it, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, doc)
+
+	// like Advance, a target that is not beyond the current doc moves to the next doc
+	doc, err = SlowAdvance(it, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, doc)
+}
@@ -4,13 +4,37 @@ type Source struct {
 	packed   *packedTokenAttr
 	termAttr *bytesAttr
 	payload  *bytesAttr
+
+	// the term is held by the CharTermAttr instead of the BytesTermAttr
+	charTerm bool
+}
+
+type SourceOption func(*sourceOption)
+
+type sourceOption struct {
+	charTerm bool
+}
+
+// WithCharTerm
+// The stream writes its terms to the CharTermAttr, so Term2Bytes returns the CharTermAttr
+// instead of the BytesTermAttr.
+func WithCharTerm() SourceOption {
+	return func(option *sourceOption) {
+		option.charTerm = true
+	}
 }
 
-func NewSource() *Source {
+func NewSource(options ...SourceOption) *Source {
+	opt := &sourceOption{}
+	for _, fn := range options {
+		fn(opt)
+	}
+
 	return &Source{
 		packed:   newPackedTokenAttr(),
 		termAttr: newBytesAttr(ClassBytesTerm, ClassTermToBytesRef),
 		payload:  newBytesAttr(ClassPayload),
+		charTerm: opt.charTerm,
 	}
 }
 
@@ -50,7 +74,13 @@ func (r *Source) TermFrequency() TermFreqAttr {
 	return r.packed
 }
 
+// Term2Bytes
+// Returns the attribute holding the bytes of the term: the CharTermAttr if the source was created
+// WithCharTerm, otherwise the BytesTermAttr.
 func (r *Source) Term2Bytes() Term2BytesAttr {
+	if r.charTerm {
+		return r.packed.bytesAttr
+	}
 	return r.termAttr
 }
 
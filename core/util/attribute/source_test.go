@@ -68,3 +68,28 @@ func TestAttributeSource(t *testing.T) {
 	assert.Nil(t, err)
 	assert.EqualValues(t, 1, source.TermFrequency().GetTermFrequency())
 }
+
+func TestAttributeSource_WithCharTerm(t *testing.T) {
+	source := NewSource(WithCharTerm())
+
+	err := source.CharTerm().AppendString("term")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("term"), source.Term2Bytes().GetBytes())
+
+	// reading the BytesTermAttr does not change which attribute holds the term
+	assert.Empty(t, source.BytesTerm().GetBytes())
+	assert.Equal(t, []byte("term"), source.Term2Bytes().GetBytes())
+}
+
+func TestAttributeSource_Term2BytesDefault(t *testing.T) {
+	source := NewSource()
+
+	// without WithCharTerm the CharTermAttr is not the term of the stream
+	err := source.CharTerm().AppendString("term")
+	assert.Nil(t, err)
+	assert.Empty(t, source.Term2Bytes().GetBytes())
+
+	err = source.BytesTerm().SetBytes([]byte("bytes"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("bytes"), source.Term2Bytes().GetBytes())
+}
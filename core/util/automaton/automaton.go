@@ -333,8 +333,8 @@ func (r *destMinMaxSorter) Len() int {
 }
 
 func (r *destMinMaxSorter) Less(i, j int) bool {
-	iStart := 3 * i
-	jStart := 3 * j
+	iStart := 3 * (r.from + i)
+	jStart := 3 * (r.from + j)
 
 	iDest := r.transitions[iStart]
 	jDest := r.transitions[jStart]
@@ -368,7 +368,7 @@ func (r *destMinMaxSorter) Less(i, j int) bool {
 }
 
 func (r *destMinMaxSorter) Swap(i, j int) {
-	iStart, jStart := 3*i, 3*j
+	iStart, jStart := 3*(r.from+i), 3*(r.from+j)
 	r.swapOne(iStart, jStart)
 	r.swapOne(iStart+1, jStart+1)
 	r.swapOne(iStart+2, jStart+2)
@@ -390,8 +390,8 @@ func (r *minMaxDestSorter) Len() int {
 }
 
 func (r *minMaxDestSorter) Less(i, j int) bool {
-	iStart := 3 * i
-	jStart := 3 * j
+	iStart := 3 * (r.from + i)
+	jStart := 3 * (r.from + j)
 
 	// First min:
 	iMin := r.transitions[iStart+1]
@@ -424,7 +424,7 @@ func (r *minMaxDestSorter) Less(i, j int) bool {
 }
 
 func (r *minMaxDestSorter) Swap(i, j int) {
-	iStart, jStart := 3*i, 3*j
+	iStart, jStart := 3*(r.from+i), 3*(r.from+j)
 	r.swapOne(iStart, jStart)
 	r.swapOne(iStart+1, jStart+1)
 	r.swapOne(iStart+2, jStart+2)
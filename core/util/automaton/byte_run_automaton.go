@@ -5,13 +5,15 @@ type ByteRunAutomaton struct {
 	*RunAutomaton
 }
 
-func NewByteRunAutomaton(a *Automaton) *ByteRunAutomaton {
-	return &ByteRunAutomaton{
-		NewRunAutomatonV1(a, 256, 10000),
+func NewByteRunAutomaton(a *Automaton) (*ByteRunAutomaton, error) {
+	run, err := NewRunAutomatonV1(a, 256, DEFAULT_DETERMINIZE_WORK_LIMIT)
+	if err != nil {
+		return nil, err
 	}
+	return &ByteRunAutomaton{run}, nil
 }
 
-func NewByteRunAutomatonV1(a *Automaton, isBinary bool, determinizeWorkLimit int) *ByteRunAutomaton {
+func NewByteRunAutomatonV1(a *Automaton, isBinary bool, determinizeWorkLimit int) (*ByteRunAutomaton, error) {
 	var auto *Automaton
 
 	if isBinary {
@@ -20,9 +22,11 @@ func NewByteRunAutomatonV1(a *Automaton, isBinary bool, determinizeWorkLimit int
 
 	}
 
-	return &ByteRunAutomaton{
-		NewRunAutomatonV1(auto, 256, determinizeWorkLimit),
+	run, err := NewRunAutomatonV1(auto, 256, determinizeWorkLimit)
+	if err != nil {
+		return nil, err
 	}
+	return &ByteRunAutomaton{run}, nil
 }
 
 // Run Returns true if the given byte array is accepted by this automaton
@@ -38,9 +38,9 @@ type CompiledAutomaton struct {
 }
 
 // NewCompiledAutomaton
-// Create this. If finite is null, we use Operations.isFinite to determine whether it is finite. If simplify is true, we run possibly expensive operations to determine if the automaton is one the cases in CompiledAutomaton.AUTOMATON_TYPE. If simplify requires determinizing the automaton then at most determinizeWorkLimit effort will be spent. Any more than that will cause a TooComplexToDeterminizeException.
+// Create this. If finite is null, we use Operations.isFinite to determine whether it is finite. If simplify is true, we run possibly expensive operations to determine if the automaton is one the cases in CompiledAutomaton.AUTOMATON_TYPE. If simplify requires determinizing the automaton then at most determinizeWorkLimit effort will be spent. Any more than that will return ErrTooComplexToDeterminize.
 func NewCompiledAutomaton(automaton *Automaton, finite *atomic.Bool, simplify bool,
-	determinizeWorkLimit int, isBinary bool) *CompiledAutomaton {
+	determinizeWorkLimit int, isBinary bool) (*CompiledAutomaton, error) {
 
 	this := &CompiledAutomaton{}
 
@@ -64,7 +64,7 @@ func NewCompiledAutomaton(automaton *Automaton, finite *atomic.Bool, simplify bo
 			this.automaton = nil
 			this.finite = nil
 			this.sinkState = -1
-			return this
+			return this, nil
 		}
 
 		var isTotal bool
@@ -85,10 +85,14 @@ func NewCompiledAutomaton(automaton *Automaton, finite *atomic.Bool, simplify bo
 			this.automaton = nil
 			this.finite = nil
 			this.sinkState = -1
-			return this
+			return this, nil
 		}
 
-		automaton = DeterminizeAutomaton(automaton, determinizeWorkLimit)
+		var err error
+		automaton, err = DeterminizeAutomaton(automaton, determinizeWorkLimit)
+		if err != nil {
+			return nil, err
+		}
 
 		singleton, _ := GetSingletonAutomaton(automaton)
 
@@ -106,7 +110,7 @@ func NewCompiledAutomaton(automaton *Automaton, finite *atomic.Bool, simplify bo
 				this.term, _ = unicodeIntsToBytes(singleton)
 			}
 			this.sinkState = -1
-			return this
+			return this, nil
 		}
 	}
 
@@ -135,7 +139,10 @@ func NewCompiledAutomaton(automaton *Automaton, finite *atomic.Bool, simplify bo
 	if this.finite.Load() || automaton.GetNumStates()+automaton.GetNumTransitions() > 1000 {
 		this.commonSuffixRef = nil
 	} else {
-		suffix := GetCommonSuffixBytesRef(binary)
+		suffix, err := GetCommonSuffixBytesRef(binary, determinizeWorkLimit)
+		if err != nil {
+			return nil, err
+		}
 		if len(suffix) == 0 {
 			this.commonSuffixRef = nil
 		} else {
@@ -144,13 +151,17 @@ func NewCompiledAutomaton(automaton *Automaton, finite *atomic.Bool, simplify bo
 	}
 
 	// This will determinize the binary automaton for us:
-	this.runAutomaton = NewByteRunAutomatonV1(binary, true, determinizeWorkLimit)
+	runAutomaton, err := NewByteRunAutomatonV1(binary, true, determinizeWorkLimit)
+	if err != nil {
+		return nil, err
+	}
+	this.runAutomaton = runAutomaton
 	this.automaton = this.runAutomaton.automaton
 
 	// TODO: this is a bit fragile because if the automaton is not minimized there could be more than 1 sink state but this-prefix will fail
 	// to run for those:
 	this.sinkState = findSinkState(this.automaton)
-	return this
+	return this, nil
 }
 
 func findSinkState(automaton *Automaton) int {
@@ -213,6 +224,18 @@ func (r *CompiledAutomaton) RunAutomaton() *ByteRunAutomaton {
 	return r.runAutomaton
 }
 
+func (r *CompiledAutomaton) Automaton() *Automaton {
+	return r.automaton
+}
+
+func (r *CompiledAutomaton) CommonSuffixRef() []byte {
+	return r.commonSuffixRef
+}
+
+func (r *CompiledAutomaton) SinkState() int {
+	return r.sinkState
+}
+
 //func (r *CompiledAutomaton) GetTermsEnum(terms index.Terms) (index.TermsEnum, error) {
 //	switch r._type {
 //	case AUTOMATON_TYPE_NONE:
@@ -1,51 +1,112 @@
 package automaton
 
 import (
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"slices"
+	"sort"
 	"sync/atomic"
 
 	"github.com/bits-and-blooms/bitset"
 	"github.com/geange/gods-generic/cmp"
-	"github.com/geange/lucene-go/core/util/bitmixer"
-	"github.com/geange/lucene-go/core/util/structure"
 )
 
+// DEFAULT_DETERMINIZE_WORK_LIMIT Default maximum effort that DeterminizeAutomaton should spend before giving up
+const DEFAULT_DETERMINIZE_WORK_LIMIT = 10000
+
+// ErrTooComplexToDeterminize is returned when determinizing an automaton would create more states
+// than the work limit allows.
+var ErrTooComplexToDeterminize = errors.New("determinizing automaton would exceed the work limit")
+
 // DeterminizeAutomaton Determinizes the given automaton.
 // Worst case complexity: exponential in number of states.
-// Params: 	workLimit – Maximum amount of "work" that the powerset construction will spend before throwing
+// Params: 	workLimit – Maximum amount of "work" that the powerset construction will spend before returning
 //
-//	TooComplexToDeterminizeException. Higher numbers allow this operation to consume more memory and
+//	ErrTooComplexToDeterminize. Higher numbers allow this operation to consume more memory and
 //	CPU but allow more complex automatons. Use DEFAULT_DETERMINIZE_WORK_LIMIT as a decent default
 //	if you don't otherwise know what to specify.
 //
-// Throws: TooComplexToDeterminizeException – if determinizing requires more than workLimit "effort"
-func DeterminizeAutomaton(a *Automaton, workLimit int) *Automaton {
+// Returns ErrTooComplexToDeterminize if determinizing creates more than workLimit states.
+func DeterminizeAutomaton(a *Automaton, workLimit int) (*Automaton, error) {
 	if a.IsDeterministic() {
-		return a
+		return a, nil
 	}
 	if a.GetNumStates() <= 1 {
 		// Already determinized
-		return a
+		return a, nil
 	}
 
 	// subset construction
 	b := NewNewBuilder()
 
-	// Same initial values and state will always have the same hashCode
-	initialset := NewFrozenIntSet([]int{0}, bitmixer.Mix32(0)+1, 0)
 	// Create state 0:
 	b.CreateState()
+	b.SetAccept(0, a.IsAccept(0))
 
-	worklist := make([]*FrozenIntSet, 0)
-	newstate := structure.NewMap[IntSet, int]()
+	initialset := []int{0}
+	worklist := [][]int{initialset}
+	newstate := map[string]int{intSetKey(initialset): 0}
 
-	worklist = append(worklist, initialset)
-	b.SetAccept(0, a.IsAccept(0))
-	newstate.Put(initialset, 0)
+	points := a.GetStartPoints()
+	t := NewTransition()
+	for len(worklist) > 0 {
+		set := worklist[0]
+		worklist = worklist[1:]
+		source := newstate[intSetKey(set)]
+
+		for i, point := range points {
+			maxLabel := 0x10FFFF
+			if i+1 < len(points) {
+				maxLabel = points[i+1] - 1
+			}
 
-	// TODO:
+			// collect all states reachable from the set with this interval
+			dests := make([]int, 0, len(set))
+			for _, s := range set {
+				count := a.InitTransition(s, t)
+				for j := 0; j < count; j++ {
+					a.GetNextTransition(t)
+					if t.Min <= point && point <= t.Max {
+						dests = append(dests, t.Dest)
+					}
+				}
+			}
+			if len(dests) == 0 {
+				continue
+			}
+			sort.Ints(dests)
+			dests = slices.Compact(dests)
+
+			key := intSetKey(dests)
+			dest, ok := newstate[key]
+			if !ok {
+				dest = b.CreateState()
+				if dest >= workLimit {
+					return nil, fmt.Errorf("%w: more than %d states", ErrTooComplexToDeterminize, workLimit)
+				}
+				for _, s := range dests {
+					if a.IsAccept(s) {
+						b.SetAccept(dest, true)
+						break
+					}
+				}
+				newstate[key] = dest
+				worklist = append(worklist, dests)
+			}
+			b.AddTransition(source, dest, point, maxLabel)
+		}
+	}
 
-	return a
+	return b.Finish(), nil
+}
+
+func intSetKey(set []int) string {
+	key := make([]byte, 0, len(set)*4)
+	for _, v := range set {
+		key = binary.BigEndian.AppendUint32(key, uint32(v))
+	}
+	return string(key)
 }
 
 // IsEmptyAutomaton
@@ -180,14 +241,44 @@ func isFinite(scratch *Transition, a *Automaton, state int, path, visited *bitse
 // GetCommonSuffixBytesRef
 // Returns the longest BytesRef that is a suffix of all accepted strings. Worst case complexity: quadratic with the number of states+transitions.
 // Returns: common suffix, which can be an empty (length 0) BytesRef (never null)
-func GetCommonSuffixBytesRef(a *Automaton) []byte {
+func GetCommonSuffixBytesRef(a *Automaton, determinizeWorkLimit int) ([]byte, error) {
 	// reverse the language of the automaton, then reverse its common prefix.
-	panic("")
+	r, err := DeterminizeAutomaton(reverseAutomaton(a), determinizeWorkLimit)
+	if err != nil {
+		return nil, err
+	}
+	ref := GetCommonPrefixBytesRef(r)
+	reverse(ref)
+	return ref, nil
+}
+
+// GetCommonPrefixBytesRef
+// Returns the longest BytesRef that is a prefix of all accepted strings and visits each state at
+// most once. The automaton must be deterministic.
+// Returns: common prefix, which can be an empty (length 0) BytesRef (never null)
+func GetCommonPrefixBytesRef(a *Automaton) []byte {
+	builder := make([]byte, 0)
+	visited := make(map[int]struct{})
+	t := NewTransition()
+
+	s := 0
+	for {
+		visited[s] = struct{}{}
+		if a.IsAccept(s) || a.GetNumTransitionsWithState(s) != 1 {
+			break
+		}
+		a.getTransition(s, 0, t)
+		if _, ok := visited[t.Dest]; ok || t.Min != t.Max {
+			break
+		}
+		builder = append(builder, byte(t.Min))
+		s = t.Dest
+	}
+	return builder
 }
 
 func reverse[T cmp.Ordered](ref []T) {
-	i, j := 0, len(ref)-1
-	for i < j {
+	for i, j := 0, len(ref)-1; i < j; i, j = i+1, j-1 {
 		ref[i], ref[j] = ref[j], ref[i]
 	}
 }
@@ -276,18 +367,13 @@ func reverseAutomatonIntSet(a *Automaton, initialStates map[int]struct{}) *Autom
 
 	result := builder.Finish()
 
-	s := 0
 	acceptStates := a.getAcceptStates()
-	for {
-		if _, ok := acceptStates.NextSet(uint(s)); !(ok && s < numStates) {
-			break
-		}
-
+	for next, ok := acceptStates.NextSet(0); ok && int(next) < numStates; next, ok = acceptStates.NextSet(next + 1) {
+		s := int(next)
 		result.AddEpsilon(0, s+1)
 		if initialStates != nil {
 			initialStates[s+1] = struct{}{}
 		}
-		s++
 	}
 
 	result.finishState()
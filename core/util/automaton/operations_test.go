@@ -0,0 +1,103 @@
+package automaton
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newSuffixAutomaton builds a deterministic automaton accepting all strings ending with "ea".
+func newSuffixAutomaton() *Automaton {
+	b := NewNewBuilder()
+	s0, s1, s2 := b.CreateState(), b.CreateState(), b.CreateState()
+	b.AddTransition(s0, s0, 0, 'e'-1)
+	b.AddTransitionLabel(s0, s1, 'e')
+	b.AddTransition(s0, s0, 'e'+1, 255)
+	b.AddTransition(s1, s0, 0, 'a'-1)
+	b.AddTransitionLabel(s1, s2, 'a')
+	b.AddTransition(s1, s0, 'b', 'd')
+	b.AddTransitionLabel(s1, s1, 'e')
+	b.AddTransition(s1, s0, 'e'+1, 255)
+	b.AddTransition(s2, s0, 0, 'e'-1)
+	b.AddTransitionLabel(s2, s1, 'e')
+	b.AddTransition(s2, s0, 'e'+1, 255)
+	b.SetAccept(s2, true)
+	return b.Finish()
+}
+
+func TestDeterminizeAutomaton(t *testing.T) {
+	// nondeterministic: "ab" or "ac"
+	b := NewNewBuilder()
+	s0, s1, s2, s3 := b.CreateState(), b.CreateState(), b.CreateState(), b.CreateState()
+	b.AddTransitionLabel(s0, s1, 'a')
+	b.AddTransitionLabel(s0, s2, 'a')
+	b.AddTransitionLabel(s1, s3, 'b')
+	b.AddTransitionLabel(s2, s3, 'c')
+	b.SetAccept(s3, true)
+	a := b.Finish()
+	assert.False(t, a.IsDeterministic())
+
+	d, err := DeterminizeAutomaton(a, 10000)
+	assert.Nil(t, err)
+	assert.True(t, d.IsDeterministic())
+	assert.Equal(t, 3, d.GetNumStates())
+
+	run, err := NewByteRunAutomatonV1(a, true, 10000)
+	assert.Nil(t, err)
+	for term, accept := range map[string]bool{"ab": true, "ac": true, "a": false, "ad": false, "abc": false} {
+		state := 0
+		for i := 0; i < len(term) && state != -1; i++ {
+			state = run.Step(state, int(term[i]))
+		}
+		assert.Equal(t, accept, state != -1 && run.IsAccept(state), term)
+	}
+}
+
+func TestDeterminizeAutomaton_WorkLimit(t *testing.T) {
+	// nondeterministic: the n-th last letter is 'a', its determinized form has 2^n states
+	n := 10
+	b := NewNewBuilder()
+	states := make([]int, n+1)
+	for i := range states {
+		states[i] = b.CreateState()
+	}
+	b.AddTransition(states[0], states[0], 'a', 'b')
+	b.AddTransitionLabel(states[0], states[1], 'a')
+	for i := 1; i < n; i++ {
+		b.AddTransition(states[i], states[i+1], 'a', 'b')
+	}
+	b.SetAccept(states[n], true)
+	a := b.Finish()
+
+	_, err := DeterminizeAutomaton(a, 100)
+	assert.ErrorIs(t, err, ErrTooComplexToDeterminize)
+	_, err = NewCompiledAutomaton(a, nil, true, 100, true)
+	assert.ErrorIs(t, err, ErrTooComplexToDeterminize)
+
+	d, err := DeterminizeAutomaton(a, DEFAULT_DETERMINIZE_WORK_LIMIT)
+	assert.Nil(t, err)
+	assert.True(t, d.IsDeterministic())
+	assert.Equal(t, 1<<n, d.GetNumStates())
+}
+
+func TestGetCommonSuffixBytesRef(t *testing.T) {
+	a := newSuffixAutomaton()
+	suffix, err := GetCommonSuffixBytesRef(a, 10000)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("ea"), suffix)
+	assert.Equal(t, []byte{}, GetCommonPrefixBytesRef(a))
+
+	compiled, err := NewCompiledAutomaton(a, nil, false, 10000, true)
+	assert.Nil(t, err)
+	assert.Equal(t, AUTOMATON_TYPE_NORMAL, compiled.Type())
+	assert.Equal(t, []byte("ea"), compiled.CommonSuffixRef())
+
+	run := compiled.RunAutomaton()
+	for term, accept := range map[string]bool{"ea": true, "aaacea": true, "eea": true, "eae": false, "a": false} {
+		state := 0
+		for i := 0; i < len(term) && state != -1; i++ {
+			state = run.Step(state, int(term[i]))
+		}
+		assert.Equal(t, accept, state != -1 && run.IsAccept(state), term)
+	}
+}
@@ -18,12 +18,16 @@ type RunAutomaton struct {
 	classmap []int
 }
 
-func NewRunAutomatonV1(a *Automaton, alphabetSize, determinizeWorkLimit int) *RunAutomaton {
+func NewRunAutomatonV1(a *Automaton, alphabetSize, determinizeWorkLimit int) (*RunAutomaton, error) {
+	a, err := DeterminizeAutomaton(a, determinizeWorkLimit)
+	if err != nil {
+		return nil, err
+	}
 	size := Max(1, a.GetNumStates())
 	points := a.GetStartPoints()
 
 	r := RunAutomaton{
-		automaton:    DeterminizeAutomaton(a, determinizeWorkLimit),
+		automaton:    a,
 		alphabetSize: alphabetSize,
 		size:         size,
 		accept:       make([]bool, size),
@@ -56,7 +60,7 @@ func NewRunAutomatonV1(a *Automaton, alphabetSize, determinizeWorkLimit int) *Ru
 		r.classmap[j] = i
 	}
 
-	return &r
+	return &r, nil
 }
 
 // GetSize Returns number of states in automaton.
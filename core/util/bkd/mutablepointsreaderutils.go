@@ -2,9 +2,11 @@ package bkd
 
 import (
 	"bytes"
+	"cmp"
+	"sort"
+
 	"github.com/geange/lucene-go/core/types"
 	"github.com/geange/lucene-go/core/util/packed"
-	"sort"
 )
 
 var _ sort.Interface = &MutablePointValuesSorter{}
@@ -19,6 +21,8 @@ type MutablePointValuesSorter struct {
 	sortedByDocID bool
 	prevDoc       int
 	bitsPerDocId  int
+
+	scratch1, scratch2 *bytes.Buffer
 }
 
 func NewMutablePointValuesSorter(config *Config, maxDoc int, reader types.MutablePointValues,
@@ -50,6 +54,8 @@ func NewMutablePointValuesSorter(config *Config, maxDoc int, reader types.Mutabl
 		sortedByDocID: sortedByDocID,
 		prevDoc:       prevDoc,
 		bitsPerDocId:  bitsPerDocId,
+		scratch1:      new(bytes.Buffer),
+		scratch2:      new(bytes.Buffer),
 	}
 }
 
@@ -58,14 +64,12 @@ func (m *MutablePointValuesSorter) Len() int {
 }
 
 func (m *MutablePointValuesSorter) Less(i, j int) bool {
-	buf := new(bytes.Buffer)
-	m.reader.GetValue(i, buf)
-	bs1 := buf.Bytes()
+	i, j = m.from+i, m.from+j
 
-	m.reader.GetValue(j, buf)
-	bs2 := buf.Bytes()
+	m.reader.GetValue(i, m.scratch1)
+	m.reader.GetValue(j, m.scratch2)
 
-	flag := bytes.Compare(bs1, bs2)
+	flag := bytes.Compare(m.scratch1.Bytes(), m.scratch2.Bytes())
 
 	if flag == 0 {
 		if m.reader.GetDocID(i) < m.reader.GetDocID(j) {
@@ -119,6 +123,7 @@ func (r *IntroSorter) Len() int {
 }
 
 func (r *IntroSorter) Less(i, j int) bool {
+	i, j = r.from+i, r.from+j
 	r.reader.GetValue(i, r.scratch1)
 	r.reader.GetValue(j, r.scratch2)
 
@@ -144,7 +149,7 @@ func (r *IntroSorter) Less(i, j int) bool {
 }
 
 func (r *IntroSorter) Swap(i, j int) {
-	r.reader.Swap(i, j)
+	r.reader.Swap(r.from+i, r.from+j)
 }
 
 // Partition points around mid. All values on the left must be less than or equal to it and
@@ -153,5 +158,52 @@ func partition(config *Config, maxDoc, splitDim, commonPrefixLen int,
 	reader types.MutablePointValues, from, to, mid int,
 	scratch1, scratch2 *bytes.Buffer) {
 
-	panic("")
+	start := splitDim*config.bytesPerDim + commonPrefixLen
+	end := splitDim*config.bytesPerDim + config.bytesPerDim
+
+	// compare the split dimension first, then the data-only dimensions and finally doc ids
+	compare := func(i int, pivot []byte, pivotDoc int) int {
+		reader.GetValue(i, scratch1)
+		value := scratch1.Bytes()
+		if cmp := bytes.Compare(value[start:end], pivot[start:end]); cmp != 0 {
+			return cmp
+		}
+		fromIndex, toIndex := config.packedIndexBytesLength, config.packedBytesLength
+		if cmp := bytes.Compare(value[fromIndex:toIndex], pivot[fromIndex:toIndex]); cmp != 0 {
+			return cmp
+		}
+		return cmp.Compare(reader.GetDocID(i), pivotDoc)
+	}
+
+	// quickselect with a three-way partition around the pivot, so that duplicates don't degrade it
+	for to-from > 1 {
+		pivotIndex := from + (to-from)>>1
+		reader.GetValue(pivotIndex, scratch2)
+		pivot := bytes.Clone(scratch2.Bytes())
+		pivotDoc := reader.GetDocID(pivotIndex)
+
+		// [from, lt) < pivot, [lt, gt) == pivot, [gt, to) > pivot
+		lt, i, gt := from, from, to
+		for i < gt {
+			c := compare(i, pivot, pivotDoc)
+			if c < 0 {
+				reader.Swap(lt, i)
+				lt++
+				i++
+			} else if c > 0 {
+				gt--
+				reader.Swap(i, gt)
+			} else {
+				i++
+			}
+		}
+
+		if mid < lt {
+			to = lt
+		} else if mid >= gt {
+			from = gt
+		} else {
+			return
+		}
+	}
 }
@@ -87,7 +87,7 @@ func (r *OfflinePointReader) Close() error {
 	if r.countLeft == 0 && r.checked == false {
 		if in, ok := r.in.(store.ChecksumIndexInput); ok {
 			r.checked = true
-			if err := utils.CheckFooter(in); err != nil {
+			if _, err := utils.CheckCodecFooter(in); err != nil {
 				return err
 			}
 		}
@@ -108,7 +108,7 @@ func (r *OfflinePointReader) Next() (bool, error) {
 
 	if r.countLeft > r.maxPointOnHeap {
 		size := r.maxPointOnHeap * r.config.BytesPerDoc()
-		_, err := r.in.Read(r.onHeapBuffer[0:size])
+		_, err := io.ReadFull(r.in, r.onHeapBuffer[0:size])
 		if err != nil {
 			return false, err
 		}
@@ -116,7 +116,7 @@ func (r *OfflinePointReader) Next() (bool, error) {
 		r.countLeft -= r.maxPointOnHeap
 	} else {
 		size := r.countLeft * r.config.BytesPerDoc()
-		_, err := r.in.Read(r.onHeapBuffer[0:size])
+		_, err := io.ReadFull(r.in, r.onHeapBuffer[0:size])
 		if err != nil {
 			return false, err
 		}
@@ -62,16 +62,15 @@ func (r *OneDimensionBKDWriter) Add(ctx context.Context, packedValue []byte, doc
 	w := r.p
 	config := w.config
 
-	bytesPerDim := config.BytesPerDim()
-	from := (r.leafCount - 1) * bytesPerDim
-	to := from + bytesPerDim
+	packedBytesLength := config.PackedBytesLength()
+	from := (r.leafCount - 1) * packedBytesLength
+	to := from + packedBytesLength
 
 	if r.leafCount == 0 ||
-		bytes.Equal(r.leafValues[from:to], packedValue[:bytesPerDim]) == false {
+		bytes.Equal(r.leafValues[from:to], packedValue[:packedBytesLength]) == false {
 		r.leafCardinality++
 	}
 
-	packedBytesLength := config.PackedBytesLength()
 	destFrom := r.leafCount * config.PackedBytesLength()
 	destTo := destFrom + packedBytesLength
 	copy(r.leafValues[destFrom:destTo], packedValue[:packedBytesLength])
@@ -109,9 +108,11 @@ func (r *OneDimensionBKDWriter) Finish() (Runnable, error) {
 	}
 
 	if r.valueCount == 0 {
-		return emptyRunnable, nil
+		return nil, nil
 	}
 
+	w.pointCount = r.valueCount
+
 	leafNodes := &oneDimBKDTreeLeafNodes{dimWriter: r}
 
 	return func(ctx context.Context) error {
@@ -7,7 +7,6 @@ import (
 	"github.com/geange/lucene-go/core/store"
 	"github.com/geange/lucene-go/core/util/selector"
 	"github.com/geange/lucene-go/core/util/sorter"
-	"io"
 	"slices"
 	"strconv"
 )
@@ -87,8 +86,18 @@ func (b *RadixSelector) Select(points *PathSlice, partitionSlices []*PathSlice,
 
 	partitionSlices[0] = NewPathSlice(left, 0, partitionPoint-from)
 	partitionSlices[1] = NewPathSlice(right, 0, to-partitionPoint)
-	return b.buildHistogramAndPartition(offlinePointWriter, left, right, from, to,
+	partition, err := b.buildHistogramAndPartition(offlinePointWriter, left, right, from, to,
 		partitionPoint, 0, dimCommonPrefix, dim)
+	if err != nil {
+		return nil, errors.Join(err, left.Close(), right.Close())
+	}
+	if err := left.Close(); err != nil {
+		return nil, err
+	}
+	if err := right.Close(); err != nil {
+		return nil, err
+	}
+	return partition, nil
 }
 
 func (b *RadixSelector) checkArgs(from, to, partitionPoint int) error {
@@ -110,6 +119,7 @@ func (b *RadixSelector) findCommonPrefixAndHistogram(points *OfflinePointWriter,
 	if err != nil {
 		return 0, err
 	}
+	defer reader.Close()
 	// assert commonPrefixPosition > dimCommonPrefix;
 	_, err = reader.Next()
 	if err != nil {
@@ -265,6 +275,9 @@ func (b *RadixSelector) buildHistogramAndPartition(points *OfflinePointWriter, l
 	// Divide the points. This actually destroys the current writer
 	err = b.offlinePartition(points, left, right, tempDeltaPoints, from, to, dim, commonPrefix, 0)
 	if err != nil {
+		return nil, errors.Join(err, tempDeltaPoints.Close())
+	}
+	if err := tempDeltaPoints.Close(); err != nil {
 		return nil, err
 	}
 	deltaPoints := tempDeltaPoints
@@ -295,10 +308,7 @@ func (b *RadixSelector) offlinePartition(points *OfflinePointWriter, left, right
 	for {
 		ok, err := reader.Next()
 		if err != nil {
-			if errors.Is(err, io.EOF) {
-				return nil
-			}
-			return err
+			return errors.Join(err, reader.Close())
 		}
 
 		if !ok {
@@ -337,6 +347,10 @@ func (b *RadixSelector) offlinePartition(points *OfflinePointWriter, left, right
 		}
 	}
 
+	if err := reader.Close(); err != nil {
+		return err
+	}
+
 	// Delete original file
 	return points.Destroy()
 }
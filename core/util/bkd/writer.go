@@ -102,7 +102,7 @@ func NewWriter(maxDoc int, tempDir store.Directory, tempFileNamePrefix string,
 		pointCount:          0,
 		totalPointCount:     totalPointCount,
 		maxDoc:              maxDoc,
-		scratchOut:          nil,
+		scratchOut:          store.NewBufferDataOutput(),
 	}
 
 	return writer, nil
@@ -208,10 +208,6 @@ type LeafNodes interface {
 
 type Runnable func(ctx context.Context) error
 
-var (
-	emptyRunnable = func(_ context.Context) error { return nil }
-)
-
 // WriteField Write a field from a MutablePointValues. This way of writing points is faster than regular writes with add since there is opportunity for reordering points before writing them to disk. This method does not use transient disk in order to reorder points.
 func (w *Writer) WriteField(ctx context.Context, metaOut, indexOut, dataOut store.IndexOutput, fieldName string, reader types.MutablePointValues) (Runnable, error) {
 	if w.config.NumDims() == 1 {
@@ -235,7 +231,7 @@ func (w *Writer) computePackedValueBoundsV1(values types.MutablePointValues, fro
 	copy(maxPackedValue, scratch.Bytes()[:config.PackedIndexBytesLength()])
 
 	for i := from + 1; i < to; i++ {
-		values.GetValue(from, scratch)
+		values.GetValue(i, scratch)
 
 		for dim := 0; dim < config.NumIndexDims(); dim++ {
 			start := dim * config.BytesPerDim()
@@ -308,6 +304,7 @@ func (w *Writer) writeFieldNDims(ctx context.Context, metaOut, indexOut, dataOut
 	leafNodes := &leafNodesWriteFieldNDims{
 		leafBlockFPs:         leafBlockFPs,
 		p:                    w,
+		splitPackedValues:    splitPackedValues,
 		splitDimensionValues: splitDimensionValues,
 	}
 
@@ -321,6 +318,7 @@ var _ LeafNodes = &leafNodesWriteFieldNDims{}
 type leafNodesWriteFieldNDims struct {
 	leafBlockFPs         []int64
 	p                    *Writer
+	splitPackedValues    []byte
 	splitDimensionValues []byte
 }
 
@@ -334,7 +332,7 @@ func (l *leafNodesWriteFieldNDims) GetLeafLP(index int) int64 {
 
 func (l *leafNodesWriteFieldNDims) GetSplitValue(index int) []byte {
 	offset := index * l.p.config.bytesPerDim
-	return l.p.scratchBytesRef1.Bytes()[offset:]
+	return l.splitPackedValues[offset : offset+l.p.config.bytesPerDim]
 }
 
 func (l *leafNodesWriteFieldNDims) GetSplitDimension(index int) int {
@@ -419,7 +417,7 @@ func (w *Writer) Finish(ctx context.Context, metaOut, indexOut, dataOut store.In
 	}
 
 	if w.pointCount == 0 {
-		return emptyRunnable, nil
+		return nil, nil
 	}
 
 	w.finished.Store(true)
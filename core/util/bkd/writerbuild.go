@@ -70,8 +70,8 @@ func (w *Writer) build(ctx context.Context, leavesOffset, numLeaves int, reader
 	end := start + bytesPerDim
 	copy(splitPackedValues[address:], w.scratchBytesRef1.Bytes()[start:end])
 
-	minSplitPackedValue := make([]byte, len(minPackedValue))
-	maxSplitPackedValue := make([]byte, len(maxPackedValue))
+	minSplitPackedValue := bytes.Clone(minPackedValue)
+	maxSplitPackedValue := bytes.Clone(maxPackedValue)
 	copy(minSplitPackedValue[start:end], w.scratchBytesRef1.Bytes()[start:end])
 	copy(maxSplitPackedValue[start:end], w.scratchBytesRef1.Bytes()[start:end])
 
@@ -104,11 +104,11 @@ func (w *Writer) build1Leaf(ctx context.Context, leavesOffset int, reader types.
 	}
 	reader.GetValue(from, w.scratchBytesRef1)
 	for i := from + 1; i < to; i++ {
-		reader.GetValue(from, w.scratchBytesRef2)
+		reader.GetValue(i, w.scratchBytesRef2)
 		for dim := 0; dim < numDims; dim++ {
 			start := dim * bytesPerDim
-			end := start + bytesPerDim
 			dimensionPrefixLength := w.commonPrefixLengths[dim]
+			end := start + dimensionPrefixLength
 			w.commonPrefixLengths[dim] = Mismatch(
 				w.scratchBytesRef1.Bytes()[start:end],
 				w.scratchBytesRef2.Bytes()[start:end],
@@ -166,8 +166,7 @@ func (w *Writer) build1Leaf(ctx context.Context, leavesOffset int, reader types.
 			start := dim*bytesPerDim + w.commonPrefixLengths[dim]
 			end := dim*bytesPerDim + bytesPerDim
 
-			cmp := bytes.Compare(collector.Bytes()[start:end], comparator.Bytes()[start:end])
-			if cmp != -1 {
+			if !bytes.Equal(collector.Bytes()[start:end], comparator.Bytes()[start:end]) {
 				leafCardinality++
 				collector, comparator = comparator, collector
 				break
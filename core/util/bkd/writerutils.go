@@ -267,6 +267,8 @@ func (w *Writer) switchToHeap(source PointWriter) (*HeapPointWriter, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer reader.Close()
+
 	writer := NewHeapPointWriter(w.config, count)
 	for i := 0; i < count; i++ {
 		if _, err := reader.Next(); err != nil {
@@ -292,6 +294,8 @@ func (w *Writer) computePackedValueBounds(slice *PathSlice, minPackedValue, maxP
 	if err != nil {
 		return err
 	}
+	defer reader.Close()
+
 	if ok, err := reader.Next(); err != nil {
 		if errors.Is(err, io.EOF) {
 			return nil
@@ -325,6 +329,7 @@ func (w *Writer) computePackedValueBounds(slice *PathSlice, minPackedValue, maxP
 			break
 		}
 
+		value = reader.PointValue().PackedValue()
 		for dim := 0; dim < numIndexDims; dim++ {
 			startOffset := dim * bytesPerDim
 			endOffset := startOffset + bytesPerDim
@@ -1 +1,48 @@
 package bytesref
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockPool_AllocSlice(t *testing.T) {
+	pool := NewBlockPool(GetAllocatorBuilder().NewDirect(BYTE_BLOCK_SIZE))
+	pool.NextBuffer()
+
+	// write the bytes like the terms hash does: the non-zero end of a slice holds its
+	// level and tells that the slice is full
+	size := 100000
+	start := pool.NewSlice(FIRST_LEVEL_SIZE)
+	buffer, upto := pool.buffer, start
+	for i := 0; i < size; i++ {
+		if buffer[upto] != 0 {
+			upto = pool.AllocSlice(buffer, upto)
+			buffer = pool.buffer
+		}
+		buffer[upto] = byte(i)
+		upto++
+	}
+
+	byteAt := func(offset int) byte {
+		return pool.buffers[offset>>BYTE_BLOCK_SHIFT][offset&BYTE_BLOCK_MASK]
+	}
+
+	// follow the forwarding addresses written at the end of the slices
+	level, offset := 0, start
+	limit := offset + LEVEL_SIZE_ARRAY[level] - 4
+	for i := 0; i < size; i++ {
+		if offset == limit {
+			block := pool.buffers[offset>>BYTE_BLOCK_SHIFT]
+			offset = int(binary.BigEndian.Uint32(block[offset&BYTE_BLOCK_MASK:]))
+			level = NEXT_LEVEL_ARRAY[level]
+			limit = offset + LEVEL_SIZE_ARRAY[level] - 4
+		}
+		if !assert.Equal(t, byte(i), byteAt(offset), "byte %d", i) {
+			return
+		}
+		offset++
+	}
+	assert.Equal(t, len(LEVEL_SIZE_ARRAY)-1, level)
+}
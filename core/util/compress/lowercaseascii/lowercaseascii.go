@@ -0,0 +1,146 @@
+package lowercaseascii
+
+import (
+	"context"
+	"io"
+
+	"github.com/geange/lucene-go/core/store"
+)
+
+// Utility class that can efficiently compress arrays that mostly contain characters in the
+// [0x1F,0x3F) or [0x5F,0x7F) ranges, which notably include all digits, lowercase characters, '.',
+// '-' and '_'. Each of those characters is packed on 6 bits, so 4 characters take 3 bytes, and the
+// other characters are recorded as exceptions after the packed bytes.
+// This is a port of Lucene's LowercaseAsciiCompression, the block tree terms dictionary of Lucene
+// 8.6 to 8.11 compresses the suffixes of its blocks with it.
+
+func isCompressible(b int) bool {
+	high3Bits := (b + 1) & ^0x1F
+	return high3Bits == 0x20 || high3Bits == 0x60
+}
+
+// Compress Compress in[:len(in)] into out. This returns false if the content cannot be compressed,
+// in which case nothing is written to out. tmp must be at least as large as in.
+func Compress(ctx context.Context, in, tmp []byte, out store.DataOutput) (bool, error) {
+	size := len(in)
+	if size < 8 {
+		return false, nil
+	}
+
+	// 1. Count exceptions and fail compression if there are too many of them.
+	maxExceptions := size >> 5
+	previousExceptionIndex := 0
+	numExceptions := 0
+	for i, b := range in {
+		if !isCompressible(int(b)) {
+			for i-previousExceptionIndex > 0xff {
+				numExceptions++
+				previousExceptionIndex += 0xff
+			}
+			numExceptions++
+			if numExceptions > maxExceptions {
+				return false, nil
+			}
+			previousExceptionIndex = i
+		}
+	}
+
+	// 2. Now move all bytes to the [0,0x40) range (6 bits).
+	compressedLen := size - (size >> 2) // ignores exceptions
+	for i, v := range in {
+		b := v + 1
+		tmp[i] = (b & 0x1F) | ((b & 0x40) >> 1)
+	}
+
+	// 3. Now pack the bytes so that we record 4 ASCII chars in 3 bytes
+	o := 0
+	for i := compressedLen; i < size; i++ {
+		tmp[o] |= (tmp[i] & 0x30) << 2 // bits 4-5
+		o++
+	}
+	for i := compressedLen; i < size; i++ {
+		tmp[o] |= (tmp[i] & 0x0C) << 4 // bits 2-3
+		o++
+	}
+	for i := compressedLen; i < size; i++ {
+		tmp[o] |= (tmp[i] & 0x03) << 6 // bits 0-1
+		o++
+	}
+
+	if _, err := out.Write(tmp[:compressedLen]); err != nil {
+		return false, err
+	}
+
+	// 4. Finally record exceptions
+	if err := out.WriteUvarint(ctx, uint64(numExceptions)); err != nil {
+		return false, err
+	}
+	if numExceptions > 0 {
+		previousExceptionIndex = 0
+		for i, b := range in {
+			if isCompressible(int(b)) {
+				continue
+			}
+			for i-previousExceptionIndex > 0xff {
+				// We record deltas between exceptions as bytes, so we need to create
+				// "artificial" exceptions if the delta between two of them is greater
+				// than the maximum unsigned byte.
+				previousExceptionIndex += 0xff
+				if _, err := out.Write([]byte{0xff, in[previousExceptionIndex]}); err != nil {
+					return false, err
+				}
+			}
+			if _, err := out.Write([]byte{byte(i - previousExceptionIndex), b}); err != nil {
+				return false, err
+			}
+			previousExceptionIndex = i
+		}
+	}
+	return true, nil
+}
+
+// Decompress Decompress data that has been compressed with Compress. length must be the
+// length of the decompressed data, out must be at least as large.
+func Decompress(ctx context.Context, in store.DataInput, out []byte, length int) error {
+	saved := length >> 2
+	compressedLen := length - saved
+
+	// 1. Copy the packed bytes
+	if _, err := io.ReadFull(in, out[:compressedLen]); err != nil {
+		return err
+	}
+
+	// 2. Restore the leading 2 bits of each packed byte into whole bytes
+	for i := 0; i < saved; i++ {
+		out[compressedLen+i] = ((out[i] & 0xC0) >> 2) |
+			((out[saved+i] & 0xC0) >> 4) |
+			((out[(saved<<1)+i] & 0xC0) >> 6)
+	}
+
+	// 3. Move back to the original range.
+	for i := 0; i < length; i++ {
+		b := out[i]
+		out[i] = ((b & 0x1F) | 0x20 | ((b & 0x20) << 1)) - 1
+	}
+
+	// 4. Restore exceptions
+	numExceptions, err := in.ReadUvarint(ctx)
+	if err != nil {
+		return err
+	}
+	i := 0
+	for exception := uint64(0); exception < numExceptions; exception++ {
+		delta, err := in.ReadByte()
+		if err != nil {
+			return err
+		}
+		i += int(delta)
+		if i >= length {
+			return io.ErrUnexpectedEOF
+		}
+		if out[i], err = in.ReadByte(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
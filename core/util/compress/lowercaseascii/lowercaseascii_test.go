@@ -0,0 +1,57 @@
+package lowercaseascii
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/geange/lucene-go/core/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressDecompress(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(7))
+
+	lowercase := make([]byte, 1000)
+	for i := range lowercase {
+		lowercase[i] = byte('a' + r.Intn(26))
+	}
+	// a few exceptions, two of them more than 255 bytes apart
+	exceptions := bytes.Clone(lowercase)
+	exceptions[3] = 'A'
+	exceptions[600] = 0xff
+
+	inputs := map[string][]byte{
+		"lowercase":  lowercase,
+		"exceptions": exceptions,
+		"digits":     []byte("0123456789-0123456789_0123456789.0123456789"),
+	}
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			out := store.NewBufferDataOutput()
+			ok, err := Compress(ctx, input, make([]byte, len(input)), out)
+			assert.Nil(t, err)
+			assert.True(t, ok)
+			assert.Less(t, len(out.Bytes()), len(input))
+
+			dest := make([]byte, len(input))
+			assert.Nil(t, Decompress(ctx, store.NewBytesInput(out.Bytes()), dest, len(input)))
+			assert.Equal(t, input, dest)
+		})
+	}
+}
+
+func TestCompress_Incompressible(t *testing.T) {
+	ctx := context.Background()
+
+	// too short, then too many exceptions
+	for _, input := range [][]byte{[]byte("abc"), bytes.Repeat([]byte("ABCDEFGH"), 10)} {
+		out := store.NewBufferDataOutput()
+		ok, err := Compress(ctx, input, make([]byte, len(input)), out)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+		assert.Empty(t, out.Bytes())
+	}
+}
@@ -0,0 +1,366 @@
+package lz4
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// LZ4 compression and decompression routines.
+// https://github.com/lz4/lz4/tree/dev/lib http://fastcompression.blogspot.fr/p/lz4.html
+// This is a port of Lucene's LZ4, the compressed bytes carry no frame or length header, so the
+// caller needs to know the decompressed length.
+
+const (
+	MAX_DISTANCE  = 1 << 16 // maximum distance of a reference
+	MIN_MATCH     = 4       // minimum length of a match
+	LAST_LITERALS = 5       // the last 5 bytes must be encoded as literals
+
+	// MEMORY_USAGE Same as the LZ4 C library's default, 16KB of memory.
+	MEMORY_USAGE = 14
+
+	// hashLog 4 bytes per entry of the hash table
+	hashLog = MEMORY_USAGE - 2
+)
+
+var (
+	ErrMalformedInput = errors.New("lz4: malformed input")
+)
+
+// Decompress Decompress at least decompressedLen bytes into dest. Note that dest must be large
+// enough to be able to hold all decompressed data (meaning that you need to know the total
+// decompressed length). Returns the number of bytes decompressed, which may be greater than
+// decompressedLen since a sequence is never split.
+func Decompress(in io.ByteReader, decompressedLen int, dest []byte) (int, error) {
+	return decompress(in, decompressedLen, dest, 0)
+}
+
+// DecompressWithDictionary Decompress at least decompressedLen bytes into dest, after the
+// dictionary that is held by dest[:dictLen]. Returns the number of bytes decompressed after the
+// dictionary.
+// See Also: CompressWithDictionary
+func DecompressWithDictionary(in io.ByteReader, decompressedLen int, dest []byte, dictLen int) (int, error) {
+	end, err := decompress(in, dictLen+decompressedLen, dest, dictLen)
+	if err != nil {
+		return 0, err
+	}
+	return end - dictLen, nil
+}
+
+// decompress decompresses into dest from dOff until at least destEnd, and returns the offset in
+// dest where it stopped. The bytes before dOff may be referenced by matches.
+func decompress(in io.ByteReader, destEnd int, dest []byte, dOff int) (int, error) {
+	reader, ok := in.(io.Reader)
+	if !ok {
+		return 0, errors.New("lz4: input must implement io.Reader")
+	}
+
+	// like Lucene, the first token is read even if nothing needs to be decompressed
+	for {
+		// literals
+		token, err := in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		literalLen := int(token >> 4)
+
+		if literalLen != 0 {
+			if literalLen == 0x0F {
+				n, err := readLength(in)
+				if err != nil {
+					return 0, err
+				}
+				literalLen += n
+			}
+			if dOff+literalLen > len(dest) {
+				return 0, ErrMalformedInput
+			}
+			if _, err := io.ReadFull(reader, dest[dOff:dOff+literalLen]); err != nil {
+				return 0, err
+			}
+			dOff += literalLen
+		}
+
+		if dOff >= destEnd {
+			break
+		}
+
+		// matches
+		b1, err := in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		b2, err := in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		matchDec := int(b1) | int(b2)<<8
+
+		matchLen := int(token & 0x0F)
+		if matchLen == 0x0F {
+			n, err := readLength(in)
+			if err != nil {
+				return 0, err
+			}
+			matchLen += n
+		}
+		matchLen += MIN_MATCH
+
+		ref := dOff - matchDec
+		if ref < 0 || matchDec == 0 || dOff+matchLen > len(dest) {
+			return 0, ErrMalformedInput
+		}
+		if matchDec >= matchLen {
+			copy(dest[dOff:dOff+matchLen], dest[ref:ref+matchLen])
+		} else {
+			// overlapping copy, byte by byte
+			for i := 0; i < matchLen; i++ {
+				dest[dOff+i] = dest[ref+i]
+			}
+		}
+		dOff += matchLen
+		if dOff >= destEnd {
+			break
+		}
+	}
+	return dOff, nil
+}
+
+func readLength(in io.ByteReader) (int, error) {
+	length := 0
+	for {
+		b, err := in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length += int(b)
+		if b != 0xFF {
+			return length, nil
+		}
+	}
+}
+
+// Writer is the destination of the compressed bytes.
+type Writer interface {
+	io.Writer
+	io.ByteWriter
+}
+
+func encodeLen(l int, out Writer) error {
+	for l >= 0xFF {
+		if err := out.WriteByte(0xFF); err != nil {
+			return err
+		}
+		l -= 0xFF
+	}
+	return out.WriteByte(byte(l))
+}
+
+func encodeLiterals(bytes []byte, token byte, anchor, literalLen int, out Writer) error {
+	if err := out.WriteByte(token); err != nil {
+		return err
+	}
+
+	// encode literal length
+	if literalLen >= 0x0F {
+		if err := encodeLen(literalLen-0x0F, out); err != nil {
+			return err
+		}
+	}
+
+	// encode literals
+	_, err := out.Write(bytes[anchor : anchor+literalLen])
+	return err
+}
+
+func encodeLastLiterals(bytes []byte, anchor, literalLen int, out Writer) error {
+	token := byte(min(literalLen, 0x0F) << 4)
+	return encodeLiterals(bytes, token, anchor, literalLen, out)
+}
+
+func encodeSequence(bytes []byte, anchor, matchRef, matchOff, matchLen int, out Writer) error {
+	literalLen := matchOff - anchor
+	// encode token
+	token := byte(min(literalLen, 0x0F)<<4 | min(matchLen-4, 0x0F))
+	if err := encodeLiterals(bytes, token, anchor, literalLen, out); err != nil {
+		return err
+	}
+
+	// encode match dec
+	matchDec := matchOff - matchRef
+	if err := out.WriteByte(byte(matchDec)); err != nil {
+		return err
+	}
+	if err := out.WriteByte(byte(matchDec >> 8)); err != nil {
+		return err
+	}
+
+	// encode match len
+	if matchLen >= MIN_MATCH+0x0F {
+		return encodeLen(matchLen-0x0F-MIN_MATCH, out)
+	}
+	return nil
+}
+
+// HashTable A record of previous occurrences of sequences of 4 bytes.
+type HashTable interface {
+	// Reset the hash table so that it is ready to compress the given byte[] slice.
+	Reset(bytes []byte)
+
+	// Get Advance the cursor to off and return an index that stored the same 4 bytes as
+	// bytes[off:off+4]. This may only be called on strictly increasing sequences of offsets.
+	// A return value of -1 indicates that no other index could be found.
+	Get(off int) int
+}
+
+var _ HashTable = &FastCompressionHashTable{}
+
+// FastCompressionHashTable Simple lossy HashTable that only stores the last occurrence for each
+// hash on 2^14 bytes of memory.
+type FastCompressionHashTable struct {
+	bytes     []byte
+	hashTable []int32
+}
+
+func NewFastCompressionHashTable() *FastCompressionHashTable {
+	return &FastCompressionHashTable{}
+}
+
+func (h *FastCompressionHashTable) Reset(bytes []byte) {
+	h.bytes = bytes
+	if h.hashTable == nil {
+		h.hashTable = make([]int32, 1<<hashLog)
+	} else {
+		clear(h.hashTable)
+	}
+}
+
+func (h *FastCompressionHashTable) Get(off int) int {
+	v := readInt(h.bytes, off)
+	idx := hash(v, hashLog)
+	ref := int(h.hashTable[idx])
+	h.hashTable[idx] = int32(off)
+	if off-ref < MAX_DISTANCE && readInt(h.bytes, ref) == v {
+		return ref
+	}
+	return -1
+}
+
+// Compress Compress bytes into out using at most 16KB of memory. ht shouldn't be shared across
+// threads but can safely be reused.
+func Compress(bytes []byte, out Writer, ht HashTable) error {
+	off := 0
+	end := len(bytes)
+
+	anchor := off
+	off++
+
+	if len(bytes) > LAST_LITERALS+MIN_MATCH {
+		limit := end - LAST_LITERALS
+		matchLimit := limit - MIN_MATCH
+		ht.Reset(bytes)
+
+	main:
+		for off <= limit {
+			// find a match
+			var ref int
+			for {
+				if off >= matchLimit {
+					break main
+				}
+				ref = ht.Get(off)
+				if ref != -1 {
+					break
+				}
+				off++
+			}
+
+			// compute match length
+			matchLen := MIN_MATCH + commonBytes(bytes, ref+MIN_MATCH, off+MIN_MATCH, limit)
+
+			if err := encodeSequence(bytes, anchor, ref, off, matchLen, out); err != nil {
+				return err
+			}
+			off += matchLen
+			anchor = off
+		}
+	}
+
+	// last literals
+	literalLen := end - anchor
+	return encodeLastLiterals(bytes, anchor, literalLen, out)
+}
+
+// CompressWithDictionary Compress bytes[dictLen:] into out, with bytes[:dictLen] as a dictionary
+// that matches may refer to. The dictionary is not written to out, so the decompressor needs to
+// have the same dictionary in front of its destination.
+// See Also: DecompressWithDictionary
+func CompressWithDictionary(bytes []byte, dictLen int, out Writer, ht HashTable) error {
+	if dictLen == 0 {
+		return Compress(bytes, out, ht)
+	}
+	if dictLen > MAX_DISTANCE {
+		return errors.New("lz4: dictLen must not be greater than 64kB")
+	}
+
+	end := len(bytes)
+	off := dictLen
+	anchor := off
+
+	if end-dictLen > LAST_LITERALS+MIN_MATCH {
+		limit := end - LAST_LITERALS
+		matchLimit := limit - MIN_MATCH
+		ht.Reset(bytes)
+		// record the sequences of the dictionary
+		for i := 0; i < dictLen; i++ {
+			ht.Get(i)
+		}
+
+	main:
+		for off <= limit {
+			// find a match
+			var ref int
+			for {
+				if off >= matchLimit {
+					break main
+				}
+				ref = ht.Get(off)
+				if ref != -1 {
+					break
+				}
+				off++
+			}
+
+			// compute match length
+			matchLen := MIN_MATCH + commonBytes(bytes, ref+MIN_MATCH, off+MIN_MATCH, limit)
+
+			if err := encodeSequence(bytes, anchor, ref, off, matchLen, out); err != nil {
+				return err
+			}
+			off += matchLen
+			anchor = off
+		}
+	}
+
+	// last literals
+	literalLen := end - anchor
+	return encodeLastLiterals(bytes, anchor, literalLen, out)
+}
+
+func hash(i uint32, hashBits int) int {
+	return int((i * 2654435761) >> (32 - hashBits))
+}
+
+func readInt(buf []byte, i int) uint32 {
+	return binary.BigEndian.Uint32(buf[i:])
+}
+
+func commonBytes(b []byte, o1, o2, limit int) int {
+	count := 0
+	for o2 < limit && b[o1] == b[o2] {
+		o1++
+		o2++
+		count++
+	}
+	return count
+}
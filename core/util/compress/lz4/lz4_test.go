@@ -0,0 +1,81 @@
+package lz4
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressDecompress(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+
+	repetitive := make([]byte, 0, 100000)
+	for len(repetitive) < 100000 {
+		repetitive = append(repetitive, []byte("2024-01-01T00:00:00 INFO request served path=/api/v1/items")...)
+		repetitive = append(repetitive, byte('0'+r.Intn(10)))
+	}
+	random := make([]byte, 50000)
+	r.Read(random)
+
+	inputs := map[string][]byte{
+		"empty":      {},
+		"tiny":       []byte("abc"),
+		"runs":       bytes.Repeat([]byte{'a'}, 1000),
+		"repetitive": repetitive,
+		"random":     random,
+	}
+
+	ht := NewFastCompressionHashTable()
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			assert.Nil(t, Compress(input, out, ht))
+			if name == "repetitive" {
+				assert.Less(t, out.Len(), len(input)/4)
+			}
+
+			dest := make([]byte, len(input))
+			n, err := Decompress(bytes.NewReader(out.Bytes()), len(input), dest)
+			assert.Nil(t, err)
+			assert.Equal(t, len(input), n)
+			assert.Equal(t, input, dest)
+
+			// decompressing a prefix stops early but returns at least the requested bytes
+			if len(input) > 100 {
+				prefix := make([]byte, len(input))
+				n, err := Decompress(bytes.NewReader(out.Bytes()), 100, prefix)
+				assert.Nil(t, err)
+				assert.GreaterOrEqual(t, n, 100)
+				assert.Equal(t, input[:n], prefix[:n])
+			}
+		})
+	}
+}
+
+func TestCompressDecompressWithDictionary(t *testing.T) {
+	dict := []byte("2024-01-01T00:00:00 INFO request served path=/api/v1/items ")
+	data := bytes.Repeat([]byte("2024-01-01T00:00:01 INFO request served path=/api/v1/items/42 "), 3)
+	input := append(append([]byte{}, dict...), data...)
+
+	ht := NewFastCompressionHashTable()
+	withDict := new(bytes.Buffer)
+	assert.Nil(t, CompressWithDictionary(input, len(dict), withDict, ht))
+	withoutDict := new(bytes.Buffer)
+	assert.Nil(t, Compress(data, withoutDict, ht))
+	// matches refer to the dictionary, which is not written
+	assert.Less(t, withDict.Len(), withoutDict.Len())
+
+	dest := make([]byte, len(input))
+	copy(dest, dict)
+	n, err := DecompressWithDictionary(bytes.NewReader(withDict.Bytes()), len(data), dest, len(dict))
+	assert.Nil(t, err)
+	assert.Equal(t, len(data), n)
+	assert.Equal(t, input, dest)
+
+	// an empty dictionary is the same as no dictionary
+	noDict := new(bytes.Buffer)
+	assert.Nil(t, CompressWithDictionary(data, 0, noDict, ht))
+	assert.Equal(t, withoutDict.Bytes(), noDict.Bytes())
+}
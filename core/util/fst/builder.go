@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/binary"
 	"math"
-	"slices"
 )
 
 // Builder
@@ -454,12 +453,8 @@ func (b *Builder) AddInts(ctx context.Context, input []int, output Output) error
 	// 如果frontier长度小于输入的长度，进行扩容
 	inputLenPlus1 := len(input) + 1
 	if len(b.frontier) < inputLenPlus1 {
-		frontierSize := len(b.frontier)
-
-		b.frontier = slices.Grow(b.frontier, inputLenPlus1)
-
-		for i := frontierSize; i < inputLenPlus1; i++ {
-			b.frontier[i] = NewUnCompiledNode(b, i)
+		for i := len(b.frontier); i < inputLenPlus1; i++ {
+			b.frontier = append(b.frontier, NewUnCompiledNode(b, i))
 		}
 	}
 
@@ -490,7 +485,7 @@ func (b *Builder) AddInts(ctx context.Context, input []int, output Output) error
 
 		lastOutput := parentNode.GetLastOutput()
 
-		var commonOutputPrefix Output
+		commonOutputPrefix := b.noOutput
 		var wordSuffix Output
 
 		if !lastOutput.IsNoOutput() {
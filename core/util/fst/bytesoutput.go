@@ -0,0 +1,201 @@
+package fst
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+
+	"github.com/geange/lucene-go/core/store"
+)
+
+var _ Output = &BytesOutput{}
+
+// BytesOutput An FST Output where each output is a sequence of bytes.
+type BytesOutput struct {
+	bytes []byte
+}
+
+func NewBytesOutput(bs []byte) *BytesOutput {
+	return &BytesOutput{bytes: bs}
+}
+
+// Bytes Returns the underlying bytes, callers must not modify them.
+func (r *BytesOutput) Bytes() []byte {
+	return r.bytes
+}
+
+func (r *BytesOutput) check(v Output) (*BytesOutput, error) {
+	output, ok := v.(*BytesOutput)
+	if !ok {
+		return nil, errors.New("not *BytesOutput")
+	}
+	return output, nil
+}
+
+// Common Eg common("foobar", "food") -> "foo"
+func (r *BytesOutput) Common(v Output) (Output, error) {
+	output, err := r.check(v)
+	if err != nil {
+		return nil, err
+	}
+
+	pos := 0
+	stop := min(len(r.bytes), len(output.bytes))
+	for pos < stop && r.bytes[pos] == output.bytes[pos] {
+		pos++
+	}
+
+	switch pos {
+	case 0:
+		return &BytesOutput{}, nil
+	case len(r.bytes):
+		return r, nil
+	case len(output.bytes):
+		return output, nil
+	default:
+		return &BytesOutput{bytes: r.bytes[:pos]}, nil
+	}
+}
+
+// Sub Eg sub("foobar", "foo") -> "bar"
+func (r *BytesOutput) Sub(v Output) (Output, error) {
+	output, err := r.check(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(output.bytes) == 0 {
+		return r, nil
+	}
+	if !bytes.HasPrefix(r.bytes, output.bytes) {
+		return nil, errors.New("inc is not a prefix of output")
+	}
+	if len(output.bytes) == len(r.bytes) {
+		return &BytesOutput{}, nil
+	}
+	return &BytesOutput{bytes: r.bytes[len(output.bytes):]}, nil
+}
+
+// Add Eg add("foo", "bar") -> "foobar"
+func (r *BytesOutput) Add(v Output) (Output, error) {
+	output, err := r.check(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(r.bytes) == 0 {
+		return output, nil
+	}
+	if len(output.bytes) == 0 {
+		return r, nil
+	}
+	bs := make([]byte, 0, len(r.bytes)+len(output.bytes))
+	bs = append(bs, r.bytes...)
+	bs = append(bs, output.bytes...)
+	return &BytesOutput{bytes: bs}, nil
+}
+
+func (r *BytesOutput) Merge(v Output) (Output, error) {
+	return nil, errors.New("unsupported operation")
+}
+
+func (r *BytesOutput) IsNoOutput() bool {
+	return len(r.bytes) == 0
+}
+
+func (r *BytesOutput) Equal(v Output) bool {
+	output, err := r.check(v)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(r.bytes, output.bytes)
+}
+
+func (r *BytesOutput) Hash() int64 {
+	result := int64(0)
+	for _, b := range r.bytes {
+		result = 31*result + int64(b)
+	}
+	return result
+}
+
+var _ OutputManager = &BytesOutputManager{}
+
+// BytesOutputManager An FST OutputManager implementation where each output is a sequence of bytes.
+type BytesOutputManager struct {
+	emptyOutput Output
+}
+
+func NewBytesOutputManager() *BytesOutputManager {
+	return &BytesOutputManager{emptyOutput: &BytesOutput{}}
+}
+
+func (b *BytesOutputManager) EmptyOutput() Output {
+	return b.emptyOutput
+}
+
+func (b *BytesOutputManager) New() Output {
+	return &BytesOutput{}
+}
+
+func (b *BytesOutputManager) check(v any) (*BytesOutput, error) {
+	output, ok := v.(*BytesOutput)
+	if !ok {
+		return nil, errors.New("not *BytesOutput")
+	}
+	return output, nil
+}
+
+func (b *BytesOutputManager) Read(ctx context.Context, in store.DataInput, v any) error {
+	output, err := b.check(v)
+	if err != nil {
+		return err
+	}
+	size, err := in.ReadUvarint(ctx)
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		output.bytes = nil
+		return nil
+	}
+	output.bytes = make([]byte, size)
+	_, err = io.ReadFull(in, output.bytes)
+	return err
+}
+
+func (b *BytesOutputManager) SkipOutput(ctx context.Context, in store.DataInput) error {
+	size, err := in.ReadUvarint(ctx)
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		return nil
+	}
+	return in.SkipBytes(ctx, int(size))
+}
+
+func (b *BytesOutputManager) ReadFinalOutput(ctx context.Context, in store.DataInput, v any) error {
+	return b.Read(ctx, in, v)
+}
+
+func (b *BytesOutputManager) SkipFinalOutput(ctx context.Context, in store.DataInput) error {
+	return b.SkipOutput(ctx, in)
+}
+
+func (b *BytesOutputManager) Write(ctx context.Context, out store.DataOutput, v any) error {
+	output, err := b.check(v)
+	if err != nil {
+		return err
+	}
+	if err := out.WriteUvarint(ctx, uint64(len(output.bytes))); err != nil {
+		return err
+	}
+	_, err = out.Write(output.bytes)
+	return err
+}
+
+func (b *BytesOutputManager) WriteFinalOutput(ctx context.Context, out store.DataOutput, v any) error {
+	return b.Write(ctx, out, v)
+}
@@ -0,0 +1,61 @@
+package fst
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/geange/lucene-go/core/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBytesOutput(t *testing.T) {
+	ctx := context.Background()
+	manager := NewBytesOutputManager()
+
+	builder, err := NewBuilder(BYTE1, manager, WithDoShareNonSingletonNodes(false))
+	assert.Nil(t, err)
+
+	keys := make([]string, 0)
+	for i := 0; i < 200; i++ {
+		keys = append(keys, fmt.Sprintf("a-rather-long-common-prefix-%04d", i))
+	}
+
+	assert.Nil(t, builder.AddInts(ctx, nil, NewBytesOutput([]byte("root"))))
+	for i, key := range keys {
+		input := make([]int, len(key))
+		for j := range key {
+			input[j] = int(key[j])
+		}
+		output := NewBytesOutput([]byte(fmt.Sprintf("out%d", i%7)))
+		assert.Nil(t, builder.AddInts(ctx, input, output))
+	}
+
+	fst, err := builder.Finish(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("root"), fst.GetEmptyOutput().(*BytesOutput).Bytes())
+
+	metaOut := store.NewBufferDataOutput()
+	dataOut := store.NewBufferDataOutput()
+	assert.Nil(t, fst.Save(ctx, metaOut, dataOut))
+
+	loaded, err := NewFstV1(ctx, manager, store.NewBytesInput(metaOut.Bytes()), store.NewBytesInput(dataOut.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("root"), loaded.GetEmptyOutput().(*BytesOutput).Bytes())
+
+	fstEnum, err := NewEnum[byte](loaded)
+	assert.Nil(t, err)
+	next, err := fstEnum.Next(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(next.GetInput()))
+	assert.Equal(t, []byte("root"), next.GetOutput().(*BytesOutput).Bytes())
+	for i, key := range keys {
+		next, err := fstEnum.Next(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, key, string(next.GetInput()))
+		assert.Equal(t, fmt.Sprintf("out%d", i%7), string(next.GetOutput().(*BytesOutput).Bytes()))
+	}
+	next, err = fstEnum.Next(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, next)
+}
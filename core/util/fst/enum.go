@@ -2,7 +2,6 @@ package fst
 
 import (
 	"context"
-	"slices"
 )
 
 // enum Can next() and advance() through the terms in an FST
@@ -835,8 +834,12 @@ func (r *enum) getArc(idx int) *Arc {
 func (r *enum) incr(lm LabelManager) {
 	r.upto++
 	lm.Grow()
-	r.arcs = slices.Grow(r.arcs, r.upto+1)
-	r.output = slices.Grow(r.output, r.upto+1)
+	for len(r.arcs) < r.upto+1 {
+		r.arcs = append(r.arcs, &Arc{})
+	}
+	for len(r.output) < r.upto+1 {
+		r.output = append(r.output, nil)
+	}
 }
 
 type AbsEnum interface {
@@ -973,5 +976,7 @@ func (b *Enum[T]) SetCurrentLabel(label int) error {
 }
 
 func (b *Enum[T]) Grow() {
-	b.current = slices.Grow(b.current, b.enum.GetUpTo()+1)
+	if size := b.enum.GetUpTo() + 1; len(b.current) < size {
+		b.current = append(b.current, make([]T, size-len(b.current))...)
+	}
 }
@@ -156,6 +156,12 @@ func (f *FST) SetEmptyOutput(output Output) error {
 	return nil
 }
 
+// GetEmptyOutput Returns the output for the empty input, or the no-output value if the FST does not
+// accept the empty input.
+func (f *FST) GetEmptyOutput() Output {
+	return f.emptyOutput
+}
+
 func (f *FST) Save(ctx context.Context, metaOut store.DataOutput, out store.DataOutput) error {
 	if f.startNode == -1 {
 		return errors.New("call finish first")
@@ -376,7 +376,7 @@ func NewPostingOutputManager() *PostingOutputManager {
 }
 
 func (p *PostingOutputManager) EmptyOutput() Output {
-	if p.emptyOutput != nil {
+	if p.emptyOutput == nil {
 		p.emptyOutput = p.New()
 	}
 	return p.emptyOutput
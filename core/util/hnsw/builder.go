@@ -0,0 +1,215 @@
+package hnsw
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+)
+
+const (
+	// DEFAULT_MAX_CONN Default number of maximum connections per node
+	DEFAULT_MAX_CONN = 16
+
+	// DEFAULT_BEAM_WIDTH Default number of the size of the queue maintained while searching during a
+	// graph construction.
+	DEFAULT_BEAM_WIDTH = 100
+
+	// DEFAULT_RAND_SEED Default random seed for level generation
+	DEFAULT_RAND_SEED = 42
+)
+
+// GraphBuilder
+// Builder for HNSW graph. See Graph for a gloss on the algorithm and the meaning of the
+// hyperparameters.
+type GraphBuilder struct {
+	maxConn            int
+	beamWidth          int
+	ml                 float64
+	random             *rand.Rand
+	similarityFunction document.VectorSimilarityFunction
+	graph              *OnHeapGraph
+	searcher           *graphSearcher
+
+	// vectors and buildVectors are two independent views over the same vectors: one is used to
+	// navigate the graph, the other to check the diversity of the neighbors
+	vectors      index.RandomAccessVectorValues
+	buildVectors index.RandomAccessVectorValues
+}
+
+// NewGraphBuilder
+// Reads all the vectors from a VectorValues, builds a graph connecting them by their dense
+// ordinals, using the given hyperparameter settings, and returns the resulting graph.
+// vectors: the vectors whose relations are represented by the graph - must provide a different
+// view over those vectors than the one used to add via AddGraphNode.
+// maxConn: the number of connections to make when adding a new graph node; roughly speaking the
+// graph fanout.
+// beamWidth: the size of the beam search to use when finding nearest neighbors.
+// seed: the seed for a random number generator used during graph construction. Provide this to
+// ensure repeatable construction.
+func NewGraphBuilder(vectors index.RandomAccessVectorValuesProducer,
+	similarityFunction document.VectorSimilarityFunction, maxConn, beamWidth int, seed int64) (*GraphBuilder, error) {
+
+	if maxConn <= 0 {
+		return nil, errors.New("maxConn must be positive")
+	}
+	if beamWidth <= 0 {
+		return nil, errors.New("beamWidth must be positive")
+	}
+
+	values, err := vectors.RandomAccess()
+	if err != nil {
+		return nil, err
+	}
+	buildVectors, err := vectors.RandomAccess()
+	if err != nil {
+		return nil, err
+	}
+
+	ml := 0.0
+	if maxConn > 1 {
+		ml = 1 / math.Log(float64(maxConn))
+	}
+
+	return &GraphBuilder{
+		maxConn:            maxConn,
+		beamWidth:          beamWidth,
+		ml:                 ml,
+		random:             rand.New(rand.NewSource(seed)),
+		similarityFunction: similarityFunction,
+		graph:              NewOnHeapGraph(maxConn),
+		searcher:           newGraphSearcher(similarityFunction, values.Size()),
+		vectors:            values,
+		buildVectors:       buildVectors,
+	}, nil
+}
+
+// Build
+// Adds every vector of the given values to the graph, in ordinal order, and returns the graph.
+// vectors: the vectors for which to build a nearest neighbors graph. Must be an independent
+// accessor for the vectors
+func (b *GraphBuilder) Build(vectors index.RandomAccessVectorValues) (*OnHeapGraph, error) {
+	for node := 0; node < vectors.Size(); node++ {
+		value, err := vectors.VectorValueByOrd(node)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.AddGraphNode(node, value); err != nil {
+			return nil, err
+		}
+	}
+	return b.graph, nil
+}
+
+// AddGraphNode
+// Inserts a doc with vector value to the graph
+func (b *GraphBuilder) AddGraphNode(node int, value []float32) error {
+	nodeLevel := b.randomLevel()
+	curMaxLevel := b.graph.NumLevels() - 1
+
+	if curMaxLevel < 0 {
+		// the first node of the graph is the entry point of every level
+		for level := 0; level <= nodeLevel; level++ {
+			if err := b.graph.AddNode(level, node); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	eps := []int{b.graph.EntryNode()}
+
+	// if a node introduces new levels to the graph, add these new levels
+	for level := curMaxLevel + 1; level <= nodeLevel; level++ {
+		if err := b.graph.AddNode(level, node); err != nil {
+			return err
+		}
+	}
+
+	// for levels > nodeLevel search with topk = 1
+	for level := curMaxLevel; level > nodeLevel; level-- {
+		candidates, err := b.searcher.searchLevel(value, 1, level, eps, b.vectors, b.graph, nil)
+		if err != nil {
+			return err
+		}
+		eps = []int{candidates.Pop()}
+	}
+
+	// for levels <= nodeLevel search with topk = beamWidth, and add connections
+	for level := min(nodeLevel, curMaxLevel); level >= 0; level-- {
+		candidates, err := b.searcher.searchLevel(value, b.beamWidth, level, eps, b.vectors, b.graph, nil)
+		if err != nil {
+			return err
+		}
+		eps = candidates.Nodes()
+		if err := b.graph.AddNode(level, node); err != nil {
+			return err
+		}
+		if err := b.addDiverseNeighbors(level, node, candidates); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *GraphBuilder) randomLevel() int {
+	// 1 - Float64() is in (0, 1], so its logarithm is finite
+	return int(-math.Log(1-b.random.Float64()) * b.ml)
+}
+
+// addDiverseNeighbors
+// Connects the node to the most similar candidates that are closer to the node than to any
+// already selected neighbor, then adds the reverse links.
+func (b *GraphBuilder) addDiverseNeighbors(level, node int, candidates *NeighborQueue) error {
+	// pop the candidates: the least similar one comes first
+	size := candidates.Size()
+	nodes := make([]int, size)
+	scores := make([]float32, size)
+	for i := size - 1; i >= 0; i-- {
+		scores[i] = candidates.TopScore()
+		nodes[i] = candidates.Pop()
+	}
+
+	neighbors := b.graph.GetNeighbors(level, node)
+	for i := 0; i < size && neighbors.Size() < b.maxConn; i++ {
+		diverse, err := b.diversityCheck(nodes[i], scores[i], neighbors)
+		if err != nil {
+			return err
+		}
+		if diverse {
+			neighbors.Add(nodes[i], scores[i])
+		}
+	}
+
+	// add the reverse links, dropping the least similar neighbor when a node has too many
+	for i := 0; i < neighbors.Size(); i++ {
+		nbr := b.graph.GetNeighbors(level, neighbors.Node(i))
+		nbr.Add(node, neighbors.Score(i))
+		if nbr.Size() > b.maxConn {
+			nbr.RemoveLast()
+		}
+	}
+	return nil
+}
+
+// diversityCheck
+// Returns true if the candidate is more similar to the node being added than to any of the
+// already selected neighbors.
+func (b *GraphBuilder) diversityCheck(candidate int, score float32, neighbors *NeighborArray) (bool, error) {
+	candidateVector, err := b.vectors.VectorValueByOrd(candidate)
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i < neighbors.Size(); i++ {
+		neighborVector, err := b.buildVectors.VectorValueByOrd(neighbors.Node(i))
+		if err != nil {
+			return false, err
+		}
+		if b.similarityFunction.Compare(candidateVector, neighborVector) >= score {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,178 @@
+package hnsw
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Graph
+// Hierarchical Navigable Small World graph. Provides efficient approximate nearest neighbor search
+// for high dimensional vectors. See Efficient and robust approximate nearest neighbor search using
+// Hierarchical Navigable Small World graphs [2018] paper for details.
+//
+// The nomenclature is a bit different here from what's used in the paper:
+//
+// Hyperparameters
+//   - beamWidth in GraphBuilder has the same meaning as efConst in the paper. It is the number of
+//     nearest neighbor candidates to track while searching the graph for each newly inserted node.
+//   - maxConn has the same meaning as M in the paper; it controls how many of the efConst neighbors
+//     are connected to the new node
+//
+// Note: The graph may be searched by multiple threads concurrently, but updates are not thread-safe.
+type Graph interface {
+
+	// Size
+	// Returns the number of nodes in the graph
+	Size() int
+
+	// NumLevels
+	// Returns the number of levels of the graph
+	NumLevels() int
+
+	// EntryNode
+	// Returns graph's entry point on the top level, or -1 if the graph is empty
+	EntryNode() int
+
+	// Neighbors
+	// Returns the neighbors of the node on the given level. The returned slice must not be modified.
+	Neighbors(level, node int) ([]int, error)
+
+	// NodesOnLevel
+	// Returns the nodes on the given level in increasing order. Level 0 holds every node of the graph.
+	NodesOnLevel(level int) ([]int, error)
+}
+
+var _ Graph = &OnHeapGraph{}
+
+// OnHeapGraph
+// An Graph where all nodes and connections are held in memory. This class is used to construct
+// the HNSW graph before it's written to the index.
+type OnHeapGraph struct {
+	maxConn   int
+	entryNode int
+
+	// levels[level] holds the neighbors of each node of the level
+	levels []map[int]*NeighborArray
+
+	// nodesByLevel[level] holds the nodes of the level, in the order they were added
+	nodesByLevel [][]int
+}
+
+func NewOnHeapGraph(maxConn int) *OnHeapGraph {
+	return &OnHeapGraph{
+		maxConn:   maxConn,
+		entryNode: -1,
+	}
+}
+
+// AddNode
+// Add node on the given level. A node must be added on level 0 before it is added on the upper
+// levels, and the levels must be added in increasing order.
+func (g *OnHeapGraph) AddNode(level, node int) error {
+	if level > len(g.levels) {
+		return fmt.Errorf("level %d added before level %d", level, len(g.levels))
+	}
+	if level == len(g.levels) {
+		g.levels = append(g.levels, make(map[int]*NeighborArray))
+		g.nodesByLevel = append(g.nodesByLevel, nil)
+		// a node on a new top level becomes the entry point of the graph
+		g.entryNode = node
+	}
+	if _, ok := g.levels[level][node]; ok {
+		return fmt.Errorf("node %d already exists on level %d", node, level)
+	}
+	g.levels[level][node] = NewNeighborArray(g.maxConn + 1)
+	g.nodesByLevel[level] = append(g.nodesByLevel[level], node)
+	return nil
+}
+
+// GetNeighbors
+// Returns the NeighborArray connected to the given node, or nil if the node is not on the level.
+func (g *OnHeapGraph) GetNeighbors(level, node int) *NeighborArray {
+	if level >= len(g.levels) {
+		return nil
+	}
+	return g.levels[level][node]
+}
+
+func (g *OnHeapGraph) Size() int {
+	if len(g.nodesByLevel) == 0 {
+		return 0
+	}
+	return len(g.nodesByLevel[0])
+}
+
+func (g *OnHeapGraph) NumLevels() int {
+	return len(g.levels)
+}
+
+func (g *OnHeapGraph) EntryNode() int {
+	return g.entryNode
+}
+
+func (g *OnHeapGraph) Neighbors(level, node int) ([]int, error) {
+	neighbors := g.GetNeighbors(level, node)
+	if neighbors == nil {
+		return nil, fmt.Errorf("node %d is not on level %d", node, level)
+	}
+	return neighbors.nodes, nil
+}
+
+func (g *OnHeapGraph) NodesOnLevel(level int) ([]int, error) {
+	if level >= len(g.nodesByLevel) {
+		return nil, fmt.Errorf("level %d does not exist", level)
+	}
+	nodes := make([]int, len(g.nodesByLevel[level]))
+	copy(nodes, g.nodesByLevel[level])
+	sort.Ints(nodes)
+	return nodes, nil
+}
+
+// NeighborArray
+// The neighbors of a graph node with their scores, sorted from the most to the least similar.
+type NeighborArray struct {
+	nodes  []int
+	scores []float32
+}
+
+func NewNeighborArray(maxSize int) *NeighborArray {
+	return &NeighborArray{
+		nodes:  make([]int, 0, maxSize),
+		scores: make([]float32, 0, maxSize),
+	}
+}
+
+// Add a neighbor, keeping the neighbors sorted by descending score
+func (a *NeighborArray) Add(node int, score float32) {
+	idx := sort.Search(len(a.scores), func(i int) bool {
+		return a.scores[i] < score
+	})
+	a.nodes = append(a.nodes, 0)
+	a.scores = append(a.scores, 0)
+	copy(a.nodes[idx+1:], a.nodes[idx:])
+	copy(a.scores[idx+1:], a.scores[idx:])
+	a.nodes[idx] = node
+	a.scores[idx] = score
+}
+
+// RemoveLast Removes the least similar neighbor
+func (a *NeighborArray) RemoveLast() {
+	a.nodes = a.nodes[:len(a.nodes)-1]
+	a.scores = a.scores[:len(a.scores)-1]
+}
+
+func (a *NeighborArray) Size() int {
+	return len(a.nodes)
+}
+
+func (a *NeighborArray) Node(i int) int {
+	return a.nodes[i]
+}
+
+func (a *NeighborArray) Score(i int) float32 {
+	return a.scores[i]
+}
+
+func (a *NeighborArray) Nodes() []int {
+	return a.nodes
+}
@@ -0,0 +1,221 @@
+package hnsw
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNeighborQueue(t *testing.T) {
+	minHeap := NewNeighborQueue(4, false)
+	maxHeap := NewNeighborQueue(4, true)
+	for node, score := range []float32{0.5, 0.1, 0.9, 0.5, 0.3} {
+		minHeap.Add(node, score)
+		maxHeap.Add(node, score)
+	}
+
+	// ties are broken in favor of the lower node
+	expected := []int{1, 4, 3, 0, 2}
+	for _, node := range expected {
+		assert.Equal(t, node, minHeap.Pop())
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		assert.Equal(t, expected[i], maxHeap.Pop())
+	}
+
+	results := NewNeighborQueue(2, false)
+	assert.True(t, results.InsertWithOverflow(0, 0.2, 2))
+	assert.True(t, results.InsertWithOverflow(1, 0.4, 2))
+	assert.True(t, results.InsertWithOverflow(2, 0.6, 2))
+	assert.False(t, results.InsertWithOverflow(3, 0.1, 2))
+	assert.Equal(t, 2, results.Size())
+	assert.Equal(t, float32(0.4), results.TopScore())
+	assert.Equal(t, 1, results.TopNode())
+}
+
+func TestGraphBuilder(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	vectors := randomVectors(r, 1000, 16)
+
+	builder, err := NewGraphBuilder(vectors, document.VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN, 8, 50, DEFAULT_RAND_SEED)
+	assert.Nil(t, err)
+	graph, err := builder.Build(vectors.copy())
+	assert.Nil(t, err)
+	assert.Equal(t, 1000, graph.Size())
+	assert.Greater(t, graph.NumLevels(), 1)
+
+	for level := 0; level < graph.NumLevels(); level++ {
+		nodes, err := graph.NodesOnLevel(level)
+		assert.Nil(t, err)
+		assert.True(t, sort.IntsAreSorted(nodes))
+		if level == 0 {
+			assert.Equal(t, 1000, len(nodes))
+		}
+		for _, node := range nodes {
+			neighbors, err := graph.Neighbors(level, node)
+			assert.Nil(t, err)
+			assert.LessOrEqual(t, len(neighbors), 8)
+			assert.NotContains(t, neighbors, node)
+		}
+	}
+
+	// the entry node is on every level
+	for level := 0; level < graph.NumLevels(); level++ {
+		nodes, _ := graph.NodesOnLevel(level)
+		assert.Contains(t, nodes, graph.EntryNode())
+	}
+
+	// the same seed builds the same graph
+	other, err := NewGraphBuilder(vectors, document.VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN, 8, 50, DEFAULT_RAND_SEED)
+	assert.Nil(t, err)
+	otherGraph, err := other.Build(vectors.copy())
+	assert.Nil(t, err)
+	assert.Equal(t, graph.NumLevels(), otherGraph.NumLevels())
+	assert.Equal(t, graph.EntryNode(), otherGraph.EntryNode())
+}
+
+func TestSearchRecall(t *testing.T) {
+	for _, similarityFunction := range []document.VectorSimilarityFunction{
+		document.VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN,
+		document.VECTOR_SIMILARITY_FUNCTION_DOT_PRODUCT,
+		document.VECTOR_SIMILARITY_FUNCTION_COSINE,
+	} {
+		r := rand.New(rand.NewSource(11))
+		vectors := randomVectors(r, 2000, 8)
+		if similarityFunction == document.VECTOR_SIMILARITY_FUNCTION_DOT_PRODUCT {
+			for _, v := range vectors.vectors {
+				util.L2Normalize(v)
+			}
+		}
+
+		builder, err := NewGraphBuilder(vectors, similarityFunction, DEFAULT_MAX_CONN, DEFAULT_BEAM_WIDTH, DEFAULT_RAND_SEED)
+		assert.Nil(t, err)
+		graph, err := builder.Build(vectors.copy())
+		assert.Nil(t, err)
+
+		topK, matched, total := 10, 0, 0
+		for i := 0; i < 50; i++ {
+			query := randomVectors(r, 1, 8).vectors[0]
+			if similarityFunction == document.VECTOR_SIMILARITY_FUNCTION_DOT_PRODUCT {
+				util.L2Normalize(query)
+			}
+			results, err := Search(query, topK, vectors.copy(), similarityFunction, graph, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, topK, results.Size())
+			assert.Greater(t, results.VisitedCount(), 0)
+
+			expected := bruteForce(query, topK, vectors, similarityFunction, nil)
+			for _, node := range results.Nodes() {
+				if expected[node] {
+					matched++
+				}
+			}
+			total += topK
+		}
+		recall := float64(matched) / float64(total)
+		assert.Greater(t, recall, 0.9, similarityFunction.String())
+	}
+}
+
+func TestSearchAcceptOrds(t *testing.T) {
+	r := rand.New(rand.NewSource(13))
+	vectors := randomVectors(r, 500, 8)
+
+	builder, err := NewGraphBuilder(vectors, document.VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN, DEFAULT_MAX_CONN, DEFAULT_BEAM_WIDTH, DEFAULT_RAND_SEED)
+	assert.Nil(t, err)
+	graph, err := builder.Build(vectors.copy())
+	assert.Nil(t, err)
+
+	acceptOrds := &evenBits{size: 500}
+	query := randomVectors(r, 1, 8).vectors[0]
+	results, err := Search(query, 20, vectors.copy(), document.VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN, graph, acceptOrds)
+	assert.Nil(t, err)
+	assert.Equal(t, 20, results.Size())
+	for _, node := range results.Nodes() {
+		assert.Equal(t, 0, node%2)
+	}
+
+	// an empty graph returns no results
+	empty := NewOnHeapGraph(DEFAULT_MAX_CONN)
+	results, err = Search(query, 20, vectors.copy(), document.VECTOR_SIMILARITY_FUNCTION_EUCLIDEAN, empty, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, results.Size())
+}
+
+func bruteForce(query []float32, topK int, vectors *testVectors,
+	similarityFunction document.VectorSimilarityFunction, acceptOrds util.Bits) map[int]bool {
+
+	ords := make([]int, 0, len(vectors.vectors))
+	scores := make([]float32, len(vectors.vectors))
+	for i, v := range vectors.vectors {
+		if acceptOrds == nil || acceptOrds.Test(uint(i)) {
+			ords = append(ords, i)
+			scores[i] = similarityFunction.Compare(query, v)
+		}
+	}
+	sort.SliceStable(ords, func(i, j int) bool {
+		return scores[ords[i]] > scores[ords[j]]
+	})
+
+	expected := make(map[int]bool, topK)
+	for _, ord := range ords[:min(topK, len(ords))] {
+		expected[ord] = true
+	}
+	return expected
+}
+
+var _ index.RandomAccessVectorValues = &testVectors{}
+var _ index.RandomAccessVectorValuesProducer = &testVectors{}
+
+type testVectors struct {
+	dimension int
+	vectors   [][]float32
+}
+
+func randomVectors(r *rand.Rand, size, dimension int) *testVectors {
+	vectors := make([][]float32, size)
+	for i := range vectors {
+		vectors[i] = make([]float32, dimension)
+		for j := range vectors[i] {
+			vectors[i][j] = r.Float32()*2 - 1
+		}
+	}
+	return &testVectors{dimension: dimension, vectors: vectors}
+}
+
+func (v *testVectors) copy() *testVectors {
+	return &testVectors{dimension: v.dimension, vectors: v.vectors}
+}
+
+func (v *testVectors) Size() int {
+	return len(v.vectors)
+}
+
+func (v *testVectors) Dimension() int {
+	return v.dimension
+}
+
+func (v *testVectors) VectorValueByOrd(targetOrd int) ([]float32, error) {
+	return v.vectors[targetOrd], nil
+}
+
+func (v *testVectors) RandomAccess() (index.RandomAccessVectorValues, error) {
+	return v.copy(), nil
+}
+
+type evenBits struct {
+	size int
+}
+
+func (e *evenBits) Test(index uint) bool {
+	return index%2 == 0
+}
+
+func (e *evenBits) Len() uint {
+	return uint(e.size)
+}
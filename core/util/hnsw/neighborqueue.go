@@ -0,0 +1,140 @@
+package hnsw
+
+import "container/heap"
+
+// NeighborQueue
+// A heap of graph nodes ordered by their score. A min heap keeps the least similar node on top, so it
+// can be used to collect the best k nodes by evicting the top; a max heap pops the most similar node
+// first, so it can be used to hold the candidates to explore. Ties are broken in favor of the lower
+// node ordinal.
+type NeighborQueue struct {
+	heap *neighborHeap
+
+	// Used to track the number of neighbors visited during a single graph traversal
+	visitedCount int
+}
+
+func NewNeighborQueue(initialSize int, maxHeap bool) *NeighborQueue {
+	return &NeighborQueue{
+		heap: &neighborHeap{
+			items:   make([]neighbor, 0, initialSize),
+			maxHeap: maxHeap,
+		},
+	}
+}
+
+// Size Returns the number of elements in the heap
+func (q *NeighborQueue) Size() int {
+	return q.heap.Len()
+}
+
+// Add a new graph node to the heap.
+func (q *NeighborQueue) Add(node int, score float32) {
+	heap.Push(q.heap, neighbor{node: node, score: score})
+}
+
+// InsertWithOverflow
+// If the heap is not full (size is less than the maxSize), add the new node, otherwise replace
+// the top node when the new node ranks above it. Returns true if the node was added.
+func (q *NeighborQueue) InsertWithOverflow(node int, score float32, maxSize int) bool {
+	if q.Size() < maxSize {
+		q.Add(node, score)
+		return true
+	}
+
+	item := neighbor{node: node, score: score}
+	if !q.heap.before(q.heap.items[0], item) {
+		return false
+	}
+	q.heap.items[0] = item
+	heap.Fix(q.heap, 0)
+	return true
+}
+
+// Pop Removes the top element and returns its node id.
+func (q *NeighborQueue) Pop() int {
+	return heap.Pop(q.heap).(neighbor).node
+}
+
+// TopNode Returns the top element's node id.
+func (q *NeighborQueue) TopNode() int {
+	return q.heap.items[0].node
+}
+
+// TopScore Returns the top element's node score.
+func (q *NeighborQueue) TopScore() float32 {
+	return q.heap.items[0].score
+}
+
+// Nodes Returns the node ids of the heap, in no particular order.
+func (q *NeighborQueue) Nodes() []int {
+	nodes := make([]int, 0, q.Size())
+	for _, item := range q.heap.items {
+		nodes = append(nodes, item.node)
+	}
+	return nodes
+}
+
+// Clear Removes every element of the heap.
+func (q *NeighborQueue) Clear() {
+	q.heap.items = q.heap.items[:0]
+	q.visitedCount = 0
+}
+
+func (q *NeighborQueue) VisitedCount() int {
+	return q.visitedCount
+}
+
+func (q *NeighborQueue) SetVisitedCount(visitedCount int) {
+	q.visitedCount = visitedCount
+}
+
+type neighbor struct {
+	node  int
+	score float32
+}
+
+var _ heap.Interface = &neighborHeap{}
+
+type neighborHeap struct {
+	items   []neighbor
+	maxHeap bool
+}
+
+// before reports whether a is nearer the top of the heap than b
+func (h *neighborHeap) before(a, b neighbor) bool {
+	if h.maxHeap {
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return a.node < b.node
+	}
+
+	if a.score != b.score {
+		return a.score < b.score
+	}
+	return a.node > b.node
+}
+
+func (h *neighborHeap) Len() int {
+	return len(h.items)
+}
+
+func (h *neighborHeap) Less(i, j int) bool {
+	return h.before(h.items[i], h.items[j])
+}
+
+func (h *neighborHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *neighborHeap) Push(x any) {
+	h.items = append(h.items, x.(neighbor))
+}
+
+func (h *neighborHeap) Pop() any {
+	last := len(h.items) - 1
+	item := h.items[last]
+	h.items = h.items[:last]
+	return item
+}
@@ -0,0 +1,176 @@
+package hnsw
+
+import (
+	"math"
+
+	"github.com/geange/lucene-go/core/document"
+	"github.com/geange/lucene-go/core/interface/index"
+	"github.com/geange/lucene-go/core/util"
+)
+
+// Search
+// Searches the graph for the nearest neighbors of a query vector. The search walks greedily down
+// the upper levels of the graph, then explores the bottom level keeping the topK best nodes.
+// query: search query vector
+// topK: the number of nodes to be returned
+// vectors: the vector values
+// similarityFunction: the similarity function to compare vectors
+// graph: the graph values. May represent the entire graph, or a level in a hierarchical graph.
+// acceptOrds: Bits that represents the allowed document ordinals to match, or nil if they are all allowed to match.
+// Returns: a priority queue holding the closest neighbors found, the least similar one on top
+func Search(query []float32, topK int, vectors index.RandomAccessVectorValues,
+	similarityFunction document.VectorSimilarityFunction, graph Graph, acceptOrds util.Bits) (*NeighborQueue, error) {
+
+	results := NewNeighborQueue(topK, false)
+	entryNode := graph.EntryNode()
+	if entryNode == -1 {
+		return results, nil
+	}
+
+	searcher := newGraphSearcher(similarityFunction, graph.Size())
+	eps := []int{entryNode}
+	numVisited := 0
+	for level := graph.NumLevels() - 1; level >= 1; level-- {
+		results, err := searcher.searchLevel(query, 1, level, eps, vectors, graph, nil)
+		if err != nil {
+			return nil, err
+		}
+		numVisited += results.VisitedCount()
+		eps[0] = results.Pop()
+	}
+
+	results, err := searcher.searchLevel(query, topK, 0, eps, vectors, graph, acceptOrds)
+	if err != nil {
+		return nil, err
+	}
+	results.SetVisitedCount(results.VisitedCount() + numVisited)
+	return results, nil
+}
+
+// graphSearcher
+// Searches an HNSW graph to find nearest neighbors to a query vector.
+type graphSearcher struct {
+	similarityFunction document.VectorSimilarityFunction
+
+	// Scratch data structures that are used in each searchLevel call. These can be expensive to
+	// allocate, so they're cleared and reused across calls.
+	candidates *NeighborQueue
+	visited    *visitedSet
+}
+
+func newGraphSearcher(similarityFunction document.VectorSimilarityFunction, numNodes int) *graphSearcher {
+	return &graphSearcher{
+		similarityFunction: similarityFunction,
+		candidates:         NewNeighborQueue(100, true),
+		visited:            newVisitedSet(numNodes),
+	}
+}
+
+// searchLevel
+// Searches for the nearest neighbors of a query vector in a given level, starting from the entry points.
+func (s *graphSearcher) searchLevel(query []float32, topK, level int, eps []int,
+	vectors index.RandomAccessVectorValues, graph Graph, acceptOrds util.Bits) (*NeighborQueue, error) {
+
+	results := NewNeighborQueue(topK, false)
+	s.candidates.Clear()
+	s.visited.clear()
+
+	numVisited := 0
+	for _, ep := range eps {
+		if s.visited.getAndSet(ep) {
+			continue
+		}
+		vector, err := vectors.VectorValueByOrd(ep)
+		if err != nil {
+			return nil, err
+		}
+		score := s.similarityFunction.Compare(query, vector)
+		numVisited++
+		s.candidates.Add(ep, score)
+		if acceptOrds == nil || acceptOrds.Test(uint(ep)) {
+			results.Add(ep, score)
+		}
+	}
+
+	// Set the bound to the worst current result and below reject any newly-generated candidates
+	// failing to exceed this bound
+	minAcceptedSimilarity := float32(math.Inf(-1))
+	if results.Size() >= topK {
+		minAcceptedSimilarity = results.TopScore()
+	}
+
+	for s.candidates.Size() > 0 {
+		// get the best candidate (closest or best scoring)
+		topCandidateScore := s.candidates.TopScore()
+		if topCandidateScore < minAcceptedSimilarity {
+			break
+		}
+
+		topCandidateNode := s.candidates.Pop()
+		friends, err := graph.Neighbors(level, topCandidateNode)
+		if err != nil {
+			return nil, err
+		}
+		for _, friend := range friends {
+			if s.visited.getAndSet(friend) {
+				continue
+			}
+
+			vector, err := vectors.VectorValueByOrd(friend)
+			if err != nil {
+				return nil, err
+			}
+			score := s.similarityFunction.Compare(query, vector)
+			numVisited++
+			if score < minAcceptedSimilarity {
+				continue
+			}
+			s.candidates.Add(friend, score)
+			if acceptOrds == nil || acceptOrds.Test(uint(friend)) {
+				if results.InsertWithOverflow(friend, score, topK) && results.Size() >= topK {
+					minAcceptedSimilarity = results.TopScore()
+				}
+			}
+		}
+	}
+
+	results.SetVisitedCount(numVisited)
+	return results, nil
+}
+
+// visitedSet
+// A set of node ordinals which can be cleared in constant time: a node is in the set when its
+// mark equals the current generation.
+type visitedSet struct {
+	marks      []uint32
+	generation uint32
+}
+
+func newVisitedSet(size int) *visitedSet {
+	return &visitedSet{
+		marks:      make([]uint32, size),
+		generation: 1,
+	}
+}
+
+// getAndSet Adds the node to the set, returns true if it was already in the set
+func (v *visitedSet) getAndSet(node int) bool {
+	if node >= len(v.marks) {
+		marks := make([]uint32, max(node+1, 2*len(v.marks)))
+		copy(marks, v.marks)
+		v.marks = marks
+	}
+	if v.marks[node] == v.generation {
+		return true
+	}
+	v.marks[node] = v.generation
+	return false
+}
+
+func (v *visitedSet) clear() {
+	v.generation++
+	if v.generation == 0 {
+		clear(v.marks)
+		v.generation = 1
+	}
+}
@@ -123,8 +123,16 @@ func (b *BlockPool) Reset(zeroFillBuffers, reuseFirst bool) {
 // to initialize the pool. In contrast to the constructor a reset() call will advance the pool to its first
 // buffer immediately.
 func (b *BlockPool) NextBuffer() {
-	b.buffers = append(b.buffers, b.allocator.GetIntBlock())
-	b.buffer = b.buffers[b.bufferUpto+1]
+	newBlock := b.allocator.GetIntBlock()
+
+	// the buffers released by Reset keep their slots
+	if 1+b.bufferUpto == len(b.buffers) {
+		b.buffers = append(b.buffers, newBlock)
+	} else {
+		b.buffers[1+b.bufferUpto] = newBlock
+	}
+
+	b.buffer = newBlock
 	b.bufferUpto++
 	b.intUpto = 0
 	b.intOffset += INT_BLOCK_SIZE
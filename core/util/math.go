@@ -74,3 +74,23 @@ func NumberOfLeadingZeros(i int32) int {
 	}
 	return n - int(i>>1)
 }
+
+// Gcd
+// Return the greatest common divisor of a and b, consistently with big.Int.GCD.
+// NOTE: A greatest common divisor must be positive, but 2^64 cannot be expressed as a long although
+// it is the GCD of math.MinInt64 and 0 and the GCD of math.MinInt64 and math.MinInt64. So in these
+// 2 cases, and only them, this method will return math.MinInt64.
+func Gcd(a, b int64) int64 {
+	x, y := absUint64(a), absUint64(b)
+	for y != 0 {
+		x, y = y, x%y
+	}
+	return int64(x)
+}
+
+func absUint64(v int64) uint64 {
+	if v < 0 {
+		return uint64(-v)
+	}
+	return uint64(v)
+}
@@ -0,0 +1,16 @@
+package util
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGcd(t *testing.T) {
+	assert.EqualValues(t, 6, Gcd(12, 18))
+	assert.EqualValues(t, 6, Gcd(-12, 18))
+	assert.EqualValues(t, 7, Gcd(0, 7))
+	assert.EqualValues(t, 1, Gcd(17, 5))
+	assert.EqualValues(t, math.MinInt64, Gcd(math.MinInt64, 0))
+}
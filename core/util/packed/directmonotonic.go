@@ -0,0 +1,261 @@
+package packed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/geange/lucene-go/core/store"
+)
+
+const (
+	DIRECT_MONOTONIC_MIN_BLOCK_SHIFT = 2
+	DIRECT_MONOTONIC_MAX_BLOCK_SHIFT = 22
+)
+
+// DirectMonotonicWriter
+// Write monotonically-increasing sequences of integers. This writer splits data into blocks and then for each
+// block, computes the average slope, the minimum value and only encode the delta from the expected value
+// using a DirectWriter.
+// See Also: DirectMonotonicReader
+// lucene.internal
+type DirectMonotonicWriter struct {
+	meta            store.IndexOutput
+	data            store.IndexOutput
+	numValues       int64
+	baseDataPointer int64
+	buffer          []int64
+	bufferSize      int
+	count           int64
+	finished        bool
+	previous        int64
+}
+
+// NewDirectMonotonicWriter
+// Returns an instance suitable for encoding numValues into monotonic blocks of 2^blockShift values.
+// Metadata will be written to metaOut and actual data to dataOut.
+func NewDirectMonotonicWriter(metaOut, dataOut store.IndexOutput, numValues int64, blockShift int) (*DirectMonotonicWriter, error) {
+	if blockShift < DIRECT_MONOTONIC_MIN_BLOCK_SHIFT || blockShift > DIRECT_MONOTONIC_MAX_BLOCK_SHIFT {
+		return nil, fmt.Errorf("blockShift must be in [%d-%d], got %d",
+			DIRECT_MONOTONIC_MIN_BLOCK_SHIFT, DIRECT_MONOTONIC_MAX_BLOCK_SHIFT, blockShift)
+	}
+	if numValues < 0 {
+		return nil, fmt.Errorf("numValues can't be negative, got %d", numValues)
+	}
+
+	blockSize := int64(1) << blockShift
+	return &DirectMonotonicWriter{
+		meta:            metaOut,
+		data:            dataOut,
+		numValues:       numValues,
+		baseDataPointer: dataOut.GetFilePointer(),
+		buffer:          make([]int64, min(numValues, blockSize)),
+		previous:        math.MinInt64,
+	}, nil
+}
+
+func (d *DirectMonotonicWriter) flush(ctx context.Context) error {
+	buffer := d.buffer[:d.bufferSize]
+
+	avgInc := float32(float64(buffer[len(buffer)-1]-buffer[0]) / float64(max(1, len(buffer)-1)))
+	for i := range buffer {
+		buffer[i] -= int64(avgInc * float32(i))
+	}
+
+	minValue := buffer[0]
+	for _, v := range buffer[1:] {
+		minValue = min(minValue, v)
+	}
+
+	maxDelta := int64(0)
+	for i := range buffer {
+		buffer[i] -= minValue
+		// use | will change nothing when it comes to computing required bits
+		// but has the benefit of working fine with negative values too
+		// (in case of overflow)
+		maxDelta |= buffer[i]
+	}
+
+	if err := d.meta.WriteUint64(ctx, uint64(minValue)); err != nil {
+		return err
+	}
+	if err := d.meta.WriteUint32(ctx, math.Float32bits(avgInc)); err != nil {
+		return err
+	}
+	if err := d.meta.WriteUint64(ctx, uint64(d.data.GetFilePointer()-d.baseDataPointer)); err != nil {
+		return err
+	}
+
+	if maxDelta == 0 {
+		if err := d.meta.WriteByte(0); err != nil {
+			return err
+		}
+	} else {
+		bitsRequired := DirectUnsignedBitsRequired(uint64(maxDelta))
+		writer, err := NewDirectWriter(d.data, len(buffer), bitsRequired)
+		if err != nil {
+			return err
+		}
+		for _, v := range buffer {
+			if err := writer.Add(uint64(v)); err != nil {
+				return err
+			}
+		}
+		if err := writer.Finish(); err != nil {
+			return err
+		}
+		if err := d.meta.WriteByte(byte(bitsRequired)); err != nil {
+			return err
+		}
+	}
+	d.bufferSize = 0
+	return nil
+}
+
+// Add
+// Write a new value. Note that data might not make it to storage until Finish() is called.
+// Returns an error if values don't come in order
+func (d *DirectMonotonicWriter) Add(ctx context.Context, v int64) error {
+	if v < d.previous {
+		return fmt.Errorf("values do not come in order: %d, %d", d.previous, v)
+	}
+	if d.count >= d.numValues {
+		return errors.New("writing past end of stream")
+	}
+
+	if d.bufferSize == len(d.buffer) {
+		if err := d.flush(ctx); err != nil {
+			return err
+		}
+	}
+	d.buffer[d.bufferSize] = v
+	d.bufferSize++
+	d.previous = v
+	d.count++
+	return nil
+}
+
+// Finish
+// This must be called exactly once after all values have been added.
+func (d *DirectMonotonicWriter) Finish(ctx context.Context) error {
+	if d.count != d.numValues {
+		return fmt.Errorf("wrong number of values added, expected: %d, got: %d", d.numValues, d.count)
+	}
+	if d.finished {
+		return errors.New("finish has been called already")
+	}
+	if d.bufferSize > 0 {
+		if err := d.flush(ctx); err != nil {
+			return err
+		}
+	}
+	d.finished = true
+	return nil
+}
+
+// DirectMonotonicMeta
+// In-memory metadata that needs to be kept around for DirectMonotonicReader to read data from disk.
+type DirectMonotonicMeta struct {
+	blockShift int
+	numBlocks  int
+	mins       []int64
+	avgs       []float32
+	bpvs       []byte
+	offsets    []int64
+}
+
+// LoadDirectMonotonicMeta
+// Load metadata from the given DataInput.
+// See Also: NewDirectMonotonicWriter
+func LoadDirectMonotonicMeta(ctx context.Context, metaIn store.DataInput, numValues int64, blockShift int) (*DirectMonotonicMeta, error) {
+	numBlocks := int64(0)
+	if numValues > 0 {
+		numBlocks = (numValues-1)>>blockShift + 1
+	}
+
+	meta := &DirectMonotonicMeta{
+		blockShift: blockShift,
+		numBlocks:  int(numBlocks),
+		mins:       make([]int64, numBlocks),
+		avgs:       make([]float32, numBlocks),
+		bpvs:       make([]byte, numBlocks),
+		offsets:    make([]int64, numBlocks),
+	}
+	for i := 0; i < meta.numBlocks; i++ {
+		minValue, err := metaIn.ReadUint64(ctx)
+		if err != nil {
+			return nil, err
+		}
+		avg, err := metaIn.ReadUint32(ctx)
+		if err != nil {
+			return nil, err
+		}
+		offset, err := metaIn.ReadUint64(ctx)
+		if err != nil {
+			return nil, err
+		}
+		bpv, err := metaIn.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		meta.mins[i] = int64(minValue)
+		meta.avgs[i] = math.Float32frombits(avg)
+		meta.offsets[i] = int64(offset)
+		meta.bpvs[i] = bpv
+	}
+	return meta, nil
+}
+
+var _ LongValuesReader = &DirectMonotonicReader{}
+
+// DirectMonotonicReader
+// Retrieves an instance previously written by DirectMonotonicWriter.
+type DirectMonotonicReader struct {
+	blockShift int
+	blockMask  int64
+	readers    []LongValuesReader
+	mins       []int64
+	avgs       []float32
+}
+
+// NewDirectMonotonicReader
+// Retrieves an instance from the specified slice.
+func NewDirectMonotonicReader(meta *DirectMonotonicMeta, data store.RandomAccessInput) (*DirectMonotonicReader, error) {
+	readers := make([]LongValuesReader, meta.numBlocks)
+	for i := range readers {
+		if meta.bpvs[i] == 0 {
+			readers[i] = zeroValues{}
+			continue
+		}
+		reader, err := NewDirectReader().GetInstance(data, int(meta.bpvs[i]), meta.offsets[i])
+		if err != nil {
+			return nil, err
+		}
+		readers[i] = reader
+	}
+
+	return &DirectMonotonicReader{
+		blockShift: meta.blockShift,
+		blockMask:  int64(1)<<meta.blockShift - 1,
+		readers:    readers,
+		mins:       meta.mins,
+		avgs:       meta.avgs,
+	}, nil
+}
+
+func (d *DirectMonotonicReader) Get(index int64) (uint64, error) {
+	block := index >> d.blockShift
+	blockIndex := index & d.blockMask
+	delta, err := d.readers[block].Get(blockIndex)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(d.mins[block] + int64(d.avgs[block]*float32(blockIndex)) + int64(delta)), nil
+}
+
+type zeroValues struct{}
+
+func (zeroValues) Get(index int64) (uint64, error) {
+	return 0, nil
+}
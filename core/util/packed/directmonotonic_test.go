@@ -0,0 +1,75 @@
+package packed
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/geange/lucene-go/core/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectMonotonic(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+
+	testDirectMonotonic(t, []int64{}, 4)
+	testDirectMonotonic(t, []int64{42}, 4)
+	testDirectMonotonic(t, []int64{3, 3, 3, 3, 3, 3, 3, 3, 3, 3}, 2)
+
+	// exactly linear: every block is stored with 0 bits per value
+	linear := make([]int64, 1000)
+	for i := range linear {
+		linear[i] = int64(i) * 17
+	}
+	testDirectMonotonic(t, linear, 6)
+
+	for _, blockShift := range []int{2, 5, 10, 16} {
+		values := make([]int64, 2000+r.Intn(3000))
+		v := r.Int63n(1 << 40)
+		for i := range values {
+			values[i] = v
+			if r.Intn(5) != 0 {
+				v += r.Int63n(1 << (r.Intn(30) + 1))
+			}
+		}
+		testDirectMonotonic(t, values, blockShift)
+	}
+}
+
+func testDirectMonotonic(t *testing.T, values []int64, blockShift int) {
+	ctx := context.Background()
+	metaOut := store.NewBufferDataOutput()
+	dataOut := store.NewBufferDataOutput()
+
+	writer, err := NewDirectMonotonicWriter(metaOut, dataOut, int64(len(values)), blockShift)
+	assert.Nil(t, err)
+	for _, v := range values {
+		assert.Nil(t, writer.Add(ctx, v))
+	}
+	assert.Nil(t, writer.Finish(ctx))
+	assert.NotNil(t, writer.Finish(ctx))
+
+	meta, err := LoadDirectMonotonicMeta(ctx, store.NewBytesInput(metaOut.Bytes()), int64(len(values)), blockShift)
+	assert.Nil(t, err)
+	reader, err := NewDirectMonotonicReader(meta, store.NewBytesRandomAccessInput(dataOut.Bytes(), binary.BigEndian))
+	assert.Nil(t, err)
+	for i, expected := range values {
+		actual, err := reader.Get(int64(i))
+		assert.Nil(t, err)
+		assert.Equal(t, expected, int64(actual), "index %d", i)
+	}
+}
+
+func TestDirectMonotonicWriterErrors(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := NewDirectMonotonicWriter(store.NewBufferDataOutput(), store.NewBufferDataOutput(), 10, 1)
+	assert.NotNil(t, err)
+
+	writer, err := NewDirectMonotonicWriter(store.NewBufferDataOutput(), store.NewBufferDataOutput(), 2, 2)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Add(ctx, 5))
+	assert.NotNil(t, writer.Add(ctx, 4))
+	assert.NotNil(t, writer.Finish(ctx))
+}
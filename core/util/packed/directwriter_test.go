@@ -60,3 +60,37 @@ func testDirectRW(t *testing.T, numValues, bitsPerValue int) {
 		assert.EqualValues(t, expectValues[i], v)
 	}
 }
+
+func TestDirectWriterMultipleBuffers(t *testing.T) {
+	for _, bitsPerValue := range []int{1, 4, 12, 20, 40, 64} {
+		numValues := 10000 + rand.Intn(1000)
+		dataOutput := store.NewBufferDataOutput()
+		directWriter, err := NewDirectWriter(dataOutput, numValues, bitsPerValue)
+		assert.Nil(t, err)
+
+		expectValues := make([]uint64, numValues)
+		for i := range expectValues {
+			expectValues[i] = rand.Uint64() >> (64 - bitsPerValue)
+			assert.Nil(t, directWriter.Add(expectValues[i]))
+		}
+		assert.Nil(t, directWriter.Finish())
+
+		// the last, partial buffer must not write more than the values it holds, plus 3 bytes of padding
+		assert.EqualValues(t, FormatPacked.ByteCount(VERSION_CURRENT, numValues, bitsPerValue)+3,
+			len(dataOutput.Bytes()))
+
+		accessInput := store.NewBytesRandomAccessInput(dataOutput.Bytes(), binary.BigEndian)
+		reader, err := NewDirectReader().GetInstance(accessInput, bitsPerValue, 0)
+		assert.Nil(t, err)
+		for i, expected := range expectValues {
+			v, err := reader.Get(int64(i))
+			assert.Nil(t, err)
+			assert.Equal(t, expected, v)
+		}
+	}
+
+	_, err := NewDirectWriter(store.NewBufferDataOutput(), 10, 3)
+	assert.NotNil(t, err)
+	assert.Equal(t, 4, DirectUnsignedBitsRequired(9))
+	assert.Equal(t, 40, DirectUnsignedBitsRequired(1<<33))
+}
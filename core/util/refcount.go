@@ -11,9 +11,13 @@ type RefCount[T io.Closer] struct {
 	release  func(r *RefCount[T]) error
 }
 
+// NewRefCount
+// The reference count starts at 1, it is held by the creator of the object.
 func NewRefCount[T io.Closer](object T, release func(r *RefCount[T]) error) *RefCount[T] {
+	refCount := new(atomic.Int32)
+	refCount.Store(1)
 	return &RefCount[T]{
-		refCount: new(atomic.Int32),
+		refCount: refCount,
 		object:   object,
 		release:  release,
 	}
@@ -1,10 +1,12 @@
 package util
 
 import (
+	"encoding/binary"
 	"errors"
 	"github.com/geange/lucene-go/core/util/array"
 	"golang.org/x/exp/rand"
 	"math/big"
+	"math/bits"
 	"sync"
 )
 
@@ -16,6 +18,71 @@ func BytesDifference(priorTerm, currentTerm []byte) (int, error) {
 	return mismatch, nil
 }
 
+// SortKeyLength
+// Returns the length of currentTerm needed for use as a sort key. so that
+// bytes.Compare(priorTerm, currentTerm[:length]) < 0.
+// This method assumes currentTerm comes after priorTerm.
+func SortKeyLength(priorTerm, currentTerm []byte) int {
+	limit := min(len(priorTerm), len(currentTerm))
+	for i := 0; i < limit; i++ {
+		if priorTerm[i] != currentTerm[i] {
+			return i + 1
+		}
+	}
+	return min(1+len(priorTerm), len(currentTerm))
+}
+
+// Murmurhash3_x86_32 Returns the MurmurHash3_x86_32 hash of data.
+// Original source/tests at https://github.com/yonik/java_util/
+func Murmurhash3_x86_32(data []byte, seed uint32) uint32 {
+	const c1 = 0xcc9e2d51
+	const c2 = 0x1b873593
+
+	h1 := seed
+	roundedEnd := len(data) &^ 0x03 // round down to 4 byte block
+
+	for i := 0; i < roundedEnd; i += 4 {
+		// little endian load order
+		k1 := binary.LittleEndian.Uint32(data[i:])
+		k1 *= c1
+		k1 = bits.RotateLeft32(k1, 15)
+		k1 *= c2
+
+		h1 ^= k1
+		h1 = bits.RotateLeft32(h1, 13)
+		h1 = h1*5 + 0xe6546b64
+	}
+
+	// tail
+	k1 := uint32(0)
+	switch len(data) & 0x03 {
+	case 3:
+		k1 = uint32(data[roundedEnd+2]) << 16
+		fallthrough
+	case 2:
+		k1 |= uint32(data[roundedEnd+1]) << 8
+		fallthrough
+	case 1:
+		k1 |= uint32(data[roundedEnd])
+		k1 *= c1
+		k1 = bits.RotateLeft32(k1, 15)
+		k1 *= c2
+		h1 ^= k1
+	}
+
+	// finalization
+	h1 ^= uint32(len(data))
+
+	// fmix(h1);
+	h1 ^= h1 >> 16
+	h1 *= 0x85ebca6b
+	h1 ^= h1 >> 13
+	h1 *= 0xc2b2ae35
+	h1 ^= h1 >> 16
+
+	return h1
+}
+
 var (
 	nextId = big.NewInt(rand.Int63())
 	idLock sync.Mutex
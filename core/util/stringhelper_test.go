@@ -1,5 +1,11 @@
 package util
 
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
 //func TestRandomId(t *testing.T) {
 //	id := big.NewInt(int64(math.MaxInt64))
 //	fmt.Println(id.String())
@@ -9,3 +15,21 @@ package util
 //	fmt.Println(len(id.NewBytes()))
 //
 //}
+
+func TestSortKeyLength(t *testing.T) {
+	assert.Equal(t, 1, SortKeyLength([]byte("abc"), []byte("b")))
+	assert.Equal(t, 3, SortKeyLength([]byte("abc"), []byte("abdz")))
+	assert.Equal(t, 4, SortKeyLength([]byte("abc"), []byte("abcde")))
+	assert.Equal(t, 1, SortKeyLength([]byte{}, []byte("a")))
+}
+
+func TestMurmurhash3_x86_32(t *testing.T) {
+	assert.Equal(t, uint32(0), Murmurhash3_x86_32([]byte{}, 0))
+	assert.Equal(t, uint32(0x514e28b7), Murmurhash3_x86_32([]byte{}, 1))
+	assert.Equal(t, uint32(0x2e4ff723), Murmurhash3_x86_32([]byte("The quick brown fox jumps over the lazy dog"), 0))
+	assert.Equal(t, uint32(0xfaf6cdb3), Murmurhash3_x86_32([]byte("Hello, world!"), 1234))
+	// tails of 1, 2 and 3 bytes
+	assert.Equal(t, uint32(0x3c2569b2), Murmurhash3_x86_32([]byte("a"), 0))
+	assert.Equal(t, uint32(0x9bbfd75f), Murmurhash3_x86_32([]byte("ab"), 0))
+	assert.Equal(t, uint32(0xb3dd93fa), Murmurhash3_x86_32([]byte("abc"), 0))
+}
@@ -0,0 +1,54 @@
+package util
+
+import (
+	"errors"
+	"math"
+)
+
+// DotProduct Returns the vector dot product of the two vectors. The vectors must have the same
+// length.
+func DotProduct(a, b []float32) float32 {
+	res := float32(0)
+	for i := range a {
+		res += a[i] * b[i]
+	}
+	return res
+}
+
+// Cosine Returns the cosine similarity between the two vectors. The vectors must have the same
+// length, and must not be zero vectors: the result is NaN then.
+func Cosine(a, b []float32) float32 {
+	sum, norm1, norm2 := float32(0), float32(0), float32(0)
+	for i := range a {
+		elem1, elem2 := a[i], b[i]
+		sum += elem1 * elem2
+		norm1 += elem1 * elem1
+		norm2 += elem2 * elem2
+	}
+	return float32(float64(sum) / math.Sqrt(float64(norm1)*float64(norm2)))
+}
+
+// SquareDistance Returns the sum of squared differences of the two vectors. The vectors must
+// have the same length.
+func SquareDistance(a, b []float32) float32 {
+	squareSum := float32(0)
+	for i := range a {
+		diff := a[i] - b[i]
+		squareSum += diff * diff
+	}
+	return squareSum
+}
+
+// L2Normalize Modifies the argument to be unit length, dividing by its l2-norm. Returns an error
+// when the vector is zero.
+func L2Normalize(v []float32) ([]float32, error) {
+	squareSum := DotProduct(v, v)
+	if squareSum == 0 {
+		return nil, errors.New("cannot normalize a zero-length vector")
+	}
+	length := float32(math.Sqrt(float64(squareSum)))
+	for i := range v {
+		v[i] /= length
+	}
+	return v, nil
+}
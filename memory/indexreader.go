@@ -161,6 +161,16 @@ func (m *IndexReader) GetPointValues(field string) (types.PointValues, bool) {
 	return nil, false
 }
 
+// GetVectorValues a MemoryIndex does not index vectors
+func (m *IndexReader) GetVectorValues(field string) (index.VectorValues, error) {
+	return nil, nil
+}
+
+// SearchNearestVectors a MemoryIndex does not index vectors
+func (m *IndexReader) SearchNearestVectors(ctx context.Context, field string, target []float32, k int, acceptDocs util.Bits) (index.TopDocs, error) {
+	return nil, nil
+}
+
 func (m *IndexReader) CheckIntegrity() error {
 	return nil
 }